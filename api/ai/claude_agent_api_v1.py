@@ -37,7 +37,7 @@ from claude_agent_sdk import (
 from fastapi import FastAPI, Request, WebSocket, WebSocketDisconnect
 from fastapi.middleware.cors import CORSMiddleware
 from fastapi.responses import JSONResponse
-from incident_tools import create_incident_tools_server, set_auth_token, set_org_id, set_project_id as set_incident_project_id
+from incident_tools import create_incident_tools_server, set_artifact_context, set_auth_token, set_org_id, set_project_id as set_incident_project_id
 from memory_tools import create_memory_tools_server, set_user_id, set_project_id as set_memory_project_id
 from zero_trust_verifier import get_verifier, init_verifier
 from audit_service import (
@@ -1336,6 +1336,7 @@ async def agent_task_streaming(
         # Set user_id for memory tools tracking
         if context["user_id"]:
             set_user_id(context["user_id"])
+            set_artifact_context(context["user_id"], context["session_id"])
 
         # Get user workspace
         if context["user_id"]:
@@ -1370,6 +1371,7 @@ async def agent_task_streaming(
             "mcp__incident_tools__get_incidents_by_id",
             "mcp__incident_tools__get_current_time",
             "mcp__incident_tools__get_incident_stats",
+            "mcp__incident_tools__search_diagnostic_artifacts",
             "mcp__memory_tools__update_memory",
             "Skill"
         ]
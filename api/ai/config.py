@@ -141,6 +141,8 @@ class Config:
         self.oidc_client_id = os.getenv("OIDC_CLIENT_ID") or config_dict.get("oidc_client_id")
         # Backend session token (HS256 - fast path, shared with Go backend)
         self.session_secret = os.getenv("SESSION_SECRET") or config_dict.get("session_secret")
+        # Service token for the Go API's /internal routes (shared with Go backend)
+        self.internal_api_token = os.getenv("INTERNAL_API_TOKEN") or config_dict.get("internal_api_token")
 
         # External services
         self.anthropic_api_key = os.getenv("ANTHROPIC_API_KEY") or config_dict.get("anthropic_api_key")
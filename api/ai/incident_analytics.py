@@ -91,6 +91,7 @@ class IncidentAnalyticsPGMQ:
         priority = incident.get("priority", "unknown")
         labels = incident.get("labels", {})
         raw_data = incident.get("raw_data", {})
+        recent_changes = incident.get("recent_changes", [])
 
         prompt = f"""You are SRE, analyze this incident 
 # Incident Details
@@ -108,6 +109,16 @@ class IncidentAnalyticsPGMQ:
             for k, v in labels.items():
                 prompt += f"- {k}: {v}\n"
 
+        if recent_changes:
+            prompt += "\n# Recent Changes (2h before the incident)\n"
+            for change in recent_changes:
+                line = f"- {change.get('occurred_at', '')} [{change.get('type', 'other')}] {change.get('summary', '')}"
+                if change.get("version"):
+                    line += f" (version {change['version']})"
+                if change.get("environment"):
+                    line += f" in {change['environment']}"
+                prompt += line + "\n"
+
         if raw_data:
             prompt += f"\n# Raw Data\n```json\n{json.dumps(raw_data, indent=2)}\n```\n"
 
@@ -11,6 +11,7 @@ from datetime import datetime, timedelta
 from typing import Any, Optional
 from contextvars import ContextVar
 
+import httpx
 import psycopg2
 from psycopg2.extras import RealDictCursor
 from claude_agent_sdk import create_sdk_mcp_server, tool
@@ -20,6 +21,11 @@ from config import config
 # Context variables for tenant isolation (ReBAC)
 _org_id_ctx: ContextVar[Optional[str]] = ContextVar("org_id", default=None)
 _project_id_ctx: ContextVar[Optional[str]] = ContextVar("project_id", default=None)
+_user_id_ctx: ContextVar[Optional[str]] = ContextVar("user_id", default=None)
+_session_id_ctx: ContextVar[Optional[str]] = ContextVar("session_id", default=None)
+
+# Diagnostic artifacts are stored (and redacted) by the Go API
+ARTIFACT_API_TIMEOUT = 10.0
 
 
 def set_org_id(org_id: str) -> None:
@@ -42,6 +48,21 @@ def get_project_id() -> str:
     return _project_id_ctx.get() or ""
 
 
+def set_artifact_context(user_id: str, session_id: str) -> None:
+    """Set who diagnostic artifacts are attached on behalf of, and from which session."""
+    _user_id_ctx.set(user_id)
+    _session_id_ctx.set(session_id)
+
+
+def _api_base_url() -> str:
+    return os.getenv("API_BASE_URL", "http://localhost:8080").rstrip("/")
+
+
+def _internal_api_headers() -> dict[str, str]:
+    """Service credential the Go API requires on its /internal routes."""
+    return {"Authorization": f"Bearer {config.internal_api_token or ''}"}
+
+
 # Deprecated - kept for backward compatibility but no longer needed
 def set_auth_token(token: str) -> None:
     """Deprecated: Auth token not needed for direct DB access."""
@@ -504,6 +525,118 @@ async def _search_incidents_impl(args: dict[str, Any]) -> dict[str, Any]:
         }
 
 
+async def _attach_diagnostic_artifact_impl(args: dict[str, Any]) -> dict[str, Any]:
+    """
+    Store the output of a diagnostic command on an incident.
+
+    The Go API redacts credentials before storing it and applies the
+    retention policy, so the output becomes shared evidence for responders
+    instead of disappearing with this session.
+
+    Args:
+        incident_id: Incident the output belongs to
+        kind: logs, kubectl, command, metrics or other
+        title: Short description (e.g. "api pod logs, last 15m")
+        command: The command that produced the output
+        content: The output itself
+        retention_days: Optional retention (1-365), default from config
+    """
+    incident_id = args.get("incident_id")
+    content = args.get("content", "")
+    if not incident_id or not content or not args.get("title"):
+        return {
+            "content": [{"type": "text", "text": "Error: incident_id, title and content are required"}],
+            "isError": True,
+        }
+
+    org_id = get_org_id()
+    if not org_id:
+        return {
+            "content": [{"type": "text", "text": "Error: no organization in context"}],
+            "isError": True,
+        }
+
+    payload = {
+        "incident_id": incident_id,
+        "org_id": org_id,
+        "user_id": _user_id_ctx.get() or "",
+        "session_id": _session_id_ctx.get() or "",
+        "kind": args.get("kind") or "other",
+        "title": args.get("title"),
+        "command": args.get("command", ""),
+        "content": content,
+    }
+    if args.get("retention_days"):
+        payload["retention_days"] = int(args["retention_days"])
+
+    try:
+        async with httpx.AsyncClient(timeout=ARTIFACT_API_TIMEOUT) as client:
+            resp = await client.post(
+                f"{_api_base_url()}/internal/incident-artifacts", json=payload, headers=_internal_api_headers()
+            )
+        if resp.status_code != 201:
+            return {
+                "content": [{"type": "text", "text": f"Error: failed to store artifact ({resp.status_code}): {resp.text}"}],
+                "isError": True,
+            }
+        artifact = resp.json().get("artifact", {})
+        artifact.pop("content", None)
+        return {"content": [{"type": "text", "text": json.dumps(artifact, indent=2, default=str)}]}
+
+    except Exception as e:
+        return {
+            "content": [{"type": "text", "text": f"Error: {str(e)}"}],
+            "isError": True,
+        }
+
+
+async def _search_diagnostic_artifacts_impl(args: dict[str, Any]) -> dict[str, Any]:
+    """
+    Search stored diagnostic artifacts (redacted command output) of incidents
+    the current user can see.
+
+    Args:
+        query: Search query (e.g. "OOMKilled", "connection refused")
+        incident_id: Optional incident to search within
+        kind: Optional artifact kind
+        limit: Maximum number of results (default: 20, max: 100)
+    """
+    query = args.get("query", "")
+    if not query or query.strip() == "":
+        return {
+            "content": [{"type": "text", "text": "Error: Search query is required"}],
+            "isError": True,
+        }
+
+    params = {
+        "org_id": get_org_id(),
+        "user_id": _user_id_ctx.get() or "",
+        "q": query,
+        "limit": args.get("limit", 20),
+    }
+    for key in ("incident_id", "kind"):
+        if args.get(key):
+            params[key] = args[key]
+
+    try:
+        async with httpx.AsyncClient(timeout=ARTIFACT_API_TIMEOUT) as client:
+            resp = await client.get(
+                f"{_api_base_url()}/internal/incident-artifacts/search", params=params, headers=_internal_api_headers()
+            )
+        if resp.status_code != 200:
+            return {
+                "content": [{"type": "text", "text": f"Error: artifact search failed ({resp.status_code}): {resp.text}"}],
+                "isError": True,
+            }
+        return {"content": [{"type": "text", "text": json.dumps(resp.json(), indent=2, default=str)}]}
+
+    except Exception as e:
+        return {
+            "content": [{"type": "text", "text": f"Error: {str(e)}"}],
+            "isError": True,
+        }
+
+
 # Tool decorators for Claude Agent SDK
 @tool(
     "get_incidents_by_time",
@@ -560,6 +693,36 @@ async def search_incidents(args: dict[str, Any]) -> dict[str, Any]:
     return await _search_incidents_impl(args)
 
 
+@tool(
+    "attach_diagnostic_artifact",
+    "Attach the output of a diagnostic command (fetched logs, kubectl describe, metrics) to an incident so responders can review it later. Credentials are redacted before storing.",
+    {
+        "incident_id": str,
+        "kind": str,
+        "title": str,
+        "command": str,
+        "content": str,
+        "retention_days": int,
+    },
+)
+async def attach_diagnostic_artifact(args: dict[str, Any]) -> dict[str, Any]:
+    return await _attach_diagnostic_artifact_impl(args)
+
+
+@tool(
+    "search_diagnostic_artifacts",
+    "Search diagnostic output previously attached to incidents (by you or responders), e.g. to find earlier occurrences of an error message.",
+    {
+        "query": str,
+        "incident_id": str,
+        "kind": str,
+        "limit": int,
+    },
+)
+async def search_diagnostic_artifacts(args: dict[str, Any]) -> dict[str, Any]:
+    return await _search_diagnostic_artifacts_impl(args)
+
+
 # Export all tools as a list
 INCIDENT_TOOLS = [
     get_incidents_by_time,
@@ -567,6 +730,8 @@ INCIDENT_TOOLS = [
     get_incident_stats,
     get_current_time,
     search_incidents,
+    attach_diagnostic_artifact,
+    search_diagnostic_artifacts,
 ]
 
 
@@ -586,6 +751,7 @@ __all__ = [
     "get_org_id",
     "set_project_id",
     "get_project_id",
+    "set_artifact_context",
     "set_auth_token",  # Deprecated but kept for compatibility
     "get_auth_token",  # Deprecated but kept for compatibility
 ]
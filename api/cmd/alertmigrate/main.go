@@ -0,0 +1,73 @@
+// Command alertmigrate moves the legacy alerts table onto incidents.
+//
+// Alerts predate organizations and projects, so the operator names the
+// organization and project they land in. Each alert becomes an incident with
+// its original timestamps, assignee and ack state; the /alerts endpoints keep
+// resolving old alert IDs to the new incidents. Runs are resumable: alerts
+// already migrated are skipped.
+//
+// Usage:
+//
+//	go run ./cmd/alertmigrate -org <org-id> -project <project-id> -dry-run
+//	go run ./cmd/alertmigrate -org <org-id> -project <project-id>
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/services"
+)
+
+func main() {
+	opts := db.AlertMigrationOptions{}
+	flag.StringVar(&opts.OrganizationID, "org", "", "organization the migrated incidents belong to (required)")
+	flag.StringVar(&opts.ProjectID, "project", "", "project in -org the migrated incidents belong to (required)")
+	flag.IntVar(&opts.BatchSize, "batch", 500, "alerts migrated per statement")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "only report what would be migrated")
+	flag.Parse()
+
+	if opts.OrganizationID == "" || opts.ProjectID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := config.LoadConfig(os.Getenv("SLAR_CONFIG_PATH")); err != nil {
+		log.Fatalf("❌ Failed to load config: %v", err)
+	}
+	if config.Current().DatabaseURL == "" {
+		log.Fatal("❌ DATABASE_URL environment variable (or config) is required")
+	}
+
+	pg, err := sql.Open("postgres", config.Current().DatabaseURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer pg.Close()
+	if err := pg.Ping(); err != nil {
+		log.Fatalf("❌ Failed to ping database: %v", err)
+	}
+
+	report, err := services.NewAlertMigrationService(pg).MigrateAlerts(opts)
+	if err != nil {
+		if report != nil && report.Migrated > 0 {
+			log.Printf("⚠️  %d alerts were migrated before the failure; rerun to continue", report.Migrated)
+		}
+		log.Fatalf("❌ Migration failed: %v", err)
+	}
+
+	verb := "Migrated"
+	if report.DryRun {
+		verb = "Would migrate"
+	}
+	log.Printf("✅ %s %d alerts (%d already migrated)", verb, report.Migrated, report.AlreadyMigrated)
+	for status, count := range report.ByStatus {
+		log.Printf("   %s: %d", status, count)
+	}
+	log.Printf("Legacy-only alert fields flagged for removal: %v", db.LegacyAlertOnlyFields)
+}
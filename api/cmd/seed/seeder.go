@@ -0,0 +1,523 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	firstNames = []string{"Alex", "Bao", "Chen", "Dana", "Elif", "Farah", "Goran", "Hana", "Ivan", "Jun", "Kiran", "Linh", "Mateo", "Nadia", "Omar", "Priya", "Quang", "Rosa", "Sven", "Thao", "Uma", "Viktor", "Wei", "Yuki", "Zara"}
+	lastNames  = []string{"Nguyen", "Smith", "Garcia", "Kim", "Tran", "Müller", "Rossi", "Sato", "Novak", "Silva", "Khan", "Le", "Cohen", "Ivanova", "Okafor"}
+	teamNames  = []string{"Platform", "Payments", "Search", "Identity", "Data", "Mobile", "Edge", "Messaging", "Billing", "Infra"}
+	components = []string{"api-gateway", "checkout", "ledger", "auth", "search-indexer", "notifications", "cdn", "scheduler", "postgres", "redis", "kafka", "ingest", "web", "reports"}
+	symptoms   = []string{"High error rate", "Latency p99 above SLO", "Pod crash looping", "Disk usage above 90%", "Replication lag", "Queue backlog growing", "Certificate expiring", "5xx spike", "Memory pressure", "Health check failing"}
+
+	integrationTypes = []string{"prometheus", "datadog", "grafana", "webhook", "aws"}
+	severities       = []weighted{{"critical", 8}, {"error", 27}, {"warning", 50}, {"info", 15}}
+	priorities       = []weighted{{"P1", 5}, {"P2", 15}, {"P3", 40}, {"P4", 30}, {"P5", 10}}
+)
+
+type weighted struct {
+	value  string
+	weight float64
+}
+
+type seedGroup struct {
+	ID          string
+	SchedulerID string
+	PolicyID    string
+	Members     []string
+}
+
+type seedService struct {
+	ID    string
+	Group *seedGroup
+}
+
+type seeder struct {
+	pg   *sql.DB
+	opts seedOptions
+	rng  *rand.Rand
+
+	orgID        string
+	userIDs      []string
+	groups       []*seedGroup
+	services     []seedService
+	serviceCDF   []float64 // cumulative Zipf weights: a few services are very noisy
+	integrations []string
+}
+
+func newSeeder(pg *sql.DB, opts seedOptions) *seeder {
+	return &seeder{pg: pg, opts: opts, rng: rand.New(rand.NewSource(opts.RandSeed))}
+}
+
+// Run creates the organization and all seeded resources
+func (s *seeder) Run() error {
+	var existing int
+	if err := s.pg.QueryRow(`SELECT COUNT(*) FROM organizations WHERE slug = $1`, s.opts.OrgSlug).Scan(&existing); err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("organization %q already exists; run with -reset first", s.opts.OrgSlug)
+	}
+	if s.opts.Users < 1 || s.opts.Groups < 1 || s.opts.Services < 1 {
+		return fmt.Errorf("-users, -groups and -services must be at least 1")
+	}
+
+	steps := []struct {
+		name string
+		fn   func(tx *sql.Tx) error
+	}{
+		{"organization", s.seedOrganization},
+		{"users", s.seedUsers},
+		{"groups", s.seedGroups},
+		{"schedules", s.seedSchedules},
+		{"escalation policies", s.seedEscalationPolicies},
+		{"services", s.seedServices},
+		{"integrations", s.seedIntegrations},
+	}
+	for _, step := range steps {
+		start := time.Now()
+		if err := s.inTx(step.fn); err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+		log.Printf("   • %-20s %s", step.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	start := time.Now()
+	if err := s.seedIncidents(); err != nil {
+		return fmt.Errorf("incidents: %w", err)
+	}
+	log.Printf("   • %-20s %s", "incidents", time.Since(start).Round(time.Millisecond))
+
+	// Refresh planner statistics so benchmarks see realistic query plans immediately
+	if _, err := s.pg.Exec(`ANALYZE incidents`); err != nil {
+		log.Printf("⚠️  ANALYZE incidents failed: %v", err)
+	}
+	return nil
+}
+
+// Reset deletes everything created for the organization slug
+func (s *seeder) Reset() error {
+	var orgID string
+	err := s.pg.QueryRow(`SELECT id FROM organizations WHERE slug = $1`, s.opts.OrgSlug).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.inTx(func(tx *sql.Tx) error {
+		statements := []string{
+			`DELETE FROM incident_events WHERE incident_id IN (SELECT id FROM incidents WHERE organization_id = $1)`,
+			`DELETE FROM incidents WHERE organization_id = $1`,
+			`DELETE FROM service_integrations WHERE integration_id IN (SELECT id FROM integrations WHERE organization_id = $1)`,
+			`DELETE FROM integrations WHERE organization_id = $1`,
+			`DELETE FROM services WHERE organization_id = $1`,
+			`DELETE FROM escalation_levels WHERE policy_id IN (SELECT id FROM escalation_policies WHERE organization_id = $1)`,
+			`DELETE FROM escalation_policies WHERE organization_id = $1`,
+			`DELETE FROM shifts WHERE organization_id = $1`,
+			`DELETE FROM schedulers WHERE organization_id = $1`,
+			`DELETE FROM memberships WHERE resource_type = 'group' AND resource_id IN (SELECT id FROM groups WHERE organization_id = $1)`,
+			`DELETE FROM groups WHERE organization_id = $1`,
+			`DELETE FROM users WHERE provider = 'seed' AND id IN (SELECT user_id FROM memberships WHERE resource_type = 'org' AND resource_id = $1)`,
+			`DELETE FROM memberships WHERE resource_type = 'org' AND resource_id = $1`,
+			`DELETE FROM organizations WHERE id = $1`,
+		}
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt, orgID); err != nil {
+				return fmt.Errorf("%s: %w", stmt, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *seeder) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.pg.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *seeder) seedOrganization(tx *sql.Tx) error {
+	s.orgID = uuid.New().String()
+	_, err := tx.Exec(`
+		INSERT INTO organizations (id, name, slug, description)
+		VALUES ($1, $2, $3, 'Synthetic data generated by cmd/seed')
+	`, s.orgID, "Seed "+s.opts.OrgSlug, s.opts.OrgSlug)
+	return err
+}
+
+func (s *seeder) seedUsers(tx *sql.Tx) error {
+	stmt, err := tx.Prepare(pq.CopyIn("users", "id", "name", "email", "role", "team", "is_active", "created_at", "updated_at", "provider", "provider_id"))
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for i := 0; i < s.opts.Users; i++ {
+		id := uuid.New().String()
+		name := firstNames[s.rng.Intn(len(firstNames))] + " " + lastNames[s.rng.Intn(len(lastNames))]
+		email := fmt.Sprintf("%s-user%d@seed.slar.test", s.opts.OrgSlug, i)
+		if _, err := stmt.Exec(id, name, email, "engineer", teamNames[i%len(teamNames)], true, now, now, "seed", id); err != nil {
+			return err
+		}
+		s.userIDs = append(s.userIDs, id)
+	}
+	if _, err := stmt.Exec(); err != nil {
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	// First user owns the org, ~5% are admins
+	for i, id := range s.userIDs {
+		role := "member"
+		switch {
+		case i == 0:
+			role = "owner"
+		case s.rng.Float64() < 0.05:
+			role = "admin"
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO memberships (user_id, resource_type, resource_id, role) VALUES ($1, 'org', $2, $3)
+		`, id, s.orgID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) seedGroups(tx *sql.Tx) error {
+	for i := 0; i < s.opts.Groups; i++ {
+		g := &seedGroup{ID: uuid.New().String()}
+		team := teamNames[i%len(teamNames)]
+		if _, err := tx.Exec(`
+			INSERT INTO groups (id, name, description, type, visibility, created_by, organization_id)
+			VALUES ($1, $2, $3, 'escalation', 'organization', $4, $5)
+		`, g.ID, fmt.Sprintf("%s On-Call %d", team, i+1), "Seeded on-call team", s.userIDs[0], s.orgID); err != nil {
+			return err
+		}
+
+		// 4-10 members per group, first one is the leader
+		size := 4 + s.rng.Intn(7)
+		if size > len(s.userIDs) {
+			size = len(s.userIDs)
+		}
+		for j, idx := range s.rng.Perm(len(s.userIDs))[:size] {
+			role := "member"
+			if j == 0 {
+				role = "admin"
+			}
+			g.Members = append(g.Members, s.userIDs[idx])
+			if _, err := tx.Exec(`
+				INSERT INTO memberships (user_id, resource_type, resource_id, role) VALUES ($1, 'group', $2, $3)
+			`, s.userIDs[idx], g.ID, role); err != nil {
+				return err
+			}
+		}
+		s.groups = append(s.groups, g)
+	}
+	return nil
+}
+
+// seedSchedules creates one weekly rotation per group covering the incident
+// history window plus two weeks ahead
+func (s *seeder) seedSchedules(tx *sql.Tx) error {
+	stmt, err := tx.Prepare(pq.CopyIn("shifts", "id", "group_id", "user_id", "shift_type", "start_time", "end_time",
+		"is_active", "is_recurring", "rotation_days", "schedule_scope", "scheduler_id", "organization_id", "created_at", "updated_at", "created_by"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	// Align handoffs to Monday 09:00 UTC like most real rotations
+	start := now.AddDate(0, 0, -s.opts.Days).Truncate(24 * time.Hour).Add(9 * time.Hour)
+	for start.Weekday() != time.Monday {
+		start = start.AddDate(0, 0, -1)
+	}
+	end := now.AddDate(0, 0, 14)
+
+	for _, g := range s.groups {
+		g.SchedulerID = uuid.New().String()
+		if _, err := tx.Exec(`
+			INSERT INTO schedulers (id, name, display_name, group_id, description, rotation_type, organization_id, created_by)
+			VALUES ($1, $2, $3, $4, 'Seeded weekly rotation', 'weekly', $5, 'seed')
+		`, g.SchedulerID, "primary-"+g.ID[:8], "Primary", g.ID, s.orgID); err != nil {
+			return err
+		}
+
+		for shiftStart, i := start, 0; shiftStart.Before(end); shiftStart, i = shiftStart.AddDate(0, 0, 7), i+1 {
+			userID := g.Members[i%len(g.Members)]
+			if _, err := stmt.Exec(uuid.New().String(), g.ID, userID, "weekly", shiftStart, shiftStart.AddDate(0, 0, 7),
+				true, true, 7, "group", g.SchedulerID, s.orgID, now, now, "seed"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := stmt.Exec(); err != nil {
+		return err
+	}
+	return stmt.Close()
+}
+
+// seedEscalationPolicies gives each group: level 1 = on-call schedule, level 2 = whole group
+func (s *seeder) seedEscalationPolicies(tx *sql.Tx) error {
+	for _, g := range s.groups {
+		g.PolicyID = uuid.New().String()
+		if _, err := tx.Exec(`
+			INSERT INTO escalation_policies (id, name, description, is_active, repeat_max_times, group_id, created_by, organization_id, escalate_after_minutes)
+			VALUES ($1, $2, 'Seeded policy', true, 1, $3, 'seed', $4, 5)
+		`, g.PolicyID, "Default policy "+g.ID[:8], g.ID, s.orgID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO escalation_levels (policy_id, level_number, target_type, target_id, timeout_minutes)
+			VALUES ($1, 1, 'scheduler', $2, 5), ($1, 2, 'group', $3, 10)
+		`, g.PolicyID, g.SchedulerID, g.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) seedServices(tx *sql.Tx) error {
+	weights := make([]float64, s.opts.Services)
+	total := 0.0
+	for i := 0; i < s.opts.Services; i++ {
+		g := s.groups[i%len(s.groups)]
+		svc := seedService{ID: uuid.New().String(), Group: g}
+		name := fmt.Sprintf("%s-%d", components[i%len(components)], i+1)
+		if _, err := tx.Exec(`
+			INSERT INTO services (id, group_id, name, description, routing_key, is_active, created_by, escalation_policy_id, organization_id)
+			VALUES ($1, $2, $3, 'Seeded service', $4, true, 'seed', $5, $6)
+		`, svc.ID, g.ID, name, "seed-"+svc.ID, g.PolicyID, s.orgID); err != nil {
+			return err
+		}
+		s.services = append(s.services, svc)
+
+		// Zipf-like: service k gets weight 1/k^1.1
+		weights[i] = 1 / math.Pow(float64(i+1), 1.1)
+		total += weights[i]
+	}
+
+	// Shuffle so noisy services aren't always the first few created
+	s.rng.Shuffle(len(weights), func(i, j int) { weights[i], weights[j] = weights[j], weights[i] })
+	cum := 0.0
+	for _, w := range weights {
+		cum += w / total
+		s.serviceCDF = append(s.serviceCDF, cum)
+	}
+	return nil
+}
+
+func (s *seeder) seedIntegrations(tx *sql.Tx) error {
+	for i := 0; i < s.opts.Integrations; i++ {
+		id := uuid.New().String()
+		itype := integrationTypes[i%len(integrationTypes)]
+		if _, err := tx.Exec(`
+			INSERT INTO integrations (id, name, type, description, config, is_active, heartbeat_interval, created_by, organization_id, webhook_url)
+			VALUES ($1, $2, $3, 'Seeded integration', '{}', true, 300, 'seed', $4, $5)
+		`, id, fmt.Sprintf("%s-%d", itype, i+1), itype, s.orgID, fmt.Sprintf("http://localhost:8080/webhook/%s/%s", itype, id)); err != nil {
+			return err
+		}
+		s.integrations = append(s.integrations, id)
+
+		// Each integration feeds a handful of services
+		for _, idx := range s.rng.Perm(len(s.services))[:min(3, len(s.services))] {
+			if _, err := tx.Exec(`
+				INSERT INTO service_integrations (service_id, integration_id, routing_conditions, priority, is_active, created_by)
+				VALUES ($1, $2, '{}', 100, true, 'seed')
+			`, s.services[idx].ID, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// seedIncidents bulk-loads historical incidents with COPY in batches.
+// Arrival times favour business hours and weekdays; ack/resolve durations are
+// log-normal (median ~4m to ack, ~45m to resolve) with a long tail.
+func (s *seeder) seedIncidents() error {
+	now := time.Now().UTC()
+	window := time.Duration(s.opts.Days) * 24 * time.Hour
+
+	createdTimes := make([]time.Time, 0, s.opts.Incidents)
+	for len(createdTimes) < s.opts.Incidents {
+		t := now.Add(-time.Duration(s.rng.Int63n(int64(window))))
+		if s.rng.Float64() < arrivalWeight(t) {
+			createdTimes = append(createdTimes, t)
+		}
+	}
+	sort.Slice(createdTimes, func(i, j int) bool { return createdTimes[i].Before(createdTimes[j]) })
+
+	total := s.opts.Incidents + s.opts.OpenIncidents
+	batch := s.opts.BatchSize
+	if batch <= 0 {
+		batch = 5000
+	}
+
+	for offset := 0; offset < total; offset += batch {
+		end := min(offset+batch, total)
+		err := s.inTx(func(tx *sql.Tx) error {
+			stmt, err := tx.Prepare(pq.CopyIn("incidents",
+				"id", "title", "description", "status", "urgency", "priority", "severity", "source",
+				"integration_id", "service_id", "group_id", "escalation_policy_id", "organization_id",
+				"assigned_to", "assigned_at", "acknowledged_by", "acknowledged_at", "resolved_by", "resolved_at",
+				"current_escalation_level", "last_escalated_at", "escalation_status", "incident_key", "alert_count",
+				"labels", "created_at", "updated_at"))
+			if err != nil {
+				return err
+			}
+			for i := offset; i < end; i++ {
+				var row []interface{}
+				if i < s.opts.Incidents {
+					row = s.incidentRow(createdTimes[i], now, false)
+				} else {
+					// Currently triggered incidents for the escalation worker to chew on
+					row = s.incidentRow(now.Add(-time.Duration(s.rng.Intn(600))*time.Second), now, true)
+				}
+				if _, err := stmt.Exec(row...); err != nil {
+					return err
+				}
+			}
+			if _, err := stmt.Exec(); err != nil {
+				return err
+			}
+			return stmt.Close()
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("     incidents %d/%d", end, total)
+	}
+	return nil
+}
+
+// incidentRow builds one COPY row; open forces a triggered, unacknowledged incident
+func (s *seeder) incidentRow(created, now time.Time, open bool) []interface{} {
+	svc := s.pickService()
+	g := svc.Group
+	assignee := g.Members[s.rng.Intn(len(g.Members))]
+	severity := s.pick(severities)
+	urgency := "low"
+	if severity == "critical" || severity == "error" {
+		urgency = "high"
+	}
+
+	component := components[s.rng.Intn(len(components))]
+	title := fmt.Sprintf("%s: %s", symptoms[s.rng.Intn(len(symptoms))], component)
+	labels, _ := json.Marshal(map[string]string{"component": component, "env": "production", "seed": "true"})
+
+	var integrationID interface{}
+	source := "manual"
+	if len(s.integrations) > 0 && s.rng.Float64() < 0.85 {
+		integrationID = s.integrations[s.rng.Intn(len(s.integrations))]
+		source = "webhook"
+	}
+
+	ackAfter := logNormalDuration(s.rng, 4*time.Minute, 1.0)
+	resolveAfter := ackAfter + logNormalDuration(s.rng, 45*time.Minute, 1.2)
+
+	status := "resolved"
+	escalationStatus := "completed"
+	level := 1
+	var ackBy, ackAt, resolvedBy, resolvedAt, lastEscalated interface{}
+	switch {
+	case open || created.Add(ackAfter).After(now):
+		status, escalationStatus = "triggered", "pending"
+		level = 0
+		lastEscalated = created
+	case created.Add(resolveAfter).After(now):
+		status = "acknowledged"
+		ackBy, ackAt = assignee, created.Add(ackAfter)
+	default:
+		ackBy, ackAt = assignee, created.Add(ackAfter)
+		resolvedBy, resolvedAt = assignee, created.Add(resolveAfter)
+	}
+	if ackAfter > 5*time.Minute && status != "triggered" {
+		level = 2 // escalated past level 1 before someone acked
+	}
+
+	updated := created
+	if t, ok := resolvedAt.(time.Time); ok {
+		updated = t
+	} else if t, ok := ackAt.(time.Time); ok {
+		updated = t
+	}
+
+	return []interface{}{
+		uuid.New().String(), title, "Synthetic incident generated by cmd/seed", status, urgency, s.pick(priorities), severity, source,
+		integrationID, svc.ID, g.ID, g.PolicyID, s.orgID,
+		assignee, created, ackBy, ackAt, resolvedBy, resolvedAt,
+		level, lastEscalated, escalationStatus, fmt.Sprintf("%s-%s", component, svc.ID[:8]), 1 + geometric(s.rng, 0.6),
+		string(labels), created, updated,
+	}
+}
+
+func (s *seeder) pickService() seedService {
+	r := s.rng.Float64()
+	idx := sort.SearchFloat64s(s.serviceCDF, r)
+	if idx >= len(s.services) {
+		idx = len(s.services) - 1
+	}
+	return s.services[idx]
+}
+
+func (s *seeder) pick(options []weighted) string {
+	total := 0.0
+	for _, o := range options {
+		total += o.weight
+	}
+	r := s.rng.Float64() * total
+	for _, o := range options {
+		if r < o.weight {
+			return o.value
+		}
+		r -= o.weight
+	}
+	return options[len(options)-1].value
+}
+
+// arrivalWeight is the acceptance probability for an incident at t:
+// weekday business hours (UTC) are ~3x busier than nights and weekends
+func arrivalWeight(t time.Time) float64 {
+	w := 0.35
+	if h := t.Hour(); h >= 8 && h < 19 {
+		w = 1.0
+	}
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		w *= 0.5
+	}
+	return w
+}
+
+func logNormalDuration(rng *rand.Rand, median time.Duration, sigma float64) time.Duration {
+	return time.Duration(float64(median) * math.Exp(sigma*rng.NormFloat64()))
+}
+
+func geometric(rng *rand.Rand, p float64) int {
+	n := 0
+	for rng.Float64() > p && n < 50 {
+		n++
+	}
+	return n
+}
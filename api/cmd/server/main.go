@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/vanchonlee/slar/internal/config"
 	"github.com/vanchonlee/slar/internal/database"
+	"github.com/vanchonlee/slar/internal/faults"
 	"github.com/vanchonlee/slar/router"
 	"github.com/vanchonlee/slar/services"
 	"github.com/vanchonlee/slar/workers"
@@ -26,6 +29,8 @@ func main() {
 	if err := config.LoadConfig(configPath); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	config.StartSecretRefresher(context.Background())
+	config.WatchReloadSignal(context.Background())
 
 	// Set Gin mode
 	gin.SetMode(gin.DebugMode)
@@ -37,11 +42,12 @@ func main() {
 	var err error
 
 	// Database connection is required for workers
-	if config.App.DatabaseURL == "" {
+	if config.Current().DatabaseURL == "" {
 		log.Fatal("DATABASE_URL environment variable (or config) is required")
 	}
 
-	db, err = sql.Open("postgres", config.App.DatabaseURL)
+	// Wrapped to inject DB latency and pgmq failures when fault injection is enabled
+	db, err = sql.Open(faults.DriverName(), config.Current().DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -67,14 +73,14 @@ func main() {
 		MigrationsDir: database.MigrationsDir,
 	})
 
-	if config.App.MigrateBaseline {
+	if config.Current().MigrateBaseline {
 		// Baseline mode: mark all migrations as applied without running them
 		// Use this for existing databases that already have the schema
 		log.Println("Running migration baseline (marking all as applied)...")
 		if err := migrator.MarkAllAsApplied(); err != nil {
 			log.Fatalf("Failed to baseline migrations: %v", err)
 		}
-	} else if config.App.AutoMigrate {
+	} else if config.Current().AutoMigrate {
 		log.Println("Running database migrations...")
 		if err := migrator.Run(); err != nil {
 			log.Fatalf("Failed to run migrations: %v", err)
@@ -83,6 +89,16 @@ func main() {
 		log.Println("Auto-migration disabled (set AUTO_MIGRATE=true to enable)")
 	}
 
+	// Pick up injected faults (no-op unless fault_injection_enabled is set)
+	faults.Watch(context.Background(), db)
+
+	// Encrypt legacy plaintext columns and move values to the active key (no-op without ENCRYPTION_KEYS)
+	go func() {
+		if err := services.ReencryptSensitiveColumns(db); err != nil {
+			log.Printf("Failed to re-encrypt sensitive columns: %v", err)
+		}
+	}()
+
 	// Initialize router
 	r := router.NewGinRouter(db)
 
@@ -114,10 +130,19 @@ func main() {
 		incidentWorker.StartIncidentWorker()
 	}()
 
+	// Start FCM token lifecycle worker
+	fcmTokenWorker := workers.NewFCMTokenWorker(fcmService)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Println("Starting FCM token worker...")
+		fcmTokenWorker.StartFCMTokenWorker()
+	}()
+
 	log.Println("Workers started successfully")
 
 	// Start server in a goroutine
-	port := config.App.Port
+	port := config.Current().Port
 
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -134,7 +159,10 @@ func main() {
 		log.Printf("Authentication: Supabase JWT tokens required for protected endpoints")
 		log.Printf("")
 
-		if err := r.Run(":" + port); err != nil {
+		if prefix := config.NormalizedBasePath(); prefix != "" {
+			log.Printf("Serving API under base path %s", prefix)
+		}
+		if err := http.ListenAndServe(":"+port, router.WithBasePath(router.WithAPIVersions(r), config.Current().BasePath)); err != nil {
 			serverErrors <- err
 		}
 	}()
@@ -0,0 +1,155 @@
+// Command slar backs up and restores an instance's configuration.
+//
+// A backup is a versioned JSON bundle of organizations, projects, users
+// (metadata only), memberships, groups, services, escalation policies,
+// schedules, integrations and per-service settings, keyed by their original
+// IDs. Incidents and their history are not included. Secrets (integration
+// webhook secrets, Cloudflare tokens, monitor headers, phone numbers, push
+// tokens) are blanked and must be re-entered after a restore.
+//
+// Restore into a freshly migrated instance at the same schema version, before
+// anyone signs in: rows that already exist are skipped, and a user who signed
+// up with the same email under a new ID would orphan the restored memberships.
+// Restores run in one transaction and can be rerun.
+//
+// Usage:
+//
+//	go run ./cmd/slar backup -o slar-config.json
+//	go run ./cmd/slar restore -i slar-config.json -dry-run
+//	go run ./cmd/slar restore -i slar-config.json
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/services"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  slar backup [-o file]")
+	fmt.Fprintln(os.Stderr, "  slar restore [-i file] [-dry-run] [-allow-schema-mismatch]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "backup":
+		backup(os.Args[2:])
+	case "restore":
+		restore(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func connect() *sql.DB {
+	if err := config.LoadConfig(os.Getenv("SLAR_CONFIG_PATH")); err != nil {
+		log.Fatalf("❌ Failed to load config: %v", err)
+	}
+	if config.Current().DatabaseURL == "" {
+		log.Fatal("❌ DATABASE_URL environment variable (or config) is required")
+	}
+
+	pg, err := sql.Open("postgres", config.Current().DatabaseURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	if err := pg.Ping(); err != nil {
+		log.Fatalf("❌ Failed to ping database: %v", err)
+	}
+	return pg
+}
+
+func backup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("o", "-", "file to write the bundle to (- for stdout)")
+	fs.Parse(args)
+
+	pg := connect()
+	defer pg.Close()
+
+	bundle, err := services.NewConfigBackupService(pg).Backup()
+	if err != nil {
+		log.Fatalf("❌ Backup failed: %v", err)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		// Bundles hold user and org metadata; keep them private
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			log.Fatalf("❌ Failed to create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundle); err != nil {
+		log.Fatalf("❌ Failed to write bundle: %v", err)
+	}
+
+	total := 0
+	for _, table := range bundle.Tables {
+		total += len(table.Rows)
+	}
+	log.Printf("✅ Backed up %d rows from %d tables (schema %s)", total, len(bundle.Tables), bundle.SchemaVersion)
+}
+
+func restore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := fs.String("i", "-", "bundle to restore (- for stdin)")
+	opts := db.ConfigRestoreOptions{}
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "only report what would be restored")
+	fs.BoolVar(&opts.AllowSchemaMismatch, "allow-schema-mismatch", false, "restore a bundle taken at another schema version")
+	fs.Parse(args)
+
+	r := io.Reader(os.Stdin)
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatalf("❌ Failed to open %s: %v", *in, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var bundle db.ConfigBundle
+	if err := decoder.Decode(&bundle); err != nil {
+		log.Fatalf("❌ Failed to read bundle: %v", err)
+	}
+
+	pg := connect()
+	defer pg.Close()
+
+	report, err := services.NewConfigBackupService(pg).Restore(&bundle, opts)
+	if err != nil {
+		log.Fatalf("❌ Restore failed: %v", err)
+	}
+
+	verb := "Restored"
+	if report.DryRun {
+		verb = "Would restore"
+	}
+	log.Printf("✅ %s %d rows (%d already present)", verb, report.Inserted, report.Skipped)
+	for _, table := range report.Tables {
+		log.Printf("   %s: %d new, %d skipped", table.Name, table.Inserted, table.Skipped)
+		if len(table.Redacted) > 0 && table.Inserted > 0 {
+			log.Printf("      re-enter: %v", table.Redacted)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+// Command slarctl handles pages from a terminal through the SLAR API.
+//
+// It authenticates with an API key, acting as the user who owns the key, and
+// reads its connection from the environment:
+//
+//	SLAR_URL      API base URL (default http://localhost:8080)
+//	SLAR_API_KEY  API key (required)
+//	SLAR_ORG_ID   organization to work in; optional for org-scoped keys,
+//	              except for oncall which always needs it
+//
+// Incidents can be named by ID or by number (INC-1024). trigger sends a
+// PagerDuty-style event to a service's routing key; send it with a test-mode
+// API key so the incident is flagged as a test and nobody is paged.
+//
+// Usage:
+//
+//	slarctl incidents [-status open] [-limit 20]
+//	slarctl ack INC-1024 [-note text]
+//	slarctl resolve INC-1024 [-note text] [-resolution text]
+//	slarctl oncall [-group id]
+//	slarctl trigger -routing-key key [-summary text] [-severity warning] [-dedup-key key]
+//
+// Every command takes -json to print the API's answers for scripts, and exits
+// non-zero when a request fails.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  slarctl incidents [-status open] [-limit 20] [-json]")
+	fmt.Fprintln(os.Stderr, "  slarctl ack <incident> [-note text] [-json]")
+	fmt.Fprintln(os.Stderr, "  slarctl resolve <incident> [-note text] [-resolution text] [-json]")
+	fmt.Fprintln(os.Stderr, "  slarctl oncall [-group id] [-json]")
+	fmt.Fprintln(os.Stderr, "  slarctl trigger -routing-key key [-summary text] [-severity warning] [-dedup-key key] [-json]")
+	fmt.Fprintln(os.Stderr, "Environment: SLAR_URL, SLAR_API_KEY, SLAR_ORG_ID")
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("slarctl: ")
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "incidents":
+		err = listIncidents(args)
+	case "ack":
+		err = updateIncident("acknowledge", args)
+	case "resolve":
+		err = updateIncident("resolve", args)
+	case "oncall":
+		err = onCall(args)
+	case "trigger":
+		err = trigger(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// client calls the SLAR API with an API key
+type client struct {
+	BaseURL string
+	APIKey  string
+	OrgID   string
+	HTTP    *http.Client
+}
+
+func newClient() (*client, error) {
+	c := &client{
+		BaseURL: strings.TrimRight(os.Getenv("SLAR_URL"), "/"),
+		APIKey:  os.Getenv("SLAR_API_KEY"),
+		OrgID:   os.Getenv("SLAR_ORG_ID"),
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = "http://localhost:8080"
+	}
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("SLAR_API_KEY is not set")
+	}
+	return c, nil
+}
+
+// do sends body as JSON and decodes the answer into out. Answers outside 2xx
+// become errors carrying the API's own message.
+func (c *client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.OrgID != "" {
+		req.Header.Set("X-Org-ID", c.OrgID)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return apiError(method, path, resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+	return nil
+}
+
+// apiError turns an error answer into a readable error, preferring the
+// message and details fields the API sets
+func apiError(method, path string, status int, data []byte) error {
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Details string `json:"details"`
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil {
+		parts := []string{}
+		for _, p := range []string{body.Error, body.Message, body.Details} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		if len(parts) > 0 {
+			msg = strings.Join(parts, ": ")
+		}
+	}
+	return fmt.Errorf("%s %s: %d %s", method, path, status, msg)
+}
+
+// parseArgs parses flags, letting them follow the one positional argument
+// (slarctl ack INC-12 -note "on it") as well as precede it
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", nil
+	}
+	arg := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return arg, nil
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func listIncidents(args []string) error {
+	fs := flag.NewFlagSet("incidents", flag.ExitOnError)
+	status := fs.String("status", "open", "open, triggered, acknowledged, resolved, a workflow state, or all")
+	limit := fs.Int("limit", 20, "most incidents to show (up to 100)")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	query := url.Values{"limit": {strconv.Itoa(*limit)}}
+	if *status != "all" {
+		query.Set("status", *status)
+	}
+	var answer struct {
+		Incidents []db.IncidentResponse `json:"incidents"`
+	}
+	if err := c.do(http.MethodGet, "/incidents?"+query.Encode(), nil, &answer); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(answer.Incidents)
+	}
+
+	if len(answer.Incidents) == 0 {
+		fmt.Println("No incidents")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INCIDENT\tSTATUS\tSEVERITY\tSERVICE\tASSIGNED\tAGE\tTITLE")
+	for _, i := range answer.Incidents {
+		ref := i.Reference
+		if ref == "" {
+			ref = i.ID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", ref, i.Status, dash(i.Severity), dash(i.ServiceName),
+			dash(i.AssignedToName), age(i.CreatedAt), i.Title)
+	}
+	return w.Flush()
+}
+
+// updateIncident acknowledges or resolves one incident
+func updateIncident(action string, args []string) error {
+	fs := flag.NewFlagSet(action, flag.ExitOnError)
+	note := fs.String("note", "", "note added to the incident timeline")
+	resolution := ""
+	if action == "resolve" {
+		fs.StringVar(&resolution, "resolution", "", "how the incident was resolved")
+	}
+	asJSON := fs.Bool("json", false, "print JSON")
+	id, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		usage()
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	var body interface{} = db.AcknowledgeIncidentRequest{Note: *note}
+	if action == "resolve" {
+		body = db.ResolveIncidentRequest{Note: *note, Resolution: resolution}
+	}
+	var answer map[string]interface{}
+	if err := c.do(http.MethodPost, "/incidents/"+url.PathEscape(id)+"/"+action, body, &answer); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(answer)
+	}
+	fmt.Printf("%s %sd\n", id, action)
+	return nil
+}
+
+// onCall shows who is on call now for one group, or every group the key's
+// user belongs to
+func onCall(args []string) error {
+	fs := flag.NewFlagSet("oncall", flag.ExitOnError)
+	groupID := fs.String("group", "", "only this group")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	groups := []db.Group{{ID: *groupID, Name: *groupID}}
+	if *groupID == "" {
+		var answer struct {
+			Groups []db.Group `json:"groups"`
+		}
+		if err := c.do(http.MethodGet, "/groups?active_only=true", nil, &answer); err != nil {
+			return err
+		}
+		groups = answer.Groups
+	}
+
+	type groupOnCall struct {
+		GroupID   string    `json:"group_id"`
+		GroupName string    `json:"group_name"`
+		OnCall    *db.Shift `json:"current_oncall"`
+	}
+	result := []groupOnCall{}
+	for _, g := range groups {
+		var answer struct {
+			CurrentOnCall *db.Shift `json:"current_oncall"`
+		}
+		if err := c.do(http.MethodGet, "/groups/"+url.PathEscape(g.ID)+"/schedules/current", nil, &answer); err != nil {
+			return err
+		}
+		result = append(result, groupOnCall{GroupID: g.ID, GroupName: g.Name, OnCall: answer.CurrentOnCall})
+	}
+	if *asJSON {
+		return printJSON(result)
+	}
+
+	if len(result) == 0 {
+		fmt.Println("No groups")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tON CALL\tEMAIL\tUNTIL")
+	for _, r := range result {
+		if r.OnCall == nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\n", r.GroupName)
+			continue
+		}
+		name := r.OnCall.UserName
+		if r.OnCall.IsOverridden {
+			name += " (override)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.GroupName, dash(name), dash(r.OnCall.UserEmail),
+			r.OnCall.EndTime.Local().Format("Mon 15:04"))
+	}
+	return w.Flush()
+}
+
+// trigger sends a trigger event to a service's routing key
+func trigger(args []string) error {
+	fs := flag.NewFlagSet("trigger", flag.ExitOnError)
+	routingKey := fs.String("routing-key", "", "routing key of the service to page (required)")
+	summary := fs.String("summary", "Test event from slarctl", "incident title")
+	severity := fs.String("severity", "warning", "critical, error, warning or info")
+	source := fs.String("source", "slarctl", "where the event comes from")
+	dedupKey := fs.String("dedup-key", "", "events with the same key update one incident")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+	if *routingKey == "" {
+		usage()
+	}
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	event := db.WebhookIncidentRequest{
+		RoutingKey:  *routingKey,
+		EventAction: "trigger",
+		DedupKey:    *dedupKey,
+		Payload:     db.WebhookIncidentPayload{Summary: *summary, Source: *source, Severity: *severity},
+	}
+	var answer db.WebhookIncidentResponse
+	if err := c.do(http.MethodPost, "/webhooks/incident", event, &answer); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(answer)
+	}
+	fmt.Printf("%s: %s (incident %s, dedup key %s)\n", answer.Status, answer.Message, dash(answer.IncidentID), dash(answer.DedupKey))
+	return nil
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// age is how long ago t was, in its largest whole unit
+func age(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
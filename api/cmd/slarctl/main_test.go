@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"INC-12", "-note", "on it"},
+		{"-note", "on it", "INC-12"},
+	} {
+		fs := flag.NewFlagSet("ack", flag.ContinueOnError)
+		note := fs.String("note", "", "")
+		id, err := parseArgs(fs, args)
+		if err != nil || id != "INC-12" || *note != "on it" {
+			t.Errorf("%v: got %q, %q, %v", args, id, *note, err)
+		}
+	}
+
+	fs := flag.NewFlagSet("ack", flag.ContinueOnError)
+	if _, err := parseArgs(fs, []string{"INC-12", "INC-13"}); err == nil {
+		t.Error("expected an error for a second incident")
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key-1" || r.Header.Get("X-Org-ID") != "org-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/incidents/INC-12/acknowledge" {
+			w.Write([]byte(`{"message":"Incident acknowledged successfully"}`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"forbidden","message":"You do not have permission"}`))
+	}))
+	defer srv.Close()
+
+	c := &client{BaseURL: srv.URL, APIKey: "key-1", OrgID: "org-1", HTTP: srv.Client()}
+	var answer map[string]string
+	if err := c.do(http.MethodPost, "/incidents/INC-12/acknowledge", map[string]string{}, &answer); err != nil {
+		t.Fatal(err)
+	}
+	if answer["message"] == "" {
+		t.Errorf("answer not decoded: %v", answer)
+	}
+
+	err := c.do(http.MethodPost, "/incidents/INC-13/resolve", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "403 forbidden: You do not have permission") {
+		t.Errorf("got %v", err)
+	}
+}
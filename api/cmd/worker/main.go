@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/internal/faults"
 	"github.com/vanchonlee/slar/services"
 	"github.com/vanchonlee/slar/workers"
 )
@@ -24,13 +26,16 @@ func main() {
 	if err := config.LoadConfig(configPath); err != nil {
 		log.Fatalf("❌ Failed to load config: %v", err)
 	}
+	config.StartSecretRefresher(context.Background())
+	config.WatchReloadSignal(context.Background())
 
 	// Database connection
-	if config.App.DatabaseURL == "" {
+	if config.Current().DatabaseURL == "" {
 		log.Fatal("❌ DATABASE_URL environment variable (or config) is required")
 	}
 
-	pg, err := sql.Open("postgres", config.App.DatabaseURL)
+	// Wrapped to inject DB latency and pgmq failures when fault injection is enabled
+	pg, err := sql.Open(faults.DriverName(), config.Current().DatabaseURL)
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to database: %v", err)
 	}
@@ -50,6 +55,9 @@ func main() {
 
 	log.Println("✅ Connected to database successfully")
 
+	// Pick up injected faults (no-op unless fault_injection_enabled is set)
+	faults.Watch(context.Background(), pg)
+
 	// Initialize services
 	fcmService, _ := services.NewFCMService(pg)
 	incidentService := services.NewIncidentService(pg, fcmService)
@@ -83,6 +91,49 @@ func main() {
 		incidentWorker.StartIncidentWorker()
 	}()
 
+	// Start FCM token lifecycle worker
+	fcmTokenWorker := workers.NewFCMTokenWorker(fcmService)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Println("Starting FCM token worker...")
+		fcmTokenWorker.StartFCMTokenWorker()
+	}()
+
+	// Start metrics export worker when a provider is configured
+	if config.Current().MetricsExport.Provider != "" {
+		exporter, err := services.NewMetricsExportService(pg, config.Current().MetricsExport)
+		if err != nil {
+			log.Printf("⚠️  Metrics export disabled: %v", err)
+		} else {
+			metricsExportWorker := workers.NewMetricsExportWorker(exporter, config.Current().MetricsExport.Interval)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				log.Println("Starting metrics export worker...")
+				metricsExportWorker.StartMetricsExportWorker()
+			}()
+		}
+	}
+
+	// Start SIEM export worker; organizations opt in with a "siem" integration
+	siemExportWorker := workers.NewSIEMExportWorker(services.NewSIEMExportService(pg), 0)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Println("Starting SIEM export worker...")
+		siemExportWorker.StartSIEMExportWorker()
+	}()
+
+	// Start sheet export worker; groups opt in with a sheet export
+	sheetExportWorker := workers.NewSheetExportWorker(services.NewSheetExportService(pg), 0)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Println("Starting sheet export worker...")
+		sheetExportWorker.StartSheetExportWorker()
+	}()
+
 	// Start uptime monitoring worker - DISABLED
 	// wg.Add(1)
 	// go func() {
@@ -0,0 +1,81 @@
+package db
+
+import "time"
+
+// How often an activity digest is sent
+const (
+	ActivityDigestDaily  = "daily"
+	ActivityDigestWeekly = "weekly"
+)
+
+// ActivityDigestMaxNotable bounds the P1 incidents listed in one digest
+const ActivityDigestMaxNotable = 10
+
+// ActivityDigestSubscription asks for a daily or weekly email about the
+// incident activity of the groups a user manages
+type ActivityDigestSubscription struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"user_id"`
+	Frequency string   `json:"frequency"`
+	GroupIDs  []string `json:"group_ids"` // empty for every group the user manages
+	// End of the last period a digest was sent for
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type SubscribeActivityDigestRequest struct {
+	Frequency string   `json:"frequency" binding:"required,oneof=daily weekly"`
+	GroupIDs  []string `json:"group_ids"`
+}
+
+// DigestTrend is a mean time over the digest's period next to the one
+// before it; nil when no incident was acknowledged or resolved in the period
+type DigestTrend struct {
+	Seconds         *float64 `json:"seconds"`
+	PreviousSeconds *float64 `json:"previous_seconds"`
+}
+
+// ActivityDigestGroup is one group's incident activity over the period
+type ActivityDigestGroup struct {
+	GroupID   string      `json:"group_id"`
+	GroupName string      `json:"group_name"`
+	Opened    int         `json:"opened"`
+	Resolved  int         `json:"resolved"`
+	Open      int         `json:"open"` // still triggered or acknowledged
+	MTTA      DigestTrend `json:"mtta"`
+	MTTR      DigestTrend `json:"mttr"`
+}
+
+// DigestIncident is a notable incident listed in a digest
+type DigestIncident struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	Status     string     `json:"status"`
+	GroupName  string     `json:"group_name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// CoverageGap is a stretch in which nobody is on call for a group
+type CoverageGap struct {
+	GroupID   string    `json:"group_id"`
+	GroupName string    `json:"group_name"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Seconds   int64     `json:"seconds"`
+}
+
+// ActivityDigest is the incident activity of a manager's groups over
+// [From, To), compared with the period before, and the coverage gaps over
+// the next period from GeneratedAt
+type ActivityDigest struct {
+	UserID           string                `json:"user_id"`
+	Frequency        string                `json:"frequency"`
+	From             time.Time             `json:"from"`
+	To               time.Time             `json:"to"`
+	GeneratedAt      time.Time             `json:"generated_at"`
+	Groups           []ActivityDigestGroup `json:"groups"`
+	NotableIncidents []DigestIncident      `json:"notable_incidents"`
+	CoverageGaps     []CoverageGap         `json:"coverage_gaps"`
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Dedup review statuses
+const (
+	DedupReviewStatusPending   = "pending"
+	DedupReviewStatusAttached  = "attached"
+	DedupReviewStatusPromoted  = "promoted"
+	DedupReviewStatusDismissed = "dismissed"
+)
+
+// DedupStrategyTitle is the only match strategy that is parked for review:
+// a title says nothing about which host or resource is alerting
+const DedupStrategyTitle = "title"
+
+// Dedup decision modes and outcomes counted in alert_dedup_decisions
+const (
+	DedupModeAuto   = "auto"
+	DedupModeManual = "manual"
+
+	DedupOutcomeAttached  = "attached"  // alert joined an existing incident
+	DedupOutcomeCreated   = "created"   // alert opened a new incident
+	DedupOutcomeResolved  = "resolved"  // resolve alert closed an incident
+	DedupOutcomeParked    = "parked"    // sent to the review queue
+	DedupOutcomePromoted  = "promoted"  // reviewer opened a new incident
+	DedupOutcomeDismissed = "dismissed" // reviewer dropped the alert
+)
+
+// AlertDedupReview is an alert parked until someone decides which incident
+// it belongs to. Alert holds the alert as it was received.
+type AlertDedupReview struct {
+	ID                     string          `json:"id"`
+	OrganizationID         string          `json:"organization_id"`
+	IntegrationID          string          `json:"integration_id,omitempty"`
+	CandidateIncidentID    string          `json:"candidate_incident_id,omitempty"`
+	CandidateIncidentTitle string          `json:"candidate_incident_title,omitempty"`
+	AlertName              string          `json:"alert_name"`
+	AlertStatus            string          `json:"alert_status"` // firing, resolved
+	Severity               string          `json:"severity,omitempty"`
+	MatchStrategy          string          `json:"match_strategy"`
+	Alert                  json.RawMessage `json:"alert"`
+	Status                 string          `json:"status"`
+	IncidentID             string          `json:"incident_id,omitempty"` // where the alert ended up
+	DecidedBy              string          `json:"decided_by,omitempty"`
+	DecidedAt              *time.Time      `json:"decided_at,omitempty"`
+	CreatedAt              time.Time       `json:"created_at"`
+}
+
+// AttachDedupReviewRequest attaches a parked alert to an incident, the
+// candidate incident when IncidentID is empty
+type AttachDedupReviewRequest struct {
+	IncidentID string `json:"incident_id,omitempty" binding:"omitempty,uuid"`
+}
+
+// AlertDedupStats counts deduplication decisions by outcome over a period.
+// ManualRate is the share of decisions that needed a person.
+type AlertDedupStats struct {
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Auto        map[string]int `json:"auto"`
+	Manual      map[string]int `json:"manual"`
+	AutoTotal   int            `json:"auto_total"`
+	ManualTotal int            `json:"manual_total"`
+	ManualRate  float64        `json:"manual_rate"`
+	Pending     int            `json:"pending"`
+}
@@ -0,0 +1,30 @@
+package db
+
+// Legacy alert statuses, as served by the /alerts compatibility endpoints
+const (
+	LegacyAlertStatusNew    = "new"
+	LegacyAlertStatusAcked  = "acked"
+	LegacyAlertStatusClosed = "closed"
+)
+
+// LegacyAlertOnlyFields are AlertResponse fields with no incident counterpart.
+// /alerts responses list them in the X-Deprecated-Fields header; they are
+// always empty now and will be removed with the endpoints.
+var LegacyAlertOnlyFields = []string{"escalation_rule_id", "escalation_rule_name", "escalation_history"}
+
+// AlertMigrationOptions controls a run of the legacy alert migration
+type AlertMigrationOptions struct {
+	// Legacy alerts predate organizations, so the operator picks where they land
+	OrganizationID string
+	ProjectID      string
+	BatchSize      int
+	DryRun         bool
+}
+
+// AlertMigrationReport summarizes a legacy alert migration run
+type AlertMigrationReport struct {
+	DryRun          bool           `json:"dry_run"`
+	Migrated        int            `json:"migrated"` // would be migrated, on a dry run
+	AlreadyMigrated int            `json:"already_migrated"`
+	ByStatus        map[string]int `json:"by_status"` // incident status of the migrated alerts
+}
@@ -0,0 +1,69 @@
+package db
+
+import "time"
+
+// Ways to group incidents in the noisy alerts report
+const (
+	AlertQualityByAlertName   = "alertname"
+	AlertQualityByFingerprint = "fingerprint"
+)
+
+const (
+	// AlertQualityFastAutoResolveSeconds is how soon an auto-resolve counts
+	// as flapping: the alert cleared before anyone could reasonably act
+	AlertQualityFastAutoResolveSeconds = 300
+
+	NoisyAlertsDefaultMinIncidents = 3
+	NoisyAlertsDefaultLimit        = 20
+	NoisyAlertsMaxLimit            = 100
+)
+
+// Recommendations attached to noisy alerts
+const (
+	AlertRecommendationRouteAway = "route_away_from_paging" // pages nobody acts on
+	AlertRecommendationTune      = "tune_threshold"         // mostly clears on its own, quickly
+)
+
+// AlertQualityScore summarizes how actionable the incidents raised by one
+// alert (alertname or fingerprint) were. Rates are over resolved incidents.
+type AlertQualityScore struct {
+	Key       string    `json:"key"`
+	Title     string    `json:"title"`
+	Incidents int       `json:"incidents"`
+	Alerts    int       `json:"alerts"`
+	Resolved  int       `json:"resolved"`
+	Services  int       `json:"services"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	ResolvedWithoutAck     int     `json:"resolved_without_ack"`
+	ResolvedWithoutAckRate float64 `json:"resolved_without_ack_rate"`
+	AutoResolved           int     `json:"auto_resolved"`
+	AutoResolveRate        float64 `json:"auto_resolve_rate"`
+	FastAutoResolved       int     `json:"fast_auto_resolved"`
+	FastAutoResolveRate    float64 `json:"fast_auto_resolve_rate"`
+
+	AvgSecondsToAutoResolve    *float64 `json:"avg_seconds_to_auto_resolve"`
+	MedianSecondsToAutoResolve *float64 `json:"median_seconds_to_auto_resolve"`
+
+	// NoiseScore is 0 (every page was acted on) to 100 (pure noise)
+	NoiseScore     float64 `json:"noise_score"`
+	Recommendation string  `json:"recommendation,omitempty"`
+}
+
+// NoisyAlertsReport ranks an organization's alerts by noise score
+type NoisyAlertsReport struct {
+	OrganizationID string              `json:"organization_id"`
+	From           time.Time           `json:"from"`
+	To             time.Time           `json:"to"`
+	GroupBy        string              `json:"group_by"`
+	MinIncidents   int                 `json:"min_incidents"`
+	Alerts         []AlertQualityScore `json:"alerts"`
+}
+
+// NoisyAlertsFilter narrows the noisy alerts report
+type NoisyAlertsFilter struct {
+	GroupBy      string
+	ServiceID    string
+	MinIncidents int
+	Limit        int
+}
@@ -0,0 +1,49 @@
+package db
+
+import "time"
+
+// IncidentSourceAlertStorm is the incident source for alert storm meta-incidents
+const IncidentSourceAlertStorm = "alert_storm"
+
+// AlertStormIncidentKeyPrefix + service ID dedups alert storm incidents
+const AlertStormIncidentKeyPrefix = "alert-storm:"
+
+// Defaults for fields an alert storm rule leaves out
+const (
+	AlertStormDefaultSpikeMultiple = 5
+	AlertStormDefaultWindowMinutes = 10
+	AlertStormDefaultBaselineHours = 24
+	AlertStormDefaultMinIncidents  = 5
+)
+
+// ServiceAlertStormRule detects a spike in a service's incident rate: more
+// than MinIncidents incidents in the last WindowMinutes and over
+// SpikeMultiple times the service's average for a window of that length over
+// the BaselineHours before it
+type ServiceAlertStormRule struct {
+	ServiceID     string    `json:"service_id"`
+	SpikeMultiple float64   `json:"spike_multiple"`
+	WindowMinutes int       `json:"window_minutes"`
+	BaselineHours int       `json:"baseline_hours"`
+	MinIncidents  int       `json:"min_incidents"`
+	GroupAlerts   bool      `json:"group_alerts"` // new alerts join the storm incident instead of paging
+	UpdatedBy     string    `json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SetAlertStormRuleRequest sets a service's alert storm rule; zero fields take the defaults
+type SetAlertStormRuleRequest struct {
+	SpikeMultiple float64 `json:"spike_multiple"`
+	WindowMinutes int     `json:"window_minutes"`
+	BaselineHours int     `json:"baseline_hours"`
+	MinIncidents  int     `json:"min_incidents"`
+	GroupAlerts   bool    `json:"group_alerts"`
+}
+
+// AlertStormStatus is a service's alert storm rule and, while a storm is
+// going on, its incident
+type AlertStormStatus struct {
+	Rule            *ServiceAlertStormRule `json:"rule"`
+	StormIncidentID string                 `json:"storm_incident_id,omitempty"`
+	StormSince      *time.Time             `json:"storm_since,omitempty"`
+}
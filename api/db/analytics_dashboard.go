@@ -0,0 +1,64 @@
+package db
+
+import "time"
+
+// AnalyticsDashboard is a saved layout of metric widgets. It is private to
+// its owner unless shared with a group.
+type AnalyticsDashboard struct {
+	ID          string                 `json:"id"`
+	OwnerID     string                 `json:"owner_id"`
+	OwnerName   string                 `json:"owner_name,omitempty"`
+	GroupID     string                 `json:"group_id,omitempty"`
+	GroupName   string                 `json:"group_name,omitempty"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Widgets     []DashboardWidget      `json:"widgets"`
+	Filters     map[string]interface{} `json:"filters"`
+	CanEdit     bool                   `json:"can_edit"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// DashboardWidget places one metric on the dashboard's 12-column grid.
+// Filters override the dashboard-wide filters for this widget only.
+type DashboardWidget struct {
+	ID            string                 `json:"id"`
+	Metric        string                 `json:"metric"`
+	Title         string                 `json:"title,omitempty"`
+	Visualization string                 `json:"visualization,omitempty"` // number, bar, pie, line, table
+	X             int                    `json:"x"`
+	Y             int                    `json:"y"`
+	W             int                    `json:"w"`
+	H             int                    `json:"h"`
+	Filters       map[string]interface{} `json:"filters,omitempty"`
+}
+
+// AnalyticsMetric describes a metric a widget can show and the analytics
+// endpoint the client reads it from
+type AnalyticsMetric struct {
+	Key            string   `json:"key"`
+	Name           string   `json:"name"`
+	Source         string   `json:"source"`
+	Field          string   `json:"field,omitempty"`
+	Visualizations []string `json:"visualizations"`
+}
+
+// CreateAnalyticsDashboardRequest saves a new dashboard. GroupID shares it
+// with that group.
+type CreateAnalyticsDashboardRequest struct {
+	Name        string                 `json:"name" binding:"required,max=200"`
+	Description string                 `json:"description"`
+	GroupID     string                 `json:"group_id"`
+	Widgets     []DashboardWidget      `json:"widgets"`
+	Filters     map[string]interface{} `json:"filters"`
+}
+
+// UpdateAnalyticsDashboardRequest changes only the fields that are set.
+// GroupID "" makes the dashboard private again.
+type UpdateAnalyticsDashboardRequest struct {
+	Name        *string                 `json:"name" binding:"omitempty,min=1,max=200"`
+	Description *string                 `json:"description"`
+	GroupID     *string                 `json:"group_id"`
+	Widgets     *[]DashboardWidget      `json:"widgets"`
+	Filters     *map[string]interface{} `json:"filters"`
+}
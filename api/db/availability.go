@@ -0,0 +1,37 @@
+package db
+
+import "time"
+
+// Kinds of user unavailability
+const (
+	UnavailabilityVacation = "vacation"
+	UnavailabilityDND      = "dnd"
+)
+
+// UserUnavailability is a period a user must not be paged through schedules
+type UserUnavailability struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Kind      string    `json:"kind"` // vacation, dnd
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateUnavailabilityRequest marks the caller unavailable for a period
+type CreateUnavailabilityRequest struct {
+	Kind     string    `json:"kind"` // default vacation
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Reason   string    `json:"reason"`
+}
+
+// SkippedResponder is an on-call user an escalation passed over because they
+// were unavailable
+type SkippedResponder struct {
+	UserID   string    `json:"user_id"`
+	UserName string    `json:"user_name,omitempty"`
+	Kind     string    `json:"kind"`
+	Until    time.Time `json:"until"`
+}
@@ -0,0 +1,54 @@
+package db
+
+import "time"
+
+// Change event types
+const (
+	ChangeEventTypeDeploy         = "deploy"
+	ChangeEventTypeFeatureFlag    = "feature_flag"
+	ChangeEventTypeInfrastructure = "infrastructure"
+	ChangeEventTypeConfig         = "config"
+	ChangeEventTypeOther          = "other"
+)
+
+// ChangeEventCorrelationWindow is how far before an incident started its
+// service's changes are considered related
+const ChangeEventCorrelationWindow = 2 * time.Hour
+
+// ChangeEvent is a deploy, feature flag flip or infrastructure change on a service
+type ChangeEvent struct {
+	ID             string                 `json:"id"`
+	OrganizationID string                 `json:"organization_id"`
+	ProjectID      string                 `json:"project_id,omitempty"`
+	ServiceID      string                 `json:"service_id,omitempty"`
+	ServiceName    string                 `json:"service_name,omitempty"`
+	Type           string                 `json:"type"`
+	Source         string                 `json:"source"`
+	Summary        string                 `json:"summary"`
+	URL            string                 `json:"url,omitempty"`
+	Version        string                 `json:"version,omitempty"`
+	Environment    string                 `json:"environment,omitempty"`
+	Author         string                 `json:"author,omitempty"`
+	CustomDetails  map[string]interface{} `json:"custom_details,omitempty"`
+	OccurredAt     time.Time              `json:"occurred_at"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// WebhookChangeEventRequest is the body of POST /webhooks/change
+type WebhookChangeEventRequest struct {
+	RoutingKey string                    `json:"routing_key" binding:"required"`
+	Payload    WebhookChangeEventPayload `json:"payload" binding:"required"`
+}
+
+// WebhookChangeEventPayload describes the change being reported
+type WebhookChangeEventPayload struct {
+	Summary       string                 `json:"summary" binding:"required"`
+	Source        string                 `json:"source" binding:"required"`
+	Type          string                 `json:"type,omitempty" binding:"omitempty,oneof=deploy feature_flag infrastructure config other"`
+	Timestamp     *time.Time             `json:"timestamp,omitempty"`
+	URL           string                 `json:"url,omitempty"`
+	Version       string                 `json:"version,omitempty"`
+	Environment   string                 `json:"environment,omitempty"`
+	Author        string                 `json:"author,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
@@ -0,0 +1,64 @@
+package db
+
+import "time"
+
+// Built-in group chat channel types; more can be registered through the notify package
+const (
+	ChatChannelDiscord    = "discord"
+	ChatChannelTelegram   = "telegram"
+	ChatChannelGoogleChat = "google_chat"
+)
+
+// GroupChatChannel is a Discord webhook, Telegram chat, Google Chat space or
+// other registered channel that receives a group's incident notifications.
+// Secrets are never returned by the API.
+type GroupChatChannel struct {
+	ID           string            `json:"id"`
+	GroupID      string            `json:"group_id"`
+	ChannelType  string            `json:"channel_type"` // discord, telegram, google_chat or a registered type
+	Name         string            `json:"name"`
+	WebhookURL   string            `json:"-"`
+	BotToken     string            `json:"-"`
+	ChatID       string            `json:"chat_id,omitempty"`
+	Config       map[string]string `json:"-"`                // settings without a column of their own
+	PublicConfig map[string]string `json:"config,omitempty"` // non-secret settings
+	IsActive     bool              `json:"is_active"`
+	CreatedBy    string            `json:"created_by,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// CreateGroupChatChannelRequest adds a chat channel to a group. Settings are
+// described by the channel type's schema (GET /chat-channel-types) and go in
+// config; webhook_url, bot_token and chat_id are also accepted at the top level.
+type CreateGroupChatChannelRequest struct {
+	ChannelType string            `json:"channel_type" binding:"required"`
+	Name        string            `json:"name" binding:"required"`
+	WebhookURL  string            `json:"webhook_url"`
+	BotToken    string            `json:"bot_token"`
+	ChatID      string            `json:"chat_id"`
+	Config      map[string]string `json:"config"`
+}
+
+// UpdateGroupChatChannelRequest changes a chat channel; empty secrets are kept
+type UpdateGroupChatChannelRequest struct {
+	Name       *string           `json:"name"`
+	WebhookURL *string           `json:"webhook_url"`
+	BotToken   *string           `json:"bot_token"`
+	ChatID     *string           `json:"chat_id"`
+	Config     map[string]string `json:"config"`
+	IsActive   *bool             `json:"is_active"`
+}
+
+// IncidentAckLink is the public view of an acknowledge link sent to chat
+type IncidentAckLink struct {
+	IncidentID  string     `json:"incident_id"`
+	Title       string     `json:"title"`
+	Status      string     `json:"status"`
+	Severity    string     `json:"severity,omitempty"`
+	Urgency     string     `json:"urgency"`
+	ServiceName string     `json:"service_name,omitempty"`
+	UserName    string     `json:"user_name,omitempty"` // who acknowledging acts on behalf of
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty"`
+}
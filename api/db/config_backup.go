@@ -0,0 +1,52 @@
+package db
+
+import "time"
+
+// ConfigBundleFormatVersion is the layout version of configuration bundles.
+// Restore refuses bundles written in another layout.
+const ConfigBundleFormatVersion = 1
+
+// ConfigBundle is a configuration backup: organizations, users (metadata
+// only), groups, services, escalation policies, schedules, integrations and
+// their settings, keyed by their original IDs. Secrets are blanked.
+// SchemaVersion is the latest migration applied on the source instance; the
+// bundle restores onto an instance at the same version.
+type ConfigBundle struct {
+	FormatVersion int                 `json:"format_version"`
+	SchemaVersion string              `json:"schema_version"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Tables        []ConfigBundleTable `json:"tables"`
+}
+
+// ConfigBundleTable holds every row of one table. Redacted lists the columns
+// whose values were blanked and must be re-entered after a restore.
+type ConfigBundleTable struct {
+	Name     string                   `json:"name"`
+	Redacted []string                 `json:"redacted,omitempty"`
+	Rows     []map[string]interface{} `json:"rows"`
+}
+
+// ConfigRestoreOptions controls a configuration restore
+type ConfigRestoreOptions struct {
+	DryRun bool
+	// AllowSchemaMismatch restores onto an instance at another schema
+	// version; only columns both instances have are restored
+	AllowSchemaMismatch bool
+}
+
+// ConfigRestoreTableResult is what a restore did with one table
+type ConfigRestoreTableResult struct {
+	Name     string   `json:"name"`
+	Rows     int      `json:"rows"`
+	Inserted int      `json:"inserted"`
+	Skipped  int      `json:"skipped"` // already present (same ID or unique key)
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+// ConfigRestoreReport summarizes a configuration restore
+type ConfigRestoreReport struct {
+	DryRun   bool                       `json:"dry_run"`
+	Inserted int                        `json:"inserted"`
+	Skipped  int                        `json:"skipped"`
+	Tables   []ConfigRestoreTableResult `json:"tables"`
+}
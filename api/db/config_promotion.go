@@ -0,0 +1,55 @@
+package db
+
+// Config promotion change actions
+const (
+	ConfigChangeCreate = "create"
+	ConfigChangeUpdate = "update"
+	ConfigChangeDelete = "delete"
+)
+
+// ConfigFieldChange is one column that differs between environments
+type ConfigFieldChange struct {
+	Column string      `json:"column"`
+	From   interface{} `json:"from"`
+	To     interface{} `json:"to"`
+}
+
+// ConfigChange is one row that differs between the source (e.g. staging)
+// bundle and this instance. ID ("<table>/<key>") selects it for promotion.
+type ConfigChange struct {
+	ID     string              `json:"id"`
+	Table  string              `json:"table"`
+	Key    string              `json:"key"`
+	Action string              `json:"action"`
+	Fields []ConfigFieldChange `json:"fields,omitempty"`
+}
+
+// ConfigDiff is every change promoting a bundle onto this instance would make.
+// Secrets and runtime state (timestamps, last check results) are not compared.
+type ConfigDiff struct {
+	SourceSchemaVersion string         `json:"source_schema_version"`
+	TargetSchemaVersion string         `json:"target_schema_version"`
+	Creates             int            `json:"creates"`
+	Updates             int            `json:"updates"`
+	Deletes             int            `json:"deletes"`
+	Changes             []ConfigChange `json:"changes"`
+}
+
+// ConfigDiffRequest diffs a source bundle against this instance
+type ConfigDiffRequest struct {
+	Bundle *ConfigBundle `json:"bundle" binding:"required"`
+}
+
+// PromoteConfigRequest applies the selected changes of a source bundle
+type PromoteConfigRequest struct {
+	Bundle  *ConfigBundle `json:"bundle" binding:"required"`
+	Changes []string      `json:"changes" binding:"required"` // ConfigChange IDs
+	DryRun  bool          `json:"dry_run"`
+}
+
+// ConfigPromotionResult is the outcome of a promotion. Applied lists the
+// changes made (or that would be made, on a dry run).
+type ConfigPromotionResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Applied []ConfigChange `json:"applied"`
+}
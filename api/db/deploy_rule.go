@@ -0,0 +1,67 @@
+package db
+
+import "time"
+
+// Deploy rule actions
+const (
+	DeployRuleActionAnnotate = "annotate"
+	DeployRuleActionResolve  = "resolve"
+)
+
+// ServiceDeployRule annotates or resolves a service's open incidents when a
+// deployment webhook for the service arrives
+type ServiceDeployRule struct {
+	ID          string            `json:"id"`
+	ServiceID   string            `json:"service_id"`
+	Name        string            `json:"name"`
+	Environment string            `json:"environment,omitempty"` // empty = any environment
+	MatchLabels map[string]string `json:"match_labels"`          // incident must carry all of these labels
+	Action      string            `json:"action"`                // annotate, resolve
+	IsActive    bool              `json:"is_active"`
+	CreatedBy   string            `json:"created_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+type CreateServiceDeployRuleRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Environment string            `json:"environment"`
+	MatchLabels map[string]string `json:"match_labels"`
+	Action      string            `json:"action" binding:"required,oneof=annotate resolve"`
+}
+
+type UpdateServiceDeployRuleRequest struct {
+	Name        *string            `json:"name,omitempty"`
+	Environment *string            `json:"environment,omitempty"`
+	MatchLabels *map[string]string `json:"match_labels,omitempty"`
+	Action      *string            `json:"action,omitempty" binding:"omitempty,oneof=annotate resolve"`
+	IsActive    *bool              `json:"is_active,omitempty"`
+}
+
+// DeployEvent is a successful (or failed) deployment normalized from a
+// GitHub, GitLab or ArgoCD webhook
+type DeployEvent struct {
+	Provider    string `json:"provider"` // github, gitlab, argocd
+	Repository  string `json:"repository,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Version     string `json:"version,omitempty"` // commit SHA, tag or ArgoCD revision
+	Ref         string `json:"ref,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Status      string `json:"status"` // success, failure, pending, ...
+	DeployedBy  string `json:"deployed_by,omitempty"`
+}
+
+// Succeeded reports whether the deploy finished successfully; only successful
+// deploys fire rules
+func (e DeployEvent) Succeeded() bool {
+	return e.Status == "success"
+}
+
+// DeployRuleMatch records what a deploy did to one incident
+type DeployRuleMatch struct {
+	IncidentID string `json:"incident_id"`
+	ServiceID  string `json:"service_id"`
+	RuleID     string `json:"rule_id"`
+	RuleName   string `json:"rule_name"`
+	Action     string `json:"action"`
+}
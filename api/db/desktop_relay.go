@@ -0,0 +1,64 @@
+package db
+
+import "time"
+
+// Messages the desktop relay sends (type field)
+const (
+	DesktopMessageHello     = "hello"
+	DesktopMessageIncident  = "incident"
+	DesktopMessageOnCall    = "oncall"
+	DesktopMessageAckResult = "ack_result"
+	DesktopMessagePong      = "pong"
+	DesktopMessageError     = "error"
+)
+
+// Messages a desktop client sends
+const (
+	DesktopClientPing = "ping"
+	DesktopClientAck  = "ack"
+)
+
+// DesktopResumeWindow is how far back a reconnecting client can ask to be
+// caught up with ?since=
+const DesktopResumeWindow = time.Hour
+
+// DesktopIncident is an incident assigned to the desktop client's user
+type DesktopIncident struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Status      string    `json:"status"`
+	Urgency     string    `json:"urgency"`
+	Priority    string    `json:"priority,omitempty"`
+	ServiceName string    `json:"service_name,omitempty"`
+	GroupName   string    `json:"group_name,omitempty"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DesktopShift is a shift the user is on call for, overrides applied
+type DesktopShift struct {
+	GroupID       string    `json:"group_id"`
+	GroupName     string    `json:"group_name"`
+	SchedulerName string    `json:"scheduler_name"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+}
+
+// DesktopOnCallStatus is what the tray icon shows: whether the user is on
+// call now, whether they are paged (not on vacation or DND) and their next
+// shift
+type DesktopOnCallStatus struct {
+	OnCall    bool           `json:"on_call"`
+	Available bool           `json:"available"`
+	Current   []DesktopShift `json:"current"`
+	Next      *DesktopShift  `json:"next,omitempty"`
+}
+
+// DesktopClientMessage is a message from a desktop client. RequestID is
+// echoed in the reply so the client can match them up.
+type DesktopClientMessage struct {
+	Type       string `json:"type"`
+	IncidentID string `json:"incident_id,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+}
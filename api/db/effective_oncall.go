@@ -0,0 +1,47 @@
+package db
+
+import "time"
+
+const (
+	// EffectiveOnCallDefaultDays is the window when no to is given
+	EffectiveOnCallDefaultDays = 7
+
+	// EffectiveOnCallMaxDays bounds the window of one request, a quarter
+	EffectiveOnCallMaxDays = 92
+)
+
+// OnCallInterval is a contiguous stretch in which one person is on call for a
+// scheduler, overrides applied. OverrideID and OriginalUserID are set when an
+// override put them there.
+type OnCallInterval struct {
+	SchedulerID    string    `json:"scheduler_id"`
+	SchedulerName  string    `json:"scheduler_name"`
+	UserID         string    `json:"user_id"`
+	UserName       string    `json:"user_name"`
+	UserEmail      string    `json:"user_email"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Seconds        int64     `json:"seconds"`
+	OverrideID     string    `json:"override_id,omitempty"`
+	OriginalUserID string    `json:"original_user_id,omitempty"`
+}
+
+// OnCallUserTotal is how long one person was on call in the window, summed
+// over all of the group's schedulers
+type OnCallUserTotal struct {
+	UserID    string  `json:"user_id"`
+	UserName  string  `json:"user_name"`
+	UserEmail string  `json:"user_email"`
+	Seconds   int64   `json:"seconds"`
+	Hours     float64 `json:"hours"`
+}
+
+// EffectiveOnCall is who was (or will be) on call for a group over
+// [From, To): intervals ordered by start, then scheduler
+type EffectiveOnCall struct {
+	GroupID   string            `json:"group_id"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Intervals []OnCallInterval  `json:"intervals"`
+	Totals    []OnCallUserTotal `json:"totals"`
+}
@@ -0,0 +1,35 @@
+package db
+
+// ExternalOnCallIntegrationType is the integration type that looks up who is
+// on call in a scheduler outside SLAR, so escalation levels can page teams
+// that haven't moved their rotations over yet. Its config holds:
+//
+//	provider     http or grafana_oncall
+//	url          http: endpoint answering GET with the on-call emails, as
+//	             {"emails": [...]} or {"email": "..."}; grafana_oncall: the
+//	             Grafana OnCall API base URL
+//	token        optional for http (sent as a bearer token); the Grafana
+//	             OnCall API token
+//	schedule_id  grafana_oncall: the schedule to read
+//	email_map    optional: external email -> SLAR email, for people whose
+//	             addresses differ between the two
+const ExternalOnCallIntegrationType = "external_oncall"
+
+const (
+	ExternalOnCallProviderHTTP    = "http"
+	ExternalOnCallProviderGrafana = "grafana_oncall"
+)
+
+// EscalationTargetExternalSchedule is the escalation level target type that
+// pages whoever an external_oncall integration reports on call; the level's
+// target_id is the integration
+const EscalationTargetExternalSchedule = "external_schedule"
+
+// ExternalOnCallLookup is what an external on-call provider reported and the
+// SLAR users its emails map to
+type ExternalOnCallLookup struct {
+	IntegrationID string   `json:"integration_id"`
+	Emails        []string `json:"emails"`
+	UserIDs       []string `json:"user_ids"`
+	Unmatched     []string `json:"unmatched,omitempty"` // emails with no SLAR user in the organization
+}
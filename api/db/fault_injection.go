@@ -0,0 +1,45 @@
+package db
+
+import "time"
+
+// Kinds of fault that can be injected
+const (
+	FaultDBLatency      = "db_latency"
+	FaultPGMQFailure    = "pgmq_failure"
+	FaultProviderOutage = "provider_outage"
+)
+
+// FaultProviders are the notification and on-call providers a provider_outage
+// can take down
+var FaultProviders = []string{"email", "slack", "fcm", "web_push", "whatsapp", "voice", "external_oncall"}
+
+// How long a fault lasts when no duration is given, and the longest allowed,
+// so a forgotten fault clears itself
+const (
+	FaultDefaultDurationSeconds = 600
+	FaultMaxDurationSeconds     = 3600
+	FaultMaxDelayMS             = 60000
+)
+
+// Fault is an injected failure. Target narrows it: the SQL a db_latency
+// applies to, the queue of a pgmq_failure or the provider of a
+// provider_outage. Rate is the share of matching calls affected.
+type Fault struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target"`
+	DelayMS   int       `json:"delay_ms,omitempty"`
+	Rate      float64   `json:"rate"`
+	Note      string    `json:"note,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateFaultRequest struct {
+	Kind            string   `json:"kind" binding:"required,oneof=db_latency pgmq_failure provider_outage"`
+	Target          string   `json:"target"`
+	DelayMS         int      `json:"delay_ms"`
+	Rate            *float64 `json:"rate"` // default 1
+	DurationSeconds int      `json:"duration_seconds"`
+	Note            string   `json:"note"`
+}
@@ -0,0 +1,35 @@
+package db
+
+import "time"
+
+// Feature flags gating subsystems that are rolled out gradually
+const (
+	FeatureAsyncIngestion     = "async_ingestion"
+	FeatureAIAnalysis         = "ai_analysis"
+	FeatureEscalationEngineV2 = "escalation_engine_v2"
+)
+
+// Where a flag's effective value came from
+const (
+	FeatureSourceConfig  = "config"  // feature_flags setting / FEATURE_FLAGS env var
+	FeatureSourceOrg     = "org"     // organization_feature_flags row
+	FeatureSourceDefault = "default" // feature_flags.default_enabled
+)
+
+// FeatureFlag is a flag's effective value for one organization
+type FeatureFlag struct {
+	Key            string     `json:"key"`
+	Description    string     `json:"description"`
+	Enabled        bool       `json:"enabled"`
+	Source         string     `json:"source"`
+	DefaultEnabled bool       `json:"default_enabled"`
+	OrgEnabled     *bool      `json:"org_enabled,omitempty"`
+	UpdatedBy      string     `json:"updated_by,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// SetOrgFeatureFlagRequest enables or disables a flag for an organization.
+// A null Enabled removes the org setting so the default applies again.
+type SetOrgFeatureFlagRequest struct {
+	Enabled *bool `json:"enabled"`
+}
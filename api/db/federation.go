@@ -0,0 +1,49 @@
+package db
+
+import "time"
+
+// FederationIntegrationType is the integration type that forwards incidents
+// to another SLAR instance. Its config holds:
+//
+//	remote_url      base URL of the remote API, e.g. https://noc.example.com
+//	remote_api_key  API key for the remote /webhooks/incident endpoint
+//	routing_key     routing key of the remote service incidents land on
+//	service_ids     optional: only forward incidents of these services
+//	severities      optional: only forward incidents with these severities
+//
+// The receiving instance reports status changes back only when its
+// organization has a federation integration whose remote_url is the origin
+// instance.
+const FederationIntegrationType = "federation"
+
+// Incident labels set on the remote copy of a federated incident, telling the
+// remote instance where to report status changes
+const (
+	FederationCallbackURLLabel   = "slar_federation_callback_url"
+	FederationCallbackTokenLabel = "slar_federation_token"
+	FederationOriginLabel        = "slar_federation_origin_incident_id"
+)
+
+// IncidentFederationLink tracks an incident forwarded to a remote instance
+type IncidentFederationLink struct {
+	ID                string     `json:"id"`
+	IncidentID        string     `json:"incident_id"`
+	IntegrationID     string     `json:"integration_id"`
+	RemoteIncidentID  string     `json:"remote_incident_id,omitempty"`
+	RemoteIncidentKey string     `json:"remote_incident_key,omitempty"`
+	RemoteStatus      string     `json:"remote_status,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	ForwardedAt       *time.Time `json:"forwarded_at,omitempty"`
+	LastSyncedAt      *time.Time `json:"last_synced_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// FederationStatusUpdate is posted by the remote instance to the origin's
+// federation webhook when the forwarded incident changes status
+type FederationStatusUpdate struct {
+	Token             string `json:"token"`
+	Status            string `json:"status"`
+	Note              string `json:"note,omitempty"`
+	RemoteIncidentID  string `json:"remote_incident_id,omitempty"`
+	RemoteIncidentKey string `json:"remote_incident_key,omitempty"`
+}
@@ -0,0 +1,53 @@
+package db
+
+import "time"
+
+// FreezeDefaultEscalationTimeoutPercent halves escalation timeouts during a
+// freeze unless the period says otherwise
+const FreezeDefaultEscalationTimeoutPercent = 50
+
+// FreezePeriod is a declared code freeze or high-sensitivity period for an
+// organization, or for one group when GroupID is set. While it is active,
+// incidents are not auto-resolved (when DisableAutoResolve), escalation step
+// timeouts run at EscalationTimeoutPercent of their length, and ReviewerIDs
+// are paged onto every new incident.
+type FreezePeriod struct {
+	ID                       string    `json:"id"`
+	OrganizationID           string    `json:"organization_id"`
+	GroupID                  string    `json:"group_id,omitempty"`
+	Name                     string    `json:"name"`
+	Description              string    `json:"description,omitempty"`
+	StartTime                time.Time `json:"start_time"`
+	EndTime                  time.Time `json:"end_time"`
+	DisableAutoResolve       bool      `json:"disable_auto_resolve"`
+	EscalationTimeoutPercent int       `json:"escalation_timeout_percent"`
+	ReviewerIDs              []string  `json:"reviewer_ids"`
+	CreatedBy                string    `json:"created_by,omitempty"`
+	CreatedAt                time.Time `json:"created_at"`
+	UpdatedAt                time.Time `json:"updated_at"`
+}
+
+// CreateFreezePeriodRequest declares a freeze period. DisableAutoResolve
+// defaults to true and EscalationTimeoutPercent to
+// FreezeDefaultEscalationTimeoutPercent.
+type CreateFreezePeriodRequest struct {
+	Name                     string    `json:"name" binding:"required"`
+	Description              string    `json:"description"`
+	GroupID                  string    `json:"group_id"`
+	StartTime                time.Time `json:"start_time" binding:"required"`
+	EndTime                  time.Time `json:"end_time" binding:"required,gtfield=StartTime"`
+	DisableAutoResolve       *bool     `json:"disable_auto_resolve"`
+	EscalationTimeoutPercent int       `json:"escalation_timeout_percent" binding:"omitempty,min=1,max=100"`
+	ReviewerIDs              []string  `json:"reviewer_ids"`
+}
+
+// UpdateFreezePeriodRequest changes a freeze period. The group is immutable.
+type UpdateFreezePeriodRequest struct {
+	Name                     *string    `json:"name,omitempty"`
+	Description              *string    `json:"description,omitempty"`
+	StartTime                *time.Time `json:"start_time,omitempty"`
+	EndTime                  *time.Time `json:"end_time,omitempty"`
+	DisableAutoResolve       *bool      `json:"disable_auto_resolve,omitempty"`
+	EscalationTimeoutPercent *int       `json:"escalation_timeout_percent,omitempty" binding:"omitempty,min=1,max=100"`
+	ReviewerIDs              *[]string  `json:"reviewer_ids,omitempty"`
+}
@@ -0,0 +1,66 @@
+package db
+
+import "time"
+
+// Grafana data source metrics (targets) served under /read/grafana
+const (
+	GrafanaMetricIncidentsCreated  = "incidents.created"  // incidents opened per interval
+	GrafanaMetricIncidentsResolved = "incidents.resolved" // incidents resolved per interval
+	GrafanaMetricMTTA              = "incidents.mtta"     // mean seconds to acknowledge, by acknowledgement time
+	GrafanaMetricMTTR              = "incidents.mttr"     // mean seconds to resolve, by resolution time
+	GrafanaMetricOnCallCovered     = "oncall.covered_groups"
+	GrafanaMetricOnCallShifts      = "oncall.shifts" // table of shifts overlapping the range
+)
+
+const (
+	GrafanaMinInterval      = time.Minute
+	GrafanaDefaultMaxPoints = 1000
+	GrafanaMaxPoints        = 10000
+	GrafanaMaxRangeDays     = 366
+	GrafanaMaxShiftRows     = 1000
+)
+
+// GrafanaQueryRequest is a SimpleJSON /query request. Infinity sends the same
+// shape, or the equivalent query parameters on GET.
+type GrafanaQueryRequest struct {
+	Range         GrafanaRange    `json:"range"`
+	IntervalMs    int64           `json:"intervalMs"`
+	MaxDataPoints int             `json:"maxDataPoints"`
+	Targets       []GrafanaTarget `json:"targets"`
+}
+
+// GrafanaRange is the dashboard time range
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// GrafanaTarget is one query in a panel. Payload may narrow incident metrics
+// with service_id, group_id or severity.
+type GrafanaTarget struct {
+	Target  string                 `json:"target"`
+	RefID   string                 `json:"refId"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// GrafanaTimeSeries is a SimpleJSON time series; datapoints are
+// [value, unix milliseconds]
+type GrafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaTableColumn describes one column of a GrafanaTable
+type GrafanaTableColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"` // string, time or number
+}
+
+// GrafanaTable is a SimpleJSON table response
+type GrafanaTable struct {
+	Type    string               `json:"type"` // always "table"
+	RefID   string               `json:"refId,omitempty"`
+	Columns []GrafanaTableColumn `json:"columns"`
+	Rows    [][]interface{}      `json:"rows"`
+}
@@ -0,0 +1,42 @@
+package db
+
+import "time"
+
+const (
+	// GroupBroadcastLabel marks incidents opened by a group broadcast
+	GroupBroadcastLabel       = "slar_broadcast"
+	GroupBroadcastTitlePrefix = "[BROADCAST] "
+	GroupBroadcastSource      = "broadcast"
+
+	// GroupBroadcastLimitPerHour caps broadcasts per group in a rolling hour
+	GroupBroadcastLimitPerHour = 3
+)
+
+// GroupBroadcastRequest pages a whole group. Without IncidentID a new
+// critical incident is opened for the broadcast.
+type GroupBroadcastRequest struct {
+	Message    string `json:"message" binding:"required,max=1000"`
+	IncidentID string `json:"incident_id,omitempty" binding:"omitempty,uuid"`
+	Severity   string `json:"severity,omitempty" binding:"omitempty,oneof=critical error warning info"`
+}
+
+// GroupBroadcast is an emergency page sent to every active member of a group
+type GroupBroadcast struct {
+	ID             string                    `json:"id"`
+	GroupID        string                    `json:"group_id"`
+	GroupName      string                    `json:"group_name"`
+	OrganizationID string                    `json:"organization_id"`
+	IncidentID     string                    `json:"incident_id"`
+	Message        string                    `json:"message"`
+	RecipientCount int                       `json:"recipient_count"`
+	Recipients     []GroupBroadcastRecipient `json:"recipients"`
+	SentBy         string                    `json:"sent_by"`
+	CreatedAt      time.Time                 `json:"created_at"`
+}
+
+// GroupBroadcastRecipient is a group member paged by a broadcast
+type GroupBroadcastRecipient struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
@@ -0,0 +1,73 @@
+package db
+
+import "time"
+
+// Group invitation status constants
+const (
+	GroupInvitationStatusPending  = "pending"
+	GroupInvitationStatusAccepted = "accepted"
+	GroupInvitationStatusRevoked  = "revoked"
+	GroupInvitationStatusExpired  = "expired"
+)
+
+// Group join request status constants
+const (
+	GroupJoinRequestStatusPending   = "pending"
+	GroupJoinRequestStatusApproved  = "approved"
+	GroupJoinRequestStatusRejected  = "rejected"
+	GroupJoinRequestStatusCancelled = "cancelled"
+)
+
+// GroupInvitation is an email invitation to join a group.
+// The raw token is only ever sent in the email link; the database stores its hash.
+type GroupInvitation struct {
+	ID         string     `json:"id"`
+	GroupID    string     `json:"group_id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	Status     string     `json:"status"`
+	InvitedBy  string     `json:"invited_by,omitempty"`
+	AcceptedBy string     `json:"accepted_by,omitempty"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// For API responses
+	GroupName     string `json:"group_name,omitempty"`
+	InviterName   string `json:"inviter_name,omitempty"`
+	EmailSent     bool   `json:"email_sent"`
+	InvitationURL string `json:"invitation_url,omitempty"` // Only returned when email delivery is not configured
+}
+
+// GroupJoinRequest is a user-initiated request to join a public or organization group
+type GroupJoinRequest struct {
+	ID         string     `json:"id"`
+	GroupID    string     `json:"group_id"`
+	UserID     string     `json:"user_id"`
+	Message    string     `json:"message,omitempty"`
+	Status     string     `json:"status"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// User info (for display)
+	UserName  string `json:"user_name,omitempty"`
+	UserEmail string `json:"user_email,omitempty"`
+}
+
+// CreateGroupInvitationRequest for inviting a user to a group by email
+type CreateGroupInvitationRequest struct {
+	Email          string `json:"email" binding:"required,email"`
+	Role           string `json:"role,omitempty" binding:"omitempty,oneof=member leader backup"`
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"` // Default 168 (7 days)
+}
+
+// AcceptGroupInvitationRequest for accepting an invitation from the email link
+type AcceptGroupInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CreateGroupJoinRequestRequest for requesting to join a group
+type CreateGroupJoinRequestRequest struct {
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,99 @@
+package db
+
+import "time"
+
+// Kinds of diagnostic artifacts
+const (
+	ArtifactKindLogs     = "logs"
+	ArtifactKindKubectl  = "kubectl"
+	ArtifactKindCommand  = "command"
+	ArtifactKindMetrics  = "metrics"
+	ArtifactKindOther    = "other"
+	ArtifactKindSnapshot = "snapshot" // chart image attached by an integration
+)
+
+// Who attached an artifact
+const (
+	ArtifactSourceAgent       = "agent"
+	ArtifactSourceUser        = "user"
+	ArtifactSourceIntegration = "integration"
+)
+
+// Artifact limits. Content past IncidentArtifactMaxBytes is cut off and the
+// artifact marked truncated.
+const (
+	IncidentArtifactMaxBytes         = 1 << 20
+	IncidentArtifactMaxRetentionDays = 365
+)
+
+// IncidentArtifact is the redacted output of a diagnostic command kept on an
+// incident as evidence for responders and the AI agent alike
+type IncidentArtifact struct {
+	ID            string    `json:"id"`
+	IncidentID    string    `json:"incident_id"`
+	Kind          string    `json:"kind"`
+	Source        string    `json:"source"`
+	Title         string    `json:"title"`
+	Command       string    `json:"command,omitempty"`
+	Content       string    `json:"content,omitempty"` // left out of lists
+	ContentSHA256 string    `json:"content_sha256"`
+	SizeBytes     int       `json:"size_bytes"`
+	Truncated     bool      `json:"truncated"`
+	Redactions    int       `json:"redactions"` // secrets removed before storing
+	SessionID     string    `json:"session_id,omitempty"`
+	CreatedBy     string    `json:"created_by,omitempty"`
+	CreatedByName string    `json:"created_by_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+
+	// Snapshots only: where the image can be viewed, the provider's URL or
+	// SLAR's public URL for an uploaded image
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// IncidentSnapshotMaxBytes is the largest snapshot image an integration may upload
+const IncidentSnapshotMaxBytes = 5 << 20
+
+// IncidentSnapshotContentTypes are the image formats snapshots may be uploaded in
+var IncidentSnapshotContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// AttachIncidentSnapshotRequest is how an integration attaches a chart
+// snapshot, to the incident with IncidentID or to the open incident its
+// alert Fingerprint grouped into. It carries ImageURL or ImageBase64;
+// multipart uploads send the image as the "image" file instead.
+type AttachIncidentSnapshotRequest struct {
+	IncidentID  string `json:"incident_id" form:"incident_id"`
+	Fingerprint string `json:"fingerprint" form:"fingerprint"`
+	Title       string `json:"title" form:"title" binding:"max=200"`
+	ImageURL    string `json:"image_url" form:"image_url"`
+	ImageBase64 string `json:"image_base64" form:"-"`
+}
+
+// CreateIncidentArtifactRequest attaches an artifact. RetentionDays defaults
+// to the configured incident artifact retention.
+type CreateIncidentArtifactRequest struct {
+	Kind          string `json:"kind" binding:"required,oneof=logs kubectl command metrics other"`
+	Title         string `json:"title" binding:"required,max=200"`
+	Command       string `json:"command" binding:"max=2000"`
+	Content       string `json:"content" binding:"required"`
+	SessionID     string `json:"session_id"`
+	RetentionDays int    `json:"retention_days" binding:"omitempty,gte=1,lte=365"`
+}
+
+// InternalCreateIncidentArtifactRequest is how the AI agent attaches an
+// artifact on behalf of the user it is working for
+type InternalCreateIncidentArtifactRequest struct {
+	CreateIncidentArtifactRequest
+	IncidentID string `json:"incident_id" binding:"required"`
+	OrgID      string `json:"org_id" binding:"required"`
+	UserID     string `json:"user_id"`
+}
+
+// IncidentArtifactSearchResult is an artifact matching a search, with the
+// best-matching excerpt wrapped in **
+type IncidentArtifactSearchResult struct {
+	IncidentArtifact
+	IncidentTitle string  `json:"incident_title"`
+	Snippet       string  `json:"snippet"`
+	Rank          float64 `json:"rank"`
+}
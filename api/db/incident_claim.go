@@ -0,0 +1,23 @@
+package db
+
+import "time"
+
+// IncidentClaim is who has taken an incident. ClaimedBy is empty while the
+// incident is unclaimed.
+type IncidentClaim struct {
+	IncidentID     string     `json:"incident_id"`
+	Status         string     `json:"status"`
+	ClaimedBy      string     `json:"claimed_by,omitempty"`
+	ClaimedByName  string     `json:"claimed_by_name,omitempty"`
+	ClaimedAt      *time.Time `json:"claimed_at,omitempty"`
+	AssignedTo     string     `json:"assigned_to,omitempty"`
+	AssignedToName string     `json:"assigned_to_name,omitempty"`
+}
+
+// ClaimIncidentRequest claims an incident. With ExpectedAssignedTo set (""
+// for unassigned) the claim only succeeds if the incident is still assigned
+// as the caller last saw it.
+type ClaimIncidentRequest struct {
+	ExpectedAssignedTo *string `json:"expected_assigned_to,omitempty"`
+	Note               string  `json:"note,omitempty"`
+}
@@ -0,0 +1,58 @@
+package db
+
+import "math"
+
+// IncidentCostEstimate is the estimated cost of an incident, from its service's
+// cost parameters: impact (duration × revenue per minute) plus responder time
+// (minutes each responder spent on the incident × engineer hourly cost)
+type IncidentCostEstimate struct {
+	DurationMinutes float64 `json:"duration_minutes"`
+	Ongoing         bool    `json:"ongoing"`
+
+	RevenuePerMinute float64 `json:"revenue_per_minute"`
+	ImpactCost       float64 `json:"impact_cost"`
+
+	Responders         int     `json:"responders"`
+	ResponderMinutes   float64 `json:"responder_minutes"`
+	EngineerHourlyCost float64 `json:"engineer_hourly_cost"`
+	ResponderCost      float64 `json:"responder_cost"`
+
+	TotalCost float64 `json:"total_cost"`
+}
+
+// NewIncidentCostEstimate computes the cost breakdown, rounded to cents
+func NewIncidentCostEstimate(durationMinutes, revenuePerMinute float64, responders int, responderMinutes, engineerHourlyCost float64) IncidentCostEstimate {
+	est := IncidentCostEstimate{
+		DurationMinutes:    roundCents(durationMinutes),
+		RevenuePerMinute:   revenuePerMinute,
+		Responders:         responders,
+		ResponderMinutes:   roundCents(responderMinutes),
+		EngineerHourlyCost: engineerHourlyCost,
+		ImpactCost:         roundCents(durationMinutes * revenuePerMinute),
+		ResponderCost:      roundCents(responderMinutes / 60 * engineerHourlyCost),
+	}
+	est.TotalCost = roundCents(est.ImpactCost + est.ResponderCost)
+	return est
+}
+
+// IncidentCostSummary aggregates cost estimates over a set of incidents
+type IncidentCostSummary struct {
+	IncidentsCosted int     `json:"incidents_costed"`
+	ImpactCost      float64 `json:"impact_cost"`
+	ResponderCost   float64 `json:"responder_cost"`
+	TotalCost       float64 `json:"total_cost"`
+}
+
+// NewIncidentCostSummary builds a summary, rounded to cents
+func NewIncidentCostSummary(incidents int, impactCost, responderCost float64) IncidentCostSummary {
+	return IncidentCostSummary{
+		IncidentsCosted: incidents,
+		ImpactCost:      roundCents(impactCost),
+		ResponderCost:   roundCents(responderCost),
+		TotalCost:       roundCents(impactCost + responderCost),
+	}
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,88 @@
+package db
+
+import "time"
+
+// Drill statuses
+const (
+	DrillStatusScheduled = "scheduled"
+	DrillStatusRunning   = "running"
+	DrillStatusCompleted = "completed"
+	DrillStatusCancelled = "cancelled"
+	DrillStatusFailed    = "failed"
+)
+
+// Drill outcomes reported in DrillReport
+const (
+	DrillOutcomePending      = "pending"
+	DrillOutcomeInProgress   = "in_progress"
+	DrillOutcomeAcknowledged = "acknowledged"
+	DrillOutcomeMissed       = "missed" // ended without anyone acknowledging
+	DrillOutcomeCancelled    = "cancelled"
+	DrillOutcomeFailed       = "failed"
+)
+
+const (
+	// DrillLabel marks drill incidents; DrillIDLabel links back to the drill
+	DrillLabel   = "slar_drill"
+	DrillIDLabel = "slar_drill_id"
+
+	DrillTitlePrefix        = "[DRILL] "
+	DrillSource             = "drill"
+	DrillDefaultMaxDuration = 60
+	DrillDefaultSeverity    = "critical"
+)
+
+// IncidentDrill is a scheduled or ad-hoc on-call readiness drill for a service
+type IncidentDrill struct {
+	ID                 string     `json:"id"`
+	ServiceID          string     `json:"service_id"`
+	Title              string     `json:"title"`
+	Severity           string     `json:"severity"`
+	Status             string     `json:"status"`
+	ScheduledAt        time.Time  `json:"scheduled_at"`
+	MaxDurationMinutes int        `json:"max_duration_minutes"`
+	IncidentID         string     `json:"incident_id,omitempty"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	Error              string     `json:"error,omitempty"`
+	CreatedBy          string     `json:"created_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// CreateIncidentDrillRequest schedules a drill. Without scheduled_at the
+// drill starts immediately.
+type CreateIncidentDrillRequest struct {
+	Title              string     `json:"title"`
+	Severity           string     `json:"severity" binding:"omitempty,oneof=critical error warning info"`
+	ScheduledAt        *time.Time `json:"scheduled_at"`
+	MaxDurationMinutes int        `json:"max_duration_minutes" binding:"omitempty,min=5,max=1440"`
+}
+
+// DrillResponder is one responder's reaction to a drill's pages
+type DrillResponder struct {
+	UserID           string     `json:"user_id"`
+	UserName         string     `json:"user_name"`
+	NotificationType string     `json:"notification_type"`
+	PagedAt          time.Time  `json:"paged_at"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
+	SeenAt           *time.Time `json:"seen_at,omitempty"`
+	SecondsToSeen    *float64   `json:"seconds_to_seen,omitempty"`
+	Acknowledged     bool       `json:"acknowledged"`
+}
+
+// DrillReport summarizes how the on-call pipeline and responders reacted
+type DrillReport struct {
+	Drill                  IncidentDrill    `json:"drill"`
+	Outcome                string           `json:"outcome"`
+	TriggeredAt            *time.Time       `json:"triggered_at,omitempty"`
+	FirstPageAt            *time.Time       `json:"first_page_at,omitempty"`
+	AcknowledgedAt         *time.Time       `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy         string           `json:"acknowledged_by,omitempty"`
+	ResolvedAt             *time.Time       `json:"resolved_at,omitempty"`
+	SecondsToFirstPage     *float64         `json:"seconds_to_first_page,omitempty"`
+	SecondsToAcknowledge   *float64         `json:"seconds_to_acknowledge,omitempty"`
+	SecondsToResolve       *float64         `json:"seconds_to_resolve,omitempty"`
+	EscalationLevelReached int              `json:"escalation_level_reached"`
+	Escalations            int              `json:"escalations"`
+	Responders             []DrillResponder `json:"responders"`
+}
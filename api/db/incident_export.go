@@ -0,0 +1,70 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	IncidentExportFormatNDJSON  = "ndjson"
+	IncidentExportFormatParquet = "parquet"
+
+	IncidentExportDefaultLimit = 10000
+	IncidentExportMaxLimit     = 50000
+)
+
+// IncidentExportRecord is one line of the incident export. Its fields are
+// flat and stable so warehouse tables can be loaded without transformation.
+type IncidentExportRecord struct {
+	ID                     string          `json:"id"`
+	OrganizationID         string          `json:"organization_id"`
+	ProjectID              *string         `json:"project_id"`
+	Title                  string          `json:"title"`
+	Description            string          `json:"description"`
+	Status                 string          `json:"status"`
+	Urgency                string          `json:"urgency"`
+	Priority               *string         `json:"priority"`
+	Severity               *string         `json:"severity"`
+	Source                 string          `json:"source"`
+	IntegrationID          *string         `json:"integration_id"`
+	ServiceID              *string         `json:"service_id"`
+	GroupID                *string         `json:"group_id"`
+	EscalationPolicyID     *string         `json:"escalation_policy_id"`
+	CurrentEscalationLevel int             `json:"current_escalation_level"`
+	EscalationStatus       string          `json:"escalation_status"`
+	AssignedTo             *string         `json:"assigned_to"`
+	AssignedAt             *time.Time      `json:"assigned_at"`
+	AcknowledgedBy         *string         `json:"acknowledged_by"`
+	AcknowledgedAt         *time.Time      `json:"acknowledged_at"`
+	ResolvedBy             *string         `json:"resolved_by"`
+	ResolvedAt             *time.Time      `json:"resolved_at"`
+	IncidentKey            *string         `json:"incident_key"`
+	AlertCount             int             `json:"alert_count"`
+	IsTest                 bool            `json:"is_test"`
+	DrillID                *string         `json:"drill_id"`
+	Labels                 json.RawMessage `json:"labels"`
+	CustomFields           json.RawMessage `json:"custom_fields"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UpdatedAt              time.Time       `json:"updated_at"`
+}
+
+// IncidentExportCursor marks a position in an export. SnapshotAt is fixed
+// when the export starts, so every page sees the same set of incidents.
+type IncidentExportCursor struct {
+	Since          time.Time `json:"since"`
+	SnapshotAt     time.Time `json:"snapshot_at"`
+	AfterUpdatedAt time.Time `json:"after_updated_at"`
+	AfterID        string    `json:"after_id"`
+}
+
+// IncidentExportCheckpoint is written as the last line of each export page.
+// Pass Cursor back to continue; once HasMore is false, start the next run
+// with since=NextSince.
+type IncidentExportCheckpoint struct {
+	Cursor     string    `json:"cursor"`
+	HasMore    bool      `json:"has_more"`
+	Exported   int       `json:"exported"`
+	SnapshotAt time.Time `json:"snapshot_at"`
+	NextSince  time.Time `json:"next_since"`
+	Error      string    `json:"error,omitempty"`
+}
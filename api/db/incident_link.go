@@ -0,0 +1,11 @@
+package db
+
+// IncidentLink is an incident's deep link resolved from its short ID, for
+// the mobile app to open the incident a universal or app link pointed at
+type IncidentLink struct {
+	IncidentID string `json:"incident_id"`
+	ShortID    string `json:"short_id"`
+	URL        string `json:"url"`     // the deep link itself, <base>/r/<short_id>
+	WebURL     string `json:"web_url"` // the incident in the web UI
+	AppURL     string `json:"app_url"` // the incident under the app's custom URL scheme
+}
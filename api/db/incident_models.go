@@ -1,10 +1,16 @@
 package db
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 // Incident represents a PagerDuty-style incident
 type Incident struct {
 	ID          string    `json:"id"`
+	ShortID     string    `json:"short_id,omitempty"`  // used in deep links, /r/<short_id>
+	Number      int64     `json:"number"`              // sequential per organization
+	Reference   string    `json:"reference,omitempty"` // Number as people say it, e.g. INC-1024
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Status      string    `json:"status"`   // triggered, acknowledged, resolved
@@ -13,6 +19,9 @@ type Incident struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// Custom workflow state (org-defined, e.g. mitigating) while acknowledged
+	WorkflowState string `json:"workflow_state,omitempty"`
+
 	// Assignment & Acknowledgment
 	AssignedTo     string     `json:"assigned_to,omitempty"`
 	AssignedAt     *time.Time `json:"assigned_at,omitempty"`
@@ -48,6 +57,19 @@ type Incident struct {
 	AlertCount   int                    `json:"alert_count"`
 	Labels       map[string]interface{} `json:"labels,omitempty"`
 	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+
+	// Normalized by the organization's tagging rules at creation
+	Tags      []string `json:"tags,omitempty"`
+	Team      string   `json:"team,omitempty"`
+	Component string   `json:"component,omitempty"`
+
+	// Environment of the integration binding the alert arrived through, e.g.
+	// staging; empty for production and incidents created by hand
+	Environment string `json:"environment,omitempty"`
+
+	// Created through a test-mode integration or API key: excluded from
+	// analytics, never pages for real, Slack goes to the test channel
+	IsTest bool `json:"is_test"`
 }
 
 // IncidentResponse includes additional information for API responses
@@ -73,6 +95,43 @@ type IncidentResponse struct {
 
 	// Recent events
 	RecentEvents []IncidentEvent `json:"recent_events,omitempty"`
+
+	// Latest page receipt per responder ("seen at")
+	Receipts []NotificationReceipt `json:"receipts,omitempty"`
+
+	// Who each escalation level paged, over which channels, and the outcome
+	PagingAudit []PagingAuditLevel `json:"paging_audit,omitempty"`
+
+	// Service changes in the window before the incident started
+	RecentChanges []ChangeEvent `json:"recent_changes,omitempty"`
+
+	// Estimated cost, when the service has cost parameters
+	Cost *IncidentCostEstimate `json:"cost,omitempty"`
+
+	// Who has claimed the incident; only loaded for a single incident
+	Claim *IncidentClaim `json:"claim,omitempty"`
+
+	// Response checklist; Tasks is only loaded for a single incident
+	Tasks         []IncidentTask `json:"tasks,omitempty"`
+	TaskCount     int            `json:"task_count"`
+	OpenTaskCount int            `json:"open_task_count"`
+
+	// Links to other incidents and causing changes; only loaded for a single incident
+	Relations []IncidentRelation `json:"relations,omitempty"`
+
+	// Elapsed times, only with ?include=durations
+	*IncidentDurations
+}
+
+// IncidentDurations are an incident's elapsed times in whole seconds,
+// computed by the server at DurationsAsOf so clients need no clock or
+// timezone math. TimeToAck and TimeToResolve are null until reached.
+type IncidentDurations struct {
+	DurationsAsOf       time.Time `json:"durations_as_of"`
+	TimeSinceTriggered  int64     `json:"time_since_triggered"`
+	TimeInCurrentStatus int64     `json:"time_in_current_status"`
+	TimeToAck           *int64    `json:"time_to_ack"`
+	TimeToResolve       *int64    `json:"time_to_resolve"`
 }
 
 // IncidentEvent represents an event in the incident timeline
@@ -86,6 +145,71 @@ type IncidentEvent struct {
 	CreatedByName string                 `json:"created_by_name,omitempty"`
 }
 
+// IncidentFeedEntry is one incident event in the org-wide activity feed
+type IncidentFeedEntry struct {
+	IncidentEvent
+
+	IncidentTitle  string `json:"incident_title"`
+	IncidentStatus string `json:"incident_status"`
+	IncidentURL    string `json:"incident_url"`
+	Urgency        string `json:"urgency"`
+	Severity       string `json:"severity,omitempty"`
+	GroupID        string `json:"group_id,omitempty"`
+	GroupName      string `json:"group_name,omitempty"`
+	ServiceID      string `json:"service_id,omitempty"`
+	ServiceName    string `json:"service_name,omitempty"`
+	ProjectID      string `json:"project_id,omitempty"`
+	AssignedToName string `json:"assigned_to_name,omitempty"`
+}
+
+// IncidentFeedPage is a page of the activity feed, newest first.
+// Pass NextCursor back as ?cursor= to fetch older entries.
+type IncidentFeedPage struct {
+	Entries    []IncidentFeedEntry `json:"entries"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	HasMore    bool                `json:"has_more"`
+}
+
+// EscalationTimeline describes the escalation that has happened for an incident
+// and what is scheduled next, for rendering a countdown in the UI
+type EscalationTimeline struct {
+	IncidentID           string                   `json:"incident_id"`
+	Status               string                   `json:"status"`
+	EscalationStatus     string                   `json:"escalation_status"`
+	EscalationPolicyID   string                   `json:"escalation_policy_id,omitempty"`
+	EscalationPolicyName string                   `json:"escalation_policy_name,omitempty"`
+	CurrentLevel         int                      `json:"current_level"`
+	LastEscalatedAt      *time.Time               `json:"last_escalated_at,omitempty"`
+	NextEscalationAt     *time.Time               `json:"next_escalation_at,omitempty"`
+	SecondsUntilNext     *int64                   `json:"seconds_until_next,omitempty"`
+	GeneratedAt          time.Time                `json:"generated_at"`
+	Steps                []EscalationTimelineStep `json:"steps"`
+}
+
+// Escalation timeline step states
+const (
+	EscalationStepCompleted  = "completed"   // level was paged and has timed out
+	EscalationStepActive     = "active"      // level currently holding the incident
+	EscalationStepScheduled  = "scheduled"   // level will be paged at ETA unless the incident is acked/resolved
+	EscalationStepNotReached = "not_reached" // escalation stopped before this level
+)
+
+// EscalationTimelineStep is one escalation level of the incident's policy
+type EscalationTimelineStep struct {
+	Level          int        `json:"level"`
+	State          string     `json:"state"`
+	TargetType     string     `json:"target_type"`
+	TargetID       string     `json:"target_id,omitempty"`
+	TargetName     string     `json:"target_name,omitempty"`
+	TimeoutMinutes int        `json:"timeout_minutes"`
+	EscalatedAt    *time.Time `json:"escalated_at,omitempty"` // when this level was paged
+	ETA            *time.Time `json:"eta,omitempty"`          // when this level will be paged
+
+	// Who was (or will be) paged, resolved at EscalatedAt or ETA
+	ResolvedUserID   string `json:"resolved_user_id,omitempty"`
+	ResolvedUserName string `json:"resolved_user_name,omitempty"`
+}
+
 // RawAlert represents raw alert data before processing into incidents
 type RawAlert struct {
 	ID            string                 `json:"id"`
@@ -134,6 +258,31 @@ type AcknowledgeIncidentRequest struct {
 	Note string `json:"note,omitempty"`
 }
 
+// SetWorkflowStateRequest for moving an acknowledged incident to a custom workflow state.
+// State "acknowledged" returns it to the plain acknowledged state.
+type SetWorkflowStateRequest struct {
+	State string `json:"state" binding:"required"`
+	Note  string `json:"note,omitempty"`
+}
+
+// PageIncidentRequest for paging an extra responder onto an incident outside
+// its escalation policy. TargetType is user, scheduler or group; schedulers and
+// groups page whoever is currently on call.
+type PageIncidentRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=user scheduler group"`
+	TargetID   string `json:"target_id" binding:"required"`
+	Message    string `json:"message,omitempty"`
+}
+
+// PageIncidentResult describes who was paged
+type PageIncidentResult struct {
+	TargetType    string `json:"target_type"`
+	TargetID      string `json:"target_id"`
+	TargetName    string `json:"target_name"`
+	PagedUserID   string `json:"paged_user_id"`
+	PagedUserName string `json:"paged_user_name"`
+}
+
 // ResolveIncidentRequest for resolving an incident
 type ResolveIncidentRequest struct {
 	Note       string `json:"note,omitempty"`
@@ -204,6 +353,27 @@ const (
 	IncidentEventEscalated    = "escalated"
 	IncidentEventNoteAdded    = "note_added"
 	IncidentEventUpdated      = "updated"
+
+	IncidentEventWorkflowStateChanged = "workflow_state_changed"
+	IncidentEventResponderPaged       = "responder_paged"
+	IncidentEventDeployLinked         = "deploy_linked"
+	IncidentEventUrgencyChanged       = "urgency_changed"
+	IncidentEventReassigned           = "reassigned"
+	IncidentEventTaskCompleted        = "task_completed"
+	IncidentEventTaskReopened         = "task_reopened"
+	IncidentEventGroupBroadcast       = "group_broadcast"
+	IncidentEventVoiceCall            = "voice_call"
+	IncidentEventClaimed              = "claimed"
+	IncidentEventUnclaimed            = "unclaimed"
+	IncidentEventResponderSkipped     = "responder_skipped"
+
+	IncidentEventNotificationSettingsChanged = "notification_settings_changed"
+	IncidentEventArtifactAttached            = "artifact_attached"
+	IncidentEventPostmortemDrafted           = "postmortem_drafted"
+	IncidentEventAlertGrouped                = "alert_grouped"
+	IncidentEventAutoResolveHeld             = "auto_resolve_held"
+	IncidentEventRelationAdded               = "relation_added"
+	IncidentEventAckTimedOut                 = "ack_timed_out"
 )
 
 // Webhook event actions
@@ -222,3 +392,14 @@ type EscalationResult struct {
 	TargetType       string `json:"target_type"`
 	HasMoreLevels    bool   `json:"has_more_levels"`
 }
+
+// IncidentNumberPrefix starts an incident's reference, e.g. INC-1024
+const IncidentNumberPrefix = "INC-"
+
+// FormatIncidentNumber is an incident number as people say it, e.g. INC-1024
+func FormatIncidentNumber(number int64) string {
+	if number <= 0 {
+		return ""
+	}
+	return IncidentNumberPrefix + strconv.FormatInt(number, 10)
+}
@@ -0,0 +1,24 @@
+package db
+
+import "time"
+
+// Incident presence modes
+const (
+	PresenceViewing = "viewing"
+	PresenceEditing = "editing" // writing a note or changing the incident
+)
+
+// IncidentViewer is someone who has an incident open right now
+type IncidentViewer struct {
+	UserID     string    `json:"user_id"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email,omitempty"`
+	Mode       string    `json:"mode"`  // viewing, editing
+	Since      time.Time `json:"since"` // when they opened the incident
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// IncidentPresenceRequest is the heartbeat sent while an incident is open
+type IncidentPresenceRequest struct {
+	Mode string `json:"mode" binding:"omitempty,oneof=viewing editing"`
+}
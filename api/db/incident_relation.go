@@ -0,0 +1,67 @@
+package db
+
+import "time"
+
+// Incident relation types. "related" is symmetric; "duplicate_of" and
+// "caused_by" read from the incident the relation was added on.
+const (
+	IncidentRelationRelated     = "related"
+	IncidentRelationDuplicateOf = "duplicate_of"
+	IncidentRelationCausedBy    = "caused_by"
+)
+
+// IncidentRelationMaxDepth bounds how far "caused by" chains are followed
+const IncidentRelationMaxDepth = 10
+
+// IncidentRelation links an incident to another incident or, for
+// "caused_by", to a change event. Seen from the target incident, Inverse is
+// true: "duplicate_of" then means the other incident duplicates this one and
+// "caused_by" that this one caused it.
+type IncidentRelation struct {
+	ID                    string    `json:"id"`
+	IncidentID            string    `json:"incident_id"`
+	Type                  string    `json:"type"`
+	Inverse               bool      `json:"inverse"`
+	RelatedIncidentID     string    `json:"related_incident_id,omitempty"`
+	RelatedIncidentNumber int64     `json:"related_incident_number,omitempty"`
+	RelatedReference      string    `json:"related_reference,omitempty"`
+	RelatedTitle          string    `json:"related_title,omitempty"`
+	RelatedStatus         string    `json:"related_status,omitempty"`
+	ChangeEventID         string    `json:"change_event_id,omitempty"`
+	ChangeSummary         string    `json:"change_summary,omitempty"`
+	ChangeType            string    `json:"change_type,omitempty"`
+	CreatedBy             string    `json:"created_by,omitempty"`
+	CreatedByName         string    `json:"created_by_name,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// CreateIncidentRelationRequest links the incident to exactly one target.
+// RelatedIncidentID also takes an incident number (INC-1024).
+type CreateIncidentRelationRequest struct {
+	Type              string `json:"type" binding:"required,oneof=related duplicate_of caused_by"`
+	RelatedIncidentID string `json:"related_incident_id"`
+	ChangeEventID     string `json:"change_event_id"`
+}
+
+// CausedByIncident is one incident in a "caused by" chain, Depth 1 being
+// caused directly by the root
+type CausedByIncident struct {
+	ID        string    `json:"id"`
+	Number    int64     `json:"number"`
+	Reference string    `json:"reference"`
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	Severity  string    `json:"severity,omitempty"`
+	Depth     int       `json:"depth"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CausedByReport counts the incidents a change or incident caused, directly
+// and through chains of "caused by" relations
+type CausedByReport struct {
+	ChangeEventID string             `json:"change_event_id,omitempty"`
+	IncidentID    string             `json:"incident_id,omitempty"`
+	Count         int                `json:"count"`
+	DirectCount   int                `json:"direct_count"`
+	Incidents     []CausedByIncident `json:"incidents"`
+}
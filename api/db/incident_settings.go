@@ -0,0 +1,37 @@
+package db
+
+import "time"
+
+// How much of an incident reaches its group's chat channels
+const (
+	ChatUpdatesAll       = "all"
+	ChatUpdatesPagesOnly = "pages_only" // pages and status updates only
+	ChatUpdatesNone      = "none"
+)
+
+// Bounds for an incident's status update interval
+const (
+	MinStatusUpdateMinutes = 5
+	MaxStatusUpdateMinutes = 240
+)
+
+// IncidentNotificationSettings overrides how an ongoing incident notifies.
+// Only the incident commander changes them, and they are reset when the
+// incident resolves.
+type IncidentNotificationSettings struct {
+	IncidentID          string     `json:"incident_id"`
+	ChatUpdates         string     `json:"chat_updates"`          // all, pages_only, none
+	MutedUserIDs        []string   `json:"muted_user_ids"`        // get pages but no updates
+	StatusUpdateMinutes int        `json:"status_update_minutes"` // 0 = no status updates
+	LastStatusUpdateAt  *time.Time `json:"last_status_update_at,omitempty"`
+	CommanderID         string     `json:"commander_id,omitempty"`
+	UpdatedBy           string     `json:"updated_by,omitempty"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"` // nil while the defaults apply
+}
+
+// UpdateIncidentNotificationSettingsRequest changes the fields that are set
+type UpdateIncidentNotificationSettingsRequest struct {
+	ChatUpdates         *string   `json:"chat_updates,omitempty" binding:"omitempty,oneof=all pages_only none"`
+	MutedUserIDs        *[]string `json:"muted_user_ids,omitempty"`
+	StatusUpdateMinutes *int      `json:"status_update_minutes,omitempty"`
+}
@@ -0,0 +1,71 @@
+package db
+
+import "time"
+
+// Incident share link lifetimes
+const (
+	IncidentShareDefaultHours = 72
+	IncidentShareMaxHours     = 30 * 24
+)
+
+// IncidentShareLink is an expiring public link to a read-only incident summary
+type IncidentShareLink struct {
+	ID           string     `json:"id"`
+	IncidentID   string     `json:"incident_id"`
+	Label        string     `json:"label,omitempty"`
+	IncludeNotes bool       `json:"include_notes"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy    string     `json:"revoked_by,omitempty"`
+	ViewCount    int        `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+
+	// Only populated on creation
+	Token string `json:"token,omitempty"`
+}
+
+// CreateIncidentShareLinkRequest creates a share link. Notes are left out
+// unless IncludeNotes is set, since they are usually written for responders.
+type CreateIncidentShareLinkRequest struct {
+	Label          string `json:"label"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,gte=1,lte=720"` // default 72
+	IncludeNotes   bool   `json:"include_notes"`
+}
+
+// IncidentShareLinkView is one access to a share link
+type IncidentShareLinkView struct {
+	ViewedAt  time.Time `json:"viewed_at"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// IncidentSummaryEntry is one highlight of a shared incident's timeline or
+// one status update
+type IncidentSummaryEntry struct {
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+}
+
+// IncidentSummary is the read-only, print-friendly view of an incident shown
+// through share links. It carries no responder names, IDs or labels.
+type IncidentSummary struct {
+	Title          string                 `json:"title"`
+	Status         string                 `json:"status"`
+	WorkflowState  string                 `json:"workflow_state,omitempty"`
+	Severity       string                 `json:"severity,omitempty"`
+	Urgency        string                 `json:"urgency,omitempty"`
+	ServiceName    string                 `json:"service_name,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	AcknowledgedAt *time.Time             `json:"acknowledged_at,omitempty"`
+	ResolvedAt     *time.Time             `json:"resolved_at,omitempty"`
+	Timeline       []IncidentSummaryEntry `json:"timeline"`
+	Updates        []IncidentSummaryEntry `json:"updates"`
+	GeneratedAt    time.Time              `json:"generated_at"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"` // set when viewed through a share link
+
+	// The organization's timezone and formats to render it with
+	Display OrgDisplaySettings `json:"display"`
+}
@@ -0,0 +1,42 @@
+package db
+
+import "time"
+
+// IncidentTask is one checklist item on an incident, e.g. a failover step
+type IncidentTask struct {
+	ID              string     `json:"id"`
+	IncidentID      string     `json:"incident_id"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description,omitempty"`
+	Position        int        `json:"position"`
+	AssignedTo      string     `json:"assigned_to,omitempty"`
+	AssignedToName  string     `json:"assigned_to_name,omitempty"`
+	Completed       bool       `json:"completed"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	CompletedBy     string     `json:"completed_by,omitempty"`
+	CompletedByName string     `json:"completed_by_name,omitempty"`
+	CreatedBy       string     `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// CreateIncidentTaskRequest adds a task to the end of an incident's checklist
+type CreateIncidentTaskRequest struct {
+	Title       string `json:"title" binding:"required,max=500"`
+	Description string `json:"description"`
+	AssignedTo  string `json:"assigned_to"`
+}
+
+// UpdateIncidentTaskRequest changes only the fields that are set.
+// AssignedTo "" unassigns the task.
+type UpdateIncidentTaskRequest struct {
+	Title       *string `json:"title" binding:"omitempty,min=1,max=500"`
+	Description *string `json:"description"`
+	AssignedTo  *string `json:"assigned_to"`
+	Completed   *bool   `json:"completed"`
+}
+
+// ReorderIncidentTasksRequest lists every task of the incident in its new order
+type ReorderIncidentTasksRequest struct {
+	TaskIDs []string `json:"task_ids" binding:"required"`
+}
@@ -0,0 +1,40 @@
+package db
+
+import "time"
+
+// IncidentWorkflowState is an org-defined incident state between acknowledged
+// and resolved. Incidents in a custom state keep status "acknowledged" so
+// integrations only ever see the canonical three states.
+type IncidentWorkflowState struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Key            string    `json:"key"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description,omitempty"`
+	Color          string    `json:"color,omitempty"`
+	Position       int       `json:"position"`
+	AllowedFrom    []string  `json:"allowed_from"` // "acknowledged" or other state keys; empty = any
+	IsActive       bool      `json:"is_active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CreateIncidentWorkflowStateRequest for defining a new workflow state
+type CreateIncidentWorkflowStateRequest struct {
+	Key         string   `json:"key" binding:"required"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Color       string   `json:"color"`
+	Position    int      `json:"position"`
+	AllowedFrom []string `json:"allowed_from"`
+}
+
+// UpdateIncidentWorkflowStateRequest for changing a workflow state. The key is immutable.
+type UpdateIncidentWorkflowStateRequest struct {
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Color       *string   `json:"color,omitempty"`
+	Position    *int      `json:"position,omitempty"`
+	AllowedFrom *[]string `json:"allowed_from,omitempty"`
+	IsActive    *bool     `json:"is_active,omitempty"`
+}
@@ -0,0 +1,29 @@
+package db
+
+import "time"
+
+// Ingest backpressure levels
+const (
+	BackpressureNormal    = "normal"
+	BackpressureShedding  = "shedding"  // low-severity alerts are dropped
+	BackpressureRejecting = "rejecting" // every webhook gets a 429
+)
+
+// IngestBackpressureState is how webhook ingestion is currently degraded and
+// why. Counters are totals since the API process started.
+type IngestBackpressureState struct {
+	Level          string           `json:"level"`
+	QueueDepth     int64            `json:"queue_depth"` // messages across the notification queues
+	Queues         map[string]int64 `json:"queues"`
+	ShedSeverities []string         `json:"shed_severities"`
+	InFlight       int64            `json:"in_flight"`
+	SampledAt      *time.Time       `json:"sampled_at,omitempty"`
+	SampleError    string           `json:"sample_error,omitempty"`
+
+	ShedQueueDepth   int `json:"shed_queue_depth"`
+	RejectQueueDepth int `json:"reject_queue_depth"`
+	MaxInFlight      int `json:"max_in_flight"`
+
+	ShedAlerts       int64 `json:"shed_alerts"`
+	RejectedRequests int64 `json:"rejected_requests"`
+}
@@ -0,0 +1,40 @@
+package db
+
+import "time"
+
+// Built-in scrub rules
+const (
+	ScrubRuleEmail = "email"
+	ScrubRuleIP    = "ip"
+	ScrubRulePhone = "phone" // international format, starting with +
+	ScrubRuleToken = "token" // bearer tokens, API keys, passwords, private keys
+)
+
+// ScrubRules are the built-in rules a scrub policy can enable
+var ScrubRules = []string{ScrubRuleEmail, ScrubRuleIP, ScrubRulePhone, ScrubRuleToken}
+
+// Redaction counters for what isn't a built-in rule
+const (
+	ScrubCountField   = "field"
+	ScrubCountPattern = "pattern"
+)
+
+// IntegrationScrubPolicy redacts data from an integration's webhook payloads
+// before anything from them is stored. Values under a key named in Fields
+// (at any depth, case-insensitive) are replaced whole; every other string is
+// run through the built-in Rules and the custom Patterns (RE2 syntax).
+type IntegrationScrubPolicy struct {
+	Rules    []string `json:"rules"`
+	Patterns []string `json:"patterns,omitempty"`
+	Fields   []string `json:"fields,omitempty"`
+}
+
+// IntegrationScrubAudit is an integration's scrub policy and how much it has
+// redacted; Redactions is keyed by rule, "field" or "pattern"
+type IntegrationScrubAudit struct {
+	IntegrationID    string                  `json:"integration_id"`
+	Policy           *IntegrationScrubPolicy `json:"policy"` // nil = scrubbing off
+	ScrubbedPayloads int64                   `json:"scrubbed_payloads"`
+	Redactions       map[string]int64        `json:"redactions"`
+	LastScrubbedAt   *time.Time              `json:"last_scrubbed_at,omitempty"`
+}
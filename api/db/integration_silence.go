@@ -0,0 +1,20 @@
+package db
+
+// IncidentSourceIntegrationHealth is the incident source for silent-integration incidents
+const IncidentSourceIntegrationHealth = "integration_health"
+
+// IntegrationSilenceIncidentKeyPrefix + integration ID dedups silence incidents
+const IntegrationSilenceIncidentKeyPrefix = "integration-silence:"
+
+// IntegrationSilenceAlert pages when an integration sends no webhooks for
+// AfterMinutes while it is expected to be active. Outside the activity hours
+// silence is expected and the clock starts again when they begin.
+type IntegrationSilenceAlert struct {
+	AfterMinutes int `json:"after_minutes"`
+	// Hours of the day the source is expected to send, [FromHour, ToHour) in
+	// Timezone; FromHour > ToHour wraps past midnight, equal means all day
+	FromHour int    `json:"from_hour"`
+	ToHour   int    `json:"to_hour"`
+	Days     []int  `json:"days,omitempty"`     // 0 = Sunday; empty = every day
+	Timezone string `json:"timezone,omitempty"` // IANA name, default UTC
+}
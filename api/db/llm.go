@@ -0,0 +1,59 @@
+package db
+
+import "time"
+
+// OrgLLMSettings is the language model an organization's AI features use.
+// Without a provider of its own the organization uses the instance default.
+// The API key is never returned.
+type OrgLLMSettings struct {
+	OrganizationID     string     `json:"organization_id"`
+	Provider           string     `json:"provider,omitempty"`
+	Model              string     `json:"model,omitempty"`
+	BaseURL            string     `json:"base_url,omitempty"`
+	APIVersion         string     `json:"api_version,omitempty"`
+	HasAPIKey          bool       `json:"has_api_key"`
+	MonthlyTokenBudget *int64     `json:"monthly_token_budget"` // nil: instance default, 0: unlimited
+	UsesDefault        bool       `json:"uses_default"`         // no provider of its own
+	UpdatedBy          string     `json:"updated_by,omitempty"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateOrgLLMSettingsRequest changes the fields that are set. An empty
+// provider goes back to the instance default, an empty api_key removes the
+// stored key and a monthly_token_budget of -1 goes back to the instance
+// default budget.
+type UpdateOrgLLMSettingsRequest struct {
+	Provider           *string `json:"provider,omitempty"`
+	Model              *string `json:"model,omitempty"`
+	APIKey             *string `json:"api_key,omitempty"`
+	BaseURL            *string `json:"base_url,omitempty"`
+	APIVersion         *string `json:"api_version,omitempty"`
+	MonthlyTokenBudget *int64  `json:"monthly_token_budget,omitempty" binding:"omitempty,gte=-1"`
+}
+
+// LLMUsageBreakdown is usage for one provider, model and feature
+type LLMUsageBreakdown struct {
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+	Feature      string `json:"feature"`
+	Requests     int64  `json:"requests"`
+	Failed       int64  `json:"failed"`
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+}
+
+// LLMUsageReport is an organization's language model usage over a period,
+// with where it stands against this month's budget
+type LLMUsageReport struct {
+	OrganizationID string              `json:"organization_id"`
+	From           time.Time           `json:"from"`
+	To             time.Time           `json:"to"`
+	Requests       int64               `json:"requests"`
+	InputTokens    int64               `json:"input_tokens"`
+	OutputTokens   int64               `json:"output_tokens"`
+	Breakdown      []LLMUsageBreakdown `json:"breakdown"`
+
+	MonthlyTokenBudget int64  `json:"monthly_token_budget"` // 0: unlimited
+	UsedThisMonth      int64  `json:"used_this_month"`
+	RemainingThisMonth *int64 `json:"remaining_this_month,omitempty"` // nil when unlimited
+}
@@ -0,0 +1,19 @@
+package db
+
+const (
+	MetricsExportProviderDatadog     = "datadog"
+	MetricsExportProviderRemoteWrite = "prometheus_remote_write"
+
+	// Window of the incidents-created rate and of the MTTA/MTTR averages
+	MetricsExportRateWindowSeconds = 3600
+	MetricsExportMTTWindowSeconds  = 86400
+)
+
+// OperationalMetric is one gauge sample of SLAR's own health. Name is
+// dotted ("incidents.open"); exporters add the "slar" prefix and convert it
+// to their naming scheme.
+type OperationalMetric struct {
+	Name  string
+	Value float64
+	Tags  map[string]string
+}
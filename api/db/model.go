@@ -10,13 +10,29 @@ import "time"
 type Integration struct {
 	ID          string                 `json:"id"`
 	Name        string                 `json:"name"`
-	Type        string                 `json:"type"` // prometheus, datadog, grafana, webhook, aws, custom
+	Type        string                 `json:"type"` // prometheus, datadog, grafana, webhook, aws, custom, github, gitlab, argocd, federation, siem
 	Description string                 `json:"description"`
 	Config      map[string]interface{} `json:"config"`      // Integration-specific configuration
 	WebhookURL  string                 `json:"webhook_url"` // Auto-generated webhook URL
 
 	// Security
-	WebhookSecret string `json:"webhook_secret,omitempty"`
+	WebhookSecret      string   `json:"webhook_secret,omitempty"`
+	AllowedSourceCIDRs []string `json:"allowed_source_cidrs"` // Empty = any source IP
+	AllowedUserAgents  []string `json:"allowed_user_agents"`  // Case-insensitive prefixes, empty = any
+
+	// Rejected inbound webhook requests (allowlist violations)
+	RejectedIPCount        int64      `json:"rejected_ip_count"`
+	RejectedUserAgentCount int64      `json:"rejected_user_agent_count"`
+	LastRejectedAt         *time.Time `json:"last_rejected_at,omitempty"`
+	LastRejectedSource     string     `json:"last_rejected_source,omitempty"`
+
+	// Rejected payloads (too large, malformed, missing required fields)
+	PayloadErrorCount  int64      `json:"payload_error_count"`
+	LastPayloadError   string     `json:"last_payload_error,omitempty"`
+	LastPayloadErrorAt *time.Time `json:"last_payload_error_at,omitempty"`
+
+	// Test mode: incidents are marked as test (no real paging, excluded from analytics)
+	TestMode bool `json:"test_mode"`
 
 	// Health monitoring
 	IsActive          bool       `json:"is_active"`
@@ -24,6 +40,9 @@ type Integration struct {
 	HeartbeatInterval int        `json:"heartbeat_interval"`      // seconds
 	HealthStatus      string     `json:"health_status,omitempty"` // healthy, warning, unhealthy, unknown
 
+	// Page when the integration goes silent; nil = disabled
+	SilenceAlert *IntegrationSilenceAlert `json:"silence_alert,omitempty"`
+
 	// Tenant isolation (ReBAC)
 	OrganizationID string `json:"organization_id,omitempty"` // MANDATORY for tenant isolation
 	ProjectID      string `json:"project_id,omitempty"`      // OPTIONAL for project scoping
@@ -46,6 +65,13 @@ type ServiceIntegration struct {
 	Priority          int                    `json:"priority"`           // Lower number = higher priority
 	IsActive          bool                   `json:"is_active"`
 
+	// Environment the integration's alerts come from (e.g. staging, prod);
+	// carried onto their incidents. Empty means production.
+	Environment string `json:"environment,omitempty"`
+	// EscalationPolicyID pages this policy instead of the service's. Alerts
+	// from a non-production environment without one page nobody.
+	EscalationPolicyID string `json:"escalation_policy_id,omitempty"`
+
 	// Metadata
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -79,6 +105,12 @@ type CreateIntegrationRequest struct {
 	Config            map[string]interface{} `json:"config"`
 	WebhookSecret     string                 `json:"webhook_secret,omitempty"`
 	HeartbeatInterval int                    `json:"heartbeat_interval,omitempty"`
+	// Inbound webhook allowlists (optional)
+	AllowedSourceCIDRs []string `json:"allowed_source_cidrs,omitempty"`
+	AllowedUserAgents  []string `json:"allowed_user_agents,omitempty"`
+	TestMode           bool     `json:"test_mode,omitempty"`
+	// Page when no webhooks arrive (optional)
+	SilenceAlert *IntegrationSilenceAlert `json:"silence_alert,omitempty"`
 	// ReBAC: Tenant isolation fields
 	OrganizationID string `json:"organization_id,omitempty"` // MANDATORY for tenant isolation
 	ProjectID      string `json:"project_id,omitempty"`      // OPTIONAL for project scoping
@@ -91,20 +123,30 @@ type UpdateIntegrationRequest struct {
 	WebhookSecret     *string                `json:"webhook_secret,omitempty"`
 	IsActive          *bool                  `json:"is_active,omitempty"`
 	HeartbeatInterval *int                   `json:"heartbeat_interval,omitempty"`
+	// Inbound webhook allowlists; send an empty list to clear
+	AllowedSourceCIDRs *[]string `json:"allowed_source_cidrs,omitempty"`
+	AllowedUserAgents  *[]string `json:"allowed_user_agents,omitempty"`
+	TestMode           *bool     `json:"test_mode,omitempty"`
+	// Silence alerting; send after_minutes 0 to disable
+	SilenceAlert *IntegrationSilenceAlert `json:"silence_alert,omitempty"`
 }
 
 // ServiceIntegration request models
 type CreateServiceIntegrationRequest struct {
-	ServiceID         string                 `json:"service_id,omitempty"`
-	IntegrationID     string                 `json:"integration_id" binding:"required"`
-	RoutingConditions map[string]interface{} `json:"routing_conditions"`
-	Priority          int                    `json:"priority,omitempty"`
+	ServiceID          string                 `json:"service_id,omitempty"`
+	IntegrationID      string                 `json:"integration_id" binding:"required"`
+	RoutingConditions  map[string]interface{} `json:"routing_conditions"`
+	Priority           int                    `json:"priority,omitempty"`
+	Environment        string                 `json:"environment,omitempty"`
+	EscalationPolicyID string                 `json:"escalation_policy_id,omitempty"`
 }
 
 type UpdateServiceIntegrationRequest struct {
-	RoutingConditions map[string]interface{} `json:"routing_conditions,omitempty"`
-	Priority          *int                   `json:"priority,omitempty"`
-	IsActive          *bool                  `json:"is_active,omitempty"`
+	RoutingConditions  map[string]interface{} `json:"routing_conditions,omitempty"`
+	Priority           *int                   `json:"priority,omitempty"`
+	IsActive           *bool                  `json:"is_active,omitempty"`
+	Environment        *string                `json:"environment,omitempty"`          // "" clears
+	EscalationPolicyID *string                `json:"escalation_policy_id,omitempty"` // "" clears
 }
 
 type User struct {
@@ -198,6 +240,10 @@ type Service struct {
 	Integrations         map[string]interface{} `json:"integrations,omitempty"` // Datadog, Prometheus configs
 	NotificationSettings map[string]interface{} `json:"notification_settings,omitempty"`
 
+	// Incident cost parameters (optional)
+	RevenuePerMinute   *float64 `json:"revenue_per_minute,omitempty"`   // Business impact per minute of incident
+	EngineerHourlyCost *float64 `json:"engineer_hourly_cost,omitempty"` // Loaded cost of one responder hour
+
 	// Display info (for API responses)
 	GroupName          string `json:"group_name,omitempty"`
 	EscalationRuleName string `json:"escalation_rule_name,omitempty"`
@@ -218,6 +264,8 @@ type CreateServiceRequest struct {
 	EscalationPolicyID   *string                `json:"escalation_policy_id,omitempty"` // Datadog-style escalation policy
 	Integrations         map[string]interface{} `json:"integrations,omitempty"`
 	NotificationSettings map[string]interface{} `json:"notification_settings,omitempty"`
+	RevenuePerMinute     *float64               `json:"revenue_per_minute,omitempty" binding:"omitempty,gte=0"`
+	EngineerHourlyCost   *float64               `json:"engineer_hourly_cost,omitempty" binding:"omitempty,gte=0"`
 
 	// Tenant isolation (required for multi-tenant)
 	OrganizationID string `json:"organization_id,omitempty"` // Tenant context
@@ -233,6 +281,8 @@ type UpdateServiceRequest struct {
 	IsActive             *bool                  `json:"is_active,omitempty"`
 	Integrations         map[string]interface{} `json:"integrations,omitempty"`
 	NotificationSettings map[string]interface{} `json:"notification_settings,omitempty"`
+	RevenuePerMinute     *float64               `json:"revenue_per_minute,omitempty" binding:"omitempty,gte=0"`   // 0 clears
+	EngineerHourlyCost   *float64               `json:"engineer_hourly_cost,omitempty" binding:"omitempty,gte=0"` // 0 clears
 }
 
 // UptimeService represents uptime monitoring services (renamed from Service to avoid conflict)
@@ -318,6 +368,7 @@ type APIKey struct {
 	TotalAlertsCreated int        `json:"total_alerts_created"`
 	Description        string     `json:"description"`
 	Environment        string     `json:"environment"` // prod, dev, test
+	TestMode           bool       `json:"test_mode"`   // incidents are marked as test (no real paging, excluded from analytics)
 	CreatedBy          string     `json:"created_by,omitempty"`
 
 	// Tenant isolation
@@ -376,6 +427,32 @@ type APIKeyStats struct {
 	Status             string     `json:"status"` // active, disabled, expired
 }
 
+// APIKeyQuota is the state of one rate limit window for an API key
+type APIKeyQuota struct {
+	Window    string    `json:"window"` // hour, day
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// APIKeyDailyUsage is the number of accepted requests on one UTC day
+type APIKeyDailyUsage struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Requests int    `json:"requests"`
+}
+
+// APIKeyUsage is the usage dashboard for one API key
+type APIKeyUsage struct {
+	APIKeyID      string             `json:"api_key_id"`
+	Name          string             `json:"name"`
+	UserID        string             `json:"user_id"`
+	TotalRequests int                `json:"total_requests"`
+	LastUsedAt    *time.Time         `json:"last_used_at,omitempty"`
+	Quotas        []APIKeyQuota      `json:"quotas"`
+	Daily         []APIKeyDailyUsage `json:"daily"`
+}
+
 // Request/Response DTOs
 type CreateAPIKeyRequest struct {
 	Name             string     `json:"name" binding:"required"`
@@ -386,6 +463,7 @@ type CreateAPIKeyRequest struct {
 	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
 	RateLimitPerHour int        `json:"rate_limit_per_hour,omitempty"`
 	RateLimitPerDay  int        `json:"rate_limit_per_day,omitempty"`
+	TestMode         *bool      `json:"test_mode,omitempty"` // defaults to true for the test environment
 }
 
 type CreateAPIKeyResponse struct {
@@ -393,6 +471,7 @@ type CreateAPIKeyResponse struct {
 	Name        string     `json:"name"`
 	APIKey      string     `json:"api_key"` // Only shown once
 	Environment string     `json:"environment"`
+	TestMode    bool       `json:"test_mode"`
 	Permissions []string   `json:"permissions"`
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
@@ -407,6 +486,7 @@ type UpdateAPIKeyRequest struct {
 	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
 	RateLimitPerHour *int       `json:"rate_limit_per_hour,omitempty"`
 	RateLimitPerDay  *int       `json:"rate_limit_per_day,omitempty"`
+	TestMode         *bool      `json:"test_mode,omitempty"`
 }
 
 type WebhookAlertRequest struct {
@@ -446,11 +526,31 @@ var ValidPermissions = []Permission{
 
 // Environment constants
 const (
-	EnvironmentProd = "prod"
-	EnvironmentDev  = "dev"
-	EnvironmentTest = "test"
+	EnvironmentProd       = "prod"
+	EnvironmentProduction = "production"
+	EnvironmentDev        = "dev"
+	EnvironmentTest       = "test"
 )
 
+// IsProductionEnvironment reports whether alerts from an environment page
+// the service's on-call. Alerts without an environment count as production.
+func IsProductionEnvironment(environment string) bool {
+	return environment == "" || environment == EnvironmentProd || environment == EnvironmentProduction
+}
+
+// EffectiveEscalationPolicy returns the escalation policy alerts arriving
+// through this binding page: its own, else the service's for production,
+// else none
+func (si *ServiceIntegration) EffectiveEscalationPolicy(servicePolicyID string) string {
+	if si.EscalationPolicyID != "" {
+		return si.EscalationPolicyID
+	}
+	if IsProductionEnvironment(si.Environment) {
+		return servicePolicyID
+	}
+	return ""
+}
+
 // Rate limit window types
 const (
 	WindowTypeHour = "hour"
@@ -477,11 +577,29 @@ type Group struct {
 	UserEmail         string    `json:"user_email,omitempty"`
 	UserTeam          string    `json:"user_team,omitempty"`
 
+	// Used by services created in the group without an escalation policy
+	DefaultEscalationPolicyID string `json:"default_escalation_policy_id,omitempty"`
+
+	// Parent team; targeting a group includes its sub-teams
+	ParentGroupID string `json:"parent_group_id,omitempty"`
+
+	// Hand open incidents to the incoming on-call when the assignee's shift ends
+	ReassignOnHandoff bool `json:"reassign_on_handoff"`
+
 	// Tenant isolation
 	OrganizationID string `json:"organization_id,omitempty"` // Tenant isolation
 	ProjectID      string `json:"project_id,omitempty"`      // Project scoping
 }
 
+// SubGroup is a descendant of a group in the team hierarchy
+type SubGroup struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ParentGroupID string `json:"parent_group_id"`
+	Depth         int    `json:"depth"` // 1 = direct sub-team
+	MemberCount   int    `json:"member_count"`
+}
+
 // GroupWithMembers includes member information
 type GroupWithMembers struct {
 	Group
@@ -520,6 +638,16 @@ type EscalationPolicy struct {
 	GroupID              string    `json:"group_id"`
 	CreatedBy            string    `json:"created_by,omitempty"`
 
+	// Escalate early when nobody has opened the latest page (0 = disabled)
+	UnseenEscalateAfterMinutes int `json:"unseen_escalate_after_minutes"`
+
+	// Reassign to the next schedule member when the assignee hasn't acknowledged (0 = disabled)
+	ReassignAfterMinutes int `json:"reassign_after_minutes"`
+
+	// Re-trigger and keep escalating when still unresolved this long after
+	// acknowledgment (0 = acknowledgment stops escalation)
+	AckTimeoutMinutes int `json:"ack_timeout_minutes"`
+
 	// Tenant isolation
 	OrganizationID string `json:"organization_id,omitempty"` // Tenant isolation
 
@@ -532,8 +660,8 @@ type EscalationLevel struct {
 	ID                  string    `json:"id"`
 	PolicyID            string    `json:"policy_id"`
 	LevelNumber         int       `json:"level_number"`
-	TargetType          string    `json:"target_type"`          // 'current_schedule', 'user', 'group', 'external'
-	TargetID            string    `json:"target_id,omitempty"`  // user_id, schedule_id, group_id, webhook_url
+	TargetType          string    `json:"target_type"`          // 'current_schedule', 'user', 'group', 'external', 'external_schedule'
+	TargetID            string    `json:"target_id,omitempty"`  // user_id, schedule_id, group_id, webhook_url, integration_id
 	TimeoutMinutes      int       `json:"timeout_minutes"`      // Override policy default (0 = use policy default)
 	NotificationMethods []string  `json:"notification_methods"` // ["email", "sms", "phone", "push"]
 	MessageTemplate     string    `json:"message_template"`
@@ -790,6 +918,7 @@ type CreateGroupRequest struct {
 	Visibility        string `json:"visibility,omitempty" binding:"omitempty,oneof=private public organization"`
 	EscalationTimeout int    `json:"escalation_timeout,omitempty"`
 	EscalationMethod  string `json:"escalation_method,omitempty"`
+	ParentGroupID     string `json:"parent_group_id,omitempty"` // Make this group a sub-team
 
 	// Tenant isolation (required for multi-tenant)
 	OrganizationID string `json:"organization_id,omitempty"` // Tenant context
@@ -805,6 +934,12 @@ type UpdateGroupRequest struct {
 	IsActive          *bool   `json:"is_active,omitempty"`
 	EscalationTimeout *int    `json:"escalation_timeout,omitempty"`
 	EscalationMethod  *string `json:"escalation_method,omitempty"`
+	// Send an empty string to clear the group's default escalation policy
+	DefaultEscalationPolicyID *string `json:"default_escalation_policy_id,omitempty"`
+	// Send an empty string to make the group top-level
+	ParentGroupID *string `json:"parent_group_id,omitempty"`
+	// Hand open incidents to the incoming on-call when a shift ends
+	ReassignOnHandoff *bool `json:"reassign_on_handoff,omitempty"`
 }
 
 // AddGroupMemberRequest for adding a user to a group
@@ -847,7 +982,7 @@ type UpdateEscalationPolicyRequest struct {
 // CreateEscalationLevelRequest for creating escalation levels
 type CreateEscalationLevelRequest struct {
 	LevelNumber         int      `json:"level_number" binding:"required,min=1"`
-	TargetType          string   `json:"target_type" binding:"required,oneof=scheduler user group external current_schedule"`
+	TargetType          string   `json:"target_type" binding:"required,oneof=scheduler user group external current_schedule external_schedule"`
 	TargetID            string   `json:"target_id,omitempty"`
 	TimeoutMinutes      int      `json:"timeout_minutes" binding:"required,min=1,max=1440"`
 	NotificationMethods []string `json:"notification_methods"`
@@ -946,6 +1081,9 @@ type ShiftSwapResponse struct {
 	SwappedAt       time.Time `json:"swapped_at"`
 	CurrentSchedule Shift     `json:"current_schedule"`
 	TargetSchedule  Shift     `json:"target_schedule"`
+	// Set instead of the swap when the requestor doesn't manage the shifts
+	// and a schedule manager has to approve it
+	SwapRequest *SwapRequest `json:"swap_request,omitempty"`
 }
 
 // Swap type constants
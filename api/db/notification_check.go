@@ -0,0 +1,56 @@
+package db
+
+// Notification channels a test notification can go to
+const (
+	NotificationChannelSlack    = "slack"
+	NotificationChannelEmail    = "email"
+	NotificationChannelPush     = "push" // mobile app, through the cloud relay
+	NotificationChannelWebPush  = "web_push"
+	NotificationChannelWhatsApp = "whatsapp"
+)
+
+// NotificationChannels lists every channel POST /notifications/test checks
+var NotificationChannels = []string{
+	NotificationChannelSlack, NotificationChannelEmail, NotificationChannelPush,
+	NotificationChannelWebPush, NotificationChannelWhatsApp,
+}
+
+// Test notification outcomes per channel
+const (
+	NotificationCheckSent    = "sent"
+	NotificationCheckFailed  = "failed"
+	NotificationCheckSkipped = "skipped"
+)
+
+// Diagnostic codes explaining a failed or skipped channel
+const (
+	NotificationCheckNotConfigured      = "not_configured"         // server has no credentials for the channel
+	NotificationCheckNotSetUp           = "not_set_up"             // the user hasn't linked the channel
+	NotificationCheckCredentialsInvalid = "credentials_invalid"    // provider rejected the server's credentials
+	NotificationCheckTokenInvalid       = "token_invalid"          // the user's device/browser registration is gone
+	NotificationCheckChannelNotFound    = "channel_not_found"      // Slack channel or user doesn't exist or the bot isn't in it
+	NotificationCheckOutsideSession     = "outside_session_window" // WhatsApp only allows templates outside 24h
+	NotificationCheckDeliveryFailed     = "delivery_failed"
+)
+
+// TestNotificationRequest picks the channels to test; empty means all
+type TestNotificationRequest struct {
+	Channels []string `json:"channels"`
+}
+
+// NotificationChannelResult is the delivery diagnostic for one channel
+type NotificationChannelResult struct {
+	Channel   string `json:"channel"`
+	Status    string `json:"status"` // sent, failed, skipped
+	Code      string `json:"code,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	Recipient string `json:"recipient,omitempty"` // where it went, masked
+}
+
+// TestNotificationResult is the outcome of a test notification
+type TestNotificationResult struct {
+	Sent    int                         `json:"sent"`
+	Failed  int                         `json:"failed"`
+	Skipped int                         `json:"skipped"`
+	Results []NotificationChannelResult `json:"results"`
+}
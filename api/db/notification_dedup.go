@@ -0,0 +1,29 @@
+package db
+
+// Cross-channel dedup policies for a user's pages
+const (
+	NotificationDedupAll       = "all"        // every channel at once
+	NotificationDedupPushFirst = "push_first" // hold the Slack DM until push had its chance
+)
+
+const (
+	NotificationDedupDefaultDelaySeconds = 60
+	NotificationDedupMinDelaySeconds     = 10
+	NotificationDedupMaxDelaySeconds     = 900
+)
+
+// NotificationDedup is how a user's pages are deduplicated across channels.
+// With push_first the Slack DM of a page waits DelaySeconds and is dropped if
+// the push was delivered, the page was seen or the incident was acknowledged
+// in the meantime. Group channel posts are never held.
+type NotificationDedup struct {
+	Policy       string `json:"policy"`
+	DelaySeconds int    `json:"delay_seconds"`
+}
+
+// UpdateNotificationDedupRequest changes the calling user's dedup policy;
+// DelaySeconds defaults to NotificationDedupDefaultDelaySeconds
+type UpdateNotificationDedupRequest struct {
+	Policy       string `json:"policy" binding:"required,oneof=all push_first"`
+	DelaySeconds int    `json:"delay_seconds" binding:"omitempty,min=10,max=900"`
+}
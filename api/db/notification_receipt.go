@@ -0,0 +1,30 @@
+package db
+
+import "time"
+
+// Notification receipt events reported by clients
+const (
+	ReceiptEventDelivered = "delivered"
+	ReceiptEventSeen      = "seen"
+)
+
+// NotificationReceipt tracks delivery and read state of one page sent to a responder
+type NotificationReceipt struct {
+	ID               string     `json:"id"`
+	IncidentID       string     `json:"incident_id"`
+	UserID           string     `json:"user_id"`
+	UserName         string     `json:"user_name,omitempty"`
+	NotificationType string     `json:"notification_type"` // assigned, escalated, paged
+	SentAt           time.Time  `json:"sent_at"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
+	DeliveredChannel string     `json:"delivered_channel,omitempty"`
+	SeenAt           *time.Time `json:"seen_at,omitempty"`
+	SeenChannel      string     `json:"seen_channel,omitempty"`
+}
+
+// RecordNotificationReceiptRequest is sent by mobile/Slack/web clients when a page
+// is delivered to the device or opened by the responder
+type RecordNotificationReceiptRequest struct {
+	Channel string `json:"channel" binding:"required,oneof=push slack email sms web"`
+	Event   string `json:"event" binding:"required,oneof=delivered seen"`
+}
@@ -0,0 +1,50 @@
+package db
+
+// Week start days an organization can pick
+const (
+	WeekStartMonday = "monday"
+	WeekStartSunday = "sunday"
+)
+
+// Clock formats an organization can pick
+const (
+	TimeFormat24h = "24h"
+	TimeFormat12h = "12h"
+)
+
+// OrgDateFormats maps the date formats an organization can pick to Go layouts
+var OrgDateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD.MM.YYYY": "02.01.2006",
+	"D MMM YYYY": "2 Jan 2006",
+}
+
+// OrgDisplaySettings is how an organization wants times shown and bucketed in
+// generated reports, calendars, messages and analytics. Stored under
+// "display" in organizations.settings.
+type OrgDisplaySettings struct {
+	Timezone   string `json:"timezone"`    // IANA name
+	DateFormat string `json:"date_format"` // key of OrgDateFormats
+	TimeFormat string `json:"time_format"` // 24h, 12h
+	WeekStart  string `json:"week_start"`  // monday, sunday
+}
+
+// DefaultOrgDisplaySettings applies to organizations that haven't chosen
+func DefaultOrgDisplaySettings() OrgDisplaySettings {
+	return OrgDisplaySettings{
+		Timezone:   "UTC",
+		DateFormat: "YYYY-MM-DD",
+		TimeFormat: TimeFormat24h,
+		WeekStart:  WeekStartMonday,
+	}
+}
+
+// UpdateOrgDisplaySettingsRequest changes the fields that are set
+type UpdateOrgDisplaySettingsRequest struct {
+	Timezone   *string `json:"timezone,omitempty"`
+	DateFormat *string `json:"date_format,omitempty"`
+	TimeFormat *string `json:"time_format,omitempty" binding:"omitempty,oneof=24h 12h"`
+	WeekStart  *string `json:"week_start,omitempty" binding:"omitempty,oneof=monday sunday"`
+}
@@ -0,0 +1,48 @@
+package db
+
+import "time"
+
+// Paging audit outcomes for one responder, best first
+const (
+	PagingOutcomeAcknowledged = "acknowledged" // the responder acknowledged the incident
+	PagingOutcomeSeen         = "seen"         // a client reported the page was opened
+	PagingOutcomeDelivered    = "delivered"    // at least one channel delivered the page
+	PagingOutcomeFailed       = "failed"       // every channel that tried failed
+	PagingOutcomeQueued       = "queued"       // no channel has reported back yet
+)
+
+// PagingAuditLevel is one escalation level of an incident: who it paged,
+// over which channels, and what came of it
+type PagingAuditLevel struct {
+	Level        int                    `json:"level"`
+	TargetType   string                 `json:"target_type,omitempty"`
+	TargetID     string                 `json:"target_id,omitempty"`
+	TargetName   string                 `json:"target_name,omitempty"`
+	StartedAt    time.Time              `json:"started_at"`
+	Acknowledged bool                   `json:"acknowledged"` // acknowledged while this level was current
+	Responders   []PagingAuditResponder `json:"responders"`
+}
+
+// PagingAuditResponder is one page sent to a responder at a level
+type PagingAuditResponder struct {
+	UserID           string                `json:"user_id"`
+	UserName         string                `json:"user_name,omitempty"`
+	NotificationType string                `json:"notification_type"` // assigned, escalated, paged
+	SentAt           time.Time             `json:"sent_at"`
+	DeliveredAt      *time.Time            `json:"delivered_at,omitempty"`
+	DeliveredChannel string                `json:"delivered_channel,omitempty"`
+	SeenAt           *time.Time            `json:"seen_at,omitempty"`
+	SeenChannel      string                `json:"seen_channel,omitempty"`
+	Outcome          string                `json:"outcome"`
+	Deliveries       []PagingAuditDelivery `json:"deliveries"`
+}
+
+// PagingAuditDelivery is one channel's attempt at delivering a page, taken from
+// notification_logs or, for phone calls, voice_calls
+type PagingAuditDelivery struct {
+	Channel string    `json:"channel"` // slack, whatsapp, web_push, voice, ...
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Outcome string    `json:"outcome,omitempty"` // phone calls: acknowledged, escalated, unanswered, ...
+	At      time.Time `json:"at"`
+}
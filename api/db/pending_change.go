@@ -0,0 +1,49 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Destructive operations that need a second admin's approval
+const (
+	PendingChangeGroupDelete        = "group_delete"
+	PendingChangeAPIKeysRotate      = "api_keys_rotate"
+	PendingChangeIntegrationDisable = "integration_disable"
+)
+
+// Pending change statuses
+const (
+	PendingChangeStatusPending  = "pending"
+	PendingChangeStatusApproved = "approved"
+	PendingChangeStatusRejected = "rejected"
+	PendingChangeStatusExpired  = "expired"
+	// Approved, but running the operation failed
+	PendingChangeStatusFailed = "failed"
+)
+
+// PendingChange is a destructive operation held until another org admin
+// approves it or it expires
+type PendingChange struct {
+	ID              string          `json:"id"`
+	OrganizationID  string          `json:"organization_id"`
+	Action          string          `json:"action"`
+	ResourceID      string          `json:"resource_id,omitempty"`
+	ResourceName    string          `json:"resource_name,omitempty"`
+	Payload         json.RawMessage `json:"payload,omitempty"`
+	Reason          string          `json:"reason,omitempty"`
+	Status          string          `json:"status"`
+	RequestedBy     string          `json:"requested_by"`
+	RequestedByName string          `json:"requested_by_name,omitempty"`
+	DecidedBy       string          `json:"decided_by,omitempty"`
+	DecidedByName   string          `json:"decided_by_name,omitempty"`
+	DecidedAt       *time.Time      `json:"decided_at,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	ExpiresAt       time.Time       `json:"expires_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// PendingChangeDecision approves or rejects a pending change
+type PendingChangeDecision struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,65 @@
+package db
+
+import "time"
+
+// Postmortems live in the incident's custom fields: the text under
+// "postmortem" (which resolution search indexes) and, when it was drafted by
+// a language model, where it came from under "postmortem_provenance".
+const (
+	PostmortemField           = "postmortem"
+	PostmortemProvenanceField = "postmortem_provenance"
+
+	PostmortemGeneratedByAI = "ai"
+)
+
+// DraftPostmortemRequest asks for an AI-generated postmortem draft. A
+// postmortem a person wrote or edited is only replaced with Overwrite set.
+type DraftPostmortemRequest struct {
+	Overwrite bool `json:"overwrite"`
+	// Instructions are passed to the model, e.g. "focus on the failover"
+	Instructions string `json:"instructions,omitempty" binding:"max=2000"`
+}
+
+// PostmortemTimelineEntry is one line of a drafted postmortem's timeline
+type PostmortemTimelineEntry struct {
+	Time        string `json:"time"`
+	Description string `json:"description"`
+}
+
+// PostmortemSources counts what the draft was written from
+type PostmortemSources struct {
+	Events       int `json:"events"`
+	Notes        int `json:"notes"`
+	ChatMessages int `json:"chat_messages"`
+}
+
+// PostmortemProvenance records that a postmortem was AI-generated. It stays
+// on the incident after people edit the draft; ContentSHA256 tells whether
+// the text is still the model's.
+type PostmortemProvenance struct {
+	GeneratedBy   string            `json:"generated_by"` // ai
+	Provider      string            `json:"provider"`
+	Model         string            `json:"model"`
+	GeneratedAt   time.Time         `json:"generated_at"`
+	RequestedBy   string            `json:"requested_by,omitempty"`
+	ContentSHA256 string            `json:"content_sha256"`
+	InputTokens   int               `json:"input_tokens"`
+	OutputTokens  int               `json:"output_tokens"`
+	Sources       PostmortemSources `json:"sources"`
+}
+
+// PostmortemDraft is an AI-generated postmortem. Structured is false when the
+// model didn't answer in the requested format and Markdown is its reply as is.
+type PostmortemDraft struct {
+	IncidentID     string                    `json:"incident_id"`
+	Summary        string                    `json:"summary,omitempty"`
+	Impact         string                    `json:"impact,omitempty"`
+	RootCause      string                    `json:"root_cause,omitempty"`
+	Resolution     string                    `json:"resolution,omitempty"`
+	Timeline       []PostmortemTimelineEntry `json:"timeline,omitempty"`
+	ActionItems    []string                  `json:"action_items,omitempty"`
+	LessonsLearned []string                  `json:"lessons_learned,omitempty"`
+	Structured     bool                      `json:"structured"`
+	Markdown       string                    `json:"markdown"`
+	Provenance     PostmortemProvenance      `json:"provenance"`
+}
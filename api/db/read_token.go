@@ -0,0 +1,41 @@
+package db
+
+import "time"
+
+// Read token scopes, one per endpoint under /read
+const (
+	ReadScopeIncidents = "incidents:read" // GET /read/incidents
+	ReadScopeOnCall    = "oncall:read"    // GET /read/oncall
+	ReadScopeMonitors  = "monitors:read"  // GET /read/monitors
+)
+
+// ValidReadScopes lists every scope a read token may be given
+var ValidReadScopes = []string{ReadScopeIncidents, ReadScopeOnCall, ReadScopeMonitors}
+
+// ReadToken is a read-only token a user creates to power dashboards and
+// portals. It acts as its owner, so it only sees the owner's incidents,
+// groups and monitors in OrganizationID (and ProjectID, if set).
+type ReadToken struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"user_id"`
+	OrganizationID string     `json:"organization_id"`
+	ProjectID      string     `json:"project_id,omitempty"`
+	Name           string     `json:"name"`
+	Scopes         []string   `json:"scopes"`
+	IsActive       bool       `json:"is_active"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Only populated on creation
+	Token string `json:"token,omitempty"`
+}
+
+// CreateReadTokenRequest for issuing a new read token
+type CreateReadTokenRequest struct {
+	Name           string     `json:"name" binding:"required"`
+	OrganizationID string     `json:"organization_id" binding:"required"`
+	ProjectID      string     `json:"project_id"`
+	Scopes         []string   `json:"scopes" binding:"required"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
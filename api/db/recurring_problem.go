@@ -0,0 +1,50 @@
+package db
+
+import "time"
+
+const (
+	// RecurringProblemMinIncidents is how many incidents make a problem recurring
+	RecurringProblemMinIncidents = 2
+	// RecurringProblemsPerService is how many problems are kept per service
+	RecurringProblemsPerService = 10
+	// RecurringProblemTitleSimilarity is the Jaccard similarity of normalized
+	// title words at which two incidents count as the same problem
+	RecurringProblemTitleSimilarity = 0.6
+)
+
+// RecurringProblem is a cluster of resolved incidents that look like the same
+// problem: the same alert fingerprint or near-identical titles
+type RecurringProblem struct {
+	// Fingerprint is set when the cluster was formed by alert fingerprint
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Title is the most common title in the cluster
+	Title     string `json:"title"`
+	Incidents int    `json:"incidents"`
+	// TotalSecondsOpen is the time from trigger to resolve, summed
+	TotalSecondsOpen   int64     `json:"total_seconds_open"`
+	AvgSecondsOpen     int64     `json:"avg_seconds_open"`
+	FirstSeen          time.Time `json:"first_seen"`
+	LastSeen           time.Time `json:"last_seen"`
+	SampleIncidentIDs  []string  `json:"sample_incident_ids"`
+	DistinctTitles     int       `json:"distinct_titles"`
+	ResolvedWithoutAck int       `json:"resolved_without_ack"`
+}
+
+// ServiceRecurringProblems is one service's top recurring problems, most
+// time spent first. ServiceID is empty for incidents without a service.
+type ServiceRecurringProblems struct {
+	ServiceID   string             `json:"service_id,omitempty"`
+	ServiceName string             `json:"service_name,omitempty"`
+	Incidents   int                `json:"incidents"`
+	Problems    []RecurringProblem `json:"problems"`
+}
+
+// RecurringProblemsReport is an organization's latest recurring problems report
+type RecurringProblemsReport struct {
+	OrganizationID    string                     `json:"organization_id"`
+	WindowStart       time.Time                  `json:"window_start"`
+	WindowEnd         time.Time                  `json:"window_end"`
+	IncidentsAnalyzed int                        `json:"incidents_analyzed"`
+	Services          []ServiceRecurringProblems `json:"services"`
+	GeneratedAt       time.Time                  `json:"generated_at"`
+}
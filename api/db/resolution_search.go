@@ -0,0 +1,25 @@
+package db
+
+import "time"
+
+// ResolutionSearchResult is a past incident whose fix matches a search
+type ResolutionSearchResult struct {
+	IncidentID     string     `json:"incident_id"`
+	Title          string     `json:"title"`
+	Status         string     `json:"status"`
+	Severity       string     `json:"severity,omitempty"`
+	ServiceName    string     `json:"service_name,omitempty"`
+	GroupName      string     `json:"group_name,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedByName string     `json:"resolved_by_name,omitempty"`
+
+	// Resolution notes in full; Snippet is the best-matching excerpt from
+	// the resolution, postmortem or notes with matches wrapped in **
+	Resolution string  `json:"resolution,omitempty"`
+	Snippet    string  `json:"snippet"`
+	Rank       float64 `json:"rank"`
+
+	IncidentURL   string `json:"incident_url"`
+	PostmortemURL string `json:"postmortem_url,omitempty"`
+}
@@ -0,0 +1,44 @@
+package db
+
+import "time"
+
+// DefaultRoutingKeyGraceMinutes is how long the previous routing key keeps
+// working after a rotation when the request doesn't say
+const DefaultRoutingKeyGraceMinutes = 24 * 60
+
+// ServiceRoutingKey is one of the routing keys a service accepts events on.
+// The primary key is the service's routing_key; the others are extra keys or
+// rotated-out keys still inside their grace period.
+type ServiceRoutingKey struct {
+	ID         string     `json:"id"`
+	ServiceID  string     `json:"service_id"`
+	RoutingKey string     `json:"routing_key"`
+	IsPrimary  bool       `json:"is_primary"`
+	Active     bool       `json:"active"` // not revoked and not expired
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UseCount   int64      `json:"use_count"`
+}
+
+// CreateServiceRoutingKeyRequest adds an extra key; an empty routing_key is generated
+type CreateServiceRoutingKeyRequest struct {
+	RoutingKey string     `json:"routing_key"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// RotateServiceRoutingKeyRequest replaces the primary key. The previous key
+// stays valid for GraceMinutes (default DefaultRoutingKeyGraceMinutes, 0 =
+// revoke immediately).
+type RotateServiceRoutingKeyRequest struct {
+	RoutingKey   string `json:"routing_key"`
+	GraceMinutes *int   `json:"grace_minutes,omitempty" binding:"omitempty,gte=0"`
+}
+
+// RoutingKeyRotation is the result of a rotation
+type RoutingKeyRotation struct {
+	RoutingKey ServiceRoutingKey `json:"routing_key"`
+	Previous   ServiceRoutingKey `json:"previous"`
+}
@@ -0,0 +1,70 @@
+package db
+
+import "time"
+
+// Look-ahead of a schedule impact preview, in hours
+const (
+	ScheduleImpactDefaultHours = 24
+	ScheduleImpactMaxHours     = 168
+)
+
+// Kinds of incident impact
+const (
+	ScheduleImpactCurrentAssignee = "current_assignee" // the assignee of an open incident goes off call
+	ScheduleImpactEscalation      = "escalation"       // an upcoming escalation would page someone else
+)
+
+// ScheduleImpactRequest describes unsaved shift and override changes of a
+// group. Nothing is written; the preview reports what the changes would do to
+// open incidents over the next Hours.
+type ScheduleImpactRequest struct {
+	Shifts    ShiftBatchRequest       `json:"shifts"`
+	Overrides ScheduleImpactOverrides `json:"overrides"`
+	Hours     int                     `json:"hours"`  // default ScheduleImpactDefaultHours
+	Notify    bool                    `json:"notify"` // DM the users whose incidents would move
+}
+
+// ScheduleImpactOverrides are the proposed override changes
+type ScheduleImpactOverrides struct {
+	Create []CreateScheduleOverrideRequest `json:"create"`
+	Delete []string                        `json:"delete"` // override IDs
+}
+
+// ScheduleImpact is the result of a schedule change preview
+type ScheduleImpact struct {
+	GroupID   string                   `json:"group_id"`
+	From      time.Time                `json:"from"`
+	To        time.Time                `json:"to"`
+	Changes   []ScheduleImpactChange   `json:"changes"`   // who is on call, before and after
+	Incidents []ScheduleImpactIncident `json:"incidents"` // open incidents that would change hands
+	Notified  []string                 `json:"notified"`  // user IDs sent a DM
+}
+
+// ScheduleImpactChange is a stretch of a scheduler where the on-call person
+// changes. An empty user means nobody in the group is on call.
+type ScheduleImpactChange struct {
+	SchedulerID   string    `json:"scheduler_id"`
+	SchedulerName string    `json:"scheduler_name"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	FromUserID    string    `json:"from_user_id"`
+	FromUserName  string    `json:"from_user_name"`
+	ToUserID      string    `json:"to_user_id"`
+	ToUserName    string    `json:"to_user_name"`
+}
+
+// ScheduleImpactIncident is an open incident whose assignee, or the person a
+// coming escalation level pages, would differ after the change. At is now for
+// current_assignee and the level's ETA for escalation.
+type ScheduleImpactIncident struct {
+	IncidentID   string    `json:"incident_id"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	Kind         string    `json:"kind"`
+	Level        int       `json:"level"`
+	At           time.Time `json:"at"`
+	FromUserID   string    `json:"from_user_id"`
+	FromUserName string    `json:"from_user_name"`
+	ToUserID     string    `json:"to_user_id"`
+	ToUserName   string    `json:"to_user_name"`
+}
@@ -0,0 +1,40 @@
+package db
+
+import "time"
+
+// RegenerateSchedulesRequest recomputes the future shifts of every scheduler
+// in a group that the departing user is on. With Preview nothing is written.
+type RegenerateSchedulesRequest struct {
+	UserID  string `json:"user_id" binding:"required"`
+	Preview bool   `json:"preview"`
+}
+
+// ShiftReassignment is one future shift moving to another member
+type ShiftReassignment struct {
+	ShiftID    string    `json:"shift_id"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	FromUserID string    `json:"from_user_id"`
+	ToUserID   string    `json:"to_user_id"`
+}
+
+// SchedulerRegeneration is the outcome for one affected scheduler. Members is
+// the remaining rotation order; SkippedReason is set when nobody is left to
+// take the departing user's shifts, in which case they are left untouched.
+type SchedulerRegeneration struct {
+	SchedulerID   string              `json:"scheduler_id"`
+	SchedulerName string              `json:"scheduler_name"`
+	Members       []string            `json:"members"`
+	Reassignments []ShiftReassignment `json:"reassignments"`
+	SkippedReason string              `json:"skipped_reason,omitempty"`
+}
+
+// ScheduleRegenerationResult describes a (previewed or applied) regeneration
+type ScheduleRegenerationResult struct {
+	GroupID          string                  `json:"group_id"`
+	UserID           string                  `json:"user_id"`
+	Preview          bool                    `json:"preview"`
+	Schedulers       []SchedulerRegeneration `json:"schedulers"`
+	ReassignedShifts int                     `json:"reassigned_shifts"`
+	GeneratedAt      time.Time               `json:"generated_at"`
+}
@@ -0,0 +1,44 @@
+package db
+
+import "time"
+
+// SchedulerManager is a user allowed to change one scheduler's shifts and
+// overrides without being an owner or admin of its group
+type SchedulerManager struct {
+	SchedulerID string    `json:"scheduler_id"`
+	UserID      string    `json:"user_id"`
+	UserName    string    `json:"user_name,omitempty"`
+	UserEmail   string    `json:"user_email,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AddSchedulerManagerRequest makes a group member a manager of a scheduler
+type AddSchedulerManagerRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// Shift swap request statuses
+const (
+	SwapRequestPending   = "pending"
+	SwapRequestApproved  = "approved"
+	SwapRequestRejected  = "rejected"
+	SwapRequestCancelled = "cancelled"
+)
+
+// SwapRequest is a member's request to swap one of their shifts with another
+// member's, waiting for a schedule manager to approve it
+type SwapRequest struct {
+	ID             string     `json:"id"`
+	GroupID        string     `json:"group_id"`
+	CurrentShiftID string     `json:"current_shift_id"`
+	TargetShiftID  string     `json:"target_shift_id"`
+	CurrentUserID  string     `json:"current_user_id"`
+	TargetUserID   string     `json:"target_user_id"`
+	Message        string     `json:"message,omitempty"`
+	Status         string     `json:"status"`
+	RequestedBy    string     `json:"requested_by"`
+	DecidedBy      string     `json:"decided_by,omitempty"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,113 @@
+package db
+
+import "time"
+
+// SCIM 2.0 schema URNs (RFC 7643 / RFC 7644)
+const (
+	SCIMSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMSchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMSchemaResourceType = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// SCIMToken is an org-scoped bearer token used by an IdP to call /scim/v2
+type SCIMToken struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	Name           string     `json:"name"`
+	IsActive       bool       `json:"is_active"`
+	CreatedBy      string     `json:"created_by,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Only populated on creation
+	Token string `json:"token,omitempty"`
+}
+
+// CreateSCIMTokenRequest for issuing a new SCIM token
+type CreateSCIMTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// SCIMMeta is the common "meta" attribute on SCIM resources
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// SCIMName is the SCIM user "name" complex attribute
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMMultiValued is a generic SCIM multi-valued attribute entry (emails, phoneNumbers, members, groups)
+type SCIMMultiValued struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMUser is the SCIM 2.0 User resource
+type SCIMUser struct {
+	Schemas      []string          `json:"schemas"`
+	ID           string            `json:"id,omitempty"`
+	ExternalID   string            `json:"externalId,omitempty"`
+	UserName     string            `json:"userName"`
+	Name         *SCIMName         `json:"name,omitempty"`
+	DisplayName  string            `json:"displayName,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Active       *bool             `json:"active,omitempty"`
+	Emails       []SCIMMultiValued `json:"emails,omitempty"`
+	PhoneNumbers []SCIMMultiValued `json:"phoneNumbers,omitempty"`
+	Groups       []SCIMMultiValued `json:"groups,omitempty"`
+	Meta         *SCIMMeta         `json:"meta,omitempty"`
+}
+
+// SCIMGroup is the SCIM 2.0 Group resource
+type SCIMGroup struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id,omitempty"`
+	ExternalID  string            `json:"externalId,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMMultiValued `json:"members,omitempty"`
+	Meta        *SCIMMeta         `json:"meta,omitempty"`
+}
+
+// SCIMListResponse wraps paginated SCIM query results
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMPatchOperation is a single operation within a PatchOp request
+type SCIMPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// SCIMPatchRequest is the SCIM PatchOp message body
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMError is the SCIM error response body
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
@@ -0,0 +1,34 @@
+package db
+
+import "time"
+
+// AlertMuteMaxDuration is the furthest ahead a mute may expire, so a
+// forgotten mute can't hide a rule for good
+const AlertMuteMaxDuration = 30 * 24 * time.Hour
+
+// ServiceAlertMute silences specific alerts on a service until it expires,
+// unlike a maintenance window which covers the whole service. An alert
+// matches when its name is one of AlertNames (any name if empty) and it
+// carries all of MatchLabels; matching alerts don't open incidents.
+type ServiceAlertMute struct {
+	ID          string            `json:"id"`
+	ServiceID   string            `json:"service_id"`
+	AlertNames  []string          `json:"alert_names"`
+	MatchLabels map[string]string `json:"match_labels"`
+	Reason      string            `json:"reason,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	MutedCount  int               `json:"muted_count"` // alerts dropped so far
+	LastMutedAt *time.Time        `json:"last_muted_at,omitempty"`
+	CreatedBy   string            `json:"created_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// CreateServiceAlertMuteRequest mutes alerts on a service. It needs at least
+// one alert name or label, and an expiry: ExpiresAt or DurationMinutes.
+type CreateServiceAlertMuteRequest struct {
+	AlertNames      []string          `json:"alert_names"`
+	MatchLabels     map[string]string `json:"match_labels"`
+	Reason          string            `json:"reason" binding:"max=500"`
+	ExpiresAt       *time.Time        `json:"expires_at"`
+	DurationMinutes int               `json:"duration_minutes" binding:"min=0"`
+}
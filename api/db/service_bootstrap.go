@@ -0,0 +1,48 @@
+package db
+
+import "time"
+
+// Service bootstrap defaults
+const (
+	BootstrapDefaultRotationWeeks   = 12
+	BootstrapMaxRotationWeeks       = 52
+	BootstrapDefaultTimeoutMinutes  = 5
+	BootstrapDefaultIntegrationType = "webhook"
+)
+
+// BootstrapServiceRequest sets up a new team's service in one call: an on-call
+// scheduler with a weekly rotation, an escalation policy paging it, the service
+// itself and an integration routing alerts to it.
+type BootstrapServiceRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	RoutingKey  string `json:"routing_key" binding:"required"`
+
+	// Weekly rotation; members hand off in the order given
+	Members       []string   `json:"members" binding:"required,min=1,dive,required"` // user IDs
+	RotationStart *time.Time `json:"rotation_start,omitempty"`                       // default: start of the next hour
+	RotationWeeks int        `json:"rotation_weeks,omitempty"`                       // weeks of shifts to generate (default 12, max 52)
+
+	// Minutes the on-call user has to acknowledge before the whole group is paged
+	AckTimeoutMinutes int `json:"ack_timeout_minutes,omitempty" binding:"omitempty,min=1"`
+
+	// Link an existing integration, or create one of IntegrationType (default "webhook")
+	IntegrationID   string `json:"integration_id,omitempty"`
+	IntegrationType string `json:"integration_type,omitempty"`
+
+	// Tenant isolation (required for multi-tenant)
+	OrganizationID string `json:"organization_id,omitempty"`
+	ProjectID      string `json:"project_id,omitempty"`
+}
+
+// BootstrapServiceResult is everything created by a service bootstrap
+type BootstrapServiceResult struct {
+	Service          Service          `json:"service"`
+	EscalationPolicy EscalationPolicy `json:"escalation_policy"`
+	Scheduler        Scheduler        `json:"scheduler"`
+	Integration      Integration      `json:"integration"`
+	WebhookURL       string           `json:"webhook_url"`
+
+	// True when the new policy became the group's default escalation policy
+	GroupDefaultPolicySet bool `json:"group_default_policy_set"`
+}
@@ -0,0 +1,69 @@
+package db
+
+import "time"
+
+// Service calendar event types
+const (
+	CalendarEventMaintenance = "maintenance"
+	CalendarEventOnCallShift = "oncall_shift"
+	CalendarEventIncident    = "incident"
+)
+
+// Service calendar range defaults
+const (
+	CalendarDefaultPastDays   = 7
+	CalendarDefaultFutureDays = 14
+	CalendarMaxRangeDays      = 90
+)
+
+// MaintenanceWindow is scheduled maintenance on a service
+type MaintenanceWindow struct {
+	ID          string    `json:"id"`
+	ServiceID   string    `json:"service_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateMaintenanceWindowRequest schedules maintenance on a service
+type CreateMaintenanceWindowRequest struct {
+	Title       string    `json:"title" binding:"required"`
+	Description string    `json:"description"`
+	StartTime   time.Time `json:"start_time" binding:"required"`
+	EndTime     time.Time `json:"end_time" binding:"required,gtfield=StartTime"`
+}
+
+// ServiceCalendarEvent is one entry on a service's calendar. Incidents that are
+// still open have no end time.
+type ServiceCalendarEvent struct {
+	Type      string     `json:"type"` // maintenance, oncall_shift, incident
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+
+	// On-call shifts: the person on call (after overrides) and their scheduler
+	UserID        string `json:"user_id,omitempty"`
+	UserName      string `json:"user_name,omitempty"`
+	SchedulerName string `json:"scheduler_name,omitempty"`
+	IsOverride    bool   `json:"is_override,omitempty"`
+
+	// Incidents
+	Status   string `json:"status,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Urgency  string `json:"urgency,omitempty"`
+}
+
+// ServiceCalendar is a service's combined timeline over [From, To]
+type ServiceCalendar struct {
+	ServiceID string                 `json:"service_id"`
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Events    []ServiceCalendarEvent `json:"events"`
+
+	// The organization's timezone, formats and week start to render it with
+	Display OrgDisplaySettings `json:"display"`
+}
@@ -0,0 +1,26 @@
+package db
+
+import "time"
+
+// ServiceSeverityMapping maps a technical alert severity to the business
+// priority and/or urgency of incidents opened on a service. Severities
+// without a mapping keep the global defaults.
+type ServiceSeverityMapping struct {
+	ServiceID string    `json:"service_id"`
+	Severity  string    `json:"severity"`
+	Priority  string    `json:"priority,omitempty"` // P1-P5
+	Urgency   string    `json:"urgency,omitempty"`  // high, low
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SeverityMappingInput is one mapping in a SetSeverityMappingsRequest
+type SeverityMappingInput struct {
+	Severity string `json:"severity" binding:"required"`
+	Priority string `json:"priority" binding:"omitempty,oneof=P1 P2 P3 P4 P5"`
+	Urgency  string `json:"urgency" binding:"omitempty,oneof=high low"`
+}
+
+// SetSeverityMappingsRequest replaces a service's severity mappings
+type SetSeverityMappingsRequest struct {
+	Mappings []SeverityMappingInput `json:"mappings" binding:"dive"`
+}
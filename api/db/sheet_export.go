@@ -0,0 +1,70 @@
+package db
+
+import "time"
+
+// Sheet export sinks
+const (
+	SheetSinkGoogleSheets = "google_sheets" // appended through the Sheets API with a service account
+	SheetSinkCSVWebhook   = "csv_webhook"   // posted as a text/csv document with a header row
+)
+
+const (
+	// SheetExportDefaultSheetName is the tab rows go to when none is set
+	SheetExportDefaultSheetName = "Sheet1"
+
+	// SheetExportBatchSize bounds the incidents exported per export and run
+	SheetExportBatchSize = 200
+)
+
+// SheetExportColumns is the header row; each exported incident is one row
+// with these columns
+var SheetExportColumns = []string{
+	"Incident", "Title", "Severity", "Urgency", "Service", "Assigned to", "Resolved by",
+	"Triggered at (UTC)", "Acknowledged at (UTC)", "Resolved at (UTC)",
+	"Time to acknowledge (min)", "Time to resolve (min)", "Link",
+}
+
+// GroupSheetExport appends a group's resolved incidents to a Google Sheet or
+// a CSV webhook, for teams that track incidents in spreadsheets. Credentials
+// and the webhook URL are never returned by the API; share the sheet with
+// ServiceAccountEmail to let the export write to it.
+type GroupSheetExport struct {
+	ID                  string     `json:"id"`
+	GroupID             string     `json:"group_id"`
+	SinkType            string     `json:"sink_type"` // google_sheets or csv_webhook
+	Name                string     `json:"name"`
+	SpreadsheetID       string     `json:"spreadsheet_id,omitempty"`
+	SheetName           string     `json:"sheet_name,omitempty"`
+	Credentials         string     `json:"-"` // service account key JSON
+	ServiceAccountEmail string     `json:"service_account_email,omitempty"`
+	WebhookURL          string     `json:"-"`
+	IsActive            bool       `json:"is_active"`
+	ExportedCount       int64      `json:"exported_count"`
+	LastExportedAt      *time.Time `json:"last_exported_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	CreatedBy           string     `json:"created_by,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// CreateGroupSheetExportRequest adds a sheet export to a group. Google Sheets
+// exports need spreadsheet_id and credentials; CSV webhook exports webhook_url.
+type CreateGroupSheetExportRequest struct {
+	SinkType      string `json:"sink_type" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	SpreadsheetID string `json:"spreadsheet_id"`
+	SheetName     string `json:"sheet_name"`
+	Credentials   string `json:"credentials"`
+	WebhookURL    string `json:"webhook_url"`
+}
+
+// UpdateGroupSheetExportRequest changes a sheet export; empty secrets are kept
+type UpdateGroupSheetExportRequest struct {
+	Name          *string `json:"name"`
+	SpreadsheetID *string `json:"spreadsheet_id"`
+	SheetName     *string `json:"sheet_name"`
+	Credentials   *string `json:"credentials"`
+	WebhookURL    *string `json:"webhook_url"`
+	IsActive      *bool   `json:"is_active"`
+}
@@ -0,0 +1,66 @@
+package db
+
+import "time"
+
+// ShiftBatchMaxOperations caps how many shifts one batch may touch
+const ShiftBatchMaxOperations = 500
+
+// Error codes of a rejected shift batch operation
+const (
+	ShiftBatchErrorInvalid  = "invalid"   // the operation itself is malformed
+	ShiftBatchErrorNotFound = "not_found" // no active shift with that ID in the group
+	ShiftBatchErrorConflict = "conflict"  // the shift changed since the client read it
+	ShiftBatchErrorOverlap  = "overlap"   // the user would be on two shifts at once
+)
+
+// ShiftBatchRequest creates, updates and deletes shifts of a group in one
+// transaction; either every operation applies or none does. Updates and
+// deletes carry the updated_at the client last saw, and are rejected if the
+// shift has changed since.
+type ShiftBatchRequest struct {
+	Create []ShiftBatchCreate `json:"create" binding:"dive"`
+	Update []ShiftBatchUpdate `json:"update" binding:"dive"`
+	Delete []ShiftBatchDelete `json:"delete" binding:"dive"`
+}
+
+// ShiftBatchCreate is a new manual shift
+type ShiftBatchCreate struct {
+	SchedulerID   string    `json:"scheduler_id" binding:"required"`
+	UserID        string    `json:"user_id" binding:"required"`
+	StartTime     time.Time `json:"start_time" binding:"required"`
+	EndTime       time.Time `json:"end_time" binding:"required"`
+	ServiceID     *string   `json:"service_id,omitempty"`
+	ScheduleScope string    `json:"schedule_scope"` // 'group' (default) or 'service'
+}
+
+// ShiftBatchUpdate moves or reassigns a shift; omitted fields keep their value
+type ShiftBatchUpdate struct {
+	ID        string     `json:"id" binding:"required"`
+	UpdatedAt time.Time  `json:"updated_at" binding:"required"`
+	UserID    *string    `json:"user_id,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+}
+
+// ShiftBatchDelete removes a shift
+type ShiftBatchDelete struct {
+	ID        string    `json:"id" binding:"required"`
+	UpdatedAt time.Time `json:"updated_at" binding:"required"`
+}
+
+// ShiftBatchResult is the outcome of an applied batch
+type ShiftBatchResult struct {
+	Created []Shift  `json:"created"`
+	Updated []Shift  `json:"updated"`
+	Deleted []string `json:"deleted"`
+}
+
+// ShiftBatchItemError explains why one operation of a batch was rejected.
+// Op is create, update or delete and Index its position in that list.
+type ShiftBatchItemError struct {
+	Op      string `json:"op"`
+	Index   int    `json:"index"`
+	ShiftID string `json:"shift_id,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,62 @@
+package db
+
+import "time"
+
+// SIEMIntegrationType is the integration type that streams an organization's
+// incident and audit events to a SIEM. Its config holds:
+//
+//	provider     splunk_hec, elastic or syslog
+//	url          Splunk HEC or Elasticsearch base URL, or tcp://host:port or
+//	             udp://host:port for syslog
+//	token        HEC token, or Elasticsearch API key; not used by syslog
+//	index        optional: Splunk or Elasticsearch index
+//	format       json (default) or cef
+//	event_types  optional: only export these event types, e.g. "acknowledged"
+//	             or "tool.executed"
+const SIEMIntegrationType = "siem"
+
+const (
+	SIEMProviderSplunkHEC = "splunk_hec"
+	SIEMProviderElastic   = "elastic"
+	SIEMProviderSyslog    = "syslog"
+)
+
+const (
+	SIEMFormatJSON = "json"
+	SIEMFormatCEF  = "cef"
+)
+
+// SIEM event categories: incident lifecycle (incident_events) and audit
+// (agent_audit_logs)
+const (
+	SIEMCategoryIncident = "incident"
+	SIEMCategoryAudit    = "audit"
+)
+
+const (
+	// SIEMDefaultElasticIndex is used when an Elasticsearch integration sets no index
+	SIEMDefaultElasticIndex = "slar-events"
+
+	// SIEMExportBatchSize bounds the events read from each source per run
+	SIEMExportBatchSize = 500
+)
+
+// SIEMEvent is one exported event; the incident fields are set for incident
+// events only
+type SIEMEvent struct {
+	ID             string                 `json:"id"`
+	Category       string                 `json:"category"`
+	Type           string                 `json:"type"`
+	Time           time.Time              `json:"time"`
+	OrganizationID string                 `json:"organization_id"`
+	IncidentID     string                 `json:"incident_id,omitempty"`
+	IncidentNumber int64                  `json:"incident_number,omitempty"`
+	IncidentTitle  string                 `json:"incident_title,omitempty"`
+	IncidentStatus string                 `json:"incident_status,omitempty"`
+	Severity       string                 `json:"severity,omitempty"`
+	ActorID        string                 `json:"actor_id,omitempty"`
+	ActorName      string                 `json:"actor_name,omitempty"`
+	SourceIP       string                 `json:"source_ip,omitempty"`
+	Outcome        string                 `json:"outcome,omitempty"` // audit: success, failure, pending
+	Data           map[string]interface{} `json:"data,omitempty"`
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"math"
+	"time"
+)
+
+// SLO sources: where the SLI comes from
+const (
+	SLOSourceIncidents = "incidents" // time covered by the service's high-urgency incidents counts as bad
+	SLOSourceMonitor   = "monitor"   // share of failed checks of the linked uptime monitor
+)
+
+// SLOBurnRateWindow is the lookback used for burn-rate alerting
+const SLOBurnRateWindow = time.Hour
+
+// IncidentSourceSLO is the incident source for burn-rate incidents
+const IncidentSourceSLO = "slo"
+
+// ServiceSLO is an availability target for a service over a rolling window
+type ServiceSLO struct {
+	ID                string     `json:"id"`
+	ServiceID         string     `json:"service_id"`
+	Name              string     `json:"name"`
+	Target            float64    `json:"target"` // percent, e.g. 99.9
+	WindowDays        int        `json:"window_days"`
+	Source            string     `json:"source"` // incidents, monitor
+	MonitorID         string     `json:"monitor_id,omitempty"`
+	BurnRateThreshold *float64   `json:"burn_rate_threshold,omitempty"` // open an incident above this 1h burn rate
+	IsActive          bool       `json:"is_active"`
+	CreatedBy         string     `json:"created_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Status            *SLOStatus `json:"status,omitempty"`
+}
+
+// SLOStatus is the error budget position of an SLO at a point in time
+type SLOStatus struct {
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+
+	// Measured availability over the window, in percent
+	SLI float64 `json:"sli"`
+
+	// Share of the error budget used (1 = exhausted; can exceed 1) and left
+	BudgetConsumed  float64 `json:"budget_consumed"`
+	BudgetRemaining float64 `json:"budget_remaining"`
+
+	// Allowed and actual bad minutes (incident-backed SLOs)
+	BudgetMinutes float64 `json:"budget_minutes,omitempty"`
+	BadMinutes    float64 `json:"bad_minutes,omitempty"`
+
+	// Check counts (monitor-backed SLOs)
+	TotalChecks int `json:"total_checks,omitempty"`
+	GoodChecks  int `json:"good_checks,omitempty"`
+
+	// Error rate over SLOBurnRateWindow relative to the budget: 1 exhausts the
+	// budget exactly at the end of the window, 14.4 in about two days for a 30-day SLO
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// NewSLOStatus derives budget figures from the error rate over the window and
+// over the burn-rate window
+func NewSLOStatus(target float64, windowStart, windowEnd time.Time, errorRate, burnErrorRate float64) SLOStatus {
+	st := SLOStatus{
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+		SLI:         roundTo((1-errorRate)*100, 4),
+	}
+	if budget := 1 - target/100; budget > 0 {
+		st.BudgetConsumed = roundTo(errorRate/budget, 4)
+		st.BurnRate = roundTo(burnErrorRate/budget, 2)
+	}
+	st.BudgetRemaining = roundTo(1-st.BudgetConsumed, 4)
+	return st
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+type CreateServiceSLORequest struct {
+	Name              string   `json:"name" binding:"required"`
+	Target            float64  `json:"target" binding:"required,gt=0,lt=100"`
+	WindowDays        int      `json:"window_days" binding:"omitempty,min=1,max=90"`
+	Source            string   `json:"source" binding:"omitempty,oneof=incidents monitor"`
+	MonitorID         string   `json:"monitor_id"`
+	BurnRateThreshold *float64 `json:"burn_rate_threshold,omitempty" binding:"omitempty,gt=0"`
+}
+
+type UpdateServiceSLORequest struct {
+	Name              *string  `json:"name,omitempty"`
+	Target            *float64 `json:"target,omitempty" binding:"omitempty,gt=0,lt=100"`
+	WindowDays        *int     `json:"window_days,omitempty" binding:"omitempty,min=1,max=90"`
+	BurnRateThreshold *float64 `json:"burn_rate_threshold,omitempty" binding:"omitempty,gte=0"` // 0 disables burn-rate incidents
+	IsActive          *bool    `json:"is_active,omitempty"`
+}
@@ -0,0 +1,45 @@
+package db
+
+import "time"
+
+const (
+	// SMSVerificationCodeTTL is how long a user has to text the code back
+	SMSVerificationCodeTTL = 15 * time.Minute
+
+	// SMSIncidentRefLength is how much of an incident ID is shown as its SMS
+	// reference; replies may use any prefix of at least SMSIncidentRefMinLength
+	SMSIncidentRefLength    = 8
+	SMSIncidentRefMinLength = 4
+)
+
+// SMSPhone is the number a user texts incident commands from
+type SMSPhone struct {
+	UserID        string     `json:"user_id"`
+	PhoneNumber   string     `json:"phone_number"`
+	Verified      bool       `json:"verified"`
+	VoiceEnabled  bool       `json:"voice_enabled"` // also called when paged
+	VerifiedAt    *time.Time `json:"verified_at,omitempty"`
+	CodeExpiresAt *time.Time `json:"code_expires_at,omitempty"`
+	LastInboundAt *time.Time `json:"last_inbound_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// SetSMSPhoneRequest starts linking a number to the current user
+type SetSMSPhoneRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+// SMSPhoneVerification tells the user what to text, and where, to prove
+// they hold the number
+type SMSPhoneVerification struct {
+	Phone     *SMSPhone `json:"phone"`
+	Code      string    `json:"code"`
+	SendTo    string    `json:"send_to"`
+	Message   string    `json:"message"` // the exact text to send, e.g. "VERIFY 123456"
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SetVoiceCallsRequest turns phone-call pages to the user's verified number on or off
+type SetVoiceCallsRequest struct {
+	Enabled bool `json:"enabled"`
+}
@@ -22,6 +22,12 @@ const (
 	SystemUserAPI = "00000000-0000-0000-0000-000000000006"
 )
 
+// SystemUserIDs lists every system user, e.g. to exclude automated actions
+var SystemUserIDs = []string{
+	SystemUserPrometheus, SystemUserDatadog, SystemUserGrafana,
+	SystemUserAWS, SystemUserWebhook, SystemUserAPI,
+}
+
 // GetSystemUserBySource returns the appropriate system user ID based on alert source
 func GetSystemUserBySource(source string) string {
 	switch source {
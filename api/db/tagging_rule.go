@@ -0,0 +1,82 @@
+package db
+
+import "time"
+
+// Incident fields a tagging condition can look at
+const (
+	TaggingFieldTitle       = "title"
+	TaggingFieldDescription = "description"
+	TaggingFieldLabel       = "label"
+)
+
+// How a tagging condition matches its pattern
+const (
+	TaggingMatchKeyword = "keyword" // case-insensitive substring
+	TaggingMatchRegex   = "regex"   // RE2 syntax; add (?i) for case-insensitive
+)
+
+// Limits on an organization's tagging rules
+const (
+	TaggingMaxRules         = 100
+	TaggingMaxConditions    = 10
+	TaggingMaxPatternLength = 500
+	TaggingMaxTagLength     = 64
+)
+
+// TaggingCondition matches one field of a new incident. For the label field,
+// Label names the label; left empty, any label value may match.
+type TaggingCondition struct {
+	Field   string `json:"field" binding:"required,oneof=title description label"`
+	Label   string `json:"label,omitempty"`
+	Match   string `json:"match" binding:"required,oneof=keyword regex"`
+	Pattern string `json:"pattern" binding:"required"`
+}
+
+// IncidentTaggingRule tags new incidents of an organization whose title,
+// description or labels match all of its conditions
+type IncidentTaggingRule struct {
+	ID             string             `json:"id"`
+	OrganizationID string             `json:"organization_id"`
+	Name           string             `json:"name"`
+	Position       int                `json:"position"`
+	Conditions     []TaggingCondition `json:"conditions"`
+	AddTags        []string           `json:"add_tags,omitempty"`
+	SetTeam        string             `json:"set_team,omitempty"`
+	SetComponent   string             `json:"set_component,omitempty"`
+	Stop           bool               `json:"stop"` // later rules are skipped when this one matches
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+// TaggingRuleInput is one rule in a SetTaggingRulesRequest
+type TaggingRuleInput struct {
+	Name         string             `json:"name" binding:"required"`
+	Conditions   []TaggingCondition `json:"conditions" binding:"required,min=1,dive"`
+	AddTags      []string           `json:"add_tags"`
+	SetTeam      string             `json:"set_team"`
+	SetComponent string             `json:"set_component"`
+	Stop         bool               `json:"stop"`
+}
+
+// SetTaggingRulesRequest replaces an organization's tagging rules; order is
+// evaluation order
+type SetTaggingRulesRequest struct {
+	Rules []TaggingRuleInput `json:"rules" binding:"dive"`
+}
+
+// TestTaggingRulesRequest runs tagging rules against a sample incident
+// without creating it. With Rules set, those are tried instead of the saved
+// ones, so changes can be checked before saving.
+type TestTaggingRulesRequest struct {
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Labels      map[string]interface{} `json:"labels"`
+	Rules       []TaggingRuleInput     `json:"rules" binding:"omitempty,dive"`
+}
+
+// TaggingResult is what tagging rules did to an incident
+type TaggingResult struct {
+	Tags      []string `json:"tags"`
+	Team      string   `json:"team,omitempty"`
+	Component string   `json:"component,omitempty"`
+	Rules     []string `json:"rules"` // names of the matching rules, in order
+}
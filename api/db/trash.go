@@ -0,0 +1,24 @@
+package db
+
+import "time"
+
+// Resource types that go to the trash when deleted
+const (
+	TrashResourceService          = "service"
+	TrashResourceScheduler        = "scheduler"
+	TrashResourceEscalationPolicy = "escalation_policy"
+	TrashResourceIntegration      = "integration"
+)
+
+// TrashItem is a deleted resource that can still be restored until PurgeAt
+type TrashItem struct {
+	ID             string    `json:"id"`
+	ResourceType   string    `json:"resource_type"`
+	ResourceID     string    `json:"resource_id"`
+	OrganizationID string    `json:"organization_id"`
+	Name           string    `json:"name"`
+	DeletedBy      string    `json:"deleted_by,omitempty"`
+	DeletedByName  string    `json:"deleted_by_name,omitempty"`
+	DeletedAt      time.Time `json:"deleted_at"`
+	PurgeAt        time.Time `json:"purge_at"`
+}
@@ -0,0 +1,49 @@
+package db
+
+import "time"
+
+// ServiceUrgencyRule recomputes an open incident's urgency and priority when
+// more alerts join it or its severity changes. A rule matches once the
+// incident has at least MinAlertCount alerts and, if Severities is set, its
+// current severity is one of them.
+type ServiceUrgencyRule struct {
+	ID            string    `json:"id"`
+	ServiceID     string    `json:"service_id"`
+	Name          string    `json:"name"`
+	Position      int       `json:"position"`
+	MinAlertCount int       `json:"min_alert_count,omitempty"`
+	Severities    []string  `json:"severities,omitempty"`
+	SetUrgency    string    `json:"set_urgency,omitempty"`  // high, low
+	SetPriority   string    `json:"set_priority,omitempty"` // P1-P5
+	Repage        bool      `json:"repage"`                 // re-page the assignee when urgency is raised
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UrgencyRuleInput is one rule in a SetUrgencyRulesRequest
+type UrgencyRuleInput struct {
+	Name          string   `json:"name" binding:"required"`
+	MinAlertCount int      `json:"min_alert_count" binding:"min=0"`
+	Severities    []string `json:"severities"`
+	SetUrgency    string   `json:"set_urgency" binding:"omitempty,oneof=high low"`
+	SetPriority   string   `json:"set_priority" binding:"omitempty,oneof=P1 P2 P3 P4 P5"`
+	Repage        bool     `json:"repage"`
+}
+
+// SetUrgencyRulesRequest replaces a service's urgency rules; order is evaluation order
+type SetUrgencyRulesRequest struct {
+	Rules []UrgencyRuleInput `json:"rules" binding:"dive"`
+}
+
+// UrgencyChange describes how an incident changed when an alert joined it
+type UrgencyChange struct {
+	IncidentID   string `json:"incident_id"`
+	AlertCount   int    `json:"alert_count"`
+	FromSeverity string `json:"from_severity,omitempty"`
+	ToSeverity   string `json:"to_severity,omitempty"`
+	FromUrgency  string `json:"from_urgency"`
+	ToUrgency    string `json:"to_urgency"`
+	FromPriority string `json:"from_priority,omitempty"`
+	ToPriority   string `json:"to_priority,omitempty"`
+	Rule         string `json:"rule,omitempty"` // matching rule, empty for severity-based changes
+	Repaged      bool   `json:"repaged"`
+}
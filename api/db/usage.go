@@ -0,0 +1,29 @@
+package db
+
+import "time"
+
+// Months of usage returned when none are asked for, and the most allowed
+const (
+	OrgUsageDefaultMonths = 12
+	OrgUsageMaxMonths     = 36
+)
+
+// OrgUsageMonth is an organization's metered usage for one calendar month (UTC)
+type OrgUsageMonth struct {
+	Month time.Time `json:"month"` // first day of the month
+	// Most org members and active services seen while the month was metered
+	PeakUsers    int `json:"peak_users"`
+	PeakServices int `json:"peak_services"`
+	// Incidents opened during the month, test incidents excluded
+	Incidents int `json:"incidents"`
+	// Notifications sent during the month by channel
+	Notifications      map[string]int `json:"notifications"`
+	NotificationsTotal int            `json:"notifications_total"`
+	UpdatedAt          *time.Time     `json:"updated_at,omitempty"` // nil for months never metered
+}
+
+// OrgUsage is an organization's monthly usage, newest month first
+type OrgUsage struct {
+	OrganizationID string          `json:"organization_id"`
+	Months         []OrgUsageMonth `json:"months"`
+}
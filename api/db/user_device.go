@@ -0,0 +1,38 @@
+package db
+
+import "time"
+
+const (
+	// UserDeviceMaxFailures is how many sends in a row may fail for other
+	// reasons than an unregistered token before the device is pruned
+	UserDeviceMaxFailures = 10
+
+	// UserDeviceStaleAfter is how long a device may go without registering
+	// again; FCM expires tokens of apps inactive for 270 days
+	UserDeviceStaleAfter = 270 * 24 * time.Hour
+)
+
+// UserDevice is an app installation registered for push notifications
+// through FCM. The token is never returned by the API.
+type UserDevice struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"user_id"`
+	FCMToken        string     `json:"-"`
+	Platform        string     `json:"platform,omitempty"`
+	AppVersion      string     `json:"app_version,omitempty"`
+	DeviceName      string     `json:"device_name,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastSeenAt      time.Time  `json:"last_seen_at"`
+	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
+	LastValidatedAt *time.Time `json:"last_validated_at,omitempty"`
+	FailureCount    int        `json:"failure_count"`
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+// RegisterDeviceRequest registers (or refreshes) the calling app installation
+type RegisterDeviceRequest struct {
+	FCMToken   string `json:"fcm_token" binding:"required"`
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+	DeviceName string `json:"device_name"`
+}
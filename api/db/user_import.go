@@ -0,0 +1,50 @@
+package db
+
+// User import limits and defaults
+const (
+	UserImportMaxRows     = 5000
+	UserImportMaxBytes    = 5 << 20
+	UserImportDefaultRole = "engineer"
+	UserImportDefaultTeam = "Default Team"
+)
+
+// User import row outcomes
+const (
+	UserImportStatusCreated   = "created"
+	UserImportStatusUpdated   = "updated"
+	UserImportStatusUnchanged = "unchanged"
+	UserImportStatusAdded     = "added" // existing account from outside the org, profile left as is
+	UserImportStatusInvalid   = "invalid"
+)
+
+// UserImportRow is one parsed CSV row (name, email, team, role, phone)
+type UserImportRow struct {
+	Line  int    `json:"line"` // line number in the CSV; the header is line 1
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Team  string `json:"team"`
+	Role  string `json:"role"`
+	Phone string `json:"phone"`
+}
+
+// UserImportRowResult is the outcome of importing one CSV row
+type UserImportRowResult struct {
+	Line           int      `json:"line"`
+	Email          string   `json:"email,omitempty"`
+	UserID         string   `json:"user_id,omitempty"`
+	Status         string   `json:"status"` // created, updated, unchanged, added, invalid
+	Errors         []string `json:"errors,omitempty"`
+	InvitationSent bool     `json:"invitation_sent,omitempty"`
+}
+
+// UserImportResult summarizes a CSV user import
+type UserImportResult struct {
+	DryRun    bool                  `json:"dry_run"`
+	Total     int                   `json:"total"`
+	Created   int                   `json:"created"`
+	Updated   int                   `json:"updated"`
+	Unchanged int                   `json:"unchanged"`
+	Added     int                   `json:"added"`
+	Invalid   int                   `json:"invalid"`
+	Rows      []UserImportRowResult `json:"rows"`
+}
@@ -0,0 +1,32 @@
+package db
+
+import "time"
+
+// Working hours used to count after-hours pages, in the user's notification
+// timezone. Weekends are after hours all day.
+const (
+	WorkdayStartHour = 9
+	WorkdayEndHour   = 18
+
+	UserIncidentStatsDefaultDays = 30
+	UserIncidentStatsMaxDays     = 366
+)
+
+// UserIncidentStats summarizes a user's incident participation in an
+// organization over a period. Test incidents are not counted.
+type UserIncidentStats struct {
+	UserID         string    `json:"user_id"`
+	OrganizationID string    `json:"organization_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Timezone       string    `json:"timezone"`
+
+	Acknowledged int `json:"acknowledged"`
+	Resolved     int `json:"resolved"`
+	// Mean time from incident creation to this user's acknowledgement
+	AvgResponseSeconds *float64 `json:"avg_response_seconds"`
+
+	Pages               int `json:"pages"`
+	AfterHoursPages     int `json:"after_hours_pages"`
+	EscalationsReceived int `json:"escalations_received"`
+}
@@ -0,0 +1,34 @@
+package db
+
+import "time"
+
+// Voice call outcomes, recorded on the call and as a voice_call incident event
+const (
+	VoiceOutcomeAcknowledged     = "acknowledged"      // pressed 1
+	VoiceOutcomeEscalated        = "escalated"         // pressed 2
+	VoiceOutcomeEscalationFailed = "escalation_failed" // pressed 2, but there was no level to escalate to
+	VoiceOutcomeNoInput          = "no_input"          // answered without choosing an option
+	VoiceOutcomeUnanswered       = "unanswered"        // busy, no answer or canceled
+	VoiceOutcomeFailed           = "failed"            // the call could not be placed
+)
+
+// IVR menu keys
+const (
+	VoiceDigitAcknowledge = "1"
+	VoiceDigitEscalate    = "2"
+)
+
+// VoiceCall is one phone-call page to a user
+type VoiceCall struct {
+	ID               string    `json:"id"`
+	IncidentID       string    `json:"incident_id"`
+	UserID           string    `json:"user_id"`
+	NotificationType string    `json:"notification_type"`
+	CallSID          string    `json:"call_sid,omitempty"`
+	Status           string    `json:"status"` // Twilio call status
+	Outcome          string    `json:"outcome,omitempty"`
+	Digits           string    `json:"digits,omitempty"`
+	DurationSeconds  int       `json:"duration_seconds,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,92 @@
+package db
+
+import "time"
+
+// WallboardToken is a read-only, org-scoped token for NOC/TV displays.
+// If ProjectID is set the wallboard only shows that project.
+type WallboardToken struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	ProjectID      string     `json:"project_id,omitempty"`
+	Name           string     `json:"name"`
+	IsActive       bool       `json:"is_active"`
+	CreatedBy      string     `json:"created_by,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Only populated on creation
+	Token string `json:"token,omitempty"`
+}
+
+// CreateWallboardTokenRequest for issuing a new wallboard token
+type CreateWallboardTokenRequest struct {
+	Name      string `json:"name" binding:"required"`
+	ProjectID string `json:"project_id"`
+}
+
+// WallboardIncident is the trimmed incident shape shown on a wallboard
+type WallboardIncident struct {
+	ID             string    `json:"id"`
+	Title          string    `json:"title"`
+	Status         string    `json:"status"`
+	Urgency        string    `json:"urgency"`
+	Severity       string    `json:"severity"`
+	ServiceName    string    `json:"service_name,omitempty"`
+	GroupName      string    `json:"group_name,omitempty"`
+	AssignedToName string    `json:"assigned_to_name,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WallboardSeverityGroup is the open incidents sharing one severity
+type WallboardSeverityGroup struct {
+	Severity  string              `json:"severity"`
+	Count     int                 `json:"count"`
+	Incidents []WallboardIncident `json:"incidents"`
+}
+
+// WallboardIncidents groups open incidents by severity, most severe first
+type WallboardIncidents struct {
+	Total        int                      `json:"total"`
+	Triggered    int                      `json:"triggered"`
+	Acknowledged int                      `json:"acknowledged"`
+	BySeverity   []WallboardSeverityGroup `json:"by_severity"`
+}
+
+// WallboardOnCall is one group's current on-call responder
+type WallboardOnCall struct {
+	GroupID    string     `json:"group_id"`
+	GroupName  string     `json:"group_name"`
+	UserName   string     `json:"user_name,omitempty"`
+	UserEmail  string     `json:"user_email,omitempty"`
+	ShiftEnd   *time.Time `json:"shift_end,omitempty"`
+	IsOverride bool       `json:"is_override"`
+}
+
+// WallboardMonitor is the last known state of an uptime monitor
+type WallboardMonitor struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	GroupName   string     `json:"group_name,omitempty"`
+	IsUp        *bool      `json:"is_up"`
+	LastLatency *int       `json:"last_latency,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastCheckAt *time.Time `json:"last_check_at,omitempty"`
+}
+
+// WallboardMonitors summarizes monitor health
+type WallboardMonitors struct {
+	Total    int                `json:"total"`
+	Up       int                `json:"up"`
+	Down     int                `json:"down"`
+	Unknown  int                `json:"unknown"`
+	Monitors []WallboardMonitor `json:"monitors"`
+}
+
+// Wallboard is the full bundle returned to a NOC display in one request
+type Wallboard struct {
+	GeneratedAt            time.Time          `json:"generated_at"`
+	RefreshIntervalSeconds int                `json:"refresh_interval_seconds"`
+	Incidents              WallboardIncidents `json:"incidents"`
+	OnCall                 []WallboardOnCall  `json:"oncall"`
+	Monitors               WallboardMonitors  `json:"monitors"`
+}
@@ -0,0 +1,32 @@
+package db
+
+import "time"
+
+// WebPushSubscription is a browser registered to receive incident pages
+// through the Push API. Keys and the endpoint are never returned by the API.
+type WebPushSubscription struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Endpoint   string     `json:"-"`
+	P256dh     string     `json:"-"`
+	Auth       string     `json:"-"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// WebPushSubscribeRequest is the browser's PushSubscription.toJSON() plus the
+// user agent, used to tell a user's browsers apart
+type WebPushSubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+	UserAgent string `json:"user_agent"`
+}
+
+// WebPushUnsubscribeRequest removes a browser's subscription
+type WebPushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
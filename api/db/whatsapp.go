@@ -0,0 +1,38 @@
+package db
+
+import "time"
+
+// WhatsApp consent states
+const (
+	WhatsAppOptedIn  = "opted_in"
+	WhatsAppOptedOut = "opted_out"
+)
+
+// WhatsApp consent sources
+const (
+	WhatsAppSourceWeb      = "web"      // the user's notification settings
+	WhatsAppSourceWhatsApp = "whatsapp" // STOP/START replies to the business number
+)
+
+// WhatsAppSessionWindow is how long after a user's last message free-form
+// (session) messages may be sent; outside it only templates are delivered
+const WhatsAppSessionWindow = 24 * time.Hour
+
+// WhatsAppConsent is a user's WhatsApp opt-in state
+type WhatsAppConsent struct {
+	UserID        string     `json:"user_id"`
+	PhoneNumber   string     `json:"phone_number"`
+	Status        string     `json:"status"` // opted_in, opted_out
+	Source        string     `json:"source"` // web, whatsapp
+	OptedInAt     *time.Time `json:"opted_in_at,omitempty"`
+	OptedOutAt    *time.Time `json:"opted_out_at,omitempty"`
+	LastInboundAt *time.Time `json:"last_inbound_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// WhatsAppOptInRequest records a user's consent to WhatsApp notifications.
+// Consent must be explicitly true.
+type WhatsAppOptInRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Consent     bool   `json:"consent" binding:"required"`
+}
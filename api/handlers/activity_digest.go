@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ActivityDigestHandler lets group managers subscribe to daily or weekly
+// incident activity emails
+type ActivityDigestHandler struct {
+	ActivityDigestService *services.ActivityDigestService
+}
+
+func NewActivityDigestHandler(activityDigestService *services.ActivityDigestService) *ActivityDigestHandler {
+	return &ActivityDigestHandler{ActivityDigestService: activityDigestService}
+}
+
+// ListActivityDigests returns the caller's digest subscriptions
+// GET /api/users/me/activity-digests
+func (h *ActivityDigestHandler) ListActivityDigests(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	subs, err := h.ActivityDigestService.ListSubscriptions(userID)
+	if err != nil {
+		log.Printf("ListActivityDigests error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list digest subscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subs, "total": len(subs)})
+}
+
+// SubscribeActivityDigest subscribes the caller to a digest, or changes the
+// groups of the one they have at that frequency
+// POST /api/users/me/activity-digests
+// Body: {"frequency": "daily|weekly", "group_ids": [...]} (no group_ids: every group they manage)
+func (h *ActivityDigestHandler) SubscribeActivityDigest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.SubscribeActivityDigestRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sub, err := h.ActivityDigestService.Subscribe(userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDigestFrequency):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrDigestGroupNotManaged), errors.Is(err, services.ErrActivityDigestNoManaging):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			log.Printf("SubscribeActivityDigest error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save digest subscription"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// PreviewActivityDigest returns the digest the caller would get now, without
+// sending it
+// GET /api/users/me/activity-digests/preview?frequency=daily|weekly
+func (h *ActivityDigestHandler) PreviewActivityDigest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	frequency := c.DefaultQuery("frequency", db.ActivityDigestWeekly)
+	if frequency != db.ActivityDigestDaily && frequency != db.ActivityDigestWeekly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidDigestFrequency.Error()})
+		return
+	}
+
+	sub := db.ActivityDigestSubscription{UserID: userID, Frequency: frequency}
+	subs, err := h.ActivityDigestService.ListSubscriptions(userID)
+	if err == nil {
+		for _, existing := range subs {
+			if existing.Frequency == frequency {
+				sub = existing
+			}
+		}
+	}
+	digest, err := h.ActivityDigestService.BuildDigest(sub, time.Now())
+	if err != nil {
+		log.Printf("PreviewActivityDigest error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build digest"})
+		return
+	}
+	c.JSON(http.StatusOK, digest)
+}
+
+// UnsubscribeActivityDigest stops one of the caller's digests
+// DELETE /api/users/me/activity-digests/:id
+func (h *ActivityDigestHandler) UnsubscribeActivityDigest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.ActivityDigestService.Unsubscribe(userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrActivityDigestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Digest subscription not found"})
+			return
+		}
+		log.Printf("UnsubscribeActivityDigest error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete digest subscription"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed from digest"})
+}
@@ -30,8 +30,7 @@ func (h *AgentRegistrationHandler) RegisterAgent(c *gin.Context) {
 		Port      string `json:"port" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -71,8 +70,7 @@ func (h *AgentRegistrationHandler) Heartbeat(c *gin.Context) {
 		OrgID     string `json:"org_id"`     // Required for org-level agents
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -124,8 +122,7 @@ func (h *AgentRegistrationHandler) UnregisterAgent(c *gin.Context) {
 		OrgID     string `json:"org_id"`     // Required for org-level agents
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -1,28 +1,95 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// AlertHandler keeps the legacy /alerts API working for old mobile and app
+// clients. Alerts have been replaced by incidents; every endpoint here reads
+// and writes incidents and answers in the old alert shape.
 type AlertHandler struct {
-	Service *services.AlertService
+	incidents *IncidentHandler
 }
 
-func NewAlertHandler(service *services.AlertService) *AlertHandler {
-	return &AlertHandler{Service: service}
+func NewAlertHandler(incidentHandler *IncidentHandler) *AlertHandler {
+	return &AlertHandler{incidents: incidentHandler}
+}
+
+// Deprecated marks every /alerts response as deprecated and points clients at
+// /incidents and at the fields that are going away
+func (h *AlertHandler) Deprecated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", `</incidents>; rel="successor-version"`)
+		c.Header("X-Deprecated-Fields", strings.Join(db.LegacyAlertOnlyFields, ","))
+		c.Next()
+	}
+}
+
+// resolveAlert maps an /alerts ID to its incident and checks access to it,
+// writing the error response when it fails
+func (h *AlertHandler) resolveAlert(c *gin.Context, action authz.Action) (*db.IncidentResponse, bool) {
+	incidentID, err := h.incidents.incidentService.ResolveLegacyAlertID(c.Param("id"))
+	if err == nil {
+		var incident *db.IncidentResponse
+		if incident, err = h.incidents.checkIncidentAccess(c, incidentID, action); err == nil {
+			return incident, true
+		}
+	}
+	switch err.Error() {
+	case "incident not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case "forbidden":
+		c.JSON(http.StatusForbidden, gin.H{"error": "user does not have permission to access this alert"})
+	case "unauthorized":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	}
+	return nil, false
 }
 
 func (h *AlertHandler) ListAlerts(c *gin.Context) {
-	alerts, err := h.Service.ListAlerts()
+	filters := authz.GetReBACFilters(c)
+	if filters["current_org_id"] == nil || filters["current_org_id"].(string) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = services.IncidentStatusFromLegacyAlert(status)
+	}
+	if severity := c.Query("severity"); severity != "" {
+		filters["severity"] = severity
+	}
+	// The old endpoint returned the latest 100
+	filters["limit"] = 100
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 && limit < 100 {
+		filters["limit"] = limit
+	}
+
+	incidents, err := h.incidents.incidentService.ListIncidents(filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
 
+	alerts := make([]db.AlertResponse, 0, len(incidents))
+	for i := range incidents {
+		alerts = append(alerts, services.LegacyAlertFromIncident(&incidents[i]))
+	}
+
 	// Return with metadata for mobile app compatibility
 	response := gin.H{
 		"alerts": alerts,
@@ -34,40 +101,55 @@ func (h *AlertHandler) ListAlerts(c *gin.Context) {
 }
 
 func (h *AlertHandler) CreateAlert(c *gin.Context) {
-	alert, err := h.Service.CreateAlertFromRequest(c)
+	var alert db.Alert
+	if err := c.ShouldBindJSON(&alert); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Incidents always belong to a project; the middleware has validated it
+	projectID := authz.GetProjectIDFromContext(c)
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required to create an alert"})
+		return
+	}
+
+	source := alert.Source
+	if source == "" {
+		source = "manual"
+	}
+	incident, err := h.incidents.incidentService.CreateIncident(&db.Incident{
+		Title:          alert.Title,
+		Description:    alert.Description,
+		Severity:       alert.Severity,
+		Urgency:        db.IncidentUrgencyHigh,
+		Source:         source,
+		GroupID:        alert.GroupID,
+		ServiceID:      alert.ServiceID,
+		ProjectID:      projectID,
+		OrganizationID: authz.GetOrgIDFromContext(c),
+	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, alert)
+	c.JSON(http.StatusCreated, services.LegacyAlertFromIncident(&db.IncidentResponse{Incident: *incident}))
 }
 
 func (h *AlertHandler) GetAlert(c *gin.Context) {
-	id := c.Param("id")
-	alert, err := h.Service.GetAlert(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	incident, ok := h.resolveAlert(c, authz.ActionView)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, alert)
+	c.JSON(http.StatusOK, services.LegacyAlertFromIncident(incident))
 }
 
 func (h *AlertHandler) AckAlert(c *gin.Context) {
-	id := c.Param("id")
-
-	// Get user ID from context (set by Supabase auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	incident, ok := h.resolveAlert(c, authz.ActionUpdate)
+	if !ok {
 		return
 	}
-
-	// Use new permission-aware ACK method
-	if err := h.Service.AckAlertByUser(id, userID.(string)); err != nil {
-		if err.Error() == "user does not have permission to acknowledge this alert" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
+	if err := h.incidents.incidentService.AcknowledgeIncident(incident.ID, c.GetString("user_id"), ""); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
@@ -75,8 +157,15 @@ func (h *AlertHandler) AckAlert(c *gin.Context) {
 }
 
 func (h *AlertHandler) UnackAlert(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.Service.UnackAlert(id); err != nil {
+	incident, ok := h.resolveAlert(c, authz.ActionUpdate)
+	if !ok {
+		return
+	}
+	if err := h.incidents.incidentService.UnacknowledgeIncident(incident.ID, c.GetString("user_id")); err != nil {
+		if errors.Is(err, services.ErrIncidentNotAcknowledged) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
@@ -84,8 +173,11 @@ func (h *AlertHandler) UnackAlert(c *gin.Context) {
 }
 
 func (h *AlertHandler) CloseAlert(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.Service.CloseAlert(id); err != nil {
+	incident, ok := h.resolveAlert(c, authz.ActionUpdate)
+	if !ok {
+		return
+	}
+	if err := h.incidents.incidentService.ResolveIncident(incident.ID, c.GetString("user_id"), "", ""); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// parkForDedupReview queues an alert that only matched candidateID by title
+// instead of guessing which incident it belongs to
+func (h *WebhookHandler) parkForDedupReview(integration db.Integration, alert ProcessedAlert, candidateID string) error {
+	alertJSON, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert for review: %w", err)
+	}
+
+	status := alert.Status
+	if status != "resolved" {
+		status = "firing"
+	}
+	review := &db.AlertDedupReview{
+		OrganizationID:      integration.OrganizationID,
+		IntegrationID:       integration.ID,
+		CandidateIncidentID: candidateID,
+		AlertName:           alert.AlertName,
+		AlertStatus:         status,
+		Severity:            alert.Severity,
+		MatchStrategy:       db.DedupStrategyTitle,
+		Alert:               alertJSON,
+	}
+	if err := h.dedupReviews.Park(review); err != nil {
+		return err
+	}
+
+	log.Printf("INFO: Alert %s (%s) only matched incident %s by title, parked for review %s",
+		alert.AlertName, status, candidateID, review.ID)
+	return nil
+}
+
+// recordDedupDecision counts an automatic deduplication decision
+func (h *WebhookHandler) recordDedupDecision(integration db.Integration, mode, outcome string) {
+	if h.dedupReviews != nil {
+		h.dedupReviews.RecordDecision(integration.OrganizationID, mode, outcome)
+	}
+}
+
+// dedupReviewOrg returns the organization a request acts on, or writes the
+// error response when the caller lacks the action on it
+func (h *WebhookHandler) dedupReviewOrg(c *gin.Context, action authz.Action) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return "", false
+	}
+	if !h.authorizer.Check(c.Request.Context(), userID, action, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to review this organization's alerts"})
+		return "", false
+	}
+	return orgID, true
+}
+
+func dedupReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDedupReviewNotFound), errors.Is(err, services.ErrDedupIncidentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDedupReviewDecided), errors.Is(err, services.ErrDedupReviewNotPromotable),
+		errors.Is(err, services.ErrDedupIncidentNotAvailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Dedup review error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process dedup review"})
+	}
+}
+
+// ListDedupReviews lists parked alerts, pending ones by default
+// GET /dedup-reviews?status=pending|attached|promoted|dismissed|all&limit=50
+func (h *WebhookHandler) ListDedupReviews(c *gin.Context) {
+	orgID, ok := h.dedupReviewOrg(c, authz.ActionView)
+	if !ok {
+		return
+	}
+
+	status := c.DefaultQuery("status", db.DedupReviewStatusPending)
+	switch status {
+	case "all":
+		status = ""
+	case db.DedupReviewStatusPending, db.DedupReviewStatusAttached, db.DedupReviewStatusPromoted, db.DedupReviewStatusDismissed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, attached, promoted, dismissed or all"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	reviews, err := h.dedupReviews.ListReviews(orgID, status, limit)
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews, "total": len(reviews)})
+}
+
+// GetDedupReview returns one parked alert
+// GET /dedup-reviews/:id
+func (h *WebhookHandler) GetDedupReview(c *gin.Context) {
+	orgID, ok := h.dedupReviewOrg(c, authz.ActionView)
+	if !ok {
+		return
+	}
+	review, err := h.dedupReviews.GetReview(orgID, c.Param("id"))
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+
+// AttachDedupReview adds a parked alert to an incident, the candidate unless
+// incident_id is given. A firing alert joins the incident; a resolved alert
+// resolves it.
+// POST /dedup-reviews/:id/attach
+func (h *WebhookHandler) AttachDedupReview(c *gin.Context) {
+	orgID, ok := h.dedupReviewOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+	var req db.AttachDedupReviewRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	review, err := h.dedupReviews.ClaimReview(orgID, c.Param("id"), db.DedupReviewStatusAttached, c.GetString("user_id"))
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+
+	incidentID, err := h.attachReviewedAlert(review, orgID, req.IncidentID, c.GetString("user_id"))
+	if err != nil {
+		h.dedupReviews.ReleaseReview(review.ID)
+		dedupReviewError(c, err)
+		return
+	}
+	h.dedupReviews.CompleteReview(review, incidentID)
+
+	c.JSON(http.StatusOK, gin.H{"review": review, "message": "Alert attached to incident"})
+}
+
+func (h *WebhookHandler) attachReviewedAlert(review *db.AlertDedupReview, orgID, incidentID, userID string) (string, error) {
+	if incidentID == "" {
+		incidentID = review.CandidateIncidentID
+	}
+	if incidentID == "" {
+		return "", services.ErrDedupIncidentNotFound
+	}
+	inOrg, err := h.dedupReviews.IncidentInOrg(incidentID, orgID)
+	if err != nil {
+		return "", err
+	}
+	if !inOrg {
+		return "", services.ErrDedupIncidentNotFound
+	}
+
+	if review.AlertStatus == "resolved" {
+		resolution := fmt.Sprintf("Resolved by %s alert resolution (matched in dedup review)", review.AlertName)
+		if err := h.incidentService.ResolveIncident(incidentID, userID, "Alert resolved", resolution); err != nil {
+			return "", err
+		}
+		return incidentID, nil
+	}
+
+	change, err := h.incidentService.RecordIncidentAlert(incidentID, review.Severity)
+	if err != nil {
+		return "", err
+	}
+	if change == nil {
+		return "", services.ErrDedupIncidentNotAvailable
+	}
+	return incidentID, nil
+}
+
+// PromoteDedupReview opens a new incident for a parked firing alert, routed
+// the same way the webhook would have
+// POST /dedup-reviews/:id/promote
+func (h *WebhookHandler) PromoteDedupReview(c *gin.Context) {
+	orgID, ok := h.dedupReviewOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	review, err := h.dedupReviews.GetReview(orgID, c.Param("id"))
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+	if review.AlertStatus != "firing" {
+		dedupReviewError(c, services.ErrDedupReviewNotPromotable)
+		return
+	}
+	review, err = h.dedupReviews.ClaimReview(orgID, review.ID, db.DedupReviewStatusPromoted, c.GetString("user_id"))
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+
+	var alert ProcessedAlert
+	if err := json.Unmarshal(review.Alert, &alert); err != nil {
+		h.dedupReviews.ReleaseReview(review.ID)
+		dedupReviewError(c, fmt.Errorf("failed to read parked alert: %w", err))
+		return
+	}
+	integration := db.Integration{ID: review.IntegrationID, OrganizationID: orgID}
+	if review.IntegrationID != "" {
+		if found, err := h.integrationService.GetIntegration(review.IntegrationID); err == nil {
+			integration = found
+		}
+	}
+
+	serviceInfo, assigneeInfo, err := h.resolveServiceAndAssignee(integration, alert)
+	if err != nil {
+		log.Printf("DEBUG: Failed to resolve service/assignee for promoted alert: %v", err)
+	}
+	incident, err := h.createIncidentAtomic(integration, alert, serviceInfo, assigneeInfo)
+	if err != nil {
+		h.dedupReviews.ReleaseReview(review.ID)
+		dedupReviewError(c, err)
+		return
+	}
+	h.dedupReviews.CompleteReview(review, incident.ID)
+
+	c.JSON(http.StatusCreated, gin.H{"review": review, "incident": incident, "message": "Alert promoted to a new incident"})
+}
+
+// DismissDedupReview drops a parked alert without touching any incident
+// POST /dedup-reviews/:id/dismiss
+func (h *WebhookHandler) DismissDedupReview(c *gin.Context) {
+	orgID, ok := h.dedupReviewOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+	review, err := h.dedupReviews.ClaimReview(orgID, c.Param("id"), db.DedupReviewStatusDismissed, c.GetString("user_id"))
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+	h.dedupReviews.CompleteReview(review, "")
+
+	c.JSON(http.StatusOK, gin.H{"review": review, "message": "Alert dismissed"})
+}
+
+// GetDedupStats counts automatic and manual deduplication decisions
+// GET /dedup-reviews/stats?from=&to=
+func (h *WebhookHandler) GetDedupStats(c *gin.Context) {
+	orgID, ok := h.dedupReviewOrg(c, authz.ActionView)
+	if !ok {
+		return
+	}
+	from, to, err := parseStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.dedupReviews.GetStats(orgID, from, to)
+	if err != nil {
+		dedupReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
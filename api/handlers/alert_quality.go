@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// AlertQualityHandler reports which alerts page people without needing them
+// and which problems keep coming back
+type AlertQualityHandler struct {
+	IncidentService *services.IncidentService
+	authorizer      authz.Authorizer
+}
+
+func NewAlertQualityHandler(incidentService *services.IncidentService, authorizer authz.Authorizer) *AlertQualityHandler {
+	return &AlertQualityHandler{IncidentService: incidentService, authorizer: authorizer}
+}
+
+// checkOrgAccess resolves the current organization and checks the user may
+// view it, writing the error response when not
+func (h *AlertQualityHandler) checkOrgAccess(c *gin.Context) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return "", false
+	}
+	if !h.authorizer.Check(c.Request.Context(), userID, authz.ActionView, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this organization"})
+		return "", false
+	}
+	return orgID, true
+}
+
+// GetNoisyAlerts ranks alerts by noise score
+// GET /analytics/noisy-alerts?org_id=&from=&to=&group_by=alertname|fingerprint&service_id=&min_incidents=3&limit=20
+func (h *AlertQualityHandler) GetNoisyAlerts(c *gin.Context) {
+	orgID, ok := h.checkOrgAccess(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := db.NoisyAlertsFilter{
+		GroupBy:   c.DefaultQuery("group_by", db.AlertQualityByAlertName),
+		ServiceID: c.Query("service_id"),
+	}
+	if filter.GroupBy != db.AlertQualityByAlertName && filter.GroupBy != db.AlertQualityByFingerprint {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be alertname or fingerprint"})
+		return
+	}
+	if filter.MinIncidents, err = strconv.Atoi(c.DefaultQuery("min_incidents", strconv.Itoa(db.NoisyAlertsDefaultMinIncidents))); err != nil || filter.MinIncidents < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_incidents must be a positive number"})
+		return
+	}
+	if filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(db.NoisyAlertsDefaultLimit))); err != nil || filter.Limit < 1 || filter.Limit > db.NoisyAlertsMaxLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(db.NoisyAlertsMaxLimit)})
+		return
+	}
+
+	report, err := h.IncidentService.GetNoisyAlerts(orgID, from, to, filter)
+	if err != nil {
+		log.Printf("GetNoisyAlerts error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build noisy alerts report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetRecurringProblems returns the organization's top recurring problems per
+// service, from resolved incidents clustered by fingerprint and title
+// GET /analytics/recurring-problems?org_id=&service_id=
+func (h *AlertQualityHandler) GetRecurringProblems(c *gin.Context) {
+	orgID, ok := h.checkOrgAccess(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.IncidentService.GetRecurringProblemsReport(orgID, c.Query("service_id"))
+	if err != nil {
+		log.Printf("GetRecurringProblems error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build recurring problems report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// GetAlertStormRule returns a service's alert storm rule and any storm in progress
+// GET /services/{id}/alert-storm
+func (h *ServiceHandler) GetAlertStormRule(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	status, err := h.ServiceService.GetAlertStormStatus(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alert storm rule: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alert_storm": status})
+}
+
+// SetAlertStormRule turns on or changes alert storm detection for a service
+// PUT /services/{id}/alert-storm
+func (h *ServiceHandler) SetAlertStormRule(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.SetAlertStormRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	status, err := h.ServiceService.SetAlertStormRule(c.Param("id"), c.GetString("user_id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAlertStormRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save alert storm rule: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alert_storm": status,
+		"message":     "Alert storm rule saved successfully",
+	})
+}
+
+// DeleteAlertStormRule turns off alert storm detection for a service
+// DELETE /services/{id}/alert-storm
+func (h *ServiceHandler) DeleteAlertStormRule(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	if err := h.ServiceService.DeleteAlertStormRule(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert storm rule: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert storm rule deleted successfully"})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// AnalyticsDashboardHandler handles saved analytics dashboards
+type AnalyticsDashboardHandler struct {
+	DashboardService *services.AnalyticsDashboardService
+}
+
+// NewAnalyticsDashboardHandler creates a new AnalyticsDashboardHandler
+func NewAnalyticsDashboardHandler(dashboardService *services.AnalyticsDashboardService) *AnalyticsDashboardHandler {
+	return &AnalyticsDashboardHandler{DashboardService: dashboardService}
+}
+
+// respondDashboardError maps service errors to HTTP responses
+func respondDashboardError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrAnalyticsDashboardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dashboard not found"})
+	case errors.Is(err, services.ErrAnalyticsDashboardForbidden), errors.Is(err, services.ErrDashboardGroupNotMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAnalyticsDashboard):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " dashboard: " + err.Error()})
+	}
+}
+
+// ListMetrics handles GET /analytics/metrics: the metrics widgets can show
+func (h *AnalyticsDashboardHandler) ListMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"metrics": services.AnalyticsMetrics})
+}
+
+// ListDashboards handles GET /analytics/dashboards
+func (h *AnalyticsDashboardHandler) ListDashboards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	dashboards, err := h.DashboardService.ListDashboards(userID)
+	if err != nil {
+		respondDashboardError(c, "list", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dashboards": dashboards, "total": len(dashboards)})
+}
+
+// GetDashboard handles GET /analytics/dashboards/:id
+func (h *AnalyticsDashboardHandler) GetDashboard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	dashboard, err := h.DashboardService.GetDashboard(c.Param("id"), userID)
+	if err != nil {
+		respondDashboardError(c, "get", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dashboard": dashboard})
+}
+
+// CreateDashboard handles POST /analytics/dashboards
+func (h *AnalyticsDashboardHandler) CreateDashboard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.CreateAnalyticsDashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	dashboard, err := h.DashboardService.CreateDashboard(userID, req)
+	if err != nil {
+		respondDashboardError(c, "create", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"dashboard": dashboard})
+}
+
+// UpdateDashboard handles PATCH /analytics/dashboards/:id
+func (h *AnalyticsDashboardHandler) UpdateDashboard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.UpdateAnalyticsDashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	dashboard, err := h.DashboardService.UpdateDashboard(c.Param("id"), userID, req)
+	if err != nil {
+		respondDashboardError(c, "update", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dashboard": dashboard})
+}
+
+// DeleteDashboard handles DELETE /analytics/dashboards/:id
+func (h *AnalyticsDashboardHandler) DeleteDashboard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.DashboardService.DeleteDashboard(c.Param("id"), userID); err != nil {
+		respondDashboardError(c, "delete", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dashboard deleted"})
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -34,8 +37,7 @@ func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 	}
 
 	var req db.CreateAPIKeyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -110,8 +112,7 @@ func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
 	}
 
 	var req db.UpdateAPIKeyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -185,6 +186,35 @@ func (h *APIKeyHandler) RegenerateAPIKey(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetAPIKeyUsage returns quota usage and a daily request series for an API key
+// GET /api-keys/:id/usage?days=30
+func (h *APIKeyHandler) GetAPIKeyUsage(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+		return
+	}
+
+	usage, err := h.APIKeyService.GetAPIKeyUsage(c.Param("id"), userID.(string), days)
+	if err != nil {
+		if err.Error() == "API key not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting API key usage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"usage": usage})
+}
+
 // GetAPIKeyStats gets usage statistics for API keys
 func (h *APIKeyHandler) GetAPIKeyStats(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -259,16 +289,6 @@ func (h *APIKeyHandler) WebhookAlert(c *gin.Context) {
 		return
 	}
 
-	// Update API key usage counters
-	go func() {
-		if err := h.APIKeyService.UpdateLastUsed(apiKey.ID); err != nil {
-			log.Printf("Error updating API key last used: %v", err)
-		}
-		if err := h.APIKeyService.IncrementRateLimit(apiKey.ID); err != nil {
-			log.Printf("Error incrementing rate limit: %v", err)
-		}
-	}()
-
 	// Log successful usage
 	h.logAPIKeyUsage(apiKey.ID, c, http.StatusCreated, time.Since(startTime), createdAlert.ID, req.Title, req.Severity, "")
 
@@ -291,12 +311,16 @@ func (h *APIKeyHandler) APIKeyAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
 
-		// Extract API key from query parameter
+		// Extract API key from query parameter, or a Bearer header for
+		// clients (like slarctl) that keep keys out of URLs
 		apiKeyValue := c.Query("api_key")
+		if authHeader := c.GetHeader("Authorization"); apiKeyValue == "" && strings.HasPrefix(authHeader, "Bearer ") {
+			apiKeyValue = strings.TrimPrefix(authHeader, "Bearer ")
+		}
 		if apiKeyValue == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "api_key_required",
-				"message": "API key is required in query parameter 'apikey'",
+				"message": "API key is required in query parameter 'api_key' or an Authorization: Bearer header",
 			})
 			c.Abort()
 			return
@@ -332,15 +356,13 @@ func (h *APIKeyHandler) APIKeyAuthMiddleware() gin.HandlerFunc {
 		// Check rate limits
 		if err := h.APIKeyService.CheckRateLimit(apiKey.ID, apiKey); err != nil {
 			h.logAPIKeyUsage(apiKey.ID, c, http.StatusTooManyRequests, time.Since(startTime), "", "", "", err.Error())
-
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "rate_limit_exceeded",
-				"message": err.Error(),
-			})
-			c.Abort()
+			abortRateLimited(c, err)
 			return
 		}
 
+		// Count the call against the key's quotas (async, don't block request)
+		go h.APIKeyService.RecordUsage(apiKey.ID)
+
 		// Set context values
 		c.Set("api_key", apiKey)
 		c.Set("user_id", apiKey.UserID)
@@ -350,6 +372,32 @@ func (h *APIKeyHandler) APIKeyAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// abortRateLimited responds 429, with the exhausted window's reset time when known
+func abortRateLimited(c *gin.Context, err error) {
+	body := gin.H{
+		"error":   "rate_limit_exceeded",
+		"message": err.Error(),
+	}
+
+	var rateErr *services.RateLimitError
+	if errors.As(err, &rateErr) {
+		retryAfter := int(math.Ceil(time.Until(rateErr.ResetAt).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rateErr.Limit))
+		c.Header("X-RateLimit-Remaining", "0")
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(rateErr.ResetAt.Unix(), 10))
+		body["window"] = rateErr.Window
+		body["limit"] = rateErr.Limit
+		body["reset_at"] = rateErr.ResetAt
+	}
+
+	c.JSON(http.StatusTooManyRequests, body)
+	c.Abort()
+}
+
 // Helper methods
 
 func (h *APIKeyHandler) hasRequiredPermission(apiKey *db.APIKey, endpoint string) bool {
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// AvailabilityHandler lets users mark when they are on vacation or DND, so
+// schedule-based escalations pass over them
+type AvailabilityHandler struct {
+	AvailabilityService *services.AvailabilityService
+}
+
+func NewAvailabilityHandler(availabilityService *services.AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{AvailabilityService: availabilityService}
+}
+
+// ListUnavailability returns the caller's current and upcoming away periods
+// GET /api/users/me/unavailability
+func (h *AvailabilityHandler) ListUnavailability(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	periods, err := h.AvailabilityService.ListUnavailability(userID)
+	if err != nil {
+		log.Printf("ListUnavailability error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list unavailability"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unavailability": periods, "total": len(periods)})
+}
+
+// CreateUnavailability marks the caller unavailable for a period
+// POST /api/users/me/unavailability
+// Body: {"kind": "vacation|dnd", "starts_at", "ends_at", "reason"}
+func (h *AvailabilityHandler) CreateUnavailability(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.CreateUnavailabilityRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	period, err := h.AvailabilityService.CreateUnavailability(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUnavailability) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("CreateUnavailability error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create unavailability"})
+		return
+	}
+	c.JSON(http.StatusCreated, period)
+}
+
+// DeleteUnavailability removes one of the caller's away periods
+// DELETE /api/users/me/unavailability/:id
+func (h *AvailabilityHandler) DeleteUnavailability(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.AvailabilityService.DeleteUnavailability(userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrUnavailabilityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unavailability not found"})
+			return
+		}
+		log.Printf("DeleteUnavailability error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete unavailability"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Unavailability deleted"})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+)
+
+// WebhookCreateChangeEvent handles POST /webhooks/change
+// Records a deploy, feature flag or infrastructure change for the service
+// identified by routing_key (PagerDuty Change Events style)
+func (h *IncidentHandler) WebhookCreateChangeEvent(c *gin.Context) {
+	var req db.WebhookChangeEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if h.serviceService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Service lookup not available"})
+		return
+	}
+
+	service, err := h.serviceService.GetServiceByRoutingKey(req.RoutingKey)
+	if err != nil {
+		log.Printf("ERROR: Service lookup by routing_key '%s' failed: %v", req.RoutingKey, err)
+		c.JSON(http.StatusBadRequest, gin.H{"status": "invalid_request", "message": "Invalid routing_key: " + req.RoutingKey})
+		return
+	}
+	if service.OrganizationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "invalid_request",
+			"message": "Service '" + service.Name + "' must belong to an organization",
+		})
+		return
+	}
+
+	change, err := h.incidentService.CreateChangeEvent(&service, req.Payload)
+	if err != nil {
+		log.Printf("ERROR: Failed to create change event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to record change event"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":       "success",
+		"message":      "Change event recorded",
+		"change_event": change,
+	})
+}
+
+// GetIncidentChanges handles GET /incidents/:id/changes
+// Returns the service's changes in the window before the incident started
+func (h *IncidentHandler) GetIncidentChanges(c *gin.Context) {
+	id := c.Param("id")
+
+	incident, err := h.checkIncidentAccess(c, id, authz.ActionView)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		if err.Error() == "forbidden" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission", "details": err.Error()})
+		return
+	}
+
+	changes, err := h.incidentService.GetIncidentChanges(&incident.Incident)
+	if err != nil {
+		log.Printf("GetIncidentChanges error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":        changes,
+		"total":          len(changes),
+		"window_minutes": int(db.ChangeEventCorrelationWindow.Minutes()),
+	})
+}
+
+// ListServiceChangeEvents handles GET /services/:id/change-events
+// Query params: since (RFC3339, default 24h ago), limit (default 50)
+// Only changes recorded in the current organization are returned.
+func (h *IncidentHandler) ListServiceChangeEvents(c *gin.Context) {
+	orgID, ok := h.checkOrgAccess(c, authz.ActionView)
+	if !ok {
+		return
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		since = parsed
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	changes, err := h.incidentService.ListServiceChangeEvents(orgID, c.Param("id"), since, limit)
+	if err != nil {
+		log.Printf("ListServiceChangeEvents error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve change events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"change_events": changes, "total": len(changes)})
+}
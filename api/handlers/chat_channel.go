@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/notify"
+	"github.com/vanchonlee/slar/services"
+)
+
+const chatChannelsAdminOnly = "Only group leaders can manage chat channels"
+
+// ChatChannelHandler handles group chat channels (Discord, Telegram, Google
+// Chat and registered channel types) and the public acknowledge links posted to them
+type ChatChannelHandler struct {
+	ChatChannelService *services.ChatChannelService
+	IncidentService    *services.IncidentService
+	InvitationService  *services.GroupInvitationService // group admin checks
+}
+
+// NewChatChannelHandler creates a new ChatChannelHandler
+func NewChatChannelHandler(chatChannelService *services.ChatChannelService, incidentService *services.IncidentService, invitationService *services.GroupInvitationService) *ChatChannelHandler {
+	return &ChatChannelHandler{
+		ChatChannelService: chatChannelService,
+		IncidentService:    incidentService,
+		InvitationService:  invitationService,
+	}
+}
+
+// requireGroupMember aborts with 403 unless the current user belongs to the group
+func (h *ChatChannelHandler) requireGroupMember(c *gin.Context, groupID string) bool {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return false
+	}
+
+	isMember, err := h.InvitationService.GroupService.IsUserInGroup(groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check group membership"})
+		return false
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only group members can view chat channels"})
+		return false
+	}
+	return true
+}
+
+// ListChatChannelTypes handles GET /chat-channel-types. The UI renders each
+// type's settings form from its config schema.
+func (h *ChatChannelHandler) ListChatChannelTypes(c *gin.Context) {
+	types := notify.Describe()
+	c.JSON(http.StatusOK, gin.H{
+		"channel_types": types,
+		"count":         len(types),
+	})
+}
+
+// ListChatChannels handles GET /groups/:id/chat-channels
+func (h *ChatChannelHandler) ListChatChannels(c *gin.Context) {
+	groupID := c.Param("id")
+	if !h.requireGroupMember(c, groupID) {
+		return
+	}
+
+	channels, err := h.ChatChannelService.ListChannels(groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list chat channels: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chat_channels": channels,
+		"count":         len(channels),
+	})
+}
+
+// CreateChatChannel handles POST /groups/:id/chat-channels
+func (h *ChatChannelHandler) CreateChatChannel(c *gin.Context) {
+	groupID := c.Param("id")
+
+	var req db.CreateGroupChatChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !requireGroupAdmin(c, h.InvitationService, groupID, chatChannelsAdminOnly) {
+		return
+	}
+
+	channel, err := h.ChatChannelService.CreateChannel(groupID, req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidChatChannel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chat channel: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"chat_channel": channel,
+		"message":      "Chat channel created successfully",
+	})
+}
+
+// UpdateChatChannel handles PATCH /groups/:id/chat-channels/:channel_id
+func (h *ChatChannelHandler) UpdateChatChannel(c *gin.Context) {
+	groupID := c.Param("id")
+
+	var req db.UpdateGroupChatChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !requireGroupAdmin(c, h.InvitationService, groupID, chatChannelsAdminOnly) {
+		return
+	}
+
+	channel, err := h.ChatChannelService.UpdateChannel(groupID, c.Param("channel_id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrChatChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chat channel not found"})
+		case errors.Is(err, services.ErrInvalidChatChannel):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chat channel: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chat_channel": channel,
+		"message":      "Chat channel updated successfully",
+	})
+}
+
+// DeleteChatChannel handles DELETE /groups/:id/chat-channels/:channel_id
+func (h *ChatChannelHandler) DeleteChatChannel(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, chatChannelsAdminOnly) {
+		return
+	}
+
+	if err := h.ChatChannelService.DeleteChannel(groupID, c.Param("channel_id")); err != nil {
+		if errors.Is(err, services.ErrChatChannelNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chat channel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chat channel: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chat channel deleted successfully"})
+}
+
+// TestChatChannel handles POST /groups/:id/chat-channels/:channel_id/test
+func (h *ChatChannelHandler) TestChatChannel(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, chatChannelsAdminOnly) {
+		return
+	}
+
+	if err := h.ChatChannelService.SendTestMessage(groupID, c.Param("channel_id")); err != nil {
+		if errors.Is(err, services.ErrChatChannelNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chat channel not found"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send test message: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent"})
+}
+
+// GetAckLink handles GET /ack/:token (public). It only describes the incident,
+// so chat link previews can't acknowledge it.
+func (h *ChatChannelHandler) GetAckLink(c *gin.Context) {
+	link, err := h.ChatChannelService.GetAckLink(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrAckLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This acknowledge link is invalid"})
+			return
+		}
+		log.Printf("GetAckLink error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load acknowledge link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ack_link": link})
+}
+
+// AcknowledgeWithAckLink handles POST /ack/:token (public)
+func (h *ChatChannelHandler) AcknowledgeWithAckLink(c *gin.Context) {
+	link, err := h.ChatChannelService.AcknowledgeWithAckLink(c.Param("token"), h.IncidentService)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAckLinkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "This acknowledge link is invalid"})
+		case errors.Is(err, services.ErrAckLinkExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "This acknowledge link has expired", "ack_link": link})
+		case errors.Is(err, services.ErrAckLinkUsed):
+			c.JSON(http.StatusConflict, gin.H{"error": "This acknowledge link has already been used", "ack_link": link})
+		case errors.Is(err, services.ErrIncidentNotTriggered):
+			c.JSON(http.StatusConflict, gin.H{"error": "This incident has already been " + link.Status, "ack_link": link})
+		default:
+			log.Printf("AcknowledgeWithAckLink error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge incident"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ack_link": link,
+		"message":  "Incident acknowledged",
+	})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressMinBytes is the smallest body worth compressing; below it the gzip
+// framing costs more than it saves
+const compressMinBytes = 1024
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// CompressionMiddleware gzips JSON and text responses of 1 KB or more for
+// clients that accept it. Brotli is left to the reverse proxy: the standard
+// library has no encoder. Streams (Server-Sent Events, WebSocket upgrades)
+// and responses that are already encoded pass through. It must run before
+// middleware that rewrites bodies, so it sees their final output.
+func CompressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) || c.GetHeader("Upgrade") != "" ||
+			c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &gzipResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.finish()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressibleType reports whether a Content-Type is text that gzip shrinks
+func compressibleType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/x-ndjson",
+		mediaType == "application/yaml",
+		mediaType == "application/x-yaml":
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the first compressMinBytes of a response to
+// decide whether compressing it is worthwhile, then streams it through gzip
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	n, _ := w.buf.Write(data)
+	if w.buf.Len() >= compressMinBytes {
+		w.decide(true)
+		if err := w.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been written so far, for handlers that stream
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.decide(w.buf.Len() >= compressMinBytes)
+		_ = w.flushBuffer()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide picks gzip or identity for the response; large says the body is
+// big enough to be worth it
+func (w *gzipResponseWriter) decide(large bool) {
+	w.decided = true
+	h := w.Header()
+	status := w.Status()
+	if !large || h.Get("Content-Encoding") != "" || !compressibleType(h.Get("Content-Type")) ||
+		status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		return
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+	// The compressed bytes are a different representation of the same resource
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", "W/"+etag)
+	}
+	gz := gzipWriterPool.Get().(*gzip.Writer)
+	gz.Reset(w.ResponseWriter)
+	w.gz = gz
+}
+
+func (w *gzipResponseWriter) flushBuffer() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	data := w.buf.Bytes()
+	w.buf.Reset()
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(data)
+	} else {
+		_, err = w.ResponseWriter.Write(data)
+	}
+	return err
+}
+
+// finish writes out a response that stayed below the threshold and closes
+// the gzip stream
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		if w.buf.Len() == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	_ = w.flushBuffer()
+	if w.gz != nil {
+		_ = w.gz.Close()
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCompressionRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CompressionMiddleware(), RequestIDMiddleware(), ErrorSchemaMiddleware())
+	r.GET("/big", ConditionalGetMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"text": strings.Repeat("incident ", 500)})
+	})
+	r.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", make([]byte, 4096))
+	})
+	return r
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	r := newCompressionRouter()
+
+	w := serveConditionalGet(r, "/big", map[string]string{"Accept-Encoding": "br, gzip;q=0.8"})
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(strings.Join(w.Header().Values("Vary"), ", "), "Accept-Encoding") {
+		t.Fatalf("headers = %v", w.Header())
+	}
+	if etag := w.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("ETag = %q, want weak", etag)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(gz)
+	var decoded map[string]string
+	if err := json.Unmarshal(body, &decoded); err != nil || !strings.HasPrefix(decoded["text"], "incident ") {
+		t.Errorf("decoded body = %q (%v)", body, err)
+	}
+
+	for path, headers := range map[string]map[string]string{
+		"/big":    {"Accept-Encoding": "gzip;q=0, identity"},
+		"/small":  {"Accept-Encoding": "gzip"},
+		"/binary": {"Accept-Encoding": "gzip"},
+	} {
+		w := serveConditionalGet(r, path, headers)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s %v: compressed unexpectedly", path, headers)
+		}
+	}
+}
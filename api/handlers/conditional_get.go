@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readCacheControl lets browsers and the mobile app keep a response but makes
+// them revalidate it before every use; shared caches must not store it, since
+// responses depend on who is asking
+const readCacheControl = "private, no-cache"
+
+// readVary lists the request headers read responses depend on
+const readVary = "Authorization, X-Org-ID, X-Project-ID"
+
+// ConditionalGetMiddleware adds ETag and Cache-Control to successful GET
+// responses and answers If-None-Match and If-Modified-Since with 304 Not
+// Modified. The ETag is a hash of the body, so it changes with any field of
+// the response. Last-Modified is only sent (and If-Modified-Since only
+// honored) when the handler set it with setLastModified; If-None-Match takes
+// precedence when both are present.
+func ConditionalGetMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &conditionalGetWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			return
+		}
+		body := w.buf.Bytes()
+		h := w.ResponseWriter.Header()
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		h.Set("ETag", etag)
+		if h.Get("Cache-Control") == "" {
+			h.Set("Cache-Control", readCacheControl)
+		}
+		h.Add("Vary", readVary)
+
+		if notModified(c.Request, etag, h.Get("Last-Modified")) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// setLastModified sets the Last-Modified header ConditionalGetMiddleware
+// compares If-Modified-Since against. modified must cover everything in the
+// response, or clients may keep a stale copy.
+func setLastModified(c *gin.Context, modified time.Time) {
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates the request's preconditions against the response
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && !modified.After(since)
+}
+
+// conditionalGetWriter holds back 200 responses so their ETag can be computed
+// before anything is sent
+type conditionalGetWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	buffering bool
+}
+
+func (w *conditionalGetWriter) Write(data []byte) (int, error) {
+	if w.buffering || (w.Status() == http.StatusOK && !w.ResponseWriter.Written()) {
+		w.buffering = true
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *conditionalGetWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newConditionalGetRouter(modified time.Time) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware(), ErrorSchemaMiddleware())
+	r.GET("/things/:id", ConditionalGetMiddleware(), func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Thing not found"})
+			return
+		}
+		setLastModified(c, modified)
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+	return r
+}
+
+func serveConditionalGet(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalGetETag(t *testing.T) {
+	r := newConditionalGetRouter(time.Time{})
+
+	first := serveConditionalGet(r, "/things/1", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.String() != `{"id":"1"}` {
+		t.Fatalf("unexpected response %d %q etag %q", first.Code, first.Body.String(), etag)
+	}
+	if first.Header().Get("Cache-Control") != readCacheControl || first.Header().Get("Last-Modified") != "" {
+		t.Errorf("headers = %v", first.Header())
+	}
+
+	again := serveConditionalGet(r, "/things/1", map[string]string{"If-None-Match": `"other", W/` + etag})
+	if again.Code != http.StatusNotModified || again.Body.Len() != 0 || again.Header().Get("ETag") != etag {
+		t.Errorf("revalidation = %d %q", again.Code, again.Body.String())
+	}
+
+	other := serveConditionalGet(r, "/things/2", map[string]string{"If-None-Match": etag})
+	if other.Code != http.StatusOK || other.Header().Get("ETag") == etag {
+		t.Errorf("different body matched the ETag: %d", other.Code)
+	}
+
+	// Errors are passed through untouched
+	missing := serveConditionalGet(r, "/things/missing", map[string]string{"If-None-Match": "*"})
+	if missing.Code != http.StatusNotFound || missing.Header().Get("ETag") != "" {
+		t.Errorf("error response = %d etag %q", missing.Code, missing.Header().Get("ETag"))
+	}
+}
+
+func TestConditionalGetLastModified(t *testing.T) {
+	modified := time.Date(2026, 5, 4, 10, 30, 0, 0, time.UTC)
+	r := newConditionalGetRouter(modified)
+
+	w := serveConditionalGet(r, "/things/1", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)})
+	if w.Code != http.StatusNotModified || w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+		t.Errorf("unchanged since = %d", w.Code)
+	}
+	w = serveConditionalGet(r, "/things/1", map[string]string{"If-Modified-Since": modified.Add(-time.Minute).Format(http.TimeFormat)})
+	if w.Code != http.StatusOK {
+		t.Errorf("changed since = %d", w.Code)
+	}
+	// If-None-Match wins over If-Modified-Since
+	w = serveConditionalGet(r, "/things/1", map[string]string{
+		"If-None-Match":     `"stale"`,
+		"If-Modified-Since": modified.Format(http.TimeFormat),
+	})
+	if w.Code != http.StatusOK {
+		t.Errorf("stale ETag = %d", w.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ConfigPromotionHandler promotes configuration from one instance to another
+// (e.g. staging to production). Routes live under /internal: the target
+// instance is called by the operator or a deploy pipeline, not by users, with
+// config_promotion_token as a bearer token.
+type ConfigPromotionHandler struct {
+	ConfigBackupService *services.ConfigBackupService
+}
+
+// NewConfigPromotionHandler creates a new ConfigPromotionHandler
+func NewConfigPromotionHandler(configBackupService *services.ConfigBackupService) *ConfigPromotionHandler {
+	return &ConfigPromotionHandler{ConfigBackupService: configBackupService}
+}
+
+// GetBundle handles GET /internal/config/bundle
+// Exports this instance's configuration, to be diffed against another instance
+func (h *ConfigPromotionHandler) GetBundle(c *gin.Context) {
+	bundle, err := h.ConfigBackupService.Backup()
+	if err != nil {
+		log.Printf("Config bundle export error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export configuration"})
+		return
+	}
+	c.JSON(http.StatusOK, bundle)
+}
+
+// DiffBundle handles POST /internal/config/promotions/diff
+// Lists what promoting the posted bundle onto this instance would change
+func (h *ConfigPromotionHandler) DiffBundle(c *gin.Context) {
+	var req db.ConfigDiffRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	diff, err := h.ConfigBackupService.DiffBundle(req.Bundle)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// Promote handles POST /internal/config/promotions/apply
+// Applies the selected changes of the posted bundle; dry_run reports them only
+func (h *ConfigPromotionHandler) Promote(c *gin.Context) {
+	var req db.PromoteConfigRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	result, err := h.ConfigBackupService.Promote(req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if !result.DryRun {
+		log.Printf("✅ Promoted %d configuration changes", len(result.Applied))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *ConfigPromotionHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidConfigBundle), errors.Is(err, services.ErrInvalidConfigPromotion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrConfigBundleSchemaMismatch), errors.Is(err, services.ErrConfigPromotionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Config promotion error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote configuration"})
+	}
+}
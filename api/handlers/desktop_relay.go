@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const (
+	desktopPollInterval   = 3 * time.Second
+	desktopOnCallInterval = time.Minute
+	// The server pings this often; a client that hasn't answered (or sent
+	// anything) within desktopPongWait is dropped
+	desktopPingInterval = 25 * time.Second
+	desktopPongWait     = 60 * time.Second
+	desktopWriteWait    = 10 * time.Second
+
+	// Reconnect backoff clients are told to use, with jitter
+	desktopReconnectMinDelay = time.Second
+	desktopReconnectMaxDelay = time.Minute
+
+	// Rate limits: open connections and connection attempts per user, and
+	// messages per connection (a burst, refilled one per second)
+	desktopMaxConnectionsPerUser = 3
+	desktopConnectsPerMinute     = 10
+	desktopMessageBurst          = 10
+	desktopMaxDroppedMessages    = 20
+	desktopMaxMessageBytes       = 4096
+)
+
+var (
+	errDesktopTooManyConnections = errors.New("too many desktop connections open")
+	errDesktopConnectingTooOften = errors.New("too many desktop connection attempts")
+)
+
+// DesktopRelayHandler serves the persistent WebSocket used by the desktop
+// tray notifier: it pushes the incidents paging the user and their on-call
+// status, and takes acknowledgements
+type DesktopRelayHandler struct {
+	DesktopRelayService *services.DesktopRelayService
+	limiter             *desktopConnLimiter
+}
+
+func NewDesktopRelayHandler(desktopRelayService *services.DesktopRelayService) *DesktopRelayHandler {
+	return &DesktopRelayHandler{DesktopRelayService: desktopRelayService, limiter: newDesktopConnLimiter()}
+}
+
+// desktopConnLimiter caps each user's open desktop connections and how often
+// they may connect, so a client stuck in a reconnect loop can't pile up
+type desktopConnLimiter struct {
+	mu       sync.Mutex
+	open     map[string]int
+	attempts map[string][]time.Time
+}
+
+func newDesktopConnLimiter() *desktopConnLimiter {
+	return &desktopConnLimiter{open: map[string]int{}, attempts: map[string][]time.Time{}}
+}
+
+// acquire records a connection attempt and reserves a connection slot
+func (l *desktopConnLimiter) acquire(userID string, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.attempts[userID][:0]
+	for _, at := range l.attempts[userID] {
+		if now.Sub(at) < time.Minute {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= desktopConnectsPerMinute {
+		l.attempts[userID] = recent
+		return errDesktopConnectingTooOften
+	}
+	l.attempts[userID] = append(recent, now)
+
+	if l.open[userID] >= desktopMaxConnectionsPerUser {
+		return errDesktopTooManyConnections
+	}
+	l.open[userID]++
+	return nil
+}
+
+func (l *desktopConnLimiter) release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.open[userID]--; l.open[userID] <= 0 {
+		delete(l.open, userID)
+	}
+}
+
+// desktopMessageLimiter is a token bucket for a connection's inbound messages
+type desktopMessageLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *desktopMessageLimiter) allow(now time.Time) bool {
+	if l.last.IsZero() {
+		l.tokens = desktopMessageBurst
+	} else {
+		l.tokens += now.Sub(l.last).Seconds()
+		if l.tokens > desktopMessageBurst {
+			l.tokens = desktopMessageBurst
+		}
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// desktopResumeFrom is where a reconnecting client's catch-up starts: its
+// ?since= cursor, no further back than db.DesktopResumeWindow. Zero means a
+// fresh start.
+func desktopResumeFrom(since string, now time.Time) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, since)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if earliest := now.Add(-db.DesktopResumeWindow); t.Before(earliest) {
+		return earliest, nil
+	}
+	return t, nil
+}
+
+// Connect upgrades to the desktop notifier WebSocket
+// GET /ws/desktop?since=<cursor>
+//
+// The server sends JSON messages: "hello" with the heartbeat and reconnect
+// settings and a starting cursor, "incident" whenever an incident assigned to
+// the user changes (each carries a cursor), "oncall" on connect and whenever
+// the user's on-call status changes, "ack_result" and "pong" in reply to the
+// client, and "error". The client may send {"type": "ack", "incident_id",
+// "request_id"} and {"type": "ping"}. After a drop, clients reconnect with
+// backoff and ?since= set to the last cursor they saw to be caught up on what
+// they missed.
+func (h *DesktopRelayHandler) Connect(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	since, err := desktopResumeFrom(c.Query("since"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+		return
+	}
+	if err := h.limiter.acquire(userID, time.Now()); err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(desktopReconnectMaxDelay.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	defer h.limiter.release(userID)
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Desktop relay: failed to upgrade: %v", err)
+		return
+	}
+	defer conn.Close()
+	(&desktopSession{service: h.DesktopRelayService, conn: conn, userID: userID, cursor: since}).run()
+}
+
+// desktopSession is one desktop client's connection. Only run writes to the
+// connection; the reader hands replies over on out.
+type desktopSession struct {
+	service    *services.DesktopRelayService
+	conn       *websocket.Conn
+	userID     string
+	cursor     time.Time
+	snapshot   bool // the open incidents are still to be sent
+	lastOnCall string
+	out        chan interface{}
+}
+
+func (s *desktopSession) run() {
+	s.out = make(chan interface{}, desktopMessageBurst)
+	done := make(chan struct{})
+	go s.read(done)
+
+	if s.cursor.IsZero() {
+		s.cursor, s.snapshot = time.Now(), true
+	}
+	if !s.write(gin.H{
+		"type":                       db.DesktopMessageHello,
+		"user_id":                    s.userID,
+		"cursor":                     s.cursor,
+		"heartbeat_interval_seconds": int(desktopPingInterval.Seconds()),
+		"heartbeat_timeout_seconds":  int(desktopPongWait.Seconds()),
+		"reconnect": gin.H{
+			"min_delay_seconds": int(desktopReconnectMinDelay.Seconds()),
+			"max_delay_seconds": int(desktopReconnectMaxDelay.Seconds()),
+		},
+	}) || !s.pushIncidents() || !s.pushOnCall() {
+		return
+	}
+
+	poll := time.NewTicker(desktopPollInterval)
+	defer poll.Stop()
+	onCall := time.NewTicker(desktopOnCallInterval)
+	defer onCall.Stop()
+	ping := time.NewTicker(desktopPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-s.out:
+			if !s.write(msg) {
+				return
+			}
+		case <-poll.C:
+			if !s.pushIncidents() {
+				return
+			}
+		case <-onCall.C:
+			if !s.pushOnCall() {
+				return
+			}
+		case <-ping.C:
+			s.conn.SetWriteDeadline(time.Now().Add(desktopWriteWait))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *desktopSession) write(msg interface{}) bool {
+	s.conn.SetWriteDeadline(time.Now().Add(desktopWriteWait))
+	return s.conn.WriteJSON(msg) == nil
+}
+
+// pushIncidents sends the incidents that changed since the cursor, or the
+// open ones to a client starting fresh
+func (s *desktopSession) pushIncidents() bool {
+	since := s.cursor
+	if s.snapshot {
+		since = time.Time{}
+	}
+	incidents, err := s.service.IncidentUpdates(s.userID, since)
+	if err != nil {
+		log.Printf("Desktop relay: %v", err)
+		return true // transient; try again on the next tick
+	}
+	s.snapshot = false
+	for _, inc := range incidents {
+		if inc.UpdatedAt.After(s.cursor) {
+			s.cursor = inc.UpdatedAt
+		}
+		if !s.write(gin.H{"type": db.DesktopMessageIncident, "incident": inc, "cursor": s.cursor}) {
+			return false
+		}
+	}
+	return true
+}
+
+// pushOnCall sends the on-call status when it differs from the last one sent
+func (s *desktopSession) pushOnCall() bool {
+	status, err := s.service.OnCallStatus(s.userID)
+	if err != nil {
+		log.Printf("Desktop relay: %v", err)
+		return true
+	}
+	key, _ := json.Marshal(status)
+	if string(key) == s.lastOnCall {
+		return true
+	}
+	s.lastOnCall = string(key)
+	return s.write(gin.H{"type": db.DesktopMessageOnCall, "status": status})
+}
+
+// read handles client messages until the connection drops or the client
+// misbehaves, then closes done
+func (s *desktopSession) read(done chan struct{}) {
+	defer close(done)
+	s.conn.SetReadLimit(desktopMaxMessageBytes)
+	s.conn.SetReadDeadline(time.Now().Add(desktopPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(desktopPongWait))
+	})
+
+	var limiter desktopMessageLimiter
+	dropped := 0
+	for {
+		var msg db.DesktopClientMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				s.reply(gin.H{"type": db.DesktopMessageError, "code": "invalid_message", "message": "messages must be JSON objects"})
+				continue
+			}
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(desktopPongWait))
+
+		if !limiter.allow(time.Now()) {
+			if dropped++; dropped > desktopMaxDroppedMessages {
+				// The writer may be mid-message; a close frame is best effort
+				s.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded"),
+					time.Now().Add(desktopWriteWait))
+				return
+			}
+			s.reply(gin.H{"type": db.DesktopMessageError, "code": "rate_limited", "request_id": msg.RequestID,
+				"message": "Too many messages; slow down"})
+			continue
+		}
+		dropped = 0
+
+		switch msg.Type {
+		case db.DesktopClientPing:
+			s.reply(gin.H{"type": db.DesktopMessagePong, "request_id": msg.RequestID})
+		case db.DesktopClientAck:
+			s.reply(s.acknowledge(msg))
+		default:
+			s.reply(gin.H{"type": db.DesktopMessageError, "code": "unknown_type", "request_id": msg.RequestID,
+				"message": "Unknown message type " + strconv.Quote(msg.Type)})
+		}
+	}
+}
+
+func (s *desktopSession) acknowledge(msg db.DesktopClientMessage) gin.H {
+	result := gin.H{"type": db.DesktopMessageAckResult, "request_id": msg.RequestID, "incident_id": msg.IncidentID, "ok": false}
+	err := s.service.Acknowledge(s.userID, msg.IncidentID)
+	switch {
+	case err == nil:
+		result["ok"] = true
+	case errors.Is(err, services.ErrDesktopIncidentNotFound), errors.Is(err, services.ErrDesktopIncidentNotOpen):
+		result["error"] = err.Error()
+	default:
+		log.Printf("Desktop relay: failed to acknowledge %s: %v", msg.IncidentID, err)
+		result["error"] = "Failed to acknowledge incident"
+	}
+	return result
+}
+
+// reply queues a message for the writer, dropping it if the client isn't
+// keeping up
+func (s *desktopSession) reply(msg interface{}) {
+	select {
+	case s.out <- msg:
+	default:
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+func TestDesktopConnLimiter(t *testing.T) {
+	l := newDesktopConnLimiter()
+	now := time.Now()
+
+	for i := 0; i < desktopMaxConnectionsPerUser; i++ {
+		if err := l.acquire("alice", now); err != nil {
+			t.Fatalf("connection %d: %v", i, err)
+		}
+	}
+	if err := l.acquire("alice", now); err != errDesktopTooManyConnections {
+		t.Errorf("over the connection cap err = %v", err)
+	}
+	if err := l.acquire("bob", now); err != nil {
+		t.Errorf("other user err = %v", err)
+	}
+
+	// A client reconnecting in a loop is slowed down even as its connections close
+	for i := 0; i < desktopConnectsPerMinute; i++ {
+		l.release("alice")
+		l.acquire("alice", now)
+	}
+	if err := l.acquire("alice", now.Add(30*time.Second)); err != errDesktopConnectingTooOften {
+		t.Errorf("reconnect loop err = %v", err)
+	}
+	l.release("alice")
+	if err := l.acquire("alice", now.Add(2*time.Minute)); err != nil {
+		t.Errorf("after a minute err = %v", err)
+	}
+}
+
+func TestDesktopMessageLimiter(t *testing.T) {
+	var l desktopMessageLimiter
+	now := time.Now()
+	for i := 0; i < desktopMessageBurst; i++ {
+		if !l.allow(now) {
+			t.Fatalf("message %d of the burst refused", i)
+		}
+	}
+	if l.allow(now) {
+		t.Error("message past the burst allowed")
+	}
+	if !l.allow(now.Add(time.Second)) {
+		t.Error("message a second later refused")
+	}
+}
+
+func TestDesktopResumeFrom(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if since, err := desktopResumeFrom("", now); err != nil || !since.IsZero() {
+		t.Errorf("empty = %v, %v", since, err)
+	}
+	if since, _ := desktopResumeFrom("2026-10-16T11:50:00Z", now); !since.Equal(now.Add(-10 * time.Minute)) {
+		t.Errorf("recent = %v", since)
+	}
+	if since, _ := desktopResumeFrom("2026-10-15T12:00:00Z", now); !since.Equal(now.Add(-db.DesktopResumeWindow)) {
+		t.Errorf("old = %v", since)
+	}
+	if _, err := desktopResumeFrom("yesterday", now); err == nil {
+		t.Error("invalid since accepted")
+	}
+}
+
+func TestDesktopRelayConnect(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	createdAt := time.Now().Add(-time.Minute)
+
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("alice", time.Time{}, true, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status", "urgency", "priority", "service", "group", "short_id", "created_at", "updated_at"}).
+			AddRow("inc-1", "Checkout down", "triggered", "high", "P1", "checkout", "Payments", "", createdAt, createdAt))
+	mock.ExpectQuery(`SELECT user_available`).WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow(true))
+	mock.ExpectQuery(`FROM effective_shifts es`).WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id", "group_name", "scheduler", "start", "end"}).
+			AddRow("grp-1", "Payments", "Primary", createdAt.Add(-time.Hour), time.Now().Add(time.Hour)))
+	mock.ExpectQuery(`SELECT i.status`).WithArgs("alice", "inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("acknowledged"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", "alice") })
+	r.GET("/ws/desktop", NewDesktopRelayHandler(services.NewDesktopRelayService(pg, nil)).Connect)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/desktop", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	next := func(wantType string) map[string]interface{} {
+		t.Helper()
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("reading %s: %v", wantType, err)
+		}
+		if msg["type"] != wantType {
+			t.Fatalf("got %v, want a %s message", msg, wantType)
+		}
+		return msg
+	}
+
+	if hello := next(db.DesktopMessageHello); hello["heartbeat_interval_seconds"] != float64(25) {
+		t.Errorf("hello = %v", hello)
+	}
+	incident := next(db.DesktopMessageIncident)["incident"].(map[string]interface{})
+	if incident["id"] != "inc-1" || incident["priority"] != "P1" {
+		t.Errorf("incident = %v", incident)
+	}
+	status := next(db.DesktopMessageOnCall)["status"].(map[string]interface{})
+	if status["on_call"] != true || status["available"] != true {
+		t.Errorf("on-call status = %v", status)
+	}
+
+	conn.WriteJSON(db.DesktopClientMessage{Type: db.DesktopClientPing, RequestID: "r1"})
+	if pong := next(db.DesktopMessagePong); pong["request_id"] != "r1" {
+		t.Errorf("pong = %v", pong)
+	}
+	conn.WriteJSON(db.DesktopClientMessage{Type: db.DesktopClientAck, IncidentID: "inc-1", RequestID: "r2"})
+	if result := next(db.DesktopMessageAckResult); result["ok"] != false || result["request_id"] != "r2" {
+		t.Errorf("ack result = %v", result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
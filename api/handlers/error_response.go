@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	// Error codes used when a handler doesn't give one
+	ErrorCodeValidation = "validation_failed"
+)
+
+// ErrorResponse is the body of every 4xx/5xx JSON response. Error repeats
+// Message for clients written against the older {"error": "..."} shape.
+// Handlers may add their own fields (details, hints) next to these.
+type ErrorResponse struct {
+	Code        string       `json:"code"`
+	Message     string       `json:"message"`
+	FieldErrors []FieldError `json:"field_errors,omitempty"`
+	RequestID   string       `json:"request_id"`
+	Error       string       `json:"error"`
+}
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var (
+	errorCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+)
+
+func init() {
+	// Report validation errors with the JSON field names clients send
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// RequestIDMiddleware gives every request an ID, reusing a well-formed
+// X-Request-ID from the caller or gateway, and echoes it in the response
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+			c.Request.Header.Set(RequestIDHeader, id)
+		}
+		c.Set("request_id", id)
+		c.Writer.Header().Set(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// ErrorSchemaMiddleware rewrites JSON error responses into ErrorResponse, so
+// clients get code, message, field_errors and request_id whatever shape the
+// handler wrote. Field errors come from bindJSON.
+func ErrorSchemaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &errorCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.captured {
+			return
+		}
+		body := w.buf.Bytes()
+		if rewritten, ok := normalizeErrorBody(body, w.Status(), c.GetString("request_id"), fieldErrorsFrom(c)); ok {
+			body = rewritten
+		}
+		w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// errorCaptureWriter holds back JSON error bodies so they can be rewritten
+type errorCaptureWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	captured bool
+}
+
+func (w *errorCaptureWriter) capturing() bool {
+	return w.Status() >= http.StatusBadRequest && !w.ResponseWriter.Written() &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+func (w *errorCaptureWriter) Write(data []byte) (int, error) {
+	if w.captured || w.capturing() {
+		w.captured = true
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorCaptureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// normalizeErrorBody maps a handler's error JSON onto ErrorResponse, keeping
+// any extra fields. A snake_case "error" is taken as the code.
+func normalizeErrorBody(body []byte, status int, requestID string, fieldErrors []FieldError) ([]byte, bool) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+
+	errText, _ := fields["error"].(string)
+	message, _ := fields["message"].(string)
+	code, _ := fields["code"].(string)
+
+	switch {
+	case errText != "" && errorCodePattern.MatchString(errText) && code == "":
+		code = errText
+		if message == "" {
+			message = http.StatusText(status)
+		}
+	case errText != "":
+		// Older handlers put the description in "error" and a hint in "message"
+		if message != "" && message != errText {
+			if _, ok := fields["details"]; !ok {
+				fields["details"] = message
+			}
+		}
+		message = errText
+	case message == "":
+		message = http.StatusText(status)
+	}
+	if code == "" {
+		code = defaultErrorCode(status, len(fieldErrors) > 0)
+	}
+
+	fields["code"] = code
+	fields["message"] = message
+	if _, structured := fields["error"]; !structured || errText != "" {
+		fields["error"] = message
+	}
+	fields["request_id"] = requestID
+	if len(fieldErrors) > 0 {
+		fields["field_errors"] = fieldErrors
+	}
+
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
+
+func defaultErrorCode(status int, hasFieldErrors bool) string {
+	switch {
+	case hasFieldErrors:
+		return ErrorCodeValidation
+	case status == http.StatusBadRequest:
+		return "bad_request"
+	case status == http.StatusUnauthorized:
+		return "unauthorized"
+	case status == http.StatusForbidden:
+		return "forbidden"
+	case status == http.StatusNotFound:
+		return "not_found"
+	case status == http.StatusConflict:
+		return "conflict"
+	case status == http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	case status == http.StatusUnprocessableEntity:
+		return "unprocessable_entity"
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status == http.StatusServiceUnavailable:
+		return "service_unavailable"
+	case status >= http.StatusInternalServerError:
+		return "internal_error"
+	default:
+		return "request_failed"
+	}
+}
+
+// bindJSON binds the request body and, when it is invalid, responds 400 with
+// per-field errors. Handlers return when it reports false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+	c.Error(err).SetType(gin.ErrorTypeBind)
+
+	message := "Invalid request body"
+	if len(fieldErrorsOf(err)) == 0 {
+		message = "Invalid request body: " + err.Error()
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": ErrorCodeValidation, "message": message})
+	return false
+}
+
+// fieldErrorsFrom collects field errors from binding errors recorded on c
+func fieldErrorsFrom(c *gin.Context) []FieldError {
+	var out []FieldError
+	for _, e := range c.Errors.ByType(gin.ErrorTypeBind) {
+		out = append(out, fieldErrorsOf(e.Err)...)
+	}
+	return out
+}
+
+func fieldErrorsOf(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		out := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			out = append(out, FieldError{Field: fieldPath(fe), Message: validationMessage(fe)})
+		}
+		return out
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Field: "", Message: "body must be valid JSON"}}
+	}
+	return nil
+}
+
+// fieldPath drops the struct name from a validator namespace:
+// CreateIncidentRequest.labels[0] -> labels[0]
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	}
+	return "failed " + fe.Tag() + " validation"
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
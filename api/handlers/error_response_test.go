@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newErrorSchemaRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware(), ErrorSchemaMiddleware())
+	r.POST("/things", func(c *gin.Context) {
+		var req struct {
+			Name  string `json:"name" binding:"required"`
+			Count int    `json:"count" binding:"min=1"`
+			Kind  string `json:"kind" binding:"omitempty,oneof=a b"`
+		}
+		if !bindJSON(c, &req) {
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"name": req.Name})
+	})
+	r.GET("/legacy", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization_id is required", "message": "Provide org_id"})
+	})
+	r.GET("/coded", func(c *gin.Context) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization_required", "message": "Authorization header is required"})
+	})
+	return r
+}
+
+func serveErrorSchema(t *testing.T, r *gin.Engine, req *http.Request) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+func TestBindJSONReportsFieldErrors(t *testing.T) {
+	r := newErrorSchemaRouter()
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(`{"count": 0, "kind": "c"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(RequestIDHeader, "req-123")
+
+	w, body := serveErrorSchema(t, r, req)
+	if w.Code != http.StatusBadRequest || body["code"] != ErrorCodeValidation || body["request_id"] != "req-123" {
+		t.Fatalf("unexpected response %d %v", w.Code, body)
+	}
+	if w.Header().Get(RequestIDHeader) != "req-123" {
+		t.Errorf("request ID not echoed")
+	}
+
+	got := map[string]string{}
+	for _, fe := range body["field_errors"].([]interface{}) {
+		fe := fe.(map[string]interface{})
+		got[fe["field"].(string)] = fe["message"].(string)
+	}
+	want := map[string]string{"name": "is required", "count": "must be at least 1", "kind": "must be one of: a, b"}
+	for field, message := range want {
+		if got[field] != message {
+			t.Errorf("%s: got %q, want %q", field, got[field], message)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(`{"name": 5}`))
+	req.Header.Set("Content-Type", "application/json")
+	_, body = serveErrorSchema(t, r, req)
+	fieldErrors, _ := body["field_errors"].([]interface{})
+	if len(fieldErrors) != 1 || fieldErrors[0].(map[string]interface{})["field"] != "name" {
+		t.Errorf("expected a type error on name, got %v", body)
+	}
+	if body["request_id"] == "" {
+		t.Errorf("expected a generated request ID")
+	}
+}
+
+func TestErrorSchemaNormalizesLegacyShapes(t *testing.T) {
+	r := newErrorSchemaRouter()
+
+	_, body := serveErrorSchema(t, r, httptest.NewRequest(http.MethodGet, "/legacy", nil))
+	if body["code"] != "bad_request" || body["message"] != "organization_id is required" ||
+		body["error"] != "organization_id is required" || body["details"] != "Provide org_id" {
+		t.Errorf("unexpected legacy body: %v", body)
+	}
+
+	_, body = serveErrorSchema(t, r, httptest.NewRequest(http.MethodGet, "/coded", nil))
+	if body["code"] != "authorization_required" || body["message"] != "Authorization header is required" {
+		t.Errorf("unexpected coded body: %v", body)
+	}
+}
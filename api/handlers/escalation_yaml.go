@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/services"
+)
+
+// maxPolicyYAMLBytes bounds the size of an imported policy document
+const maxPolicyYAMLBytes = 256 << 10
+
+var policyFilenameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// requireGroupMember aborts with 403 unless the current user belongs to the group
+func (h *GroupHandler) requireGroupMember(c *gin.Context, groupID string) bool {
+	ok, err := h.GroupService.IsUserInGroup(groupID, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check group membership"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return false
+	}
+	return true
+}
+
+// ExportEscalationPolicyYAML handles GET /groups/:id/escalation-policies/:policy_id/export
+func (h *GroupHandler) ExportEscalationPolicyYAML(c *gin.Context) {
+	groupID := c.Param("id")
+	if !h.requireGroupMember(c, groupID) {
+		return
+	}
+
+	data, err := h.EscalationService.ExportEscalationPolicyYAML(groupID, c.Param("policy_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrEscalationPolicyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export escalation policy", "details": err.Error()})
+		return
+	}
+
+	filename := strings.Trim(policyFilenameUnsafe.ReplaceAllString(strings.ToLower(c.Param("policy_id")), "-"), "-")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="escalation-policy-%s.yaml"`, filename))
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// ImportEscalationPolicyYAML handles POST /groups/:id/escalation-policies/import.
+// The request body is the YAML document; ?dry_run=true validates it without saving.
+func (h *GroupHandler) ImportEscalationPolicyYAML(c *gin.Context) {
+	groupID := c.Param("id")
+	if !h.requireGroupMember(c, groupID) {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPolicyYAMLBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(data) > maxPolicyYAMLBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Policy document is too large"})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	policy, created, err := h.EscalationService.ImportEscalationPolicyYAML(groupID, c.GetString("user_id"), data, dryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPolicyYAML) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import escalation policy", "details": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created && !dryRun {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"policy":  policy,
+		"created": created,
+		"dry_run": dryRun,
+	})
+}
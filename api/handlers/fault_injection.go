@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// FaultInjectionHandler lets operators inject and clear faults for
+// resilience testing. Its routes only exist when fault_injection_enabled is
+// set, and only organization admins may use them.
+type FaultInjectionHandler struct {
+	FaultInjectionService *services.FaultInjectionService
+	authorizer            authz.Authorizer
+}
+
+func NewFaultInjectionHandler(faultInjectionService *services.FaultInjectionService, authorizer authz.Authorizer) *FaultInjectionHandler {
+	return &FaultInjectionHandler{FaultInjectionService: faultInjectionService, authorizer: authorizer}
+}
+
+// RequireOrgAdmin lets a request through when the caller can manage the
+// organization it names (org_id query param or X-Org-ID header)
+func (h *FaultInjectionHandler) RequireOrgAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+		if orgID == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "organization_id is required",
+				"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+			})
+			return
+		}
+		if !h.authorizer.Check(c.Request.Context(), userID, authz.ActionManage, authz.ResourceOrg, orgID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only organization admins can inject faults"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListFaults returns the faults in force
+// GET /internal/faults
+func (h *FaultInjectionHandler) ListFaults(c *gin.Context) {
+	faults, err := h.FaultInjectionService.ListFaults()
+	if err != nil {
+		log.Printf("ListFaults error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list faults"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"faults": faults, "total": len(faults)})
+}
+
+// CreateFault injects a fault
+// POST /internal/faults
+// Body: {"kind": "db_latency|pgmq_failure|provider_outage", "target", "delay_ms", "rate", "duration_seconds", "note"}
+func (h *FaultInjectionHandler) CreateFault(c *gin.Context) {
+	var req db.CreateFaultRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	fault, err := h.FaultInjectionService.CreateFault(req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFault) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("CreateFault error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create fault"})
+		return
+	}
+	c.JSON(http.StatusCreated, fault)
+}
+
+// DeleteFault clears one fault
+// DELETE /internal/faults/:id
+func (h *FaultInjectionHandler) DeleteFault(c *gin.Context) {
+	if err := h.FaultInjectionService.DeleteFault(c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrFaultNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Fault not found"})
+			return
+		}
+		log.Printf("DeleteFault error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete fault"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Fault cleared"})
+}
+
+// ClearFaults clears every fault
+// DELETE /internal/faults
+func (h *FaultInjectionHandler) ClearFaults(c *gin.Context) {
+	cleared, err := h.FaultInjectionService.ClearFaults()
+	if err != nil {
+		log.Printf("ClearFaults error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear faults"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Faults cleared", "cleared": cleared})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// FeatureFlagHandler exposes feature flags to the frontend and lets org
+// admins opt their organization in or out
+type FeatureFlagHandler struct {
+	FeatureFlagService *services.FeatureFlagService
+}
+
+// NewFeatureFlagHandler creates a new FeatureFlagHandler
+func NewFeatureFlagHandler(featureFlagService *services.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{FeatureFlagService: featureFlagService}
+}
+
+// GetFeatures handles GET /features
+// Effective flags for the current organization (org_id query param or
+// X-Org-ID header) as a key -> enabled map, plus the full flag details
+func (h *FeatureFlagHandler) GetFeatures(c *gin.Context) {
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+
+	flags, err := h.FeatureFlagService.ListFlags(orgID)
+	if err != nil {
+		log.Printf("ListFeatureFlags error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feature flags"})
+		return
+	}
+
+	features := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		features[flag.Key] = flag.Enabled
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"organization_id": orgID,
+		"features":        features,
+		"flags":           flags,
+	})
+}
+
+// ListOrgFlags handles GET /orgs/:id/features
+func (h *FeatureFlagHandler) ListOrgFlags(c *gin.Context) {
+	flags, err := h.FeatureFlagService.ListFlags(c.Param("id"))
+	if err != nil {
+		log.Printf("ListFeatureFlags error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feature flags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"flags": flags, "total": len(flags)})
+}
+
+// SetOrgFlag handles PUT /orgs/:id/features/:key
+// {"enabled": true|false} sets the org's value, {"enabled": null} removes it
+func (h *FeatureFlagHandler) SetOrgFlag(c *gin.Context) {
+	var req db.SetOrgFeatureFlagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	flag, err := h.FeatureFlagService.SetOrgFlag(c.Param("id"), c.Param("key"), req.Enabled, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrFeatureFlagNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature flag not found"})
+			return
+		}
+		log.Printf("SetOrgFeatureFlag error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature flag"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"flag": flag})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListFreezePeriods handles GET /orgs/:id/freeze-periods?from=&to=
+// Returns org-wide and per-group freeze periods overlapping the range
+func (h *IncidentHandler) ListFreezePeriods(c *gin.Context) {
+	from, to, err := parseCalendarRange(c, h.incidentService.OrgDisplaySettings(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	periods, err := h.incidentService.ListFreezePeriods(c.Param("id"), from, to)
+	if err != nil {
+		log.Printf("ListFreezePeriods error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list freeze periods"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"freeze_periods": periods, "from": from, "to": to, "total": len(periods)})
+}
+
+// CreateFreezePeriod handles POST /orgs/:id/freeze-periods
+func (h *IncidentHandler) CreateFreezePeriod(c *gin.Context) {
+	var req db.CreateFreezePeriodRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	period, err := h.incidentService.CreateFreezePeriod(c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		h.handleFreezePeriodError(c, err, "Failed to create freeze period")
+		return
+	}
+	c.JSON(http.StatusCreated, period)
+}
+
+// UpdateFreezePeriod handles PATCH /orgs/:id/freeze-periods/:period_id
+func (h *IncidentHandler) UpdateFreezePeriod(c *gin.Context) {
+	var req db.UpdateFreezePeriodRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	period, err := h.incidentService.UpdateFreezePeriod(c.Param("id"), c.Param("period_id"), req)
+	if err != nil {
+		h.handleFreezePeriodError(c, err, "Failed to update freeze period")
+		return
+	}
+	c.JSON(http.StatusOK, period)
+}
+
+// DeleteFreezePeriod handles DELETE /orgs/:id/freeze-periods/:period_id
+func (h *IncidentHandler) DeleteFreezePeriod(c *gin.Context) {
+	if err := h.incidentService.DeleteFreezePeriod(c.Param("id"), c.Param("period_id")); err != nil {
+		h.handleFreezePeriodError(c, err, "Failed to delete freeze period")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Freeze period deleted"})
+}
+
+func (h *IncidentHandler) handleFreezePeriodError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrFreezePeriodNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Freeze period not found"})
+	case errors.Is(err, services.ErrInvalidFreezePeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// GRAFANA DATA SOURCE (read token)
+// Speaks the SimpleJSON protocol (also used by the JSON API and Infinity
+// plugins): point the data source at <api>/read/grafana with the read token
+// as a Bearer header.
+
+// GrafanaTestDatasource handles GET /read/grafana, Grafana's "Save & test"
+func (h *ReadTokenHandler) GrafanaTestDatasource(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GrafanaSearch handles POST /read/grafana/search
+// Lists the metrics the token may query
+func (h *ReadTokenHandler) GrafanaSearch(c *gin.Context) {
+	c.JSON(http.StatusOK, h.ReadTokenService.GrafanaMetrics(readToken(c)))
+}
+
+// GrafanaQuery handles POST /read/grafana/query (SimpleJSON body) and
+// GET /read/grafana/query?target=&from=&to=&interval_ms=&max_points= for
+// Infinity URL queries. from/to are RFC3339 or Unix milliseconds, as
+// ${__from} and ${__to} expand to. service_id, group_id and severity narrow
+// incident metrics.
+func (h *ReadTokenHandler) GrafanaQuery(c *gin.Context) {
+	var req db.GrafanaQueryRequest
+	if c.Request.Method == http.MethodGet {
+		var err error
+		if req, err = grafanaQueryFromParams(c); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if !bindJSON(c, &req) {
+		return
+	}
+
+	results, err := h.ReadTokenService.QueryGrafana(readToken(c), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidGrafanaQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrGrafanaScopeMissing):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			h.handleError(c, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func grafanaQueryFromParams(c *gin.Context) (db.GrafanaQueryRequest, error) {
+	var req db.GrafanaQueryRequest
+	var err error
+	if req.Range.From, err = parseGrafanaTime(c.Query("from")); err != nil {
+		return req, errors.New("from must be an RFC3339 timestamp or Unix milliseconds")
+	}
+	if req.Range.To, err = parseGrafanaTime(c.Query("to")); err != nil {
+		return req, errors.New("to must be an RFC3339 timestamp or Unix milliseconds")
+	}
+	req.IntervalMs, _ = strconv.ParseInt(c.Query("interval_ms"), 10, 64)
+	req.MaxDataPoints, _ = strconv.Atoi(c.Query("max_points"))
+
+	payload := map[string]interface{}{}
+	for _, key := range []string{"service_id", "group_id", "severity"} {
+		if v := c.Query(key); v != "" {
+			payload[key] = v
+		}
+	}
+	for _, target := range c.QueryArray("target") {
+		req.Targets = append(req.Targets, db.GrafanaTarget{Target: target, RefID: target, Payload: payload})
+	}
+	return req, nil
+}
+
+func parseGrafanaTime(v string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
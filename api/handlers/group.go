@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,14 +14,16 @@ import (
 )
 
 type GroupHandler struct {
-	GroupService      *services.GroupService
-	EscalationService *services.EscalationService
+	GroupService         *services.GroupService
+	EscalationService    *services.EscalationService
+	PendingChangeService *services.PendingChangeService
 }
 
-func NewGroupHandler(groupService *services.GroupService, escalationService *services.EscalationService) *GroupHandler {
+func NewGroupHandler(groupService *services.GroupService, escalationService *services.EscalationService, pendingChangeService *services.PendingChangeService) *GroupHandler {
 	return &GroupHandler{
-		GroupService:      groupService,
-		EscalationService: escalationService,
+		GroupService:         groupService,
+		EscalationService:    escalationService,
+		PendingChangeService: pendingChangeService,
 	}
 }
 
@@ -104,8 +107,7 @@ func (h *GroupHandler) GetGroupWithMembers(c *gin.Context) {
 // CreateGroup creates a new group
 func (h *GroupHandler) CreateGroup(c *gin.Context) {
 	var req db.CreateGroupRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -144,6 +146,10 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 
 	group, err := h.GroupService.CreateGroup(req, userID.(string))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidParentGroup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("Failed to create group: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
 		return
@@ -160,13 +166,16 @@ func (h *GroupHandler) UpdateGroup(c *gin.Context) {
 	id := c.Param("id")
 
 	var req db.UpdateGroupRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	group, err := h.GroupService.UpdateGroup(id, req)
 	if err != nil {
+		if errors.Is(err, services.ErrPolicyNotInGroup) || errors.Is(err, services.ErrInvalidParentGroup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
 		return
 	}
@@ -177,11 +186,31 @@ func (h *GroupHandler) UpdateGroup(c *gin.Context) {
 	})
 }
 
-// DeleteGroup soft deletes a group
+// DeleteGroup soft deletes a group. A team that still has members is only
+// deleted once another org admin approves; until then this answers 202 with
+// the pending change.
+// DELETE /groups/{id}?reason=...
 func (h *GroupHandler) DeleteGroup(c *gin.Context) {
 	id := c.Param("id")
 
-	err := h.GroupService.DeleteGroup(id)
+	change, err := h.PendingChangeService.GroupDeleteChange(id)
+	if err != nil {
+		log.Printf("DeleteGroup approval check error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+		return
+	}
+	if change != nil {
+		pending, err := h.PendingChangeService.RequestChange(change, c.GetString("user_id"), c.Query("reason"))
+		if err != nil {
+			log.Printf("DeleteGroup approval request error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request group deletion"})
+			return
+		}
+		respondPendingChange(c, pending)
+		return
+	}
+
+	err = h.GroupService.DeleteGroup(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
 		return
@@ -190,13 +219,35 @@ func (h *GroupHandler) DeleteGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
 }
 
+// GetSubGroups lists every sub-team below a group
+// GET /groups/{id}/subgroups
+func (h *GroupHandler) GetSubGroups(c *gin.Context) {
+	subGroups, err := h.GroupService.GetSubGroups(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sub-groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subgroups": subGroups,
+		"total":     len(subGroups),
+	})
+}
+
 // GROUP MEMBER MANAGEMENT ENDPOINTS
 
 // GetGroupMembers retrieves all members of a group
+// ?include_subgroups=true rolls up the members of its sub-teams
 func (h *GroupHandler) GetGroupMembers(c *gin.Context) {
 	groupID := c.Param("id")
 
-	members, err := h.GroupService.GetGroupMembers(groupID)
+	var members []db.GroupMember
+	var err error
+	if rollup, _ := strconv.ParseBool(c.Query("include_subgroups")); rollup {
+		members, err = h.GroupService.GetRollupMembers(groupID)
+	} else {
+		members, err = h.GroupService.GetGroupMembers(groupID)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve group members"})
 		return
@@ -213,8 +264,7 @@ func (h *GroupHandler) AddGroupMember(c *gin.Context) {
 	groupID := c.Param("id")
 
 	var req db.AddGroupMemberRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -252,8 +302,7 @@ func (h *GroupHandler) UpdateGroupMember(c *gin.Context) {
 	memberUserID := c.Param("user_id")
 
 	var req db.UpdateGroupMemberRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -280,7 +329,13 @@ func (h *GroupHandler) RemoveGroupMember(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Member removed from group successfully"})
+	response := gin.H{"message": "Member removed from group successfully"}
+	if count, err := h.GroupService.CountFutureShifts(groupID, memberUserID); err == nil && count > 0 {
+		// Their shifts stay until the schedules are regenerated
+		response["future_shifts"] = count
+		response["regenerate_schedules_url"] = "/groups/" + groupID + "/schedulers/regenerate"
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // ESCALATION RULE MANAGEMENT ENDPOINTS
@@ -655,8 +710,7 @@ func (h *GroupHandler) AddMultipleGroupMembers(c *gin.Context) {
 	var req struct {
 		Members []db.AddGroupMemberRequest `json:"members" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -818,7 +872,7 @@ func (h *GroupHandler) DeleteEscalationPolicy(c *gin.Context) {
 	}
 
 	// Delete escalation policy
-	err = h.EscalationService.DeleteEscalationPolicy(policyID)
+	err = h.EscalationService.DeleteEscalationPolicy(policyID, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
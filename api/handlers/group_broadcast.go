@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// GroupBroadcastHandler sends emergency pages to a whole group
+type GroupBroadcastHandler struct {
+	IncidentService *services.IncidentService
+	authorizer      authz.Authorizer
+}
+
+func NewGroupBroadcastHandler(incidentService *services.IncidentService, authorizer authz.Authorizer) *GroupBroadcastHandler {
+	return &GroupBroadcastHandler{IncidentService: incidentService, authorizer: authorizer}
+}
+
+// Broadcast pages every active member of a group at once, regardless of
+// schedule, for events where the escalation ladder is too slow
+// POST /groups/:id/broadcast
+// Only group leaders and org admins may broadcast.
+func (h *GroupBroadcastHandler) Broadcast(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+
+	groupID := c.Param("id")
+	if !h.authorizer.Check(c.Request.Context(), userID, authz.ActionManage, authz.ResourceOrg, orgID) {
+		isLeader, err := h.IncidentService.CanBroadcastToGroup(groupID, userID)
+		if err != nil {
+			log.Printf("Broadcast permission check error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check group permissions"})
+			return
+		}
+		if !isLeader {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only group leaders and organization admins can broadcast to a group"})
+			return
+		}
+	}
+
+	var req db.GroupBroadcastRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	broadcast, err := h.IncidentService.BroadcastToGroup(groupID, orgID, req, userID)
+	if err != nil {
+		var rateErr *services.RateLimitError
+		switch {
+		case errors.As(err, &rateErr):
+			abortRateLimited(c, err)
+		case errors.Is(err, services.ErrBroadcastGroupNotFound), errors.Is(err, services.ErrBroadcastIncidentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBroadcastNoRecipients):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIncidentResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Broadcast error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to broadcast to group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"broadcast": broadcast,
+		"message":   "Broadcast sent to all active group members",
+	})
+}
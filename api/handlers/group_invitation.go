@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const invitationsAdminOnly = "Only group leaders can manage invitations and join requests"
+
+// GroupInvitationHandler handles group invitations and join requests
+type GroupInvitationHandler struct {
+	InvitationService *services.GroupInvitationService
+}
+
+// NewGroupInvitationHandler creates a new GroupInvitationHandler
+func NewGroupInvitationHandler(invitationService *services.GroupInvitationService) *GroupInvitationHandler {
+	return &GroupInvitationHandler{InvitationService: invitationService}
+}
+
+// requireGroupAdmin aborts with 403 and deniedMsg unless the current user is
+// an admin (leader) of the group
+func requireGroupAdmin(c *gin.Context, invitations *services.GroupInvitationService, groupID, deniedMsg string) bool {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return false
+	}
+
+	isAdmin, err := invitations.IsGroupAdmin(groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check group permissions"})
+		return false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": deniedMsg})
+		return false
+	}
+	return true
+}
+
+// CreateInvitation handles POST /groups/:id/invitations
+func (h *GroupInvitationHandler) CreateInvitation(c *gin.Context) {
+	groupID := c.Param("id")
+
+	var req db.CreateGroupInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !requireGroupAdmin(c, h.InvitationService, groupID, invitationsAdminOnly) {
+		return
+	}
+
+	invitation, err := h.InvitationService.CreateInvitation(groupID, req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrAlreadyGroupMember) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("CreateInvitation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"message":    "Invitation created successfully",
+	})
+}
+
+// ListInvitations handles GET /groups/:id/invitations?status=pending
+func (h *GroupInvitationHandler) ListInvitations(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, invitationsAdminOnly) {
+		return
+	}
+
+	invitations, err := h.InvitationService.ListInvitations(groupID, c.Query("status"))
+	if err != nil {
+		log.Printf("ListInvitations error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"total":       len(invitations),
+	})
+}
+
+// RevokeInvitation handles DELETE /groups/:id/invitations/:invitation_id
+func (h *GroupInvitationHandler) RevokeInvitation(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, invitationsAdminOnly) {
+		return
+	}
+
+	if err := h.InvitationService.RevokeInvitation(groupID, c.Param("invitation_id")); err != nil {
+		if errors.Is(err, services.ErrInvitationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending invitation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitation handles POST /group-invitations/accept
+// The token comes from the emailed join link; the authenticated user's email must match.
+func (h *GroupInvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req db.AcceptGroupInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	member, err := h.InvitationService.AcceptInvitation(req.Token, userID, c.GetString("user_email"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvitationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		case errors.Is(err, services.ErrInvitationNotPending), errors.Is(err, services.ErrInvitationExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvitationEmailMismatch), errors.Is(err, services.ErrInvitationOrgMembershipRequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			log.Printf("AcceptInvitation error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"member":  member,
+		"message": "Invitation accepted successfully",
+	})
+}
+
+// CreateJoinRequest handles POST /groups/:id/join-requests
+func (h *GroupInvitationHandler) CreateJoinRequest(c *gin.Context) {
+	groupID := c.Param("id")
+
+	var req db.CreateGroupJoinRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	joinRequest, err := h.InvitationService.CreateJoinRequest(groupID, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrJoinRequestNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAlreadyGroupMember):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("CreateJoinRequest error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create join request"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"join_request": joinRequest,
+		"message":      "Join request submitted successfully",
+	})
+}
+
+// ListJoinRequests handles GET /groups/:id/join-requests?status=pending
+func (h *GroupInvitationHandler) ListJoinRequests(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, invitationsAdminOnly) {
+		return
+	}
+
+	requests, err := h.InvitationService.ListJoinRequests(groupID, c.Query("status"))
+	if err != nil {
+		log.Printf("ListJoinRequests error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve join requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"join_requests": requests,
+		"total":         len(requests),
+	})
+}
+
+// ApproveJoinRequest handles POST /groups/:id/join-requests/:request_id/approve
+func (h *GroupInvitationHandler) ApproveJoinRequest(c *gin.Context) {
+	h.reviewJoinRequest(c, true)
+}
+
+// RejectJoinRequest handles POST /groups/:id/join-requests/:request_id/reject
+func (h *GroupInvitationHandler) RejectJoinRequest(c *gin.Context) {
+	h.reviewJoinRequest(c, false)
+}
+
+func (h *GroupInvitationHandler) reviewJoinRequest(c *gin.Context, approve bool) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, invitationsAdminOnly) {
+		return
+	}
+
+	joinRequest, err := h.InvitationService.ReviewJoinRequest(groupID, c.Param("request_id"), c.GetString("user_id"), approve)
+	if err != nil {
+		if errors.Is(err, services.ErrJoinRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending join request not found"})
+			return
+		}
+		log.Printf("ReviewJoinRequest error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"join_request": joinRequest,
+		"message":      "Join request " + joinRequest.Status,
+	})
+}
+
+// CancelJoinRequest handles DELETE /groups/:id/join-requests/:request_id (requester only)
+func (h *GroupInvitationHandler) CancelJoinRequest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.InvitationService.CancelJoinRequest(c.Param("id"), c.Param("request_id"), userID); err != nil {
+		if errors.Is(err, services.ErrJoinRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending join request not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel join request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join request cancelled"})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// incidentIdempotency tracks the Idempotency-Key of a single create request
+type incidentIdempotency struct {
+	service *services.IncidentService
+	scope   string
+	key     string
+}
+
+// reserveIncidentIdempotency reserves the request's Idempotency-Key, if any.
+// It returns the previously created incident when the request is a retry.
+// Without the header it returns a nil reservation and the request proceeds as usual.
+func (h *IncidentHandler) reserveIncidentIdempotency(c *gin.Context, scope string, request ...interface{}) (*incidentIdempotency, *db.Incident, error) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		return nil, nil, nil
+	}
+	if err := services.ValidateIdempotencyKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	existingID, err := h.incidentService.ReserveIdempotencyKey(scope, key, services.HashIdempotentRequest(request...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if existingID != "" {
+		existing, err := h.incidentService.GetIncident(existingID)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		return nil, &existing.Incident, nil
+	}
+	return &incidentIdempotency{service: h.incidentService, scope: scope, key: key}, nil, nil
+}
+
+// finish records the created incident, or releases the key if creation failed
+func (r *incidentIdempotency) finish(incident *db.Incident) {
+	if r == nil {
+		return
+	}
+	var err error
+	if incident != nil {
+		err = r.service.CompleteIdempotencyKey(r.scope, r.key, incident.ID)
+	} else {
+		err = r.service.ReleaseIdempotencyKey(r.scope, r.key)
+	}
+	if err != nil {
+		log.Printf("WARNING: idempotency key %q (%s): %v", r.key, r.scope, err)
+	}
+}
+
+// idempotencyErrorStatus maps reservation errors to HTTP status codes
+func idempotencyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidIdempotencyKey):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrIdempotencyRequestInFlight):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,7 +33,48 @@ func NewIncidentHandler(incidentService *services.IncidentService, serviceServic
 	}
 }
 
+// ResolveIncidentNumber lets every /incidents/:id route take an incident
+// number (INC-1024) in place of the UUID. The number is looked up in the
+// current organization and :id rewritten, so handlers check access as usual.
+func (h *IncidentHandler) ResolveIncidentNumber() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		number, ok := services.ParseIncidentNumber(c.Param("id"))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+		if orgID == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "organization_id is required",
+				"message": "Incident numbers are per organization; provide org_id query param or X-Org-ID header",
+			})
+			return
+		}
+
+		incidentID, err := h.incidentService.ResolveIncidentNumber(orgID, number)
+		if err != nil {
+			if errors.Is(err, services.ErrIncidentNumberNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+				return
+			}
+			log.Printf("Resolve incident number error: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to get incident"})
+			return
+		}
+
+		for i := range c.Params {
+			if c.Params[i].Key == "id" {
+				c.Params[i].Value = incidentID
+			}
+		}
+		c.Next()
+	}
+}
+
 // ListIncidents handles GET /incidents and GET /projects/:project_id/incidents
+// ?fields=title,status,assigned_to_name trims each incident to those fields
 // ReBAC: Uses organization context for MANDATORY tenant isolation
 func (h *IncidentHandler) ListIncidents(c *gin.Context) {
 	// =========================================================================
@@ -65,6 +108,12 @@ func (h *IncidentHandler) ListIncidents(c *gin.Context) {
 	if status := c.Query("status"); status != "" {
 		filters["status"] = status
 	}
+	if workflowState := c.Query("workflow_state"); workflowState != "" {
+		filters["workflow_state"] = workflowState
+	}
+	if isTest, err := strconv.ParseBool(c.Query("is_test")); err == nil {
+		filters["is_test"] = isTest
+	}
 	if urgency := c.Query("urgency"); urgency != "" {
 		filters["urgency"] = urgency
 	}
@@ -80,6 +129,18 @@ func (h *IncidentHandler) ListIncidents(c *gin.Context) {
 	if serviceID := c.Query("service_id"); serviceID != "" {
 		filters["service_id"] = serviceID
 	}
+	if tag := c.Query("tag"); tag != "" {
+		filters["tag"] = tag
+	}
+	if team := c.Query("team"); team != "" {
+		filters["team"] = team
+	}
+	if component := c.Query("component"); component != "" {
+		filters["component"] = component
+	}
+	if environment := c.Query("environment"); environment != "" {
+		filters["environment"] = strings.ToLower(environment)
+	}
 	if sort := c.Query("sort"); sort != "" {
 		filters["sort"] = sort
 	}
@@ -104,6 +165,12 @@ func (h *IncidentHandler) ListIncidents(c *gin.Context) {
 		})
 		return
 	}
+	if wantsInclude(c, "durations") {
+		now := time.Now()
+		for i := range incidents {
+			incidents[i].IncidentDurations = services.IncidentDurations(&incidents[i].Incident, now)
+		}
+	}
 
 	// Calculate pagination info
 	total := len(incidents)
@@ -116,8 +183,13 @@ func (h *IncidentHandler) ListIncidents(c *gin.Context) {
 		limit = l
 	}
 
+	items, ok := selectFields(c, incidents)
+	if !ok {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"incidents": incidents,
+		"incidents": items,
 		"page":      page,
 		"limit":     limit,
 		"total":     total,
@@ -148,10 +220,52 @@ func (h *IncidentHandler) GetIncident(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch incident", "details": err.Error()})
 		return
 	}
+	if wantsInclude(c, "durations") {
+		incident.IncidentDurations = services.IncidentDurations(&incident.Incident, time.Now())
+	} else {
+		// Durations of an open incident grow by the second
+		setLastModified(c, incidentLastModified(incident))
+	}
 
 	c.JSON(http.StatusOK, incident)
 }
 
+// incidentLastModified is the latest change GetIncident can see in an
+// incident: its own updates, timeline events, tasks, page receipts and claim
+func incidentLastModified(incident *db.IncidentResponse) time.Time {
+	latest := incident.UpdatedAt
+	later := func(t *time.Time) {
+		if t != nil && t.After(latest) {
+			latest = *t
+		}
+	}
+	for i := range incident.RecentEvents {
+		later(&incident.RecentEvents[i].CreatedAt)
+	}
+	for i := range incident.Tasks {
+		later(&incident.Tasks[i].UpdatedAt)
+	}
+	for i := range incident.Receipts {
+		later(&incident.Receipts[i].SentAt)
+		later(incident.Receipts[i].DeliveredAt)
+		later(incident.Receipts[i].SeenAt)
+	}
+	if incident.Claim != nil {
+		later(incident.Claim.ClaimedAt)
+	}
+	return latest
+}
+
+// wantsInclude reports whether ?include= (comma-separated) asks for what
+func wantsInclude(c *gin.Context, what string) bool {
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(include) == what {
+			return true
+		}
+	}
+	return false
+}
+
 // checkIncidentAccess verifies if the user has permission to access the incident
 // ReBAC: project_id is MANDATORY - all incidents must belong to a project
 func (h *IncidentHandler) checkIncidentAccess(c *gin.Context, incidentID string, action authz.Action) (*db.IncidentResponse, error) {
@@ -179,6 +293,52 @@ func (h *IncidentHandler) checkIncidentAccess(c *gin.Context, incidentID string,
 	return nil, fmt.Errorf("forbidden")
 }
 
+// incidentViewDenied is the 403 message when the caller cannot view the incident
+const incidentViewDenied = "You do not have permission to view this incident"
+
+// authorizeIncident checks the caller may perform action on the incident and
+// writes the error response if not, with deniedMsg when access is forbidden
+func (h *IncidentHandler) authorizeIncident(c *gin.Context, id string, action authz.Action, deniedMsg string) bool {
+	_, err := h.checkIncidentAccess(c, id, action)
+	if err == nil {
+		return true
+	}
+	switch err.Error() {
+	case "incident not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+	case "forbidden":
+		c.JSON(http.StatusForbidden, gin.H{"error": deniedMsg})
+	case "unauthorized":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission", "details": err.Error()})
+	}
+	return false
+}
+
+// checkOrgAccess returns the current organization when the user may perform
+// action in it. Otherwise it writes the error response and returns false.
+func (h *IncidentHandler) checkOrgAccess(c *gin.Context, action authz.Action) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return "", false
+	}
+	if !h.authorizer.Check(c.Request.Context(), userID, action, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this organization"})
+		return "", false
+	}
+	return orgID, true
+}
+
 // CreateIncident handles POST /incidents
 func (h *IncidentHandler) CreateIncident(c *gin.Context) {
 	var req db.CreateIncidentRequest
@@ -208,6 +368,23 @@ func (h *IncidentHandler) CreateIncident(c *gin.Context) {
 		projectID = req.ProjectID
 	}
 
+	// Retried requests with the same Idempotency-Key get the original incident back
+	idempotency, replayed, err := h.reserveIncidentIdempotency(c, "org:"+organizationID, req, projectID)
+	if err != nil {
+		status := idempotencyErrorStatus(err)
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			log.Printf("ERROR: Failed to reserve idempotency key: %v", err)
+			message = "Failed to create incident"
+		}
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	if replayed != nil {
+		c.JSON(http.StatusCreated, replayed)
+		return
+	}
+
 	// Convert request to incident
 	incident := &db.Incident{
 		Title:              req.Title,
@@ -226,6 +403,9 @@ func (h *IncidentHandler) CreateIncident(c *gin.Context) {
 		OrganizationID:     organizationID,
 	}
 
+	// Priority and urgency not given explicitly come from the service's severity mapping
+	h.incidentService.ApplySeverityMapping(incident, true)
+
 	// Set default urgency if not provided
 	if incident.Urgency == "" {
 		incident.Urgency = db.IncidentUrgencyHigh
@@ -256,6 +436,7 @@ func (h *IncidentHandler) CreateIncident(c *gin.Context) {
 	log.Printf("DEBUG: Final incident state before creation - AssignedTo: '%s', AssignedAt: %v", incident.AssignedTo, incident.AssignedAt)
 
 	createdIncident, err := h.incidentService.CreateIncident(incident)
+	idempotency.finish(createdIncident)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create incident",
@@ -550,6 +731,62 @@ func (h *IncidentHandler) EscalateIncident(c *gin.Context) {
 	})
 }
 
+// PageIncident handles POST /incidents/:id/page
+// Pages an extra user, scheduler or group (whoever is on call) onto the incident
+// outside the escalation policy
+func (h *IncidentHandler) PageIncident(c *gin.Context) {
+	id := c.Param("id")
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	// Check permission (ActionUpdate)
+	_, err := h.checkIncidentAccess(c, id, authz.ActionUpdate)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		if err.Error() == "forbidden" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to page responders for this incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission", "details": err.Error()})
+		return
+	}
+
+	var req db.PageIncidentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.incidentService.PageResponder(id, req, userID.(string))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPageTargetNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoOnCallResponder), errors.Is(err, services.ErrIncidentResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to page responder",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Responder paged successfully",
+		"page":    result,
+	})
+}
+
 // AddIncidentNote handles POST /incidents/:id/notes
 func (h *IncidentHandler) AddIncidentNote(c *gin.Context) {
 	id := c.Param("id")
@@ -630,9 +867,46 @@ func (h *IncidentHandler) GetIncidentEvents(c *gin.Context) {
 	})
 }
 
+// GetEscalationTimeline handles GET /incidents/:id/escalation-timeline
+func (h *IncidentHandler) GetEscalationTimeline(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Incident ID is required",
+		})
+		return
+	}
+
+	incident, err := h.checkIncidentAccess(c, id, authz.ActionView)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		if err.Error() == "forbidden" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch incident", "details": err.Error()})
+		return
+	}
+
+	timeline, err := h.incidentService.GetEscalationTimeline(incident)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to build escalation timeline",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
 // GetIncidentStats handles GET /incidents/stats
 func (h *IncidentHandler) GetIncidentStats(c *gin.Context) {
-	stats, err := h.incidentService.GetIncidentStats()
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	stats, err := h.incidentService.GetIncidentStats(orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch incident stats",
@@ -715,8 +989,10 @@ func (h *IncidentHandler) WebhookCreateIncident(c *gin.Context) {
 			case db.WebhookActionResolve:
 				// TODO: Resolve existing incident
 			case db.WebhookActionTrigger:
-				// Update existing incident (increment alert count, update timestamp)
-				// TODO: Implement incident update
+				// Another alert joined the incident: count it and re-evaluate urgency
+				if _, err := h.incidentService.RecordIncidentAlert(existingIncident.ID, req.Payload.Severity); err != nil {
+					log.Printf("ERROR: Failed to record alert for incident %s: %v", existingIncident.ID, err)
+				}
 			}
 
 			c.JSON(http.StatusOK, db.WebhookIncidentResponse{
@@ -732,6 +1008,31 @@ func (h *IncidentHandler) WebhookCreateIncident(c *gin.Context) {
 
 	// Create new incident for trigger events
 	if req.EventAction == db.WebhookActionTrigger {
+		idempotency, replayed, err := h.reserveIncidentIdempotency(c, "service:"+service.ID, req)
+		if err != nil {
+			status := idempotencyErrorStatus(err)
+			message := err.Error()
+			if status == http.StatusInternalServerError {
+				log.Printf("ERROR: Failed to reserve idempotency key: %v", err)
+				message = "Failed to create incident"
+			}
+			c.JSON(status, db.WebhookIncidentResponse{
+				Status:  "error",
+				Message: message,
+			})
+			return
+		}
+		if replayed != nil {
+			c.JSON(http.StatusCreated, db.WebhookIncidentResponse{
+				Status:      "success",
+				Message:     "Incident created",
+				DedupKey:    req.DedupKey,
+				IncidentID:  replayed.ID,
+				IncidentKey: replayed.IncidentKey,
+			})
+			return
+		}
+
 		incident = &db.Incident{
 			Title:       req.Payload.Summary,
 			Description: fmt.Sprintf("Source: %s\nComponent: %s\nClass: %s", req.Payload.Source, req.Payload.Component, req.Payload.Class),
@@ -747,6 +1048,14 @@ func (h *IncidentHandler) WebhookCreateIncident(c *gin.Context) {
 		incident.ServiceID = service.ID
 		incident.GroupID = service.GroupID
 		incident.EscalationPolicyID = service.EscalationPolicyID
+
+		// Incidents sent with a test-mode API key are flagged as test
+		if key, ok := c.Get("api_key"); ok {
+			if apiKey, ok := key.(*db.APIKey); ok {
+				incident.APIKeyID = apiKey.ID
+				incident.IsTest = apiKey.TestMode
+			}
+		}
 		log.Printf("INFO: Incident will be created with org_id=%s, project_id=%s, service_id=%s",
 			incident.OrganizationID, incident.ProjectID, incident.ServiceID)
 
@@ -761,6 +1070,7 @@ func (h *IncidentHandler) WebhookCreateIncident(c *gin.Context) {
 		}
 
 		createdIncident, err := h.incidentService.CreateIncident(incident)
+		idempotency.finish(createdIncident)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, db.WebhookIncidentResponse{
 				Status:  "error",
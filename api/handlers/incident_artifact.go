@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// incidentArtifactsDenied is the 403 message for artifacts; reading them
+// needs view access, attaching and deleting them update access
+const incidentArtifactsDenied = "You do not have permission to access this incident's artifacts"
+
+// ListIncidentArtifacts handles GET /incidents/:id/artifacts
+// Lists the incident's diagnostic artifacts without their content
+func (h *IncidentHandler) ListIncidentArtifacts(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentArtifactsDenied) {
+		return
+	}
+
+	artifacts, err := h.incidentService.ListIncidentArtifacts(id)
+	if err != nil {
+		log.Printf("ListIncidentArtifacts error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve artifacts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"artifacts": artifacts, "total": len(artifacts)})
+}
+
+// GetIncidentArtifact handles GET /incidents/:id/artifacts/:artifact_id
+func (h *IncidentHandler) GetIncidentArtifact(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentArtifactsDenied) {
+		return
+	}
+
+	artifact, err := h.incidentService.GetIncidentArtifact(id, c.Param("artifact_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrIncidentArtifactNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Artifact not found or expired"})
+			return
+		}
+		log.Printf("GetIncidentArtifact error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve artifact"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"artifact": artifact})
+}
+
+// CreateIncidentArtifact handles POST /incidents/:id/artifacts
+// Attaches diagnostic output a responder ran themselves. Credentials are
+// redacted before it is stored.
+func (h *IncidentHandler) CreateIncidentArtifact(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentArtifactsDenied) {
+		return
+	}
+
+	var req db.CreateIncidentArtifactRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	artifact, err := h.incidentService.CreateIncidentArtifact(id, "", c.GetString("user_id"), db.ArtifactSourceUser, req)
+	if err != nil {
+		log.Printf("CreateIncidentArtifact error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store artifact"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"artifact": artifact})
+}
+
+// DeleteIncidentArtifact handles DELETE /incidents/:id/artifacts/:artifact_id
+func (h *IncidentHandler) DeleteIncidentArtifact(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentArtifactsDenied) {
+		return
+	}
+
+	if err := h.incidentService.DeleteIncidentArtifact(id, c.Param("artifact_id")); err != nil {
+		if errors.Is(err, services.ErrIncidentArtifactNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Artifact not found"})
+			return
+		}
+		log.Printf("DeleteIncidentArtifact error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete artifact"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Artifact deleted"})
+}
+
+// GetIncidentSnapshotImage handles GET /snapshots/:token (public)
+// Serves a snapshot image an integration uploaded, so Slack and chat channels
+// can show it. The token in the URL is the only credential.
+func (h *IncidentHandler) GetIncidentSnapshotImage(c *gin.Context) {
+	image, contentType, err := h.incidentService.GetIncidentSnapshotImage(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrIncidentArtifactNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found or expired"})
+			return
+		}
+		log.Printf("GetIncidentSnapshotImage error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve snapshot"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// SearchIncidentArtifacts handles GET /search/artifacts?q=
+// Full-text search over the diagnostic artifacts of incidents the user can see
+func (h *IncidentHandler) SearchIncidentArtifacts(c *gin.Context) {
+	filters := authz.GetReBACFilters(c)
+	if orgID, _ := filters["current_org_id"].(string); orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+	h.searchIncidentArtifacts(c, filters)
+}
+
+func (h *IncidentHandler) searchIncidentArtifacts(c *gin.Context, filters map[string]interface{}) {
+	filters["q"] = c.Query("q")
+	for _, param := range []string{"incident_id", "kind"} {
+		if value := c.Query(param); value != "" {
+			filters[param] = value
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters["limit"] = limit
+		}
+	}
+
+	results, err := h.incidentService.SearchIncidentArtifacts(filters)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search artifacts",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   c.Query("q"),
+		"results": results,
+		"total":   len(results),
+	})
+}
+
+// INTERNAL (AI agent relay, network-isolated)
+
+// InternalCreateIncidentArtifact handles POST /internal/incident-artifacts
+// The AI agent stores the output of diagnostic tools it ran for a user. The
+// incident must belong to the given organization.
+func (h *IncidentHandler) InternalCreateIncidentArtifact(c *gin.Context) {
+	var req db.InternalCreateIncidentArtifactRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	artifact, err := h.incidentService.CreateIncidentArtifact(req.IncidentID, req.OrgID, req.UserID, db.ArtifactSourceAgent,
+		req.CreateIncidentArtifactRequest)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		log.Printf("InternalCreateIncidentArtifact error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store artifact"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"artifact": artifact})
+}
+
+// InternalSearchIncidentArtifacts handles GET /internal/incident-artifacts/search?org_id=&user_id=&q=
+// Searches with the access of the user the agent is working for
+func (h *IncidentHandler) InternalSearchIncidentArtifacts(c *gin.Context) {
+	orgID, userID := c.Query("org_id"), c.Query("user_id")
+	if orgID == "" || userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "org_id and user_id are required"})
+		return
+	}
+	h.searchIncidentArtifacts(c, map[string]interface{}{
+		"current_org_id":  orgID,
+		"current_user_id": userID,
+	})
+}
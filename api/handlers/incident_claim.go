@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const incidentClaimDenied = "You do not have permission to claim this incident"
+
+// ClaimIncident handles POST /incidents/:id/claim
+// Takes the incident's assignment unless someone else got there first, in
+// which case 409 returns who holds it.
+func (h *IncidentHandler) ClaimIncident(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentClaimDenied) {
+		return
+	}
+
+	// The body is optional
+	var req db.ClaimIncidentRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	claim, err := h.incidentService.ClaimIncident(id, c.GetString("user_id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIncidentAlreadyClaimed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Already claimed by " + claimHolder(claim), "claim": claim})
+		case errors.Is(err, services.ErrIncidentAssigneeChanged):
+			c.JSON(http.StatusConflict, gin.H{"error": "The incident was reassigned in the meantime", "claim": claim})
+		case errors.Is(err, services.ErrIncidentNotClaimable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "claim": claim})
+		case errors.Is(err, services.ErrNotIncidentGroupMember):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "incident not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		default:
+			log.Printf("ClaimIncident error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim incident"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"claim": claim, "message": "Incident claimed"})
+}
+
+// UnclaimIncident handles POST /incidents/:id/unclaim
+// Releases the caller's claim so someone else can take the incident
+func (h *IncidentHandler) UnclaimIncident(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentClaimDenied) {
+		return
+	}
+
+	var req db.ClaimIncidentRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	claim, err := h.incidentService.UnclaimIncident(id, c.GetString("user_id"), req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIncidentNotClaimedByUser):
+			c.JSON(http.StatusConflict, gin.H{"error": "You haven't claimed this incident", "claim": claim})
+		case err.Error() == "incident not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		default:
+			log.Printf("UnclaimIncident error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unclaim incident"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"claim": claim, "message": "Incident unclaimed"})
+}
+
+func claimHolder(claim *db.IncidentClaim) string {
+	if claim == nil || claim.ClaimedBy == "" {
+		return "someone else"
+	}
+	if claim.ClaimedByName != "" {
+		return claim.ClaimedByName
+	}
+	return claim.ClaimedBy
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// drillError maps drill service errors to responses
+func drillError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrDrillNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Drill not found"})
+	case errors.Is(err, services.ErrDrillServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+	case errors.Is(err, services.ErrDrillNotCancelable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + ": " + err.Error()})
+	}
+}
+
+// CreateDrill schedules an on-call drill for a service, or starts it now
+// when scheduled_at is omitted
+// POST /services/{id}/drills
+// Requires manage access to the service's organization.
+func (h *IncidentHandler) CreateDrill(c *gin.Context) {
+	orgID, ok := authorizeServiceOrg(c, h.authorizer, h.serviceService, authz.ActionManage)
+	if !ok {
+		return
+	}
+	var req db.CreateIncidentDrillRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	drill, err := h.incidentService.CreateDrill(orgID, c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		drillError(c, err, "create drill")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"drill": drill})
+}
+
+// ListDrills returns a service's drills, newest first
+// GET /services/{id}/drills
+func (h *IncidentHandler) ListDrills(c *gin.Context) {
+	orgID, ok := authorizeServiceOrg(c, h.authorizer, h.serviceService, authz.ActionView)
+	if !ok {
+		return
+	}
+	drills, err := h.incidentService.ListDrills(orgID, c.Param("id"))
+	if err != nil {
+		drillError(c, err, "list drills")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"drills": drills,
+		"count":  len(drills),
+	})
+}
+
+// GetDrill returns one drill
+// GET /services/{id}/drills/{drill_id}
+func (h *IncidentHandler) GetDrill(c *gin.Context) {
+	orgID, ok := authorizeServiceOrg(c, h.authorizer, h.serviceService, authz.ActionView)
+	if !ok {
+		return
+	}
+	drill, err := h.incidentService.GetDrill(orgID, c.Param("id"), c.Param("drill_id"))
+	if err != nil {
+		drillError(c, err, "get drill")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drill": drill})
+}
+
+// CancelDrill cancels a drill that hasn't started
+// POST /services/{id}/drills/{drill_id}/cancel
+// Requires manage access to the service's organization.
+func (h *IncidentHandler) CancelDrill(c *gin.Context) {
+	orgID, ok := authorizeServiceOrg(c, h.authorizer, h.serviceService, authz.ActionManage)
+	if !ok {
+		return
+	}
+	drill, err := h.incidentService.CancelDrill(orgID, c.Param("id"), c.Param("drill_id"))
+	if err != nil {
+		drillError(c, err, "cancel drill")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drill": drill})
+}
+
+// GetDrillReport returns reaction times and the outcome of a drill
+// GET /services/{id}/drills/{drill_id}/report
+func (h *IncidentHandler) GetDrillReport(c *gin.Context) {
+	orgID, ok := authorizeServiceOrg(c, h.authorizer, h.serviceService, authz.ActionView)
+	if !ok {
+		return
+	}
+	report, err := h.incidentService.GetDrillReport(orgID, c.Param("id"), c.Param("drill_id"))
+	if err != nil {
+		drillError(c, err, "get drill report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
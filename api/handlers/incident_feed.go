@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// GetIncidentFeed handles GET /incidents/feed
+// Org-wide incident activity across all groups the user can see, newest first.
+// Responds with JSON by default, RSS 2.0 with ?format=rss and Atom with
+// ?format=atom (or the matching Accept header).
+func (h *IncidentHandler) GetIncidentFeed(c *gin.Context) {
+	filters := authz.GetReBACFilters(c)
+	orgID, _ := filters["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+
+	for _, param := range []string{"group_id", "service_id", "urgency", "severity"} {
+		if value := c.Query(param); value != "" {
+			filters[param] = value
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filters["incident_status"] = status
+	}
+	if eventTypes := c.Query("event_type"); eventTypes != "" {
+		filters["event_types"] = strings.Split(eventTypes, ",")
+	}
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+		filters["since"] = since
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		filters["cursor"] = cursor
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters["limit"] = limit
+		}
+	}
+
+	page, err := h.incidentService.GetIncidentFeed(filters)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch incident feed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	switch feedFormat(c) {
+	case "atom":
+		c.Header("Content-Type", "application/atom+xml; charset=utf-8")
+		c.String(http.StatusOK, xml.Header+renderAtomFeed(c, orgID, page))
+	case "rss":
+		c.Header("Content-Type", "application/rss+xml; charset=utf-8")
+		c.String(http.StatusOK, xml.Header+renderRSSFeed(c, page))
+	default:
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+func feedFormat(c *gin.Context) string {
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		return format
+	}
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "application/atom+xml"):
+		return "atom"
+	case strings.Contains(accept, "application/rss+xml"):
+		return "rss"
+	}
+	return "json"
+}
+
+// feedLink returns the absolute URL of this request, optionally with a different cursor
+func feedLink(c *gin.Context, cursor string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	u := url.URL{Scheme: scheme, Host: c.Request.Host, Path: c.Request.URL.Path}
+	query := c.Request.URL.Query()
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func feedEntryTitle(entry db.IncidentFeedEntry) string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(strings.ReplaceAll(entry.EventType, "_", " ")), entry.IncidentTitle)
+}
+
+func feedEntrySummary(entry db.IncidentFeedEntry) string {
+	parts := []string{"Status: " + entry.IncidentStatus, "Urgency: " + entry.Urgency}
+	if entry.Severity != "" {
+		parts = append(parts, "Severity: "+entry.Severity)
+	}
+	if entry.ServiceName != "" {
+		parts = append(parts, "Service: "+entry.ServiceName)
+	}
+	if entry.GroupName != "" {
+		parts = append(parts, "Group: "+entry.GroupName)
+	}
+	if entry.AssignedToName != "" {
+		parts = append(parts, "Assigned to: "+entry.AssignedToName)
+	}
+	if entry.CreatedByName != "" {
+		parts = append(parts, "By: "+entry.CreatedByName)
+	}
+	if note, ok := entry.EventData["note"].(string); ok && note != "" {
+		parts = append(parts, "Note: "+note)
+	}
+	return strings.Join(parts, " | ")
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title    string        `xml:"title"`
+	ID       string        `xml:"id"`
+	Updated  string        `xml:"updated"`
+	Link     atomLink      `xml:"link"`
+	Summary  string        `xml:"summary"`
+	Category *atomCategory `xml:"category,omitempty"`
+	Author   *atomAuthor   `xml:"author,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+func renderAtomFeed(c *gin.Context, orgID string, page *db.IncidentFeedPage) string {
+	updated := time.Now().UTC()
+	if len(page.Entries) > 0 {
+		updated = page.Entries[0].CreatedAt.UTC()
+	}
+	feed := atomFeed{
+		Title:   "SLAR incident activity",
+		ID:      "urn:slar:org:" + orgID + ":incident-feed",
+		Updated: updated.Format(time.RFC3339),
+		Links:   []atomLink{{Rel: "self", Href: feedLink(c, "")}},
+	}
+	if page.HasMore {
+		feed.Links = append(feed.Links, atomLink{Rel: "next", Href: feedLink(c, page.NextCursor)})
+	}
+	for _, entry := range page.Entries {
+		item := atomEntry{
+			Title:    feedEntryTitle(entry),
+			ID:       "urn:slar:incident-event:" + entry.ID,
+			Updated:  entry.CreatedAt.UTC().Format(time.RFC3339),
+			Link:     atomLink{Href: entry.IncidentURL},
+			Summary:  feedEntrySummary(entry),
+			Category: &atomCategory{Term: entry.EventType},
+		}
+		if entry.CreatedByName != "" {
+			item.Author = &atomAuthor{Name: entry.CreatedByName}
+		}
+		feed.Entries = append(feed.Entries, item)
+	}
+	out, _ := xml.MarshalIndent(feed, "", "  ")
+	return string(out)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSSFeed(c *gin.Context, page *db.IncidentFeedPage) string {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         "SLAR incident activity",
+			Link:          feedLink(c, ""),
+			Description:   "Incident activity across all groups",
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, entry := range page.Entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       feedEntryTitle(entry),
+			Link:        entry.IncidentURL,
+			Description: feedEntrySummary(entry),
+			Category:    entry.EventType,
+			GUID:        rssGUID{IsPermaLink: "false", Value: "urn:slar:incident-event:" + entry.ID},
+			PubDate:     entry.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	out, _ := xml.MarshalIndent(feed, "", "  ")
+	return string(out)
+}
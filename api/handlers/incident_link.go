@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/services"
+)
+
+// IncidentLinkHandler serves incident deep links and the app association
+// files. All routes are public: a link only reveals the incident's ID.
+type IncidentLinkHandler struct {
+	IncidentLinkService *services.IncidentLinkService
+}
+
+// NewIncidentLinkHandler creates a new IncidentLinkHandler
+func NewIncidentLinkHandler(incidentLinkService *services.IncidentLinkService) *IncidentLinkHandler {
+	return &IncidentLinkHandler{IncidentLinkService: incidentLinkService}
+}
+
+// Resolve handles GET /r/:short_id (public)
+// With the mobile app installed the OS opens it before this is reached; the
+// app then calls this with Accept: application/json to get the incident ID.
+// Browsers are redirected to the incident in the web UI.
+func (h *IncidentLinkHandler) Resolve(c *gin.Context) {
+	wantsJSON := strings.Contains(c.GetHeader("Accept"), "application/json")
+	link, err := h.IncidentLinkService.Resolve(c.Param("short_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrIncidentLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This incident link is invalid"})
+			return
+		}
+		log.Printf("Resolve incident link error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve incident link"})
+		return
+	}
+
+	if wantsJSON {
+		c.JSON(http.StatusOK, gin.H{"link": link})
+		return
+	}
+	c.Redirect(http.StatusFound, link.WebURL)
+}
+
+// AppleAppSiteAssociation handles GET /.well-known/apple-app-site-association (public)
+func (h *IncidentLinkHandler) AppleAppSiteAssociation(c *gin.Context) {
+	association, ok := h.IncidentLinkService.AppleAppSiteAssociation()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No iOS app is configured"})
+		return
+	}
+	c.JSON(http.StatusOK, association)
+}
+
+// AssetLinks handles GET /.well-known/assetlinks.json (public)
+func (h *IncidentLinkHandler) AssetLinks(c *gin.Context) {
+	statements, ok := h.IncidentLinkService.AssetLinks()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No Android app is configured"})
+		return
+	}
+	c.JSON(http.StatusOK, statements)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// How often a presence stream checks for changes, and how often it sends a
+// keep-alive so proxies don't close an idle stream
+const (
+	presencePollInterval      = 2 * time.Second
+	presenceKeepAliveInterval = 15 * time.Second
+)
+
+// UpdateIncidentPresence handles POST /incidents/:id/presence
+// Heartbeat sent while the current user has the incident open; returns who
+// else is viewing it
+func (h *IncidentHandler) UpdateIncidentPresence(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	var req db.IncidentPresenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewers, err := h.incidentService.UpdateIncidentPresence(id, c.GetString("user_id"), req.Mode)
+	if err != nil {
+		log.Printf("UpdateIncidentPresence error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update presence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"viewers": viewers, "ttl_seconds": int(services.IncidentPresenceTTL.Seconds())})
+}
+
+// LeaveIncidentPresence handles DELETE /incidents/:id/presence
+func (h *IncidentHandler) LeaveIncidentPresence(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	if err := h.incidentService.LeaveIncidentPresence(id, c.GetString("user_id")); err != nil {
+		log.Printf("LeaveIncidentPresence error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave presence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left incident"})
+}
+
+// GetIncidentPresence handles GET /incidents/:id/presence
+func (h *IncidentHandler) GetIncidentPresence(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	viewers, err := h.incidentService.ListIncidentViewers(id)
+	if err != nil {
+		log.Printf("GetIncidentPresence error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get presence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"viewers": viewers})
+}
+
+// StreamIncidentPresence handles GET /incidents/:id/presence/stream
+// Server-sent events: a "presence" event with the current viewers on connect
+// and whenever someone arrives, leaves or starts editing
+func (h *IncidentHandler) StreamIncidentPresence(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // don't let nginx buffer the stream
+
+	poll := time.NewTicker(presencePollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(presenceKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	lastKey := ""
+	first := true
+	send := func() bool {
+		viewers, err := h.incidentService.ListIncidentViewers(id)
+		if err != nil {
+			log.Printf("StreamIncidentPresence error: %v", err)
+			return true // transient; try again on the next tick
+		}
+		key := services.IncidentPresenceKey(viewers)
+		if !first && key == lastKey {
+			return true
+		}
+		first, lastKey = false, key
+		c.SSEvent("presence", gin.H{"viewers": viewers})
+		c.Writer.Flush()
+		return c.Request.Context().Err() == nil
+	}
+
+	if !send() {
+		return
+	}
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-poll.C:
+			if !send() {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
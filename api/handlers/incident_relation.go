@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const incidentLinkDenied = "You do not have permission to link this incident"
+
+// ListIncidentRelations handles GET /incidents/:id/relations
+func (h *IncidentHandler) ListIncidentRelations(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	relations, err := h.incidentService.ListIncidentRelations(id)
+	if err != nil {
+		log.Printf("ListIncidentRelations error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve relations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"relations": relations})
+}
+
+// CreateIncidentRelation handles POST /incidents/:id/relations
+// Links the incident as related to, a duplicate of, or caused by another
+// incident (ID or number) the caller can view, or caused by a change event.
+func (h *IncidentHandler) CreateIncidentRelation(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentLinkDenied) {
+		return
+	}
+
+	var req db.CreateIncidentRelationRequest
+	var err error
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if req.RelatedIncidentID != "" {
+		if number, ok := services.ParseIncidentNumber(req.RelatedIncidentID); ok {
+			var incident *db.IncidentResponse
+			incident, err = h.incidentService.GetIncident(id)
+			if err == nil {
+				req.RelatedIncidentID, err = h.incidentService.ResolveIncidentNumber(incident.OrganizationID, number)
+			}
+			if err != nil {
+				if errors.Is(err, services.ErrIncidentNumberNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Related incident not found"})
+					return
+				}
+				log.Printf("CreateIncidentRelation error: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link incident"})
+				return
+			}
+		}
+		// Linking must not reveal incidents the caller cannot see
+		if _, err := h.checkIncidentAccess(c, req.RelatedIncidentID, authz.ActionView); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Related incident not found"})
+			return
+		}
+	}
+
+	relation, err := h.incidentService.CreateIncidentRelation(id, c.GetString("user_id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIncidentRelation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIncidentRelationTargetNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIncidentRelationExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("CreateIncidentRelation error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link incident"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"relation": relation})
+}
+
+// DeleteIncidentRelation handles DELETE /incidents/:id/relations/:relation_id
+func (h *IncidentHandler) DeleteIncidentRelation(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentLinkDenied) {
+		return
+	}
+
+	if err := h.incidentService.DeleteIncidentRelation(id, c.Param("relation_id")); err != nil {
+		if errors.Is(err, services.ErrIncidentRelationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Relation not found"})
+			return
+		}
+		log.Printf("DeleteIncidentRelation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove relation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Relation removed"})
+}
+
+// GetCausedByReport counts the incidents a change event or incident caused,
+// directly and through "caused by" chains
+// GET /analytics/caused-by?org_id=&change_event_id= (or &incident_id=, which takes a number too)
+func (h *IncidentHandler) GetCausedByReport(c *gin.Context) {
+	orgID, ok := h.checkOrgAccess(c, authz.ActionView)
+	if !ok {
+		return
+	}
+
+	changeEventID, incidentID := c.Query("change_event_id"), c.Query("incident_id")
+	if (changeEventID == "") == (incidentID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide exactly one of change_event_id or incident_id"})
+		return
+	}
+	if number, ok := services.ParseIncidentNumber(incidentID); ok {
+		var err error
+		if incidentID, err = h.incidentService.ResolveIncidentNumber(orgID, number); err != nil {
+			if errors.Is(err, services.ErrIncidentNumberNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+				return
+			}
+			log.Printf("GetCausedByReport error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build caused-by report"})
+			return
+		}
+	}
+
+	report, err := h.incidentService.GetCausedByReport(orgID, changeEventID, incidentID)
+	if err != nil {
+		log.Printf("GetCausedByReport error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build caused-by report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
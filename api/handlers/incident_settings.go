@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const incidentSettingsDenied = "You do not have permission to access this incident"
+
+// GetIncidentSettings handles GET /incidents/:id/settings
+// Returns the incident's notification overrides, or the defaults
+func (h *IncidentHandler) GetIncidentSettings(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentSettingsDenied) {
+		return
+	}
+
+	settings, err := h.incidentService.GetIncidentNotificationSettings(id)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		log.Printf("GetIncidentSettings error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get incident settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": settings})
+}
+
+// UpdateIncidentSettings handles PUT /incidents/:id/settings
+// Lets the incident commander change how the incident notifies until it
+// resolves: chat channel updates, muted users and the status update interval
+func (h *IncidentHandler) UpdateIncidentSettings(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentSettingsDenied) {
+		return
+	}
+
+	var req struct {
+		Notifications db.UpdateIncidentNotificationSettingsRequest `json:"notifications"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	settings, err := h.incidentService.UpdateIncidentNotificationSettings(id, c.GetString("user_id"), req.Notifications)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotIncidentCommander):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrIncidentSettingsResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidIncidentNotificationSetting):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "incident not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		default:
+			log.Printf("UpdateIncidentSettings error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update incident settings"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": settings, "message": "Incident settings updated"})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// incidentShareDenied is the 403 message for share links and their access
+// log, which need update access; printing only needs view access
+const incidentShareDenied = "You do not have permission to share this incident"
+
+// GetIncidentSummary handles GET /incidents/:id/summary
+// The print-friendly summary, the same shape share links show. Notes are
+// included unless ?include_notes=false.
+func (h *IncidentHandler) GetIncidentSummary(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	summary, err := h.incidentService.GetIncidentSummary(id, c.DefaultQuery("include_notes", "true") != "false")
+	if err != nil {
+		log.Printf("GetIncidentSummary error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build incident summary"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
+
+// CreateIncidentShareLink handles POST /incidents/:id/share-links
+func (h *IncidentHandler) CreateIncidentShareLink(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentShareDenied) {
+		return
+	}
+
+	var req db.CreateIncidentShareLinkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	link, err := h.incidentService.CreateShareLink(id, c.GetString("user_id"), req)
+	if err != nil {
+		log.Printf("CreateIncidentShareLink error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"share_link": link,
+		"url":        services.IncidentShareURL(link.Token),
+		"message":    "Copy this link now - it will not be shown again",
+	})
+}
+
+// ListIncidentShareLinks handles GET /incidents/:id/share-links
+func (h *IncidentHandler) ListIncidentShareLinks(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentShareDenied) {
+		return
+	}
+
+	links, err := h.incidentService.ListShareLinks(id)
+	if err != nil {
+		log.Printf("ListIncidentShareLinks error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share links"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"share_links": links, "total": len(links)})
+}
+
+// RevokeIncidentShareLink handles DELETE /incidents/:id/share-links/:link_id
+func (h *IncidentHandler) RevokeIncidentShareLink(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentShareDenied) {
+		return
+	}
+
+	if err := h.incidentService.RevokeShareLink(id, c.Param("link_id"), c.GetString("user_id")); err != nil {
+		if errors.Is(err, services.ErrIncidentShareLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found or already revoked"})
+			return
+		}
+		log.Printf("RevokeIncidentShareLink error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
+
+// ListIncidentShareLinkViews handles GET /incidents/:id/share-links/:link_id/views?limit=100
+func (h *IncidentHandler) ListIncidentShareLinkViews(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentShareDenied) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	views, err := h.incidentService.ListShareLinkViews(id, c.Param("link_id"), limit)
+	if err != nil {
+		log.Printf("ListIncidentShareLinkViews error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share link views"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"views": views, "total": len(views)})
+}
+
+// GetSharedIncident handles GET /shared/incidents/:token (public)
+// The read-only summary for someone holding a share link
+func (h *IncidentHandler) GetSharedIncident(c *gin.Context) {
+	summary, err := h.incidentService.GetSharedIncidentSummary(c.Param("token"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIncidentShareLinkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "This share link is invalid"})
+		case errors.Is(err, services.ErrIncidentShareLinkExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "This share link has expired or been revoked"})
+		default:
+			log.Printf("GetSharedIncident error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load incident"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// incidentTasksDenied is the 403 message for changing the checklist, which
+// needs update access like notes
+const incidentTasksDenied = "You do not have permission to change tasks on this incident"
+
+// ListIncidentTasks handles GET /incidents/:id/tasks
+func (h *IncidentHandler) ListIncidentTasks(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionView, incidentViewDenied) {
+		return
+	}
+
+	tasks, err := h.incidentService.ListIncidentTasks(id)
+	if err != nil {
+		log.Printf("ListIncidentTasks error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
+		return
+	}
+
+	total, open := services.CountOpenTasks(tasks)
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks, "total": total, "open": open})
+}
+
+// CreateIncidentTask handles POST /incidents/:id/tasks
+func (h *IncidentHandler) CreateIncidentTask(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentTasksDenied) {
+		return
+	}
+
+	var req db.CreateIncidentTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	task, err := h.incidentService.CreateIncidentTask(id, c.GetString("user_id"), req)
+	if err != nil {
+		log.Printf("CreateIncidentTask error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"task": task})
+}
+
+// UpdateIncidentTask handles PATCH /incidents/:id/tasks/:task_id
+// Edits, assigns, completes ({"completed": true}) or reopens a task
+func (h *IncidentHandler) UpdateIncidentTask(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentTasksDenied) {
+		return
+	}
+
+	var req db.UpdateIncidentTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	task, err := h.incidentService.UpdateIncidentTask(id, c.Param("task_id"), c.GetString("user_id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrIncidentTaskNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		log.Printf("UpdateIncidentTask error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task": task})
+}
+
+// DeleteIncidentTask handles DELETE /incidents/:id/tasks/:task_id
+func (h *IncidentHandler) DeleteIncidentTask(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentTasksDenied) {
+		return
+	}
+
+	if err := h.incidentService.DeleteIncidentTask(id, c.Param("task_id")); err != nil {
+		if errors.Is(err, services.ErrIncidentTaskNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		log.Printf("DeleteIncidentTask error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task deleted"})
+}
+
+// ReorderIncidentTasks handles PUT /incidents/:id/tasks/order
+func (h *IncidentHandler) ReorderIncidentTasks(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, incidentTasksDenied) {
+		return
+	}
+
+	var req db.ReorderIncidentTasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	tasks, err := h.incidentService.ReorderIncidentTasks(id, req.TaskIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTaskOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("ReorderIncidentTasks error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
+}
@@ -109,7 +109,8 @@ func TestIncidentHandler_GetIncident_ReBAC(t *testing.T) {
 			"source", "integration_id", "service_id", "external_id", "external_url",
 			"escalation_policy_id", "current_escalation_level", "last_escalated_at",
 			"escalation_status", "group_id", "api_key_id", "severity", "incident_key",
-			"alert_count", "labels", "custom_fields",
+			"alert_count", "labels", "custom_fields", "workflow_state", "is_test", "short_id", "number",
+			"tags", "team", "component", "environment",
 			"organization_id", "project_id",
 			"assigned_to_name", "assigned_to_email",
 			"acknowledged_by_name", "acknowledged_by_email",
@@ -122,7 +123,8 @@ func TestIncidentHandler_GetIncident_ReBAC(t *testing.T) {
 			"manual", nil, nil, nil, nil,
 			nil, 0, nil,
 			"pending", nil, nil, "critical", "key-1",
-			1, nil, nil,
+			1, nil, nil, "", false, "abc234defg", int64(1024),
+			"{}", "", "", "",
 			"org-1", "proj-1",
 			nil, nil, nil, nil, nil, nil, nil, nil, nil,
 		)
@@ -159,7 +161,8 @@ func TestIncidentHandler_GetIncident_ReBAC(t *testing.T) {
 			"source", "integration_id", "service_id", "external_id", "external_url",
 			"escalation_policy_id", "current_escalation_level", "last_escalated_at",
 			"escalation_status", "group_id", "api_key_id", "severity", "incident_key",
-			"alert_count", "labels", "custom_fields",
+			"alert_count", "labels", "custom_fields", "workflow_state", "is_test", "short_id", "number",
+			"tags", "team", "component", "environment",
 			"organization_id", "project_id",
 			"assigned_to_name", "assigned_to_email",
 			"acknowledged_by_name", "acknowledged_by_email",
@@ -172,7 +175,8 @@ func TestIncidentHandler_GetIncident_ReBAC(t *testing.T) {
 			"manual", nil, nil, nil, nil,
 			nil, 0, nil,
 			"pending", nil, nil, "critical", "key-2",
-			1, nil, nil,
+			1, nil, nil, "", false, "abc234defg", int64(1024),
+			"{}", "", "", "",
 			"org-1", "proj-2",
 			nil, nil, nil, nil, nil, nil, nil, nil, nil,
 		)
@@ -209,7 +213,8 @@ func TestIncidentHandler_GetIncident_ReBAC(t *testing.T) {
 			"source", "integration_id", "service_id", "external_id", "external_url",
 			"escalation_policy_id", "current_escalation_level", "last_escalated_at",
 			"escalation_status", "group_id", "api_key_id", "severity", "incident_key",
-			"alert_count", "labels", "custom_fields",
+			"alert_count", "labels", "custom_fields", "workflow_state", "is_test", "short_id", "number",
+			"tags", "team", "component", "environment",
 			"organization_id", "project_id",
 			"assigned_to_name", "assigned_to_email",
 			"acknowledged_by_name", "acknowledged_by_email",
@@ -222,7 +227,8 @@ func TestIncidentHandler_GetIncident_ReBAC(t *testing.T) {
 			"manual", nil, nil, nil, nil,
 			nil, 0, nil,
 			"pending", nil, nil, "critical", "key-3",
-			1, nil, nil,
+			1, nil, nil, "", false, "abc234defg", int64(1024),
+			"{}", "", "", "",
 			"org-1", "proj-3",
 			"User One", "user1@example.com", nil, nil, nil, nil, nil, nil, nil,
 		)
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListWorkflowStates handles GET /orgs/:id/incident-workflow-states
+func (h *IncidentHandler) ListWorkflowStates(c *gin.Context) {
+	states, err := h.incidentService.ListWorkflowStates(c.Param("id"), c.Query("include_inactive") == "true")
+	if err != nil {
+		log.Printf("ListWorkflowStates error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workflow states"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"workflow_states": states, "total": len(states)})
+}
+
+// CreateWorkflowState handles POST /orgs/:id/incident-workflow-states
+func (h *IncidentHandler) CreateWorkflowState(c *gin.Context) {
+	var req db.CreateIncidentWorkflowStateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	state, err := h.incidentService.CreateWorkflowState(c.Param("id"), req)
+	if err != nil {
+		h.handleWorkflowError(c, err, "Failed to create workflow state")
+		return
+	}
+	c.JSON(http.StatusCreated, state)
+}
+
+// UpdateWorkflowState handles PATCH /orgs/:id/incident-workflow-states/:state_id
+func (h *IncidentHandler) UpdateWorkflowState(c *gin.Context) {
+	var req db.UpdateIncidentWorkflowStateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	state, err := h.incidentService.UpdateWorkflowState(c.Param("id"), c.Param("state_id"), req)
+	if err != nil {
+		h.handleWorkflowError(c, err, "Failed to update workflow state")
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// DeleteWorkflowState handles DELETE /orgs/:id/incident-workflow-states/:state_id
+func (h *IncidentHandler) DeleteWorkflowState(c *gin.Context) {
+	if err := h.incidentService.DeleteWorkflowState(c.Param("id"), c.Param("state_id")); err != nil {
+		h.handleWorkflowError(c, err, "Failed to delete workflow state")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Workflow state deleted"})
+}
+
+// SetIncidentWorkflowState handles POST /incidents/:id/workflow-state
+// Moves an acknowledged incident to a custom workflow state, or back with state "acknowledged"
+func (h *IncidentHandler) SetIncidentWorkflowState(c *gin.Context) {
+	id := c.Param("id")
+
+	_, err := h.checkIncidentAccess(c, id, authz.ActionUpdate)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		if err.Error() == "forbidden" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission", "details": err.Error()})
+		return
+	}
+
+	var req db.SetWorkflowStateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	incident, err := h.incidentService.SetIncidentWorkflowState(id, req.State, c.GetString("user_id"), req.Note)
+	if err != nil {
+		h.handleWorkflowError(c, err, "Failed to set workflow state")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Workflow state updated",
+		"status":         incident.Status,
+		"workflow_state": incident.WorkflowState,
+	})
+}
+
+func (h *IncidentHandler) handleWorkflowError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWorkflowStateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow state not found"})
+	case errors.Is(err, services.ErrWorkflowStateExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWorkflowStateKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWorkflowTransition), errors.Is(err, services.ErrIncidentNotInWorkflowPhase):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
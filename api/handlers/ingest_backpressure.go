@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IngestBackpressureMiddleware refuses webhooks with 429 and Retry-After
+// while ingestion is backed up, before the payload is read or the database
+// is touched
+func (h *WebhookHandler) IngestBackpressureMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.backpressure == nil {
+			c.Next()
+			return
+		}
+		release, ok := h.backpressure.Admit()
+		if !ok {
+			retryAfter := int(h.backpressure.RetryAfter().Seconds())
+			log.Printf("Refused webhook %s: ingestion is backed up", c.Request.URL.Path)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":               "Ingestion is backed up, retry later",
+				"retry_after_seconds": retryAfter,
+			})
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
+
+// GetIngestBackpressure handles GET /internal/ingest/backpressure
+// Shows whether webhooks are being shed or refused, and why
+func (h *WebhookHandler) GetIngestBackpressure(c *gin.Context) {
+	if h.backpressure == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ingest backpressure is not enabled"})
+		return
+	}
+	c.JSON(http.StatusOK, h.backpressure.State())
+}
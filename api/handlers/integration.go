@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -11,12 +12,14 @@ import (
 )
 
 type IntegrationHandler struct {
-	IntegrationService *services.IntegrationService
+	IntegrationService   *services.IntegrationService
+	PendingChangeService *services.PendingChangeService
 }
 
-func NewIntegrationHandler(integrationService *services.IntegrationService) *IntegrationHandler {
+func NewIntegrationHandler(integrationService *services.IntegrationService, pendingChangeService *services.PendingChangeService) *IntegrationHandler {
 	return &IntegrationHandler{
-		IntegrationService: integrationService,
+		IntegrationService:   integrationService,
+		PendingChangeService: pendingChangeService,
 	}
 }
 
@@ -61,7 +64,8 @@ func (h *IntegrationHandler) CreateIntegration(c *gin.Context) {
 	}
 
 	// Validate integration type
-	validTypes := []string{"prometheus", "datadog", "grafana", "webhook", "aws", "custom"}
+	validTypes := []string{"prometheus", "datadog", "grafana", "webhook", "aws", "custom", "github", "gitlab", "argocd",
+		db.ExternalOnCallIntegrationType}
 	isValidType := false
 	for _, validType := range validTypes {
 		if req.Type == validType {
@@ -86,6 +90,11 @@ func (h *IntegrationHandler) CreateIntegration(c *gin.Context) {
 
 	integration, err := h.IntegrationService.CreateIntegration(req, createdBy)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSourceCIDR) || errors.Is(err, services.ErrInvalidFederationConfig) ||
+			errors.Is(err, services.ErrInvalidSilenceAlert) || errors.Is(err, services.ErrInvalidExternalOnCallConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create integration", "details": err.Error()})
 		return
 	}
@@ -163,8 +172,10 @@ func (h *IntegrationHandler) GetIntegration(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"integration": integration})
 }
 
-// UpdateIntegration updates an existing integration
-// PUT /api/integrations/:id
+// UpdateIntegration updates an existing integration. Disabling one that feeds
+// alerts into active services waits for another org admin's approval; the
+// whole update then answers 202 with the pending change.
+// PUT /api/integrations/:id?reason=...
 func (h *IntegrationHandler) UpdateIntegration(c *gin.Context) {
 	integrationID := c.Param("id")
 	if integrationID == "" {
@@ -178,12 +189,32 @@ func (h *IntegrationHandler) UpdateIntegration(c *gin.Context) {
 		return
 	}
 
+	change, err := h.PendingChangeService.IntegrationDisableChange(integrationID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update integration", "details": err.Error()})
+		return
+	}
+	if change != nil {
+		pending, err := h.PendingChangeService.RequestChange(change, c.GetString("user_id"), c.Query("reason"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request integration update", "details": err.Error()})
+			return
+		}
+		respondPendingChange(c, pending)
+		return
+	}
+
 	integration, err := h.IntegrationService.UpdateIntegration(integrationID, req)
 	if err != nil {
 		if err.Error() == "integration not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
 			return
 		}
+		if errors.Is(err, services.ErrInvalidSourceCIDR) || errors.Is(err, services.ErrInvalidFederationConfig) ||
+			errors.Is(err, services.ErrInvalidSilenceAlert) || errors.Is(err, services.ErrInvalidExternalOnCallConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update integration", "details": err.Error()})
 		return
 	}
@@ -203,7 +234,7 @@ func (h *IntegrationHandler) DeleteIntegration(c *gin.Context) {
 		return
 	}
 
-	err := h.IntegrationService.DeleteIntegration(integrationID)
+	err := h.IntegrationService.DeleteIntegration(integrationID, c.GetString("user_id"))
 	if err != nil {
 		if err.Error() == "integration not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
@@ -271,6 +302,10 @@ func (h *IntegrationHandler) CreateServiceIntegration(c *gin.Context) {
 
 	serviceIntegration, err := h.IntegrationService.CreateServiceIntegration(req, createdBy)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidServiceIntegration) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service integration", "details": err.Error()})
 		return
 	}
@@ -323,6 +358,25 @@ func (h *IntegrationHandler) GetIntegrationServices(c *gin.Context) {
 	})
 }
 
+// LookupExternalOnCall shows who an external_oncall integration reports on
+// call right now and the users they map to
+// GET /api/integrations/:id/on-call
+func (h *IntegrationHandler) LookupExternalOnCall(c *gin.Context) {
+	lookup, err := h.IntegrationService.LookupExternalOnCall(c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrExternalOnCallNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "External on-call integration not found"})
+		case errors.Is(err, services.ErrInvalidExternalOnCallConfig):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{"error": "External on-call lookup failed", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, lookup)
+}
+
 // UpdateServiceIntegration updates a service-integration mapping
 // PUT /api/service-integrations/:id
 func (h *IntegrationHandler) UpdateServiceIntegration(c *gin.Context) {
@@ -344,6 +398,10 @@ func (h *IntegrationHandler) UpdateServiceIntegration(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Service integration not found"})
 			return
 		}
+		if errors.Is(err, services.ErrInvalidServiceIntegration) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service integration", "details": err.Error()})
 		return
 	}
@@ -403,6 +461,16 @@ func (h *IntegrationHandler) GetIntegrationTemplates(c *gin.Context) {
 			"name":        "Generic Webhook",
 			"description": "Generic webhook integration for custom monitoring tools",
 		},
+		{
+			"type":        db.FederationIntegrationType,
+			"name":        "SLAR Federation",
+			"description": "Forward selected incidents to another SLAR instance and mirror status changes back",
+		},
+		{
+			"type":        db.SIEMIntegrationType,
+			"name":        "SIEM Export",
+			"description": "Stream incident lifecycle and audit events to Splunk HEC, Elasticsearch or syslog as JSON or CEF",
+		},
 	}
 
 	// Filter by type if provided
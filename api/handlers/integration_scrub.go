@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// GetScrubPolicy returns an integration's payload scrub policy and how much
+// it has redacted
+// GET /api/integrations/:id/scrub-policy
+func (h *IntegrationHandler) GetScrubPolicy(c *gin.Context) {
+	audit, err := h.IntegrationService.GetScrubAudit(c.Param("id"))
+	if err != nil {
+		if err.Error() == "integration not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scrub policy", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, audit)
+}
+
+// SetScrubPolicy replaces an integration's payload scrub policy; a policy
+// without rules, patterns or fields turns scrubbing off
+// PUT /api/integrations/:id/scrub-policy
+func (h *IntegrationHandler) SetScrubPolicy(c *gin.Context) {
+	var req db.IntegrationScrubPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	audit, err := h.IntegrationService.SetScrubPolicy(c.Param("id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidScrubPolicy):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "integration not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set scrub policy", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Scrub policy updated successfully",
+		"scrub_policy": audit,
+	})
+}
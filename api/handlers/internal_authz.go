@@ -71,8 +71,7 @@ type checkAccessRequest struct {
 // Performs a generic authorization check: can user perform action on resource?
 func (h *InternalAuthzHandler) CheckAccess(c *gin.Context) {
 	var req checkAccessRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireBearerToken guards /internal routes called by operators, pipelines
+// or the AI agent rather than users: requests must send
+// "Authorization: Bearer <token>". The token is read on every request so a
+// rotated secret applies without a restart; when it isn't configured the
+// routes stay closed.
+func RequireBearerToken(setting string, token func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		want := token()
+		if want == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "not_configured",
+				"message": setting + " is not configured",
+			})
+			c.Abort()
+			return
+		}
+
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_token",
+				"message": "A valid bearer token is required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := ""
+	r := gin.New()
+	r.GET("/internal/thing", RequireBearerToken("thing_token", func() string { return token }), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	serve := func(auth string) int {
+		req := httptest.NewRequest(http.MethodGet, "/internal/thing", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := serve("Bearer "); code != http.StatusServiceUnavailable {
+		t.Errorf("unconfigured token: got %d, want 503", code)
+	}
+
+	token = "s3cret"
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if code := serve(tt.auth); code != tt.want {
+			t.Errorf("Authorization %q: got %d, want %d", tt.auth, code, tt.want)
+		}
+	}
+}
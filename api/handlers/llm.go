@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/llm"
+	"github.com/vanchonlee/slar/services"
+)
+
+// LLMHandler exposes organization LLM provider settings and usage
+type LLMHandler struct {
+	LLMService *services.LLMService
+}
+
+// NewLLMHandler creates a new LLMHandler
+func NewLLMHandler(llmService *services.LLMService) *LLMHandler {
+	return &LLMHandler{LLMService: llmService}
+}
+
+// GetLLMSettings handles GET /orgs/:id/settings/llm
+// Returns the organization's provider settings (never its key), the
+// available providers and the instance default it falls back to
+func (h *LLMHandler) GetLLMSettings(c *gin.Context) {
+	settings, err := h.LLMService.GetOrgSettings(c.Param("id"))
+	if err != nil {
+		log.Printf("GetLLMSettings error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load LLM settings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"llm":       settings,
+		"providers": llm.Describe(),
+		"default": gin.H{
+			"provider":             config.Current().LLM.Provider,
+			"model":                config.Current().LLM.Model,
+			"monthly_token_budget": config.Current().LLM.MonthlyTokenBudget,
+		},
+	})
+}
+
+// UpdateLLMSettings handles PATCH /orgs/:id/settings/llm
+// Sets the organization's own provider, model and key, or its monthly token budget
+func (h *LLMHandler) UpdateLLMSettings(c *gin.Context) {
+	var req db.UpdateOrgLLMSettingsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	settings, err := h.LLMService.UpdateOrgSettings(c.Param("id"), c.GetString("user_id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrgSettingsNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		case errors.Is(err, services.ErrLLMProviderNotFound), errors.Is(err, services.ErrInvalidLLMSettings):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("UpdateLLMSettings error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update LLM settings"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"llm": settings})
+}
+
+// GetLLMUsage handles GET /orgs/:id/llm-usage?from=&to=
+// Token usage by provider, model and feature; defaults to this month (UTC)
+func (h *LLMHandler) GetLLMUsage(c *gin.Context) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+
+	report, err := h.LLMService.GetUsage(c.Param("id"), from, to)
+	if err != nil {
+		log.Printf("GetLLMUsage error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load LLM usage"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": report})
+}
+
+// InternalCompleteRequest is a completion requested by an internal AI worker
+type InternalCompleteRequest struct {
+	OrgID   string      `json:"org_id" binding:"required"`
+	UserID  string      `json:"user_id"`
+	Feature string      `json:"feature" binding:"required"`
+	Request llm.Request `json:"request"`
+}
+
+// InternalComplete handles POST /internal/llm/complete
+// Lets AI workers use the organization's provider, budget and metering
+func (h *LLMHandler) InternalComplete(c *gin.Context) {
+	var req InternalCompleteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Request.Messages) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request.messages is required"})
+		return
+	}
+
+	resp, err := h.LLMService.Complete(c.Request.Context(), req.OrgID, req.UserID, req.Feature, req.Request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLLMBudgetExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLLMNotConfigured), errors.Is(err, services.ErrLLMProviderNotFound):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLLMRequestFailed):
+			log.Printf("InternalComplete error for org %s: %v", req.OrgID, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			log.Printf("InternalComplete error for org %s: %v", req.OrgID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run completion"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"response": resp})
+}
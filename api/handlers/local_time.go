@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimezoneHeader echoes the zone local times were rendered in
+const TimezoneHeader = "X-Timezone"
+
+// LocalTimeMiddleware renders timestamps in the caller's time zone when the
+// request has ?tz=<IANA zone>, so the web UI and mobile app don't each do
+// their own conversions. Next to every RFC 3339 timestamp field "x" in a
+// successful JSON response it adds "x_local", the same instant with the
+// zone's offset, and "x_offset", the offset alone (e.g. "+02:00"). The UTC
+// fields are left as they are. Without ?tz= responses are untouched; an
+// unknown zone is rejected with 400.
+func LocalTimeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tz := c.Query("tz")
+		if tz == "" {
+			c.Next()
+			return
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone such as Europe/Berlin"})
+			return
+		}
+
+		w := &localTimeWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			return
+		}
+		body := w.buf.Bytes()
+		if localized, ok := localizeTimes(body, loc); ok {
+			body = localized
+		}
+		h := w.ResponseWriter.Header()
+		h.Set(TimezoneHeader, loc.String())
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// localizeTimes adds the local renderings to every timestamp in a JSON body
+func localizeTimes(body []byte, loc *time.Location) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	localized, err := json.Marshal(localizeValue(v, loc))
+	if err != nil {
+		return nil, false
+	}
+	return localized, true
+}
+
+func localizeValue(v interface{}, loc *time.Location) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		added := map[string]interface{}{}
+		for key, field := range v {
+			s, ok := field.(string)
+			if !ok {
+				v[key] = localizeValue(field, loc)
+				continue
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				continue
+			}
+			local := t.In(loc)
+			added[key+"_local"] = local.Format(time.RFC3339)
+			added[key+"_offset"] = local.Format("-07:00")
+		}
+		for key, field := range added {
+			v[key] = field
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = localizeValue(v[i], loc)
+		}
+	}
+	return v
+}
+
+// localTimeWriter holds back successful JSON responses so their timestamps
+// can be localized
+type localTimeWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	buffering bool
+}
+
+func (w *localTimeWriter) Write(data []byte) (int, error) {
+	if w.buffering || (w.Status() >= 200 && w.Status() < 300 && !w.ResponseWriter.Written() &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")) {
+		w.buffering = true
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *localTimeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newLocalTimeRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/shifts", LocalTimeMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"total": 1,
+			"shifts": []gin.H{{
+				"id":         "sh-1",
+				"start_time": "2026-03-29T00:30:00Z",
+				"end_time":   "2026-03-29T08:30:00Z",
+				"ended_at":   nil,
+			}},
+		})
+	})
+	return r
+}
+
+func TestLocalTimeMiddleware(t *testing.T) {
+	r := newLocalTimeRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shifts?tz=Europe/Berlin", nil))
+	if w.Code != http.StatusOK || w.Header().Get(TimezoneHeader) != "Europe/Berlin" {
+		t.Fatalf("response = %d %v", w.Code, w.Header())
+	}
+	var body struct {
+		Total  json.Number              `json:"total"`
+		Shifts []map[string]interface{} `json:"shifts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	shift := body.Shifts[0]
+	// The shift spans the switch to summer time
+	if shift["start_time"] != "2026-03-29T00:30:00Z" || shift["start_time_local"] != "2026-03-29T01:30:00+01:00" ||
+		shift["start_time_offset"] != "+01:00" {
+		t.Errorf("start = %v", shift)
+	}
+	if shift["end_time_local"] != "2026-03-29T10:30:00+02:00" || shift["end_time_offset"] != "+02:00" {
+		t.Errorf("end = %v", shift)
+	}
+	if _, ok := shift["id_local"]; ok {
+		t.Errorf("non-timestamp localized: %v", shift)
+	}
+	if _, ok := shift["ended_at_local"]; ok || body.Total != "1" {
+		t.Errorf("body = %s", w.Body.String())
+	}
+
+	// Without ?tz= the response is untouched
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shifts", nil))
+	if w.Header().Get(TimezoneHeader) != "" || strings.Contains(w.Body.String(), "_local") {
+		t.Errorf("untouched response = %v %s", w.Header(), w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shifts?tz=Mars/Olympus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown zone = %d", w.Code)
+	}
+}
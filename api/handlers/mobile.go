@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/services"
 )
 
@@ -517,11 +518,7 @@ func (h *MobileHandler) RegisterDeviceForPush(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		FCMToken   string `json:"fcm_token" binding:"required"`
-		Platform   string `json:"platform"`
-		AppVersion string `json:"app_version"`
-	}
+	var req db.RegisterDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
@@ -571,6 +568,11 @@ func (h *MobileHandler) RegisterDeviceForPush(c *gin.Context) {
 	var result map[string]interface{}
 	json.Unmarshal(body, &result)
 
+	// Keep the device locally too, so send errors can prune its token
+	if _, err := services.NewUserService(h.PG).RegisterDevice(userID, req); err != nil {
+		fmt.Printf("Warning: Failed to save device locally: %v\n", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
 		"device_id": result["device_id"],
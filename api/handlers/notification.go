@@ -1,19 +1,24 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/services"
 )
 
 type NotificationHandler struct {
-	SlackService *services.SlackService
+	SlackService             *services.SlackService
+	NotificationCheckService *services.NotificationCheckService
 }
 
-func NewNotificationHandler(slackService *services.SlackService) *NotificationHandler {
+func NewNotificationHandler(slackService *services.SlackService, notificationCheckService *services.NotificationCheckService) *NotificationHandler {
 	return &NotificationHandler{
-		SlackService: slackService,
+		SlackService:             slackService,
+		NotificationCheckService: notificationCheckService,
 	}
 }
 
@@ -107,35 +112,83 @@ func (h *NotificationHandler) UpdateNotificationConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetNotificationDedup returns the user's cross-channel dedup policy
+// GET /api/users/me/notifications/dedup
+func (h *NotificationHandler) GetNotificationDedup(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	dedup, err := h.SlackService.GetNotificationDedup(userID)
+	if err != nil {
+		log.Printf("Get notification dedup error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification dedup policy"})
+		return
+	}
+	c.JSON(http.StatusOK, dedup)
+}
+
+// UpdateNotificationDedup sets the user's cross-channel dedup policy
+// PUT /api/users/me/notifications/dedup
+func (h *NotificationHandler) UpdateNotificationDedup(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.UpdateNotificationDedupRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	dedup, err := h.SlackService.UpdateNotificationDedup(userID, req)
+	if err != nil {
+		log.Printf("Update notification dedup error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification dedup policy"})
+		return
+	}
+	c.JSON(http.StatusOK, dedup)
+}
+
+// TestNotifications sends a test notification to the calling user on the
+// selected channels (all when none) and returns per-channel diagnostics
+// POST /api/notifications/test
+func (h *NotificationHandler) TestNotifications(c *gin.Context) {
+	var req db.TestNotificationRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+	h.sendTestNotification(c, req.Channels)
+}
+
 // TestSlackNotification sends a test Slack notification to user
 // POST /api/users/me/notifications/test/slack
+// Deprecated: use POST /api/notifications/test with channels ["slack"]
 func (h *NotificationHandler) TestSlackNotification(c *gin.Context) {
-	// Get user_id from context (set by auth middleware, already converted to UUID)
-	userID, exists := c.Get("user_id")
-	if !exists {
+	h.sendTestNotification(c, []string{db.NotificationChannelSlack})
+}
+
+func (h *NotificationHandler) sendTestNotification(c *gin.Context, channels []string) {
+	userID := c.GetString("user_id")
+	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	userIDStr := userID.(string)
 
-	// Create a dummy incident for testing
-	testIncidentID := "test-incident-" + userIDStr
-
-	err := h.SlackService.SendIncidentNotification(userIDStr, testIncidentID, "test")
+	result, err := h.NotificationCheckService.SendTestNotification(userID, channels)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to send test notification",
-			"details": err.Error(),
-		})
+		if errors.Is(err, services.ErrUnknownNotificationChannel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Test notification error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test notification"})
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Test Slack notification sent successfully",
-		"user_id":     userIDStr,
-		"incident_id": testIncidentID,
-		"type":        "test",
-	})
+	c.JSON(http.StatusOK, result)
 }
 
 // GetNotificationStats gets notification statistics for user
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// RecordNotificationReceipt handles POST /incidents/:id/receipts
+// Called by the mobile app, Slack bot or web UI when the current user's page is
+// delivered or opened
+func (h *IncidentHandler) RecordNotificationReceipt(c *gin.Context) {
+	id := c.Param("id")
+
+	_, err := h.checkIncidentAccess(c, id, authz.ActionView)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		if err.Error() == "forbidden" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission", "details": err.Error()})
+		return
+	}
+
+	var req db.RecordNotificationReceiptRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	receipt, err := h.incidentService.RecordNotificationReceipt(id, c.GetString("user_id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrNotificationReceiptNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("RecordNotificationReceipt error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record notification receipt"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"receipt": receipt})
+}
+
+// GetNotificationReceipts handles GET /incidents/:id/receipts
+// Returns the latest page receipt per responder ("seen at")
+func (h *IncidentHandler) GetNotificationReceipts(c *gin.Context) {
+	id := c.Param("id")
+
+	_, err := h.checkIncidentAccess(c, id, authz.ActionView)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return
+		}
+		if err.Error() == "forbidden" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission", "details": err.Error()})
+		return
+	}
+
+	receipts, err := h.incidentService.ListNotificationReceipts(id)
+	if err != nil {
+		log.Printf("GetNotificationReceipts error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notification receipts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"receipts": receipts, "total": len(receipts)})
+}
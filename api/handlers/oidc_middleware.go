@@ -44,15 +44,15 @@ type OIDCAuthMiddleware struct {
 }
 
 // NewOIDCAuthMiddleware creates a new OIDC auth middleware
-// Reads configuration from config.App (loaded from config.yaml):
+// Reads configuration from config.Current() (loaded from config.yaml):
 // - OIDCIssuer: The OIDC issuer URL (required)
 // - OIDCClientID: Default client ID (optional, used as fallback)
 // - OIDCWebClientID: Client ID for web frontend (optional)
 // - OIDCMobileClientID: Client ID for mobile app (optional)
 // Returns nil if OIDC is not configured - API will start but protected endpoints will be disabled
 func NewOIDCAuthMiddleware(userService *services.UserService, apiKeyService *services.APIKeyService, sessionTokenService *services.SessionTokenService) *OIDCAuthMiddleware {
-	// Read from config.App (loaded from config.yaml)
-	oidcIssuer := config.App.OIDCIssuer
+	// Read from config.Current() (loaded from config.yaml)
+	oidcIssuer := config.Current().OIDCIssuer
 
 	if oidcIssuer == "" {
 		log.Println("⚠️  WARNING: oidc_issuer not configured in config.yaml. Protected endpoints will be disabled.")
@@ -65,23 +65,23 @@ func NewOIDCAuthMiddleware(userService *services.UserService, apiKeyService *ser
 	clientIDs := []string{}
 
 	// Add default client ID
-	if config.App.OIDCClientID != "" {
-		clientIDs = append(clientIDs, config.App.OIDCClientID)
+	if config.Current().OIDCClientID != "" {
+		clientIDs = append(clientIDs, config.Current().OIDCClientID)
 	}
 
 	// Add web-specific client ID
-	if config.App.OIDCWebClientID != "" {
+	if config.Current().OIDCWebClientID != "" {
 		// Only add if different from default
-		if !contains(clientIDs, config.App.OIDCWebClientID) {
-			clientIDs = append(clientIDs, config.App.OIDCWebClientID)
+		if !contains(clientIDs, config.Current().OIDCWebClientID) {
+			clientIDs = append(clientIDs, config.Current().OIDCWebClientID)
 		}
 	}
 
 	// Add mobile-specific client ID
-	if config.App.OIDCMobileClientID != "" {
+	if config.Current().OIDCMobileClientID != "" {
 		// Only add if different from others
-		if !contains(clientIDs, config.App.OIDCMobileClientID) {
-			clientIDs = append(clientIDs, config.App.OIDCMobileClientID)
+		if !contains(clientIDs, config.Current().OIDCMobileClientID) {
+			clientIDs = append(clientIDs, config.Current().OIDCMobileClientID)
 		}
 	}
 
@@ -156,9 +156,13 @@ func (m *OIDCAuthMiddleware) OIDCAuthMiddleware() gin.HandlerFunc {
 				if apiKey.OrganizationID != "" {
 					c.Set("org_id", apiKey.OrganizationID)
 				}
+				if err := m.APIKeyService.CheckRateLimit(apiKey.ID, apiKey); err != nil {
+					abortRateLimited(c, err)
+					return
+				}
 				log.Printf("AUTH SUCCESS - API Key: %s (user: %s)", apiKey.Name, apiKey.UserID)
-				// Update last used timestamp (async, don't block request)
-				go m.APIKeyService.UpdateLastUsed(apiKey.ID)
+				// Count the call against the key's quotas (async, don't block request)
+				go m.APIKeyService.RecordUsage(apiKey.ID)
 				c.Next()
 				return
 			}
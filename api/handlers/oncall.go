@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vanchonlee/slar/db"
@@ -86,8 +88,7 @@ func (h *OnCallHandler) CreateSchedule(c *gin.Context) {
 	}
 
 	var req db.CreateShiftRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -124,8 +125,7 @@ func (h *OnCallHandler) UpdateSchedule(c *gin.Context) {
 	}
 
 	var req db.UpdateOnCallScheduleRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -193,7 +193,49 @@ func (h *OnCallHandler) GetUpcomingSchedules(c *gin.Context) {
 	})
 }
 
-// SwapSchedules handles schedule swapping requests
+// GetEffectiveOnCall returns who is on call for each of the group's
+// schedulers over a window, overrides applied, with per-person totals for
+// payroll and compensation tools
+// GET /groups/:id/effective-oncall?from=&to= (RFC3339; defaults to the next 7 days)
+func (h *OnCallHandler) GetEffectiveOnCall(c *gin.Context) {
+	groupID := c.Param("id")
+
+	from := time.Now().UTC()
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, db.EffectiveOnCallDefaultDays)
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	if to.Sub(from) > db.EffectiveOnCallMaxDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "range must not exceed " + strconv.Itoa(db.EffectiveOnCallMaxDays) + " days"})
+		return
+	}
+
+	oncall, err := h.OnCallService.GetEffectiveOnCall(groupID, from, to)
+	if err != nil {
+		log.Printf("GetEffectiveOnCall error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve on-call schedule"})
+		return
+	}
+	c.JSON(http.StatusOK, oncall)
+}
+
+// SwapSchedules handles schedule swapping requests. Schedule managers swap
+// right away (200); other members asking to swap their own shift get a
+// pending swap request back (202).
 func (h *OnCallHandler) SwapSchedules(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
@@ -203,8 +245,7 @@ func (h *OnCallHandler) SwapSchedules(c *gin.Context) {
 	}
 
 	var req db.ShiftSwapRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -220,7 +261,7 @@ func (h *OnCallHandler) SwapSchedules(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err.Error() == "only group leaders can swap other people's schedules" {
+		if errors.Is(err, services.ErrSchedulerForbidden) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
@@ -232,6 +273,11 @@ func (h *OnCallHandler) SwapSchedules(c *gin.Context) {
 		return
 	}
 
+	// A member's swap waits for a schedule manager's approval
+	if response.SwapRequest != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
 	c.JSON(http.StatusOK, response)
 }
 
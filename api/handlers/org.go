@@ -26,8 +26,7 @@ func (h *OrgHandler) CreateOrg(c *gin.Context) {
 	}
 
 	var input authz.CreateOrgInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
@@ -89,8 +88,7 @@ func (h *OrgHandler) UpdateOrg(c *gin.Context) {
 	orgID := c.Param("id")
 
 	var input authz.UpdateOrgInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
@@ -152,8 +150,7 @@ func (h *OrgHandler) AddOrgMember(c *gin.Context) {
 	orgID := c.Param("id")
 
 	var input authz.AddOrgMemberInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
@@ -180,8 +177,7 @@ func (h *OrgHandler) UpdateOrgMemberRole(c *gin.Context) {
 	var input struct {
 		Role authz.Role `json:"role" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
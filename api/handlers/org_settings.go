@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// OrgSettingsHandler exposes organization-wide preferences
+type OrgSettingsHandler struct {
+	OrgSettingsService *services.OrgSettingsService
+}
+
+// NewOrgSettingsHandler creates a new OrgSettingsHandler
+func NewOrgSettingsHandler(orgSettingsService *services.OrgSettingsService) *OrgSettingsHandler {
+	return &OrgSettingsHandler{OrgSettingsService: orgSettingsService}
+}
+
+// GetDisplaySettings handles GET /orgs/:id/settings/display
+func (h *OrgSettingsHandler) GetDisplaySettings(c *gin.Context) {
+	settings, err := h.OrgSettingsService.GetDisplaySettings(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrOrgSettingsNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		log.Printf("GetDisplaySettings error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load display settings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"display": settings, "date_formats": db.OrgDateFormats})
+}
+
+// UpdateDisplaySettings handles PATCH /orgs/:id/settings/display
+// Sets the default timezone, date and clock format and week start used in
+// reports, calendars, messages and analytics
+func (h *OrgSettingsHandler) UpdateDisplaySettings(c *gin.Context) {
+	var req db.UpdateOrgDisplaySettingsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	settings, err := h.OrgSettingsService.UpdateDisplaySettings(c.Param("id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrgSettingsNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		case errors.Is(err, services.ErrInvalidOrgDisplaySettings):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("UpdateDisplaySettings error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update display settings"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"display": settings})
+}
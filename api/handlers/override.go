@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -28,12 +29,15 @@ func (h *OverrideHandler) CreateOverride(c *gin.Context) {
 	}
 
 	var req db.CreateScheduleOverrideRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	override, err := h.OverrideService.CreateOverride(req, userID.(string))
+	if errors.Is(err, services.ErrSchedulerForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -73,9 +77,16 @@ func (h *OverrideHandler) DeleteOverride(c *gin.Context) {
 		return
 	}
 
-	err := h.OverrideService.DeleteOverride(overrideID)
+	err := h.OverrideService.DeleteOverride(overrideID, c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrOverrideNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Override not found"})
+		case errors.Is(err, services.ErrSchedulerForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// PendingChangeHandler lets org admins review destructive operations that are
+// waiting for a second admin's approval
+type PendingChangeHandler struct {
+	PendingChangeService *services.PendingChangeService
+	authorizer           authz.Authorizer
+}
+
+func NewPendingChangeHandler(pendingChangeService *services.PendingChangeService, authorizer authz.Authorizer) *PendingChangeHandler {
+	return &PendingChangeHandler{PendingChangeService: pendingChangeService, authorizer: authorizer}
+}
+
+// pendingChangeOrg returns the organization a request acts on, or writes the
+// error response when the caller lacks the action on it
+func (h *PendingChangeHandler) pendingChangeOrg(c *gin.Context, action authz.Action) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return "", false
+	}
+	if !h.authorizer.Check(c.Request.Context(), userID, action, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to review this organization's changes"})
+		return "", false
+	}
+	return orgID, true
+}
+
+// respondPendingChange answers a destructive request that is now waiting for
+// approval
+func respondPendingChange(c *gin.Context, change *db.PendingChange) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":        "This change needs another admin's approval before it runs",
+		"pending_change": change,
+	})
+}
+
+// ListPendingChanges returns the organization's changes, newest first
+// GET /pending-changes?status=pending|approved|rejected|expired|failed
+func (h *PendingChangeHandler) ListPendingChanges(c *gin.Context) {
+	orgID, ok := h.pendingChangeOrg(c, authz.ActionView)
+	if !ok {
+		return
+	}
+
+	changes, err := h.PendingChangeService.ListPendingChanges(orgID, c.Query("status"))
+	if err != nil {
+		log.Printf("ListPendingChanges error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pending changes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pending_changes": changes, "total": len(changes)})
+}
+
+// GetPendingChange returns one change
+// GET /pending-changes/:id
+func (h *PendingChangeHandler) GetPendingChange(c *gin.Context) {
+	orgID, ok := h.pendingChangeOrg(c, authz.ActionView)
+	if !ok {
+		return
+	}
+
+	change, err := h.PendingChangeService.GetPendingChange(orgID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrPendingChangeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending change not found"})
+			return
+		}
+		log.Printf("GetPendingChange error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending change"})
+		return
+	}
+	c.JSON(http.StatusOK, change)
+}
+
+// ApprovePendingChange approves a change requested by another admin and runs
+// it. Rotating API keys returns the new keys, shown only this once.
+// POST /pending-changes/:id/approve
+func (h *PendingChangeHandler) ApprovePendingChange(c *gin.Context) {
+	orgID, ok := h.pendingChangeOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	change, result, err := h.PendingChangeService.ApproveChange(orgID, c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		h.pendingChangeError(c, "ApprovePendingChange", err)
+		return
+	}
+	if change.Status == db.PendingChangeStatusFailed {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Change was approved but failed to run", "pending_change": change})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pending_change": change, "result": result})
+}
+
+// RejectPendingChange turns a change down; the requester may withdraw their own
+// POST /pending-changes/:id/reject
+// Body: {"reason": "..."}
+func (h *PendingChangeHandler) RejectPendingChange(c *gin.Context) {
+	orgID, ok := h.pendingChangeOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	var req db.PendingChangeDecision
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	change, err := h.PendingChangeService.RejectChange(orgID, c.Param("id"), c.GetString("user_id"), req.Reason)
+	if err != nil {
+		h.pendingChangeError(c, "RejectPendingChange", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pending_change": change})
+}
+
+// RotateAllAPIKeys asks to regenerate every active API key of the
+// organization. It runs once another admin approves it.
+// POST /api-keys/rotate-all
+// Body: {"reason": "..."}
+func (h *PendingChangeHandler) RotateAllAPIKeys(c *gin.Context) {
+	orgID, ok := h.pendingChangeOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	var req db.PendingChangeDecision
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	change, err := h.PendingChangeService.RequestChange(services.APIKeysRotateChange(orgID), c.GetString("user_id"), req.Reason)
+	if err != nil {
+		log.Printf("RotateAllAPIKeys error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request API key rotation"})
+		return
+	}
+	respondPendingChange(c, change)
+}
+
+func (h *PendingChangeHandler) pendingChangeError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, services.ErrPendingChangeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending change not found"})
+	case errors.Is(err, services.ErrPendingChangeClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPendingChangeSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pending change"})
+	}
+}
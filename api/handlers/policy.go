@@ -111,8 +111,7 @@ func (h *PolicyHandler) GetPolicy(c *gin.Context) {
 // Pattern follows CreateGroup exactly: body > query param > X-Org-ID header
 func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
 	var req db.CreatePolicyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -169,8 +168,7 @@ func (h *PolicyHandler) UpdatePolicy(c *gin.Context) {
 	}
 
 	var req db.UpdatePolicyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// DraftPostmortem handles POST /incidents/:id/postmortem/draft
+// Has the organization's language model draft a postmortem from the incident's
+// timeline, alerts, notes and chat history and saves it as the incident's
+// postmortem, marked as AI-generated, for responders to edit
+func (h *IncidentHandler) DraftPostmortem(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorizeIncident(c, id, authz.ActionUpdate, "You do not have permission to update this incident") {
+		return
+	}
+
+	var req db.DraftPostmortemRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	draft, err := h.incidentService.DraftPostmortem(c.Request.Context(), id, c.GetString("user_id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPostmortemExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "hint": "set overwrite to replace it"})
+		case errors.Is(err, services.ErrLLMBudgetExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLLMNotConfigured), errors.Is(err, services.ErrLLMProviderNotFound):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLLMRequestFailed):
+			log.Printf("DraftPostmortem error for incident %s: %v", id, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			log.Printf("DraftPostmortem error for incident %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to draft postmortem"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"postmortem": draft})
+}
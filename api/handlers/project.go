@@ -32,8 +32,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		Slug        string `json:"slug" binding:"required"`
 		Description string `json:"description"`
 	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
@@ -120,8 +119,7 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	projectID := c.Param("id")
 
 	var input authz.UpdateProjectInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
@@ -183,8 +181,7 @@ func (h *ProjectHandler) AddProjectMember(c *gin.Context) {
 	projectID := c.Param("id")
 
 	var input authz.AddProjectMemberInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
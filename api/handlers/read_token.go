@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ReadTokenHandler manages users' read-only tokens and serves the read-only
+// API (/read) those tokens unlock, e.g. for Grafana JSON datasources
+type ReadTokenHandler struct {
+	ReadTokenService *services.ReadTokenService
+	Authorizer       authz.Authorizer
+}
+
+// NewReadTokenHandler creates a new ReadTokenHandler
+func NewReadTokenHandler(readTokenService *services.ReadTokenService, authorizer authz.Authorizer) *ReadTokenHandler {
+	return &ReadTokenHandler{ReadTokenService: readTokenService, Authorizer: authorizer}
+}
+
+// ReadTokenAuthMiddleware authenticates a read token sent as a Bearer header
+// or as ?token= for portals that can only embed a URL. Only read routes are
+// registered behind it, so a token can never change anything.
+func (h *ReadTokenHandler) ReadTokenAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("token")
+		if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			raw = strings.TrimPrefix(authHeader, "Bearer ")
+		}
+		if raw == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Read token required"})
+			c.Abort()
+			return
+		}
+
+		token, err := h.ReadTokenService.AuthenticateToken(raw)
+		if err != nil {
+			if !errors.Is(err, services.ErrReadTokenUnauthorized) {
+				log.Printf("Read token auth error: %v", err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid read token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("read_token", token)
+		c.Next()
+	}
+}
+
+// RequireReadScope rejects read tokens that were not given scope
+func (h *ReadTokenHandler) RequireReadScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, s := range readToken(c).Scopes {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Read token lacks the " + scope + " scope"})
+		c.Abort()
+	}
+}
+
+// READ TOKEN MANAGEMENT (OIDC-authenticated, any user)
+
+// CreateToken handles POST /read-tokens
+// Any user may create a token for an organization (and project) they can access
+func (h *ReadTokenHandler) CreateToken(c *gin.Context) {
+	var req db.CreateReadTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if !h.Authorizer.CanAccessOrg(c.Request.Context(), userID, req.OrganizationID) ||
+		(req.ProjectID != "" && !h.Authorizer.CanAccessProject(c.Request.Context(), userID, req.ProjectID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this organization or project"})
+		return
+	}
+
+	token, err := h.ReadTokenService.CreateToken(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReadToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "valid_scopes": db.ValidReadScopes})
+			return
+		}
+		log.Printf("CreateReadToken error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create read token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"read_token": token,
+		"message":    "Store this token securely - it will not be shown again",
+	})
+}
+
+// ListTokens handles GET /read-tokens
+func (h *ReadTokenHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.ReadTokenService.ListTokens(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve read tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"read_tokens": tokens, "total": len(tokens)})
+}
+
+// RevokeToken handles DELETE /read-tokens/:id
+func (h *ReadTokenHandler) RevokeToken(c *gin.Context) {
+	if err := h.ReadTokenService.RevokeToken(c.GetString("user_id"), c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrReadTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Read token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke read token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Read token revoked"})
+}
+
+// READ-ONLY ENDPOINTS (read token)
+
+// GetIncidents handles GET /read/incidents?status=open|all|<status>&limit=
+func (h *ReadTokenHandler) GetIncidents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	incidents, err := h.ReadTokenService.ListIncidents(readToken(c), c.Query("status"), limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"incidents": incidents, "total": len(incidents)})
+}
+
+// GetOnCall handles GET /read/oncall
+func (h *ReadTokenHandler) GetOnCall(c *gin.Context) {
+	oncall, err := h.ReadTokenService.GetOnCall(readToken(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"oncall": oncall, "total": len(oncall)})
+}
+
+// GetMonitors handles GET /read/monitors
+func (h *ReadTokenHandler) GetMonitors(c *gin.Context) {
+	monitors, err := h.ReadTokenService.GetMonitorStatus(readToken(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, monitors)
+}
+
+func (h *ReadTokenHandler) handleError(c *gin.Context, err error) {
+	log.Printf("Read API error: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load data"})
+}
+
+func readToken(c *gin.Context) *db.ReadToken {
+	return c.MustGet("read_token").(*db.ReadToken)
+}
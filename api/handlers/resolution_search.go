@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/services"
+)
+
+// SearchResolutions handles GET /search/resolutions?q=
+// Ranked past fixes: incidents whose resolution notes, postmortem or notes
+// match the query, with links back to each incident
+func (h *IncidentHandler) SearchResolutions(c *gin.Context) {
+	filters := authz.GetReBACFilters(c)
+	if orgID, _ := filters["current_org_id"].(string); orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+
+	filters["q"] = c.Query("q")
+	for _, param := range []string{"project_id", "group_id", "service_id"} {
+		if value := c.Query(param); value != "" {
+			filters[param] = value
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters["limit"] = limit
+		}
+	}
+
+	results, err := h.incidentService.SearchResolutions(filters)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search resolutions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   c.Query("q"),
+		"results": results,
+		"total":   len(results),
+	})
+}
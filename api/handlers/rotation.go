@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -29,8 +30,7 @@ func (h *RotationHandler) CreateRotationCycle(c *gin.Context) {
 	}
 
 	var req db.CreateRotationCycleRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -181,8 +181,7 @@ func (h *RotationHandler) GetCurrentRotationMember(c *gin.Context) {
 // CreateScheduleOverride creates an override for existing schedule
 func (h *RotationHandler) CreateScheduleOverride(c *gin.Context) {
 	var req db.CreateScheduleOverrideRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -194,6 +193,10 @@ func (h *RotationHandler) CreateScheduleOverride(c *gin.Context) {
 	}
 
 	overrideID, err := h.RotationService.CreateScheduleOverride(req, userID.(string))
+	if errors.Is(err, services.ErrSchedulerForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
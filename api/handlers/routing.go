@@ -67,8 +67,7 @@ func (h *RoutingHandler) GetRoutingTableWithRules(c *gin.Context) {
 // CreateRoutingTable creates a new routing table
 func (h *RoutingHandler) CreateRoutingTable(c *gin.Context) {
 	var req db.CreateRoutingTableRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -96,8 +95,7 @@ func (h *RoutingHandler) UpdateRoutingTable(c *gin.Context) {
 	id := c.Param("id")
 
 	var req db.UpdateRoutingTableRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -165,8 +163,7 @@ func (h *RoutingHandler) CreateRoutingRule(c *gin.Context) {
 	tableID := c.Param("id")
 
 	var req db.CreateRoutingRuleRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -207,8 +204,7 @@ func (h *RoutingHandler) DeleteRoutingRule(c *gin.Context) {
 // TestRouting tests routing for given alert attributes
 func (h *RoutingHandler) TestRouting(c *gin.Context) {
 	var req db.TestRoutingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ScheduleImpactHandler previews what unsaved schedule changes would do to
+// open incidents
+type ScheduleImpactHandler struct {
+	IncidentService *services.IncidentService
+}
+
+func NewScheduleImpactHandler(incidentService *services.IncidentService) *ScheduleImpactHandler {
+	return &ScheduleImpactHandler{IncidentService: incidentService}
+}
+
+// PreviewScheduleImpact reports which on-call stretches, open incidents and
+// upcoming escalations would change assignee if the given shift and override
+// changes were saved. Nothing is saved. With "notify": true the affected
+// users get a Slack DM about each incident that would move.
+// POST /groups/{id}/schedules/preview-impact
+// Body: {"shifts": {"create", "update", "delete"}, "overrides": {"create": [...], "delete": ["id"]}, "hours": 24, "notify": false}
+func (h *ScheduleImpactHandler) PreviewScheduleImpact(c *gin.Context) {
+	groupID := c.Param("id")
+	if groupID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group ID is required"})
+		return
+	}
+
+	var req db.ScheduleImpactRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	impact, err := h.IncidentService.PreviewScheduleImpact(groupID, req)
+	if err != nil {
+		var batchErr *services.ShiftBatchError
+		if errors.As(err, &batchErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Schedule change is invalid", "errors": batchErr.Errors})
+			return
+		}
+		log.Printf("PreviewScheduleImpact error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview schedule impact"})
+		return
+	}
+	c.JSON(http.StatusOK, impact)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+)
+
+// RegenerateSchedules redistributes a departing member's future shifts across
+// the remaining members of each affected scheduler's rotation
+// POST /groups/{id}/schedulers/regenerate
+// Body: {"user_id": "...", "preview": true}
+func (h *SchedulerHandler) RegenerateSchedules(c *gin.Context) {
+	groupID := c.Param("id")
+	if groupID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group ID is required"})
+		return
+	}
+
+	var req db.RegenerateSchedulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	// Anyone may preview; applying it rewrites shifts across the group's
+	// schedulers, which only the group's owners and admins manage
+	if !req.Preview {
+		canManage, err := h.SchedulerService.CanManageGroupSchedules(groupID, c.GetString("user_id"))
+		if err != nil {
+			log.Printf("RegenerateSchedules error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check schedule permissions"})
+			return
+		}
+		if !canManage {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only group owners and admins can regenerate schedules"})
+			return
+		}
+	}
+
+	result, err := h.SchedulerService.RegenerateSchedulesForDeparture(groupID, req.UserID, req.Preview)
+	if err != nil {
+		log.Printf("RegenerateSchedules error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate schedules: " + err.Error()})
+		return
+	}
+
+	message := "Schedules regenerated successfully"
+	if req.Preview {
+		message = "Preview only - no shifts were changed"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"regeneration": result,
+		"message":      message,
+	})
+}
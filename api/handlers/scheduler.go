@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -143,6 +144,18 @@ func (h *SchedulerHandler) CreateServiceSchedule(c *gin.Context) {
 		req.SchedulerID = scheduler.ID
 	}
 
+	// Only the scheduler's managers may add shifts to it
+	canManage, err := h.SchedulerService.CanManageScheduler(req.SchedulerID, userID.(string))
+	if err != nil {
+		log.Printf("CreateGroupSchedule error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check schedule permissions"})
+		return
+	}
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrSchedulerForbidden.Error()})
+		return
+	}
+
 	// Set default shift type if not provided
 	if req.ShiftType == "" {
 		req.ShiftType = "custom"
@@ -472,8 +485,12 @@ func (h *SchedulerHandler) DeleteScheduler(c *gin.Context) {
 		return
 	}
 
-	err := h.SchedulerService.DeleteScheduler(schedulerID)
+	err := h.SchedulerService.DeleteScheduler(schedulerID, c.GetString("user_id"))
 	if err != nil {
+		if errors.Is(err, services.ErrSchedulerForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err.Error() == "scheduler not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Scheduler not found"})
 			return
@@ -683,6 +700,11 @@ func (h *SchedulerHandler) UpdateSchedulerWithShifts(c *gin.Context) {
 		userID.(string),
 	)
 
+	// No point falling back for a user who doesn't manage the scheduler
+	if errors.Is(err, services.ErrSchedulerForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("⚠️  Optimized update failed, falling back to original service: %v", err)
 
@@ -695,6 +717,10 @@ func (h *SchedulerHandler) UpdateSchedulerWithShifts(c *gin.Context) {
 		)
 
 		if err != nil {
+			if errors.Is(err, services.ErrSchedulerForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
 			if err.Error() == "scheduler not found" {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Scheduler not found"})
 				return
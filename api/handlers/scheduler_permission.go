@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// writeSchedulerManagerError maps scheduler manager errors to a response
+func writeSchedulerManagerError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, services.ErrSchedulerNotInGroup), errors.Is(err, services.ErrSchedulerManagerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSchedulerManagersForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSchedulerManagerNotMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduler managers"})
+	}
+}
+
+// ListSchedulerManagers returns the users listed as managers of a scheduler.
+// The group's owners and admins manage every scheduler without being listed.
+// GET /groups/{id}/schedulers/{scheduler_id}/managers
+func (h *SchedulerHandler) ListSchedulerManagers(c *gin.Context) {
+	managers, err := h.SchedulerService.ListSchedulerManagers(c.Param("id"), c.Param("scheduler_id"))
+	if err != nil {
+		writeSchedulerManagerError(c, "ListSchedulerManagers", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"managers": managers, "total": len(managers)})
+}
+
+// AddSchedulerManager lets a group member change the scheduler's shifts and
+// overrides; only group owners and admins may add managers
+// POST /groups/{id}/schedulers/{scheduler_id}/managers
+// Body: {"user_id": "..."}
+func (h *SchedulerHandler) AddSchedulerManager(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.AddSchedulerManagerRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	manager, err := h.SchedulerService.AddSchedulerManager(c.Param("id"), c.Param("scheduler_id"), req, userID)
+	if err != nil {
+		writeSchedulerManagerError(c, "AddSchedulerManager", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"manager": manager, "message": "Scheduler manager added"})
+}
+
+// RemoveSchedulerManager takes a user off the scheduler's managers
+// DELETE /groups/{id}/schedulers/{scheduler_id}/managers/{user_id}
+func (h *SchedulerHandler) RemoveSchedulerManager(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	err := h.SchedulerService.RemoveSchedulerManager(c.Param("id"), c.Param("scheduler_id"), c.Param("user_id"), userID)
+	if err != nil {
+		writeSchedulerManagerError(c, "RemoveSchedulerManager", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduler manager removed"})
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const scimContentType = "application/scim+json"
+
+// SCIMHandler serves the SCIM 2.0 provisioning API (/scim/v2) and SCIM token management
+type SCIMHandler struct {
+	SCIMService *services.SCIMService
+}
+
+// NewSCIMHandler creates a new SCIMHandler
+func NewSCIMHandler(scimService *services.SCIMService) *SCIMHandler {
+	return &SCIMHandler{SCIMService: scimService}
+}
+
+// SCIMAuthMiddleware authenticates IdP requests with an org-scoped SCIM bearer token
+// and sets scim_org_id in the context.
+func (h *SCIMHandler) SCIMAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			scimError(c, http.StatusUnauthorized, "", "Bearer token required")
+			c.Abort()
+			return
+		}
+
+		orgID, err := h.SCIMService.AuthenticateToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			if !errors.Is(err, services.ErrSCIMUnauthorized) {
+				log.Printf("SCIM auth error: %v", err)
+			}
+			scimError(c, http.StatusUnauthorized, "", "Invalid SCIM token")
+			c.Abort()
+			return
+		}
+
+		c.Set("scim_org_id", orgID)
+		c.Next()
+	}
+}
+
+// SCIM TOKEN MANAGEMENT (OIDC-authenticated, org admins)
+
+// CreateToken handles POST /orgs/:id/scim-tokens
+func (h *SCIMHandler) CreateToken(c *gin.Context) {
+	var req db.CreateSCIMTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	token, err := h.SCIMService.CreateToken(c.Param("id"), req.Name, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("CreateSCIMToken error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create SCIM token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"scim_token": token,
+		"base_url":   "/scim/v2",
+		"message":    "Store this token securely - it will not be shown again",
+	})
+}
+
+// ListTokens handles GET /orgs/:id/scim-tokens
+func (h *SCIMHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.SCIMService.ListTokens(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve SCIM tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scim_tokens": tokens, "total": len(tokens)})
+}
+
+// RevokeToken handles DELETE /orgs/:id/scim-tokens/:token_id
+func (h *SCIMHandler) RevokeToken(c *gin.Context) {
+	if err := h.SCIMService.RevokeToken(c.Param("id"), c.Param("token_id")); err != nil {
+		if errors.Is(err, services.ErrSCIMNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "SCIM token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke SCIM token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "SCIM token revoked"})
+}
+
+// DISCOVERY
+
+// ServiceProviderConfig handles GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(http.StatusOK, gin.H{
+		"schemas":        []string{db.SCIMSchemaSPConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": 200},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Organization-scoped SCIM token issued from SLAR organization settings",
+			"primary":     true,
+		}},
+	})
+}
+
+// ResourceTypes handles GET /scim/v2/ResourceTypes
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	resources := []gin.H{
+		{"schemas": []string{db.SCIMSchemaResourceType}, "id": "User", "name": "User", "endpoint": "/Users", "schema": db.SCIMSchemaUser},
+		{"schemas": []string{db.SCIMSchemaResourceType}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": db.SCIMSchemaGroup},
+	}
+	scimJSON(c, http.StatusOK, db.SCIMListResponse{
+		Schemas:      []string{db.SCIMSchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// USERS
+
+// ListUsers handles GET /scim/v2/Users?filter=userName eq "x"&startIndex=1&count=100
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	startIndex, count := scimPagination(c)
+	users, total, err := h.SCIMService.ListUsers(c.GetString("scim_org_id"), c.Query("filter"), startIndex, count)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, db.SCIMListResponse{
+		Schemas:      []string{db.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(users),
+		Resources:    users,
+	})
+}
+
+// GetUser handles GET /scim/v2/Users/:id
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	user, err := h.SCIMService.GetUser(c.GetString("scim_org_id"), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, user)
+}
+
+// CreateUser handles POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req db.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	user, err := h.SCIMService.CreateUser(c.GetString("scim_org_id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusCreated, user)
+}
+
+// ReplaceUser handles PUT /scim/v2/Users/:id
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var req db.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	user, err := h.SCIMService.ReplaceUser(c.GetString("scim_org_id"), c.Param("id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, user)
+}
+
+// PatchUser handles PATCH /scim/v2/Users/:id
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var req db.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	user, err := h.SCIMService.PatchUser(c.GetString("scim_org_id"), c.Param("id"), req.Operations)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, user)
+}
+
+// DeleteUser handles DELETE /scim/v2/Users/:id
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	if err := h.SCIMService.DeleteUser(c.GetString("scim_org_id"), c.Param("id")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GROUPS
+
+// ListGroups handles GET /scim/v2/Groups?filter=displayName eq "x"&excludedAttributes=members
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	startIndex, count := scimPagination(c)
+	includeMembers := !strings.Contains(c.Query("excludedAttributes"), "members")
+	groups, total, err := h.SCIMService.ListGroups(c.GetString("scim_org_id"), c.Query("filter"), startIndex, count, includeMembers)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, db.SCIMListResponse{
+		Schemas:      []string{db.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(groups),
+		Resources:    groups,
+	})
+}
+
+// GetGroup handles GET /scim/v2/Groups/:id
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	group, err := h.SCIMService.GetGroup(c.GetString("scim_org_id"), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, group)
+}
+
+// CreateGroup handles POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var req db.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	group, err := h.SCIMService.CreateGroup(c.GetString("scim_org_id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup handles PUT /scim/v2/Groups/:id
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var req db.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	group, err := h.SCIMService.ReplaceGroup(c.GetString("scim_org_id"), c.Param("id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, group)
+}
+
+// PatchGroup handles PATCH /scim/v2/Groups/:id
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var req db.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	group, err := h.SCIMService.PatchGroup(c.GetString("scim_org_id"), c.Param("id"), req.Operations)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, group)
+}
+
+// DeleteGroup handles DELETE /scim/v2/Groups/:id
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	if err := h.SCIMService.DeleteGroup(c.GetString("scim_org_id"), c.Param("id")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// handleError maps service errors to SCIM error responses
+func (h *SCIMHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSCIMNotFound):
+		scimError(c, http.StatusNotFound, "", "Resource not found")
+	case errors.Is(err, services.ErrSCIMConflict):
+		scimError(c, http.StatusConflict, "uniqueness", err.Error())
+	case errors.Is(err, services.ErrSCIMInvalidFilter):
+		scimError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+	case errors.Is(err, services.ErrSCIMInvalidValue):
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+	default:
+		log.Printf("SCIM error: %v", err)
+		scimError(c, http.StatusInternalServerError, "", "Internal server error")
+	}
+}
+
+func scimPagination(c *gin.Context) (int, int) {
+	startIndex, count := 1, 100
+	if v, err := strconv.Atoi(c.Query("startIndex")); err == nil && v > 0 {
+		startIndex = v
+	}
+	if v, err := strconv.Atoi(c.Query("count")); err == nil && v >= 0 {
+		count = v
+	}
+	if count > 200 {
+		count = 200
+	}
+	return startIndex, count
+}
+
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+func scimError(c *gin.Context, status int, scimType, detail string) {
+	scimJSON(c, status, db.SCIMError{
+		Schemas:  []string{db.SCIMSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
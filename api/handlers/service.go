@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,10 +13,43 @@ import (
 
 type ServiceHandler struct {
 	ServiceService *services.ServiceService
+	authorizer     authz.Authorizer
 }
 
-func NewServiceHandler(serviceService *services.ServiceService) *ServiceHandler {
-	return &ServiceHandler{ServiceService: serviceService}
+func NewServiceHandler(serviceService *services.ServiceService, authorizer authz.Authorizer) *ServiceHandler {
+	return &ServiceHandler{ServiceService: serviceService, authorizer: authorizer}
+}
+
+// authorizeService checks the user may perform action on the organization of
+// the service in :id and returns that organization
+func (h *ServiceHandler) authorizeService(c *gin.Context, action authz.Action) (string, bool) {
+	return authorizeServiceOrg(c, h.authorizer, h.ServiceService, action)
+}
+
+// authorizeServiceOrg looks up the organization of the service in :id and
+// checks the user may perform action on it, writing the error response when
+// not. The organization is returned for scoping queries.
+func authorizeServiceOrg(c *gin.Context, authorizer authz.Authorizer, serviceService *services.ServiceService, action authz.Action) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	orgID, err := serviceService.GetServiceOrganizationID(c.Param("id"))
+	if errors.Is(err, services.ErrServiceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return "", false
+	}
+	if err != nil {
+		log.Printf("authorizeServiceOrg error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up service"})
+		return "", false
+	}
+	if orgID == "" || !authorizer.Check(c.Request.Context(), userID, action, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this service"})
+		return "", false
+	}
+	return orgID, true
 }
 
 // CreateService creates a new service within a group
@@ -90,7 +125,7 @@ func (h *ServiceHandler) GetService(c *gin.Context) {
 }
 
 // GetGroupServices returns all services in a group
-// GET /groups/{id}/services
+// GET /groups/{id}/services?fields=name,is_active
 func (h *ServiceHandler) GetGroupServices(c *gin.Context) {
 	groupID := c.Param("id")
 	if groupID == "" {
@@ -104,8 +139,13 @@ func (h *ServiceHandler) GetGroupServices(c *gin.Context) {
 		return
 	}
 
+	items, ok := selectFields(c, services)
+	if !ok {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"services": services,
+		"services": items,
 		"count":    len(services),
 	})
 }
@@ -146,7 +186,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 		return
 	}
 
-	err := h.ServiceService.DeleteService(serviceID)
+	err := h.ServiceService.DeleteService(serviceID, c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service: " + err.Error()})
 		return
@@ -174,7 +214,7 @@ func (h *ServiceHandler) GetServiceByRoutingKey(c *gin.Context) {
 }
 
 // ListAllServices returns all services with ReBAC filtering
-// GET /services
+// GET /services?fields=name,is_active
 // ReBAC: Uses organization context for MANDATORY tenant isolation
 func (h *ServiceHandler) ListAllServices(c *gin.Context) {
 	// =========================================================================
@@ -215,8 +255,13 @@ func (h *ServiceHandler) ListAllServices(c *gin.Context) {
 		return
 	}
 
+	items, ok := selectFields(c, services)
+	if !ok {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"services": services,
+		"services": items,
 		"count":    len(services),
 	})
 }
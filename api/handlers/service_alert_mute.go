@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListAlertMutes returns a service's active alert mutes
+// GET /services/{id}/mutes?include_expired=true
+func (h *ServiceHandler) ListAlertMutes(c *gin.Context) {
+	orgID, ok := h.authorizeService(c, authz.ActionView)
+	if !ok {
+		return
+	}
+	mutes, err := h.ServiceService.ListAlertMutes(orgID, c.Param("id"), c.Query("include_expired") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert mutes: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mutes": mutes,
+		"count": len(mutes),
+	})
+}
+
+// CreateAlertMute silences specific alerts on a service until the mute expires
+// POST /services/{id}/mutes
+func (h *ServiceHandler) CreateAlertMute(c *gin.Context) {
+	orgID, ok := h.authorizeService(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+	var req db.CreateServiceAlertMuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	mute, err := h.ServiceService.CreateAlertMute(orgID, c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAlertMute) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrServiceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert mute: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"mute":    mute,
+		"message": "Alert mute created successfully",
+	})
+}
+
+// DeleteAlertMute lifts an alert mute before it expires
+// DELETE /services/{id}/mutes/{mute_id}
+func (h *ServiceHandler) DeleteAlertMute(c *gin.Context) {
+	orgID, ok := h.authorizeService(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+	if err := h.ServiceService.DeleteAlertMute(orgID, c.Param("id"), c.Param("mute_id")); err != nil {
+		if errors.Is(err, services.ErrAlertMuteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert mute not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert mute: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert mute deleted successfully"})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// BootstrapService creates a service with its scheduler, escalation policy and
+// integration in one call and returns the webhook URL to send alerts to
+// POST /groups/{id}/services/bootstrap
+func (h *ServiceHandler) BootstrapService(c *gin.Context) {
+	var req db.BootstrapServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ReBAC: Auto-fill tenant context if not provided in request
+	filters := authz.GetReBACFilters(c)
+	if req.OrganizationID == "" {
+		if orgID, ok := filters["current_org_id"].(string); ok && orgID != "" {
+			req.OrganizationID = orgID
+		}
+	}
+	if req.ProjectID == "" {
+		if projectID, ok := filters["project_id"].(string); ok && projectID != "" {
+			req.ProjectID = projectID
+		}
+	}
+
+	result, err := h.ServiceService.BootstrapService(c.Param("id"), req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBootstrapRoutingKeyTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBootstrapMemberNotInGroup),
+			errors.Is(err, services.ErrBootstrapInvalidIntegrationType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBootstrapIntegrationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bootstrap service: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"bootstrap": result,
+		"message":   "Service bootstrapped successfully",
+	})
+}
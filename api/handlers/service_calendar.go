@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// parseCalendarRange reads the RFC3339 from/to query params, defaulting to
+// whole weeks in the organization's timezone around the past week and the
+// next two weeks
+func parseCalendarRange(c *gin.Context, display db.OrgDisplaySettings) (time.Time, time.Time, error) {
+	from, to := services.DefaultCalendarRange(display, time.Now())
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("from must be an RFC3339 timestamp")
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("to must be an RFC3339 timestamp")
+		}
+	}
+	if !to.After(from) {
+		return from, to, errors.New("to must be after from")
+	}
+	if to.Sub(from) > db.CalendarMaxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("range must not exceed %d days", db.CalendarMaxRangeDays)
+	}
+	return from, to, nil
+}
+
+// GetServiceCalendar returns maintenance windows, on-call shifts and incidents
+// for a service as one timeline
+// GET /services/{id}/calendar?from=&to=
+func (h *ServiceHandler) GetServiceCalendar(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	display := h.ServiceService.ServiceDisplaySettings(c.Param("id"))
+	from, to, err := parseCalendarRange(c, display)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar, err := h.ServiceService.GetServiceCalendar(c.Param("id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get service calendar: " + err.Error()})
+		return
+	}
+	calendar.Display = display
+
+	c.JSON(http.StatusOK, gin.H{"calendar": calendar})
+}
+
+// ListMaintenanceWindows returns a service's maintenance windows in a range
+// GET /services/{id}/maintenance-windows?from=&to=
+func (h *ServiceHandler) ListMaintenanceWindows(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	from, to, err := parseCalendarRange(c, h.ServiceService.ServiceDisplaySettings(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	windows, err := h.ServiceService.ListMaintenanceWindows(c.Param("id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list maintenance windows: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"maintenance_windows": windows,
+		"count":               len(windows),
+	})
+}
+
+// CreateMaintenanceWindow schedules maintenance on a service
+// POST /services/{id}/maintenance-windows
+func (h *ServiceHandler) CreateMaintenanceWindow(c *gin.Context) {
+	orgID, ok := h.authorizeService(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+	var req db.CreateMaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	window, err := h.ServiceService.CreateMaintenanceWindow(orgID, c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrServiceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"maintenance_window": window,
+		"message":            "Maintenance window created successfully",
+	})
+}
+
+// DeleteMaintenanceWindow removes a maintenance window
+// DELETE /services/{id}/maintenance-windows/{window_id}
+func (h *ServiceHandler) DeleteMaintenanceWindow(c *gin.Context) {
+	orgID, ok := h.authorizeService(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+	if err := h.ServiceService.DeleteMaintenanceWindow(orgID, c.Param("id"), c.Param("window_id")); err != nil {
+		if errors.Is(err, services.ErrMaintenanceWindowNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete maintenance window: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window deleted successfully"})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListDeployRules returns a service's resolve-on-deploy rules
+// GET /services/{id}/deploy-rules
+func (h *ServiceHandler) ListDeployRules(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	rules, err := h.ServiceService.ListDeployRules(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deploy rules: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deploy_rules": rules,
+		"count":        len(rules),
+	})
+}
+
+// CreateDeployRule adds a resolve-on-deploy rule to a service
+// POST /services/{id}/deploy-rules
+func (h *ServiceHandler) CreateDeployRule(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.CreateServiceDeployRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	rule, err := h.ServiceService.CreateDeployRule(c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create deploy rule: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"deploy_rule": rule,
+		"message":     "Deploy rule created successfully",
+	})
+}
+
+// UpdateDeployRule updates a deploy rule
+// PATCH /services/{id}/deploy-rules/{rule_id}
+func (h *ServiceHandler) UpdateDeployRule(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.UpdateServiceDeployRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	rule, err := h.ServiceService.UpdateDeployRule(c.Param("id"), c.Param("rule_id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrDeployRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deploy rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deploy rule: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deploy_rule": rule,
+		"message":     "Deploy rule updated successfully",
+	})
+}
+
+// DeleteDeployRule removes a deploy rule
+// DELETE /services/{id}/deploy-rules/{rule_id}
+func (h *ServiceHandler) DeleteDeployRule(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	if err := h.ServiceService.DeleteDeployRule(c.Param("id"), c.Param("rule_id")); err != nil {
+		if errors.Is(err, services.ErrDeployRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deploy rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete deploy rule: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deploy rule deleted successfully"})
+}
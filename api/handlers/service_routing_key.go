@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// routingKeyError maps routing key errors to a response
+func routingKeyError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoutingKeyServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+	case errors.Is(err, services.ErrRoutingKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routing key not found"})
+	case errors.Is(err, services.ErrRoutingKeyTaken), errors.Is(err, services.ErrRoutingKeyPrimary):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidRoutingKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + ": " + err.Error()})
+	}
+}
+
+// ListRoutingKeys returns a service's routing keys with their expiry and usage
+// GET /services/{id}/routing-keys
+func (h *ServiceHandler) ListRoutingKeys(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	keys, err := h.ServiceService.ListRoutingKeys(c.Param("id"))
+	if err != nil {
+		routingKeyError(c, "list routing keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"routing_keys": keys,
+		"count":        len(keys),
+	})
+}
+
+// CreateRoutingKey adds another active routing key to a service
+// POST /services/{id}/routing-keys
+func (h *ServiceHandler) CreateRoutingKey(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.CreateServiceRoutingKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	key, err := h.ServiceService.CreateRoutingKey(c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		routingKeyError(c, "create routing key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"routing_key": key,
+		"message":     "Routing key created successfully",
+	})
+}
+
+// RotateRoutingKey replaces a service's primary routing key, keeping the old
+// one valid for a grace period
+// POST /services/{id}/routing-keys/rotate
+func (h *ServiceHandler) RotateRoutingKey(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.RotateServiceRoutingKeyRequest
+	// The body is optional: an empty rotation generates a key with the default grace period
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	rotation, err := h.ServiceService.RotateRoutingKey(c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		routingKeyError(c, "rotate routing key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"routing_key": rotation.RoutingKey,
+		"previous":    rotation.Previous,
+		"message":     "Routing key rotated successfully",
+	})
+}
+
+// RevokeRoutingKey stops a non-primary routing key from routing events
+// DELETE /services/{id}/routing-keys/{key_id}
+func (h *ServiceHandler) RevokeRoutingKey(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	if err := h.ServiceService.RevokeRoutingKey(c.Param("id"), c.Param("key_id")); err != nil {
+		routingKeyError(c, "revoke routing key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Routing key revoked successfully"})
+}
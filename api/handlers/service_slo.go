@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListSLOs returns a service's SLOs with their error budget status
+// GET /services/{id}/slos
+func (h *ServiceHandler) ListSLOs(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	slos, err := h.ServiceService.ListSLOs(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list SLOs: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"slos":  slos,
+		"count": len(slos),
+	})
+}
+
+// GetSLO returns one SLO with its error budget status
+// GET /services/{id}/slos/{slo_id}
+func (h *ServiceHandler) GetSLO(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	slo, err := h.ServiceService.GetSLO(c.Param("id"), c.Param("slo_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrSLONotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "SLO not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get SLO: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"slo": slo})
+}
+
+// CreateSLO adds an SLO to a service
+// POST /services/{id}/slos
+func (h *ServiceHandler) CreateSLO(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.CreateServiceSLORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	slo, err := h.ServiceService.CreateSLO(c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrSLOMonitorRequired) || errors.Is(err, services.ErrSLOMonitorNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create SLO: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"slo":     slo,
+		"message": "SLO created successfully",
+	})
+}
+
+// UpdateSLO updates an SLO
+// PATCH /services/{id}/slos/{slo_id}
+func (h *ServiceHandler) UpdateSLO(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.UpdateServiceSLORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	slo, err := h.ServiceService.UpdateSLO(c.Param("id"), c.Param("slo_id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrSLONotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "SLO not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update SLO: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"slo":     slo,
+		"message": "SLO updated successfully",
+	})
+}
+
+// DeleteSLO removes an SLO
+// DELETE /services/{id}/slos/{slo_id}
+func (h *ServiceHandler) DeleteSLO(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	if err := h.ServiceService.DeleteSLO(c.Param("id"), c.Param("slo_id")); err != nil {
+		if errors.Is(err, services.ErrSLONotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "SLO not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete SLO: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLO deleted successfully"})
+}
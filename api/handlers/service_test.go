@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/services"
+)
+
+func TestAuthorizeServiceOrg(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pg, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	authorizer := new(MockAuthorizer)
+	authorizer.On("Check", mock.Anything, "user-1", authz.ActionManage, authz.ResourceOrg, "org-1").Return(true)
+	authorizer.On("Check", mock.Anything, "user-1", authz.ActionManage, authz.ResourceOrg, "org-2").Return(false)
+
+	serviceService := services.NewServiceService(pg)
+	r := gin.New()
+	r.POST("/services/:id/thing", func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		orgID, ok := authorizeServiceOrg(c, authorizer, serviceService, authz.ActionManage)
+		if ok {
+			c.String(http.StatusOK, orgID)
+		}
+	})
+
+	serve := func(serviceID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/services/"+serviceID+"/thing", nil))
+		return w
+	}
+
+	sqlMock.ExpectQuery(`SELECT organization_id FROM services`).WithArgs("svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow("org-1"))
+	if w := serve("svc-1"); w.Code != http.StatusOK || w.Body.String() != "org-1" {
+		t.Errorf("own service: got %d %q", w.Code, w.Body.String())
+	}
+
+	sqlMock.ExpectQuery(`SELECT organization_id FROM services`).WithArgs("svc-2").
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow("org-2"))
+	if w := serve("svc-2"); w.Code != http.StatusForbidden {
+		t.Errorf("another org's service: got %d, want 403", w.Code)
+	}
+
+	sqlMock.ExpectQuery(`SELECT organization_id FROM services`).WithArgs("svc-3").
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}))
+	if w := serve("svc-3"); w.Code != http.StatusNotFound {
+		t.Errorf("missing service: got %d, want 404", w.Code)
+	}
+
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
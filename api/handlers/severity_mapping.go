@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListSeverityMappings returns how a service maps alert severities to
+// incident priority and urgency
+// GET /services/{id}/severity-mappings
+func (h *ServiceHandler) ListSeverityMappings(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	mappings, err := h.ServiceService.ListSeverityMappings(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list severity mappings: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"severity_mappings": mappings,
+		"count":             len(mappings),
+	})
+}
+
+// SetSeverityMappings replaces a service's severity mappings
+// PUT /services/{id}/severity-mappings
+func (h *ServiceHandler) SetSeverityMappings(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.SetSeverityMappingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	mappings, err := h.ServiceService.SetSeverityMappings(c.Param("id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSeverityMapping) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save severity mappings: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"severity_mappings": mappings,
+		"message":           "Severity mappings saved successfully",
+	})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const sheetExportsAdminOnly = "Only group leaders can manage sheet exports"
+
+// SheetExportHandler handles group sheet exports, which append the group's
+// resolved incidents to a Google Sheet or a CSV webhook
+type SheetExportHandler struct {
+	SheetExportService *services.SheetExportService
+	InvitationService  *services.GroupInvitationService // group admin checks
+}
+
+// NewSheetExportHandler creates a new SheetExportHandler
+func NewSheetExportHandler(sheetExportService *services.SheetExportService, invitationService *services.GroupInvitationService) *SheetExportHandler {
+	return &SheetExportHandler{
+		SheetExportService: sheetExportService,
+		InvitationService:  invitationService,
+	}
+}
+
+// ListSheetExports handles GET /groups/:id/sheet-exports
+func (h *SheetExportHandler) ListSheetExports(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, sheetExportsAdminOnly) {
+		return
+	}
+
+	exports, err := h.SheetExportService.ListExports(groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sheet exports: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sheet_exports": exports,
+		"count":         len(exports),
+	})
+}
+
+// CreateSheetExport handles POST /groups/:id/sheet-exports
+func (h *SheetExportHandler) CreateSheetExport(c *gin.Context) {
+	groupID := c.Param("id")
+
+	var req db.CreateGroupSheetExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !requireGroupAdmin(c, h.InvitationService, groupID, sheetExportsAdminOnly) {
+		return
+	}
+
+	export, err := h.SheetExportService.CreateExport(groupID, req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSheetExport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sheet export: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"sheet_export": export,
+		"message":      "Sheet export created successfully",
+	})
+}
+
+// UpdateSheetExport handles PATCH /groups/:id/sheet-exports/:export_id
+func (h *SheetExportHandler) UpdateSheetExport(c *gin.Context) {
+	groupID := c.Param("id")
+
+	var req db.UpdateGroupSheetExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !requireGroupAdmin(c, h.InvitationService, groupID, sheetExportsAdminOnly) {
+		return
+	}
+
+	export, err := h.SheetExportService.UpdateExport(groupID, c.Param("export_id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSheetExportNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sheet export not found"})
+		case errors.Is(err, services.ErrInvalidSheetExport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sheet export: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sheet_export": export,
+		"message":      "Sheet export updated successfully",
+	})
+}
+
+// DeleteSheetExport handles DELETE /groups/:id/sheet-exports/:export_id
+func (h *SheetExportHandler) DeleteSheetExport(c *gin.Context) {
+	groupID := c.Param("id")
+	if !requireGroupAdmin(c, h.InvitationService, groupID, sheetExportsAdminOnly) {
+		return
+	}
+
+	if err := h.SheetExportService.DeleteExport(groupID, c.Param("export_id")); err != nil {
+		if errors.Is(err, services.ErrSheetExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sheet export not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sheet export: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sheet export deleted successfully"})
+}
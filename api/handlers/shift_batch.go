@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// BatchEditShifts creates, updates and deletes many shifts of a group in one
+// transaction, for the drag-and-drop schedule calendar. Updates and deletes
+// carry the shift's updated_at; if any shift changed since, or a shift would
+// overlap another of the same user, nothing is applied and every failing
+// operation is listed under "errors".
+// POST /groups/{id}/shifts/batch
+// Body: {"create": [...], "update": [{"id", "updated_at", ...}], "delete": [{"id", "updated_at"}]}
+func (h *SchedulerHandler) BatchEditShifts(c *gin.Context) {
+	groupID := c.Param("id")
+	if groupID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group ID is required"})
+		return
+	}
+
+	var req db.ShiftBatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.SchedulerService.BatchEditShifts(groupID, req, c.GetString("user_id"))
+	if err != nil {
+		var batchErr *services.ShiftBatchError
+		if errors.As(err, &batchErr) {
+			status := http.StatusUnprocessableEntity
+			if batchErr.HasCode(db.ShiftBatchErrorConflict) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": "Shift batch rejected; no changes were applied", "errors": batchErr.Errors})
+			return
+		}
+		if errors.Is(err, services.ErrSchedulerForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("BatchEditShifts error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply shift batch"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/services"
+)
+
+// writeSwapRequestError maps swap request errors to a response
+func writeSwapRequestError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, services.ErrSwapRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Swap request not found"})
+	case errors.Is(err, services.ErrSchedulerForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSwapRequestClosed), errors.Is(err, services.ErrSwapRequestStale):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update swap request"})
+	}
+}
+
+// ListSwapRequests returns the group's shift swap requests
+// GET /groups/{id}/swap-requests?status=pending
+func (h *OnCallHandler) ListSwapRequests(c *gin.Context) {
+	requests, err := h.OnCallService.ListSwapRequests(c.Param("id"), c.Query("status"))
+	if err != nil {
+		log.Printf("ListSwapRequests error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve swap requests"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"swap_requests": requests, "total": len(requests)})
+}
+
+// ApproveSwapRequest swaps the shifts of a pending request; the approver must
+// manage both shifts
+// POST /groups/{id}/swap-requests/{request_id}/approve
+func (h *OnCallHandler) ApproveSwapRequest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	response, err := h.OnCallService.ApproveSwapRequest(c.Param("id"), c.Param("request_id"), userID)
+	if err != nil {
+		writeSwapRequestError(c, "ApproveSwapRequest", err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RejectSwapRequest turns down a pending request; the rejecter must manage
+// both shifts
+// POST /groups/{id}/swap-requests/{request_id}/reject
+func (h *OnCallHandler) RejectSwapRequest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	swap, err := h.OnCallService.RejectSwapRequest(c.Param("id"), c.Param("request_id"), userID)
+	if err != nil {
+		writeSwapRequestError(c, "RejectSwapRequest", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"swap_request": swap, "message": "Swap request rejected"})
+}
+
+// CancelSwapRequest withdraws the user's own pending request
+// DELETE /groups/{id}/swap-requests/{request_id}
+func (h *OnCallHandler) CancelSwapRequest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.OnCallService.CancelSwapRequest(c.Param("id"), c.Param("request_id"), userID); err != nil {
+		writeSwapRequestError(c, "CancelSwapRequest", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Swap request cancelled"})
+}
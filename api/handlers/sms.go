@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/services"
+)
+
+// SMSHandler handles linking phone numbers for SMS commands and the Twilio
+// inbound message webhook
+type SMSHandler struct {
+	SMSService *services.SMSService
+}
+
+// NewSMSHandler creates a new SMSHandler
+func NewSMSHandler(smsService *services.SMSService) *SMSHandler {
+	return &SMSHandler{SMSService: smsService}
+}
+
+// GetSMSPhone handles GET /users/me/sms
+func (h *SMSHandler) GetSMSPhone(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	phone, err := h.SMSService.GetPhone(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get SMS phone: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available": h.SMSService.IsConfigured(),
+		"send_to":   config.Current().Twilio.PhoneNumber,
+		"phone":     phone,
+	})
+}
+
+// SetSMSPhone handles POST /users/me/sms: links a number and returns the code
+// to text from it
+func (h *SMSHandler) SetSMSPhone(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.SetSMSPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !h.SMSService.IsConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SMS commands are not configured"})
+		return
+	}
+
+	verification, err := h.SMSService.StartVerification(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWhatsAppNumber) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link SMS phone: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"verification": verification,
+		"message":      "Text " + verification.Message + " to " + verification.SendTo + " from this phone to finish linking it",
+	})
+}
+
+// DeleteSMSPhone handles DELETE /users/me/sms
+func (h *SMSHandler) DeleteSMSPhone(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.SMSService.RemovePhone(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove SMS phone: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SMS phone removed"})
+}
+
+// ReceiveTwilioWebhook handles POST /sms/twilio/webhook (public, signed with
+// the Twilio auth token) and replies to the sender with TwiML
+func (h *SMSHandler) ReceiveTwilioWebhook(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 64<<10)
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	params := c.Request.PostForm
+	if err := h.SMSService.VerifyTwilioSignature(h.SMSService.WebhookURL(), params, c.GetHeader("X-Twilio-Signature")); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if sid := config.Current().Twilio.AccountSID; sid != "" && params.Get("AccountSid") != sid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unknown Twilio account"})
+		return
+	}
+
+	reply, err := h.SMSService.HandleInboundMessage(params.Get("From"), params.Get("Body"))
+	if err != nil {
+		log.Printf("Twilio webhook error: %v", err)
+		reply = "Sorry, SLAR couldn't process that command. Try again or use the app."
+	}
+	twimlReply(c, reply)
+}
+
+// twimlReply answers a Twilio webhook with a single reply message
+func twimlReply(c *gin.Context, message string) {
+	var body strings.Builder
+	body.WriteString(xml.Header + "<Response><Message>")
+	xml.EscapeText(&body, []byte(message))
+	body.WriteString("</Message></Response>")
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", []byte(body.String()))
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// selectFields applies the ?fields= sparse fieldset to the items of a list
+// response: each item is cut down to the requested top-level JSON fields, and
+// "id" is always kept so clients can still address what they got back.
+// Without ?fields= the items are returned unchanged. Unknown field names are
+// rejected with 400 rather than silently returning empty objects; ok is false
+// once that response has been written.
+func selectFields[T any](c *gin.Context, items []T) (interface{}, bool) {
+	fields := parseFields(c.Query("fields"))
+	if len(fields) == 0 {
+		return items, true
+	}
+
+	known := jsonFieldNames(reflect.TypeOf((*T)(nil)).Elem())
+	var unknown []string
+	for field := range fields {
+		if !known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown fields: " + strings.Join(unknown, ", "),
+		})
+		return nil, false
+	}
+
+	selected := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		raw, err := json.Marshal(item)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+			return nil, false
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &all); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+			return nil, false
+		}
+		kept := make(map[string]json.RawMessage, len(fields))
+		for field := range fields {
+			if v, ok := all[field]; ok {
+				kept[field] = v
+			}
+		}
+		selected = append(selected, kept)
+	}
+	return selected, true
+}
+
+// parseFields splits a comma-separated fields parameter; "id" is added to any
+// non-empty selection
+func parseFields(param string) map[string]bool {
+	fields := map[string]bool{}
+	for _, field := range strings.Split(param, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields[field] = true
+		}
+	}
+	if len(fields) > 0 {
+		fields["id"] = true
+	}
+	return fields
+}
+
+// jsonFieldNames returns the top-level JSON keys a struct type encodes to,
+// including those promoted from embedded structs
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := map[string]bool{}
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			for embedded := range jsonFieldNames(f.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestSelectFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	incidents := []db.IncidentResponse{{
+		Incident:       db.Incident{ID: "inc-1", Title: "DB down", Status: "triggered", Description: "long"},
+		AssignedToName: "Dana",
+	}}
+
+	serve := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/incidents"+query, nil)
+		if items, ok := selectFields(c, incidents); ok {
+			c.JSON(http.StatusOK, gin.H{"incidents": items})
+		}
+		return w
+	}
+
+	w := serve("?fields=title,%20status,assigned_to_name")
+	var got struct {
+		Incidents []map[string]interface{} `json:"incidents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"id": "inc-1", "title": "DB down", "status": "triggered", "assigned_to_name": "Dana"}
+	if len(got.Incidents) != 1 || len(got.Incidents[0]) != len(want) {
+		t.Fatalf("incidents = %v", got.Incidents)
+	}
+	for k, v := range want {
+		if got.Incidents[0][k] != v {
+			t.Errorf("%s = %v, want %v", k, got.Incidents[0][k], v)
+		}
+	}
+
+	if w := serve(""); !strings.Contains(w.Body.String(), `"description":"long"`) {
+		t.Errorf("no fields param trimmed the response: %s", w.Body.String())
+	}
+	if w := serve("?fields=title,nope"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "nope") {
+		t.Errorf("unknown field = %d %s", w.Code, w.Body.String())
+	}
+}
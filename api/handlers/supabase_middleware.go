@@ -71,9 +71,13 @@ func (m *SupabaseAuthMiddleware) SupabaseAuthMiddleware() gin.HandlerFunc {
 				if apiKey.OrganizationID != "" {
 					c.Set("org_id", apiKey.OrganizationID)
 				}
+				if err := m.APIKeyService.CheckRateLimit(apiKey.ID, apiKey); err != nil {
+					abortRateLimited(c, err)
+					return
+				}
 				log.Printf("AUTH SUCCESS - API Key: %s (user: %s)", apiKey.Name, apiKey.UserID)
-				// Update last used timestamp (async, don't block request)
-				go m.APIKeyService.UpdateLastUsed(apiKey.ID)
+				// Count the call against the key's quotas (async, don't block request)
+				go m.APIKeyService.RecordUsage(apiKey.ID)
 				c.Next()
 				return
 			}
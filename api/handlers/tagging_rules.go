@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListTaggingRules handles GET /orgs/:id/tagging-rules
+func (h *IncidentHandler) ListTaggingRules(c *gin.Context) {
+	rules, err := h.incidentService.ListTaggingRules(c.Param("id"))
+	if err != nil {
+		log.Printf("ListTaggingRules error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tagging rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "total": len(rules)})
+}
+
+// SetTaggingRules handles PUT /orgs/:id/tagging-rules
+// Replaces the organization's rules; their order is the evaluation order
+func (h *IncidentHandler) SetTaggingRules(c *gin.Context) {
+	var req db.SetTaggingRulesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	rules, err := h.incidentService.SetTaggingRules(c.Param("id"), req)
+	if err != nil {
+		h.handleTaggingRuleError(c, err, "Failed to save tagging rules")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "total": len(rules)})
+}
+
+// TestTaggingRules handles POST /orgs/:id/tagging-rules/test
+// Shows what the saved rules, or the proposed ones in the body, would do to a
+// sample incident
+func (h *IncidentHandler) TestTaggingRules(c *gin.Context) {
+	var req db.TestTaggingRulesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.incidentService.TestTaggingRules(c.Param("id"), req)
+	if err != nil {
+		h.handleTaggingRuleError(c, err, "Failed to test tagging rules")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *IncidentHandler) handleTaggingRuleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTaggingRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
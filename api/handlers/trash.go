@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/services"
+)
+
+// TrashHandler lists and restores deleted services, schedulers, escalation
+// policies and integrations
+type TrashHandler struct {
+	TrashService *services.TrashService
+	authorizer   authz.Authorizer
+}
+
+func NewTrashHandler(trashService *services.TrashService, authorizer authz.Authorizer) *TrashHandler {
+	return &TrashHandler{TrashService: trashService, authorizer: authorizer}
+}
+
+// trashOrg returns the organization a trash request acts on, or writes the
+// error response when the caller lacks the action on it
+func (h *TrashHandler) trashOrg(c *gin.Context, action authz.Action) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return "", false
+	}
+	if !h.authorizer.Check(c.Request.Context(), userID, action, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this organization's trash"})
+		return "", false
+	}
+	return orgID, true
+}
+
+// ListTrash returns the organization's deleted resources, newest first
+// GET /trash?type=service|scheduler|escalation_policy|integration
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	orgID, ok := h.trashOrg(c, authz.ActionView)
+	if !ok {
+		return
+	}
+
+	items, err := h.TrashService.ListTrash(orgID, c.Query("type"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTrashResource) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be service, scheduler, escalation_policy or integration"})
+			return
+		}
+		log.Printf("ListTrash error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list trash"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+// RestoreTrashItem brings a deleted resource back. Only org admins may restore.
+// POST /trash/:type/:id/restore
+func (h *TrashHandler) RestoreTrashItem(c *gin.Context) {
+	orgID, ok := h.trashOrg(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	item, err := h.TrashService.RestoreTrashItem(orgID, c.Param("type"), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTrashResource):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be service, scheduler, escalation_policy or integration"})
+		case errors.Is(err, services.ErrTrashItemNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+		case errors.Is(err, services.ErrTrashRestoreConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "A resource with this ID already exists"})
+		default:
+			log.Printf("RestoreTrashItem error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"item": item, "message": "Restored successfully"})
+}
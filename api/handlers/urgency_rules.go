@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// ListUrgencyRules returns a service's urgency rules in evaluation order
+// GET /services/{id}/urgency-rules
+func (h *ServiceHandler) ListUrgencyRules(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionView); !ok {
+		return
+	}
+	rules, err := h.ServiceService.ListUrgencyRules(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list urgency rules: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"urgency_rules": rules,
+		"count":         len(rules),
+	})
+}
+
+// SetUrgencyRules replaces a service's urgency rules
+// PUT /services/{id}/urgency-rules
+func (h *ServiceHandler) SetUrgencyRules(c *gin.Context) {
+	if _, ok := h.authorizeService(c, authz.ActionManage); !ok {
+		return
+	}
+	var req db.SetUrgencyRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	rules, err := h.ServiceService.SetUrgencyRules(c.Param("id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUrgencyRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save urgency rules: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"urgency_rules": rules,
+		"message":       "Urgency rules saved successfully",
+	})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// UsageHandler reports metered organization usage to org admins
+type UsageHandler struct {
+	UsageService *services.UsageService
+}
+
+func NewUsageHandler(usageService *services.UsageService) *UsageHandler {
+	return &UsageHandler{UsageService: usageService}
+}
+
+// GetOrgUsage returns the organization's monthly usage rollups, newest first:
+// peak members and services, incidents opened and notifications by channel
+// GET /orgs/:id/usage?months=12
+func (h *UsageHandler) GetOrgUsage(c *gin.Context) {
+	months := db.OrgUsageDefaultMonths
+	if raw := c.Query("months"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > db.OrgUsageMaxMonths {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be between 1 and " + strconv.Itoa(db.OrgUsageMaxMonths)})
+			return
+		}
+		months = n
+	}
+
+	usage, err := h.UsageService.GetOrgUsage(c.Param("id"), months)
+	if err != nil {
+		log.Printf("GetOrgUsage error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization usage"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/services"
 )
 
@@ -166,19 +168,15 @@ func (h *UserHandler) UpdateFCMToken(c *gin.Context) {
 		return
 	}
 
-	var request struct {
-		FCMToken   string `json:"fcm_token" binding:"required"`
-		Platform   string `json:"platform"`
-		AppVersion string `json:"app_version"`
-	}
-
+	var request db.RegisterDeviceRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
 
-	// Update FCM token in local database
-	if err := h.Service.UpdateFCMToken(userID.(string), request.FCMToken); err != nil {
+	// Register the device in local database
+	device, err := h.Service.RegisterDevice(userID.(string), request)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update FCM token"})
 		return
 	}
@@ -232,9 +230,46 @@ func (h *UserHandler) UpdateFCMToken(c *gin.Context) {
 		"status":         "success",
 		"gateway_status": gatewayStatus,
 		"device_id":      deviceID,
+		"device":         device,
 	})
 }
 
+// ListDevices handles GET /users/me/devices: the user's app installations
+// registered for push notifications
+func (h *UserHandler) ListDevices(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	devices, err := h.Service.ListDevices(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get devices: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+// DeleteDevice handles DELETE /users/me/devices/:id
+func (h *UserHandler) DeleteDevice(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.Service.DeleteDevice(userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrUserDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove device: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Device removed"})
+}
+
 // GetFCMToken returns current user's FCM token (for debugging)
 func (h *UserHandler) GetFCMToken(c *gin.Context) {
 	// Get user ID from context (set by Supabase auth middleware)
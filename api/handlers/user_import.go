@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+type UserImportHandler struct {
+	Service    *services.UserImportService
+	authorizer authz.Authorizer
+}
+
+func NewUserImportHandler(service *services.UserImportService, authorizer authz.Authorizer) *UserImportHandler {
+	return &UserImportHandler{Service: service, authorizer: authorizer}
+}
+
+// ImportUsers creates or updates users from a CSV (name, email, team, role, phone)
+// sent as a multipart "file" field or as a text/csv body
+// POST /users/import?dry_run=true
+func (h *UserImportHandler) ImportUsers(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	// Imported users join the current organization, which requires managing it
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+	if !h.authorizer.Check(c.Request.Context(), userID, authz.ActionManage, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to add users to this organization"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, db.UserImportMaxBytes)
+	var csvData io.Reader = body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.Request.Body = body
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required in the 'file' field: " + err.Error()})
+			return
+		}
+		defer file.Close()
+		csvData = file
+	}
+
+	rows, err := services.ParseUserImportCSV(csvData)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file is too large"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	result, err := h.Service.ImportUsers(rows, orgID, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import users: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"import": result})
+}
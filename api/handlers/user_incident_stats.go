@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// UserIncidentStatsHandler serves per-user incident participation metrics for
+// personal dashboards and manager reports
+type UserIncidentStatsHandler struct {
+	Service    *services.UserService
+	authorizer authz.Authorizer
+}
+
+func NewUserIncidentStatsHandler(service *services.UserService, authorizer authz.Authorizer) *UserIncidentStatsHandler {
+	return &UserIncidentStatsHandler{Service: service, authorizer: authorizer}
+}
+
+// parseStatsRange reads the RFC3339 from/to query params, defaulting to the
+// last 30 days
+func parseStatsRange(c *gin.Context) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -db.UserIncidentStatsDefaultDays)
+
+	var err error
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("to must be an RFC3339 timestamp")
+		}
+		from = to.AddDate(0, 0, -db.UserIncidentStatsDefaultDays)
+	}
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("from must be an RFC3339 timestamp")
+		}
+	}
+	if !to.After(from) {
+		return from, to, errors.New("to must be after from")
+	}
+	if to.Sub(from) > db.UserIncidentStatsMaxDays*24*time.Hour {
+		return from, to, fmt.Errorf("range must not exceed %d days", db.UserIncidentStatsMaxDays)
+	}
+	return from, to, nil
+}
+
+// GetIncidentStats returns acknowledged/resolved counts, average response
+// time, after-hours pages and escalations received for a user
+// GET /users/:id/incident-stats?org_id=&from=&to=
+// Users can read their own stats; anyone else needs to manage the org.
+func (h *UserIncidentStatsHandler) GetIncidentStats(c *gin.Context) {
+	currentUserID := c.GetString("user_id")
+	if currentUserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orgID, _ := authz.GetReBACFilters(c)["current_org_id"].(string)
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "organization_id is required",
+			"message": "Please provide org_id query param or X-Org-ID header for tenant isolation",
+		})
+		return
+	}
+
+	userID := c.Param("id")
+	if userID == "me" {
+		userID = currentUserID
+	}
+	if userID != currentUserID && !h.authorizer.Check(c.Request.Context(), currentUserID, authz.ActionManage, authz.ResourceOrg, orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this user's incident stats"})
+		return
+	}
+
+	from, to, err := parseStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.Service.GetIncidentStats(userID, orgID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get incident stats: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// voiceErrorTwiML is said when a call callback fails
+const voiceErrorTwiML = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+	`<Response><Say>Sorry, SLAR could not handle this call. Please check the incident in the app.</Say><Hangup/></Response>`
+
+// VoiceHandler handles the phone-call page setting and Twilio's call callbacks
+type VoiceHandler struct {
+	VoiceService *services.VoiceService
+}
+
+// NewVoiceHandler creates a new VoiceHandler
+func NewVoiceHandler(voiceService *services.VoiceService) *VoiceHandler {
+	return &VoiceHandler{VoiceService: voiceService}
+}
+
+// SetVoiceCalls handles PUT /users/me/voice: turns phone-call pages to the
+// user's verified SMS number on or off
+func (h *VoiceHandler) SetVoiceCalls(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.SetVoiceCallsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if req.Enabled && !h.VoiceService.IsConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Phone calls are not configured"})
+		return
+	}
+
+	if err := h.VoiceService.SetVoiceCalls(userID, req.Enabled); err != nil {
+		if errors.Is(err, services.ErrVoicePhoneNotVerified) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update phone calls: " + err.Error()})
+		return
+	}
+
+	message := "Phone calls disabled"
+	if req.Enabled {
+		message = "Phone calls enabled"
+	}
+	c.JSON(http.StatusOK, gin.H{"voice_enabled": req.Enabled, "message": message})
+}
+
+// verifyCallback checks a Twilio call callback was signed for this URL
+func (h *VoiceHandler) verifyCallback(c *gin.Context, action string) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 64<<10)
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return false
+	}
+	callbackURL := h.VoiceService.CallbackURL(c.Param("id"), action)
+	if err := h.VoiceService.VerifyTwilioSignature(callbackURL, c.Request.PostForm, c.GetHeader("X-Twilio-Signature")); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func twimlResponse(c *gin.Context, twiml string, err error) {
+	if err != nil {
+		log.Printf("Voice call %s error: %v", c.Param("id"), err)
+		twiml = voiceErrorTwiML
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", []byte(twiml))
+}
+
+// CallTwiML handles POST /voice/twilio/calls/:id (public, signed): what the
+// call says when answered
+func (h *VoiceHandler) CallTwiML(c *gin.Context) {
+	if !h.verifyCallback(c, "") {
+		return
+	}
+	twiml, err := h.VoiceService.CallTwiML(c.Param("id"))
+	twimlResponse(c, twiml, err)
+}
+
+// GatherCall handles POST /voice/twilio/calls/:id/gather (public, signed):
+// the key pressed in the menu
+func (h *VoiceHandler) GatherCall(c *gin.Context) {
+	if !h.verifyCallback(c, "gather") {
+		return
+	}
+	twiml, err := h.VoiceService.HandleGather(c.Param("id"), c.Request.PostForm.Get("Digits"))
+	twimlResponse(c, twiml, err)
+}
+
+// CallStatus handles POST /voice/twilio/calls/:id/status (public, signed)
+func (h *VoiceHandler) CallStatus(c *gin.Context) {
+	if !h.verifyCallback(c, "status") {
+		return
+	}
+	duration, _ := strconv.Atoi(c.Request.PostForm.Get("CallDuration"))
+	if err := h.VoiceService.HandleStatus(c.Param("id"), c.Request.PostForm.Get("CallStatus"), duration); err != nil {
+		if errors.Is(err, services.ErrVoiceCallNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Voice call %s status error: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record call status"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
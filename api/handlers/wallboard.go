@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// WallboardHandler serves the read-only wallboard/NOC display API (/wallboard)
+// and wallboard token management
+type WallboardHandler struct {
+	WallboardService *services.WallboardService
+}
+
+// NewWallboardHandler creates a new WallboardHandler
+func NewWallboardHandler(wallboardService *services.WallboardService) *WallboardHandler {
+	return &WallboardHandler{WallboardService: wallboardService}
+}
+
+// WallboardAuthMiddleware authenticates displays with a wallboard token and sets
+// wallboard_org_id / wallboard_project_id in the context. The token may be sent as
+// a Bearer header or as ?token= for browsers in kiosk mode that can't set headers.
+func (h *WallboardHandler) WallboardAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("token")
+		if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			raw = strings.TrimPrefix(authHeader, "Bearer ")
+		}
+		if raw == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Wallboard token required"})
+			c.Abort()
+			return
+		}
+
+		token, err := h.WallboardService.AuthenticateToken(raw)
+		if err != nil {
+			if !errors.Is(err, services.ErrWallboardUnauthorized) {
+				log.Printf("Wallboard auth error: %v", err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid wallboard token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("wallboard_org_id", token.OrganizationID)
+		c.Set("wallboard_project_id", token.ProjectID)
+		c.Next()
+	}
+}
+
+// WALLBOARD TOKEN MANAGEMENT (OIDC-authenticated, org admins)
+
+// CreateToken handles POST /orgs/:id/wallboard-tokens
+func (h *WallboardHandler) CreateToken(c *gin.Context) {
+	var req db.CreateWallboardTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	token, err := h.WallboardService.CreateToken(c.Param("id"), req.ProjectID, req.Name, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("CreateWallboardToken error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wallboard token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"wallboard_token": token,
+		"url":             "/wallboard?token=" + token.Token,
+		"message":         "Store this token securely - it will not be shown again",
+	})
+}
+
+// ListTokens handles GET /orgs/:id/wallboard-tokens
+func (h *WallboardHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.WallboardService.ListTokens(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wallboard tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"wallboard_tokens": tokens, "total": len(tokens)})
+}
+
+// RevokeToken handles DELETE /orgs/:id/wallboard-tokens/:token_id
+func (h *WallboardHandler) RevokeToken(c *gin.Context) {
+	if err := h.WallboardService.RevokeToken(c.Param("id"), c.Param("token_id")); err != nil {
+		if errors.Is(err, services.ErrWallboardTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Wallboard token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke wallboard token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wallboard token revoked"})
+}
+
+// DISPLAY ENDPOINTS (wallboard token)
+
+// GetWallboard handles GET /wallboard
+// Returns open incidents, on-call roster and monitor status in one response
+func (h *WallboardHandler) GetWallboard(c *gin.Context) {
+	wallboard, err := h.WallboardService.GetWallboard(c.GetString("wallboard_org_id"), c.GetString("wallboard_project_id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	wallboardJSON(c, wallboard)
+}
+
+// GetIncidents handles GET /wallboard/incidents
+func (h *WallboardHandler) GetIncidents(c *gin.Context) {
+	incidents, err := h.WallboardService.GetOpenIncidents(c.GetString("wallboard_org_id"), c.GetString("wallboard_project_id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	wallboardJSON(c, incidents)
+}
+
+// GetOnCall handles GET /wallboard/oncall
+func (h *WallboardHandler) GetOnCall(c *gin.Context) {
+	roster, err := h.WallboardService.GetOnCallRoster(c.GetString("wallboard_org_id"), c.GetString("wallboard_project_id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	wallboardJSON(c, gin.H{"oncall": roster, "total": len(roster)})
+}
+
+// GetMonitors handles GET /wallboard/monitors
+func (h *WallboardHandler) GetMonitors(c *gin.Context) {
+	monitors, err := h.WallboardService.GetMonitorStatus(c.GetString("wallboard_org_id"), c.GetString("wallboard_project_id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	wallboardJSON(c, monitors)
+}
+
+func (h *WallboardHandler) handleError(c *gin.Context, err error) {
+	log.Printf("Wallboard error: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load wallboard"})
+}
+
+// wallboardJSON writes a display response with a Refresh hint so plain browsers
+// in kiosk mode re-poll without any client-side code
+func wallboardJSON(c *gin.Context, body interface{}) {
+	seconds := int(services.WallboardRefreshInterval.Seconds())
+	c.Header("Cache-Control", "no-store")
+	c.Header("Refresh", strconv.Itoa(seconds))
+	c.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// WebPushHandler handles browser push subscriptions
+type WebPushHandler struct {
+	WebPushService *services.WebPushService
+}
+
+// NewWebPushHandler creates a new WebPushHandler
+func NewWebPushHandler(webPushService *services.WebPushService) *WebPushHandler {
+	return &WebPushHandler{WebPushService: webPushService}
+}
+
+// GetWebPush handles GET /users/me/web-push: the VAPID public key browsers
+// subscribe with and the user's subscribed browsers
+func (h *WebPushHandler) GetWebPush(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	subs, err := h.WebPushService.ListSubscriptions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get push subscriptions: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available":     h.WebPushService.IsConfigured(),
+		"public_key":    h.WebPushService.PublicKey(),
+		"subscriptions": subs,
+	})
+}
+
+// Subscribe handles POST /users/me/web-push/subscriptions
+func (h *WebPushHandler) Subscribe(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.WebPushSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.UserAgent == "" {
+		req.UserAgent = c.Request.UserAgent()
+	}
+
+	if !h.WebPushService.IsConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Web push notifications are not configured"})
+		return
+	}
+
+	sub, err := h.WebPushService.Subscribe(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebPushSubscription) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrWebPushSubscriptionTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save push subscription: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription": sub,
+		"message":      "Browser notifications enabled",
+	})
+}
+
+// Unsubscribe handles POST /users/me/web-push/unsubscribe, called by a browser
+// with its own endpoint
+func (h *WebPushHandler) Unsubscribe(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.WebPushUnsubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if err := h.WebPushService.Unsubscribe(userID, req.Endpoint); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove push subscription: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Browser notifications disabled"})
+}
+
+// DeleteSubscription handles DELETE /users/me/web-push/subscriptions/:id
+func (h *WebPushHandler) DeleteSubscription(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.WebPushService.DeleteSubscription(userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrWebPushSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove push subscription: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Browser removed"})
+}
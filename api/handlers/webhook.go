@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/authz"
 	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/services"
 )
@@ -19,14 +20,20 @@ type WebhookHandler struct {
 	alertService       *services.AlertService
 	incidentService    *services.IncidentService
 	serviceService     *services.ServiceService
+	dedupReviews       *services.AlertDedupReviewService
+	backpressure       *services.IngestBackpressureService
+	authorizer         authz.Authorizer
 }
 
-func NewWebhookHandler(integrationService *services.IntegrationService, alertService *services.AlertService, incidentService *services.IncidentService, serviceService *services.ServiceService) *WebhookHandler {
+func NewWebhookHandler(integrationService *services.IntegrationService, alertService *services.AlertService, incidentService *services.IncidentService, serviceService *services.ServiceService, dedupReviews *services.AlertDedupReviewService, backpressure *services.IngestBackpressureService, authorizer authz.Authorizer) *WebhookHandler {
 	return &WebhookHandler{
 		integrationService: integrationService,
 		alertService:       alertService,
 		incidentService:    incidentService,
 		serviceService:     serviceService,
+		dedupReviews:       dedupReviews,
+		backpressure:       backpressure,
+		authorizer:         authorizer,
 	}
 }
 
@@ -74,32 +81,27 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 
 	log.Printf("Received webhook: type=%s, integration_id=%s", integrationType, integrationID)
 
-	// Verify integration exists and is active
-	integration, err := h.integrationService.GetIntegration(integrationID)
-	if err != nil {
-		log.Printf("Integration not found: %s, error: %v", integrationID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+	integration, ok := h.loadWebhookIntegration(c, integrationType, integrationID)
+	if !ok {
 		return
 	}
 
-	if !integration.IsActive {
-		log.Printf("Integration is inactive: %s", integrationID)
-		c.JSON(http.StatusForbidden, gin.H{"error": "Integration is inactive"})
+	// Get raw body (size-limited)
+	rawPayload, ok := h.bindWebhookPayload(c, integrationID, webhookMaxBodyBytes())
+	if !ok {
 		return
 	}
 
-	// Verify integration type matches
-	if integration.Type != integrationType {
-		log.Printf("Integration type mismatch: expected %s, got %s", integration.Type, integrationType)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Integration type mismatch"})
+	// Redact what the integration's scrub policy covers before anything is stored
+	scrubber, ok := h.loadPayloadScrubber(c, integrationID)
+	if !ok {
 		return
 	}
+	h.scrubPayload(integrationID, scrubber, rawPayload)
 
-	// Get raw body
-	var rawPayload map[string]interface{}
-	if err := c.ShouldBindJSON(&rawPayload); err != nil {
-		log.Printf("Invalid JSON payload: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+	// Reject payloads missing the fields the provider's processor needs
+	if fieldErrors := validateWebhookPayload(integrationType, rawPayload); len(fieldErrors) > 0 {
+		h.rejectWebhookPayload(c, integrationID, http.StatusUnprocessableEntity, "Payload validation failed", fieldErrors)
 		return
 	}
 
@@ -109,23 +111,27 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 		// Don't fail the webhook for this
 	}
 
-	// Process webhook based on type
-	var processedAlerts []ProcessedAlert
-	switch integrationType {
-	case "prometheus":
-		processedAlerts = h.processPrometheusWebhook(rawPayload)
-	case "datadog":
-		processedAlerts = h.processDatadogWebhook(rawPayload)
-	case "grafana":
-		processedAlerts = h.processGrafanaWebhook(rawPayload)
-	case "webhook":
-		processedAlerts = h.processGenericWebhook(rawPayload)
-	case "aws":
-		processedAlerts = h.processAWSWebhook(rawPayload)
-	default:
-		processedAlerts = h.processGenericWebhook(rawPayload)
+	// Federation integrations receive status changes of forwarded incidents
+	if integrationType == db.FederationIntegrationType {
+		h.receiveFederationWebhook(c, integration, rawPayload)
+		return
+	}
+
+	// SIEM integrations only send events out
+	if integrationType == db.SIEMIntegrationType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SIEM integrations do not accept webhooks"})
+		return
 	}
 
+	// Deployment integrations feed deploy rules instead of creating alerts
+	if isDeployIntegrationType(integrationType) {
+		h.receiveDeployWebhook(c, integration, rawPayload)
+		return
+	}
+
+	// Process webhook based on type
+	processedAlerts := h.processWebhookPayload(integrationType, rawPayload)
+
 	// Log webhook payload for debugging/audit
 	webhookPayload := WebhookPayload{
 		IntegrationType: integrationType,
@@ -158,6 +164,50 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 	})
 }
 
+// loadWebhookIntegration verifies the integration exists, is active and matches
+// the webhook type, writing the error response when it doesn't
+func (h *WebhookHandler) loadWebhookIntegration(c *gin.Context, integrationType, integrationID string) (db.Integration, bool) {
+	integration, err := h.integrationService.GetIntegration(integrationID)
+	if err != nil {
+		log.Printf("Integration not found: %s, error: %v", integrationID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+		return integration, false
+	}
+
+	if !integration.IsActive {
+		log.Printf("Integration is inactive: %s", integrationID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Integration is inactive"})
+		return integration, false
+	}
+
+	// Verify integration type matches
+	if integration.Type != integrationType {
+		log.Printf("Integration type mismatch: expected %s, got %s", integration.Type, integrationType)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Integration type mismatch"})
+		return integration, false
+	}
+
+	return integration, true
+}
+
+// processWebhookPayload converts a provider payload into alerts
+func (h *WebhookHandler) processWebhookPayload(integrationType string, payload map[string]interface{}) []ProcessedAlert {
+	switch integrationType {
+	case "prometheus":
+		return h.processPrometheusWebhook(payload)
+	case "datadog":
+		return h.processDatadogWebhook(payload)
+	case "grafana":
+		return h.processGrafanaWebhook(payload)
+	case "webhook":
+		return h.processGenericWebhook(payload)
+	case "aws":
+		return h.processAWSWebhook(payload)
+	default:
+		return h.processGenericWebhook(payload)
+	}
+}
+
 // Process Prometheus AlertManager webhook
 func (h *WebhookHandler) processPrometheusWebhook(payload map[string]interface{}) []ProcessedAlert {
 	var alerts []ProcessedAlert
@@ -488,6 +538,13 @@ func (h *WebhookHandler) processGenericWebhookLegacy(payload map[string]interfac
 func (h *WebhookHandler) routeAlert(integration db.Integration, alert ProcessedAlert) error {
 	log.Printf("DEBUG: Routing alert %s with status %s", alert.AlertName, alert.Status)
 
+	// While ingestion is backed up, low-severity alerts are dropped so the
+	// rest still page; resolves always go through
+	if alert.Status != "resolved" && h.backpressure != nil && h.backpressure.ShouldShed(alert.Severity) {
+		log.Printf("SHED: Alert %s (severity %s) dropped, ingestion is backed up", alert.AlertName, alert.Severity)
+		return nil
+	}
+
 	switch alert.Status {
 	case "firing":
 		return h.routeAlertToCreateIncident(integration, alert)
@@ -503,6 +560,28 @@ func (h *WebhookHandler) routeAlert(integration db.Integration, alert ProcessedA
 func (h *WebhookHandler) routeAlertToCreateIncident(integration db.Integration, alert ProcessedAlert) error {
 	log.Printf("DEBUG: Starting atomic incident creation for integration %s", integration.ID)
 
+	// Alerts with the fingerprint of an open incident join it instead of opening a new one
+	if alert.Fingerprint != "" {
+		existing, err := h.findIncidentByFingerprint(integration, alert.Fingerprint)
+		if err == nil && existing != nil {
+			change, err := h.incidentService.RecordIncidentAlert(existing.ID, alert.Severity)
+			if err != nil {
+				return fmt.Errorf("failed to add alert to incident: %w", err)
+			}
+			if change != nil {
+				log.Printf("SUCCESS: Alert %s joined incident %s (%d alerts, urgency %s)",
+					alert.AlertName, existing.ID, change.AlertCount, change.ToUrgency)
+				h.recordDedupDecision(integration, db.DedupModeAuto, db.DedupOutcomeAttached)
+				h.attachAlertSnapshot(integration, existing.ID, alert)
+				return nil
+			}
+		}
+	} else if candidateID := h.findIncidentByTitle(integration, incidentTitle(alert)); candidateID != "" {
+		// Without a fingerprint a title match can't tell a repeat of the same
+		// alert from the same check failing somewhere else: ask a person
+		return h.parkForDedupReview(integration, alert, candidateID)
+	}
+
 	// Step 1: Resolve service and assignment BEFORE creating incident
 	serviceInfo, assigneeInfo, err := h.resolveServiceAndAssignee(integration, alert)
 	if err != nil {
@@ -510,6 +589,31 @@ func (h *WebhookHandler) routeAlertToCreateIncident(integration db.Integration,
 		// Continue with incident creation even if service resolution fails
 	}
 
+	// Alerts a mute on the service silences are dropped; the rest still page
+	if serviceInfo.Found && serviceInfo.Service != nil {
+		mute, err := h.serviceService.MatchAlertMute(serviceInfo.Service.ID, alert.AlertName, alertLabelValues(alert))
+		if err != nil {
+			log.Printf("WARNING: Failed to check alert mutes on service %s: %v", serviceInfo.Service.ID, err)
+		} else if mute != nil {
+			log.Printf("MUTED: Alert %s on service %s silenced by mute %s until %s",
+				alert.AlertName, serviceInfo.Service.ID, mute.ID, mute.ExpiresAt.Format(time.RFC3339))
+			return nil
+		}
+	}
+
+	// During an alert storm on the service, alerts join the storm incident
+	// instead of each paging for their own
+	if serviceInfo.Found && serviceInfo.Service != nil && !integration.TestMode {
+		stormID, err := h.incidentService.GroupAlertIntoStorm(serviceInfo.Service.ID, incidentTitle(alert), alert.Severity, alert.Fingerprint)
+		if err != nil {
+			log.Printf("WARNING: Failed to check for an alert storm on service %s: %v", serviceInfo.Service.ID, err)
+		} else if stormID != "" {
+			log.Printf("SUCCESS: Alert %s grouped into alert storm incident %s", alert.AlertName, stormID)
+			h.recordDedupDecision(integration, db.DedupModeAuto, db.DedupOutcomeAttached)
+			return nil
+		}
+	}
+
 	// Step 2: Create incident atomically with all resolved information
 	incident, err := h.createIncidentAtomic(integration, alert, serviceInfo, assigneeInfo)
 	if err != nil {
@@ -519,16 +623,27 @@ func (h *WebhookHandler) routeAlertToCreateIncident(integration db.Integration,
 
 	log.Printf("SUCCESS: Created incident %s with ServiceID=%s, AssignedTo=%s",
 		incident.ID, incident.ServiceID, incident.AssignedTo)
+	h.recordDedupDecision(integration, db.DedupModeAuto, db.DedupOutcomeCreated)
+	h.attachAlertSnapshot(integration, incident.ID, alert)
 
 	return nil
 }
 
+// alertLabelValues is an alert's labels as strings, for matching label rules
+func alertLabelValues(alert ProcessedAlert) map[string]string {
+	labels := make(map[string]string, len(alert.Labels))
+	for k, v := range alert.Labels {
+		labels[k] = fmt.Sprint(v)
+	}
+	return labels
+}
+
 // Route alert: resolve existing incident based on alert fingerprint/labels
 func (h *WebhookHandler) routeAlertToResolveIncident(integration db.Integration, alert ProcessedAlert) error {
 	log.Printf("DEBUG: Attempting to resolve incident for alert %s", alert.AlertName)
 
 	// Find existing incident based on alert fingerprint or labels
-	incident, err := h.findIncidentByAlert(integration, alert)
+	incident, strategy, err := h.findIncidentByAlert(integration, alert)
 	if err != nil {
 		log.Printf("ERROR: Failed to find incident for resolved alert %s: %v", alert.AlertName, err)
 		return fmt.Errorf("failed to find incident: %w", err)
@@ -539,6 +654,11 @@ func (h *WebhookHandler) routeAlertToResolveIncident(integration db.Integration,
 		return nil
 	}
 
+	// A title match alone is not enough to close someone's incident
+	if strategy == db.DedupStrategyTitle {
+		return h.parkForDedupReview(integration, alert, incident.ID)
+	}
+
 	// Resolve the incident using IncidentService (triggers notifications)
 	note := "Alert resolved automatically"
 	resolution := fmt.Sprintf("Automatically resolved by %s alert resolution", alert.AlertName)
@@ -555,19 +675,21 @@ func (h *WebhookHandler) routeAlertToResolveIncident(integration db.Integration,
 	}
 
 	log.Printf("SUCCESS: Resolved incident %s for alert %s", incident.ID, alert.AlertName)
+	h.recordDedupDecision(integration, db.DedupModeAuto, db.DedupOutcomeResolved)
 	return nil
 }
 
-// Find existing incident based on alert labels/fingerprint
-func (h *WebhookHandler) findIncidentByAlert(integration db.Integration, alert ProcessedAlert) (*db.Incident, error) {
+// Find existing incident based on alert labels/fingerprint. The strategy that
+// matched is returned with it.
+func (h *WebhookHandler) findIncidentByAlert(integration db.Integration, alert ProcessedAlert) (*db.Incident, string, error) {
 	log.Printf("DEBUG: Finding incident for alert %s", alert.AlertName)
 
 	// Strategy 1: Find by alert fingerprint (if available)
 	if alert.Fingerprint != "" {
-		incident, err := h.findIncidentByFingerprint(alert.Fingerprint)
+		incident, err := h.findIncidentByFingerprint(integration, alert.Fingerprint)
 		if err == nil && incident != nil {
 			log.Printf("DEBUG: Found incident %s by fingerprint %s", incident.ID, alert.Fingerprint)
-			return incident, nil
+			return incident, "fingerprint", nil
 		}
 	}
 
@@ -581,29 +703,28 @@ func (h *WebhookHandler) findIncidentByAlert(integration db.Integration, alert P
 		if err == nil && incident != nil {
 			log.Printf("DEBUG: Found incident %s by labels (alertname=%s, instance=%s, job=%s)",
 				incident.ID, alertname, instance, job)
-			return incident, nil
+			return incident, "labels", nil
 		}
 	}
 
-	// Strategy 3: Find by title match (last resort)
+	// Strategy 3: Find by title match (last resort, needs review)
 	if alertname != "" {
-		incident, err := h.findIncidentByTitle(alertname)
-		if err == nil && incident != nil {
-			log.Printf("DEBUG: Found incident %s by title match %s", incident.ID, alertname)
-			return incident, nil
+		if incidentID := h.findIncidentByTitle(integration, alertname); incidentID != "" {
+			log.Printf("DEBUG: Found incident %s by title match %s", incidentID, alertname)
+			return &db.Incident{ID: incidentID, Title: alertname}, db.DedupStrategyTitle, nil
 		}
 	}
 
 	log.Printf("DEBUG: No incident found for alert %s", alert.AlertName)
-	return nil, nil
+	return nil, "", nil
 }
 
 // Find incident by fingerprint
-func (h *WebhookHandler) findIncidentByFingerprint(fingerprint string) (*db.Incident, error) {
+func (h *WebhookHandler) findIncidentByFingerprint(integration db.Integration, fingerprint string) (*db.Incident, error) {
 	log.Printf("DEBUG: Searching for incident with fingerprint: %s", fingerprint)
 
 	// Use direct database query for fingerprint search (more efficient)
-	incident, err := h.findIncidentByFingerprintDirect(fingerprint)
+	incident, err := h.findIncidentByFingerprintDirect(integration, fingerprint)
 	if err != nil {
 		log.Printf("ERROR: Failed to search incident by fingerprint: %v", err)
 		return nil, err
@@ -619,10 +740,10 @@ func (h *WebhookHandler) findIncidentByFingerprint(fingerprint string) (*db.Inci
 }
 
 // Direct database query for fingerprint search
-func (h *WebhookHandler) findIncidentByFingerprintDirect(fingerprint string) (*db.Incident, error) {
+func (h *WebhookHandler) findIncidentByFingerprintDirect(integration db.Integration, fingerprint string) (*db.Incident, error) {
 	// Get database connection from incident service
 	// We'll need to add a method to access the database
-	return h.incidentService.FindIncidentByFingerprint(fingerprint)
+	return h.incidentService.FindIncidentByFingerprint(integration, fingerprint)
 }
 
 // Find incident by alert labels
@@ -666,28 +787,18 @@ func (h *WebhookHandler) findIncidentByLabels(alertname, instance, job string) (
 	return nil, nil
 }
 
-// Find incident by title (last resort)
-func (h *WebhookHandler) findIncidentByTitle(alertname string) (*db.Incident, error) {
-	// Search for incidents with matching title
-	filters := map[string]interface{}{
-		"search": alertname,
-		"status": "triggered,acknowledged",
-		"limit":  10,
+// findIncidentByTitle returns the newest open incident in the integration's
+// organization with this exact title, or ""
+func (h *WebhookHandler) findIncidentByTitle(integration db.Integration, title string) string {
+	if h.dedupReviews == nil || integration.OrganizationID == "" || title == "" {
+		return ""
 	}
-
-	incidents, err := h.incidentService.ListIncidents(filters)
+	incidentID, err := h.dedupReviews.FindOpenIncidentByTitle(integration.OrganizationID, title)
 	if err != nil {
-		return nil, err
+		log.Printf("ERROR: %v", err)
+		return ""
 	}
-
-	// Find exact title match
-	for _, incident := range incidents {
-		if incident.Title == alertname {
-			return h.convertToIncident(&incident), nil
-		}
-	}
-
-	return nil, nil
+	return incidentID
 }
 
 // Convert IncidentResponse to Incident
@@ -773,12 +884,14 @@ func (h *WebhookHandler) resolveServiceAndAssignee(integration db.Integration, a
 			log.Printf("DEBUG: Service details - ID: %s, Name: %s, EscalationPolicyID: %s, GroupID: %s",
 				service.ID, service.Name, service.EscalationPolicyID, service.GroupID)
 
-			// Step 3: Resolve assignee if service has escalation policy
-			if service.EscalationPolicyID != "" && service.GroupID != "" {
+			// Step 3: Resolve assignee if the binding pages an escalation policy;
+			// non-production bindings never page the service's own
+			policyID := serviceIntegration.EffectiveEscalationPolicy(service.EscalationPolicyID)
+			if policyID != "" && service.GroupID != "" {
 				log.Printf("DEBUG: Resolving assignee with escalation policy %s and group %s",
-					service.EscalationPolicyID, service.GroupID)
+					policyID, service.GroupID)
 
-				assigneeID, err := h.incidentService.GetAssigneeFromEscalationPolicy(service.EscalationPolicyID, service.GroupID)
+				assigneeID, err := h.incidentService.GetAssigneeFromEscalationPolicy(policyID, service.GroupID)
 				if err != nil {
 					log.Printf("DEBUG: Failed to resolve assignee: %v", err)
 				} else if assigneeID != "" {
@@ -818,6 +931,7 @@ func (h *WebhookHandler) createIncidentAtomic(integration db.Integration, alert
 		Status:      db.IncidentStatusTriggered,
 		Source:      "webhook",
 		Urgency:     db.IncidentUrgencyHigh, // Default to high for webhook incidents
+		IsTest:      integration.TestMode,
 	}
 
 	// Add alert metadata
@@ -851,10 +965,17 @@ func (h *WebhookHandler) createIncidentAtomic(integration db.Integration, alert
 		incident.ServiceID = serviceInfo.Service.ID
 		incident.EscalationPolicyID = serviceInfo.Service.EscalationPolicyID
 		incident.GroupID = serviceInfo.Service.GroupID
+		if binding := serviceInfo.ServiceIntegration; binding != nil {
+			incident.Environment = binding.Environment
+			incident.EscalationPolicyID = binding.EffectiveEscalationPolicy(serviceInfo.Service.EscalationPolicyID)
+		}
 		log.Printf("DEBUG: Adding service info - ServiceID: %s, EscalationPolicyID: %s, GroupID: %s",
 			incident.ServiceID, incident.EscalationPolicyID, incident.GroupID)
 	}
 
+	// The service's severity mapping overrides the global severity defaults
+	h.incidentService.ApplySeverityMapping(incident, false)
+
 	// Add assignment information if resolved
 	if assigneeInfo.Found && assigneeInfo.UserID != "" {
 		incident.AssignedTo = assigneeInfo.UserID
@@ -881,6 +1002,14 @@ func (h *WebhookHandler) createIncidentAtomic(integration db.Integration, alert
 	return createdIncident, nil
 }
 
+// incidentTitle is the title createIncidentAtomic gives an alert's incident
+func incidentTitle(alert ProcessedAlert) string {
+	if alert.Summary != "" && alert.Summary != alert.Description {
+		return alert.Summary
+	}
+	return alert.AlertName
+}
+
 // Legacy functions removed - replaced by atomic transaction approach
 
 // Check if alert matches routing conditions
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/services"
+)
+
+// WebhookAllowlistMiddleware enforces per-integration source IP and User-Agent
+// allowlists before the payload is read. Rejected requests are counted on the
+// integration (rejected_ip_count / rejected_user_agent_count).
+func (h *WebhookHandler) WebhookAllowlistMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		integrationID := c.Param("integration_id")
+
+		policy, err := h.integrationService.GetWebhookAccessPolicy(integrationID)
+		if err != nil {
+			// Unknown integrations are reported by the handler itself
+			c.Next()
+			return
+		}
+
+		clientIP := c.ClientIP()
+		reason := policy.Check(clientIP, c.Request.UserAgent())
+		if reason == "" {
+			c.Next()
+			return
+		}
+
+		log.Printf("Rejected webhook for integration %s: reason=%s ip=%s user_agent=%q",
+			integrationID, reason, clientIP, c.Request.UserAgent())
+
+		go func() {
+			if err := h.integrationService.RecordWebhookRejection(integrationID, reason, clientIP); err != nil {
+				log.Printf("Failed to record webhook rejection for %s: %v", integrationID, err)
+			}
+		}()
+
+		message := "Source IP is not allowed for this integration"
+		if reason == services.WebhookRejectUserAgent {
+			message = "User-Agent is not allowed for this integration"
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		c.Abort()
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBatchEvents caps the number of events accepted in one batch request
+const maxWebhookBatchEvents = 500
+
+// Batch item statuses
+const (
+	WebhookBatchItemAccepted = "accepted" // all alerts routed
+	WebhookBatchItemRejected = "rejected" // payload failed validation
+	WebhookBatchItemFailed   = "failed"   // valid payload, but routing an alert failed
+)
+
+// WebhookBatchRequest is the body of POST /webhook/:type/:integration_id/batch.
+// Each event is a payload in the integration's own format, exactly as it would
+// be sent to the single-event webhook.
+type WebhookBatchRequest struct {
+	Events []json.RawMessage `json:"events"`
+}
+
+// WebhookBatchItemResult reports the outcome of one event in a batch
+type WebhookBatchItemResult struct {
+	Index       int                 `json:"index"`
+	Status      string              `json:"status"`
+	AlertsCount int                 `json:"alerts_count"`
+	Errors      []WebhookFieldError `json:"errors,omitempty"`
+}
+
+// webhookBatchMaxBodyBytes is the body limit for batch requests (32x the single-event limit)
+func webhookBatchMaxBodyBytes() int64 {
+	return webhookMaxBodyBytes() * 32
+}
+
+// ReceiveWebhookBatch ingests many events for one integration in a single request
+// POST /webhook/:type/:integration_id/batch
+func (h *WebhookHandler) ReceiveWebhookBatch(c *gin.Context) {
+	integrationType := c.Param("type")
+	integrationID := c.Param("integration_id")
+
+	integration, ok := h.loadWebhookIntegration(c, integrationType, integrationID)
+	if !ok {
+		return
+	}
+
+	if isDeployIntegrationType(integrationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch ingest is not supported for deployment integrations"})
+		return
+	}
+
+	var req WebhookBatchRequest
+	if !h.bindLimitedJSON(c, integrationID, webhookBatchMaxBodyBytes(), &req) {
+		return
+	}
+	if len(req.Events) == 0 {
+		h.rejectWebhookPayload(c, integrationID, http.StatusUnprocessableEntity, "Payload validation failed", []WebhookFieldError{
+			{Field: "events", Message: "must contain at least one event"},
+		})
+		return
+	}
+	if len(req.Events) > maxWebhookBatchEvents {
+		h.rejectWebhookPayload(c, integrationID, http.StatusRequestEntityTooLarge, "Too many events in batch", []WebhookFieldError{
+			{Field: "events", Message: fmt.Sprintf("must contain at most %d events", maxWebhookBatchEvents)},
+		})
+		return
+	}
+
+	scrubber, ok := h.loadPayloadScrubber(c, integrationID)
+	if !ok {
+		return
+	}
+
+	if err := h.integrationService.UpdateHeartbeat(integrationID); err != nil {
+		log.Printf("Failed to update heartbeat for integration %s: %v", integrationID, err)
+	}
+
+	results := make([]WebhookBatchItemResult, len(req.Events))
+	var accepted, rejected, failed, alertsCount int
+	var firstRejection string
+	for i, raw := range req.Events {
+		result := WebhookBatchItemResult{Index: i}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(raw, &payload); err != nil || payload == nil {
+			result.Errors = []WebhookFieldError{{Field: "body", Message: "must be a JSON object"}}
+		} else {
+			h.scrubPayload(integrationID, scrubber, payload)
+			result.Errors = validateWebhookPayload(integrationType, payload)
+		}
+		if len(result.Errors) > 0 {
+			result.Status = WebhookBatchItemRejected
+			rejected++
+			if firstRejection == "" {
+				firstRejection = formatWebhookErrors(fmt.Sprintf("Batch event %d failed validation", i), result.Errors)
+			}
+			results[i] = result
+			continue
+		}
+
+		alerts := h.processWebhookPayload(integrationType, payload)
+		result.AlertsCount = len(alerts)
+		result.Status = WebhookBatchItemAccepted
+		for _, alert := range alerts {
+			if err := h.routeAlert(integration, alert); err != nil {
+				log.Printf("Failed to process alert %s: %v", alert.AlertName, err)
+				result.Status = WebhookBatchItemFailed
+				result.Errors = append(result.Errors, WebhookFieldError{Field: "alert:" + alert.AlertName, Message: err.Error()})
+			}
+		}
+		if result.Status == WebhookBatchItemAccepted {
+			accepted++
+		} else {
+			failed++
+		}
+		alertsCount += result.AlertsCount
+		results[i] = result
+	}
+
+	if firstRejection != "" {
+		h.recordWebhookPayloadError(integrationID, firstRejection)
+	}
+
+	log.Printf("Processed webhook batch: integration=%s, events=%d, accepted=%d, rejected=%d, failed=%d, alerts_count=%d",
+		integrationID, len(req.Events), accepted, rejected, failed, alertsCount)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Webhook batch processed",
+		"events_count":   len(req.Events),
+		"accepted":       accepted,
+		"rejected":       rejected,
+		"failed":         failed,
+		"alerts_count":   alertsCount,
+		"results":        results,
+		"integration_id": integrationID,
+		"timestamp":      time.Now(),
+	})
+}
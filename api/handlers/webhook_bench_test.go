@@ -0,0 +1,394 @@
+package handlers
+
+// Benchmarks for the webhook ingestion hot path:
+//
+//	ReceiveWebhook[Batch] → processPrometheusWebhook → resolveServiceAndAssignee → CreateIncident
+//
+// Database calls go through benchDriver, an in-memory database/sql driver that
+// answers the queries on this path with canned rows, so the numbers measure
+// handler/service CPU and allocations rather than Postgres. Set
+// SLAR_BENCH_DB_LATENCY (e.g. "500us") to add a fixed delay per round trip and
+// see how the synchronous design scales with real network latency.
+//
+// See docs/benchmarks.md for baselines and scripts/bench.sh for profiling.
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+const (
+	benchIntegrationID = "11111111-1111-1111-1111-111111111111"
+	benchServiceID     = "22222222-2222-2222-2222-222222222222"
+	benchGroupID       = "33333333-3333-3333-3333-333333333333"
+	benchPolicyID      = "44444444-4444-4444-4444-444444444444"
+	benchSchedulerID   = "55555555-5555-5555-5555-555555555555"
+	benchUserID        = "66666666-6666-6666-6666-666666666666"
+	benchOrgID         = "77777777-7777-7777-7777-777777777777"
+)
+
+// benchQuery maps a distinctive query fragment to the single row it returns
+type benchQuery struct {
+	match   string
+	columns []string
+	row     func() []driver.Value
+}
+
+var benchQueries = []benchQuery{
+	{
+		// IntegrationService.GetIntegration
+		match: "get_integration_health_status",
+		columns: []string{"id", "name", "type", "description", "config", "webhook_url", "webhook_secret",
+			"is_active", "last_heartbeat", "heartbeat_interval", "created_at", "updated_at", "created_by",
+			"health_status", "services_count", "allowed_source_cidrs", "allowed_user_agents",
+			"rejected_ip_count", "rejected_user_agent_count", "last_rejected_at", "last_rejected_source",
+			"payload_error_count", "last_payload_error", "last_payload_error_at", "test_mode", "silence_alert"},
+		row: func() []driver.Value {
+			now := time.Now()
+			return []driver.Value{benchIntegrationID, "bench-prometheus", "prometheus", "", []byte(`{}`), nil, "",
+				true, now, int64(300), now, now, "", "healthy", int64(1), []byte(`{}`), []byte(`{}`),
+				int64(0), int64(0), nil, "", int64(0), "", nil, false, nil}
+		},
+	},
+	{
+		// IntegrationService.GetWebhookAccessPolicy
+		match:   "SELECT allowed_source_cidrs, allowed_user_agents",
+		columns: []string{"allowed_source_cidrs", "allowed_user_agents"},
+		row:     func() []driver.Value { return []driver.Value{[]byte(`{}`), []byte(`{}`)} },
+	},
+	{
+		// IntegrationService.GetIntegrationServices
+		match: "si.routing_conditions",
+		columns: []string{"id", "service_id", "integration_id", "routing_conditions", "priority", "is_active",
+			"created_at", "updated_at", "created_by", "service_name", "integration_name", "integration_type",
+			"environment", "escalation_policy_id"},
+		row: func() []driver.Value {
+			now := time.Now()
+			return []driver.Value{"si-1", benchServiceID, benchIntegrationID,
+				[]byte(`{"severity":["critical","warning"]}`), int64(1), true, now, now, "",
+				"checkout-api", "bench-prometheus", "prometheus", "", ""}
+		},
+	},
+	{
+		// ServiceService.GetService
+		match: "g.name as group_name",
+		columns: []string{"id", "group_id", "name", "description", "routing_key", "escalation_policy_id",
+			"is_active", "created_at", "updated_at", "created_by", "integrations", "notification_settings", "group_name",
+			"revenue_per_minute", "engineer_hourly_cost"},
+		row: func() []driver.Value {
+			now := time.Now()
+			return []driver.Value{benchServiceID, benchGroupID, "checkout-api", "", "checkout-api-key", benchPolicyID,
+				true, now, now, "", []byte(`{}`), []byte(`{}`), "payments", nil, nil}
+		},
+	},
+	{
+		// IncidentService.GetAssigneeFromEscalationPolicy
+		match:   "FROM escalation_levels",
+		columns: []string{"target_type", "target_id"},
+		row:     func() []driver.Value { return []driver.Value{"scheduler", benchSchedulerID} },
+	},
+	{
+		// IncidentService.getCurrentOnCallUserFromScheduler
+		match:   "FROM effective_shifts",
+		columns: []string{"effective_user_id"},
+		row:     func() []driver.Value { return []driver.Value{benchUserID} },
+	},
+	{
+		// IncidentService.CreateIncident tenant lookup
+		match:   "SELECT organization_id, project_id",
+		columns: []string{"organization_id", "project_id"},
+		row:     func() []driver.Value { return []driver.Value{benchOrgID, nil} },
+	},
+	{
+		// IncidentService.CreateIncident insert
+		match:   "RETURNING short_id, number",
+		columns: []string{"short_id", "number"},
+		row:     func() []driver.Value { return []driver.Value{"k3m9x2ab7q", int64(1)} },
+	},
+	{
+		// IncidentService.CreateIncident assignee display name
+		match:   "FROM users WHERE id",
+		columns: []string{"name"},
+		row:     func() []driver.Value { return []driver.Value{"On-call Engineer"} },
+	},
+}
+
+var registerBenchDriver sync.Once
+
+// benchDriver is a minimal database/sql driver serving benchQueries.
+// Unknown queries return no rows; every Exec affects one row.
+type benchDriver struct{}
+
+type benchConn struct{ latency time.Duration }
+
+type benchRows struct {
+	columns []string
+	row     []driver.Value
+	done    bool
+}
+
+type benchResult struct{}
+
+func (benchDriver) Open(name string) (driver.Conn, error) {
+	latency, _ := time.ParseDuration(name)
+	return &benchConn{latency: latency}, nil
+}
+
+func (c *benchConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("benchdb: prepared statements are not supported")
+}
+
+func (c *benchConn) Close() error { return nil }
+
+func (c *benchConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("benchdb: transactions are not supported")
+}
+
+func (c *benchConn) roundTrip() {
+	if c.latency > 0 {
+		time.Sleep(c.latency)
+	}
+}
+
+func (c *benchConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.roundTrip()
+	for _, q := range benchQueries {
+		if strings.Contains(query, q.match) {
+			return &benchRows{columns: q.columns, row: q.row()}, nil
+		}
+	}
+	return &benchRows{columns: []string{"?"}, done: true}, nil
+}
+
+func (c *benchConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.roundTrip()
+	return benchResult{}, nil
+}
+
+func (r *benchRows) Columns() []string { return r.columns }
+
+func (r *benchRows) Close() error { return nil }
+
+func (r *benchRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.done = true
+	return nil
+}
+
+func (benchResult) LastInsertId() (int64, error) { return 0, nil }
+
+func (benchResult) RowsAffected() (int64, error) { return 1, nil }
+
+// newBenchWebhookHandler wires a WebhookHandler to benchDriver and silences
+// logging for the duration of the benchmark. The log calls on the hot path
+// still format their arguments, so their CPU cost stays in the numbers.
+func newBenchWebhookHandler(b *testing.B) *WebhookHandler {
+	b.Helper()
+	registerBenchDriver.Do(func() { sql.Register("slar-benchdb", benchDriver{}) })
+
+	pg, err := sql.Open("slar-benchdb", os.Getenv("SLAR_BENCH_DB_LATENCY"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	prevOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() {
+		log.SetOutput(prevOutput)
+		pg.Close()
+	})
+
+	return NewWebhookHandler(
+		services.NewIntegrationService(pg),
+		services.NewAlertService(pg, nil),
+		services.NewIncidentService(pg, nil),
+		services.NewServiceService(pg),
+		services.NewAlertDedupReviewService(pg),
+		nil,
+		nil,
+	)
+}
+
+// benchPrometheusPayload builds an Alertmanager notification with n firing alerts
+func benchPrometheusPayload(n int) []byte {
+	alerts := make([]map[string]interface{}, n)
+	for i := range alerts {
+		alerts[i] = map[string]interface{}{
+			"status": "firing",
+			"labels": map[string]string{
+				"alertname":   "HighCPUUsage",
+				"instance":    fmt.Sprintf("prod-web-%03d:9100", i),
+				"job":         "node-exporter",
+				"severity":    "critical",
+				"service":     "checkout-api",
+				"environment": "production",
+				"region":      "us-east-1",
+				"namespace":   "payments",
+				"pod":         fmt.Sprintf("checkout-api-7d8f9c6b5d-%05d", i),
+			},
+			"annotations": map[string]string{
+				"summary":     "High CPU usage detected",
+				"description": fmt.Sprintf("CPU usage is above 90%% on prod-web-%03d for more than 5 minutes", i),
+				"runbook_url": "https://runbooks.example.com/high-cpu",
+			},
+			"startsAt":     "2026-01-01T00:00:00Z",
+			"endsAt":       "0001-01-01T00:00:00Z",
+			"generatorURL": "http://prometheus:9090/graph?g0.expr=cpu",
+			"fingerprint":  fmt.Sprintf("%016x", i),
+		}
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"version":           "4",
+		"groupKey":          `{}:{alertname="HighCPUUsage"}`,
+		"status":            "firing",
+		"receiver":          "slar-webhook",
+		"groupLabels":       map[string]string{"alertname": "HighCPUUsage"},
+		"commonLabels":      map[string]string{"alertname": "HighCPUUsage", "severity": "critical"},
+		"commonAnnotations": map[string]string{},
+		"externalURL":       "http://alertmanager:9093",
+		"alerts":            alerts,
+	})
+	return payload
+}
+
+var benchAlertCounts = []int{1, 10, 100}
+
+func BenchmarkProcessPrometheusWebhook(b *testing.B) {
+	h := newBenchWebhookHandler(b)
+	for _, n := range benchAlertCounts {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(benchPrometheusPayload(n), &payload); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("alerts=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if alerts := h.processPrometheusWebhook(payload); len(alerts) != n {
+					b.Fatalf("got %d alerts, want %d", len(alerts), n)
+				}
+			}
+		})
+	}
+}
+
+func benchIntegrationAndAlert(b *testing.B, h *WebhookHandler) (db.Integration, ProcessedAlert) {
+	b.Helper()
+	integration, err := h.integrationService.GetIntegration(benchIntegrationID)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(benchPrometheusPayload(1), &payload); err != nil {
+		b.Fatal(err)
+	}
+	return integration, h.processPrometheusWebhook(payload)[0]
+}
+
+func BenchmarkResolveServiceAndAssignee(b *testing.B) {
+	h := newBenchWebhookHandler(b)
+	integration, alert := benchIntegrationAndAlert(b, h)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serviceInfo, assigneeInfo, err := h.resolveServiceAndAssignee(integration, alert)
+		if err != nil || !serviceInfo.Found || !assigneeInfo.Found {
+			b.Fatalf("resolve failed: service=%v assignee=%v err=%v", serviceInfo.Found, assigneeInfo.Found, err)
+		}
+	}
+}
+
+func BenchmarkRouteAlertToCreateIncident(b *testing.B) {
+	h := newBenchWebhookHandler(b)
+	integration, alert := benchIntegrationAndAlert(b, h)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// CreateIncident mutates the labels map it is given
+		alert.Labels = map[string]interface{}{"alertname": alert.AlertName, "severity": alert.Severity}
+		if err := h.routeAlertToCreateIncident(integration, alert); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReceiveWebhookPrometheus(b *testing.B) {
+	h := newBenchWebhookHandler(b)
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.POST("/webhook/:type/:integration_id", h.WebhookAllowlistMiddleware(), h.ReceiveWebhook)
+	url := "/webhook/prometheus/" + benchIntegrationID
+
+	for _, n := range benchAlertCounts {
+		payload := benchPrometheusPayload(n)
+		b.Run(fmt.Sprintf("alerts=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					b.Fatalf("status = %d: %s", w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReceiveWebhookBatchPrometheus(b *testing.B) {
+	h := newBenchWebhookHandler(b)
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.POST("/webhook/:type/:integration_id/batch", h.WebhookAllowlistMiddleware(), h.ReceiveWebhookBatch)
+	url := "/webhook/prometheus/" + benchIntegrationID + "/batch"
+
+	for _, n := range benchAlertCounts {
+		events := make([]json.RawMessage, n)
+		for i := range events {
+			events[i] = benchPrometheusPayload(1)
+		}
+		payload, _ := json.Marshal(WebhookBatchRequest{Events: events})
+		b.Run(fmt.Sprintf("events=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					b.Fatalf("status = %d: %s", w.Code, w.Body.String())
+				}
+				var resp struct {
+					Accepted int `json:"accepted"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Accepted != n {
+					b.Fatalf("accepted = %d, want %d: %s", resp.Accepted, n, w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+)
+
+// isDeployIntegrationType reports whether an integration receives deployment
+// webhooks (handled by deploy rules) rather than alerts
+func isDeployIntegrationType(integrationType string) bool {
+	switch integrationType {
+	case "github", "gitlab", "argocd":
+		return true
+	}
+	return false
+}
+
+// receiveDeployWebhook normalizes a deployment webhook, records it as a change
+// event and applies the deploy rules of the services linked to the integration
+func (h *WebhookHandler) receiveDeployWebhook(c *gin.Context, integration db.Integration, payload map[string]interface{}) {
+	var event *db.DeployEvent
+	switch integration.Type {
+	case "github":
+		event = parseGitHubDeploy(payload)
+	case "gitlab":
+		event = parseGitLabDeploy(payload)
+	case "argocd":
+		event = parseArgoCDDeploy(payload)
+	}
+
+	if event == nil {
+		// Other events from the same hook (pings, pushes, ...) are acknowledged and ignored
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Event ignored: not a deployment",
+			"integration_id": integration.ID,
+		})
+		return
+	}
+
+	if err := h.incidentService.RecordDeployChangeEvents(integration.ID, *event); err != nil {
+		log.Printf("Failed to record deploy change events for integration %s: %v", integration.ID, err)
+	}
+
+	matches, err := h.incidentService.ApplyDeployEvent(integration.ID, *event, db.GetSystemUserBySource(integration.Type))
+	if err != nil {
+		log.Printf("Failed to apply deploy rules for integration %s: %v", integration.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process deployment"})
+		return
+	}
+
+	log.Printf("Processed deploy webhook: integration=%s, status=%s, environment=%s, matched_incidents=%d",
+		integration.ID, event.Status, event.Environment, len(matches))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Deployment processed successfully",
+		"deploy":         event,
+		"matches":        matches,
+		"integration_id": integration.ID,
+		"timestamp":      time.Now(),
+	})
+}
+
+// parseGitHubDeploy handles GitHub "deployment_status" events
+// Reference: https://docs.github.com/en/webhooks/webhook-events-and-payloads#deployment_status
+func parseGitHubDeploy(payload map[string]interface{}) *db.DeployEvent {
+	status := getMapFromMap(payload, "deployment_status")
+	if len(status) == 0 {
+		return nil
+	}
+
+	url := getStringFromMap(status, "environment_url", "")
+	if url == "" {
+		url = getStringFromMap(status, "target_url", "")
+	}
+	return &db.DeployEvent{
+		Provider:    "github",
+		Repository:  getStringFromMap(payload, "repository.full_name", ""),
+		Environment: getStringFromMap(status, "environment", getStringFromMap(payload, "deployment.environment", "")),
+		Version:     getStringFromMap(payload, "deployment.sha", ""),
+		Ref:         getStringFromMap(payload, "deployment.ref", ""),
+		URL:         url,
+		Status:      strings.ToLower(getStringFromMap(status, "state", "")),
+		DeployedBy:  getStringFromMap(payload, "deployment.creator.login", getStringFromMap(payload, "sender.login", "")),
+	}
+}
+
+// parseGitLabDeploy handles GitLab deployment events
+// Reference: https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#deployment-events
+func parseGitLabDeploy(payload map[string]interface{}) *db.DeployEvent {
+	if getStringFromMap(payload, "object_kind", "") != "deployment" {
+		return nil
+	}
+
+	url := getStringFromMap(payload, "environment_external_url", "")
+	if url == "" {
+		url = getStringFromMap(payload, "deployable_url", "")
+	}
+	return &db.DeployEvent{
+		Provider:    "gitlab",
+		Repository:  getStringFromMap(payload, "project.path_with_namespace", ""),
+		Environment: getStringFromMap(payload, "environment", ""),
+		Version:     getStringFromMap(payload, "short_sha", ""),
+		Ref:         getStringFromMap(payload, "ref", ""),
+		URL:         url,
+		Status:      strings.ToLower(getStringFromMap(payload, "status", "")),
+		DeployedBy:  getStringFromMap(payload, "user.username", ""),
+	}
+}
+
+// parseArgoCDDeploy handles ArgoCD Notifications webhooks. ArgoCD has no fixed
+// payload, so the notification template is expected to send:
+//
+//	{"app": "{{.app.metadata.name}}", "revision": "{{.app.status.sync.revision}}",
+//	 "operation_phase": "{{.app.status.operationState.phase}}",
+//	 "environment": "production", "url": "{{.context.argocdUrl}}/applications/{{.app.metadata.name}}"}
+func parseArgoCDDeploy(payload map[string]interface{}) *db.DeployEvent {
+	app := getStringFromMap(payload, "app", getStringFromMap(payload, "application", ""))
+	if app == "" {
+		return nil
+	}
+
+	var status string
+	switch strings.ToLower(getStringFromMap(payload, "operation_phase", "")) {
+	case "succeeded":
+		status = "success"
+	case "failed", "error":
+		status = "failure"
+	case "":
+		// Fall back to sync/health for templates that don't send the operation phase
+		if getStringFromMap(payload, "sync_status", "") == "Synced" && getStringFromMap(payload, "health_status", "") == "Healthy" {
+			status = "success"
+		} else {
+			status = "pending"
+		}
+	default:
+		status = "pending"
+	}
+
+	return &db.DeployEvent{
+		Provider:    "argocd",
+		Repository:  app,
+		Environment: getStringFromMap(payload, "environment", ""),
+		Version:     getStringFromMap(payload, "revision", ""),
+		URL:         getStringFromMap(payload, "url", ""),
+		Status:      status,
+		DeployedBy:  getStringFromMap(payload, "initiated_by", ""),
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseDeployWebhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(map[string]interface{}) *db.DeployEvent
+		payload string
+		want    *db.DeployEvent
+	}{
+		{
+			name:  "GitHub deployment_status success",
+			parse: parseGitHubDeploy,
+			payload: `{
+				"action": "created",
+				"deployment_status": {"state": "success", "environment": "production", "target_url": "https://github.com/acme/api/actions/runs/1"},
+				"deployment": {"sha": "a1b2c3d", "ref": "v2.4.1", "environment": "production", "creator": {"login": "octocat"}},
+				"repository": {"full_name": "acme/api"}
+			}`,
+			want: &db.DeployEvent{
+				Provider: "github", Repository: "acme/api", Environment: "production", Version: "a1b2c3d",
+				Ref: "v2.4.1", URL: "https://github.com/acme/api/actions/runs/1", Status: "success", DeployedBy: "octocat",
+			},
+		},
+		{
+			name:    "GitHub ping is not a deployment",
+			parse:   parseGitHubDeploy,
+			payload: `{"zen": "Keep it logically awesome.", "hook_id": 1}`,
+			want:    nil,
+		},
+		{
+			name:  "GitLab deployment",
+			parse: parseGitLabDeploy,
+			payload: `{
+				"object_kind": "deployment", "status": "success", "environment": "staging",
+				"short_sha": "279484c0", "ref": "main", "deployable_url": "https://gitlab.com/acme/web/-/jobs/42",
+				"project": {"path_with_namespace": "acme/web"}, "user": {"username": "root"}
+			}`,
+			want: &db.DeployEvent{
+				Provider: "gitlab", Repository: "acme/web", Environment: "staging", Version: "279484c0",
+				Ref: "main", URL: "https://gitlab.com/acme/web/-/jobs/42", Status: "success", DeployedBy: "root",
+			},
+		},
+		{
+			name:    "GitLab push is not a deployment",
+			parse:   parseGitLabDeploy,
+			payload: `{"object_kind": "push", "ref": "refs/heads/main"}`,
+			want:    nil,
+		},
+		{
+			name:  "ArgoCD operation succeeded",
+			parse: parseArgoCDDeploy,
+			payload: `{"app": "checkout", "revision": "9f8e7d", "operation_phase": "Succeeded",
+				"environment": "production", "url": "https://argocd.acme.io/applications/checkout"}`,
+			want: &db.DeployEvent{
+				Provider: "argocd", Repository: "checkout", Environment: "production", Version: "9f8e7d",
+				URL: "https://argocd.acme.io/applications/checkout", Status: "success",
+			},
+		},
+		{
+			name:    "ArgoCD out of sync falls back to pending",
+			parse:   parseArgoCDDeploy,
+			payload: `{"app": "checkout", "sync_status": "OutOfSync", "health_status": "Healthy"}`,
+			want:    &db.DeployEvent{Provider: "argocd", Repository: "checkout", Status: "pending"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.payload), &payload); err != nil {
+				t.Fatal(err)
+			}
+
+			got := tt.parse(payload)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected no deploy event, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a deploy event, got nil")
+			}
+			if *got != *tt.want {
+				t.Errorf("got %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
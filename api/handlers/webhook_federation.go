@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// receiveFederationWebhook applies a status change reported by the remote
+// SLAR instance an incident was forwarded to
+func (h *WebhookHandler) receiveFederationWebhook(c *gin.Context, integration db.Integration, payload map[string]interface{}) {
+	update := db.FederationStatusUpdate{
+		Token:             getStringFromMap(payload, "token", ""),
+		Status:            getStringFromMap(payload, "status", ""),
+		Note:              getStringFromMap(payload, "note", ""),
+		RemoteIncidentID:  getStringFromMap(payload, "remote_incident_id", ""),
+		RemoteIncidentKey: getStringFromMap(payload, "remote_incident_key", ""),
+	}
+
+	link, err := h.incidentService.ApplyFederationStatus(integration.ID, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrFederationLinkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Federated incident not found"})
+		case errors.Is(err, services.ErrInvalidFederationStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Failed to apply federation status for integration %s: %v", integration.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply status"})
+		}
+		return
+	}
+
+	log.Printf("Mirrored federation status: integration=%s, incident=%s, status=%s",
+		integration.ID, link.IncidentID, update.Status)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Status applied",
+		"incident_id":    link.IncidentID,
+		"status":         update.Status,
+		"integration_id": integration.ID,
+		"timestamp":      time.Now(),
+	})
+}
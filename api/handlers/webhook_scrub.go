@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/services"
+)
+
+// loadPayloadScrubber loads the integration's scrub policy. When it can't be
+// loaded the webhook is refused rather than stored unscrubbed; senders retry.
+func (h *WebhookHandler) loadPayloadScrubber(c *gin.Context, integrationID string) (*services.PayloadScrubber, bool) {
+	scrubber, err := h.integrationService.GetPayloadScrubber(integrationID)
+	if err != nil {
+		log.Printf("Failed to load scrub policy for integration %s: %v", integrationID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scrub policy unavailable, payload not accepted"})
+		return nil, false
+	}
+	return scrubber, true
+}
+
+// scrubPayload redacts a webhook payload in place before it is processed,
+// counting the redactions on the integration for audits
+func (h *WebhookHandler) scrubPayload(integrationID string, scrubber *services.PayloadScrubber, payload map[string]interface{}) {
+	counts := scrubber.Scrub(payload)
+	if len(counts) == 0 {
+		return
+	}
+	go func() {
+		if err := h.integrationService.RecordScrubRedactions(integrationID, counts); err != nil {
+			log.Printf("Failed to record scrub redactions for %s: %v", integrationID, err)
+		}
+	}()
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// snapshotMaxBodyBytes leaves room for base64 and multipart overhead around
+// the largest image accepted
+const snapshotMaxBodyBytes = db.IncidentSnapshotMaxBytes*4/3 + 64<<10
+
+// ReceiveSnapshot handles POST /webhook/:type/:integration_id/snapshots
+// Attaches a chart snapshot (e.g. a Grafana panel render) to an incident when
+// it opens or on a significant update. Integrations send JSON with image_url
+// or image_base64, or upload the image as the "image" field of a multipart
+// form, naming the incident by incident_id or by the fingerprint of its alert.
+func (h *WebhookHandler) ReceiveSnapshot(c *gin.Context) {
+	integrationID := c.Param("integration_id")
+	integration, ok := h.loadWebhookIntegration(c, c.Param("type"), integrationID)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, snapshotMaxBodyBytes)
+	var req db.AttachIncidentSnapshotRequest
+	var image []byte
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBind(&req); err != nil {
+			h.rejectSnapshot(c, err)
+			return
+		}
+		if header, err := c.FormFile("image"); err == nil {
+			file, err := header.Open()
+			if err != nil {
+				h.rejectSnapshot(c, err)
+				return
+			}
+			image, err = io.ReadAll(io.LimitReader(file, db.IncidentSnapshotMaxBytes+1))
+			file.Close()
+			if err != nil {
+				h.rejectSnapshot(c, err)
+				return
+			}
+		}
+	} else {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.rejectSnapshot(c, err)
+			return
+		}
+		if req.ImageBase64 != "" {
+			decoded, err := base64.StdEncoding.DecodeString(req.ImageBase64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "image_base64 is not valid base64"})
+				return
+			}
+			image = decoded
+		}
+	}
+
+	incidentID := req.IncidentID
+	if incidentID == "" {
+		if req.Fingerprint == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "incident_id or fingerprint is required"})
+			return
+		}
+		incident, err := h.incidentService.FindIncidentByFingerprint(integration, req.Fingerprint)
+		if err != nil || incident == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No open incident for this fingerprint"})
+			return
+		}
+		incidentID = incident.ID
+	}
+
+	artifact, err := h.incidentService.AttachIncidentSnapshot(incidentID, integration.ID,
+		db.GetSystemUserBySource(integration.Type), req.Title, req.ImageURL, image)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIncidentSnapshot):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "incident not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		default:
+			log.Printf("Failed to attach snapshot from integration %s: %v", integrationID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach snapshot"})
+		}
+		return
+	}
+
+	log.Printf("Attached snapshot %s to incident %s from integration %s", artifact.ID, incidentID, integrationID)
+	c.JSON(http.StatusCreated, gin.H{"artifact": artifact})
+}
+
+// rejectSnapshot answers a snapshot request whose body couldn't be read
+func (h *WebhookHandler) rejectSnapshot(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Snapshot is too large"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot request", "details": err.Error()})
+}
+
+// attachAlertSnapshot keeps the chart image an alert links to (Grafana's
+// imageURL or an image_url annotation) on the incident it opened or joined.
+// Failures are logged: the alert itself was handled.
+func (h *WebhookHandler) attachAlertSnapshot(integration db.Integration, incidentID string, alert ProcessedAlert) {
+	imageURL, _ := alert.Annotations["image_url"].(string)
+	if imageURL == "" {
+		return
+	}
+	if _, err := h.incidentService.AttachIncidentSnapshot(incidentID, integration.ID,
+		db.GetSystemUserBySource(integration.Type), alert.AlertName, imageURL, nil); err != nil {
+		log.Printf("WARNING: Failed to attach snapshot to incident %s: %v", incidentID, err)
+	}
+}
@@ -96,6 +96,7 @@ type GrafanaAlert struct {
 	SilenceURL   string             `json:"silenceURL"`
 	DashboardURL string             `json:"dashboardURL"`
 	PanelURL     string             `json:"panelURL"`
+	ImageURL     string             `json:"imageURL"`
 	Values       map[string]float64 `json:"values"`
 }
 
@@ -262,6 +263,12 @@ func parseDatadogTimestampFromString(date, lastUpdated string) time.Time {
 }
 
 func (g *GrafanaWebhook) ToProcessedAlert() ProcessedAlert {
+	// Unified alerting renders the panel image per alert
+	imageURL := g.ImageURL
+	if imageURL == "" && len(g.Alerts) > 0 {
+		imageURL = g.Alerts[0].ImageURL
+	}
+
 	alert := ProcessedAlert{
 		AlertName:   g.RuleName,
 		Severity:    mapGrafanaSeverity(g.State),
@@ -275,7 +282,7 @@ func (g *GrafanaWebhook) ToProcessedAlert() ProcessedAlert {
 		},
 		Annotations: map[string]interface{}{
 			"grafana_url": g.RuleURL,
-			"image_url":   g.ImageURL,
+			"image_url":   imageURL,
 		},
 		StartsAt: time.Now(),
 	}
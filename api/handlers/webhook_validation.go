@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// defaultWebhookMaxBodyBytes applies when webhook_max_body_bytes is not configured
+const defaultWebhookMaxBodyBytes = 1 << 20
+
+// WebhookFieldError describes one field that failed payload validation
+type WebhookFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e WebhookFieldError) String() string {
+	return e.Field + ": " + e.Message
+}
+
+func webhookMaxBodyBytes() int64 {
+	if config.Current().WebhookMaxBodyBytes > 0 {
+		return config.Current().WebhookMaxBodyBytes
+	}
+	return defaultWebhookMaxBodyBytes
+}
+
+// bindWebhookPayload reads the JSON object body under the given size limit. On
+// failure it writes a 413 (too large) or 400 (malformed) response, records the
+// error on the integration and returns false.
+func (h *WebhookHandler) bindWebhookPayload(c *gin.Context, integrationID string, limit int64) (map[string]interface{}, bool) {
+	var payload map[string]interface{}
+	if !h.bindLimitedJSON(c, integrationID, limit, &payload) {
+		return nil, false
+	}
+	if payload == nil {
+		h.rejectWebhookPayload(c, integrationID, http.StatusBadRequest, "Invalid JSON payload", []WebhookFieldError{
+			{Field: "body", Message: "must be a JSON object"},
+		})
+		return nil, false
+	}
+	return payload, true
+}
+
+func (h *WebhookHandler) bindLimitedJSON(c *gin.Context, integrationID string, limit int64, obj interface{}) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		message := fmt.Sprintf("Payload exceeds maximum size of %d bytes", limit)
+		h.rejectWebhookPayload(c, integrationID, http.StatusRequestEntityTooLarge, message, []WebhookFieldError{
+			{Field: "body", Message: fmt.Sprintf("must be at most %d bytes", limit)},
+		})
+		return false
+	}
+
+	h.rejectWebhookPayload(c, integrationID, http.StatusBadRequest, "Invalid JSON payload", []WebhookFieldError{
+		{Field: "body", Message: err.Error()},
+	})
+	return false
+}
+
+// formatWebhookErrors flattens field errors into the integration's "last error" text
+func formatWebhookErrors(message string, fieldErrors []WebhookFieldError) string {
+	if len(fieldErrors) == 0 {
+		return message
+	}
+	details := make([]string, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		details[i] = fe.String()
+	}
+	return message + ": " + strings.Join(details, "; ")
+}
+
+// recordWebhookPayloadError stores the integration's "last error" without
+// blocking the response
+func (h *WebhookHandler) recordWebhookPayloadError(integrationID, lastError string) {
+	log.Printf("Rejected webhook payload for integration %s: %s", integrationID, lastError)
+	if h.integrationService == nil {
+		return
+	}
+	go func() {
+		if err := h.integrationService.RecordWebhookPayloadError(integrationID, lastError); err != nil {
+			log.Printf("Failed to record webhook payload error for %s: %v", integrationID, err)
+		}
+	}()
+}
+
+// rejectWebhookPayload writes a structured error response and records the
+// error on the integration
+func (h *WebhookHandler) rejectWebhookPayload(c *gin.Context, integrationID string, status int, message string, fieldErrors []WebhookFieldError) {
+	h.recordWebhookPayloadError(integrationID, formatWebhookErrors(message, fieldErrors))
+
+	c.JSON(status, gin.H{
+		"error":          message,
+		"errors":         fieldErrors,
+		"integration_id": integrationID,
+	})
+}
+
+// validateWebhookPayload checks the fields each provider's processor relies on.
+// It returns nil when the payload can be processed.
+func validateWebhookPayload(integrationType string, payload map[string]interface{}) []WebhookFieldError {
+	switch integrationType {
+	case "prometheus":
+		return validateAlertsArray(payload, "alerts", true)
+	case "grafana":
+		// Unified alerting sends an alerts array; legacy alerting sends a single rule
+		if _, ok := payload["alerts"]; ok {
+			return validateAlertsArray(payload, "alerts", false)
+		}
+		return requireOneOf(payload, "ruleName", "title")
+	case "datadog":
+		return requireOneOf(payload, "title", "alert_title")
+	case "aws":
+		// SNS wraps the CloudWatch alarm in Message; direct posts carry AlarmName
+		return requireOneOf(payload, "Message", "AlarmName")
+	case "github", "gitlab", "argocd":
+		// Deployment webhooks ignore events they don't understand
+		return nil
+	case "federation":
+		return requireOneOf(payload, "token")
+	default:
+		// Generic webhooks accept any JSON object: a missing name becomes
+		// "generic-alert" and an unknown status is treated as firing
+		return nil
+	}
+}
+
+// validateAlertsArray requires payload[key] to be a non-empty array of alert
+// objects; withAlertname also requires labels.alertname on every alert
+func validateAlertsArray(payload map[string]interface{}, key string, withAlertname bool) []WebhookFieldError {
+	raw, ok := payload[key]
+	if !ok {
+		return []WebhookFieldError{{Field: key, Message: "is required"}}
+	}
+	alerts, ok := raw.([]interface{})
+	if !ok {
+		return []WebhookFieldError{{Field: key, Message: "must be an array"}}
+	}
+	if len(alerts) == 0 {
+		return []WebhookFieldError{{Field: key, Message: "must contain at least one alert"}}
+	}
+
+	var errs []WebhookFieldError
+	for i, item := range alerts {
+		prefix := fmt.Sprintf("%s[%d]", key, i)
+		alert, ok := item.(map[string]interface{})
+		if !ok {
+			errs = append(errs, WebhookFieldError{Field: prefix, Message: "must be an object"})
+			continue
+		}
+		if withAlertname {
+			labels, ok := alert["labels"].(map[string]interface{})
+			if !ok {
+				errs = append(errs, WebhookFieldError{Field: prefix + ".labels", Message: "must be an object"})
+			} else if name, _ := labels["alertname"].(string); strings.TrimSpace(name) == "" {
+				errs = append(errs, WebhookFieldError{Field: prefix + ".labels.alertname", Message: "is required"})
+			}
+		}
+		for _, fe := range checkAlertStatus(alert, "status") {
+			fe.Field = prefix + "." + fe.Field
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// checkAlertStatus validates an optional status field
+func checkAlertStatus(m map[string]interface{}, key string) []WebhookFieldError {
+	raw, ok := m[key]
+	if !ok || raw == nil {
+		return nil
+	}
+	status, ok := raw.(string)
+	if !ok {
+		return []WebhookFieldError{{Field: key, Message: "must be a string"}}
+	}
+	if status != "" && status != "firing" && status != "resolved" {
+		return []WebhookFieldError{{Field: key, Message: fmt.Sprintf("must be \"firing\" or \"resolved\", got %q", status)}}
+	}
+	return nil
+}
+
+func requireString(m map[string]interface{}, key string) []WebhookFieldError {
+	raw, ok := m[key]
+	if !ok || raw == nil {
+		return []WebhookFieldError{{Field: key, Message: "is required"}}
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return []WebhookFieldError{{Field: key, Message: "must be a string"}}
+	}
+	if strings.TrimSpace(s) == "" {
+		return []WebhookFieldError{{Field: key, Message: "must not be empty"}}
+	}
+	return nil
+}
+
+// requireOneOf requires at least one of the keys to be a non-empty string
+func requireOneOf(m map[string]interface{}, keys ...string) []WebhookFieldError {
+	for _, key := range keys {
+		if requireString(m, key) == nil {
+			return nil
+		}
+	}
+	return []WebhookFieldError{{Field: strings.Join(keys, "|"), Message: "one of these fields is required"}}
+}
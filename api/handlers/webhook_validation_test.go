@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestValidateWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name            string
+		integrationType string
+		payload         string
+		wantFields      []string
+	}{
+		{
+			name:            "Prometheus valid",
+			integrationType: "prometheus",
+			payload:         `{"alerts": [{"status": "firing", "labels": {"alertname": "HighCPU"}}]}`,
+		},
+		{
+			name:            "Prometheus missing alerts",
+			integrationType: "prometheus",
+			payload:         `{"status": "firing"}`,
+			wantFields:      []string{"alerts"},
+		},
+		{
+			name:            "Prometheus empty alerts",
+			integrationType: "prometheus",
+			payload:         `{"alerts": []}`,
+			wantFields:      []string{"alerts"},
+		},
+		{
+			name:            "Prometheus alert without alertname and bad status",
+			integrationType: "prometheus",
+			payload:         `{"alerts": [{"labels": {"alertname": "ok"}}, {"status": "pending", "labels": {}}, "x"]}`,
+			wantFields:      []string{"alerts[1].labels.alertname", "alerts[1].status", "alerts[2]"},
+		},
+		{
+			name:            "Grafana legacy valid",
+			integrationType: "grafana",
+			payload:         `{"ruleName": "Disk full", "state": "alerting"}`,
+		},
+		{
+			name:            "Grafana unified with empty alerts",
+			integrationType: "grafana",
+			payload:         `{"alerts": []}`,
+			wantFields:      []string{"alerts"},
+		},
+		{
+			name:            "Datadog missing title",
+			integrationType: "datadog",
+			payload:         `{"alert_priority": "P1"}`,
+			wantFields:      []string{"title|alert_title"},
+		},
+		{
+			name:            "AWS SNS valid",
+			integrationType: "aws",
+			payload:         `{"Type": "Notification", "Message": "{\"AlarmName\":\"cpu\"}"}`,
+		},
+		{
+			name:            "AWS empty",
+			integrationType: "aws",
+			payload:         `{"Type": "Notification"}`,
+			wantFields:      []string{"Message|AlarmName"},
+		},
+		{
+			name:            "Generic valid",
+			integrationType: "webhook",
+			payload:         `{"alert_name": "Queue backlog", "status": "resolved"}`,
+		},
+		{
+			name:            "Generic missing name and unknown status",
+			integrationType: "webhook",
+			payload:         `{"severity": "critical", "status": "ok"}`,
+		},
+		{
+			name:            "Generic non-string alert_name",
+			integrationType: "custom",
+			payload:         `{"alert_name": 42}`,
+		},
+		{
+			name:            "Deploy integrations are not validated",
+			integrationType: "github",
+			payload:         `{"zen": "ping"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.payload), &payload); err != nil {
+				t.Fatalf("bad test payload: %v", err)
+			}
+
+			var gotFields []string
+			for _, fe := range validateWebhookPayload(tt.integrationType, payload) {
+				gotFields = append(gotFields, fe.Field)
+			}
+			if !reflect.DeepEqual(gotFields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", gotFields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestBindWebhookPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := config.Current().WebhookMaxBodyBytes
+	config.Update(func(c *config.Config) { c.WebhookMaxBodyBytes = 64 })
+	defer config.Update(func(c *config.Config) { c.WebhookMaxBodyBytes = prev })
+
+	h := &WebhookHandler{}
+	r := gin.New()
+	r.POST("/webhook/:integration_id", func(c *gin.Context) {
+		if _, ok := h.bindWebhookPayload(c, c.Param("integration_id"), webhookMaxBodyBytes()); ok {
+			c.Status(http.StatusOK)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"alert_name": "x"}`, http.StatusOK},
+		{"malformed", `{"alert_name":`, http.StatusBadRequest},
+		{"not an object", `null`, http.StatusBadRequest},
+		{"too large", `{"summary": "` + strings.Repeat("a", 128) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook/int-1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK && !strings.Contains(w.Body.String(), `"field":"body"`) {
+				t.Errorf("expected a body field error, got %s", w.Body.String())
+			}
+		})
+	}
+}
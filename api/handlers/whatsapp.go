@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/services"
+)
+
+// WhatsAppHandler handles WhatsApp notification consent and the WhatsApp
+// Business webhook
+type WhatsAppHandler struct {
+	WhatsAppService *services.WhatsAppService
+}
+
+// NewWhatsAppHandler creates a new WhatsAppHandler
+func NewWhatsAppHandler(whatsAppService *services.WhatsAppService) *WhatsAppHandler {
+	return &WhatsAppHandler{WhatsAppService: whatsAppService}
+}
+
+// GetWhatsAppConsent handles GET /users/me/whatsapp
+func (h *WhatsAppHandler) GetWhatsAppConsent(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	consent, err := h.WhatsAppService.GetConsent(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get WhatsApp consent: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available": h.WhatsAppService.IsConfigured(),
+		"consent":   consent,
+	})
+}
+
+// OptInWhatsApp handles POST /users/me/whatsapp/opt-in
+func (h *WhatsAppHandler) OptInWhatsApp(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req db.WhatsAppOptInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if !h.WhatsAppService.IsConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "WhatsApp notifications are not configured"})
+		return
+	}
+
+	consent, err := h.WhatsAppService.OptIn(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWhatsAppNumber) || errors.Is(err, services.ErrWhatsAppConsentRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to opt in to WhatsApp notifications: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consent": consent,
+		"message": "WhatsApp notifications enabled",
+	})
+}
+
+// OptOutWhatsApp handles POST /users/me/whatsapp/opt-out
+func (h *WhatsAppHandler) OptOutWhatsApp(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.WhatsAppService.OptOut(userID, db.WhatsAppSourceWeb); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to opt out of WhatsApp notifications: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "WhatsApp notifications disabled"})
+}
+
+// VerifyWebhook handles GET /whatsapp/webhook (public), Meta's subscription handshake
+func (h *WhatsAppHandler) VerifyWebhook(c *gin.Context) {
+	if !h.WhatsAppService.VerifyWebhookSubscription(c.Query("hub.mode"), c.Query("hub.verify_token")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid verify token"})
+		return
+	}
+	c.String(http.StatusOK, c.Query("hub.challenge"))
+}
+
+// ReceiveWebhook handles POST /whatsapp/webhook (public, signed with the app secret)
+func (h *WhatsAppHandler) ReceiveWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	if err := h.WhatsAppService.VerifyWebhookSignature(body, c.GetHeader("X-Hub-Signature-256")); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.WhatsAppService.HandleWebhook(body); err != nil {
+		log.Printf("WhatsApp webhook error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
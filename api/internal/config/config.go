@@ -1,11 +1,16 @@
 package config
 
 import (
+	"context"
 	"log"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
+	"github.com/vanchonlee/slar/internal/encryption"
 	"github.com/vanchonlee/slar/internal/logger"
 )
 
@@ -51,8 +56,102 @@ type Config struct {
 	SlackBotToken   string `mapstructure:"slack_bot_token"`
 	SlackAppToken   string `mapstructure:"slack_app_token"`
 
+	// Slack channel for notifications about test-mode incidents (empty = not sent)
+	SlackTestChannel string `mapstructure:"slack_test_channel"`
+
 	// AI Incident Analytics
 	AIIncidentAnalytics AIIncidentAnalyticsConfig `mapstructure:"ai_incident_analytics"`
+
+	// Default language model provider for AI features; organizations can override it
+	LLM LLMConfig `mapstructure:"llm"`
+
+	// Outbound email (group invitations, etc.)
+	SMTP SMTPConfig `mapstructure:"smtp"`
+
+	// WhatsApp Business (Cloud API) notifications
+	WhatsApp WhatsAppConfig `mapstructure:"whatsapp"`
+
+	// Web Push (VAPID) notifications to browsers
+	WebPush WebPushConfig `mapstructure:"web_push"`
+
+	// Incident deep links (/r/<short_id>) opening the mobile app when installed
+	DeepLinks DeepLinksConfig `mapstructure:"deep_links"`
+
+	// Twilio number engineers text ACK/RES commands to
+	Twilio TwilioConfig `mapstructure:"twilio"`
+
+	// Reverse proxies allowed to set X-Forwarded-For (CIDRs or IPs); used for webhook IP allowlists
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// Header a trusted platform sets to the client IP (e.g. CF-Connecting-IP,
+	// X-Real-IP); only read from trusted_proxies
+	ClientIPHeader string `mapstructure:"client_ip_header"`
+	// Path prefix the API is served under behind a gateway (e.g. /slar/api)
+	BasePath string `mapstructure:"base_path"`
+	// Date (YYYY-MM-DD) the unversioned API paths, aliases of /v1, are due to
+	// be removed; sent as Sunset on their responses when set
+	UnversionedAPISunset string `mapstructure:"unversioned_api_sunset"`
+
+	// Browser origins allowed to call the API, and CSRF checks for cookies
+	CORS CORSConfig `mapstructure:"cors"`
+
+	// Maximum accepted body size for inbound alert webhooks
+	WebhookMaxBodyBytes int64 `mapstructure:"webhook_max_body_bytes"`
+
+	// Column encryption keys ("id1:base64key1,id2:base64key2") and the key ID used for new writes
+	EncryptionKeys      string `mapstructure:"encryption_keys"`
+	EncryptionActiveKey string `mapstructure:"encryption_active_key"`
+
+	// How often vault://, aws-sm:// and gcp-sm:// references are re-resolved (0 disables)
+	SecretsRefreshInterval time.Duration `mapstructure:"secrets_refresh_interval"`
+
+	// How long past its timeout an escalation may sit before the watchdog re-drives it
+	EscalationWatchdogGrace time.Duration `mapstructure:"escalation_watchdog_grace"`
+
+	// How long diagnostic artifacts are kept on incidents unless they ask for less or more
+	IncidentArtifactRetention time.Duration `mapstructure:"incident_artifact_retention"`
+
+	// How long incident_events are kept; whole monthly partitions older than
+	// this are dropped (0 keeps everything)
+	IncidentEventRetention time.Duration `mapstructure:"incident_event_retention"`
+
+	// How long deleted services, schedulers, escalation policies and
+	// integrations stay restorable from the trash
+	TrashRetention time.Duration `mapstructure:"trash_retention"`
+
+	// How long a destructive admin change waits for a second admin's approval
+	// before it expires
+	PendingChangeTTL time.Duration `mapstructure:"pending_change_ttl"`
+
+	// Allows injecting database latency, pgmq failures and notification
+	// provider outages through /internal/faults, for resilience testing.
+	// Never enable it in production.
+	FaultInjectionEnabled bool `mapstructure:"fault_injection_enabled"`
+
+	// Bearer token the /internal/config bundle and promotion routes require;
+	// they are closed while it is empty
+	ConfigPromotionToken string `mapstructure:"config_promotion_token"`
+
+	// Bearer token internal callers (AI workers, the agent and operators) send
+	// to the /internal routes without a login, such as LLM completions,
+	// incident artifacts and config reload; they are closed while it is empty
+	InternalAPIToken string `mapstructure:"internal_api_token"`
+
+	// How many weeks of resolved incidents the recurring problems report clusters
+	RecurringProblemsWeeks int `mapstructure:"recurring_problems_weeks"`
+
+	// Rate limits given to new API keys that don't set their own
+	APIKeyRateLimitPerHour int `mapstructure:"api_key_rate_limit_per_hour"`
+	APIKeyRateLimitPerDay  int `mapstructure:"api_key_rate_limit_per_day"`
+
+	// Feature flags forced on ("name") or off ("-name") for every org,
+	// checked with FeatureEnabled / FeatureOverride
+	FeatureFlags []string `mapstructure:"feature_flags"`
+
+	// Push SLAR's own operational metrics to Datadog or a Prometheus remote-write endpoint
+	MetricsExport MetricsExportConfig `mapstructure:"metrics_export"`
+
+	// Shed and refuse alert webhooks while the notification queues are backed up
+	IngestBackpressure IngestBackpressureConfig `mapstructure:"ingest_backpressure"`
 }
 
 type NotificationGatewayConfig struct {
@@ -61,6 +160,80 @@ type NotificationGatewayConfig struct {
 	APIToken   string `mapstructure:"api_token"`
 }
 
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type WhatsAppConfig struct {
+	PhoneNumberID    string `mapstructure:"phone_number_id"`
+	AccessToken      string `mapstructure:"access_token"`
+	AppSecret        string `mapstructure:"app_secret"`        // verifies inbound webhook signatures
+	VerifyToken      string `mapstructure:"verify_token"`      // webhook subscription handshake
+	PageTemplate     string `mapstructure:"page_template"`     // approved template used for pages
+	TemplateLanguage string `mapstructure:"template_language"` // language code of the page template
+}
+
+type TwilioConfig struct {
+	AccountSID  string `mapstructure:"account_sid"`
+	AuthToken   string `mapstructure:"auth_token"`   // verifies X-Twilio-Signature on inbound webhooks
+	PhoneNumber string `mapstructure:"phone_number"` // the number users text, in E.164 form
+	WebhookURL  string `mapstructure:"webhook_url"`  // public URL Twilio posts to, as configured in the console
+}
+
+type CORSConfig struct {
+	// Exact origins ("https://slar.example.com"), wildcard subdomains
+	// ("https://*.example.com") or "*" for any origin
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // never sent for "*"
+	MaxAge           time.Duration `mapstructure:"max_age"`           // how long browsers cache preflights
+}
+
+type WebPushConfig struct {
+	VAPIDPublicKey  string `mapstructure:"vapid_public_key"`  // base64url uncompressed P-256 point
+	VAPIDPrivateKey string `mapstructure:"vapid_private_key"` // base64url 32-byte scalar
+	Subject         string `mapstructure:"subject"`           // mailto: or https: contact for push services
+}
+
+type DeepLinksConfig struct {
+	BaseURL                 string   `mapstructure:"base_url"`                  // host serving /r/ and /.well-known; defaults to public_url + base_path
+	IOSAppIDs               []string `mapstructure:"ios_app_ids"`               // "<team ID>.<bundle ID>" for universal links
+	AndroidPackage          string   `mapstructure:"android_package"`           // package name for app links
+	AndroidCertFingerprints []string `mapstructure:"android_cert_fingerprints"` // SHA-256 fingerprints of the app signing certificates
+	AppScheme               string   `mapstructure:"app_scheme"`                // custom URL scheme the app registers
+}
+
+type MetricsExportConfig struct {
+	Provider string            `mapstructure:"provider"` // "datadog" or "prometheus_remote_write"; empty disables
+	Interval time.Duration     `mapstructure:"interval"` // how often metrics are pushed
+	Tags     map[string]string `mapstructure:"tags"`     // added to every series, e.g. env: prod
+
+	DatadogAPIKey string `mapstructure:"datadog_api_key"`
+	DatadogSite   string `mapstructure:"datadog_site"` // e.g. datadoghq.com, datadoghq.eu
+
+	RemoteWriteURL         string `mapstructure:"remote_write_url"`
+	RemoteWriteUsername    string `mapstructure:"remote_write_username"` // basic auth, e.g. Grafana Cloud instance ID
+	RemoteWritePassword    string `mapstructure:"remote_write_password"`
+	RemoteWriteBearerToken string `mapstructure:"remote_write_bearer_token"`
+}
+
+// IngestBackpressureConfig degrades webhook ingestion gracefully under a
+// flood. Once the notification queues hold ShedQueueDepth messages, firing
+// alerts with the lowest of ShedSeverities are dropped, more of them as the
+// depth grows; at RejectQueueDepth every webhook gets a 429. A zero depth
+// disables that stage.
+type IngestBackpressureConfig struct {
+	ShedQueueDepth   int           `mapstructure:"shed_queue_depth"`
+	RejectQueueDepth int           `mapstructure:"reject_queue_depth"`
+	ShedSeverities   []string      `mapstructure:"shed_severities"` // lowest first, shed in this order
+	MaxInFlight      int           `mapstructure:"max_in_flight"`   // webhooks processed at once; 0 = unlimited
+	RetryAfter       time.Duration `mapstructure:"retry_after"`     // sent to refused senders
+	SampleInterval   time.Duration `mapstructure:"sample_interval"` // how often queue depths are read
+}
+
 type AIIncidentAnalyticsConfig struct {
 	Enabled        bool     `mapstructure:"enabled"`
 	Model          string   `mapstructure:"model"`
@@ -69,8 +242,44 @@ type AIIncidentAnalyticsConfig struct {
 	AllowedTools   []string `mapstructure:"allowed_tools"`
 }
 
-// App holds the global config instance
-var App Config
+// LLMConfig is the language model provider used by organizations that don't
+// configure their own
+type LLMConfig struct {
+	Provider   string `mapstructure:"provider"` // openai, anthropic, azure_openai, ollama
+	Model      string `mapstructure:"model"`    // Azure OpenAI: the deployment name
+	APIKey     string `mapstructure:"api_key"`
+	BaseURL    string `mapstructure:"base_url"`    // provider default when empty
+	APIVersion string `mapstructure:"api_version"` // Azure OpenAI only
+
+	// Tokens each organization may use per calendar month (UTC) unless it
+	// sets its own budget; 0 means unlimited
+	MonthlyTokenBudget int64 `mapstructure:"monthly_token_budget"`
+}
+
+var (
+	current  atomic.Pointer[Config]
+	updateMu sync.Mutex
+)
+
+func init() {
+	current.Store(&Config{})
+}
+
+// Current returns the running config. Reloads and secret rotation swap in a
+// new copy instead of editing it, so the returned value must be treated as
+// read-only.
+func Current() *Config {
+	return current.Load()
+}
+
+// Update applies fn to a copy of the running config and swaps the copy in
+func Update(fn func(*Config)) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	next := *current.Load()
+	fn(&next)
+	current.Store(&next)
+}
 
 // LoadConfig loads configuration from file and environment variables
 func LoadConfig(path string) error {
@@ -83,6 +292,87 @@ func LoadConfig(path string) error {
 		log.Println("✅ Loaded .env file")
 	}
 
+	v := newViper(path)
+	configPath = path
+
+	// 1. Read config file
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			log.Println("ℹ️  No config file found, using defaults and environment variables")
+		} else {
+			return err
+		}
+	} else {
+		log.Printf("✅ Loaded config from: %s", v.ConfigFileUsed())
+	}
+
+	// 2. Resolve secret references (vault://, aws-sm://, gcp-sm://) and unmarshal into struct
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resetSecretBindings()
+	if err := resolveEnvSecrets(ctx); err != nil {
+		return err
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return err
+	}
+	if err := resolveConfigSecrets(ctx, &cfg); err != nil {
+		return err
+	}
+	Update(func(c *Config) { *c = cfg })
+	if err := encryption.Configure(cfg.EncryptionKeys, cfg.EncryptionActiveKey); err != nil {
+		return err
+	}
+	if !encryption.Default().Enabled() {
+		log.Println("ℹ️  ENCRYPTION_KEYS not set, sensitive columns are stored in plaintext")
+	}
+
+	// 3. Initialize logger with configured level
+	logger.SetLevelString(cfg.LogLevel)
+	log.Printf("✅ Log level set to: %s", logger.GetLevelString())
+	markLoaded()
+
+	// 3. Backfill environment variables for legacy code compatibility
+	// Many existing services (FCM, Router, etc.) still use os.Getenv()
+	// This ensures they work without refactoring the entire codebase immediately.
+	setEnvIfEmpty("DATABASE_URL", cfg.DatabaseURL)
+	setEnvIfEmpty("PORT", cfg.Port)
+
+	// OIDC
+	setEnvIfEmpty("OIDC_ISSUER", cfg.OIDCIssuer)
+	setEnvIfEmpty("OIDC_CLIENT_ID", cfg.OIDCClientID)
+	setEnvIfEmpty("OIDC_WEB_CLIENT_ID", cfg.OIDCWebClientID)
+	setEnvIfEmpty("OIDC_MOBILE_CLIENT_ID", cfg.OIDCMobileClientID)
+	setEnvIfEmpty("SESSION_SECRET", cfg.SessionSecret)
+
+	// Supabase (Deprecated - for migration period only)
+	setEnvIfEmpty("SUPABASE_URL", cfg.SupabaseURL)
+	setEnvIfEmpty("MOBILE_SUPABASE_URL", cfg.MobileSupabaseURL)
+	setEnvIfEmpty("AGENT_URL", cfg.AgentURL)
+	setEnvIfEmpty("SUPABASE_ANON_KEY", cfg.SupabaseAnonKey)
+	setEnvIfEmpty("SUPABASE_SERVICE_ROLE_KEY", cfg.SupabaseServiceRoleKey)
+	setEnvIfEmpty("SUPABASE_JWT_SECRET", cfg.SupabaseJWTSecret)
+
+	setEnvIfEmpty("SLAR_CLOUD_URL", cfg.NotificationGatewayDetails.URL)
+	setEnvIfEmpty("SLAR_INSTANCE_ID", cfg.NotificationGatewayDetails.InstanceID)
+	setEnvIfEmpty("SLAR_CLOUD_TOKEN", cfg.NotificationGatewayDetails.APIToken)
+
+	setEnvIfEmpty("ANTHROPIC_API_KEY", cfg.AnthropicAPIKey)
+	setEnvIfEmpty("SLACK_BOT_TOKEN", cfg.SlackBotToken)
+	setEnvIfEmpty("SLACK_APP_TOKEN", cfg.SlackAppToken)
+
+	setEnvIfEmpty("SLAR_PUBLIC_URL", cfg.PublicURL)
+	setEnvIfEmpty("SLAR_AGENT_URL", cfg.AgentURL)
+	setEnvIfEmpty("SLAR_BACKEND_URL", cfg.BackendURL)
+	setEnvIfEmpty("WEBHOOK_API_BASE_URL", cfg.WebhookAPIBaseURL)
+	setEnvIfEmpty("SLAR_DATA_DIR", cfg.DataDir)
+
+	return nil
+}
+
+// newViper sets up defaults, the config file location and env bindings
+func newViper(path string) *viper.Viper {
 	v := viper.New()
 
 	// Set default values
@@ -105,108 +395,191 @@ func LoadConfig(path string) error {
 	v.SetDefault("backend_url", "http://localhost:8080")
 	v.SetDefault("data_dir", "./data")
 	v.SetDefault("log_level", "INFO")
-	v.BindEnv("log_level", "LOG_LEVEL")
+	bindEnv(v, "log_level", "LOG_LEVEL")
 
 	// Bind standard environment variables (Docker/deploy compatibility)
 	// This allows using standard keys like DATABASE_URL instead of SLAR_DATABASE_URL
-	v.BindEnv("database_url", "DATABASE_URL")
-	v.BindEnv("port", "PORT")
+	bindEnv(v, "database_url", "DATABASE_URL")
+	bindEnv(v, "port", "PORT")
 
 	// Bind OIDC Env Vars
-	v.BindEnv("oidc_issuer", "OIDC_ISSUER")
-	v.BindEnv("oidc_client_id", "OIDC_CLIENT_ID")
-	v.BindEnv("oidc_web_client_id", "OIDC_WEB_CLIENT_ID")
-	v.BindEnv("oidc_mobile_client_id", "OIDC_MOBILE_CLIENT_ID")
+	bindEnv(v, "oidc_issuer", "OIDC_ISSUER")
+	bindEnv(v, "oidc_client_id", "OIDC_CLIENT_ID")
+	bindEnv(v, "oidc_web_client_id", "OIDC_WEB_CLIENT_ID")
+	bindEnv(v, "oidc_mobile_client_id", "OIDC_MOBILE_CLIENT_ID")
 
 	// Bind Session Token Env Vars
-	v.BindEnv("session_secret", "SESSION_SECRET")
+	bindEnv(v, "session_secret", "SESSION_SECRET")
 
 	// Bind Supabase Env Vars (Deprecated - for migration period only)
-	v.BindEnv("supabase_url", "SUPABASE_URL")
-	v.BindEnv("mobile_supabase_url", "MOBILE_SUPABASE_URL")
-	v.BindEnv("supabase_anon_key", "SUPABASE_ANON_KEY")
-	v.BindEnv("supabase_service_role_key", "SUPABASE_SERVICE_ROLE_KEY")
-	v.BindEnv("supabase_jwt_secret", "SUPABASE_JWT_SECRET")
+	bindEnv(v, "supabase_url", "SUPABASE_URL")
+	bindEnv(v, "mobile_supabase_url", "MOBILE_SUPABASE_URL")
+	bindEnv(v, "supabase_anon_key", "SUPABASE_ANON_KEY")
+	bindEnv(v, "supabase_service_role_key", "SUPABASE_SERVICE_ROLE_KEY")
+	bindEnv(v, "supabase_jwt_secret", "SUPABASE_JWT_SECRET")
 
 	// Bind External Services Env Vars
-	v.BindEnv("anthropic_api_key", "ANTHROPIC_API_KEY")
-	v.BindEnv("slack_bot_token", "SLACK_BOT_TOKEN")
-	v.BindEnv("slack_app_token", "SLACK_APP_TOKEN")
+	bindEnv(v, "anthropic_api_key", "ANTHROPIC_API_KEY")
+	bindEnv(v, "slack_bot_token", "SLACK_BOT_TOKEN")
+	bindEnv(v, "slack_app_token", "SLACK_APP_TOKEN")
+	bindEnv(v, "slack_test_channel", "SLACK_TEST_CHANNEL")
 
 	// Bind Notification Gateway Env Vars
-	v.BindEnv("notification_gateway.url", "SLAR_CLOUD_URL")
-	v.BindEnv("notification_gateway.api_token", "SLAR_CLOUD_TOKEN")
-	v.BindEnv("notification_gateway.instance_id", "SLAR_INSTANCE_ID")
-	v.BindEnv("webhook_api_base_url", "WEBHOOK_API_BASE_URL")
+	bindEnv(v, "notification_gateway.url", "SLAR_CLOUD_URL")
+	bindEnv(v, "notification_gateway.api_token", "SLAR_CLOUD_TOKEN")
+	bindEnv(v, "notification_gateway.instance_id", "SLAR_INSTANCE_ID")
+	bindEnv(v, "webhook_api_base_url", "WEBHOOK_API_BASE_URL")
 
 	// Bind AI Incident Analytics Env Vars
-	v.BindEnv("ai_incident_analytics.enabled", "AI_PILOT_ENABLED")
-	v.BindEnv("ai_incident_analytics.model", "AI_PILOT_MODEL")
+	bindEnv(v, "ai_incident_analytics.enabled", "AI_PILOT_ENABLED")
+	bindEnv(v, "ai_incident_analytics.model", "AI_PILOT_MODEL")
+
+	// Bind LLM provider Env Vars
+	bindEnv(v, "llm.provider", "LLM_PROVIDER")
+	bindEnv(v, "llm.model", "LLM_MODEL")
+	bindEnv(v, "llm.api_key", "LLM_API_KEY")
+	bindEnv(v, "llm.base_url", "LLM_BASE_URL")
+	bindEnv(v, "llm.api_version", "LLM_API_VERSION")
+	bindEnv(v, "llm.monthly_token_budget", "LLM_MONTHLY_TOKEN_BUDGET")
+
+	// Bind SMTP Env Vars
+	bindEnv(v, "smtp.host", "SMTP_HOST")
+	bindEnv(v, "smtp.port", "SMTP_PORT")
+	bindEnv(v, "smtp.username", "SMTP_USERNAME")
+	bindEnv(v, "smtp.password", "SMTP_PASSWORD")
+	bindEnv(v, "smtp.from", "SMTP_FROM")
+	v.SetDefault("smtp.port", 587)
+
+	// Bind WhatsApp Business Env Vars
+	bindEnv(v, "whatsapp.phone_number_id", "WHATSAPP_PHONE_NUMBER_ID")
+	bindEnv(v, "whatsapp.access_token", "WHATSAPP_ACCESS_TOKEN")
+	bindEnv(v, "whatsapp.app_secret", "WHATSAPP_APP_SECRET")
+	bindEnv(v, "whatsapp.verify_token", "WHATSAPP_VERIFY_TOKEN")
+	bindEnv(v, "whatsapp.page_template", "WHATSAPP_PAGE_TEMPLATE")
+	bindEnv(v, "whatsapp.template_language", "WHATSAPP_TEMPLATE_LANGUAGE")
+	v.SetDefault("whatsapp.page_template", "incident_page")
+	v.SetDefault("whatsapp.template_language", "en")
+
+	// Bind Web Push Env Vars
+	bindEnv(v, "web_push.vapid_public_key", "WEB_PUSH_VAPID_PUBLIC_KEY")
+	bindEnv(v, "web_push.vapid_private_key", "WEB_PUSH_VAPID_PRIVATE_KEY")
+	bindEnv(v, "web_push.subject", "WEB_PUSH_SUBJECT")
+
+	// Bind Deep Link Env Vars (lists comma-separated)
+	bindEnv(v, "deep_links.base_url", "DEEP_LINKS_BASE_URL")
+	bindEnv(v, "deep_links.ios_app_ids", "DEEP_LINKS_IOS_APP_IDS")
+	bindEnv(v, "deep_links.android_package", "DEEP_LINKS_ANDROID_PACKAGE")
+	bindEnv(v, "deep_links.android_cert_fingerprints", "DEEP_LINKS_ANDROID_CERT_FINGERPRINTS")
+	bindEnv(v, "deep_links.app_scheme", "DEEP_LINKS_APP_SCHEME")
+	v.SetDefault("deep_links.app_scheme", "slar")
+
+	// Bind Twilio Env Vars
+	bindEnv(v, "twilio.account_sid", "TWILIO_ACCOUNT_SID")
+	bindEnv(v, "twilio.auth_token", "TWILIO_AUTH_TOKEN")
+	bindEnv(v, "twilio.phone_number", "TWILIO_PHONE_NUMBER")
+	bindEnv(v, "twilio.webhook_url", "TWILIO_WEBHOOK_URL")
 
 	// Bind Auto Migration Env Var
-	v.BindEnv("auto_migrate", "AUTO_MIGRATE")
+	bindEnv(v, "auto_migrate", "AUTO_MIGRATE")
 	v.SetDefault("auto_migrate", false)
-	v.BindEnv("migrate_baseline", "MIGRATE_BASELINE")
+	bindEnv(v, "migrate_baseline", "MIGRATE_BASELINE")
 	v.SetDefault("migrate_baseline", false)
 
-	v.AutomaticEnv()
+	// Bind Trusted Proxies Env Var (comma-separated)
+	bindEnv(v, "trusted_proxies", "TRUSTED_PROXIES")
+	bindEnv(v, "client_ip_header", "CLIENT_IP_HEADER")
+	bindEnv(v, "base_path", "BASE_PATH")
+	bindEnv(v, "unversioned_api_sunset", "UNVERSIONED_API_SUNSET")
+
+	// CORS: "*" keeps the API open to any origin; list origins before exposing it publicly
+	bindEnv(v, "cors.allowed_origins", "CORS_ALLOWED_ORIGINS")
+	bindEnv(v, "cors.allow_credentials", "CORS_ALLOW_CREDENTIALS")
+	bindEnv(v, "cors.max_age", "CORS_MAX_AGE")
+	v.SetDefault("cors.allowed_origins", []string{"*"})
+	v.SetDefault("cors.max_age", "10m")
+
+	// Inbound webhook body limit (1 MiB)
+	bindEnv(v, "webhook_max_body_bytes", "WEBHOOK_MAX_BODY_BYTES")
+	v.SetDefault("webhook_max_body_bytes", 1<<20)
+
+	// Bind Column Encryption Env Vars
+	bindEnv(v, "encryption_keys", "ENCRYPTION_KEYS")
+	bindEnv(v, "encryption_active_key", "ENCRYPTION_ACTIVE_KEY")
+
+	// Secret references refresh interval
+	bindEnv(v, "secrets_refresh_interval", "SECRETS_REFRESH_INTERVAL")
+	v.SetDefault("secrets_refresh_interval", "15m")
+
+	// Escalation watchdog grace period
+	bindEnv(v, "escalation_watchdog_grace", "ESCALATION_WATCHDOG_GRACE")
+	v.SetDefault("escalation_watchdog_grace", "2m")
+
+	// Incident artifact retention (90 days)
+	bindEnv(v, "incident_artifact_retention", "INCIDENT_ARTIFACT_RETENTION")
+	v.SetDefault("incident_artifact_retention", "2160h")
+
+	// Incident event retention (0 keeps every month)
+	bindEnv(v, "incident_event_retention", "INCIDENT_EVENT_RETENTION")
+	v.SetDefault("incident_event_retention", "0")
+
+	// Trash retention (30 days)
+	bindEnv(v, "trash_retention", "TRASH_RETENTION")
+	v.SetDefault("trash_retention", "720h")
+
+	// Pending admin change approval window (1 day)
+	bindEnv(v, "pending_change_ttl", "PENDING_CHANGE_TTL")
+	v.SetDefault("pending_change_ttl", "24h")
+
+	// Fault injection for resilience testing (off)
+	bindEnv(v, "fault_injection_enabled", "FAULT_INJECTION_ENABLED")
+	v.SetDefault("fault_injection_enabled", false)
+
+	// Config promotion token (empty closes the promotion routes)
+	bindEnv(v, "config_promotion_token", "CONFIG_PROMOTION_TOKEN")
+
+	// Internal service token (empty closes the internal LLM and artifact routes)
+	bindEnv(v, "internal_api_token", "INTERNAL_API_TOKEN")
+
+	bindEnv(v, "recurring_problems_weeks", "RECURRING_PROBLEMS_WEEKS")
+	v.SetDefault("recurring_problems_weeks", 4)
+
+	// Default API key rate limits
+	bindEnv(v, "api_key_rate_limit_per_hour", "API_KEY_RATE_LIMIT_PER_HOUR")
+	v.SetDefault("api_key_rate_limit_per_hour", 1000)
+	bindEnv(v, "api_key_rate_limit_per_day", "API_KEY_RATE_LIMIT_PER_DAY")
+	v.SetDefault("api_key_rate_limit_per_day", 10000)
+
+	// Bind Feature Flags Env Var (comma-separated)
+	bindEnv(v, "feature_flags", "FEATURE_FLAGS")
+
+	// Bind Metrics Export Env Vars
+	bindEnv(v, "metrics_export.provider", "METRICS_EXPORT_PROVIDER")
+	bindEnv(v, "metrics_export.interval", "METRICS_EXPORT_INTERVAL")
+	bindEnv(v, "metrics_export.datadog_api_key", "DD_API_KEY")
+	bindEnv(v, "metrics_export.datadog_site", "DD_SITE")
+	bindEnv(v, "metrics_export.remote_write_url", "PROMETHEUS_REMOTE_WRITE_URL")
+	bindEnv(v, "metrics_export.remote_write_username", "PROMETHEUS_REMOTE_WRITE_USERNAME")
+	bindEnv(v, "metrics_export.remote_write_password", "PROMETHEUS_REMOTE_WRITE_PASSWORD")
+	bindEnv(v, "metrics_export.remote_write_bearer_token", "PROMETHEUS_REMOTE_WRITE_BEARER_TOKEN")
+	v.SetDefault("metrics_export.interval", "1m")
+	v.SetDefault("metrics_export.datadog_site", "datadoghq.com")
+
+	// Ingest backpressure
+	bindEnv(v, "ingest_backpressure.shed_queue_depth", "INGEST_SHED_QUEUE_DEPTH")
+	bindEnv(v, "ingest_backpressure.reject_queue_depth", "INGEST_REJECT_QUEUE_DEPTH")
+	bindEnv(v, "ingest_backpressure.shed_severities", "INGEST_SHED_SEVERITIES")
+	bindEnv(v, "ingest_backpressure.max_in_flight", "INGEST_MAX_IN_FLIGHT")
+	bindEnv(v, "ingest_backpressure.retry_after", "INGEST_RETRY_AFTER")
+	bindEnv(v, "ingest_backpressure.sample_interval", "INGEST_SAMPLE_INTERVAL")
+	v.SetDefault("ingest_backpressure.shed_queue_depth", 5000)
+	v.SetDefault("ingest_backpressure.reject_queue_depth", 20000)
+	v.SetDefault("ingest_backpressure.shed_severities", []string{"info", "low", "warning"})
+	v.SetDefault("ingest_backpressure.max_in_flight", 500)
+	v.SetDefault("ingest_backpressure.retry_after", "30s")
+	v.SetDefault("ingest_backpressure.sample_interval", "5s")
 
-	// 1. Read config file
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Println("ℹ️  No config file found, using defaults and environment variables")
-		} else {
-			return err
-		}
-	} else {
-		log.Printf("✅ Loaded config from: %s", v.ConfigFileUsed())
-	}
-
-	// 2. Unmarshal into struct
-	if err := v.Unmarshal(&App); err != nil {
-		return err
-	}
-
-	// 3. Initialize logger with configured level
-	logger.SetLevelString(App.LogLevel)
-	log.Printf("✅ Log level set to: %s", logger.GetLevelString())
-
-	// 3. Backfill environment variables for legacy code compatibility
-	// Many existing services (FCM, Router, etc.) still use os.Getenv()
-	// This ensures they work without refactoring the entire codebase immediately.
-	setEnvIfEmpty("DATABASE_URL", App.DatabaseURL)
-	setEnvIfEmpty("PORT", App.Port)
-
-	// OIDC
-	setEnvIfEmpty("OIDC_ISSUER", App.OIDCIssuer)
-	setEnvIfEmpty("OIDC_CLIENT_ID", App.OIDCClientID)
-	setEnvIfEmpty("OIDC_WEB_CLIENT_ID", App.OIDCWebClientID)
-	setEnvIfEmpty("OIDC_MOBILE_CLIENT_ID", App.OIDCMobileClientID)
-	setEnvIfEmpty("SESSION_SECRET", App.SessionSecret)
-
-	// Supabase (Deprecated - for migration period only)
-	setEnvIfEmpty("SUPABASE_URL", App.SupabaseURL)
-	setEnvIfEmpty("MOBILE_SUPABASE_URL", App.MobileSupabaseURL)
-	setEnvIfEmpty("AGENT_URL", App.AgentURL)
-	setEnvIfEmpty("SUPABASE_ANON_KEY", App.SupabaseAnonKey)
-	setEnvIfEmpty("SUPABASE_SERVICE_ROLE_KEY", App.SupabaseServiceRoleKey)
-	setEnvIfEmpty("SUPABASE_JWT_SECRET", App.SupabaseJWTSecret)
-
-	setEnvIfEmpty("SLAR_CLOUD_URL", App.NotificationGatewayDetails.URL)
-	setEnvIfEmpty("SLAR_INSTANCE_ID", App.NotificationGatewayDetails.InstanceID)
-	setEnvIfEmpty("SLAR_CLOUD_TOKEN", App.NotificationGatewayDetails.APIToken)
-
-	setEnvIfEmpty("ANTHROPIC_API_KEY", App.AnthropicAPIKey)
-	setEnvIfEmpty("SLACK_BOT_TOKEN", App.SlackBotToken)
-	setEnvIfEmpty("SLACK_APP_TOKEN", App.SlackAppToken)
-
-	setEnvIfEmpty("SLAR_PUBLIC_URL", App.PublicURL)
-	setEnvIfEmpty("SLAR_AGENT_URL", App.AgentURL)
-	setEnvIfEmpty("SLAR_BACKEND_URL", App.BackendURL)
-	setEnvIfEmpty("WEBHOOK_API_BASE_URL", App.WebhookAPIBaseURL)
-	setEnvIfEmpty("SLAR_DATA_DIR", App.DataDir)
-
-	return nil
+	v.AutomaticEnv()
+	return v
 }
 
 func setEnvIfEmpty(key, value string) {
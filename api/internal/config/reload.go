@@ -0,0 +1,260 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/vanchonlee/slar/internal/logger"
+	"github.com/vanchonlee/slar/internal/secrets"
+)
+
+// reloadableKeys are the settings Reload applies to a running process. They
+// are read on use rather than captured at startup, so a new value takes
+// effect without a restart. Connection settings (database, OIDC, SMTP, ...)
+// are deliberately not listed.
+var reloadableKeys = []string{
+	"log_level",
+	"feature_flags",
+	"ai_incident_analytics.enabled",
+	"ai_incident_analytics.model",
+	"slack_test_channel",
+	"whatsapp.page_template",
+	"whatsapp.template_language",
+	"webhook_max_body_bytes",
+	"api_key_rate_limit_per_hour",
+	"api_key_rate_limit_per_day",
+	"escalation_watchdog_grace",
+	"incident_artifact_retention",
+	"incident_event_retention",
+	"trash_retention",
+	"pending_change_ttl",
+	"unversioned_api_sunset",
+	"recurring_problems_weeks",
+	"cors.allowed_origins",
+	"cors.allow_credentials",
+	"cors.max_age",
+	"ingest_backpressure.shed_queue_depth",
+	"ingest_backpressure.reject_queue_depth",
+	"ingest_backpressure.shed_severities",
+	"ingest_backpressure.max_in_flight",
+	"ingest_backpressure.retry_after",
+}
+
+var (
+	reloadMu   sync.Mutex
+	configPath string
+
+	versionMu sync.RWMutex
+	version   string
+	loadedAt  time.Time
+)
+
+// ReloadResult reports what a reload changed
+type ReloadResult struct {
+	Version  string    `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Changed  []string  `json:"changed"`
+	// Settings that differ from the running values but need a restart
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// Version returns a short hash of the reloadable settings and when they were
+// last loaded. Replicas serving the same config report the same version.
+func Version() (string, time.Time) {
+	versionMu.RLock()
+	defer versionMu.RUnlock()
+	return version, loadedAt
+}
+
+// FeatureEnabled reports whether a feature flag is forced on in feature_flags
+func FeatureEnabled(name string) bool {
+	enabled, ok := FeatureOverride(name)
+	return ok && enabled
+}
+
+// FeatureOverride reports whether feature_flags forces a flag on ("name") or
+// off ("-name"). ok is false when the flag isn't listed.
+func FeatureOverride(name string) (enabled, ok bool) {
+	for _, flag := range Current().FeatureFlags {
+		flag = strings.TrimSpace(flag)
+		if strings.EqualFold(flag, name) {
+			return true, true
+		}
+		if strings.HasPrefix(flag, "-") && strings.EqualFold(flag[1:], name) {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// markLoaded records the version of the running settings
+func markLoaded() {
+	values := make(map[string]interface{}, len(reloadableKeys))
+	app := reflect.ValueOf(Current()).Elem()
+	for _, key := range reloadableKeys {
+		if fv, ok := fieldByKey(app, key); ok {
+			values[key] = fv.Interface()
+		}
+	}
+	raw, _ := json.Marshal(values) // map keys are sorted, so the hash is stable
+	sum := sha256.Sum256(raw)
+
+	versionMu.Lock()
+	version = hex.EncodeToString(sum[:])[:12]
+	loadedAt = time.Now()
+	versionMu.Unlock()
+}
+
+// Reload re-reads the config file and environment and applies the
+// reloadable settings to the running config. Other settings that changed are
+// reported in RestartRequired and left as they are.
+func Reload() (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	v := newViper(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return ReloadResult{}, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+	var next Config
+	if err := v.Unmarshal(&next); err != nil {
+		return ReloadResult{}, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	result := ReloadResult{Changed: []string{}}
+	nextValue := reflect.ValueOf(&next).Elem()
+	var logLevel string
+
+	// Apply the changes to a copy; requests keep reading the old config
+	// until it is swapped in
+	Update(func(c *Config) {
+		app := reflect.ValueOf(c).Elem()
+
+		reloadable := make(map[string]bool, len(reloadableKeys))
+		for _, key := range reloadableKeys {
+			reloadable[key] = true
+			cur, ok := fieldByKey(app, key)
+			if !ok {
+				continue
+			}
+			nv, _ := fieldByKey(nextValue, key)
+			if !reflect.DeepEqual(cur.Interface(), nv.Interface()) {
+				cur.Set(nv)
+				result.Changed = append(result.Changed, key)
+			}
+		}
+
+		walkLeaves(nextValue, "", func(key string, nv reflect.Value) {
+			if reloadable[key] {
+				return
+			}
+			// Secret references resolve to different text than the running value
+			if nv.Kind() == reflect.String && secrets.IsReference(nv.String()) {
+				return
+			}
+			if cur, ok := fieldByKey(app, key); ok && !reflect.DeepEqual(cur.Interface(), nv.Interface()) {
+				result.RestartRequired = append(result.RestartRequired, key)
+			}
+		})
+		logLevel = c.LogLevel
+	})
+
+	if containsKey(result.Changed, "log_level") {
+		logger.SetLevelString(logLevel)
+	}
+	markLoaded()
+	result.Version, result.LoadedAt = Version()
+	return result, nil
+}
+
+// WatchReloadSignal reloads the config whenever the process receives SIGHUP
+func WatchReloadSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				result, err := Reload()
+				if err != nil {
+					log.Printf("⚠️  Config reload failed: %v", err)
+					continue
+				}
+				LogReload(result)
+			}
+		}
+	}()
+}
+
+// LogReload logs the outcome of a reload
+func LogReload(result ReloadResult) {
+	if len(result.Changed) == 0 {
+		log.Printf("🔄 Config reloaded (version %s): no changes", result.Version)
+	} else {
+		log.Printf("🔄 Config reloaded (version %s): %s", result.Version, strings.Join(result.Changed, ", "))
+	}
+	if len(result.RestartRequired) > 0 {
+		log.Printf("⚠️  Changed settings that need a restart: %s", strings.Join(result.RestartRequired, ", "))
+	}
+}
+
+// fieldByKey finds the Config field for a dotted mapstructure key
+func fieldByKey(v reflect.Value, key string) (reflect.Value, bool) {
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		found := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("mapstructure") == part {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// walkLeaves calls fn for every non-struct field with its dotted key
+func walkLeaves(v reflect.Value, prefix string, fn func(key string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := prefix + t.Field(i).Tag.Get("mapstructure")
+		if fv := v.Field(i); fv.Kind() == reflect.Struct {
+			walkLeaves(fv, key+".", fn)
+		} else {
+			fn(key, fv)
+		}
+	}
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
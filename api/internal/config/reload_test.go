@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadAppliesOnlyReloadableSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slar.yaml")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	write("smtp:\n  host: mail-a\nlog_level: INFO\nfeature_flags: [ai_triage]\n")
+	require.NoError(t, LoadConfig(path))
+	before, _ := Version()
+	assert.True(t, FeatureEnabled("ai_triage"))
+
+	write("smtp:\n  host: mail-b\nlog_level: DEBUG\nfeature_flags: [ai_triage, federation]\napi_key_rate_limit_per_hour: 50\n")
+	result, err := Reload()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"log_level", "feature_flags", "api_key_rate_limit_per_hour"}, result.Changed)
+	assert.Contains(t, result.RestartRequired, "smtp.host")
+	assert.Equal(t, "mail-a", Current().SMTP.Host, "connection settings are not reloaded")
+	assert.Equal(t, "DEBUG", Current().LogLevel)
+	assert.Equal(t, 50, Current().APIKeyRateLimitPerHour)
+	assert.True(t, FeatureEnabled("federation"))
+	assert.NotEqual(t, before, result.Version)
+
+	again, err := Reload()
+	require.NoError(t, err)
+	assert.Empty(t, again.Changed)
+	assert.Equal(t, result.Version, again.Version)
+}
+
+func TestFeatureOverride(t *testing.T) {
+	saved := Current().FeatureFlags
+	defer Update(func(c *Config) { c.FeatureFlags = saved })
+
+	Update(func(c *Config) { c.FeatureFlags = []string{"AI_Analysis", " -escalation_engine_v2"} })
+	enabled, ok := FeatureOverride("ai_analysis")
+	assert.True(t, ok)
+	assert.True(t, enabled)
+
+	enabled, ok = FeatureOverride("escalation_engine_v2")
+	assert.True(t, ok)
+	assert.False(t, enabled)
+	assert.False(t, FeatureEnabled("escalation_engine_v2"))
+
+	_, ok = FeatureOverride("async_ingestion")
+	assert.False(t, ok)
+}
+
+func TestReloadSwapsConfigWhileItIsRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slar.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("api_key_rate_limit_per_hour: 10\n"), 0o600))
+	require.NoError(t, LoadConfig(path))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = Current().APIKeyRateLimitPerHour
+			_ = FeatureEnabled("federation")
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		_, err := Reload()
+		require.NoError(t, err)
+	}
+	<-done
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/vanchonlee/slar/internal/secrets"
+)
+
+// secretBinding remembers where a secret reference came from so the
+// refresher can re-resolve it later.
+type secretBinding struct {
+	name  string // env var name or mapstructure path, used in logs
+	ref   string
+	env   string // set for env var bindings
+	field []int  // set for Config field bindings
+}
+
+var (
+	secretResolver = secrets.NewResolver()
+	secretsMu      sync.Mutex
+	secretBindings []secretBinding
+
+	// envKeys maps env var names to the config key they are bound to
+	envKeys = map[string]string{}
+)
+
+// bindEnv wraps viper's BindEnv and remembers the mapping so env secrets
+// can be refreshed into the matching Config field as well.
+func bindEnv(v *viper.Viper, key, env string) {
+	v.BindEnv(key, env)
+	secretsMu.Lock()
+	envKeys[env] = key
+	secretsMu.Unlock()
+}
+
+// resetSecretBindings forgets the references found by a previous load so
+// loading the config again doesn't refresh every secret more than once.
+func resetSecretBindings() {
+	secretsMu.Lock()
+	secretBindings = nil
+	secretsMu.Unlock()
+}
+
+func addSecretBinding(b secretBinding) {
+	secretsMu.Lock()
+	secretBindings = append(secretBindings, b)
+	secretsMu.Unlock()
+}
+
+// resolveEnvSecrets replaces secret references in the process environment
+// (e.g. DATABASE_URL=vault://secret/data/slar#database_url) with their values.
+// Runs before viper unmarshals so bound env vars pick up plaintext values.
+func resolveEnvSecrets(ctx context.Context) error {
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !secrets.IsReference(value) {
+			continue
+		}
+		resolved, err := secretResolver.ResolveString(ctx, value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secret for %s: %w", key, err)
+		}
+		os.Setenv(key, resolved)
+		addSecretBinding(secretBinding{name: key, ref: value, env: key})
+	}
+	return nil
+}
+
+// resolveConfigSecrets replaces secret references in string fields of cfg,
+// which covers values set directly in the YAML config file.
+func resolveConfigSecrets(ctx context.Context, cfg *Config) error {
+	var walk func(v reflect.Value, t reflect.Type, index []int, prefix string) error
+	walk = func(v reflect.Value, t reflect.Type, index []int, prefix string) error {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fv := v.Field(i)
+			name := prefix + f.Tag.Get("mapstructure")
+			path := append(append([]int{}, index...), i)
+
+			switch fv.Kind() {
+			case reflect.Struct:
+				if err := walk(fv, f.Type, path, name+"."); err != nil {
+					return err
+				}
+			case reflect.String:
+				ref := fv.String()
+				if !secrets.IsReference(ref) {
+					attachEnvBinding(name, path)
+					continue
+				}
+				resolved, err := secretResolver.ResolveString(ctx, ref)
+				if err != nil {
+					return fmt.Errorf("failed to resolve secret for %s: %w", name, err)
+				}
+				fv.SetString(resolved)
+				addSecretBinding(secretBinding{name: name, ref: ref, field: path})
+			}
+		}
+		return nil
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	return walk(v, v.Type(), nil, "")
+}
+
+// attachEnvBinding links an env var secret to the Config field it populated
+func attachEnvBinding(key string, field []int) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for i := range secretBindings {
+		if b := &secretBindings[i]; b.env != "" && envKeys[b.env] == key {
+			b.field = field
+		}
+	}
+}
+
+// StartSecretRefresher periodically re-resolves secret references found at
+// startup and applies changed values to the config and the process environment.
+// It is a no-op when no references were used or secrets_refresh_interval is 0.
+//
+// Values captured by services at construction time (e.g. the database pool)
+// are not rebuilt; a change to DATABASE_URL still requires a restart.
+func StartSecretRefresher(ctx context.Context) {
+	interval := Current().SecretsRefreshInterval
+	secretsMu.Lock()
+	count := len(secretBindings)
+	secretsMu.Unlock()
+	if interval <= 0 || count == 0 {
+		return
+	}
+
+	log.Printf("🔐 Refreshing %d secret reference(s) every %s", count, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				refreshSecrets(rctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+func refreshSecrets(ctx context.Context) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secretResolver.Reset()
+	app := reflect.ValueOf(Current()).Elem()
+	rotated := map[int]string{} // secretBindings index -> new value for Config fields
+
+	for i, b := range secretBindings {
+		resolved, err := secretResolver.ResolveString(ctx, b.ref)
+		if err != nil {
+			// Keep serving the last known value; the backend may be briefly unavailable
+			log.Printf("⚠️  Failed to refresh secret %s: %v", b.name, err)
+			continue
+		}
+
+		changed := false
+		if b.env != "" && os.Getenv(b.env) != resolved {
+			os.Setenv(b.env, resolved)
+			changed = true
+		}
+		if b.field != nil && app.FieldByIndex(b.field).String() != resolved {
+			rotated[i] = resolved
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+
+		log.Printf("🔐 Secret %s rotated", b.name)
+		if b.name == "DATABASE_URL" || b.name == "database_url" {
+			log.Printf("⚠️  DATABASE_URL changed; restart the service to reconnect with the new credentials")
+		}
+	}
+
+	if len(rotated) == 0 {
+		return
+	}
+	// Swap in an updated copy; requests may be reading the running config
+	Update(func(c *Config) {
+		next := reflect.ValueOf(c).Elem()
+		for i, value := range rotated {
+			next.FieldByIndex(secretBindings[i].field).SetString(value)
+		}
+	})
+}
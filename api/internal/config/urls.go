@@ -0,0 +1,50 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// NormalizedBasePath returns base_path with a leading slash and no trailing
+// slash, or "" when the API is served at the root
+func NormalizedBasePath() string {
+	base := strings.Trim(strings.TrimSpace(Current().BasePath), "/")
+	if base == "" {
+		return ""
+	}
+	return "/" + base
+}
+
+// ExternalAPIURL is the API's address as clients outside the cluster reach
+// it: public_url plus base_path, falling back to localhost on the configured
+// port. Used to build absolute links instead of hard-coded hosts.
+func ExternalAPIURL() string {
+	cfg := Current()
+	base := strings.TrimRight(cfg.PublicURL, "/")
+	if base == "" {
+		port := cfg.Port
+		if port == "" {
+			port = "8080"
+		}
+		base = "http://localhost:" + port
+	}
+
+	prefix := NormalizedBasePath()
+	if prefix != "" {
+		// public_url may already include the prefix
+		if u, err := url.Parse(base); err != nil || !strings.HasSuffix(strings.TrimRight(u.Path, "/"), prefix) {
+			base += prefix
+		}
+	}
+	return base
+}
+
+// WebhookBaseURL is the base of inbound webhook URLs shown to users:
+// webhook_api_base_url when set (e.g. a dedicated ingress), otherwise
+// ExternalAPIURL
+func WebhookBaseURL() string {
+	if base := Current().WebhookAPIBaseURL; base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return ExternalAPIURL()
+}
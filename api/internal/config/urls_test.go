@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalAPIURL(t *testing.T) {
+	saved := Current()
+	defer Update(func(c *Config) { *c = *saved })
+
+	Update(func(c *Config) { *c = Config{Port: "9000"} })
+	assert.Equal(t, "http://localhost:9000", ExternalAPIURL())
+	assert.Equal(t, "http://localhost:9000", WebhookBaseURL())
+
+	Update(func(c *Config) { *c = Config{PublicURL: "https://gw.corp.example/", BasePath: "slar/api/"} })
+	assert.Equal(t, "/slar/api", NormalizedBasePath())
+	assert.Equal(t, "https://gw.corp.example/slar/api", ExternalAPIURL())
+
+	Update(func(c *Config) { c.PublicURL = "https://gw.corp.example/slar/api" })
+	assert.Equal(t, "https://gw.corp.example/slar/api", ExternalAPIURL(), "prefix is not doubled")
+
+	Update(func(c *Config) { c.WebhookAPIBaseURL = "https://hooks.corp.example/" })
+	assert.Equal(t, "https://hooks.corp.example", WebhookBaseURL())
+}
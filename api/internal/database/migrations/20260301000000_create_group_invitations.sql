@@ -0,0 +1,45 @@
+-- Migration: Group invitations and join requests
+-- Invitations: group admins email a signed join link (token stored as SHA-256 hash)
+-- Join requests: users ask to join public/organization groups, admins approve or reject
+
+CREATE TABLE IF NOT EXISTS group_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member',
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked', 'expired')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_invitations_group ON group_invitations(group_id, status);
+CREATE INDEX IF NOT EXISTS idx_group_invitations_email ON group_invitations(lower(email));
+
+-- Only one pending invitation per email per group
+CREATE UNIQUE INDEX IF NOT EXISTS uq_group_invitations_pending
+    ON group_invitations(group_id, lower(email)) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS group_join_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_join_requests_group ON group_join_requests(group_id, status);
+
+-- Only one pending join request per user per group
+CREATE UNIQUE INDEX IF NOT EXISTS uq_group_join_requests_pending
+    ON group_join_requests(group_id, user_id) WHERE status = 'pending';
+
+COMMENT ON TABLE group_invitations IS 'Email invitations to join a group via signed link';
+COMMENT ON COLUMN group_invitations.token_hash IS 'SHA-256 hex of the invitation token; raw token is only sent by email';
+COMMENT ON TABLE group_join_requests IS 'User-initiated requests to join public/organization groups, approved by group admins';
@@ -0,0 +1,29 @@
+-- Migration: SCIM 2.0 provisioning
+-- scim_tokens: per-organization bearer tokens used by the IdP (Okta, Azure AD)
+-- scim_external_ids: maps IdP externalId values to SLAR users/groups within an org
+
+CREATE TABLE IF NOT EXISTS scim_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_tokens_org ON scim_tokens(organization_id);
+
+CREATE TABLE IF NOT EXISTS scim_external_ids (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    resource_type TEXT NOT NULL CHECK (resource_type IN ('user', 'group')),
+    resource_id UUID NOT NULL,
+    external_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, resource_type, resource_id),
+    UNIQUE (organization_id, resource_type, external_id)
+);
+
+COMMENT ON TABLE scim_tokens IS 'Bearer tokens for SCIM 2.0 provisioning (one org per token). Raw token shown once on creation.';
+COMMENT ON TABLE scim_external_ids IS 'IdP externalId mapping for SCIM-provisioned users and groups';
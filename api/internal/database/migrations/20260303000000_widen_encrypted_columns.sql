@@ -0,0 +1,5 @@
+-- Migration: Application-level column encryption
+-- Encrypted values (enc:v1:<key-id>:<base64>) are longer than the plaintext,
+-- so widen columns that had a length limit. users.phone and users.fcm_token are already TEXT.
+
+ALTER TABLE integrations ALTER COLUMN webhook_secret TYPE TEXT;
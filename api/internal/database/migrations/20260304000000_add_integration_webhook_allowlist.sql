@@ -0,0 +1,12 @@
+-- Migration: Per-integration inbound webhook allowlists
+-- allowed_source_cidrs: source IP ranges allowed to call /webhook/:type/:id (empty = any)
+-- allowed_user_agents: case-insensitive User-Agent prefixes allowed (empty = any)
+-- rejected_* counters give visibility into blocked requests
+
+ALTER TABLE integrations
+    ADD COLUMN IF NOT EXISTS allowed_source_cidrs TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS allowed_user_agents TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS rejected_ip_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rejected_user_agent_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_rejected_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_rejected_source TEXT;
@@ -0,0 +1,18 @@
+-- Migration: Idempotency keys for incident creation
+-- Clients send an Idempotency-Key header on POST /incidents and POST /webhooks/incident.
+-- The first request reserves (scope, idempotency_key) and stores a hash of the request;
+-- retries with the same key and payload get the original incident back instead of a duplicate.
+-- scope: 'org:<organization_id>' for the API, 'service:<service_id>' for webhooks
+
+CREATE TABLE IF NOT EXISTS incident_idempotency_keys (
+    scope           TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_hash    TEXT NOT NULL,
+    incident_id     UUID REFERENCES incidents(id) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_idempotency_keys_expires_at
+    ON incident_idempotency_keys (expires_at);
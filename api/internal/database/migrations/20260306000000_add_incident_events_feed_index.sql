@@ -0,0 +1,6 @@
+-- Migration: Keyset pagination index for the org-wide incident activity feed
+-- GET /incidents/feed orders by (created_at DESC, id DESC) and pages with
+-- WHERE (created_at, id) < (cursor_created_at, cursor_id)
+
+CREATE INDEX IF NOT EXISTS idx_incident_events_feed
+    ON incident_events (created_at DESC, id DESC);
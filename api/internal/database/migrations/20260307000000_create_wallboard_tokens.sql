@@ -0,0 +1,19 @@
+-- Migration: Wallboard / NOC display tokens
+-- Read-only, org-scoped tokens for TV dashboards that auto-refresh without a user session.
+-- Optionally narrowed to a single project.
+
+CREATE TABLE IF NOT EXISTS wallboard_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallboard_tokens_org ON wallboard_tokens(organization_id);
+
+COMMENT ON TABLE wallboard_tokens IS 'Read-only bearer tokens for wallboard/NOC displays. Raw token shown once on creation.';
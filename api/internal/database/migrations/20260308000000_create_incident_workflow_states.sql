@@ -0,0 +1,32 @@
+-- Migration: Custom incident workflow states
+-- Orgs can define extra states between acknowledged and resolved (e.g. "mitigating", "monitoring").
+-- incidents.status keeps the canonical triggered/acknowledged/resolved values for integrations;
+-- incidents.workflow_state holds the custom sub-state of an acknowledged incident.
+
+CREATE TABLE IF NOT EXISTS incident_workflow_states (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    color TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    -- States this one may be entered from ('acknowledged' or other custom keys). Empty = any.
+    allowed_from TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (organization_id, key),
+    CONSTRAINT incident_workflow_states_reserved_key CHECK (key NOT IN ('triggered', 'acknowledged', 'resolved'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_workflow_states_org ON incident_workflow_states(organization_id, position);
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS workflow_state TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_workflow_state
+    ON incidents(organization_id, workflow_state)
+    WHERE workflow_state IS NOT NULL;
+
+COMMENT ON TABLE incident_workflow_states IS 'Org-defined incident workflow states between acknowledged and resolved';
+COMMENT ON COLUMN incidents.workflow_state IS 'Custom workflow state key (incident_workflow_states.key); NULL when the incident is in a canonical state';
@@ -0,0 +1,29 @@
+-- Migration: Notification read receipts
+-- One row per page sent to a responder (assigned, escalated, ad-hoc page). Mobile and
+-- Slack clients call back when the notification is delivered and when it is opened,
+-- so the incident detail can show who has actually seen the page.
+
+CREATE TABLE IF NOT EXISTS notification_receipts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification_type TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ,
+    delivered_channel TEXT,
+    seen_at TIMESTAMPTZ,
+    seen_channel TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_receipts_incident_user
+    ON notification_receipts(incident_id, user_id, sent_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notification_receipts_seen
+    ON notification_receipts(incident_id, seen_at)
+    WHERE seen_at IS NOT NULL;
+
+-- Escalate before the level timeout when nobody has opened the latest page
+ALTER TABLE escalation_policies ADD COLUMN IF NOT EXISTS unseen_escalate_after_minutes INTEGER;
+
+COMMENT ON TABLE notification_receipts IS 'Delivery and read receipts for incident pages, per responder';
+COMMENT ON COLUMN escalation_policies.unseen_escalate_after_minutes IS 'Escalate early when no responder has seen the latest page within this many minutes; NULL/0 = disabled';
@@ -0,0 +1,31 @@
+-- Migration: Resolve-on-deploy rules
+-- Deployment webhooks (GitHub, GitLab, ArgoCD) arrive through the normal /webhook/:type/:integration_id
+-- endpoint. For each service linked to the integration, matching rules annotate open incidents with
+-- the deploy link or resolve them (e.g. a version regression fixed by the next release).
+
+ALTER TABLE integrations DROP CONSTRAINT IF EXISTS integrations_type_valid;
+ALTER TABLE integrations ADD CONSTRAINT integrations_type_valid CHECK (
+    type IN ('prometheus', 'datadog', 'grafana', 'webhook', 'aws', 'custom', 'github', 'gitlab', 'argocd')
+);
+
+CREATE TABLE IF NOT EXISTS service_deploy_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Only deploys to this environment fire the rule (NULL = any environment)
+    environment TEXT,
+    -- Labels an open incident must carry to match (JSONB containment, {} = every open incident)
+    match_labels JSONB NOT NULL DEFAULT '{}',
+    action TEXT NOT NULL DEFAULT 'annotate',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_deploy_rules_action_valid CHECK (action IN ('annotate', 'resolve'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_deploy_rules_service
+    ON service_deploy_rules(service_id)
+    WHERE is_active = TRUE;
+
+COMMENT ON TABLE service_deploy_rules IS 'Per-service rules that annotate or auto-resolve open incidents when a deployment webhook arrives';
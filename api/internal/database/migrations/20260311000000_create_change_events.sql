@@ -0,0 +1,27 @@
+-- Migration: Change events
+-- Deploys, feature flag flips and infrastructure changes, stored per service so incidents can show
+-- "what changed recently" (most incidents correlate with a recent change). Ingested via
+-- POST /webhooks/change (routing_key, PagerDuty Change Events style) and from deploy integrations.
+
+CREATE TABLE IF NOT EXISTS change_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID,
+    service_id UUID REFERENCES services(id) ON DELETE CASCADE,
+    type TEXT NOT NULL DEFAULT 'other',
+    source TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    url TEXT,
+    version TEXT,
+    environment TEXT,
+    author TEXT,
+    custom_details JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT change_events_type_valid CHECK (type IN ('deploy', 'feature_flag', 'infrastructure', 'config', 'other'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_events_service_occurred ON change_events(service_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_change_events_project_occurred ON change_events(project_id, occurred_at DESC);
+
+COMMENT ON TABLE change_events IS 'Deploys, feature flag and infrastructure changes per service, correlated with incidents';
@@ -0,0 +1,8 @@
+-- Migration: Per-integration payload error tracking
+-- Webhooks rejected for being too large, malformed or missing required fields are counted on the
+-- integration together with the last error, so senders can self-diagnose from the integration page.
+
+ALTER TABLE integrations
+    ADD COLUMN IF NOT EXISTS payload_error_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_payload_error TEXT,
+    ADD COLUMN IF NOT EXISTS last_payload_error_at TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+-- Migration: Per-service incident cost parameters
+-- Optional inputs for estimating what an incident costs: business impact per minute of
+-- downtime and the loaded hourly cost of an engineer responding. NULL means "not configured".
+
+ALTER TABLE services
+    ADD COLUMN IF NOT EXISTS revenue_per_minute NUMERIC(12, 2),
+    ADD COLUMN IF NOT EXISTS engineer_hourly_cost NUMERIC(12, 2);
+
+ALTER TABLE services DROP CONSTRAINT IF EXISTS services_cost_parameters_non_negative;
+ALTER TABLE services ADD CONSTRAINT services_cost_parameters_non_negative
+    CHECK (COALESCE(revenue_per_minute, 0) >= 0 AND COALESCE(engineer_hourly_cost, 0) >= 0);
@@ -0,0 +1,42 @@
+-- Migration: Service SLOs and error budgets
+-- An SLO sets an availability target for a service over a rolling window. The SLI comes either
+-- from the service's high-urgency incident time or from the results of a linked uptime monitor,
+-- which are rolled up into hourly buckets as monitor reports arrive. When burn_rate_threshold is
+-- set, the worker opens an incident on the service while the last hour's burn rate exceeds it.
+
+CREATE TABLE IF NOT EXISTS service_slos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Availability target in percent, e.g. 99.9
+    target NUMERIC(6, 3) NOT NULL,
+    window_days INTEGER NOT NULL DEFAULT 30,
+    source TEXT NOT NULL DEFAULT 'incidents',
+    monitor_id UUID REFERENCES monitors(id) ON DELETE SET NULL,
+    -- Open an incident when the 1h burn rate exceeds this (NULL = never)
+    burn_rate_threshold NUMERIC(8, 2),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_slos_target_valid CHECK (target > 0 AND target < 100),
+    CONSTRAINT service_slos_window_valid CHECK (window_days BETWEEN 1 AND 90),
+    CONSTRAINT service_slos_source_valid CHECK (source IN ('incidents', 'monitor')),
+    CONSTRAINT service_slos_monitor_required CHECK (source <> 'monitor' OR monitor_id IS NOT NULL),
+    CONSTRAINT service_slos_burn_rate_positive CHECK (burn_rate_threshold IS NULL OR burn_rate_threshold > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_slos_service ON service_slos(service_id);
+CREATE INDEX IF NOT EXISTS idx_service_slos_monitor ON service_slos(monitor_id) WHERE is_active = TRUE;
+
+-- Hourly monitor check counts per monitor-backed SLO
+CREATE TABLE IF NOT EXISTS slo_check_buckets (
+    slo_id UUID NOT NULL REFERENCES service_slos(id) ON DELETE CASCADE,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    total_checks INTEGER NOT NULL DEFAULT 0,
+    good_checks INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (slo_id, bucket_start)
+);
+
+COMMENT ON TABLE service_slos IS 'Per-service availability SLOs with error budget tracking and optional burn-rate alerting';
+COMMENT ON TABLE slo_check_buckets IS 'Hourly rollup of uptime monitor results feeding monitor-backed SLOs';
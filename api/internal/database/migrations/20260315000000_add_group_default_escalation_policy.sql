@@ -0,0 +1,7 @@
+-- Migration: Per-group default escalation policy
+-- Services created in a group without an explicit escalation policy fall back to the
+-- group's default. Deleting the policy clears the default rather than blocking the delete.
+
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS default_escalation_policy_id UUID
+        REFERENCES escalation_policies(id) ON DELETE SET NULL;
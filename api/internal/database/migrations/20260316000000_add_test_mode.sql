@@ -0,0 +1,14 @@
+-- Migration: Sandbox/test mode for integrations and API keys
+-- Events sent through a test-mode integration or API key run the full pipeline but the
+-- resulting incidents are flagged is_test: they are excluded from analytics, never page
+-- anyone for real, and their Slack messages go to the configured test channel.
+
+ALTER TABLE integrations ADD COLUMN IF NOT EXISTS test_mode BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS test_mode BOOLEAN NOT NULL DEFAULT false;
+
+-- Keys already created for the test environment start out in test mode
+UPDATE api_keys SET test_mode = true WHERE environment = 'test';
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS is_test BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_is_test ON incidents(created_at) WHERE is_test;
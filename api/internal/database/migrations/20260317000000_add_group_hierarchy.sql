@@ -0,0 +1,26 @@
+-- Migration: Group hierarchy (sub-teams)
+-- A group may have a parent group. Targeting a group (escalation levels, dashboard
+-- filters, on-call lookups) includes its active sub-teams, resolved with group_subtree().
+
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS parent_group_id UUID
+        REFERENCES groups(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_groups_parent_group_id
+    ON groups(parent_group_id) WHERE parent_group_id IS NOT NULL;
+
+-- group_subtree returns the group and all of its active descendants with their
+-- depth below it (0 = the group itself). The path check guards against cycles.
+CREATE OR REPLACE FUNCTION group_subtree(root UUID)
+RETURNS TABLE (group_id UUID, depth INT) AS $$
+    WITH RECURSIVE tree(group_id, depth, path) AS (
+        SELECT root, 0, ARRAY[root]
+        UNION ALL
+        SELECT g.id, t.depth + 1, t.path || g.id
+        FROM groups g
+        JOIN tree t ON g.parent_group_id = t.group_id
+        WHERE g.is_active = true
+          AND NOT g.id = ANY(t.path)
+    )
+    SELECT tree.group_id, tree.depth FROM tree
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,18 @@
+-- Migration: Service maintenance windows
+-- Scheduled maintenance for a service, shown on the service calendar alongside
+-- upcoming on-call shifts and recent incidents.
+
+CREATE TABLE IF NOT EXISTS service_maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ NOT NULL,
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_maintenance_windows_range_valid CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_maintenance_windows_service_time
+    ON service_maintenance_windows(service_id, start_time, end_time);
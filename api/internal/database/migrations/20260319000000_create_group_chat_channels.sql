@@ -0,0 +1,42 @@
+-- Migration: Discord and Telegram notification channels per group
+-- Incident notifications for a group's incidents are also posted to its chat
+-- channels by the notification worker (queue: chat_notifications). Pages carry
+-- a one-time acknowledge link backed by incident_ack_links.
+
+CREATE TABLE IF NOT EXISTS group_chat_channels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    channel_type TEXT NOT NULL CHECK (channel_type IN ('discord', 'telegram')),
+    name TEXT NOT NULL,
+    -- Discord: incoming webhook URL. Telegram: bot token and chat ID.
+    -- webhook_url and bot_token are encrypted at rest.
+    webhook_url TEXT,
+    bot_token TEXT,
+    chat_id TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT group_chat_channels_config CHECK (
+        (channel_type = 'discord' AND webhook_url IS NOT NULL)
+        OR (channel_type = 'telegram' AND bot_token IS NOT NULL AND chat_id IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_chat_channels_group_id
+    ON group_chat_channels(group_id) WHERE is_active = true;
+
+CREATE TABLE IF NOT EXISTS incident_ack_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash TEXT NOT NULL UNIQUE,
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    -- The paged user; acknowledging through the link acts on their behalf
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_ack_links_incident_id ON incident_ack_links(incident_id);
+
+SELECT pgmq.create('chat_notifications');
@@ -0,0 +1,13 @@
+-- Migration: Google Chat spaces as group chat channels
+-- Google Chat channels use an incoming webhook URL (stored encrypted in webhook_url).
+-- Notifications for the same incident are threaded using the incident ID as thread key.
+
+ALTER TABLE group_chat_channels DROP CONSTRAINT IF EXISTS group_chat_channels_channel_type_check;
+ALTER TABLE group_chat_channels ADD CONSTRAINT group_chat_channels_channel_type_check
+    CHECK (channel_type IN ('discord', 'telegram', 'google_chat'));
+
+ALTER TABLE group_chat_channels DROP CONSTRAINT IF EXISTS group_chat_channels_config;
+ALTER TABLE group_chat_channels ADD CONSTRAINT group_chat_channels_config CHECK (
+    (channel_type IN ('discord', 'google_chat') AND webhook_url IS NOT NULL)
+    OR (channel_type = 'telegram' AND bot_token IS NOT NULL AND chat_id IS NOT NULL)
+);
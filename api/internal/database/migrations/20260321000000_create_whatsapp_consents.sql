@@ -0,0 +1,31 @@
+-- Migration: WhatsApp Business notifications with per-user opt-in consent
+-- A user is only messaged on WhatsApp while opted in. phone_number is encrypted
+-- at rest; phone_lookup (SHA-256 of the digits) matches inbound webhook messages.
+-- last_inbound_at tracks the 24h window in which free-form session messages are allowed.
+
+CREATE TABLE IF NOT EXISTS whatsapp_consents (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    phone_number TEXT NOT NULL,
+    phone_lookup TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('opted_in', 'opted_out')),
+    source TEXT NOT NULL,
+    opted_in_at TIMESTAMPTZ,
+    opted_out_at TIMESTAMPTZ,
+    last_inbound_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_consents_phone_lookup ON whatsapp_consents(phone_lookup);
+
+-- Audit trail of consent changes
+CREATE TABLE IF NOT EXISTS whatsapp_consent_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('opted_in', 'opted_out')),
+    source TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_consent_events_user_id ON whatsapp_consent_events(user_id, created_at DESC);
+
+SELECT pgmq.create('whatsapp_notifications');
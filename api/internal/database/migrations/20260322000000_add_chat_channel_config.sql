@@ -0,0 +1,10 @@
+-- Migration: settings for pluggable chat channel types
+-- Channel types are now registered in code (api/notify), so the database no longer
+-- restricts channel_type or which columns each type needs; settings are validated
+-- against the type's config schema instead. Settings without a column of their own
+-- are stored as one encrypted JSON document in config.
+
+ALTER TABLE group_chat_channels DROP CONSTRAINT IF EXISTS group_chat_channels_channel_type_check;
+ALTER TABLE group_chat_channels DROP CONSTRAINT IF EXISTS group_chat_channels_config;
+
+ALTER TABLE group_chat_channels ADD COLUMN IF NOT EXISTS config TEXT;
@@ -0,0 +1,22 @@
+-- Migration: Service urgency rules
+-- Rules re-evaluated whenever another alert joins an open incident (dedup key or
+-- fingerprint match) or its severity changes upstream. The first matching rule,
+-- in position order, sets the incident's urgency and/or priority and can re-page
+-- the assignee when urgency is raised.
+
+CREATE TABLE IF NOT EXISTS service_urgency_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    min_alert_count INTEGER NOT NULL DEFAULT 0 CHECK (min_alert_count >= 0),
+    severities TEXT[] NOT NULL DEFAULT '{}',
+    set_urgency TEXT CHECK (set_urgency IN ('high', 'low')),
+    set_priority TEXT,
+    repage BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_urgency_rules_action CHECK (set_urgency IS NOT NULL OR set_priority IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_urgency_rules_service
+    ON service_urgency_rules(service_id, position);
@@ -0,0 +1,8 @@
+-- Migration: Time-to-acknowledge auto-reassign
+-- When the assignee of a triggered incident hasn't acknowledged within this many
+-- minutes, the incident is handed to the next member of their schedule instead
+-- of waiting for the level timeout. Both people are notified.
+
+ALTER TABLE escalation_policies ADD COLUMN IF NOT EXISTS reassign_after_minutes INTEGER;
+
+COMMENT ON COLUMN escalation_policies.reassign_after_minutes IS 'Reassign to the next schedule member when the assignee has not acknowledged within this many minutes; NULL/0 = disabled';
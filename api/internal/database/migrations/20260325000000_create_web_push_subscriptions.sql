@@ -0,0 +1,20 @@
+-- Migration: Web Push subscriptions
+-- One row per browser that asked to receive incident pages through the Push
+-- API (VAPID). endpoint, p256dh and auth are encrypted at rest; endpoint_lookup
+-- (SHA-256 of the endpoint) makes re-subscribing the same browser idempotent.
+
+CREATE TABLE IF NOT EXISTS web_push_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL,
+    endpoint_lookup TEXT NOT NULL UNIQUE,
+    p256dh TEXT NOT NULL,
+    auth TEXT NOT NULL,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_web_push_subscriptions_user_id ON web_push_subscriptions(user_id);
+
+SELECT pgmq.create('webpush_notifications');
@@ -0,0 +1,16 @@
+-- Migration: Incident presence
+-- Who has an incident open right now. Clients heartbeat while the incident page
+-- is open; rows older than the presence TTL are ignored and purged by the
+-- worker. Presence is ephemeral, so the table is UNLOGGED (no WAL, emptied
+-- after a crash).
+
+CREATE UNLOGGED TABLE IF NOT EXISTS incident_viewers (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mode TEXT NOT NULL DEFAULT 'viewing' CHECK (mode IN ('viewing', 'editing')),
+    since TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (incident_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_viewers_last_seen ON incident_viewers(last_seen_at);
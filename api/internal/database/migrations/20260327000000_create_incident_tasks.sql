@@ -0,0 +1,23 @@
+-- Migration: Incident tasks
+-- Ordered checklist items on an incident (e.g. failover steps) that responders
+-- can assign and tick off together. Completing or reopening a task is recorded
+-- in the incident timeline.
+
+CREATE TABLE IF NOT EXISTS incident_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    assigned_to UUID REFERENCES users(id) ON DELETE SET NULL,
+    completed_at TIMESTAMPTZ,
+    completed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_tasks_incident ON incident_tasks(incident_id, position);
+
+-- Open-task counts in the incident list
+CREATE INDEX IF NOT EXISTS idx_incident_tasks_open ON incident_tasks(incident_id) WHERE completed_at IS NULL;
@@ -0,0 +1,19 @@
+-- Migration: Saved analytics dashboards
+-- User-defined layouts of metric widgets on top of the analytics endpoints.
+-- A dashboard is private to its owner unless shared with a group, in which
+-- case every member of that group can open it. Only the owner can change it.
+
+CREATE TABLE IF NOT EXISTS analytics_dashboards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id UUID REFERENCES groups(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    widgets JSONB NOT NULL DEFAULT '[]',
+    filters JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_dashboards_owner ON analytics_dashboards(owner_id);
+CREATE INDEX IF NOT EXISTS idx_analytics_dashboards_group ON analytics_dashboards(group_id) WHERE group_id IS NOT NULL;
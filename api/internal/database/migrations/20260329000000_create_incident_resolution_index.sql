@@ -0,0 +1,93 @@
+-- Migration: Past-incident resolution search
+-- Full-text index over how incidents were fixed, so on-call engineers can ask
+-- "how did we fix this last time". One row per incident combines:
+--   * resolution notes from "resolved" events (weight A)
+--   * the postmortem, kept in incidents.custom_fields->>'postmortem' (weight B)
+--   * notes added while the incident was worked (weight C)
+-- Rows are refreshed by triggers, like incidents.search_vector.
+
+CREATE TABLE IF NOT EXISTS incident_resolution_index (
+    incident_id UUID PRIMARY KEY REFERENCES incidents(id) ON DELETE CASCADE,
+    resolution_text TEXT NOT NULL DEFAULT '',
+    postmortem_text TEXT NOT NULL DEFAULT '',
+    notes_text TEXT NOT NULL DEFAULT '',
+    search_vector tsvector NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_resolution_index_search
+  ON incident_resolution_index
+  USING gin(search_vector);
+
+-- Rebuild one incident's row from its events and custom fields
+CREATE OR REPLACE FUNCTION refresh_incident_resolution_index(p_incident_id uuid) RETURNS void AS $$
+BEGIN
+  INSERT INTO incident_resolution_index (incident_id, resolution_text, postmortem_text, notes_text, search_vector, updated_at)
+  SELECT
+    i.id,
+    COALESCE(r.text, ''),
+    COALESCE(i.custom_fields->>'postmortem', ''),
+    COALESCE(n.text, ''),
+    setweight(to_tsvector('english', COALESCE(r.text, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(i.custom_fields->>'postmortem', '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(n.text, '')), 'C'),
+    NOW()
+  FROM incidents i
+  LEFT JOIN LATERAL (
+    SELECT string_agg(concat_ws(E'\n', NULLIF(ie.event_data->>'resolution', ''), NULLIF(ie.event_data->>'note', '')), E'\n' ORDER BY ie.created_at) AS text
+    FROM incident_events ie
+    WHERE ie.incident_id = i.id AND ie.event_type = 'resolved'
+  ) r ON true
+  LEFT JOIN LATERAL (
+    SELECT string_agg(ie.event_data->>'note', E'\n' ORDER BY ie.created_at) AS text
+    FROM incident_events ie
+    WHERE ie.incident_id = i.id AND ie.event_type = 'note_added'
+  ) n ON true
+  WHERE i.id = p_incident_id
+  ON CONFLICT (incident_id) DO UPDATE SET
+    resolution_text = EXCLUDED.resolution_text,
+    postmortem_text = EXCLUDED.postmortem_text,
+    notes_text = EXCLUDED.notes_text,
+    search_vector = EXCLUDED.search_vector,
+    updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION incident_events_resolution_index_trigger() RETURNS trigger AS $$
+BEGIN
+  PERFORM refresh_incident_resolution_index(NEW.incident_id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS incident_events_resolution_index_update ON incident_events;
+CREATE TRIGGER incident_events_resolution_index_update
+  AFTER INSERT ON incident_events
+  FOR EACH ROW
+  WHEN (NEW.event_type IN ('resolved', 'note_added'))
+  EXECUTE FUNCTION incident_events_resolution_index_trigger();
+
+CREATE OR REPLACE FUNCTION incidents_resolution_index_trigger() RETURNS trigger AS $$
+BEGIN
+  IF COALESCE(NEW.custom_fields->>'postmortem', '') IS DISTINCT FROM COALESCE(OLD.custom_fields->>'postmortem', '') THEN
+    PERFORM refresh_incident_resolution_index(NEW.id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS incidents_resolution_index_update ON incidents;
+CREATE TRIGGER incidents_resolution_index_update
+  AFTER UPDATE OF custom_fields ON incidents
+  FOR EACH ROW
+  EXECUTE FUNCTION incidents_resolution_index_trigger();
+
+-- Populate existing data
+SELECT refresh_incident_resolution_index(i.id)
+FROM incidents i
+WHERE EXISTS (
+  SELECT 1 FROM incident_events ie
+  WHERE ie.incident_id = i.id AND ie.event_type IN ('resolved', 'note_added')
+) OR COALESCE(i.custom_fields->>'postmortem', '') <> '';
+
+COMMENT ON TABLE incident_resolution_index IS 'Full-text index of resolution notes, postmortems and notes (auto-updated by triggers)';
@@ -0,0 +1,26 @@
+-- Migration: Cross-instance federation
+-- A "federation" integration forwards matching incidents to a remote SLAR
+-- instance through its events API (/webhooks/incident). One row per forwarded
+-- incident and integration tracks the remote copy; the remote reports status
+-- changes back to the integration's webhook URL with the per-link callback
+-- token, stored here as a SHA-256 hash.
+
+CREATE TABLE IF NOT EXISTS incident_federation_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    integration_id UUID NOT NULL REFERENCES integrations(id) ON DELETE CASCADE,
+    callback_token_hash TEXT NOT NULL UNIQUE,
+    remote_incident_id TEXT,
+    remote_incident_key TEXT,
+    remote_status TEXT,
+    last_error TEXT,
+    forwarded_at TIMESTAMPTZ,
+    last_synced_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (incident_id, integration_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_federation_links_integration ON incident_federation_links(integration_id);
+
+SELECT pgmq.create('federation_events');
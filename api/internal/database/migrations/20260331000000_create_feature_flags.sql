@@ -0,0 +1,29 @@
+-- Migration: Feature flags
+-- Gate risky subsystems for gradual rollout. Each flag has a global default;
+-- organizations can be opted in or out individually. The feature_flags config
+-- setting (FEATURE_FLAGS env var) overrides both.
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    default_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_feature_flags (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    flag_key TEXT NOT NULL REFERENCES feature_flags(key) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, flag_key)
+);
+
+INSERT INTO feature_flags (key, description, default_enabled) VALUES
+    ('async_ingestion', 'Queue incoming webhooks and create incidents asynchronously', FALSE),
+    ('ai_analysis', 'AI analysis of new incidents (also requires ai_incident_analytics.enabled)', TRUE),
+    ('escalation_engine_v2', 'New escalation engine', FALSE)
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE organization_feature_flags IS 'Per-organization feature flag settings; a missing row falls back to feature_flags.default_enabled.';
@@ -0,0 +1,32 @@
+-- Migration: Incident drills
+-- A drill triggers a clearly labeled fake incident for a service that runs
+-- through the real escalation and notification pipeline, so teams can test
+-- their on-call readiness. Drill incidents are tagged with drill_id and left
+-- out of SLO, cost and responder statistics.
+
+CREATE TABLE IF NOT EXISTS incident_drills (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    severity TEXT NOT NULL DEFAULT 'critical',
+    status TEXT NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'running', 'completed', 'cancelled', 'failed')),
+    scheduled_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    max_duration_minutes INTEGER NOT NULL DEFAULT 60 CHECK (max_duration_minutes > 0),
+    incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_drills_service ON incident_drills(service_id, scheduled_at DESC);
+CREATE INDEX IF NOT EXISTS idx_incident_drills_pending
+    ON incident_drills(scheduled_at)
+    WHERE status IN ('scheduled', 'running');
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS drill_id UUID REFERENCES incident_drills(id) ON DELETE SET NULL;
+
+COMMENT ON TABLE incident_drills IS 'Scheduled or ad-hoc on-call readiness drills; each runs one labeled incident';
+COMMENT ON COLUMN incidents.drill_id IS 'Set on incidents created by a drill; excluded from SLO, cost and responder stats';
@@ -0,0 +1,21 @@
+-- Migration: Group emergency broadcasts
+-- A broadcast pages every active member of a group at once, ignoring
+-- schedules and escalation order, for events too severe to wait for the
+-- normal ladder. Each broadcast is tied to an incident (new or existing) so
+-- acknowledgements and read receipts work as usual. Rows also back the
+-- per-group broadcast rate limit.
+
+CREATE TABLE IF NOT EXISTS group_broadcasts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+    message TEXT NOT NULL,
+    recipient_count INTEGER NOT NULL DEFAULT 0,
+    sent_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_broadcasts_group ON group_broadcasts(group_id, created_at DESC);
+
+COMMENT ON TABLE group_broadcasts IS 'Emergency pages sent to every active member of a group';
@@ -0,0 +1,41 @@
+-- Migration: Alert deduplication review queue
+-- Alerts that only match an open incident by title (no fingerprint to tell
+-- them apart) are parked here for a person to attach to an incident, promote
+-- to a new one or dismiss, instead of the pipeline guessing.
+-- alert_dedup_decisions keeps daily counts of automatic and manual
+-- deduplication decisions per organization.
+
+CREATE TABLE IF NOT EXISTS alert_dedup_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    integration_id UUID REFERENCES integrations(id) ON DELETE SET NULL,
+    candidate_incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+    alert_name TEXT NOT NULL,
+    alert_status TEXT NOT NULL CHECK (alert_status IN ('firing', 'resolved')),
+    severity TEXT,
+    match_strategy TEXT NOT NULL DEFAULT 'title',
+    alert JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'attached', 'promoted', 'dismissed')),
+    incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_dedup_reviews_org ON alert_dedup_reviews(organization_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_dedup_reviews_pending
+    ON alert_dedup_reviews(organization_id, created_at)
+    WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS alert_dedup_decisions (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    mode TEXT NOT NULL CHECK (mode IN ('auto', 'manual')),
+    outcome TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (organization_id, day, mode, outcome)
+);
+
+COMMENT ON TABLE alert_dedup_reviews IS 'Alerts parked for a person to decide which incident they belong to';
+COMMENT ON TABLE alert_dedup_decisions IS 'Daily counts of automatic vs manual alert deduplication decisions';
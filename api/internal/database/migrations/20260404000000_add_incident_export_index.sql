@@ -0,0 +1,5 @@
+-- Migration: Index for the incident export API
+-- GET /export/incidents walks an organization's incidents in
+-- (updated_at, id) order so nightly warehouse loads can resume from a cursor.
+
+CREATE INDEX IF NOT EXISTS idx_incidents_org_updated_id ON incidents(organization_id, updated_at, id);
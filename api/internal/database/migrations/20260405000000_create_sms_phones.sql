@@ -0,0 +1,22 @@
+-- Migration: phone numbers linked to users for SMS incident commands
+-- A number is linked once the user texts the verification code from it, which
+-- proves they hold it. phone_number is encrypted at rest; phone_lookup
+-- (SHA-256 of the digits) matches the sender of inbound messages.
+
+CREATE TABLE IF NOT EXISTS sms_phones (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    phone_number TEXT NOT NULL,
+    phone_lookup TEXT NOT NULL,
+    verification_code_hash TEXT,
+    code_expires_at TIMESTAMPTZ,
+    verified_at TIMESTAMPTZ,
+    last_inbound_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sms_phones_phone_lookup ON sms_phones(phone_lookup);
+
+-- A number can only be verified for one user at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sms_phones_verified_lookup
+    ON sms_phones(phone_lookup) WHERE verified_at IS NOT NULL;
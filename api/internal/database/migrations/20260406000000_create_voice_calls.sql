@@ -0,0 +1,25 @@
+-- Migration: phone-call pages with an IVR menu
+-- Users with a verified SMS number can also be called when paged
+-- (voice_enabled). Each call reads a summary of the incident and offers
+-- 1 to acknowledge, 2 to escalate; its outcome is kept here and recorded as a
+-- "voice_call" incident event.
+
+ALTER TABLE sms_phones ADD COLUMN IF NOT EXISTS voice_enabled BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS voice_calls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification_type TEXT NOT NULL,
+    call_sid TEXT,
+    status TEXT NOT NULL DEFAULT 'queued',
+    outcome TEXT CHECK (outcome IN ('acknowledged', 'escalated', 'escalation_failed', 'no_input', 'unanswered', 'failed')),
+    digits TEXT,
+    duration_seconds INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_voice_calls_incident_id ON voice_calls(incident_id, created_at DESC);
+
+SELECT pgmq.create('voice_calls');
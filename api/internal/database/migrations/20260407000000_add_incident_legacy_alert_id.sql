@@ -0,0 +1,19 @@
+-- Migration: Retire legacy alerts onto incidents
+-- cmd/alertmigrate copies each row of the pre-incident alerts table into an
+-- incident, keeping its timestamps and ack state. legacy_alert_id records where
+-- it came from, so /alerts/:id links held by old mobile clients keep resolving.
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS legacy_alert_id UUID;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_legacy_alert_id
+    ON incidents(legacy_alert_id)
+    WHERE legacy_alert_id IS NOT NULL;
+
+COMMENT ON COLUMN incidents.legacy_alert_id IS 'alerts.id this incident was migrated from; NULL for incidents created natively';
+
+-- Legacy-only fields, kept until /alerts clients are gone
+COMMENT ON TABLE alerts IS 'DEPRECATED: superseded by incidents; migrate with cmd/alertmigrate, /alerts is served from incidents';
+COMMENT ON COLUMN alerts.escalation_rule_id IS 'DEPRECATED: legacy-only, incidents use escalation_policy_id';
+COMMENT ON COLUMN alerts.code IS 'DEPRECATED: legacy-only, not carried over to incidents';
+COMMENT ON COLUMN alerts.count IS 'DEPRECATED: legacy-only, incidents track alert_count';
+COMMENT ON COLUMN alerts.author IS 'DEPRECATED: legacy-only, not carried over to incidents';
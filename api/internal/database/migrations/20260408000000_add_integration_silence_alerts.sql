@@ -0,0 +1,8 @@
+-- Migration: Integration silence alerting
+-- An integration whose heartbeat (last webhook received) is older than its
+-- silence_alert.after_minutes during its expected activity hours opens a
+-- "monitoring pipeline degraded" incident; it resolves once webhooks resume.
+
+ALTER TABLE integrations ADD COLUMN IF NOT EXISTS silence_alert JSONB;
+
+COMMENT ON COLUMN integrations.silence_alert IS 'Page when no webhook arrives for after_minutes within from_hour..to_hour on days (timezone); NULL = disabled';
@@ -0,0 +1,29 @@
+-- Migration: Multiple active routing keys per service
+-- services.routing_key stays the primary key (shown in webhook URLs). Every key a service accepts,
+-- including the primary, has a row here with its expiry and usage, so a key can be rotated while
+-- senders still using the old one keep working until its grace period ends.
+
+CREATE TABLE IF NOT EXISTS service_routing_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    routing_key TEXT NOT NULL UNIQUE,
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- NULL = never expires; set on the previous key when the service's key is rotated
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    use_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_routing_keys_service
+    ON service_routing_keys(service_id);
+
+INSERT INTO service_routing_keys (service_id, routing_key, created_by, created_at)
+SELECT id, routing_key, created_by, created_at
+FROM services
+WHERE routing_key IS NOT NULL AND routing_key <> ''
+ON CONFLICT (routing_key) DO NOTHING;
+
+COMMENT ON TABLE service_routing_keys IS 'Routing keys a service accepts events on, with expiry for rotation grace periods and per-key usage';
+COMMENT ON COLUMN services.routing_key IS 'Primary webhook key used to route alerts to this service. Older keys stay valid through service_routing_keys until they expire.';
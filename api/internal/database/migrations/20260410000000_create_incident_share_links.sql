@@ -0,0 +1,35 @@
+-- Migration: Public incident share links
+-- Expiring, revocable links to a read-only incident summary for customers and vendors without a
+-- SLAR account. Only the SHA-256 of the token is stored; every view is logged.
+
+CREATE TABLE IF NOT EXISTS incident_share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    -- Who the link was made for, e.g. "Acme Corp support"
+    label TEXT,
+    -- Whether incident notes are shown as status updates
+    include_notes BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    revoked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    view_count INTEGER NOT NULL DEFAULT 0,
+    last_viewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_share_links_incident ON incident_share_links(incident_id);
+
+CREATE TABLE IF NOT EXISTS incident_share_link_views (
+    id BIGSERIAL PRIMARY KEY,
+    link_id UUID NOT NULL REFERENCES incident_share_links(id) ON DELETE CASCADE,
+    viewed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ip_address TEXT,
+    user_agent TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_share_link_views_link ON incident_share_link_views(link_id, viewed_at DESC);
+
+COMMENT ON TABLE incident_share_links IS 'Expiring public links to a read-only incident summary. Raw token shown once on creation.';
+COMMENT ON TABLE incident_share_link_views IS 'Access log of public incident share links';
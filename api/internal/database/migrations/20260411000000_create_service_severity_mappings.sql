@@ -0,0 +1,21 @@
+-- Migration: Service severity mappings
+-- Separates an alert's technical severity (what the monitoring tool reports)
+-- from the business priority and urgency of the incident it opens. Each
+-- service maps the severities it cares about to a priority and/or urgency, so
+-- a "critical" alert on a dev cluster can open a low-urgency P4 while the
+-- same severity on production pages as a P1. Unmapped severities keep the
+-- global defaults.
+
+CREATE TABLE IF NOT EXISTS service_severity_mappings (
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    severity TEXT NOT NULL,
+    priority TEXT CHECK (priority IN ('P1', 'P2', 'P3', 'P4', 'P5')),
+    urgency TEXT CHECK (urgency IN ('high', 'low')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (service_id, severity),
+    CONSTRAINT service_severity_mappings_action CHECK (priority IS NOT NULL OR urgency IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_priority ON incidents(priority) WHERE priority IS NOT NULL;
+
+COMMENT ON TABLE service_severity_mappings IS 'Per-service mapping from technical alert severity to business priority and urgency';
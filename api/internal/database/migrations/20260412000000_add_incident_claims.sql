@@ -0,0 +1,12 @@
+-- Migration: Incident claims
+-- A responder claims an incident to take its assignment and tell everyone
+-- else it is being worked. Claims are compare-and-swap on the incident row:
+-- only an unclaimed, unresolved incident can be claimed, so two people paged
+-- for the same incident can't both take it. Acknowledging also claims an
+-- unclaimed incident.
+
+ALTER TABLE incidents
+    ADD COLUMN IF NOT EXISTS claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN incidents.claimed_by IS 'Responder who claimed the incident; NULL while unclaimed';
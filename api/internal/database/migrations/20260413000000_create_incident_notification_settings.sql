@@ -0,0 +1,23 @@
+-- Migration: Per-incident notification settings
+-- Lets an ongoing incident's commander (whoever claimed it, otherwise its
+-- assignee) tune how noisy the incident is without touching anyone's
+-- personal preferences: how much of it reaches the group's chat channels,
+-- which users stop getting update notifications (pages still go out), and
+-- how often a status update is posted to chat. The row is deleted when the
+-- incident resolves, so the overrides never outlive the incident.
+
+CREATE TABLE IF NOT EXISTS incident_notification_settings (
+    incident_id UUID PRIMARY KEY REFERENCES incidents(id) ON DELETE CASCADE,
+    chat_updates TEXT NOT NULL DEFAULT 'all' CHECK (chat_updates IN ('all', 'pages_only', 'none')),
+    muted_user_ids TEXT[] NOT NULL DEFAULT '{}',
+    status_update_minutes INTEGER NOT NULL DEFAULT 0 CHECK (status_update_minutes = 0 OR status_update_minutes BETWEEN 5 AND 240),
+    last_status_update_at TIMESTAMPTZ,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_notification_settings_status_updates
+    ON incident_notification_settings(incident_id) WHERE status_update_minutes > 0;
+
+COMMENT ON TABLE incident_notification_settings IS 'Notification overrides for one ongoing incident, reset on resolution';
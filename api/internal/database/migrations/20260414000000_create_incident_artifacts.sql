@@ -0,0 +1,35 @@
+-- Migration: Incident diagnostic artifacts
+-- Output of diagnostic commands (fetched logs, kubectl describe, ...) run by
+-- the AI agent or pasted by responders, kept on the incident as shared
+-- evidence instead of disappearing with the chat session. Content is
+-- redacted before it is stored; the number of redactions is recorded so
+-- readers know something was removed. Each artifact expires after its
+-- retention period and is purged by the incident worker.
+
+CREATE TABLE IF NOT EXISTS incident_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('logs', 'kubectl', 'command', 'metrics', 'other')),
+    source TEXT NOT NULL CHECK (source IN ('agent', 'user')),
+    title TEXT NOT NULL,
+    command TEXT,
+    content TEXT NOT NULL,
+    content_sha256 TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    redactions INTEGER NOT NULL DEFAULT 0,
+    session_id TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title || ' ' || COALESCE(command, '')), 'A') ||
+        setweight(to_tsvector('simple', content), 'B')
+    ) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_artifacts_incident ON incident_artifacts(incident_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_incident_artifacts_expires ON incident_artifacts(expires_at);
+CREATE INDEX IF NOT EXISTS idx_incident_artifacts_search ON incident_artifacts USING gin(search_vector);
+
+COMMENT ON TABLE incident_artifacts IS 'Redacted diagnostic command output attached to incidents, with retention';
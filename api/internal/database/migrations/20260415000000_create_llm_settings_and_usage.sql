@@ -0,0 +1,36 @@
+-- Migration: LLM provider settings and usage metering
+-- AI features call a language model through a provider abstraction (OpenAI,
+-- Anthropic, Azure OpenAI or a local Ollama server). The instance default
+-- comes from config (llm.*); an organization can bring its own provider,
+-- model and key, and cap how many tokens it uses per calendar month. Every
+-- call is metered in llm_usage so admins can see where tokens go.
+
+CREATE TABLE IF NOT EXISTS org_llm_settings (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    provider TEXT,              -- NULL: use the instance default provider
+    model TEXT,
+    api_key TEXT,               -- encrypted at rest
+    base_url TEXT,
+    api_version TEXT,
+    monthly_token_budget BIGINT CHECK (monthly_token_budget >= 0), -- NULL: instance default, 0: unlimited
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    feature TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    succeeded BOOLEAN NOT NULL DEFAULT true,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_org_created ON llm_usage(organization_id, created_at);
+
+COMMENT ON TABLE org_llm_settings IS 'Per-organization LLM provider, key and monthly token budget';
+COMMENT ON TABLE llm_usage IS 'One row per LLM call, for budgets and usage reports';
@@ -0,0 +1,21 @@
+-- Migration: Recurring problem reports
+-- The incident worker clusters each organization's resolved incidents from
+-- the last recurring_problems_weeks weeks by alert fingerprint and title
+-- similarity, per service, and keeps the latest result here for the
+-- analytics API. Each run replaces the organization's previous report.
+
+CREATE TABLE IF NOT EXISTS recurring_problem_reports (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL,
+    incidents_analyzed INTEGER NOT NULL DEFAULT 0,
+    -- Services with their top recurring problems, as served by the API
+    services JSONB NOT NULL DEFAULT '[]',
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Finds the resolved incidents a report is built from
+CREATE INDEX IF NOT EXISTS idx_incidents_org_resolved_at
+    ON incidents(organization_id, resolved_at) WHERE status = 'resolved';
+
+COMMENT ON TABLE recurring_problem_reports IS 'Latest top recurring problems per service for each organization, rebuilt daily';
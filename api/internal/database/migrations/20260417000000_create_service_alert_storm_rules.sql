@@ -0,0 +1,25 @@
+-- Migration: Alert storm detection
+-- Self-monitoring on incident volume: when a service opens incidents at more
+-- than spike_multiple times its baseline rate, the incident worker opens one
+-- "alert storm" meta-incident for it (incident_key alert-storm:<service_id>)
+-- and resolves it once the rate falls back. With group_alerts set, new alerts
+-- for the service join the storm incident instead of each opening and paging
+-- for their own incident until the storm subsides.
+
+CREATE TABLE IF NOT EXISTS service_alert_storm_rules (
+    service_id UUID PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    spike_multiple DOUBLE PRECISION NOT NULL CHECK (spike_multiple > 1),
+    window_minutes INTEGER NOT NULL CHECK (window_minutes BETWEEN 1 AND 240),
+    baseline_hours INTEGER NOT NULL CHECK (baseline_hours BETWEEN 1 AND 720),
+    min_incidents INTEGER NOT NULL CHECK (min_incidents >= 1),
+    group_alerts BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_alert_storm_rules_baseline CHECK (baseline_hours * 60 > window_minutes)
+);
+
+-- Counts a service's recent incidents
+CREATE INDEX IF NOT EXISTS idx_incidents_service_created_at ON incidents(service_id, created_at);
+
+COMMENT ON TABLE service_alert_storm_rules IS 'Per-service incident rate spike detection; opens an alert storm meta-incident and optionally groups alerts into it';
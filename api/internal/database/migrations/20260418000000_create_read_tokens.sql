@@ -0,0 +1,22 @@
+-- Migration: Read-only API tokens
+-- User-owned bearer tokens for Grafana JSON datasources and internal portals.
+-- They only reach the GET endpoints under /read allowed by their scopes and
+-- only return what their owner can see; they can never change anything.
+
+CREATE TABLE IF NOT EXISTS read_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_read_tokens_user ON read_tokens(user_id);
+
+COMMENT ON TABLE read_tokens IS 'Read-only, endpoint-scoped bearer tokens acting as their owner. Raw token shown once on creation.';
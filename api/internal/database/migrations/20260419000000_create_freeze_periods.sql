@@ -0,0 +1,28 @@
+-- Migration: Freeze periods
+-- Declared code freeze or high-sensitivity periods (e.g. Black Friday) for a
+-- whole organization or one group. While a period is active, integrations and
+-- monitors no longer auto-resolve its incidents, escalation step timeouts are
+-- cut to escalation_timeout_percent of their configured length, and every new
+-- incident pages reviewer_ids in addition to the escalation policy.
+
+CREATE TABLE IF NOT EXISTS freeze_periods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    group_id UUID REFERENCES groups(id) ON DELETE CASCADE, -- NULL = whole organization
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ NOT NULL,
+    disable_auto_resolve BOOLEAN NOT NULL DEFAULT TRUE,
+    escalation_timeout_percent INTEGER NOT NULL DEFAULT 50 CHECK (escalation_timeout_percent BETWEEN 1 AND 100),
+    reviewer_ids UUID[] NOT NULL DEFAULT '{}',
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT freeze_periods_range_valid CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_freeze_periods_org_time
+    ON freeze_periods(organization_id, start_time, end_time);
+
+COMMENT ON TABLE freeze_periods IS 'Org or group code freeze periods: no auto-resolve, shorter escalation timeouts, extra reviewers paged';
@@ -0,0 +1,33 @@
+-- Migration: FCM devices per user
+-- One row per app installation registered for push notifications, so a user
+-- with a phone and a tablet gets paged on both. fcm_token is encrypted at
+-- rest; token_lookup (SHA-256 of the token) makes re-registering the same
+-- installation idempotent. Tokens FCM reports as unregistered are deleted,
+-- and failure_count tracks other send errors until the token is pruned.
+
+CREATE TABLE IF NOT EXISTS user_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fcm_token TEXT NOT NULL,
+    token_lookup TEXT NOT NULL UNIQUE,
+    platform TEXT,
+    app_version TEXT,
+    device_name TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_success_at TIMESTAMPTZ,
+    last_validated_at TIMESTAMPTZ,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_devices_user_id ON user_devices(user_id);
+
+-- Carry over the single token users had before. The token may already be
+-- encrypted, so it can't be hashed here: the row gets a placeholder lookup and
+-- is replaced when the app registers the same token again.
+INSERT INTO user_devices (user_id, fcm_token, token_lookup)
+SELECT id, fcm_token, 'legacy:' || id::text
+FROM users
+WHERE fcm_token IS NOT NULL AND fcm_token <> ''
+ON CONFLICT (token_lookup) DO NOTHING;
@@ -0,0 +1,16 @@
+-- Migration: incident short IDs
+-- A short, case-insensitive ID per incident for deep links (/r/<short_id>)
+-- that open the incident in the mobile app or the web UI. Generated by the
+-- database on insert so every way of creating an incident gets one; adding
+-- the column fills it for existing incidents. 10 characters from a
+-- 31-letter alphabet without look-alikes (0/o, 1/l/i) keep collisions
+-- negligible.
+
+CREATE OR REPLACE FUNCTION generate_incident_short_id() RETURNS TEXT AS $$
+    SELECT string_agg(substr('23456789abcdefghjkmnpqrstuvwxyz', 1 + floor(random() * 31)::int, 1), '')
+    FROM generate_series(1, 10)
+$$ LANGUAGE sql VOLATILE;
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS short_id TEXT NOT NULL DEFAULT generate_incident_short_id();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_short_id ON incidents(short_id);
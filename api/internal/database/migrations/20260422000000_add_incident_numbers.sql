@@ -0,0 +1,53 @@
+-- Migration: per-organization incident numbers
+-- Sequential numbers (shown as INC-1024) that people can say out loud and
+-- type in chat, alongside the UUID. Numbers count up per organization from
+-- incident_number_counters, assigned by a trigger so every way of creating an
+-- incident gets one. Incidents without an organization share the nil UUID's
+-- counter.
+
+CREATE TABLE IF NOT EXISTS incident_number_counters (
+    organization_id UUID PRIMARY KEY,
+    last_number BIGINT NOT NULL
+);
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS number BIGINT;
+
+-- Number existing incidents in creation order, keeping their updated_at
+ALTER TABLE incidents DISABLE TRIGGER trigger_incidents_updated_at;
+WITH numbered AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY organization_id ORDER BY created_at, id) AS n
+    FROM incidents
+)
+UPDATE incidents i SET number = numbered.n
+FROM numbered
+WHERE i.id = numbered.id AND i.number IS NULL;
+ALTER TABLE incidents ENABLE TRIGGER trigger_incidents_updated_at;
+
+INSERT INTO incident_number_counters (organization_id, last_number)
+SELECT COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid), MAX(number)
+FROM incidents
+GROUP BY 1
+ON CONFLICT (organization_id) DO UPDATE SET last_number = GREATEST(incident_number_counters.last_number, EXCLUDED.last_number);
+
+CREATE OR REPLACE FUNCTION assign_incident_number() RETURNS trigger AS $$
+BEGIN
+  IF NEW.number IS NULL THEN
+    INSERT INTO incident_number_counters (organization_id, last_number)
+    VALUES (COALESCE(NEW.organization_id, '00000000-0000-0000-0000-000000000000'::uuid), 1)
+    ON CONFLICT (organization_id) DO UPDATE SET last_number = incident_number_counters.last_number + 1
+    RETURNING last_number INTO NEW.number;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS incidents_assign_number ON incidents;
+CREATE TRIGGER incidents_assign_number
+  BEFORE INSERT ON incidents
+  FOR EACH ROW
+  EXECUTE FUNCTION assign_incident_number();
+
+ALTER TABLE incidents ALTER COLUMN number SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_org_number
+    ON incidents(COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid), number);
@@ -0,0 +1,39 @@
+-- Migration: Incident relations
+-- Typed links from an incident to another incident ("related", "duplicate_of",
+-- "caused_by") or to the change event that caused it. "related" is symmetric;
+-- the others read from incident_id: incident_id is a duplicate of / was caused
+-- by the target. Two incidents are linked at most once, whatever the type.
+
+CREATE TABLE IF NOT EXISTS incident_relations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    related_incident_id UUID REFERENCES incidents(id) ON DELETE CASCADE,
+    change_event_id UUID REFERENCES change_events(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT incident_relations_type_valid CHECK (type IN ('related', 'duplicate_of', 'caused_by')),
+    CONSTRAINT incident_relations_one_target CHECK ((related_incident_id IS NULL) <> (change_event_id IS NULL)),
+    CONSTRAINT incident_relations_change_caused_by CHECK (change_event_id IS NULL OR type = 'caused_by'),
+    CONSTRAINT incident_relations_not_self CHECK (related_incident_id IS DISTINCT FROM incident_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incident_relations_pair
+    ON incident_relations(LEAST(incident_id, related_incident_id), GREATEST(incident_id, related_incident_id))
+    WHERE related_incident_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incident_relations_change
+    ON incident_relations(incident_id, change_event_id)
+    WHERE change_event_id IS NOT NULL;
+
+-- An incident is a duplicate of one incident at most
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incident_relations_duplicate_of
+    ON incident_relations(incident_id)
+    WHERE type = 'duplicate_of';
+
+-- Both directions: listing an incident's relations and walking "caused by" chains
+CREATE INDEX IF NOT EXISTS idx_incident_relations_incident ON incident_relations(incident_id);
+CREATE INDEX IF NOT EXISTS idx_incident_relations_related ON incident_relations(related_incident_id) WHERE related_incident_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_incident_relations_change_event ON incident_relations(change_event_id) WHERE change_event_id IS NOT NULL;
+
+COMMENT ON TABLE incident_relations IS 'Typed links between incidents, and from incidents to the changes that caused them';
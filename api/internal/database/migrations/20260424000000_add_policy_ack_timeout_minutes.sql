@@ -0,0 +1,9 @@
+-- Migration: Acknowledgment timeout re-escalation
+-- By default acknowledging an incident stops its escalation for good. With
+-- ack_timeout_minutes set, an incident still acknowledged (not resolved) that
+-- many minutes after the acknowledgment goes back to triggered and escalation
+-- continues with the next level, or pages the last level again.
+
+ALTER TABLE escalation_policies ADD COLUMN IF NOT EXISTS ack_timeout_minutes INTEGER;
+
+COMMENT ON COLUMN escalation_policies.ack_timeout_minutes IS 'Re-trigger and keep escalating incidents still unresolved this many minutes after acknowledgment; NULL/0 = acknowledgment stops escalation';
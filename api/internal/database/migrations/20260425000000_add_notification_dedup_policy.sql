@@ -0,0 +1,14 @@
+-- Migration: cross-channel notification dedup
+-- A page normally reaches a user as a Slack DM, a group channel post and a
+-- push notification at the same moment. With dedup_policy 'push_first' the
+-- Slack DM of a page is held back dedup_delay_seconds and dropped if the push
+-- was delivered, the page was seen or the incident is no longer triggered by
+-- then. Group channel posts are shared by the team and are never held.
+
+ALTER TABLE user_notification_configs
+    ADD COLUMN IF NOT EXISTS dedup_policy TEXT NOT NULL DEFAULT 'all'
+        CHECK (dedup_policy IN ('all', 'push_first')),
+    ADD COLUMN IF NOT EXISTS dedup_delay_seconds INTEGER NOT NULL DEFAULT 60
+        CHECK (dedup_delay_seconds BETWEEN 10 AND 900);
+
+SELECT pgmq.create('held_slack_notifications');
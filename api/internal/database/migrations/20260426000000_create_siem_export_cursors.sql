@@ -0,0 +1,23 @@
+-- Migration: SIEM export
+-- A "siem" integration streams its organization's incident lifecycle events
+-- (incident_events) and audit events (agent_audit_logs) to Splunk HEC,
+-- Elasticsearch or a syslog collector, as JSON or CEF. The worker reads both
+-- tables in (time, id) order; the position it has exported up to is kept here
+-- per integration. New integrations start at the moment they are first seen
+-- instead of replaying history.
+
+CREATE TABLE IF NOT EXISTS siem_export_cursors (
+    integration_id UUID PRIMARY KEY REFERENCES integrations(id) ON DELETE CASCADE,
+    incident_event_at TIMESTAMP NOT NULL,
+    incident_event_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    audit_event_at TIMESTAMPTZ NOT NULL,
+    audit_event_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    exported_count BIGINT NOT NULL DEFAULT 0,
+    last_exported_at TIMESTAMPTZ,
+    last_error TEXT,
+    last_error_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_audit_logs_org_export ON agent_audit_logs (org_id, event_time, id);
@@ -0,0 +1,257 @@
+-- Migration: Partition incidents and incident_events by month
+-- Both tables become partitioned by RANGE (created_at) with one partition per
+-- month, named <table>_YYYY_MM. Time-ranged reads (the incident list,
+-- analytics, the activity feed, SIEM export) only touch the months they
+-- cover, and retention drops whole incident_events partitions instead of
+-- running large DELETEs. The worker creates upcoming months ahead of time
+-- (IncidentService.MaintainIncidentPartitions); <table>_default catches rows
+-- outside every monthly range, such as alerts migrated with old timestamps.
+--
+-- A unique key on a partitioned table must include the partition key, so
+-- incidents' primary key becomes (id, created_at) and the keys that must
+-- stay unique across months (id, short_id, the per-organization number and
+-- legacy_alert_id) move to incident_keys, kept in step by a trigger. Every
+-- foreign key that referenced incidents(id) now references incident_keys(id)
+-- with the same ON DELETE action; deleting an incident deletes its key,
+-- which cascades as before. created_at can no longer change once set.
+
+-- create_monthly_partition creates the partition of parent for the month
+-- containing month_start and returns false when it already exists. Rows for
+-- that month sitting in the default partition are moved into the new
+-- partition before it is attached, since attaching fails while the default
+-- partition holds rows in its range.
+CREATE OR REPLACE FUNCTION create_monthly_partition(parent regclass, month_start timestamp) RETURNS boolean AS $$
+DECLARE
+    lower_bound timestamp := date_trunc('month', month_start);
+    upper_bound timestamp := date_trunc('month', month_start) + interval '1 month';
+    parent_name text := (SELECT relname FROM pg_class WHERE oid = parent);
+    partition_name text := parent_name || '_' || to_char(lower_bound, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN false;
+    END IF;
+    EXECUTE format(
+        'CREATE TABLE %I (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING GENERATED)',
+        partition_name, parent
+    );
+    IF to_regclass(parent_name || '_default') IS NOT NULL THEN
+        -- Moving rows is not deleting them: sync_incident_keys keeps their keys
+        PERFORM set_config('slar.moving_partition_rows', 'on', true);
+        EXECUTE format(
+            'WITH moved AS (DELETE FROM %I WHERE created_at >= %L AND created_at < %L RETURNING *) INSERT INTO %I SELECT * FROM moved',
+            parent_name || '_default', lower_bound, upper_bound, partition_name
+        );
+        PERFORM set_config('slar.moving_partition_rows', 'off', true);
+    END IF;
+    EXECUTE format(
+        'ALTER TABLE %s ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+        parent, partition_name, lower_bound, upper_bound
+    );
+    RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Incidents -------------------------------------------------------------------
+
+CREATE TABLE incident_keys (
+    id uuid PRIMARY KEY,
+    organization_id uuid,
+    number bigint NOT NULL,
+    short_id text NOT NULL UNIQUE,
+    legacy_alert_id uuid UNIQUE
+);
+
+CREATE UNIQUE INDEX idx_incident_keys_org_number
+    ON incident_keys (COALESCE(organization_id, '00000000-0000-0000-0000-000000000000'::uuid), number);
+
+INSERT INTO incident_keys (id, organization_id, number, short_id, legacy_alert_id)
+SELECT id, organization_id, number, short_id, legacy_alert_id FROM incidents;
+
+-- Point every foreign key at incident_keys, keeping its name and actions
+DO $$
+DECLARE
+    fk record;
+BEGIN
+    FOR fk IN
+        SELECT conrelid::regclass AS tbl, conname, pg_get_constraintdef(oid) AS def
+        FROM pg_constraint
+        WHERE contype = 'f' AND confrelid = 'incidents'::regclass
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %s DROP CONSTRAINT %I, ADD CONSTRAINT %I %s',
+            fk.tbl, fk.conname, fk.conname,
+            regexp_replace(fk.def, 'REFERENCES (public\.)?incidents\(', 'REFERENCES incident_keys(')
+        );
+    END LOOP;
+END $$;
+
+ALTER TABLE incidents RENAME TO incidents_unpartitioned;
+ALTER TABLE incidents_unpartitioned RENAME CONSTRAINT incidents_pkey TO incidents_unpartitioned_pkey;
+
+CREATE TABLE incidents (
+    LIKE incidents_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING GENERATED INCLUDING COMMENTS,
+    CONSTRAINT incidents_pkey PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE incidents_default PARTITION OF incidents DEFAULT;
+
+-- Partitions for every month with existing incidents, plus the next three
+DO $$
+DECLARE
+    month timestamp;
+BEGIN
+    FOR month IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE((SELECT MIN(created_at) FROM incidents_unpartitioned), now()::timestamp)),
+            date_trunc('month', now()::timestamp) + interval '3 months',
+            interval '1 month'
+        )
+    LOOP
+        PERFORM create_monthly_partition('incidents', month);
+    END LOOP;
+END $$;
+
+-- Copied before the triggers exist so rows keep their numbers and timestamps
+INSERT INTO incidents SELECT * FROM incidents_unpartitioned;
+
+-- Carry over the outgoing foreign keys, triggers and indexes. Unique indexes
+-- become plain ones; incident_keys enforces those keys now.
+DO $$
+DECLARE
+    con record;
+    trg record;
+    idx record;
+    index_defs text[] := '{}';
+    index_def text;
+BEGIN
+    FOR con IN
+        SELECT conname, pg_get_constraintdef(oid) AS def
+        FROM pg_constraint
+        WHERE contype = 'f' AND conrelid = 'incidents_unpartitioned'::regclass
+    LOOP
+        EXECUTE format('ALTER TABLE incidents ADD CONSTRAINT %I %s', con.conname, con.def);
+    END LOOP;
+
+    FOR trg IN
+        SELECT pg_get_triggerdef(oid) AS def
+        FROM pg_trigger
+        WHERE tgrelid = 'incidents_unpartitioned'::regclass AND NOT tgisinternal
+    LOOP
+        EXECUTE regexp_replace(trg.def, ' ON (public\.)?incidents_unpartitioned ', ' ON incidents ');
+    END LOOP;
+
+    FOR idx IN
+        SELECT pg_get_indexdef(indexrelid) AS def
+        FROM pg_index
+        WHERE indrelid = 'incidents_unpartitioned'::regclass AND NOT indisprimary
+    LOOP
+        index_defs := index_defs || regexp_replace(
+            regexp_replace(idx.def, '^CREATE UNIQUE INDEX', 'CREATE INDEX'),
+            ' ON (public\.)?incidents_unpartitioned ', ' ON incidents ');
+    END LOOP;
+
+    DROP TABLE incidents_unpartitioned;
+
+    FOREACH index_def IN ARRAY index_defs LOOP
+        EXECUTE index_def;
+    END LOOP;
+END $$;
+
+-- sync_incident_keys mirrors incidents' unique keys into incident_keys.
+-- Rows moved out of the default partition by create_monthly_partition are
+-- not deleted incidents and keep their keys.
+CREATE OR REPLACE FUNCTION sync_incident_keys() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO incident_keys (id, organization_id, number, short_id, legacy_alert_id)
+        VALUES (NEW.id, NEW.organization_id, NEW.number, NEW.short_id, NEW.legacy_alert_id);
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE incident_keys
+        SET id = NEW.id, organization_id = NEW.organization_id, number = NEW.number,
+            short_id = NEW.short_id, legacy_alert_id = NEW.legacy_alert_id
+        WHERE id = OLD.id;
+    ELSIF current_setting('slar.moving_partition_rows', true) IS DISTINCT FROM 'on' THEN
+        DELETE FROM incident_keys WHERE id = OLD.id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER incidents_sync_keys
+  AFTER INSERT OR DELETE OR UPDATE OF id, organization_id, number, short_id, legacy_alert_id ON incidents
+  FOR EACH ROW
+  EXECUTE FUNCTION sync_incident_keys();
+
+-- Changing created_at would move the row to another partition, which runs
+-- as a delete and an insert and would cascade the delete through
+-- incident_keys
+CREATE OR REPLACE FUNCTION reject_incident_created_at_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'incidents.created_at cannot be changed';
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER incidents_created_at_immutable
+  BEFORE UPDATE OF created_at ON incidents
+  FOR EACH ROW
+  WHEN (NEW.created_at IS DISTINCT FROM OLD.created_at)
+  EXECUTE FUNCTION reject_incident_created_at_change();
+
+ALTER TABLE incidents DISABLE ROW LEVEL SECURITY;
+
+-- Incident events -------------------------------------------------------------
+
+ALTER TABLE incident_events RENAME TO incident_events_unpartitioned;
+ALTER TABLE incident_events_unpartitioned RENAME CONSTRAINT incident_events_pkey TO incident_events_unpartitioned_pkey;
+ALTER TABLE incident_events_unpartitioned RENAME CONSTRAINT incident_events_created_by_fkey TO incident_events_unpartitioned_created_by_fkey;
+ALTER INDEX idx_incident_events_created_at RENAME TO idx_incident_events_unpartitioned_created_at;
+ALTER INDEX idx_incident_events_incident_id RENAME TO idx_incident_events_unpartitioned_incident_id;
+ALTER INDEX IF EXISTS idx_incident_events_feed RENAME TO idx_incident_events_unpartitioned_feed;
+
+CREATE TABLE incident_events (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    incident_id uuid NOT NULL,
+    event_type text NOT NULL,
+    event_data jsonb,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    created_by uuid,
+    CONSTRAINT incident_events_pkey PRIMARY KEY (id, created_at),
+    CONSTRAINT incident_events_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE incident_events_default PARTITION OF incident_events DEFAULT;
+
+CREATE INDEX idx_incident_events_created_at ON incident_events (created_at DESC);
+CREATE INDEX idx_incident_events_incident_id ON incident_events (incident_id);
+CREATE INDEX idx_incident_events_feed ON incident_events (created_at DESC, id DESC);
+
+-- Partitions for every month with existing events, plus the next three
+DO $$
+DECLARE
+    month timestamp;
+BEGIN
+    FOR month IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE((SELECT MIN(created_at) FROM incident_events_unpartitioned), now()::timestamp)),
+            date_trunc('month', now()::timestamp) + interval '3 months',
+            interval '1 month'
+        )
+    LOOP
+        PERFORM create_monthly_partition('incident_events', month);
+    END LOOP;
+END $$;
+
+INSERT INTO incident_events (id, incident_id, event_type, event_data, created_at, created_by)
+SELECT id, incident_id, event_type, event_data, COALESCE(created_at, now()::timestamp), created_by
+FROM incident_events_unpartitioned;
+
+DROP TABLE incident_events_unpartitioned;
+
+-- Row triggers on a partitioned table apply to every partition
+CREATE TRIGGER incident_events_resolution_index_update
+  AFTER INSERT ON incident_events
+  FOR EACH ROW
+  WHEN (NEW.event_type IN ('resolved', 'note_added'))
+  EXECUTE FUNCTION incident_events_resolution_index_trigger();
+
+ALTER TABLE incident_events DISABLE ROW LEVEL SECURITY;
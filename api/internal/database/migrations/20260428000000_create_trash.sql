@@ -0,0 +1,25 @@
+-- Migration: Trash bin for deleted configuration
+-- Deleting a service, scheduler, escalation policy or integration records it
+-- here so an admin can restore it (POST /trash/:type/:id/restore) until the
+-- worker purges the entry after trash_retention. Services and schedulers are
+-- soft-deleted, so their snapshot only lists the child rows that were
+-- deactivated with them; escalation policies and integrations are deleted
+-- outright, so their snapshot holds the rows needed to re-insert them.
+-- Snapshots are never returned by the API: integration configs carry sealed
+-- credentials.
+
+CREATE TABLE IF NOT EXISTS trash (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    resource_type TEXT NOT NULL
+        CHECK (resource_type IN ('service', 'scheduler', 'escalation_policy', 'integration')),
+    resource_id UUID NOT NULL,
+    organization_id UUID,
+    name TEXT NOT NULL DEFAULT '',
+    snapshot JSONB NOT NULL DEFAULT '{}',
+    deleted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (resource_type, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trash_org_deleted ON trash (organization_id, deleted_at DESC);
+CREATE INDEX IF NOT EXISTS idx_trash_deleted_at ON trash (deleted_at);
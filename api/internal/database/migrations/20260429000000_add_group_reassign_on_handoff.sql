@@ -0,0 +1,8 @@
+-- Migration: Reassign open incidents on shift handoff
+-- When a group opts in, open incidents assigned to someone whose shift in the
+-- group has ended are handed to whoever is on call now, so they don't stay
+-- with someone who is off duty. Off by default.
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS reassign_on_handoff BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN groups.reassign_on_handoff IS 'Hand open incidents to the incoming on-call when the assignee''s shift ends';
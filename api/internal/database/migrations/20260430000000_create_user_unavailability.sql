@@ -0,0 +1,28 @@
+-- Migration: Vacation/DND-aware escalation
+-- People record when they are away (vacation) or must not be paged (dnd).
+-- Escalation targets resolved through schedules skip anyone unavailable at the
+-- time and fall through to the next person on call, or the next level; the
+-- worker logs each skip in the incident timeline. Direct user targets are
+-- still paged.
+
+CREATE TABLE IF NOT EXISTS user_unavailability (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL DEFAULT 'vacation' CHECK (kind IN ('vacation', 'dnd')),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_unavailability_user ON user_unavailability (user_id, ends_at);
+
+-- user_available reports whether a user may be paged at the given time
+CREATE OR REPLACE FUNCTION user_available(p_user_id UUID, p_at TIMESTAMPTZ)
+RETURNS BOOLEAN AS $$
+    SELECT NOT EXISTS (
+        SELECT 1 FROM user_unavailability ua
+        WHERE ua.user_id = p_user_id AND ua.starts_at <= p_at AND ua.ends_at > p_at
+    )
+$$ LANGUAGE sql STABLE;
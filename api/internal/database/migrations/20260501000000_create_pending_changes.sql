@@ -0,0 +1,32 @@
+-- Migration: Two-admin approval for destructive admin operations
+-- Deleting a team that still has members, rotating every API key of an
+-- organization and disabling an integration that feeds live services are
+-- held as pending changes until a second org admin approves them. Pending
+-- changes expire after pending_change_ttl. Each request, decision and
+-- execution is written to agent_audit_logs.
+
+CREATE TABLE IF NOT EXISTS pending_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('group_delete', 'api_keys_rotate', 'integration_disable')),
+    resource_id TEXT NOT NULL DEFAULT '',
+    resource_name TEXT NOT NULL DEFAULT '',
+    -- Request body replayed when the change is approved
+    payload JSONB NOT NULL DEFAULT '{}',
+    reason TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'expired', 'failed')),
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ,
+    error TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_changes_org ON pending_changes (organization_id, status, created_at DESC);
+
+-- One open request per operation and resource
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_changes_open
+    ON pending_changes (organization_id, action, resource_id)
+    WHERE status = 'pending';
@@ -0,0 +1,23 @@
+-- Migration: Organization usage metering
+-- The incident worker meters each organization's usage per calendar month
+-- (UTC): the most members and active services seen during the month, incidents
+-- opened and notifications sent by channel. Past months are finalized once
+-- after they end. Groundwork for cloud billing and self-host capacity planning.
+
+CREATE TABLE IF NOT EXISTS org_usage_monthly (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- First day of the month
+    month DATE NOT NULL,
+    peak_users INTEGER NOT NULL DEFAULT 0,
+    peak_services INTEGER NOT NULL DEFAULT 0,
+    incidents INTEGER NOT NULL DEFAULT 0,
+    -- Notifications sent per channel, e.g. {"email": 12, "slack": 40, "voice": 2}
+    notifications JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, month)
+);
+
+-- Counts the incidents opened in a month
+CREATE INDEX IF NOT EXISTS idx_incidents_org_created_at ON incidents (organization_id, created_at);
+
+COMMENT ON TABLE org_usage_monthly IS 'Monthly usage rollups per organization for billing and capacity planning';
@@ -0,0 +1,20 @@
+-- Migration: Incident activity digests
+-- Group managers (owners and admins) can subscribe to a daily or weekly email
+-- summarizing their groups' incident activity: counts, notable P1s, MTTA/MTTR
+-- against the period before and coverage gaps coming up. The reporting worker
+-- sends each subscription once per period.
+
+CREATE TABLE IF NOT EXISTS activity_digest_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+    -- Groups to cover; empty means every group the user manages
+    group_ids UUID[] NOT NULL DEFAULT '{}',
+    -- End of the last period a digest was sent for
+    last_sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, frequency)
+);
+
+COMMENT ON TABLE activity_digest_subscriptions IS 'Daily or weekly incident activity emails for group managers';
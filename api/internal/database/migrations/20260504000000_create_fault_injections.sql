@@ -0,0 +1,25 @@
+-- Migration: Fault injection for resilience testing
+-- Operators can inject database latency, pgmq failures and notification
+-- provider outages through /internal/faults when fault_injection_enabled is
+-- set, to check escalation and retry paths before a real partial outage. The
+-- API server and workers poll this table, so a fault reaches every process.
+-- Faults expire on their own.
+
+CREATE TABLE IF NOT EXISTS fault_injections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL CHECK (kind IN ('db_latency', 'pgmq_failure', 'provider_outage')),
+    -- db_latency: SQL text to match (empty for every statement)
+    -- pgmq_failure: queue name (empty for every queue)
+    -- provider_outage: email, slack, fcm, web_push, whatsapp or voice
+    target TEXT NOT NULL DEFAULT '',
+    delay_ms INTEGER NOT NULL DEFAULT 0,
+    -- Share of matching calls affected, 0-1
+    rate DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (rate > 0 AND rate <= 1),
+    note TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_fault_injections_expires_at ON fault_injections (expires_at);
+
+COMMENT ON TABLE fault_injections IS 'Faults injected on demand for resilience testing; only honored when fault injection is enabled';
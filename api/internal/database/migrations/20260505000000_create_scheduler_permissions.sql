@@ -0,0 +1,41 @@
+-- Migration: Schedule-level permissions
+-- Only schedule managers may change a scheduler's shifts and overrides: the
+-- group's owners and admins, plus the users listed in scheduler_managers for
+-- that scheduler. Other group members can only ask to swap one of their own
+-- shifts; the request waits in shift_swap_requests until a manager of both
+-- shifts approves or rejects it.
+
+CREATE TABLE IF NOT EXISTS scheduler_managers (
+    scheduler_id UUID NOT NULL REFERENCES schedulers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scheduler_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduler_managers_user ON scheduler_managers (user_id);
+
+CREATE TABLE IF NOT EXISTS shift_swap_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    current_shift_id UUID NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    target_shift_id UUID NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    -- Who held each shift when the swap was requested; approval is refused
+    -- if either has changed since
+    current_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled')),
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shift_swap_requests_group ON shift_swap_requests (group_id, status, created_at DESC);
+
+-- One open request per pair of shifts
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shift_swap_requests_open
+    ON shift_swap_requests (current_shift_id, target_shift_id)
+    WHERE status = 'pending';
@@ -0,0 +1,34 @@
+-- Migration: Incident tagging rules
+-- Organization-wide rules run on every new incident before it is stored.
+-- Each rule's conditions (keyword or regex over the title, the description or
+-- a label) must all match; a matching rule adds tags and can set the
+-- incident's team and component. Rules run in position order; for team and
+-- component the first rule that sets one wins, and a rule with stop set ends
+-- the evaluation.
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS team TEXT;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS component TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_tags ON incidents USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_incidents_org_team ON incidents (organization_id, team) WHERE team IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_incidents_org_component ON incidents (organization_id, component) WHERE component IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS incident_tagging_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    -- [{"field": "title|description|label", "label": "<key>", "match": "keyword|regex", "pattern": "..."}]
+    conditions JSONB NOT NULL DEFAULT '[]',
+    add_tags TEXT[] NOT NULL DEFAULT '{}',
+    set_team TEXT,
+    set_component TEXT,
+    stop BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT incident_tagging_rules_action
+        CHECK (cardinality(add_tags) > 0 OR set_team IS NOT NULL OR set_component IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_tagging_rules_org
+    ON incident_tagging_rules (organization_id, position);
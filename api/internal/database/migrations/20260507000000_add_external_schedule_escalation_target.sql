@@ -0,0 +1,14 @@
+-- Migration: Escalation levels targeting external on-call schedulers
+-- An external_schedule level pages whoever an external_oncall integration (a generic HTTP
+-- endpoint or a Grafana OnCall schedule) reports on call, matched to SLAR users by email.
+-- target_id is the integration.
+
+ALTER TABLE escalation_levels DROP CONSTRAINT IF EXISTS escalation_levels_target_type_valid;
+ALTER TABLE escalation_levels ADD CONSTRAINT escalation_levels_target_type_valid CHECK (
+    target_type IN ('current_schedule', 'user', 'group', 'external', 'scheduler', 'external_schedule')
+);
+
+ALTER TABLE integrations DROP CONSTRAINT IF EXISTS integrations_type_valid;
+ALTER TABLE integrations ADD CONSTRAINT integrations_type_valid CHECK (
+    type IN ('prometheus', 'datadog', 'grafana', 'webhook', 'aws', 'custom', 'github', 'gitlab', 'argocd', 'external_oncall')
+);
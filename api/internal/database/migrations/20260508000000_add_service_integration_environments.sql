@@ -0,0 +1,14 @@
+-- Migration: Per-environment service integrations
+-- A service can bind one integration per environment (e.g. staging and prod webhooks with their
+-- own secrets). The binding's environment is carried onto incidents so the incident list can show
+-- every environment together or filter to one. Alerts from a non-production binding page its own
+-- escalation policy, or nobody, never the service's production on-call.
+
+ALTER TABLE service_integrations
+    ADD COLUMN IF NOT EXISTS environment TEXT,
+    ADD COLUMN IF NOT EXISTS escalation_policy_id UUID REFERENCES escalation_policies(id) ON DELETE SET NULL;
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS environment TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_org_environment
+    ON incidents (organization_id, environment) WHERE environment IS NOT NULL;
@@ -0,0 +1,40 @@
+-- Migration: resolved incident exports to spreadsheets per group
+-- A group's sheet exports receive one row per resolved incident of the group
+-- (key fields and acknowledge/resolve durations), appended by the worker
+-- either to a Google Sheet through a service account or as a CSV document
+-- posted to a webhook. Each export keeps the (resolved_at, id) position it has
+-- exported up to; new exports start at the moment they are created instead of
+-- replaying history.
+
+CREATE TABLE IF NOT EXISTS group_sheet_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    sink_type TEXT NOT NULL CHECK (sink_type IN ('google_sheets', 'csv_webhook')),
+    name TEXT NOT NULL,
+    -- Google Sheets: spreadsheet, tab and service account key (encrypted).
+    -- CSV webhook: the URL rows are posted to (encrypted).
+    spreadsheet_id TEXT,
+    sheet_name TEXT,
+    credentials TEXT,
+    service_account_email TEXT,
+    webhook_url TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    resolved_at_cursor TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP,
+    incident_id_cursor UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    exported_count BIGINT NOT NULL DEFAULT 0,
+    last_exported_at TIMESTAMPTZ,
+    last_error TEXT,
+    last_error_at TIMESTAMPTZ,
+    created_by TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT group_sheet_exports_config CHECK (
+        (sink_type = 'google_sheets' AND spreadsheet_id IS NOT NULL AND credentials IS NOT NULL)
+        OR (sink_type = 'csv_webhook' AND webhook_url IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_sheet_exports_group_id ON group_sheet_exports(group_id);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_group_resolved_at
+    ON incidents(group_id, resolved_at, id) WHERE status = 'resolved';
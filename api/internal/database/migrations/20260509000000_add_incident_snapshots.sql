@@ -0,0 +1,28 @@
+-- Migration: Chart snapshots on incidents
+-- Integrations attach a chart image (e.g. a Grafana panel render) to an
+-- incident when it opens and on significant updates. Snapshots are stored
+-- with the incident's artifacts, either as a link to the provider's image or
+-- as the image itself. Stored images are served at an unguessable URL so
+-- Slack and chat channels can show them without signing in.
+
+ALTER TABLE incident_artifacts DROP CONSTRAINT IF EXISTS incident_artifacts_kind_check;
+ALTER TABLE incident_artifacts ADD CONSTRAINT incident_artifacts_kind_check
+    CHECK (kind IN ('logs', 'kubectl', 'command', 'metrics', 'other', 'snapshot'));
+
+ALTER TABLE incident_artifacts DROP CONSTRAINT IF EXISTS incident_artifacts_source_check;
+ALTER TABLE incident_artifacts ADD CONSTRAINT incident_artifacts_source_check
+    CHECK (source IN ('agent', 'user', 'integration'));
+
+ALTER TABLE incident_artifacts
+    ADD COLUMN IF NOT EXISTS image_url TEXT,
+    ADD COLUMN IF NOT EXISTS image_data BYTEA,
+    ADD COLUMN IF NOT EXISTS image_content_type TEXT,
+    ADD COLUMN IF NOT EXISTS image_token TEXT,
+    ADD COLUMN IF NOT EXISTS integration_id UUID REFERENCES integrations(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incident_artifacts_image_token
+    ON incident_artifacts(image_token) WHERE image_token IS NOT NULL;
+
+COMMENT ON COLUMN incident_artifacts.image_url IS 'Snapshot image hosted by the provider';
+COMMENT ON COLUMN incident_artifacts.image_data IS 'Snapshot image uploaded by the integration';
+COMMENT ON COLUMN incident_artifacts.image_token IS 'Unguessable token of the public URL serving image_data';
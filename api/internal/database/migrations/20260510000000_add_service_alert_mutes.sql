@@ -0,0 +1,24 @@
+-- Migration: Service alert mutes
+-- Silence one known-noisy rule on a service without putting the whole
+-- service into maintenance. A mute matches alerts by alert name and/or label
+-- values until it expires; matching alerts are dropped instead of opening an
+-- incident, while every other alert on the service still pages.
+
+CREATE TABLE IF NOT EXISTS service_alert_mutes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    alert_names TEXT[] NOT NULL DEFAULT '{}',
+    match_labels JSONB NOT NULL DEFAULT '{}',
+    reason TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    muted_count INTEGER NOT NULL DEFAULT 0,
+    last_muted_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT service_alert_mutes_has_matcher CHECK (cardinality(alert_names) > 0 OR match_labels <> '{}'::jsonb)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_alert_mutes_service_expires ON service_alert_mutes(service_id, expires_at);
+
+COMMENT ON TABLE service_alert_mutes IS 'Per-service mutes of specific alert names/labels with an expiry; matching alerts do not open incidents';
+COMMENT ON COLUMN service_alert_mutes.match_labels IS 'Label values an alert must all have to match';
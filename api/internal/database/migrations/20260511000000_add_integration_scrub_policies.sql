@@ -0,0 +1,16 @@
+-- Migration: Integration payload scrubbing
+-- Per-integration policy that redacts personal data and secrets (emails, IP
+-- addresses, phone numbers, tokens, custom patterns and named fields) from
+-- inbound webhook payloads before anything from them is stored, so production
+-- alerts can be sent to a hosted SLAR without that data leaving the sender's
+-- region. How much was redacted is counted per rule for compliance audits.
+
+ALTER TABLE integrations
+    ADD COLUMN IF NOT EXISTS scrub_policy JSONB,
+    ADD COLUMN IF NOT EXISTS scrubbed_payload_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS scrub_redaction_counts JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS last_scrubbed_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN integrations.scrub_policy IS 'Redaction rules, patterns and fields applied to webhook payloads before storage; NULL = off';
+COMMENT ON COLUMN integrations.scrubbed_payload_count IS 'Webhook payloads the scrub policy redacted something from';
+COMMENT ON COLUMN integrations.scrub_redaction_counts IS 'Redactions made by the scrub policy, by rule';
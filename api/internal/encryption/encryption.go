@@ -0,0 +1,189 @@
+// Package encryption provides application-level encryption for sensitive
+// database columns (integration webhook secrets, FCM tokens, phone numbers).
+//
+// Values are sealed with AES-256-GCM and stored as
+//
+//	enc:v1:<key-id>:<base64(nonce || ciphertext)>
+//
+// The key ID is embedded so old keys can stay in the keyring for reads while
+// new writes use the active key (see ReencryptSensitiveColumns in services).
+// Values without the prefix are treated as legacy plaintext and returned as-is.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const prefix = "enc:v1:"
+
+var (
+	ErrUnknownKey       = errors.New("encryption key not found in keyring")
+	ErrMalformedPayload = errors.New("malformed encrypted value")
+)
+
+// Keyring holds the data encryption keys by ID
+type Keyring struct {
+	activeID string
+	aeads    map[string]cipher.AEAD
+}
+
+// NewKeyring parses a key spec of the form "id1:base64key1,id2:base64key2".
+// Keys must decode to 32 bytes. activeID selects the key used for new writes;
+// when empty the first key in the spec is used.
+func NewKeyring(spec, activeID string) (*Keyring, error) {
+	kr := &Keyring{aeads: make(map[string]cipher.AEAD)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid encryption key entry: expected id:base64key")
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("invalid encryption key %q: must be 32 bytes, got %d", id, len(raw))
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		kr.aeads[id] = aead
+		if kr.activeID == "" {
+			kr.activeID = id
+		}
+	}
+
+	if activeID != "" {
+		if _, ok := kr.aeads[activeID]; !ok {
+			return nil, fmt.Errorf("active encryption key %q is not in the keyring", activeID)
+		}
+		kr.activeID = activeID
+	}
+	return kr, nil
+}
+
+// Enabled reports whether any key is configured
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.activeID != ""
+}
+
+// ActiveKeyID returns the ID of the key used for new writes
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.activeID
+}
+
+// Encrypt seals plaintext with the active key. Empty strings stay empty so
+// non-empty column filters keep working, and without a keyring values are
+// passed through unchanged.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || !k.Enabled() {
+		return plaintext, nil
+	}
+	aead := k.aeads[k.activeID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.activeID))
+	return prefix + k.activeID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Legacy plaintext is returned unchanged.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", ErrMalformedPayload
+	}
+	if k == nil {
+		return "", ErrUnknownKey
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformedPayload
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value with key %s: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value should be rewritten: it is plaintext
+// or sealed with a key other than the active one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" || !k.Enabled() {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, prefix+k.activeID+":")
+}
+
+// IsEncrypted reports whether value carries the encryption prefix
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Process-wide keyring, configured once from config at startup
+
+var (
+	mu      sync.RWMutex
+	current *Keyring
+)
+
+// Configure installs the process-wide keyring
+func Configure(spec, activeID string) error {
+	kr, err := NewKeyring(spec, activeID)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	current = kr
+	mu.Unlock()
+	return nil
+}
+
+// Default returns the process-wide keyring (may be disabled)
+func Default() *Keyring {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Encrypt seals plaintext with the process-wide keyring
+func Encrypt(plaintext string) (string, error) {
+	return Default().Encrypt(plaintext)
+}
+
+// Decrypt opens value with the process-wide keyring
+func Decrypt(value string) (string, error) {
+	return Default().Decrypt(value)
+}
@@ -0,0 +1,81 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestKeyringRoundTripAndRotation(t *testing.T) {
+	old, err := NewKeyring("k1:"+testKey('a'), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := old.Encrypt("+15551234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:k1:") || strings.Contains(sealed, "5551234567") {
+		t.Fatalf("unexpected ciphertext %q", sealed)
+	}
+
+	// New key is active, old key kept for reads
+	rotated, err := NewKeyring("k2:"+testKey('b')+",k1:"+testKey('a'), "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := rotated.Decrypt(sealed)
+	if err != nil || plain != "+15551234567" {
+		t.Fatalf("Decrypt() = (%q, %v)", plain, err)
+	}
+	if !rotated.NeedsRotation(sealed) {
+		t.Error("value sealed with k1 should need rotation")
+	}
+	resealed, _ := rotated.Encrypt(plain)
+	if rotated.NeedsRotation(resealed) {
+		t.Error("value sealed with active key should not need rotation")
+	}
+	if !rotated.NeedsRotation("legacy-plaintext") {
+		t.Error("plaintext should need rotation")
+	}
+
+	// Once k1 is dropped, old ciphertext can no longer be read
+	if _, err := (&Keyring{}).Decrypt(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() with missing key error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestKeyringPassthrough(t *testing.T) {
+	var disabled *Keyring
+	if got, _ := disabled.Encrypt("secret"); got != "secret" {
+		t.Errorf("disabled Encrypt() = %q, want plaintext", got)
+	}
+
+	kr, _ := NewKeyring("k1:"+testKey('a'), "")
+	if got, _ := kr.Encrypt(""); got != "" {
+		t.Errorf("Encrypt(\"\") = %q, want empty", got)
+	}
+	if got, _ := kr.Decrypt("plain-fcm-token"); got != "plain-fcm-token" {
+		t.Errorf("Decrypt(plaintext) = %q, want passthrough", got)
+	}
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	for _, spec := range []string{
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1:not-base64!",
+		testKey('a'),
+	} {
+		if _, err := NewKeyring(spec, ""); err == nil {
+			t.Errorf("NewKeyring(%q) expected error", spec)
+		}
+	}
+	if _, err := NewKeyring("k1:"+testKey('a'), "k9"); err == nil {
+		t.Error("expected error for unknown active key")
+	}
+}
@@ -0,0 +1,169 @@
+// Package faults injects database latency, pgmq failures and notification
+// provider outages on demand, so operators can check that escalation and
+// retry paths hold up before a real partial outage. Nothing is injected
+// unless fault_injection_enabled is set; faults themselves are created
+// through /internal/faults and stored in fault_injections, which every
+// process polls.
+package faults
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// ErrInjected is wrapped by every error a fault causes
+var ErrInjected = errors.New("injected fault")
+
+// pollInterval is how soon a fault created in one process reaches the others
+const pollInterval = 5 * time.Second
+
+var (
+	mu     sync.RWMutex
+	active []db.Fault
+)
+
+// Enabled reports whether fault injection is switched on for this process
+func Enabled() bool {
+	return config.Current().FaultInjectionEnabled
+}
+
+// Active returns the faults currently in force
+func Active() []db.Fault {
+	mu.RLock()
+	defer mu.RUnlock()
+	now := time.Now()
+	faults := []db.Fault{}
+	for _, f := range active {
+		if f.ExpiresAt.After(now) {
+			faults = append(faults, f)
+		}
+	}
+	return faults
+}
+
+// Set replaces the faults in force
+func Set(faults []db.Fault) {
+	mu.Lock()
+	active = faults
+	mu.Unlock()
+}
+
+// Refresh reloads the faults in force from fault_injections
+func Refresh(pg *sql.DB) error {
+	rows, err := pg.Query(`
+		SELECT id, kind, target, delay_ms, rate, note, expires_at, created_at
+		FROM fault_injections
+		WHERE expires_at > NOW()
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to load faults: %w", err)
+	}
+	defer rows.Close()
+
+	faults := []db.Fault{}
+	for rows.Next() {
+		var f db.Fault
+		if err := rows.Scan(&f.ID, &f.Kind, &f.Target, &f.DelayMS, &f.Rate, &f.Note, &f.ExpiresAt, &f.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan fault: %w", err)
+		}
+		faults = append(faults, f)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load faults: %w", err)
+	}
+	Set(faults)
+	return nil
+}
+
+// Watch keeps the faults in force in step with fault_injections until ctx is
+// done. It does nothing unless fault injection is enabled.
+func Watch(ctx context.Context, pg *sql.DB) {
+	if !Enabled() {
+		return
+	}
+	log.Println("⚠️  Fault injection is enabled: faults created under /internal/faults will be injected")
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			if err := Refresh(pg); err != nil {
+				log.Printf("Fault injection: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// hit reports whether a matching call is affected, given the fault's rate
+func hit(f db.Fault) bool {
+	return f.Rate >= 1 || rand.Float64() < f.Rate
+}
+
+// ProviderOutage returns an error when a provider_outage fault has taken the
+// notification provider down. Senders call it before contacting the provider.
+func ProviderOutage(provider string) error {
+	if !Enabled() {
+		return nil
+	}
+	for _, f := range Active() {
+		if f.Kind == db.FaultProviderOutage && f.Target == provider && hit(f) {
+			return fmt.Errorf("%w: %s is unavailable", ErrInjected, provider)
+		}
+	}
+	return nil
+}
+
+// statementFault returns how long to hold up a SQL statement and whether to
+// fail it instead. The fault_injections table itself is exempt, so faults can
+// always be listed and cleared.
+func statementFault(query string, args []string) (time.Duration, error) {
+	if !Enabled() || strings.Contains(query, "fault_injections") {
+		return 0, nil
+	}
+	var delay time.Duration
+	lower := strings.ToLower(query)
+	for _, f := range Active() {
+		switch f.Kind {
+		case db.FaultDBLatency:
+			if (f.Target == "" || strings.Contains(lower, strings.ToLower(f.Target))) && hit(f) {
+				if d := time.Duration(f.DelayMS) * time.Millisecond; d > delay {
+					delay = d
+				}
+			}
+		case db.FaultPGMQFailure:
+			if strings.Contains(lower, "pgmq.") && pgmqQueueMatches(f.Target, query, args) && hit(f) {
+				return 0, fmt.Errorf("%w: pgmq is unavailable", ErrInjected)
+			}
+		}
+	}
+	return delay, nil
+}
+
+// pgmqQueueMatches reports whether a pgmq statement works on the queue, given
+// as an argument or inline
+func pgmqQueueMatches(queue, query string, args []string) bool {
+	if queue == "" {
+		return true
+	}
+	for _, arg := range args {
+		if arg == queue {
+			return true
+		}
+	}
+	return strings.Contains(query, "'"+queue+"'")
+}
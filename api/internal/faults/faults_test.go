@@ -0,0 +1,115 @@
+package faults
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func withFaults(t *testing.T, enabled bool, faults ...db.Fault) {
+	t.Helper()
+	prevEnabled, prevActive := config.Current().FaultInjectionEnabled, Active()
+	config.Update(func(c *config.Config) { c.FaultInjectionEnabled = enabled })
+	for i := range faults {
+		if faults[i].ExpiresAt.IsZero() {
+			faults[i].ExpiresAt = time.Now().Add(time.Minute)
+		}
+		if faults[i].Rate == 0 {
+			faults[i].Rate = 1
+		}
+	}
+	Set(faults)
+	t.Cleanup(func() {
+		config.Update(func(c *config.Config) { c.FaultInjectionEnabled = prevEnabled })
+		Set(prevActive)
+	})
+}
+
+func TestProviderOutage(t *testing.T) {
+	withFaults(t, true,
+		db.Fault{Kind: db.FaultProviderOutage, Target: "slack"},
+		db.Fault{Kind: db.FaultProviderOutage, Target: "email", ExpiresAt: time.Now().Add(-time.Second)},
+	)
+
+	if err := ProviderOutage("slack"); !errors.Is(err, ErrInjected) {
+		t.Errorf("slack err = %v", err)
+	}
+	if err := ProviderOutage("email"); err != nil {
+		t.Errorf("expired email outage err = %v", err)
+	}
+	if err := ProviderOutage("voice"); err != nil {
+		t.Errorf("voice err = %v", err)
+	}
+
+	// Faults are ignored unless injection is enabled
+	config.Update(func(c *config.Config) { c.FaultInjectionEnabled = false })
+	if err := ProviderOutage("slack"); err != nil {
+		t.Errorf("disabled slack err = %v", err)
+	}
+}
+
+func TestStatementFault(t *testing.T) {
+	withFaults(t, true,
+		db.Fault{Kind: db.FaultDBLatency, Target: "FROM incidents", DelayMS: 200},
+		db.Fault{Kind: db.FaultDBLatency, DelayMS: 50},
+		db.Fault{Kind: db.FaultPGMQFailure, Target: "incident_notifications"},
+	)
+
+	if delay, err := statementFault("SELECT * FROM incidents WHERE id = $1", nil); err != nil || delay != 200*time.Millisecond {
+		t.Errorf("incidents delay = %v, err = %v", delay, err)
+	}
+	if delay, _ := statementFault("SELECT 1", nil); delay != 50*time.Millisecond {
+		t.Errorf("other delay = %v", delay)
+	}
+	if _, err := statementFault("SELECT pgmq.send($1, $2)", []string{"incident_notifications", "{}"}); !errors.Is(err, ErrInjected) {
+		t.Errorf("pgmq send to the queue err = %v", err)
+	}
+	if _, err := statementFault("SELECT pgmq.send($1, $2)", []string{"slack_feedback", "{}"}); err != nil {
+		t.Errorf("pgmq send to another queue err = %v", err)
+	}
+	if delay, err := statementFault("DELETE FROM fault_injections", nil); delay != 0 || err != nil {
+		t.Errorf("fault_injections delay = %v, err = %v", delay, err)
+	}
+}
+
+type stubConn struct {
+	driver.Conn
+	queries int
+}
+
+func (c *stubConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries++
+	return driver.RowsAffected(1), nil
+}
+
+func TestFaultConnExec(t *testing.T) {
+	withFaults(t, true,
+		db.Fault{Kind: db.FaultPGMQFailure},
+		db.Fault{Kind: db.FaultDBLatency, Target: "UPDATE incidents", DelayMS: 10000},
+	)
+	stub := &stubConn{}
+	conn := &faultConn{Conn: stub}
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT pgmq.archive($1, $2)", nil); !errors.Is(err, ErrInjected) {
+		t.Errorf("pgmq err = %v", err)
+	}
+
+	// The delay gives way to the caller's deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "UPDATE incidents SET status = $1", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("delayed err = %v", err)
+	}
+
+	if _, err := conn.ExecContext(context.Background(), "UPDATE services SET name = $1", nil); err != nil {
+		t.Errorf("unaffected err = %v", err)
+	}
+	if stub.queries != 1 {
+		t.Errorf("queries reaching the connection = %d, want 1", stub.queries)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/vanchonlee/slar/internal/config"
 )
 
 type DeploymentHandler struct {
@@ -146,7 +147,7 @@ func (h *DeploymentHandler) DeployWorker(c *gin.Context) {
 	// Add API URL binding
 	apiURL := os.Getenv("NEXT_PUBLIC_API_URL")
 	if apiURL == "" {
-		apiURL = "https://api.slar.app" // Default fallback
+		apiURL = config.ExternalAPIURL()
 	}
 	bindings = append(bindings, WorkerBinding{Type: "plain_text", Name: "SLAR_API_URL", Text: apiURL})
 
@@ -377,10 +378,10 @@ func (h *DeploymentHandler) RedeployWorker(c *gin.Context) {
 		return
 	}
 
-	// Get SLAR_API_URL from env
+	// SLAR_API_URL from env, else the configured external URL
 	slarAPIURL := os.Getenv("NEXT_PUBLIC_API_URL")
 	if slarAPIURL == "" {
-		slarAPIURL = "https://api.slar.app"
+		slarAPIURL = config.ExternalAPIURL()
 	}
 
 	// Prepare bindings
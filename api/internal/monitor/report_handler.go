@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -77,6 +78,11 @@ func (h *ReportHandler) HandleReport(c *gin.Context) {
 			continue
 		}
 
+		// Feed monitor-backed SLOs
+		if err := services.RecordSLOMonitorCheck(h.db, monitorID.String(), result.IsUp); err != nil {
+			log.Printf("Failed to record SLO check for monitor %s: %v", monitorID, err)
+		}
+
 		// Handle Incident Logic
 		if currentIsUp != nil && *currentIsUp != result.IsUp {
 			if !result.IsUp {
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GCPSecretManagerProvider reads secrets from Google Cloud Secret Manager.
+//
+// Authenticates with Application Default Credentials (GOOGLE_APPLICATION_CREDENTIALS,
+// workload identity, or the GCE metadata server). The reference path is the
+// secret resource name; "/versions/latest" is appended when no version is given.
+type GCPSecretManagerProvider struct {
+	Client *http.Client
+}
+
+func (p *GCPSecretManagerProvider) Fetch(ctx context.Context, name string) (string, error) {
+	name = strings.Trim(name, "/")
+	if !strings.HasPrefix(name, "projects/") {
+		return "", fmt.Errorf("GCP secret must be of the form projects/<project>/secrets/<name>[/versions/<version>]")
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	client := p.Client
+	if client == nil {
+		ts, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			return "", fmt.Errorf("failed to load GCP credentials: %w", err)
+		}
+		client = oauth2.NewClient(ctx, ts)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://secretmanager.googleapis.com/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret manager returned status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("failed to decode secret manager response: %w", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret payload: %w", err)
+	}
+	return string(decoded), nil
+}
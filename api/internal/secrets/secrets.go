@@ -0,0 +1,159 @@
+// Package secrets resolves secret references such as
+//
+//	vault://secret/data/slar#database_url
+//	aws-sm://prod/slar#SLACK_BOT_TOKEN
+//	gcp-sm://projects/my-project/secrets/slar-db-url
+//
+// into their plaintext values so self-hosted deployments don't need to keep
+// credentials in env files or config YAML.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Provider fetches the raw payload of a secret from a backend.
+// The payload is either a plain string or a JSON object; Resolver handles
+// picking a single key out of JSON payloads.
+type Provider interface {
+	Fetch(ctx context.Context, path string) (string, error)
+}
+
+// Reference is a parsed secret reference: <scheme>://<path>[#<key>]
+type Reference struct {
+	Scheme string
+	Path   string
+	Key    string
+}
+
+func (r Reference) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// ParseReference parses a secret reference. ok is false when value is not a
+// reference (i.e. a plain config value that should be used as-is).
+func ParseReference(value string) (ref Reference, ok bool) {
+	scheme, rest, found := strings.Cut(strings.TrimSpace(value), "://")
+	if !found || rest == "" {
+		return Reference{}, false
+	}
+	if _, known := defaultProviders()[scheme]; !known {
+		return Reference{}, false
+	}
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return Reference{}, false
+	}
+	return Reference{Scheme: scheme, Path: path, Key: key}, true
+}
+
+// IsReference reports whether value looks like a secret reference
+func IsReference(value string) bool {
+	_, ok := ParseReference(value)
+	return ok
+}
+
+// Resolver resolves references through registered providers.
+// Payloads are cached per scheme+path so several keys of the same secret
+// only cost one round-trip; call Reset before each refresh pass.
+type Resolver struct {
+	providers map[string]Provider
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewResolver creates a resolver with the built-in Vault, AWS Secrets Manager
+// and GCP Secret Manager providers configured from the environment
+func NewResolver() *Resolver {
+	return &Resolver{
+		providers: defaultProviders(),
+		cache:     make(map[string]string),
+	}
+}
+
+func defaultProviders() map[string]Provider {
+	return map[string]Provider{
+		"vault":  &VaultProvider{},
+		"aws-sm": &AWSSecretsManagerProvider{},
+		"gcp-sm": &GCPSecretManagerProvider{},
+	}
+}
+
+// Register overrides or adds the provider for a scheme
+func (r *Resolver) Register(scheme string, p Provider) {
+	r.providers[scheme] = p
+}
+
+// Reset clears the payload cache
+func (r *Resolver) Reset() {
+	r.mu.Lock()
+	r.cache = make(map[string]string)
+	r.mu.Unlock()
+}
+
+// Resolve returns the plaintext value for a reference
+func (r *Resolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	provider, ok := r.providers[ref.Scheme]
+	if !ok {
+		return "", fmt.Errorf("no secret provider for scheme %q", ref.Scheme)
+	}
+
+	cacheKey := ref.Scheme + "://" + ref.Path
+	r.mu.Lock()
+	payload, cached := r.cache[cacheKey]
+	r.mu.Unlock()
+
+	if !cached {
+		var err error
+		payload, err = provider.Fetch(ctx, ref.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %w", cacheKey, err)
+		}
+		r.mu.Lock()
+		r.cache[cacheKey] = payload
+		r.mu.Unlock()
+	}
+
+	if ref.Key == "" {
+		return payload, nil
+	}
+	return extractKey(payload, ref.Key)
+}
+
+// ResolveString resolves value if it is a reference, otherwise returns it unchanged
+func (r *Resolver) ResolveString(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+	return r.Resolve(ctx, ref)
+}
+
+func extractKey(payload, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot select key %q", key)
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret", key)
+	}
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case nil:
+		return "", nil
+	default:
+		b, _ := json.Marshal(val)
+		return string(b), nil
+	}
+}
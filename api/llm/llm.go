@@ -0,0 +1,161 @@
+// Package llm is the extension point for the language model providers behind
+// SLAR's AI features.
+//
+// A provider (OpenAI, Anthropic, Azure OpenAI, Ollama, ...) implements
+// Provider and registers itself from an init function:
+//
+//	func init() {
+//		llm.Register(&ollamaProvider{})
+//	}
+//
+// Which provider, model and key an organization uses is configured globally
+// in config.Current().LLM and can be overridden per organization. Callers go
+// through services.LLMService, which resolves that configuration, enforces
+// the organization's token budget and meters usage.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Message roles
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string `json:"role"` // user, assistant
+	Content string `json:"content"`
+}
+
+// Request is a provider-neutral completion request. The system prompt is kept
+// apart from Messages since providers take it in different places.
+type Request struct {
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+}
+
+// Usage is the tokens a completion consumed, as reported by the provider
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Total is the tokens counted against budgets
+func (u Usage) Total() int { return u.InputTokens + u.OutputTokens }
+
+// Response is a completion
+type Response struct {
+	Text     string `json:"text"`
+	Model    string `json:"model"`
+	Provider string `json:"provider,omitempty"` // filled in by LLMService
+	Usage    Usage  `json:"usage"`
+}
+
+// Config is the resolved connection to a provider
+type Config struct {
+	Provider   string
+	Model      string // for Azure OpenAI, the deployment name
+	APIKey     string
+	BaseURL    string // provider default when empty; required for Azure OpenAI
+	APIVersion string // Azure OpenAI only
+}
+
+// Requirements tell SLAR which Config fields a provider needs
+type Requirements struct {
+	APIKey     bool `json:"api_key"`
+	BaseURL    bool `json:"base_url"`
+	APIVersion bool `json:"api_version"`
+}
+
+// Provider is implemented by every model provider. Implementations must be
+// safe for concurrent use.
+type Provider interface {
+	// Type is the stable identifier stored in settings, e.g. "ollama"
+	Type() string
+	// Name is the display name shown in the UI
+	Name() string
+	// Requirements lists the Config fields the provider needs
+	Requirements() Requirements
+	// Complete runs a completion
+	Complete(ctx context.Context, cfg Config, req Request) (*Response, error)
+}
+
+// ProviderInfo is a provider as served to the UI
+type ProviderInfo struct {
+	Type         string       `json:"type"`
+	Name         string       `json:"name"`
+	Requirements Requirements `json:"requirements"`
+}
+
+var typePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Register makes a provider available. Like database/sql drivers it is meant
+// to be called from init, and panics on an invalid or duplicate type.
+func Register(p Provider) {
+	if p == nil {
+		panic("llm: Register provider is nil")
+	}
+	providerType := p.Type()
+	if !typePattern.MatchString(providerType) {
+		panic(fmt.Sprintf("llm: invalid provider type %q", providerType))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := providers[providerType]; dup {
+		panic(fmt.Sprintf("llm: Register called twice for provider type %q", providerType))
+	}
+	providers[providerType] = p
+}
+
+// Lookup returns the registered provider for a type
+func Lookup(providerType string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[providerType]
+	return p, ok
+}
+
+// Describe returns every registered provider, sorted by type
+func Describe() []ProviderInfo {
+	mu.RLock()
+	defer mu.RUnlock()
+	infos := make([]ProviderInfo, 0, len(providers))
+	for t, p := range providers {
+		infos = append(infos, ProviderInfo{Type: t, Name: p.Name(), Requirements: p.Requirements()})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+// ValidateConfig checks a Config has what its provider requires
+func ValidateConfig(p Provider, cfg Config) error {
+	req := p.Requirements()
+	if cfg.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if req.APIKey && cfg.APIKey == "" {
+		return fmt.Errorf("%s requires an API key", p.Name())
+	}
+	if req.BaseURL && cfg.BaseURL == "" {
+		return fmt.Errorf("%s requires a base URL", p.Name())
+	}
+	if req.APIVersion && cfg.APIVersion == "" {
+		return fmt.Errorf("%s requires an API version", p.Name())
+	}
+	return nil
+}
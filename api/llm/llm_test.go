@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+type testProvider struct {
+	providerType string
+	req          Requirements
+}
+
+func (p testProvider) Type() string               { return p.providerType }
+func (testProvider) Name() string                 { return "Test" }
+func (p testProvider) Requirements() Requirements { return p.req }
+
+func (testProvider) Complete(ctx context.Context, cfg Config, req Request) (*Response, error) {
+	return &Response{Text: "ok", Model: cfg.Model}, nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(testProvider{providerType: "test_register"})
+
+	if _, ok := Lookup("test_register"); !ok {
+		t.Fatal("registered provider not found")
+	}
+	found := false
+	for _, info := range Describe() {
+		found = found || info.Type == "test_register"
+	}
+	if !found {
+		t.Error("registered provider missing from Describe")
+	}
+
+	for _, providerType := range []string{"test_register", "Bad Type"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) should panic", providerType)
+				}
+			}()
+			Register(testProvider{providerType: providerType})
+		}()
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	p := testProvider{providerType: "test_validate", req: Requirements{APIKey: true, BaseURL: true}}
+	cases := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{"complete", Config{Model: "m", APIKey: "k", BaseURL: "https://example.com"}, true},
+		{"no model", Config{APIKey: "k", BaseURL: "https://example.com"}, false},
+		{"no key", Config{Model: "m", BaseURL: "https://example.com"}, false},
+		{"no base url", Config{Model: "m", APIKey: "k"}, false},
+	}
+	for _, tc := range cases {
+		if err := ValidateConfig(p, tc.cfg); (err == nil) != tc.ok {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+}
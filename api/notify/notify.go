@@ -0,0 +1,185 @@
+// Package notify is the extension point for group notification channels.
+//
+// A channel (Discord, Telegram, Mattermost, Zulip, ...) implements
+// NotificationChannel and registers itself from an init function:
+//
+//	func init() {
+//		notify.Register(&mattermostChannel{})
+//	}
+//
+// Importing the package for its side effects from the server and worker
+// binaries is then enough for groups to add the channel, for its settings
+// form to be rendered from ConfigSchema, and for incident notifications to
+// be delivered through Send. See docs/notification-channels.md.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Config holds a channel's settings keyed by ConfigField.Key
+type Config map[string]string
+
+// Config field types, used by the UI to pick an input
+const (
+	FieldText = "text"
+	FieldURL  = "url"
+)
+
+// ConfigField describes one setting a channel needs
+type ConfigField struct {
+	Key         string `json:"key"`
+	Label       string `json:"label"`
+	Type        string `json:"type"` // text, url
+	Required    bool   `json:"required"`
+	Secret      bool   `json:"secret"` // encrypted at rest and never returned by the API
+	Placeholder string `json:"placeholder,omitempty"`
+	Help        string `json:"help,omitempty"`
+}
+
+// Capabilities tell SLAR how much of a Message a channel can render
+type Capabilities struct {
+	AckLinks bool `json:"ack_links"` // renders Message.AckURL so responders can acknowledge from chat
+	Threads  bool `json:"threads"`   // groups messages for the same incident by Message.ThreadKey
+}
+
+// Field is a label/value pair shown with a notification
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Message is a provider-neutral incident notification
+type Message struct {
+	Event       string // assigned, escalated, paged, acknowledged, resolved, test
+	IncidentID  string
+	Headline    string
+	Title       string
+	Fields      []Field
+	IncidentURL string
+	AckURL      string // set only for channels with the AckLinks capability
+	ImageURL    string // chart snapshot an integration attached to the incident, if any
+	Color       int    // accent color as 0xRRGGBB
+	ThreadKey   string
+}
+
+// NotificationChannel is implemented by every channel type. Implementations
+// must be safe for concurrent use.
+type NotificationChannel interface {
+	// Type is the stable identifier stored with each configured channel, e.g. "mattermost"
+	Type() string
+	// Name is the display name shown in the UI
+	Name() string
+	// ConfigSchema lists the settings a configured channel needs
+	ConfigSchema() []ConfigField
+	// Capabilities reports which Message features the channel renders
+	Capabilities() Capabilities
+	// Validate checks settings beyond the schema's required fields, e.g. URL formats
+	Validate(cfg Config) error
+	// Send delivers a message using a configured channel's settings
+	Send(ctx context.Context, cfg Config, msg Message) error
+}
+
+// ChannelInfo is a channel type as served to the UI
+type ChannelInfo struct {
+	Type         string        `json:"type"`
+	Name         string        `json:"name"`
+	Capabilities Capabilities  `json:"capabilities"`
+	Fields       []ConfigField `json:"fields"`
+}
+
+var typePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+var (
+	mu       sync.RWMutex
+	channels = map[string]NotificationChannel{}
+)
+
+// Register makes a channel type available. Like database/sql drivers it is
+// meant to be called from init, and panics on an invalid or duplicate type.
+func Register(ch NotificationChannel) {
+	if ch == nil {
+		panic("notify: Register channel is nil")
+	}
+	channelType := ch.Type()
+	if !typePattern.MatchString(channelType) {
+		panic(fmt.Sprintf("notify: invalid channel type %q", channelType))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := channels[channelType]; dup {
+		panic(fmt.Sprintf("notify: Register called twice for channel type %q", channelType))
+	}
+	channels[channelType] = ch
+}
+
+// Lookup returns the registered channel for a type
+func Lookup(channelType string) (NotificationChannel, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	ch, ok := channels[channelType]
+	return ch, ok
+}
+
+// Types returns the registered channel types, sorted
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(channels))
+	for t := range channels {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Describe returns every registered channel type with its config schema
+func Describe() []ChannelInfo {
+	infos := []ChannelInfo{}
+	for _, t := range Types() {
+		ch, _ := Lookup(t)
+		infos = append(infos, ChannelInfo{
+			Type:         t,
+			Name:         ch.Name(),
+			Capabilities: ch.Capabilities(),
+			Fields:       ch.ConfigSchema(),
+		})
+	}
+	return infos
+}
+
+// ValidateConfig keeps only the settings in the channel's schema, checks the
+// required ones are present and then runs the channel's own validation
+func ValidateConfig(ch NotificationChannel, cfg Config) (Config, error) {
+	clean := Config{}
+	for _, f := range ch.ConfigSchema() {
+		value := strings.TrimSpace(cfg[f.Key])
+		if value == "" {
+			if f.Required {
+				return nil, fmt.Errorf("%s is required", f.Key)
+			}
+			continue
+		}
+		clean[f.Key] = value
+	}
+	if err := ch.Validate(clean); err != nil {
+		return nil, err
+	}
+	return clean, nil
+}
+
+// IsSecret reports whether a setting of the channel is secret
+func IsSecret(ch NotificationChannel, key string) bool {
+	for _, f := range ch.ConfigSchema() {
+		if f.Key == key {
+			return f.Secret
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testChannel struct{ channelType string }
+
+func (c testChannel) Type() string { return c.channelType }
+func (testChannel) Name() string   { return "Test" }
+
+func (testChannel) ConfigSchema() []ConfigField {
+	return []ConfigField{
+		{Key: "url", Label: "URL", Type: FieldURL, Required: true, Secret: true},
+		{Key: "channel", Label: "Channel", Type: FieldText},
+	}
+}
+
+func (testChannel) Capabilities() Capabilities { return Capabilities{Threads: true} }
+
+func (testChannel) Validate(cfg Config) error {
+	if !strings.HasPrefix(cfg["url"], "https://") {
+		return errors.New("url must use https")
+	}
+	return nil
+}
+
+func (testChannel) Send(ctx context.Context, cfg Config, msg Message) error { return nil }
+
+func TestRegister(t *testing.T) {
+	Register(testChannel{channelType: "test_register"})
+
+	if _, ok := Lookup("test_register"); !ok {
+		t.Fatal("registered channel not found")
+	}
+	found := false
+	for _, info := range Describe() {
+		if info.Type == "test_register" {
+			found = info.Name == "Test" && len(info.Fields) == 2 && info.Capabilities.Threads
+		}
+	}
+	if !found {
+		t.Error("registered channel missing from Describe")
+	}
+
+	for _, channelType := range []string{"test_register", "Bad Type"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) should panic", channelType)
+				}
+			}()
+			Register(testChannel{channelType: channelType})
+		}()
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	ch := testChannel{channelType: "test_validate"}
+
+	cfg, err := ValidateConfig(ch, Config{"url": " https://chat.example.com/hook ", "unknown": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg["url"] != "https://chat.example.com/hook" || len(cfg) != 1 {
+		t.Errorf("expected trimmed schema settings only, got %v", cfg)
+	}
+
+	if _, err := ValidateConfig(ch, Config{"channel": "ops"}); err == nil || err.Error() != "url is required" {
+		t.Errorf("err = %v, want url is required", err)
+	}
+	if _, err := ValidateConfig(ch, Config{"url": "http://chat.example.com"}); err == nil {
+		t.Error("expected the channel's own validation to run")
+	}
+}
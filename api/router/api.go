@@ -2,6 +2,7 @@ package router
 
 import (
 	"database/sql"
+	"expvar"
 	"log"
 	"os"
 	"time"
@@ -9,8 +10,10 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/vanchonlee/slar/authz"
+	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/handlers"
 	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/internal/faults"
 	"github.com/vanchonlee/slar/internal/monitor"
 	"github.com/vanchonlee/slar/services"
 )
@@ -18,20 +21,19 @@ import (
 func NewGinRouter(pg *sql.DB) *gin.Engine {
 	r := gin.Default()
 
-	// Add CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Org-ID, X-Project-ID")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+	// Client IPs for webhook allowlists, API key audit and share links
+	configureClientIP(r, config.Current().TrustedProxies, config.Current().ClientIPHeader)
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	// gzip for clients that accept it; outermost so it compresses the final body
+	r.Use(handlers.CompressionMiddleware())
 
-		c.Next()
-	})
+	// Request IDs and the shared error body (code, message, field_errors, request_id)
+	r.Use(handlers.RequestIDMiddleware())
+	r.Use(handlers.ErrorSchemaMiddleware())
+
+	// CORS from cors.allowed_origins, and CSRF checks for cookie-carrying requests
+	r.Use(corsMiddleware())
+	r.Use(csrfMiddleware())
 
 	// Initialize services
 	fcmService, _ := services.NewFCMService(pg)
@@ -53,10 +55,12 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	schedulerService := services.NewSchedulerService(pg)                                  // NEW: Service scheduling
 	serviceService := services.NewServiceService(pg)                                      // NEW: Service management
 	integrationService := services.NewIntegrationService(pg)                              // NEW: Integration management
-	identityService, err := services.NewIdentityServiceWithDB(config.App.DataDir, pg, "") // Initialize IdentityService with DB for K8s persistence
+	identityService, err := services.NewIdentityServiceWithDB(config.Current().DataDir, pg, "") // Initialize IdentityService with DB for K8s persistence
 	if err != nil {
 		log.Printf("Warning: Failed to initialize identity service: %v", err)
 	}
+	// Destructive admin changes wait for a second admin's approval
+	pendingChangeService := services.NewPendingChangeService(pg, groupService, apiKeyService, integrationService)
 
 	// Initialize cloud relay and auto-register with cloud if configured
 	cloudRelayService := services.NewCloudRelayService(identityService)
@@ -76,7 +80,6 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	projectScopedMiddleware := authz.NewProjectScopedMiddleware(authzBackend, projectService) // ReBAC project scoping
 
 	// Initialize handlers
-	alertHandler := handlers.NewAlertHandler(alertService)
 	// Initialize analytics service for AI-powered incident analysis
 	analyticsService := services.NewIncidentAnalyticsService(pg)
 	if err := analyticsService.CreateQueueIfNotExists(); err != nil {
@@ -84,21 +87,25 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	}
 
 	incidentHandler := handlers.NewIncidentHandler(incidentService, serviceService, projectService, authzBackend, analyticsService) // NEW: Incident handler with ReBAC
+	alertHandler := handlers.NewAlertHandler(incidentHandler)                                                                      // Legacy /alerts, served from incidents
 	userHandler := handlers.NewUserHandler(userService)
 	uptimeHandler := handlers.NewUptimeHandler(uptimeService)
 	alertManagerHandler := handlers.NewAlertManagerHandler(alertManagerService)
 	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, alertService, userService)
 	dashboardHandler := handlers.NewDashboardHandler(userService)
 	// testHandler := handlers.NewTestHandler(alertManagerHandler)
-	groupHandler := handlers.NewGroupHandler(groupService, escalationService)
+	groupHandler := handlers.NewGroupHandler(groupService, escalationService, pendingChangeService)
 	onCallHandler := handlers.NewOnCallHandler(onCallService, schedulerService)
 	rotationHandler := handlers.NewRotationHandler(rotationService)
 	overrideHandler := handlers.NewOverrideHandler(onCallService.OverrideService)
 	schedulerHandler := handlers.NewSchedulerHandler(schedulerService, onCallService, serviceService)               // NEW: Service scheduling
-	serviceHandler := handlers.NewServiceHandler(serviceService)                                                    // NEW: Service management
-	integrationHandler := handlers.NewIntegrationHandler(integrationService)                                        // NEW: Integration handler
-	webhookHandler := handlers.NewWebhookHandler(integrationService, alertService, incidentService, serviceService) // NEW: Webhook handler
-	notificationHandler := handlers.NewNotificationHandler(slackService)                                            // NEW: Notification handler
+	serviceHandler := handlers.NewServiceHandler(serviceService, authzBackend)                                      // NEW: Service management
+	integrationHandler := handlers.NewIntegrationHandler(integrationService, pendingChangeService)                  // NEW: Integration handler
+	dedupReviewService := services.NewAlertDedupReviewService(pg)
+	// Sheds low-severity alerts and refuses webhooks while the notification queues are backed up
+	ingestBackpressure := services.NewIngestBackpressureService(pg)
+	go ingestBackpressure.Run(config.Current().IngestBackpressure.SampleInterval)
+	webhookHandler := handlers.NewWebhookHandler(integrationService, alertService, incidentService, serviceService, dedupReviewService, ingestBackpressure, authzBackend) // NEW: Webhook handler
 	mobileHandler := handlers.NewMobileHandler(pg, identityService)                                                 // Inject IdentityService
 	identityHandler := handlers.NewIdentityHandler(identityService)                                                 // Initialize IdentityHandler
 	agentHandler := handlers.NewAgentHandler(pg, identityService)                                                   // Initialize AgentHandler for Zero-Trust
@@ -108,6 +115,50 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	internalAuthzHandler := handlers.NewInternalAuthzHandler(authzBackend)                                          // Internal authz for AI Agent
 	policyService := services.NewPolicyService(pg)              // Agent policy engine
 	policyHandler := handlers.NewPolicyHandler(policyService)    // Agent policy handler
+	emailService := services.NewEmailService()
+	groupInvitationService := services.NewGroupInvitationService(pg, groupService, emailService)
+	groupInvitationHandler := handlers.NewGroupInvitationHandler(groupInvitationService) // Group invitations & join requests
+	chatChannelService := services.NewChatChannelService(pg)
+	chatChannelHandler := handlers.NewChatChannelHandler(chatChannelService, incidentService, groupInvitationService) // Discord/Telegram/Google Chat group channels
+	sheetExportHandler := handlers.NewSheetExportHandler(services.NewSheetExportService(pg), groupInvitationService) // Resolved incidents to Google Sheets/CSV webhooks
+	whatsAppService := services.NewWhatsAppService(pg)
+	whatsAppHandler := handlers.NewWhatsAppHandler(whatsAppService)
+	smsHandler := handlers.NewSMSHandler(services.NewSMSService(pg, incidentService))       // ACK/RES commands by text message
+	voiceHandler := handlers.NewVoiceHandler(services.NewVoiceService(pg, incidentService)) // Phone-call pages with an IVR menu
+	webPushService := services.NewWebPushService(pg)
+	webPushHandler := handlers.NewWebPushHandler(webPushService)
+	incidentLinkHandler := handlers.NewIncidentLinkHandler(services.NewIncidentLinkService(pg))
+	notificationCheckService := services.NewNotificationCheckService(pg, slackService, emailService, fcmService, webPushService, whatsAppService)
+	notificationHandler := handlers.NewNotificationHandler(slackService, notificationCheckService) // Notification settings and test notifications
+	availabilityHandler := handlers.NewAvailabilityHandler(services.NewAvailabilityService(pg))      // Vacation/DND periods skipped by escalation
+	// Daily/weekly incident activity emails for group managers
+	activityDigestHandler := handlers.NewActivityDigestHandler(services.NewActivityDigestService(pg, emailService))
+	// Desktop tray notifier WebSocket: incident pushes, acks and on-call status
+	desktopRelayHandler := handlers.NewDesktopRelayHandler(services.NewDesktopRelayService(pg, incidentService))
+	userImportService := services.NewUserImportService(pg, emailService)
+	userImportHandler := handlers.NewUserImportHandler(userImportService, authzBackend) // Bulk CSV user import
+	userIncidentStatsHandler := handlers.NewUserIncidentStatsHandler(userService, authzBackend) // Per-user participation metrics
+	groupBroadcastHandler := handlers.NewGroupBroadcastHandler(incidentService, authzBackend)   // Emergency pages to a whole group
+	alertQualityHandler := handlers.NewAlertQualityHandler(incidentService, authzBackend)        // Noisy alert report
+	incidentExportHandler := handlers.NewIncidentExportHandler(incidentService, authzBackend)   // Bulk NDJSON export for warehouses
+	trashHandler := handlers.NewTrashHandler(services.NewTrashService(pg), authzBackend)        // Restore deleted configuration
+	scheduleImpactHandler := handlers.NewScheduleImpactHandler(incidentService)                // Preview schedule changes against open incidents
+	pendingChangeHandler := handlers.NewPendingChangeHandler(pendingChangeService, authzBackend) // Approve or reject held destructive changes
+	usageHandler := handlers.NewUsageHandler(services.NewUsageService(pg))                        // Monthly usage metering per org
+	scimService := services.NewSCIMService(pg, groupService)
+	scimHandler := handlers.NewSCIMHandler(scimService) // SCIM 2.0 provisioning
+	wallboardService := services.NewWallboardService(pg)
+	wallboardHandler := handlers.NewWallboardHandler(wallboardService) // Wallboard/NOC displays
+	readTokenService := services.NewReadTokenService(pg)
+	readTokenHandler := handlers.NewReadTokenHandler(readTokenService, authzBackend) // Read-only dashboard/portal tokens
+	configPromotionHandler := handlers.NewConfigPromotionHandler(services.NewConfigBackupService(pg))
+	featureFlagHandler := handlers.NewFeatureFlagHandler(services.NewFeatureFlagService(pg))
+	orgSettingsHandler := handlers.NewOrgSettingsHandler(services.NewOrgSettingsService(pg))
+	llmService := services.NewLLMService(pg)
+	incidentService.SetLLMService(llmService) // For AI-drafted postmortems
+	llmHandler := handlers.NewLLMHandler(llmService)
+	analyticsDashboardService := services.NewAnalyticsDashboardService(pg)
+	analyticsDashboardHandler := handlers.NewAnalyticsDashboardHandler(analyticsDashboardService) // Saved analytics dashboards
 
 	// AI Agent Registry - Multi-agent routing with self-registration
 	agentRegistry := services.NewAgentRegistry()
@@ -139,7 +190,7 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 
 	// Initialize session token service for backend-issued JWT tokens
 	// This decouples API auth from OIDC provider's short-lived ID tokens (5 min)
-	sessionTokenService := services.NewSessionTokenService(config.App.SessionSecret)
+	sessionTokenService := services.NewSessionTokenService(config.Current().SessionSecret)
 
 	// Initialize middleware (OIDC auth - replaces Supabase)
 	// Returns nil if OIDC is not configured
@@ -173,16 +224,21 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 
 		// Return OIDC config for frontend authentication
 		// Use web-specific client ID if configured, otherwise fallback to default
-		webClientID := config.App.OIDCWebClientID
+		webClientID := config.Current().OIDCWebClientID
 		if webClientID == "" {
-			webClientID = config.App.OIDCClientID // Fallback to default
+			webClientID = config.Current().OIDCClientID // Fallback to default
 		}
 
 		// Only standard OIDC fields - provider name not needed (use generic "SSO")
+		// config_version changes when reloadable settings change (see POST /internal/config/reload)
+		configVersion, configLoadedAt := config.Version()
 		c.JSON(200, gin.H{
-			"oidc_issuer":    config.App.OIDCIssuer,
-			"oidc_client_id": webClientID,
-			"api_url":        config.App.BackendURL,
+			"oidc_issuer":      config.Current().OIDCIssuer,
+			"oidc_client_id":   webClientID,
+			"api_url":          config.Current().BackendURL,
+			"external_url":     config.ExternalAPIURL(),
+			"config_version":   configVersion,
+			"config_loaded_at": configLoadedAt,
 		})
 	})
 
@@ -212,20 +268,77 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 
 	// PUBLIC WEBHOOK ENDPOINTS (no authentication - secured by integration secret)
 	webhookRoutes := r.Group("/webhook")
+	webhookRoutes.Use(webhookHandler.IngestBackpressureMiddleware())
 	{
 		// Integration webhooks: /webhook/:type/:integration_id
-		webhookRoutes.POST("/:type/:integration_id", webhookHandler.ReceiveWebhook)
+		webhookRoutes.POST("/:type/:integration_id", webhookHandler.WebhookAllowlistMiddleware(), webhookHandler.ReceiveWebhook)
+		// Batch ingest for high-volume forwarders: /webhook/:type/:integration_id/batch
+		webhookRoutes.POST("/:type/:integration_id/batch", webhookHandler.WebhookAllowlistMiddleware(), webhookHandler.ReceiveWebhookBatch)
+		// Chart snapshots for incidents: /webhook/:type/:integration_id/snapshots
+		webhookRoutes.POST("/:type/:integration_id/snapshots", webhookHandler.WebhookAllowlistMiddleware(), webhookHandler.ReceiveSnapshot)
 	}
 
 	// API KEY AUTHENTICATED WEBHOOK ENDPOINTS
 	apiKeyWebhookRoutes := r.Group("/webhooks")
-	apiKeyWebhookRoutes.Use(apiKeyHandler.APIKeyAuthMiddleware())
+	apiKeyWebhookRoutes.Use(webhookHandler.IngestBackpressureMiddleware(), apiKeyHandler.APIKeyAuthMiddleware())
 	{
-		apiKeyWebhookRoutes.POST("/incident", incidentHandler.WebhookCreateIncident) // NEW: PagerDuty-style incident webhook
-		apiKeyWebhookRoutes.POST("/alert", apiKeyHandler.WebhookAlert)               // Legacy
+		apiKeyWebhookRoutes.POST("/incident", incidentHandler.WebhookCreateIncident)  // NEW: PagerDuty-style incident webhook
+		apiKeyWebhookRoutes.POST("/change", incidentHandler.WebhookCreateChangeEvent) // Change events (deploys, flags, infra)
+		apiKeyWebhookRoutes.POST("/alert", apiKeyHandler.WebhookAlert)                // Legacy
 		apiKeyWebhookRoutes.POST("/alertmanager", alertManagerHandler.ReceiveWebhook)
 	}
 
+	// SCIM 2.0 PROVISIONING (no OIDC - secured by org-scoped SCIM bearer token)
+	// Used by Okta / Azure AD to provision users and group memberships
+	scimRoutes := r.Group("/scim/v2")
+	scimRoutes.Use(scimHandler.SCIMAuthMiddleware())
+	{
+		scimRoutes.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+		scimRoutes.GET("/ResourceTypes", scimHandler.ResourceTypes)
+
+		scimRoutes.GET("/Users", scimHandler.ListUsers)
+		scimRoutes.POST("/Users", scimHandler.CreateUser)
+		scimRoutes.GET("/Users/:id", scimHandler.GetUser)
+		scimRoutes.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scimRoutes.PATCH("/Users/:id", scimHandler.PatchUser)
+		scimRoutes.DELETE("/Users/:id", scimHandler.DeleteUser)
+
+		scimRoutes.GET("/Groups", scimHandler.ListGroups)
+		scimRoutes.POST("/Groups", scimHandler.CreateGroup)
+		scimRoutes.GET("/Groups/:id", scimHandler.GetGroup)
+		scimRoutes.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scimRoutes.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scimRoutes.DELETE("/Groups/:id", scimHandler.DeleteGroup)
+	}
+
+	// WALLBOARD / NOC DISPLAY (no OIDC - secured by read-only, org-scoped wallboard token)
+	// Used by auto-refreshing TV dashboards that have no user account
+	wallboardRoutes := r.Group("/wallboard")
+	wallboardRoutes.Use(wallboardHandler.WallboardAuthMiddleware())
+	{
+		wallboardRoutes.GET("", wallboardHandler.GetWallboard)
+		wallboardRoutes.GET("/incidents", wallboardHandler.GetIncidents)
+		wallboardRoutes.GET("/oncall", wallboardHandler.GetOnCall)
+		wallboardRoutes.GET("/monitors", wallboardHandler.GetMonitors)
+	}
+
+	// READ-ONLY API (no OIDC - secured by a user's read token, never mutates)
+	// Powers Grafana JSON datasources and internal portals; each route needs its scope
+	readRoutes := r.Group("/read")
+	readRoutes.Use(readTokenHandler.ReadTokenAuthMiddleware())
+	{
+		readRoutes.GET("/incidents", readTokenHandler.RequireReadScope(db.ReadScopeIncidents), readTokenHandler.GetIncidents)
+		readRoutes.GET("/oncall", readTokenHandler.RequireReadScope(db.ReadScopeOnCall), readTokenHandler.GetOnCall)
+		readRoutes.GET("/monitors", readTokenHandler.RequireReadScope(db.ReadScopeMonitors), readTokenHandler.GetMonitors)
+
+		// Grafana SimpleJSON / Infinity data source; metrics are checked against the token's scopes.
+		// The POSTs only carry the query, as the protocol requires - nothing is changed.
+		readRoutes.GET("/grafana", readTokenHandler.GrafanaTestDatasource)
+		readRoutes.POST("/grafana/search", readTokenHandler.GrafanaSearch)
+		readRoutes.POST("/grafana/query", readTokenHandler.GrafanaQuery)
+		readRoutes.GET("/grafana/query", readTokenHandler.GrafanaQuery)
+	}
+
 	// INTERNAL ENDPOINTS (service-to-service, no OIDC auth - secured at network level)
 	// Used by AI Agent to delegate authorization checks to Go API
 	internalAuthzRoutes := r.Group("/internal/authz")
@@ -243,6 +356,20 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	}
 	log.Println("✅ Internal policy endpoints initialized: /internal/policies/*")
 
+	// Internal LLM completions (no OIDC auth - called by AI workers with internal_api_token)
+	requireInternalToken := handlers.RequireBearerToken("internal_api_token", func() string {
+		return config.Current().InternalAPIToken
+	})
+	r.POST("/internal/llm/complete", requireInternalToken, llmHandler.InternalComplete)
+
+	// Internal incident artifact endpoints (no OIDC auth - called by the Python agent with internal_api_token)
+	internalArtifactRoutes := r.Group("/internal/incident-artifacts")
+	internalArtifactRoutes.Use(requireInternalToken)
+	{
+		internalArtifactRoutes.POST("", incidentHandler.InternalCreateIncidentArtifact)
+		internalArtifactRoutes.GET("/search", incidentHandler.InternalSearchIncidentArtifacts)
+	}
+
 	// AI Agent self-registration endpoints (no auth - agents call these on startup)
 	internalAgentRoutes := r.Group("/internal/agents")
 	{
@@ -253,6 +380,45 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	}
 	log.Println("✅ Agent registration endpoints initialized: /internal/agents/*")
 
+	// Process metrics (expvar), e.g. escalation watchdog counters; scrapers
+	// send internal_api_token as a bearer token
+	r.GET("/internal/metrics", requireInternalToken, gin.WrapH(expvar.Handler()))
+
+	// Webhook ingest backpressure: level, queue depths, shed and refused counts
+	r.GET("/internal/ingest/backpressure", requireInternalToken, webhookHandler.GetIngestBackpressure)
+
+	// Re-read non-connection settings without a restart (same as SIGHUP).
+	// Callers send internal_api_token as a bearer token.
+	r.POST("/internal/config/reload", requireInternalToken, func(c *gin.Context) {
+		result, err := config.Reload()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		config.LogReload(result)
+		c.JSON(200, result)
+	})
+
+	// Staging -> production config promotion: export here, diff and apply there.
+	// Callers send config_promotion_token as a bearer token.
+	promotionRoutes := r.Group("/internal/config")
+	promotionRoutes.Use(handlers.RequireBearerToken("config_promotion_token", func() string {
+		return config.Current().ConfigPromotionToken
+	}))
+	{
+		promotionRoutes.GET("/bundle", configPromotionHandler.GetBundle)
+		promotionRoutes.POST("/promotions/diff", configPromotionHandler.DiffBundle)
+		promotionRoutes.POST("/promotions/apply", configPromotionHandler.Promote)
+	}
+
+	// Conditional GETs (ETag/Last-Modified, 304) for hot read endpoints the
+	// web UI and mobile app poll
+	conditionalGet := handlers.ConditionalGetMiddleware()
+
+	// ?tz= adds local renderings next to the UTC timestamps of incident and
+	// schedule responses
+	localTime := handlers.LocalTimeMiddleware()
+
 	// PROTECTED ENDPOINTS (require OIDC authentication)
 	protected := r.Group("/")
 	if oidcAuthMiddleware != nil {
@@ -261,6 +427,20 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 		protected.Use(authNotConfiguredMiddleware())
 	}
 	{
+		// Resilience testing: inject DB latency, pgmq failures and provider
+		// outages (only when fault_injection_enabled is set, org admins only)
+		if faults.Enabled() {
+			faultInjectionHandler := handlers.NewFaultInjectionHandler(services.NewFaultInjectionService(pg), authzBackend)
+			faultRoutes := protected.Group("/internal/faults")
+			faultRoutes.Use(faultInjectionHandler.RequireOrgAdmin())
+			{
+				faultRoutes.GET("", faultInjectionHandler.ListFaults)
+				faultRoutes.POST("", faultInjectionHandler.CreateFault)
+				faultRoutes.DELETE("", faultInjectionHandler.ClearFaults)
+				faultRoutes.DELETE("/:id", faultInjectionHandler.DeleteFault)
+			}
+		}
+
 		// =====================================================================
 		// ORGANIZATION MANAGEMENT (Defense in Depth)
 		// =====================================================================
@@ -297,6 +477,87 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 				orgDetailRoutes.DELETE("/members/:user_id",
 					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
 					orgHandler.RemoveOrgMember)
+
+				// SCIM provisioning tokens require ActionManage
+				orgDetailRoutes.GET("/scim-tokens",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					scimHandler.ListTokens)
+				orgDetailRoutes.POST("/scim-tokens",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					scimHandler.CreateToken)
+				orgDetailRoutes.DELETE("/scim-tokens/:token_id",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					scimHandler.RevokeToken)
+
+				// Monthly usage rollups (billing, capacity planning) require ActionManage
+				orgDetailRoutes.GET("/usage",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					usageHandler.GetOrgUsage)
+
+				// Wallboard display tokens require ActionManage
+				orgDetailRoutes.GET("/wallboard-tokens",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					wallboardHandler.ListTokens)
+				orgDetailRoutes.POST("/wallboard-tokens",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					wallboardHandler.CreateToken)
+				orgDetailRoutes.DELETE("/wallboard-tokens/:token_id",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					wallboardHandler.RevokeToken)
+
+				// Custom incident workflow states: anyone in the org can read, admins manage
+				orgDetailRoutes.GET("/incident-workflow-states", incidentHandler.ListWorkflowStates)
+				orgDetailRoutes.POST("/incident-workflow-states",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.CreateWorkflowState)
+				orgDetailRoutes.PATCH("/incident-workflow-states/:state_id",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.UpdateWorkflowState)
+				orgDetailRoutes.DELETE("/incident-workflow-states/:state_id",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.DeleteWorkflowState)
+
+				// Incident tagging rules: anyone in the org can read and test, admins manage
+				orgDetailRoutes.GET("/tagging-rules", incidentHandler.ListTaggingRules)
+				orgDetailRoutes.PUT("/tagging-rules",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.SetTaggingRules)
+				orgDetailRoutes.POST("/tagging-rules/test", incidentHandler.TestTaggingRules)
+
+				// Freeze periods (code freeze calendar): anyone in the org can read, admins manage
+				orgDetailRoutes.GET("/freeze-periods", incidentHandler.ListFreezePeriods)
+				orgDetailRoutes.POST("/freeze-periods",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.CreateFreezePeriod)
+				orgDetailRoutes.PATCH("/freeze-periods/:period_id",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.UpdateFreezePeriod)
+				orgDetailRoutes.DELETE("/freeze-periods/:period_id",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					incidentHandler.DeleteFreezePeriod)
+
+				// Per-org feature flag rollout: anyone in the org can read, admins manage
+				orgDetailRoutes.GET("/features", featureFlagHandler.ListOrgFlags)
+				orgDetailRoutes.PUT("/features/:key",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					featureFlagHandler.SetOrgFlag)
+
+				// Display settings (timezone, date/time format, week start): anyone in the org can read, admins update
+				orgDetailRoutes.GET("/settings/display", orgSettingsHandler.GetDisplaySettings)
+				orgDetailRoutes.PATCH("/settings/display",
+					authzMiddleware.RequirePermission(authz.ActionUpdate, authz.ResourceOrg),
+					orgSettingsHandler.UpdateDisplaySettings)
+
+				// LLM provider, key and token budget, and usage metering: admins only
+				orgDetailRoutes.GET("/settings/llm",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					llmHandler.GetLLMSettings)
+				orgDetailRoutes.PATCH("/settings/llm",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					llmHandler.UpdateLLMSettings)
+				orgDetailRoutes.GET("/llm-usage",
+					authzMiddleware.RequirePermission(authz.ActionManage, authz.ResourceOrg),
+					llmHandler.GetLLMUsage)
 			}
 
 			// Projects under org - requires org access first
@@ -348,18 +609,64 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 		// Uses ProjectScopedMiddleware to inject project context (ReBAC)
 		incidentRoutes := protected.Group("/incidents")
 		incidentRoutes.Use(projectScopedMiddleware.InjectProjectContext()) // ReBAC: inject project_id/org_id/accessible_project_ids
+		incidentRoutes.Use(incidentHandler.ResolveIncidentNumber())        // :id may be an incident number (INC-1024)
+		incidentRoutes.Use(localTime)
 		{
 			incidentRoutes.GET("", incidentHandler.ListIncidents)
 			incidentRoutes.POST("", incidentHandler.CreateIncident)
 			incidentRoutes.GET("/stats", incidentHandler.GetIncidentStats)
-			incidentRoutes.GET("/:id", incidentHandler.GetIncident)
+			incidentRoutes.GET("/feed", incidentHandler.GetIncidentFeed)
+			incidentRoutes.GET("/:id", conditionalGet, incidentHandler.GetIncident)
 			incidentRoutes.PUT("/:id", incidentHandler.UpdateIncident)
 			incidentRoutes.POST("/:id/acknowledge", incidentHandler.AcknowledgeIncident)
+			incidentRoutes.POST("/:id/claim", incidentHandler.ClaimIncident)
+			incidentRoutes.POST("/:id/unclaim", incidentHandler.UnclaimIncident)
+			incidentRoutes.GET("/:id/settings", incidentHandler.GetIncidentSettings)
+			incidentRoutes.PUT("/:id/settings", incidentHandler.UpdateIncidentSettings)
+			incidentRoutes.POST("/:id/postmortem/draft", incidentHandler.DraftPostmortem) // AI-generated, saved for editing
 			incidentRoutes.POST("/:id/resolve", incidentHandler.ResolveIncident)
+			incidentRoutes.POST("/:id/workflow-state", incidentHandler.SetIncidentWorkflowState)
 			incidentRoutes.POST("/:id/assign", incidentHandler.AssignIncident)
 			incidentRoutes.POST("/:id/escalate", incidentHandler.EscalateIncident)
+			incidentRoutes.POST("/:id/page", incidentHandler.PageIncident)
+			incidentRoutes.GET("/:id/receipts", incidentHandler.GetNotificationReceipts)
+			incidentRoutes.POST("/:id/receipts", incidentHandler.RecordNotificationReceipt)
+			incidentRoutes.GET("/:id/presence", incidentHandler.GetIncidentPresence)
+			incidentRoutes.POST("/:id/presence", incidentHandler.UpdateIncidentPresence)
+			incidentRoutes.DELETE("/:id/presence", incidentHandler.LeaveIncidentPresence)
+			incidentRoutes.GET("/:id/presence/stream", incidentHandler.StreamIncidentPresence)
+			incidentRoutes.GET("/:id/tasks", incidentHandler.ListIncidentTasks)
+			incidentRoutes.POST("/:id/tasks", incidentHandler.CreateIncidentTask)
+			incidentRoutes.PUT("/:id/tasks/order", incidentHandler.ReorderIncidentTasks)
+			incidentRoutes.PATCH("/:id/tasks/:task_id", incidentHandler.UpdateIncidentTask)
+			incidentRoutes.DELETE("/:id/tasks/:task_id", incidentHandler.DeleteIncidentTask)
+			incidentRoutes.GET("/:id/relations", incidentHandler.ListIncidentRelations)
+			incidentRoutes.POST("/:id/relations", incidentHandler.CreateIncidentRelation) // related / duplicate_of / caused_by
+			incidentRoutes.DELETE("/:id/relations/:relation_id", incidentHandler.DeleteIncidentRelation)
 			incidentRoutes.POST("/:id/notes", incidentHandler.AddIncidentNote)
 			incidentRoutes.GET("/:id/events", incidentHandler.GetIncidentEvents)
+			incidentRoutes.GET("/:id/escalation-timeline", incidentHandler.GetEscalationTimeline)
+			incidentRoutes.GET("/:id/changes", incidentHandler.GetIncidentChanges)
+			incidentRoutes.GET("/:id/summary", incidentHandler.GetIncidentSummary)
+			incidentRoutes.GET("/:id/share-links", incidentHandler.ListIncidentShareLinks)
+			incidentRoutes.POST("/:id/share-links", incidentHandler.CreateIncidentShareLink)
+			incidentRoutes.DELETE("/:id/share-links/:link_id", incidentHandler.RevokeIncidentShareLink)
+			incidentRoutes.GET("/:id/share-links/:link_id/views", incidentHandler.ListIncidentShareLinkViews)
+			incidentRoutes.GET("/:id/artifacts", incidentHandler.ListIncidentArtifacts)
+			incidentRoutes.POST("/:id/artifacts", incidentHandler.CreateIncidentArtifact)
+			incidentRoutes.GET("/:id/artifacts/:artifact_id", incidentHandler.GetIncidentArtifact)
+			incidentRoutes.DELETE("/:id/artifacts/:artifact_id", incidentHandler.DeleteIncidentArtifact)
+		}
+
+		// FEATURE FLAGS for the current org (frontend gates UI on these)
+		protected.GET("/features", featureFlagHandler.GetFeatures)
+
+		// PAST-INCIDENT SEARCH ("how did we fix this last time")
+		searchRoutes := protected.Group("/search")
+		searchRoutes.Use(projectScopedMiddleware.InjectProjectContext()) // ReBAC: same scope as the incident list
+		{
+			searchRoutes.GET("/resolutions", incidentHandler.SearchResolutions)
+			searchRoutes.GET("/artifacts", incidentHandler.SearchIncidentArtifacts)
 		}
 
 		// =====================================================================
@@ -368,6 +675,7 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 		// Incidents filtered by project - requires project VIEW access
 		projectIncidentRoutes := protected.Group("/projects/:id/incidents")
 		projectIncidentRoutes.Use(authzMiddleware.RequirePermission(authz.ActionView, authz.ResourceProject))
+		projectIncidentRoutes.Use(localTime)
 		{
 			projectIncidentRoutes.GET("", incidentHandler.ListIncidents)          // Filtered by project_id from URL
 			projectIncidentRoutes.GET("/stats", incidentHandler.GetIncidentStats) // Stats for this project
@@ -378,8 +686,9 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 				incidentHandler.CreateIncident)
 		}
 
-		// ALERTS MANAGEMENT (Legacy - for backward compatibility)
+		// ALERTS MANAGEMENT (Legacy - for backward compatibility, served from incidents)
 		alertRoutes := protected.Group("/alerts")
+		alertRoutes.Use(projectScopedMiddleware.InjectProjectContext(), alertHandler.Deprecated())
 		{
 			alertRoutes.GET("", alertHandler.ListAlerts)
 			alertRoutes.POST("", alertHandler.CreateAlert)
@@ -389,6 +698,38 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			alertRoutes.POST("/:id/close", alertHandler.CloseAlert)
 		}
 
+		// ALERT DEDUP REVIEW QUEUE (alerts that only matched an open incident by title)
+		dedupReviewRoutes := protected.Group("/dedup-reviews")
+		{
+			dedupReviewRoutes.GET("", webhookHandler.ListDedupReviews)
+			dedupReviewRoutes.GET("/stats", webhookHandler.GetDedupStats)
+			dedupReviewRoutes.GET("/:id", webhookHandler.GetDedupReview)
+			dedupReviewRoutes.POST("/:id/attach", webhookHandler.AttachDedupReview)
+			dedupReviewRoutes.POST("/:id/promote", webhookHandler.PromoteDedupReview)
+			dedupReviewRoutes.POST("/:id/dismiss", webhookHandler.DismissDedupReview)
+		}
+
+		// TEST NOTIFICATION to the calling user on every (or selected) channel, with per-channel diagnostics
+		protected.POST("/notifications/test", notificationHandler.TestNotifications)
+
+		// DESKTOP NOTIFIER: persistent WebSocket pushing the user's incidents and
+		// on-call status to the tray client, which can acknowledge from it
+		protected.GET("/ws/desktop", desktopRelayHandler.Connect)
+
+		// BULK EXPORT (nightly warehouse loads; cursor-paged NDJSON)
+		protected.GET("/export/incidents", incidentExportHandler.ExportIncidents)
+
+		// TRASH: deleted services, schedulers, escalation policies and integrations stay restorable for trash_retention
+		protected.GET("/trash", trashHandler.ListTrash)
+		protected.POST("/trash/:type/:id/restore", trashHandler.RestoreTrashItem)
+
+		// PENDING CHANGES: deleting a team with members, rotating every API key and
+		// disabling a live integration wait for a second org admin (pending_change_ttl)
+		protected.GET("/pending-changes", pendingChangeHandler.ListPendingChanges)
+		protected.GET("/pending-changes/:id", pendingChangeHandler.GetPendingChange)
+		protected.POST("/pending-changes/:id/approve", pendingChangeHandler.ApprovePendingChange)
+		protected.POST("/pending-changes/:id/reject", pendingChangeHandler.RejectPendingChange)
+
 		// API KEY MANAGEMENT
 		apiKeyRoutes := protected.Group("/api-keys")
 		{
@@ -398,30 +739,74 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			apiKeyRoutes.PUT("/:id", apiKeyHandler.UpdateAPIKey)
 			apiKeyRoutes.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
 			apiKeyRoutes.POST("/:id/regenerate", apiKeyHandler.RegenerateAPIKey)
+			apiKeyRoutes.POST("/rotate-all", pendingChangeHandler.RotateAllAPIKeys) // Held until another org admin approves
+			apiKeyRoutes.GET("/:id/usage", apiKeyHandler.GetAPIKeyUsage)
 			apiKeyRoutes.GET("/stats", apiKeyHandler.GetAPIKeyStats)
 		}
 
+		// READ TOKENS (any user; read-only tokens for dashboards and portals, see /read)
+		readTokenRoutes := protected.Group("/read-tokens")
+		{
+			readTokenRoutes.POST("", readTokenHandler.CreateToken)
+			readTokenRoutes.GET("", readTokenHandler.ListTokens)
+			readTokenRoutes.DELETE("/:id", readTokenHandler.RevokeToken)
+		}
+
 		// USER MANAGEMENT
 		userRoutes := protected.Group("/users")
 		{
 			userRoutes.GET("", userHandler.ListUsers)
 			userRoutes.GET("/search", userHandler.SearchUsers)
 			userRoutes.POST("", userHandler.CreateUser)
+			userRoutes.POST("/import", userImportHandler.ImportUsers)
 			userRoutes.GET("/:id", userHandler.GetUser)
+			userRoutes.GET("/:id/incident-stats", userIncidentStatsHandler.GetIncidentStats)
 			userRoutes.PUT("/:id", userHandler.UpdateUser)
 			userRoutes.DELETE("/:id", userHandler.DeleteUser)
 			userRoutes.POST("/fcm-token", userHandler.UpdateFCMToken)
 			userRoutes.GET("/fcm-token", userHandler.GetFCMToken)
+			userRoutes.GET("/me/devices", userHandler.ListDevices)
+			userRoutes.DELETE("/me/devices/:id", userHandler.DeleteDevice)
 
 			// Notification configuration endpoints (uses authenticated user from context)
 			userRoutes.GET("/me/notifications/config", notificationHandler.GetNotificationConfig)
 			userRoutes.PUT("/me/notifications/config", notificationHandler.UpdateNotificationConfig)
-			userRoutes.POST("/me/notifications/test/slack", notificationHandler.TestSlackNotification)
+			userRoutes.GET("/me/notifications/dedup", notificationHandler.GetNotificationDedup)
+			userRoutes.PUT("/me/notifications/dedup", notificationHandler.UpdateNotificationDedup)
+			userRoutes.POST("/me/notifications/test/slack", notificationHandler.TestSlackNotification) // Deprecated: POST /notifications/test
 			userRoutes.GET("/me/notifications/stats", notificationHandler.GetNotificationStats)
+
+			// Vacation/DND: schedule-based escalations pass over the user
+			userRoutes.GET("/me/unavailability", availabilityHandler.ListUnavailability)
+			userRoutes.POST("/me/unavailability", availabilityHandler.CreateUnavailability)
+			userRoutes.DELETE("/me/unavailability/:id", availabilityHandler.DeleteUnavailability)
+
+			// Daily/weekly incident activity emails for group managers
+			userRoutes.GET("/me/activity-digests", activityDigestHandler.ListActivityDigests)
+			userRoutes.POST("/me/activity-digests", activityDigestHandler.SubscribeActivityDigest)
+			userRoutes.GET("/me/activity-digests/preview", activityDigestHandler.PreviewActivityDigest)
+			userRoutes.DELETE("/me/activity-digests/:id", activityDigestHandler.UnsubscribeActivityDigest)
+
+			// WhatsApp notification consent
+			userRoutes.GET("/me/whatsapp", whatsAppHandler.GetWhatsAppConsent)
+			userRoutes.POST("/me/whatsapp/opt-in", whatsAppHandler.OptInWhatsApp)
+			userRoutes.POST("/me/whatsapp/opt-out", whatsAppHandler.OptOutWhatsApp)
+
+			// Phone number for SMS incident commands
+			userRoutes.GET("/me/sms", smsHandler.GetSMSPhone)
+			userRoutes.POST("/me/sms", smsHandler.SetSMSPhone)
+			userRoutes.DELETE("/me/sms", smsHandler.DeleteSMSPhone)
+			userRoutes.PUT("/me/voice", voiceHandler.SetVoiceCalls)
+
+			// Browser (Web Push) notification subscriptions
+			userRoutes.GET("/me/web-push", webPushHandler.GetWebPush)
+			userRoutes.POST("/me/web-push/subscriptions", webPushHandler.Subscribe)
+			userRoutes.DELETE("/me/web-push/subscriptions/:id", webPushHandler.DeleteSubscription)
+			userRoutes.POST("/me/web-push/unsubscribe", webPushHandler.Unsubscribe)
 		}
 
 		// ON-CALL MANAGEMENT
-		oncallRoutes := protected.Group("/oncall")
+		oncallRoutes := protected.Group("/oncall", localTime)
 		{
 			// Legacy endpoints (for backward compatibility)
 			oncallRoutes.GET("/schedules", onCallHandler.ListOnCallSchedules)
@@ -431,14 +816,14 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 		}
 
 		// SCHEDULE MANAGEMENT (direct schedule operations)
-		scheduleRoutes := protected.Group("/schedules")
+		scheduleRoutes := protected.Group("/schedules", localTime)
 		{
 			scheduleRoutes.PUT("/:id", onCallHandler.UpdateSchedule)
 			scheduleRoutes.DELETE("/:id", onCallHandler.DeleteSchedule)
 		}
 
 		// ROTATION CYCLE MANAGEMENT (automatic rotation operations)
-		rotationRoutes := protected.Group("/rotations")
+		rotationRoutes := protected.Group("/rotations", localTime)
 		{
 			rotationRoutes.GET("/:rotationId", rotationHandler.GetRotationCycle)
 			rotationRoutes.GET("/:rotationId/preview", rotationHandler.GetRotationPreview)
@@ -496,7 +881,7 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 		}
 
 		// GROUP MANAGEMENT
-		groupRoutes := protected.Group("/groups")
+		groupRoutes := protected.Group("/groups", localTime) // Schedulers, shifts and overrides live under groups
 		{
 			// Admin-only endpoints (all groups)
 			groupRoutes.GET("/all", groupHandler.ListGroups)
@@ -513,6 +898,7 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			groupRoutes.PUT("/:id", groupHandler.UpdateGroup)
 			groupRoutes.DELETE("/:id", groupHandler.DeleteGroup)
 			groupRoutes.GET("/:id/statistics", groupHandler.GetGroupStatistics)
+			groupRoutes.GET("/:id/subgroups", groupHandler.GetSubGroups)
 
 			// Group member management
 			groupRoutes.GET("/:id/members", groupHandler.GetGroupMembers)
@@ -521,16 +907,49 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			groupRoutes.PUT("/:id/members/:user_id", groupHandler.UpdateGroupMember)
 			groupRoutes.DELETE("/:id/members/:user_id", groupHandler.RemoveGroupMember)
 
+			// Emergency broadcast: page every active member at once
+			groupRoutes.POST("/:id/broadcast", groupBroadcastHandler.Broadcast)
+
+			// Group invitations (email join links) and join requests (public/organization groups)
+			groupRoutes.GET("/:id/invitations", groupInvitationHandler.ListInvitations)
+			groupRoutes.POST("/:id/invitations", groupInvitationHandler.CreateInvitation)
+			groupRoutes.DELETE("/:id/invitations/:invitation_id", groupInvitationHandler.RevokeInvitation)
+			groupRoutes.GET("/:id/join-requests", groupInvitationHandler.ListJoinRequests)
+			groupRoutes.POST("/:id/join-requests", groupInvitationHandler.CreateJoinRequest)
+			groupRoutes.POST("/:id/join-requests/:request_id/approve", groupInvitationHandler.ApproveJoinRequest)
+			groupRoutes.POST("/:id/join-requests/:request_id/reject", groupInvitationHandler.RejectJoinRequest)
+			groupRoutes.DELETE("/:id/join-requests/:request_id", groupInvitationHandler.CancelJoinRequest)
+
+			// Chat notification channels (Discord, Telegram, Google Chat and registered types)
+			groupRoutes.GET("/:id/chat-channels", chatChannelHandler.ListChatChannels)
+			groupRoutes.POST("/:id/chat-channels", chatChannelHandler.CreateChatChannel)
+			groupRoutes.PATCH("/:id/chat-channels/:channel_id", chatChannelHandler.UpdateChatChannel)
+			groupRoutes.DELETE("/:id/chat-channels/:channel_id", chatChannelHandler.DeleteChatChannel)
+			groupRoutes.POST("/:id/chat-channels/:channel_id/test", chatChannelHandler.TestChatChannel)
+
+			// Sheet exports: resolved incidents appended to a Google Sheet or CSV webhook
+			groupRoutes.GET("/:id/sheet-exports", sheetExportHandler.ListSheetExports)
+			groupRoutes.POST("/:id/sheet-exports", sheetExportHandler.CreateSheetExport)
+			groupRoutes.PATCH("/:id/sheet-exports/:export_id", sheetExportHandler.UpdateSheetExport)
+			groupRoutes.DELETE("/:id/sheet-exports/:export_id", sheetExportHandler.DeleteSheetExport)
+
 			// Group scheduler management (NEW: Scheduler + Shifts architecture)
 			groupRoutes.GET("/:id/schedulers", schedulerHandler.GetGroupSchedulers)                              // List schedulers (basic info)
 			groupRoutes.POST("/:id/schedulers/with-shifts", schedulerHandler.CreateSchedulerWithShiftsOptimized) // Create scheduler + shifts (OPTIMIZED - default)
 			groupRoutes.POST("/:id/schedulers/with-shifts-legacy", schedulerHandler.CreateSchedulerWithShifts)   // LEGACY: Fallback to non-optimized
 			groupRoutes.GET("/:id/schedulers/stats", schedulerHandler.GetSchedulerPerformanceStats)              // Performance statistics
+			groupRoutes.POST("/:id/schedulers/regenerate", schedulerHandler.RegenerateSchedules)                  // Redistribute a departing member's future shifts (supports preview)
 			groupRoutes.POST("/:id/schedulers/benchmark", schedulerHandler.BenchmarkSchedulerCreation)           // Performance benchmark
 			groupRoutes.GET("/:id/schedulers/:scheduler_id", schedulerHandler.GetSchedulerWithShifts)            // Get scheduler with shifts
 			groupRoutes.PUT("/:id/schedulers/:scheduler_id", schedulerHandler.UpdateSchedulerWithShifts)         // Update scheduler and its shifts
 			groupRoutes.DELETE("/:id/schedulers/:scheduler_id", schedulerHandler.DeleteScheduler)                // Delete scheduler and its shifts
 			groupRoutes.GET("/:id/shifts", schedulerHandler.GetGroupShifts)                                      // Get all shifts in group (with scheduler context)
+			groupRoutes.POST("/:id/shifts/batch", schedulerHandler.BatchEditShifts)                              // Create/update/delete many shifts in one transaction
+
+			// Schedule managers: besides group owners and admins, the users allowed to change a scheduler's shifts and overrides
+			groupRoutes.GET("/:id/schedulers/:scheduler_id/managers", schedulerHandler.ListSchedulerManagers)
+			groupRoutes.POST("/:id/schedulers/:scheduler_id/managers", schedulerHandler.AddSchedulerManager)
+			groupRoutes.DELETE("/:id/schedulers/:scheduler_id/managers/:user_id", schedulerHandler.RemoveSchedulerManager)
 
 			// Debug: Log that delete route is registered
 			log.Println("🔧 DELETE route registered: /groups/:id/schedulers/:scheduler_id")
@@ -550,11 +969,17 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			// Group schedule management (Legacy: Individual shifts)
 			groupRoutes.GET("/:id/schedules", onCallHandler.GetGroupSchedules)
 			groupRoutes.POST("/:id/schedules", schedulerHandler.CreateGroupSchedule) // Updated to support service scheduling
-			groupRoutes.GET("/:id/schedules/current", onCallHandler.GetCurrentOnCallUser)
+			groupRoutes.GET("/:id/schedules/current", conditionalGet, onCallHandler.GetCurrentOnCallUser)
 			groupRoutes.GET("/:id/schedules/upcoming", onCallHandler.GetUpcomingSchedules)
+			groupRoutes.GET("/:id/effective-oncall", onCallHandler.GetEffectiveOnCall) // Resolved on-call intervals over ?from&to (overrides applied)
+			groupRoutes.POST("/:id/schedules/preview-impact", scheduleImpactHandler.PreviewScheduleImpact) // Who open incidents would move to if the changes were saved
 
-			// Schedule swap endpoint
+			// Schedule swap endpoint (members without schedule manager rights get a swap request instead)
 			groupRoutes.POST("/:id/schedules/swap", onCallHandler.SwapSchedules)
+			groupRoutes.GET("/:id/swap-requests", onCallHandler.ListSwapRequests)
+			groupRoutes.POST("/:id/swap-requests/:request_id/approve", onCallHandler.ApproveSwapRequest)
+			groupRoutes.POST("/:id/swap-requests/:request_id/reject", onCallHandler.RejectSwapRequest)
+			groupRoutes.DELETE("/:id/swap-requests/:request_id", onCallHandler.CancelSwapRequest)
 
 			// Group rotation cycle management (automatic rotations)
 			groupRoutes.GET("/:id/rotations", rotationHandler.GetGroupRotationCycles)
@@ -567,10 +992,11 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 
 			// NEW: Service scheduling endpoints (DEPRECATED - use /schedulers instead)
 			groupRoutes.GET("/:id/scheduler-timelines", schedulerHandler.GetGroupSchedulerTimelines)
-			groupRoutes.GET("/:id/services", serviceHandler.GetGroupServices) // Use ServiceHandler instead
+			groupRoutes.GET("/:id/services", conditionalGet, serviceHandler.GetGroupServices) // Use ServiceHandler instead
 
 			// Service management within groups
 			groupRoutes.POST("/:id/services", serviceHandler.CreateService)
+			groupRoutes.POST("/:id/services/bootstrap", serviceHandler.BootstrapService) // Service + scheduler + policy + integration in one call
 
 			// Service-specific scheduling
 			groupRoutes.GET("/:id/services/:service_id/effective-schedule", schedulerHandler.GetEffectiveScheduleForService)
@@ -579,11 +1005,13 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			// Group escalation policies
 			groupRoutes.GET("/:id/escalation-policies", groupHandler.GetGroupEscalationPolicies)
 			groupRoutes.POST("/:id/escalation-policies", groupHandler.CreateEscalationPolicy)
+			groupRoutes.POST("/:id/escalation-policies/import", groupHandler.ImportEscalationPolicyYAML)
 			groupRoutes.GET("/:id/escalation-policies/:policy_id", groupHandler.GetEscalationPolicy)
 			groupRoutes.GET("/:id/escalation-policies/:policy_id/detail", groupHandler.GetEscalationPolicyDetail)
 			groupRoutes.PUT("/:id/escalation-policies/:policy_id", groupHandler.UpdateEscalationPolicy)
 			groupRoutes.DELETE("/:id/escalation-policies/:policy_id", groupHandler.DeleteEscalationPolicy)
 			groupRoutes.GET("/:id/escalation-policies/:policy_id/levels", groupHandler.GetEscalationLevels)
+			groupRoutes.GET("/:id/escalation-policies/:policy_id/export", groupHandler.ExportEscalationPolicyYAML)
 
 		}
 
@@ -591,7 +1019,7 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 		serviceRoutes := protected.Group("/services")
 		{
 			// Service CRUD operations
-			serviceRoutes.GET("", serviceHandler.ListAllServices)      // Admin: list all services
+			serviceRoutes.GET("", conditionalGet, serviceHandler.ListAllServices)      // Admin: list all services
 			serviceRoutes.GET("/:id", serviceHandler.GetService)       // Get specific service
 			serviceRoutes.PUT("/:id", serviceHandler.UpdateService)    // Update service
 			serviceRoutes.DELETE("/:id", serviceHandler.DeleteService) // Delete service
@@ -602,6 +1030,52 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			// Service-Integration mappings
 			serviceRoutes.GET("/:id/integrations", integrationHandler.GetServiceIntegrations)
 			serviceRoutes.POST("/:id/integrations", integrationHandler.CreateServiceIntegration)
+
+			// Routing keys (several active keys, rotation with a grace period)
+			serviceRoutes.GET("/:id/routing-keys", serviceHandler.ListRoutingKeys)
+			serviceRoutes.POST("/:id/routing-keys", serviceHandler.CreateRoutingKey)
+			serviceRoutes.POST("/:id/routing-keys/rotate", serviceHandler.RotateRoutingKey)
+			serviceRoutes.DELETE("/:id/routing-keys/:key_id", serviceHandler.RevokeRoutingKey)
+
+			// Resolve-on-deploy rules (fired by github/gitlab/argocd integrations)
+			serviceRoutes.GET("/:id/deploy-rules", serviceHandler.ListDeployRules)
+			serviceRoutes.POST("/:id/deploy-rules", serviceHandler.CreateDeployRule)
+			serviceRoutes.PATCH("/:id/deploy-rules/:rule_id", serviceHandler.UpdateDeployRule)
+			serviceRoutes.DELETE("/:id/deploy-rules/:rule_id", serviceHandler.DeleteDeployRule)
+
+			// Change events (deploys, feature flags, infra changes)
+			serviceRoutes.GET("/:id/change-events", incidentHandler.ListServiceChangeEvents)
+
+			// On-call readiness drills
+			serviceRoutes.GET("/:id/drills", incidentHandler.ListDrills)
+			serviceRoutes.POST("/:id/drills", incidentHandler.CreateDrill)
+			serviceRoutes.GET("/:id/drills/:drill_id", incidentHandler.GetDrill)
+			serviceRoutes.POST("/:id/drills/:drill_id/cancel", incidentHandler.CancelDrill)
+			serviceRoutes.GET("/:id/drills/:drill_id/report", incidentHandler.GetDrillReport)
+
+			// SLOs and error budgets
+			serviceRoutes.GET("/:id/slos", serviceHandler.ListSLOs)
+			serviceRoutes.POST("/:id/slos", serviceHandler.CreateSLO)
+			serviceRoutes.GET("/:id/slos/:slo_id", serviceHandler.GetSLO)
+			serviceRoutes.PATCH("/:id/slos/:slo_id", serviceHandler.UpdateSLO)
+			serviceRoutes.DELETE("/:id/slos/:slo_id", serviceHandler.DeleteSLO)
+
+			// Calendar (maintenance windows, on-call shifts, incidents)
+			serviceRoutes.GET("/:id/calendar", serviceHandler.GetServiceCalendar)
+			serviceRoutes.GET("/:id/maintenance-windows", serviceHandler.ListMaintenanceWindows)
+			serviceRoutes.POST("/:id/maintenance-windows", serviceHandler.CreateMaintenanceWindow)
+			serviceRoutes.DELETE("/:id/maintenance-windows/:window_id", serviceHandler.DeleteMaintenanceWindow)
+			// Alert mutes (silence specific alert names/labels until they expire)
+			serviceRoutes.GET("/:id/mutes", serviceHandler.ListAlertMutes)
+			serviceRoutes.POST("/:id/mutes", serviceHandler.CreateAlertMute)
+			serviceRoutes.DELETE("/:id/mutes/:mute_id", serviceHandler.DeleteAlertMute)
+			serviceRoutes.GET("/:id/urgency-rules", serviceHandler.ListUrgencyRules)
+			serviceRoutes.PUT("/:id/urgency-rules", serviceHandler.SetUrgencyRules)
+			serviceRoutes.GET("/:id/severity-mappings", serviceHandler.ListSeverityMappings)
+			serviceRoutes.PUT("/:id/severity-mappings", serviceHandler.SetSeverityMappings)
+			serviceRoutes.GET("/:id/alert-storm", serviceHandler.GetAlertStormRule)
+			serviceRoutes.PUT("/:id/alert-storm", serviceHandler.SetAlertStormRule)
+			serviceRoutes.DELETE("/:id/alert-storm", serviceHandler.DeleteAlertStormRule)
 		}
 
 		// INTEGRATION MANAGEMENT
@@ -621,6 +1095,13 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			// Integration services
 			integrationRoutes.GET("/:id/services", integrationHandler.GetIntegrationServices)
 
+			// Who an external_oncall integration reports on call, for checking its email mapping
+			integrationRoutes.GET("/:id/on-call", integrationHandler.LookupExternalOnCall)
+
+			// Payload scrubbing (PII and secrets redacted before storage) and its redaction counts
+			integrationRoutes.GET("/:id/scrub-policy", integrationHandler.GetScrubPolicy)
+			integrationRoutes.PUT("/:id/scrub-policy", integrationHandler.SetScrubPolicy)
+
 			// Integration templates
 			integrationRoutes.GET("/templates", integrationHandler.GetIntegrationTemplates)
 		}
@@ -639,15 +1120,41 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 			escalationRoutes.GET("/alerts/:alert_id/history", groupHandler.GetAlertEscalations)
 		}
 
+		// GROUP INVITATION ACCEPTANCE (token from emailed join link)
+		protected.POST("/group-invitations/accept", groupInvitationHandler.AcceptInvitation)
+
 		// USER GROUP UTILITIES
 		userGroupRoutes := protected.Group("/user-groups")
 		{
 			userGroupRoutes.GET("/:user_id", groupHandler.GetUserGroups)
 		}
 
+		// CHAT CHANNEL TYPES (config schemas for the channel settings form)
+		protected.GET("/chat-channel-types", chatChannelHandler.ListChatChannelTypes)
+
 		// DASHBOARD
 		protected.GET("/dashboard", dashboardHandler.GetDashboard)
 
+		// SAVED ANALYTICS DASHBOARDS (private to the owner or shared with a group)
+		analyticsRoutes := protected.Group("/analytics")
+		{
+			analyticsRoutes.GET("/metrics", analyticsDashboardHandler.ListMetrics)
+			analyticsRoutes.GET("/dashboards", analyticsDashboardHandler.ListDashboards)
+			analyticsRoutes.POST("/dashboards", analyticsDashboardHandler.CreateDashboard)
+			analyticsRoutes.GET("/dashboards/:id", analyticsDashboardHandler.GetDashboard)
+			analyticsRoutes.PATCH("/dashboards/:id", analyticsDashboardHandler.UpdateDashboard)
+			analyticsRoutes.DELETE("/dashboards/:id", analyticsDashboardHandler.DeleteDashboard)
+
+			// Alerts ranked by how often their pages needed nobody
+			analyticsRoutes.GET("/noisy-alerts", alertQualityHandler.GetNoisyAlerts)
+
+			// Resolved incidents clustered into recurring problems per service (rebuilt daily)
+			analyticsRoutes.GET("/recurring-problems", alertQualityHandler.GetRecurringProblems)
+
+			// Incidents caused by a change or incident, following "caused by" chains
+			analyticsRoutes.GET("/caused-by", incidentHandler.GetCausedByReport)
+		}
+
 		// AI AGENT
 		protected.GET("/verify-token", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "Token is valid"})
@@ -716,6 +1223,33 @@ func NewGinRouter(pg *sql.DB) *gin.Engine {
 	// PUBLIC SHARED CONVERSATION VIEW (no auth - anyone with link can view)
 	r.GET("/shared/:token", conversationShareHandler.GetSharedConversation)
 
+	// PUBLIC SHARED INCIDENT SUMMARY (no auth - expiring, revocable share links, every view logged)
+	r.GET("/shared/incidents/:token", incidentHandler.GetSharedIncident)
+
+	// PUBLIC ACKNOWLEDGE LINKS (no auth - one-time links posted to chat channels)
+	r.GET("/ack/:token", chatChannelHandler.GetAckLink)
+	r.POST("/ack/:token", chatChannelHandler.AcknowledgeWithAckLink)
+
+	// PUBLIC SNAPSHOT IMAGES (no auth - unguessable URLs so chat apps can show uploaded charts)
+	r.GET("/snapshots/:token", incidentHandler.GetIncidentSnapshotImage)
+
+	// PUBLIC INCIDENT DEEP LINKS (no auth - open the mobile app when installed, the web UI otherwise)
+	r.GET("/r/:short_id", incidentLinkHandler.Resolve)
+	r.GET("/.well-known/apple-app-site-association", incidentLinkHandler.AppleAppSiteAssociation)
+	r.GET("/.well-known/assetlinks.json", incidentLinkHandler.AssetLinks)
+
+	// PUBLIC WHATSAPP BUSINESS WEBHOOK (verify token handshake, signed inbound messages)
+	r.GET("/whatsapp/webhook", whatsAppHandler.VerifyWebhook)
+	r.POST("/whatsapp/webhook", whatsAppHandler.ReceiveWebhook)
+
+	// PUBLIC TWILIO SMS WEBHOOK (signed inbound messages: VERIFY, ACK, RES)
+	r.POST("/sms/twilio/webhook", smsHandler.ReceiveTwilioWebhook)
+
+	// PUBLIC TWILIO VOICE CALLBACKS (signed: call TwiML, IVR keypress, call status)
+	r.POST("/voice/twilio/calls/:id", voiceHandler.CallTwiML)
+	r.POST("/voice/twilio/calls/:id/gather", voiceHandler.GatherCall)
+	r.POST("/voice/twilio/calls/:id/status", voiceHandler.CallStatus)
+
 	// PoC: AI PROXY WebSocket (Control Plane pattern)
 	// Route: /ws/proxy?token=xxx&org_id=xxx&project_id=xxx
 	// This proxies WebSocket connections to internal AI Agent
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// APIVersionHeader names the API version a response was served as. Clients
+// calling unversioned paths may send it to pick a version instead.
+const APIVersionHeader = "API-Version"
+
+// CurrentAPIVersion is the version unversioned paths are served as
+const CurrentAPIVersion = 1
+
+// supportedAPIVersions are the versions served under /v<N>/
+var supportedAPIVersions = []int{1}
+
+// unversionedDeprecatedSince is when the unversioned paths were deprecated in
+// favour of /v1
+var unversionedDeprecatedSince = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+// unversionedPaths stay unversioned and are never marked deprecated: URLs
+// handed to alert sources, messaging providers, chat messages, identity
+// providers and load balancers, which can't follow a version change
+var unversionedPaths = []string{
+	"/health", "/env", "/internal/", "/webhook/", "/webhooks/", "/scim/", "/shared/", "/ack/", "/r/",
+	"/.well-known/", "/whatsapp/", "/sms/", "/voice/", "/ws/",
+}
+
+var versionPrefixPattern = regexp.MustCompile(`^/v([0-9]+)(/|$)`)
+
+// WithAPIVersions serves the API under /v<N>/ as well as at the unversioned
+// paths. /v1/incidents is routed to /incidents and answered with
+// "API-Version: 1". The unversioned paths keep working as aliases of the
+// current version, but their responses carry Deprecation, Link to the /v1
+// path and, once unversioned_api_sunset is set, Sunset headers, unless the
+// client asked for a version with the API-Version header. Unsupported versions
+// are rejected before routing, so a future /v2 can't be reached by accident.
+func WithAPIVersions(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m := versionPrefixPattern.FindStringSubmatch(r.URL.Path); m != nil {
+			version, _ := strconv.Atoi(m[1])
+			if !apiVersionSupported(version) {
+				writeUnsupportedAPIVersion(w, http.StatusNotFound, m[1])
+				return
+			}
+			prefix := "/v" + m[1]
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			if r2.URL.Path == "" {
+				r2.URL.Path = "/"
+			}
+			if r.URL.RawPath != "" {
+				r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+			}
+			w.Header().Set(APIVersionHeader, m[1])
+			h.ServeHTTP(w, r2)
+			return
+		}
+
+		if requested := r.Header.Get(APIVersionHeader); requested != "" {
+			version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(requested), "v"))
+			if err != nil || !apiVersionSupported(version) {
+				writeUnsupportedAPIVersion(w, http.StatusBadRequest, requested)
+				return
+			}
+			w.Header().Set(APIVersionHeader, strconv.Itoa(version))
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if !isUnversionedPath(r.URL.Path) {
+			header := w.Header()
+			header.Set(APIVersionHeader, strconv.Itoa(CurrentAPIVersion))
+			header.Set("Deprecation", "@"+strconv.FormatInt(unversionedDeprecatedSince.Unix(), 10))
+			header.Add("Link", "<"+config.NormalizedBasePath()+"/v"+strconv.Itoa(CurrentAPIVersion)+r.URL.Path+`>; rel="successor-version"`)
+			if sunset, ok := unversionedAPISunset(); ok {
+				header.Set("Sunset", sunset.Format(http.TimeFormat))
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func apiVersionSupported(version int) bool {
+	for _, v := range supportedAPIVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnversionedPath(path string) bool {
+	for _, p := range unversionedPaths {
+		p = strings.TrimSuffix(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// unversionedAPISunset is when the unversioned aliases are due to be removed
+func unversionedAPISunset() (time.Time, bool) {
+	if config.Current().UnversionedAPISunset == "" {
+		return time.Time{}, false
+	}
+	sunset, err := time.Parse("2006-01-02", config.Current().UnversionedAPISunset)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return sunset, true
+}
+
+// writeUnsupportedAPIVersion answers in the shared error body, since the
+// request never reaches the router's error middleware
+func writeUnsupportedAPIVersion(w http.ResponseWriter, status int, version string) {
+	message := "API version " + version + " is not supported"
+	body, _ := json.Marshal(map[string]interface{}{
+		"code":               "unsupported_api_version",
+		"message":            message,
+		"error":              message,
+		"supported_versions": supportedAPIVersions,
+	})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestWithAPIVersions(t *testing.T) {
+	prev := config.Current().UnversionedAPISunset
+	config.Update(func(c *config.Config) { c.UnversionedAPISunset = "2027-06-30" })
+	defer config.Update(func(c *config.Config) { c.UnversionedAPISunset = prev })
+
+	var got string
+	h := WithAPIVersions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}))
+	serve := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		got = ""
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/v1/incidents/INC-1", nil)
+	if got != "/incidents/INC-1" || w.Header().Get(APIVersionHeader) != "1" || w.Header().Get("Deprecation") != "" {
+		t.Errorf("/v1: routed to %q, headers %v", got, w.Header())
+	}
+
+	w = serve("/incidents/INC-1", nil)
+	if got != "/incidents/INC-1" || w.Header().Get("Deprecation") == "" ||
+		w.Header().Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" ||
+		w.Header().Get("Link") != `</v1/incidents/INC-1>; rel="successor-version"` {
+		t.Errorf("unversioned: routed to %q, headers %v", got, w.Header())
+	}
+
+	// Asking for a version by header opts out of the deprecation notice
+	w = serve("/incidents", map[string]string{APIVersionHeader: "1"})
+	if got != "/incidents" || w.Header().Get("Deprecation") != "" || w.Header().Get(APIVersionHeader) != "1" {
+		t.Errorf("negotiated: routed to %q, headers %v", got, w.Header())
+	}
+
+	// Webhook URLs live in third-party tools and are never deprecated
+	w = serve("/webhooks/alertmanager", nil)
+	if got != "/webhooks/alertmanager" || w.Header().Get("Deprecation") != "" {
+		t.Errorf("webhook: routed to %q, headers %v", got, w.Header())
+	}
+	if w = serve("/environments", nil); w.Header().Get("Deprecation") == "" {
+		t.Error("/environments treated as /env")
+	}
+
+	if w = serve("/v2/incidents", nil); w.Code != http.StatusNotFound || got != "" {
+		t.Errorf("/v2: %d, routed to %q", w.Code, got)
+	}
+	if w = serve("/incidents", map[string]string{APIVersionHeader: "7"}); w.Code != http.StatusBadRequest || got != "" {
+		t.Errorf("API-Version 7: %d, routed to %q", w.Code, got)
+	}
+}
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithBasePath serves h under a path prefix for deployments behind a gateway
+// that forwards /<prefix>/... without stripping it. Requests outside the
+// prefix (load balancer health checks hitting /health directly) are passed
+// through unchanged.
+func WithBasePath(h http.Handler, basePath string) http.Handler {
+	prefix := "/" + strings.Trim(basePath, "/")
+	if prefix == "/" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != prefix && !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		if r2.URL.Path == "" {
+			r2.URL.Path = "/"
+		}
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithBasePath(t *testing.T) {
+	var got string
+	h := WithBasePath(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}), "slar/api/")
+
+	for path, want := range map[string]string{
+		"/slar/api/incidents": "/incidents",
+		"/slar/api":           "/",
+		"/health":             "/health",
+		"/slar/apiary":        "/slar/apiary",
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got != want {
+			t.Errorf("%s: routed to %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configureClientIP makes c.ClientIP() trust X-Forwarded-For, or the
+// platform's clientIPHeader, only on requests from trustedProxies, so
+// per-integration source IP allowlists can't be bypassed with a spoofed
+// header. Without trusted proxies the peer address is always used.
+func configureClientIP(r *gin.Engine, trustedProxies []string, clientIPHeader string) {
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Printf("Invalid trusted_proxies, trusting no proxy: %v", err)
+		trustedProxies = nil
+		r.SetTrustedProxies(nil)
+	}
+
+	// Platforms like Cloudflare or a corporate gateway put the client IP in a
+	// single header instead; gin reads it from trusted proxies only
+	if clientIPHeader != "" {
+		r.RemoteIPHeaders = []string{clientIPHeader}
+		if trustedProxies == nil {
+			log.Printf("client_ip_header %s is ignored until trusted_proxies lists the platform's addresses", clientIPHeader)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConfigureClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clientIP := func(trustedProxies []string, clientIPHeader, remoteAddr string, headers map[string]string) string {
+		r := gin.New()
+		configureClientIP(r, trustedProxies, clientIPHeader)
+		var got string
+		r.GET("/ip", func(c *gin.Context) { got = c.ClientIP() })
+
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	spoofed := map[string]string{"X-Forwarded-For": "198.51.100.7", "CF-Connecting-IP": "198.51.100.8"}
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		clientIPHeader string
+		remoteAddr     string
+		want           string
+	}{
+		{"no trusted proxies", nil, "", "203.0.113.5:4000", "203.0.113.5"},
+		{"platform header without trusted proxies", nil, "CF-Connecting-IP", "203.0.113.5:4000", "203.0.113.5"},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "CF-Connecting-IP", "203.0.113.5:4000", "203.0.113.5"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "", "10.1.2.3:4000", "198.51.100.7"},
+		{"trusted platform", []string{"10.0.0.0/8"}, "CF-Connecting-IP", "10.1.2.3:4000", "198.51.100.8"},
+		{"invalid proxies", []string{"not-a-cidr"}, "", "203.0.113.5:4000", "203.0.113.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientIP(tt.trustedProxies, tt.clientIPHeader, tt.remoteAddr, spoofed); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
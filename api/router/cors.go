@@ -0,0 +1,136 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/handlers"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+const (
+	corsAllowHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Org-ID, X-Project-ID, Idempotency-Key, If-None-Match, If-Modified-Since, API-Version"
+	corsAllowMethods = "POST, OPTIONS, GET, PUT, DELETE, PATCH"
+)
+
+// originAllowed reports whether origin matches one of the allowed patterns.
+// wildcard is true when it only matched "*".
+func originAllowed(origin string, allowed []string) (ok, wildcard bool) {
+	origin = strings.TrimRight(origin, "/")
+	for _, pattern := range allowed {
+		pattern = strings.TrimRight(strings.TrimSpace(pattern), "/")
+		switch {
+		case pattern == "*":
+			wildcard = true
+		case strings.EqualFold(pattern, origin):
+			return true, false
+		case strings.Contains(pattern, "://*."):
+			// https://*.example.com matches any subdomain, not the apex
+			scheme, domain, _ := strings.Cut(pattern, "://*.")
+			if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Scheme, scheme) &&
+				strings.HasSuffix(strings.ToLower(u.Host), "."+strings.ToLower(domain)) {
+				return true, false
+			}
+		}
+	}
+	return wildcard, wildcard
+}
+
+// corsMiddleware answers browsers from the configured allowed origins.
+// Credentials are only allowed for origins listed explicitly.
+func corsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.Current().CORS
+		origin := c.GetHeader("Origin")
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if origin != "" {
+			ok, wildcard := originAllowed(origin, cfg.AllowedOrigins)
+			if !ok {
+				if c.Request.Method == http.MethodOptions {
+					c.AbortWithStatus(http.StatusForbidden)
+					return
+				}
+				// Not a CORS-enabled origin: the browser blocks the response
+				c.Next()
+				return
+			}
+
+			h := c.Writer.Header()
+			if wildcard {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Expose-Headers", handlers.RequestIDHeader+", ETag, Last-Modified, "+
+				APIVersionHeader+", Deprecation, Sunset, Link, "+handlers.TimezoneHeader)
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// csrfMiddleware rejects state-changing requests that carry cookies but no
+// explicit credentials unless they come from an allowed origin. Requests
+// authenticated with a bearer token or API key can't be forged by another
+// site, so they are not checked.
+func csrfMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if c.GetHeader("Authorization") != "" || c.Query("api_key") != "" || len(c.Request.Cookies()) == 0 {
+			c.Next()
+			return
+		}
+
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			if referer, err := url.Parse(c.GetHeader("Referer")); err == nil && referer.Host != "" {
+				origin = referer.Scheme + "://" + referer.Host
+			}
+		}
+		if !sameOriginOrTrusted(c.Request, origin) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "csrf_check_failed",
+				"message": "Cross-site request rejected: origin is not allowed",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// sameOriginOrTrusted reports whether origin is the API's own host or an
+// origin listed in cors.allowed_origins ("*" doesn't count)
+func sameOriginOrTrusted(r *http.Request, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	ok, wildcard := originAllowed(origin, config.Current().CORS.AllowedOrigins)
+	return ok && !wildcard
+}
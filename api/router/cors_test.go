@@ -0,0 +1,110 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func newCORSTestRouter(cors config.CORSConfig) (*gin.Engine, func()) {
+	saved := config.Current().CORS
+	config.Update(func(c *config.Config) { c.CORS = cors })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(corsMiddleware(), csrfMiddleware())
+	r.POST("/incidents", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r, func() { config.Update(func(c *config.Config) { c.CORS = saved }) }
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	r, restore := newCORSTestRouter(config.CORSConfig{
+		AllowedOrigins:   []string{"https://slar.example.com", "https://*.corp.example"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	defer restore()
+
+	for origin, allowed := range map[string]bool{
+		"https://slar.example.com": true,
+		"https://noc.corp.example": true,
+		"https://corp.example":     false,
+		"http://noc.corp.example":  false,
+		"https://evil.example.com": false,
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/incidents", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if allowed {
+			if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != origin ||
+				w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("%s: expected allowed preflight, got %d %v", origin, w.Code, w.Header())
+			}
+		} else if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("%s: expected rejected preflight, got %d %v", origin, w.Code, w.Header())
+		}
+	}
+}
+
+func TestCORSWildcardNeverAllowsCredentials(t *testing.T) {
+	r, restore := newCORSTestRouter(config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	defer restore()
+
+	req := httptest.NewRequest(http.MethodOptions, "/incidents", nil)
+	req.Header.Set("Origin", "https://anything.example")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("unexpected wildcard headers: %v", w.Header())
+	}
+}
+
+func TestCSRFOnCookieRequests(t *testing.T) {
+	r, restore := newCORSTestRouter(config.CORSConfig{AllowedOrigins: []string{"*", "https://slar.example.com"}})
+	defer restore()
+
+	post := func(setup func(*http.Request)) int {
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/incidents", nil)
+		setup(req)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	withCookie := func(req *http.Request) { req.AddCookie(&http.Cookie{Name: "session", Value: "s"}) }
+
+	cases := map[string]struct {
+		setup func(*http.Request)
+		want  int
+	}{
+		"no cookies": {func(req *http.Request) {}, http.StatusCreated},
+		"bearer token": {func(req *http.Request) {
+			withCookie(req)
+			req.Header.Set("Authorization", "Bearer t")
+		}, http.StatusCreated},
+		"cookie without origin": {withCookie, http.StatusForbidden},
+		"cookie from wildcard-only origin": {func(req *http.Request) {
+			withCookie(req)
+			req.Header.Set("Origin", "https://evil.example")
+		}, http.StatusForbidden},
+		"cookie from listed origin": {func(req *http.Request) {
+			withCookie(req)
+			req.Header.Set("Origin", "https://slar.example.com")
+		}, http.StatusCreated},
+		"cookie with same-origin referer": {func(req *http.Request) {
+			withCookie(req)
+			req.Header.Set("Referer", "http://api.example.com/docs")
+		}, http.StatusCreated},
+	}
+	for name, tc := range cases {
+		if got := post(tc.setup); got != tc.want {
+			t.Errorf("%s: got %d, want %d", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+#!/usr/bin/env bash
+# Webhook ingestion benchmarks and profiling harness.
+#
+#   scripts/bench.sh run [out.txt]          run the benchmarks (BENCH_COUNT runs each)
+#   scripts/bench.sh profile [pattern]      CPU + memory profile, prints the top frames
+#   scripts/bench.sh compare old.txt new.txt
+#                                           fail if any benchmark regressed past the thresholds
+#
+# Environment:
+#   BENCH_COUNT                  runs per benchmark (default 6)
+#   BENCH_TIME                   -benchtime value (default 1s)
+#   BENCH_MAX_TIME_REGRESSION    allowed ns/op increase in percent (default 20)
+#   BENCH_MAX_ALLOC_REGRESSION   allowed allocs/op increase in percent (default 10)
+#   SLAR_BENCH_DB_LATENCY        simulated per-query DB latency, e.g. 500us (default 0)
+#
+# See docs/benchmarks.md for the recorded baselines.
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+PKG=./handlers
+PATTERN='ProcessPrometheusWebhook|ResolveServiceAndAssignee|RouteAlertToCreateIncident|ReceiveWebhookPrometheus'
+COUNT="${BENCH_COUNT:-6}"
+BENCHTIME="${BENCH_TIME:-1s}"
+
+run() {
+  local out="${1:-/dev/stdout}"
+  go test "$PKG" -run '^$' -bench "$PATTERN" -benchmem -count "$COUNT" -benchtime "$BENCHTIME" | tee "$out"
+}
+
+profile() {
+  local pattern="${1:-ReceiveWebhookPrometheus}"
+  local dir="bench-profiles"
+  mkdir -p "$dir"
+  go test "$PKG" -run '^$' -bench "$pattern" -benchmem -benchtime "$BENCHTIME" \
+    -cpuprofile "$dir/cpu.out" -memprofile "$dir/mem.out" -o "$dir/handlers.test"
+  echo
+  echo "== CPU (top 20) =="
+  go tool pprof -top -nodecount=20 "$dir/handlers.test" "$dir/cpu.out"
+  echo
+  echo "== Allocations (top 20) =="
+  go tool pprof -top -nodecount=20 -sample_index=alloc_space "$dir/handlers.test" "$dir/mem.out"
+  echo
+  echo "Interactive: go tool pprof -http=:8081 $dir/handlers.test $dir/cpu.out"
+}
+
+# compare takes the median ns/op and allocs/op of each benchmark in both files
+# and fails when the new median exceeds the old one by more than the threshold.
+compare() {
+  local old="$1" new="$2"
+  awk -v max_time="${BENCH_MAX_TIME_REGRESSION:-20}" -v max_alloc="${BENCH_MAX_ALLOC_REGRESSION:-10}" '
+    function median(list,    n, arr, i, j, t) {
+      n = split(list, arr, " ")
+      for (i = 2; i <= n; i++) {
+        for (j = i; j > 1 && arr[j-1] + 0 > arr[j] + 0; j--) { t = arr[j]; arr[j] = arr[j-1]; arr[j-1] = t }
+      }
+      return (n % 2) ? arr[(n + 1) / 2] : (arr[n / 2] + arr[n / 2 + 1]) / 2
+    }
+    /^Benchmark/ {
+      name = $1; sub(/-[0-9]+$/, "", name)
+      for (i = 3; i < NF; i++) {
+        if ($(i+1) == "ns/op") ns[FILENAME == ARGV[1], name] = ns[FILENAME == ARGV[1], name] " " $i
+        if ($(i+1) == "allocs/op") al[FILENAME == ARGV[1], name] = al[FILENAME == ARGV[1], name] " " $i
+      }
+      names[name] = 1
+    }
+    END {
+      failed = 0
+      printf "%-48s %14s %14s %8s %10s %10s %8s\n", "benchmark", "old ns/op", "new ns/op", "delta", "old allocs", "new allocs", "delta"
+      for (name in names) {
+        if (!((1, name) in ns) || !((0, name) in ns)) continue
+        on = median(ns[1, name]); nn = median(ns[0, name])
+        oa = median(al[1, name]); na = median(al[0, name])
+        dt = on > 0 ? (nn - on) * 100 / on : 0
+        da = oa > 0 ? (na - oa) * 100 / oa : 0
+        flag = ""
+        if (dt > max_time || da > max_alloc) { flag = "  REGRESSION"; failed = 1 }
+        printf "%-48s %14.0f %14.0f %+7.1f%% %10.0f %10.0f %+7.1f%%%s\n", name, on, nn, dt, oa, na, da, flag
+      }
+      if (failed) {
+        printf "\nbenchmarks regressed by more than %s%% ns/op or %s%% allocs/op\n", max_time, max_alloc
+        exit 1
+      }
+    }
+  ' "$old" "$new"
+}
+
+case "${1:-run}" in
+  run) shift || true; run "$@" ;;
+  profile) shift; profile "$@" ;;
+  compare) shift; compare "$@" ;;
+  *) echo "usage: $0 {run [out]|profile [pattern]|compare old new}" >&2; exit 2 ;;
+esac
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrActivityDigestNotFound   = errors.New("digest subscription not found")
+	ErrDigestGroupNotManaged    = errors.New("you can only get digests for groups you own or administer")
+	ErrInvalidDigestFrequency   = errors.New("frequency must be daily or weekly")
+	ErrActivityDigestNoManaging = errors.New("you don't manage any groups")
+)
+
+// ActivityDigestService emails group managers a daily or weekly summary of
+// their groups' incident activity
+type ActivityDigestService struct {
+	PG           *sql.DB
+	EmailService *EmailService
+}
+
+func NewActivityDigestService(pg *sql.DB, emailService *EmailService) *ActivityDigestService {
+	return &ActivityDigestService{PG: pg, EmailService: emailService}
+}
+
+// digestPeriod returns the last complete period of the frequency before now:
+// yesterday, or last week from Monday (UTC)
+func digestPeriod(frequency string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if frequency == db.ActivityDigestWeekly {
+		to = to.AddDate(0, 0, -((int(to.Weekday()) + 6) % 7))
+		return to.AddDate(0, 0, -7), to
+	}
+	return to.AddDate(0, 0, -1), to
+}
+
+const activityDigestSelect = `
+	SELECT id, user_id, frequency, group_ids::text[], last_sent_at, created_at, updated_at
+	FROM activity_digest_subscriptions`
+
+func scanActivityDigestSubscription(row interface{ Scan(...interface{}) error }) (db.ActivityDigestSubscription, error) {
+	var sub db.ActivityDigestSubscription
+	var groupIDs pq.StringArray
+	var lastSentAt sql.NullTime
+	if err := row.Scan(&sub.ID, &sub.UserID, &sub.Frequency, &groupIDs, &lastSentAt, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return sub, err
+	}
+	sub.GroupIDs = []string(groupIDs)
+	if sub.GroupIDs == nil {
+		sub.GroupIDs = []string{}
+	}
+	if lastSentAt.Valid {
+		sub.LastSentAt = &lastSentAt.Time
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns the user's digest subscriptions
+func (s *ActivityDigestService) ListSubscriptions(userID string) ([]db.ActivityDigestSubscription, error) {
+	rows, err := s.PG.Query(activityDigestSelect+` WHERE user_id = $1 ORDER BY frequency`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []db.ActivityDigestSubscription{}
+	for rows.Next() {
+		sub, err := scanActivityDigestSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan digest subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Subscribe starts a digest of the given frequency, or changes the groups of
+// the existing one. Every group must be one the user owns or administers.
+func (s *ActivityDigestService) Subscribe(userID string, req db.SubscribeActivityDigestRequest) (*db.ActivityDigestSubscription, error) {
+	if req.Frequency != db.ActivityDigestDaily && req.Frequency != db.ActivityDigestWeekly {
+		return nil, ErrInvalidDigestFrequency
+	}
+	groupIDs := uniqueStrings(req.GroupIDs)
+	managed, err := s.managedGroups(userID, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(managed) == 0 {
+		return nil, ErrActivityDigestNoManaging
+	}
+	if len(managed) != len(groupIDs) && len(groupIDs) > 0 {
+		return nil, ErrDigestGroupNotManaged
+	}
+
+	sub, err := scanActivityDigestSubscription(s.PG.QueryRow(`
+		INSERT INTO activity_digest_subscriptions (user_id, frequency, group_ids)
+		VALUES ($1, $2, $3::uuid[])
+		ON CONFLICT (user_id, frequency) DO UPDATE SET group_ids = EXCLUDED.group_ids, updated_at = NOW()
+		RETURNING id, user_id, frequency, group_ids::text[], last_sent_at, created_at, updated_at
+	`, userID, req.Frequency, pq.Array(groupIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save digest subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// Unsubscribe stops one of the user's digests
+func (s *ActivityDigestService) Unsubscribe(userID, id string) error {
+	res, err := s.PG.Exec(`DELETE FROM activity_digest_subscriptions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete digest subscription: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrActivityDigestNotFound
+	}
+	return nil
+}
+
+type digestGroup struct {
+	id   string
+	name string
+}
+
+// managedGroups returns the active groups the user owns or administers,
+// limited to groupIDs when any are given
+func (s *ActivityDigestService) managedGroups(userID string, groupIDs []string) ([]digestGroup, error) {
+	if groupIDs == nil {
+		groupIDs = []string{}
+	}
+	rows, err := s.PG.Query(`
+		SELECT g.id::text, g.name
+		FROM groups g
+		JOIN memberships m ON m.resource_type = 'group' AND m.resource_id = g.id
+		WHERE m.user_id = $1 AND m.role IN ('owner', 'admin') AND g.is_active = true
+		  AND (cardinality($2::uuid[]) = 0 OR g.id = ANY($2::uuid[]))
+		ORDER BY g.name ASC
+	`, userID, pq.Array(groupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get managed groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []digestGroup
+	for rows.Next() {
+		var g digestGroup
+		if err := rows.Scan(&g.id, &g.name); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// BuildDigest gathers the activity of the subscription's groups over the
+// last complete period. Groups the user no longer manages are left out.
+// Test and drill incidents don't count.
+func (s *ActivityDigestService) BuildDigest(sub db.ActivityDigestSubscription, now time.Time) (*db.ActivityDigest, error) {
+	from, to := digestPeriod(sub.Frequency, now)
+	length := to.Sub(from)
+	digest := &db.ActivityDigest{
+		UserID:           sub.UserID,
+		Frequency:        sub.Frequency,
+		From:             from,
+		To:               to,
+		GeneratedAt:      now,
+		Groups:           []db.ActivityDigestGroup{},
+		NotableIncidents: []db.DigestIncident{},
+		CoverageGaps:     []db.CoverageGap{},
+	}
+
+	groups, err := s.managedGroups(sub.UserID, sub.GroupIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return digest, nil
+	}
+	groupIDs := make([]string, len(groups))
+	for i, g := range groups {
+		groupIDs[i] = g.id
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT g.id::text, g.name,
+		       COUNT(i.id) FILTER (WHERE i.created_at >= $2 AND i.created_at < $3),
+		       COUNT(i.id) FILTER (WHERE i.resolved_at >= $2 AND i.resolved_at < $3),
+		       COUNT(i.id) FILTER (WHERE i.status IN ('triggered', 'acknowledged')),
+		       AVG(EXTRACT(EPOCH FROM (i.acknowledged_at - i.created_at))) FILTER (WHERE i.acknowledged_at >= $2 AND i.acknowledged_at < $3),
+		       AVG(EXTRACT(EPOCH FROM (i.acknowledged_at - i.created_at))) FILTER (WHERE i.acknowledged_at >= $1 AND i.acknowledged_at < $2),
+		       AVG(EXTRACT(EPOCH FROM (i.resolved_at - i.created_at))) FILTER (WHERE i.resolved_at >= $2 AND i.resolved_at < $3),
+		       AVG(EXTRACT(EPOCH FROM (i.resolved_at - i.created_at))) FILTER (WHERE i.resolved_at >= $1 AND i.resolved_at < $2)
+		FROM groups g
+		LEFT JOIN incidents i ON i.group_id = g.id
+		      AND NOT COALESCE(i.is_test, false) AND i.drill_id IS NULL
+		      AND (i.status IN ('triggered', 'acknowledged') OR i.created_at >= $1
+		           OR i.acknowledged_at >= $1 OR i.resolved_at >= $1)
+		WHERE g.id = ANY($4::uuid[])
+		GROUP BY g.id, g.name
+		ORDER BY g.name ASC
+	`, from.Add(-length), from, to, pq.Array(groupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group activity: %w", err)
+	}
+	for rows.Next() {
+		var g db.ActivityDigestGroup
+		var mtta, prevMTTA, mttr, prevMTTR sql.NullFloat64
+		if err := rows.Scan(&g.GroupID, &g.GroupName, &g.Opened, &g.Resolved, &g.Open, &mtta, &prevMTTA, &mttr, &prevMTTR); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan group activity: %w", err)
+		}
+		g.MTTA = db.DigestTrend{Seconds: nullFloatPtr(mtta), PreviousSeconds: nullFloatPtr(prevMTTA)}
+		g.MTTR = db.DigestTrend{Seconds: nullFloatPtr(mttr), PreviousSeconds: nullFloatPtr(prevMTTR)}
+		digest.Groups = append(digest.Groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get group activity: %w", err)
+	}
+
+	rows, err = s.PG.Query(`
+		SELECT i.id::text, i.title, i.status, g.name, i.created_at, i.resolved_at
+		FROM incidents i
+		JOIN groups g ON g.id = i.group_id
+		WHERE i.group_id = ANY($1::uuid[]) AND i.priority = 'P1'
+		  AND i.created_at >= $2 AND i.created_at < $3
+		  AND NOT COALESCE(i.is_test, false) AND i.drill_id IS NULL
+		ORDER BY i.created_at DESC
+		LIMIT $4
+	`, pq.Array(groupIDs), from, to, db.ActivityDigestMaxNotable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notable incidents: %w", err)
+	}
+	for rows.Next() {
+		var inc db.DigestIncident
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&inc.ID, &inc.Title, &inc.Status, &inc.GroupName, &inc.CreatedAt, &resolvedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan notable incident: %w", err)
+		}
+		if resolvedAt.Valid {
+			inc.ResolvedAt = &resolvedAt.Time
+		}
+		digest.NotableIncidents = append(digest.NotableIncidents, inc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get notable incidents: %w", err)
+	}
+
+	// Overrides only change who is on call, so the shifts alone tell the gaps
+	gapsTo := now.Add(length)
+	for _, g := range groups {
+		shifts, _, err := loadOnCallShifts(s.PG, g.id, now, gapsTo)
+		if err != nil {
+			return nil, err
+		}
+		for _, gap := range coverageGaps(shifts, now, gapsTo) {
+			gap.GroupID = g.id
+			gap.GroupName = g.name
+			digest.CoverageGaps = append(digest.CoverageGaps, gap)
+		}
+	}
+
+	return digest, nil
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// coverageGaps returns the parts of [from, to) no shift covers
+func coverageGaps(shifts []onCallShift, from, to time.Time) []db.CoverageGap {
+	sorted := append([]onCallShift(nil), shifts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var gaps []db.CoverageGap
+	covered := from
+	for _, sh := range sorted {
+		if !sh.End.After(covered) {
+			continue
+		}
+		if sh.Start.After(covered) {
+			end := earlierTime(sh.Start, to)
+			gaps = append(gaps, db.CoverageGap{Start: covered, End: end, Seconds: int64(end.Sub(covered).Seconds())})
+		}
+		covered = sh.End
+		if !covered.Before(to) {
+			return gaps
+		}
+	}
+	if covered.Before(to) {
+		gaps = append(gaps, db.CoverageGap{Start: covered, End: to, Seconds: int64(to.Sub(covered).Seconds())})
+	}
+	return gaps
+}
+
+// SendDueDigests emails every subscription whose last complete period hasn't
+// been sent yet. Subscriptions of users who manage no groups any more are
+// skipped quietly. It is run by the incident worker.
+func (s *ActivityDigestService) SendDueDigests() (int, error) {
+	if !s.EmailService.IsConfigured() {
+		return 0, nil
+	}
+	now := time.Now()
+	_, dailyTo := digestPeriod(db.ActivityDigestDaily, now)
+	_, weeklyTo := digestPeriod(db.ActivityDigestWeekly, now)
+
+	rows, err := s.PG.Query(`
+		SELECT s.id, s.user_id, s.frequency, s.group_ids::text[], s.last_sent_at, s.created_at, s.updated_at, u.email
+		FROM activity_digest_subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE COALESCE(u.is_active, true) AND u.email <> ''
+		  AND (s.last_sent_at IS NULL
+		       OR (s.frequency = 'daily' AND s.last_sent_at < $1)
+		       OR (s.frequency = 'weekly' AND s.last_sent_at < $2))
+	`, dailyTo, weeklyTo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due digests: %w", err)
+	}
+	type dueDigest struct {
+		sub   db.ActivityDigestSubscription
+		email string
+	}
+	var due []dueDigest
+	for rows.Next() {
+		var d dueDigest
+		var groupIDs pq.StringArray
+		var lastSentAt sql.NullTime
+		if err := rows.Scan(&d.sub.ID, &d.sub.UserID, &d.sub.Frequency, &groupIDs, &lastSentAt,
+			&d.sub.CreatedAt, &d.sub.UpdatedAt, &d.email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan due digest: %w", err)
+		}
+		d.sub.GroupIDs = []string(groupIDs)
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, d := range due {
+		digest, err := s.BuildDigest(d.sub, now)
+		if err != nil {
+			log.Printf("Activity digest %s: %v", d.sub.ID, err)
+			continue
+		}
+		if len(digest.Groups) > 0 {
+			subject, body := formatActivityDigest(digest)
+			if err := s.EmailService.Send(d.email, subject, body); err != nil {
+				log.Printf("Activity digest %s: failed to send to %s: %v", d.sub.ID, d.email, err)
+				continue
+			}
+			sent++
+		}
+		if _, err := s.PG.Exec(`UPDATE activity_digest_subscriptions SET last_sent_at = $2 WHERE id = $1`, d.sub.ID, digest.To); err != nil {
+			return sent, fmt.Errorf("failed to mark digest %s sent: %w", d.sub.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+// formatActivityDigest renders a digest as a plain-text email
+func formatActivityDigest(d *db.ActivityDigest) (string, string) {
+	period, previous := "day", "the day before"
+	if d.Frequency == db.ActivityDigestWeekly {
+		period, previous = "week", "the week before"
+	}
+	opened, resolved, open := 0, 0, 0
+	for _, g := range d.Groups {
+		opened += g.Opened
+		resolved += g.Resolved
+		open += g.Open
+	}
+
+	subject := fmt.Sprintf("SLAR %s digest: %d incidents, %d P1, %d coverage gaps",
+		d.Frequency, opened, len(d.NotableIncidents), len(d.CoverageGaps))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Incident activity for %s to %s (UTC)\n\n",
+		d.From.Format("Mon Jan 2 2006"), d.To.Add(-time.Second).Format("Mon Jan 2 2006"))
+	fmt.Fprintf(&b, "Opened: %d   Resolved: %d   Still open: %d\n", opened, resolved, open)
+
+	b.WriteString("\nBy group (MTTA / MTTR vs " + previous + ")\n")
+	for _, g := range d.Groups {
+		fmt.Fprintf(&b, "- %s: %d opened, %d resolved, %d open; MTTA %s; MTTR %s\n",
+			g.GroupName, g.Opened, g.Resolved, g.Open, formatDigestTrend(g.MTTA), formatDigestTrend(g.MTTR))
+	}
+
+	if len(d.NotableIncidents) > 0 {
+		b.WriteString("\nP1 incidents\n")
+		for _, inc := range d.NotableIncidents {
+			fmt.Fprintf(&b, "- [%s] %s (%s), opened %s\n",
+				inc.Status, inc.Title, inc.GroupName, inc.CreatedAt.UTC().Format("Jan 2 15:04"))
+		}
+	}
+
+	if len(d.CoverageGaps) > 0 {
+		b.WriteString("\nNobody on call in the next " + period + "\n")
+		for _, gap := range d.CoverageGaps {
+			fmt.Fprintf(&b, "- %s: %s to %s (%s)\n", gap.GroupName,
+				gap.Start.UTC().Format("Mon Jan 2 15:04"), gap.End.UTC().Format("Mon Jan 2 15:04"),
+				formatDigestDuration(float64(gap.Seconds)))
+		}
+	} else {
+		b.WriteString("\nEvery group has someone on call for the next " + period + ".\n")
+	}
+
+	b.WriteString("\nManage your digests in SLAR under your notification settings.\n")
+	return subject, b.String()
+}
+
+// formatDigestTrend shows a mean time and how it moved, e.g. "4m (was 6m, down)"
+func formatDigestTrend(t db.DigestTrend) string {
+	if t.Seconds == nil {
+		if t.PreviousSeconds == nil {
+			return "n/a"
+		}
+		return "n/a (was " + formatDigestDuration(*t.PreviousSeconds) + ")"
+	}
+	current := formatDigestDuration(*t.Seconds)
+	if t.PreviousSeconds == nil {
+		return current
+	}
+	direction := "flat"
+	switch {
+	case *t.Seconds > *t.PreviousSeconds*1.05:
+		direction = "up"
+	case *t.Seconds < *t.PreviousSeconds*0.95:
+		direction = "down"
+	}
+	return fmt.Sprintf("%s (was %s, %s)", current, formatDigestDuration(*t.PreviousSeconds), direction)
+}
+
+func formatDigestDuration(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestDigestPeriod(t *testing.T) {
+	// Thursday afternoon
+	now := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC)
+
+	from, to := digestPeriod(db.ActivityDigestDaily, now)
+	if !from.Equal(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily = %v - %v", from, to)
+	}
+	from, to = digestPeriod(db.ActivityDigestWeekly, now)
+	if !from.Equal(time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("weekly = %v - %v", from, to)
+	}
+	// On a Monday the week that just ended is reported
+	from, _ = digestPeriod(db.ActivityDigestWeekly, time.Date(2026, 10, 12, 1, 0, 0, 0, time.UTC))
+	if !from.Equal(time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("weekly on Monday from = %v", from)
+	}
+}
+
+func TestCoverageGaps(t *testing.T) {
+	base := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+
+	shifts := []onCallShift{
+		{Start: at(10), End: at(14)},
+		{Start: at(-2), End: at(4)},
+		{Start: at(12), End: at(16)}, // overlaps the one before
+	}
+	gaps := coverageGaps(shifts, at(0), at(24))
+	if len(gaps) != 2 {
+		t.Fatalf("gaps = %+v", gaps)
+	}
+	if !gaps[0].Start.Equal(at(4)) || !gaps[0].End.Equal(at(10)) || gaps[0].Seconds != 6*3600 {
+		t.Errorf("first gap = %+v", gaps[0])
+	}
+	if !gaps[1].Start.Equal(at(16)) || !gaps[1].End.Equal(at(24)) {
+		t.Errorf("second gap = %+v", gaps[1])
+	}
+
+	if gaps := coverageGaps([]onCallShift{{Start: at(-1), End: at(30)}}, at(0), at(24)); len(gaps) != 0 {
+		t.Errorf("fully covered gaps = %+v", gaps)
+	}
+	if gaps := coverageGaps(nil, at(0), at(24)); len(gaps) != 1 || gaps[0].Seconds != 24*3600 {
+		t.Errorf("no shifts gaps = %+v", gaps)
+	}
+}
+
+func TestFormatActivityDigest(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	from := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	digest := &db.ActivityDigest{
+		Frequency: db.ActivityDigestWeekly,
+		From:      from,
+		To:        from.AddDate(0, 0, 7),
+		Groups: []db.ActivityDigestGroup{
+			{GroupName: "Payments", Opened: 4, Resolved: 3, Open: 1,
+				MTTA: db.DigestTrend{Seconds: f(240), PreviousSeconds: f(360)},
+				MTTR: db.DigestTrend{Seconds: f(5400), PreviousSeconds: f(3600)}},
+			{GroupName: "Search", Opened: 1},
+		},
+		NotableIncidents: []db.DigestIncident{{Title: "Checkout down", Status: "resolved", GroupName: "Payments", CreatedAt: from.Add(time.Hour)}},
+		CoverageGaps:     []db.CoverageGap{{GroupName: "Search", Start: from, End: from.Add(2 * time.Hour), Seconds: 7200}},
+	}
+
+	subject, body := formatActivityDigest(digest)
+	if subject != "SLAR weekly digest: 5 incidents, 1 P1, 1 coverage gaps" {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{
+		"Incident activity for Mon Oct 5 2026 to Sun Oct 11 2026",
+		"Opened: 5   Resolved: 3   Still open: 1",
+		"- Payments: 4 opened, 3 resolved, 1 open; MTTA 4m (was 6m, down); MTTR 1h30m (was 1h00m, up)",
+		"- Search: 1 opened, 0 resolved, 0 open; MTTA n/a; MTTR n/a",
+		"- [resolved] Checkout down (Payments)",
+		"- Search: Mon Oct 5 00:00 to Mon Oct 5 02:00 (2h00m)",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body is missing %q:\n%s", want, body)
+		}
+	}
+}
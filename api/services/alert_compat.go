@@ -0,0 +1,102 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrIncidentNotAcknowledged = errors.New("incident is not acknowledged")
+
+// LegacyAlertFromIncident renders an incident in the shape old /alerts clients
+// expect. The ID is the incident's, so follow-up ack/close calls go straight to it.
+func LegacyAlertFromIncident(incident *db.IncidentResponse) db.AlertResponse {
+	return db.AlertResponse{
+		ID:                     incident.ID,
+		Title:                  incident.Title,
+		Description:            incident.Description,
+		Status:                 LegacyAlertStatus(incident.Status),
+		CreatedAt:              incident.CreatedAt,
+		UpdatedAt:              incident.UpdatedAt,
+		Severity:               incident.Severity,
+		Source:                 incident.Source,
+		AckedBy:                incident.AcknowledgedBy,
+		AckedAt:                incident.AcknowledgedAt,
+		AssignedTo:             incident.AssignedTo,
+		AssignedToName:         incident.AssignedToName,
+		AssignedToEmail:        incident.AssignedToEmail,
+		AssignedAt:             incident.AssignedAt,
+		GroupID:                incident.GroupID,
+		GroupName:              incident.GroupName,
+		ServiceID:              incident.ServiceID,
+		ServiceName:            incident.ServiceName,
+		CurrentEscalationLevel: incident.CurrentEscalationLevel,
+		LastEscalatedAt:        incident.LastEscalatedAt,
+		EscalationStatus:       incident.EscalationStatus,
+	}
+}
+
+// LegacyAlertStatus maps an incident status to the alert status it replaced
+func LegacyAlertStatus(incidentStatus string) string {
+	switch incidentStatus {
+	case db.IncidentStatusAcknowledged:
+		return db.LegacyAlertStatusAcked
+	case db.IncidentStatusResolved:
+		return db.LegacyAlertStatusClosed
+	}
+	return db.LegacyAlertStatusNew
+}
+
+// IncidentStatusFromLegacyAlert maps an alert status filter to incident
+// statuses; incident statuses are passed through
+func IncidentStatusFromLegacyAlert(alertStatus string) string {
+	switch alertStatus {
+	case db.LegacyAlertStatusNew:
+		return db.IncidentStatusTriggered
+	case db.LegacyAlertStatusAcked:
+		return db.IncidentStatusAcknowledged
+	case db.LegacyAlertStatusClosed:
+		return db.IncidentStatusResolved
+	}
+	return alertStatus
+}
+
+// ResolveLegacyAlertID returns the incident behind an /alerts ID, which is
+// either an incident ID or the ID of an alert migrated into one
+func (s *IncidentService) ResolveLegacyAlertID(id string) (string, error) {
+	var incidentID string
+	err := s.PG.QueryRow(`
+		SELECT id FROM incidents
+		WHERE id::text = $1 OR legacy_alert_id::text = $1
+		LIMIT 1
+	`, id).Scan(&incidentID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up alert: %w", err)
+	}
+	return incidentID, nil
+}
+
+// UnacknowledgeIncident puts an acknowledged incident back to triggered. Only
+// the legacy /alerts/:id/unack endpoint offers this.
+func (s *IncidentService) UnacknowledgeIncident(id, userID string) error {
+	result, err := s.PG.Exec(`
+		UPDATE incidents
+		SET status = $1, acknowledged_by = NULL, acknowledged_at = NULL, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`, db.IncidentStatusTriggered, time.Now(), id, db.IncidentStatusAcknowledged)
+	if err != nil {
+		return fmt.Errorf("failed to unacknowledge incident: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrIncidentNotAcknowledged
+	}
+
+	s.createIncidentEvent(id, db.IncidentEventTriggered, map[string]interface{}{"reason": "unacknowledged"}, userID)
+	return nil
+}
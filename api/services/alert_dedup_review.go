@@ -0,0 +1,250 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrDedupReviewNotFound       = errors.New("dedup review not found")
+	ErrDedupReviewDecided        = errors.New("dedup review has already been decided")
+	ErrDedupReviewNotPromotable  = errors.New("only firing alerts can be promoted to a new incident")
+	ErrDedupIncidentNotFound     = errors.New("incident not found in this organization")
+	ErrDedupIncidentNotAvailable = errors.New("incident is no longer open")
+)
+
+// AlertDedupReviewService stores alerts the webhook pipeline could not
+// confidently deduplicate, and counts automatic and manual decisions
+type AlertDedupReviewService struct {
+	PG *sql.DB
+}
+
+// NewAlertDedupReviewService creates a new AlertDedupReviewService
+func NewAlertDedupReviewService(pg *sql.DB) *AlertDedupReviewService {
+	return &AlertDedupReviewService{PG: pg}
+}
+
+const dedupReviewSelect = `
+	SELECT r.id, COALESCE(r.organization_id::text, ''), COALESCE(r.integration_id::text, ''),
+	       COALESCE(r.candidate_incident_id::text, ''), COALESCE(ci.title, ''),
+	       r.alert_name, r.alert_status, COALESCE(r.severity, ''), r.match_strategy, r.alert,
+	       r.status, COALESCE(r.incident_id::text, ''), COALESCE(r.decided_by::text, ''),
+	       r.decided_at, r.created_at
+	FROM alert_dedup_reviews r
+	LEFT JOIN incidents ci ON ci.id = r.candidate_incident_id`
+
+// FindOpenIncidentByTitle returns the newest open incident in the org with
+// exactly this title, or "" when there is none
+func (s *AlertDedupReviewService) FindOpenIncidentByTitle(orgID, title string) (string, error) {
+	var incidentID string
+	err := s.PG.QueryRow(`
+		SELECT id FROM incidents
+		WHERE organization_id = $1 AND title = $2 AND status IN ('triggered', 'acknowledged')
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, orgID, title).Scan(&incidentID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find incident by title: %w", err)
+	}
+	return incidentID, nil
+}
+
+// Park queues an alert for review and counts it as parked
+func (s *AlertDedupReviewService) Park(review *db.AlertDedupReview) error {
+	review.Status = db.DedupReviewStatusPending
+	err := s.PG.QueryRow(`
+		INSERT INTO alert_dedup_reviews
+			(organization_id, integration_id, candidate_incident_id, alert_name, alert_status,
+			 severity, match_strategy, alert)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, nullIfEmpty(review.OrganizationID), nullIfEmpty(review.IntegrationID), nullIfEmpty(review.CandidateIncidentID),
+		review.AlertName, review.AlertStatus, nullIfEmpty(review.Severity), review.MatchStrategy,
+		string(review.Alert)).Scan(&review.ID, &review.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to park alert for review: %w", err)
+	}
+	s.RecordDecision(review.OrganizationID, db.DedupModeAuto, db.DedupOutcomeParked)
+	return nil
+}
+
+// ListReviews returns an organization's reviews, newest first. An empty
+// status returns every status.
+func (s *AlertDedupReviewService) ListReviews(orgID, status string, limit int) ([]db.AlertDedupReview, error) {
+	rows, err := s.PG.Query(dedupReviewSelect+`
+		WHERE r.organization_id = $1 AND ($2 = '' OR r.status = $2)
+		ORDER BY r.created_at DESC
+		LIMIT $3
+	`, orgID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dedup reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []db.AlertDedupReview{}
+	for rows.Next() {
+		review, err := scanDedupReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dedup review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+// GetReview returns one of an organization's reviews
+func (s *AlertDedupReviewService) GetReview(orgID, id string) (*db.AlertDedupReview, error) {
+	review, err := scanDedupReview(s.PG.QueryRow(dedupReviewSelect+`
+		WHERE r.id = $1 AND r.organization_id = $2
+	`, id, orgID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDedupReviewNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dedup review: %w", err)
+	}
+	return &review, nil
+}
+
+// ClaimReview marks a pending review as decided so two reviewers can't act
+// on the same alert. Call ReleaseReview if acting on the decision fails.
+func (s *AlertDedupReviewService) ClaimReview(orgID, id, status, userID string) (*db.AlertDedupReview, error) {
+	res, err := s.PG.Exec(`
+		UPDATE alert_dedup_reviews
+		SET status = $3, decided_by = $4, decided_at = NOW()
+		WHERE id = $1 AND organization_id = $2 AND status = $5
+	`, id, orgID, status, nullIfEmpty(userID), db.DedupReviewStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim dedup review: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := s.GetReview(orgID, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrDedupReviewDecided
+	}
+	return s.GetReview(orgID, id)
+}
+
+// ReleaseReview puts a claimed review back in the queue
+func (s *AlertDedupReviewService) ReleaseReview(id string) {
+	if _, err := s.PG.Exec(`
+		UPDATE alert_dedup_reviews SET status = $2, decided_by = NULL, decided_at = NULL WHERE id = $1
+	`, id, db.DedupReviewStatusPending); err != nil {
+		log.Printf("⚠️  Failed to release dedup review %s: %v", id, err)
+	}
+}
+
+// CompleteReview records where a decided alert ended up and counts the
+// manual decision
+func (s *AlertDedupReviewService) CompleteReview(review *db.AlertDedupReview, incidentID string) {
+	if incidentID != "" {
+		if _, err := s.PG.Exec(`UPDATE alert_dedup_reviews SET incident_id = $2 WHERE id = $1`,
+			review.ID, incidentID); err != nil {
+			log.Printf("⚠️  Failed to record incident for dedup review %s: %v", review.ID, err)
+		}
+		review.IncidentID = incidentID
+	}
+	s.RecordDecision(review.OrganizationID, db.DedupModeManual, review.Status)
+}
+
+// IncidentInOrg reports whether an incident exists in the organization
+func (s *AlertDedupReviewService) IncidentInOrg(incidentID, orgID string) (bool, error) {
+	var exists bool
+	err := s.PG.QueryRow(`SELECT EXISTS(SELECT 1 FROM incidents WHERE id = $1 AND organization_id = $2)`,
+		incidentID, orgID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check incident: %w", err)
+	}
+	return exists, nil
+}
+
+// RecordDecision counts one deduplication decision on today's date in the
+// organization's timezone. Counting never fails the alert it is about.
+func (s *AlertDedupReviewService) RecordDecision(orgID, mode, outcome string) {
+	if orgID == "" {
+		return
+	}
+	if _, err := s.PG.Exec(`
+		INSERT INTO alert_dedup_decisions (organization_id, day, mode, outcome, count)
+		VALUES ($1, (NOW() AT TIME ZONE $4)::date, $2, $3, 1)
+		ON CONFLICT (organization_id, day, mode, outcome) DO UPDATE SET count = alert_dedup_decisions.count + 1
+	`, orgID, mode, outcome, orgDisplaySettingsOrDefault(s.PG, orgID).Timezone); err != nil {
+		log.Printf("⚠️  Failed to count dedup decision: %v", err)
+	}
+}
+
+// GetStats counts an organization's deduplication decisions between from and
+// to (inclusive days). Parked alerts are listed under auto but left out of
+// the totals, since each one becomes a manual decision.
+func (s *AlertDedupReviewService) GetStats(orgID string, from, to time.Time) (*db.AlertDedupStats, error) {
+	stats := &db.AlertDedupStats{From: from, To: to, Auto: map[string]int{}, Manual: map[string]int{}}
+
+	rows, err := s.PG.Query(`
+		SELECT mode, outcome, SUM(count)
+		FROM alert_dedup_decisions
+		WHERE organization_id = $1 AND day BETWEEN $2::date AND $3::date
+		GROUP BY mode, outcome
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dedup stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mode, outcome string
+		var count int
+		if err := rows.Scan(&mode, &outcome, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan dedup stats: %w", err)
+		}
+		switch {
+		case mode == db.DedupModeManual:
+			stats.Manual[outcome] = count
+			stats.ManualTotal += count
+		case outcome == db.DedupOutcomeParked:
+			stats.Auto[outcome] = count
+		default:
+			stats.Auto[outcome] = count
+			stats.AutoTotal += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if total := stats.AutoTotal + stats.ManualTotal; total > 0 {
+		stats.ManualRate = float64(stats.ManualTotal) / float64(total)
+	}
+
+	if err := s.PG.QueryRow(`
+		SELECT COUNT(*) FROM alert_dedup_reviews WHERE organization_id = $1 AND status = $2
+	`, orgID, db.DedupReviewStatusPending).Scan(&stats.Pending); err != nil {
+		return nil, fmt.Errorf("failed to count pending dedup reviews: %w", err)
+	}
+	return stats, nil
+}
+
+func scanDedupReview(row interface{ Scan(...interface{}) error }) (db.AlertDedupReview, error) {
+	var review db.AlertDedupReview
+	var alert []byte
+	var decidedAt sql.NullTime
+	if err := row.Scan(&review.ID, &review.OrganizationID, &review.IntegrationID,
+		&review.CandidateIncidentID, &review.CandidateIncidentTitle,
+		&review.AlertName, &review.AlertStatus, &review.Severity, &review.MatchStrategy, &alert,
+		&review.Status, &review.IncidentID, &review.DecidedBy, &decidedAt, &review.CreatedAt); err != nil {
+		return db.AlertDedupReview{}, err
+	}
+	review.Alert = alert
+	if decidedAt.Valid {
+		t := decidedAt.Time
+		review.DecidedAt = &t
+	}
+	return review, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestAlertDedupStatsLeavesParkedOutOfTotals(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	mock.ExpectQuery(`FROM alert_dedup_decisions`).WithArgs("org-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"mode", "outcome", "sum"}).
+			AddRow(db.DedupModeAuto, db.DedupOutcomeAttached, 50).
+			AddRow(db.DedupModeAuto, db.DedupOutcomeCreated, 40).
+			AddRow(db.DedupModeAuto, db.DedupOutcomeParked, 12).
+			AddRow(db.DedupModeManual, db.DedupOutcomeAttached, 6).
+			AddRow(db.DedupModeManual, db.DedupOutcomePromoted, 4))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM alert_dedup_reviews`).WithArgs("org-1", db.DedupReviewStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	stats, err := NewAlertDedupReviewService(pg).GetStats("org-1", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.AutoTotal != 90 || stats.ManualTotal != 10 || stats.Pending != 2 {
+		t.Errorf("unexpected totals: %+v", stats)
+	}
+	if stats.Auto[db.DedupOutcomeParked] != 12 {
+		t.Errorf("parked count missing: %+v", stats.Auto)
+	}
+	if stats.ManualRate != 0.1 {
+		t.Errorf("ManualRate = %v, want 0.1", stats.ManualRate)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClaimDecidedDedupReview(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectExec(`UPDATE alert_dedup_reviews`).
+		WithArgs("rev-1", "org-1", db.DedupReviewStatusAttached, "user-1", db.DedupReviewStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM alert_dedup_reviews r`).WithArgs("rev-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "integration_id", "candidate_incident_id",
+			"candidate_title", "alert_name", "alert_status", "severity", "match_strategy", "alert", "status",
+			"incident_id", "decided_by", "decided_at", "created_at"}).
+			AddRow("rev-1", "org-1", "int-1", "inc-1", "HighCPU", "HighCPU", "firing", "critical",
+				db.DedupStrategyTitle, []byte(`{}`), db.DedupReviewStatusDismissed, "", "user-2", now, now))
+
+	_, err = NewAlertDedupReviewService(pg).ClaimReview("org-1", "rev-1", db.DedupReviewStatusAttached, "user-1")
+	if !errors.Is(err, ErrDedupReviewDecided) {
+		t.Errorf("expected ErrDedupReviewDecided, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
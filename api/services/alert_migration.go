@@ -0,0 +1,146 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrAlertMigrationProjectNotInOrg = errors.New("project does not belong to the organization")
+	ErrAlertMigrationInvalidOptions  = errors.New("organization and project are required")
+)
+
+const defaultAlertMigrationBatchSize = 500
+
+// legacyAlertIncidentStatus maps an alerts.status to the incident status it
+// becomes; anything unrecognised is treated as still open
+const legacyAlertIncidentStatus = `
+	CASE a.status WHEN 'acked' THEN 'acknowledged' WHEN 'closed' THEN 'resolved' ELSE 'triggered' END`
+
+// AlertMigrationService converts the legacy alerts table into incidents
+type AlertMigrationService struct {
+	PG *sql.DB
+}
+
+// NewAlertMigrationService creates a new AlertMigrationService
+func NewAlertMigrationService(pg *sql.DB) *AlertMigrationService {
+	return &AlertMigrationService{PG: pg}
+}
+
+// MigrateAlerts copies every alert that has no incident yet into the given
+// organization and project, in batches. Timestamps, assignee and ack state are
+// kept, and the incident timeline gets triggered/acknowledged/resolved events
+// at the original times. Each batch is one statement, so a run can be stopped
+// and restarted; alerts already migrated are skipped.
+func (s *AlertMigrationService) MigrateAlerts(opts db.AlertMigrationOptions) (*db.AlertMigrationReport, error) {
+	if opts.OrganizationID == "" || opts.ProjectID == "" {
+		return nil, ErrAlertMigrationInvalidOptions
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultAlertMigrationBatchSize
+	}
+
+	var inOrg bool
+	if err := s.PG.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1 AND organization_id = $2)
+	`, opts.ProjectID, opts.OrganizationID).Scan(&inOrg); err != nil {
+		return nil, fmt.Errorf("failed to check project: %w", err)
+	}
+	if !inOrg {
+		return nil, ErrAlertMigrationProjectNotInOrg
+	}
+
+	report := &db.AlertMigrationReport{DryRun: opts.DryRun, ByStatus: map[string]int{}}
+	if err := s.PG.QueryRow(`SELECT COUNT(*) FROM incidents WHERE legacy_alert_id IS NOT NULL`).Scan(&report.AlreadyMigrated); err != nil {
+		return nil, fmt.Errorf("failed to count migrated alerts: %w", err)
+	}
+
+	if opts.DryRun {
+		err := s.countByStatus(report, `
+			SELECT `+legacyAlertIncidentStatus+`, COUNT(*)
+			FROM alerts a
+			WHERE NOT EXISTS (SELECT 1 FROM incidents i WHERE i.legacy_alert_id = a.id)
+			GROUP BY 1
+		`)
+		return report, err
+	}
+
+	for {
+		before := report.Migrated
+		if err := s.countByStatus(report, migrateAlertBatchQuery, opts.OrganizationID, opts.ProjectID, opts.BatchSize); err != nil {
+			return report, err
+		}
+		if report.Migrated-before < opts.BatchSize {
+			return report, nil
+		}
+	}
+}
+
+// countByStatus adds (status, count) rows from a query to the report
+func (s *AlertMigrationService) countByStatus(report *db.AlertMigrationReport, query string, args ...interface{}) error {
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to migrate alerts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return fmt.Errorf("failed to scan migrated alerts: %w", err)
+		}
+		report.ByStatus[status] += count
+		report.Migrated += count
+	}
+	return rows.Err()
+}
+
+// migrateAlertBatchQuery moves up to $3 unmigrated alerts, oldest first.
+// acked_by is free text in alerts, so it is only kept when it names a user.
+// A closed alert has no close time of its own; its last update stands in.
+// Two runs racing for the same alert fail on incident_keys' unique
+// legacy_alert_id instead of migrating it twice.
+const migrateAlertBatchQuery = `
+	WITH batch AS (
+		SELECT a.*, ` + legacyAlertIncidentStatus + ` AS incident_status
+		FROM alerts a
+		WHERE NOT EXISTS (SELECT 1 FROM incidents i WHERE i.legacy_alert_id = a.id)
+		ORDER BY a.created_at, a.id
+		LIMIT $3
+	), migrated AS (
+		INSERT INTO incidents (
+			title, description, status, urgency, severity, source,
+			created_at, updated_at, assigned_to, assigned_at,
+			acknowledged_by, acknowledged_at, resolved_at,
+			escalation_status, current_escalation_level, last_escalated_at,
+			organization_id, project_id, legacy_alert_id
+		)
+		SELECT
+			a.title, a.description, a.incident_status,
+			CASE WHEN LOWER(COALESCE(a.severity, '')) IN ('low', 'info', 'warning') THEN 'low' ELSE 'high' END,
+			NULLIF(a.severity, ''), COALESCE(NULLIF(a.source, ''), 'legacy_alert'),
+			a.created_at, a.updated_at, a.assigned_to, a.assigned_at,
+			u.id,
+			CASE WHEN a.incident_status != 'triggered' OR a.acked_at IS NOT NULL THEN COALESCE(a.acked_at, a.updated_at) END,
+			CASE WHEN a.incident_status = 'resolved' THEN a.updated_at END,
+			COALESCE(a.escalation_status, 'none'), COALESCE(a.current_escalation_level, 0), a.last_escalated_at,
+			$1, $2, a.id
+		FROM batch a
+		LEFT JOIN users u ON u.id::text = a.acked_by
+		RETURNING id, legacy_alert_id, status, created_at, acknowledged_by, acknowledged_at, resolved_at
+	), events AS (
+		INSERT INTO incident_events (incident_id, event_type, event_data, created_at, created_by)
+		SELECT id, 'triggered', jsonb_build_object('migrated_from_alert', legacy_alert_id), created_at, NULL FROM migrated
+		UNION ALL
+		SELECT id, 'acknowledged', jsonb_build_object('migrated_from_alert', legacy_alert_id), acknowledged_at, acknowledged_by
+		FROM migrated WHERE acknowledged_at IS NOT NULL
+		UNION ALL
+		SELECT id, 'resolved', jsonb_build_object('migrated_from_alert', legacy_alert_id), resolved_at, NULL
+		FROM migrated WHERE resolved_at IS NOT NULL
+	)
+	SELECT status, COUNT(*) FROM migrated GROUP BY status
+`
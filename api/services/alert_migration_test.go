@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestMigrateAlertsInBatches(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM projects`).WithArgs("proj-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`WHERE legacy_alert_id IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO incidents`).WithArgs("org-1", "proj-1", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow("triggered", 1).AddRow("resolved", 1))
+	mock.ExpectQuery(`INSERT INTO incidents`).WithArgs("org-1", "proj-1", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow("acknowledged", 1))
+
+	report, err := NewAlertMigrationService(pg).MigrateAlerts(db.AlertMigrationOptions{
+		OrganizationID: "org-1", ProjectID: "proj-1", BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Migrated != 3 || report.AlreadyMigrated != 3 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.ByStatus["triggered"] != 1 || report.ByStatus["acknowledged"] != 1 || report.ByStatus["resolved"] != 1 {
+		t.Errorf("unexpected status breakdown %v", report.ByStatus)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigrateAlertsRejectsProjectOutsideOrg(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM projects`).WithArgs("proj-1", "org-2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	_, err = NewAlertMigrationService(pg).MigrateAlerts(db.AlertMigrationOptions{OrganizationID: "org-2", ProjectID: "proj-1"})
+	if !errors.Is(err, ErrAlertMigrationProjectNotInOrg) {
+		t.Fatalf("expected ErrAlertMigrationProjectNotInOrg, got %v", err)
+	}
+}
+
+func TestLegacyAlertFromIncident(t *testing.T) {
+	acked := time.Now()
+	incident := &db.IncidentResponse{Incident: db.Incident{
+		ID: "inc-1", Title: "Disk full", Status: db.IncidentStatusAcknowledged,
+		AcknowledgedBy: "user-1", AcknowledgedAt: &acked,
+	}}
+
+	alert := LegacyAlertFromIncident(incident)
+	if alert.ID != "inc-1" || alert.Status != db.LegacyAlertStatusAcked || alert.AckedBy != "user-1" || alert.AckedAt != &acked {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+
+	for alertStatus, incidentStatus := range map[string]string{
+		db.LegacyAlertStatusNew:    db.IncidentStatusTriggered,
+		db.LegacyAlertStatusAcked:  db.IncidentStatusAcknowledged,
+		db.LegacyAlertStatusClosed: db.IncidentStatusResolved,
+	} {
+		if got := IncidentStatusFromLegacyAlert(alertStatus); got != incidentStatus {
+			t.Errorf("IncidentStatusFromLegacyAlert(%q) = %q, want %q", alertStatus, got, incidentStatus)
+		}
+		if got := LegacyAlertStatus(incidentStatus); got != alertStatus {
+			t.Errorf("LegacyAlertStatus(%q) = %q, want %q", incidentStatus, got, alertStatus)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+// alertQualityKeySQL picks the report's grouping key from an incident (i)
+var alertQualityKeySQL = map[string]string{
+	db.AlertQualityByAlertName:   `COALESCE(NULLIF(i.labels->>'alertname', ''), i.title)`,
+	db.AlertQualityByFingerprint: `NULLIF(i.labels->>'fingerprint', '')`,
+}
+
+// GetNoisyAlerts ranks an organization's alerts by how often their incidents
+// were resolved without acknowledgement or auto-resolved by the monitoring
+// system, so teams can tune or stop paging on them. Test and drill
+// incidents are left out.
+func (s *IncidentService) GetNoisyAlerts(orgID string, from, to time.Time, filter db.NoisyAlertsFilter) (*db.NoisyAlertsReport, error) {
+	keySQL, ok := alertQualityKeySQL[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group_by %q", filter.GroupBy)
+	}
+
+	rows, err := s.PG.Query(`
+		WITH scoped AS (
+			SELECT `+keySQL+` AS alert_key, i.title, i.service_id, i.created_at,
+			       i.status = 'resolved' AS resolved,
+			       i.status = 'resolved' AND i.acknowledged_at IS NULL AS unacked,
+			       i.status = 'resolved' AND i.resolved_by = ANY($4::uuid[]) AS auto_resolved,
+			       EXTRACT(EPOCH FROM (i.resolved_at - i.created_at)) AS resolve_seconds,
+			       COALESCE(i.alert_count, 1) AS alert_count
+			FROM incidents i
+			WHERE i.organization_id = $1 AND i.created_at >= $2 AND i.created_at < $3
+			  AND NOT COALESCE(i.is_test, false) AND i.drill_id IS NULL
+			  AND ($5 = '' OR i.service_id::text = $5)
+		)
+		SELECT alert_key, MAX(title), COUNT(*), SUM(alert_count), COUNT(*) FILTER (WHERE resolved),
+		       COUNT(DISTINCT service_id), MAX(created_at),
+		       COUNT(*) FILTER (WHERE unacked),
+		       COUNT(*) FILTER (WHERE auto_resolved),
+		       COUNT(*) FILTER (WHERE auto_resolved AND resolve_seconds <= $6),
+		       AVG(resolve_seconds) FILTER (WHERE auto_resolved),
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY resolve_seconds) FILTER (WHERE auto_resolved)
+		FROM scoped
+		WHERE alert_key IS NOT NULL
+		GROUP BY alert_key
+		HAVING COUNT(*) >= $7
+	`, orgID, from, to, pq.Array(db.SystemUserIDs), filter.ServiceID,
+		db.AlertQualityFastAutoResolveSeconds, filter.MinIncidents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get noisy alerts: %w", err)
+	}
+	defer rows.Close()
+
+	report := &db.NoisyAlertsReport{
+		OrganizationID: orgID,
+		From:           from,
+		To:             to,
+		GroupBy:        filter.GroupBy,
+		MinIncidents:   filter.MinIncidents,
+		Alerts:         []db.AlertQualityScore{},
+	}
+	for rows.Next() {
+		var score db.AlertQualityScore
+		var avg, median sql.NullFloat64
+		if err := rows.Scan(&score.Key, &score.Title, &score.Incidents, &score.Alerts, &score.Resolved,
+			&score.Services, &score.LastSeen, &score.ResolvedWithoutAck, &score.AutoResolved,
+			&score.FastAutoResolved, &avg, &median); err != nil {
+			return nil, fmt.Errorf("failed to scan noisy alert: %w", err)
+		}
+		if avg.Valid {
+			score.AvgSecondsToAutoResolve = &avg.Float64
+		}
+		if median.Valid {
+			score.MedianSecondsToAutoResolve = &median.Float64
+		}
+		scoreAlertQuality(&score)
+		report.Alerts = append(report.Alerts, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(report.Alerts, func(i, j int) bool {
+		a, b := report.Alerts[i], report.Alerts[j]
+		if a.NoiseScore != b.NoiseScore {
+			return a.NoiseScore > b.NoiseScore
+		}
+		return a.Incidents > b.Incidents
+	})
+	if filter.Limit > 0 && len(report.Alerts) > filter.Limit {
+		report.Alerts = report.Alerts[:filter.Limit]
+	}
+	return report, nil
+}
+
+// scoreAlertQuality fills in the rates, noise score and recommendation. An
+// unacknowledged resolve weighs most: someone was paged and did nothing.
+// Quick auto-resolves add to that, as the alert is likely flapping.
+func scoreAlertQuality(score *db.AlertQualityScore) {
+	if score.Resolved == 0 {
+		return
+	}
+	resolved := float64(score.Resolved)
+	score.ResolvedWithoutAckRate = float64(score.ResolvedWithoutAck) / resolved
+	score.AutoResolveRate = float64(score.AutoResolved) / resolved
+	score.FastAutoResolveRate = float64(score.FastAutoResolved) / resolved
+
+	noise := 0.5*score.ResolvedWithoutAckRate + 0.3*score.AutoResolveRate + 0.2*score.FastAutoResolveRate
+	score.NoiseScore = float64(int(noise*1000+0.5)) / 10
+
+	switch {
+	case score.ResolvedWithoutAckRate >= 0.8:
+		score.Recommendation = db.AlertRecommendationRouteAway
+	case score.FastAutoResolveRate >= 0.5:
+		score.Recommendation = db.AlertRecommendationTune
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestScoreAlertQuality(t *testing.T) {
+	score := db.AlertQualityScore{Resolved: 10, ResolvedWithoutAck: 9, AutoResolved: 8, FastAutoResolved: 2}
+	scoreAlertQuality(&score)
+	if score.ResolvedWithoutAckRate != 0.9 || score.AutoResolveRate != 0.8 || score.FastAutoResolveRate != 0.2 {
+		t.Errorf("unexpected rates: %+v", score)
+	}
+	// 0.5*0.9 + 0.3*0.8 + 0.2*0.2 = 0.73
+	if score.NoiseScore != 73 {
+		t.Errorf("NoiseScore = %v, want 73", score.NoiseScore)
+	}
+	if score.Recommendation != db.AlertRecommendationRouteAway {
+		t.Errorf("Recommendation = %q, want %q", score.Recommendation, db.AlertRecommendationRouteAway)
+	}
+
+	flapping := db.AlertQualityScore{Resolved: 4, ResolvedWithoutAck: 1, AutoResolved: 4, FastAutoResolved: 3}
+	scoreAlertQuality(&flapping)
+	if flapping.Recommendation != db.AlertRecommendationTune {
+		t.Errorf("Recommendation = %q, want %q", flapping.Recommendation, db.AlertRecommendationTune)
+	}
+
+	open := db.AlertQualityScore{Incidents: 3}
+	scoreAlertQuality(&open)
+	if open.NoiseScore != 0 || open.Recommendation != "" {
+		t.Errorf("unresolved alerts should not be scored: %+v", open)
+	}
+}
+
+func TestGetNoisyAlertsRanksByNoise(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	columns := []string{"alert_key", "title", "incidents", "alerts", "resolved", "services", "last_seen",
+		"unacked", "auto_resolved", "fast_auto_resolved", "avg", "median"}
+	mock.ExpectQuery(`WITH scoped AS`).
+		WithArgs("org-1", from, to, sqlmock.AnyArg(), "", db.AlertQualityFastAutoResolveSeconds, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("DiskFull", "DiskFull", 5, 5, 5, 1, to, 0, 0, 0, nil, nil).
+			AddRow("HighCPU", "High CPU", 12, 40, 10, 2, to, 10, 9, 6, 210.0, 180.0).
+			AddRow("Latency", "Latency", 4, 4, 4, 1, to, 2, 1, 0, 900.0, 900.0))
+
+	report, err := (&IncidentService{PG: pg}).GetNoisyAlerts("org-1", from, to,
+		db.NoisyAlertsFilter{GroupBy: db.AlertQualityByAlertName, MinIncidents: 3, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Alerts) != 2 || report.Alerts[0].Key != "HighCPU" || report.Alerts[1].Key != "Latency" {
+		t.Fatalf("unexpected ranking: %+v", report.Alerts)
+	}
+	if m := report.Alerts[0].MedianSecondsToAutoResolve; m == nil || *m != 180 {
+		t.Errorf("MedianSecondsToAutoResolve = %v, want 180", m)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
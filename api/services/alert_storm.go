@@ -0,0 +1,299 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidAlertStormRule = errors.New("invalid alert storm rule")
+
+// ALERT STORM RULES
+
+// GetAlertStormStatus returns a service's alert storm rule (nil when it has
+// none) and the storm incident currently open for it, if any
+func (s *ServiceService) GetAlertStormStatus(serviceID string) (*db.AlertStormStatus, error) {
+	status := &db.AlertStormStatus{}
+	rule := db.ServiceAlertStormRule{ServiceID: serviceID}
+	err := s.PG.QueryRow(`
+		SELECT spike_multiple, window_minutes, baseline_hours, min_incidents, group_alerts,
+		       COALESCE(updated_by::text, ''), updated_at
+		FROM service_alert_storm_rules
+		WHERE service_id = $1
+	`, serviceID).Scan(&rule.SpikeMultiple, &rule.WindowMinutes, &rule.BaselineHours, &rule.MinIncidents,
+		&rule.GroupAlerts, &rule.UpdatedBy, &rule.UpdatedAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, fmt.Errorf("failed to get alert storm rule: %w", err)
+	default:
+		status.Rule = &rule
+	}
+
+	var since sql.NullTime
+	err = s.PG.QueryRow(`
+		SELECT id, created_at FROM incidents
+		WHERE incident_key = $1 AND status IN ('triggered', 'acknowledged')
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, db.AlertStormIncidentKeyPrefix+serviceID).Scan(&status.StormIncidentID, &since)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get alert storm incident: %w", err)
+	}
+	status.StormSince = nullTimePtr(since)
+	return status, nil
+}
+
+// normalizeAlertStormRule fills in defaults and validates a rule
+func normalizeAlertStormRule(req db.SetAlertStormRuleRequest) (db.SetAlertStormRuleRequest, error) {
+	if req.SpikeMultiple == 0 {
+		req.SpikeMultiple = db.AlertStormDefaultSpikeMultiple
+	}
+	if req.WindowMinutes == 0 {
+		req.WindowMinutes = db.AlertStormDefaultWindowMinutes
+	}
+	if req.BaselineHours == 0 {
+		req.BaselineHours = db.AlertStormDefaultBaselineHours
+	}
+	if req.MinIncidents == 0 {
+		req.MinIncidents = db.AlertStormDefaultMinIncidents
+	}
+	switch {
+	case req.SpikeMultiple <= 1:
+		return req, fmt.Errorf("%w: spike_multiple must be greater than 1", ErrInvalidAlertStormRule)
+	case req.WindowMinutes < 1 || req.WindowMinutes > 240:
+		return req, fmt.Errorf("%w: window_minutes must be 1-240", ErrInvalidAlertStormRule)
+	case req.BaselineHours < 1 || req.BaselineHours > 720:
+		return req, fmt.Errorf("%w: baseline_hours must be 1-720", ErrInvalidAlertStormRule)
+	case req.BaselineHours*60 <= req.WindowMinutes:
+		return req, fmt.Errorf("%w: baseline_hours must be longer than window_minutes", ErrInvalidAlertStormRule)
+	case req.MinIncidents < 1:
+		return req, fmt.Errorf("%w: min_incidents must be positive", ErrInvalidAlertStormRule)
+	}
+	return req, nil
+}
+
+// SetAlertStormRule turns on alert storm detection for a service or changes it
+func (s *ServiceService) SetAlertStormRule(serviceID, userID string, req db.SetAlertStormRuleRequest) (*db.AlertStormStatus, error) {
+	req, err := normalizeAlertStormRule(req)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.PG.Exec(`
+		INSERT INTO service_alert_storm_rules (service_id, spike_multiple, window_minutes, baseline_hours, min_incidents, group_alerts, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (service_id) DO UPDATE
+		SET spike_multiple = EXCLUDED.spike_multiple, window_minutes = EXCLUDED.window_minutes,
+		    baseline_hours = EXCLUDED.baseline_hours, min_incidents = EXCLUDED.min_incidents,
+		    group_alerts = EXCLUDED.group_alerts, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, serviceID, req.SpikeMultiple, req.WindowMinutes, req.BaselineHours, req.MinIncidents, req.GroupAlerts, nullIfEmpty(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save alert storm rule: %w", err)
+	}
+	return s.GetAlertStormStatus(serviceID)
+}
+
+// DeleteAlertStormRule turns off alert storm detection for a service. A storm
+// incident already open stays open for people to resolve.
+func (s *ServiceService) DeleteAlertStormRule(serviceID string) error {
+	if _, err := s.PG.Exec(`DELETE FROM service_alert_storm_rules WHERE service_id = $1`, serviceID); err != nil {
+		return fmt.Errorf("failed to delete alert storm rule: %w", err)
+	}
+	return nil
+}
+
+// ALERT STORM DETECTION
+
+// alertStormBaselineRate is the service's average number of incidents per
+// window over the baseline period
+func alertStormBaselineRate(rule db.ServiceAlertStormRule, baselineIncidents int) float64 {
+	windows := float64(rule.BaselineHours*60-rule.WindowMinutes) / float64(rule.WindowMinutes)
+	return float64(baselineIncidents) / windows
+}
+
+// alertStormSpiking reports whether recent incidents are a storm
+func alertStormSpiking(rule db.ServiceAlertStormRule, recent, baselineIncidents int) bool {
+	return recent >= rule.MinIncidents && float64(recent) > rule.SpikeMultiple*alertStormBaselineRate(rule, baselineIncidents)
+}
+
+// alertStormTarget is a service with an alert storm rule and its recent activity
+type alertStormTarget struct {
+	rule           db.ServiceAlertStormRule
+	service        db.Service
+	recent         int
+	baseline       int
+	openIncidentID string
+	openSince      time.Time
+}
+
+// EvaluateAlertStorms opens an "alert storm" incident for every service with
+// an alert storm rule whose incident rate over the rule's window spikes past
+// its baseline, and resolves the incident once a window has passed and the
+// rate is back down. Alerts grouped into a storm incident count towards the
+// rate, so grouping doesn't end the storm by itself. It returns the number of
+// incidents opened and resolved.
+func (s *IncidentService) EvaluateAlertStorms() (int, int, error) {
+	rows, err := s.PG.Query(`
+		SELECT r.service_id, r.spike_multiple, r.window_minutes, r.baseline_hours, r.min_incidents, r.group_alerts,
+		       sv.name, COALESCE(sv.group_id::text, ''), COALESCE(sv.escalation_policy_id::text, ''),
+		       COALESCE(sv.organization_id::text, ''), COALESCE(sv.project_id::text, ''),
+		       recent.n + COALESCE(grouped.n, 0), baseline.n,
+		       COALESCE(si.id::text, ''), si.created_at
+		FROM service_alert_storm_rules r
+		JOIN services sv ON sv.id = r.service_id AND sv.is_active
+		LEFT JOIN LATERAL (
+			SELECT inc.id, inc.created_at
+			FROM incidents inc
+			WHERE inc.incident_key = $1 || r.service_id::text AND inc.status IN ('triggered', 'acknowledged')
+			ORDER BY inc.created_at DESC
+			LIMIT 1
+		) si ON TRUE
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS n FROM incidents inc
+			WHERE inc.service_id = r.service_id AND inc.source <> $2 AND NOT COALESCE(inc.is_test, false)
+			  AND inc.created_at >= NOW() - make_interval(mins => r.window_minutes)
+		) recent
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS n FROM incidents inc
+			WHERE inc.service_id = r.service_id AND inc.source <> $2 AND NOT COALESCE(inc.is_test, false)
+			  AND inc.created_at >= NOW() - make_interval(hours => r.baseline_hours)
+			  AND inc.created_at < NOW() - make_interval(mins => r.window_minutes)
+		) baseline
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS n FROM incident_events e
+			WHERE e.incident_id = si.id AND e.event_type = $3
+			  AND e.created_at >= NOW() - make_interval(mins => r.window_minutes)
+		) grouped ON TRUE
+	`, db.AlertStormIncidentKeyPrefix, db.IncidentSourceAlertStorm, db.IncidentEventAlertGrouped)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to evaluate alert storm rules: %w", err)
+	}
+
+	var targets []alertStormTarget
+	for rows.Next() {
+		var t alertStormTarget
+		var openSince sql.NullTime
+		if err := rows.Scan(&t.rule.ServiceID, &t.rule.SpikeMultiple, &t.rule.WindowMinutes, &t.rule.BaselineHours,
+			&t.rule.MinIncidents, &t.rule.GroupAlerts,
+			&t.service.Name, &t.service.GroupID, &t.service.EscalationPolicyID,
+			&t.service.OrganizationID, &t.service.ProjectID,
+			&t.recent, &t.baseline, &t.openIncidentID, &openSince); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan alert storm rule: %w", err)
+		}
+		t.service.ID = t.rule.ServiceID
+		t.openSince = openSince.Time
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	opened, resolved := 0, 0
+	now := time.Now()
+	for _, t := range targets {
+		spiking := alertStormSpiking(t.rule, t.recent, t.baseline)
+		window := time.Duration(t.rule.WindowMinutes) * time.Minute
+
+		if t.openIncidentID != "" {
+			if spiking || now.Sub(t.openSince) < window {
+				continue
+			}
+			note := fmt.Sprintf("Incident rate on %s is back to normal (%d in the last %d minutes)",
+				t.service.Name, t.recent, t.rule.WindowMinutes)
+			if err := s.ResolveIncident(t.openIncidentID, db.SystemUserAPI, note, "alert_storm_subsided"); err != nil {
+				log.Printf("Failed to resolve alert storm incident for service %s: %v", t.service.ID, err)
+				continue
+			}
+			resolved++
+			continue
+		}
+		if !spiking {
+			continue
+		}
+
+		baselineRate := alertStormBaselineRate(t.rule, t.baseline)
+		description := fmt.Sprintf("%s opened %d incidents in the last %d minutes, against a baseline of %.1f per %d minutes over the previous %d hours (spike threshold %gx).",
+			t.service.Name, t.recent, t.rule.WindowMinutes, baselineRate, t.rule.WindowMinutes, t.rule.BaselineHours, t.rule.SpikeMultiple)
+		if t.rule.GroupAlerts {
+			description += " New alerts for this service are grouped into this incident instead of paging until the storm subsides."
+		}
+		incident := &db.Incident{
+			Title:              fmt.Sprintf("Alert storm on service %s", t.service.Name),
+			Description:        description,
+			Severity:           "high",
+			Urgency:            db.IncidentUrgencyHigh,
+			Source:             db.IncidentSourceAlertStorm,
+			IncidentKey:        db.AlertStormIncidentKeyPrefix + t.service.ID,
+			ServiceID:          t.service.ID,
+			GroupID:            t.service.GroupID,
+			EscalationPolicyID: t.service.EscalationPolicyID,
+			OrganizationID:     t.service.OrganizationID,
+			ProjectID:          t.service.ProjectID,
+			Labels: map[string]interface{}{
+				"service_id":       t.service.ID,
+				"service_name":     t.service.Name,
+				"recent_incidents": t.recent,
+				"baseline_rate":    baselineRate,
+				"group_alerts":     t.rule.GroupAlerts,
+			},
+		}
+		if _, err := s.CreateIncident(incident); err != nil {
+			log.Printf("Failed to open alert storm incident for service %s: %v", t.service.ID, err)
+			continue
+		}
+		opened++
+	}
+	return opened, resolved, nil
+}
+
+// GroupAlertIntoStorm adds an alert to the service's open storm incident when
+// its alert storm rule groups alerts, instead of it opening and paging for an
+// incident of its own. It returns the storm incident's ID, or "" when the
+// alert should be handled as usual.
+func (s *IncidentService) GroupAlertIntoStorm(serviceID, title, severity, fingerprint string) (string, error) {
+	if serviceID == "" {
+		return "", nil
+	}
+	var stormID string
+	var alertCount int
+	err := s.PG.QueryRow(`
+		UPDATE incidents i
+		SET alert_count = i.alert_count + 1, updated_at = NOW()
+		FROM service_alert_storm_rules r
+		WHERE r.service_id = $1 AND r.group_alerts
+		  AND i.id = (
+		        SELECT id FROM incidents
+		        WHERE incident_key = $2 AND status IN ('triggered', 'acknowledged')
+		        ORDER BY created_at DESC
+		        LIMIT 1
+		      )
+		RETURNING i.id, i.alert_count
+	`, serviceID, db.AlertStormIncidentKeyPrefix+serviceID).Scan(&stormID, &alertCount)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to group alert into storm: %w", err)
+	}
+
+	eventData := map[string]interface{}{
+		"title":       title,
+		"alert_count": alertCount,
+	}
+	if severity != "" {
+		eventData["severity"] = severity
+	}
+	if fingerprint != "" {
+		eventData["fingerprint"] = fingerprint
+	}
+	if err := s.createIncidentEvent(stormID, db.IncidentEventAlertGrouped, eventData, ""); err != nil {
+		log.Printf("Failed to record grouped alert on storm incident %s: %v", stormID, err)
+	}
+	return stormID, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestAlertStormSpiking(t *testing.T) {
+	// 10-minute window over a 24h baseline: 143 earlier windows
+	rule := db.ServiceAlertStormRule{SpikeMultiple: 5, WindowMinutes: 10, BaselineHours: 24, MinIncidents: 5}
+	cases := []struct {
+		name             string
+		recent, baseline int
+		want             bool
+	}{
+		{"quiet service, burst", 6, 0, true},
+		{"below minimum", 4, 0, false},
+		{"busy service, normal rate", 8, 286, false}, // 2 per window
+		{"busy service, spike", 11, 286, true},
+		{"exactly at multiple", 10, 286, false},
+	}
+	for _, tc := range cases {
+		if got := alertStormSpiking(rule, tc.recent, tc.baseline); got != tc.want {
+			t.Errorf("%s: alertStormSpiking(%d, %d) = %v, want %v", tc.name, tc.recent, tc.baseline, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeAlertStormRule(t *testing.T) {
+	req, err := normalizeAlertStormRule(db.SetAlertStormRuleRequest{GroupAlerts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.SpikeMultiple != db.AlertStormDefaultSpikeMultiple || req.WindowMinutes != db.AlertStormDefaultWindowMinutes ||
+		req.BaselineHours != db.AlertStormDefaultBaselineHours || req.MinIncidents != db.AlertStormDefaultMinIncidents || !req.GroupAlerts {
+		t.Errorf("defaults not applied: %+v", req)
+	}
+
+	for _, bad := range []db.SetAlertStormRuleRequest{
+		{SpikeMultiple: 1},
+		{WindowMinutes: 300},
+		{WindowMinutes: 120, BaselineHours: 1},
+		{MinIncidents: -1},
+	} {
+		if _, err := normalizeAlertStormRule(bad); !errors.Is(err, ErrInvalidAlertStormRule) {
+			t.Errorf("expected ErrInvalidAlertStormRule for %+v, got %v", bad, err)
+		}
+	}
+}
+
+func TestGroupAlertIntoStorm(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := &IncidentService{PG: pg}
+
+	mock.ExpectQuery(`UPDATE incidents i`).WithArgs("svc-1", db.AlertStormIncidentKeyPrefix+"svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "alert_count"}).AddRow("storm-1", 7))
+	mock.ExpectExec(`INSERT INTO incident_events`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	stormID, err := s.GroupAlertIntoStorm("svc-1", "High CPU", "critical", "fp-1")
+	if err != nil || stormID != "storm-1" {
+		t.Fatalf("GroupAlertIntoStorm = %q, %v", stormID, err)
+	}
+
+	// No storm, or the rule doesn't group alerts
+	mock.ExpectQuery(`UPDATE incidents i`).WithArgs("svc-2", db.AlertStormIncidentKeyPrefix+"svc-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "alert_count"}))
+	if stormID, err := s.GroupAlertIntoStorm("svc-2", "High CPU", "", ""); err != nil || stormID != "" {
+		t.Errorf("expected no grouping, got %q, %v", stormID, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vanchonlee/slar/db"
+)
+
+const (
+	dashboardGridColumns   = 12
+	maxDashboardWidgets    = 50
+	maxDashboardWidgetRows = 24
+)
+
+var (
+	ErrAnalyticsDashboardNotFound  = errors.New("dashboard not found")
+	ErrAnalyticsDashboardForbidden = errors.New("only the dashboard owner can change it")
+	ErrInvalidAnalyticsDashboard   = errors.New("invalid dashboard")
+	ErrDashboardGroupNotMember     = errors.New("you can only share a dashboard with a group you belong to")
+)
+
+// AnalyticsMetrics is the catalog of metrics a dashboard widget can show.
+// Widgets store only the key; clients fetch values from Source.
+var AnalyticsMetrics = []db.AnalyticsMetric{
+	{Key: "incidents.total", Name: "Total incidents", Source: "/incidents/stats", Field: "total", Visualizations: []string{"number"}},
+	{Key: "incidents.open", Name: "Open incidents", Source: "/incidents/stats", Field: "triggered,acknowledged", Visualizations: []string{"number", "bar", "pie"}},
+	{Key: "incidents.by_status", Name: "Incidents by status", Source: "/incidents/stats", Field: "triggered,acknowledged,resolved", Visualizations: []string{"bar", "pie", "table"}},
+	{Key: "incidents.high_urgency", Name: "High-urgency incidents", Source: "/incidents/stats", Field: "high_urgency", Visualizations: []string{"number"}},
+	{Key: "incidents.by_workflow_state", Name: "Incidents by workflow state", Source: "/incidents/stats", Field: "by_workflow_state", Visualizations: []string{"bar", "pie", "table"}},
+	{Key: "incidents.estimated_cost", Name: "Estimated incident cost", Source: "/incidents/stats", Field: "estimated_cost", Visualizations: []string{"number", "table"}},
+	{Key: "incidents.recent", Name: "Recent incidents", Source: "/incidents", Visualizations: []string{"table"}},
+	{Key: "incidents.caused_by", Name: "Incidents caused by a change", Source: "/analytics/caused-by", Field: "count", Visualizations: []string{"number", "table"}},
+	{Key: "notifications.stats", Name: "Notification delivery", Source: "/users/me/notifications/stats", Visualizations: []string{"number", "bar", "table"}},
+	{Key: "uptime.services", Name: "Service uptime", Source: "/uptime", Visualizations: []string{"table", "bar"}},
+	{Key: "group.statistics", Name: "Team statistics", Source: "/groups/:group_id/statistics", Visualizations: []string{"number", "table"}},
+	{Key: "service.slos", Name: "Service SLOs", Source: "/services/:service_id/slos", Visualizations: []string{"table", "line"}},
+}
+
+// dashboardFilterKeys are the analytics filters a dashboard or widget may set;
+// they map onto the query parameters of the analytics endpoints
+var dashboardFilterKeys = map[string]bool{
+	"time_range": true,
+	"project_id": true,
+	"group_id":   true,
+	"service_id": true,
+	"urgency":    true,
+	"severity":   true,
+	"status":     true,
+}
+
+// AnalyticsDashboardService stores user-defined analytics dashboards
+type AnalyticsDashboardService struct {
+	PG *sql.DB
+}
+
+// NewAnalyticsDashboardService creates a new AnalyticsDashboardService
+func NewAnalyticsDashboardService(pg *sql.DB) *AnalyticsDashboardService {
+	return &AnalyticsDashboardService{PG: pg}
+}
+
+func findAnalyticsMetric(key string) *db.AnalyticsMetric {
+	for i := range AnalyticsMetrics {
+		if AnalyticsMetrics[i].Key == key {
+			return &AnalyticsMetrics[i]
+		}
+	}
+	return nil
+}
+
+func validateDashboardFilters(filters map[string]interface{}) error {
+	for key := range filters {
+		if !dashboardFilterKeys[key] {
+			return fmt.Errorf("%w: unsupported filter %q", ErrInvalidAnalyticsDashboard, key)
+		}
+	}
+	return nil
+}
+
+// validateDashboardWidgets checks widgets reference known metrics and fit the
+// grid, and gives new widgets an ID
+func validateDashboardWidgets(widgets []db.DashboardWidget) error {
+	if len(widgets) > maxDashboardWidgets {
+		return fmt.Errorf("%w: at most %d widgets", ErrInvalidAnalyticsDashboard, maxDashboardWidgets)
+	}
+	seen := make(map[string]bool, len(widgets))
+	for i := range widgets {
+		w := &widgets[i]
+		metric := findAnalyticsMetric(w.Metric)
+		if metric == nil {
+			return fmt.Errorf("%w: unknown metric %q", ErrInvalidAnalyticsDashboard, w.Metric)
+		}
+		if w.Visualization == "" {
+			w.Visualization = metric.Visualizations[0]
+		} else if !containsString(metric.Visualizations, w.Visualization) {
+			return fmt.Errorf("%w: metric %q cannot be shown as %q", ErrInvalidAnalyticsDashboard, w.Metric, w.Visualization)
+		}
+		if w.X < 0 || w.Y < 0 || w.W < 1 || w.H < 1 || w.X+w.W > dashboardGridColumns || w.H > maxDashboardWidgetRows {
+			return fmt.Errorf("%w: widget %d does not fit the %d-column grid", ErrInvalidAnalyticsDashboard, i+1, dashboardGridColumns)
+		}
+		if err := validateDashboardFilters(w.Filters); err != nil {
+			return err
+		}
+		if w.ID == "" {
+			w.ID = uuid.New().String()
+		}
+		if seen[w.ID] {
+			return fmt.Errorf("%w: duplicate widget id %q", ErrInvalidAnalyticsDashboard, w.ID)
+		}
+		seen[w.ID] = true
+	}
+	return nil
+}
+
+// checkDashboardGroup verifies the user may share with groupID
+func (s *AnalyticsDashboardService) checkDashboardGroup(groupID, userID string) error {
+	if groupID == "" {
+		return nil
+	}
+	var member bool
+	err := s.PG.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM memberships
+			WHERE resource_type = 'group' AND resource_id = $1 AND user_id = $2
+		)
+	`, groupID, userID).Scan(&member)
+	if err != nil {
+		return fmt.Errorf("failed to check group membership: %w", err)
+	}
+	if !member {
+		return ErrDashboardGroupNotMember
+	}
+	return nil
+}
+
+const analyticsDashboardColumns = `
+	d.id, d.owner_id, COALESCE(u.name, u.email, ''), d.group_id, COALESCE(g.name, ''),
+	d.name, d.description, d.widgets, d.filters, d.created_at, d.updated_at
+`
+
+const analyticsDashboardJoins = `
+	LEFT JOIN users u ON u.id = d.owner_id
+	LEFT JOIN groups g ON g.id = d.group_id
+`
+
+// analyticsDashboardVisibleSQL limits dashboards (aliased d) to those owned
+// by, or shared with a group of, the user ($1)
+const analyticsDashboardVisibleSQL = `
+	(d.owner_id = $1 OR d.group_id IN (
+		SELECT resource_id FROM memberships WHERE resource_type = 'group' AND user_id = $1
+	))
+`
+
+func scanAnalyticsDashboard(row interface{ Scan(...interface{}) error }, userID string) (db.AnalyticsDashboard, error) {
+	var d db.AnalyticsDashboard
+	var groupID sql.NullString
+	var widgets, filters []byte
+	err := row.Scan(&d.ID, &d.OwnerID, &d.OwnerName, &groupID, &d.GroupName,
+		&d.Name, &d.Description, &widgets, &filters, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return d, err
+	}
+	d.GroupID = groupID.String
+	d.CanEdit = d.OwnerID == userID
+	d.Widgets = []db.DashboardWidget{}
+	d.Filters = map[string]interface{}{}
+	if len(widgets) > 0 {
+		json.Unmarshal(widgets, &d.Widgets)
+	}
+	if len(filters) > 0 {
+		json.Unmarshal(filters, &d.Filters)
+	}
+	return d, nil
+}
+
+// ListDashboards returns the user's own dashboards and those shared with
+// their groups, most recently updated first
+func (s *AnalyticsDashboardService) ListDashboards(userID string) ([]db.AnalyticsDashboard, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+analyticsDashboardColumns+`
+		FROM analytics_dashboards d
+		`+analyticsDashboardJoins+`
+		WHERE `+analyticsDashboardVisibleSQL+`
+		ORDER BY d.updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dashboards: %w", err)
+	}
+	defer rows.Close()
+
+	dashboards := []db.AnalyticsDashboard{}
+	for rows.Next() {
+		d, err := scanAnalyticsDashboard(rows, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard: %w", err)
+		}
+		dashboards = append(dashboards, d)
+	}
+	return dashboards, rows.Err()
+}
+
+// GetDashboard returns a dashboard the user can see. Dashboards they can't
+// see are reported as not found.
+func (s *AnalyticsDashboardService) GetDashboard(id, userID string) (*db.AnalyticsDashboard, error) {
+	d, err := scanAnalyticsDashboard(s.PG.QueryRow(`
+		SELECT `+analyticsDashboardColumns+`
+		FROM analytics_dashboards d
+		`+analyticsDashboardJoins+`
+		WHERE d.id = $2 AND `+analyticsDashboardVisibleSQL, userID, id), userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrAnalyticsDashboardNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dashboard: %w", err)
+	}
+	return &d, nil
+}
+
+// CreateDashboard saves a new dashboard owned by the user
+func (s *AnalyticsDashboardService) CreateDashboard(userID string, req db.CreateAnalyticsDashboardRequest) (*db.AnalyticsDashboard, error) {
+	if req.Widgets == nil {
+		req.Widgets = []db.DashboardWidget{}
+	}
+	if req.Filters == nil {
+		req.Filters = map[string]interface{}{}
+	}
+	if err := validateDashboardWidgets(req.Widgets); err != nil {
+		return nil, err
+	}
+	if err := validateDashboardFilters(req.Filters); err != nil {
+		return nil, err
+	}
+	if err := s.checkDashboardGroup(req.GroupID, userID); err != nil {
+		return nil, err
+	}
+
+	widgets, _ := json.Marshal(req.Widgets)
+	filters, _ := json.Marshal(req.Filters)
+	var id string
+	err := s.PG.QueryRow(`
+		INSERT INTO analytics_dashboards (owner_id, group_id, name, description, widgets, filters)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, userID, nullIfEmpty(req.GroupID), strings.TrimSpace(req.Name), req.Description, string(widgets), string(filters)).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dashboard: %w", err)
+	}
+	return s.GetDashboard(id, userID)
+}
+
+// UpdateDashboard changes a dashboard; only its owner may do so
+func (s *AnalyticsDashboardService) UpdateDashboard(id, userID string, req db.UpdateAnalyticsDashboardRequest) (*db.AnalyticsDashboard, error) {
+	current, err := s.GetDashboard(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.CanEdit {
+		return nil, ErrAnalyticsDashboardForbidden
+	}
+
+	sets := []string{"updated_at = NOW()"}
+	args := []interface{}{id}
+	add := func(expr string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf(expr, len(args)))
+	}
+	if req.Name != nil {
+		add("name = $%d", strings.TrimSpace(*req.Name))
+	}
+	if req.Description != nil {
+		add("description = $%d", *req.Description)
+	}
+	if req.GroupID != nil {
+		if err := s.checkDashboardGroup(*req.GroupID, userID); err != nil {
+			return nil, err
+		}
+		add("group_id = $%d", nullIfEmpty(*req.GroupID))
+	}
+	if req.Widgets != nil {
+		widgets := *req.Widgets
+		if widgets == nil {
+			widgets = []db.DashboardWidget{}
+		}
+		if err := validateDashboardWidgets(widgets); err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(widgets)
+		add("widgets = $%d", string(data))
+	}
+	if req.Filters != nil {
+		filters := *req.Filters
+		if filters == nil {
+			filters = map[string]interface{}{}
+		}
+		if err := validateDashboardFilters(filters); err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(filters)
+		add("filters = $%d", string(data))
+	}
+
+	if _, err := s.PG.Exec(`UPDATE analytics_dashboards SET `+strings.Join(sets, ", ")+` WHERE id = $1`, args...); err != nil {
+		return nil, fmt.Errorf("failed to update dashboard: %w", err)
+	}
+	return s.GetDashboard(id, userID)
+}
+
+// DeleteDashboard removes a dashboard; only its owner may do so
+func (s *AnalyticsDashboardService) DeleteDashboard(id, userID string) error {
+	current, err := s.GetDashboard(id, userID)
+	if err != nil {
+		return err
+	}
+	if !current.CanEdit {
+		return ErrAnalyticsDashboardForbidden
+	}
+	if _, err := s.PG.Exec(`DELETE FROM analytics_dashboards WHERE id = $1 AND owner_id = $2`, id, userID); err != nil {
+		return fmt.Errorf("failed to delete dashboard: %w", err)
+	}
+	return nil
+}
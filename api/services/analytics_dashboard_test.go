@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestValidateDashboardWidgets(t *testing.T) {
+	widgets := []db.DashboardWidget{
+		{Metric: "incidents.by_status", X: 0, Y: 0, W: 6, H: 4},
+		{Metric: "incidents.total", Visualization: "number", X: 6, Y: 0, W: 6, H: 2, Filters: map[string]interface{}{"urgency": "high"}},
+	}
+	if err := validateDashboardWidgets(widgets); err != nil {
+		t.Fatalf("valid widgets rejected: %v", err)
+	}
+	if widgets[0].ID == "" || widgets[0].ID == widgets[1].ID {
+		t.Errorf("widgets not given distinct ids: %q %q", widgets[0].ID, widgets[1].ID)
+	}
+	if widgets[0].Visualization != "bar" {
+		t.Errorf("default visualization = %q, want bar", widgets[0].Visualization)
+	}
+
+	invalid := map[string]db.DashboardWidget{
+		"unknown metric":        {Metric: "incidents.nope", W: 1, H: 1},
+		"unsupported chart":     {Metric: "incidents.total", Visualization: "pie", W: 1, H: 1},
+		"past the grid":         {Metric: "incidents.total", X: 8, W: 6, H: 1},
+		"zero width":            {Metric: "incidents.total", W: 0, H: 1},
+		"unknown widget filter": {Metric: "incidents.total", W: 1, H: 1, Filters: map[string]interface{}{"sql": "1=1"}},
+	}
+	for name, w := range invalid {
+		if err := validateDashboardWidgets([]db.DashboardWidget{w}); !errors.Is(err, ErrInvalidAnalyticsDashboard) {
+			t.Errorf("%s: err = %v, want ErrInvalidAnalyticsDashboard", name, err)
+		}
+	}
+}
+
+func TestUpdateDashboardRequiresOwner(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	// Shared with the user's group, but owned by someone else
+	mock.ExpectQuery(`FROM analytics_dashboards d`).WithArgs("user-1", "dash-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "owner_name", "group_id", "group_name",
+			"name", "description", "widgets", "filters", "created_at", "updated_at"}).
+			AddRow("dash-1", "user-2", "Lee", "group-1", "SRE", "Ops review", "", []byte(`[]`), []byte(`{}`), now, now))
+
+	service := NewAnalyticsDashboardService(pg)
+	name := "Mine now"
+	_, err = service.UpdateDashboard("dash-1", "user-1", db.UpdateAnalyticsDashboardRequest{Name: &name})
+	if !errors.Is(err, ErrAnalyticsDashboardForbidden) {
+		t.Errorf("err = %v, want ErrAnalyticsDashboardForbidden", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
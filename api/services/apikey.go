@@ -14,6 +14,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
 )
 
 type APIKeyService struct {
@@ -70,22 +71,34 @@ func (s *APIKeyService) CreateAPIKey(userID string, req *db.CreateAPIKeyRequest)
 
 	// Set default rate limits if not provided
 	rateLimitPerHour := req.RateLimitPerHour
+	if rateLimitPerHour == 0 {
+		rateLimitPerHour = config.Current().APIKeyRateLimitPerHour
+	}
 	if rateLimitPerHour == 0 {
 		rateLimitPerHour = 1000
 	}
 
 	rateLimitPerDay := req.RateLimitPerDay
+	if rateLimitPerDay == 0 {
+		rateLimitPerDay = config.Current().APIKeyRateLimitPerDay
+	}
 	if rateLimitPerDay == 0 {
 		rateLimitPerDay = 10000
 	}
 
+	// Keys for the test environment default to test mode
+	testMode := req.Environment == "test"
+	if req.TestMode != nil {
+		testMode = *req.TestMode
+	}
+
 	// Insert into database
 	query := `
 		INSERT INTO api_keys (
 			user_id, name, api_key, api_key_hash, permissions, 
 			description, environment, expires_at, 
-			rate_limit_per_hour, rate_limit_per_day, created_by
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			rate_limit_per_hour, rate_limit_per_day, created_by, test_mode
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
 
@@ -95,7 +108,7 @@ func (s *APIKeyService) CreateAPIKey(userID string, req *db.CreateAPIKeyRequest)
 		query,
 		userID, req.Name, apiKey, apiKeyHash, pq.Array(req.Permissions),
 		req.Description, req.Environment, req.ExpiresAt,
-		rateLimitPerHour, rateLimitPerDay, userID,
+		rateLimitPerHour, rateLimitPerDay, userID, testMode,
 	).Scan(&id, &createdAt)
 
 	if err != nil {
@@ -107,6 +120,7 @@ func (s *APIKeyService) CreateAPIKey(userID string, req *db.CreateAPIKeyRequest)
 		Name:        req.Name,
 		APIKey:      apiKey, // Only shown once
 		Environment: req.Environment,
+		TestMode:    testMode,
 		Permissions: req.Permissions,
 		CreatedAt:   createdAt,
 		ExpiresAt:   req.ExpiresAt,
@@ -120,7 +134,7 @@ func (s *APIKeyService) GetAPIKeyByKey(apiKey string) (*db.APIKey, error) {
 		SELECT id, user_id, name, api_key_hash, permissions, is_active,
 			   last_used_at, created_at, updated_at, expires_at,
 			   rate_limit_per_hour, rate_limit_per_day, total_requests,
-			   total_alerts_created, description, environment, created_by
+			   total_alerts_created, description, environment, created_by, test_mode
 		FROM api_keys 
 		WHERE api_key = $1
 	`
@@ -135,7 +149,7 @@ func (s *APIKeyService) GetAPIKeyByKey(apiKey string) (*db.APIKey, error) {
 		&key.IsActive, &lastUsedAt, &key.CreatedAt, &key.UpdatedAt,
 		&expiresAt, &key.RateLimitPerHour, &key.RateLimitPerDay,
 		&key.TotalRequests, &key.TotalAlertsCreated, &key.Description,
-		&key.Environment, &createdBy,
+		&key.Environment, &createdBy, &key.TestMode,
 	)
 
 	if err != nil {
@@ -196,31 +210,132 @@ func (s *APIKeyService) HasPermission(key *db.APIKey, permission db.Permission)
 	return false
 }
 
+// RateLimitError is returned by CheckRateLimit when an API key has used up one
+// of its quotas; ResetAt is when the exhausted window rolls over
+type RateLimitError struct {
+	Window  string
+	Limit   int
+	ResetAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%sly rate limit of %d requests exceeded", e.Window, e.Limit)
+}
+
+// rateLimitWindow returns the start and end of the rate limit window containing now
+func rateLimitWindow(now time.Time, windowType string) (time.Time, time.Time) {
+	if windowType == db.WindowTypeHour {
+		start := now.Truncate(time.Hour)
+		return start, start.Add(time.Hour)
+	}
+	start := now.Truncate(24 * time.Hour)
+	return start, start.Add(24 * time.Hour)
+}
+
 // CheckRateLimit checks if the API key has exceeded its rate limits
 func (s *APIKeyService) CheckRateLimit(apiKeyID string, key *db.APIKey) error {
 	now := time.Now()
 
-	// Check hourly limit
-	hourStart := now.Truncate(time.Hour)
-	hourlyCount, err := s.getRateLimitCount(apiKeyID, hourStart, db.WindowTypeHour)
+	windows := []struct {
+		windowType string
+		limit      int
+	}{
+		{db.WindowTypeHour, key.RateLimitPerHour},
+		{db.WindowTypeDay, key.RateLimitPerDay},
+	}
+	for _, window := range windows {
+		start, end := rateLimitWindow(now, window.windowType)
+		count, err := s.getRateLimitCount(apiKeyID, start, window.windowType)
+		if err != nil {
+			log.Printf("Error checking %sly rate limit: %v", window.windowType, err)
+			// Don't fail the request due to rate limit check error
+			continue
+		}
+		if count >= window.limit {
+			return &RateLimitError{Window: window.windowType, Limit: window.limit, ResetAt: end}
+		}
+	}
+
+	return nil
+}
+
+// RecordUsage counts an accepted API call against the key's quotas
+func (s *APIKeyService) RecordUsage(apiKeyID string) {
+	if err := s.UpdateLastUsed(apiKeyID); err != nil {
+		log.Printf("Error updating API key last used: %v", err)
+	}
+	if err := s.IncrementRateLimit(apiKeyID); err != nil {
+		log.Printf("Error incrementing rate limit: %v", err)
+	}
+}
+
+// GetAPIKeyUsage returns current quota usage and a per-day request series for
+// the last days days of an API key owned by userID
+func (s *APIKeyService) GetAPIKeyUsage(keyID, userID string, days int) (*db.APIKeyUsage, error) {
+	key, err := s.GetAPIKey(keyID, userID)
 	if err != nil {
-		log.Printf("Error checking hourly rate limit: %v", err)
-		// Don't fail the request due to rate limit check error
-	} else if hourlyCount >= key.RateLimitPerHour {
-		return fmt.Errorf("hourly rate limit of %d requests exceeded", key.RateLimitPerHour)
+		return nil, err
 	}
 
-	// Check daily limit
-	dayStart := now.Truncate(24 * time.Hour)
-	dailyCount, err := s.getRateLimitCount(apiKeyID, dayStart, db.WindowTypeDay)
+	now := time.Now()
+	usage := &db.APIKeyUsage{
+		APIKeyID:      key.ID,
+		Name:          key.Name,
+		UserID:        key.UserID,
+		TotalRequests: key.TotalRequests,
+		LastUsedAt:    key.LastUsedAt,
+	}
+
+	limits := map[string]int{db.WindowTypeHour: key.RateLimitPerHour, db.WindowTypeDay: key.RateLimitPerDay}
+	for _, windowType := range []string{db.WindowTypeHour, db.WindowTypeDay} {
+		start, end := rateLimitWindow(now, windowType)
+		used, err := s.getRateLimitCount(key.ID, start, windowType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %sly usage: %w", windowType, err)
+		}
+		remaining := limits[windowType] - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.Quotas = append(usage.Quotas, db.APIKeyQuota{
+			Window: windowType, Limit: limits[windowType], Used: used, Remaining: remaining, ResetAt: end,
+		})
+	}
+
+	today, _ := rateLimitWindow(now, db.WindowTypeDay)
+	from := today.AddDate(0, 0, -(days - 1))
+
+	rows, err := s.DB.Query(`
+		SELECT window_start, request_count
+		FROM api_key_rate_limits
+		WHERE api_key_id = $1 AND window_type = $2 AND window_start >= $3
+	`, key.ID, db.WindowTypeDay, from)
 	if err != nil {
-		log.Printf("Error checking daily rate limit: %v", err)
-		// Don't fail the request due to rate limit check error
-	} else if dailyCount >= key.RateLimitPerDay {
-		return fmt.Errorf("daily rate limit of %d requests exceeded", key.RateLimitPerDay)
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	counts := make(map[string]int)
+	for rows.Next() {
+		var windowStart time.Time
+		var count int
+		if err := rows.Scan(&windowStart, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan daily usage: %w", err)
+		}
+		counts[windowStart.UTC().Format("2006-01-02")] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+
+	// Zero-fill days without traffic so the series is continuous
+	usage.Daily = make([]db.APIKeyDailyUsage, 0, days)
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.UTC().Format("2006-01-02")
+		usage.Daily = append(usage.Daily, db.APIKeyDailyUsage{Date: date, Requests: counts[date]})
+	}
+
+	return usage, nil
 }
 
 // IncrementRateLimit increments the rate limit counters
@@ -286,7 +401,7 @@ func (s *APIKeyService) ListAPIKeys(userID string) ([]db.APIKey, error) {
 		SELECT id, user_id, name, permissions, is_active,
 			   last_used_at, created_at, updated_at, expires_at,
 			   rate_limit_per_hour, rate_limit_per_day, total_requests,
-			   total_alerts_created, description, environment, created_by
+			   total_alerts_created, description, environment, created_by, test_mode
 		FROM api_keys 
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -309,7 +424,7 @@ func (s *APIKeyService) ListAPIKeys(userID string) ([]db.APIKey, error) {
 			&key.ID, &key.UserID, &key.Name, &permissions, &key.IsActive,
 			&lastUsedAt, &key.CreatedAt, &key.UpdatedAt, &expiresAt,
 			&key.RateLimitPerHour, &key.RateLimitPerDay, &key.TotalRequests,
-			&key.TotalAlertsCreated, &key.Description, &key.Environment, &createdBy,
+			&key.TotalAlertsCreated, &key.Description, &key.Environment, &createdBy, &key.TestMode,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
@@ -339,7 +454,7 @@ func (s *APIKeyService) GetAPIKey(keyID, userID string) (*db.APIKey, error) {
 		SELECT id, user_id, name, permissions, is_active,
 			   last_used_at, created_at, updated_at, expires_at,
 			   rate_limit_per_hour, rate_limit_per_day, total_requests,
-			   total_alerts_created, description, environment, created_by
+			   total_alerts_created, description, environment, created_by, test_mode
 		FROM api_keys 
 		WHERE id = $1 AND user_id = $2
 	`
@@ -353,7 +468,7 @@ func (s *APIKeyService) GetAPIKey(keyID, userID string) (*db.APIKey, error) {
 		&key.ID, &key.UserID, &key.Name, &permissions, &key.IsActive,
 		&lastUsedAt, &key.CreatedAt, &key.UpdatedAt, &expiresAt,
 		&key.RateLimitPerHour, &key.RateLimitPerDay, &key.TotalRequests,
-		&key.TotalAlertsCreated, &key.Description, &key.Environment, &createdBy,
+		&key.TotalAlertsCreated, &key.Description, &key.Environment, &createdBy, &key.TestMode,
 	)
 
 	if err != nil {
@@ -430,6 +545,12 @@ func (s *APIKeyService) UpdateAPIKey(keyID, userID string, req *db.UpdateAPIKeyR
 		argIndex++
 	}
 
+	if req.TestMode != nil {
+		setParts = append(setParts, fmt.Sprintf("test_mode = $%d", argIndex))
+		args = append(args, *req.TestMode)
+		argIndex++
+	}
+
 	if len(setParts) == 0 {
 		return errors.New("no fields to update")
 	}
@@ -539,6 +660,65 @@ func (s *APIKeyService) RegenerateAPIKey(keyID, userID string) (*db.CreateAPIKey
 	return response, nil
 }
 
+// RotateOrgAPIKeys regenerates every active API key of an organization. The
+// new keys are only returned here, so the caller must hand them out.
+func (s *APIKeyService) RotateOrgAPIKeys(orgID string) ([]db.CreateAPIKeyResponse, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, environment FROM api_keys
+		WHERE organization_id = $1 AND is_active = true
+		ORDER BY created_at ASC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	type orgKey struct{ id, environment string }
+	var keys []orgKey
+	for rows.Next() {
+		var k orgKey
+		if err := rows.Scan(&k.id, &k.environment); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	rotated := []db.CreateAPIKeyResponse{}
+	for _, k := range keys {
+		newAPIKey, err := s.GenerateAPIKey(k.environment)
+		if err != nil {
+			return rotated, err
+		}
+		newAPIKeyHash, err := s.HashAPIKey(newAPIKey)
+		if err != nil {
+			return rotated, err
+		}
+
+		response := db.CreateAPIKeyResponse{ID: k.id, APIKey: newAPIKey, Environment: k.environment}
+		var permissions pq.StringArray
+		var expiresAt sql.NullTime
+		err = s.DB.QueryRow(`
+			UPDATE api_keys
+			SET api_key = $1, api_key_hash = $2, updated_at = NOW()
+			WHERE id = $3
+			RETURNING name, permissions, created_at, expires_at
+		`, newAPIKey, newAPIKeyHash, k.id).Scan(&response.Name, &permissions, &response.CreatedAt, &expiresAt)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to rotate API key %s: %w", k.id, err)
+		}
+		response.Permissions = []string(permissions)
+		if expiresAt.Valid {
+			response.ExpiresAt = &expiresAt.Time
+		}
+		response.Message = "API key rotated. Please save it securely as it won't be shown again."
+		rotated = append(rotated, response)
+	}
+	return rotated, nil
+}
+
 // GetAPIKeyStats gets statistics for API keys
 func (s *APIKeyService) GetAPIKeyStats(userID string) ([]db.APIKeyStats, error) {
 	query := `
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestCheckRateLimitReturnsResetTime(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM api_key_rate_limits`).WithArgs("key-1", sqlmock.AnyArg(), db.WindowTypeHour).
+		WillReturnRows(sqlmock.NewRows([]string{"request_count"}).AddRow(10))
+	mock.ExpectQuery(`FROM api_key_rate_limits`).WithArgs("key-1", sqlmock.AnyArg(), db.WindowTypeDay).
+		WillReturnRows(sqlmock.NewRows([]string{"request_count"}).AddRow(50))
+
+	key := &db.APIKey{ID: "key-1", RateLimitPerHour: 100, RateLimitPerDay: 50}
+	err = NewAPIKeyService(pg).CheckRateLimit(key.ID, key)
+
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("err = %v, want *RateLimitError", err)
+	}
+	if rateErr.Window != db.WindowTypeDay || rateErr.Limit != 50 {
+		t.Errorf("got %s limit %d, want day limit 50", rateErr.Window, rateErr.Limit)
+	}
+	wantReset := time.Now().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if !rateErr.ResetAt.Equal(wantReset) {
+		t.Errorf("ResetAt = %v, want %v", rateErr.ResetAt, wantReset)
+	}
+}
+
+func TestRateLimitWindow(t *testing.T) {
+	now := time.Date(2026, 3, 2, 14, 35, 0, 0, time.UTC)
+
+	start, end := rateLimitWindow(now, db.WindowTypeHour)
+	if !start.Equal(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)) || !end.Equal(start.Add(time.Hour)) {
+		t.Errorf("hour window = %v..%v", start, end)
+	}
+
+	start, end = rateLimitWindow(now, db.WindowTypeDay)
+	if !start.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day window = %v..%v", start, end)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrUnavailabilityNotFound = errors.New("unavailability not found")
+	ErrInvalidUnavailability  = errors.New("invalid unavailability")
+)
+
+// AvailabilityService manages the periods users are away or must not be paged
+type AvailabilityService struct {
+	PG *sql.DB
+}
+
+func NewAvailabilityService(pg *sql.DB) *AvailabilityService {
+	return &AvailabilityService{PG: pg}
+}
+
+// ListUnavailability returns the user's current and future unavailability,
+// soonest first
+func (s *AvailabilityService) ListUnavailability(userID string) ([]db.UserUnavailability, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, user_id, kind, starts_at, ends_at, reason, created_at
+		FROM user_unavailability
+		WHERE user_id = $1 AND ends_at > NOW()
+		ORDER BY starts_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unavailability: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []db.UserUnavailability{}
+	for rows.Next() {
+		var p db.UserUnavailability
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Kind, &p.StartsAt, &p.EndsAt, &p.Reason, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan unavailability: %w", err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// CreateUnavailability marks the user unavailable for a period
+func (s *AvailabilityService) CreateUnavailability(userID string, req db.CreateUnavailabilityRequest) (*db.UserUnavailability, error) {
+	if req.Kind == "" {
+		req.Kind = db.UnavailabilityVacation
+	}
+	if req.Kind != db.UnavailabilityVacation && req.Kind != db.UnavailabilityDND {
+		return nil, fmt.Errorf("%w: kind must be vacation or dnd", ErrInvalidUnavailability)
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidUnavailability)
+	}
+	if !req.EndsAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: ends_at must be in the future", ErrInvalidUnavailability)
+	}
+
+	p := db.UserUnavailability{UserID: userID, Kind: req.Kind, StartsAt: req.StartsAt, EndsAt: req.EndsAt, Reason: req.Reason}
+	err := s.PG.QueryRow(`
+		INSERT INTO user_unavailability (user_id, kind, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, userID, p.Kind, p.StartsAt, p.EndsAt, p.Reason).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create unavailability: %w", err)
+	}
+	return &p, nil
+}
+
+// DeleteUnavailability removes one of the user's periods
+func (s *AvailabilityService) DeleteUnavailability(userID, id string) error {
+	result, err := s.PG.Exec(`DELETE FROM user_unavailability WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete unavailability: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUnavailabilityNotFound
+	}
+	return nil
+}
+
+// onCallCandidate is someone on call for an escalation target. Depth is how
+// far down the team hierarchy their group is; schedulers are always 0.
+type onCallCandidate struct {
+	UserID    string
+	Depth     int
+	Available bool
+}
+
+// pickOnCallResponders walks the candidates in order and returns who the
+// target pages, passing over anyone unavailable: the first available
+// candidate, or with all every available one at the nearest depth that has
+// one. skipped lists the unavailable candidates passed over on the way.
+func pickOnCallResponders(candidates []onCallCandidate, all bool) (users, skipped []string) {
+	depth := -1
+	for _, c := range candidates {
+		if depth >= 0 && c.Depth != depth {
+			break
+		}
+		if !c.Available {
+			if !containsString(skipped, c.UserID) {
+				skipped = append(skipped, c.UserID)
+			}
+			continue
+		}
+		if !containsString(users, c.UserID) {
+			users = append(users, c.UserID)
+		}
+		depth = c.Depth
+		if !all {
+			break
+		}
+	}
+	return users, skipped
+}
+
+// describeSkippedResponders looks up why each skipped user is unavailable now
+func describeSkippedResponders(pg *sql.DB, userIDs []string) ([]db.SkippedResponder, error) {
+	skipped := []db.SkippedResponder{}
+	if len(userIDs) == 0 {
+		return skipped, nil
+	}
+	rows, err := pg.Query(`
+		SELECT DISTINCT ON (ua.user_id) ua.user_id::text, ua.kind, ua.ends_at, COALESCE(u.name, '')
+		FROM user_unavailability ua
+		LEFT JOIN users u ON u.id = ua.user_id
+		WHERE ua.user_id::text = ANY($1) AND ua.starts_at <= NOW() AND ua.ends_at > NOW()
+		ORDER BY ua.user_id, ua.ends_at DESC
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unavailability: %w", err)
+	}
+	defer rows.Close()
+
+	byUser := map[string]db.SkippedResponder{}
+	for rows.Next() {
+		var r db.SkippedResponder
+		if err := rows.Scan(&r.UserID, &r.Kind, &r.Until, &r.UserName); err != nil {
+			return nil, fmt.Errorf("failed to scan unavailability: %w", err)
+		}
+		byUser[r.UserID] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get unavailability: %w", err)
+	}
+	// Keep the order they were skipped in
+	for _, id := range userIDs {
+		if r, ok := byUser[id]; ok {
+			skipped = append(skipped, r)
+		}
+	}
+	return skipped, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestPickOnCallResponders(t *testing.T) {
+	candidates := []onCallCandidate{
+		{UserID: "alice", Depth: 0, Available: false},
+		{UserID: "bob", Depth: 0, Available: true},
+		{UserID: "carol", Depth: 0, Available: true},
+		{UserID: "dave", Depth: 1, Available: true},
+	}
+	users, skipped := pickOnCallResponders(candidates, false)
+	if !reflect.DeepEqual(users, []string{"bob"}) || !reflect.DeepEqual(skipped, []string{"alice"}) {
+		t.Errorf("first: users = %v, skipped = %v", users, skipped)
+	}
+	users, _ = pickOnCallResponders(candidates, true)
+	if !reflect.DeepEqual(users, []string{"bob", "carol"}) {
+		t.Errorf("all: users = %v, want the nearest depth only", users)
+	}
+
+	// Everyone in the group is away, so its sub-team covers
+	users, skipped = pickOnCallResponders([]onCallCandidate{
+		{UserID: "alice", Depth: 0},
+		{UserID: "bob", Depth: 0},
+		{UserID: "dave", Depth: 1, Available: true},
+	}, true)
+	if !reflect.DeepEqual(users, []string{"dave"}) || !reflect.DeepEqual(skipped, []string{"alice", "bob"}) {
+		t.Errorf("fallback: users = %v, skipped = %v", users, skipped)
+	}
+}
+
+func TestResolveEscalationLevelUsersSkipsUnavailable(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	until := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`FROM effective_shifts\s+WHERE scheduler_id = \$1`).WithArgs("sch-1", "grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"effective_user_id", "depth", "available"}).
+			AddRow("alice", 0, false).AddRow("bob", 0, true))
+	mock.ExpectQuery(`FROM user_unavailability`).WithArgs(`{"alice"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "kind", "ends_at", "name"}).
+			AddRow("alice", db.UnavailabilityVacation, until, "Alice"))
+
+	users, skipped, err := ResolveEscalationLevelUsers(pg, "grp-1", []db.EscalationLevel{{TargetType: "scheduler", TargetID: "sch-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(users, []string{"bob"}) {
+		t.Errorf("users = %v", users)
+	}
+	if len(skipped) != 1 || skipped[0].UserName != "Alice" || skipped[0].Kind != db.UnavailabilityVacation || !skipped[0].Until.Equal(until) {
+		t.Errorf("skipped = %+v", skipped)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateUnavailabilityValidates(t *testing.T) {
+	s := NewAvailabilityService(nil)
+	start := time.Now().Add(time.Hour)
+	for name, req := range map[string]db.CreateUnavailabilityRequest{
+		"kind":  {Kind: "sick", StartsAt: start, EndsAt: start.Add(time.Hour)},
+		"order": {StartsAt: start, EndsAt: start},
+		"past":  {StartsAt: start.Add(-72 * time.Hour), EndsAt: start.Add(-48 * time.Hour)},
+	} {
+		if _, err := s.CreateUnavailability("user-1", req); !errors.Is(err, ErrInvalidUnavailability) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
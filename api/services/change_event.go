@@ -0,0 +1,160 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+const changeEventSelect = `
+	SELECT ce.id, ce.organization_id, COALESCE(ce.project_id::text, ''), COALESCE(ce.service_id::text, ''),
+	       COALESCE(s.name, ''), ce.type, ce.source, ce.summary, COALESCE(ce.url, ''), COALESCE(ce.version, ''),
+	       COALESCE(ce.environment, ''), COALESCE(ce.author, ''), ce.custom_details, ce.occurred_at, ce.created_at
+	FROM change_events ce
+	LEFT JOIN services s ON s.id = ce.service_id
+`
+
+func scanChangeEvents(rows *sql.Rows) ([]db.ChangeEvent, error) {
+	defer rows.Close()
+
+	changes := []db.ChangeEvent{}
+	for rows.Next() {
+		var ce db.ChangeEvent
+		var customDetails []byte
+		if err := rows.Scan(&ce.ID, &ce.OrganizationID, &ce.ProjectID, &ce.ServiceID, &ce.ServiceName,
+			&ce.Type, &ce.Source, &ce.Summary, &ce.URL, &ce.Version, &ce.Environment, &ce.Author,
+			&customDetails, &ce.OccurredAt, &ce.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan change event: %w", err)
+		}
+		if len(customDetails) > 0 {
+			if err := json.Unmarshal(customDetails, &ce.CustomDetails); err != nil {
+				return nil, fmt.Errorf("failed to decode custom details of change event %s: %w", ce.ID, err)
+			}
+		}
+		changes = append(changes, ce)
+	}
+	return changes, rows.Err()
+}
+
+// listRecentChanges returns the changes in the correlation window before at,
+// newest first: the service's changes, or the project's when there is no service
+func listRecentChanges(pg *sql.DB, serviceID, projectID string, at time.Time) ([]db.ChangeEvent, error) {
+	if serviceID == "" && projectID == "" {
+		return []db.ChangeEvent{}, nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	scope := `ce.service_id = $1`
+	scopeID := serviceID
+	if serviceID == "" {
+		scope = `ce.project_id = $1`
+		scopeID = projectID
+	}
+
+	rows, err := pg.Query(changeEventSelect+`
+		WHERE `+scope+` AND ce.occurred_at BETWEEN $2 AND $3
+		ORDER BY ce.occurred_at DESC
+		LIMIT 20
+	`, scopeID, at.Add(-db.ChangeEventCorrelationWindow), at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent changes: %w", err)
+	}
+	return scanChangeEvents(rows)
+}
+
+// CreateChangeEvent stores a change reported for a service
+func (s *IncidentService) CreateChangeEvent(service *db.Service, payload db.WebhookChangeEventPayload) (*db.ChangeEvent, error) {
+	ce := &db.ChangeEvent{
+		OrganizationID: service.OrganizationID,
+		ProjectID:      service.ProjectID,
+		ServiceID:      service.ID,
+		ServiceName:    service.Name,
+		Type:           payload.Type,
+		Source:         payload.Source,
+		Summary:        payload.Summary,
+		URL:            payload.URL,
+		Version:        payload.Version,
+		Environment:    payload.Environment,
+		Author:         payload.Author,
+		CustomDetails:  payload.CustomDetails,
+		OccurredAt:     time.Now(),
+	}
+	if ce.Type == "" {
+		ce.Type = db.ChangeEventTypeOther
+	}
+	if payload.Timestamp != nil {
+		ce.OccurredAt = *payload.Timestamp
+	}
+
+	var customDetails interface{}
+	if ce.CustomDetails != nil {
+		b, _ := json.Marshal(ce.CustomDetails)
+		customDetails = string(b)
+	}
+
+	err := s.PG.QueryRow(`
+		INSERT INTO change_events (organization_id, project_id, service_id, type, source, summary,
+		                           url, version, environment, author, custom_details, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at
+	`, ce.OrganizationID, nullIfEmpty(ce.ProjectID), ce.ServiceID, ce.Type, ce.Source, ce.Summary,
+		nullIfEmpty(ce.URL), nullIfEmpty(ce.Version), nullIfEmpty(ce.Environment), nullIfEmpty(ce.Author),
+		customDetails, ce.OccurredAt).Scan(&ce.ID, &ce.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create change event: %w", err)
+	}
+	return ce, nil
+}
+
+// RecordDeployChangeEvents stores a successful deploy as a change event on every
+// service linked to the deploy integration
+func (s *IncidentService) RecordDeployChangeEvents(integrationID string, event db.DeployEvent) error {
+	if !event.Succeeded() {
+		return nil
+	}
+
+	summary := fmt.Sprintf("Deployed %s", deployLabel(event))
+	if event.Repository != "" {
+		summary = fmt.Sprintf("Deployed %s %s", event.Repository, deployLabel(event))
+	}
+	_, err := s.PG.Exec(`
+		INSERT INTO change_events (organization_id, project_id, service_id, type, source, summary,
+		                           url, version, environment, author)
+		SELECT sv.organization_id, sv.project_id, sv.id, $2, $3, $4, $5, $6, $7, $8
+		FROM service_integrations si
+		JOIN services sv ON sv.id = si.service_id
+		WHERE si.integration_id = $1 AND si.is_active = TRUE AND sv.organization_id IS NOT NULL
+	`, integrationID, db.ChangeEventTypeDeploy, event.Provider, summary, nullIfEmpty(event.URL),
+		nullIfEmpty(event.Version), nullIfEmpty(event.Environment), nullIfEmpty(event.DeployedBy))
+	if err != nil {
+		return fmt.Errorf("failed to record deploy change events: %w", err)
+	}
+	return nil
+}
+
+// ListServiceChangeEvents returns a service's changes in the organization since
+// the given time, newest first
+func (s *IncidentService) ListServiceChangeEvents(orgID, serviceID string, since time.Time, limit int) ([]db.ChangeEvent, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := s.PG.Query(changeEventSelect+`
+		WHERE ce.organization_id = $1 AND ce.service_id = $2 AND ce.occurred_at >= $3
+		ORDER BY ce.occurred_at DESC
+		LIMIT $4
+	`, orgID, serviceID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list change events: %w", err)
+	}
+	return scanChangeEvents(rows)
+}
+
+// GetIncidentChanges returns the changes in the correlation window before the incident started
+func (s *IncidentService) GetIncidentChanges(incident *db.Incident) ([]db.ChangeEvent, error) {
+	return listRecentChanges(s.PG, incident.ServiceID, incident.ProjectID, incident.CreatedAt)
+}
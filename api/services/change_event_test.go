@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var changeEventColumns = []string{"id", "organization_id", "project_id", "service_id", "service_name", "type", "source",
+	"summary", "url", "version", "environment", "author", "custom_details", "occurred_at", "created_at"}
+
+func TestListRecentChangesScope(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	windowStart := started.Add(-db.ChangeEventCorrelationWindow)
+
+	// Incidents with a service only see that service's changes
+	mock.ExpectQuery(`WHERE ce.service_id = \$1 AND ce.occurred_at BETWEEN`).WithArgs("svc-1", windowStart, started).
+		WillReturnRows(sqlmock.NewRows(changeEventColumns).
+			AddRow("ce-1", "org-1", "proj-1", "svc-1", "checkout", "deploy", "github", "Deployed v2.4.1",
+				"", "v2.4.1", "production", "octocat", []byte(`{"pr": 42}`), started.Add(-30*time.Minute), started))
+	changes, err := listRecentChanges(pg, "svc-1", "proj-1", started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].ServiceName != "checkout" || changes[0].CustomDetails["pr"] != float64(42) {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	// Without a service, fall back to the project
+	mock.ExpectQuery(`WHERE ce.project_id = \$1 AND ce.occurred_at BETWEEN`).WithArgs("proj-1", windowStart, started).
+		WillReturnRows(sqlmock.NewRows(changeEventColumns))
+	if _, err := listRecentChanges(pg, "", "proj-1", started); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing to correlate against: no query
+	if changes, err := listRecentChanges(pg, "", "", started); err != nil || len(changes) != 0 {
+		t.Errorf("unscoped: changes = %v, err = %v", changes, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateChangeEventDefaults(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	service := &db.Service{ID: "svc-1", Name: "checkout", OrganizationID: "org-1", ProjectID: "proj-1"}
+	mock.ExpectQuery(`INSERT INTO change_events`).
+		WithArgs("org-1", "proj-1", "svc-1", db.ChangeEventTypeOther, "launchdarkly", "Enabled new-checkout flag",
+			nil, nil, "production", nil, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("ce-1", time.Now()))
+
+	change, err := NewIncidentService(pg, nil).CreateChangeEvent(service, db.WebhookChangeEventPayload{
+		Summary: "Enabled new-checkout flag", Source: "launchdarkly", Environment: "production",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.ID != "ce-1" || change.Type != db.ChangeEventTypeOther || change.OccurredAt.IsZero() {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListServiceChangeEventsScopesToOrg(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WHERE ce.organization_id = \$1 AND ce.service_id = \$2`).WithArgs("org-1", "svc-1", since, 50).
+		WillReturnRows(sqlmock.NewRows(changeEventColumns).
+			AddRow("ce-1", "org-1", "", "svc-1", "checkout", "deploy", "github", "Deployed v2.4.1",
+				"", "", "", "", []byte(`not json`), since, since))
+
+	_, err = NewIncidentService(pg, nil).ListServiceChangeEvents("org-1", "svc-1", since, 0)
+	if err == nil {
+		t.Error("expected malformed custom_details to be reported")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,840 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/notify"
+)
+
+// Sentinel errors for group chat channels and acknowledge links (mapped to HTTP status in handlers)
+var (
+	ErrChatChannelNotFound  = errors.New("chat channel not found")
+	ErrInvalidChatChannel   = errors.New("invalid chat channel")
+	ErrAckLinkNotFound      = errors.New("acknowledge link not found")
+	ErrAckLinkExpired       = errors.New("acknowledge link has expired")
+	ErrAckLinkUsed          = errors.New("acknowledge link has already been used")
+	ErrIncidentNotTriggered = errors.New("incident is no longer triggered")
+)
+
+const (
+	chatNotificationQueue = "chat_notifications"
+	chatSendTimeout       = 15 * time.Second
+	ackLinkTTL            = 24 * time.Hour
+)
+
+var (
+	discordWebhookPattern    = regexp.MustCompile(`^https://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/api/webhooks/\d+/[\w-]+$`)
+	telegramTokenPattern     = regexp.MustCompile(`^\d+:[\w-]+$`)
+	telegramChatIDPattern    = regexp.MustCompile(`^(?:-?\d+|@\w{5,})$`)
+	googleChatWebhookPattern = regexp.MustCompile(`^https://chat\.googleapis\.com/v1/spaces/[\w-]+/messages\?\S+$`)
+
+	// telegramAPIBaseURL is overridden in tests
+	telegramAPIBaseURL = "https://api.telegram.org"
+)
+
+// ChatChannelService manages per-group chat channels and delivers incident
+// notifications to them through the channel types registered with notify
+type ChatChannelService struct {
+	PG *sql.DB
+}
+
+func NewChatChannelService(pg *sql.DB) *ChatChannelService {
+	return &ChatChannelService{PG: pg}
+}
+
+// CHANNELS
+
+// ListChannels returns a group's chat channels (without their secrets)
+func (s *ChatChannelService) ListChannels(groupID string) ([]db.GroupChatChannel, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, group_id, channel_type, name, COALESCE(chat_id, ''), COALESCE(config, ''), is_active,
+		       COALESCE(created_by, ''), created_at, updated_at
+		FROM group_chat_channels
+		WHERE group_id = $1
+		ORDER BY created_at ASC
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []db.GroupChatChannel{}
+	for rows.Next() {
+		var ch db.GroupChatChannel
+		var config string
+		if err := rows.Scan(&ch.ID, &ch.GroupID, &ch.ChannelType, &ch.Name, &ch.ChatID, &config, &ch.IsActive,
+			&ch.CreatedBy, &ch.CreatedAt, &ch.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat channel: %w", err)
+		}
+		ch.Config = decodeChatChannelConfig(config)
+		setPublicChatChannelConfig(&ch)
+		channels = append(channels, ch)
+	}
+	return channels, rows.Err()
+}
+
+// CreateChannel adds a chat channel of any registered type to a group
+func (s *ChatChannelService) CreateChannel(groupID string, req db.CreateGroupChatChannelRequest, createdBy string) (*db.GroupChatChannel, error) {
+	ch := db.GroupChatChannel{
+		GroupID:     groupID,
+		ChannelType: req.ChannelType,
+		Name:        strings.TrimSpace(req.Name),
+		WebhookURL:  strings.TrimSpace(req.WebhookURL),
+		BotToken:    strings.TrimSpace(req.BotToken),
+		ChatID:      strings.TrimSpace(req.ChatID),
+		Config:      req.Config,
+		IsActive:    true,
+		CreatedBy:   createdBy,
+	}
+	if err := validateChatChannel(&ch); err != nil {
+		return nil, err
+	}
+
+	webhookURL, botToken, config, err := encryptChatChannelSecrets(ch)
+	if err != nil {
+		return nil, err
+	}
+	err = s.PG.QueryRow(`
+		INSERT INTO group_chat_channels (group_id, channel_type, name, webhook_url, bot_token, chat_id, config, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`, groupID, ch.ChannelType, ch.Name, webhookURL, botToken, nullIfEmpty(ch.ChatID), config, nullIfEmpty(createdBy)).
+		Scan(&ch.ID, &ch.CreatedAt, &ch.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat channel: %w", err)
+	}
+	setPublicChatChannelConfig(&ch)
+	return &ch, nil
+}
+
+// UpdateChannel changes a group's chat channel. Secrets left empty are kept.
+func (s *ChatChannelService) UpdateChannel(groupID, channelID string, req db.UpdateGroupChatChannelRequest) (*db.GroupChatChannel, error) {
+	ch, err := s.getChannel(groupID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		ch.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.WebhookURL != nil && strings.TrimSpace(*req.WebhookURL) != "" {
+		ch.WebhookURL = strings.TrimSpace(*req.WebhookURL)
+	}
+	if req.BotToken != nil && strings.TrimSpace(*req.BotToken) != "" {
+		ch.BotToken = strings.TrimSpace(*req.BotToken)
+	}
+	if req.ChatID != nil {
+		ch.ChatID = strings.TrimSpace(*req.ChatID)
+	}
+	if len(req.Config) > 0 {
+		merged := chatChannelConfig(*ch)
+		channel, _ := notify.Lookup(ch.ChannelType)
+		for key, value := range req.Config {
+			if strings.TrimSpace(value) == "" && channel != nil && notify.IsSecret(channel, key) {
+				continue
+			}
+			merged[key] = value
+		}
+		ch.WebhookURL, ch.BotToken, ch.ChatID, ch.Config = "", "", "", merged
+	}
+	if req.IsActive != nil {
+		ch.IsActive = *req.IsActive
+	}
+	if err := validateChatChannel(ch); err != nil {
+		return nil, err
+	}
+
+	webhookURL, botToken, config, err := encryptChatChannelSecrets(*ch)
+	if err != nil {
+		return nil, err
+	}
+	err = s.PG.QueryRow(`
+		UPDATE group_chat_channels
+		SET name = $3, webhook_url = $4, bot_token = $5, chat_id = $6, config = $7, is_active = $8, updated_at = NOW()
+		WHERE id = $1 AND group_id = $2
+		RETURNING updated_at
+	`, channelID, groupID, ch.Name, webhookURL, botToken, nullIfEmpty(ch.ChatID), config, ch.IsActive).Scan(&ch.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update chat channel: %w", err)
+	}
+	setPublicChatChannelConfig(ch)
+	return ch, nil
+}
+
+// DeleteChannel removes a chat channel from a group
+func (s *ChatChannelService) DeleteChannel(groupID, channelID string) error {
+	result, err := s.PG.Exec(`DELETE FROM group_chat_channels WHERE id = $1 AND group_id = $2`, channelID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat channel: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrChatChannelNotFound
+	}
+	return nil
+}
+
+// SendTestMessage posts a test message so admins can check the channel is wired up
+func (s *ChatChannelService) SendTestMessage(groupID, channelID string) error {
+	ch, err := s.getChannel(groupID, channelID)
+	if err != nil {
+		return err
+	}
+	return s.send(*ch, notify.Message{
+		Event:    "test",
+		Headline: "✅ SLAR test message",
+		Title:    "Notifications for this group will be posted here",
+		Color:    chatColorResolved,
+	})
+}
+
+// getChannel loads a group's chat channel with its secrets decrypted
+func (s *ChatChannelService) getChannel(groupID, channelID string) (*db.GroupChatChannel, error) {
+	var ch db.GroupChatChannel
+	var config string
+	err := s.PG.QueryRow(`
+		SELECT id, group_id, channel_type, name, COALESCE(webhook_url, ''), COALESCE(bot_token, ''),
+		       COALESCE(chat_id, ''), COALESCE(config, ''), is_active, COALESCE(created_by, ''), created_at, updated_at
+		FROM group_chat_channels
+		WHERE id = $1 AND group_id = $2
+	`, channelID, groupID).Scan(&ch.ID, &ch.GroupID, &ch.ChannelType, &ch.Name, &ch.WebhookURL, &ch.BotToken,
+		&ch.ChatID, &config, &ch.IsActive, &ch.CreatedBy, &ch.CreatedAt, &ch.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrChatChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat channel: %w", err)
+	}
+	decryptColumns(&ch.WebhookURL, &ch.BotToken, &config)
+	ch.Config = decodeChatChannelConfig(config)
+	setPublicChatChannelConfig(&ch)
+	return &ch, nil
+}
+
+// chatChannelColumns are settings stored in their own group_chat_channels
+// columns; any other setting of a channel type lives in the config column
+var chatChannelColumns = []string{"webhook_url", "bot_token", "chat_id"}
+
+// chatChannelConfig returns a channel's settings keyed by its config schema
+func chatChannelConfig(ch db.GroupChatChannel) notify.Config {
+	cfg := notify.Config{}
+	for key, value := range ch.Config {
+		cfg[key] = value
+	}
+	for key, value := range map[string]string{"webhook_url": ch.WebhookURL, "bot_token": ch.BotToken, "chat_id": ch.ChatID} {
+		if value != "" {
+			cfg[key] = value
+		}
+	}
+	return cfg
+}
+
+// validateChatChannel checks a channel's settings against its type's schema
+// and clears any setting the type doesn't use
+func validateChatChannel(ch *db.GroupChatChannel) error {
+	if ch.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidChatChannel)
+	}
+	channel, ok := notify.Lookup(ch.ChannelType)
+	if !ok {
+		return fmt.Errorf("%w: channel_type must be one of %s", ErrInvalidChatChannel, strings.Join(notify.Types(), ", "))
+	}
+	cfg, err := notify.ValidateConfig(channel, chatChannelConfig(*ch))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidChatChannel, err)
+	}
+
+	ch.WebhookURL, ch.BotToken, ch.ChatID = cfg["webhook_url"], cfg["bot_token"], cfg["chat_id"]
+	for _, key := range chatChannelColumns {
+		delete(cfg, key)
+	}
+	ch.Config = cfg
+	return nil
+}
+
+// setPublicChatChannelConfig exposes a channel's non-secret settings
+func setPublicChatChannelConfig(ch *db.GroupChatChannel) {
+	channel, ok := notify.Lookup(ch.ChannelType)
+	if !ok {
+		return
+	}
+	cfg := chatChannelConfig(*ch)
+	public := map[string]string{}
+	for _, f := range channel.ConfigSchema() {
+		if !f.Secret && cfg[f.Key] != "" {
+			public[f.Key] = cfg[f.Key]
+		}
+	}
+	if len(public) > 0 {
+		ch.PublicConfig = public
+	}
+}
+
+// encryptChatChannelSecrets seals the webhook URL, bot token and the other
+// settings (stored as one JSON document) for storage
+func encryptChatChannelSecrets(ch db.GroupChatChannel) (interface{}, interface{}, interface{}, error) {
+	webhookURL, err := encryptColumn(ch.WebhookURL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	botToken, err := encryptColumn(ch.BotToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	config := ""
+	if len(ch.Config) > 0 {
+		raw, err := json.Marshal(ch.Config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if config, err = encryptColumn(string(raw)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return nullIfEmpty(webhookURL), nullIfEmpty(botToken), nullIfEmpty(config), nil
+}
+
+// decodeChatChannelConfig parses a decrypted config column
+func decodeChatChannelConfig(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	var cfg map[string]string
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		log.Printf("⚠️  Ignoring unreadable chat channel config: %v", err)
+		return nil
+	}
+	return cfg
+}
+
+// NOTIFICATIONS
+
+// EnqueueChatNotification queues an incident notification for the incident's
+// group chat channels. Nothing is queued when the group has no active channel,
+// the incident is a test incident or its commander turned the update off for
+// chat (see db.IncidentNotificationSettings).
+func EnqueueChatNotification(pg *sql.DB, userID, incidentID, notificationType string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_id":     userID,
+		"incident_id": incidentID,
+		"type":        notificationType,
+		"created_at":  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat notification: %w", err)
+	}
+
+	_, err = pg.Exec(`
+		SELECT pgmq.send($1, $2)
+		WHERE EXISTS (
+			SELECT 1 FROM incidents i
+			JOIN group_chat_channels c ON c.group_id = i.group_id AND c.is_active = true
+			WHERE i.id = $3 AND NOT COALESCE(i.is_test, false)
+			AND NOT EXISTS (
+				SELECT 1 FROM incident_notification_settings ns
+				WHERE ns.incident_id = i.id
+				AND (ns.chat_updates = 'none' OR (ns.chat_updates = 'pages_only' AND NOT $4))
+			)
+		)
+	`, chatNotificationQueue, string(payload), incidentID, isPageNotification(notificationType) || notificationType == "status_update")
+	if err != nil {
+		return fmt.Errorf("failed to queue chat notification: %w", err)
+	}
+	return nil
+}
+
+// chatIncident is the incident context included in chat notifications
+type chatIncident struct {
+	ID          string
+	ShortID     string
+	Title       string
+	Status      string
+	Severity    string
+	Urgency     string
+	GroupID     string
+	ServiceName string
+	UserName    string
+	SnapshotURL string
+}
+
+// DeliverIncidentNotification posts an incident notification to every active
+// chat channel of the incident's group. Pages for triggered incidents carry a
+// one-time acknowledge link on behalf of the paged user. It fails only when no
+// channel could be reached, so a retry doesn't repeat successful posts.
+func (s *ChatChannelService) DeliverIncidentNotification(userID, incidentID, notificationType string) error {
+	inc := chatIncident{ID: incidentID}
+	var snapshotToken string
+	err := s.PG.QueryRow(`
+		SELECT i.title, i.status, COALESCE(i.severity, ''), i.urgency, COALESCE(i.group_id::text, ''),
+		       COALESCE(sv.name, ''), COALESCE(u.name, ''), i.short_id,
+		       COALESCE(snap.image_url, ''), COALESCE(snap.image_token, '')
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		LEFT JOIN users u ON u.id::text = $2
+		LEFT JOIN LATERAL (
+			SELECT a.image_url, a.image_token FROM incident_artifacts a
+			WHERE a.incident_id = i.id AND a.kind = 'snapshot' AND a.expires_at > NOW()
+			ORDER BY a.created_at DESC LIMIT 1
+		) snap ON TRUE
+		WHERE i.id = $1
+	`, incidentID, userID).Scan(&inc.Title, &inc.Status, &inc.Severity, &inc.Urgency, &inc.GroupID,
+		&inc.ServiceName, &inc.UserName, &inc.ShortID, &inc.SnapshotURL, &snapshotToken)
+	if err == sql.ErrNoRows || (err == nil && inc.GroupID == "") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident for chat notification: %w", err)
+	}
+	if snapshotToken != "" {
+		inc.SnapshotURL = incidentSnapshotImageURL(snapshotToken)
+	}
+
+	channels, err := s.activeChannels(inc.GroupID)
+	if err != nil || len(channels) == 0 {
+		return err
+	}
+
+	ackURL := ""
+	if isPageNotification(notificationType) && inc.Status == db.IncidentStatusTriggered && userID != "" && anyChannelRendersAckLinks(channels) {
+		token, err := createIncidentAckLink(s.PG, incidentID, userID)
+		if err != nil {
+			log.Printf("⚠️  Failed to create acknowledge link for incident %s: %v", incidentID, err)
+		} else {
+			ackURL = ackLinkURL(token)
+		}
+	}
+
+	msg := buildChatMessage(inc, notificationType, ackURL)
+	var lastErr error
+	delivered := 0
+	for _, ch := range channels {
+		err := s.send(ch, msg)
+		s.logChatNotification(userID, incidentID, notificationType, ch, msg, err)
+		if err != nil {
+			log.Printf("❌ Failed to post incident %s to %s channel %q: %v", incidentID, ch.ChannelType, ch.Name, err)
+			lastErr = err
+			continue
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+func anyChannelRendersAckLinks(channels []db.GroupChatChannel) bool {
+	for _, ch := range channels {
+		if channel, ok := notify.Lookup(ch.ChannelType); ok && channel.Capabilities().AckLinks {
+			return true
+		}
+	}
+	return false
+}
+
+// activeChannels returns a group's active chat channels with their secrets decrypted
+func (s *ChatChannelService) activeChannels(groupID string) ([]db.GroupChatChannel, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, channel_type, name, COALESCE(webhook_url, ''), COALESCE(bot_token, ''), COALESCE(chat_id, ''),
+		       COALESCE(config, '')
+		FROM group_chat_channels
+		WHERE group_id = $1 AND is_active = true
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []db.GroupChatChannel
+	for rows.Next() {
+		ch := db.GroupChatChannel{GroupID: groupID, IsActive: true}
+		var config string
+		if err := rows.Scan(&ch.ID, &ch.ChannelType, &ch.Name, &ch.WebhookURL, &ch.BotToken, &ch.ChatID, &config); err != nil {
+			return nil, fmt.Errorf("failed to scan chat channel: %w", err)
+		}
+		decryptColumns(&ch.WebhookURL, &ch.BotToken, &config)
+		ch.Config = decodeChatChannelConfig(config)
+		channels = append(channels, ch)
+	}
+	return channels, rows.Err()
+}
+
+func (s *ChatChannelService) logChatNotification(userID, incidentID, notificationType string, ch db.GroupChatChannel, msg notify.Message, sendErr error) {
+	status, errorMsg := "sent", ""
+	var sentAt interface{} = time.Now()
+	if sendErr != nil {
+		status, errorMsg, sentAt = "failed", sendErr.Error(), nil
+	}
+	_, err := s.PG.Exec(`
+		INSERT INTO notification_logs (user_id, incident_id, notification_type, channel, recipient, message, status, error_message, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, userID, incidentID, "incident_"+notificationType, ch.ChannelType, ch.Name, msg.Headline+"\n"+msg.Title, status, errorMsg, sentAt)
+	if err != nil {
+		log.Printf("Failed to log notification: %v", err)
+	}
+}
+
+func isPageNotification(notificationType string) bool {
+	switch notificationType {
+	case "assigned", "escalated", "paged":
+		return true
+	}
+	return false
+}
+
+// Embed colors (Discord takes them as integers)
+const (
+	chatColorTriggered    = 0xE01E5A
+	chatColorAcknowledged = 0xECB22E
+	chatColorResolved     = 0x2EB67D
+)
+
+func buildChatMessage(inc chatIncident, notificationType, ackURL string) notify.Message {
+	who := inc.UserName
+	if who == "" {
+		who = "on-call"
+	}
+
+	msg := notify.Message{
+		Event:       notificationType,
+		IncidentID:  inc.ID,
+		Title:       inc.Title,
+		IncidentURL: incidentLinkURL(inc.ID, inc.ShortID),
+		AckURL:      ackURL,
+		ImageURL:    inc.SnapshotURL,
+		Color:       chatColorTriggered,
+		ThreadKey:   inc.ID,
+	}
+	switch notificationType {
+	case "assigned":
+		msg.Headline = "🚨 Incident assigned to " + who
+	case "escalated":
+		msg.Headline = "⬆️ Incident escalated to " + who
+	case "paged":
+		msg.Headline = "📟 " + who + " was paged"
+	case "reassigned":
+		msg.Headline = "↪️ Incident reassigned: " + who + " didn't acknowledge in time"
+	case "handoff":
+		msg.Headline = "🔁 Incident handed off: " + who + "'s shift ended"
+	case "acknowledged":
+		msg.Headline = "👀 Incident acknowledged by " + who
+		msg.Color = chatColorAcknowledged
+	case "resolved":
+		msg.Headline = "✅ Incident resolved by " + who
+		msg.Color = chatColorResolved
+	case "status_update":
+		msg.Headline = "🕒 Status update: incident is still " + inc.Status
+		if inc.Status == db.IncidentStatusAcknowledged {
+			msg.Color = chatColorAcknowledged
+		}
+	default:
+		msg.Headline = "🔔 Incident update"
+	}
+
+	msg.Fields = append(msg.Fields, notify.Field{Name: "Status", Value: inc.Status}, notify.Field{Name: "Urgency", Value: inc.Urgency})
+	if inc.Severity != "" {
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Severity", Value: inc.Severity})
+	}
+	if inc.ServiceName != "" {
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Service", Value: inc.ServiceName})
+	}
+	return msg
+}
+
+// send delivers a message through the channel's registered type. Channels
+// that can't render acknowledge links don't get them.
+func (s *ChatChannelService) send(ch db.GroupChatChannel, msg notify.Message) error {
+	channel, ok := notify.Lookup(ch.ChannelType)
+	if !ok {
+		return fmt.Errorf("unsupported chat channel type %q", ch.ChannelType)
+	}
+	if !channel.Capabilities().AckLinks {
+		msg.AckURL = ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chatSendTimeout)
+	defer cancel()
+	return channel.Send(ctx, chatChannelConfig(ch), msg)
+}
+
+// discordPayload renders a message for a Discord incoming webhook. Webhooks
+// can't carry buttons, so the acknowledge link is a markdown link in the embed.
+func discordPayload(msg notify.Message) map[string]interface{} {
+	var links []string
+	if msg.AckURL != "" {
+		links = append(links, "[✅ Acknowledge]("+msg.AckURL+")")
+	}
+	if msg.IncidentURL != "" {
+		links = append(links, "[View incident]("+msg.IncidentURL+")")
+	}
+
+	fields := make([]map[string]interface{}, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{"name": f.Name, "value": f.Value, "inline": true})
+	}
+
+	embed := map[string]interface{}{
+		"title":       truncateRunes(msg.Title, 256),
+		"description": strings.Join(links, " · "),
+		"color":       msg.Color,
+		"fields":      fields,
+	}
+	if msg.IncidentURL != "" {
+		embed["url"] = msg.IncidentURL
+	}
+	if msg.ImageURL != "" {
+		embed["image"] = map[string]string{"url": msg.ImageURL}
+	}
+	return map[string]interface{}{
+		"content":          msg.Headline,
+		"embeds":           []map[string]interface{}{embed},
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	}
+}
+
+// telegramPayload renders a sendMessage request with inline URL buttons.
+// Telegram rejects non-HTTPS button URLs, so those fall back to text links.
+func telegramPayload(chatID string, msg notify.Message) map[string]interface{} {
+	var b strings.Builder
+	b.WriteString("<b>" + html.EscapeString(msg.Headline) + "</b>\n")
+	b.WriteString(html.EscapeString(msg.Title) + "\n")
+	for _, f := range msg.Fields {
+		b.WriteString("\n" + html.EscapeString(f.Name) + ": " + html.EscapeString(f.Value))
+	}
+
+	var buttons []map[string]string
+	for _, link := range []struct{ text, url string }{
+		{"✅ Acknowledge", msg.AckURL},
+		{"View incident", msg.IncidentURL},
+		{"📈 Chart", msg.ImageURL},
+	} {
+		switch {
+		case link.url == "":
+		case strings.HasPrefix(link.url, "https://"):
+			buttons = append(buttons, map[string]string{"text": link.text, "url": link.url})
+		default:
+			b.WriteString("\n" + `<a href="` + html.EscapeString(link.url) + `">` + html.EscapeString(link.text) + "</a>")
+		}
+	}
+
+	payload := map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     b.String(),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if len(buttons) > 0 {
+		payload["reply_markup"] = map[string]interface{}{
+			"inline_keyboard": [][]map[string]string{buttons},
+		}
+	}
+	return payload
+}
+
+// googleChatThreadURL adds the thread key to a Google Chat webhook URL so every
+// update for an incident lands in the same thread (started by the first one)
+func googleChatThreadURL(webhookURL, threadKey string) (string, error) {
+	if threadKey == "" {
+		return webhookURL, nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid Google Chat webhook URL")
+	}
+	q := u.Query()
+	q.Set("threadKey", threadKey)
+	q.Set("messageReplyOption", "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// googleChatPayload renders a message as a Google Chat card with link buttons
+func googleChatPayload(msg notify.Message) map[string]interface{} {
+	widgets := make([]map[string]interface{}, 0, len(msg.Fields)+1)
+	for _, f := range msg.Fields {
+		widgets = append(widgets, map[string]interface{}{
+			"decoratedText": map[string]interface{}{"topLabel": f.Name, "text": html.EscapeString(f.Value)},
+		})
+	}
+
+	if msg.ImageURL != "" {
+		widgets = append(widgets, map[string]interface{}{
+			"image": map[string]string{"imageUrl": msg.ImageURL, "altText": "Chart snapshot"},
+		})
+	}
+
+	var buttons []map[string]interface{}
+	if msg.AckURL != "" {
+		buttons = append(buttons, map[string]interface{}{
+			"text":    "✅ Acknowledge",
+			"onClick": map[string]interface{}{"openLink": map[string]string{"url": msg.AckURL}},
+		})
+	}
+	if msg.IncidentURL != "" {
+		buttons = append(buttons, map[string]interface{}{
+			"text":    "View incident",
+			"onClick": map[string]interface{}{"openLink": map[string]string{"url": msg.IncidentURL}},
+		})
+	}
+	if len(buttons) > 0 {
+		widgets = append(widgets, map[string]interface{}{"buttonList": map[string]interface{}{"buttons": buttons}})
+	}
+
+	card := map[string]interface{}{
+		"header": map[string]string{
+			"title":    msg.Headline,
+			"subtitle": msg.Title,
+		},
+		"sections": []map[string]interface{}{{"widgets": widgets}},
+	}
+	return map[string]interface{}{
+		"fallbackText": msg.Headline + ": " + msg.Title,
+		"cardsV2":      []map[string]interface{}{{"cardId": "incident", "card": card}},
+	}
+}
+
+// postChatJSON posts a chat payload. Transport errors are unwrapped so the
+// request URL, which carries the webhook or bot token, never ends up in logs.
+func postChatJSON(ctx context.Context, client *http.Client, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid request URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+// ACKNOWLEDGE LINKS
+
+// createIncidentAckLink stores a one-time acknowledge link for the paged user
+// and returns its token
+func createIncidentAckLink(pg *sql.DB, incidentID, userID string) (string, error) {
+	token, tokenHash, err := generateInvitationToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = pg.Exec(`
+		INSERT INTO incident_ack_links (token_hash, incident_id, user_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, tokenHash, incidentID, userID, time.Now().Add(ackLinkTTL))
+	if err != nil {
+		return "", fmt.Errorf("failed to store acknowledge link: %w", err)
+	}
+	return token, nil
+}
+
+func ackLinkURL(token string) string {
+	return webBaseURL() + "/ack/" + url.PathEscape(token)
+}
+
+// GetAckLink returns the incident behind an acknowledge link
+func (s *ChatChannelService) GetAckLink(token string) (*db.IncidentAckLink, error) {
+	link, _, _, err := s.getAckLink(token)
+	return link, err
+}
+
+// AcknowledgeWithAckLink acknowledges the link's incident on behalf of the
+// paged user. Links work once, expire after a day and stop working once the
+// incident has been acknowledged or resolved.
+func (s *ChatChannelService) AcknowledgeWithAckLink(token string, incidents *IncidentService) (*db.IncidentAckLink, error) {
+	link, linkID, userID, err := s.getAckLink(token)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(link.ExpiresAt) {
+		return link, ErrAckLinkExpired
+	}
+	if link.UsedAt != nil {
+		return link, ErrAckLinkUsed
+	}
+	if link.Status != db.IncidentStatusTriggered {
+		return link, ErrIncidentNotTriggered
+	}
+
+	// Claim the link before acknowledging so a replayed request can't use it again
+	result, err := s.PG.Exec(`UPDATE incident_ack_links SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use acknowledge link: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return link, ErrAckLinkUsed
+	}
+
+	if err := incidents.AcknowledgeIncident(link.IncidentID, userID, "Acknowledged from chat notification"); err != nil {
+		// Release the link so the user can retry
+		if _, releaseErr := s.PG.Exec(`UPDATE incident_ack_links SET used_at = NULL WHERE id = $1`, linkID); releaseErr != nil {
+			log.Printf("⚠️  Failed to release acknowledge link: %v", releaseErr)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	link.Status = db.IncidentStatusAcknowledged
+	link.UsedAt = &now
+	return link, nil
+}
+
+func (s *ChatChannelService) getAckLink(token string) (*db.IncidentAckLink, string, string, error) {
+	var link db.IncidentAckLink
+	var linkID, userID string
+	var usedAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT l.id, l.user_id::text, l.incident_id, i.title, i.status, COALESCE(i.severity, ''), i.urgency,
+		       COALESCE(sv.name, ''), COALESCE(u.name, ''), l.expires_at, l.used_at
+		FROM incident_ack_links l
+		JOIN incidents i ON i.id = l.incident_id
+		LEFT JOIN services sv ON sv.id = i.service_id
+		LEFT JOIN users u ON u.id = l.user_id
+		WHERE l.token_hash = $1
+	`, hashInvitationToken(token)).Scan(&linkID, &userID, &link.IncidentID, &link.Title, &link.Status,
+		&link.Severity, &link.Urgency, &link.ServiceName, &link.UserName, &link.ExpiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", "", ErrAckLinkNotFound
+	}
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get acknowledge link: %w", err)
+	}
+	if usedAt.Valid {
+		link.UsedAt = &usedAt.Time
+	}
+	return &link, linkID, userID, nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/notify"
+)
+
+// Built-in chat channel types. Settings keys match the group_chat_channels
+// columns they have always been stored in.
+func init() {
+	client := &http.Client{Timeout: 10 * time.Second}
+	notify.Register(discordChannel{client: client})
+	notify.Register(telegramChannel{client: client})
+	notify.Register(googleChatChannel{client: client})
+}
+
+type discordChannel struct{ client *http.Client }
+
+func (discordChannel) Type() string { return db.ChatChannelDiscord }
+func (discordChannel) Name() string { return "Discord" }
+
+func (discordChannel) ConfigSchema() []notify.ConfigField {
+	return []notify.ConfigField{{
+		Key: "webhook_url", Label: "Webhook URL", Type: notify.FieldURL, Required: true, Secret: true,
+		Placeholder: "https://discord.com/api/webhooks/...",
+		Help:        "Channel settings → Integrations → Webhooks",
+	}}
+}
+
+func (discordChannel) Capabilities() notify.Capabilities {
+	return notify.Capabilities{AckLinks: true}
+}
+
+func (discordChannel) Validate(cfg notify.Config) error {
+	if !discordWebhookPattern.MatchString(cfg["webhook_url"]) {
+		return errors.New("webhook_url must be a Discord webhook URL")
+	}
+	return nil
+}
+
+func (c discordChannel) Send(ctx context.Context, cfg notify.Config, msg notify.Message) error {
+	return postChatJSON(ctx, c.client, cfg["webhook_url"], discordPayload(msg))
+}
+
+type telegramChannel struct{ client *http.Client }
+
+func (telegramChannel) Type() string { return db.ChatChannelTelegram }
+func (telegramChannel) Name() string { return "Telegram" }
+
+func (telegramChannel) ConfigSchema() []notify.ConfigField {
+	return []notify.ConfigField{
+		{
+			Key: "bot_token", Label: "Bot token", Type: notify.FieldText, Required: true, Secret: true,
+			Placeholder: "123456:ABC-DEF...",
+			Help:        "Create a bot with @BotFather and add it to the chat",
+		},
+		{
+			Key: "chat_id", Label: "Chat ID", Type: notify.FieldText, Required: true,
+			Placeholder: "-1001234567890 or @channel",
+		},
+	}
+}
+
+func (telegramChannel) Capabilities() notify.Capabilities {
+	return notify.Capabilities{AckLinks: true}
+}
+
+func (telegramChannel) Validate(cfg notify.Config) error {
+	if !telegramTokenPattern.MatchString(cfg["bot_token"]) {
+		return errors.New("bot_token must be a Telegram bot token")
+	}
+	if !telegramChatIDPattern.MatchString(cfg["chat_id"]) {
+		return errors.New("chat_id must be a numeric chat ID or @channel name")
+	}
+	return nil
+}
+
+func (c telegramChannel) Send(ctx context.Context, cfg notify.Config, msg notify.Message) error {
+	return postChatJSON(ctx, c.client, telegramAPIBaseURL+"/bot"+cfg["bot_token"]+"/sendMessage", telegramPayload(cfg["chat_id"], msg))
+}
+
+type googleChatChannel struct{ client *http.Client }
+
+func (googleChatChannel) Type() string { return db.ChatChannelGoogleChat }
+func (googleChatChannel) Name() string { return "Google Chat" }
+
+func (googleChatChannel) ConfigSchema() []notify.ConfigField {
+	return []notify.ConfigField{{
+		Key: "webhook_url", Label: "Webhook URL", Type: notify.FieldURL, Required: true, Secret: true,
+		Placeholder: "https://chat.googleapis.com/v1/spaces/...",
+		Help:        "Space settings → Apps & integrations → Webhooks",
+	}}
+}
+
+func (googleChatChannel) Capabilities() notify.Capabilities {
+	return notify.Capabilities{AckLinks: true, Threads: true}
+}
+
+func (googleChatChannel) Validate(cfg notify.Config) error {
+	if !googleChatWebhookPattern.MatchString(cfg["webhook_url"]) {
+		return errors.New("webhook_url must be a Google Chat space webhook URL")
+	}
+	return nil
+}
+
+func (c googleChatChannel) Send(ctx context.Context, cfg notify.Config, msg notify.Message) error {
+	target, err := googleChatThreadURL(cfg["webhook_url"], msg.ThreadKey)
+	if err != nil {
+		return err
+	}
+	return postChatJSON(ctx, c.client, target, googleChatPayload(msg))
+}
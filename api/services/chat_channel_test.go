@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/notify"
+)
+
+func TestValidateChatChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel db.GroupChatChannel
+		valid   bool
+	}{
+		{"discord", db.GroupChatChannel{Name: "ops", ChannelType: db.ChatChannelDiscord, WebhookURL: "https://discord.com/api/webhooks/123/abc-DEF_1"}, true},
+		{"discord other host", db.GroupChatChannel{Name: "ops", ChannelType: db.ChatChannelDiscord, WebhookURL: "https://example.com/api/webhooks/123/abc"}, false},
+		{"telegram", db.GroupChatChannel{Name: "ops", ChannelType: db.ChatChannelTelegram, BotToken: "123456:AAE-token_x", ChatID: "-1001234567890"}, true},
+		{"telegram channel name", db.GroupChatChannel{Name: "ops", ChannelType: db.ChatChannelTelegram, BotToken: "123456:AAE", ChatID: "@ops_alerts"}, true},
+		{"telegram missing chat", db.GroupChatChannel{Name: "ops", ChannelType: db.ChatChannelTelegram, BotToken: "123456:AAE"}, false},
+		{"missing name", db.GroupChatChannel{ChannelType: db.ChatChannelDiscord, WebhookURL: "https://discord.com/api/webhooks/123/abc"}, false},
+		{"unknown type", db.GroupChatChannel{Name: "ops", ChannelType: "teams"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChatChannel(&tt.channel)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidChatChannel) {
+				t.Errorf("err = %v, want ErrInvalidChatChannel", err)
+			}
+		})
+	}
+}
+
+func TestTelegramPayloadButtonsRequireHTTPS(t *testing.T) {
+	msg := notify.Message{
+		Headline:    "🚨 Incident assigned to Alice",
+		Title:       "CPU <high>",
+		AckURL:      "https://slar.example.com/ack/tok",
+		IncidentURL: "http://localhost:3000/incidents/inc-1",
+	}
+	payload := telegramPayload("-100", msg)
+
+	text := payload["text"].(string)
+	if !strings.Contains(text, "CPU &lt;high&gt;") {
+		t.Errorf("title not HTML-escaped: %q", text)
+	}
+	if !strings.Contains(text, `<a href="http://localhost:3000/incidents/inc-1">`) {
+		t.Errorf("non-HTTPS link should fall back to text: %q", text)
+	}
+	keyboard := payload["reply_markup"].(map[string]interface{})["inline_keyboard"].([][]map[string]string)
+	if len(keyboard[0]) != 1 || keyboard[0][0]["url"] != msg.AckURL {
+		t.Errorf("expected only the HTTPS acknowledge button, got %v", keyboard)
+	}
+}
+
+func TestDeliverIncidentNotificationPostsAckLink(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	var posted map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&posted)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	oldBase := telegramAPIBaseURL
+	telegramAPIBaseURL = server.URL
+	defer func() { telegramAPIBaseURL = oldBase }()
+
+	mock.ExpectQuery(`FROM incidents i`).
+		WithArgs("inc-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "status", "severity", "urgency", "group_id", "service", "user", "short_id",
+			"snapshot_url", "snapshot_token"}).
+			AddRow("Checkout errors", "triggered", "SEV1", "high", "grp-1", "checkout", "Alice", "k3m9x2ab7q", "", ""))
+	mock.ExpectQuery(`FROM group_chat_channels`).
+		WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "channel_type", "name", "webhook_url", "bot_token", "chat_id", "config"}).
+			AddRow("ch-1", "telegram", "ops", "", "123:ABC", "-100", ""))
+	mock.ExpectExec(`INSERT INTO incident_ack_links`).
+		WithArgs(sqlmock.AnyArg(), "inc-1", "user-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO notification_logs`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	service := &ChatChannelService{PG: pg}
+	if err := service.DeliverIncidentNotification("user-1", "inc-1", "escalated"); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/bot123:ABC/sendMessage" {
+		t.Errorf("posted to %q", path)
+	}
+	text, _ := posted["text"].(string)
+	if !strings.Contains(text, "escalated to Alice") || !strings.Contains(text, "/ack/") {
+		t.Errorf("message missing headline or acknowledge link: %q", text)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGoogleChatThreadsByIncident(t *testing.T) {
+	webhook := "https://chat.googleapis.com/v1/spaces/AAAA/messages?key=k&token=t"
+	ch := db.GroupChatChannel{Name: "ops", ChannelType: db.ChatChannelGoogleChat, WebhookURL: webhook}
+	if err := validateChatChannel(&ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	target, err := googleChatThreadURL(webhook, "inc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(target)
+	q := u.Query()
+	if q.Get("threadKey") != "inc-1" || q.Get("messageReplyOption") != "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD" || q.Get("token") != "t" {
+		t.Errorf("unexpected thread URL %q", target)
+	}
+
+	msg := buildChatMessage(chatIncident{ID: "inc-1", Title: "Checkout errors", Status: "triggered", Urgency: "high", UserName: "Alice"},
+		"assigned", "https://slar.example.com/ack/tok")
+	payload := googleChatPayload(msg)
+	card := payload["cardsV2"].([]map[string]interface{})[0]["card"].(map[string]interface{})
+	if card["header"].(map[string]string)["subtitle"] != "Checkout errors" {
+		t.Errorf("unexpected card header: %v", card["header"])
+	}
+	widgets := card["sections"].([]map[string]interface{})[0]["widgets"].([]map[string]interface{})
+	buttons := widgets[len(widgets)-1]["buttonList"].(map[string]interface{})["buttons"].([]map[string]interface{})
+	if len(buttons) != 2 || buttons[0]["text"] != "✅ Acknowledge" {
+		t.Errorf("expected acknowledge and view buttons, got %v", buttons)
+	}
+}
+
+type zulipTestChannel struct{}
+
+func (zulipTestChannel) Type() string { return "zulip_test" }
+func (zulipTestChannel) Name() string { return "Zulip" }
+
+func (zulipTestChannel) ConfigSchema() []notify.ConfigField {
+	return []notify.ConfigField{
+		{Key: "api_key", Label: "API key", Type: notify.FieldText, Required: true, Secret: true},
+		{Key: "stream", Label: "Stream", Type: notify.FieldText, Required: true},
+	}
+}
+
+func (zulipTestChannel) Capabilities() notify.Capabilities { return notify.Capabilities{} }
+func (zulipTestChannel) Validate(cfg notify.Config) error  { return nil }
+
+func (zulipTestChannel) Send(ctx context.Context, cfg notify.Config, msg notify.Message) error {
+	if msg.AckURL != "" {
+		return errors.New("ack link sent to a channel that can't render it")
+	}
+	return nil
+}
+
+func TestRegisteredChatChannelType(t *testing.T) {
+	notify.Register(zulipTestChannel{})
+
+	ch := db.GroupChatChannel{Name: "ops", ChannelType: "zulip_test", WebhookURL: "https://ignored",
+		Config: map[string]string{"api_key": "secret", "stream": "incidents"}}
+	if err := validateChatChannel(&ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch.WebhookURL != "" || ch.Config["api_key"] != "secret" {
+		t.Errorf("expected settings outside the schema to be dropped, got %+v", ch)
+	}
+
+	setPublicChatChannelConfig(&ch)
+	if _, leaked := ch.PublicConfig["api_key"]; leaked || ch.PublicConfig["stream"] != "incidents" {
+		t.Errorf("unexpected public config %v", ch.PublicConfig)
+	}
+
+	service := &ChatChannelService{}
+	if err := service.send(ch, notify.Message{Headline: "test", AckURL: "https://slar.example.com/ack/tok"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAcknowledgeWithAckLinkWorksOnce(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	// A concurrent request claimed the link between the lookup and the update
+	mock.ExpectQuery(`FROM incident_ack_links l`).WithArgs(hashInvitationToken("tok")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "incident_id", "title", "status", "severity",
+			"urgency", "service", "user", "expires_at", "used_at"}).
+			AddRow("link-1", "user-1", "inc-1", "Checkout down", db.IncidentStatusTriggered, "critical", "high",
+				"checkout", "Alice", time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE incident_ack_links SET used_at = NOW\(\) WHERE id = \$1 AND used_at IS NULL`).
+		WithArgs("link-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s := NewChatChannelService(pg)
+	if _, err := s.AcknowledgeWithAckLink("tok", NewIncidentService(pg, nil)); !errors.Is(err, ErrAckLinkUsed) {
+		t.Errorf("err = %v, want ErrAckLinkUsed", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrInvalidConfigBundle        = errors.New("invalid configuration bundle")
+	ErrConfigBundleSchemaMismatch = errors.New("configuration bundle was taken at another schema version")
+)
+
+// configBackupTables are the configuration tables in a bundle, parents before
+// children so foreign keys hold while restoring. redact maps secret columns
+// to the placeholder written instead; they are re-entered after a restore.
+// Incidents, events, chat history, sessions and tokens are not configuration.
+var configBackupTables = []struct {
+	name   string
+	redact map[string]interface{}
+}{
+	{"organizations", nil},
+	{"users", map[string]interface{}{"phone": "", "fcm_token": ""}},
+	{"projects", nil},
+	{"memberships", nil},
+	{"groups", nil},
+	{"group_members", nil},
+	{"escalation_policies", nil},
+	{"escalation_levels", nil},
+	{"services", nil},
+	{"schedulers", nil},
+	{"shifts", nil},
+	{"rotation_cycles", nil},
+	{"rotation_configurations", nil},
+	{"schedule_overrides", nil},
+	{"integrations", map[string]interface{}{"webhook_secret": ""}},
+	{"service_integrations", nil},
+	{"service_routing_keys", nil},
+	{"monitor_deployments", map[string]interface{}{"cf_api_token": ""}},
+	{"monitors", map[string]interface{}{"headers": map[string]interface{}{}}},
+	{"service_urgency_rules", nil},
+	{"service_severity_mappings", nil},
+	{"service_maintenance_windows", nil},
+	{"service_alert_mutes", nil},
+	{"service_deploy_rules", nil},
+	{"service_slos", nil},
+	{"service_alert_storm_rules", nil},
+	{"incident_workflow_states", nil},
+	{"incident_tagging_rules", nil},
+	{"freeze_periods", nil},
+	{"feature_flags", nil},
+	{"organization_feature_flags", nil},
+	{"user_notification_configs", nil},
+}
+
+// ConfigBackupService exports an instance's configuration as a versioned
+// bundle and restores it, for disaster recovery and cloning environments
+type ConfigBackupService struct {
+	PG *sql.DB
+}
+
+// NewConfigBackupService creates a new ConfigBackupService
+func NewConfigBackupService(pg *sql.DB) *ConfigBackupService {
+	return &ConfigBackupService{PG: pg}
+}
+
+// schemaVersion is the latest migration applied to q's database
+func schemaVersion(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}) (string, error) {
+	var version string
+	if err := q.QueryRow(`SELECT COALESCE(MAX(version), '') FROM schema_migrations`).Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Backup exports every configuration table with secrets blanked
+func (s *ConfigBackupService) Backup() (*db.ConfigBundle, error) {
+	version, err := schemaVersion(s.PG)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &db.ConfigBundle{
+		FormatVersion: db.ConfigBundleFormatVersion,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC(),
+		Tables:        []db.ConfigBundleTable{},
+	}
+
+	for _, table := range configBackupTables {
+		rows, err := s.PG.Query(fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t`, pq.QuoteIdentifier(table.name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		exported := db.ConfigBundleTable{Name: table.name, Rows: []map[string]interface{}{}}
+		for column := range table.redact {
+			exported.Redacted = append(exported.Redacted, column)
+		}
+		sort.Strings(exported.Redacted)
+
+		for rows.Next() {
+			var raw string
+			if err := rows.Scan(&raw); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+			}
+			row, err := decodeConfigRow(raw)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+			}
+			for column, placeholder := range table.redact {
+				if _, ok := row[column]; ok {
+					row[column] = placeholder
+				}
+			}
+			exported.Rows = append(exported.Rows, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		bundle.Tables = append(bundle.Tables, exported)
+	}
+	return bundle, nil
+}
+
+// decodeConfigRow keeps numbers as written so bigints survive the round trip
+func decodeConfigRow(raw string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// Restore inserts a bundle's rows in one transaction, keeping their IDs.
+// Rows that already exist (same ID or unique key) are left untouched, so a
+// restore can be rerun. Nothing is written on a dry run.
+func (s *ConfigBackupService) Restore(bundle *db.ConfigBundle, opts db.ConfigRestoreOptions) (*db.ConfigRestoreReport, error) {
+	tables, err := configBundleTables(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	version, err := schemaVersion(tx)
+	if err != nil {
+		return nil, err
+	}
+	if version != bundle.SchemaVersion && !opts.AllowSchemaMismatch {
+		return nil, fmt.Errorf("%w: bundle %s, this instance %s", ErrConfigBundleSchemaMismatch, bundle.SchemaVersion, version)
+	}
+
+	report := &db.ConfigRestoreReport{DryRun: opts.DryRun, Tables: []db.ConfigRestoreTableResult{}}
+	for _, def := range configBackupTables {
+		table, ok := tables[def.name]
+		if !ok || len(table.Rows) == 0 {
+			continue
+		}
+		inserted, err := restoreConfigTable(tx, table)
+		if err != nil {
+			return nil, err
+		}
+		result := db.ConfigRestoreTableResult{
+			Name:     table.Name,
+			Rows:     len(table.Rows),
+			Inserted: inserted,
+			Skipped:  len(table.Rows) - inserted,
+			Redacted: table.Redacted,
+		}
+		report.Inserted += result.Inserted
+		report.Skipped += result.Skipped
+		report.Tables = append(report.Tables, result)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return report, nil
+}
+
+func isConfigBackupTable(name string) bool {
+	for _, table := range configBackupTables {
+		if table.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreConfigTable inserts the rows of one table and returns how many were
+// new. Only columns present in both the bundle and this instance are
+// written; generated columns are left to the database.
+func restoreConfigTable(tx *sql.Tx, table db.ConfigBundleTable) (int, error) {
+	columns, err := instanceColumns(tx, table.Name)
+	if err != nil {
+		return 0, err
+	}
+	columns = configRestoreColumns(columns, table.Rows)
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("%w: %s shares no columns with this instance", ErrInvalidConfigBundle, table.Name)
+	}
+	payload, err := json.Marshal(table.Rows)
+	if err != nil {
+		return 0, err
+	}
+
+	name := pq.QuoteIdentifier(table.Name)
+	list := quoteColumns(columns)
+	result, err := tx.Exec(fmt.Sprintf(
+		`INSERT INTO %s (%s) SELECT %s FROM json_populate_recordset(NULL::%s, $1::json) ON CONFLICT DO NOTHING`,
+		name, list, list, name,
+	), string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore %s: %w", table.Name, err)
+	}
+	inserted, _ := result.RowsAffected()
+	return int(inserted), nil
+}
+
+// instanceColumns lists a table's writable columns on this instance
+func instanceColumns(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		  AND is_generated = 'NEVER' AND identity_generation IS DISTINCT FROM 'ALWAYS'
+		ORDER BY ordinal_position
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pq.QuoteIdentifier(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// configRestoreColumns keeps the instance's columns that bundle rows set, in
+// the instance's order. Columns the bundle lacks keep their defaults.
+func configRestoreColumns(instance []string, rows []map[string]interface{}) []string {
+	present := map[string]bool{}
+	for _, row := range rows {
+		for column := range row {
+			present[column] = true
+		}
+	}
+	columns := []string{}
+	for _, column := range instance {
+		if present[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestConfigBackupRedactsSecrets(t *testing.T) {
+	pg, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT COALESCE(MAX(version), '') FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("20260418000000"))
+	for _, table := range configBackupTables {
+		rows := sqlmock.NewRows([]string{"row"})
+		switch table.name {
+		case "integrations":
+			rows.AddRow(`{"id":"int-1","name":"Datadog","webhook_secret":"s3cret","config":{"n":9007199254740993}}`)
+		case "users":
+			rows.AddRow(`{"id":"user-1","email":"dana@example.com","phone":"+14155550123","fcm_token":"tok"}`)
+		}
+		mock.ExpectQuery(`SELECT row_to_json(t)::text FROM "` + table.name + `" t`).WillReturnRows(rows)
+	}
+
+	bundle, err := NewConfigBackupService(pg).Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.FormatVersion != db.ConfigBundleFormatVersion || bundle.SchemaVersion != "20260418000000" {
+		t.Errorf("bundle versions = (%d, %s)", bundle.FormatVersion, bundle.SchemaVersion)
+	}
+	if len(bundle.Tables) != len(configBackupTables) {
+		t.Fatalf("got %d tables, want %d", len(bundle.Tables), len(configBackupTables))
+	}
+
+	out, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cret", "+14155550123", `"tok"`} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("bundle contains secret %s", secret)
+		}
+	}
+	for _, kept := range []string{"dana@example.com", "9007199254740993", `"redacted":["fcm_token","phone"]`} {
+		if !strings.Contains(string(out), kept) {
+			t.Errorf("bundle is missing %s", kept)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigRestoreRejects(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := NewConfigBackupService(pg)
+
+	if _, err := s.Restore(&db.ConfigBundle{FormatVersion: 99}, db.ConfigRestoreOptions{}); !errors.Is(err, ErrInvalidConfigBundle) {
+		t.Errorf("format version: error = %v, want ErrInvalidConfigBundle", err)
+	}
+	bundle := &db.ConfigBundle{FormatVersion: db.ConfigBundleFormatVersion, Tables: []db.ConfigBundleTable{{Name: "incidents"}}}
+	if _, err := s.Restore(bundle, db.ConfigRestoreOptions{}); !errors.Is(err, ErrInvalidConfigBundle) {
+		t.Errorf("unknown table: error = %v, want ErrInvalidConfigBundle", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("20260418000000"))
+	mock.ExpectRollback()
+	bundle = &db.ConfigBundle{FormatVersion: db.ConfigBundleFormatVersion, SchemaVersion: "20260301000000"}
+	if _, err := s.Restore(bundle, db.ConfigRestoreOptions{}); !errors.Is(err, ErrConfigBundleSchemaMismatch) {
+		t.Errorf("schema version: error = %v, want ErrConfigBundleSchemaMismatch", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigRestoreDryRunRollsBack(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	bundle := &db.ConfigBundle{
+		FormatVersion: db.ConfigBundleFormatVersion,
+		SchemaVersion: "20260418000000",
+		Tables: []db.ConfigBundleTable{
+			// Out of dependency order on purpose: restore follows configBackupTables
+			{Name: "groups", Rows: []map[string]interface{}{{"id": "g-1", "name": "DBA", "dropped_column": 1}}},
+			{Name: "organizations", Rows: []map[string]interface{}{{"id": "org-1", "name": "Acme"}, {"id": "org-2", "name": "Beta"}}},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("20260418000000"))
+	mock.ExpectQuery(`information_schema.columns`).WithArgs("organizations").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("name").AddRow("slug"))
+	mock.ExpectExec(`INSERT INTO "organizations" \("id", "name"\) SELECT "id", "name" FROM json_populate_recordset`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`information_schema.columns`).WithArgs("groups").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("name"))
+	mock.ExpectExec(`INSERT INTO "groups" \("id", "name"\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	report, err := NewConfigBackupService(pg).Restore(bundle, db.ConfigRestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Inserted != 2 || report.Skipped != 1 {
+		t.Errorf("report = %+v, want dry run with 2 inserted, 1 skipped", report)
+	}
+	if len(report.Tables) != 2 || report.Tables[0].Name != "organizations" || report.Tables[1].Name != "groups" {
+		t.Errorf("tables = %+v, want organizations then groups", report.Tables)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrInvalidConfigPromotion  = errors.New("invalid configuration promotion")
+	ErrConfigPromotionConflict = errors.New("configuration promotion conflicts with this instance")
+)
+
+// configTableKeys are the primary key columns of configuration tables not
+// keyed by id
+var configTableKeys = map[string][]string{
+	"feature_flags":              {"key"},
+	"organization_feature_flags": {"organization_id", "flag_key"},
+	"service_alert_storm_rules":  {"service_id"},
+	"service_severity_mappings":  {"service_id", "severity"},
+}
+
+func configTableKey(table string) []string {
+	if key, ok := configTableKeys[table]; ok {
+		return key
+	}
+	return []string{"id"}
+}
+
+// configRowKey identifies a row within its table, e.g. "svc-1,critical"
+func configRowKey(table string, row map[string]interface{}) string {
+	parts := []string{}
+	for _, column := range configTableKey(table) {
+		parts = append(parts, fmt.Sprint(row[column]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// isRuntimeConfigColumn reports columns that differ between environments
+// without being configuration: timestamps and the latest check or usage
+func isRuntimeConfigColumn(column string) bool {
+	return column == "created_at" || column == "updated_at" || column == "is_up" || strings.HasPrefix(column, "last_")
+}
+
+// configValuesEqual compares values by their JSON encoding, so a number
+// decoded as float64 from a request equals the json.Number from a backup
+func configValuesEqual(a, b interface{}) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
+
+// configBundleTables validates a bundle and indexes its tables by name
+func configBundleTables(bundle *db.ConfigBundle) (map[string]db.ConfigBundleTable, error) {
+	if bundle.FormatVersion != db.ConfigBundleFormatVersion {
+		return nil, fmt.Errorf("%w: format version %d, expected %d", ErrInvalidConfigBundle, bundle.FormatVersion, db.ConfigBundleFormatVersion)
+	}
+	tables := map[string]db.ConfigBundleTable{}
+	for _, table := range bundle.Tables {
+		if !isConfigBackupTable(table.Name) {
+			return nil, fmt.Errorf("%w: unexpected table %q", ErrInvalidConfigBundle, table.Name)
+		}
+		tables[table.Name] = table
+	}
+	return tables, nil
+}
+
+// diffConfigBundles lists what promoting source onto target would change,
+// table by table in dependency order. Tables missing from source are left
+// alone; redacted and runtime columns are not compared.
+func diffConfigBundles(source, target *db.ConfigBundle) (*db.ConfigDiff, error) {
+	sourceTables, err := configBundleTables(source)
+	if err != nil {
+		return nil, err
+	}
+	targetTables, err := configBundleTables(target)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &db.ConfigDiff{
+		SourceSchemaVersion: source.SchemaVersion,
+		TargetSchemaVersion: target.SchemaVersion,
+		Changes:             []db.ConfigChange{},
+	}
+	for _, def := range configBackupTables {
+		src, ok := sourceTables[def.name]
+		if !ok {
+			continue
+		}
+		dst := targetTables[def.name]
+		skip := map[string]bool{}
+		for _, column := range append(append([]string{}, src.Redacted...), dst.Redacted...) {
+			skip[column] = true
+		}
+		for _, column := range configTableKey(def.name) {
+			skip[column] = true
+		}
+
+		existing := map[string]map[string]interface{}{}
+		for _, row := range dst.Rows {
+			existing[configRowKey(def.name, row)] = row
+		}
+		seen := map[string]bool{}
+
+		var changes []db.ConfigChange
+		for _, row := range src.Rows {
+			key := configRowKey(def.name, row)
+			seen[key] = true
+			change := db.ConfigChange{ID: def.name + "/" + key, Table: def.name, Key: key}
+
+			current, ok := existing[key]
+			if !ok {
+				change.Action = db.ConfigChangeCreate
+				change.Fields = configFieldChanges(nil, row, skip)
+				changes = append(changes, change)
+				continue
+			}
+			if change.Fields = configFieldChanges(current, row, skip); len(change.Fields) > 0 {
+				change.Action = db.ConfigChangeUpdate
+				changes = append(changes, change)
+			}
+		}
+		for _, row := range dst.Rows {
+			if key := configRowKey(def.name, row); !seen[key] {
+				changes = append(changes, db.ConfigChange{ID: def.name + "/" + key, Table: def.name, Key: key, Action: db.ConfigChangeDelete})
+			}
+		}
+
+		sort.SliceStable(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+		for _, change := range changes {
+			switch change.Action {
+			case db.ConfigChangeCreate:
+				diff.Creates++
+			case db.ConfigChangeUpdate:
+				diff.Updates++
+			case db.ConfigChangeDelete:
+				diff.Deletes++
+			}
+		}
+		diff.Changes = append(diff.Changes, changes...)
+	}
+	return diff, nil
+}
+
+// configFieldChanges lists the compared columns whose values differ, by name
+func configFieldChanges(from, to map[string]interface{}, skip map[string]bool) []db.ConfigFieldChange {
+	columns := map[string]bool{}
+	for column := range from {
+		columns[column] = true
+	}
+	for column := range to {
+		columns[column] = true
+	}
+	names := []string{}
+	for column := range columns {
+		if !skip[column] && !isRuntimeConfigColumn(column) {
+			names = append(names, column)
+		}
+	}
+	sort.Strings(names)
+
+	fields := []db.ConfigFieldChange{}
+	for _, column := range names {
+		if from != nil && configValuesEqual(from[column], to[column]) {
+			continue
+		}
+		fields = append(fields, db.ConfigFieldChange{Column: column, From: from[column], To: to[column]})
+	}
+	return fields
+}
+
+// DiffBundle compares a bundle from another instance (e.g. staging) with
+// this instance's configuration. Both must be at the same schema version.
+func (s *ConfigBackupService) DiffBundle(source *db.ConfigBundle) (*db.ConfigDiff, error) {
+	target, err := s.Backup()
+	if err != nil {
+		return nil, err
+	}
+	if source.SchemaVersion != target.SchemaVersion {
+		return nil, fmt.Errorf("%w: bundle %s, this instance %s", ErrConfigBundleSchemaMismatch, source.SchemaVersion, target.SchemaVersion)
+	}
+	return diffConfigBundles(source, target)
+}
+
+// Promote applies the selected changes of a bundle's diff in one
+// transaction: deletes children first, then creates and updates parents
+// first. Changes are identified by ConfigChange.ID and must still be pending,
+// so a promotion reviewed against a stale diff is refused. Nothing is written
+// on a dry run.
+func (s *ConfigBackupService) Promote(req db.PromoteConfigRequest) (*db.ConfigPromotionResult, error) {
+	if len(req.Changes) == 0 {
+		return nil, fmt.Errorf("%w: no changes selected", ErrInvalidConfigPromotion)
+	}
+	diff, err := s.DiffBundle(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := map[string]db.ConfigChange{}
+	for _, change := range diff.Changes {
+		pending[change.ID] = change
+	}
+	selected := map[string]bool{}
+	for _, id := range req.Changes {
+		if _, ok := pending[id]; !ok {
+			return nil, fmt.Errorf("%w: %q is not a pending change, re-run the diff", ErrInvalidConfigPromotion, id)
+		}
+		selected[id] = true
+	}
+
+	sourceRows := map[string]map[string]interface{}{}
+	for _, table := range req.Bundle.Tables {
+		for _, row := range table.Rows {
+			sourceRows[table.Name+"/"+configRowKey(table.Name, row)] = row
+		}
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &db.ConfigPromotionResult{DryRun: req.DryRun, Applied: []db.ConfigChange{}}
+	for i := len(diff.Changes) - 1; i >= 0; i-- {
+		change := diff.Changes[i]
+		if !selected[change.ID] || change.Action != db.ConfigChangeDelete {
+			continue
+		}
+		if err := deleteConfigRow(tx, change); err != nil {
+			return nil, err
+		}
+		result.Applied = append(result.Applied, change)
+	}
+
+	creates := map[string][]map[string]interface{}{}
+	for _, change := range diff.Changes {
+		if selected[change.ID] && change.Action == db.ConfigChangeCreate {
+			creates[change.Table] = append(creates[change.Table], sourceRows[change.ID])
+		}
+	}
+	for _, def := range configBackupTables {
+		if rows := creates[def.name]; len(rows) > 0 {
+			inserted, err := restoreConfigTable(tx, db.ConfigBundleTable{Name: def.name, Rows: rows})
+			if err != nil {
+				return nil, err
+			}
+			if inserted != len(rows) {
+				return nil, fmt.Errorf("%w: %d of %d new %s rows clash with existing rows", ErrConfigPromotionConflict, len(rows)-inserted, len(rows), def.name)
+			}
+		}
+		for _, change := range diff.Changes {
+			if change.Table != def.name || !selected[change.ID] {
+				continue
+			}
+			switch change.Action {
+			case db.ConfigChangeCreate:
+				result.Applied = append(result.Applied, change)
+			case db.ConfigChangeUpdate:
+				if err := updateConfigRow(tx, change, sourceRows[change.ID]); err != nil {
+					return nil, err
+				}
+				result.Applied = append(result.Applied, change)
+			}
+		}
+	}
+
+	if req.DryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit promotion: %w", err)
+	}
+	return result, nil
+}
+
+// configKeyCondition matches a row by its key columns, compared as text,
+// with placeholders numbered from first
+func configKeyCondition(change db.ConfigChange, first int) (string, []interface{}) {
+	columns := configTableKey(change.Table)
+	values := strings.SplitN(change.Key, ",", len(columns))
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("%s::text = $%d", pq.QuoteIdentifier(column), first+i)
+		args[i] = values[i]
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func deleteConfigRow(tx *sql.Tx, change db.ConfigChange) error {
+	condition, args := configKeyCondition(change, 1)
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s`, pq.QuoteIdentifier(change.Table), condition), args...); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", change.ID, err)
+	}
+	return nil
+}
+
+// updateConfigRow writes only the changed columns of one row
+func updateConfigRow(tx *sql.Tx, change db.ConfigChange, row map[string]interface{}) error {
+	columns := make([]string, len(change.Fields))
+	for i, field := range change.Fields {
+		columns[i] = field.Column
+	}
+	payload, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	name := pq.QuoteIdentifier(change.Table)
+	list := quoteColumns(columns)
+	condition, args := configKeyCondition(change, 2)
+	result, err := tx.Exec(fmt.Sprintf(
+		`UPDATE %s SET (%s) = (SELECT %s FROM json_populate_record(NULL::%s, $1::json)) WHERE %s`,
+		name, list, list, name, condition,
+	), append([]interface{}{string(payload)}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", change.ID, err)
+	}
+	if updated, _ := result.RowsAffected(); updated != 1 {
+		return fmt.Errorf("%w: %s no longer exists", ErrConfigPromotionConflict, change.ID)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func configBundle(version string, tables ...db.ConfigBundleTable) *db.ConfigBundle {
+	return &db.ConfigBundle{FormatVersion: db.ConfigBundleFormatVersion, SchemaVersion: version, Tables: tables}
+}
+
+func TestDiffConfigBundles(t *testing.T) {
+	staging := configBundle("20260418000000",
+		db.ConfigBundleTable{Name: "groups", Rows: []map[string]interface{}{
+			{"id": "g-1", "name": "DBA", "updated_at": "2026-04-02T00:00:00Z"},
+			{"id": "g-2", "name": "SRE (renamed)", "escalation_timeout": float64(300)},
+			{"id": "g-4", "name": "Payments"},
+		}},
+		db.ConfigBundleTable{Name: "integrations", Redacted: []string{"webhook_secret"}, Rows: []map[string]interface{}{
+			{"id": "int-1", "name": "Datadog", "webhook_secret": "", "last_heartbeat": "2026-04-02T00:00:00Z"},
+		}},
+		db.ConfigBundleTable{Name: "service_severity_mappings", Rows: []map[string]interface{}{
+			{"service_id": "svc-1", "severity": "critical", "urgency": "high"},
+		}},
+	)
+	production := configBundle("20260418000000",
+		db.ConfigBundleTable{Name: "groups", Rows: []map[string]interface{}{
+			{"id": "g-1", "name": "DBA", "updated_at": "2026-03-01T00:00:00Z"},
+			{"id": "g-2", "name": "SRE", "escalation_timeout": json.Number("300")},
+			{"id": "g-3", "name": "Legacy"},
+		}},
+		db.ConfigBundleTable{Name: "integrations", Redacted: []string{"webhook_secret"}, Rows: []map[string]interface{}{
+			{"id": "int-1", "name": "Datadog", "webhook_secret": "", "last_heartbeat": "2026-04-01T00:00:00Z"},
+		}},
+		db.ConfigBundleTable{Name: "service_severity_mappings", Rows: []map[string]interface{}{
+			{"service_id": "svc-1", "severity": "critical", "urgency": "low"},
+		}},
+		// Left alone: staging's bundle has no monitors table
+		db.ConfigBundleTable{Name: "monitors", Rows: []map[string]interface{}{{"id": "m-1"}}},
+	)
+
+	diff, err := diffConfigBundles(staging, production)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Creates != 1 || diff.Updates != 2 || diff.Deletes != 1 {
+		t.Errorf("counts = (%d, %d, %d), want (1, 2, 1)", diff.Creates, diff.Updates, diff.Deletes)
+	}
+	want := []struct{ id, action string }{
+		{"groups/g-2", db.ConfigChangeUpdate},
+		{"groups/g-3", db.ConfigChangeDelete},
+		{"groups/g-4", db.ConfigChangeCreate},
+		{"service_severity_mappings/svc-1,critical", db.ConfigChangeUpdate},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d", diff.Changes, len(want))
+	}
+	for i, w := range want {
+		if diff.Changes[i].ID != w.id || diff.Changes[i].Action != w.action {
+			t.Errorf("change %d = %s %s, want %s %s", i, diff.Changes[i].Action, diff.Changes[i].ID, w.action, w.id)
+		}
+	}
+	if fields := diff.Changes[0].Fields; len(fields) != 1 || fields[0].Column != "name" || fields[0].From != "SRE" {
+		t.Errorf("g-2 fields = %+v, want only name", fields)
+	}
+
+	production.SchemaVersion = "20260301000000"
+	production.FormatVersion = 0
+	if _, err := diffConfigBundles(staging, production); !errors.Is(err, ErrInvalidConfigBundle) {
+		t.Errorf("error = %v, want ErrInvalidConfigBundle", err)
+	}
+}
+
+func TestConfigPromoteDryRun(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("20260418000000"))
+	for _, table := range configBackupTables {
+		rows := sqlmock.NewRows([]string{"row"})
+		if table.name == "groups" {
+			rows.AddRow(`{"id":"g-1","name":"DBA"}`).AddRow(`{"id":"g-3","name":"Legacy"}`)
+		}
+		mock.ExpectQuery(`SELECT row_to_json\(t\)::text FROM "` + table.name + `" t`).WillReturnRows(rows)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "groups" WHERE "id"::text = \$1`).WithArgs("g-3").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`information_schema.columns`).WithArgs("groups").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("name"))
+	mock.ExpectExec(`INSERT INTO "groups"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "groups" SET \("name"\) = \(SELECT "name" FROM json_populate_record\(NULL::"groups", \$1::json\)\) WHERE "id"::text = \$2`).
+		WithArgs(sqlmock.AnyArg(), "g-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	req := db.PromoteConfigRequest{
+		Bundle: configBundle("20260418000000", db.ConfigBundleTable{Name: "groups", Rows: []map[string]interface{}{
+			{"id": "g-1", "name": "Databases"},
+			{"id": "g-2", "name": "SRE"},
+		}}),
+		Changes: []string{"groups/g-1", "groups/g-2", "groups/g-3"},
+		DryRun:  true,
+	}
+	result, err := NewConfigBackupService(pg).Promote(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.DryRun || len(result.Applied) != 3 || result.Applied[0].Action != db.ConfigChangeDelete {
+		t.Errorf("result = %+v, want dry run applying the delete first", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigPromoteRejectsStaleChanges(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("20260418000000"))
+	for range configBackupTables {
+		mock.ExpectQuery(`SELECT row_to_json`).WillReturnRows(sqlmock.NewRows([]string{"row"}))
+	}
+
+	req := db.PromoteConfigRequest{Bundle: configBundle("20260418000000"), Changes: []string{"groups/g-1"}}
+	if _, err := NewConfigBackupService(pg).Promote(req); !errors.Is(err, ErrInvalidConfigPromotion) {
+		t.Errorf("error = %v, want ErrInvalidConfigPromotion", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrDeployRuleNotFound = errors.New("deploy rule not found")
+
+const deployRuleSelect = `
+	SELECT id, service_id, name, COALESCE(environment, ''), match_labels, action, is_active,
+	       COALESCE(created_by, ''), created_at, updated_at
+	FROM service_deploy_rules
+`
+
+const deployRuleReturning = `
+	RETURNING id, service_id, name, COALESCE(environment, ''), match_labels, action, is_active,
+	          COALESCE(created_by, ''), created_at, updated_at
+`
+
+func scanDeployRule(row interface{ Scan(...interface{}) error }) (db.ServiceDeployRule, error) {
+	var rule db.ServiceDeployRule
+	var matchLabels []byte
+	err := row.Scan(&rule.ID, &rule.ServiceID, &rule.Name, &rule.Environment, &matchLabels,
+		&rule.Action, &rule.IsActive, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return rule, err
+	}
+	rule.MatchLabels = map[string]string{}
+	if len(matchLabels) > 0 {
+		json.Unmarshal(matchLabels, &rule.MatchLabels)
+	}
+	return rule, nil
+}
+
+func marshalMatchLabels(labels map[string]string) string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	b, _ := json.Marshal(labels)
+	return string(b)
+}
+
+// ListDeployRules returns a service's resolve-on-deploy rules
+func (s *ServiceService) ListDeployRules(serviceID string) ([]db.ServiceDeployRule, error) {
+	rows, err := s.PG.Query(deployRuleSelect+` WHERE service_id = $1 ORDER BY created_at ASC`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deploy rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []db.ServiceDeployRule{}
+	for rows.Next() {
+		rule, err := scanDeployRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deploy rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateDeployRule adds a resolve-on-deploy rule to a service
+func (s *ServiceService) CreateDeployRule(serviceID string, req db.CreateServiceDeployRuleRequest, createdBy string) (*db.ServiceDeployRule, error) {
+	rule, err := scanDeployRule(s.PG.QueryRow(`
+		INSERT INTO service_deploy_rules (service_id, name, environment, match_labels, action, created_by)
+		VALUES ($1, $2, $3, $4::jsonb, $5, $6)
+	`+deployRuleReturning, serviceID, req.Name, nullIfEmpty(req.Environment), marshalMatchLabels(req.MatchLabels),
+		req.Action, nullIfEmpty(createdBy)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deploy rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// UpdateDeployRule changes a deploy rule's match criteria, action or active flag
+func (s *ServiceService) UpdateDeployRule(serviceID, ruleID string, req db.UpdateServiceDeployRuleRequest) (*db.ServiceDeployRule, error) {
+	query := "UPDATE service_deploy_rules SET updated_at = NOW()"
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		query += fmt.Sprintf(", name = $%d", argIndex)
+		args = append(args, *req.Name)
+		argIndex++
+	}
+	if req.Environment != nil {
+		query += fmt.Sprintf(", environment = $%d", argIndex)
+		args = append(args, nullIfEmpty(*req.Environment))
+		argIndex++
+	}
+	if req.MatchLabels != nil {
+		query += fmt.Sprintf(", match_labels = $%d::jsonb", argIndex)
+		args = append(args, marshalMatchLabels(*req.MatchLabels))
+		argIndex++
+	}
+	if req.Action != nil {
+		query += fmt.Sprintf(", action = $%d", argIndex)
+		args = append(args, *req.Action)
+		argIndex++
+	}
+	if req.IsActive != nil {
+		query += fmt.Sprintf(", is_active = $%d", argIndex)
+		args = append(args, *req.IsActive)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d AND service_id = $%d", argIndex, argIndex+1) + deployRuleReturning
+	args = append(args, ruleID, serviceID)
+
+	rule, err := scanDeployRule(s.PG.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeployRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update deploy rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// DeleteDeployRule removes a deploy rule
+func (s *ServiceService) DeleteDeployRule(serviceID, ruleID string) error {
+	result, err := s.PG.Exec(`DELETE FROM service_deploy_rules WHERE id = $1 AND service_id = $2`, ruleID, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete deploy rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeployRuleNotFound
+	}
+	return nil
+}
+
+// ApplyDeployEvent runs the deploy rules of every service linked to the
+// integration against their open incidents. Each incident is handled once; when
+// several rules match, a resolve rule wins over annotate. Matching incidents get
+// a deploy_linked timeline event with the deploy link, and resolve rules then
+// resolve them as actorID (the integration's system user).
+func (s *IncidentService) ApplyDeployEvent(integrationID string, event db.DeployEvent, actorID string) ([]db.DeployRuleMatch, error) {
+	matches := []db.DeployRuleMatch{}
+	if !event.Succeeded() {
+		return matches, nil
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT DISTINCT ON (i.id) i.id, r.service_id, r.id, r.name, r.action
+		FROM service_integrations si
+		JOIN service_deploy_rules r ON r.service_id = si.service_id AND r.is_active = TRUE
+		JOIN incidents i ON i.service_id = r.service_id
+		WHERE si.integration_id = $1 AND si.is_active = TRUE
+		  AND (r.environment IS NULL OR r.environment = $2)
+		  AND i.status IN ('triggered', 'acknowledged')
+		  AND COALESCE(i.labels, '{}'::jsonb) @> r.match_labels
+		ORDER BY i.id, (r.action = 'resolve') DESC, r.created_at ASC
+	`, integrationID, event.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match deploy rules: %w", err)
+	}
+	for rows.Next() {
+		var m db.DeployRuleMatch
+		if err := rows.Scan(&m.IncidentID, &m.ServiceID, &m.RuleID, &m.RuleName, &m.Action); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deploy rule match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range matches {
+		eventData := map[string]interface{}{
+			"provider":    event.Provider,
+			"repository":  event.Repository,
+			"environment": event.Environment,
+			"version":     event.Version,
+			"ref":         event.Ref,
+			"url":         event.URL,
+			"deployed_by": event.DeployedBy,
+			"rule_id":     m.RuleID,
+			"rule_name":   m.RuleName,
+			"action":      m.Action,
+		}
+		if err := s.createIncidentEvent(m.IncidentID, db.IncidentEventDeployLinked, eventData, actorID); err != nil {
+			log.Printf("Failed to record deploy on incident %s: %v", m.IncidentID, err)
+		}
+
+		if m.Action != db.DeployRuleActionResolve {
+			continue
+		}
+		resolution := fmt.Sprintf("Resolved by %s deploy %s", event.Provider, deployLabel(event))
+		if event.URL != "" {
+			resolution += ": " + event.URL
+		}
+		if err := s.ResolveIncident(m.IncidentID, actorID, fmt.Sprintf("Deploy rule %q matched", m.RuleName), resolution); err != nil {
+			log.Printf("Failed to resolve incident %s on deploy: %v", m.IncidentID, err)
+		}
+	}
+
+	return matches, nil
+}
+
+// deployLabel describes a deploy as "<version> to <environment>"
+func deployLabel(event db.DeployEvent) string {
+	label := event.Version
+	if label == "" {
+		label = event.Ref
+	}
+	if event.Environment != "" {
+		label += " to " + event.Environment
+	}
+	return label
+}
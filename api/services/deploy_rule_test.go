@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestApplyDeployEvent(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	event := db.DeployEvent{Provider: "github", Environment: "production", Version: "a1b2c3d", URL: "https://example.com/run/1", Status: "success"}
+
+	mock.ExpectQuery(`FROM service_integrations si`).WithArgs("integ-1", "production").
+		WillReturnRows(sqlmock.NewRows([]string{"incident_id", "service_id", "rule_id", "rule_name", "action"}).
+			AddRow("inc-1", "svc-1", "rule-1", "Fix regressions", db.DeployRuleActionResolve).
+			AddRow("inc-2", "svc-1", "rule-2", "Link deploys", db.DeployRuleActionAnnotate))
+
+	// inc-1: annotated, then resolved
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventDeployLinked, sqlmock.AnyArg(), db.SystemUserWebhook).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE incidents`).
+		WithArgs(db.IncidentStatusResolved, db.SystemUserWebhook, "inc-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventResolved, sqlmock.AnyArg(), db.SystemUserWebhook).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// inc-2: annotated only
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-2", db.IncidentEventDeployLinked, sqlmock.AnyArg(), db.SystemUserWebhook).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	matches, err := NewIncidentService(pg, nil).ApplyDeployEvent("integ-1", event, db.SystemUserWebhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].Action != db.DeployRuleActionResolve || matches[1].IncidentID != "inc-2" {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyDeployEventIgnoresFailedDeploys(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	matches, err := NewIncidentService(pg, nil).ApplyDeployEvent("integ-1", db.DeployEvent{Provider: "gitlab", Status: "failed"}, db.SystemUserWebhook)
+	if err != nil || len(matches) != 0 {
+		t.Errorf("failed deploy: matches = %v, err = %v", matches, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeployLabel(t *testing.T) {
+	tests := []struct {
+		event db.DeployEvent
+		want  string
+	}{
+		{db.DeployEvent{Version: "a1b2c3d", Environment: "production"}, "a1b2c3d to production"},
+		{db.DeployEvent{Ref: "v1.2.0"}, "v1.2.0"},
+	}
+	for _, tt := range tests {
+		if got := deployLabel(tt.event); got != tt.want {
+			t.Errorf("deployLabel(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
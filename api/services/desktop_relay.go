@@ -0,0 +1,134 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrDesktopIncidentNotFound = errors.New("incident not found")
+	ErrDesktopIncidentNotOpen  = errors.New("incident is already acknowledged or resolved")
+)
+
+// desktopIncidentBatch bounds the incidents sent in one poll
+const desktopIncidentBatch = 50
+
+// DesktopRelayService backs the desktop notifier's WebSocket: the incidents
+// paging the user, their on-call status and acknowledging from the tray
+type DesktopRelayService struct {
+	PG              *sql.DB
+	IncidentService *IncidentService
+}
+
+func NewDesktopRelayService(pg *sql.DB, incidentService *IncidentService) *DesktopRelayService {
+	return &DesktopRelayService{PG: pg, IncidentService: incidentService}
+}
+
+// IncidentUpdates returns the incidents assigned to the user, in their
+// organizations, that changed after since, oldest change first. With a zero
+// since it returns the ones still open instead, for a client that has
+// nothing to resume from.
+func (s *DesktopRelayService) IncidentUpdates(userID string, since time.Time) ([]db.DesktopIncident, error) {
+	rows, err := s.PG.Query(`
+		SELECT i.id, i.title, i.status, COALESCE(i.urgency, ''), COALESCE(i.priority, ''),
+		       COALESCE(s.name, ''), COALESCE(g.name, ''), COALESCE(i.short_id, ''), i.created_at, i.updated_at
+		FROM incidents i
+		LEFT JOIN services s ON s.id = i.service_id
+		LEFT JOIN groups g ON g.id = i.group_id
+		WHERE i.assigned_to = $1
+		  AND EXISTS (
+			SELECT 1 FROM memberships m
+			WHERE m.resource_type = 'org' AND m.resource_id = i.organization_id AND m.user_id = $1
+		  )
+		  AND (($3 AND i.status IN ('triggered', 'acknowledged')) OR (NOT $3 AND i.updated_at > $2))
+		ORDER BY i.updated_at ASC
+		LIMIT $4
+	`, userID, since, since.IsZero(), desktopIncidentBatch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get desktop incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []db.DesktopIncident{}
+	for rows.Next() {
+		var inc db.DesktopIncident
+		var shortID string
+		if err := rows.Scan(&inc.ID, &inc.Title, &inc.Status, &inc.Urgency, &inc.Priority,
+			&inc.ServiceName, &inc.GroupName, &shortID, &inc.CreatedAt, &inc.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan desktop incident: %w", err)
+		}
+		inc.URL = incidentLinkURL(inc.ID, shortID)
+		incidents = append(incidents, inc)
+	}
+	return incidents, rows.Err()
+}
+
+// OnCallStatus returns the user's current shifts, their next one within a
+// week and whether escalations page them right now
+func (s *DesktopRelayService) OnCallStatus(userID string) (*db.DesktopOnCallStatus, error) {
+	status := &db.DesktopOnCallStatus{Current: []db.DesktopShift{}}
+	if err := s.PG.QueryRow(`SELECT user_available($1, NOW())`, userID).Scan(&status.Available); err != nil {
+		return nil, fmt.Errorf("failed to check availability: %w", err)
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT es.group_id, g.name, COALESCE(sc.display_name, sc.name, ''), es.start_time, es.end_time
+		FROM effective_shifts es
+		JOIN groups g ON g.id = es.group_id
+		JOIN schedulers sc ON sc.id = es.scheduler_id
+		WHERE es.effective_user_id = $1 AND es.is_active = true AND sc.is_active = true
+		  AND es.end_time > NOW() AND es.start_time < NOW() + INTERVAL '7 days'
+		ORDER BY es.start_time ASC
+		LIMIT 20
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get on-call shifts: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	for rows.Next() {
+		var sh db.DesktopShift
+		if err := rows.Scan(&sh.GroupID, &sh.GroupName, &sh.SchedulerName, &sh.Start, &sh.End); err != nil {
+			return nil, fmt.Errorf("failed to scan on-call shift: %w", err)
+		}
+		if sh.Start.After(now) {
+			if status.Next == nil {
+				status.Next = &sh
+			}
+			continue
+		}
+		status.Current = append(status.Current, sh)
+	}
+	status.OnCall = len(status.Current) > 0
+	return status, rows.Err()
+}
+
+// Acknowledge acknowledges a triggered incident in one of the user's
+// organizations from the desktop client
+func (s *DesktopRelayService) Acknowledge(userID, incidentID string) error {
+	var status string
+	err := s.PG.QueryRow(`
+		SELECT i.status
+		FROM incidents i
+		WHERE i.id::text = $2
+		  AND EXISTS (
+			SELECT 1 FROM memberships m
+			WHERE m.resource_type = 'org' AND m.resource_id = i.organization_id AND m.user_id = $1
+		  )
+	`, userID, incidentID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrDesktopIncidentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident: %w", err)
+	}
+	if status != db.IncidentStatusTriggered {
+		return ErrDesktopIncidentNotOpen
+	}
+	return s.IncidentService.AcknowledgeIncident(incidentID, userID, "Acknowledged from desktop")
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+type onCallShift struct {
+	ID            string
+	SchedulerID   string
+	SchedulerName string
+	UserID        string
+	Start, End    time.Time
+}
+
+type onCallOverride struct {
+	ID         string
+	ShiftID    string
+	NewUserID  string
+	Start, End time.Time
+}
+
+// GetEffectiveOnCall resolves who is on call for each of the group's
+// schedulers over [from, to): the generated rotation shifts with overrides
+// applied for exactly the time they cover, as effective_shifts does for the
+// current moment. Totals count each person's time once even when they are on
+// call for several schedulers at the same time.
+func (s *OnCallService) GetEffectiveOnCall(groupID string, from, to time.Time) (*db.EffectiveOnCall, error) {
+	shifts, overrides, err := loadOnCallShifts(s.PG, groupID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &db.EffectiveOnCall{
+		GroupID:   groupID,
+		From:      from,
+		To:        to,
+		Intervals: resolveOnCallIntervals(shifts, overrides, from, to),
+	}
+
+	userIDs := []string{}
+	for _, iv := range result.Intervals {
+		userIDs = append(userIDs, iv.UserID)
+	}
+	userIDs = uniqueStrings(userIDs)
+	type userInfo struct{ name, email string }
+	users := map[string]userInfo{}
+	if len(userIDs) > 0 {
+		rows, err := s.PG.Query(`SELECT id, COALESCE(name, ''), COALESCE(email, '') FROM users WHERE id = ANY($1)`, pq.Array(userIDs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get on-call users: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			var u userInfo
+			if err := rows.Scan(&id, &u.name, &u.email); err != nil {
+				return nil, fmt.Errorf("failed to scan on-call user: %w", err)
+			}
+			users[id] = u
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get on-call users: %w", err)
+		}
+	}
+	for i := range result.Intervals {
+		u := users[result.Intervals[i].UserID]
+		result.Intervals[i].UserName, result.Intervals[i].UserEmail = u.name, u.email
+	}
+
+	result.Totals = onCallUserTotals(result.Intervals)
+	return result, nil
+}
+
+// loadOnCallShifts returns the group's active shifts and overrides that
+// overlap [from, to)
+func loadOnCallShifts(pg *sql.DB, groupID string, from, to time.Time) ([]onCallShift, []onCallOverride, error) {
+	rows, err := pg.Query(`
+		SELECT s.id, s.scheduler_id, COALESCE(sc.display_name, sc.name, ''), s.user_id, s.start_time, s.end_time
+		FROM shifts s
+		JOIN schedulers sc ON sc.id = s.scheduler_id
+		WHERE s.group_id = $1 AND s.is_active = true AND sc.is_active = true
+		  AND s.start_time < $3 AND s.end_time > $2
+		ORDER BY s.start_time ASC
+	`, groupID, from, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get shifts: %w", err)
+	}
+	shifts := []onCallShift{}
+	for rows.Next() {
+		var sh onCallShift
+		if err := rows.Scan(&sh.ID, &sh.SchedulerID, &sh.SchedulerName, &sh.UserID, &sh.Start, &sh.End); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan shift: %w", err)
+		}
+		shifts = append(shifts, sh)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to get shifts: %w", err)
+	}
+
+	rows, err = pg.Query(`
+		SELECT id, original_schedule_id, new_user_id, override_start_time, override_end_time
+		FROM schedule_overrides
+		WHERE group_id = $1 AND is_active = true
+		  AND override_start_time < $3 AND override_end_time > $2
+		ORDER BY created_at ASC
+	`, groupID, from, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get overrides: %w", err)
+	}
+	overrides := []onCallOverride{}
+	for rows.Next() {
+		var o onCallOverride
+		if err := rows.Scan(&o.ID, &o.ShiftID, &o.NewUserID, &o.Start, &o.End); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to get overrides: %w", err)
+	}
+
+	return shifts, overrides, nil
+}
+
+// resolveOnCallIntervals clips the shifts to [from, to), hands the parts an
+// override covers to its user (the latest override wins where they overlap)
+// and joins back-to-back stretches of the same person on the same scheduler
+func resolveOnCallIntervals(shifts []onCallShift, overrides []onCallOverride, from, to time.Time) []db.OnCallInterval {
+	byShift := map[string][]onCallOverride{}
+	for _, o := range overrides {
+		byShift[o.ShiftID] = append(byShift[o.ShiftID], o)
+	}
+
+	pieces := []db.OnCallInterval{}
+	for _, sh := range shifts {
+		start, end := laterTime(sh.Start, from), earlierTime(sh.End, to)
+		if !end.After(start) {
+			continue
+		}
+
+		bounds := []time.Time{start, end}
+		for _, o := range byShift[sh.ID] {
+			if o.Start.After(start) && o.Start.Before(end) {
+				bounds = append(bounds, o.Start)
+			}
+			if o.End.After(start) && o.End.Before(end) {
+				bounds = append(bounds, o.End)
+			}
+		}
+		sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+		for i := 0; i+1 < len(bounds); i++ {
+			a, b := bounds[i], bounds[i+1]
+			if !b.After(a) {
+				continue
+			}
+			piece := db.OnCallInterval{
+				SchedulerID:   sh.SchedulerID,
+				SchedulerName: sh.SchedulerName,
+				UserID:        sh.UserID,
+				Start:         a,
+				End:           b,
+			}
+			for _, o := range byShift[sh.ID] {
+				if !o.Start.After(a) && !o.End.Before(b) {
+					piece.UserID, piece.OverrideID, piece.OriginalUserID = o.NewUserID, o.ID, sh.UserID
+				}
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+
+	sort.SliceStable(pieces, func(i, j int) bool {
+		if pieces[i].SchedulerID != pieces[j].SchedulerID {
+			return pieces[i].SchedulerID < pieces[j].SchedulerID
+		}
+		return pieces[i].Start.Before(pieces[j].Start)
+	})
+	intervals := []db.OnCallInterval{}
+	for _, p := range pieces {
+		if n := len(intervals); n > 0 {
+			last := &intervals[n-1]
+			if last.SchedulerID == p.SchedulerID && last.UserID == p.UserID &&
+				last.OverrideID == p.OverrideID && last.End.Equal(p.Start) {
+				last.End = p.End
+				continue
+			}
+		}
+		intervals = append(intervals, p)
+	}
+	for i := range intervals {
+		intervals[i].Seconds = int64(intervals[i].End.Sub(intervals[i].Start).Seconds())
+	}
+
+	sort.SliceStable(intervals, func(i, j int) bool {
+		if !intervals[i].Start.Equal(intervals[j].Start) {
+			return intervals[i].Start.Before(intervals[j].Start)
+		}
+		return intervals[i].SchedulerName < intervals[j].SchedulerName
+	})
+	return intervals
+}
+
+// onCallUserTotals sums each person's on-call time, counting overlapping
+// intervals on different schedulers once; longest first
+func onCallUserTotals(intervals []db.OnCallInterval) []db.OnCallUserTotal {
+	byUser := map[string][]db.OnCallInterval{}
+	order := []string{}
+	for _, iv := range intervals {
+		if _, ok := byUser[iv.UserID]; !ok {
+			order = append(order, iv.UserID)
+		}
+		byUser[iv.UserID] = append(byUser[iv.UserID], iv)
+	}
+
+	totals := []db.OnCallUserTotal{}
+	for _, userID := range order {
+		ivs := byUser[userID] // already ordered by start
+		total := db.OnCallUserTotal{UserID: userID, UserName: ivs[0].UserName, UserEmail: ivs[0].UserEmail}
+		var covered time.Duration
+		curStart, curEnd := ivs[0].Start, ivs[0].End
+		for _, iv := range ivs[1:] {
+			if iv.Start.After(curEnd) {
+				covered += curEnd.Sub(curStart)
+				curStart, curEnd = iv.Start, iv.End
+			} else if iv.End.After(curEnd) {
+				curEnd = iv.End
+			}
+		}
+		covered += curEnd.Sub(curStart)
+		total.Seconds = int64(covered.Seconds())
+		total.Hours = float64(total.Seconds) / 3600
+		totals = append(totals, total)
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Seconds > totals[j].Seconds })
+	return totals
+}
+
+func laterTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlierTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveOnCallIntervals(t *testing.T) {
+	day := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+
+	shifts := []onCallShift{
+		{ID: "s1", SchedulerID: "primary", SchedulerName: "Primary", UserID: "alice", Start: at(-24), End: at(24)},
+		{ID: "s2", SchedulerID: "primary", SchedulerName: "Primary", UserID: "bob", Start: at(24), End: at(48)},
+		{ID: "s3", SchedulerID: "primary", SchedulerName: "Primary", UserID: "alice", Start: at(48), End: at(72)},
+		{ID: "s4", SchedulerID: "secondary", SchedulerName: "Secondary", UserID: "alice", Start: at(12), End: at(36)},
+	}
+	overrides := []onCallOverride{
+		// Carol covers the middle of Alice's first shift
+		{ID: "o1", ShiftID: "s1", NewUserID: "carol", Start: at(6), End: at(10)},
+		// Bob takes the whole of Alice's last shift, so his stretch continues
+		// on the override
+		{ID: "o2", ShiftID: "s3", NewUserID: "bob", Start: at(40), End: at(80)},
+	}
+
+	intervals := resolveOnCallIntervals(shifts, overrides, at(0), at(60))
+
+	type want struct {
+		scheduler, user, override string
+		start, end                int
+	}
+	wants := []want{
+		{"primary", "alice", "", 0, 6},
+		{"primary", "carol", "o1", 6, 10},
+		{"primary", "alice", "", 10, 24},
+		{"secondary", "alice", "", 12, 36},
+		{"primary", "bob", "", 24, 48},
+		{"primary", "bob", "o2", 48, 60},
+	}
+	if len(intervals) != len(wants) {
+		t.Fatalf("got %d intervals, want %d: %+v", len(intervals), len(wants), intervals)
+	}
+	for i, w := range wants {
+		iv := intervals[i]
+		if iv.SchedulerID != w.scheduler || iv.UserID != w.user || iv.OverrideID != w.override ||
+			!iv.Start.Equal(at(w.start)) || !iv.End.Equal(at(w.end)) {
+			t.Errorf("interval %d = %+v, want %+v", i, iv, w)
+		}
+	}
+	if intervals[1].OriginalUserID != "alice" || intervals[1].Seconds != 4*3600 {
+		t.Errorf("override interval = %+v", intervals[1])
+	}
+
+	totals := onCallUserTotals(intervals)
+	got := map[string]int64{}
+	for _, total := range totals {
+		got[total.UserID] = total.Seconds / 3600
+	}
+	// Alice's primary and secondary shifts overlap from 12 to 24: counted once
+	if got["alice"] != 6+(36-10) || got["bob"] != 36 || got["carol"] != 4 {
+		t.Errorf("totals = %+v", totals)
+	}
+	if totals[0].UserID != "bob" {
+		t.Errorf("totals not ordered longest first: %+v", totals)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+	"strings"
+
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/internal/faults"
+)
+
+// EmailService sends plain-text transactional emails over SMTP.
+// It is a no-op (IsConfigured() == false) when SMTP_HOST/SMTP_FROM are not set,
+// so callers can fall back to returning links directly in API responses.
+type EmailService struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewEmailService creates an EmailService from config.Current().SMTP
+func NewEmailService() *EmailService {
+	cfg := config.Current().SMTP
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	return &EmailService{
+		host:     cfg.Host,
+		port:     port,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+	}
+}
+
+// IsConfigured returns true if SMTP delivery is available
+func (s *EmailService) IsConfigured() bool {
+	return s != nil && s.host != "" && s.from != ""
+}
+
+// Send delivers a plain-text email to a single recipient
+func (s *EmailService) Send(to, subject, body string) error {
+	if !s.IsConfigured() {
+		return fmt.Errorf("email delivery is not configured")
+	}
+	if err := faults.ProviderOutage("email"); err != nil {
+		return err
+	}
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid email header value")
+	}
+	// smtp.from may carry a display name ("SLAR <oncall@example.com>"), but
+	// MAIL FROM only takes the bare address
+	sender, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("invalid smtp.from address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	if err := smtp.SendMail(addr, auth, sender.Address, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/vanchonlee/slar/internal/encryption"
+)
+
+// encryptColumn seals a sensitive column value before it is written.
+// Passes the value through unchanged when ENCRYPTION_KEYS is not configured.
+func encryptColumn(value string) (string, error) {
+	sealed, err := encryption.Encrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt column: %w", err)
+	}
+	return sealed, nil
+}
+
+// decryptColumns opens scanned sensitive column values in place.
+// Values that can't be decrypted (e.g. key removed from the keyring) are
+// blanked so ciphertext never reaches API responses or notification providers.
+func decryptColumns(values ...*string) {
+	for _, v := range values {
+		if v == nil || *v == "" {
+			continue
+		}
+		plain, err := encryption.Decrypt(*v)
+		if err != nil {
+			log.Printf("Failed to decrypt column value: %v", err)
+			*v = ""
+			continue
+		}
+		*v = plain
+	}
+}
+
+// encryptUserColumns seals the sensitive columns of a user row (phone, fcm_token)
+func encryptUserColumns(phone, fcmToken string) (string, string, error) {
+	sealedPhone, err := encryptColumn(phone)
+	if err != nil {
+		return "", "", err
+	}
+	sealedToken, err := encryptColumn(fcmToken)
+	if err != nil {
+		return "", "", err
+	}
+	return sealedPhone, sealedToken, nil
+}
+
+// encryptedColumns lists the columns sealed by the application, used for
+// initial encryption of existing plaintext rows and for key rotation.
+var encryptedColumns = []struct {
+	table  string
+	column string
+	key    string // primary key column
+}{
+	{"users", "phone", "id"},
+	{"users", "fcm_token", "id"},
+	{"user_devices", "fcm_token", "id"},
+	{"integrations", "webhook_secret", "id"},
+	{"group_chat_channels", "webhook_url", "id"},
+	{"group_chat_channels", "bot_token", "id"},
+	{"group_chat_channels", "config", "id"},
+	{"group_sheet_exports", "credentials", "id"},
+	{"group_sheet_exports", "webhook_url", "id"},
+	{"whatsapp_consents", "phone_number", "user_id"},
+	{"web_push_subscriptions", "endpoint", "id"},
+	{"web_push_subscriptions", "p256dh", "id"},
+	{"web_push_subscriptions", "auth", "id"},
+}
+
+// ReencryptSensitiveColumns rewrites every encrypted column value that is
+// still plaintext or sealed with a non-active key. It is idempotent and safe
+// to run on every startup; once it reports zero rows, retired keys can be
+// removed from ENCRYPTION_KEYS.
+func ReencryptSensitiveColumns(pg *sql.DB) error {
+	keyring := encryption.Default()
+	if !keyring.Enabled() {
+		return nil
+	}
+
+	for _, col := range encryptedColumns {
+		rows, err := pg.Query(fmt.Sprintf(
+			`SELECT %s, %s FROM %s WHERE %s IS NOT NULL AND %s != ''`,
+			col.key, col.column, col.table, col.column, col.column,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to scan %s.%s: %w", col.table, col.column, err)
+		}
+
+		pending := map[string]string{}
+		for rows.Next() {
+			var id, value string
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return err
+			}
+			if keyring.NeedsRotation(value) {
+				pending[id] = value
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rotated := 0
+		for id, value := range pending {
+			plain, err := keyring.Decrypt(value)
+			if err != nil {
+				log.Printf("Skipping %s.%s for %s: %v", col.table, col.column, id, err)
+				continue
+			}
+			sealed, err := keyring.Encrypt(plain)
+			if err != nil {
+				return err
+			}
+			// Compare-and-swap so a concurrent write isn't overwritten with stale data
+			res, err := pg.Exec(fmt.Sprintf(
+				`UPDATE %s SET %s = $1 WHERE %s = $2 AND %s = $3`,
+				col.table, col.column, col.key, col.column,
+			), sealed, id, value)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt %s.%s for %s: %w", col.table, col.column, id, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				rotated++
+			}
+		}
+
+		if rotated > 0 {
+			log.Printf("🔐 Re-encrypted %d value(s) in %s.%s with key %s", rotated, col.table, col.column, keyring.ActiveKeyID())
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/vanchonlee/slar/db"
 )
 
@@ -71,6 +72,10 @@ func (s *EscalationService) CreateEscalationPolicy(groupID string, req db.Escala
 		CreatedBy:            req.CreatedBy,
 		EscalateAfterMinutes: req.EscalateAfterMinutes,
 		GroupID:              groupID,
+
+		UnseenEscalateAfterMinutes: req.UnseenEscalateAfterMinutes,
+		ReassignAfterMinutes:       req.ReassignAfterMinutes,
+		AckTimeoutMinutes:          req.AckTimeoutMinutes,
 	}
 
 	// Set defaults
@@ -85,33 +90,52 @@ func (s *EscalationService) CreateEscalationPolicy(groupID string, req db.Escala
 	}
 	defer tx.Rollback()
 
+	if err = insertEscalationPolicy(tx, &policy, req.Levels); err != nil {
+		return policy, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return policy, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Created escalation policy: %s with %d levels", policy.Name, len(policy.Levels))
+	return policy, nil
+}
+
+// insertEscalationPolicy writes a policy and its levels inside the caller's
+// transaction, applying level defaults and appending the created levels to policy.Levels
+func insertEscalationPolicy(tx *sql.Tx, policy *db.EscalationPolicy, levels []db.EscalationLevel) error {
 	// Insert escalation policy
 	query := `
 		INSERT INTO escalation_policies (
 			id, name, description, is_active, repeat_max_times, 
-			created_at, updated_at, group_id, created_by, escalate_after_minutes
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+			created_at, updated_at, group_id, created_by, escalate_after_minutes,
+			unseen_escalate_after_minutes, reassign_after_minutes, ack_timeout_minutes
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 
-	_, err = tx.Exec(query,
+	_, err := tx.Exec(query,
 		policy.ID, policy.Name, policy.Description, policy.IsActive,
-		policy.RepeatMaxTimes, policy.CreatedAt, policy.UpdatedAt, policy.GroupID, policy.CreatedBy, policy.EscalateAfterMinutes)
+		policy.RepeatMaxTimes, policy.CreatedAt, policy.UpdatedAt, policy.GroupID, policy.CreatedBy, policy.EscalateAfterMinutes,
+		policy.UnseenEscalateAfterMinutes, policy.ReassignAfterMinutes, policy.AckTimeoutMinutes)
 	if err != nil {
 		log.Println("Failed to insert escalation policy:", err)
-		return policy, fmt.Errorf("failed to insert escalation policy: %w", err)
+		return fmt.Errorf("failed to insert escalation policy: %w", err)
 	}
 
 	// Insert escalation levels
-	for _, levelReq := range req.Levels {
+	for _, levelReq := range levels {
 		// Validate target_type
 		validTargetTypes := map[string]bool{
-			"user":             true,
-			"scheduler":        true,
-			"current_schedule": true,
-			"group":            true,
-			"external":         true,
+			"user":              true,
+			"scheduler":         true,
+			"current_schedule":  true,
+			"group":             true,
+			"external":          true,
+			"external_schedule": true,
 		}
 		if !validTargetTypes[levelReq.TargetType] {
-			return policy, fmt.Errorf("invalid target_type '%s' for level %d. Must be one of: user, scheduler, current_schedule, group, external",
+			return fmt.Errorf("invalid target_type '%s' for level %d. Must be one of: user, scheduler, current_schedule, group, external, external_schedule",
 				levelReq.TargetType, levelReq.LevelNumber)
 		}
 
@@ -141,7 +165,7 @@ func (s *EscalationService) CreateEscalationPolicy(groupID string, req db.Escala
 		// Serialize notification methods to JSON
 		notificationMethodsJSON, err := json.Marshal(level.NotificationMethods)
 		if err != nil {
-			return policy, fmt.Errorf("failed to serialize notification methods: %w", err)
+			return fmt.Errorf("failed to serialize notification methods: %w", err)
 		}
 
 		levelQuery := `
@@ -155,19 +179,13 @@ func (s *EscalationService) CreateEscalationPolicy(groupID string, req db.Escala
 			level.TimeoutMinutes, notificationMethodsJSON, level.MessageTemplate, level.CreatedAt)
 		if err != nil {
 			log.Println("Failed to insert escalation level:", err)
-			return policy, fmt.Errorf("failed to insert escalation level: %w", err)
+			return fmt.Errorf("failed to insert escalation level: %w", err)
 		}
 
 		policy.Levels = append(policy.Levels, level)
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return policy, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	log.Printf("Created escalation policy: %s with %d levels", policy.Name, len(policy.Levels))
-	return policy, nil
+	return nil
 }
 
 // UpdateEscalationPolicy updates an existing escalation policy with levels
@@ -185,6 +203,9 @@ func (s *EscalationService) UpdateEscalationPolicy(policyID string, req db.Escal
 	// policy.IsActive = req.IsActive
 	policy.RepeatMaxTimes = req.RepeatMaxTimes
 	policy.EscalateAfterMinutes = req.EscalateAfterMinutes
+	policy.UnseenEscalateAfterMinutes = req.UnseenEscalateAfterMinutes
+	policy.ReassignAfterMinutes = req.ReassignAfterMinutes
+	policy.AckTimeoutMinutes = req.AckTimeoutMinutes
 	policy.UpdatedAt = time.Now()
 
 	// Set defaults
@@ -203,12 +224,14 @@ func (s *EscalationService) UpdateEscalationPolicy(policyID string, req db.Escal
 	updateQuery := `
 		UPDATE escalation_policies 
 		SET name = $2, description = $3, is_active = $4, repeat_max_times = $5,
-			updated_at = $6, escalate_after_minutes = $7
+			updated_at = $6, escalate_after_minutes = $7, unseen_escalate_after_minutes = $8,
+			reassign_after_minutes = $9, ack_timeout_minutes = $10
 		WHERE id = $1`
 
 	_, err = tx.Exec(updateQuery,
 		policy.ID, policy.Name, policy.Description, policy.IsActive,
-		policy.RepeatMaxTimes, policy.UpdatedAt, policy.EscalateAfterMinutes)
+		policy.RepeatMaxTimes, policy.UpdatedAt, policy.EscalateAfterMinutes, policy.UnseenEscalateAfterMinutes,
+		policy.ReassignAfterMinutes, policy.AckTimeoutMinutes)
 	if err != nil {
 		log.Println("Failed to update escalation policy:", err)
 		return policy, fmt.Errorf("failed to update escalation policy: %w", err)
@@ -226,14 +249,15 @@ func (s *EscalationService) UpdateEscalationPolicy(policyID string, req db.Escal
 	for _, levelReq := range req.Levels {
 		// Validate target_type
 		validTargetTypes := map[string]bool{
-			"user":             true,
-			"scheduler":        true,
-			"current_schedule": true,
-			"group":            true,
-			"external":         true,
+			"user":              true,
+			"scheduler":         true,
+			"current_schedule":  true,
+			"group":             true,
+			"external":          true,
+			"external_schedule": true,
 		}
 		if !validTargetTypes[levelReq.TargetType] {
-			return policy, fmt.Errorf("invalid target_type '%s' for level %d. Must be one of: user, scheduler, current_schedule, group, external",
+			return policy, fmt.Errorf("invalid target_type '%s' for level %d. Must be one of: user, scheduler, current_schedule, group, external, external_schedule",
 				levelReq.TargetType, levelReq.LevelNumber)
 		}
 
@@ -292,8 +316,9 @@ func (s *EscalationService) UpdateEscalationPolicy(policyID string, req db.Escal
 	return policy, nil
 }
 
-// DeleteEscalationPolicy deletes an escalation policy and all its levels
-func (s *EscalationService) DeleteEscalationPolicy(policyID string) error {
+// DeleteEscalationPolicy deletes an escalation policy and all its levels,
+// keeping a copy in the trash so it can be restored
+func (s *EscalationService) DeleteEscalationPolicy(policyID, deletedBy string) error {
 	// Start transaction
 	tx, err := s.PG.Begin()
 	if err != nil {
@@ -301,6 +326,28 @@ func (s *EscalationService) DeleteEscalationPolicy(policyID string) error {
 	}
 	defer tx.Rollback()
 
+	// Snapshot the policy, its levels and the groups using it as default
+	var snapshot escalationPolicyTrashSnapshot
+	var policy, levels []byte
+	err = tx.QueryRow(`
+		SELECT to_jsonb(ep),
+		       COALESCE((SELECT jsonb_agg(el) FROM escalation_levels el WHERE el.policy_id = ep.id), '[]'),
+		       COALESCE((SELECT array_agg(g.id::text) FROM groups g WHERE g.default_escalation_policy_id = ep.id), '{}')
+		FROM escalation_policies ep
+		WHERE ep.id = $1
+		FOR UPDATE OF ep
+	`, policyID).Scan(&policy, &levels, pq.Array(&snapshot.DefaultForGroup))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("escalation policy not found: %s", policyID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read escalation policy: %w", err)
+	}
+	snapshot.Policy, snapshot.Levels = policy, levels
+	if err := moveToTrash(tx, db.TrashResourceEscalationPolicy, policyID, deletedBy, snapshot); err != nil {
+		return err
+	}
+
 	// Delete escalation levels first (due to foreign key constraint)
 	deleteLevelsQuery := `DELETE FROM escalation_levels WHERE policy_id = $1`
 	_, err = tx.Exec(deleteLevelsQuery, policyID)
@@ -392,14 +439,16 @@ func (s *EscalationService) GetEscalationPolicyDetail(id string) (db.EscalationP
 		SELECT id, name, description, is_active, repeat_max_times, 
 			   created_at, updated_at, COALESCE(created_by, '') as created_by,
 			   COALESCE(escalate_after_minutes, 0) as escalate_after_minutes,
-			   group_id
+			   group_id, COALESCE(unseen_escalate_after_minutes, 0), COALESCE(reassign_after_minutes, 0),
+			   COALESCE(ack_timeout_minutes, 0)
 		FROM escalation_policies 
 		WHERE id = $1`
 
 	err := s.PG.QueryRow(query, id).Scan(
 		&result.ID, &result.Name, &result.Description, &result.IsActive,
 		&result.RepeatMaxTimes, &result.CreatedAt, &result.UpdatedAt, &result.CreatedBy,
-		&result.EscalateAfterMinutes, &result.GroupID)
+		&result.EscalateAfterMinutes, &result.GroupID, &result.UnseenEscalateAfterMinutes,
+		&result.ReassignAfterMinutes, &result.AckTimeoutMinutes)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Escalation policy not found: %s", id)
@@ -610,6 +659,17 @@ func (s *EscalationService) populateTargetInfoForLevel(level *db.EscalationLevel
 	case "current_schedule":
 		level.TargetName = "Current On-Call"
 		level.TargetDescription = "Currently scheduled person(s)"
+	case db.EscalationTargetExternalSchedule:
+		var name, provider string
+		err := s.PG.QueryRow(`SELECT name, COALESCE(config->>'provider', '') FROM integrations WHERE id = $1`, level.TargetID).Scan(&name, &provider)
+		if err == nil {
+			level.TargetName = name
+			level.TargetDescription = "External on-call schedule (" + provider + ")"
+		} else {
+			log.Printf("External on-call integration not found for ID: %s, error: %v", level.TargetID, err)
+			level.TargetName = "Unknown External Schedule"
+			level.TargetDescription = level.TargetID
+		}
 	case "external":
 		if level.TargetID != "" && level.TargetID != "null" && len(strings.TrimSpace(level.TargetID)) > 0 {
 			level.TargetName = "External Webhook"
@@ -1089,6 +1149,8 @@ func (s *EscalationService) executeEscalationLevel(alert *db.Alert, policy *db.E
 		err = s.notifyGroup(alert, level.TargetID, level.NotificationMethods)
 	case "external":
 		err = s.notifyExternal(alert, level.TargetID, level.NotificationMethods)
+	case db.EscalationTargetExternalSchedule:
+		err = s.notifyExternalSchedule(alert, level.TargetID, level.NotificationMethods)
 	default:
 		err = fmt.Errorf("unknown target type: %s", level.TargetType)
 	}
@@ -1193,6 +1255,25 @@ func (s *EscalationService) notifyExternal(alert *db.Alert, target string, metho
 	return nil
 }
 
+// notifyExternalSchedule notifies the users an external_oncall integration
+// reports on call
+func (s *EscalationService) notifyExternalSchedule(alert *db.Alert, integrationID string, methods []string) error {
+	candidates, _, err := externalOnCallCandidates(s.PG, externalOnCallClient, integrationID)
+	if err != nil {
+		return err
+	}
+	userIDs, _ := pickOnCallResponders(candidates, true)
+	if len(userIDs) == 0 {
+		return fmt.Errorf("no available users on call in external schedule %s", integrationID)
+	}
+	for _, userID := range userIDs {
+		if err := s.notifyUser(alert, userID, methods); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // scheduleNextEscalationStep schedules the next escalation step (all targets in parallel)
 func (s *EscalationService) scheduleNextEscalationStep(alert *db.Alert, policy *db.EscalationPolicyWithLevels, stepNumber int, delay time.Duration) {
 	// TODO: Implement escalation scheduling using Redis or background jobs
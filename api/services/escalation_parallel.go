@@ -0,0 +1,247 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// ESCALATION: PARALLEL TARGETS
+
+// ParallelPagedUsersKey is the escalated/assigned event field listing everyone
+// a level paged at once. The first of them to acknowledge claims the incident.
+const ParallelPagedUsersKey = "paged_user_ids"
+
+// ResolveEscalationLevelUsers returns everyone an escalation level pages: the
+// users behind each of its targets, in target order and without repeats.
+// Group targets page every member currently on call when the group's
+// escalation_method is parallel, otherwise only the first. External
+// schedule targets page everyone their provider reports on call who maps to
+// a user; a provider that can't be reached pages nobody, so the level's
+// other targets still go out. Webhook (external) targets page nobody here.
+// Schedule and group targets pass over anyone on vacation or DND and fall
+// through to the next person on call; those are returned as skipped.
+func ResolveEscalationLevelUsers(pg *sql.DB, incidentGroupID string, targets []db.EscalationLevel) ([]string, []db.SkippedResponder, error) {
+	users := []string{}
+	seen := map[string]bool{}
+	add := func(ids ...string) {
+		for _, id := range ids {
+			if id != "" && !seen[id] {
+				seen[id] = true
+				users = append(users, id)
+			}
+		}
+	}
+	var skipped []string
+
+	for _, target := range targets {
+		switch target.TargetType {
+		case "user":
+			add(target.TargetID)
+		case "scheduler":
+			candidates, err := queryOnCallCandidates(pg, `
+				SELECT effective_user_id, 0, user_available(effective_user_id, NOW())
+				FROM effective_shifts
+				WHERE scheduler_id = $1 AND group_id = $2
+				AND start_time <= NOW() AND end_time >= NOW()
+				ORDER BY start_time ASC
+			`, target.TargetID, incidentGroupID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get on-call user for scheduler %s: %w", target.TargetID, err)
+			}
+			ids, passed := pickOnCallResponders(candidates, false)
+			add(ids...)
+			skipped = append(skipped, passed...)
+		case "group", "current_schedule":
+			groupID := target.TargetID
+			if target.TargetType == "current_schedule" {
+				groupID = incidentGroupID
+			}
+			ids, passed, err := groupOnCallUsers(pg, groupID)
+			if err != nil {
+				return nil, nil, err
+			}
+			add(ids...)
+			skipped = append(skipped, passed...)
+		case db.EscalationTargetExternalSchedule:
+			candidates, _, err := externalOnCallCandidates(pg, externalOnCallClient, target.TargetID)
+			if err != nil {
+				log.Printf("⚠️  Failed to get on-call users from external schedule %s: %v", target.TargetID, err)
+				continue
+			}
+			ids, passed := pickOnCallResponders(candidates, true)
+			add(ids...)
+			skipped = append(skipped, passed...)
+		}
+	}
+
+	var passedOver []string
+	for _, id := range skipped {
+		if !seen[id] && !containsString(passedOver, id) {
+			passedOver = append(passedOver, id)
+		}
+	}
+	details, err := describeSkippedResponders(pg, passedOver)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users, details, nil
+}
+
+// groupOnCallUsers returns who a group target pages: everyone available on
+// call in the group (or its nearest sub-team with someone available) for
+// parallel groups, otherwise just the first of them, and the unavailable
+// people passed over
+func groupOnCallUsers(pg *sql.DB, groupID string) ([]string, []string, error) {
+	if groupID == "" {
+		return nil, nil, nil
+	}
+	var method string
+	if err := pg.QueryRow(`SELECT COALESCE(escalation_method, '') FROM groups WHERE id = $1`, groupID).Scan(&method); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get group %s: %w", groupID, err)
+	}
+
+	candidates, err := queryOnCallCandidates(pg, `
+		SELECT es.effective_user_id, gs.depth, user_available(es.effective_user_id, NOW())
+		FROM effective_shifts es
+		JOIN group_subtree($1) gs ON gs.group_id = es.group_id
+		WHERE es.start_time <= NOW() AND es.end_time >= NOW()
+		ORDER BY gs.depth ASC, es.start_time ASC
+	`, groupID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get on-call users for group %s: %w", groupID, err)
+	}
+	users, skipped := pickOnCallResponders(candidates, method == db.EscalationMethodParallel)
+	return users, skipped, nil
+}
+
+// queryOnCallCandidates runs a query returning user ID, depth and availability
+func queryOnCallCandidates(pg *sql.DB, query string, args ...interface{}) ([]onCallCandidate, error) {
+	rows, err := pg.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []onCallCandidate
+	for rows.Next() {
+		var c onCallCandidate
+		if err := rows.Scan(&c.UserID, &c.Depth, &c.Available); err != nil {
+			return nil, fmt.Errorf("failed to scan on-call user: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// claimParallelPage makes the acknowledger the assignee when they were one
+// of several responders the current level paged in parallel, and tells the
+// others it has been claimed
+func (s *IncidentService) claimParallelPage(incidentID, userID string) {
+	var raw []byte
+	err := s.PG.QueryRow(`
+		SELECT event_data->'`+ParallelPagedUsersKey+`'
+		FROM incident_events
+		WHERE incident_id = $1 AND event_type IN ($2, $3)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, incidentID, db.IncidentEventEscalated, db.IncidentEventAssigned).Scan(&raw)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("⚠️  Failed to check parallel page for incident %s: %v", incidentID, err)
+		}
+		return
+	}
+	var paged []string
+	if len(raw) == 0 || json.Unmarshal(raw, &paged) != nil || len(paged) < 2 || !containsString(paged, userID) {
+		return
+	}
+
+	result, err := s.PG.Exec(`
+		UPDATE incidents SET assigned_to = $2::uuid, assigned_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND assigned_to IS DISTINCT FROM $2::uuid
+	`, incidentID, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to assign incident %s to claimer %s: %v", incidentID, userID, err)
+		return
+	}
+
+	var userName string
+	if err := s.PG.QueryRow(`SELECT COALESCE(name, email, 'Someone') FROM users WHERE id = $1`, userID).Scan(&userName); err != nil {
+		userName = "Someone"
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.createIncidentEvent(incidentID, db.IncidentEventAssigned, map[string]interface{}{
+			"assigned_to_id": userID,
+			"assigned_to":    userName,
+			"method":         "parallel_claim",
+			"reason":         "first to acknowledge a parallel page",
+		}, userID)
+	}
+
+	if s.NotificationWorker == nil {
+		return
+	}
+	message := userName + " acknowledged and claimed this incident"
+	for _, other := range paged {
+		if other == userID {
+			continue
+		}
+		if err := s.NotificationWorker.SendIncidentClaimedNotification(other, incidentID, message); err != nil {
+			log.Printf("⚠️  Failed to tell %s incident %s was claimed: %v", other, incidentID, err)
+		}
+	}
+}
+
+// firstLevelParallelUsers returns everyone a new incident's first escalation
+// level pages alongside its assignee, assignee first, when that is more than
+// one responder: several level 1 targets, or a group escalating in parallel
+func (s *IncidentService) firstLevelParallelUsers(incident *db.Incident) []string {
+	if incident.EscalationPolicyID == "" || incident.AssignedTo == "" {
+		return nil
+	}
+	rows, err := s.PG.Query(`
+		SELECT target_type, COALESCE(target_id::text, '')
+		FROM escalation_levels
+		WHERE policy_id = $1 AND level_number = 1
+		ORDER BY created_at ASC
+	`, incident.EscalationPolicyID)
+	if err != nil {
+		log.Printf("⚠️  Failed to get first escalation level for incident %s: %v", incident.ID, err)
+		return nil
+	}
+	var targets []db.EscalationLevel
+	for rows.Next() {
+		var target db.EscalationLevel
+		if err := rows.Scan(&target.TargetType, &target.TargetID); err == nil {
+			targets = append(targets, target)
+		}
+	}
+	rows.Close()
+	if len(targets) == 0 || (len(targets) == 1 && targets[0].TargetType != "group" && targets[0].TargetType != "current_schedule" &&
+		targets[0].TargetType != db.EscalationTargetExternalSchedule) {
+		return nil
+	}
+
+	resolved, _, err := ResolveEscalationLevelUsers(s.PG, incident.GroupID, targets)
+	if err != nil {
+		log.Printf("⚠️  Failed to resolve first escalation level for incident %s: %v", incident.ID, err)
+		return nil
+	}
+	users := []string{incident.AssignedTo}
+	for _, userID := range resolved {
+		if userID != incident.AssignedTo {
+			users = append(users, userID)
+		}
+	}
+	if len(users) < 2 {
+		return nil
+	}
+	return users
+}
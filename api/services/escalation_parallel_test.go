@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestResolveEscalationLevelUsersPagesParallelGroup(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT COALESCE\(escalation_method, ''\) FROM groups`).WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"escalation_method"}).AddRow(db.EscalationMethodParallel))
+	mock.ExpectQuery(`JOIN group_subtree\(\$1\)`).WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"effective_user_id", "depth", "available"}).
+			AddRow("alice", 0, true).AddRow("bob", 0, true).AddRow("alice", 0, true))
+
+	users, skipped, err := ResolveEscalationLevelUsers(pg, "grp-1", []db.EscalationLevel{
+		{TargetType: "user", TargetID: "bob"},
+		{TargetType: "group", TargetID: "grp-1"},
+		{TargetType: "external", TargetID: "hook-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0] != "bob" || users[1] != "alice" {
+		t.Errorf("expected [bob alice] without repeats, got %v", users)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped = %+v", skipped)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+type claimRecordingSender struct {
+	LightweightNotificationSender
+	claimed []string
+}
+
+func (r *claimRecordingSender) SendIncidentAcknowledgedNotification(userID, incidentID string) error {
+	return nil
+}
+
+func (r *claimRecordingSender) SendIncidentClaimedNotification(userID, incidentID, message string) error {
+	r.claimed = append(r.claimed, userID)
+	return nil
+}
+
+func TestAcknowledgeClaimsParallelPage(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectExec(`UPDATE incidents\s+SET status = \$1, acknowledged_by`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT event_data->'paged_user_ids'`).
+		WithArgs("inc-1", db.IncidentEventEscalated, db.IncidentEventAssigned).
+		WillReturnRows(sqlmock.NewRows([]string{"paged"}).AddRow([]byte(`["alice","bob","carol"]`)))
+	mock.ExpectExec(`UPDATE incidents SET assigned_to = \$2::uuid`).WithArgs("inc-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COALESCE\(name, email, 'Someone'\) FROM users`).WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Bob"))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventAssigned, sqlmock.AnyArg(), "bob").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventAcknowledged, sqlmock.AnyArg(), "bob").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	sender := &claimRecordingSender{}
+	svc := NewIncidentService(pg, nil)
+	svc.NotificationWorker = sender
+
+	if err := svc.AcknowledgeIncident("inc-1", "bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.claimed) != 2 || sender.claimed[0] != "alice" || sender.claimed[1] != "carol" {
+		t.Errorf("expected alice and carol to hear it was claimed, got %v", sender.claimed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// Timeout the escalation worker assumes when a level has none configured
+const defaultEscalationTimeoutMinutes = 5
+
+// GetEscalationTimeline returns the escalation history of an incident and the
+// levels still to come, with ETAs computed the same way the escalation worker
+// schedules them (last_escalated_at, or created_at, plus the current level's timeout)
+func (s *IncidentService) GetEscalationTimeline(incident *db.IncidentResponse) (*db.EscalationTimeline, error) {
+	now := time.Now().UTC()
+	if incident.EscalationPolicyID == "" {
+		return buildEscalationTimeline(&incident.Incident, nil, nil, now), nil
+	}
+
+	levels, err := s.getTimelineEscalationLevels(incident.EscalationPolicyID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.getEscalationHistory(incident.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := buildEscalationTimeline(&incident.Incident, levels, history, now)
+	timeline.EscalationPolicyName = incident.EscalationPolicyName
+
+	for i := range timeline.Steps {
+		step := &timeline.Steps[i]
+		switch {
+		case step.State == db.EscalationStepActive && incident.AssignedTo != "":
+			step.ResolvedUserID, step.ResolvedUserName = incident.AssignedTo, incident.AssignedToName
+		case history[step.Level].UserID != "":
+			step.ResolvedUserID, step.ResolvedUserName = history[step.Level].UserID, history[step.Level].UserName
+		case step.EscalatedAt != nil:
+			step.ResolvedUserID, step.ResolvedUserName = s.resolveEscalationTargetAt(*step, incident.GroupID, *step.EscalatedAt)
+		case step.ETA != nil:
+			step.ResolvedUserID, step.ResolvedUserName = s.resolveEscalationTargetAt(*step, incident.GroupID, *step.ETA)
+		}
+	}
+
+	return timeline, nil
+}
+
+// escalationRecord is a past escalation taken from the incident's "escalated" events
+type escalationRecord struct {
+	At       time.Time
+	UserID   string
+	UserName string
+}
+
+// buildEscalationTimeline lays out the policy levels relative to the incident's
+// current level. Level 1 is assigned when the incident is created; the worker
+// moves to the next level once the current level's timeout has elapsed.
+func buildEscalationTimeline(incident *db.Incident, levels []db.EscalationLevel, history map[int]escalationRecord, now time.Time) *db.EscalationTimeline {
+	timeline := &db.EscalationTimeline{
+		IncidentID:         incident.ID,
+		Status:             incident.Status,
+		EscalationStatus:   incident.EscalationStatus,
+		EscalationPolicyID: incident.EscalationPolicyID,
+		CurrentLevel:       incident.CurrentEscalationLevel,
+		LastEscalatedAt:    incident.LastEscalatedAt,
+		GeneratedAt:        now,
+		Steps:              []db.EscalationTimelineStep{},
+	}
+
+	current := incident.CurrentEscalationLevel
+	escalating := incident.Status == db.IncidentStatusTriggered &&
+		(incident.EscalationStatus == "none" || incident.EscalationStatus == "pending" || incident.EscalationStatus == "")
+
+	timeouts := make(map[int]int, len(levels))
+	for _, level := range levels {
+		timeouts[level.LevelNumber] = level.GetEffectiveTimeout(defaultEscalationTimeoutMinutes)
+	}
+
+	// The next level is paged once the current one times out
+	next := incident.CreatedAt
+	if incident.LastEscalatedAt != nil {
+		next = *incident.LastEscalatedAt
+	}
+	waitLevel := current
+	if waitLevel < 1 {
+		waitLevel = 1
+	}
+	next = next.Add(time.Duration(timeouts[waitLevel]) * time.Minute)
+
+	for _, level := range levels {
+		step := db.EscalationTimelineStep{
+			Level:          level.LevelNumber,
+			TargetType:     level.TargetType,
+			TargetID:       level.TargetID,
+			TargetName:     level.TargetName,
+			TimeoutMinutes: timeouts[level.LevelNumber],
+		}
+
+		switch {
+		case level.LevelNumber < current:
+			step.State = db.EscalationStepCompleted
+		case level.LevelNumber == current:
+			step.State = db.EscalationStepActive
+			if incident.Status == db.IncidentStatusResolved {
+				step.State = db.EscalationStepCompleted
+			}
+		case escalating:
+			step.State = db.EscalationStepScheduled
+			eta := next
+			step.ETA = &eta
+			next = next.Add(time.Duration(timeouts[level.LevelNumber]) * time.Minute)
+			if timeline.NextEscalationAt == nil {
+				timeline.NextEscalationAt = step.ETA
+			}
+		default:
+			step.State = db.EscalationStepNotReached
+		}
+
+		if level.LevelNumber <= current {
+			if record, ok := history[level.LevelNumber]; ok {
+				at := record.At
+				step.EscalatedAt = &at
+			} else if level.LevelNumber == 1 {
+				at := incident.CreatedAt
+				step.EscalatedAt = &at
+			}
+		}
+
+		timeline.Steps = append(timeline.Steps, step)
+	}
+
+	if timeline.NextEscalationAt != nil {
+		seconds := int64(timeline.NextEscalationAt.Sub(now).Seconds())
+		if seconds < 0 {
+			seconds = 0 // overdue, the worker will pick it up on its next pass
+		}
+		timeline.SecondsUntilNext = &seconds
+	}
+
+	return timeline
+}
+
+// getTimelineEscalationLevels loads a policy's levels with display names for their targets
+func (s *IncidentService) getTimelineEscalationLevels(policyID string) ([]db.EscalationLevel, error) {
+	rows, err := s.PG.Query(`
+		SELECT el.level_number, el.target_type, COALESCE(el.target_id::text, ''), el.timeout_minutes,
+		       COALESCE(u.name, g.name, sc.name, '') as target_name
+		FROM escalation_levels el
+		LEFT JOIN users u ON el.target_type = 'user' AND u.id = el.target_id
+		LEFT JOIN groups g ON el.target_type = 'group' AND g.id = el.target_id
+		LEFT JOIN schedulers sc ON el.target_type = 'scheduler' AND sc.id = el.target_id
+		WHERE el.policy_id = $1
+		ORDER BY el.level_number ASC
+	`, policyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query escalation levels: %w", err)
+	}
+	defer rows.Close()
+
+	var levels []db.EscalationLevel
+	for rows.Next() {
+		level := db.EscalationLevel{PolicyID: policyID}
+		if err := rows.Scan(&level.LevelNumber, &level.TargetType, &level.TargetID, &level.TimeoutMinutes, &level.TargetName); err != nil {
+			return nil, fmt.Errorf("failed to scan escalation level: %w", err)
+		}
+		if level.TargetType == "current_schedule" {
+			level.TargetName = "Current On-Call"
+		}
+		levels = append(levels, level)
+	}
+	return levels, rows.Err()
+}
+
+// getEscalationHistory returns when each level was paged, keyed by level number
+func (s *IncidentService) getEscalationHistory(incidentID string) (map[int]escalationRecord, error) {
+	rows, err := s.PG.Query(`
+		SELECT event_data, created_at
+		FROM incident_events
+		WHERE incident_id = $1 AND event_type = $2
+		ORDER BY created_at ASC
+	`, incidentID, db.IncidentEventEscalated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query escalation events: %w", err)
+	}
+	defer rows.Close()
+
+	history := map[int]escalationRecord{}
+	for rows.Next() {
+		var eventDataJSON []byte
+		var createdAt time.Time
+		if err := rows.Scan(&eventDataJSON, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan escalation event: %w", err)
+		}
+
+		var eventData struct {
+			EscalationLevel int    `json:"escalation_level"`
+			AssignedToID    string `json:"assigned_to_id"`
+			AssignedTo      string `json:"assigned_to"`
+		}
+		if err := json.Unmarshal(eventDataJSON, &eventData); err != nil || eventData.EscalationLevel == 0 {
+			continue // manual escalations don't record a level
+		}
+		// Keep the latest escalation to each level
+		history[eventData.EscalationLevel] = escalationRecord{
+			At:       createdAt,
+			UserID:   eventData.AssignedToID,
+			UserName: eventData.AssignedTo,
+		}
+	}
+	return history, rows.Err()
+}
+
+// resolveEscalationTargetAt returns the user a level pages at the given time,
+// passing over anyone unavailable then as the escalation worker does
+func (s *IncidentService) resolveEscalationTargetAt(step db.EscalationTimelineStep, incidentGroupID string, at time.Time) (string, string) {
+	var query, target string
+	switch step.TargetType {
+	case "user":
+		return step.TargetID, step.TargetName
+	case "scheduler":
+		query, target = `
+			SELECT effective_user_id, COALESCE(user_name, '')
+			FROM effective_shifts
+			WHERE scheduler_id = $1 AND start_time <= $2 AND end_time >= $2
+			AND user_available(effective_user_id, $2)
+			ORDER BY start_time DESC
+			LIMIT 1
+		`, step.TargetID
+	case "group", "current_schedule":
+		target = step.TargetID
+		if step.TargetType == "current_schedule" {
+			target = incidentGroupID
+		}
+		// Sub-teams cover for the group, nearest first, as in the escalation worker
+		query = `
+			SELECT es.effective_user_id, COALESCE(es.user_name, '')
+			FROM effective_shifts es
+			JOIN group_subtree($1) gs ON gs.group_id = es.group_id
+			WHERE es.start_time <= $2 AND es.end_time >= $2
+			AND user_available(es.effective_user_id, $2)
+			ORDER BY gs.depth ASC, es.start_time DESC
+			LIMIT 1
+		`
+	default:
+		return "", ""
+	}
+	if target == "" {
+		return "", ""
+	}
+
+	var userID, userName string
+	if err := s.PG.QueryRow(query, target, at).Scan(&userID, &userName); err != nil && err != sql.ErrNoRows {
+		log.Printf("WARNING: Failed to resolve escalation target for level %d: %v", step.Level, err)
+	}
+	return userID, userName
+}
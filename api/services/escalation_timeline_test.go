@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestBuildEscalationTimeline(t *testing.T) {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	levels := []db.EscalationLevel{
+		{LevelNumber: 1, TargetType: "scheduler", TimeoutMinutes: 10},
+		{LevelNumber: 2, TargetType: "group", TimeoutMinutes: 15},
+		{LevelNumber: 3, TargetType: "user", TimeoutMinutes: 30},
+	}
+
+	t.Run("freshly triggered incident counts down to level 2", func(t *testing.T) {
+		incident := &db.Incident{
+			ID: "inc-1", Status: db.IncidentStatusTriggered, EscalationStatus: "none",
+			EscalationPolicyID: "pol-1", CurrentEscalationLevel: 1, CreatedAt: created,
+		}
+		now := created.Add(4 * time.Minute)
+		timeline := buildEscalationTimeline(incident, levels, nil, now)
+
+		wantStates := []string{db.EscalationStepActive, db.EscalationStepScheduled, db.EscalationStepScheduled}
+		for i, step := range timeline.Steps {
+			if step.State != wantStates[i] {
+				t.Errorf("step %d state = %q, want %q", step.Level, step.State, wantStates[i])
+			}
+		}
+		if got := timeline.Steps[0].EscalatedAt; got == nil || !got.Equal(created) {
+			t.Errorf("level 1 escalated_at = %v, want %v", got, created)
+		}
+		if got := *timeline.Steps[1].ETA; !got.Equal(created.Add(10 * time.Minute)) {
+			t.Errorf("level 2 ETA = %v", got)
+		}
+		if got := *timeline.Steps[2].ETA; !got.Equal(created.Add(25 * time.Minute)) {
+			t.Errorf("level 3 ETA = %v", got)
+		}
+		if timeline.SecondsUntilNext == nil || *timeline.SecondsUntilNext != 360 {
+			t.Errorf("seconds_until_next = %v, want 360", timeline.SecondsUntilNext)
+		}
+	})
+
+	t.Run("escalated incident waits on the current level timeout", func(t *testing.T) {
+		escalated := created.Add(11 * time.Minute)
+		incident := &db.Incident{
+			ID: "inc-2", Status: db.IncidentStatusTriggered, EscalationStatus: "pending",
+			EscalationPolicyID: "pol-1", CurrentEscalationLevel: 2, CreatedAt: created, LastEscalatedAt: &escalated,
+		}
+		history := map[int]escalationRecord{2: {At: escalated, UserID: "u-2", UserName: "Bob"}}
+		timeline := buildEscalationTimeline(incident, levels, history, escalated.Add(20*time.Minute))
+
+		if timeline.Steps[0].State != db.EscalationStepCompleted || timeline.Steps[1].State != db.EscalationStepActive {
+			t.Errorf("unexpected states %q, %q", timeline.Steps[0].State, timeline.Steps[1].State)
+		}
+		if got := timeline.Steps[1].EscalatedAt; got == nil || !got.Equal(escalated) {
+			t.Errorf("level 2 escalated_at = %v, want %v", got, escalated)
+		}
+		if got := *timeline.NextEscalationAt; !got.Equal(escalated.Add(15 * time.Minute)) {
+			t.Errorf("next_escalation_at = %v", got)
+		}
+		if *timeline.SecondsUntilNext != 0 {
+			t.Errorf("overdue escalation should report 0 seconds, got %d", *timeline.SecondsUntilNext)
+		}
+	})
+
+	t.Run("acknowledged incident stops the countdown", func(t *testing.T) {
+		incident := &db.Incident{
+			ID: "inc-3", Status: db.IncidentStatusAcknowledged, EscalationStatus: "stopped",
+			EscalationPolicyID: "pol-1", CurrentEscalationLevel: 1, CreatedAt: created,
+		}
+		timeline := buildEscalationTimeline(incident, levels, nil, created.Add(time.Minute))
+
+		if timeline.NextEscalationAt != nil || timeline.SecondsUntilNext != nil {
+			t.Error("acknowledged incident should have no next escalation")
+		}
+		for _, step := range timeline.Steps[1:] {
+			if step.State != db.EscalationStepNotReached || step.ETA != nil {
+				t.Errorf("level %d = %q with ETA %v, want not_reached", step.Level, step.State, step.ETA)
+			}
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+	"gopkg.in/yaml.v3"
+)
+
+// EscalationPolicyKind identifies an escalation policy YAML document
+const EscalationPolicyKind = "EscalationPolicy"
+
+var (
+	ErrEscalationPolicyNotFound = errors.New("escalation policy not found")
+	ErrInvalidPolicyYAML        = errors.New("invalid escalation policy YAML")
+)
+
+// escalationPolicyDoc is the YAML form of an escalation policy. Targets are
+// referenced by stable names instead of IDs so a file can be reviewed in git
+// and applied to another SLAR instance:
+//
+//	kind: EscalationPolicy
+//	name: Payments primary
+//	escalate_after_minutes: 5
+//	levels:
+//	  - level: 1
+//	    target: {type: scheduler, scheduler: Payments weekly}
+//	  - level: 2
+//	    target: {type: user, user: dana@example.com}
+//	    notification_methods: [push, sms]
+type escalationPolicyDoc struct {
+	Kind                       string               `yaml:"kind"`
+	Name                       string               `yaml:"name"`
+	Description                string               `yaml:"description,omitempty"`
+	RepeatMaxTimes             int                  `yaml:"repeat_max_times,omitempty"`
+	EscalateAfterMinutes       int                  `yaml:"escalate_after_minutes,omitempty"`
+	UnseenEscalateAfterMinutes int                  `yaml:"unseen_escalate_after_minutes,omitempty"`
+	ReassignAfterMinutes       int                  `yaml:"reassign_after_minutes,omitempty"`
+	AckTimeoutMinutes          int                  `yaml:"ack_timeout_minutes,omitempty"`
+	Levels                     []escalationLevelDoc `yaml:"levels"`
+}
+
+type escalationLevelDoc struct {
+	Level               int                 `yaml:"level"`
+	Target              escalationTargetDoc `yaml:"target"`
+	TimeoutMinutes      int                 `yaml:"timeout_minutes,omitempty"`
+	NotificationMethods []string            `yaml:"notification_methods,omitempty,flow"`
+	MessageTemplate     string              `yaml:"message_template,omitempty"`
+}
+
+// escalationTargetDoc names a level's target: a user by email, a group by
+// name within the organization, a scheduler by name within the policy's
+// group, or an external webhook by URL
+type escalationTargetDoc struct {
+	Type      string `yaml:"type"`
+	User      string `yaml:"user,omitempty"`
+	Group     string `yaml:"group,omitempty"`
+	Scheduler string `yaml:"scheduler,omitempty"`
+	URL       string `yaml:"url,omitempty"`
+	// Integration names the external_oncall integration of an external_schedule target
+	Integration string `yaml:"integration,omitempty"`
+}
+
+// ExportEscalationPolicyYAML renders a group's escalation policy as YAML
+func (s *EscalationService) ExportEscalationPolicyYAML(groupID, policyID string) ([]byte, error) {
+	policy, err := s.GetEscalationPolicyDetail(policyID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrEscalationPolicyNotFound
+		}
+		return nil, err
+	}
+	if policy.GroupID != groupID {
+		return nil, ErrEscalationPolicyNotFound
+	}
+
+	levels, err := s.GetEscalationLevels(policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := escalationPolicyDoc{
+		Kind:                       EscalationPolicyKind,
+		Name:                       policy.Name,
+		Description:                policy.Description,
+		RepeatMaxTimes:             policy.RepeatMaxTimes,
+		EscalateAfterMinutes:       policy.EscalateAfterMinutes,
+		UnseenEscalateAfterMinutes: policy.UnseenEscalateAfterMinutes,
+		ReassignAfterMinutes:       policy.ReassignAfterMinutes,
+		AckTimeoutMinutes:          policy.AckTimeoutMinutes,
+		Levels:                     make([]escalationLevelDoc, 0, len(levels)),
+	}
+	for _, level := range levels {
+		target, err := s.exportEscalationTarget(level)
+		if err != nil {
+			return nil, err
+		}
+		doc.Levels = append(doc.Levels, escalationLevelDoc{
+			Level:               level.LevelNumber,
+			Target:              target,
+			TimeoutMinutes:      level.TimeoutMinutes,
+			NotificationMethods: level.NotificationMethods,
+			MessageTemplate:     level.MessageTemplate,
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode escalation policy: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportEscalationTarget replaces a level's target ID with its stable name
+func (s *EscalationService) exportEscalationTarget(level db.EscalationLevel) (escalationTargetDoc, error) {
+	target := escalationTargetDoc{Type: level.TargetType}
+	var err error
+	switch level.TargetType {
+	case "user":
+		err = s.PG.QueryRow(`SELECT email FROM users WHERE id = $1`, level.TargetID).Scan(&target.User)
+	case "group":
+		err = s.PG.QueryRow(`SELECT name FROM groups WHERE id = $1`, level.TargetID).Scan(&target.Group)
+	case "scheduler":
+		err = s.PG.QueryRow(`SELECT name FROM schedulers WHERE id = $1`, level.TargetID).Scan(&target.Scheduler)
+	case "external":
+		target.URL = level.TargetID
+	case db.EscalationTargetExternalSchedule:
+		err = s.PG.QueryRow(`SELECT name FROM integrations WHERE id = $1`, level.TargetID).Scan(&target.Integration)
+	}
+	if err == sql.ErrNoRows {
+		return target, fmt.Errorf("level %d: %s %s no longer exists", level.LevelNumber, level.TargetType, level.TargetID)
+	}
+	if err != nil {
+		return target, fmt.Errorf("failed to resolve level %d target: %w", level.LevelNumber, err)
+	}
+	return target, nil
+}
+
+// parseEscalationPolicyYAML decodes and checks a policy document without
+// touching the database. Unknown fields are rejected so typos don't pass
+// review silently.
+func parseEscalationPolicyYAML(data []byte) (*escalationPolicyDoc, error) {
+	var doc escalationPolicyDoc
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicyYAML, err)
+	}
+
+	var problems []string
+	if doc.Kind != EscalationPolicyKind {
+		problems = append(problems, fmt.Sprintf("kind must be %q", EscalationPolicyKind))
+	}
+	doc.Name = strings.TrimSpace(doc.Name)
+	if doc.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if len(doc.Levels) == 0 {
+		problems = append(problems, "at least one level is required")
+	}
+	seen := map[int]bool{}
+	for i := range doc.Levels {
+		level := &doc.Levels[i]
+		if level.Level == 0 {
+			level.Level = i + 1
+		}
+		if seen[level.Level] {
+			problems = append(problems, fmt.Sprintf("level %d appears more than once", level.Level))
+		}
+		seen[level.Level] = true
+
+		t := level.Target
+		var ref string
+		switch t.Type {
+		case "user":
+			ref = t.User
+		case "group":
+			ref = t.Group
+		case "scheduler":
+			ref = t.Scheduler
+		case "external":
+			ref = t.URL
+		case db.EscalationTargetExternalSchedule:
+			ref = t.Integration
+		case "current_schedule":
+			ref = "-"
+		default:
+			problems = append(problems, fmt.Sprintf("level %d: target type must be one of user, group, scheduler, current_schedule, external, external_schedule", level.Level))
+			continue
+		}
+		if strings.TrimSpace(ref) == "" {
+			problems = append(problems, fmt.Sprintf("level %d: %s target needs a %s", level.Level, t.Type, escalationTargetField(t.Type)))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicyYAML, strings.Join(problems, "; "))
+	}
+	return &doc, nil
+}
+
+func escalationTargetField(targetType string) string {
+	switch targetType {
+	case "external":
+		return "url"
+	case db.EscalationTargetExternalSchedule:
+		return "integration"
+	}
+	return targetType
+}
+
+// resolveEscalationTarget looks up the ID a level's target name refers to
+func (s *EscalationService) resolveEscalationTarget(groupID string, level escalationLevelDoc) (string, error) {
+	t := level.Target
+	var rows *sql.Rows
+	var err error
+	switch t.Type {
+	case "current_schedule":
+		return "", nil
+	case "external":
+		return strings.TrimSpace(t.URL), nil
+	case "user":
+		rows, err = s.PG.Query(`SELECT id FROM users WHERE LOWER(email) = LOWER($1)`, strings.TrimSpace(t.User))
+	case "group":
+		rows, err = s.PG.Query(`
+			SELECT g.id FROM groups g
+			WHERE g.name = $1
+			AND g.organization_id IS NOT DISTINCT FROM (SELECT organization_id FROM groups WHERE id = $2)
+		`, strings.TrimSpace(t.Group), groupID)
+	case "scheduler":
+		rows, err = s.PG.Query(`SELECT id FROM schedulers WHERE group_id = $1 AND name = $2 AND is_active = true`,
+			groupID, strings.TrimSpace(t.Scheduler))
+	case db.EscalationTargetExternalSchedule:
+		rows, err = s.PG.Query(`
+			SELECT i.id FROM integrations i
+			WHERE i.type = $1 AND i.name = $2 AND i.is_active = true
+			AND i.organization_id IS NOT DISTINCT FROM (SELECT organization_id FROM groups WHERE id = $3)
+		`, db.ExternalOnCallIntegrationType, strings.TrimSpace(t.Integration), groupID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve level %d target: %w", level.Level, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", fmt.Errorf("failed to resolve level %d target: %w", level.Level, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to resolve level %d target: %w", level.Level, err)
+	}
+
+	name := t.User + t.Group + t.Scheduler + t.Integration
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%w: level %d: no %s named %q", ErrInvalidPolicyYAML, level.Level, t.Type, name)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%w: level %d: more than one %s named %q", ErrInvalidPolicyYAML, level.Level, t.Type, name)
+	}
+}
+
+// ImportEscalationPolicyYAML creates or updates a group's escalation policy
+// from YAML. A policy with the same name in the group is updated in place,
+// otherwise a new one is created. With dryRun the document is validated and
+// resolved but nothing is saved.
+func (s *EscalationService) ImportEscalationPolicyYAML(groupID, userID string, data []byte, dryRun bool) (policy db.EscalationPolicy, created bool, err error) {
+	doc, err := parseEscalationPolicyYAML(data)
+	if err != nil {
+		return policy, false, err
+	}
+
+	policy = db.EscalationPolicy{
+		Name:                       doc.Name,
+		Description:                doc.Description,
+		IsActive:                   true,
+		RepeatMaxTimes:             doc.RepeatMaxTimes,
+		EscalateAfterMinutes:       doc.EscalateAfterMinutes,
+		UnseenEscalateAfterMinutes: doc.UnseenEscalateAfterMinutes,
+		ReassignAfterMinutes:       doc.ReassignAfterMinutes,
+		AckTimeoutMinutes:          doc.AckTimeoutMinutes,
+		GroupID:                    groupID,
+		CreatedBy:                  userID,
+	}
+	for _, levelDoc := range doc.Levels {
+		targetID, err := s.resolveEscalationTarget(groupID, levelDoc)
+		if err != nil {
+			return policy, false, err
+		}
+		policy.Levels = append(policy.Levels, db.EscalationLevel{
+			LevelNumber:         levelDoc.Level,
+			TargetType:          levelDoc.Target.Type,
+			TargetID:            targetID,
+			TimeoutMinutes:      levelDoc.TimeoutMinutes,
+			NotificationMethods: levelDoc.NotificationMethods,
+			MessageTemplate:     levelDoc.MessageTemplate,
+		})
+	}
+
+	var existingID string
+	err = s.PG.QueryRow(`
+		SELECT id FROM escalation_policies
+		WHERE group_id = $1 AND name = $2
+		ORDER BY created_at ASC
+		LIMIT 1
+	`, groupID, doc.Name).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return policy, false, fmt.Errorf("failed to look up escalation policy: %w", err)
+	}
+	created = existingID == ""
+
+	if dryRun {
+		policy.ID = existingID
+		return policy, created, nil
+	}
+	if created {
+		policy, err = s.CreateEscalationPolicy(groupID, policy)
+	} else {
+		policy, err = s.UpdateEscalationPolicy(existingID, policy)
+	}
+	return policy, created, err
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const samplePolicyYAML = `kind: EscalationPolicy
+name: Payments primary
+escalate_after_minutes: 5
+levels:
+  - target: {type: scheduler, scheduler: Payments weekly}
+  - target: {type: user, user: Dana@example.com}
+    notification_methods: [push, sms]
+`
+
+func TestParseEscalationPolicyYAMLRejectsBadDocuments(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":  "kind: EscalationPolicy\nname: x\nlevles: []\n",
+		"wrong kind":     "kind: Schedule\nname: x\nlevels: [{target: {type: current_schedule}}]\n",
+		"missing target": "kind: EscalationPolicy\nname: x\nlevels: [{target: {type: user}}]\n",
+		"bad type":       "kind: EscalationPolicy\nname: x\nlevels: [{target: {type: pager}}]\n",
+		"duplicate":      "kind: EscalationPolicy\nname: x\nlevels: [{level: 1, target: {type: current_schedule}}, {level: 1, target: {type: current_schedule}}]\n",
+	}
+	for name, doc := range cases {
+		if _, err := parseEscalationPolicyYAML([]byte(doc)); !errors.Is(err, ErrInvalidPolicyYAML) {
+			t.Errorf("%s: expected ErrInvalidPolicyYAML, got %v", name, err)
+		}
+	}
+
+	doc, err := parseEscalationPolicyYAML([]byte(samplePolicyYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Levels[0].Level != 1 || doc.Levels[1].Level != 2 {
+		t.Errorf("levels should be numbered by position, got %+v", doc.Levels)
+	}
+}
+
+func TestImportEscalationPolicyYAMLDryRunResolvesNames(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT id FROM schedulers WHERE group_id = \$1 AND name = \$2`).
+		WithArgs("group-1", "Payments weekly").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("sched-1"))
+	mock.ExpectQuery(`SELECT id FROM users WHERE LOWER\(email\) = LOWER\(\$1\)`).
+		WithArgs("Dana@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("user-2"))
+	mock.ExpectQuery(`SELECT id FROM escalation_policies`).
+		WithArgs("group-1", "Payments primary").
+		WillReturnError(sql.ErrNoRows)
+
+	service := &EscalationService{PG: pg}
+	policy, created, err := service.ImportEscalationPolicyYAML("group-1", "user-1", []byte(samplePolicyYAML), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("expected a new policy")
+	}
+	if len(policy.Levels) != 2 || policy.Levels[0].TargetID != "sched-1" || policy.Levels[1].TargetID != "user-2" {
+		t.Errorf("unexpected levels: %+v", policy.Levels)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportEscalationPolicyYAMLUnknownTarget(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT id FROM schedulers`).
+		WithArgs("group-1", "Payments weekly").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	service := &EscalationService{PG: pg}
+	_, _, err = service.ImportEscalationPolicyYAML("group-1", "user-1", []byte(samplePolicyYAML), true)
+	if !errors.Is(err, ErrInvalidPolicyYAML) || !strings.Contains(err.Error(), "Payments weekly") {
+		t.Errorf("expected unresolved scheduler error, got %v", err)
+	}
+}
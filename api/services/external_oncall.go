@@ -0,0 +1,255 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/encryption"
+	"github.com/vanchonlee/slar/internal/faults"
+)
+
+var (
+	ErrInvalidExternalOnCallConfig = errors.New("invalid external on-call config")
+	ErrExternalOnCallNotFound      = errors.New("external on-call integration not found")
+)
+
+// externalOnCallClient asks external on-call providers who is on call. It is
+// shared because escalation targets resolve outside any one service.
+var externalOnCallClient = &http.Client{Timeout: 10 * time.Second}
+
+// externalOnCallConfig is the parsed config of an external_oncall integration
+type externalOnCallConfig struct {
+	Provider   string
+	URL        string
+	Token      string
+	ScheduleID string
+	EmailMap   map[string]string // lowercased external email -> lowercased SLAR email
+}
+
+// parseExternalOnCallConfig reads and checks an external_oncall integration's config
+func parseExternalOnCallConfig(config map[string]interface{}) (externalOnCallConfig, error) {
+	cfg := externalOnCallConfig{
+		Provider:   strings.TrimSpace(configString(config, "provider")),
+		URL:        strings.TrimRight(strings.TrimSpace(configString(config, "url")), "/"),
+		Token:      configString(config, "token"),
+		ScheduleID: strings.TrimSpace(configString(config, "schedule_id")),
+		EmailMap:   map[string]string{},
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return cfg, fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidExternalOnCallConfig)
+	}
+	switch cfg.Provider {
+	case db.ExternalOnCallProviderHTTP:
+	case db.ExternalOnCallProviderGrafana:
+		if cfg.Token == "" {
+			return cfg, fmt.Errorf("%w: token is required for Grafana OnCall", ErrInvalidExternalOnCallConfig)
+		}
+		if cfg.ScheduleID == "" {
+			return cfg, fmt.Errorf("%w: schedule_id is required for Grafana OnCall", ErrInvalidExternalOnCallConfig)
+		}
+	default:
+		return cfg, fmt.Errorf("%w: provider must be %s or %s", ErrInvalidExternalOnCallConfig,
+			db.ExternalOnCallProviderHTTP, db.ExternalOnCallProviderGrafana)
+	}
+
+	if raw, ok := config["email_map"]; ok && raw != nil {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return cfg, fmt.Errorf("%w: email_map must map external emails to SLAR emails", ErrInvalidExternalOnCallConfig)
+		}
+		for from, to := range m {
+			email, _ := to.(string)
+			from, email = strings.ToLower(strings.TrimSpace(from)), strings.ToLower(strings.TrimSpace(email))
+			if from == "" || email == "" {
+				return cfg, fmt.Errorf("%w: email_map must map external emails to SLAR emails", ErrInvalidExternalOnCallConfig)
+			}
+			cfg.EmailMap[from] = email
+		}
+	}
+	return cfg, nil
+}
+
+// prepareExternalOnCallConfig validates an external_oncall integration's
+// config and seals the token before it is stored
+func prepareExternalOnCallConfig(config map[string]interface{}) error {
+	if _, err := parseExternalOnCallConfig(config); err != nil {
+		return err
+	}
+	token := configString(config, "token")
+	if token == "" || encryption.IsEncrypted(token) {
+		return nil
+	}
+	sealed, err := encryptColumn(token)
+	if err != nil {
+		return err
+	}
+	config["token"] = sealed
+	return nil
+}
+
+// getExternalOnCallJSON GETs a provider URL and decodes its JSON answer
+func getExternalOnCallJSON(client *http.Client, target, authorization string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s answered %d: %s", target, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", target, err)
+	}
+	return nil
+}
+
+// fetchEmails asks the provider who is on call now, in its order
+func (c externalOnCallConfig) fetchEmails(client *http.Client) ([]string, error) {
+	var emails []string
+	switch c.Provider {
+	case db.ExternalOnCallProviderGrafana:
+		var schedule struct {
+			OnCallNow []string `json:"on_call_now"`
+		}
+		if err := getExternalOnCallJSON(client, c.URL+"/api/v1/schedules/"+url.PathEscape(c.ScheduleID)+"/", c.Token, &schedule); err != nil {
+			return nil, err
+		}
+		for _, userID := range schedule.OnCallNow {
+			var user struct {
+				Email string `json:"email"`
+			}
+			if err := getExternalOnCallJSON(client, c.URL+"/api/v1/users/"+url.PathEscape(userID)+"/", c.Token, &user); err != nil {
+				return nil, err
+			}
+			emails = append(emails, user.Email)
+		}
+	default:
+		authorization := ""
+		if c.Token != "" {
+			authorization = "Bearer " + c.Token
+		}
+		var answer struct {
+			Email  string   `json:"email"`
+			Emails []string `json:"emails"`
+		}
+		if err := getExternalOnCallJSON(client, c.URL, authorization, &answer); err != nil {
+			return nil, err
+		}
+		emails = append([]string{answer.Email}, answer.Emails...)
+	}
+
+	mapped := []string{}
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if to, ok := c.EmailMap[email]; ok {
+			email = to
+		}
+		if email != "" && !containsString(mapped, email) {
+			mapped = append(mapped, email)
+		}
+	}
+	return mapped, nil
+}
+
+// externalOnCallCandidates asks an external_oncall integration who is on call
+// and matches their emails to active users of the integration's organization
+func externalOnCallCandidates(pg *sql.DB, client *http.Client, integrationID string) ([]onCallCandidate, *db.ExternalOnCallLookup, error) {
+	var orgID string
+	var configJSON []byte
+	err := pg.QueryRow(`
+		SELECT COALESCE(organization_id::text, ''), config
+		FROM integrations
+		WHERE id::text = $1 AND type = $2 AND is_active = true
+	`, integrationID, db.ExternalOnCallIntegrationType).Scan(&orgID, &configJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrExternalOnCallNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get external on-call integration: %w", err)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidExternalOnCallConfig, err)
+	}
+	cfg, err := parseExternalOnCallConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	decryptColumns(&cfg.Token)
+
+	if err := faults.ProviderOutage("external_oncall"); err != nil {
+		return nil, nil, err
+	}
+	emails, err := cfg.fetchEmails(client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("external on-call lookup failed: %w", err)
+	}
+	lookup := &db.ExternalOnCallLookup{IntegrationID: integrationID, Emails: emails, UserIDs: []string{}}
+	if len(emails) == 0 {
+		return nil, lookup, nil
+	}
+
+	rows, err := pg.Query(`
+		SELECT u.id, LOWER(u.email), user_available(u.id, NOW())
+		FROM users u
+		JOIN memberships m ON m.user_id = u.id AND m.resource_type = 'org' AND m.resource_id::text = $1
+		WHERE LOWER(u.email) = ANY($2) AND COALESCE(u.is_active, TRUE)
+	`, orgID, pq.Array(emails))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to match external on-call users: %w", err)
+	}
+	defer rows.Close()
+	byEmail := map[string]onCallCandidate{}
+	for rows.Next() {
+		var c onCallCandidate
+		var email string
+		if err := rows.Scan(&c.UserID, &email, &c.Available); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan external on-call user: %w", err)
+		}
+		byEmail[email] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to match external on-call users: %w", err)
+	}
+
+	// Keep the provider's order
+	var candidates []onCallCandidate
+	for _, email := range emails {
+		c, ok := byEmail[email]
+		if !ok {
+			lookup.Unmatched = append(lookup.Unmatched, email)
+			continue
+		}
+		candidates = append(candidates, c)
+		lookup.UserIDs = append(lookup.UserIDs, c.UserID)
+	}
+	return candidates, lookup, nil
+}
+
+// LookupExternalOnCall shows who an external_oncall integration reports on
+// call right now and which SLAR users they map to, for checking the email
+// mapping before pointing an escalation level at it
+func (s *IntegrationService) LookupExternalOnCall(integrationID string) (*db.ExternalOnCallLookup, error) {
+	_, lookup, err := externalOnCallCandidates(s.PG, externalOnCallClient, integrationID)
+	return lookup, err
+}
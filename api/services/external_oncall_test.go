@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseExternalOnCallConfig(t *testing.T) {
+	invalid := map[string]map[string]interface{}{
+		"bad url":          {"provider": "http", "url": "ftp://oncall"},
+		"unknown provider": {"provider": "pagerduty", "url": "https://oncall.example.com"},
+		"grafana no token": {"provider": "grafana_oncall", "url": "https://oncall.example.com", "schedule_id": "S1"},
+		"grafana no sched": {"provider": "grafana_oncall", "url": "https://oncall.example.com", "token": "t"},
+		"bad email map":    {"provider": "http", "url": "https://oncall.example.com", "email_map": map[string]interface{}{"a@x.com": 1}},
+	}
+	for name, config := range invalid {
+		if _, err := parseExternalOnCallConfig(config); !errors.Is(err, ErrInvalidExternalOnCallConfig) {
+			t.Errorf("%s: got %v, want ErrInvalidExternalOnCallConfig", name, err)
+		}
+	}
+
+	cfg, err := parseExternalOnCallConfig(map[string]interface{}{
+		"provider": "http", "url": "https://oncall.example.com/now/",
+		"email_map": map[string]interface{}{" Ops@Old.example ": "ops@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.URL != "https://oncall.example.com/now" || cfg.EmailMap["ops@old.example"] != "ops@example.com" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
+
+func TestFetchGrafanaOnCallEmails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "grafana-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/schedules/SCHED1/":
+			json.NewEncoder(w).Encode(map[string]interface{}{"on_call_now": []string{"U1", "U2"}})
+		case "/api/v1/users/U1/":
+			json.NewEncoder(w).Encode(map[string]string{"email": "Alice@Example.com"})
+		case "/api/v1/users/U2/":
+			json.NewEncoder(w).Encode(map[string]string{"email": "bob@legacy.example"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := externalOnCallConfig{
+		Provider: db.ExternalOnCallProviderGrafana, URL: srv.URL, Token: "grafana-token", ScheduleID: "SCHED1",
+		EmailMap: map[string]string{"bob@legacy.example": "bob@example.com"},
+	}
+	emails, err := cfg.fetchEmails(srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 2 || emails[0] != "alice@example.com" || emails[1] != "bob@example.com" {
+		t.Errorf("emails = %v", emails)
+	}
+
+	cfg.Token = "wrong"
+	if _, err := cfg.fetchEmails(srv.Client()); err == nil {
+		t.Error("expected an error when the provider rejects the token")
+	}
+}
+
+func TestResolveEscalationLevelUsersFromExternalSchedule(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"emails": []string{"carol@example.com", "alice@example.com", "ghost@example.com"}})
+	}))
+	defer srv.Close()
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	config, _ := json.Marshal(map[string]interface{}{"provider": "http", "url": srv.URL, "token": "secret"})
+	mock.ExpectQuery(`FROM integrations`).WithArgs("int-1", db.ExternalOnCallIntegrationType).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id", "config"}).AddRow("org-1", config))
+	mock.ExpectQuery(`FROM users u`).
+		WithArgs("org-1", pq.Array([]string{"carol@example.com", "alice@example.com", "ghost@example.com"})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "available"}).
+			AddRow("alice", "alice@example.com", true).AddRow("carol", "carol@example.com", false))
+	mock.ExpectQuery(`FROM user_unavailability`).WithArgs(pq.Array([]string{"carol"})).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "kind", "ends_at", "name"}))
+
+	users, _, err := ResolveEscalationLevelUsers(pg, "grp-1", []db.EscalationLevel{
+		{TargetType: db.EscalationTargetExternalSchedule, TargetID: "int-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0] != "alice" {
+		t.Errorf("expected only available alice to be paged, got %v", users)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/faults"
+)
+
+var (
+	ErrFaultNotFound = errors.New("fault not found")
+	ErrInvalidFault  = errors.New("invalid fault")
+)
+
+// FaultInjectionService stores the faults operators inject for resilience
+// testing. Every process picks them up from fault_injections; see package
+// faults.
+type FaultInjectionService struct {
+	PG *sql.DB
+}
+
+func NewFaultInjectionService(pg *sql.DB) *FaultInjectionService {
+	return &FaultInjectionService{PG: pg}
+}
+
+// validateFault checks a fault request and fills in its defaults
+func validateFault(req *db.CreateFaultRequest) error {
+	switch req.Kind {
+	case db.FaultDBLatency:
+		if req.DelayMS <= 0 || req.DelayMS > db.FaultMaxDelayMS {
+			return fmt.Errorf("%w: delay_ms must be between 1 and %d", ErrInvalidFault, db.FaultMaxDelayMS)
+		}
+	case db.FaultPGMQFailure:
+	case db.FaultProviderOutage:
+		if !containsString(db.FaultProviders, req.Target) {
+			return fmt.Errorf("%w: target must be one of %v", ErrInvalidFault, db.FaultProviders)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidFault, req.Kind)
+	}
+	if req.Kind != db.FaultDBLatency && req.DelayMS != 0 {
+		return fmt.Errorf("%w: delay_ms only applies to db_latency", ErrInvalidFault)
+	}
+	if req.Rate == nil {
+		rate := 1.0
+		req.Rate = &rate
+	}
+	if *req.Rate <= 0 || *req.Rate > 1 {
+		return fmt.Errorf("%w: rate must be above 0 and at most 1", ErrInvalidFault)
+	}
+	if req.DurationSeconds == 0 {
+		req.DurationSeconds = db.FaultDefaultDurationSeconds
+	}
+	if req.DurationSeconds < 0 || req.DurationSeconds > db.FaultMaxDurationSeconds {
+		return fmt.Errorf("%w: duration_seconds must be between 1 and %d", ErrInvalidFault, db.FaultMaxDurationSeconds)
+	}
+	return nil
+}
+
+// ListFaults returns the faults in force
+func (s *FaultInjectionService) ListFaults() ([]db.Fault, error) {
+	if err := faults.Refresh(s.PG); err != nil {
+		return nil, err
+	}
+	return faults.Active(), nil
+}
+
+// CreateFault injects a fault until it expires or is cleared
+func (s *FaultInjectionService) CreateFault(req db.CreateFaultRequest) (*db.Fault, error) {
+	if err := validateFault(&req); err != nil {
+		return nil, err
+	}
+
+	f := db.Fault{Kind: req.Kind, Target: req.Target, DelayMS: req.DelayMS, Rate: *req.Rate, Note: req.Note}
+	err := s.PG.QueryRow(`
+		INSERT INTO fault_injections (kind, target, delay_ms, rate, note, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6 * INTERVAL '1 second')
+		RETURNING id, expires_at, created_at
+	`, f.Kind, f.Target, f.DelayMS, f.Rate, f.Note, req.DurationSeconds).Scan(&f.ID, &f.ExpiresAt, &f.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fault: %w", err)
+	}
+	log.Printf("⚠️  Fault injected: %s %q (rate %.2f) until %s", f.Kind, f.Target, f.Rate, f.ExpiresAt.Format(time.RFC3339))
+	s.refresh()
+	return &f, nil
+}
+
+// DeleteFault clears one fault
+func (s *FaultInjectionService) DeleteFault(id string) error {
+	res, err := s.PG.Exec(`DELETE FROM fault_injections WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete fault: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrFaultNotFound
+	}
+	log.Printf("Fault cleared: %s", id)
+	s.refresh()
+	return nil
+}
+
+// ClearFaults clears every fault, returning how many rows were removed
+func (s *FaultInjectionService) ClearFaults() (int64, error) {
+	res, err := s.PG.Exec(`DELETE FROM fault_injections`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear faults: %w", err)
+	}
+	cleared, _ := res.RowsAffected()
+	log.Printf("Faults cleared: %d", cleared)
+	s.refresh()
+	return cleared, nil
+}
+
+// refresh applies a change to this process right away; the others catch up
+// on their next poll
+func (s *FaultInjectionService) refresh() {
+	if err := faults.Refresh(s.PG); err != nil {
+		log.Printf("Fault injection: %v", err)
+	}
+}
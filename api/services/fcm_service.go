@@ -15,6 +15,7 @@ import (
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
 	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/faults"
 	"google.golang.org/api/option"
 )
 
@@ -32,7 +33,8 @@ type NotificationData struct {
 	AlertTitle string `json:"alert_title"`
 	Severity   string `json:"severity"`
 	Source     string `json:"source"`
-	Type       string `json:"type"` // "alert", "schedule", "reminder"
+	Type       string `json:"type"`          // "alert", "schedule", "reminder"
+	URL        string `json:"url,omitempty"` // incident deep link the app opens on tap
 }
 
 func NewFCMService(pg *sql.DB) (*FCMService, error) {
@@ -94,21 +96,17 @@ func (s *FCMService) SendAlertNotification(alert *db.Alert) error {
 		log.Println("FCM client not initialized and cloud relay not configured, skipping notification")
 		return nil
 	}
+	if err := faults.ProviderOutage("fcm"); err != nil {
+		return err
+	}
 
-	// Get user's FCM token
-	var fcmToken string
-	var userName string
-	err := s.PG.QueryRow(
-		"SELECT fcm_token, name FROM users WHERE id = $1 AND fcm_token IS NOT NULL AND fcm_token != ''",
-		alert.AssignedTo,
-	).Scan(&fcmToken, &userName)
-
+	devices, err := listUserDevices(s.PG, `WHERE user_id = $1`, alert.AssignedTo)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("No FCM token found for user %s", alert.AssignedTo)
-			return nil
-		}
-		return fmt.Errorf("error fetching user FCM token: %v", err)
+		return fmt.Errorf("error fetching user devices: %v", err)
+	}
+	if len(devices) == 0 {
+		log.Printf("No FCM device registered for user %s", alert.AssignedTo)
+		return nil
 	}
 
 	// Prepare notification data
@@ -118,6 +116,7 @@ func (s *FCMService) SendAlertNotification(alert *db.Alert) error {
 		Severity:   alert.Severity,
 		Source:     alert.Source,
 		Type:       "alert",
+		URL:        s.incidentDeepLink(alert.ID),
 	}
 
 	dataMap := make(map[string]string)
@@ -126,7 +125,6 @@ func (s *FCMService) SendAlertNotification(alert *db.Alert) error {
 
 	// Create FCM message
 	message := &messaging.Message{
-		Token: fcmToken,
 		Notification: &messaging.Notification{
 			Title: fmt.Sprintf("[ALERT] %s", alert.Severity),
 			Body:  fmt.Sprintf("%s\nSource: %s", alert.Title, alert.Source),
@@ -161,15 +159,27 @@ func (s *FCMService) SendAlertNotification(alert *db.Alert) error {
 		},
 	}
 
-	// Send message
-	response, err := s.client.Send(context.Background(), message)
-	if err != nil {
-		log.Printf("Error sending FCM message to user %s: %v", userName, err)
-		return err
+	// Send to every device; each result updates that device's token state
+	sent := 0
+	var lastErr error
+	for _, device := range devices {
+		if device.FCMToken == "" {
+			continue
+		}
+		message.Token = device.FCMToken
+		response, err := s.client.Send(context.Background(), message)
+		s.recordDeviceSend(device, err)
+		if err != nil {
+			log.Printf("Error sending FCM message to device %s of user %s: %v", device.ID, alert.AssignedTo, err)
+			lastErr = err
+			continue
+		}
+		sent++
+		log.Printf("Successfully sent FCM notification to device %s of user %s: %s", device.ID, alert.AssignedTo, response)
+	}
+	if sent == 0 {
+		return lastErr
 	}
-
-	log.Printf("Successfully sent FCM notification to %s (token: %s...): %s",
-		userName, fcmToken[:10], response)
 
 	return nil
 }
@@ -180,39 +190,37 @@ func (s *FCMService) SendNotificationToOnCallUsers(alert *db.Alert) error {
 		log.Println("FCM client not initialized, skipping notification")
 		return nil
 	}
+	if err := faults.ProviderOutage("fcm"); err != nil {
+		return err
+	}
 
-	// Get all on-call users with FCM tokens
-	query := `
-		SELECT DISTINCT u.id, u.name, u.fcm_token 
-		FROM users u 
-		        JOIN shifts ocs ON u.id = ocs.user_id 
-		WHERE ocs.is_active = true 
-		AND NOW() BETWEEN ocs.start_time AND ocs.end_time
-		AND u.fcm_token IS NOT NULL 
-		AND u.fcm_token != ''
-		AND u.is_active = true
-	`
-
-	rows, err := s.PG.Query(query)
+	// Get every device of the on-call users
+	devices, err := listUserDevices(s.PG, `
+		WHERE user_id IN (
+			SELECT u.id
+			FROM users u
+			        JOIN shifts ocs ON u.id = ocs.user_id
+			WHERE ocs.is_active = true
+			AND NOW() BETWEEN ocs.start_time AND ocs.end_time
+			AND u.is_active = true
+		)`)
 	if err != nil {
 		return fmt.Errorf("error fetching on-call users: %v", err)
 	}
-	defer rows.Close()
 
 	var tokens []string
-	var userNames []string
+	var sentTo []db.UserDevice
 
-	for rows.Next() {
-		var userID, userName, fcmToken string
-		if err := rows.Scan(&userID, &userName, &fcmToken); err != nil {
+	for _, device := range devices {
+		if device.FCMToken == "" {
 			continue
 		}
-		tokens = append(tokens, fcmToken)
-		userNames = append(userNames, userName)
+		tokens = append(tokens, device.FCMToken)
+		sentTo = append(sentTo, device)
 	}
 
 	if len(tokens) == 0 {
-		log.Println("No on-call users with FCM tokens found")
+		log.Println("No on-call users with FCM devices found")
 		return nil
 	}
 
@@ -223,6 +231,7 @@ func (s *FCMService) SendNotificationToOnCallUsers(alert *db.Alert) error {
 		Severity:   alert.Severity,
 		Source:     alert.Source,
 		Type:       "alert",
+		URL:        s.incidentDeepLink(alert.ID),
 	}
 
 	dataMap := make(map[string]string)
@@ -257,26 +266,23 @@ func (s *FCMService) SendNotificationToOnCallUsers(alert *db.Alert) error {
 		return err
 	}
 
-	log.Printf("Successfully sent FCM notifications to %d users: %v (Success: %d, Failed: %d)",
-		len(userNames), userNames, response.SuccessCount, response.FailureCount)
+	log.Printf("Successfully sent FCM notifications to %d devices (Success: %d, Failed: %d)",
+		len(sentTo), response.SuccessCount, response.FailureCount)
 
-	// Log any failures
+	// Log any failures; responses are in token order
 	for i, resp := range response.Responses {
 		if !resp.Success {
-			log.Printf("Failed to send to %s: %v", userNames[i], resp.Error)
+			log.Printf("Failed to send to device %s of user %s: %v", sentTo[i].ID, sentTo[i].UserID, resp.Error)
 		}
+		s.recordDeviceSend(sentTo[i], resp.Error)
 	}
 
 	return nil
 }
 
-// UpdateUserFCMToken updates user's FCM token
+// UpdateUserFCMToken registers an FCM token as one of the user's devices
 func (s *FCMService) UpdateUserFCMToken(userID, fcmToken string) error {
-	_, err := s.PG.Exec(
-		"UPDATE users SET fcm_token = $1, updated_at = NOW() WHERE id = $2",
-		fcmToken, userID,
-	)
-	if err != nil {
+	if _, err := NewUserService(s.PG).RegisterDevice(userID, db.RegisterDeviceRequest{FCMToken: fcmToken}); err != nil {
 		return fmt.Errorf("error updating FCM token: %v", err)
 	}
 
@@ -340,12 +346,18 @@ func (s *FCMService) sendAlertViaCloudRelay(alert *db.Alert) error {
 			},
 		},
 	}
+	if link := s.incidentDeepLink(alert.ID); link != "" {
+		payload.Notification.Data["url"] = link
+	}
 
 	return s.sendToCloudRelay(payload)
 }
 
 // sendToCloudRelay sends notification payload to cloud relay
 func (s *FCMService) sendToCloudRelay(payload CloudRelayNotification) error {
+	if err := faults.ProviderOutage("fcm"); err != nil {
+		return err
+	}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal cloud relay payload: %v", err)
@@ -370,7 +382,7 @@ func (s *FCMService) sendToCloudRelay(payload CloudRelayNotification) error {
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("cloud relay error (status %d): %s", resp.StatusCode, string(body))
+		return &apiStatusError{StatusCode: resp.StatusCode, msg: fmt.Sprintf("cloud relay error (status %d): %s", resp.StatusCode, string(body))}
 	}
 
 	var relayResp CloudRelayResponse
@@ -405,6 +417,16 @@ func (s *FCMService) SendNotificationToUserViaRelay(userID, title, body string,
 	return s.sendToCloudRelay(payload)
 }
 
+// incidentDeepLink is the deep link of the incident an alert notification is
+// for, or "" for legacy alerts
+func (s *FCMService) incidentDeepLink(id string) string {
+	var shortID string
+	if err := s.PG.QueryRow(`SELECT short_id FROM incidents WHERE id::text = $1`, id).Scan(&shortID); err != nil {
+		return ""
+	}
+	return IncidentShortURL(shortID)
+}
+
 func getPriorityBySeverity(severity string) string {
 	switch severity {
 	case "critical", "high":
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var ErrFeatureFlagNotFound = errors.New("feature flag not found")
+
+// FeatureFlagService resolves feature flags for an organization. The
+// feature_flags config setting wins, then the org's own setting, then the
+// flag's global default.
+type FeatureFlagService struct {
+	PG *sql.DB
+}
+
+// NewFeatureFlagService creates a new FeatureFlagService
+func NewFeatureFlagService(pg *sql.DB) *FeatureFlagService {
+	return &FeatureFlagService{PG: pg}
+}
+
+const featureFlagSelect = `
+	SELECT f.key, f.description, f.default_enabled, o.enabled,
+	       COALESCE(o.updated_by::text, ''), o.updated_at
+	FROM feature_flags f
+	LEFT JOIN organization_feature_flags o
+	       ON o.flag_key = f.key AND o.organization_id = NULLIF($1, '')::uuid`
+
+// ListFlags returns every flag's effective value for an organization. With
+// an empty orgID only the config overrides and defaults apply.
+func (s *FeatureFlagService) ListFlags(orgID string) ([]db.FeatureFlag, error) {
+	rows, err := s.PG.Query(featureFlagSelect+` ORDER BY f.key`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []db.FeatureFlag{}
+	for rows.Next() {
+		flag, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+// GetFlag returns one flag's effective value for an organization
+func (s *FeatureFlagService) GetFlag(orgID, key string) (db.FeatureFlag, error) {
+	flag, err := scanFeatureFlag(s.PG.QueryRow(featureFlagSelect+` WHERE f.key = $2`, orgID, key))
+	if err == sql.ErrNoRows {
+		return db.FeatureFlag{}, ErrFeatureFlagNotFound
+	}
+	if err != nil {
+		return db.FeatureFlag{}, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+	return flag, nil
+}
+
+// IsEnabled reports whether a flag is on for an organization. Unknown flags
+// are off.
+func (s *FeatureFlagService) IsEnabled(orgID, key string) (bool, error) {
+	if enabled, ok := config.FeatureOverride(key); ok {
+		return enabled, nil
+	}
+	flag, err := s.GetFlag(orgID, key)
+	if errors.Is(err, ErrFeatureFlagNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return flag.Enabled, nil
+}
+
+// SetOrgFlag enables or disables a flag for an organization. A nil enabled
+// removes the org setting so the default applies again.
+func (s *FeatureFlagService) SetOrgFlag(orgID, key string, enabled *bool, userID string) (db.FeatureFlag, error) {
+	var exists bool
+	if err := s.PG.QueryRow(`SELECT EXISTS(SELECT 1 FROM feature_flags WHERE key = $1)`, key).Scan(&exists); err != nil {
+		return db.FeatureFlag{}, fmt.Errorf("failed to check feature flag: %w", err)
+	}
+	if !exists {
+		return db.FeatureFlag{}, ErrFeatureFlagNotFound
+	}
+
+	if enabled == nil {
+		if _, err := s.PG.Exec(`
+			DELETE FROM organization_feature_flags WHERE organization_id = $1 AND flag_key = $2`,
+			orgID, key); err != nil {
+			return db.FeatureFlag{}, fmt.Errorf("failed to clear feature flag: %w", err)
+		}
+	} else {
+		if _, err := s.PG.Exec(`
+			INSERT INTO organization_feature_flags (organization_id, flag_key, enabled, updated_by, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (organization_id, flag_key)
+			DO UPDATE SET enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by, updated_at = NOW()`,
+			orgID, key, *enabled, nullIfEmpty(userID)); err != nil {
+			return db.FeatureFlag{}, fmt.Errorf("failed to set feature flag: %w", err)
+		}
+	}
+	return s.GetFlag(orgID, key)
+}
+
+func scanFeatureFlag(row interface{ Scan(...interface{}) error }) (db.FeatureFlag, error) {
+	var flag db.FeatureFlag
+	var orgEnabled sql.NullBool
+	var updatedAt sql.NullTime
+	if err := row.Scan(&flag.Key, &flag.Description, &flag.DefaultEnabled, &orgEnabled,
+		&flag.UpdatedBy, &updatedAt); err != nil {
+		return db.FeatureFlag{}, err
+	}
+	if updatedAt.Valid {
+		t := updatedAt.Time
+		flag.UpdatedAt = &t
+	}
+
+	flag.Enabled, flag.Source = flag.DefaultEnabled, db.FeatureSourceDefault
+	if orgEnabled.Valid {
+		enabled := orgEnabled.Bool
+		flag.OrgEnabled = &enabled
+		flag.Enabled, flag.Source = enabled, db.FeatureSourceOrg
+	}
+	if enabled, ok := config.FeatureOverride(flag.Key); ok {
+		flag.Enabled, flag.Source = enabled, db.FeatureSourceConfig
+	}
+	return flag, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestFeatureFlagResolutionOrder(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	saved := config.Current().FeatureFlags
+	defer config.Update(func(c *config.Config) { c.FeatureFlags = saved })
+	config.Update(func(c *config.Config) { c.FeatureFlags = []string{"-escalation_engine_v2"} })
+
+	now := time.Now()
+	columns := []string{"key", "description", "default_enabled", "enabled", "updated_by", "updated_at"}
+	mock.ExpectQuery(`FROM feature_flags f`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(db.FeatureAIAnalysis, "", true, false, "user-1", now).
+			AddRow(db.FeatureAsyncIngestion, "", false, nil, "", nil).
+			AddRow(db.FeatureEscalationEngineV2, "", false, true, "user-1", now))
+
+	service := NewFeatureFlagService(pg)
+	flags, err := service.ListFlags("org-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		enabled bool
+		source  string
+	}{
+		db.FeatureAIAnalysis:         {false, db.FeatureSourceOrg},
+		db.FeatureAsyncIngestion:     {false, db.FeatureSourceDefault},
+		db.FeatureEscalationEngineV2: {false, db.FeatureSourceConfig},
+	}
+	for _, flag := range flags {
+		if w := want[flag.Key]; flag.Enabled != w.enabled || flag.Source != w.source {
+			t.Errorf("%s: got enabled=%v source=%s, want %v/%s", flag.Key, flag.Enabled, flag.Source, w.enabled, w.source)
+		}
+	}
+
+	// Config overrides are answered without a query
+	if enabled, err := service.IsEnabled("org-1", db.FeatureEscalationEngineV2); err != nil || enabled {
+		t.Errorf("expected config override to disable flag, got %v, %v", enabled, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetOrgFlagUnknownKey(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	enabled := true
+	if _, err := NewFeatureFlagService(pg).SetOrgFlag("org-1", "nope", &enabled, "user-1"); err != ErrFeatureFlagNotFound {
+		t.Errorf("expected ErrFeatureFlagNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,525 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/encryption"
+)
+
+var (
+	ErrInvalidFederationConfig = errors.New("invalid federation config")
+	ErrFederationLinkNotFound  = errors.New("federation link not found")
+	ErrInvalidFederationStatus = errors.New("invalid federation status")
+)
+
+const federationEventQueue = "federation_events"
+
+// federationDedupPrefix marks the dedup key of a forwarded incident on the
+// remote instance, so repeated deliveries update the same remote incident
+const federationDedupPrefix = "slar-federation:"
+
+// FederationService forwards incidents to remote SLAR instances through
+// "federation" integrations and reports the status of incidents received
+// from another instance back to it
+type FederationService struct {
+	PG     *sql.DB
+	client *http.Client
+}
+
+func NewFederationService(pg *sql.DB) *FederationService {
+	return &FederationService{
+		PG:     pg,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// federationConfig is the parsed config of a federation integration
+type federationConfig struct {
+	RemoteURL    string
+	RemoteAPIKey string
+	RoutingKey   string
+	ServiceIDs   []string
+	Severities   []string
+}
+
+// parseFederationConfig reads and checks a federation integration's config
+func parseFederationConfig(config map[string]interface{}) (federationConfig, error) {
+	cfg := federationConfig{
+		RemoteURL:    strings.TrimRight(strings.TrimSpace(configString(config, "remote_url")), "/"),
+		RemoteAPIKey: configString(config, "remote_api_key"),
+		RoutingKey:   strings.TrimSpace(configString(config, "routing_key")),
+		ServiceIDs:   configStrings(config, "service_ids"),
+		Severities:   configStrings(config, "severities"),
+	}
+
+	u, err := url.Parse(cfg.RemoteURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return cfg, fmt.Errorf("%w: remote_url must be an http(s) URL", ErrInvalidFederationConfig)
+	}
+	if cfg.RemoteAPIKey == "" {
+		return cfg, fmt.Errorf("%w: remote_api_key is required", ErrInvalidFederationConfig)
+	}
+	if cfg.RoutingKey == "" {
+		return cfg, fmt.Errorf("%w: routing_key is required", ErrInvalidFederationConfig)
+	}
+	return cfg, nil
+}
+
+// matches reports whether an incident passes the service and severity filters
+func (c federationConfig) matches(serviceID, severity string) bool {
+	if len(c.ServiceIDs) > 0 && !containsString(c.ServiceIDs, serviceID) {
+		return false
+	}
+	if len(c.Severities) > 0 && !containsString(c.Severities, strings.ToLower(severity)) {
+		return false
+	}
+	return true
+}
+
+func configString(config map[string]interface{}, key string) string {
+	v, _ := config[key].(string)
+	return v
+}
+
+func configStrings(config map[string]interface{}, key string) []string {
+	raw, _ := config[key].([]interface{})
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			values = append(values, strings.ToLower(strings.TrimSpace(s)))
+		}
+	}
+	return values
+}
+
+// prepareFederationConfig validates a federation integration's config and
+// seals the remote API key before it is stored
+func prepareFederationConfig(config map[string]interface{}) error {
+	if _, err := parseFederationConfig(config); err != nil {
+		return err
+	}
+	key := configString(config, "remote_api_key")
+	if encryption.IsEncrypted(key) {
+		return nil
+	}
+	sealed, err := encryptColumn(key)
+	if err != nil {
+		return err
+	}
+	config["remote_api_key"] = sealed
+	return nil
+}
+
+// federationSeverity maps a local severity onto the values the events API accepts
+func federationSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "medium", "warning":
+		return "warning"
+	case "low", "info":
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+func generateFederationToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate federation token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashFederationToken(token), nil
+}
+
+func hashFederationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// EnqueueFederationEvent queues an incident change for federation delivery.
+// A new incident ("triggered") is queued when its organization has an active
+// federation integration; acknowledged and resolved are queued only for
+// incidents received from another instance, which get reported back to it.
+// Test incidents are never forwarded.
+func EnqueueFederationEvent(pg *sql.DB, incidentID, eventType string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"incident_id": incidentID,
+		"type":        eventType,
+		"created_at":  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal federation event: %w", err)
+	}
+
+	if eventType == db.IncidentStatusTriggered {
+		_, err = pg.Exec(`
+			SELECT pgmq.send($1, $2)
+			WHERE EXISTS (
+				SELECT 1 FROM incidents i
+				JOIN integrations g ON g.organization_id = i.organization_id AND g.type = $4 AND g.is_active = true
+				WHERE i.id = $3 AND NOT COALESCE(i.is_test, false)
+				AND NOT (COALESCE(i.labels, '{}'::jsonb) ? $5)
+			)
+		`, federationEventQueue, string(payload), incidentID, db.FederationIntegrationType, db.FederationCallbackURLLabel)
+	} else {
+		_, err = pg.Exec(`
+			SELECT pgmq.send($1, $2)
+			WHERE EXISTS (
+				SELECT 1 FROM incidents i
+				WHERE i.id = $3 AND COALESCE(i.labels, '{}'::jsonb) ? $4
+			)
+		`, federationEventQueue, string(payload), incidentID, db.FederationCallbackURLLabel)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to queue federation event: %w", err)
+	}
+	return nil
+}
+
+// DeliverIncidentEvent handles a queued federation event. It matches the
+// worker's delivery signature; the user is not used.
+func (s *FederationService) DeliverIncidentEvent(_, incidentID, eventType string) error {
+	switch eventType {
+	case db.IncidentStatusTriggered:
+		return s.forwardIncident(incidentID)
+	case db.IncidentStatusAcknowledged, db.IncidentStatusResolved:
+		return s.reportStatus(incidentID, eventType)
+	}
+	return nil
+}
+
+// forwardIncident sends an incident to every matching federation integration
+// it hasn't been forwarded to yet
+func (s *FederationService) forwardIncident(incidentID string) error {
+	var title, description, severity, serviceID, orgID, serviceName, status string
+	err := s.PG.QueryRow(`
+		SELECT i.title, COALESCE(i.description, ''), COALESCE(i.severity, ''),
+		       COALESCE(i.service_id::text, ''), COALESCE(i.organization_id::text, ''),
+		       COALESCE(sv.name, ''), i.status
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		WHERE i.id = $1
+	`, incidentID).Scan(&title, &description, &severity, &serviceID, &orgID, &serviceName, &status)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident for federation: %w", err)
+	}
+	if status == db.IncidentStatusResolved || orgID == "" {
+		return nil
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT g.id, g.config, COALESCE(g.webhook_url, '')
+		FROM integrations g
+		WHERE g.type = $1 AND g.is_active = true AND g.organization_id::text = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM incident_federation_links l
+			WHERE l.integration_id = g.id AND l.incident_id = $3 AND l.forwarded_at IS NOT NULL
+		)
+	`, db.FederationIntegrationType, orgID, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to list federation integrations: %w", err)
+	}
+	type target struct {
+		integrationID string
+		callbackURL   string
+		cfg           federationConfig
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		var configJSON []byte
+		if err := rows.Scan(&t.integrationID, &configJSON, &t.callbackURL); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan federation integration: %w", err)
+		}
+		var config map[string]interface{}
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			log.Printf("⚠️  Skipping federation integration %s: invalid config: %v", t.integrationID, err)
+			continue
+		}
+		cfg, err := parseFederationConfig(config)
+		if err != nil {
+			log.Printf("⚠️  Skipping federation integration %s: %v", t.integrationID, err)
+			continue
+		}
+		if !cfg.matches(serviceID, severity) {
+			continue
+		}
+		decryptColumns(&cfg.RemoteAPIKey)
+		t.cfg = cfg
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list federation integrations: %w", err)
+	}
+
+	var errs []error
+	for _, t := range targets {
+		token, tokenHash, err := generateFederationToken()
+		if err != nil {
+			return err
+		}
+		if _, err := s.PG.Exec(`
+			INSERT INTO incident_federation_links (incident_id, integration_id, callback_token_hash)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (incident_id, integration_id)
+			DO UPDATE SET callback_token_hash = EXCLUDED.callback_token_hash, updated_at = NOW()
+		`, incidentID, t.integrationID, tokenHash); err != nil {
+			return fmt.Errorf("failed to record federation link: %w", err)
+		}
+
+		req := db.WebhookIncidentRequest{
+			RoutingKey:  t.cfg.RoutingKey,
+			EventAction: db.WebhookActionTrigger,
+			DedupKey:    federationDedupPrefix + incidentID,
+			Payload: db.WebhookIncidentPayload{
+				Summary:   title,
+				Source:    "slar-federation",
+				Severity:  federationSeverity(severity),
+				Component: serviceName,
+				CustomDetails: map[string]interface{}{
+					db.FederationCallbackURLLabel:   t.callbackURL,
+					db.FederationCallbackTokenLabel: token,
+					db.FederationOriginLabel:        incidentID,
+					"description":                   description,
+				},
+			},
+		}
+		resp, sendErr := s.sendTrigger(t.cfg, req)
+		if sendErr != nil {
+			log.Printf("⚠️  Failed to forward incident %s via federation integration %s: %v", incidentID, t.integrationID, sendErr)
+			if _, err := s.PG.Exec(`
+				UPDATE incident_federation_links SET last_error = $3, updated_at = NOW()
+				WHERE incident_id = $1 AND integration_id = $2
+			`, incidentID, t.integrationID, sendErr.Error()); err != nil {
+				log.Printf("⚠️  Failed to record federation error: %v", err)
+			}
+			errs = append(errs, sendErr)
+			continue
+		}
+
+		if _, err := s.PG.Exec(`
+			UPDATE incident_federation_links
+			SET remote_incident_id = $3, remote_incident_key = $4, remote_status = $5,
+			    last_error = NULL, forwarded_at = NOW(), updated_at = NOW()
+			WHERE incident_id = $1 AND integration_id = $2
+		`, incidentID, t.integrationID, resp.IncidentID, resp.IncidentKey, db.IncidentStatusTriggered); err != nil {
+			return fmt.Errorf("failed to update federation link: %w", err)
+		}
+		log.Printf("🔗 Forwarded incident %s to %s (remote incident %s)", incidentID, t.cfg.RemoteURL, resp.IncidentID)
+	}
+	return errors.Join(errs...)
+}
+
+// sendTrigger posts an incident to the remote instance's events API
+func (s *FederationService) sendTrigger(cfg federationConfig, req db.WebhookIncidentRequest) (db.WebhookIncidentResponse, error) {
+	var out db.WebhookIncidentResponse
+	body, err := json.Marshal(req)
+	if err != nil {
+		return out, err
+	}
+	// The key goes in a header so it stays out of proxy and access logs
+	httpReq, err := http.NewRequest(http.MethodPost, cfg.RemoteURL+"/webhooks/incident", bytes.NewReader(body))
+	if err != nil {
+		return out, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+cfg.RemoteAPIKey)
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return out, fmt.Errorf("remote instance unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return out, fmt.Errorf("remote instance returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return out, fmt.Errorf("invalid response from remote instance: %w", err)
+	}
+	return out, nil
+}
+
+// reportStatus tells the origin instance an incident received through
+// federation was acknowledged or resolved here. Labels come from whoever sent
+// the alert, so the callback URL is only used when it points at the remote of
+// one of the organization's own federation integrations.
+func (s *FederationService) reportStatus(incidentID, status string) error {
+	var labelsJSON []byte
+	var orgID, incidentKey, note string
+	err := s.PG.QueryRow(`
+		SELECT COALESCE(i.labels, '{}'::jsonb), COALESCE(i.organization_id::text, ''), COALESCE(i.incident_key, ''),
+		       COALESCE((
+		           SELECT COALESCE(e.event_data->>'resolution', e.event_data->>'note', '')
+		           FROM incident_events e
+		           WHERE e.incident_id = i.id AND e.event_type = $2
+		           ORDER BY e.created_at DESC LIMIT 1
+		       ), '')
+		FROM incidents i
+		WHERE i.id = $1
+	`, incidentID, status).Scan(&labelsJSON, &orgID, &incidentKey, &note)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident for federation status: %w", err)
+	}
+
+	var labels map[string]interface{}
+	if err := json.Unmarshal(labelsJSON, &labels); err != nil {
+		return nil
+	}
+	callbackURL := configString(labels, db.FederationCallbackURLLabel)
+	token := configString(labels, db.FederationCallbackTokenLabel)
+	if callbackURL == "" || token == "" || orgID == "" {
+		return nil
+	}
+	registered, err := s.isFederationRemote(orgID, callbackURL)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		log.Printf("⚠️  Not reporting %s status for incident %s: callback %s is not a federation remote of its organization", status, incidentID, callbackURL)
+		return nil
+	}
+
+	body, err := json.Marshal(db.FederationStatusUpdate{
+		Token:             token,
+		Status:            status,
+		Note:              note,
+		RemoteIncidentID:  incidentID,
+		RemoteIncidentKey: incidentKey,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("origin instance unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("origin instance returned %d", resp.StatusCode)
+	default:
+		// The origin no longer knows this incident (integration removed or
+		// link expired): retrying won't help
+		log.Printf("⚠️  Origin instance rejected %s status for incident %s: %d", status, incidentID, resp.StatusCode)
+		return nil
+	}
+}
+
+// isFederationRemote reports whether rawURL is on the same scheme and host as
+// the remote_url of an active federation integration of the organization
+func (s *FederationService) isFederationRemote(orgID, rawURL string) (bool, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		return false, nil
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT config FROM integrations
+		WHERE type = $1 AND is_active = true AND organization_id::text = $2
+	`, db.FederationIntegrationType, orgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list federation integrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var configJSON []byte
+		if err := rows.Scan(&configJSON); err != nil {
+			return false, fmt.Errorf("failed to scan federation integration: %w", err)
+		}
+		var config map[string]interface{}
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			continue
+		}
+		remote, err := url.Parse(strings.TrimSpace(configString(config, "remote_url")))
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(remote.Scheme, target.Scheme) && strings.EqualFold(remote.Host, target.Host) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// ApplyFederationStatus mirrors a status change reported by the remote
+// instance onto the forwarded incident. The token identifies the link.
+func (s *IncidentService) ApplyFederationStatus(integrationID string, update db.FederationStatusUpdate) (*db.IncidentFederationLink, error) {
+	if update.Status != db.IncidentStatusAcknowledged && update.Status != db.IncidentStatusResolved {
+		return nil, fmt.Errorf("%w: status must be acknowledged or resolved", ErrInvalidFederationStatus)
+	}
+	if update.Token == "" {
+		return nil, ErrFederationLinkNotFound
+	}
+
+	var link db.IncidentFederationLink
+	var incidentStatus string
+	var remoteID, remoteKey sql.NullString
+	err := s.PG.QueryRow(`
+		UPDATE incident_federation_links l
+		SET remote_status = $3,
+		    remote_incident_id = COALESCE(NULLIF($4, ''), l.remote_incident_id),
+		    remote_incident_key = COALESCE(NULLIF($5, ''), l.remote_incident_key),
+		    last_synced_at = NOW(), updated_at = NOW()
+		FROM incidents i
+		WHERE l.integration_id = $1 AND l.callback_token_hash = $2 AND i.id = l.incident_id
+		RETURNING l.id, l.incident_id, l.integration_id, l.remote_incident_id, l.remote_incident_key,
+		          l.remote_status, l.forwarded_at, l.last_synced_at, l.created_at, i.status
+	`, integrationID, hashFederationToken(update.Token), update.Status, update.RemoteIncidentID, update.RemoteIncidentKey).Scan(
+		&link.ID, &link.IncidentID, &link.IntegrationID, &remoteID, &remoteKey,
+		&link.RemoteStatus, &link.ForwardedAt, &link.LastSyncedAt, &link.CreatedAt, &incidentStatus,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrFederationLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update federation link: %w", err)
+	}
+	link.RemoteIncidentID = remoteID.String
+	link.RemoteIncidentKey = remoteKey.String
+
+	actor := db.GetSystemUserBySource(db.FederationIntegrationType)
+	note := "Status mirrored from remote SLAR instance"
+	if link.RemoteIncidentKey != "" {
+		note += " (" + link.RemoteIncidentKey + ")"
+	}
+	switch {
+	case update.Status == db.IncidentStatusAcknowledged && incidentStatus == db.IncidentStatusTriggered:
+		err = s.AcknowledgeIncident(link.IncidentID, actor, note)
+	case update.Status == db.IncidentStatusResolved && incidentStatus != db.IncidentStatusResolved:
+		err = s.ResolveIncident(link.IncidentID, actor, note, update.Note)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseFederationConfig(t *testing.T) {
+	_, err := parseFederationConfig(map[string]interface{}{"remote_url": "ftp://noc", "remote_api_key": "k", "routing_key": "r"})
+	if !errors.Is(err, ErrInvalidFederationConfig) {
+		t.Errorf("expected invalid remote_url, got %v", err)
+	}
+	_, err = parseFederationConfig(map[string]interface{}{"remote_url": "https://noc.example.com", "routing_key": "r"})
+	if !errors.Is(err, ErrInvalidFederationConfig) {
+		t.Errorf("expected missing remote_api_key, got %v", err)
+	}
+
+	cfg, err := parseFederationConfig(map[string]interface{}{
+		"remote_url":     "https://noc.example.com/",
+		"remote_api_key": "k",
+		"routing_key":    "r",
+		"service_ids":    []interface{}{"svc-1"},
+		"severities":     []interface{}{"Critical", "high"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RemoteURL != "https://noc.example.com" {
+		t.Errorf("trailing slash not trimmed: %q", cfg.RemoteURL)
+	}
+	if !cfg.matches("svc-1", "critical") || cfg.matches("svc-2", "critical") || cfg.matches("svc-1", "low") {
+		t.Error("service and severity filters not applied")
+	}
+}
+
+func TestForwardIncidentPostsToRemoteEventsAPI(t *testing.T) {
+	var got db.WebhookIncidentRequest
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/webhooks/incident" || r.URL.RawQuery != "" || r.Header.Get("Authorization") != "Bearer remote-key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(db.WebhookIncidentResponse{Status: "success", IncidentID: "remote-1", IncidentKey: "INC-9"})
+	}))
+	defer remote.Close()
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "description", "severity", "service_id", "organization_id", "name", "status"}).
+			AddRow("Checkout down", "", "high", "svc-1", "org-1", "checkout", db.IncidentStatusTriggered))
+	config, _ := json.Marshal(map[string]interface{}{
+		"remote_url": remote.URL, "remote_api_key": "remote-key", "routing_key": "noc-key",
+		"severities": []string{"critical", "high"},
+	})
+	mock.ExpectQuery(`FROM integrations g`).WithArgs(db.FederationIntegrationType, "org-1", "inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "config", "webhook_url"}).
+			AddRow("integ-1", config, "https://origin.example.com/webhook/federation/integ-1"))
+	mock.ExpectExec(`INSERT INTO incident_federation_links`).WithArgs("inc-1", "integ-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE incident_federation_links\s+SET remote_incident_id`).
+		WithArgs("inc-1", "integ-1", "remote-1", "INC-9", db.IncidentStatusTriggered).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	service := &FederationService{PG: pg, client: &http.Client{Timeout: 5 * time.Second}}
+	if err := service.DeliverIncidentEvent("", "inc-1", db.IncidentStatusTriggered); err != nil {
+		t.Fatal(err)
+	}
+	if got.RoutingKey != "noc-key" || got.DedupKey != federationDedupPrefix+"inc-1" || got.Payload.Severity != "error" {
+		t.Errorf("unexpected forwarded request: %+v", got)
+	}
+	if got.Payload.CustomDetails[db.FederationCallbackURLLabel] != "https://origin.example.com/webhook/federation/integ-1" ||
+		got.Payload.CustomDetails[db.FederationCallbackTokenLabel] == "" {
+		t.Errorf("callback details missing: %+v", got.Payload.CustomDetails)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyFederationStatusResolvesIncident(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE incident_federation_links l`).
+		WithArgs("integ-1", hashFederationToken("tok"), db.IncidentStatusResolved, "remote-1", "INC-9").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "incident_id", "integration_id", "remote_incident_id", "remote_incident_key",
+			"remote_status", "forwarded_at", "last_synced_at", "created_at", "status"}).
+			AddRow("link-1", "inc-1", "integ-1", "remote-1", "INC-9", db.IncidentStatusResolved, now, now, now, db.IncidentStatusAcknowledged))
+	mock.ExpectExec(`UPDATE incidents`).WithArgs(db.IncidentStatusResolved, db.SystemUserWebhook, "inc-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventResolved, sqlmock.AnyArg(), db.SystemUserWebhook).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	service := &IncidentService{PG: pg}
+	link, err := service.ApplyFederationStatus("integ-1", db.FederationStatusUpdate{
+		Token: "tok", Status: db.IncidentStatusResolved, Note: "Rolled back", RemoteIncidentID: "remote-1", RemoteIncidentKey: "INC-9",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.IncidentID != "inc-1" {
+		t.Errorf("unexpected link: %+v", link)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := service.ApplyFederationStatus("integ-1", db.FederationStatusUpdate{Token: "tok", Status: "closed"}); !errors.Is(err, ErrInvalidFederationStatus) {
+		t.Errorf("expected ErrInvalidFederationStatus, got %v", err)
+	}
+}
+
+func TestReportStatusOnlyCallsFederationRemotes(t *testing.T) {
+	var posted int
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted++
+	}))
+	defer origin.Close()
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	expectIncident := func(callbackURL string) {
+		labels, _ := json.Marshal(map[string]string{
+			db.FederationCallbackURLLabel:   callbackURL,
+			db.FederationCallbackTokenLabel: "tok",
+		})
+		mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1", db.IncidentStatusAcknowledged).
+			WillReturnRows(sqlmock.NewRows([]string{"labels", "organization_id", "incident_key", "note"}).
+				AddRow(labels, "org-1", "", ""))
+		config, _ := json.Marshal(map[string]interface{}{"remote_url": origin.URL})
+		mock.ExpectQuery(`FROM integrations`).WithArgs(db.FederationIntegrationType, "org-1").
+			WillReturnRows(sqlmock.NewRows([]string{"config"}).AddRow(config))
+	}
+
+	service := &FederationService{PG: pg, client: &http.Client{Timeout: 5 * time.Second}}
+	expectIncident("http://169.254.169.254/latest/meta-data")
+	if err := service.DeliverIncidentEvent("", "inc-1", db.IncidentStatusAcknowledged); err != nil {
+		t.Fatal(err)
+	}
+	if posted != 0 {
+		t.Fatalf("posted to an unregistered callback")
+	}
+
+	expectIncident(origin.URL + "/webhook/federation/integ-1")
+	if err := service.DeliverIncidentEvent("", "inc-1", db.IncidentStatusAcknowledged); err != nil {
+		t.Fatal(err)
+	}
+	if posted != 1 {
+		t.Errorf("expected one status report to the origin, got %d", posted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrFreezePeriodNotFound = errors.New("freeze period not found")
+	ErrInvalidFreezePeriod  = errors.New("invalid freeze period")
+)
+
+// FreezeTimeoutPercentSQL is the percentage of its configured length an
+// escalation step timeout runs for incident i: the strictest active freeze
+// period covering the incident, or 100
+const FreezeTimeoutPercentSQL = `COALESCE((
+	SELECT MIN(fp.escalation_timeout_percent) FROM freeze_periods fp
+	WHERE fp.organization_id = i.organization_id
+	  AND (fp.group_id IS NULL OR fp.group_id = i.group_id)
+	  AND fp.start_time <= NOW() AND fp.end_time > NOW()
+	  AND NOT i.is_test
+), 100)`
+
+const freezePeriodColumns = `
+	id, organization_id, COALESCE(group_id::text, ''), name, description, start_time, end_time,
+	disable_auto_resolve, escalation_timeout_percent, reviewer_ids::text[], COALESCE(created_by::text, ''),
+	created_at, updated_at
+`
+
+func scanFreezePeriod(row interface{ Scan(...interface{}) error }) (db.FreezePeriod, error) {
+	var fp db.FreezePeriod
+	err := row.Scan(&fp.ID, &fp.OrganizationID, &fp.GroupID, &fp.Name, &fp.Description, &fp.StartTime, &fp.EndTime,
+		&fp.DisableAutoResolve, &fp.EscalationTimeoutPercent, pq.Array(&fp.ReviewerIDs), &fp.CreatedBy,
+		&fp.CreatedAt, &fp.UpdatedAt)
+	if fp.ReviewerIDs == nil {
+		fp.ReviewerIDs = []string{}
+	}
+	return fp, err
+}
+
+// ListFreezePeriods returns the org's freeze periods overlapping [from, to],
+// org-wide and per-group, for a calendar view
+func (s *IncidentService) ListFreezePeriods(orgID string, from, to time.Time) ([]db.FreezePeriod, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+freezePeriodColumns+`
+		FROM freeze_periods
+		WHERE organization_id = $1 AND end_time >= $2 AND start_time <= $3
+		ORDER BY start_time ASC
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list freeze periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []db.FreezePeriod{}
+	for rows.Next() {
+		fp, err := scanFreezePeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan freeze period: %w", err)
+		}
+		periods = append(periods, fp)
+	}
+	return periods, rows.Err()
+}
+
+// OrgDisplaySettings returns the organization's display settings, or the
+// defaults, e.g. to default a calendar range to its weeks
+func (s *IncidentService) OrgDisplaySettings(orgID string) db.OrgDisplaySettings {
+	return orgDisplaySettingsOrDefault(s.PG, orgID)
+}
+
+// validateFreezeTargets checks the group and reviewers belong to the org
+func (s *IncidentService) validateFreezeTargets(orgID, groupID string, reviewerIDs []string) error {
+	if groupID != "" {
+		var ok bool
+		if err := s.PG.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM groups WHERE id::text = $1 AND organization_id = $2)
+		`, groupID, orgID).Scan(&ok); err != nil {
+			return fmt.Errorf("failed to check group: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: group %s is not in this organization", ErrInvalidFreezePeriod, groupID)
+		}
+	}
+	if len(reviewerIDs) > 0 {
+		var members int
+		if err := s.PG.QueryRow(`
+			SELECT COUNT(DISTINCT user_id) FROM memberships
+			WHERE resource_type = 'org' AND resource_id = $1 AND user_id::text = ANY($2)
+		`, orgID, pq.Array(reviewerIDs)).Scan(&members); err != nil {
+			return fmt.Errorf("failed to check reviewers: %w", err)
+		}
+		if members != len(uniqueStrings(reviewerIDs)) {
+			return fmt.Errorf("%w: every reviewer must be a member of this organization", ErrInvalidFreezePeriod)
+		}
+	}
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	out := []string{}
+	for _, v := range values {
+		if !containsString(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// CreateFreezePeriod declares a freeze period for an org or one of its groups
+func (s *IncidentService) CreateFreezePeriod(orgID string, req db.CreateFreezePeriodRequest, createdBy string) (*db.FreezePeriod, error) {
+	reviewers := uniqueStrings(req.ReviewerIDs)
+	if err := s.validateFreezeTargets(orgID, req.GroupID, reviewers); err != nil {
+		return nil, err
+	}
+	disableAutoResolve := true
+	if req.DisableAutoResolve != nil {
+		disableAutoResolve = *req.DisableAutoResolve
+	}
+	percent := req.EscalationTimeoutPercent
+	if percent == 0 {
+		percent = db.FreezeDefaultEscalationTimeoutPercent
+	}
+
+	fp, err := scanFreezePeriod(s.PG.QueryRow(`
+		INSERT INTO freeze_periods (organization_id, group_id, name, description, start_time, end_time,
+			disable_auto_resolve, escalation_timeout_percent, reviewer_ids, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::uuid[], $10)
+		RETURNING `+freezePeriodColumns,
+		orgID, nullIfEmpty(req.GroupID), req.Name, req.Description, req.StartTime, req.EndTime,
+		disableAutoResolve, percent, pq.Array(reviewers), nullIfEmpty(createdBy)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create freeze period: %w", err)
+	}
+	return &fp, nil
+}
+
+// UpdateFreezePeriod changes a freeze period's schedule, name or effects
+func (s *IncidentService) UpdateFreezePeriod(orgID, periodID string, req db.UpdateFreezePeriodRequest) (*db.FreezePeriod, error) {
+	query := "UPDATE freeze_periods SET updated_at = NOW()"
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		query += fmt.Sprintf(", name = $%d", argIndex)
+		args = append(args, *req.Name)
+		argIndex++
+	}
+	if req.Description != nil {
+		query += fmt.Sprintf(", description = $%d", argIndex)
+		args = append(args, *req.Description)
+		argIndex++
+	}
+	if req.StartTime != nil {
+		query += fmt.Sprintf(", start_time = $%d", argIndex)
+		args = append(args, *req.StartTime)
+		argIndex++
+	}
+	if req.EndTime != nil {
+		query += fmt.Sprintf(", end_time = $%d", argIndex)
+		args = append(args, *req.EndTime)
+		argIndex++
+	}
+	if req.DisableAutoResolve != nil {
+		query += fmt.Sprintf(", disable_auto_resolve = $%d", argIndex)
+		args = append(args, *req.DisableAutoResolve)
+		argIndex++
+	}
+	if req.EscalationTimeoutPercent != nil {
+		query += fmt.Sprintf(", escalation_timeout_percent = $%d", argIndex)
+		args = append(args, *req.EscalationTimeoutPercent)
+		argIndex++
+	}
+	if req.ReviewerIDs != nil {
+		reviewers := uniqueStrings(*req.ReviewerIDs)
+		if err := s.validateFreezeTargets(orgID, "", reviewers); err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(", reviewer_ids = $%d::uuid[]", argIndex)
+		args = append(args, pq.Array(reviewers))
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d AND organization_id = $%d RETURNING "+freezePeriodColumns, argIndex, argIndex+1)
+	args = append(args, periodID, orgID)
+
+	fp, err := scanFreezePeriod(s.PG.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrFreezePeriodNotFound
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23514" {
+			return nil, fmt.Errorf("%w: end_time must be after start_time", ErrInvalidFreezePeriod)
+		}
+		return nil, fmt.Errorf("failed to update freeze period: %w", err)
+	}
+	return &fp, nil
+}
+
+// DeleteFreezePeriod removes a freeze period, ending it early if active
+func (s *IncidentService) DeleteFreezePeriod(orgID, periodID string) error {
+	result, err := s.PG.Exec(`DELETE FROM freeze_periods WHERE id = $1 AND organization_id = $2`, periodID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete freeze period: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrFreezePeriodNotFound
+	}
+	return nil
+}
+
+// activeFreezePeriods returns the freeze periods covering an open,
+// non-test incident right now, strictest first
+func (s *IncidentService) activeFreezePeriods(incidentID string) ([]db.FreezePeriod, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+freezePeriodColumns+`
+		FROM freeze_periods
+		WHERE id IN (
+			SELECT fp.id FROM freeze_periods fp
+			JOIN incidents i ON i.organization_id = fp.organization_id
+			WHERE i.id = $1
+			  AND i.status != 'resolved'
+			  AND NOT i.is_test
+			  AND (fp.group_id IS NULL OR fp.group_id = i.group_id)
+			  AND fp.start_time <= NOW() AND fp.end_time > NOW()
+		)
+		ORDER BY disable_auto_resolve DESC, escalation_timeout_percent ASC, start_time ASC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get freeze periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []db.FreezePeriod{}
+	for rows.Next() {
+		fp, err := scanFreezePeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan freeze period: %w", err)
+		}
+		periods = append(periods, fp)
+	}
+	return periods, rows.Err()
+}
+
+// isAutomatedActor reports the system users integrations, monitors and
+// workers resolve incidents as
+func isAutomatedActor(userID string) bool {
+	return userID == uuid.Nil.String() || containsString(db.SystemUserIDs, userID)
+}
+
+// holdAutoResolve reports whether an active freeze period keeps the incident
+// open, and records the held resolution on the timeline when it does
+func (s *IncidentService) holdAutoResolve(incidentID, userID, note, resolution string) bool {
+	periods, err := s.activeFreezePeriods(incidentID)
+	if err != nil {
+		log.Printf("⚠️  Failed to check freeze periods for incident %s: %v", incidentID, err)
+		return false
+	}
+	if len(periods) == 0 || !periods[0].DisableAutoResolve {
+		return false
+	}
+
+	eventData := map[string]interface{}{
+		"freeze_period_id":   periods[0].ID,
+		"freeze_period_name": periods[0].Name,
+	}
+	if note != "" {
+		eventData["note"] = note
+	}
+	if resolution != "" {
+		eventData["resolution"] = resolution
+	}
+	s.createIncidentEvent(incidentID, db.IncidentEventAutoResolveHeld, eventData, userID)
+	log.Printf("Freeze period %q: kept incident %s open instead of auto-resolving", periods[0].Name, incidentID)
+	return true
+}
+
+// pageFreezeReviewers pages every reviewer of the freeze periods covering a
+// new incident onto it
+func (s *IncidentService) pageFreezeReviewers(incidentID string) {
+	periods, err := s.activeFreezePeriods(incidentID)
+	if err != nil {
+		log.Printf("⚠️  Failed to check freeze periods for incident %s: %v", incidentID, err)
+		return
+	}
+
+	paged := []string{}
+	for _, fp := range periods {
+		for _, reviewerID := range fp.ReviewerIDs {
+			if containsString(paged, reviewerID) {
+				continue
+			}
+			paged = append(paged, reviewerID)
+			req := db.PageIncidentRequest{
+				TargetType: "user",
+				TargetID:   reviewerID,
+				Message:    fmt.Sprintf("Added as a reviewer during freeze period %q", fp.Name),
+			}
+			if _, err := s.PageResponder(incidentID, req, ""); err != nil {
+				log.Printf("⚠️  Failed to page freeze reviewer %s for incident %s: %v", reviewerID, incidentID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var freezePeriodRowColumns = []string{
+	"id", "organization_id", "group_id", "name", "description", "start_time", "end_time",
+	"disable_auto_resolve", "escalation_timeout_percent", "reviewer_ids", "created_by", "created_at", "updated_at",
+}
+
+func TestResolveIncidentHeldDuringFreeze(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM freeze_periods\s+WHERE id IN`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(freezePeriodRowColumns).
+			AddRow("fp-1", "org-1", "", "Black Friday", "", now.Add(-time.Hour), now.Add(time.Hour), true, 50, "{}", "", now, now))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventAutoResolveHeld, sqlmock.AnyArg(), db.SystemUserPrometheus).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := &IncidentService{PG: pg}
+	if err := s.ResolveIncident("inc-1", db.SystemUserPrometheus, "Alert resolved automatically", ""); err != nil {
+		t.Fatal(err)
+	}
+	// No UPDATE expected: the incident stays open
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsAutomatedActor(t *testing.T) {
+	for _, id := range []string{db.SystemUserDatadog, db.SystemUserAPI, "00000000-0000-0000-0000-000000000000"} {
+		if !isAutomatedActor(id) {
+			t.Errorf("isAutomatedActor(%s) = false, want true", id)
+		}
+	}
+	if isAutomatedActor("4b1c8e6a-2f7d-4a0e-9b3c-1d2e3f4a5b6c") {
+		t.Error("a person counted as an automated actor")
+	}
+}
+
+func TestCreateFreezePeriodRejectsOutsideReviewers(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM memberships`).WithArgs("org-1", pq.Array([]string{"user-1", "user-2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	req := db.CreateFreezePeriodRequest{
+		Name:        "Black Friday",
+		StartTime:   time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC),
+		EndTime:     time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+		ReviewerIDs: []string{"user-1", "user-2", "user-1"},
+	}
+	_, err = (&IncidentService{PG: pg}).CreateFreezePeriod("org-1", req, "admin-1")
+	if !errors.Is(err, ErrInvalidFreezePeriod) {
+		t.Errorf("error = %v, want ErrInvalidFreezePeriod", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
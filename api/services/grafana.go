@@ -0,0 +1,261 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrInvalidGrafanaQuery = errors.New("invalid Grafana query")
+	ErrGrafanaScopeMissing = errors.New("read token lacks the scope for this metric")
+)
+
+// grafanaMetrics lists the metrics /read/grafana serves, in /search order,
+// with the read scope each needs
+var grafanaMetrics = []struct{ name, scope string }{
+	{db.GrafanaMetricIncidentsCreated, db.ReadScopeIncidents},
+	{db.GrafanaMetricIncidentsResolved, db.ReadScopeIncidents},
+	{db.GrafanaMetricMTTA, db.ReadScopeIncidents},
+	{db.GrafanaMetricMTTR, db.ReadScopeIncidents},
+	{db.GrafanaMetricOnCallCovered, db.ReadScopeOnCall},
+	{db.GrafanaMetricOnCallShifts, db.ReadScopeOnCall},
+}
+
+// grafanaIncidentSeries buckets incidents by a timestamp column and
+// aggregates each bucket. Counts are zero-filled; averages are not.
+var grafanaIncidentSeries = map[string]struct {
+	column, value string
+	count         bool
+}{
+	db.GrafanaMetricIncidentsCreated:  {"created_at", "COUNT(*)", true},
+	db.GrafanaMetricIncidentsResolved: {"resolved_at", "COUNT(*)", true},
+	db.GrafanaMetricMTTA:              {"acknowledged_at", "AVG(EXTRACT(EPOCH FROM (i.acknowledged_at - i.created_at)))", false},
+	db.GrafanaMetricMTTR:              {"resolved_at", "AVG(EXTRACT(EPOCH FROM (i.resolved_at - i.created_at)))", false},
+}
+
+func grafanaMetricScope(metric string) (string, bool) {
+	for _, m := range grafanaMetrics {
+		if m.name == metric {
+			return m.scope, true
+		}
+	}
+	return "", false
+}
+
+// GrafanaMetrics returns the metrics a read token may query (SimpleJSON /search)
+func (s *ReadTokenService) GrafanaMetrics(token *db.ReadToken) []string {
+	metrics := []string{}
+	for _, m := range grafanaMetrics {
+		if containsString(token.Scopes, m.scope) {
+			metrics = append(metrics, m.name)
+		}
+	}
+	return metrics
+}
+
+// grafanaStep picks the bucket size: the panel's interval, at least
+// db.GrafanaMinInterval, widened so the range fits in maxPoints buckets
+func grafanaStep(from, to time.Time, intervalMs int64, maxPoints int) time.Duration {
+	if maxPoints <= 0 {
+		maxPoints = db.GrafanaDefaultMaxPoints
+	}
+	if maxPoints > db.GrafanaMaxPoints {
+		maxPoints = db.GrafanaMaxPoints
+	}
+	step := time.Duration(intervalMs) * time.Millisecond
+	if step < db.GrafanaMinInterval {
+		step = db.GrafanaMinInterval
+	}
+	if minStep := to.Sub(from) / time.Duration(maxPoints); step < minStep {
+		step = minStep
+	}
+	return step.Truncate(time.Second)
+}
+
+// grafanaBucketStart aligns t to the start of its bucket, as the SQL does
+func grafanaBucketStart(t time.Time, step time.Duration) time.Time {
+	secs := int64(step / time.Second)
+	return time.Unix(t.Unix()/secs*secs, 0).UTC()
+}
+
+// QueryGrafana answers a SimpleJSON /query request with one time series or
+// table per target, as seen by the token's owner
+func (s *ReadTokenService) QueryGrafana(token *db.ReadToken, req db.GrafanaQueryRequest) ([]interface{}, error) {
+	from, to := req.Range.From, req.Range.To
+	if from.IsZero() || to.IsZero() || !to.After(from) {
+		return nil, fmt.Errorf("%w: range.to must be after range.from", ErrInvalidGrafanaQuery)
+	}
+	if to.Sub(from) > db.GrafanaMaxRangeDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: range must not exceed %d days", ErrInvalidGrafanaQuery, db.GrafanaMaxRangeDays)
+	}
+	step := grafanaStep(from, to, req.IntervalMs, req.MaxDataPoints)
+
+	results := []interface{}{}
+	for _, target := range req.Targets {
+		if target.Target == "" {
+			continue // Grafana sends empty targets for unconfigured queries
+		}
+		scope, ok := grafanaMetricScope(target.Target)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown metric %q", ErrInvalidGrafanaQuery, target.Target)
+		}
+		if !containsString(token.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %s needs %s", ErrGrafanaScopeMissing, target.Target, scope)
+		}
+
+		var result interface{}
+		var err error
+		switch target.Target {
+		case db.GrafanaMetricOnCallCovered:
+			result, err = s.grafanaOnCallCovered(token, target, from, to, step)
+		case db.GrafanaMetricOnCallShifts:
+			result, err = s.grafanaOnCallShifts(token, target, from, to)
+		default:
+			result, err = s.grafanaIncidents(token, target, from, to, step)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func grafanaPayloadString(payload map[string]interface{}, key string) string {
+	if v, ok := payload[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (s *ReadTokenService) grafanaIncidents(token *db.ReadToken, target db.GrafanaTarget, from, to time.Time, step time.Duration) (*db.GrafanaTimeSeries, error) {
+	series := grafanaIncidentSeries[target.Target]
+	// series.column and series.value come from the fixed map above
+	query := `
+		SELECT (floor(EXTRACT(EPOCH FROM i.` + series.column + `) / $5::float8) * $5::float8)::bigint AS bucket, ` + series.value + `
+		FROM incidents i
+		WHERE
+` + incidentAccessScopeSQL + `
+		  AND NOT i.is_test
+		  AND ($3 = '' OR i.project_id::text = $3)
+		  AND i.` + series.column + ` >= $4 AND i.` + series.column + ` < $6
+		  AND ($7 = '' OR i.service_id::text = $7)
+		  AND ($8 = '' OR i.group_id::text = $8)
+		  AND ($9 = '' OR i.severity = $9)
+		GROUP BY bucket
+		ORDER BY bucket`
+	rows, err := s.PG.Query(query, token.UserID, token.OrganizationID, token.ProjectID, from, int64(step/time.Second), to,
+		grafanaPayloadString(target.Payload, "service_id"), grafanaPayloadString(target.Payload, "group_id"),
+		grafanaPayloadString(target.Payload, "severity"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", target.Target, err)
+	}
+	defer rows.Close()
+
+	values := map[int64]float64{}
+	result := &db.GrafanaTimeSeries{Target: target.Target, RefID: target.RefID, Datapoints: [][2]float64{}}
+	for rows.Next() {
+		var bucket int64
+		var value float64
+		if err := rows.Scan(&bucket, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", target.Target, err)
+		}
+		values[bucket] = value
+		if !series.count {
+			result.Datapoints = append(result.Datapoints, [2]float64{value, float64(bucket * 1000)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if series.count {
+		for t := grafanaBucketStart(from, step); t.Before(to); t = t.Add(step) {
+			result.Datapoints = append(result.Datapoints, [2]float64{values[t.Unix()], float64(t.UnixMilli())})
+		}
+	}
+	return result, nil
+}
+
+// grafanaOnCallCovered counts, at the start of every bucket, the active
+// groups the owner can see that have someone on call
+func (s *ReadTokenService) grafanaOnCallCovered(token *db.ReadToken, target db.GrafanaTarget, from, to time.Time, step time.Duration) (*db.GrafanaTimeSeries, error) {
+	query := `
+		SELECT EXTRACT(EPOCH FROM b.ts)::bigint, COUNT(DISTINCT g.id)
+		FROM generate_series($4::timestamptz, $6::timestamptz, make_interval(secs => $5::float8)) AS b(ts)
+		LEFT JOIN shifts sh ON sh.is_active = true AND b.ts BETWEEN sh.start_time AND sh.end_time
+		LEFT JOIN groups g ON g.id = sh.group_id
+		  AND g.is_active = true
+		  AND ($3 = '' OR g.project_id::text = $3)
+		  AND ` + groupAccessScopeSQL + `
+		WHERE b.ts < $6
+		GROUP BY b.ts
+		ORDER BY b.ts`
+	rows, err := s.PG.Query(query, token.UserID, token.OrganizationID, token.ProjectID,
+		grafanaBucketStart(from, step), int64(step/time.Second), to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", target.Target, err)
+	}
+	defer rows.Close()
+
+	result := &db.GrafanaTimeSeries{Target: target.Target, RefID: target.RefID, Datapoints: [][2]float64{}}
+	for rows.Next() {
+		var bucket int64
+		var covered float64
+		if err := rows.Scan(&bucket, &covered); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", target.Target, err)
+		}
+		result.Datapoints = append(result.Datapoints, [2]float64{covered, float64(bucket * 1000)})
+	}
+	return result, rows.Err()
+}
+
+// grafanaOnCallShifts lists the shifts overlapping the range in groups the
+// owner can see, e.g. for a state timeline panel
+func (s *ReadTokenService) grafanaOnCallShifts(token *db.ReadToken, target db.GrafanaTarget, from, to time.Time) (*db.GrafanaTable, error) {
+	query := `
+		SELECT g.name, COALESCE(u.name, ''), sh.start_time, sh.end_time, sh.is_override
+		FROM shifts sh
+		JOIN groups g ON g.id = sh.group_id
+		LEFT JOIN users u ON u.id = sh.user_id
+		WHERE
+` + groupAccessScopeSQL + `
+		  AND g.is_active = true
+		  AND sh.is_active = true
+		  AND ($3 = '' OR g.project_id::text = $3)
+		  AND sh.start_time < $5 AND sh.end_time > $4
+		ORDER BY sh.start_time, g.name
+		LIMIT $6`
+	rows, err := s.PG.Query(query, token.UserID, token.OrganizationID, token.ProjectID, from, to, db.GrafanaMaxShiftRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", target.Target, err)
+	}
+	defer rows.Close()
+
+	table := &db.GrafanaTable{
+		Type:  "table",
+		RefID: target.RefID,
+		Columns: []db.GrafanaTableColumn{
+			{Text: "group", Type: "string"},
+			{Text: "user", Type: "string"},
+			{Text: "start", Type: "time"},
+			{Text: "end", Type: "time"},
+			{Text: "override", Type: "string"},
+		},
+		Rows: [][]interface{}{},
+	}
+	for rows.Next() {
+		var group, user string
+		var start, end time.Time
+		var override bool
+		if err := rows.Scan(&group, &user, &start, &end, &override); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", target.Target, err)
+		}
+		table.Rows = append(table.Rows, []interface{}{group, user, start.UnixMilli(), end.UnixMilli(), strconv.FormatBool(override)})
+	}
+	return table, rows.Err()
+}
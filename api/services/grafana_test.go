@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestGrafanaStep(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name       string
+		span       time.Duration
+		intervalMs int64
+		maxPoints  int
+		want       time.Duration
+	}{
+		{"panel interval", 24 * time.Hour, int64(5 * time.Minute / time.Millisecond), 0, 5 * time.Minute},
+		{"below minimum", time.Hour, 1000, 0, time.Minute},
+		{"widened to max points", 30 * 24 * time.Hour, 60000, 100, 7*time.Hour + 12*time.Minute},
+		{"max points capped", 366 * 24 * time.Hour, 60000, 1000000, 52*time.Minute + 42*time.Second},
+	}
+	for _, tc := range cases {
+		if got := grafanaStep(from, from.Add(tc.span), tc.intervalMs, tc.maxPoints); got != tc.want {
+			t.Errorf("%s: grafanaStep = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGrafanaMetricsFollowScopes(t *testing.T) {
+	s := NewReadTokenService(nil)
+	got := s.GrafanaMetrics(&db.ReadToken{Scopes: []string{db.ReadScopeOnCall}})
+	if len(got) != 2 || got[0] != db.GrafanaMetricOnCallCovered || got[1] != db.GrafanaMetricOnCallShifts {
+		t.Errorf("GrafanaMetrics = %v, want on-call metrics only", got)
+	}
+	if got := s.GrafanaMetrics(&db.ReadToken{Scopes: []string{db.ReadScopeMonitors}}); len(got) != 0 {
+		t.Errorf("GrafanaMetrics = %v, want none", got)
+	}
+}
+
+func TestQueryGrafanaRejects(t *testing.T) {
+	s := NewReadTokenService(nil)
+	token := &db.ReadToken{Scopes: []string{db.ReadScopeOnCall}}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := db.GrafanaRange{From: from, To: from.Add(time.Hour)}
+
+	cases := []struct {
+		name string
+		req  db.GrafanaQueryRequest
+		want error
+	}{
+		{"backwards range", db.GrafanaQueryRequest{Range: db.GrafanaRange{From: rng.To, To: rng.From}}, ErrInvalidGrafanaQuery},
+		{"range too long", db.GrafanaQueryRequest{Range: db.GrafanaRange{From: from, To: from.AddDate(2, 0, 0)}}, ErrInvalidGrafanaQuery},
+		{"unknown metric", db.GrafanaQueryRequest{Range: rng, Targets: []db.GrafanaTarget{{Target: "users.passwords"}}}, ErrInvalidGrafanaQuery},
+		{"missing scope", db.GrafanaQueryRequest{Range: rng, Targets: []db.GrafanaTarget{{Target: db.GrafanaMetricMTTR}}}, ErrGrafanaScopeMissing},
+	}
+	for _, tc := range cases {
+		if _, err := s.QueryGrafana(token, tc.req); !errors.Is(err, tc.want) {
+			t.Errorf("%s: error = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestQueryGrafanaIncidentCountsAreZeroFilled(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(3 * time.Hour)
+	token := &db.ReadToken{UserID: "user-1", OrganizationID: "org-1", Scopes: []string{db.ReadScopeIncidents}}
+
+	mock.ExpectQuery(`FROM incidents i.*i.created_at >= \$4 AND i.created_at < \$6`).
+		WithArgs("user-1", "org-1", "", from, int64(3600), to, "svc-1", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).AddRow(from.Add(time.Hour).Unix(), 4))
+	mock.ExpectQuery(`AVG\(EXTRACT\(EPOCH FROM \(i.resolved_at - i.created_at\)\)\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "avg"}).AddRow(from.Unix(), 1800.5))
+
+	req := db.GrafanaQueryRequest{
+		Range:      db.GrafanaRange{From: from, To: to},
+		IntervalMs: int64(time.Hour / time.Millisecond),
+		Targets: []db.GrafanaTarget{
+			{Target: db.GrafanaMetricIncidentsCreated, RefID: "A", Payload: map[string]interface{}{"service_id": "svc-1"}},
+			{Target: ""},
+			{Target: db.GrafanaMetricMTTR, RefID: "B"},
+		},
+	}
+	results, err := NewReadTokenService(pg).QueryGrafana(token, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	created := results[0].(*db.GrafanaTimeSeries)
+	ms := float64(from.UnixMilli())
+	hour := float64(time.Hour / time.Millisecond)
+	want := [][2]float64{{0, ms}, {4, ms + hour}, {0, ms + 2*hour}}
+	if created.RefID != "A" || len(created.Datapoints) != len(want) {
+		t.Fatalf("created = %+v, want %v", created, want)
+	}
+	for i := range want {
+		if created.Datapoints[i] != want[i] {
+			t.Errorf("created datapoint %d = %v, want %v", i, created.Datapoints[i], want[i])
+		}
+	}
+
+	mttr := results[1].(*db.GrafanaTimeSeries)
+	if len(mttr.Datapoints) != 1 || mttr.Datapoints[0] != [2]float64{1800.5, ms} {
+		t.Errorf("mttr datapoints = %v, want one at the first bucket", mttr.Datapoints)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
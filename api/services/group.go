@@ -2,6 +2,7 @@ package services
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,14 @@ import (
 	"github.com/vanchonlee/slar/db"
 )
 
+var (
+	// ErrPolicyNotInGroup is returned when a group's default escalation policy belongs to another group
+	ErrPolicyNotInGroup = errors.New("escalation policy does not belong to this group")
+	// ErrInvalidParentGroup is returned when a parent group is missing, in another organization,
+	// or is the group itself or one of its sub-teams
+	ErrInvalidParentGroup = errors.New("invalid parent group")
+)
+
 type GroupService struct {
 	PG *sql.DB
 }
@@ -343,7 +352,10 @@ func (s *GroupService) GetGroup(id string) (db.Group, error) {
 		SELECT g.id, g.name, g.description, g.type, g.visibility, g.is_active, g.created_at, g.updated_at,
 		       COALESCE(u.name, 'Unknown') as created_by,
 		       g.escalation_timeout, g.escalation_method,
-		       COALESCE(mc.member_count, 0) as member_count
+		       COALESCE(mc.member_count, 0) as member_count,
+		       COALESCE(g.default_escalation_policy_id::text, '') as default_escalation_policy_id,
+		       COALESCE(g.parent_group_id::text, '') as parent_group_id,
+		       g.reassign_on_handoff
 		FROM groups g
 		LEFT JOIN users u ON g.created_by = u.id
 		LEFT JOIN (
@@ -357,6 +369,7 @@ func (s *GroupService) GetGroup(id string) (db.Group, error) {
 		&g.ID, &g.Name, &g.Description, &g.Type, &g.Visibility, &g.IsActive,
 		&g.CreatedAt, &g.UpdatedAt, &g.CreatedBy,
 		&g.EscalationTimeout, &g.EscalationMethod, &g.MemberCount,
+		&g.DefaultEscalationPolicyID, &g.ParentGroupID, &g.ReassignOnHandoff,
 	)
 	return g, err
 }
@@ -392,6 +405,13 @@ func (s *GroupService) CreateGroup(req db.CreateGroupRequest, createdBy string)
 		CreatedBy:      createdBy,
 		OrganizationID: req.OrganizationID,
 		ProjectID:      req.ProjectID,
+		ParentGroupID:  req.ParentGroupID,
+	}
+
+	if group.ParentGroupID != "" {
+		if err := s.checkParentGroup(group.ID, group.ParentGroupID, group.OrganizationID); err != nil {
+			return group, err
+		}
 	}
 
 	// Set visibility (default to private if not specified)
@@ -423,9 +443,9 @@ func (s *GroupService) CreateGroup(req db.CreateGroupRequest, createdBy string)
 
 	// Create the group with organization_id and project_id
 	_, err = tx.Exec(`
-		INSERT INTO groups (id, name, description, type, visibility, is_active, created_at, updated_at, created_by, escalation_timeout, escalation_method, organization_id, project_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-	`, group.ID, group.Name, group.Description, group.Type, group.Visibility, group.IsActive, group.CreatedAt, group.UpdatedAt, group.CreatedBy, group.EscalationTimeout, group.EscalationMethod, nullIfEmpty(group.OrganizationID), nullIfEmpty(group.ProjectID))
+		INSERT INTO groups (id, name, description, type, visibility, is_active, created_at, updated_at, created_by, escalation_timeout, escalation_method, organization_id, project_id, parent_group_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, group.ID, group.Name, group.Description, group.Type, group.Visibility, group.IsActive, group.CreatedAt, group.UpdatedAt, group.CreatedBy, group.EscalationTimeout, group.EscalationMethod, nullIfEmpty(group.OrganizationID), nullIfEmpty(group.ProjectID), nullIfEmpty(group.ParentGroupID))
 	if err != nil {
 		return group, err
 	}
@@ -482,18 +502,99 @@ func (s *GroupService) UpdateGroup(id string, req db.UpdateGroupRequest) (db.Gro
 	if req.EscalationMethod != nil {
 		group.EscalationMethod = *req.EscalationMethod
 	}
+	if req.DefaultEscalationPolicyID != nil {
+		if *req.DefaultEscalationPolicyID != "" {
+			var inGroup bool
+			err := s.PG.QueryRow(`
+				SELECT EXISTS(SELECT 1 FROM escalation_policies WHERE id = $1 AND group_id = $2)
+			`, *req.DefaultEscalationPolicyID, id).Scan(&inGroup)
+			if err != nil {
+				return group, fmt.Errorf("failed to check escalation policy: %w", err)
+			}
+			if !inGroup {
+				return group, ErrPolicyNotInGroup
+			}
+		}
+		group.DefaultEscalationPolicyID = *req.DefaultEscalationPolicyID
+	}
+	if req.ParentGroupID != nil {
+		if *req.ParentGroupID != "" {
+			var orgID string
+			err := s.PG.QueryRow(`SELECT COALESCE(organization_id::text, '') FROM groups WHERE id = $1`, id).Scan(&orgID)
+			if err != nil {
+				return group, fmt.Errorf("failed to get group organization: %w", err)
+			}
+			if err := s.checkParentGroup(id, *req.ParentGroupID, orgID); err != nil {
+				return group, err
+			}
+		}
+		group.ParentGroupID = *req.ParentGroupID
+	}
+	if req.ReassignOnHandoff != nil {
+		group.ReassignOnHandoff = *req.ReassignOnHandoff
+	}
 
 	group.UpdatedAt = time.Now()
 
 	_, err = s.PG.Exec(`
 		UPDATE groups 
-		SET name = $2, description = $3, type = $4, visibility = $5, is_active = $6, updated_at = $7, escalation_timeout = $8, escalation_method = $9
+		SET name = $2, description = $3, type = $4, visibility = $5, is_active = $6, updated_at = $7, escalation_timeout = $8, escalation_method = $9,
+		    default_escalation_policy_id = $10, parent_group_id = $11, reassign_on_handoff = $12
 		WHERE id = $1
-	`, id, group.Name, group.Description, group.Type, group.Visibility, group.IsActive, group.UpdatedAt, group.EscalationTimeout, group.EscalationMethod)
+	`, id, group.Name, group.Description, group.Type, group.Visibility, group.IsActive, group.UpdatedAt, group.EscalationTimeout, group.EscalationMethod,
+		nullIfEmpty(group.DefaultEscalationPolicyID), nullIfEmpty(group.ParentGroupID), group.ReassignOnHandoff)
 
 	return group, err
 }
 
+// checkParentGroup validates that parentID can become the parent of groupID:
+// it must be an active group in the same organization and not groupID itself
+// or one of its sub-teams (which would create a cycle)
+func (s *GroupService) checkParentGroup(groupID, parentID, orgID string) error {
+	var valid bool
+	err := s.PG.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM groups p
+			WHERE p.id = $1 AND p.is_active = true
+			AND COALESCE(p.organization_id::text, '') = $3
+			AND p.id NOT IN (SELECT group_id FROM group_subtree($2))
+		)
+	`, parentID, groupID, orgID).Scan(&valid)
+	if err != nil {
+		return fmt.Errorf("failed to check parent group: %w", err)
+	}
+	if !valid {
+		return ErrInvalidParentGroup
+	}
+	return nil
+}
+
+// GetSubGroups returns every active sub-team below a group, nearest first
+func (s *GroupService) GetSubGroups(groupID string) ([]db.SubGroup, error) {
+	rows, err := s.PG.Query(`
+		SELECT g.id, g.name, COALESCE(g.parent_group_id::text, ''), gs.depth,
+		       (SELECT COUNT(*) FROM memberships m WHERE m.resource_type = 'group' AND m.resource_id = g.id)
+		FROM group_subtree($1) gs
+		JOIN groups g ON g.id = gs.group_id
+		WHERE gs.depth > 0
+		ORDER BY gs.depth ASC, g.name ASC
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub-groups: %w", err)
+	}
+	defer rows.Close()
+
+	subGroups := []db.SubGroup{}
+	for rows.Next() {
+		var sg db.SubGroup
+		if err := rows.Scan(&sg.ID, &sg.Name, &sg.ParentGroupID, &sg.Depth, &sg.MemberCount); err != nil {
+			return nil, fmt.Errorf("failed to scan sub-group: %w", err)
+		}
+		subGroups = append(subGroups, sg)
+	}
+	return subGroups, rows.Err()
+}
+
 // DeleteGroup soft deletes a group
 func (s *GroupService) DeleteGroup(id string) error {
 	_, err := s.PG.Exec(`UPDATE groups SET is_active = false, updated_at = $1 WHERE id = $2`, time.Now(), id)
@@ -516,7 +617,26 @@ func (s *GroupService) GetGroupMembers(groupID string) ([]db.GroupMember, error)
 		WHERE m.resource_type = 'group' AND m.resource_id = $1
 		ORDER BY m.created_at ASC
 	`
+	return s.queryGroupMembers(query, groupID)
+}
+
+// GetRollupMembers returns the members of a group and all of its sub-teams.
+// A user in several of them is listed once, under the group nearest the top.
+func (s *GroupService) GetRollupMembers(groupID string) ([]db.GroupMember, error) {
+	query := `
+		SELECT DISTINCT ON (m.user_id)
+			m.id, m.resource_id as group_id, m.user_id, m.role,
+			m.created_at as added_at, COALESCE(m.invited_by::text, '') as added_by,
+			u.name as user_name, u.email as user_email, u.team as user_team
+		FROM group_subtree($1) gs
+		JOIN memberships m ON m.resource_type = 'group' AND m.resource_id = gs.group_id
+		JOIN users u ON m.user_id = u.id
+		ORDER BY m.user_id, gs.depth ASC, m.created_at ASC
+	`
+	return s.queryGroupMembers(query, groupID)
+}
 
+func (s *GroupService) queryGroupMembers(query, groupID string) ([]db.GroupMember, error) {
 	rows, err := s.PG.Query(query, groupID)
 	if err != nil {
 		return nil, err
@@ -570,6 +690,31 @@ func (s *GroupService) GetGroupMember(groupID, userID string) (db.GroupMember, e
 // Requires: User must be a member of the org that owns the group
 // Auto-adds user to Project if group belongs to a project and user is not already a member
 func (s *GroupService) AddGroupMember(groupID string, req db.AddGroupMemberRequest, addedBy string) (db.GroupMember, error) {
+	// Start transaction for atomic operation
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return db.GroupMember{}, err
+	}
+	defer tx.Rollback()
+
+	member, err := s.AddGroupMemberTx(tx, groupID, req, addedBy)
+	if err != nil {
+		return member, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return member, err
+	}
+
+	// Get the full member info with user details
+	return s.GetGroupMember(groupID, req.UserID)
+}
+
+// AddGroupMemberTx adds a user to a group within the caller's transaction,
+// with the same rules as AddGroupMember. The returned member has no user
+// details.
+func (s *GroupService) AddGroupMemberTx(tx *sql.Tx, groupID string, req db.AddGroupMemberRequest, addedBy string) (db.GroupMember, error) {
 	now := time.Now()
 	member := db.GroupMember{
 		GroupID:  groupID,
@@ -590,14 +735,14 @@ func (s *GroupService) AddGroupMember(groupID string, req db.AddGroupMemberReque
 	// Get group info (org_id and project_id)
 	var orgID string
 	var projectID sql.NullString
-	err := s.PG.QueryRow(`SELECT organization_id, project_id FROM groups WHERE id = $1`, groupID).Scan(&orgID, &projectID)
+	err := tx.QueryRow(`SELECT organization_id, project_id FROM groups WHERE id = $1`, groupID).Scan(&orgID, &projectID)
 	if err != nil {
 		return member, fmt.Errorf("failed to get group: %w", err)
 	}
 
 	// Check if user is a member of the org (REQUIRED)
 	var orgMemberCount int
-	err = s.PG.QueryRow(`
+	err = tx.QueryRow(`
 		SELECT COUNT(*) FROM memberships
 		WHERE user_id = $1 AND resource_type = 'org' AND resource_id = $2
 	`, req.UserID, orgID).Scan(&orgMemberCount)
@@ -608,13 +753,6 @@ func (s *GroupService) AddGroupMember(groupID string, req db.AddGroupMemberReque
 		return member, fmt.Errorf("user must be invited to the organization first before adding to a group")
 	}
 
-	// Start transaction for atomic operation
-	tx, err := s.PG.Begin()
-	if err != nil {
-		return member, err
-	}
-	defer tx.Rollback()
-
 	// Auto-add to Project if group has project_id and user is not already a project member
 	if projectID.Valid && projectID.String != "" {
 		var existingProjectMembership int
@@ -650,14 +788,7 @@ func (s *GroupService) AddGroupMember(groupID string, req db.AddGroupMemberReque
 		return member, fmt.Errorf("failed to add user to group: %w", err)
 	}
 	member.ID = memberID
-
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return member, err
-	}
-
-	// Get the full member info with user details
-	return s.GetGroupMember(groupID, req.UserID)
+	return member, nil
 }
 
 // UpdateGroupMember updates a group member
@@ -697,6 +828,23 @@ func (s *GroupService) RemoveGroupMember(groupID, userID string) error {
 	return err
 }
 
+// RemoveGroupMemberTx removes a user from a group within the caller's transaction
+func (s *GroupService) RemoveGroupMemberTx(tx *sql.Tx, groupID, userID string) error {
+	_, err := tx.Exec(`DELETE FROM memberships WHERE resource_type = 'group' AND resource_id = $1 AND user_id = $2`, groupID, userID)
+	return err
+}
+
+// CountFutureShifts returns how many of a user's active shifts in the group
+// haven't ended yet
+func (s *GroupService) CountFutureShifts(groupID, userID string) (int, error) {
+	var count int
+	err := s.PG.QueryRow(`
+		SELECT COUNT(*) FROM shifts
+		WHERE group_id = $1 AND user_id = $2 AND is_active = true AND end_time > NOW()
+	`, groupID, userID).Scan(&count)
+	return count, err
+}
+
 // UTILITY METHODS
 
 // GetUserGroups returns all groups that a user belongs to
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrBroadcastGroupNotFound    = errors.New("group not found in this organization")
+	ErrBroadcastNoRecipients     = errors.New("group has no active members to page")
+	ErrBroadcastIncidentNotFound = errors.New("incident not found in this organization")
+)
+
+// broadcastTitleLength is how much of the message goes into a new incident's title
+const broadcastTitleLength = 120
+
+// CanBroadcastToGroup reports whether the user leads the group. Org admins
+// are checked by the caller through the authorizer.
+func (s *IncidentService) CanBroadcastToGroup(groupID, userID string) (bool, error) {
+	var isLeader bool
+	err := s.PG.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM memberships
+			WHERE resource_type = 'group' AND resource_id = $1 AND user_id = $2 AND role = 'admin'
+		)
+	`, groupID, userID).Scan(&isLeader)
+	if err != nil {
+		return false, fmt.Errorf("failed to check group role: %w", err)
+	}
+	return isLeader, nil
+}
+
+// BroadcastToGroup pages every active member of a group at once through all
+// of their notification channels, ignoring schedules and escalation order.
+// The page is attached to req.IncidentID, or to a new critical incident for
+// the group when none is given. Each group gets at most
+// GroupBroadcastLimitPerHour broadcasts in a rolling hour.
+func (s *IncidentService) BroadcastToGroup(groupID, orgID string, req db.GroupBroadcastRequest, sentBy string) (*db.GroupBroadcast, error) {
+	broadcast := &db.GroupBroadcast{
+		GroupID:        groupID,
+		OrganizationID: orgID,
+		Message:        req.Message,
+		SentBy:         sentBy,
+	}
+
+	var projectID string
+	err := s.PG.QueryRow(`
+		SELECT name, COALESCE(project_id::text, '') FROM groups
+		WHERE id = $1 AND organization_id = $2 AND is_active = TRUE
+	`, groupID, orgID).Scan(&broadcast.GroupName, &projectID)
+	if err == sql.ErrNoRows {
+		return nil, ErrBroadcastGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	if err := s.checkBroadcastRateLimit(groupID); err != nil {
+		return nil, err
+	}
+
+	recipients, err := s.broadcastRecipients(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, ErrBroadcastNoRecipients
+	}
+	broadcast.Recipients = recipients
+	broadcast.RecipientCount = len(recipients)
+
+	if req.IncidentID != "" {
+		var status string
+		err := s.PG.QueryRow(`SELECT status FROM incidents WHERE id = $1 AND organization_id = $2`,
+			req.IncidentID, orgID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return nil, ErrBroadcastIncidentNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get incident: %w", err)
+		}
+		if status == db.IncidentStatusResolved {
+			return nil, ErrIncidentResolved
+		}
+		broadcast.IncidentID = req.IncidentID
+	} else {
+		incident, err := s.createBroadcastIncident(broadcast, projectID, req.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create broadcast incident: %w", err)
+		}
+		broadcast.IncidentID = incident.ID
+	}
+
+	err = s.PG.QueryRow(`
+		INSERT INTO group_broadcasts (group_id, organization_id, incident_id, message, recipient_count, sent_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, groupID, orgID, broadcast.IncidentID, req.Message, broadcast.RecipientCount, nullIfEmpty(sentBy)).
+		Scan(&broadcast.ID, &broadcast.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record broadcast: %w", err)
+	}
+
+	userIDs := make([]string, len(recipients))
+	for i, r := range recipients {
+		userIDs[i] = r.UserID
+	}
+	eventData := map[string]interface{}{
+		"broadcast_id":    broadcast.ID,
+		"group_id":        groupID,
+		"group_name":      broadcast.GroupName,
+		"message":         req.Message,
+		"recipient_count": broadcast.RecipientCount,
+		"paged_user_ids":  userIDs,
+	}
+	if err := s.createIncidentEvent(broadcast.IncidentID, db.IncidentEventGroupBroadcast, eventData, sentBy); err != nil {
+		log.Printf("⚠️  Failed to record group broadcast on incident %s: %v", broadcast.IncidentID, err)
+	}
+
+	if s.NotificationWorker != nil {
+		go func() {
+			for _, userID := range userIDs {
+				if err := s.NotificationWorker.SendIncidentPagedNotification(userID, broadcast.IncidentID, req.Message); err != nil {
+					log.Printf("⚠️  Failed to send broadcast page to %s: %v", userID, err)
+				}
+			}
+		}()
+	}
+
+	return broadcast, nil
+}
+
+// checkBroadcastRateLimit returns a RateLimitError once the group has used up
+// its broadcasts for the rolling hour
+func (s *IncidentService) checkBroadcastRateLimit(groupID string) error {
+	var count int
+	var oldest sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT COUNT(*), MIN(created_at) FROM group_broadcasts
+		WHERE group_id = $1 AND created_at > NOW() - INTERVAL '1 hour'
+	`, groupID).Scan(&count, &oldest)
+	if err != nil {
+		return fmt.Errorf("failed to check broadcast rate limit: %w", err)
+	}
+	if count < db.GroupBroadcastLimitPerHour {
+		return nil
+	}
+	resetAt := time.Now().Add(time.Hour)
+	if oldest.Valid {
+		resetAt = oldest.Time.Add(time.Hour)
+	}
+	return &RateLimitError{Window: db.WindowTypeHour, Limit: db.GroupBroadcastLimitPerHour, ResetAt: resetAt}
+}
+
+// broadcastRecipients lists the group's active members
+func (s *IncidentService) broadcastRecipients(groupID string) ([]db.GroupBroadcastRecipient, error) {
+	rows, err := s.PG.Query(`
+		SELECT DISTINCT u.id, COALESCE(u.name, ''), COALESCE(u.email, '')
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.resource_type = 'group' AND m.resource_id = $1 AND COALESCE(u.is_active, TRUE)
+		ORDER BY 2, 1
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []db.GroupBroadcastRecipient{}
+	for rows.Next() {
+		var r db.GroupBroadcastRecipient
+		if err := rows.Scan(&r.UserID, &r.Name, &r.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan group member: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+// createBroadcastIncident opens the incident a broadcast is tracked on. It
+// has no assignee or escalation policy: every member is paged directly.
+func (s *IncidentService) createBroadcastIncident(broadcast *db.GroupBroadcast, projectID, severity string) (*db.Incident, error) {
+	if severity == "" {
+		severity = "critical"
+	}
+	title := []rune(broadcast.Message)
+	if len(title) > broadcastTitleLength {
+		title = append(title[:broadcastTitleLength-1], '…')
+	}
+
+	incident := &db.Incident{
+		Title:          db.GroupBroadcastTitlePrefix + string(title),
+		Description:    broadcast.Message,
+		Severity:       severity,
+		Status:         db.IncidentStatusTriggered,
+		Source:         db.GroupBroadcastSource,
+		Urgency:        db.IncidentUrgencyHigh,
+		GroupID:        broadcast.GroupID,
+		OrganizationID: broadcast.OrganizationID,
+		ProjectID:      projectID,
+		Labels: map[string]interface{}{
+			db.GroupBroadcastLabel: "true",
+		},
+	}
+	return s.CreateIncident(incident)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestBroadcastToGroupPagesEveryActiveMember(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM groups\s+WHERE id = \$1 AND organization_id = \$2`).WithArgs("grp-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "project_id"}).AddRow("Platform", ""))
+	mock.ExpectQuery(`FROM group_broadcasts`).WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "min"}).AddRow(1, time.Now().Add(-10*time.Minute)))
+	mock.ExpectQuery(`FROM memberships m`).WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).
+			AddRow("user-1", "Ana", "ana@example.com").
+			AddRow("user-2", "Bo", "bo@example.com"))
+	mock.ExpectQuery(`SELECT status FROM incidents`).WithArgs("inc-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(db.IncidentStatusAcknowledged))
+	mock.ExpectQuery(`INSERT INTO group_broadcasts`).
+		WithArgs("grp-1", "org-1", "inc-1", "Region down", 2, "user-9").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("bc-1", time.Now()))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventGroupBroadcast, sqlmock.AnyArg(), "user-9").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sender := &recordingSender{paged: make(chan string, 2)}
+	s := &IncidentService{PG: pg, NotificationWorker: sender}
+	broadcast, err := s.BroadcastToGroup("grp-1", "org-1",
+		db.GroupBroadcastRequest{Message: "Region down", IncidentID: "inc-1"}, "user-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if broadcast.ID != "bc-1" || broadcast.RecipientCount != 2 || broadcast.GroupName != "Platform" {
+		t.Errorf("unexpected broadcast: %+v", broadcast)
+	}
+
+	var paged []string
+	for i := 0; i < 2; i++ {
+		select {
+		case userID := <-sender.paged:
+			paged = append(paged, userID)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for pages")
+		}
+	}
+	sort.Strings(paged)
+	if paged[0] != "user-1" || paged[1] != "user-2" {
+		t.Errorf("paged %v, want every member", paged)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBroadcastToGroupRateLimited(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	oldest := time.Now().Add(-40 * time.Minute)
+	mock.ExpectQuery(`FROM groups`).WithArgs("grp-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "project_id"}).AddRow("Platform", ""))
+	mock.ExpectQuery(`FROM group_broadcasts`).WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "min"}).AddRow(db.GroupBroadcastLimitPerHour, oldest))
+
+	_, err = (&IncidentService{PG: pg}).BroadcastToGroup("grp-1", "org-1", db.GroupBroadcastRequest{Message: "x"}, "user-9")
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if !rateErr.ResetAt.Equal(oldest.Add(time.Hour)) {
+		t.Errorf("ResetAt = %v, want an hour after the oldest broadcast", rateErr.ResetAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
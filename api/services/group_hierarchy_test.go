@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestUpdateGroupRejectsParentCycle(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT g.id, g.name`).WithArgs("platform").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "description", "type", "visibility", "is_active", "created_at", "updated_at",
+			"created_by", "escalation_timeout", "escalation_method", "member_count",
+			"default_escalation_policy_id", "parent_group_id", "reassign_on_handoff",
+		}).AddRow("platform", "Platform", "", "escalation", "private", true, now, now,
+			"alice", 300, "parallel", 3, "", "", false))
+	mock.ExpectQuery(`SELECT COALESCE\(organization_id::text, ''\) FROM groups`).WithArgs("platform").
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow("org-1"))
+	// "database" is a sub-team of "platform", so it can't become its parent
+	mock.ExpectQuery(`p.id NOT IN \(SELECT group_id FROM group_subtree\(\$2\)\)`).
+		WithArgs("database", "platform", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	parent := "database"
+	_, err = NewGroupService(pg).UpdateGroup("platform", db.UpdateGroupRequest{ParentGroupID: &parent})
+	if !errors.Is(err, ErrInvalidParentGroup) {
+		t.Fatalf("err = %v, want ErrInvalidParentGroup", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetRollupMembersQueriesSubtree(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	now := time.Now()
+
+	mock.ExpectQuery(`DISTINCT ON \(m.user_id\).*FROM group_subtree\(\$1\)`).WithArgs("platform").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "group_id", "user_id", "role", "added_at", "added_by", "user_name", "user_email", "user_team",
+		}).
+			AddRow("m-1", "platform", "alice", "admin", now, "", "Alice", "alice@example.com", "Platform").
+			AddRow("m-2", "database", "bob", "member", now, "", "Bob", "bob@example.com", "DB"))
+
+	members, err := NewGroupService(pg).GetRollupMembers("platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[1].GroupID != "database" {
+		t.Errorf("members = %+v, want alice from platform and bob from database", members)
+	}
+}
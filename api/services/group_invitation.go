@@ -0,0 +1,544 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// Sentinel errors for invitation / join request flows (mapped to HTTP status in handlers)
+var (
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationNotPending    = errors.New("invitation is no longer pending")
+	ErrInvitationExpired       = errors.New("invitation has expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+	ErrJoinRequestNotFound     = errors.New("join request not found")
+	ErrJoinRequestNotAllowed   = errors.New("group does not accept join requests")
+	ErrAlreadyGroupMember      = errors.New("user is already a member of this group")
+
+	ErrInvitationOrgMembershipRequired = errors.New("an organization admin must invite you to the organization first")
+)
+
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+// GroupInvitationService handles email invitations and join requests for groups.
+// Membership is still written through GroupService.AddGroupMember so ReBAC rules
+// (org membership, project auto-add) stay in one place.
+type GroupInvitationService struct {
+	PG           *sql.DB
+	GroupService *GroupService
+	EmailService *EmailService
+}
+
+// NewGroupInvitationService creates a new GroupInvitationService
+func NewGroupInvitationService(pg *sql.DB, groupService *GroupService, emailService *EmailService) *GroupInvitationService {
+	return &GroupInvitationService{
+		PG:           pg,
+		GroupService: groupService,
+		EmailService: emailService,
+	}
+}
+
+// IsGroupAdmin returns true if the user holds the admin (leader) role in the group
+func (s *GroupInvitationService) IsGroupAdmin(groupID, userID string) (bool, error) {
+	var count int
+	err := s.PG.QueryRow(`
+		SELECT COUNT(*) FROM memberships
+		WHERE resource_type = 'group' AND resource_id = $1 AND user_id = $2 AND role = 'admin'
+	`, groupID, userID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// INVITATIONS
+
+// CreateInvitation stores a pending invitation and emails the signed join link.
+// If SMTP is not configured, the link is returned on the invitation so the
+// inviter can share it manually.
+func (s *GroupInvitationService) CreateInvitation(groupID string, req db.CreateGroupInvitationRequest, invitedBy string) (*db.GroupInvitation, error) {
+	group, err := s.GroupService.GetGroup(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	role := req.Role
+	if role == "" {
+		role = db.GroupMemberRoleMember
+	}
+	ttl := defaultInvitationTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	// Refuse to invite someone who is already in the group
+	var memberCount int
+	err = s.PG.QueryRow(`
+		SELECT COUNT(*) FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.resource_type = 'group' AND m.resource_id = $1 AND lower(u.email) = $2
+	`, groupID, email).Scan(&memberCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check group membership: %w", err)
+	}
+	if memberCount > 0 {
+		return nil, ErrAlreadyGroupMember
+	}
+
+	token, tokenHash, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Re-inviting replaces any previous pending invitation for the same email
+	_, err = tx.Exec(`
+		UPDATE group_invitations SET status = 'revoked'
+		WHERE group_id = $1 AND lower(email) = $2 AND status = 'pending'
+	`, groupID, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke previous invitation: %w", err)
+	}
+
+	inv := db.GroupInvitation{
+		GroupID:   groupID,
+		Email:     email,
+		Role:      role,
+		Status:    db.GroupInvitationStatusPending,
+		InvitedBy: invitedBy,
+		ExpiresAt: time.Now().Add(ttl),
+		GroupName: group.Name,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO group_invitations (group_id, email, role, token_hash, status, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, 'pending', $5, $6)
+		RETURNING id, created_at
+	`, groupID, email, role, tokenHash, nullIfEmpty(invitedBy), inv.ExpiresAt).Scan(&inv.ID, &inv.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	link := invitationURL(token)
+	if s.EmailService.IsConfigured() {
+		subject := fmt.Sprintf("You have been invited to join %s on SLAR", group.Name)
+		body := fmt.Sprintf(
+			"You have been invited to join the group \"%s\" on SLAR.\n\nAccept the invitation:\n%s\n\nThis link expires on %s.\n",
+			group.Name, link, inv.ExpiresAt.UTC().Format(time.RFC1123),
+		)
+		if err := s.EmailService.Send(email, subject, body); err != nil {
+			log.Printf("Warning: failed to send group invitation email to %s: %v", email, err)
+		} else {
+			inv.EmailSent = true
+		}
+	}
+	if !inv.EmailSent {
+		inv.InvitationURL = link
+	}
+
+	return &inv, nil
+}
+
+// ListInvitations returns invitations for a group, optionally filtered by status
+func (s *GroupInvitationService) ListInvitations(groupID, status string) ([]db.GroupInvitation, error) {
+	query := `
+		SELECT gi.id, gi.group_id, gi.email, gi.role,
+			CASE WHEN gi.status = 'pending' AND gi.expires_at < NOW() THEN 'expired' ELSE gi.status END,
+			COALESCE(gi.invited_by::text, ''), COALESCE(gi.accepted_by::text, ''),
+			gi.accepted_at, gi.expires_at, gi.created_at,
+			COALESCE(u.name, '')
+		FROM group_invitations gi
+		LEFT JOIN users u ON u.id = gi.invited_by
+		WHERE gi.group_id = $1
+	`
+	args := []interface{}{groupID}
+	if status != "" {
+		query += ` AND (CASE WHEN gi.status = 'pending' AND gi.expires_at < NOW() THEN 'expired' ELSE gi.status END) = $2`
+		args = append(args, status)
+	}
+	query += " ORDER BY gi.created_at DESC"
+
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []db.GroupInvitation{}
+	for rows.Next() {
+		var inv db.GroupInvitation
+		if err := rows.Scan(
+			&inv.ID, &inv.GroupID, &inv.Email, &inv.Role, &inv.Status,
+			&inv.InvitedBy, &inv.AcceptedBy, &inv.AcceptedAt, &inv.ExpiresAt, &inv.CreatedAt,
+			&inv.InviterName,
+		); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// RevokeInvitation cancels a pending invitation
+func (s *GroupInvitationService) RevokeInvitation(groupID, invitationID string) error {
+	result, err := s.PG.Exec(`
+		UPDATE group_invitations SET status = 'revoked'
+		WHERE id = $1 AND group_id = $2 AND status = 'pending'
+	`, invitationID, groupID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation redeems an invitation token for the authenticated user.
+// The user's email must match the invited address. Accepting also grants org
+// membership when an org admin sent the invitation, since it is their consent;
+// a group leader's invitation requires the user to be in the org already.
+func (s *GroupInvitationService) AcceptInvitation(token, userID, userEmail string) (db.GroupMember, error) {
+	var inv db.GroupInvitation
+	err := s.PG.QueryRow(`
+		SELECT id, group_id, email, role, status, COALESCE(invited_by::text, ''), expires_at
+		FROM group_invitations
+		WHERE token_hash = $1
+	`, hashInvitationToken(token)).Scan(
+		&inv.ID, &inv.GroupID, &inv.Email, &inv.Role, &inv.Status, &inv.InvitedBy, &inv.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return db.GroupMember{}, ErrInvitationNotFound
+	}
+	if err != nil {
+		return db.GroupMember{}, err
+	}
+
+	if inv.Status != db.GroupInvitationStatusPending {
+		return db.GroupMember{}, ErrInvitationNotPending
+	}
+	if time.Now().After(inv.ExpiresAt) {
+		s.PG.Exec(`UPDATE group_invitations SET status = 'expired' WHERE id = $1`, inv.ID)
+		return db.GroupMember{}, ErrInvitationExpired
+	}
+	if !strings.EqualFold(strings.TrimSpace(userEmail), inv.Email) {
+		return db.GroupMember{}, ErrInvitationEmailMismatch
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return db.GroupMember{}, err
+	}
+	defer tx.Rollback()
+
+	// Consume the invitation in the same transaction as the membership writes,
+	// so a token is used once and a failed accept leaves it pending
+	result, err := tx.Exec(`
+		UPDATE group_invitations SET status = 'accepted', accepted_by = $2, accepted_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, inv.ID, userID)
+	if err != nil {
+		return db.GroupMember{}, fmt.Errorf("failed to mark invitation accepted: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return db.GroupMember{}, ErrInvitationNotPending
+	}
+
+	if err := s.ensureOrgMembership(tx, inv.GroupID, userID, inv.InvitedBy); err != nil {
+		return db.GroupMember{}, err
+	}
+
+	var isMember bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM memberships WHERE resource_type = 'group' AND resource_id = $1 AND user_id = $2)
+	`, inv.GroupID, userID).Scan(&isMember)
+	if err != nil {
+		return db.GroupMember{}, err
+	}
+	if !isMember {
+		req := db.AddGroupMemberRequest{UserID: userID, Role: inv.Role}
+		if _, err := s.GroupService.AddGroupMemberTx(tx, inv.GroupID, req, inv.InvitedBy); err != nil {
+			return db.GroupMember{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.GroupMember{}, err
+	}
+	return s.GroupService.GetGroupMember(inv.GroupID, userID)
+}
+
+// JOIN REQUESTS
+
+// CreateJoinRequest records a pending request to join a public or organization group
+func (s *GroupInvitationService) CreateJoinRequest(groupID, userID string, req db.CreateGroupJoinRequestRequest) (*db.GroupJoinRequest, error) {
+	var visibility, orgID string
+	err := s.PG.QueryRow(`SELECT visibility, COALESCE(organization_id::text, '') FROM groups WHERE id = $1`, groupID).Scan(&visibility, &orgID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("group not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch visibility {
+	case db.GroupVisibilityPublic:
+	case db.GroupVisibilityOrganization:
+		var orgMember int
+		if err := s.PG.QueryRow(`
+			SELECT COUNT(*) FROM memberships
+			WHERE user_id = $1 AND resource_type = 'org' AND resource_id = $2
+		`, userID, orgID).Scan(&orgMember); err != nil {
+			return nil, err
+		}
+		if orgMember == 0 {
+			return nil, ErrJoinRequestNotAllowed
+		}
+	default:
+		return nil, ErrJoinRequestNotAllowed
+	}
+
+	if isMember, err := s.GroupService.IsUserInGroup(groupID, userID); err != nil {
+		return nil, err
+	} else if isMember {
+		return nil, ErrAlreadyGroupMember
+	}
+
+	jr := db.GroupJoinRequest{
+		GroupID: groupID,
+		UserID:  userID,
+		Message: req.Message,
+		Status:  db.GroupJoinRequestStatusPending,
+	}
+	err = s.PG.QueryRow(`
+		INSERT INTO group_join_requests (group_id, user_id, message, status)
+		VALUES ($1, $2, $3, 'pending')
+		ON CONFLICT (group_id, user_id) WHERE status = 'pending'
+		DO UPDATE SET message = EXCLUDED.message
+		RETURNING id, created_at
+	`, groupID, userID, req.Message).Scan(&jr.ID, &jr.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create join request: %w", err)
+	}
+
+	s.notifyGroupAdminsOfJoinRequest(groupID, userID)
+
+	return &jr, nil
+}
+
+// ListJoinRequests returns join requests for a group, optionally filtered by status
+func (s *GroupInvitationService) ListJoinRequests(groupID, status string) ([]db.GroupJoinRequest, error) {
+	query := `
+		SELECT jr.id, jr.group_id, jr.user_id, COALESCE(jr.message, ''), jr.status,
+			COALESCE(jr.reviewed_by::text, ''), jr.reviewed_at, jr.created_at,
+			COALESCE(u.name, ''), COALESCE(u.email, '')
+		FROM group_join_requests jr
+		JOIN users u ON u.id = jr.user_id
+		WHERE jr.group_id = $1
+	`
+	args := []interface{}{groupID}
+	if status != "" {
+		query += " AND jr.status = $2"
+		args = append(args, status)
+	}
+	query += " ORDER BY jr.created_at DESC"
+
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []db.GroupJoinRequest{}
+	for rows.Next() {
+		var jr db.GroupJoinRequest
+		if err := rows.Scan(
+			&jr.ID, &jr.GroupID, &jr.UserID, &jr.Message, &jr.Status,
+			&jr.ReviewedBy, &jr.ReviewedAt, &jr.CreatedAt,
+			&jr.UserName, &jr.UserEmail,
+		); err != nil {
+			return nil, err
+		}
+		requests = append(requests, jr)
+	}
+	return requests, rows.Err()
+}
+
+// ReviewJoinRequest approves or rejects a pending join request.
+// Approval adds the requester as a regular group member.
+func (s *GroupInvitationService) ReviewJoinRequest(groupID, requestID, reviewerID string, approve bool) (*db.GroupJoinRequest, error) {
+	status := db.GroupJoinRequestStatusRejected
+	if approve {
+		status = db.GroupJoinRequestStatusApproved
+	}
+
+	var jr db.GroupJoinRequest
+	err := s.PG.QueryRow(`
+		UPDATE group_join_requests
+		SET status = $3, reviewed_by = $4, reviewed_at = NOW()
+		WHERE id = $1 AND group_id = $2 AND status = 'pending'
+		RETURNING id, group_id, user_id, COALESCE(message, ''), status, reviewed_at, created_at
+	`, requestID, groupID, status, reviewerID).Scan(
+		&jr.ID, &jr.GroupID, &jr.UserID, &jr.Message, &jr.Status, &jr.ReviewedAt, &jr.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrJoinRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	jr.ReviewedBy = reviewerID
+
+	if approve {
+		if _, err := s.addMemberIfMissing(groupID, jr.UserID, db.GroupMemberRoleMember, reviewerID); err != nil {
+			// Roll the request back to pending so it can be retried
+			s.PG.Exec(`UPDATE group_join_requests SET status = 'pending', reviewed_by = NULL, reviewed_at = NULL WHERE id = $1`, jr.ID)
+			return nil, err
+		}
+	}
+
+	return &jr, nil
+}
+
+// CancelJoinRequest lets the requester withdraw their own pending request
+func (s *GroupInvitationService) CancelJoinRequest(groupID, requestID, userID string) error {
+	result, err := s.PG.Exec(`
+		UPDATE group_join_requests SET status = 'cancelled'
+		WHERE id = $1 AND group_id = $2 AND user_id = $3 AND status = 'pending'
+	`, requestID, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrJoinRequestNotFound
+	}
+	return nil
+}
+
+// HELPERS
+
+func (s *GroupInvitationService) addMemberIfMissing(groupID, userID, role, addedBy string) (db.GroupMember, error) {
+	isMember, err := s.GroupService.IsUserInGroup(groupID, userID)
+	if err != nil {
+		return db.GroupMember{}, err
+	}
+	if isMember {
+		return s.GroupService.GetGroupMember(groupID, userID)
+	}
+	return s.GroupService.AddGroupMember(groupID, db.AddGroupMemberRequest{UserID: userID, Role: role}, addedBy)
+}
+
+// ensureOrgMembership makes sure the invitee belongs to the group's
+// organization. Only an invitation from an org owner or admin adds them as an
+// org member; group leaders can invite existing org members only.
+func (s *GroupInvitationService) ensureOrgMembership(tx *sql.Tx, groupID, userID, invitedBy string) error {
+	_, err := tx.Exec(`
+		INSERT INTO memberships (user_id, resource_type, resource_id, role, created_at, updated_at, invited_by)
+		SELECT $1, 'org', g.organization_id, 'member', NOW(), NOW(), $3
+		FROM groups g
+		WHERE g.id = $2 AND g.organization_id IS NOT NULL
+		  AND EXISTS (
+		      SELECT 1 FROM memberships m
+		      WHERE m.user_id = $3 AND m.resource_type = 'org' AND m.resource_id = g.organization_id
+		        AND m.role IN ('owner', 'admin'))
+		ON CONFLICT (user_id, resource_type, resource_id) DO NOTHING
+	`, userID, groupID, nullIfEmpty(invitedBy))
+	if err != nil {
+		return fmt.Errorf("failed to add user to organization: %w", err)
+	}
+
+	var inOrg bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM memberships m
+			JOIN groups g ON g.organization_id = m.resource_id
+			WHERE g.id = $1 AND m.user_id = $2 AND m.resource_type = 'org'
+		)
+	`, groupID, userID).Scan(&inOrg)
+	if err != nil {
+		return fmt.Errorf("failed to check org membership: %w", err)
+	}
+	if !inOrg {
+		return ErrInvitationOrgMembershipRequired
+	}
+	return nil
+}
+
+// notifyGroupAdminsOfJoinRequest emails group admins; failures are logged only
+func (s *GroupInvitationService) notifyGroupAdminsOfJoinRequest(groupID, requesterID string) {
+	if !s.EmailService.IsConfigured() {
+		return
+	}
+	leaders, err := s.GroupService.GetGroupLeaders(groupID)
+	if err != nil || len(leaders) == 0 {
+		return
+	}
+
+	var groupName, requesterName string
+	s.PG.QueryRow(`SELECT name FROM groups WHERE id = $1`, groupID).Scan(&groupName)
+	s.PG.QueryRow(`SELECT COALESCE(name, email) FROM users WHERE id = $1`, requesterID).Scan(&requesterName)
+
+	subject := fmt.Sprintf("%s requested to join %s", requesterName, groupName)
+	body := fmt.Sprintf("%s has requested to join the group \"%s\".\n\nReview pending requests:\n%s\n",
+		requesterName, groupName, webBaseURL()+"/groups/"+url.PathEscape(groupID))
+	for _, leader := range leaders {
+		if leader.UserEmail == "" {
+			continue
+		}
+		if err := s.EmailService.Send(leader.UserEmail, subject, body); err != nil {
+			log.Printf("Warning: failed to notify %s of join request: %v", leader.UserEmail, err)
+		}
+	}
+}
+
+// generateInvitationToken returns a URL-safe random token and its SHA-256 hex hash
+func generateInvitationToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashInvitationToken(token), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func invitationURL(token string) string {
+	return webBaseURL() + "/groups/join?token=" + url.QueryEscape(token)
+}
+
+// webBaseURL returns the frontend base URL used in emailed links
+func webBaseURL() string {
+	base := config.Current().SlarWebURL
+	if base == "" {
+		base = config.Current().PublicURL
+	}
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return strings.TrimRight(base, "/")
+}
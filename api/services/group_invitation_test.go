@@ -0,0 +1,147 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGenerateInvitationToken(t *testing.T) {
+	token, hash, err := generateInvitationToken()
+	if err != nil {
+		t.Fatalf("generateInvitationToken() error = %v", err)
+	}
+	if len(token) < 40 {
+		t.Errorf("token too short: %d chars", len(token))
+	}
+	if hash != hashInvitationToken(token) {
+		t.Errorf("hash does not match hashInvitationToken(token)")
+	}
+	if len(hash) != 64 {
+		t.Errorf("hash length = %d, want 64", len(hash))
+	}
+
+	other, _, _ := generateInvitationToken()
+	if other == token {
+		t.Errorf("expected unique tokens")
+	}
+}
+
+func TestGroupInvitationService_AcceptInvitation(t *testing.T) {
+	columns := []string{"id", "group_id", "email", "role", "status", "invited_by", "expires_at"}
+
+	tests := []struct {
+		name      string
+		userEmail string
+		mockFunc  func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name:      "unknown token",
+			userEmail: "alice@example.com",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM group_invitations").
+					WithArgs(hashInvitationToken("tok")).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvitationNotFound,
+		},
+		{
+			name:      "already accepted",
+			userEmail: "alice@example.com",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM group_invitations").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(
+						"inv-1", "group-1", "alice@example.com", "member", "accepted", "", time.Now().Add(time.Hour),
+					))
+			},
+			wantErr: ErrInvitationNotPending,
+		},
+		{
+			name:      "expired",
+			userEmail: "alice@example.com",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM group_invitations").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(
+						"inv-1", "group-1", "alice@example.com", "member", "pending", "", time.Now().Add(-time.Hour),
+					))
+				mock.ExpectExec("UPDATE group_invitations SET status = 'expired'").
+					WithArgs("inv-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: ErrInvitationExpired,
+		},
+		{
+			name:      "email mismatch",
+			userEmail: "mallory@example.com",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM group_invitations").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(
+						"inv-1", "group-1", "alice@example.com", "member", "pending", "", time.Now().Add(time.Hour),
+					))
+			},
+			wantErr: ErrInvitationEmailMismatch,
+		},
+		{
+			name:      "accepted concurrently",
+			userEmail: "alice@example.com",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM group_invitations").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(
+						"inv-1", "group-1", "alice@example.com", "member", "pending", "leader-1", time.Now().Add(time.Hour),
+					))
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE group_invitations SET status = 'accepted'").
+					WithArgs("inv-1", "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrInvitationNotPending,
+		},
+		{
+			name:      "group leader cannot add a new org member",
+			userEmail: "alice@example.com",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM group_invitations").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(
+						"inv-1", "group-1", "alice@example.com", "member", "pending", "leader-1", time.Now().Add(time.Hour),
+					))
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE group_invitations SET status = 'accepted'").
+					WithArgs("inv-1", "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO memberships").
+					WithArgs("user-1", "group-1", "leader-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT EXISTS").
+					WithArgs("group-1", "user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrInvitationOrgMembershipRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create mock: %v", err)
+			}
+			defer pg.Close()
+
+			service := NewGroupInvitationService(pg, NewGroupService(pg), &EmailService{})
+			tt.mockFunc(mock)
+
+			_, err = service.AcceptInvitation("tok", "user-1", tt.userEmail)
+			if err != tt.wantErr {
+				t.Errorf("AcceptInvitation() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyKeyTTL is how long a key keeps returning the original incident
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// A reservation whose request never completed (e.g. the process crashed) can
+// be taken over by a retry after this long
+const idempotencyReservationLease = time.Minute
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey      = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused       = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyRequestInFlight = errors.New("a request with this idempotency key is still in progress")
+)
+
+// ValidateIdempotencyKey checks a client-supplied key is 1-255 printable ASCII characters
+func ValidateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("%w: must be 1-%d characters", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return fmt.Errorf("%w: must be printable ASCII", ErrInvalidIdempotencyKey)
+		}
+	}
+	return nil
+}
+
+// HashIdempotentRequest returns a stable hash of the request parts, used to
+// detect a key being reused for a different request
+func HashIdempotentRequest(parts ...interface{}) string {
+	payload, _ := json.Marshal(parts)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReserveIdempotencyKey claims (scope, key) for a new incident. It returns the
+// ID of the incident already created with this key, or "" if the caller now
+// owns the key and must call CompleteIdempotencyKey or ReleaseIdempotencyKey.
+func (s *IncidentService) ReserveIdempotencyKey(scope, key, requestHash string) (string, error) {
+	// Expired keys behave as if they were never used
+	if _, err := s.PG.Exec(`
+		DELETE FROM incident_idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2 AND expires_at < NOW()
+	`, scope, key); err != nil {
+		return "", fmt.Errorf("failed to expire idempotency key: %w", err)
+	}
+
+	result, err := s.PG.Exec(`
+		INSERT INTO incident_idempotency_keys (scope, idempotency_key, request_hash, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (scope, idempotency_key) DO NOTHING
+	`, scope, key, requestHash, int(IdempotencyKeyTTL.Seconds()))
+	if err != nil {
+		return "", fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 1 {
+		return "", nil
+	}
+
+	var storedHash string
+	var incidentID sql.NullString
+	var createdAt time.Time
+	err = s.PG.QueryRow(`
+		SELECT request_hash, incident_id, created_at
+		FROM incident_idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2
+	`, scope, key).Scan(&storedHash, &incidentID, &createdAt)
+	if err == sql.ErrNoRows {
+		// Released by a failed request between our INSERT and SELECT
+		return "", ErrIdempotencyRequestInFlight
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if storedHash != requestHash {
+		return "", ErrIdempotencyKeyReused
+	}
+	if incidentID.Valid {
+		return incidentID.String, nil
+	}
+
+	if time.Since(createdAt) < idempotencyReservationLease {
+		return "", ErrIdempotencyRequestInFlight
+	}
+	result, err = s.PG.Exec(`
+		UPDATE incident_idempotency_keys
+		SET created_at = NOW()
+		WHERE scope = $1 AND idempotency_key = $2 AND incident_id IS NULL AND created_at = $3
+	`, scope, key, createdAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows != 1 {
+		return "", ErrIdempotencyRequestInFlight
+	}
+	return "", nil
+}
+
+// CompleteIdempotencyKey links a reserved key to the incident it created
+func (s *IncidentService) CompleteIdempotencyKey(scope, key, incidentID string) error {
+	_, err := s.PG.Exec(`
+		UPDATE incident_idempotency_keys
+		SET incident_id = $3
+		WHERE scope = $1 AND idempotency_key = $2
+	`, scope, key, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a reservation after a failed request so the client can retry
+func (s *IncidentService) ReleaseIdempotencyKey(scope, key string) error {
+	_, err := s.PG.Exec(`
+		DELETE FROM incident_idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2 AND incident_id IS NULL
+	`, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes keys past their TTL
+func (s *IncidentService) PurgeExpiredIdempotencyKeys() (int64, error) {
+	result, err := s.PG.Exec(`DELETE FROM incident_idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	for _, key := range []string{"", strings.Repeat("k", 256), "bad\nkey"} {
+		if err := ValidateIdempotencyKey(key); !errors.Is(err, ErrInvalidIdempotencyKey) {
+			t.Errorf("ValidateIdempotencyKey(%q) = %v, want ErrInvalidIdempotencyKey", key, err)
+		}
+	}
+	if err := ValidateIdempotencyKey("7f2c9a1e-retry-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHashIdempotentRequest(t *testing.T) {
+	a := HashIdempotentRequest(map[string]interface{}{"title": "db down", "urgency": "high"}, "proj-1")
+	b := HashIdempotentRequest(map[string]interface{}{"urgency": "high", "title": "db down"}, "proj-1")
+	if a != b {
+		t.Error("hash should not depend on map ordering")
+	}
+	if a == HashIdempotentRequest(map[string]interface{}{"title": "db down", "urgency": "high"}, "proj-2") {
+		t.Error("hash should change with the request")
+	}
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	const scope, key, hash = "org:org-1", "retry-1", "hash-1"
+
+	tests := []struct {
+		name    string
+		setup   func(mock sqlmock.Sqlmock)
+		wantID  string
+		wantErr error
+	}{
+		{
+			name: "first request reserves the key",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO incident_idempotency_keys").
+					WithArgs(scope, key, hash, int(IdempotencyKeyTTL.Seconds())).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "retry returns the original incident",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT request_hash, incident_id, created_at").
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "incident_id", "created_at"}).
+						AddRow(hash, "incident-1", time.Now()))
+			},
+			wantID: "incident-1",
+		},
+		{
+			name: "different payload is rejected",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT request_hash, incident_id, created_at").
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "incident_id", "created_at"}).
+						AddRow("other-hash", "incident-1", time.Now()))
+			},
+			wantErr: ErrIdempotencyKeyReused,
+		},
+		{
+			name: "concurrent request is still running",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT request_hash, incident_id, created_at").
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "incident_id", "created_at"}).
+						AddRow(hash, nil, time.Now()))
+			},
+			wantErr: ErrIdempotencyRequestInFlight,
+		},
+		{
+			name: "stale reservation is taken over",
+			setup: func(mock sqlmock.Sqlmock) {
+				stale := time.Now().Add(-5 * time.Minute)
+				mock.ExpectExec("DELETE FROM incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO incident_idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT request_hash, incident_id, created_at").
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "incident_id", "created_at"}).
+						AddRow(hash, nil, stale))
+				mock.ExpectExec("UPDATE incident_idempotency_keys").
+					WithArgs(scope, key, stale).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			tt.setup(mock)
+
+			gotID, err := NewIncidentService(db, nil).ReserveIdempotencyKey(scope, key, hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReserveIdempotencyKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if gotID != tt.wantID {
+				t.Errorf("ReserveIdempotencyKey() = %q, want %q", gotID, tt.wantID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
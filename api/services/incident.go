@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/vanchonlee/slar/db"
 )
 
@@ -15,6 +16,7 @@ type IncidentService struct {
 	PG                 *sql.DB
 	FCMService         *FCMService
 	NotificationWorker NotificationSender // Interface for sending notifications
+	LLM                *LLMService        // For AI-drafted postmortems
 }
 
 // NotificationSender interface for sending incident notifications
@@ -23,6 +25,8 @@ type NotificationSender interface {
 	SendIncidentEscalatedNotification(userID, incidentID string) error
 	SendIncidentAcknowledgedNotification(userID, incidentID string) error
 	SendIncidentResolvedNotification(userID, incidentID string) error
+	SendIncidentPagedNotification(userID, incidentID, message string) error
+	SendIncidentClaimedNotification(userID, incidentID, message string) error
 }
 
 func NewIncidentService(pg *sql.DB, fcmService *FCMService) *IncidentService {
@@ -37,6 +41,11 @@ func (s *IncidentService) SetNotificationWorker(notificationWorker NotificationS
 	s.NotificationWorker = notificationWorker
 }
 
+// SetLLMService sets the language model service used to draft postmortems
+func (s *IncidentService) SetLLMService(llmService *LLMService) {
+	s.LLM = llmService
+}
+
 // LightweightNotificationSender implements NotificationSender for API server
 // It only sends messages to PGMQ queue without processing them
 type LightweightNotificationSender struct {
@@ -65,9 +74,25 @@ func (l *LightweightNotificationSender) SendIncidentAssignedNotification(userID,
 		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
 
-	_, err = l.PG.Exec(`SELECT pgmq.send($1, $2)`, "incident_notifications", string(notificationJSON))
-	if err != nil {
-		return fmt.Errorf("failed to send notification to queue: %w", err)
+	if err := queueIncidentNotification(l.PG, userID, "assigned", notification["channels"].([]string), notificationJSON); err != nil {
+		return err
+	}
+
+	if err := EnqueueChatNotification(l.PG, userID, incidentID, "assigned"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWhatsAppNotification(l.PG, userID, incidentID, "assigned"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWebPushNotification(l.PG, userID, incidentID, "assigned"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueVoiceCall(l.PG, userID, incidentID, "assigned"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	if err := RecordNotificationSent(l.PG, userID, incidentID, "assigned"); err != nil {
+		log.Printf("⚠️  Failed to track page receipt: %v", err)
 	}
 
 	return nil
@@ -90,9 +115,25 @@ func (l *LightweightNotificationSender) SendIncidentEscalatedNotification(userID
 		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
 
-	_, err = l.PG.Exec(`SELECT pgmq.send($1, $2)`, "incident_notifications", string(notificationJSON))
-	if err != nil {
-		return fmt.Errorf("failed to send notification to queue: %w", err)
+	if err := queueIncidentNotification(l.PG, userID, "escalated", notification["channels"].([]string), notificationJSON); err != nil {
+		return err
+	}
+
+	if err := EnqueueChatNotification(l.PG, userID, incidentID, "escalated"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWhatsAppNotification(l.PG, userID, incidentID, "escalated"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWebPushNotification(l.PG, userID, incidentID, "escalated"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueVoiceCall(l.PG, userID, incidentID, "escalated"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	if err := RecordNotificationSent(l.PG, userID, incidentID, "escalated"); err != nil {
+		log.Printf("⚠️  Failed to track page receipt: %v", err)
 	}
 
 	return nil
@@ -120,6 +161,19 @@ func (l *LightweightNotificationSender) SendIncidentAcknowledgedNotification(use
 		return fmt.Errorf("failed to send notification to queue: %w", err)
 	}
 
+	if err := EnqueueChatNotification(l.PG, userID, incidentID, "acknowledged"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWhatsAppNotification(l.PG, userID, incidentID, "acknowledged"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWebPushNotification(l.PG, userID, incidentID, "acknowledged"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueFederationEvent(l.PG, incidentID, db.IncidentStatusAcknowledged); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
 	return nil
 }
 
@@ -145,54 +199,92 @@ func (l *LightweightNotificationSender) SendIncidentResolvedNotification(userID,
 		return fmt.Errorf("failed to send notification to queue: %w", err)
 	}
 
+	if err := EnqueueChatNotification(l.PG, userID, incidentID, "resolved"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWhatsAppNotification(l.PG, userID, incidentID, "resolved"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWebPushNotification(l.PG, userID, incidentID, "resolved"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueFederationEvent(l.PG, incidentID, db.IncidentStatusResolved); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
 	return nil
 }
 
-// ListIncidents returns a paginated list of incidents with filters
-// ReBAC: Explicit OR Inherited access pattern with MANDATORY Tenant Isolation
-// - Direct: User has project membership
-// - Inherited: User is org member AND project is "Open" (no explicit members)
-// - Ad-hoc: Incident assigned directly to user
-// IMPORTANT: All queries MUST be scoped to current organization (Context-Aware)
-func (s *IncidentService) ListIncidents(filters map[string]interface{}) ([]db.IncidentResponse, error) {
-	// ReBAC: Get user context
-	currentUserID, hasCurrentUser := filters["current_user_id"].(string)
-	if !hasCurrentUser || currentUserID == "" {
-		return []db.IncidentResponse{}, nil
+// SendIncidentClaimedNotification tells a responder paged in parallel that
+// someone else acknowledged the incident first
+func (l *LightweightNotificationSender) SendIncidentClaimedNotification(userID, incidentID, message string) error {
+	notification := map[string]interface{}{
+		"type":        "claimed",
+		"user_id":     userID,
+		"incident_id": incidentID,
+		"channels":    []string{"slack"},
+		"priority":    "medium",
+		"data":        map[string]interface{}{"message": message},
+		"created_at":  time.Now(),
+		"retry_count": 0,
 	}
 
-	// ReBAC: Get organization context (MANDATORY for Tenant Isolation)
-	currentOrgID, hasOrgContext := filters["current_org_id"].(string)
-	if !hasOrgContext || currentOrgID == "" {
-		log.Printf("WARNING: ListIncidents called without organization context - returning empty")
-		return []db.IncidentResponse{}, nil
+	notificationJSON, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
 
-	// ReBAC: Explicit OR Inherited access with Tenant Isolation
-	// Uses single `memberships` table with resource_type = 'project' or 'org'
-	// $1 = currentUserID, $2 = currentOrgID
-	query := `
-		SELECT
-			i.id, i.title, i.description, i.status, i.urgency, i.priority,
-			i.created_at, i.updated_at, i.assigned_to, i.assigned_at,
-			i.acknowledged_by, i.acknowledged_at, i.resolved_by, i.resolved_at,
-			i.source, i.integration_id, i.service_id, i.external_id, i.external_url,
-			i.escalation_policy_id, i.current_escalation_level, i.last_escalated_at,
-			i.escalation_status, i.group_id, i.api_key_id, i.severity, i.incident_key,
-			i.alert_count, i.labels, i.custom_fields,
-			u_assigned.name as assigned_to_name, u_assigned.email as assigned_to_email,
-			u_acked.name as acknowledged_by_name, u_acked.email as acknowledged_by_email,
-			u_resolved.name as resolved_by_name, u_resolved.email as resolved_by_email,
-			g.name as group_name, s.name as service_name,
-			ep.name as escalation_policy_name
-		FROM incidents i
-		LEFT JOIN users u_assigned ON i.assigned_to = u_assigned.id
-		LEFT JOIN users u_acked ON i.acknowledged_by = u_acked.id
-		LEFT JOIN users u_resolved ON i.resolved_by = u_resolved.id
-		LEFT JOIN groups g ON i.group_id = g.id
-		LEFT JOIN services s ON i.service_id = s.id
-		LEFT JOIN escalation_policies ep ON i.escalation_policy_id = ep.id
-		WHERE
+	_, err = l.PG.Exec(`SELECT pgmq.send($1, $2)`, "incident_notifications", string(notificationJSON))
+	if err != nil {
+		return fmt.Errorf("failed to send notification to queue: %w", err)
+	}
+
+	return nil
+}
+
+// SendIncidentPagedNotification sends an ad-hoc page (responder pulled into an incident) to queue
+func (l *LightweightNotificationSender) SendIncidentPagedNotification(userID, incidentID, message string) error {
+	notification := map[string]interface{}{
+		"type":        "paged",
+		"user_id":     userID,
+		"incident_id": incidentID,
+		"channels":    []string{"slack", "push"},
+		"priority":    "high",
+		"data":        map[string]interface{}{"message": message},
+		"created_at":  time.Now(),
+		"retry_count": 0,
+	}
+
+	notificationJSON, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	if err := queueIncidentNotification(l.PG, userID, "paged", notification["channels"].([]string), notificationJSON); err != nil {
+		return err
+	}
+
+	if err := EnqueueChatNotification(l.PG, userID, incidentID, "paged"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWhatsAppNotification(l.PG, userID, incidentID, "paged"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := EnqueueWebPushNotification(l.PG, userID, incidentID, "paged"); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	if err := RecordNotificationSent(l.PG, userID, incidentID, "paged"); err != nil {
+		log.Printf("⚠️  Failed to track page receipt: %v", err)
+	}
+
+	return nil
+}
+
+// incidentAccessScopeSQL restricts incidents (aliased i) to those the user ($1)
+// can see in the organization ($2): tenant isolation plus project, org and
+// ad-hoc (assigned) access
+const incidentAccessScopeSQL = `
 			-- TENANT ISOLATION (MANDATORY): Only incidents in current organization
 			i.organization_id = $2
 			AND (
@@ -248,7 +340,57 @@ func (s *IncidentService) ListIncidents(filters map[string]interface{}) ([]db.In
 				-- Scope D: Ad-hoc access - incident assigned directly to user
 				i.assigned_to = $1
 			)
-	`
+`
+
+// ListIncidents returns a paginated list of incidents with filters
+// ReBAC: Explicit OR Inherited access pattern with MANDATORY Tenant Isolation
+// - Direct: User has project membership
+// - Inherited: User is org member AND project is "Open" (no explicit members)
+// - Ad-hoc: Incident assigned directly to user
+// IMPORTANT: All queries MUST be scoped to current organization (Context-Aware)
+func (s *IncidentService) ListIncidents(filters map[string]interface{}) ([]db.IncidentResponse, error) {
+	// ReBAC: Get user context
+	currentUserID, hasCurrentUser := filters["current_user_id"].(string)
+	if !hasCurrentUser || currentUserID == "" {
+		return []db.IncidentResponse{}, nil
+	}
+
+	// ReBAC: Get organization context (MANDATORY for Tenant Isolation)
+	currentOrgID, hasOrgContext := filters["current_org_id"].(string)
+	if !hasOrgContext || currentOrgID == "" {
+		log.Printf("WARNING: ListIncidents called without organization context - returning empty")
+		return []db.IncidentResponse{}, nil
+	}
+
+	// ReBAC: Explicit OR Inherited access with Tenant Isolation
+	// Uses single `memberships` table with resource_type = 'project' or 'org'
+	// $1 = currentUserID, $2 = currentOrgID
+	query := `
+		SELECT
+			i.id, i.title, i.description, i.status, i.urgency, i.priority,
+			i.created_at, i.updated_at, i.assigned_to, i.assigned_at,
+			i.acknowledged_by, i.acknowledged_at, i.resolved_by, i.resolved_at,
+			i.source, i.integration_id, i.service_id, i.external_id, i.external_url,
+			i.escalation_policy_id, i.current_escalation_level, i.last_escalated_at,
+			i.escalation_status, i.group_id, i.api_key_id, i.severity, i.incident_key,
+			i.alert_count, i.labels, i.custom_fields, COALESCE(i.workflow_state, ''), i.is_test, i.short_id, i.number,
+			i.tags, COALESCE(i.team, ''), COALESCE(i.component, ''), COALESCE(i.environment, ''),
+			u_assigned.name as assigned_to_name, u_assigned.email as assigned_to_email,
+			u_acked.name as acknowledged_by_name, u_acked.email as acknowledged_by_email,
+			u_resolved.name as resolved_by_name, u_resolved.email as resolved_by_email,
+			g.name as group_name, s.name as service_name,
+			ep.name as escalation_policy_name,
+			(SELECT COUNT(*) FROM incident_tasks t WHERE t.incident_id = i.id) as task_count,
+			(SELECT COUNT(*) FROM incident_tasks t WHERE t.incident_id = i.id AND t.completed_at IS NULL) as open_task_count
+		FROM incidents i
+		LEFT JOIN users u_assigned ON i.assigned_to = u_assigned.id
+		LEFT JOIN users u_acked ON i.acknowledged_by = u_acked.id
+		LEFT JOIN users u_resolved ON i.resolved_by = u_resolved.id
+		LEFT JOIN groups g ON i.group_id = g.id
+		LEFT JOIN services s ON i.service_id = s.id
+		LEFT JOIN escalation_policies ep ON i.escalation_policy_id = ep.id
+		WHERE
+` + incidentAccessScopeSQL
 
 	args := []interface{}{currentUserID, currentOrgID}
 	argIndex := 3
@@ -259,18 +401,52 @@ func (s *IncidentService) ListIncidents(filters map[string]interface{}) ([]db.In
 	if search, ok := filters["search"].(string); ok && search != "" {
 		hasSearch = true
 		searchArgIndex = argIndex
-		query += fmt.Sprintf(" AND (i.search_vector @@ plainto_tsquery('english', $%d) OR i.title ILIKE $%d OR i.description ILIKE $%d)", argIndex, argIndex+1, argIndex+2)
+		// "INC-1024" or "#1024" finds that incident too
+		number, isNumber := ParseIncidentNumber(search)
+		numberMatch := ""
+		if isNumber {
+			numberMatch = fmt.Sprintf(" OR i.number = $%d", argIndex+3)
+		}
+		query += fmt.Sprintf(" AND (i.search_vector @@ plainto_tsquery('english', $%d) OR i.title ILIKE $%d OR i.description ILIKE $%d%s)", argIndex, argIndex+1, argIndex+2, numberMatch)
 		searchPattern := "%" + search + "%"
 		args = append(args, search, searchPattern, searchPattern)
 		argIndex += 3
+		if isNumber {
+			args = append(args, number)
+			argIndex++
+		}
 	}
 
-	if status, ok := filters["status"].(string); ok && status != "" {
-		query += fmt.Sprintf(" AND i.status = $%d", argIndex)
+	// Custom workflow states can be filtered via status too; they are sub-states of acknowledged.
+	// "open" is everything not yet resolved.
+	if status, ok := filters["status"].(string); ok && status == "open" {
+		query += " AND i.status IN ('triggered', 'acknowledged')"
+	} else if ok && status != "" {
+		if isCanonicalIncidentStatus(status) {
+			query += fmt.Sprintf(" AND i.status = $%d", argIndex)
+		} else {
+			query += fmt.Sprintf(" AND i.workflow_state = $%d", argIndex)
+		}
 		args = append(args, status)
 		argIndex++
 	}
 
+	if workflowState, ok := filters["workflow_state"].(string); ok && workflowState != "" {
+		if workflowState == "none" {
+			query += " AND i.workflow_state IS NULL"
+		} else {
+			query += fmt.Sprintf(" AND i.workflow_state = $%d", argIndex)
+			args = append(args, workflowState)
+			argIndex++
+		}
+	}
+
+	if isTest, ok := filters["is_test"].(bool); ok {
+		query += fmt.Sprintf(" AND i.is_test = $%d", argIndex)
+		args = append(args, isTest)
+		argIndex++
+	}
+
 	if urgency, ok := filters["urgency"].(string); ok && urgency != "" {
 		query += fmt.Sprintf(" AND i.urgency = $%d", argIndex)
 		args = append(args, urgency)
@@ -305,8 +481,40 @@ func (s *IncidentService) ListIncidents(filters map[string]interface{}) ([]db.In
 		argIndex++
 	}
 
+	if tag, ok := filters["tag"].(string); ok && tag != "" {
+		query += fmt.Sprintf(" AND $%d = ANY(i.tags)", argIndex)
+		args = append(args, normalizeTag(tag))
+		argIndex++
+	}
+
+	if team, ok := filters["team"].(string); ok && team != "" {
+		query += fmt.Sprintf(" AND i.team = $%d", argIndex)
+		args = append(args, team)
+		argIndex++
+	}
+
+	if component, ok := filters["component"].(string); ok && component != "" {
+		query += fmt.Sprintf(" AND i.component = $%d", argIndex)
+		args = append(args, component)
+		argIndex++
+	}
+
+	// Environment filter; "prod" also matches incidents without an environment
+	if environment, ok := filters["environment"].(string); ok && environment != "" {
+		if db.IsProductionEnvironment(environment) {
+			query += fmt.Sprintf(" AND (i.environment IS NULL OR i.environment IN ($%d, $%d))", argIndex, argIndex+1)
+			args = append(args, db.EnvironmentProd, db.EnvironmentProduction)
+			argIndex += 2
+		} else {
+			query += fmt.Sprintf(" AND i.environment = $%d", argIndex)
+			args = append(args, environment)
+			argIndex++
+		}
+	}
+
+	// Group filter includes the group's sub-teams
 	if groupID, ok := filters["group_id"].(string); ok && groupID != "" {
-		query += fmt.Sprintf(" AND i.group_id = $%d", argIndex)
+		query += fmt.Sprintf(" AND i.group_id IN (SELECT group_id FROM group_subtree($%d))", argIndex)
 		args = append(args, groupID)
 		argIndex++
 	}
@@ -404,15 +612,18 @@ func (s *IncidentService) ListIncidents(filters map[string]interface{}) ([]db.In
 			&incident.Source, &integrationID, &serviceID, &externalID, &externalURL,
 			&escalationPolicyID, &incident.CurrentEscalationLevel, &lastEscalatedAt,
 			&incident.EscalationStatus, &groupID, &apiKeyID, &incident.Severity, &incidentKey,
-			&incident.AlertCount, &labels, &customFields,
+			&incident.AlertCount, &labels, &customFields, &incident.WorkflowState, &incident.IsTest, &incident.ShortID, &incident.Number,
+			pq.Array(&incident.Tags), &incident.Team, &incident.Component, &incident.Environment,
 			&assignedToName, &assignedToEmail,
 			&acknowledgedByName, &acknowledgedByEmail,
 			&resolvedByName, &resolvedByEmail,
 			&groupName, &serviceName, &escalationPolicyName,
+			&incident.TaskCount, &incident.OpenTaskCount,
 		)
 		if err != nil {
 			continue
 		}
+		incident.Reference = db.FormatIncidentNumber(incident.Number)
 
 		// Handle nullable fields
 		if assignedTo.Valid {
@@ -512,7 +723,8 @@ func (s *IncidentService) GetIncident(id string) (*db.IncidentResponse, error) {
 			i.source, i.integration_id, i.service_id, i.external_id, i.external_url,
 			i.escalation_policy_id, i.current_escalation_level, i.last_escalated_at, 
 			i.escalation_status, i.group_id, i.api_key_id, i.severity, i.incident_key, 
-			i.alert_count, i.labels, i.custom_fields,
+			i.alert_count, i.labels, i.custom_fields, COALESCE(i.workflow_state, ''), i.is_test, i.short_id, i.number,
+			i.tags, COALESCE(i.team, ''), COALESCE(i.component, ''), COALESCE(i.environment, ''),
 			i.organization_id, i.project_id,
 			u_assigned.name as assigned_to_name, u_assigned.email as assigned_to_email,
 			u_acked.name as acknowledged_by_name, u_acked.email as acknowledged_by_email,
@@ -551,7 +763,8 @@ func (s *IncidentService) GetIncident(id string) (*db.IncidentResponse, error) {
 		&incident.Source, &integrationID, &serviceID, &externalID, &externalURL,
 		&escalationPolicyID, &incident.CurrentEscalationLevel, &lastEscalatedAt,
 		&incident.EscalationStatus, &groupID, &apiKeyID, &incident.Severity, &incidentKey,
-		&incident.AlertCount, &labels, &customFields,
+		&incident.AlertCount, &labels, &customFields, &incident.WorkflowState, &incident.IsTest, &incident.ShortID, &incident.Number,
+		pq.Array(&incident.Tags), &incident.Team, &incident.Component, &incident.Environment,
 		&organizationID, &projectID,
 		&assignedToName, &assignedToEmail,
 		&acknowledgedByName, &acknowledgedByEmail,
@@ -565,6 +778,7 @@ func (s *IncidentService) GetIncident(id string) (*db.IncidentResponse, error) {
 		}
 		return nil, fmt.Errorf("failed to get incident: %w", err)
 	}
+	incident.Reference = db.FormatIncidentNumber(incident.Number)
 
 	// Handle nullable fields
 	if assignedTo.Valid {
@@ -660,6 +874,49 @@ func (s *IncidentService) GetIncident(id string) (*db.IncidentResponse, error) {
 		incident.RecentEvents = events
 	}
 
+	// Get per-responder page receipts
+	receipts, err := s.ListNotificationReceipts(id)
+	if err == nil {
+		incident.Receipts = receipts
+	}
+
+	// Per-level breakdown of who was paged and how
+	pagingAudit, err := s.GetPagingAudit(&incident)
+	if err == nil {
+		incident.PagingAudit = pagingAudit
+	}
+
+	// Get changes shortly before the incident started
+	changes, err := s.GetIncidentChanges(&incident.Incident)
+	if err == nil {
+		incident.RecentChanges = changes
+	}
+
+	// Estimated cost from the service's cost parameters
+	cost, err := s.GetIncidentCost(&incident.Incident)
+	if err == nil {
+		incident.Cost = cost
+	}
+
+	// Who is working it
+	claim, err := s.GetIncidentClaim(id)
+	if err == nil && claim.ClaimedBy != "" {
+		incident.Claim = claim
+	}
+
+	// Response checklist
+	tasks, err := s.ListIncidentTasks(id)
+	if err == nil {
+		incident.Tasks = tasks
+		incident.TaskCount, incident.OpenTaskCount = CountOpenTasks(tasks)
+	}
+
+	// Linked incidents and causing changes
+	relations, err := s.ListIncidentRelations(id)
+	if err == nil {
+		incident.Relations = relations
+	}
+
 	return &incident, nil
 }
 
@@ -684,8 +941,9 @@ func (s *IncidentService) CreateIncident(incident *db.Incident) (*db.Incident, e
 		incident.AlertCount = 1
 	}
 
-	// Auto-assign to current on-call user if not assigned
-	if incident.AssignedTo == "" {
+	// Auto-assign to current on-call user if not assigned; non-production
+	// alerts never page production on-call
+	if incident.AssignedTo == "" && db.IsProductionEnvironment(incident.Environment) {
 		userService := NewUserService(s.PG)
 		onCallUser, err := userService.GetCurrentOnCallUser()
 		if err == nil {
@@ -797,29 +1055,56 @@ func (s *IncidentService) CreateIncident(incident *db.Incident) (*db.Incident, e
 		log.Printf("WARNING: Incident created without organization_id")
 	}
 
+	// Normalize tags, team and component from the alert content
+	taggingRules := s.ApplyTaggingRules(incident)
+	if incident.Tags == nil {
+		incident.Tags = []string{}
+	}
 
-	_, err := s.PG.Exec(`
+	err := s.PG.QueryRow(`
 		INSERT INTO incidents (
 			id, title, description, status, urgency, priority,
 			assigned_to, source, integration_id, service_id, external_id, external_url,
 			escalation_policy_id, current_escalation_level, escalation_status, group_id, api_key_id,
-			severity, incident_key, alert_count, labels, custom_fields, organization_id, project_id
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24)`,
+			severity, incident_key, alert_count, labels, custom_fields, organization_id, project_id, is_test,
+			tags, team, component, environment
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29)
+		RETURNING short_id, number`,
 		incident.ID, incident.Title, incident.Description, incident.Status, incident.Urgency, incident.Priority,
 		assignedToParam, incident.Source, integrationIDParam, serviceIDParam, incident.ExternalID, incident.ExternalURL,
 		escalationPolicyIDParam, incident.CurrentEscalationLevel, incident.EscalationStatus,
 		groupIDParam, apiKeyIDParam, incident.Severity, incident.IncidentKey, incident.AlertCount,
-		labelsJSON, customFieldsJSON, organizationIDParam, projectIDParam,
-	)
+		labelsJSON, customFieldsJSON, organizationIDParam, projectIDParam, incident.IsTest,
+		pq.Array(incident.Tags), nullIfEmpty(incident.Team), nullIfEmpty(incident.Component), nullIfEmpty(incident.Environment),
+	).Scan(&incident.ShortID, &incident.Number)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create incident: %w", err)
 	}
+	incident.Reference = db.FormatIncidentNumber(incident.Number)
 
 	// Create triggered event
-	s.createIncidentEvent(incident.ID, db.IncidentEventTriggered, map[string]interface{}{
+	triggeredData := map[string]interface{}{
 		"source":   incident.Source,
 		"severity": incident.Severity,
-	}, "")
+		"is_test":  incident.IsTest,
+	}
+	if len(taggingRules) > 0 {
+		triggeredData["tagging_rules"] = taggingRules
+	}
+	s.createIncidentEvent(incident.ID, db.IncidentEventTriggered, triggeredData, "")
+
+	// Forward to remote instances through federation integrations
+	if err := EnqueueFederationEvent(s.PG, incident.ID, db.IncidentStatusTriggered); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	// Everyone else the first escalation level pages in parallel with the assignee
+	parallelUsers := s.firstLevelParallelUsers(incident)
+
+	// Freeze periods add their reviewers to every new incident
+	if !incident.IsTest {
+		s.pageFreezeReviewers(incident.ID)
+	}
 
 	// Create assignment event if incident was auto-assigned
 	if incident.AssignedTo != "" && incident.AssignedAt != nil {
@@ -828,6 +1113,9 @@ func (s *IncidentService) CreateIncident(incident *db.Incident) (*db.Incident, e
 			"method":         "auto_assignment",
 			"reason":         "escalation_policy",
 		}
+		if len(parallelUsers) > 1 {
+			eventData[ParallelPagedUsersKey] = parallelUsers
+		}
 
 		// Get user name for display
 		var userName string
@@ -852,9 +1140,18 @@ func (s *IncidentService) CreateIncident(incident *db.Incident) (*db.Incident, e
 			}
 		}()
 	}
+	if s.NotificationWorker != nil && len(parallelUsers) > 1 {
+		go func() {
+			for _, userID := range parallelUsers[1:] {
+				if err := s.NotificationWorker.SendIncidentPagedNotification(userID, incident.ID, "Paged in parallel by the first escalation level"); err != nil {
+					log.Printf("⚠️  Failed to page %s in parallel for incident %s: %v", userID, incident.ID, err)
+				}
+			}
+		}()
+	}
 
-	// Send FCM notification (convert to alert format for now)
-	if s.FCMService != nil && incident.AssignedTo != "" {
+	// Send FCM notification (convert to alert format for now). Test incidents never page for real.
+	if s.FCMService != nil && incident.AssignedTo != "" && !incident.IsTest {
 		go func() {
 			// Convert incident to alert format for FCM compatibility
 			alert := &db.Alert{
@@ -893,7 +1190,8 @@ func (s *IncidentService) UpdateIncident(id string, req db.UpdateIncidentRequest
 		argIndex++
 	}
 	if req.Status != nil {
-		query += fmt.Sprintf(", status = $%d", argIndex)
+		// A direct status change leaves any custom workflow state
+		query += fmt.Sprintf(", status = $%d, workflow_state = NULL", argIndex)
 		args = append(args, *req.Status)
 		argIndex++
 	}
@@ -958,9 +1256,11 @@ func (s *IncidentService) UpdateIncident(id string, req db.UpdateIncidentRequest
 // AcknowledgeIncident acknowledges an incident
 func (s *IncidentService) AcknowledgeIncident(id, userID, note string) error {
 	now := time.Now()
-	_, err := s.PG.Exec(`
+	result, err := s.PG.Exec(`
 		UPDATE incidents
-		SET status = $1, acknowledged_by = $2::uuid, acknowledged_at = $3, updated_at = $4
+		SET status = $1, acknowledged_by = $2::uuid, acknowledged_at = $3, updated_at = $4,
+		    claimed_at = CASE WHEN claimed_by IS NULL THEN $3 ELSE claimed_at END,
+		    claimed_by = COALESCE(claimed_by, $2::uuid)
 		WHERE id = $5 AND status = $6
 	`, db.IncidentStatusAcknowledged, userID, now, now, id, db.IncidentStatusTriggered)
 
@@ -968,6 +1268,11 @@ func (s *IncidentService) AcknowledgeIncident(id, userID, note string) error {
 		return fmt.Errorf("failed to acknowledge incident: %w", err)
 	}
 
+	// The first of several responders paged in parallel claims the incident
+	if n, _ := result.RowsAffected(); n == 1 {
+		s.claimParallelPage(id, userID)
+	}
+
 	// Create acknowledged event
 	eventData := map[string]interface{}{}
 	if note != "" {
@@ -992,9 +1297,18 @@ func (s *IncidentService) AcknowledgeIncident(id, userID, note string) error {
 
 // ResolveIncident resolves an incident
 func (s *IncidentService) ResolveIncident(id, userID, note, resolution string) error {
+	// Integrations and monitors don't close incidents during a freeze period
+	if isAutomatedActor(userID) && s.holdAutoResolve(id, userID, note, resolution) {
+		return nil
+	}
+
+	// The commander's notification overrides end with the incident
 	_, err := s.PG.Exec(`
+		WITH reset_settings AS (
+			DELETE FROM incident_notification_settings WHERE incident_id = $3
+		)
 		UPDATE incidents
-		SET status = $1, resolved_by = $2::uuid, resolved_at = NOW() AT TIME ZONE 'UTC'
+		SET status = $1, resolved_by = $2::uuid, resolved_at = NOW() AT TIME ZONE 'UTC', workflow_state = NULL
 		WHERE id = $3 AND status != $1
 	`, db.IncidentStatusResolved, userID, id)
 
@@ -1141,8 +1455,9 @@ func (s *IncidentService) createIncidentEvent(incidentID, eventType string, even
 	return err
 }
 
-// GetIncidentStats returns incident statistics
-func (s *IncidentService) GetIncidentStats() (map[string]interface{}, error) {
+// GetIncidentStats returns incident statistics. The estimated cost covers the
+// given organization's incidents and is left out when orgID is empty.
+func (s *IncidentService) GetIncidentStats(orgID string) (map[string]interface{}, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total,
@@ -1151,7 +1466,7 @@ func (s *IncidentService) GetIncidentStats() (map[string]interface{}, error) {
 			COUNT(CASE WHEN status = 'resolved' THEN 1 END) as resolved,
 			COUNT(CASE WHEN urgency = 'high' THEN 1 END) as high_urgency
 		FROM incidents
-		WHERE created_at >= NOW() - INTERVAL '30 days'
+		WHERE created_at >= NOW() - INTERVAL '30 days' AND NOT is_test AND drill_id IS NULL
 	`
 
 	var total, triggered, acknowledged, resolved, highUrgency int
@@ -1160,13 +1475,47 @@ func (s *IncidentService) GetIncidentStats() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("failed to get incident stats: %w", err)
 	}
 
-	return map[string]interface{}{
-		"total":        total,
-		"triggered":    triggered,
-		"acknowledged": acknowledged,
-		"resolved":     resolved,
-		"high_urgency": highUrgency,
-	}, nil
+	// Acknowledged incidents broken down by custom workflow state
+	byWorkflowState := map[string]int{}
+	rows, err := s.PG.Query(`
+		SELECT workflow_state, COUNT(*)
+		FROM incidents
+		WHERE created_at >= NOW() - INTERVAL '30 days' AND NOT is_test AND drill_id IS NULL
+		  AND status = 'acknowledged' AND workflow_state IS NOT NULL
+		GROUP BY workflow_state
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow state stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var state string
+		var count int
+		if err := rows.Scan(&state, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow state stats: %w", err)
+		}
+		byWorkflowState[state] = count
+	}
+
+	stats := map[string]interface{}{
+		"total":             total,
+		"triggered":         triggered,
+		"acknowledged":      acknowledged,
+		"resolved":          resolved,
+		"high_urgency":      highUrgency,
+		"by_workflow_state": byWorkflowState,
+	}
+
+	// The cost estimate is extra; the counts are still useful without it
+	if orgID != "" {
+		costs, err := s.GetIncidentCostSummary(orgID, time.Now().AddDate(0, 0, -30))
+		if err != nil {
+			log.Printf("Failed to get incident cost summary for org %s: %v", orgID, err)
+		} else {
+			stats["estimated_cost"] = costs
+		}
+	}
+	return stats, nil
 }
 
 // GetAssigneeFromEscalationPolicy determines who should be assigned to an incident based on escalation policy
@@ -1215,13 +1564,30 @@ func (s *IncidentService) GetAssigneeFromEscalationPolicy(escalationPolicyID, gr
 		// For now, let's assign to current on-call user in the group
 		return s.getCurrentOnCallUserFromGroup(groupID)
 
+	case db.EscalationTargetExternalSchedule:
+		return s.getCurrentOnCallUserFromExternalSchedule(targetID)
+
 	default:
 		// External or unknown target types don't have direct user assignment
 		return "", nil
 	}
 }
 
-// getCurrentOnCallUserFromScheduler gets the current on-call user from a specific scheduler
+// getCurrentOnCallUserFromExternalSchedule gets the first available user an
+// external_oncall integration reports on call
+func (s *IncidentService) getCurrentOnCallUserFromExternalSchedule(integrationID string) (string, error) {
+	candidates, _, err := externalOnCallCandidates(s.PG, externalOnCallClient, integrationID)
+	if err != nil {
+		return "", err
+	}
+	if users, _ := pickOnCallResponders(candidates, false); len(users) > 0 {
+		return users[0], nil
+	}
+	return "", nil
+}
+
+// getCurrentOnCallUserFromScheduler gets the current on-call user from a specific scheduler,
+// passing over anyone on vacation or DND
 // This uses the effective_shifts view which automatically handles schedule overrides
 func (s *IncidentService) getCurrentOnCallUserFromScheduler(schedulerID, groupID string) (string, error) {
 
@@ -1232,6 +1598,7 @@ func (s *IncidentService) getCurrentOnCallUserFromScheduler(schedulerID, groupID
 		AND group_id = $2
 		AND start_time <= NOW()
 		AND end_time >= NOW()
+		AND user_available(effective_user_id, NOW())
 		ORDER BY start_time ASC
 		LIMIT 1
 	`
@@ -1249,17 +1616,19 @@ func (s *IncidentService) getCurrentOnCallUserFromScheduler(schedulerID, groupID
 	return userID, nil
 }
 
-// getCurrentOnCallUserFromGroup gets the current on-call user from the group
+// getCurrentOnCallUserFromGroup gets the current on-call user from the group,
+// or from its nearest sub-team with someone available on call
 // This uses the effective_shifts view which automatically handles schedule overrides
 func (s *IncidentService) getCurrentOnCallUserFromGroup(groupID string) (string, error) {
 
 	query := `
-		SELECT effective_user_id
-		FROM effective_shifts
-		WHERE group_id = $1
-		AND start_time <= NOW()
-		AND end_time >= NOW()
-		ORDER BY start_time ASC
+		SELECT es.effective_user_id
+		FROM effective_shifts es
+		JOIN group_subtree($1) gs ON gs.group_id = es.group_id
+		WHERE es.start_time <= NOW()
+		AND es.end_time >= NOW()
+		AND user_available(es.effective_user_id, NOW())
+		ORDER BY gs.depth ASC, es.start_time ASC
 		LIMIT 1
 	`
 
@@ -1366,6 +1735,11 @@ func (s *IncidentService) ManualEscalateIncident(incidentID, userID string) (*db
 		if err != nil {
 			log.Printf("WARNING: Failed to get on-call user from group: %v", err)
 		}
+	case db.EscalationTargetExternalSchedule:
+		assignedUserID, err = s.getCurrentOnCallUserFromExternalSchedule(targetLevel.TargetID)
+		if err != nil {
+			log.Printf("WARNING: Failed to get on-call user from external schedule: %v", err)
+		}
 	case "external":
 		// External escalation doesn't assign to a user
 	default:
@@ -1505,8 +1879,15 @@ func (s *IncidentService) getEscalationLevels(policyID string) ([]db.EscalationL
 	return levels, nil
 }
 
-// FindIncidentByFingerprint finds an incident by fingerprint in labels
-func (s *IncidentService) FindIncidentByFingerprint(fingerprint string) (*db.Incident, error) {
+// FindIncidentByFingerprint finds an open incident by fingerprint in labels.
+// The search is limited to the integration's organization, or to the
+// integration itself when it has none, so an alert can't join (or resolve)
+// another tenant's incident.
+func (s *IncidentService) FindIncidentByFingerprint(integration db.Integration, fingerprint string) (*db.Incident, error) {
+	scope, scopeID := "organization_id", integration.OrganizationID
+	if scopeID == "" {
+		scope, scopeID = "integration_id", integration.ID
+	}
 
 	query := `
 		SELECT id, title, description, status, urgency, priority,
@@ -1517,7 +1898,7 @@ func (s *IncidentService) FindIncidentByFingerprint(fingerprint string) (*db.Inc
 			   escalation_status, group_id, api_key_id, severity, incident_key,
 			   alert_count, labels, custom_fields
 		FROM incidents
-		WHERE labels->>'fingerprint' = $1
+		WHERE labels->>'fingerprint' = $1 AND ` + scope + ` = $2
 		AND status IN ('triggered', 'acknowledged')
 		ORDER BY created_at DESC
 		LIMIT 1
@@ -1532,7 +1913,7 @@ func (s *IncidentService) FindIncidentByFingerprint(fingerprint string) (*db.Inc
 	var groupID, apiKeyID, incidentKey sql.NullString
 	var labels, customFields sql.NullString
 
-	err := s.PG.QueryRow(query, fingerprint).Scan(
+	err := s.PG.QueryRow(query, fingerprint, scopeID).Scan(
 		&incident.ID, &incident.Title, &incident.Description, &incident.Status,
 		&incident.Urgency, &incident.Priority, &incident.CreatedAt, &incident.UpdatedAt,
 		&assignedTo, &assignedAt, &acknowledgedBy, &acknowledgedAt,
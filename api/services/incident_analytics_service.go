@@ -13,13 +13,15 @@ import (
 
 // IncidentAnalyticsService handles AI-powered incident analysis via PGMQ
 type IncidentAnalyticsService struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Features *FeatureFlagService
 }
 
 // NewIncidentAnalyticsService creates a new incident analytics service
 func NewIncidentAnalyticsService(database *sql.DB) *IncidentAnalyticsService {
 	return &IncidentAnalyticsService{
-		DB: database,
+		DB:       database,
+		Features: NewFeatureFlagService(database),
 	}
 }
 
@@ -42,12 +44,22 @@ type AnalysisRequest struct {
 //   - error: Any error that occurred
 func (s *IncidentAnalyticsService) QueueIncidentForAnalysis(incident *db.Incident) error {
 	// Check if AI Pilot is enabled
-	if !config.App.AIIncidentAnalytics.Enabled {
+	if !config.Current().AIIncidentAnalytics.Enabled {
 		// Log only at debug level (or not at all?) - using Printf for now as info
 		log.Printf("ℹ️  AI Pilot disabled, skipping analysis for incident %s", incident.ID)
 		return nil
 	}
 
+	// Rolled out per organization with the ai_analysis feature flag
+	enabled, err := s.Features.IsEnabled(incident.OrganizationID, db.FeatureAIAnalysis)
+	if err != nil {
+		return fmt.Errorf("failed to check ai_analysis feature flag: %w", err)
+	}
+	if !enabled {
+		log.Printf("ℹ️  AI analysis not enabled for organization %s, skipping incident %s", incident.OrganizationID, incident.ID)
+		return nil
+	}
+
 	queueName := "incident_analysis_queue"
 
 	// Build incident data for analysis
@@ -62,6 +74,7 @@ func (s *IncidentAnalyticsService) QueueIncidentForAnalysis(incident *db.Inciden
 		"created_at":      incident.CreatedAt,
 		"organization_id": incident.OrganizationID, // Required for ReBAC tenant isolation
 		"project_id":      incident.ProjectID,      // Optional project scoping
+		"is_test":         incident.IsTest,
 	}
 
 	// Add labels if present
@@ -69,6 +82,13 @@ func (s *IncidentAnalyticsService) QueueIncidentForAnalysis(incident *db.Inciden
 		incidentData["labels"] = incident.Labels
 	}
 
+	// Add recent service changes - most incidents correlate with one
+	if changes, err := listRecentChanges(s.DB, incident.ServiceID, incident.ProjectID, incident.CreatedAt); err != nil {
+		log.Printf("⚠️  Failed to load recent changes for incident %s: %v", incident.ID, err)
+	} else if len(changes) > 0 {
+		incidentData["recent_changes"] = changes
+	}
+
 	// Build message payload
 	messagePayload := AnalysisRequest{
 		IncidentID:   incident.ID,
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var ErrIncidentArtifactNotFound = errors.New("artifact not found")
+
+const (
+	defaultIncidentArtifactRetention = 90 * 24 * time.Hour
+	defaultArtifactSearchLimit       = 20
+	maxArtifactSearchLimit           = 100
+)
+
+// artifactRedactions remove credentials from diagnostic output before it is
+// stored. Token formats come first so that "token: Bearer abc" loses the
+// bearer token, not just the word "Bearer".
+var artifactRedactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`), "[REDACTED_PRIVATE_KEY]"},
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]{8,}=*`), "$1 [REDACTED]"},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}`), "[REDACTED_JWT]"},
+	{regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`), "[REDACTED_AWS_KEY]"},
+	{regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr|github_pat)_[A-Za-z0-9_]{20,}`), "[REDACTED_GITHUB_TOKEN]"},
+	{regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}`), "[REDACTED_SLACK_TOKEN]"},
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`), "[REDACTED_API_KEY]"},
+	{regexp.MustCompile(`(://[^\s:/@]+:)[^\s@/]+@`), "${1}[REDACTED]@"},
+	{regexp.MustCompile(`(?i)\b([a-z0-9_.-]*(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)[a-z0-9_.-]*"?\s*[:=]\s*"?)([^\s"',;\[][^\s"',;]*)`), "${1}[REDACTED]"},
+}
+
+// redactArtifactContent removes credentials from content and returns how
+// many it removed
+func redactArtifactContent(content string) (string, int) {
+	redactions := 0
+	for _, r := range artifactRedactions {
+		if n := len(r.pattern.FindAllStringIndex(content, -1)); n > 0 {
+			redactions += n
+			content = r.pattern.ReplaceAllString(content, r.replacement)
+		}
+	}
+	return content, redactions
+}
+
+// truncateArtifactContent cuts content to max bytes without splitting a character
+func truncateArtifactContent(content string, max int) (string, bool) {
+	if len(content) <= max {
+		return content, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut], true
+}
+
+// incidentArtifactRetention is how long an artifact is kept when it doesn't
+// choose its own retention
+func incidentArtifactRetention() time.Duration {
+	if retention := config.Current().IncidentArtifactRetention; retention > 0 {
+		return retention
+	}
+	return defaultIncidentArtifactRetention
+}
+
+const incidentArtifactColumns = `
+	a.id, a.incident_id, a.kind, a.source, a.title, COALESCE(a.command, ''), a.content_sha256, a.size_bytes,
+	a.truncated, a.redactions, COALESCE(a.session_id, ''), COALESCE(a.created_by::text, ''),
+	COALESCE(u.name, u.email, ''), a.created_at, a.expires_at, COALESCE(a.image_url, ''), COALESCE(a.image_token, '')
+`
+
+func scanIncidentArtifact(row interface{ Scan(...interface{}) error }, extra ...interface{}) (db.IncidentArtifact, error) {
+	var a db.IncidentArtifact
+	var imageToken string
+	dest := []interface{}{&a.ID, &a.IncidentID, &a.Kind, &a.Source, &a.Title, &a.Command, &a.ContentSHA256, &a.SizeBytes,
+		&a.Truncated, &a.Redactions, &a.SessionID, &a.CreatedBy, &a.CreatedByName, &a.CreatedAt, &a.ExpiresAt,
+		&a.ImageURL, &imageToken}
+	err := row.Scan(append(dest, extra...)...)
+	if imageToken != "" {
+		a.ImageURL = incidentSnapshotImageURL(imageToken)
+	}
+	return a, err
+}
+
+// CreateIncidentArtifact redacts and stores diagnostic output on an incident.
+// With orgID set the incident must belong to that organization; the AI agent
+// relay passes it since it isn't scoped by the usual access checks.
+func (s *IncidentService) CreateIncidentArtifact(incidentID, orgID, userID, source string, req db.CreateIncidentArtifactRequest) (*db.IncidentArtifact, error) {
+	sizeBytes := len(req.Content)
+	content, redactions := redactArtifactContent(req.Content)
+	content, truncated := truncateArtifactContent(content, db.IncidentArtifactMaxBytes)
+	command, commandRedactions := redactArtifactContent(req.Command)
+	redactions += commandRedactions
+	sum := sha256.Sum256([]byte(content))
+
+	retention := incidentArtifactRetention()
+	if req.RetentionDays > 0 {
+		days := req.RetentionDays
+		if days > db.IncidentArtifactMaxRetentionDays {
+			days = db.IncidentArtifactMaxRetentionDays
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	var artifactID string
+	err := s.PG.QueryRow(`
+		INSERT INTO incident_artifacts (incident_id, kind, source, title, command, content, content_sha256, size_bytes,
+		                                truncated, redactions, session_id, created_by, expires_at)
+		SELECT i.id, $3, $4, $5, $6, $7, $8, $9::int, $10::boolean, $11::int, $12, $13::uuid,
+		       NOW() + make_interval(secs => $14::float8)
+		FROM incidents i
+		WHERE i.id = $1 AND ($2 = '' OR i.organization_id::text = $2)
+		RETURNING id
+	`, incidentID, orgID, req.Kind, source, req.Title, nullIfEmpty(command), content, hex.EncodeToString(sum[:]), sizeBytes,
+		truncated, redactions, nullIfEmpty(req.SessionID), nullIfEmpty(userID), retention.Seconds()).Scan(&artifactID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store artifact: %w", err)
+	}
+
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventArtifactAttached, map[string]interface{}{
+		"artifact_id": artifactID,
+		"kind":        req.Kind,
+		"title":       req.Title,
+		"source":      source,
+		"redactions":  redactions,
+	}, userID); err != nil {
+		log.Printf("⚠️  Failed to record artifact on incident %s: %v", incidentID, err)
+	}
+
+	return s.GetIncidentArtifact(incidentID, artifactID)
+}
+
+// ListIncidentArtifacts returns an incident's unexpired artifacts, newest
+// first, without their content
+func (s *IncidentService) ListIncidentArtifacts(incidentID string) ([]db.IncidentArtifact, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+incidentArtifactColumns+`
+		FROM incident_artifacts a
+		LEFT JOIN users u ON u.id = a.created_by
+		WHERE a.incident_id = $1 AND a.expires_at > NOW()
+		ORDER BY a.created_at DESC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	artifacts := []db.IncidentArtifact{}
+	for rows.Next() {
+		a, err := scanIncidentArtifact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artifact: %w", err)
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+// GetIncidentArtifact returns one unexpired artifact with its content
+func (s *IncidentService) GetIncidentArtifact(incidentID, artifactID string) (*db.IncidentArtifact, error) {
+	var content string
+	a, err := scanIncidentArtifact(s.PG.QueryRow(`
+		SELECT `+incidentArtifactColumns+`, a.content
+		FROM incident_artifacts a
+		LEFT JOIN users u ON u.id = a.created_by
+		WHERE a.id = $1 AND a.incident_id = $2 AND a.expires_at > NOW()
+	`, artifactID, incidentID), &content)
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentArtifactNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact: %w", err)
+	}
+	a.Content = content
+	return &a, nil
+}
+
+// DeleteIncidentArtifact removes an artifact before it expires
+func (s *IncidentService) DeleteIncidentArtifact(incidentID, artifactID string) error {
+	result, err := s.PG.Exec(`DELETE FROM incident_artifacts WHERE id = $1 AND incident_id = $2`, artifactID, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete artifact: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrIncidentArtifactNotFound
+	}
+	return nil
+}
+
+// SearchIncidentArtifacts finds unexpired artifacts whose title, command or
+// content match q, best match first, on incidents the user can see.
+//
+// Filters: current_user_id and current_org_id (required), q (required),
+// incident_id, kind and limit.
+func (s *IncidentService) SearchIncidentArtifacts(filters map[string]interface{}) ([]db.IncidentArtifactSearchResult, error) {
+	results := []db.IncidentArtifactSearchResult{}
+
+	q, _ := filters["q"].(string)
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	currentUserID, _ := filters["current_user_id"].(string)
+	currentOrgID, _ := filters["current_org_id"].(string)
+	if currentUserID == "" || currentOrgID == "" {
+		return results, nil
+	}
+
+	limit := defaultArtifactSearchLimit
+	if l, ok := filters["limit"].(int); ok && l > 0 {
+		limit = l
+	}
+	if limit > maxArtifactSearchLimit {
+		limit = maxArtifactSearchLimit
+	}
+
+	// $3 is the search query
+	query := `
+		SELECT ` + incidentArtifactColumns + `, i.title,
+		       ts_headline('simple', a.content, websearch_to_tsquery('simple', $3),
+		           'StartSel=**, StopSel=**, MaxWords=35, MinWords=10, MaxFragments=2'),
+		       ts_rank(a.search_vector, websearch_to_tsquery('simple', $3))::float8 AS rank
+		FROM incident_artifacts a
+		JOIN incidents i ON i.id = a.incident_id
+		LEFT JOIN users u ON u.id = a.created_by
+		WHERE a.expires_at > NOW()
+		AND a.search_vector @@ websearch_to_tsquery('simple', $3)
+		AND
+` + incidentAccessScopeSQL
+
+	args := []interface{}{currentUserID, currentOrgID, q}
+	argIndex := 4
+
+	for _, column := range []struct{ filter, expr string }{
+		{"incident_id", "a.incident_id"},
+		{"kind", "a.kind"},
+	} {
+		if value, ok := filters[column.filter].(string); ok && value != "" {
+			query += fmt.Sprintf(" AND %s = $%d", column.expr, argIndex)
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	query += fmt.Sprintf(" ORDER BY rank DESC, a.created_at DESC LIMIT $%d", argIndex)
+	args = append(args, limit)
+
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r db.IncidentArtifactSearchResult
+		a, err := scanIncidentArtifact(rows, &r.IncidentTitle, &r.Snippet, &r.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artifact search result: %w", err)
+		}
+		r.IncidentArtifact = a
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// PurgeExpiredIncidentArtifacts deletes artifacts past their retention
+func (s *IncidentService) PurgeExpiredIncidentArtifacts() (int64, error) {
+	result, err := s.PG.Exec(`DELETE FROM incident_artifacts WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge incident artifacts: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrIncidentAlreadyClaimed   = errors.New("incident already claimed")
+	ErrIncidentAssigneeChanged  = errors.New("incident assignment changed")
+	ErrIncidentNotClaimable     = errors.New("resolved incidents can't be claimed")
+	ErrIncidentNotClaimedByUser = errors.New("incident is not claimed by this user")
+	ErrNotIncidentGroupMember   = errors.New("only members of the incident's group can claim it")
+)
+
+// GetIncidentClaim returns who has claimed an incident and who it is assigned to
+func (s *IncidentService) GetIncidentClaim(incidentID string) (*db.IncidentClaim, error) {
+	claim := &db.IncidentClaim{IncidentID: incidentID}
+	var claimedAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT i.status, COALESCE(i.claimed_by::text, ''), COALESCE(cu.name, cu.email, ''), i.claimed_at,
+		       COALESCE(i.assigned_to::text, ''), COALESCE(au.name, au.email, '')
+		FROM incidents i
+		LEFT JOIN users cu ON cu.id = i.claimed_by
+		LEFT JOIN users au ON au.id = i.assigned_to
+		WHERE i.id = $1
+	`, incidentID).Scan(&claim.Status, &claim.ClaimedBy, &claim.ClaimedByName, &claimedAt,
+		&claim.AssignedTo, &claim.AssignedToName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident claim: %w", err)
+	}
+	claim.ClaimedAt = nullTimePtr(claimedAt)
+	return claim, nil
+}
+
+// ClaimIncident makes userID the incident's assignee and claimer in one
+// compare-and-swap: it only succeeds while the incident is unresolved and
+// unclaimed (or already claimed by userID) and, when the request says so,
+// still assigned as the caller expects. Otherwise the incident's current
+// claim is returned with ErrIncidentAlreadyClaimed, ErrIncidentAssigneeChanged
+// or ErrIncidentNotClaimable so the caller can see who beat them to it.
+// Only members of the incident's group may claim it.
+func (s *IncidentService) ClaimIncident(incidentID, userID string, req db.ClaimIncidentRequest) (*db.IncidentClaim, error) {
+	var groupID string
+	err := s.PG.QueryRow(`SELECT COALESCE(group_id::text, '') FROM incidents WHERE id = $1`, incidentID).Scan(&groupID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	if groupID != "" {
+		var member bool
+		if err := s.PG.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM memberships WHERE resource_type = 'group' AND resource_id = $1 AND user_id = $2)
+		`, groupID, userID).Scan(&member); err != nil {
+			return nil, fmt.Errorf("failed to check group membership: %w", err)
+		}
+		if !member {
+			return nil, ErrNotIncidentGroupMember
+		}
+	}
+
+	var expected interface{}
+	if req.ExpectedAssignedTo != nil {
+		expected = *req.ExpectedAssignedTo
+	}
+	var previousAssignee string
+	var alreadyClaimed bool
+	err = s.PG.QueryRow(`
+		UPDATE incidents i
+		SET assigned_to = $2::uuid,
+		    assigned_at = CASE WHEN i.assigned_to IS DISTINCT FROM $2::uuid THEN NOW() ELSE i.assigned_at END,
+		    claimed_by = $2::uuid,
+		    claimed_at = CASE WHEN i.claimed_by = $2::uuid THEN i.claimed_at ELSE NOW() END,
+		    updated_at = NOW()
+		FROM (SELECT id, assigned_to, claimed_by FROM incidents WHERE id = $1 FOR UPDATE) prev
+		WHERE i.id = prev.id
+		  AND i.status != 'resolved'
+		  AND (i.claimed_by IS NULL OR i.claimed_by = $2::uuid)
+		  AND ($3::text IS NULL OR COALESCE(i.assigned_to::text, '') = $3::text)
+		RETURNING COALESCE(prev.assigned_to::text, ''), prev.claimed_by IS NOT NULL
+	`, incidentID, userID, expected).Scan(&previousAssignee, &alreadyClaimed)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to claim incident: %w", err)
+	}
+
+	if err == sql.ErrNoRows {
+		current, err := s.GetIncidentClaim(incidentID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case current.Status == db.IncidentStatusResolved:
+			return current, ErrIncidentNotClaimable
+		case current.ClaimedBy != "" && current.ClaimedBy != userID:
+			return current, ErrIncidentAlreadyClaimed
+		default:
+			return current, ErrIncidentAssigneeChanged
+		}
+	}
+
+	claim, err := s.GetIncidentClaim(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if alreadyClaimed {
+		return claim, nil
+	}
+
+	eventData := map[string]interface{}{
+		"claimed_by":    claim.ClaimedByName,
+		"claimed_by_id": userID,
+	}
+	if previousAssignee != "" && previousAssignee != userID {
+		eventData["previous_assignee_id"] = previousAssignee
+	}
+	if req.Note != "" {
+		eventData["note"] = req.Note
+	}
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventClaimed, eventData, userID); err != nil {
+		log.Printf("⚠️  Failed to record claim of incident %s: %v", incidentID, err)
+	}
+
+	if s.NotificationWorker != nil && previousAssignee != "" && previousAssignee != userID {
+		message := claim.ClaimedByName + " claimed this incident"
+		go func() {
+			if err := s.NotificationWorker.SendIncidentClaimedNotification(previousAssignee, incidentID, message); err != nil {
+				log.Printf("⚠️  Failed to tell %s incident %s was claimed: %v", previousAssignee, incidentID, err)
+			}
+		}()
+	}
+	return claim, nil
+}
+
+// UnclaimIncident releases userID's claim so someone else can claim the
+// incident. The assignment is left as it is.
+func (s *IncidentService) UnclaimIncident(incidentID, userID, note string) (*db.IncidentClaim, error) {
+	result, err := s.PG.Exec(`
+		UPDATE incidents SET claimed_by = NULL, claimed_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND claimed_by = $2::uuid
+	`, incidentID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unclaim incident: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		current, err := s.GetIncidentClaim(incidentID)
+		if err != nil {
+			return nil, err
+		}
+		return current, ErrIncidentNotClaimedByUser
+	}
+
+	eventData := map[string]interface{}{}
+	if note != "" {
+		eventData["note"] = note
+	}
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventUnclaimed, eventData, userID); err != nil {
+		log.Printf("⚠️  Failed to record unclaim of incident %s: %v", incidentID, err)
+	}
+	return s.GetIncidentClaim(incidentID)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var incidentClaimColumns = []string{"status", "claimed_by", "claimed_by_name", "claimed_at", "assigned_to", "assigned_to_name"}
+
+func TestClaimIncidentConflictReturnsHolder(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT COALESCE\(group_id::text, ''\) FROM incidents`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow("grp-1"))
+	mock.ExpectQuery(`FROM memberships`).WithArgs("grp-1", "bob").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`UPDATE incidents i`).WithArgs("inc-1", "bob", nil).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`LEFT JOIN users cu`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(incidentClaimColumns).
+			AddRow(db.IncidentStatusTriggered, "alice", "Alice", time.Now(), "alice", "Alice"))
+
+	claim, err := NewIncidentService(pg, nil).ClaimIncident("inc-1", "bob", db.ClaimIncidentRequest{})
+	if !errors.Is(err, ErrIncidentAlreadyClaimed) {
+		t.Fatalf("expected ErrIncidentAlreadyClaimed, got %v", err)
+	}
+	if claim == nil || claim.ClaimedByName != "Alice" {
+		t.Errorf("conflict should say who holds the claim, got %+v", claim)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClaimIncidentTakesAssignment(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	expected := "alice"
+	mock.ExpectQuery(`SELECT COALESCE\(group_id::text, ''\) FROM incidents`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow(""))
+	mock.ExpectQuery(`UPDATE incidents i`).WithArgs("inc-1", "bob", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"previous", "already"}).AddRow("alice", false))
+	mock.ExpectQuery(`LEFT JOIN users cu`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(incidentClaimColumns).
+			AddRow(db.IncidentStatusTriggered, "bob", "Bob", time.Now(), "bob", "Bob"))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventClaimed, sqlmock.AnyArg(), "bob").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	claim, err := NewIncidentService(pg, nil).ClaimIncident("inc-1", "bob", db.ClaimIncidentRequest{ExpectedAssignedTo: &expected})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.ClaimedBy != "bob" || claim.AssignedTo != "bob" {
+		t.Errorf("unexpected claim %+v", claim)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClaimIncidentRequiresGroupMembership(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT COALESCE\(group_id::text, ''\) FROM incidents`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow("grp-1"))
+	mock.ExpectQuery(`FROM memberships`).WithArgs("grp-1", "mallory").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if _, err := NewIncidentService(pg, nil).ClaimIncident("inc-1", "mallory", db.ClaimIncidentRequest{}); !errors.Is(err, ErrNotIncidentGroupMember) {
+		t.Fatalf("expected ErrNotIncidentGroupMember, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+// incidentRespondersSQL lists each human responder of an incident with the time
+// they got involved: their first timeline event, or the assignment for the
+// assignee. System users (integrations, API) are excluded.
+func incidentRespondersSQL(incidentRef, systemUsersRef string) string {
+	return `
+		SELECT user_id, MIN(joined_at) AS joined_at
+		FROM (
+			SELECT e.created_by AS user_id, e.created_at AS joined_at
+			FROM incident_events e
+			WHERE e.incident_id = ` + incidentRef + ` AND e.created_by IS NOT NULL
+			  AND NOT (e.created_by::text = ANY(` + systemUsersRef + `))
+			UNION ALL
+			SELECT ai.assigned_to, COALESCE(ai.assigned_at, ai.created_at)
+			FROM incidents ai
+			WHERE ai.id = ` + incidentRef + ` AND ai.assigned_to IS NOT NULL
+			  AND NOT (ai.assigned_to::text = ANY(` + systemUsersRef + `))
+		) involvement
+		GROUP BY user_id`
+}
+
+// GetIncidentCost estimates an incident's cost from its service's cost
+// parameters. It returns nil when the service has none configured.
+func (s *IncidentService) GetIncidentCost(incident *db.Incident) (*db.IncidentCostEstimate, error) {
+	if incident.ServiceID == "" {
+		return nil, nil
+	}
+
+	var revenuePerMinute, engineerHourlyCost sql.NullFloat64
+	err := s.PG.QueryRow(`
+		SELECT revenue_per_minute, engineer_hourly_cost FROM services WHERE id = $1
+	`, incident.ServiceID).Scan(&revenuePerMinute, &engineerHourlyCost)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service cost parameters: %w", err)
+	}
+	if !revenuePerMinute.Valid && !engineerHourlyCost.Valid {
+		return nil, nil
+	}
+
+	endedAt := time.Now()
+	if incident.ResolvedAt != nil {
+		endedAt = *incident.ResolvedAt
+	}
+
+	var responders int
+	var responderMinutes float64
+	err = s.PG.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(GREATEST(EXTRACT(EPOCH FROM ($3::timestamptz - r.joined_at)) / 60, 0)), 0)
+		FROM (`+incidentRespondersSQL("$1", "$2")+`) r
+	`, incident.ID, pq.Array(db.SystemUserIDs), endedAt).Scan(&responders, &responderMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident responder time: %w", err)
+	}
+
+	durationMinutes := endedAt.Sub(incident.CreatedAt).Minutes()
+	if durationMinutes < 0 {
+		durationMinutes = 0
+	}
+
+	estimate := db.NewIncidentCostEstimate(durationMinutes, revenuePerMinute.Float64,
+		responders, responderMinutes, engineerHourlyCost.Float64)
+	estimate.Ongoing = incident.ResolvedAt == nil
+	return &estimate, nil
+}
+
+// GetIncidentCostSummary totals the estimated cost of the organization's
+// incidents created since the given time, over services with cost parameters
+func (s *IncidentService) GetIncidentCostSummary(orgID string, since time.Time) (db.IncidentCostSummary, error) {
+	var incidents int
+	var impactCost, responderCost float64
+	err := s.PG.QueryRow(`
+		WITH costed AS (
+			SELECT i.id, i.created_at, COALESCE(i.resolved_at, NOW()) AS ended_at,
+			       COALESCE(sv.revenue_per_minute, 0) AS revenue_per_minute,
+			       COALESCE(sv.engineer_hourly_cost, 0) AS engineer_hourly_cost
+			FROM incidents i
+			JOIN services sv ON sv.id = i.service_id
+			WHERE i.organization_id = $3 AND i.created_at >= $2 AND NOT i.is_test AND i.drill_id IS NULL
+			  AND (sv.revenue_per_minute IS NOT NULL OR sv.engineer_hourly_cost IS NOT NULL)
+		)
+		SELECT COUNT(*),
+		       COALESCE(SUM(EXTRACT(EPOCH FROM (c.ended_at - c.created_at)) / 60 * c.revenue_per_minute), 0),
+		       COALESCE(SUM(rt.minutes / 60 * c.engineer_hourly_cost), 0)
+		FROM costed c
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(GREATEST(EXTRACT(EPOCH FROM (c.ended_at - r.joined_at)) / 60, 0)), 0) AS minutes
+			FROM (`+incidentRespondersSQL("c.id", "$1")+`) r
+		) rt
+	`, pq.Array(db.SystemUserIDs), since, orgID).Scan(&incidents, &impactCost, &responderCost)
+	if err != nil {
+		return db.IncidentCostSummary{}, fmt.Errorf("failed to get incident cost summary: %w", err)
+	}
+	return db.NewIncidentCostSummary(incidents, impactCost, responderCost), nil
+}
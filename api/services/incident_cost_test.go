@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestGetIncidentCost(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	resolvedAt := createdAt.Add(90 * time.Minute)
+	incident := &db.Incident{ID: "inc-1", ServiceID: "svc-1", CreatedAt: createdAt, ResolvedAt: &resolvedAt}
+
+	mock.ExpectQuery(`SELECT revenue_per_minute, engineer_hourly_cost FROM services`).WithArgs("svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"revenue_per_minute", "engineer_hourly_cost"}).AddRow(12.5, 150.0))
+	mock.ExpectQuery(`FROM incident_events e`).WithArgs("inc-1", sqlmock.AnyArg(), resolvedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "minutes"}).AddRow(2, 140.0))
+
+	cost, err := NewIncidentService(pg, nil).GetIncidentCost(incident)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := db.IncidentCostEstimate{
+		DurationMinutes: 90, RevenuePerMinute: 12.5, ImpactCost: 1125,
+		Responders: 2, ResponderMinutes: 140, EngineerHourlyCost: 150, ResponderCost: 350,
+		TotalCost: 1475,
+	}
+	if cost == nil || *cost != want {
+		t.Errorf("cost = %+v, want %+v", cost, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetIncidentCostWithoutParameters(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT revenue_per_minute, engineer_hourly_cost FROM services`).WithArgs("svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"revenue_per_minute", "engineer_hourly_cost"}).AddRow(nil, nil))
+
+	svc := NewIncidentService(pg, nil)
+	cost, err := svc.GetIncidentCost(&db.Incident{ID: "inc-1", ServiceID: "svc-1", CreatedAt: time.Now()})
+	if err != nil || cost != nil {
+		t.Errorf("expected no estimate, got %+v, %v", cost, err)
+	}
+
+	// Incidents without a service are never costed
+	if cost, err := svc.GetIncidentCost(&db.Incident{ID: "inc-2"}); err != nil || cost != nil {
+		t.Errorf("expected no estimate, got %+v, %v", cost, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetIncidentStatsSkipsFailedCostSummary(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`COUNT\(\*\) as total`).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "triggered", "acknowledged", "resolved", "high_urgency"}).
+			AddRow(3, 1, 1, 1, 2))
+	mock.ExpectQuery(`SELECT workflow_state, COUNT`).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_state", "count"}))
+	mock.ExpectQuery(`WITH costed AS`).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "org-1").
+		WillReturnError(errors.New("statement timeout"))
+
+	stats, err := NewIncidentService(pg, nil).GetIncidentStats("org-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["total"] != 3 {
+		t.Errorf("total = %v, want 3", stats["total"])
+	}
+	if _, ok := stats["estimated_cost"]; ok {
+		t.Errorf("expected no estimated_cost after a failed summary, got %+v", stats["estimated_cost"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrDrillNotFound        = errors.New("drill not found")
+	ErrDrillServiceNotFound = errors.New("service not found")
+	ErrDrillNotCancelable   = errors.New("only scheduled drills can be cancelled")
+)
+
+// maxDrillsPerRun bounds how many due drills one worker pass starts
+const maxDrillsPerRun = 20
+
+const drillDescription = "This is an on-call readiness drill, not a real incident. " +
+	"Acknowledge and resolve it as you would a real page; no action on the service is needed."
+
+const drillColumns = `
+	d.id, d.service_id, d.title, d.severity, d.status, d.scheduled_at, d.max_duration_minutes,
+	COALESCE(d.incident_id::text, ''), d.started_at, d.completed_at, COALESCE(d.error, ''),
+	COALESCE(d.created_by::text, ''), d.created_at
+`
+
+// drillInOrg limits a drill looked up by id and service to services of the
+// organization in $3
+const drillInOrg = `EXISTS (SELECT 1 FROM services sv WHERE sv.id = d.service_id AND sv.organization_id = $3)`
+
+func scanDrill(row interface{ Scan(...interface{}) error }) (db.IncidentDrill, error) {
+	var d db.IncidentDrill
+	var startedAt, completedAt sql.NullTime
+	if err := row.Scan(&d.ID, &d.ServiceID, &d.Title, &d.Severity, &d.Status, &d.ScheduledAt,
+		&d.MaxDurationMinutes, &d.IncidentID, &startedAt, &completedAt, &d.Error, &d.CreatedBy, &d.CreatedAt); err != nil {
+		return d, err
+	}
+	if startedAt.Valid {
+		d.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		d.CompletedAt = &completedAt.Time
+	}
+	return d, nil
+}
+
+// CreateDrill schedules a drill for one of the organization's services.
+// Drills without scheduled_at, or scheduled in the past, start right away.
+func (s *IncidentService) CreateDrill(orgID, serviceID string, req db.CreateIncidentDrillRequest, createdBy string) (db.IncidentDrill, error) {
+	var serviceName string
+	err := s.PG.QueryRow(`SELECT name FROM services WHERE id = $1 AND organization_id = $2`, serviceID, orgID).Scan(&serviceName)
+	if err == sql.ErrNoRows {
+		return db.IncidentDrill{}, ErrDrillServiceNotFound
+	}
+	if err != nil {
+		return db.IncidentDrill{}, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = serviceName + " is not responding"
+	}
+	severity := req.Severity
+	if severity == "" {
+		severity = db.DrillDefaultSeverity
+	}
+	maxDuration := req.MaxDurationMinutes
+	if maxDuration == 0 {
+		maxDuration = db.DrillDefaultMaxDuration
+	}
+	scheduledAt := time.Now()
+	if req.ScheduledAt != nil {
+		scheduledAt = *req.ScheduledAt
+	}
+
+	drill, err := scanDrill(s.PG.QueryRow(`
+		INSERT INTO incident_drills AS d (service_id, title, severity, scheduled_at, max_duration_minutes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+drillColumns,
+		serviceID, title, severity, scheduledAt, maxDuration, nullIfEmpty(createdBy)))
+	if err != nil {
+		return db.IncidentDrill{}, fmt.Errorf("failed to create drill: %w", err)
+	}
+
+	if !drill.ScheduledAt.After(time.Now()) {
+		if started, err := s.claimDrills(`d.id = $1`, drill.ID); err != nil {
+			return drill, err
+		} else if len(started) == 1 {
+			drill = s.startDrill(started[0])
+		}
+	}
+	return drill, nil
+}
+
+// ListDrills returns the drills of one of the organization's services,
+// newest first
+func (s *IncidentService) ListDrills(orgID, serviceID string) ([]db.IncidentDrill, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+drillColumns+`
+		FROM incident_drills d
+		WHERE d.service_id = $1
+		AND EXISTS (SELECT 1 FROM services sv WHERE sv.id = d.service_id AND sv.organization_id = $2)
+		ORDER BY d.scheduled_at DESC
+		LIMIT 100
+	`, serviceID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list drills: %w", err)
+	}
+	defer rows.Close()
+
+	drills := []db.IncidentDrill{}
+	for rows.Next() {
+		drill, err := scanDrill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan drill: %w", err)
+		}
+		drills = append(drills, drill)
+	}
+	return drills, rows.Err()
+}
+
+// GetDrill returns one of the drills of an organization's service
+func (s *IncidentService) GetDrill(orgID, serviceID, drillID string) (db.IncidentDrill, error) {
+	drill, err := scanDrill(s.PG.QueryRow(`
+		SELECT `+drillColumns+` FROM incident_drills d WHERE d.id = $1 AND d.service_id = $2 AND `+drillInOrg+`
+	`, drillID, serviceID, orgID))
+	if err == sql.ErrNoRows {
+		return drill, ErrDrillNotFound
+	}
+	if err != nil {
+		return drill, fmt.Errorf("failed to get drill: %w", err)
+	}
+	return drill, nil
+}
+
+// CancelDrill cancels a drill that hasn't started yet
+func (s *IncidentService) CancelDrill(orgID, serviceID, drillID string) (db.IncidentDrill, error) {
+	drill, err := scanDrill(s.PG.QueryRow(`
+		UPDATE incident_drills d SET status = $4, completed_at = NOW()
+		WHERE d.id = $1 AND d.service_id = $2 AND `+drillInOrg+` AND d.status = $5
+		RETURNING `+drillColumns,
+		drillID, serviceID, orgID, db.DrillStatusCancelled, db.DrillStatusScheduled))
+	if err == sql.ErrNoRows {
+		if _, getErr := s.GetDrill(orgID, serviceID, drillID); getErr != nil {
+			return drill, getErr
+		}
+		return drill, ErrDrillNotCancelable
+	}
+	if err != nil {
+		return drill, fmt.Errorf("failed to cancel drill: %w", err)
+	}
+	return drill, nil
+}
+
+// RunDrills starts drills that are due and ends drills whose incident was
+// resolved or that ran past their max duration. Called by the worker.
+func (s *IncidentService) RunDrills() (started, ended int, err error) {
+	due, err := s.claimDrills(`d.scheduled_at <= NOW()`, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, drill := range due {
+		if s.startDrill(drill).Status == db.DrillStatusRunning {
+			started++
+		}
+	}
+
+	ended, err = s.endDrills()
+	return started, ended, err
+}
+
+// claimDrills marks scheduled drills matching where as running and returns
+// them, so concurrent workers never start the same drill twice
+func (s *IncidentService) claimDrills(where string, arg interface{}) ([]db.IncidentDrill, error) {
+	args := []interface{}{}
+	if arg != nil {
+		args = append(args, arg)
+	}
+	rows, err := s.PG.Query(`
+		UPDATE incident_drills d SET status = '`+db.DrillStatusRunning+`', started_at = NOW()
+		WHERE d.id IN (
+			SELECT d.id FROM incident_drills d
+			WHERE `+where+` AND d.status = '`+db.DrillStatusScheduled+`'
+			ORDER BY d.scheduled_at
+			LIMIT `+fmt.Sprint(maxDrillsPerRun)+`
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+drillColumns, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim drills: %w", err)
+	}
+	defer rows.Close()
+
+	var drills []db.IncidentDrill
+	for rows.Next() {
+		drill, err := scanDrill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan drill: %w", err)
+		}
+		drills = append(drills, drill)
+	}
+	return drills, rows.Err()
+}
+
+// startDrill triggers the drill's incident through the same path as an
+// alert for the service: its escalation policy picks the assignee and the
+// worker escalates and notifies as usual
+func (s *IncidentService) startDrill(drill db.IncidentDrill) db.IncidentDrill {
+	incident, err := s.createDrillIncident(drill)
+	if err != nil {
+		log.Printf("⚠️  Drill %s failed to start: %v", drill.ID, err)
+		drill.Status = db.DrillStatusFailed
+		drill.Error = err.Error()
+		if _, err := s.PG.Exec(`
+			UPDATE incident_drills SET status = $2, error = $3, completed_at = NOW() WHERE id = $1
+		`, drill.ID, db.DrillStatusFailed, drill.Error); err != nil {
+			log.Printf("⚠️  Failed to mark drill %s failed: %v", drill.ID, err)
+		}
+		return drill
+	}
+
+	drill.IncidentID = incident.ID
+	if _, err := s.PG.Exec(`UPDATE incident_drills SET incident_id = $2 WHERE id = $1`, drill.ID, incident.ID); err != nil {
+		log.Printf("⚠️  Failed to link drill %s to incident %s: %v", drill.ID, incident.ID, err)
+	}
+	log.Printf("🎯 Drill %s started: incident %s", drill.ID, incident.ID)
+	return drill
+}
+
+func (s *IncidentService) createDrillIncident(drill db.IncidentDrill) (*db.Incident, error) {
+	var service db.Service
+	err := s.PG.QueryRow(`
+		SELECT id, COALESCE(group_id::text, ''), COALESCE(escalation_policy_id::text, ''),
+		       COALESCE(organization_id::text, ''), COALESCE(project_id::text, '')
+		FROM services WHERE id = $1
+	`, drill.ServiceID).Scan(&service.ID, &service.GroupID, &service.EscalationPolicyID,
+		&service.OrganizationID, &service.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	incident := &db.Incident{
+		Title:              db.DrillTitlePrefix + drill.Title,
+		Description:        drillDescription,
+		Severity:           drill.Severity,
+		Status:             db.IncidentStatusTriggered,
+		Source:             db.DrillSource,
+		Urgency:            db.IncidentUrgencyHigh,
+		ServiceID:          service.ID,
+		GroupID:            service.GroupID,
+		EscalationPolicyID: service.EscalationPolicyID,
+		OrganizationID:     service.OrganizationID,
+		ProjectID:          service.ProjectID,
+		Labels: map[string]interface{}{
+			db.DrillLabel:   "true",
+			db.DrillIDLabel: drill.ID,
+		},
+	}
+	if drill.Severity == "info" || drill.Severity == "warning" {
+		incident.Urgency = db.IncidentUrgencyLow
+	}
+	if service.EscalationPolicyID != "" && service.GroupID != "" {
+		if assigneeID, err := s.GetAssigneeFromEscalationPolicy(service.EscalationPolicyID, service.GroupID); err == nil && assigneeID != "" {
+			now := time.Now().UTC()
+			incident.AssignedTo = assigneeID
+			incident.AssignedAt = &now
+		}
+	}
+
+	created, err := s.CreateIncident(incident)
+	if err != nil {
+		return nil, err
+	}
+	// The drill_id column keeps the incident out of SLO, cost and responder stats
+	if _, err := s.PG.Exec(`UPDATE incidents SET drill_id = $2 WHERE id = $1`, created.ID, drill.ID); err != nil {
+		log.Printf("⚠️  Failed to tag drill incident %s: %v", created.ID, err)
+	}
+	return created, nil
+}
+
+// endDrills completes running drills whose incident is resolved, resolving
+// the incident first when the drill ran past its max duration
+func (s *IncidentService) endDrills() (int, error) {
+	rows, err := s.PG.Query(`
+		SELECT d.id, COALESCE(d.incident_id::text, ''), COALESCE(i.status, ''),
+		       d.started_at + make_interval(mins => d.max_duration_minutes) <= NOW()
+		FROM incident_drills d
+		LEFT JOIN incidents i ON i.id = d.incident_id
+		WHERE d.status = $1
+	`, db.DrillStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get running drills: %w", err)
+	}
+	type runningDrill struct {
+		id, incidentID, status string
+		expired                bool
+	}
+	var running []runningDrill
+	for rows.Next() {
+		var d runningDrill
+		if err := rows.Scan(&d.id, &d.incidentID, &d.status, &d.expired); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan running drill: %w", err)
+		}
+		running = append(running, d)
+	}
+	rows.Close()
+
+	ended := 0
+	for _, d := range running {
+		switch {
+		case d.incidentID == "" || d.status == db.IncidentStatusResolved:
+		case d.expired:
+			if err := s.ResolveIncident(d.incidentID, db.SystemUserAPI, "Drill ended: max duration reached", "drill_timeout"); err != nil {
+				log.Printf("⚠️  Failed to resolve drill incident %s: %v", d.incidentID, err)
+				continue
+			}
+		default:
+			continue
+		}
+		if _, err := s.PG.Exec(`
+			UPDATE incident_drills SET status = $2, completed_at = NOW() WHERE id = $1 AND status = $3
+		`, d.id, db.DrillStatusCompleted, db.DrillStatusRunning); err != nil {
+			log.Printf("⚠️  Failed to complete drill %s: %v", d.id, err)
+			continue
+		}
+		ended++
+	}
+	return ended, nil
+}
+
+// GetDrillReport reports the drill's timeline and each responder's reaction
+func (s *IncidentService) GetDrillReport(orgID, serviceID, drillID string) (*db.DrillReport, error) {
+	drill, err := s.GetDrill(orgID, serviceID, drillID)
+	if err != nil {
+		return nil, err
+	}
+	report := &db.DrillReport{Drill: drill, Responders: []db.DrillResponder{}}
+
+	switch drill.Status {
+	case db.DrillStatusScheduled:
+		report.Outcome = db.DrillOutcomePending
+		return report, nil
+	case db.DrillStatusCancelled:
+		report.Outcome = db.DrillOutcomeCancelled
+		return report, nil
+	case db.DrillStatusFailed:
+		report.Outcome = db.DrillOutcomeFailed
+		return report, nil
+	}
+	if drill.IncidentID == "" {
+		report.Outcome = db.DrillOutcomeInProgress
+		return report, nil
+	}
+
+	var triggeredAt time.Time
+	var acknowledgedAt, resolvedAt sql.NullTime
+	var acknowledgedBy string
+	err = s.PG.QueryRow(`
+		SELECT i.created_at, i.acknowledged_at, COALESCE(i.acknowledged_by::text, ''), i.resolved_at,
+		       COALESCE(i.current_escalation_level, 1),
+		       (SELECT COUNT(*) FROM incident_events e WHERE e.incident_id = i.id AND e.event_type = $2)
+		FROM incidents i WHERE i.id = $1
+	`, drill.IncidentID, db.IncidentEventEscalated).Scan(&triggeredAt, &acknowledgedAt, &acknowledgedBy, &resolvedAt,
+		&report.EscalationLevelReached, &report.Escalations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drill incident: %w", err)
+	}
+	report.TriggeredAt = &triggeredAt
+	report.AcknowledgedBy = acknowledgedBy
+	if acknowledgedAt.Valid {
+		report.AcknowledgedAt = &acknowledgedAt.Time
+		report.SecondsToAcknowledge = secondsBetween(triggeredAt, acknowledgedAt.Time)
+	}
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+		report.SecondsToResolve = secondsBetween(triggeredAt, resolvedAt.Time)
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT DISTINCT ON (nr.user_id)
+		       nr.user_id, COALESCE(u.name, u.email, ''), nr.notification_type,
+		       nr.sent_at, nr.delivered_at, nr.seen_at
+		FROM notification_receipts nr
+		LEFT JOIN users u ON u.id = nr.user_id
+		WHERE nr.incident_id = $1
+		ORDER BY nr.user_id, nr.sent_at ASC
+	`, drill.IncidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drill pages: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r db.DrillResponder
+		var deliveredAt, seenAt sql.NullTime
+		if err := rows.Scan(&r.UserID, &r.UserName, &r.NotificationType, &r.PagedAt, &deliveredAt, &seenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan drill page: %w", err)
+		}
+		if deliveredAt.Valid {
+			r.DeliveredAt = &deliveredAt.Time
+		}
+		if seenAt.Valid {
+			r.SeenAt = &seenAt.Time
+			r.SecondsToSeen = secondsBetween(r.PagedAt, seenAt.Time)
+		}
+		r.Acknowledged = r.UserID == acknowledgedBy
+		if report.FirstPageAt == nil || r.PagedAt.Before(*report.FirstPageAt) {
+			pagedAt := r.PagedAt
+			report.FirstPageAt = &pagedAt
+		}
+		report.Responders = append(report.Responders, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if report.FirstPageAt != nil {
+		report.SecondsToFirstPage = secondsBetween(triggeredAt, *report.FirstPageAt)
+	}
+
+	switch {
+	case acknowledgedAt.Valid:
+		report.Outcome = db.DrillOutcomeAcknowledged
+	case drill.Status == db.DrillStatusCompleted:
+		report.Outcome = db.DrillOutcomeMissed
+	default:
+		report.Outcome = db.DrillOutcomeInProgress
+	}
+	return report, nil
+}
+
+func secondsBetween(from, to time.Time) *float64 {
+	seconds := to.Sub(from).Seconds()
+	if seconds < 0 {
+		seconds = 0
+	}
+	return &seconds
+}
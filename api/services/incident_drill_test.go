@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var drillRowColumns = []string{"id", "service_id", "title", "severity", "status", "scheduled_at", "max_duration_minutes",
+	"incident_id", "started_at", "completed_at", "error", "created_by", "created_at"}
+
+func TestGetDrillReport(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	start := time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM incident_drills d WHERE d.id = \$1 AND d.service_id = \$2`).WithArgs("drill-1", "svc-1", "org-1").
+		WillReturnRows(sqlmock.NewRows(drillRowColumns).AddRow("drill-1", "svc-1", "checkout is not responding", "critical",
+			db.DrillStatusCompleted, start, 60, "inc-1", start, start.Add(20*time.Minute), "", "user-9", start))
+	mock.ExpectQuery(`FROM incidents i WHERE i.id = \$1`).WithArgs("inc-1", db.IncidentEventEscalated).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "acknowledged_at", "acknowledged_by", "resolved_at", "level", "escalations"}).
+			AddRow(start, start.Add(4*time.Minute), "user-2", start.Add(20*time.Minute), 2, 1))
+	mock.ExpectQuery(`FROM notification_receipts nr`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "name", "type", "sent_at", "delivered_at", "seen_at"}).
+			AddRow("user-1", "Ana", "assigned", start.Add(5*time.Second), start.Add(6*time.Second), nil).
+			AddRow("user-2", "Bo", "escalated", start.Add(3*time.Minute), nil, start.Add(3*time.Minute+30*time.Second)))
+
+	report, err := (&IncidentService{PG: pg}).GetDrillReport("org-1", "svc-1", "drill-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Outcome != db.DrillOutcomeAcknowledged || report.AcknowledgedBy != "user-2" {
+		t.Errorf("unexpected outcome: %+v", report)
+	}
+	if *report.SecondsToFirstPage != 5 || *report.SecondsToAcknowledge != 240 || *report.SecondsToResolve != 1200 {
+		t.Errorf("unexpected timings: first page %v, ack %v, resolve %v",
+			*report.SecondsToFirstPage, *report.SecondsToAcknowledge, *report.SecondsToResolve)
+	}
+	if report.EscalationLevelReached != 2 || report.Escalations != 1 || len(report.Responders) != 2 {
+		t.Fatalf("unexpected escalation summary: %+v", report)
+	}
+	if r := report.Responders[1]; !r.Acknowledged || r.SecondsToSeen == nil || *r.SecondsToSeen != 30 {
+		t.Errorf("unexpected responder: %+v", r)
+	}
+	if report.Responders[0].SeenAt != nil || report.Responders[0].Acknowledged {
+		t.Errorf("first responder never saw the page: %+v", report.Responders[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCancelRunningDrill(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE incident_drills d SET status`).
+		WithArgs("drill-1", "svc-1", "org-1", db.DrillStatusCancelled, db.DrillStatusScheduled).
+		WillReturnRows(sqlmock.NewRows(drillRowColumns))
+	mock.ExpectQuery(`FROM incident_drills d WHERE d.id`).WithArgs("drill-1", "svc-1", "org-1").
+		WillReturnRows(sqlmock.NewRows(drillRowColumns).AddRow("drill-1", "svc-1", "t", "critical",
+			db.DrillStatusRunning, now, 60, "inc-1", now, nil, "", "", now))
+
+	_, err = (&IncidentService{PG: pg}).CancelDrill("org-1", "svc-1", "drill-1")
+	if !errors.Is(err, ErrDrillNotCancelable) {
+		t.Errorf("expected ErrDrillNotCancelable, got %v", err)
+	}
+}
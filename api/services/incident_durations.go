@@ -0,0 +1,41 @@
+package services
+
+import (
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// IncidentDurations computes an incident's elapsed times as of now. The
+// current status is taken to have started at its timestamp: resolved_at,
+// acknowledged_at, or created_at while triggered.
+func IncidentDurations(incident *db.Incident, now time.Time) *db.IncidentDurations {
+	seconds := func(from, to time.Time) int64 {
+		if d := to.Sub(from); d > 0 {
+			return int64(d / time.Second)
+		}
+		return 0 // clock skew between the API and the database
+	}
+
+	durations := &db.IncidentDurations{
+		DurationsAsOf:      now.UTC(),
+		TimeSinceTriggered: seconds(incident.CreatedAt, now),
+	}
+	statusSince := incident.CreatedAt
+	if incident.AcknowledgedAt != nil {
+		ack := seconds(incident.CreatedAt, *incident.AcknowledgedAt)
+		durations.TimeToAck = &ack
+		if incident.Status == db.IncidentStatusAcknowledged {
+			statusSince = *incident.AcknowledgedAt
+		}
+	}
+	if incident.ResolvedAt != nil {
+		resolve := seconds(incident.CreatedAt, *incident.ResolvedAt)
+		durations.TimeToResolve = &resolve
+		if incident.Status == db.IncidentStatusResolved {
+			statusSince = *incident.ResolvedAt
+		}
+	}
+	durations.TimeInCurrentStatus = seconds(statusSince, now)
+	return durations
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestIncidentDurations(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	acked := created.Add(90 * time.Second)
+	resolved := created.Add(time.Hour)
+	now := created.Add(2 * time.Hour)
+
+	open := IncidentDurations(&db.Incident{Status: db.IncidentStatusTriggered, CreatedAt: created}, now)
+	if open.TimeSinceTriggered != 7200 || open.TimeInCurrentStatus != 7200 || open.TimeToAck != nil || open.TimeToResolve != nil {
+		t.Errorf("triggered = %+v", open)
+	}
+
+	ack := IncidentDurations(&db.Incident{Status: db.IncidentStatusAcknowledged, CreatedAt: created, AcknowledgedAt: &acked}, now)
+	if ack.TimeToAck == nil || *ack.TimeToAck != 90 || ack.TimeInCurrentStatus != 7110 {
+		t.Errorf("acknowledged = %+v", ack)
+	}
+
+	done := IncidentDurations(&db.Incident{Status: db.IncidentStatusResolved, CreatedAt: created, AcknowledgedAt: &acked, ResolvedAt: &resolved}, now)
+	if done.TimeToResolve == nil || *done.TimeToResolve != 3600 || done.TimeInCurrentStatus != 3600 || done.TimeSinceTriggered != 7200 {
+		t.Errorf("resolved = %+v", done)
+	}
+
+	// Unacknowledged again: acknowledged_at is cleared, status time runs from creation
+	skewed := IncidentDurations(&db.Incident{Status: db.IncidentStatusTriggered, CreatedAt: now.Add(time.Second)}, now)
+	if skewed.TimeSinceTriggered != 0 || !skewed.DurationsAsOf.Equal(now) {
+		t.Errorf("future created_at = %+v, want zero durations", skewed)
+	}
+}
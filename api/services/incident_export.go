@@ -0,0 +1,141 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidExportCursor = errors.New("invalid export cursor")
+
+// NewIncidentExportCursor starts an export of incidents updated since the
+// given time, up to now
+func NewIncidentExportCursor(since time.Time) db.IncidentExportCursor {
+	return db.IncidentExportCursor{Since: since.UTC(), SnapshotAt: time.Now().UTC()}
+}
+
+// EncodeIncidentExportCursor turns a cursor into an opaque URL-safe token
+func EncodeIncidentExportCursor(cursor db.IncidentExportCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeIncidentExportCursor parses a token from EncodeIncidentExportCursor
+func DecodeIncidentExportCursor(token string) (db.IncidentExportCursor, error) {
+	var cursor db.IncidentExportCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, ErrInvalidExportCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.SnapshotAt.IsZero() {
+		return db.IncidentExportCursor{}, ErrInvalidExportCursor
+	}
+	return cursor, nil
+}
+
+// ExportIncidents streams up to limit of an organization's incidents after
+// the cursor, in (updated_at, id) order, to emit. Only incidents updated
+// between the cursor's Since and SnapshotAt are included. The returned
+// checkpoint points after the last incident emitted, even when emit or the
+// query fails part way, so the caller can resume from it.
+func (s *IncidentService) ExportIncidents(orgID string, cursor db.IncidentExportCursor, limit int, emit func(*db.IncidentExportRecord) error) (db.IncidentExportCheckpoint, error) {
+	checkpoint := db.IncidentExportCheckpoint{SnapshotAt: cursor.SnapshotAt, NextSince: cursor.SnapshotAt}
+	finish := func(err error) (db.IncidentExportCheckpoint, error) {
+		checkpoint.Cursor = EncodeIncidentExportCursor(cursor)
+		return checkpoint, err
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT i.id, COALESCE(i.organization_id::text, ''), i.project_id::text, i.title, COALESCE(i.description, ''),
+		       i.status, i.urgency, i.priority, i.severity, i.source, i.integration_id, i.service_id::text,
+		       i.group_id::text, i.escalation_policy_id::text, COALESCE(i.current_escalation_level, 0),
+		       COALESCE(i.escalation_status, 'none'), i.assigned_to::text, i.assigned_at,
+		       i.acknowledged_by::text, i.acknowledged_at, i.resolved_by::text, i.resolved_at,
+		       i.incident_key, COALESCE(i.alert_count, 1), COALESCE(i.is_test, false), i.drill_id::text,
+		       COALESCE(i.labels, '{}'::jsonb), COALESCE(i.custom_fields, '{}'::jsonb),
+		       i.created_at, i.updated_at
+		FROM incidents i
+		WHERE i.organization_id = $1
+		  AND i.updated_at >= $2 AND i.updated_at < $3
+		  AND (i.updated_at, i.id) > ($4, $5::uuid)
+		ORDER BY i.updated_at, i.id
+		LIMIT $6
+	`, orgID, cursor.Since, cursor.SnapshotAt, cursor.AfterUpdatedAt, exportAfterID(cursor.AfterID), limit+1)
+	if err != nil {
+		return finish(fmt.Errorf("failed to export incidents: %w", err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if checkpoint.Exported == limit {
+			checkpoint.HasMore = true
+			break
+		}
+		record, err := scanIncidentExportRecord(rows)
+		if err != nil {
+			return finish(fmt.Errorf("failed to scan incident for export: %w", err))
+		}
+		if err := emit(record); err != nil {
+			return finish(err)
+		}
+		cursor.AfterUpdatedAt, cursor.AfterID = record.UpdatedAt, record.ID
+		checkpoint.Exported++
+	}
+	if err := rows.Err(); err != nil {
+		return finish(fmt.Errorf("failed to export incidents: %w", err))
+	}
+	return finish(nil)
+}
+
+// exportAfterID is the id to resume after; the nil UUID sorts first
+func exportAfterID(id string) string {
+	if id == "" {
+		return "00000000-0000-0000-0000-000000000000"
+	}
+	return id
+}
+
+func scanIncidentExportRecord(row interface{ Scan(...interface{}) error }) (*db.IncidentExportRecord, error) {
+	var r db.IncidentExportRecord
+	var projectID, priority, severity, integrationID, serviceID, groupID, policyID sql.NullString
+	var assignedTo, acknowledgedBy, resolvedBy, incidentKey, drillID sql.NullString
+	var assignedAt, acknowledgedAt, resolvedAt sql.NullTime
+	var labels, customFields []byte
+	if err := row.Scan(&r.ID, &r.OrganizationID, &projectID, &r.Title, &r.Description,
+		&r.Status, &r.Urgency, &priority, &severity, &r.Source, &integrationID, &serviceID,
+		&groupID, &policyID, &r.CurrentEscalationLevel,
+		&r.EscalationStatus, &assignedTo, &assignedAt,
+		&acknowledgedBy, &acknowledgedAt, &resolvedBy, &resolvedAt,
+		&incidentKey, &r.AlertCount, &r.IsTest, &drillID,
+		&labels, &customFields,
+		&r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	r.ProjectID, r.Priority, r.Severity = nullStringPtr(projectID), nullStringPtr(priority), nullStringPtr(severity)
+	r.IntegrationID, r.ServiceID, r.GroupID = nullStringPtr(integrationID), nullStringPtr(serviceID), nullStringPtr(groupID)
+	r.EscalationPolicyID, r.IncidentKey, r.DrillID = nullStringPtr(policyID), nullStringPtr(incidentKey), nullStringPtr(drillID)
+	r.AssignedTo, r.AcknowledgedBy, r.ResolvedBy = nullStringPtr(assignedTo), nullStringPtr(acknowledgedBy), nullStringPtr(resolvedBy)
+	r.AssignedAt, r.AcknowledgedAt, r.ResolvedAt = nullTimePtr(assignedAt), nullTimePtr(acknowledgedAt), nullTimePtr(resolvedAt)
+	r.Labels, r.CustomFields = labels, customFields
+	return &r, nil
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var exportColumns = []string{"id", "organization_id", "project_id", "title", "description", "status", "urgency",
+	"priority", "severity", "source", "integration_id", "service_id", "group_id", "escalation_policy_id",
+	"current_escalation_level", "escalation_status", "assigned_to", "assigned_at", "acknowledged_by",
+	"acknowledged_at", "resolved_by", "resolved_at", "incident_key", "alert_count", "is_test", "drill_id",
+	"labels", "custom_fields", "created_at", "updated_at"}
+
+func exportRow(id string, updatedAt time.Time) []driver.Value {
+	return []driver.Value{id, "org-1", nil, "Disk full", "", "resolved", "high",
+		"P1", "critical", "webhook", nil, "svc-1", nil, nil,
+		0, "none", "user-1", updatedAt, nil,
+		nil, "user-1", updatedAt, nil, 1, false, nil,
+		[]byte(`{"alertname":"DiskFull"}`), []byte(`{}`), updatedAt.Add(-time.Hour), updatedAt}
+}
+
+func TestIncidentExportCursorRoundTrip(t *testing.T) {
+	cursor := NewIncidentExportCursor(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+	cursor.AfterID = "inc-9"
+	decoded, err := DecodeIncidentExportCursor(EncodeIncidentExportCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Since.Equal(cursor.Since) || !decoded.SnapshotAt.Equal(cursor.SnapshotAt) || decoded.AfterID != "inc-9" {
+		t.Errorf("cursor changed in round trip: %+v vs %+v", decoded, cursor)
+	}
+	if _, err := DecodeIncidentExportCursor("not a cursor"); !errors.Is(err, ErrInvalidExportCursor) {
+		t.Errorf("expected ErrInvalidExportCursor, got %v", err)
+	}
+}
+
+func TestExportIncidentsStopsAtLimit(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	cursor := NewIncidentExportCursor(time.Time{})
+	t1 := cursor.SnapshotAt.Add(-3 * time.Hour)
+	t2 := cursor.SnapshotAt.Add(-2 * time.Hour)
+	mock.ExpectQuery(`FROM incidents i`).
+		WithArgs("org-1", cursor.Since, cursor.SnapshotAt, time.Time{}, "00000000-0000-0000-0000-000000000000", 3).
+		WillReturnRows(sqlmock.NewRows(exportColumns).
+			AddRow(exportRow("inc-1", t1)...).
+			AddRow(exportRow("inc-2", t2)...).
+			AddRow(exportRow("inc-3", cursor.SnapshotAt.Add(-time.Hour))...))
+
+	var exported []string
+	checkpoint, err := (&IncidentService{PG: pg}).ExportIncidents("org-1", cursor, 2, func(r *db.IncidentExportRecord) error {
+		exported = append(exported, r.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 2 || checkpoint.Exported != 2 || !checkpoint.HasMore {
+		t.Fatalf("exported %v, checkpoint %+v", exported, checkpoint)
+	}
+
+	next, err := DecodeIncidentExportCursor(checkpoint.Cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.AfterID != "inc-2" || !next.AfterUpdatedAt.Equal(t2) || !next.SnapshotAt.Equal(cursor.SnapshotAt) {
+		t.Errorf("next cursor should resume after inc-2 within the same snapshot: %+v", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+const (
+	defaultIncidentFeedLimit = 50
+	maxIncidentFeedLimit     = 200
+)
+
+var ErrInvalidFeedCursor = errors.New("invalid feed cursor")
+
+// encodeFeedCursor builds an opaque keyset cursor from the last entry of a page
+func encodeFeedCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidFeedCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return time.Time{}, "", ErrInvalidFeedCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", ErrInvalidFeedCursor
+	}
+	return createdAt, parts[1], nil
+}
+
+// GetIncidentFeed returns incident events across every group and project the
+// user can see in the organization, newest first, using keyset pagination on
+// (created_at, id) so pages stay stable while new events arrive.
+//
+// Filters: current_user_id and current_org_id (required), project_id,
+// group_id, service_id, urgency, severity, incident_status, event_types
+// ([]string), since (time.Time), cursor and limit.
+func (s *IncidentService) GetIncidentFeed(filters map[string]interface{}) (*db.IncidentFeedPage, error) {
+	page := &db.IncidentFeedPage{Entries: []db.IncidentFeedEntry{}}
+
+	currentUserID, _ := filters["current_user_id"].(string)
+	currentOrgID, _ := filters["current_org_id"].(string)
+	if currentUserID == "" || currentOrgID == "" {
+		return page, nil
+	}
+
+	limit := defaultIncidentFeedLimit
+	if l, ok := filters["limit"].(int); ok && l > 0 {
+		limit = l
+	}
+	if limit > maxIncidentFeedLimit {
+		limit = maxIncidentFeedLimit
+	}
+
+	query := `
+		SELECT ie.id, ie.incident_id, ie.event_type, ie.event_data, ie.created_at,
+		       COALESCE(ie.created_by::text, ''), COALESCE(u.name, ''),
+		       i.title, i.status, i.urgency, COALESCE(i.severity, ''),
+		       COALESCE(i.group_id::text, ''), COALESCE(g.name, ''),
+		       COALESCE(i.service_id::text, ''), COALESCE(s.name, ''),
+		       COALESCE(i.project_id::text, ''), COALESCE(u_assigned.name, '')
+		FROM incident_events ie
+		JOIN incidents i ON ie.incident_id = i.id
+		LEFT JOIN users u ON ie.created_by = u.id
+		LEFT JOIN users u_assigned ON i.assigned_to = u_assigned.id
+		LEFT JOIN groups g ON i.group_id = g.id
+		LEFT JOIN services s ON i.service_id = s.id
+		WHERE
+` + incidentAccessScopeSQL
+
+	args := []interface{}{currentUserID, currentOrgID}
+	argIndex := 3
+
+	for _, column := range []struct{ filter, expr string }{
+		{"project_id", "i.project_id"},
+		{"group_id", "i.group_id"},
+		{"service_id", "i.service_id"},
+		{"urgency", "i.urgency"},
+		{"severity", "i.severity"},
+		{"incident_status", "i.status"},
+	} {
+		if value, ok := filters[column.filter].(string); ok && value != "" {
+			query += fmt.Sprintf(" AND %s = $%d", column.expr, argIndex)
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	if eventTypes, ok := filters["event_types"].([]string); ok && len(eventTypes) > 0 {
+		query += fmt.Sprintf(" AND ie.event_type = ANY($%d)", argIndex)
+		args = append(args, pq.Array(eventTypes))
+		argIndex++
+	}
+
+	if since, ok := filters["since"].(time.Time); ok && !since.IsZero() {
+		query += fmt.Sprintf(" AND ie.created_at > $%d", argIndex)
+		args = append(args, since)
+		argIndex++
+	}
+
+	if cursor, ok := filters["cursor"].(string); ok && cursor != "" {
+		cursorTime, cursorID, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(" AND (ie.created_at, ie.id) < ($%d, $%d)", argIndex, argIndex+1)
+		args = append(args, cursorTime, cursorID)
+		argIndex += 2
+	}
+
+	// Fetch one extra row to know whether another page exists
+	query += fmt.Sprintf(" ORDER BY ie.created_at DESC, ie.id DESC LIMIT $%d", argIndex)
+	args = append(args, limit+1)
+
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incident feed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry db.IncidentFeedEntry
+		var eventDataJSON sql.NullString
+		err := rows.Scan(
+			&entry.ID, &entry.IncidentID, &entry.EventType, &eventDataJSON, &entry.CreatedAt,
+			&entry.CreatedBy, &entry.CreatedByName,
+			&entry.IncidentTitle, &entry.IncidentStatus, &entry.Urgency, &entry.Severity,
+			&entry.GroupID, &entry.GroupName, &entry.ServiceID, &entry.ServiceName,
+			&entry.ProjectID, &entry.AssignedToName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident feed entry: %w", err)
+		}
+		if eventDataJSON.Valid && eventDataJSON.String != "" {
+			json.Unmarshal([]byte(eventDataJSON.String), &entry.EventData)
+		}
+		entry.IncidentURL = webBaseURL() + "/incidents/" + entry.IncidentID
+		page.Entries = append(page.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read incident feed: %w", err)
+	}
+
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		page.HasMore = true
+		last := page.Entries[limit-1]
+		page.NextCursor = encodeFeedCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
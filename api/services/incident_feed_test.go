@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 123456000, time.UTC)
+	gotAt, gotID, err := decodeFeedCursor(encodeFeedCursor(at, "evt-1"))
+	if err != nil || !gotAt.Equal(at) || gotID != "evt-1" {
+		t.Fatalf("decodeFeedCursor() = (%v, %q, %v)", gotAt, gotID, err)
+	}
+	for _, cursor := range []string{"!!!", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXxpZA"} {
+		if _, _, err := decodeFeedCursor(cursor); !errors.Is(err, ErrInvalidFeedCursor) {
+			t.Errorf("decodeFeedCursor(%q) error = %v, want ErrInvalidFeedCursor", cursor, err)
+		}
+	}
+}
+
+func TestGetIncidentFeedKeysetPagination(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	columns := []string{"id", "incident_id", "event_type", "event_data", "created_at", "created_by", "created_by_name",
+		"title", "status", "urgency", "severity", "group_id", "group_name", "service_id", "service_name",
+		"project_id", "assigned_to_name"}
+	newest := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(columns)
+	for i := 0; i < 3; i++ {
+		rows.AddRow("evt-"+string(rune('a'+i)), "inc-1", "acknowledged", `{"note":"on it"}`,
+			newest.Add(-time.Duration(i)*time.Minute), "user-1", "Alice",
+			"DB down", "acknowledged", "high", "critical", "grp-1", "payments", "svc-1", "checkout", "proj-1", "Alice")
+	}
+
+	cursorAt := newest.Add(time.Hour)
+	mock.ExpectQuery(`FROM incident_events ie.*i.group_id = \$3.*ie.event_type = ANY\(\$4\).*\(ie.created_at, ie.id\) < \(\$5, \$6\).*LIMIT \$7`).
+		WithArgs("user-1", "org-1", "grp-1", sqlmock.AnyArg(), cursorAt, "evt-z", 3).
+		WillReturnRows(rows)
+
+	page, err := NewIncidentService(pg, nil).GetIncidentFeed(map[string]interface{}{
+		"current_user_id": "user-1",
+		"current_org_id":  "org-1",
+		"group_id":        "grp-1",
+		"event_types":     []string{"acknowledged", "resolved"},
+		"cursor":          encodeFeedCursor(cursorAt, "evt-z"),
+		"limit":           2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entries) != 2 || !page.HasMore {
+		t.Fatalf("got %d entries, has_more=%v; want 2 entries and more", len(page.Entries), page.HasMore)
+	}
+	if page.Entries[0].EventData["note"] != "on it" || page.Entries[0].GroupName != "payments" {
+		t.Errorf("unexpected entry %+v", page.Entries[0])
+	}
+
+	nextAt, nextID, err := decodeFeedCursor(page.NextCursor)
+	if err != nil || nextID != "evt-b" || !nextAt.Equal(newest.Add(-time.Minute)) {
+		t.Errorf("next cursor = (%v, %q, %v), want last entry of the page", nextAt, nextID, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestFindIncidentByFingerprintIsScoped(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := NewIncidentService(pg, nil)
+
+	mock.ExpectQuery(`labels->>'fingerprint' = \$1 AND organization_id = \$2`).WithArgs("fp-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`labels->>'fingerprint' = \$1 AND integration_id = \$2`).WithArgs("fp-1", "int-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if incident, err := s.FindIncidentByFingerprint(db.Integration{ID: "int-1", OrganizationID: "org-1"}, "fp-1"); err != nil || incident != nil {
+		t.Errorf("expected no incident, got %v, %v", incident, err)
+	}
+	if incident, err := s.FindIncidentByFingerprint(db.Integration{ID: "int-2"}, "fp-1"); err != nil || incident != nil {
+		t.Errorf("expected no incident, got %v, %v", incident, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
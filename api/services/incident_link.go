@@ -0,0 +1,137 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var ErrIncidentLinkNotFound = errors.New("incident link not found")
+
+// incidentShortIDAlphabet matches generate_incident_short_id() in the
+// database: no 0/o or 1/l/i look-alikes
+const incidentShortIDAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// IncidentLinkService resolves incident deep links (/r/<short_id>) and
+// publishes the association files that let iOS and Android open them in the
+// mobile app instead of the browser
+type IncidentLinkService struct {
+	PG *sql.DB
+}
+
+// NewIncidentLinkService creates a new IncidentLinkService
+func NewIncidentLinkService(pg *sql.DB) *IncidentLinkService {
+	return &IncidentLinkService{PG: pg}
+}
+
+// deepLinkBaseURL is the host deep links are served from: deep_links.base_url,
+// otherwise the API's external URL
+func deepLinkBaseURL() string {
+	if base := strings.TrimRight(config.Current().DeepLinks.BaseURL, "/"); base != "" {
+		return base
+	}
+	return config.ExternalAPIURL()
+}
+
+// IncidentShortURL is the deep link for an incident short ID
+func IncidentShortURL(shortID string) string {
+	return deepLinkBaseURL() + "/r/" + url.PathEscape(shortID)
+}
+
+// incidentLinkURL is the incident link put in notifications: the deep link
+// when the short ID is known, the web UI otherwise
+func incidentLinkURL(incidentID, shortID string) string {
+	if shortID != "" {
+		return IncidentShortURL(shortID)
+	}
+	return webBaseURL() + "/incidents/" + incidentID
+}
+
+// normalizeIncidentShortID lowercases a short ID (they are read aloud and
+// retyped) and rejects anything that can't be one
+func normalizeIncidentShortID(shortID string) (string, bool) {
+	shortID = strings.ToLower(strings.TrimSpace(shortID))
+	if shortID == "" || len(shortID) > 32 {
+		return "", false
+	}
+	for _, r := range shortID {
+		if !strings.ContainsRune(incidentShortIDAlphabet, r) {
+			return "", false
+		}
+	}
+	return shortID, true
+}
+
+// Resolve returns the incident a short ID links to. The link reveals only the
+// incident's ID; opening it still requires signing in.
+func (s *IncidentLinkService) Resolve(shortID string) (*db.IncidentLink, error) {
+	shortID, ok := normalizeIncidentShortID(shortID)
+	if !ok {
+		return nil, ErrIncidentLinkNotFound
+	}
+	link := db.IncidentLink{ShortID: shortID}
+	err := s.PG.QueryRow(`SELECT id FROM incidents WHERE short_id = $1`, shortID).Scan(&link.IncidentID)
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve incident link: %w", err)
+	}
+
+	link.URL = IncidentShortURL(shortID)
+	link.WebURL = webBaseURL() + "/incidents/" + link.IncidentID
+	if scheme := config.Current().DeepLinks.AppScheme; scheme != "" {
+		link.AppURL = scheme + "://incidents/" + link.IncidentID
+	}
+	return &link, nil
+}
+
+// deepLinkPathPattern is the path deep links live under on their host,
+// e.g. /r/* or /slar/api/r/* behind a base path
+func deepLinkPathPattern() string {
+	prefix := ""
+	if u, err := url.Parse(deepLinkBaseURL()); err == nil {
+		prefix = strings.TrimRight(u.Path, "/")
+	}
+	return prefix + "/r/*"
+}
+
+// AppleAppSiteAssociation is served at /.well-known/apple-app-site-association
+// so iOS opens deep links in the app (universal links). ok is false when no
+// iOS app is configured.
+func (s *IncidentLinkService) AppleAppSiteAssociation() (association map[string]interface{}, ok bool) {
+	appIDs := config.Current().DeepLinks.IOSAppIDs
+	if len(appIDs) == 0 {
+		return nil, false
+	}
+	return map[string]interface{}{
+		"applinks": map[string]interface{}{
+			"details": []map[string]interface{}{{
+				"appIDs":     appIDs,
+				"components": []map[string]string{{"/": deepLinkPathPattern()}},
+			}},
+		},
+	}, true
+}
+
+// AssetLinks is served at /.well-known/assetlinks.json so Android opens deep
+// links in the app (app links). ok is false when no Android app is configured.
+func (s *IncidentLinkService) AssetLinks() (statements []map[string]interface{}, ok bool) {
+	cfg := config.Current().DeepLinks
+	if cfg.AndroidPackage == "" || len(cfg.AndroidCertFingerprints) == 0 {
+		return nil, false
+	}
+	return []map[string]interface{}{{
+		"relation": []string{"delegate_permission/common.handle_all_urls"},
+		"target": map[string]interface{}{
+			"namespace":                "android_app",
+			"package_name":             cfg.AndroidPackage,
+			"sha256_cert_fingerprints": cfg.AndroidCertFingerprints,
+		},
+	}}, true
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestResolveIncidentLink(t *testing.T) {
+	old := config.Current()
+	defer config.Update(func(c *config.Config) { *c = *old })
+	config.Update(func(c *config.Config) {
+		c.DeepLinks = config.DeepLinksConfig{BaseURL: "https://go.slar.example.com/", AppScheme: "slar"}
+		c.SlarWebURL = "https://slar.example.com"
+	})
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	mock.ExpectQuery(`SELECT id FROM incidents WHERE short_id = \$1`).WithArgs("k3m9x2ab7q").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("inc-1"))
+
+	s := NewIncidentLinkService(pg)
+	link, err := s.Resolve(" K3M9X2AB7Q ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.URL != "https://go.slar.example.com/r/k3m9x2ab7q" || link.WebURL != "https://slar.example.com/incidents/inc-1" ||
+		link.AppURL != "slar://incidents/inc-1" {
+		t.Errorf("link = %+v", link)
+	}
+
+	// Not a short ID: rejected without a query
+	if _, err := s.Resolve("../incidents"); !errors.Is(err, ErrIncidentLinkNotFound) {
+		t.Errorf("error = %v, want ErrIncidentLinkNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIncidentLinkAssociationFiles(t *testing.T) {
+	old := config.Current()
+	defer config.Update(func(c *config.Config) { *c = *old })
+	config.Update(func(c *config.Config) {
+		c.PublicURL = "https://gw.corp.example"
+		c.BasePath = "/slar/api"
+	})
+
+	s := NewIncidentLinkService(nil)
+	if _, ok := s.AppleAppSiteAssociation(); ok {
+		t.Error("expected no association without an iOS app")
+	}
+	if _, ok := s.AssetLinks(); ok {
+		t.Error("expected no asset links without an Android app")
+	}
+
+	config.Update(func(c *config.Config) {
+		c.DeepLinks.IOSAppIDs = []string{"ABCDE12345.com.slar.slar"}
+		c.DeepLinks.AndroidPackage = "com.slar.slar"
+		c.DeepLinks.AndroidCertFingerprints = []string{"14:6D:E9"}
+	})
+	association, ok := s.AppleAppSiteAssociation()
+	if !ok {
+		t.Fatal("expected an association")
+	}
+	details := association["applinks"].(map[string]interface{})["details"].([]map[string]interface{})
+	if path := details[0]["components"].([]map[string]string)[0]["/"]; path != "/slar/api/r/*" {
+		t.Errorf("path = %q, want /slar/api/r/*", path)
+	}
+	if statements, ok := s.AssetLinks(); !ok || statements[0]["target"].(map[string]interface{})["package_name"] != "com.slar.slar" {
+		t.Errorf("asset links = %v", statements)
+	}
+}
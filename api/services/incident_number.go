@@ -0,0 +1,46 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrIncidentNumberNotFound = errors.New("incident not found")
+
+// ParseIncidentNumber reads an incident reference as people type it:
+// "INC-1024", "inc-1024", "#1024" or "1024"
+func ParseIncidentNumber(ref string) (int64, bool) {
+	ref = strings.TrimSpace(ref)
+	if len(ref) > len(db.IncidentNumberPrefix) && strings.EqualFold(ref[:len(db.IncidentNumberPrefix)], db.IncidentNumberPrefix) {
+		ref = ref[len(db.IncidentNumberPrefix):]
+	} else {
+		ref = strings.TrimPrefix(ref, "#")
+	}
+	if ref == "" || ref[0] < '1' || ref[0] > '9' {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// ResolveIncidentNumber returns the ID of the organization's incident with
+// this number. Access to the incident is still checked on the ID.
+func (s *IncidentService) ResolveIncidentNumber(orgID string, number int64) (string, error) {
+	var id string
+	err := s.PG.QueryRow(`SELECT id FROM incidents WHERE organization_id = $1 AND number = $2`, orgID, number).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrIncidentNumberNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve incident number: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseIncidentNumber(t *testing.T) {
+	tests := []struct {
+		ref    string
+		number int64
+		ok     bool
+	}{
+		{"INC-1024", 1024, true},
+		{"inc-7", 7, true},
+		{"#42", 42, true},
+		{" 1024 ", 1024, true},
+		{"INC-", 0, false},
+		{"INC-0", 0, false},
+		{"0012", 0, false},
+		{"-5", 0, false},
+		{"1e3", 0, false},
+		{"3fa85f64-5717-4562-b3fc-2c963f66afa6", 0, false},
+		{"99999999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		number, ok := ParseIncidentNumber(tt.ref)
+		if number != tt.number || ok != tt.ok {
+			t.Errorf("ParseIncidentNumber(%q) = %d, %v, want %d, %v", tt.ref, number, ok, tt.number, tt.ok)
+		}
+	}
+	if got := db.FormatIncidentNumber(1024); got != "INC-1024" {
+		t.Errorf("FormatIncidentNumber = %q", got)
+	}
+}
+
+func TestResolveIncidentNumber(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	mock.ExpectQuery(`SELECT id FROM incidents WHERE organization_id = \$1 AND number = \$2`).WithArgs("org-1", int64(1024)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("inc-1"))
+	mock.ExpectQuery(`SELECT id FROM incidents WHERE organization_id = \$1 AND number = \$2`).WithArgs("org-2", int64(1024)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := &IncidentService{PG: pg}
+	id, err := s.ResolveIncidentNumber("org-1", 1024)
+	if err != nil || id != "inc-1" {
+		t.Errorf("ResolveIncidentNumber = %q, %v", id, err)
+	}
+	// Numbers are per organization
+	if _, err := s.ResolveIncidentNumber("org-2", 1024); !errors.Is(err, ErrIncidentNumberNotFound) {
+		t.Errorf("error = %v, want ErrIncidentNumberNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
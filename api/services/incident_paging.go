@@ -0,0 +1,107 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrPageTargetNotFound = errors.New("page target not found in this organization")
+	ErrNoOnCallResponder  = errors.New("nobody is currently on call for this target")
+	ErrIncidentResolved   = errors.New("incident is already resolved")
+)
+
+// PageResponder pages an extra user, scheduler or group onto an incident outside
+// its escalation policy (e.g. pulling in a specialist team mid-incident). Schedulers
+// and groups resolve to whoever is on call right now. The page is recorded on the
+// timeline and sent through the normal notification queue; the incident's
+// assignee and escalation state are left untouched.
+func (s *IncidentService) PageResponder(incidentID string, req db.PageIncidentRequest, pagedBy string) (*db.PageIncidentResult, error) {
+	var status string
+	var orgID sql.NullString
+	err := s.PG.QueryRow(`SELECT status, organization_id FROM incidents WHERE id = $1`, incidentID).Scan(&status, &orgID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	if status == db.IncidentStatusResolved {
+		return nil, ErrIncidentResolved
+	}
+
+	result := &db.PageIncidentResult{TargetType: req.TargetType, TargetID: req.TargetID}
+
+	switch req.TargetType {
+	case "user":
+		err = s.PG.QueryRow(`
+			SELECT u.id, u.name FROM users u
+			JOIN memberships m ON m.user_id = u.id AND m.resource_type = 'org' AND m.resource_id = $2
+			WHERE u.id = $1 AND COALESCE(u.is_active, TRUE)
+		`, req.TargetID, orgID.String).Scan(&result.PagedUserID, &result.TargetName)
+		result.PagedUserName = result.TargetName
+
+	case "scheduler":
+		var groupID string
+		err = s.PG.QueryRow(`
+			SELECT sc.group_id, COALESCE(sc.display_name, sc.name) FROM schedulers sc
+			JOIN groups g ON sc.group_id = g.id
+			WHERE sc.id = $1 AND g.organization_id = $2 AND COALESCE(sc.is_active, TRUE)
+		`, req.TargetID, orgID.String).Scan(&groupID, &result.TargetName)
+		if err == nil {
+			result.PagedUserID, err = s.getCurrentOnCallUserFromScheduler(req.TargetID, groupID)
+		}
+
+	case "group":
+		err = s.PG.QueryRow(`
+			SELECT name FROM groups WHERE id = $1 AND organization_id = $2 AND is_active = TRUE
+		`, req.TargetID, orgID.String).Scan(&result.TargetName)
+		if err == nil {
+			result.PagedUserID, err = s.getCurrentOnCallUserFromGroup(req.TargetID)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported target_type %q", req.TargetType)
+	}
+
+	if err == sql.ErrNoRows {
+		return nil, ErrPageTargetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve page target: %w", err)
+	}
+	if result.PagedUserID == "" {
+		return nil, ErrNoOnCallResponder
+	}
+	if result.PagedUserName == "" {
+		s.PG.QueryRow(`SELECT COALESCE(name, email, 'Unknown') FROM users WHERE id = $1`, result.PagedUserID).Scan(&result.PagedUserName)
+	}
+
+	eventData := map[string]interface{}{
+		"target_type":     result.TargetType,
+		"target_id":       result.TargetID,
+		"target_name":     result.TargetName,
+		"paged_user_id":   result.PagedUserID,
+		"paged_user_name": result.PagedUserName,
+	}
+	if req.Message != "" {
+		eventData["message"] = req.Message
+	}
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventResponderPaged, eventData, pagedBy); err != nil {
+		return nil, fmt.Errorf("failed to record page: %w", err)
+	}
+
+	if s.NotificationWorker != nil {
+		go func() {
+			if err := s.NotificationWorker.SendIncidentPagedNotification(result.PagedUserID, incidentID, req.Message); err != nil {
+				log.Printf("⚠️  Failed to send incident paged notification: %v", err)
+			}
+		}()
+	}
+
+	return result, nil
+}
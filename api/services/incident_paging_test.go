@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestPageResponderGroupPagesCurrentOnCall(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT status, organization_id FROM incidents`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "organization_id"}).AddRow("acknowledged", "org-1"))
+	mock.ExpectQuery(`SELECT name FROM groups`).WithArgs("grp-db", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Database Team"))
+	mock.ExpectQuery(`FROM effective_shifts`).WithArgs("grp-db").
+		WillReturnRows(sqlmock.NewRows([]string{"effective_user_id"}).AddRow("user-dba"))
+	mock.ExpectQuery(`SELECT COALESCE\(name, email, 'Unknown'\) FROM users`).WithArgs("user-dba").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Dana"))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventResponderPaged, sqlmock.AnyArg(), "user-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	result, err := NewIncidentService(pg, nil).PageResponder("inc-1", db.PageIncidentRequest{
+		TargetType: "group", TargetID: "grp-db", Message: "need a DBA",
+	}, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PagedUserID != "user-dba" || result.PagedUserName != "Dana" || result.TargetName != "Database Team" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPageResponderErrors(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	svc := NewIncidentService(pg, nil)
+
+	// Resolved incidents can't be paged onto
+	mock.ExpectQuery(`SELECT status, organization_id FROM incidents`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "organization_id"}).AddRow("resolved", "org-1"))
+	if _, err := svc.PageResponder("inc-1", db.PageIncidentRequest{TargetType: "user", TargetID: "u"}, "user-1"); !errors.Is(err, ErrIncidentResolved) {
+		t.Errorf("resolved incident: err = %v, want ErrIncidentResolved", err)
+	}
+
+	// Scheduler in another org
+	mock.ExpectQuery(`SELECT status, organization_id FROM incidents`).WithArgs("inc-2").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "organization_id"}).AddRow("triggered", "org-1"))
+	mock.ExpectQuery(`FROM schedulers sc`).WithArgs("sched-x", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id", "name"}))
+	if _, err := svc.PageResponder("inc-2", db.PageIncidentRequest{TargetType: "scheduler", TargetID: "sched-x"}, "user-1"); !errors.Is(err, ErrPageTargetNotFound) {
+		t.Errorf("foreign scheduler: err = %v, want ErrPageTargetNotFound", err)
+	}
+
+	// Group with nobody on call
+	mock.ExpectQuery(`SELECT status, organization_id FROM incidents`).WithArgs("inc-3").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "organization_id"}).AddRow("triggered", "org-1"))
+	mock.ExpectQuery(`SELECT name FROM groups`).WithArgs("grp-empty", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Empty"))
+	mock.ExpectQuery(`FROM effective_shifts`).WithArgs("grp-empty").
+		WillReturnRows(sqlmock.NewRows([]string{"effective_user_id"}))
+	if _, err := svc.PageResponder("inc-3", db.PageIncidentRequest{TargetType: "group", TargetID: "grp-empty"}, "user-1"); !errors.Is(err, ErrNoOnCallResponder) {
+		t.Errorf("empty rotation: err = %v, want ErrNoOnCallResponder", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
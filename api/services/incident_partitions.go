@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+const (
+	// incidentEventPartitionPrefix names monthly partitions incident_events_YYYY_MM
+	incidentEventPartitionPrefix = "incident_events_"
+	// incidentPartitionsAhead is how many months past the current one have a
+	// partition ready, so inserts never fall into the default partition
+	incidentPartitionsAhead = 3
+)
+
+// monthlyPartitionedTables are partitioned by RANGE (created_at) with one
+// partition per month, named <table>_YYYY_MM
+var monthlyPartitionedTables = []string{"incidents", "incident_events"}
+
+// MaintainIncidentPartitions creates the monthly incidents and
+// incident_events partitions for the current month and the next few, and
+// drops incident_events months that ended longer ago than
+// incident_event_retention (0 keeps everything). Incidents are never dropped.
+func (s *IncidentService) MaintainIncidentPartitions() (created, dropped int, err error) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, table := range monthlyPartitionedTables {
+		for i := 0; i <= incidentPartitionsAhead; i++ {
+			var ok bool
+			if err := s.PG.QueryRow(`SELECT create_monthly_partition($1::regclass, $2)`, table, month.AddDate(0, i, 0)).Scan(&ok); err != nil {
+				return created, dropped, fmt.Errorf("failed to create %s partition: %w", table, err)
+			}
+			if ok {
+				created++
+			}
+		}
+	}
+
+	retention := config.Current().IncidentEventRetention
+	if retention <= 0 {
+		return created, dropped, nil
+	}
+	cutoff := now.Add(-retention)
+
+	rows, err := s.PG.Query(`
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'incident_events'::regclass`)
+	if err != nil {
+		return created, dropped, fmt.Errorf("failed to list incident_events partitions: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return created, dropped, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return created, dropped, err
+	}
+
+	for _, name := range expiredIncidentEventPartitions(names, cutoff) {
+		if _, err := s.PG.Exec(`DROP TABLE IF EXISTS ` + pq.QuoteIdentifier(name)); err != nil {
+			return created, dropped, fmt.Errorf("failed to drop partition %s: %w", name, err)
+		}
+		dropped++
+	}
+
+	// Events outside every monthly range land in the default partition
+	if _, err := s.PG.Exec(`DELETE FROM incident_events_default WHERE created_at < $1`, cutoff); err != nil {
+		return created, dropped, fmt.Errorf("failed to purge default incident_events partition: %w", err)
+	}
+	return created, dropped, nil
+}
+
+// expiredIncidentEventPartitions returns the monthly partitions whose whole
+// month ended before cutoff; the default partition and unrecognized names
+// are never returned
+func expiredIncidentEventPartitions(names []string, cutoff time.Time) []string {
+	var expired []string
+	for _, name := range names {
+		suffix, ok := strings.CutPrefix(name, incidentEventPartitionPrefix)
+		if !ok {
+			continue
+		}
+		month, err := time.Parse("2006_01", suffix)
+		if err != nil {
+			continue
+		}
+		if !month.AddDate(0, 1, 0).After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestExpiredIncidentEventPartitions(t *testing.T) {
+	names := []string{
+		"incident_events_default",
+		"incident_events_2025_12",
+		"incident_events_2026_01",
+		"incident_events_2026_02",
+		"incident_events_backup",
+	}
+	cutoff := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	got := expiredIncidentEventPartitions(names, cutoff)
+	want := []string{"incident_events_2025_12", "incident_events_2026_01"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expired = %v, want %v", got, want)
+	}
+}
+
+func TestMaintainIncidentPartitions(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	saved := config.Current().IncidentEventRetention
+	defer config.Update(func(c *config.Config) { c.IncidentEventRetention = saved })
+	config.Update(func(c *config.Config) { c.IncidentEventRetention = 90 * 24 * time.Hour })
+
+	for _, table := range []string{"incidents", "incident_events"} {
+		for i := 0; i <= incidentPartitionsAhead; i++ {
+			mock.ExpectQuery(`SELECT create_monthly_partition`).
+				WithArgs(table, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(i == incidentPartitionsAhead))
+		}
+	}
+	old := time.Now().UTC().AddDate(-1, 0, 0).Format("2006_01")
+	mock.ExpectQuery(`FROM pg_inherits`).WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+		AddRow("incident_events_default").
+		AddRow("incident_events_" + old).
+		AddRow("incident_events_" + time.Now().UTC().Format("2006_01")))
+	mock.ExpectExec(`DROP TABLE IF EXISTS "incident_events_` + old + `"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM incident_events_default`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	created, dropped, err := (&IncidentService{PG: pg}).MaintainIncidentPartitions()
+	if err != nil || created != 2 || dropped != 1 {
+		t.Errorf("created = %d, dropped = %d, err = %v", created, dropped, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
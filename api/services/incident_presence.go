@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// IncidentPresenceTTL is how long someone counts as viewing an incident after
+// their last heartbeat. Clients heartbeat about every third of it.
+const IncidentPresenceTTL = 30 * time.Second
+
+// UpdateIncidentPresence records a heartbeat from a user who has the incident
+// open and returns everyone currently viewing it. A user whose previous
+// heartbeat expired starts a new session.
+func (s *IncidentService) UpdateIncidentPresence(incidentID, userID, mode string) ([]db.IncidentViewer, error) {
+	if mode == "" {
+		mode = db.PresenceViewing
+	}
+	_, err := s.PG.Exec(`
+		INSERT INTO incident_viewers (incident_id, user_id, mode)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (incident_id, user_id) DO UPDATE
+		SET mode = EXCLUDED.mode,
+		    since = CASE WHEN incident_viewers.last_seen_at < NOW() - INTERVAL '1 second' * $4
+		                 THEN NOW() ELSE incident_viewers.since END,
+		    last_seen_at = NOW()
+	`, incidentID, userID, mode, int(IncidentPresenceTTL.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update incident presence: %w", err)
+	}
+	return s.ListIncidentViewers(incidentID)
+}
+
+// LeaveIncidentPresence removes a user from an incident's viewers, e.g. when
+// they close the page
+func (s *IncidentService) LeaveIncidentPresence(incidentID, userID string) error {
+	if _, err := s.PG.Exec(`DELETE FROM incident_viewers WHERE incident_id = $1 AND user_id = $2`, incidentID, userID); err != nil {
+		return fmt.Errorf("failed to leave incident presence: %w", err)
+	}
+	return nil
+}
+
+// ListIncidentViewers returns who has heartbeated on an incident within the
+// presence TTL, longest-present first
+func (s *IncidentService) ListIncidentViewers(incidentID string) ([]db.IncidentViewer, error) {
+	rows, err := s.PG.Query(`
+		SELECT v.user_id, COALESCE(u.name, u.email, ''), COALESCE(u.email, ''), v.mode, v.since, v.last_seen_at
+		FROM incident_viewers v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.incident_id = $1
+		AND v.last_seen_at > NOW() - INTERVAL '1 second' * $2
+		ORDER BY v.since ASC
+	`, incidentID, int(IncidentPresenceTTL.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incident viewers: %w", err)
+	}
+	defer rows.Close()
+
+	viewers := []db.IncidentViewer{}
+	for rows.Next() {
+		var v db.IncidentViewer
+		if err := rows.Scan(&v.UserID, &v.Name, &v.Email, &v.Mode, &v.Since, &v.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan incident viewer: %w", err)
+		}
+		viewers = append(viewers, v)
+	}
+	return viewers, rows.Err()
+}
+
+// PurgeStaleIncidentViewers deletes presence rows well past the TTL
+func (s *IncidentService) PurgeStaleIncidentViewers() (int64, error) {
+	result, err := s.PG.Exec(`DELETE FROM incident_viewers WHERE last_seen_at < NOW() - INTERVAL '1 hour'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge incident viewers: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// IncidentPresenceKey summarizes who is viewing and in which mode, so
+// streams only push an update when it changes
+func IncidentPresenceKey(viewers []db.IncidentViewer) string {
+	parts := make([]string, len(viewers))
+	for i, v := range viewers {
+		parts[i] = v.UserID + ":" + v.Mode
+	}
+	return strings.Join(parts, ",")
+}
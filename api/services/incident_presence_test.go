@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestUpdateIncidentPresence(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	// An empty mode is a plain viewer
+	mock.ExpectExec(`INSERT INTO incident_viewers`).WithArgs("inc-1", "user-1", db.PresenceViewing, 30).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`FROM incident_viewers v`).WithArgs("inc-1", 30).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "name", "email", "mode", "since", "last_seen_at"}).
+			AddRow("user-2", "Lee", "lee@example.com", db.PresenceEditing, now.Add(-time.Minute), now).
+			AddRow("user-1", "Dana", "dana@example.com", db.PresenceViewing, now, now))
+
+	service := &IncidentService{PG: pg}
+	viewers, err := service.UpdateIncidentPresence("inc-1", "user-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(viewers) != 2 || viewers[0].Name != "Lee" || viewers[0].Mode != db.PresenceEditing {
+		t.Errorf("unexpected viewers: %+v", viewers)
+	}
+	if key := IncidentPresenceKey(viewers); key != "user-2:editing,user-1:viewing" {
+		t.Errorf("presence key = %q", key)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
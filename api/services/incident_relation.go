@@ -0,0 +1,216 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrIncidentRelationNotFound       = errors.New("relation not found")
+	ErrIncidentRelationTargetNotFound = errors.New("related incident or change not found")
+	ErrIncidentRelationExists         = errors.New("these are already linked")
+	ErrInvalidIncidentRelation        = errors.New("invalid relation")
+)
+
+// incidentRelationSelect lists relations from the point of view of the
+// incident in $1: "other" is the incident on the far side of the link
+const incidentRelationSelect = `
+	SELECT r.id, r.incident_id, r.type, r.incident_id <> $1 AS inverse,
+	       COALESCE(other.id::text, ''), COALESCE(other.number, 0), COALESCE(other.title, ''), COALESCE(other.status, ''),
+	       COALESCE(r.change_event_id::text, ''), COALESCE(ce.summary, ''), COALESCE(ce.type, ''),
+	       COALESCE(r.created_by::text, ''), COALESCE(u.name, u.email, ''), r.created_at
+	FROM incident_relations r
+	LEFT JOIN incidents other ON other.id = CASE WHEN r.incident_id = $1 THEN r.related_incident_id ELSE r.incident_id END
+	LEFT JOIN change_events ce ON ce.id = r.change_event_id
+	LEFT JOIN users u ON u.id = r.created_by
+	WHERE (r.incident_id = $1 OR r.related_incident_id = $1)
+`
+
+func (s *IncidentService) queryIncidentRelations(incidentID, extraWhere string, args ...interface{}) ([]db.IncidentRelation, error) {
+	rows, err := s.PG.Query(incidentRelationSelect+extraWhere+`
+		ORDER BY r.created_at ASC
+	`, append([]interface{}{incidentID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incident relations: %w", err)
+	}
+	defer rows.Close()
+
+	relations := []db.IncidentRelation{}
+	for rows.Next() {
+		var r db.IncidentRelation
+		if err := rows.Scan(&r.ID, &r.IncidentID, &r.Type, &r.Inverse,
+			&r.RelatedIncidentID, &r.RelatedIncidentNumber, &r.RelatedTitle, &r.RelatedStatus,
+			&r.ChangeEventID, &r.ChangeSummary, &r.ChangeType,
+			&r.CreatedBy, &r.CreatedByName, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan incident relation: %w", err)
+		}
+		if r.RelatedIncidentNumber > 0 {
+			r.RelatedReference = db.FormatIncidentNumber(r.RelatedIncidentNumber)
+		}
+		relations = append(relations, r)
+	}
+	return relations, rows.Err()
+}
+
+// ListIncidentRelations returns the links from and to an incident, oldest first
+func (s *IncidentService) ListIncidentRelations(incidentID string) ([]db.IncidentRelation, error) {
+	return s.queryIncidentRelations(incidentID, "")
+}
+
+// CreateIncidentRelation links an incident to another incident of its
+// organization or, for "caused_by", to one of its organization's change
+// events. The link is recorded in the incident's timeline.
+func (s *IncidentService) CreateIncidentRelation(incidentID, userID string, req db.CreateIncidentRelationRequest) (*db.IncidentRelation, error) {
+	if (req.RelatedIncidentID == "") == (req.ChangeEventID == "") {
+		return nil, fmt.Errorf("%w: set exactly one of related_incident_id or change_event_id", ErrInvalidIncidentRelation)
+	}
+	target := req.RelatedIncidentID
+	insert := `
+		INSERT INTO incident_relations (organization_id, incident_id, type, related_incident_id, created_by)
+		SELECT i.organization_id, i.id, $2, t.id, $4
+		FROM incidents i
+		JOIN incidents t ON t.id = $3 AND t.organization_id IS NOT DISTINCT FROM i.organization_id
+		WHERE i.id = $1
+		RETURNING id`
+	if req.ChangeEventID != "" {
+		if req.Type != db.IncidentRelationCausedBy {
+			return nil, fmt.Errorf("%w: a change can only be linked as caused_by", ErrInvalidIncidentRelation)
+		}
+		target = req.ChangeEventID
+		insert = `
+			INSERT INTO incident_relations (organization_id, incident_id, type, change_event_id, created_by)
+			SELECT i.organization_id, i.id, $2, ce.id, $4
+			FROM incidents i
+			JOIN change_events ce ON ce.id = $3 AND ce.organization_id = i.organization_id
+			WHERE i.id = $1
+			RETURNING id`
+	}
+	if _, err := uuid.Parse(target); err != nil {
+		return nil, ErrIncidentRelationTargetNotFound
+	}
+	if target == incidentID {
+		return nil, fmt.Errorf("%w: an incident cannot be linked to itself", ErrInvalidIncidentRelation)
+	}
+
+	var relationID string
+	err := s.PG.QueryRow(insert, incidentID, req.Type, target, nullIfEmpty(userID)).Scan(&relationID)
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentRelationTargetNotFound
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrIncidentRelationExists
+		}
+		return nil, fmt.Errorf("failed to create incident relation: %w", err)
+	}
+
+	relations, err := s.queryIncidentRelations(incidentID, `AND r.id = $2`, relationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(relations) == 0 {
+		return nil, ErrIncidentRelationNotFound
+	}
+	relation := relations[0]
+
+	eventData := map[string]interface{}{
+		"relation_id":   relation.ID,
+		"relation_type": relation.Type,
+	}
+	if relation.RelatedIncidentID != "" {
+		eventData["related_incident_id"] = relation.RelatedIncidentID
+		eventData["related_reference"] = relation.RelatedReference
+		eventData["related_title"] = relation.RelatedTitle
+	} else {
+		eventData["change_event_id"] = relation.ChangeEventID
+		eventData["change_summary"] = relation.ChangeSummary
+	}
+	if relation.CreatedByName != "" {
+		eventData["user_name"] = relation.CreatedByName
+	}
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventRelationAdded, eventData, userID); err != nil {
+		return nil, fmt.Errorf("failed to record relation event: %w", err)
+	}
+	return &relation, nil
+}
+
+// DeleteIncidentRelation removes a link from or to an incident
+func (s *IncidentService) DeleteIncidentRelation(incidentID, relationID string) error {
+	if _, err := uuid.Parse(relationID); err != nil {
+		return ErrIncidentRelationNotFound
+	}
+	result, err := s.PG.Exec(`
+		DELETE FROM incident_relations
+		WHERE id = $2 AND (incident_id = $1 OR related_incident_id = $1)
+	`, incidentID, relationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete incident relation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrIncidentRelationNotFound
+	}
+	return nil
+}
+
+// GetCausedByReport lists the organization's incidents caused by a change
+// event or an incident, following "caused by" chains up to
+// IncidentRelationMaxDepth links. Exactly one of changeEventID and incidentID
+// is set.
+func (s *IncidentService) GetCausedByReport(orgID, changeEventID, incidentID string) (*db.CausedByReport, error) {
+	report := &db.CausedByReport{
+		ChangeEventID: changeEventID,
+		IncidentID:    incidentID,
+		Incidents:     []db.CausedByIncident{},
+	}
+	root := changeEventID
+	if root == "" {
+		root = incidentID
+	}
+	if _, err := uuid.Parse(root); err != nil {
+		return report, nil
+	}
+
+	rows, err := s.PG.Query(`
+		WITH RECURSIVE caused AS (
+			SELECT r.incident_id, 1 AS depth, ARRAY[r.incident_id] AS path
+			FROM incident_relations r
+			WHERE r.organization_id = $1 AND r.type = 'caused_by'
+			  AND (r.change_event_id = $2::uuid OR r.related_incident_id = $3::uuid)
+			UNION ALL
+			SELECT r.incident_id, c.depth + 1, c.path || r.incident_id
+			FROM caused c
+			JOIN incident_relations r ON r.related_incident_id = c.incident_id AND r.type = 'caused_by'
+			WHERE c.depth < $4 AND NOT r.incident_id = ANY(c.path) AND r.incident_id IS DISTINCT FROM $3::uuid
+		)
+		SELECT i.id, i.number, i.title, i.status, COALESCE(i.severity, ''), MIN(c.depth), i.created_at
+		FROM caused c
+		JOIN incidents i ON i.id = c.incident_id
+		WHERE i.organization_id = $1
+		GROUP BY i.id
+		ORDER BY MIN(c.depth), i.created_at
+	`, orgID, nullIfEmpty(changeEventID), nullIfEmpty(incidentID), db.IncidentRelationMaxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caused-by report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i db.CausedByIncident
+		if err := rows.Scan(&i.ID, &i.Number, &i.Title, &i.Status, &i.Severity, &i.Depth, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan caused-by incident: %w", err)
+		}
+		i.Reference = db.FormatIncidentNumber(i.Number)
+		if i.Depth == 1 {
+			report.DirectCount++
+		}
+		report.Incidents = append(report.Incidents, i)
+	}
+	report.Count = len(report.Incidents)
+	return report, rows.Err()
+}
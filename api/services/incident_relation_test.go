@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+const (
+	testIncidentID = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	testChangeID   = "9b2f4c1e-8d3a-4e6b-a5c7-1f0e2d3c4b5a"
+)
+
+var incidentRelationRowColumns = []string{
+	"id", "incident_id", "type", "inverse",
+	"related_incident_id", "related_number", "related_title", "related_status",
+	"change_event_id", "change_summary", "change_type",
+	"created_by", "created_by_name", "created_at",
+}
+
+func TestCreateIncidentRelationToChange(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`INSERT INTO incident_relations \(organization_id, incident_id, type, change_event_id, created_by\)`).
+		WithArgs(testIncidentID, db.IncidentRelationCausedBy, testChangeID, "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("rel-1"))
+	mock.ExpectQuery(`FROM incident_relations r`).WithArgs(testIncidentID, "rel-1").
+		WillReturnRows(sqlmock.NewRows(incidentRelationRowColumns).AddRow(
+			"rel-1", testIncidentID, db.IncidentRelationCausedBy, false,
+			"", 0, "", "",
+			testChangeID, "Deployed api v42", db.ChangeEventTypeDeploy,
+			"user-1", "Dana", time.Now()))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs(testIncidentID, db.IncidentEventRelationAdded, sqlmock.AnyArg(), "user-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s := &IncidentService{PG: pg}
+	relation, err := s.CreateIncidentRelation(testIncidentID, "user-1", db.CreateIncidentRelationRequest{
+		Type:          db.IncidentRelationCausedBy,
+		ChangeEventID: testChangeID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relation.ChangeSummary != "Deployed api v42" || relation.Inverse {
+		t.Errorf("relation = %+v", relation)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateIncidentRelationRejects(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := &IncidentService{PG: pg}
+
+	tests := []struct {
+		name string
+		req  db.CreateIncidentRelationRequest
+		want error
+	}{
+		{"no target", db.CreateIncidentRelationRequest{Type: db.IncidentRelationRelated}, ErrInvalidIncidentRelation},
+		{"two targets", db.CreateIncidentRelationRequest{Type: db.IncidentRelationCausedBy, RelatedIncidentID: testChangeID, ChangeEventID: testChangeID}, ErrInvalidIncidentRelation},
+		{"change not caused_by", db.CreateIncidentRelationRequest{Type: db.IncidentRelationDuplicateOf, ChangeEventID: testChangeID}, ErrInvalidIncidentRelation},
+		{"itself", db.CreateIncidentRelationRequest{Type: db.IncidentRelationRelated, RelatedIncidentID: testIncidentID}, ErrInvalidIncidentRelation},
+		{"not a UUID", db.CreateIncidentRelationRequest{Type: db.IncidentRelationRelated, RelatedIncidentID: "nope"}, ErrIncidentRelationTargetNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.CreateIncidentRelation(testIncidentID, "user-1", tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Other organization or unknown target: nothing is inserted
+	mock.ExpectQuery(`INSERT INTO incident_relations`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := s.CreateIncidentRelation(testIncidentID, "user-1", db.CreateIncidentRelationRequest{
+		Type: db.IncidentRelationRelated, RelatedIncidentID: testChangeID,
+	}); !errors.Is(err, ErrIncidentRelationTargetNotFound) {
+		t.Errorf("error = %v, want ErrIncidentRelationTargetNotFound", err)
+	}
+
+	// Already linked, in either direction
+	mock.ExpectQuery(`INSERT INTO incident_relations`).WillReturnError(&pq.Error{Code: "23505"})
+	if _, err := s.CreateIncidentRelation(testIncidentID, "user-1", db.CreateIncidentRelationRequest{
+		Type: db.IncidentRelationDuplicateOf, RelatedIncidentID: testChangeID,
+	}); !errors.Is(err, ErrIncidentRelationExists) {
+		t.Errorf("error = %v, want ErrIncidentRelationExists", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetCausedByReport(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`WITH RECURSIVE caused`).WithArgs("org-1", testChangeID, nil, db.IncidentRelationMaxDepth).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "number", "title", "status", "severity", "depth", "created_at"}).
+			AddRow("inc-1", 41, "API 5xx", "resolved", "critical", 1, now).
+			AddRow("inc-2", 42, "Checkout latency", "resolved", "", 1, now).
+			AddRow("inc-3", 43, "Queue backlog", "triggered", "", 2, now))
+
+	s := &IncidentService{PG: pg}
+	report, err := s.GetCausedByReport("org-1", testChangeID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != 3 || report.DirectCount != 2 || report.Incidents[2].Reference != "INC-43" {
+		t.Errorf("report = %+v", report)
+	}
+
+	// Not a UUID: nothing can link to it
+	report, err = s.GetCausedByReport("org-1", "", "nope")
+	if err != nil || report.Count != 0 {
+		t.Errorf("report = %+v, err = %v", report, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrNotIncidentCommander               = errors.New("only the incident commander can change its notification settings")
+	ErrIncidentSettingsResolved           = errors.New("resolved incidents have no notification settings")
+	ErrInvalidIncidentNotificationSetting = errors.New("invalid notification setting")
+)
+
+// maxMutedIncidentUsers caps the users one incident can silence
+const maxMutedIncidentUsers = 100
+
+// GetIncidentNotificationSettings returns an incident's notification
+// overrides, or the defaults when its commander hasn't changed any. The
+// commander is whoever claimed the incident, otherwise its assignee.
+func (s *IncidentService) GetIncidentNotificationSettings(incidentID string) (*db.IncidentNotificationSettings, error) {
+	settings, _, err := loadIncidentNotificationSettings(s.PG, incidentID)
+	return settings, err
+}
+
+func loadIncidentNotificationSettings(q queryRower, incidentID string) (*db.IncidentNotificationSettings, string, error) {
+	settings := &db.IncidentNotificationSettings{IncidentID: incidentID}
+	var status string
+	var chatUpdates, updatedBy sql.NullString
+	var statusUpdateMinutes sql.NullInt64
+	var muted pq.StringArray
+	var lastStatusUpdateAt, updatedAt sql.NullTime
+	err := q.QueryRow(`
+		SELECT i.status, COALESCE(i.claimed_by::text, i.assigned_to::text, ''),
+		       ns.chat_updates, ns.muted_user_ids, ns.status_update_minutes, ns.last_status_update_at,
+		       ns.updated_by::text, ns.updated_at
+		FROM incidents i
+		LEFT JOIN incident_notification_settings ns ON ns.incident_id = i.id
+		WHERE i.id = $1
+	`, incidentID).Scan(&status, &settings.CommanderID, &chatUpdates, &muted, &statusUpdateMinutes,
+		&lastStatusUpdateAt, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get incident notification settings: %w", err)
+	}
+
+	settings.ChatUpdates = db.ChatUpdatesAll
+	if chatUpdates.Valid {
+		settings.ChatUpdates = chatUpdates.String
+	}
+	settings.MutedUserIDs = []string(muted)
+	if settings.MutedUserIDs == nil {
+		settings.MutedUserIDs = []string{}
+	}
+	settings.StatusUpdateMinutes = int(statusUpdateMinutes.Int64)
+	settings.LastStatusUpdateAt = nullTimePtr(lastStatusUpdateAt)
+	settings.UpdatedBy = updatedBy.String
+	settings.UpdatedAt = nullTimePtr(updatedAt)
+	return settings, status, nil
+}
+
+// UpdateIncidentNotificationSettings changes the settings that are set in
+// req. Only the commander may change them; an incident nobody has taken yet
+// can be changed by anyone allowed to update it.
+func (s *IncidentService) UpdateIncidentNotificationSettings(incidentID, userID string, req db.UpdateIncidentNotificationSettingsRequest) (*db.IncidentNotificationSettings, error) {
+	settings, status, err := loadIncidentNotificationSettings(s.PG, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if status == db.IncidentStatusResolved {
+		return nil, ErrIncidentSettingsResolved
+	}
+	if settings.CommanderID != "" && settings.CommanderID != userID {
+		return nil, ErrNotIncidentCommander
+	}
+
+	if req.ChatUpdates != nil {
+		settings.ChatUpdates = *req.ChatUpdates
+	}
+	if req.MutedUserIDs != nil {
+		settings.MutedUserIDs = dedupeStrings(*req.MutedUserIDs)
+	}
+	if req.StatusUpdateMinutes != nil {
+		settings.StatusUpdateMinutes = *req.StatusUpdateMinutes
+	}
+	if err := validateIncidentNotificationSettings(settings); err != nil {
+		return nil, err
+	}
+
+	var updatedAt sql.NullTime
+	err = s.PG.QueryRow(`
+		INSERT INTO incident_notification_settings (incident_id, chat_updates, muted_user_ids, status_update_minutes, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (incident_id) DO UPDATE
+		SET chat_updates = EXCLUDED.chat_updates,
+		    muted_user_ids = EXCLUDED.muted_user_ids,
+		    status_update_minutes = EXCLUDED.status_update_minutes,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+		RETURNING updated_at
+	`, incidentID, settings.ChatUpdates, pq.Array(settings.MutedUserIDs), settings.StatusUpdateMinutes,
+		nullIfEmpty(userID)).Scan(&updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update incident notification settings: %w", err)
+	}
+	settings.UpdatedBy = userID
+	settings.UpdatedAt = nullTimePtr(updatedAt)
+
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventNotificationSettingsChanged, map[string]interface{}{
+		"chat_updates":          settings.ChatUpdates,
+		"muted_users":           len(settings.MutedUserIDs),
+		"status_update_minutes": settings.StatusUpdateMinutes,
+	}, userID); err != nil {
+		log.Printf("⚠️  Failed to record notification settings change for incident %s: %v", incidentID, err)
+	}
+	return settings, nil
+}
+
+func validateIncidentNotificationSettings(settings *db.IncidentNotificationSettings) error {
+	switch settings.ChatUpdates {
+	case db.ChatUpdatesAll, db.ChatUpdatesPagesOnly, db.ChatUpdatesNone:
+	default:
+		return fmt.Errorf("%w: chat_updates must be all, pages_only or none", ErrInvalidIncidentNotificationSetting)
+	}
+	if m := settings.StatusUpdateMinutes; m != 0 && (m < db.MinStatusUpdateMinutes || m > db.MaxStatusUpdateMinutes) {
+		return fmt.Errorf("%w: status_update_minutes must be 0 or between %d and %d",
+			ErrInvalidIncidentNotificationSetting, db.MinStatusUpdateMinutes, db.MaxStatusUpdateMinutes)
+	}
+	if len(settings.MutedUserIDs) > maxMutedIncidentUsers {
+		return fmt.Errorf("%w: at most %d muted users", ErrInvalidIncidentNotificationSetting, maxMutedIncidentUsers)
+	}
+	for _, id := range settings.MutedUserIDs {
+		if id == "" {
+			return fmt.Errorf("%w: muted_user_ids can't contain empty IDs", ErrInvalidIncidentNotificationSetting)
+		}
+		if id == settings.CommanderID {
+			return fmt.Errorf("%w: the commander can't mute themselves", ErrInvalidIncidentNotificationSetting)
+		}
+	}
+	return nil
+}
+
+func dedupeStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// SendIncidentStatusUpdates posts a status update to the chat channels of
+// every open incident whose commander asked for them and whose interval has
+// passed. Claiming the due rows and stamping them happens in one statement,
+// so concurrent workers don't post the same update twice.
+func (s *IncidentService) SendIncidentStatusUpdates() (int, error) {
+	rows, err := s.PG.Query(`
+		UPDATE incident_notification_settings ns
+		SET last_status_update_at = NOW()
+		FROM incidents i
+		WHERE i.id = ns.incident_id
+		  AND i.status != $1
+		  AND ns.status_update_minutes > 0
+		  AND COALESCE(ns.last_status_update_at, ns.updated_at) + make_interval(mins => ns.status_update_minutes) <= NOW()
+		RETURNING ns.incident_id
+	`, db.IncidentStatusResolved)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due status updates: %w", err)
+	}
+	var due []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan due status update: %w", err)
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, id := range due {
+		if err := EnqueueChatNotification(s.PG, "", id, "status_update"); err != nil {
+			log.Printf("⚠️  Failed to queue status update for incident %s: %v", id, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var incidentSettingsColumns = []string{"status", "commander", "chat_updates", "muted_user_ids", "status_update_minutes",
+	"last_status_update_at", "updated_by", "updated_at"}
+
+func TestGetIncidentNotificationSettingsDefaults(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`LEFT JOIN incident_notification_settings ns`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(incidentSettingsColumns).
+			AddRow(db.IncidentStatusTriggered, "alice", nil, nil, nil, nil, nil, nil))
+
+	settings, err := NewIncidentService(pg, nil).GetIncidentNotificationSettings("inc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ChatUpdates != db.ChatUpdatesAll || settings.StatusUpdateMinutes != 0 || len(settings.MutedUserIDs) != 0 {
+		t.Errorf("expected defaults, got %+v", settings)
+	}
+	if settings.CommanderID != "alice" || settings.UpdatedAt != nil {
+		t.Errorf("unexpected commander or update time: %+v", settings)
+	}
+}
+
+func TestUpdateIncidentNotificationSettingsRequiresCommander(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`LEFT JOIN incident_notification_settings ns`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(incidentSettingsColumns).
+			AddRow(db.IncidentStatusAcknowledged, "alice", nil, nil, nil, nil, nil, nil))
+
+	minutes := 15
+	_, err = NewIncidentService(pg, nil).UpdateIncidentNotificationSettings("inc-1", "bob",
+		db.UpdateIncidentNotificationSettingsRequest{StatusUpdateMinutes: &minutes})
+	if !errors.Is(err, ErrNotIncidentCommander) {
+		t.Fatalf("expected ErrNotIncidentCommander, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateIncidentNotificationSettingsMergesAndSaves(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`LEFT JOIN incident_notification_settings ns`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(incidentSettingsColumns).
+			AddRow(db.IncidentStatusAcknowledged, "alice", db.ChatUpdatesPagesOnly, "{carol}", 30, nil, "alice", time.Now()))
+	mock.ExpectQuery(`INSERT INTO incident_notification_settings`).
+		WithArgs("inc-1", db.ChatUpdatesPagesOnly, `{"carol","dave"}`, 10, "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventNotificationSettingsChanged, sqlmock.AnyArg(), "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	minutes := 10
+	muted := []string{"carol", "dave", "carol"}
+	settings, err := NewIncidentService(pg, nil).UpdateIncidentNotificationSettings("inc-1", "alice",
+		db.UpdateIncidentNotificationSettingsRequest{StatusUpdateMinutes: &minutes, MutedUserIDs: &muted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ChatUpdates != db.ChatUpdatesPagesOnly || settings.StatusUpdateMinutes != 10 || len(settings.MutedUserIDs) != 2 {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateIncidentNotificationSettings(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings db.IncidentNotificationSettings
+		ok       bool
+	}{
+		{"defaults", db.IncidentNotificationSettings{ChatUpdates: db.ChatUpdatesAll}, true},
+		{"unknown chat mode", db.IncidentNotificationSettings{ChatUpdates: "some"}, false},
+		{"interval too short", db.IncidentNotificationSettings{ChatUpdates: db.ChatUpdatesAll, StatusUpdateMinutes: 1}, false},
+		{"interval ok", db.IncidentNotificationSettings{ChatUpdates: db.ChatUpdatesNone, StatusUpdateMinutes: 60}, true},
+		{"commander mutes self", db.IncidentNotificationSettings{ChatUpdates: db.ChatUpdatesAll, CommanderID: "alice",
+			MutedUserIDs: []string{"alice"}}, false},
+	}
+	for _, tc := range cases {
+		err := validateIncidentNotificationSettings(&tc.settings)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+}
+
+func TestSendIncidentStatusUpdatesQueuesDueIncidents(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`UPDATE incident_notification_settings ns`).WithArgs(db.IncidentStatusResolved).
+		WillReturnRows(sqlmock.NewRows([]string{"incident_id"}).AddRow("inc-1"))
+	mock.ExpectExec(`SELECT pgmq.send`).
+		WithArgs(chatNotificationQueue, sqlmock.AnyArg(), "inc-1", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sent, err := NewIncidentService(pg, nil).SendIncidentStatusUpdates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 {
+		t.Errorf("expected 1 status update, got %d", sent)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
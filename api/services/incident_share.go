@@ -0,0 +1,288 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrIncidentShareLinkNotFound = errors.New("share link not found")
+	ErrIncidentShareLinkExpired  = errors.New("share link has expired or been revoked")
+)
+
+// maxIncidentSummaryEvents caps the events read into one summary
+const maxIncidentSummaryEvents = 500
+
+const incidentShareLinkColumns = `
+	id, incident_id, COALESCE(label, ''), include_notes, COALESCE(created_by::text, ''), created_at,
+	expires_at, revoked_at, COALESCE(revoked_by::text, ''), view_count, last_viewed_at
+`
+
+func scanIncidentShareLink(row interface{ Scan(...interface{}) error }) (db.IncidentShareLink, error) {
+	var link db.IncidentShareLink
+	var revokedAt, lastViewedAt sql.NullTime
+	err := row.Scan(&link.ID, &link.IncidentID, &link.Label, &link.IncludeNotes, &link.CreatedBy, &link.CreatedAt,
+		&link.ExpiresAt, &revokedAt, &link.RevokedBy, &link.ViewCount, &lastViewedAt)
+	if err != nil {
+		return link, err
+	}
+	link.RevokedAt = nullTimePtr(revokedAt)
+	link.LastViewedAt = nullTimePtr(lastViewedAt)
+	return link, nil
+}
+
+// CreateShareLink issues a public link to the incident's read-only summary.
+// The raw token is only returned once.
+func (s *IncidentService) CreateShareLink(incidentID, createdBy string, req db.CreateIncidentShareLinkRequest) (*db.IncidentShareLink, error) {
+	hours := req.ExpiresInHours
+	if hours <= 0 {
+		hours = db.IncidentShareDefaultHours
+	}
+	if hours > db.IncidentShareMaxHours {
+		hours = db.IncidentShareMaxHours
+	}
+
+	raw, _, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	raw = "is_" + raw
+
+	link, err := scanIncidentShareLink(s.PG.QueryRow(`
+		INSERT INTO incident_share_links (incident_id, token_hash, label, include_notes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(hours => $6))
+		RETURNING `+incidentShareLinkColumns,
+		incidentID, hashInvitationToken(raw), nullIfEmpty(req.Label), req.IncludeNotes, nullIfEmpty(createdBy), hours))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+	link.Token = raw
+	return &link, nil
+}
+
+// IncidentShareURL is the web page a share link token opens
+func IncidentShareURL(token string) string {
+	return webBaseURL() + "/shared/incidents/" + url.PathEscape(token)
+}
+
+// ListShareLinks returns an incident's share links, newest first (without tokens)
+func (s *IncidentService) ListShareLinks(incidentID string) ([]db.IncidentShareLink, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+incidentShareLinkColumns+`
+		FROM incident_share_links
+		WHERE incident_id = $1
+		ORDER BY created_at DESC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []db.IncidentShareLink{}
+	for rows.Next() {
+		link, err := scanIncidentShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// RevokeShareLink stops a share link from working
+func (s *IncidentService) RevokeShareLink(incidentID, linkID, revokedBy string) error {
+	result, err := s.PG.Exec(`
+		UPDATE incident_share_links SET revoked_at = NOW(), revoked_by = $3
+		WHERE id = $1 AND incident_id = $2 AND revoked_at IS NULL
+	`, linkID, incidentID, nullIfEmpty(revokedBy))
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrIncidentShareLinkNotFound
+	}
+	return nil
+}
+
+// ListShareLinkViews returns a share link's access log, newest first
+func (s *IncidentService) ListShareLinkViews(incidentID, linkID string, limit int) ([]db.IncidentShareLinkView, error) {
+	rows, err := s.PG.Query(`
+		SELECT v.viewed_at, COALESCE(v.ip_address, ''), COALESCE(v.user_agent, '')
+		FROM incident_share_link_views v
+		JOIN incident_share_links l ON l.id = v.link_id
+		WHERE v.link_id = $1 AND l.incident_id = $2
+		ORDER BY v.viewed_at DESC
+		LIMIT $3
+	`, linkID, incidentID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share link views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []db.IncidentShareLinkView{}
+	for rows.Next() {
+		var view db.IncidentShareLinkView
+		if err := rows.Scan(&view.ViewedAt, &view.IPAddress, &view.UserAgent); err != nil {
+			return nil, fmt.Errorf("failed to scan share link view: %w", err)
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+// GetSharedIncidentSummary returns the summary behind a share link and logs
+// the view. Expired and revoked links return ErrIncidentShareLinkExpired.
+func (s *IncidentService) GetSharedIncidentSummary(token, ipAddress, userAgent string) (*db.IncidentSummary, error) {
+	var linkID, incidentID string
+	var includeNotes bool
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT id, incident_id, include_notes, expires_at, revoked_at
+		FROM incident_share_links
+		WHERE token_hash = $1
+	`, hashInvitationToken(token)).Scan(&linkID, &incidentID, &includeNotes, &expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentShareLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	if revokedAt.Valid || time.Now().After(expiresAt) {
+		return nil, ErrIncidentShareLinkExpired
+	}
+
+	summary, err := s.GetIncidentSummary(incidentID, includeNotes)
+	if err != nil {
+		return nil, err
+	}
+	summary.ExpiresAt = &expiresAt
+
+	if _, err := s.PG.Exec(`
+		WITH logged AS (
+			INSERT INTO incident_share_link_views (link_id, ip_address, user_agent) VALUES ($1, $2, $3)
+		)
+		UPDATE incident_share_links SET view_count = view_count + 1, last_viewed_at = NOW() WHERE id = $1
+	`, linkID, nullIfEmpty(ipAddress), nullIfEmpty(userAgent)); err != nil {
+		log.Printf("⚠️  Failed to log share link view: %v", err)
+	}
+	return summary, nil
+}
+
+// GetIncidentSummary builds the read-only, print-friendly summary of an
+// incident: its status, timeline highlights and, with includeNotes, its
+// notes as status updates
+func (s *IncidentService) GetIncidentSummary(incidentID string, includeNotes bool) (*db.IncidentSummary, error) {
+	summary := &db.IncidentSummary{
+		Timeline:    []db.IncidentSummaryEntry{},
+		Updates:     []db.IncidentSummaryEntry{},
+		GeneratedAt: time.Now(),
+	}
+	var orgID string
+	var acknowledgedAt, resolvedAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT i.title, i.status, COALESCE(i.workflow_state, ''), COALESCE(i.severity, ''), COALESCE(i.urgency, ''),
+		       COALESCE(sv.name, ''), i.created_at, i.acknowledged_at, i.resolved_at, COALESCE(i.organization_id::text, '')
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		WHERE i.id = $1
+	`, incidentID).Scan(&summary.Title, &summary.Status, &summary.WorkflowState, &summary.Severity, &summary.Urgency,
+		&summary.ServiceName, &summary.CreatedAt, &acknowledgedAt, &resolvedAt, &orgID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	summary.AcknowledgedAt = nullTimePtr(acknowledgedAt)
+	summary.ResolvedAt = nullTimePtr(resolvedAt)
+	summary.Display = orgDisplaySettingsOrDefault(s.PG, orgID)
+
+	rows, err := s.PG.Query(`
+		SELECT event_type, event_data, created_at
+		FROM incident_events
+		WHERE incident_id = $1
+		ORDER BY created_at ASC
+		LIMIT $2
+	`, incidentID, maxIncidentSummaryEvents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventType string
+		var raw []byte
+		var at time.Time
+		if err := rows.Scan(&eventType, &raw, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan incident event: %w", err)
+		}
+		data := map[string]interface{}{}
+		if len(raw) > 0 {
+			json.Unmarshal(raw, &data)
+		}
+		entry, isUpdate, ok := summarizeIncidentEvent(eventType, data, includeNotes)
+		if !ok {
+			continue
+		}
+		entry.At = at
+		if isUpdate {
+			summary.Updates = append(summary.Updates, entry)
+		} else {
+			summary.Timeline = append(summary.Timeline, entry)
+		}
+	}
+	return summary, rows.Err()
+}
+
+// summarizeIncidentEvent turns an incident event into a timeline highlight or,
+// for notes, a status update. Events that would reveal responders or
+// internals (assignments, pages, tasks) are left out.
+func summarizeIncidentEvent(eventType string, data map[string]interface{}, includeNotes bool) (db.IncidentSummaryEntry, bool, bool) {
+	entry := db.IncidentSummaryEntry{Type: eventType}
+	switch eventType {
+	case db.IncidentEventTriggered:
+		entry.Message = "Incident opened"
+	case db.IncidentEventAcknowledged:
+		entry.Message = "Acknowledged by the response team"
+	case db.IncidentEventEscalated:
+		entry.Message = "Escalated"
+		if level, ok := data["escalation_level"].(float64); ok && level > 0 {
+			entry.Message = fmt.Sprintf("Escalated to level %d", int(level))
+		}
+	case db.IncidentEventWorkflowStateChanged:
+		state, _ := data["to_name"].(string)
+		if state == "" {
+			state, _ = data["to"].(string)
+		}
+		if state == "" {
+			return entry, false, false
+		}
+		entry.Message = "Status changed to " + state
+	case db.IncidentEventUrgencyChanged:
+		urgency, _ := data["to_urgency"].(string)
+		if urgency == "" {
+			return entry, false, false
+		}
+		entry.Message = "Urgency changed to " + urgency
+	case db.IncidentEventResolved:
+		entry.Message = "Resolved"
+	case db.IncidentEventNoteAdded:
+		note, _ := data["note"].(string)
+		if !includeNotes || note == "" {
+			return entry, false, false
+		}
+		entry.Message = note
+		return entry, true, true
+	default:
+		return entry, false, false
+	}
+	return entry, false, true
+}
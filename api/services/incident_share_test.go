@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestSummarizeIncidentEvent(t *testing.T) {
+	tests := []struct {
+		eventType    string
+		data         map[string]interface{}
+		includeNotes bool
+		message      string
+		isUpdate     bool
+		ok           bool
+	}{
+		{db.IncidentEventTriggered, nil, false, "Incident opened", false, true},
+		{db.IncidentEventEscalated, map[string]interface{}{"escalation_level": float64(2)}, false, "Escalated to level 2", false, true},
+		{db.IncidentEventWorkflowStateChanged, map[string]interface{}{"to": "mitigated", "to_name": "Mitigated"}, false, "Status changed to Mitigated", false, true},
+		{db.IncidentEventNoteAdded, map[string]interface{}{"note": "Rolling back"}, true, "Rolling back", true, true},
+		{db.IncidentEventNoteAdded, map[string]interface{}{"note": "Rolling back"}, false, "", false, false},
+		{db.IncidentEventAssigned, map[string]interface{}{"assigned_to": "user-1"}, true, "", false, false},
+	}
+	for _, tt := range tests {
+		entry, isUpdate, ok := summarizeIncidentEvent(tt.eventType, tt.data, tt.includeNotes)
+		if ok != tt.ok || isUpdate != tt.isUpdate || (ok && entry.Message != tt.message) {
+			t.Errorf("%s: got (%q, %v, %v), want (%q, %v, %v)", tt.eventType, entry.Message, isUpdate, ok, tt.message, tt.isUpdate, tt.ok)
+		}
+	}
+}
+
+func TestGetSharedIncidentSummaryRejectsRevokedLink(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM incident_share_links`).WithArgs(hashInvitationToken("is_abc")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "incident_id", "include_notes", "expires_at", "revoked_at"}).
+			AddRow("link-1", "inc-1", false, time.Now().Add(time.Hour), time.Now()))
+
+	_, err = (&IncidentService{PG: pg}).GetSharedIncidentSummary("is_abc", "10.0.0.1", "curl")
+	if !errors.Is(err, ErrIncidentShareLinkExpired) {
+		t.Fatalf("expected ErrIncidentShareLinkExpired, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetSharedIncidentSummaryLogsView(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM incident_share_links`).WithArgs(hashInvitationToken("is_abc")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "incident_id", "include_notes", "expires_at", "revoked_at"}).
+			AddRow("link-1", "inc-1", false, now.Add(time.Hour), nil))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "status", "workflow_state", "severity", "urgency",
+			"service_name", "created_at", "acknowledged_at", "resolved_at", "organization_id"}).
+			AddRow("API down", "resolved", "", "critical", "high", "api", now.Add(-time.Hour), nil, now, ""))
+	mock.ExpectQuery(`FROM incident_events`).WithArgs("inc-1", maxIncidentSummaryEvents).
+		WillReturnRows(sqlmock.NewRows([]string{"event_type", "event_data", "created_at"}).
+			AddRow(db.IncidentEventTriggered, []byte(`{}`), now.Add(-time.Hour)).
+			AddRow(db.IncidentEventNoteAdded, []byte(`{"note":"internal only"}`), now.Add(-30*time.Minute)).
+			AddRow(db.IncidentEventResolved, []byte(`{}`), now))
+	mock.ExpectExec(`INSERT INTO incident_share_link_views`).WithArgs("link-1", "10.0.0.1", "curl").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	summary, err := (&IncidentService{PG: pg}).GetSharedIncidentSummary("is_abc", "10.0.0.1", "curl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Timeline) != 2 || len(summary.Updates) != 0 {
+		t.Errorf("notes should be left out of the summary, got timeline %+v updates %+v", summary.Timeline, summary.Updates)
+	}
+	if summary.ExpiresAt == nil || summary.Display.Timezone != "UTC" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
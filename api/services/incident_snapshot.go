@@ -0,0 +1,171 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var ErrInvalidIncidentSnapshot = errors.New("invalid incident snapshot")
+
+const defaultIncidentSnapshotTitle = "Chart snapshot"
+
+// incidentSnapshotImageURL is the public URL an uploaded snapshot is served
+// at. Chat apps fetch it without signing in, so the token is the only secret.
+func incidentSnapshotImageURL(token string) string {
+	return config.ExternalAPIURL() + "/snapshots/" + url.PathEscape(token)
+}
+
+// snapshotContentType sniffs an uploaded image, accepting only the formats
+// chat apps and mail clients render
+func snapshotContentType(image []byte) (string, bool) {
+	contentType := http.DetectContentType(image)
+	return contentType, containsString(db.IncidentSnapshotContentTypes, contentType)
+}
+
+// AttachIncidentSnapshot stores a chart snapshot an integration sent for an
+// incident: a link to the provider's render (imageURL) or the image itself.
+// The incident must belong to the integration's organization. Sending the
+// newest snapshot again returns it instead of adding a duplicate, since
+// alerts repeat.
+func (s *IncidentService) AttachIncidentSnapshot(incidentID, integrationID, userID, title, imageURL string, image []byte) (*db.IncidentArtifact, error) {
+	imageURL = strings.TrimSpace(imageURL)
+	if (imageURL == "") == (len(image) == 0) {
+		return nil, fmt.Errorf("%w: send either an image URL or an image", ErrInvalidIncidentSnapshot)
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = defaultIncidentSnapshotTitle
+	}
+
+	var sum [32]byte
+	var contentType interface{}
+	if imageURL != "" {
+		u, err := url.Parse(imageURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(imageURL) > 2048 {
+			return nil, fmt.Errorf("%w: image_url must be an http(s) URL", ErrInvalidIncidentSnapshot)
+		}
+		sum = sha256.Sum256([]byte(imageURL))
+	} else {
+		if len(image) > db.IncidentSnapshotMaxBytes {
+			return nil, fmt.Errorf("%w: images are limited to %d MB", ErrInvalidIncidentSnapshot, db.IncidentSnapshotMaxBytes>>20)
+		}
+		sniffed, ok := snapshotContentType(image)
+		if !ok {
+			return nil, fmt.Errorf("%w: images must be %s, got %s", ErrInvalidIncidentSnapshot,
+				strings.Join(db.IncidentSnapshotContentTypes, ", "), sniffed)
+		}
+		contentType = sniffed
+		sum = sha256.Sum256(image)
+	}
+	checksum := hex.EncodeToString(sum[:])
+
+	var latestID, latestChecksum string
+	err := s.PG.QueryRow(`
+		SELECT a.id, a.content_sha256
+		FROM incident_artifacts a
+		JOIN incidents i ON i.id = a.incident_id
+		JOIN integrations ig ON ig.id::text = $3 AND ig.organization_id = i.organization_id
+		WHERE a.incident_id = $1 AND a.kind = $2 AND a.expires_at > NOW()
+		ORDER BY a.created_at DESC LIMIT 1
+	`, incidentID, db.ArtifactKindSnapshot, integrationID).Scan(&latestID, &latestChecksum)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check snapshots: %w", err)
+	}
+	if latestChecksum == checksum {
+		return s.GetIncidentArtifact(incidentID, latestID)
+	}
+
+	var token interface{}
+	var imageData interface{}
+	if len(image) > 0 {
+		t, _, err := generateInvitationToken()
+		if err != nil {
+			return nil, err
+		}
+		token, imageData = t, image
+	}
+
+	var artifactID string
+	err = s.PG.QueryRow(`
+		INSERT INTO incident_artifacts (incident_id, kind, source, title, content, content_sha256, size_bytes,
+		                                image_url, image_data, image_content_type, image_token, integration_id,
+		                                created_by, expires_at)
+		SELECT i.id, $3, $4, $5, $6, $7, $8::int, $9, $10, $11, $12, ig.id, $13::uuid,
+		       NOW() + make_interval(secs => $14::float8)
+		FROM incidents i
+		JOIN integrations ig ON ig.id::text = $2 AND ig.organization_id = i.organization_id
+		WHERE i.id = $1
+		RETURNING id
+	`, incidentID, integrationID, db.ArtifactKindSnapshot, db.ArtifactSourceIntegration, title, imageURL, checksum, len(image),
+		nullIfEmpty(imageURL), imageData, contentType, token, nullIfEmpty(userID),
+		incidentArtifactRetention().Seconds()).Scan(&artifactID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventArtifactAttached, map[string]interface{}{
+		"artifact_id":    artifactID,
+		"kind":           db.ArtifactKindSnapshot,
+		"title":          title,
+		"source":         db.ArtifactSourceIntegration,
+		"integration_id": integrationID,
+	}, userID); err != nil {
+		log.Printf("⚠️  Failed to record snapshot on incident %s: %v", incidentID, err)
+	}
+
+	return s.GetIncidentArtifact(incidentID, artifactID)
+}
+
+// GetIncidentSnapshotImage returns an uploaded snapshot image and its content
+// type by the token in its public URL
+func (s *IncidentService) GetIncidentSnapshotImage(token string) ([]byte, string, error) {
+	var image []byte
+	var contentType string
+	err := s.PG.QueryRow(`
+		SELECT image_data, COALESCE(image_content_type, '')
+		FROM incident_artifacts
+		WHERE image_token = $1 AND image_data IS NOT NULL AND expires_at > NOW()
+	`, token).Scan(&image, &contentType)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrIncidentArtifactNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return image, contentType, nil
+}
+
+// latestIncidentSnapshotURL is where the incident's newest snapshot can be
+// viewed, for showing it in notifications. It is empty when there is none.
+func latestIncidentSnapshotURL(pg *sql.DB, incidentID string) string {
+	var imageURL, token string
+	err := pg.QueryRow(`
+		SELECT COALESCE(image_url, ''), COALESCE(image_token, '')
+		FROM incident_artifacts
+		WHERE incident_id = $1 AND kind = $2 AND expires_at > NOW()
+		ORDER BY created_at DESC LIMIT 1
+	`, incidentID, db.ArtifactKindSnapshot).Scan(&imageURL, &token)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("⚠️  Failed to get snapshot of incident %s: %v", incidentID, err)
+		}
+		return ""
+	}
+	if token != "" {
+		return incidentSnapshotImageURL(token)
+	}
+	return imageURL
+}
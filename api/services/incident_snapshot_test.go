@@ -0,0 +1,64 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestAttachIncidentSnapshotValidation(t *testing.T) {
+	s := &IncidentService{}
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	cases := []struct {
+		name     string
+		imageURL string
+		image    []byte
+	}{
+		{"neither", "", nil},
+		{"both", "https://grafana.example.com/render/d/abc.png", png},
+		{"not http", "javascript:alert(1)", nil},
+		{"not an image", "", []byte("<html><body>hi</body></html>")},
+		{"too large", "", append(png, make([]byte, db.IncidentSnapshotMaxBytes)...)},
+	}
+	for _, tc := range cases {
+		_, err := s.AttachIncidentSnapshot("inc-1", "ig-1", "", "cpu", tc.imageURL, tc.image)
+		if !errors.Is(err, ErrInvalidIncidentSnapshot) {
+			t.Errorf("%s: expected ErrInvalidIncidentSnapshot, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestAttachIncidentSnapshotSkipsRepeat(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	imageURL := "https://grafana.example.com/render/d/abc.png"
+	sum := sha256.Sum256([]byte(imageURL))
+	mock.ExpectQuery(`SELECT a.id, a.content_sha256`).WithArgs("inc-1", db.ArtifactKindSnapshot, "ig-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_sha256"}).AddRow("art-1", hex.EncodeToString(sum[:])))
+	mock.ExpectQuery(`FROM incident_artifacts a`).WithArgs("art-1", "inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "incident_id", "kind", "source", "title", "command", "sha", "size",
+			"truncated", "redactions", "session_id", "created_by", "created_by_name", "created_at", "expires_at", "image_url",
+			"image_token", "content"}).
+			AddRow("art-1", "inc-1", db.ArtifactKindSnapshot, db.ArtifactSourceIntegration, "cpu", "", "abc", 0,
+				false, 0, "", "", "", time.Now(), time.Now().Add(time.Hour), imageURL, "", imageURL))
+
+	artifact, err := NewIncidentService(pg, nil).AttachIncidentSnapshot("inc-1", "ig-1", "", "cpu", imageURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.ID != "art-1" || artifact.ImageURL != imageURL {
+		t.Errorf("unexpected artifact %+v", artifact)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrIncidentTaskNotFound = errors.New("task not found")
+	ErrInvalidTaskOrder     = errors.New("task_ids must list every task of the incident exactly once")
+)
+
+const incidentTaskColumns = `
+	t.id, t.incident_id, t.title, t.description, t.position,
+	t.assigned_to, COALESCE(ua.name, ua.email, ''), t.completed_at,
+	t.completed_by, COALESCE(uc.name, uc.email, ''), t.created_by, t.created_at, t.updated_at
+`
+
+const incidentTaskJoins = `
+	LEFT JOIN users ua ON ua.id = t.assigned_to
+	LEFT JOIN users uc ON uc.id = t.completed_by
+`
+
+func scanIncidentTask(row interface{ Scan(...interface{}) error }) (db.IncidentTask, error) {
+	var t db.IncidentTask
+	var assignedTo, completedBy, createdBy sql.NullString
+	var completedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.IncidentID, &t.Title, &t.Description, &t.Position,
+		&assignedTo, &t.AssignedToName, &completedAt,
+		&completedBy, &t.CompletedByName, &createdBy, &t.CreatedAt, &t.UpdatedAt)
+	t.AssignedTo = assignedTo.String
+	t.CompletedBy = completedBy.String
+	t.CreatedBy = createdBy.String
+	if completedAt.Valid {
+		t.Completed = true
+		t.CompletedAt = &completedAt.Time
+	}
+	return t, err
+}
+
+// CountOpenTasks returns the total and not-yet-completed number of tasks
+func CountOpenTasks(tasks []db.IncidentTask) (total, open int) {
+	for _, t := range tasks {
+		if !t.Completed {
+			open++
+		}
+	}
+	return len(tasks), open
+}
+
+// ListIncidentTasks returns an incident's checklist in order
+func (s *IncidentService) ListIncidentTasks(incidentID string) ([]db.IncidentTask, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+incidentTaskColumns+`
+		FROM incident_tasks t
+		`+incidentTaskJoins+`
+		WHERE t.incident_id = $1
+		ORDER BY t.position ASC, t.created_at ASC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incident tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []db.IncidentTask{}
+	for rows.Next() {
+		t, err := scanIncidentTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// GetIncidentTask returns one task, scoped to its incident
+func (s *IncidentService) GetIncidentTask(incidentID, taskID string) (*db.IncidentTask, error) {
+	t, err := scanIncidentTask(s.PG.QueryRow(`
+		SELECT `+incidentTaskColumns+`
+		FROM incident_tasks t
+		`+incidentTaskJoins+`
+		WHERE t.incident_id = $1 AND t.id = $2
+	`, incidentID, taskID))
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident task: %w", err)
+	}
+	return &t, nil
+}
+
+// CreateIncidentTask appends a task to the end of an incident's checklist
+func (s *IncidentService) CreateIncidentTask(incidentID, userID string, req db.CreateIncidentTaskRequest) (*db.IncidentTask, error) {
+	var taskID string
+	err := s.PG.QueryRow(`
+		INSERT INTO incident_tasks (incident_id, title, description, position, assigned_to, created_by)
+		SELECT $1, $2, $3, COALESCE(MAX(position) + 1, 0), $4, $5
+		FROM incident_tasks WHERE incident_id = $1
+		RETURNING id
+	`, incidentID, strings.TrimSpace(req.Title), req.Description, nullIfEmpty(req.AssignedTo), nullIfEmpty(userID)).Scan(&taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create incident task: %w", err)
+	}
+	return s.GetIncidentTask(incidentID, taskID)
+}
+
+// UpdateIncidentTask edits, assigns, completes or reopens a task. Completing
+// or reopening is recorded in the incident timeline.
+func (s *IncidentService) UpdateIncidentTask(incidentID, taskID, userID string, req db.UpdateIncidentTaskRequest) (*db.IncidentTask, error) {
+	current, err := s.GetIncidentTask(incidentID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := []string{"updated_at = NOW()"}
+	args := []interface{}{incidentID, taskID}
+	add := func(expr string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf(expr, len(args)))
+	}
+	if req.Title != nil {
+		add("title = $%d", strings.TrimSpace(*req.Title))
+	}
+	if req.Description != nil {
+		add("description = $%d", *req.Description)
+	}
+	if req.AssignedTo != nil {
+		add("assigned_to = $%d", nullIfEmpty(*req.AssignedTo))
+	}
+	completionChanged := req.Completed != nil && *req.Completed != current.Completed
+	if completionChanged {
+		if *req.Completed {
+			sets = append(sets, "completed_at = NOW()")
+			add("completed_by = $%d", nullIfEmpty(userID))
+		} else {
+			sets = append(sets, "completed_at = NULL", "completed_by = NULL")
+		}
+	}
+
+	result, err := s.PG.Exec(`
+		UPDATE incident_tasks SET `+strings.Join(sets, ", ")+`
+		WHERE incident_id = $1 AND id = $2
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update incident task: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrIncidentTaskNotFound
+	}
+
+	task, err := s.GetIncidentTask(incidentID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if completionChanged {
+		eventType := db.IncidentEventTaskReopened
+		if task.Completed {
+			eventType = db.IncidentEventTaskCompleted
+		}
+		eventData := map[string]interface{}{
+			"task_id":    task.ID,
+			"task_title": task.Title,
+		}
+		var userName string
+		if err := s.PG.QueryRow(`SELECT COALESCE(name, email, 'Unknown') FROM users WHERE id = $1`, userID).Scan(&userName); err == nil {
+			eventData["user_name"] = userName
+		}
+		if err := s.createIncidentEvent(incidentID, eventType, eventData, userID); err != nil {
+			return nil, fmt.Errorf("failed to record task event: %w", err)
+		}
+	}
+
+	return task, nil
+}
+
+// DeleteIncidentTask removes a task from an incident's checklist
+func (s *IncidentService) DeleteIncidentTask(incidentID, taskID string) error {
+	result, err := s.PG.Exec(`DELETE FROM incident_tasks WHERE incident_id = $1 AND id = $2`, incidentID, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to delete incident task: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrIncidentTaskNotFound
+	}
+	return nil
+}
+
+// ReorderIncidentTasks sets the checklist order. taskIDs must contain every
+// task of the incident exactly once.
+func (s *IncidentService) ReorderIncidentTasks(incidentID string, taskIDs []string) ([]db.IncidentTask, error) {
+	seen := make(map[string]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		if id == "" || seen[id] {
+			return nil, ErrInvalidTaskOrder
+		}
+		seen[id] = true
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var total int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM incident_tasks WHERE incident_id = $1`, incidentID).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count incident tasks: %w", err)
+	}
+	if total != len(taskIDs) {
+		return nil, ErrInvalidTaskOrder
+	}
+
+	result, err := tx.Exec(`
+		UPDATE incident_tasks t SET position = o.ord - 1, updated_at = NOW()
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+		WHERE t.id = o.id AND t.incident_id = $1
+	`, incidentID, pq.Array(taskIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reorder incident tasks: %w", err)
+	}
+	if n, _ := result.RowsAffected(); int(n) != len(taskIDs) {
+		return nil, ErrInvalidTaskOrder
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit task order: %w", err)
+	}
+	return s.ListIncidentTasks(incidentID)
+}
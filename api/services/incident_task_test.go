@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var incidentTaskRowColumns = []string{
+	"id", "incident_id", "title", "description", "position",
+	"assigned_to", "assigned_to_name", "completed_at",
+	"completed_by", "completed_by_name", "created_by", "created_at", "updated_at",
+}
+
+func TestUpdateIncidentTaskRecordsCompletion(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM incident_tasks t`).WithArgs("inc-1", "task-1").
+		WillReturnRows(sqlmock.NewRows(incidentTaskRowColumns).
+			AddRow("task-1", "inc-1", "Fail over DB", "", 0, nil, "", nil, nil, "", "user-2", now, now))
+	mock.ExpectExec(`UPDATE incident_tasks SET updated_at = NOW\(\), completed_at = NOW\(\), completed_by = \$3`).
+		WithArgs("inc-1", "task-1", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM incident_tasks t`).WithArgs("inc-1", "task-1").
+		WillReturnRows(sqlmock.NewRows(incidentTaskRowColumns).
+			AddRow("task-1", "inc-1", "Fail over DB", "", 0, nil, "", now, "user-1", "Dana", "user-2", now, now))
+	mock.ExpectQuery(`SELECT COALESCE\(name, email, 'Unknown'\) FROM users`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Dana"))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventTaskCompleted, sqlmock.AnyArg(), "user-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	service := &IncidentService{PG: pg}
+	completed := true
+	task, err := service.UpdateIncidentTask("inc-1", "task-1", "user-1", db.UpdateIncidentTaskRequest{Completed: &completed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !task.Completed || task.CompletedByName != "Dana" {
+		t.Errorf("unexpected task: %+v", task)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateIncidentTaskNoEventWithoutCompletionChange(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	rows := func(title string) *sqlmock.Rows {
+		return sqlmock.NewRows(incidentTaskRowColumns).
+			AddRow("task-1", "inc-1", title, "", 0, nil, "", now, "user-1", "Dana", "user-2", now, now)
+	}
+	mock.ExpectQuery(`FROM incident_tasks t`).WithArgs("inc-1", "task-1").WillReturnRows(rows("Fail over DB"))
+	mock.ExpectExec(`UPDATE incident_tasks SET updated_at = NOW\(\), title = \$3\s+WHERE`).
+		WithArgs("inc-1", "task-1", "Fail over primary DB").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM incident_tasks t`).WithArgs("inc-1", "task-1").WillReturnRows(rows("Fail over primary DB"))
+
+	service := &IncidentService{PG: pg}
+	title := " Fail over primary DB "
+	completed := true // already completed: not a change
+	if _, err := service.UpdateIncidentTask("inc-1", "task-1", "user-1",
+		db.UpdateIncidentTaskRequest{Title: &title, Completed: &completed}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReorderIncidentTasksRejectsPartialOrder(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	service := &IncidentService{PG: pg}
+	if _, err := service.ReorderIncidentTasks("inc-1", []string{"a", "a"}); !errors.Is(err, ErrInvalidTaskOrder) {
+		t.Errorf("duplicate ids: err = %v, want ErrInvalidTaskOrder", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM incident_tasks`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectRollback()
+	if _, err := service.ReorderIncidentTasks("inc-1", []string{"a", "b"}); !errors.Is(err, ErrInvalidTaskOrder) {
+		t.Errorf("missing task: err = %v, want ErrInvalidTaskOrder", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrWorkflowStateNotFound      = errors.New("workflow state not found")
+	ErrWorkflowStateExists        = errors.New("workflow state key already exists")
+	ErrInvalidWorkflowStateKey    = errors.New("workflow state key must be lowercase letters, digits or underscores and not a built-in status")
+	ErrInvalidWorkflowTransition  = errors.New("workflow transition not allowed")
+	ErrIncidentNotInWorkflowPhase = errors.New("incident must be acknowledged before entering a workflow state")
+)
+
+var workflowStateKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// isCanonicalIncidentStatus reports whether status is one of the built-in statuses
+func isCanonicalIncidentStatus(status string) bool {
+	switch status {
+	case db.IncidentStatusTriggered, db.IncidentStatusAcknowledged, db.IncidentStatusResolved:
+		return true
+	}
+	return false
+}
+
+func validateWorkflowStateKey(key string) error {
+	if !workflowStateKeyPattern.MatchString(key) || isCanonicalIncidentStatus(key) {
+		return ErrInvalidWorkflowStateKey
+	}
+	return nil
+}
+
+// validateAllowedFrom normalizes allowed_from; entries are "acknowledged" or custom state keys
+func validateAllowedFrom(allowedFrom []string) ([]string, error) {
+	out := []string{}
+	for _, from := range allowedFrom {
+		from = strings.TrimSpace(from)
+		if from == "" {
+			continue
+		}
+		if from != db.IncidentStatusAcknowledged {
+			if err := validateWorkflowStateKey(from); err != nil {
+				return nil, fmt.Errorf("allowed_from %q: %w", from, err)
+			}
+		}
+		out = append(out, from)
+	}
+	return out, nil
+}
+
+// checkWorkflowTransition decides whether an incident with the given canonical
+// status and current workflow state may move to target. A nil target definition
+// means target is not a known active state. Moving back to "acknowledged" is
+// always allowed; resolving goes through ResolveIncident.
+func checkWorkflowTransition(status, current, target string, targetDef *db.IncidentWorkflowState) error {
+	if status != db.IncidentStatusAcknowledged {
+		return ErrIncidentNotInWorkflowPhase
+	}
+	if target == db.IncidentStatusAcknowledged {
+		return nil
+	}
+	if targetDef == nil || !targetDef.IsActive {
+		return ErrWorkflowStateNotFound
+	}
+
+	from := current
+	if from == "" {
+		from = db.IncidentStatusAcknowledged
+	}
+	if from == target || len(targetDef.AllowedFrom) == 0 {
+		return nil
+	}
+	for _, allowed := range targetDef.AllowedFrom {
+		if allowed == from {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidWorkflowTransition, from, target)
+}
+
+const workflowStateSelect = `
+	SELECT id, organization_id, key, name, COALESCE(description, ''), COALESCE(color, ''),
+	       position, allowed_from, is_active, created_at, updated_at
+	FROM incident_workflow_states
+`
+
+func scanWorkflowState(row interface{ Scan(...interface{}) error }) (db.IncidentWorkflowState, error) {
+	var st db.IncidentWorkflowState
+	err := row.Scan(&st.ID, &st.OrganizationID, &st.Key, &st.Name, &st.Description, &st.Color,
+		&st.Position, pq.Array(&st.AllowedFrom), &st.IsActive, &st.CreatedAt, &st.UpdatedAt)
+	if st.AllowedFrom == nil {
+		st.AllowedFrom = []string{}
+	}
+	return st, err
+}
+
+// ListWorkflowStates returns the org's workflow states in display order
+func (s *IncidentService) ListWorkflowStates(orgID string, includeInactive bool) ([]db.IncidentWorkflowState, error) {
+	query := workflowStateSelect + ` WHERE organization_id = $1`
+	if !includeInactive {
+		query += ` AND is_active = TRUE`
+	}
+	query += ` ORDER BY position ASC, name ASC`
+
+	rows, err := s.PG.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow states: %w", err)
+	}
+	defer rows.Close()
+
+	states := []db.IncidentWorkflowState{}
+	for rows.Next() {
+		st, err := scanWorkflowState(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workflow state: %w", err)
+		}
+		states = append(states, st)
+	}
+	return states, rows.Err()
+}
+
+func (s *IncidentService) getWorkflowStateByKey(orgID, key string) (*db.IncidentWorkflowState, error) {
+	st, err := scanWorkflowState(s.PG.QueryRow(workflowStateSelect+` WHERE organization_id = $1 AND key = $2`, orgID, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// CreateWorkflowState defines a new workflow state for an org
+func (s *IncidentService) CreateWorkflowState(orgID string, req db.CreateIncidentWorkflowStateRequest) (*db.IncidentWorkflowState, error) {
+	key := strings.ToLower(strings.TrimSpace(req.Key))
+	if err := validateWorkflowStateKey(key); err != nil {
+		return nil, err
+	}
+	allowedFrom, err := validateAllowedFrom(req.AllowedFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := scanWorkflowState(s.PG.QueryRow(`
+		INSERT INTO incident_workflow_states (organization_id, key, name, description, color, position, allowed_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, organization_id, key, name, COALESCE(description, ''), COALESCE(color, ''),
+		          position, allowed_from, is_active, created_at, updated_at
+	`, orgID, key, req.Name, nullIfEmpty(req.Description), nullIfEmpty(req.Color), req.Position, pq.Array(allowedFrom)))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrWorkflowStateExists
+		}
+		return nil, fmt.Errorf("failed to create workflow state: %w", err)
+	}
+	return &st, nil
+}
+
+// UpdateWorkflowState changes a workflow state's display fields, transitions or active flag
+func (s *IncidentService) UpdateWorkflowState(orgID, stateID string, req db.UpdateIncidentWorkflowStateRequest) (*db.IncidentWorkflowState, error) {
+	query := "UPDATE incident_workflow_states SET updated_at = NOW()"
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		query += fmt.Sprintf(", name = $%d", argIndex)
+		args = append(args, *req.Name)
+		argIndex++
+	}
+	if req.Description != nil {
+		query += fmt.Sprintf(", description = $%d", argIndex)
+		args = append(args, nullIfEmpty(*req.Description))
+		argIndex++
+	}
+	if req.Color != nil {
+		query += fmt.Sprintf(", color = $%d", argIndex)
+		args = append(args, nullIfEmpty(*req.Color))
+		argIndex++
+	}
+	if req.Position != nil {
+		query += fmt.Sprintf(", position = $%d", argIndex)
+		args = append(args, *req.Position)
+		argIndex++
+	}
+	if req.AllowedFrom != nil {
+		allowedFrom, err := validateAllowedFrom(*req.AllowedFrom)
+		if err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(", allowed_from = $%d", argIndex)
+		args = append(args, pq.Array(allowedFrom))
+		argIndex++
+	}
+	if req.IsActive != nil {
+		query += fmt.Sprintf(", is_active = $%d", argIndex)
+		args = append(args, *req.IsActive)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(` WHERE id = $%d AND organization_id = $%d
+		RETURNING id, organization_id, key, name, COALESCE(description, ''), COALESCE(color, ''),
+		          position, allowed_from, is_active, created_at, updated_at`, argIndex, argIndex+1)
+	args = append(args, stateID, orgID)
+
+	st, err := scanWorkflowState(s.PG.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrWorkflowStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update workflow state: %w", err)
+	}
+	return &st, nil
+}
+
+// DeleteWorkflowState removes a workflow state. Open incidents still in that
+// state fall back to plain "acknowledged".
+func (s *IncidentService) DeleteWorkflowState(orgID, stateID string) error {
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var key string
+	err = tx.QueryRow(`DELETE FROM incident_workflow_states WHERE id = $1 AND organization_id = $2 RETURNING key`, stateID, orgID).Scan(&key)
+	if err == sql.ErrNoRows {
+		return ErrWorkflowStateNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete workflow state: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE incidents SET workflow_state = NULL, updated_at = NOW()
+		WHERE organization_id = $1 AND workflow_state = $2
+	`, orgID, key); err != nil {
+		return fmt.Errorf("failed to reset incidents in workflow state: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SetIncidentWorkflowState moves an acknowledged incident into a custom workflow
+// state (or back to "acknowledged"), enforcing the org's allowed transitions
+func (s *IncidentService) SetIncidentWorkflowState(incidentID, target, userID, note string) (*db.Incident, error) {
+	var incident db.Incident
+	var orgID, current sql.NullString
+	err := s.PG.QueryRow(`SELECT id, status, organization_id, workflow_state FROM incidents WHERE id = $1`, incidentID).
+		Scan(&incident.ID, &incident.Status, &orgID, &current)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+
+	target = strings.ToLower(strings.TrimSpace(target))
+	var targetDef *db.IncidentWorkflowState
+	if target != db.IncidentStatusAcknowledged {
+		if targetDef, err = s.getWorkflowStateByKey(orgID.String, target); err != nil {
+			return nil, fmt.Errorf("failed to get workflow state: %w", err)
+		}
+	}
+	if err := checkWorkflowTransition(incident.Status, current.String, target, targetDef); err != nil {
+		return nil, err
+	}
+
+	var newState interface{}
+	if target != db.IncidentStatusAcknowledged {
+		newState = target
+		incident.WorkflowState = target
+	}
+	// Guard on the state we validated against so concurrent transitions can't skip a rule
+	result, err := s.PG.Exec(`
+		UPDATE incidents SET workflow_state = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND workflow_state IS NOT DISTINCT FROM $4
+	`, newState, incidentID, db.IncidentStatusAcknowledged, nullIfEmpty(current.String))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set workflow state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInvalidWorkflowTransition
+	}
+
+	from := current.String
+	if from == "" {
+		from = db.IncidentStatusAcknowledged
+	}
+	eventData := map[string]interface{}{"from": from, "to": target}
+	if targetDef != nil {
+		eventData["to_name"] = targetDef.Name
+	}
+	if note != "" {
+		eventData["note"] = note
+	}
+	s.createIncidentEvent(incidentID, db.IncidentEventWorkflowStateChanged, eventData, userID)
+
+	return &incident, nil
+}
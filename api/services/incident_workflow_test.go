@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestCheckWorkflowTransition(t *testing.T) {
+	mitigating := &db.IncidentWorkflowState{Key: "mitigating", IsActive: true}
+	monitoring := &db.IncidentWorkflowState{Key: "monitoring", IsActive: true, AllowedFrom: []string{"mitigating"}}
+	retired := &db.IncidentWorkflowState{Key: "retired", IsActive: false}
+
+	tests := []struct {
+		name    string
+		status  string
+		current string
+		target  string
+		def     *db.IncidentWorkflowState
+		wantErr error
+	}{
+		{name: "acknowledged to unrestricted state", status: "acknowledged", target: "mitigating", def: mitigating},
+		{name: "allowed from listed state", status: "acknowledged", current: "mitigating", target: "monitoring", def: monitoring},
+		{name: "not allowed from acknowledged", status: "acknowledged", target: "monitoring", def: monitoring, wantErr: ErrInvalidWorkflowTransition},
+		{name: "same state is a no-op", status: "acknowledged", current: "monitoring", target: "monitoring", def: monitoring},
+		{name: "back to acknowledged", status: "acknowledged", current: "monitoring", target: "acknowledged"},
+		{name: "triggered incident", status: "triggered", target: "mitigating", def: mitigating, wantErr: ErrIncidentNotInWorkflowPhase},
+		{name: "resolved incident", status: "resolved", target: "acknowledged", wantErr: ErrIncidentNotInWorkflowPhase},
+		{name: "unknown state", status: "acknowledged", target: "nope", wantErr: ErrWorkflowStateNotFound},
+		{name: "inactive state", status: "acknowledged", target: "retired", def: retired, wantErr: ErrWorkflowStateNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWorkflowTransition(tt.status, tt.current, tt.target, tt.def)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("checkWorkflowTransition() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkWorkflowTransition() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWorkflowStateKey(t *testing.T) {
+	for _, key := range []string{"mitigating", "monitoring", "waiting_on_vendor", "phase2"} {
+		if err := validateWorkflowStateKey(key); err != nil {
+			t.Errorf("validateWorkflowStateKey(%q) = %v, want nil", key, err)
+		}
+	}
+	for _, key := range []string{"", "resolved", "acknowledged", "Mitigating", "2fast", "has space", "dash-ed"} {
+		if err := validateWorkflowStateKey(key); !errors.Is(err, ErrInvalidWorkflowStateKey) {
+			t.Errorf("validateWorkflowStateKey(%q) = %v, want ErrInvalidWorkflowStateKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// Ingest backpressure metrics, published on /internal/metrics
+var (
+	ingestBackpressureLevel = expvar.NewString("ingest_backpressure_level")
+	ingestQueueDepth        = expvar.NewInt("ingest_queue_depth")
+	ingestInFlight          = expvar.NewInt("ingest_in_flight")
+	ingestAlertsShed        = expvar.NewInt("ingest_alerts_shed")
+	ingestWebhooksRejected  = expvar.NewInt("ingest_webhooks_rejected")
+)
+
+const (
+	defaultIngestRetryAfter     = 30 * time.Second
+	defaultIngestSampleInterval = 5 * time.Second
+)
+
+// IngestBackpressureService tracks how backed up the notification queues and
+// webhook ingestion are, and decides which webhooks to refuse and which
+// alerts to shed. Thresholds are read from config on use, so a reload
+// applies them immediately.
+type IngestBackpressureService struct {
+	PG *sql.DB
+
+	mu        sync.RWMutex
+	depth     int64
+	queues    map[string]int64
+	sampledAt time.Time
+	sampleErr string
+
+	inFlight atomic.Int64
+	shed     atomic.Int64
+	rejected atomic.Int64
+}
+
+// NewIngestBackpressureService creates a new IngestBackpressureService
+func NewIngestBackpressureService(pg *sql.DB) *IngestBackpressureService {
+	ingestBackpressureLevel.Set(db.BackpressureNormal)
+	return &IngestBackpressureService{PG: pg, queues: map[string]int64{}}
+}
+
+// shedSeveritiesAt returns the severities shed at a queue depth: the lowest
+// one from shed_queue_depth, one more per equal step up to reject_queue_depth
+func shedSeveritiesAt(depth int64, cfg config.IngestBackpressureConfig) []string {
+	if cfg.ShedQueueDepth <= 0 || depth < int64(cfg.ShedQueueDepth) || len(cfg.ShedSeverities) == 0 {
+		return []string{}
+	}
+	n := len(cfg.ShedSeverities)
+	if cfg.RejectQueueDepth > cfg.ShedQueueDepth {
+		step := float64(cfg.RejectQueueDepth-cfg.ShedQueueDepth) / float64(n)
+		if steps := 1 + int(float64(depth-int64(cfg.ShedQueueDepth))/step); steps < n {
+			n = steps
+		}
+	}
+	return cfg.ShedSeverities[:n]
+}
+
+// backpressureLevel is the ingestion level at a queue depth
+func backpressureLevel(depth int64, cfg config.IngestBackpressureConfig) string {
+	switch {
+	case cfg.RejectQueueDepth > 0 && depth >= int64(cfg.RejectQueueDepth):
+		return db.BackpressureRejecting
+	case len(shedSeveritiesAt(depth, cfg)) > 0:
+		return db.BackpressureShedding
+	default:
+		return db.BackpressureNormal
+	}
+}
+
+func (s *IngestBackpressureService) queueDepth() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.depth
+}
+
+// Sample reads the depth of the notification queues. Queues that can't be
+// read are skipped; when none can, the last depth is kept.
+func (s *IngestBackpressureService) Sample() error {
+	queues := map[string]int64{}
+	var depth int64
+	var sampleErr error
+	for _, queue := range metricsExportQueues {
+		var length sql.NullInt64
+		if err := s.PG.QueryRow(`SELECT queue_length FROM pgmq.metrics($1)`, queue).Scan(&length); err != nil {
+			if sampleErr == nil {
+				sampleErr = fmt.Errorf("failed to read queue %s: %w", queue, err)
+			}
+			continue
+		}
+		queues[queue] = length.Int64
+		depth += length.Int64
+	}
+
+	s.mu.Lock()
+	if len(queues) > 0 {
+		s.depth, s.queues, s.sampledAt, s.sampleErr = depth, queues, time.Now(), ""
+	} else if sampleErr != nil {
+		s.sampleErr = sampleErr.Error()
+	}
+	depth = s.depth
+	s.mu.Unlock()
+
+	ingestQueueDepth.Set(depth)
+	ingestBackpressureLevel.Set(backpressureLevel(depth, config.Current().IngestBackpressure))
+	if len(queues) == 0 {
+		return sampleErr
+	}
+	return nil
+}
+
+// Run samples the queues every interval (sample_interval by default) for the
+// life of the process
+func (s *IngestBackpressureService) Run(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultIngestSampleInterval
+	}
+	previous := ""
+	for {
+		if err := s.Sample(); err != nil {
+			log.Printf("⚠️  Ingest backpressure: %v", err)
+		}
+		if level := backpressureLevel(s.queueDepth(), config.Current().IngestBackpressure); level != previous {
+			if previous != "" {
+				log.Printf("Ingest backpressure: %s -> %s (queue depth %d)", previous, level, s.queueDepth())
+			}
+			previous = level
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Admit decides whether a webhook request is processed now. Requests are
+// refused while the queues are past reject_queue_depth or max_in_flight
+// webhooks are already being processed. Admitted requests call release
+// when done.
+func (s *IngestBackpressureService) Admit() (release func(), ok bool) {
+	cfg := config.Current().IngestBackpressure
+	if backpressureLevel(s.queueDepth(), cfg) == db.BackpressureRejecting {
+		s.reject()
+		return nil, false
+	}
+	n := s.inFlight.Add(1)
+	if cfg.MaxInFlight > 0 && n > int64(cfg.MaxInFlight) {
+		s.inFlight.Add(-1)
+		s.reject()
+		return nil, false
+	}
+	ingestInFlight.Set(n)
+	return func() { ingestInFlight.Set(s.inFlight.Add(-1)) }, true
+}
+
+func (s *IngestBackpressureService) reject() {
+	s.rejected.Add(1)
+	ingestWebhooksRejected.Add(1)
+}
+
+// ShouldShed reports whether a firing alert of this severity is dropped at
+// the current queue depth, counting it if so
+func (s *IngestBackpressureService) ShouldShed(severity string) bool {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	for _, shed := range shedSeveritiesAt(s.queueDepth(), config.Current().IngestBackpressure) {
+		if strings.EqualFold(shed, severity) {
+			s.shed.Add(1)
+			ingestAlertsShed.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// RetryAfter is how long refused senders are asked to wait
+func (s *IngestBackpressureService) RetryAfter() time.Duration {
+	if retryAfter := config.Current().IngestBackpressure.RetryAfter; retryAfter > 0 {
+		return retryAfter
+	}
+	return defaultIngestRetryAfter
+}
+
+// State reports the current backpressure level, its inputs and counters
+func (s *IngestBackpressureService) State() db.IngestBackpressureState {
+	cfg := config.Current().IngestBackpressure
+	s.mu.RLock()
+	state := db.IngestBackpressureState{
+		QueueDepth:  s.depth,
+		Queues:      make(map[string]int64, len(s.queues)),
+		SampleError: s.sampleErr,
+	}
+	for queue, length := range s.queues {
+		state.Queues[queue] = length
+	}
+	if !s.sampledAt.IsZero() {
+		sampledAt := s.sampledAt
+		state.SampledAt = &sampledAt
+	}
+	s.mu.RUnlock()
+
+	state.Level = backpressureLevel(state.QueueDepth, cfg)
+	state.ShedSeverities = shedSeveritiesAt(state.QueueDepth, cfg)
+	state.InFlight = s.inFlight.Load()
+	state.ShedQueueDepth, state.RejectQueueDepth, state.MaxInFlight = cfg.ShedQueueDepth, cfg.RejectQueueDepth, cfg.MaxInFlight
+	state.ShedAlerts, state.RejectedRequests = s.shed.Load(), s.rejected.Load()
+	return state
+}
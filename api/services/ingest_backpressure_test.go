@@ -0,0 +1,86 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestShedSeveritiesAt(t *testing.T) {
+	cfg := config.IngestBackpressureConfig{
+		ShedQueueDepth:   5000,
+		RejectQueueDepth: 20000,
+		ShedSeverities:   []string{"info", "low", "warning"},
+	}
+	cases := []struct {
+		depth int64
+		shed  []string
+		level string
+	}{
+		{0, []string{}, db.BackpressureNormal},
+		{4999, []string{}, db.BackpressureNormal},
+		{5000, []string{"info"}, db.BackpressureShedding},
+		{10000, []string{"info", "low"}, db.BackpressureShedding},
+		{19999, []string{"info", "low", "warning"}, db.BackpressureShedding},
+		{20000, []string{"info", "low", "warning"}, db.BackpressureRejecting},
+	}
+	for _, tc := range cases {
+		if got := shedSeveritiesAt(tc.depth, cfg); !reflect.DeepEqual(got, tc.shed) {
+			t.Errorf("depth %d: expected %v shed, got %v", tc.depth, tc.shed, got)
+		}
+		if got := backpressureLevel(tc.depth, cfg); got != tc.level {
+			t.Errorf("depth %d: expected level %s, got %s", tc.depth, tc.level, got)
+		}
+	}
+
+	cfg.ShedQueueDepth = 0
+	if got := backpressureLevel(10000, cfg); got != db.BackpressureNormal {
+		t.Errorf("shedding disabled: expected normal, got %s", got)
+	}
+}
+
+func TestIngestBackpressureAdmit(t *testing.T) {
+	saved := config.Current().IngestBackpressure
+	defer config.Update(func(c *config.Config) { c.IngestBackpressure = saved })
+	config.Update(func(c *config.Config) {
+		c.IngestBackpressure = config.IngestBackpressureConfig{
+			ShedQueueDepth:   100,
+			RejectQueueDepth: 200,
+			ShedSeverities:   []string{"info"},
+			MaxInFlight:      1,
+			RetryAfter:       10 * time.Second,
+		}
+	})
+
+	s := NewIngestBackpressureService(nil)
+	release, ok := s.Admit()
+	if !ok {
+		t.Fatal("first request should be admitted")
+	}
+	if _, ok := s.Admit(); ok {
+		t.Error("second concurrent request should be refused past max_in_flight")
+	}
+	release()
+	if release, ok := s.Admit(); !ok {
+		t.Error("request should be admitted once the first finished")
+	} else {
+		release()
+	}
+
+	s.depth = 150
+	if !s.ShouldShed("INFO") || s.ShouldShed("critical") {
+		t.Error("only info alerts should be shed at depth 150")
+	}
+	s.depth = 250
+	if _, ok := s.Admit(); ok {
+		t.Error("requests should be refused past reject_queue_depth")
+	}
+
+	state := s.State()
+	if state.Level != db.BackpressureRejecting || state.RejectedRequests != 2 || state.ShedAlerts != 1 || state.InFlight != 0 {
+		t.Errorf("unexpected state %+v", state)
+	}
+}
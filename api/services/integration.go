@@ -3,15 +3,25 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/internal/config"
 )
 
+// ErrInvalidServiceIntegration means a binding's environment or escalation
+// policy was rejected
+var ErrInvalidServiceIntegration = errors.New("invalid service integration")
+
+var environmentPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
 type IntegrationService struct {
 	PG *sql.DB
 }
@@ -39,6 +49,7 @@ func (s *IntegrationService) CreateIntegration(req db.CreateIntegrationRequest,
 		CreatedBy:      createdBy,
 		OrganizationID: req.OrganizationID, // ReBAC: MANDATORY tenant isolation
 		ProjectID:      req.ProjectID,      // ReBAC: OPTIONAL project scoping
+		TestMode:       req.TestMode,
 	}
 
 	// Set defaults
@@ -52,9 +63,27 @@ func (s *IntegrationService) CreateIntegration(req db.CreateIntegrationRequest,
 		integration.WebhookSecret = req.WebhookSecret
 	}
 
+	allowedCIDRs, err := NormalizeSourceCIDRs(req.AllowedSourceCIDRs)
+	if err != nil {
+		return integration, err
+	}
+	integration.AllowedSourceCIDRs = allowedCIDRs
+	integration.AllowedUserAgents = normalizeUserAgents(req.AllowedUserAgents)
+
+	if integration.SilenceAlert, err = normalizeSilenceAlert(req.SilenceAlert); err != nil {
+		return integration, err
+	}
+	silenceAlert, err := silenceAlertColumn(integration.SilenceAlert)
+	if err != nil {
+		return integration, err
+	}
+
 	if integration.Config == nil {
 		integration.Config = make(map[string]interface{})
 	}
+	if err := prepareIntegrationConfig(integration.Type, integration.Config); err != nil {
+		return integration, err
+	}
 
 	// Convert config to JSON
 	configJSON, err := json.Marshal(integration.Config)
@@ -63,23 +92,28 @@ func (s *IntegrationService) CreateIntegration(req db.CreateIntegrationRequest,
 	}
 
 	// Generate webhook URL based on environment
-	baseURL := config.App.WebhookAPIBaseURL
-	if baseURL == "" {
-		baseURL = "http://localhost:8080" // Default fallback
-	}
+	baseURL := config.WebhookBaseURL()
 	integration.WebhookURL = fmt.Sprintf("%s/webhook/%s/%s", baseURL, integration.Type, integration.ID)
 
+	webhookSecret, err := encryptColumn(integration.WebhookSecret)
+	if err != nil {
+		return integration, err
+	}
+
 	// Insert integration with webhook_url and ReBAC context
 	err = s.PG.QueryRow(`
 		INSERT INTO integrations (id, name, type, description, config, webhook_secret, webhook_url,
 		                         is_active, heartbeat_interval, created_at, updated_at, created_by,
-		                         organization_id, project_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		                         organization_id, project_id, allowed_source_cidrs, allowed_user_agents, test_mode,
+		                         silence_alert)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id
 	`, integration.ID, integration.Name, integration.Type, integration.Description,
-		configJSON, integration.WebhookSecret, integration.WebhookURL, integration.IsActive,
+		configJSON, webhookSecret, integration.WebhookURL, integration.IsActive,
 		integration.HeartbeatInterval, integration.CreatedAt, integration.UpdatedAt,
-		integration.CreatedBy, integration.OrganizationID, integration.ProjectID).Scan(&integration.ID)
+		integration.CreatedBy, integration.OrganizationID, integration.ProjectID,
+		pq.Array(integration.AllowedSourceCIDRs), pq.Array(integration.AllowedUserAgents),
+		integration.TestMode, silenceAlert).Scan(&integration.ID)
 
 	if err != nil {
 		return integration, fmt.Errorf("failed to create integration: %w", err)
@@ -94,6 +128,7 @@ func (s *IntegrationService) GetIntegration(integrationID string) (db.Integratio
 	var configJSON []byte
 	var lastHeartbeat sql.NullTime
 	var webhookURL sql.NullString
+	var silenceAlert []byte
 
 	err := s.PG.QueryRow(`
 		SELECT i.id, i.name, i.type, i.description, i.config, i.webhook_url,
@@ -101,7 +136,12 @@ func (s *IntegrationService) GetIntegration(integrationID string) (db.Integratio
 		       i.is_active, i.last_heartbeat, i.heartbeat_interval,
 		       i.created_at, i.updated_at, COALESCE(i.created_by, '') as created_by,
 		       get_integration_health_status(i.id) as health_status,
-		       COALESCE(si_count.services_count, 0) as services_count
+		       COALESCE(si_count.services_count, 0) as services_count,
+		       i.allowed_source_cidrs, i.allowed_user_agents,
+		       i.rejected_ip_count, i.rejected_user_agent_count,
+		       i.last_rejected_at, COALESCE(i.last_rejected_source, '') as last_rejected_source,
+		       i.payload_error_count, COALESCE(i.last_payload_error, '') as last_payload_error,
+		       i.last_payload_error_at, i.test_mode, i.silence_alert
 		FROM integrations i
 		LEFT JOIN (
 			SELECT integration_id, COUNT(*) as services_count
@@ -116,6 +156,11 @@ func (s *IntegrationService) GetIntegration(integrationID string) (db.Integratio
 		&integration.IsActive, &lastHeartbeat, &integration.HeartbeatInterval,
 		&integration.CreatedAt, &integration.UpdatedAt, &integration.CreatedBy,
 		&integration.HealthStatus, &integration.ServicesCount,
+		pq.Array(&integration.AllowedSourceCIDRs), pq.Array(&integration.AllowedUserAgents),
+		&integration.RejectedIPCount, &integration.RejectedUserAgentCount,
+		&integration.LastRejectedAt, &integration.LastRejectedSource,
+		&integration.PayloadErrorCount, &integration.LastPayloadError, &integration.LastPayloadErrorAt,
+		&integration.TestMode, &silenceAlert,
 	)
 
 	if err != nil {
@@ -124,6 +169,7 @@ func (s *IntegrationService) GetIntegration(integrationID string) (db.Integratio
 		}
 		return integration, fmt.Errorf("failed to get integration: %w", err)
 	}
+	decryptColumns(&integration.WebhookSecret)
 
 	// Handle nullable webhook_url
 	if webhookURL.Valid {
@@ -143,6 +189,7 @@ func (s *IntegrationService) GetIntegration(integrationID string) (db.Integratio
 	if lastHeartbeat.Valid {
 		integration.LastHeartbeat = &lastHeartbeat.Time
 	}
+	integration.SilenceAlert = parseSilenceAlert(silenceAlert)
 
 	return integration, nil
 }
@@ -155,7 +202,12 @@ func (s *IntegrationService) GetIntegrations(integType string, activeOnly bool)
 		       i.is_active, i.last_heartbeat, i.heartbeat_interval,
 		       i.created_at, i.updated_at, COALESCE(i.created_by, '') as created_by,
 		       get_integration_health_status(i.id) as health_status,
-		       COALESCE(si_count.services_count, 0) as services_count
+		       COALESCE(si_count.services_count, 0) as services_count,
+		       i.allowed_source_cidrs, i.allowed_user_agents,
+		       i.rejected_ip_count, i.rejected_user_agent_count,
+		       i.last_rejected_at, COALESCE(i.last_rejected_source, '') as last_rejected_source,
+		       i.payload_error_count, COALESCE(i.last_payload_error, '') as last_payload_error,
+		       i.last_payload_error_at, i.test_mode, i.silence_alert
 		FROM integrations i
 		LEFT JOIN (
 			SELECT integration_id, COUNT(*) as services_count
@@ -195,6 +247,7 @@ func (s *IntegrationService) GetIntegrations(integType string, activeOnly bool)
 		var configJSON []byte
 		var lastHeartbeat sql.NullTime
 		var webhookURL sql.NullString
+		var silenceAlert []byte
 
 		err := rows.Scan(
 			&integration.ID, &integration.Name, &integration.Type, &integration.Description,
@@ -202,10 +255,16 @@ func (s *IntegrationService) GetIntegrations(integType string, activeOnly bool)
 			&integration.IsActive, &lastHeartbeat, &integration.HeartbeatInterval,
 			&integration.CreatedAt, &integration.UpdatedAt, &integration.CreatedBy,
 			&integration.HealthStatus, &integration.ServicesCount,
+			pq.Array(&integration.AllowedSourceCIDRs), pq.Array(&integration.AllowedUserAgents),
+			&integration.RejectedIPCount, &integration.RejectedUserAgentCount,
+			&integration.LastRejectedAt, &integration.LastRejectedSource,
+			&integration.PayloadErrorCount, &integration.LastPayloadError, &integration.LastPayloadErrorAt,
+			&integration.TestMode, &silenceAlert,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan integration: %w", err)
 		}
+		decryptColumns(&integration.WebhookSecret)
 
 		// Handle nullable webhook_url
 		if webhookURL.Valid {
@@ -225,6 +284,7 @@ func (s *IntegrationService) GetIntegrations(integType string, activeOnly bool)
 		if lastHeartbeat.Valid {
 			integration.LastHeartbeat = &lastHeartbeat.Time
 		}
+		integration.SilenceAlert = parseSilenceAlert(silenceAlert)
 
 		integrations = append(integrations, integration)
 	}
@@ -257,7 +317,12 @@ func (s *IntegrationService) GetIntegrationsWithFilters(filters map[string]inter
 		       COALESCE(i.organization_id::text, '') as organization_id,
 		       COALESCE(i.project_id::text, '') as project_id,
 		       get_integration_health_status(i.id) as health_status,
-		       COALESCE(si_count.services_count, 0) as services_count
+		       COALESCE(si_count.services_count, 0) as services_count,
+		       i.allowed_source_cidrs, i.allowed_user_agents,
+		       i.rejected_ip_count, i.rejected_user_agent_count,
+		       i.last_rejected_at, COALESCE(i.last_rejected_source, '') as last_rejected_source,
+		       i.payload_error_count, COALESCE(i.last_payload_error, '') as last_payload_error,
+		       i.last_payload_error_at, i.test_mode, i.silence_alert
 		FROM integrations i
 		LEFT JOIN (
 			SELECT integration_id, COUNT(*) as services_count
@@ -320,6 +385,7 @@ func (s *IntegrationService) GetIntegrationsWithFilters(filters map[string]inter
 		var configJSON []byte
 		var lastHeartbeat sql.NullTime
 		var webhookURL sql.NullString
+		var silenceAlert []byte
 
 		err := rows.Scan(
 			&integration.ID, &integration.Name, &integration.Type, &integration.Description,
@@ -328,11 +394,17 @@ func (s *IntegrationService) GetIntegrationsWithFilters(filters map[string]inter
 			&integration.CreatedAt, &integration.UpdatedAt, &integration.CreatedBy,
 			&integration.OrganizationID, &integration.ProjectID,
 			&integration.HealthStatus, &integration.ServicesCount,
+			pq.Array(&integration.AllowedSourceCIDRs), pq.Array(&integration.AllowedUserAgents),
+			&integration.RejectedIPCount, &integration.RejectedUserAgentCount,
+			&integration.LastRejectedAt, &integration.LastRejectedSource,
+			&integration.PayloadErrorCount, &integration.LastPayloadError, &integration.LastPayloadErrorAt,
+			&integration.TestMode, &silenceAlert,
 		)
 		if err != nil {
 			log.Printf("failed to scan integration: %v", err)
 			continue
 		}
+		decryptColumns(&integration.WebhookSecret)
 
 		// Handle nullable webhook_url
 		if webhookURL.Valid {
@@ -352,6 +424,7 @@ func (s *IntegrationService) GetIntegrationsWithFilters(filters map[string]inter
 		if lastHeartbeat.Valid {
 			integration.LastHeartbeat = &lastHeartbeat.Time
 		}
+		integration.SilenceAlert = parseSilenceAlert(silenceAlert)
 
 		integrations = append(integrations, integration)
 	}
@@ -376,6 +449,9 @@ func (s *IntegrationService) UpdateIntegration(integrationID string, req db.Upda
 	}
 	if req.Config != nil {
 		integration.Config = req.Config
+		if err := prepareIntegrationConfig(integration.Type, integration.Config); err != nil {
+			return integration, err
+		}
 	}
 	if req.WebhookSecret != nil {
 		integration.WebhookSecret = *req.WebhookSecret
@@ -386,6 +462,28 @@ func (s *IntegrationService) UpdateIntegration(integrationID string, req db.Upda
 	if req.HeartbeatInterval != nil {
 		integration.HeartbeatInterval = *req.HeartbeatInterval
 	}
+	if req.AllowedSourceCIDRs != nil {
+		allowedCIDRs, err := NormalizeSourceCIDRs(*req.AllowedSourceCIDRs)
+		if err != nil {
+			return integration, err
+		}
+		integration.AllowedSourceCIDRs = allowedCIDRs
+	}
+	if req.AllowedUserAgents != nil {
+		integration.AllowedUserAgents = normalizeUserAgents(*req.AllowedUserAgents)
+	}
+	if req.TestMode != nil {
+		integration.TestMode = *req.TestMode
+	}
+	if req.SilenceAlert != nil {
+		if integration.SilenceAlert, err = normalizeSilenceAlert(req.SilenceAlert); err != nil {
+			return integration, err
+		}
+	}
+	silenceAlert, err := silenceAlertColumn(integration.SilenceAlert)
+	if err != nil {
+		return integration, err
+	}
 
 	integration.UpdatedAt = time.Now()
 
@@ -396,22 +494,27 @@ func (s *IntegrationService) UpdateIntegration(integrationID string, req db.Upda
 	}
 
 	// Recalculate webhook URL to ensure it matches current configuration
-	baseURL := config.App.WebhookAPIBaseURL
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
-	}
+	baseURL := config.WebhookBaseURL()
 	integration.WebhookURL = fmt.Sprintf("%s/webhook/%s/%s", baseURL, integration.Type, integration.ID)
 
+	webhookSecret, err := encryptColumn(integration.WebhookSecret)
+	if err != nil {
+		return integration, err
+	}
+
 	// Update the integration
 	_, err = s.PG.Exec(`
 		UPDATE integrations 
 		SET name = $2, description = $3, config = $4, webhook_secret = $5,
 		    is_active = $6, heartbeat_interval = $7, updated_at = $8,
-		    webhook_url = $9
+		    webhook_url = $9, allowed_source_cidrs = $10, allowed_user_agents = $11, test_mode = $12,
+		    silence_alert = $13
 		WHERE id = $1
 	`, integrationID, integration.Name, integration.Description, configJSON,
-		integration.WebhookSecret, integration.IsActive, integration.HeartbeatInterval,
-		integration.UpdatedAt, integration.WebhookURL)
+		webhookSecret, integration.IsActive, integration.HeartbeatInterval,
+		integration.UpdatedAt, integration.WebhookURL,
+		pq.Array(integration.AllowedSourceCIDRs), pq.Array(integration.AllowedUserAgents),
+		integration.TestMode, silenceAlert)
 
 	if err != nil {
 		return integration, fmt.Errorf("failed to update integration: %w", err)
@@ -420,8 +523,9 @@ func (s *IntegrationService) UpdateIntegration(integrationID string, req db.Upda
 	return integration, nil
 }
 
-// DeleteIntegration soft deletes an integration
-func (s *IntegrationService) DeleteIntegration(integrationID string) error {
+// DeleteIntegration deletes an integration, keeping a copy in the trash so it
+// can be restored with its service mappings
+func (s *IntegrationService) DeleteIntegration(integrationID, deletedBy string) error {
 	// Check if integration has active service mappings (only count if service is also active)
 	rows, err := s.PG.Query(`
 		SELECT s.name 
@@ -451,8 +555,36 @@ func (s *IntegrationService) DeleteIntegration(integrationID string) error {
 		return fmt.Errorf(`{"error": "cannot delete integration: active service mappings exist", "details": %s}`, string(detailsJSON))
 	}
 
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Snapshot the rows the delete removes or unlinks
+	var snapshot integrationTrashSnapshot
+	var integration, mappings []byte
+	err = tx.QueryRow(`
+		SELECT to_jsonb(i),
+		       COALESCE((SELECT jsonb_agg(si) FROM service_integrations si WHERE si.integration_id = i.id), '[]'),
+		       COALESCE((SELECT array_agg(md.id::text) FROM monitor_deployments md WHERE md.integration_id = i.id), '{}')
+		FROM integrations i
+		WHERE i.id = $1
+		FOR UPDATE OF i
+	`, integrationID).Scan(&integration, &mappings, pq.Array(&snapshot.MonitorDeployments))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("integration not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read integration: %w", err)
+	}
+	snapshot.Integration, snapshot.ServiceIntegrations = integration, mappings
+	if err := moveToTrash(tx, db.TrashResourceIntegration, integrationID, deletedBy, snapshot); err != nil {
+		return err
+	}
+
 	// Delete the integration (CASCADE will handle service_integrations)
-	result, err := s.PG.Exec("DELETE FROM integrations WHERE id = $1", integrationID)
+	result, err := tx.Exec("DELETE FROM integrations WHERE id = $1", integrationID)
 	if err != nil {
 		return fmt.Errorf("failed to delete integration: %w", err)
 	}
@@ -466,6 +598,9 @@ func (s *IntegrationService) DeleteIntegration(integrationID string) error {
 		return fmt.Errorf("integration not found")
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -488,20 +623,66 @@ func (s *IntegrationService) UpdateHeartbeat(integrationID string) error {
 	return nil
 }
 
+// prepareIntegrationConfig validates the config of integration types that
+// send data out and seals their credentials before the config is stored
+func prepareIntegrationConfig(integrationType string, config map[string]interface{}) error {
+	switch integrationType {
+	case db.FederationIntegrationType:
+		return prepareFederationConfig(config)
+	case db.SIEMIntegrationType:
+		return prepareSIEMConfig(config)
+	case db.ExternalOnCallIntegrationType:
+		return prepareExternalOnCallConfig(config)
+	}
+	return nil
+}
+
 // ===========================
 // SERVICE INTEGRATION OPERATIONS
 // ===========================
 
+// validateServiceIntegrationEnvironment normalizes a binding's environment
+// and checks its escalation policy belongs to the service's group
+func (s *IntegrationService) validateServiceIntegrationEnvironment(si *db.ServiceIntegration) error {
+	si.Environment = strings.ToLower(strings.TrimSpace(si.Environment))
+	if si.Environment != "" && !environmentPattern.MatchString(si.Environment) {
+		return fmt.Errorf("%w: environment must be lowercase letters, digits, - or _ (up to 32)", ErrInvalidServiceIntegration)
+	}
+	if si.EscalationPolicyID == "" {
+		return nil
+	}
+	var ok bool
+	err := s.PG.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM escalation_policies ep
+			JOIN services sv ON sv.group_id = ep.group_id
+			WHERE ep.id::text = $1 AND sv.id::text = $2
+		)
+	`, si.EscalationPolicyID, si.ServiceID).Scan(&ok)
+	if err != nil {
+		return fmt.Errorf("failed to check escalation policy: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: escalation policy must belong to the service's group", ErrInvalidServiceIntegration)
+	}
+	return nil
+}
+
 // CreateServiceIntegration creates a new service-integration mapping
 func (s *IntegrationService) CreateServiceIntegration(req db.CreateServiceIntegrationRequest, createdBy string) (db.ServiceIntegration, error) {
 	serviceIntegration := db.ServiceIntegration{
-		ID:            uuid.New().String(),
-		ServiceID:     req.ServiceID,
-		IntegrationID: req.IntegrationID,
-		IsActive:      true,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-		CreatedBy:     createdBy,
+		ID:                 uuid.New().String(),
+		ServiceID:          req.ServiceID,
+		IntegrationID:      req.IntegrationID,
+		IsActive:           true,
+		Environment:        req.Environment,
+		EscalationPolicyID: req.EscalationPolicyID,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+		CreatedBy:          createdBy,
+	}
+	if err := s.validateServiceIntegrationEnvironment(&serviceIntegration); err != nil {
+		return serviceIntegration, err
 	}
 
 	// Set routing conditions
@@ -527,11 +708,13 @@ func (s *IntegrationService) CreateServiceIntegration(req db.CreateServiceIntegr
 	// Insert service integration
 	_, err = s.PG.Exec(`
 		INSERT INTO service_integrations (id, service_id, integration_id, routing_conditions, 
-		                                 priority, is_active, created_at, updated_at, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		                                 priority, is_active, created_at, updated_at, created_by,
+		                                 environment, escalation_policy_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`, serviceIntegration.ID, serviceIntegration.ServiceID, serviceIntegration.IntegrationID,
 		conditionsJSON, serviceIntegration.Priority, serviceIntegration.IsActive,
-		serviceIntegration.CreatedAt, serviceIntegration.UpdatedAt, serviceIntegration.CreatedBy)
+		serviceIntegration.CreatedAt, serviceIntegration.UpdatedAt, serviceIntegration.CreatedBy,
+		nullIfEmpty(serviceIntegration.Environment), nullIfEmpty(serviceIntegration.EscalationPolicyID))
 
 	if err != nil {
 		log.Println("failed to create service integration: %w", err)
@@ -547,7 +730,8 @@ func (s *IntegrationService) GetServiceIntegrations(serviceID string) ([]db.Serv
 		SELECT si.id, si.service_id, si.integration_id, si.routing_conditions,
 		       si.priority, si.is_active, si.created_at, si.updated_at,
 		       COALESCE(si.created_by, '') as created_by,
-		       s.name as service_name, i.name as integration_name, i.type as integration_type
+		       s.name as service_name, i.name as integration_name, i.type as integration_type,
+		       COALESCE(si.environment, ''), COALESCE(si.escalation_policy_id::text, '')
 		FROM service_integrations si
 		JOIN services s ON si.service_id = s.id
 		JOIN integrations i ON si.integration_id = i.id
@@ -569,6 +753,7 @@ func (s *IntegrationService) GetServiceIntegrations(serviceID string) ([]db.Serv
 			&si.ID, &si.ServiceID, &si.IntegrationID, &conditionsJSON,
 			&si.Priority, &si.IsActive, &si.CreatedAt, &si.UpdatedAt, &si.CreatedBy,
 			&si.ServiceName, &si.IntegrationName, &si.IntegrationType,
+			&si.Environment, &si.EscalationPolicyID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan service integration: %w", err)
@@ -595,7 +780,8 @@ func (s *IntegrationService) GetIntegrationServices(integrationID string) ([]db.
 		SELECT si.id, si.service_id, si.integration_id, si.routing_conditions,
 		       si.priority, si.is_active, si.created_at, si.updated_at,
 		       COALESCE(si.created_by, '') as created_by,
-		       s.name as service_name, i.name as integration_name, i.type as integration_type
+		       s.name as service_name, i.name as integration_name, i.type as integration_type,
+		       COALESCE(si.environment, ''), COALESCE(si.escalation_policy_id::text, '')
 		FROM service_integrations si
 		JOIN services s ON si.service_id = s.id
 		JOIN integrations i ON si.integration_id = i.id
@@ -617,6 +803,7 @@ func (s *IntegrationService) GetIntegrationServices(integrationID string) ([]db.
 			&si.ID, &si.ServiceID, &si.IntegrationID, &conditionsJSON,
 			&si.Priority, &si.IsActive, &si.CreatedAt, &si.UpdatedAt, &si.CreatedBy,
 			&si.ServiceName, &si.IntegrationName, &si.IntegrationType,
+			&si.Environment, &si.EscalationPolicyID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan service integration: %w", err)
@@ -646,12 +833,14 @@ func (s *IntegrationService) UpdateServiceIntegration(serviceIntegrationID strin
 	err := s.PG.QueryRow(`
 		SELECT si.id, si.service_id, si.integration_id, si.routing_conditions,
 		       si.priority, si.is_active, si.created_at, si.updated_at,
-		       COALESCE(si.created_by, '') as created_by
+		       COALESCE(si.created_by, '') as created_by,
+		       COALESCE(si.environment, ''), COALESCE(si.escalation_policy_id::text, '')
 		FROM service_integrations si
 		WHERE si.id = $1
 	`, serviceIntegrationID).Scan(
 		&si.ID, &si.ServiceID, &si.IntegrationID, &conditionsJSON,
 		&si.Priority, &si.IsActive, &si.CreatedAt, &si.UpdatedAt, &si.CreatedBy,
+		&si.Environment, &si.EscalationPolicyID,
 	)
 
 	if err != nil {
@@ -678,6 +867,15 @@ func (s *IntegrationService) UpdateServiceIntegration(serviceIntegrationID strin
 	if req.IsActive != nil {
 		si.IsActive = *req.IsActive
 	}
+	if req.Environment != nil {
+		si.Environment = *req.Environment
+	}
+	if req.EscalationPolicyID != nil {
+		si.EscalationPolicyID = *req.EscalationPolicyID
+	}
+	if err := s.validateServiceIntegrationEnvironment(&si); err != nil {
+		return si, err
+	}
 
 	si.UpdatedAt = time.Now()
 
@@ -690,9 +888,11 @@ func (s *IntegrationService) UpdateServiceIntegration(serviceIntegrationID strin
 	// Update the service integration
 	_, err = s.PG.Exec(`
 		UPDATE service_integrations 
-		SET routing_conditions = $2, priority = $3, is_active = $4, updated_at = $5
+		SET routing_conditions = $2, priority = $3, is_active = $4, updated_at = $5,
+		    environment = $6, escalation_policy_id = $7
 		WHERE id = $1
-	`, serviceIntegrationID, updatedConditionsJSON, si.Priority, si.IsActive, si.UpdatedAt)
+	`, serviceIntegrationID, updatedConditionsJSON, si.Priority, si.IsActive, si.UpdatedAt,
+		nullIfEmpty(si.Environment), nullIfEmpty(si.EscalationPolicyID))
 
 	if err != nil {
 		return si, fmt.Errorf("failed to update service integration: %w", err)
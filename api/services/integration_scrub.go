@@ -0,0 +1,262 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidScrubPolicy = errors.New("invalid scrub policy")
+
+// Limits on a scrub policy, keeping the per-payload cost bounded
+const (
+	maxScrubPatterns      = 20
+	maxScrubPatternLength = 500
+	maxScrubFields        = 50
+)
+
+var (
+	scrubEmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	scrubIPv4Pattern  = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`)
+	// IPv6 candidates are confirmed with net.ParseIP so times like 12:30:45 survive
+	scrubIPv6Pattern  = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]`)
+	scrubPhonePattern = regexp.MustCompile(`\+\d[\d\s().-]{6,18}\d`)
+)
+
+// PayloadScrubber applies an integration's scrub policy to webhook payloads
+type PayloadScrubber struct {
+	rules    []string
+	patterns []*regexp.Regexp
+	fields   map[string]bool
+}
+
+// NewPayloadScrubber compiles a scrub policy, rejecting unknown rules and
+// invalid patterns
+func NewPayloadScrubber(policy db.IntegrationScrubPolicy) (*PayloadScrubber, error) {
+	p := &PayloadScrubber{fields: map[string]bool{}}
+	for _, rule := range policy.Rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if !containsString(db.ScrubRules, rule) {
+			return nil, fmt.Errorf("%w: unknown rule %q, expected one of %s", ErrInvalidScrubPolicy, rule,
+				strings.Join(db.ScrubRules, ", "))
+		}
+		if !containsString(p.rules, rule) {
+			p.rules = append(p.rules, rule)
+		}
+	}
+	if len(policy.Patterns) > maxScrubPatterns {
+		return nil, fmt.Errorf("%w: at most %d patterns", ErrInvalidScrubPolicy, maxScrubPatterns)
+	}
+	for _, pattern := range policy.Patterns {
+		if pattern == "" || len(pattern) > maxScrubPatternLength {
+			return nil, fmt.Errorf("%w: patterns must be 1 to %d characters", ErrInvalidScrubPolicy, maxScrubPatternLength)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pattern %q: %v", ErrInvalidScrubPolicy, pattern, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	if len(policy.Fields) > maxScrubFields {
+		return nil, fmt.Errorf("%w: at most %d fields", ErrInvalidScrubPolicy, maxScrubFields)
+	}
+	for _, field := range policy.Fields {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			p.fields[field] = true
+		}
+	}
+	return p, nil
+}
+
+// Empty reports whether the scrubber would never redact anything
+func (p *PayloadScrubber) Empty() bool {
+	return p == nil || (len(p.rules) == 0 && len(p.patterns) == 0 && len(p.fields) == 0)
+}
+
+// Scrub redacts a decoded JSON payload in place and returns how many values
+// each rule redacted
+func (p *PayloadScrubber) Scrub(payload map[string]interface{}) map[string]int {
+	counts := map[string]int{}
+	if !p.Empty() {
+		p.scrubMap(payload, counts)
+	}
+	return counts
+}
+
+func (p *PayloadScrubber) scrubMap(m map[string]interface{}, counts map[string]int) {
+	for key, value := range m {
+		if p.fields[strings.ToLower(key)] {
+			if value != nil && value != "" {
+				m[key] = "[REDACTED]"
+				counts[db.ScrubCountField]++
+			}
+			continue
+		}
+		m[key] = p.scrubValue(value, counts)
+	}
+}
+
+func (p *PayloadScrubber) scrubValue(value interface{}, counts map[string]int) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		p.scrubMap(v, counts)
+	case []interface{}:
+		for i := range v {
+			v[i] = p.scrubValue(v[i], counts)
+		}
+	case string:
+		return p.scrubString(v, counts)
+	}
+	return value
+}
+
+// scrubString runs the built-in rules, then the custom patterns, over s
+func (p *PayloadScrubber) scrubString(s string, counts map[string]int) string {
+	for _, rule := range p.rules {
+		var n int
+		switch rule {
+		case db.ScrubRuleToken:
+			s, n = redactArtifactContent(s)
+		case db.ScrubRuleEmail:
+			s, n = replaceCounting(scrubEmailPattern, s, "[REDACTED_EMAIL]", nil)
+		case db.ScrubRuleIP:
+			s, n = replaceCounting(scrubIPv4Pattern, s, "[REDACTED_IP]", nil)
+			var n6 int
+			s, n6 = replaceCounting(scrubIPv6Pattern, s, "[REDACTED_IP]", func(m string) bool {
+				return strings.Count(m, ":") >= 2 && net.ParseIP(m) != nil
+			})
+			n += n6
+		case db.ScrubRulePhone:
+			s, n = replaceCounting(scrubPhonePattern, s, "[REDACTED_PHONE]", nil)
+		}
+		if n > 0 {
+			counts[rule] += n
+		}
+	}
+	for _, re := range p.patterns {
+		var n int
+		if s, n = replaceCounting(re, s, "[REDACTED]", nil); n > 0 {
+			counts[db.ScrubCountPattern] += n
+		}
+	}
+	return s
+}
+
+// replaceCounting replaces the matches of re in s that pass keep (all when
+// nil) and returns how many it replaced
+func replaceCounting(re *regexp.Regexp, s, replacement string, keep func(string) bool) (string, int) {
+	n := 0
+	s = re.ReplaceAllStringFunc(s, func(m string) string {
+		if keep != nil && !keep(m) {
+			return m
+		}
+		n++
+		return replacement
+	})
+	return s, n
+}
+
+// GetPayloadScrubber loads an integration's scrub policy for the ingestion
+// path; it returns nil when scrubbing is off
+func (s *IntegrationService) GetPayloadScrubber(integrationID string) (*PayloadScrubber, error) {
+	var raw []byte
+	err := s.PG.QueryRow(`SELECT scrub_policy FROM integrations WHERE id = $1`, integrationID).Scan(&raw)
+	if err == sql.ErrNoRows || (err == nil && len(raw) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrub policy: %w", err)
+	}
+	var policy db.IntegrationScrubPolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return nil, fmt.Errorf("failed to decode scrub policy: %w", err)
+	}
+	return NewPayloadScrubber(policy)
+}
+
+// GetScrubAudit returns an integration's scrub policy and redaction counts
+func (s *IntegrationService) GetScrubAudit(integrationID string) (*db.IntegrationScrubAudit, error) {
+	audit := db.IntegrationScrubAudit{IntegrationID: integrationID, Redactions: map[string]int64{}}
+	var policy, redactions []byte
+	var lastScrubbedAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT scrub_policy, scrubbed_payload_count, scrub_redaction_counts, last_scrubbed_at
+		FROM integrations
+		WHERE id = $1
+	`, integrationID).Scan(&policy, &audit.ScrubbedPayloads, &redactions, &lastScrubbedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("integration not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrub policy: %w", err)
+	}
+	if len(policy) > 0 {
+		audit.Policy = &db.IntegrationScrubPolicy{}
+		json.Unmarshal(policy, audit.Policy)
+	}
+	if len(redactions) > 0 {
+		json.Unmarshal(redactions, &audit.Redactions)
+	}
+	if lastScrubbedAt.Valid {
+		audit.LastScrubbedAt = &lastScrubbedAt.Time
+	}
+	return &audit, nil
+}
+
+// SetScrubPolicy replaces an integration's scrub policy. A policy without
+// rules, patterns or fields turns scrubbing off. Redaction counts are kept.
+func (s *IntegrationService) SetScrubPolicy(integrationID string, policy db.IntegrationScrubPolicy) (*db.IntegrationScrubAudit, error) {
+	scrubber, err := NewPayloadScrubber(policy)
+	if err != nil {
+		return nil, err
+	}
+	var column interface{}
+	if !scrubber.Empty() {
+		normalized := db.IntegrationScrubPolicy{Rules: scrubber.rules, Patterns: policy.Patterns}
+		if normalized.Rules == nil {
+			normalized.Rules = []string{}
+		}
+		for field := range scrubber.fields {
+			normalized.Fields = append(normalized.Fields, field)
+		}
+		sort.Strings(normalized.Fields)
+		b, _ := json.Marshal(normalized)
+		column = string(b)
+	}
+
+	result, err := s.PG.Exec(`UPDATE integrations SET scrub_policy = $2::jsonb, updated_at = NOW() WHERE id = $1`,
+		integrationID, column)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set scrub policy: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("integration not found")
+	}
+	return s.GetScrubAudit(integrationID)
+}
+
+// RecordScrubRedactions adds one scrubbed payload's redactions to the
+// integration's audit counters
+func (s *IntegrationService) RecordScrubRedactions(integrationID string, counts map[string]int) error {
+	b, _ := json.Marshal(counts)
+	_, err := s.PG.Exec(`
+		UPDATE integrations
+		SET scrubbed_payload_count = scrubbed_payload_count + 1, last_scrubbed_at = NOW(),
+		    scrub_redaction_counts = (
+		        SELECT jsonb_object_agg(k, COALESCE((scrub_redaction_counts->>k)::bigint, 0) + COALESCE(($2::jsonb->>k)::bigint, 0))
+		        FROM jsonb_object_keys(scrub_redaction_counts || $2::jsonb) AS k
+		    )
+		WHERE id = $1
+	`, integrationID, string(b))
+	if err != nil {
+		return fmt.Errorf("failed to record scrub redactions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestPayloadScrubber(t *testing.T) {
+	scrubber, err := NewPayloadScrubber(db.IntegrationScrubPolicy{
+		Rules:    []string{"email", "IP", "phone", "token"},
+		Patterns: []string{`cust-\d{6}`},
+		Fields:   []string{"User"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := map[string]interface{}{
+		"title": "Login failures for jane.doe@example.com from 10.1.2.3",
+		"alerts": []interface{}{
+			map[string]interface{}{
+				"labels": map[string]interface{}{"instance": "2001:db8::1", "user": "jane", "tenant": "cust-123456"},
+				"annotations": map[string]interface{}{
+					"description": "Called +1 415 555 0100 at 12:30:45 with Authorization: Bearer abcdef123456789",
+				},
+				"value": 42.0,
+			},
+		},
+	}
+	counts := scrubber.Scrub(payload)
+
+	alert := payload["alerts"].([]interface{})[0].(map[string]interface{})
+	labels := alert["labels"].(map[string]interface{})
+	description := alert["annotations"].(map[string]interface{})["description"].(string)
+	for _, leaked := range []string{"jane.doe@example.com", "10.1.2.3"} {
+		if strings.Contains(payload["title"].(string), leaked) {
+			t.Errorf("title leaks %q: %s", leaked, payload["title"])
+		}
+	}
+	if labels["instance"] != "[REDACTED_IP]" || labels["user"] != "[REDACTED]" || labels["tenant"] != "[REDACTED]" {
+		t.Errorf("labels not scrubbed: %v", labels)
+	}
+	if strings.Contains(description, "415") || strings.Contains(description, "abcdef123456789") || !strings.Contains(description, "12:30:45") {
+		t.Errorf("description scrubbed wrongly: %s", description)
+	}
+	if alert["value"] != 42.0 {
+		t.Errorf("numbers should be kept, got %v", alert["value"])
+	}
+
+	want := map[string]int{db.ScrubRuleEmail: 1, db.ScrubRuleIP: 2, db.ScrubRulePhone: 1, db.ScrubRuleToken: 1,
+		db.ScrubCountField: 1, db.ScrubCountPattern: 1}
+	for rule, n := range want {
+		if counts[rule] != n {
+			t.Errorf("%s: expected %d redactions, got %d (%v)", rule, n, counts[rule], counts)
+		}
+	}
+}
+
+func TestNewPayloadScrubberRejectsInvalidPolicies(t *testing.T) {
+	for _, policy := range []db.IntegrationScrubPolicy{
+		{Rules: []string{"ssn"}},
+		{Patterns: []string{"("}},
+		{Patterns: []string{""}},
+	} {
+		if _, err := NewPayloadScrubber(policy); !errors.Is(err, ErrInvalidScrubPolicy) {
+			t.Errorf("%+v: expected ErrInvalidScrubPolicy, got %v", policy, err)
+		}
+	}
+
+	scrubber, err := NewPayloadScrubber(db.IntegrationScrubPolicy{Fields: []string{" "}})
+	if err != nil || !scrubber.Empty() {
+		t.Errorf("blank fields should leave scrubbing off, got %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidSilenceAlert = errors.New("invalid silence alert")
+
+// normalizeSilenceAlert validates a silence alert setting; after_minutes 0
+// turns it off
+func normalizeSilenceAlert(alert *db.IntegrationSilenceAlert) (*db.IntegrationSilenceAlert, error) {
+	if alert == nil || alert.AfterMinutes == 0 {
+		return nil, nil
+	}
+	if alert.AfterMinutes < 0 {
+		return nil, fmt.Errorf("%w: after_minutes must be positive", ErrInvalidSilenceAlert)
+	}
+	if alert.FromHour < 0 || alert.FromHour > 23 || alert.ToHour < 0 || alert.ToHour > 24 {
+		return nil, fmt.Errorf("%w: from_hour must be 0-23 and to_hour 0-24", ErrInvalidSilenceAlert)
+	}
+	for _, day := range alert.Days {
+		if day < 0 || day > 6 {
+			return nil, fmt.Errorf("%w: days must be 0 (Sunday) to 6 (Saturday)", ErrInvalidSilenceAlert)
+		}
+	}
+	if alert.Timezone == "" {
+		alert.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(alert.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSilenceAlert, alert.Timezone)
+	}
+	return alert, nil
+}
+
+// silenceAlertColumn is the silence_alert value to store; NULL when disabled
+func silenceAlertColumn(alert *db.IntegrationSilenceAlert) (interface{}, error) {
+	if alert == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(alert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal silence alert: %w", err)
+	}
+	return raw, nil
+}
+
+func parseSilenceAlert(raw []byte) *db.IntegrationSilenceAlert {
+	if len(raw) == 0 {
+		return nil
+	}
+	var alert db.IntegrationSilenceAlert
+	if err := json.Unmarshal(raw, &alert); err != nil || alert.AfterMinutes <= 0 {
+		return nil
+	}
+	return &alert
+}
+
+// silenceActiveAt reports whether the source is expected to send at t (in the
+// alert's timezone). A window wrapping past midnight counts each hour on the
+// day it falls on.
+func silenceActiveAt(alert *db.IntegrationSilenceAlert, t time.Time) bool {
+	if len(alert.Days) > 0 {
+		onDay := false
+		for _, day := range alert.Days {
+			if time.Weekday(day) == t.Weekday() {
+				onDay = true
+			}
+		}
+		if !onDay {
+			return false
+		}
+	}
+	hour := t.Hour()
+	switch {
+	case alert.FromHour == alert.ToHour%24:
+		return true
+	case alert.FromHour < alert.ToHour:
+		return hour >= alert.FromHour && hour < alert.ToHour
+	default:
+		return hour >= alert.FromHour || hour < alert.ToHour
+	}
+}
+
+// silenceActiveSince returns when the activity period containing now began,
+// or false when now is outside the activity hours. Silence before that is
+// expected, so it does not count. Lookback is capped at a week.
+func silenceActiveSince(alert *db.IntegrationSilenceAlert, now time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(alert.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	if !silenceActiveAt(alert, local) {
+		return time.Time{}, false
+	}
+	start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+	for i := 0; i < 7*24; i++ {
+		prev := start.Add(-time.Hour)
+		if !silenceActiveAt(alert, prev) {
+			break
+		}
+		start = prev
+	}
+	return start, true
+}
+
+// integrationSilentFor returns how long an integration has been silent during
+// its activity hours, or 0 when it is outside them
+func integrationSilentFor(alert *db.IntegrationSilenceAlert, lastHeartbeat *time.Time, createdAt, now time.Time) time.Duration {
+	since, active := silenceActiveSince(alert, now)
+	if !active {
+		return 0
+	}
+	if createdAt.After(since) {
+		since = createdAt
+	}
+	if lastHeartbeat != nil && lastHeartbeat.After(since) {
+		since = *lastHeartbeat
+	}
+	return now.Sub(since)
+}
+
+// silenceTarget is an integration with silence alerting and where its incident goes
+type silenceTarget struct {
+	integration    db.Integration
+	service        db.Service
+	openIncidentID string
+	openSince      time.Time
+}
+
+// EvaluateIntegrationSilence opens a "monitoring pipeline degraded" incident
+// for every integration that has sent no webhooks for longer than its silence
+// alert allows during its activity hours, routed through its highest-priority
+// service, and resolves the incident once webhooks arrive again. It returns
+// the number of incidents opened and resolved.
+func (s *IncidentService) EvaluateIntegrationSilence() (int, int, error) {
+	rows, err := s.PG.Query(`
+		SELECT i.id, i.name, i.type, i.silence_alert, i.last_heartbeat, i.created_at, i.test_mode,
+		       COALESCE(i.organization_id::text, ''), COALESCE(i.project_id::text, ''),
+		       COALESCE(sv.id::text, ''), COALESCE(sv.name, ''), COALESCE(sv.group_id::text, ''),
+		       COALESCE(sv.escalation_policy_id::text, ''), COALESCE(sv.project_id::text, ''),
+		       COALESCE(oi.id::text, ''), oi.created_at
+		FROM integrations i
+		LEFT JOIN LATERAL (
+			SELECT s.id, s.name, s.group_id, s.escalation_policy_id, s.project_id
+			FROM service_integrations si
+			JOIN services s ON s.id = si.service_id
+			WHERE si.integration_id = i.id AND si.is_active AND s.is_active
+			ORDER BY si.priority ASC, si.created_at ASC
+			LIMIT 1
+		) sv ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT inc.id, inc.created_at
+			FROM incidents inc
+			WHERE inc.incident_key = $1 || i.id::text AND inc.status IN ('triggered', 'acknowledged')
+			ORDER BY inc.created_at DESC
+			LIMIT 1
+		) oi ON TRUE
+		WHERE i.is_active AND i.silence_alert IS NOT NULL AND i.organization_id IS NOT NULL
+	`, db.IntegrationSilenceIncidentKeyPrefix)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list silence-alerted integrations: %w", err)
+	}
+
+	var targets []silenceTarget
+	for rows.Next() {
+		var t silenceTarget
+		var silenceAlert []byte
+		var lastHeartbeat, openSince sql.NullTime
+		if err := rows.Scan(&t.integration.ID, &t.integration.Name, &t.integration.Type, &silenceAlert,
+			&lastHeartbeat, &t.integration.CreatedAt, &t.integration.TestMode,
+			&t.integration.OrganizationID, &t.integration.ProjectID,
+			&t.service.ID, &t.service.Name, &t.service.GroupID, &t.service.EscalationPolicyID, &t.service.ProjectID,
+			&t.openIncidentID, &openSince); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan silence-alerted integration: %w", err)
+		}
+		t.integration.SilenceAlert = parseSilenceAlert(silenceAlert)
+		t.integration.LastHeartbeat = nullTimePtr(lastHeartbeat)
+		t.openSince = openSince.Time
+		if t.integration.SilenceAlert != nil {
+			targets = append(targets, t)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	opened, resolved := 0, 0
+	now := time.Now()
+	for _, t := range targets {
+		integration := t.integration
+		if t.openIncidentID != "" {
+			// Webhooks resumed after the incident was opened
+			if integration.LastHeartbeat != nil && integration.LastHeartbeat.After(t.openSince) {
+				note := fmt.Sprintf("Integration %s is receiving webhooks again", integration.Name)
+				if err := s.ResolveIncident(t.openIncidentID, db.GetSystemUserBySource(integration.Type), note, "integration_recovered"); err != nil {
+					log.Printf("Failed to resolve silence incident for integration %s: %v", integration.ID, err)
+					continue
+				}
+				resolved++
+			}
+			continue
+		}
+
+		silent := integrationSilentFor(integration.SilenceAlert, integration.LastHeartbeat, integration.CreatedAt, now)
+		threshold := time.Duration(integration.SilenceAlert.AfterMinutes) * time.Minute
+		if silent < threshold {
+			continue
+		}
+
+		// Org-level integrations file the incident under their service's project
+		projectID := integration.ProjectID
+		if projectID == "" {
+			projectID = t.service.ProjectID
+		}
+
+		lastSeen := "never"
+		if integration.LastHeartbeat != nil {
+			lastSeen = integration.LastHeartbeat.UTC().Format(time.RFC3339)
+		}
+		incident := &db.Incident{
+			Title: fmt.Sprintf("Monitoring pipeline degraded: %s has gone silent", integration.Name),
+			Description: fmt.Sprintf("No webhooks received from %s integration %q for %s during its expected activity hours (threshold %d minutes, last received %s). Alerts from this source may not be reaching SLAR.",
+				integration.Type, integration.Name, silent.Round(time.Minute), integration.SilenceAlert.AfterMinutes, lastSeen),
+			Severity:           "high",
+			Urgency:            db.IncidentUrgencyHigh,
+			Source:             db.IncidentSourceIntegrationHealth,
+			IncidentKey:        db.IntegrationSilenceIncidentKeyPrefix + integration.ID,
+			ServiceID:          t.service.ID,
+			GroupID:            t.service.GroupID,
+			EscalationPolicyID: t.service.EscalationPolicyID,
+			OrganizationID:     integration.OrganizationID,
+			ProjectID:          projectID,
+			IsTest:             integration.TestMode,
+			Labels: map[string]interface{}{
+				"integration_id":   integration.ID,
+				"integration_name": integration.Name,
+				"integration_type": integration.Type,
+				"last_heartbeat":   lastSeen,
+			},
+		}
+		if _, err := s.CreateIncident(incident); err != nil {
+			log.Printf("Failed to open silence incident for integration %s: %v", integration.ID, err)
+			continue
+		}
+		opened++
+	}
+	return opened, resolved, nil
+}
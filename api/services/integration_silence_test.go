@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestIntegrationSilentFor(t *testing.T) {
+	// Business hours, Monday to Friday, in UTC
+	alert := &db.IntegrationSilenceAlert{AfterMinutes: 30, FromHour: 9, ToHour: 17, Days: []int{1, 2, 3, 4, 5}, Timezone: "UTC"}
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	friday := time.Date(2026, 3, 6, 16, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 3, 9, 9, 20, 0, 0, time.UTC)
+
+	// Last heard Friday afternoon: the weekend doesn't count, Monday's clock starts at 9:00
+	if got := integrationSilentFor(alert, &friday, created, monday); got != 20*time.Minute {
+		t.Errorf("silent for %v on Monday morning, want 20m", got)
+	}
+	if got := integrationSilentFor(alert, &friday, created, monday.Add(2*time.Hour)); got != 2*time.Hour+20*time.Minute {
+		t.Errorf("silent for %v later on Monday, want 2h20m", got)
+	}
+	if got := integrationSilentFor(alert, &friday, created, time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC)); got != 0 {
+		t.Errorf("silent for %v on Saturday, want 0 outside activity hours", got)
+	}
+	recent := monday.Add(-5 * time.Minute)
+	if got := integrationSilentFor(alert, &recent, created, monday); got != 5*time.Minute {
+		t.Errorf("silent for %v after a recent webhook, want 5m", got)
+	}
+}
+
+func TestSilenceActiveAtWrapsMidnight(t *testing.T) {
+	alert := &db.IntegrationSilenceAlert{AfterMinutes: 10, FromHour: 22, ToHour: 6}
+	for hour, want := range map[int]bool{21: false, 22: true, 2: true, 6: false} {
+		if got := silenceActiveAt(alert, time.Date(2026, 3, 9, hour, 30, 0, 0, time.UTC)); got != want {
+			t.Errorf("active at %02d:30 = %v, want %v", hour, got, want)
+		}
+	}
+	if !silenceActiveAt(&db.IntegrationSilenceAlert{AfterMinutes: 10, FromHour: 0, ToHour: 24}, time.Now()) {
+		t.Error("0-24 should be active all day")
+	}
+}
+
+func TestNormalizeSilenceAlert(t *testing.T) {
+	if alert, err := normalizeSilenceAlert(&db.IntegrationSilenceAlert{AfterMinutes: 0, FromHour: 9}); alert != nil || err != nil {
+		t.Errorf("after_minutes 0 should disable, got %+v, %v", alert, err)
+	}
+	alert, err := normalizeSilenceAlert(&db.IntegrationSilenceAlert{AfterMinutes: 15})
+	if err != nil || alert.Timezone != "UTC" {
+		t.Errorf("expected UTC default, got %+v, %v", alert, err)
+	}
+	for _, bad := range []db.IntegrationSilenceAlert{
+		{AfterMinutes: -1},
+		{AfterMinutes: 5, FromHour: 24},
+		{AfterMinutes: 5, Days: []int{7}},
+		{AfterMinutes: 5, Timezone: "Mars/Olympus"},
+	} {
+		if _, err := normalizeSilenceAlert(&bad); !errors.Is(err, ErrInvalidSilenceAlert) {
+			t.Errorf("expected ErrInvalidSilenceAlert for %+v, got %v", bad, err)
+		}
+	}
+}
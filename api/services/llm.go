@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/llm"
+)
+
+var (
+	ErrLLMNotConfigured    = errors.New("no language model provider is configured")
+	ErrLLMBudgetExceeded   = errors.New("monthly token budget exhausted")
+	ErrInvalidLLMSettings  = errors.New("invalid LLM settings")
+	ErrLLMProviderNotFound = errors.New("unknown LLM provider")
+	ErrLLMRequestFailed    = errors.New("LLM request failed")
+)
+
+// LLMService runs language model completions for AI features: it resolves
+// the organization's provider, enforces its monthly token budget and meters
+// every call
+type LLMService struct {
+	PG *sql.DB
+}
+
+// NewLLMService creates a new LLMService
+func NewLLMService(pg *sql.DB) *LLMService {
+	return &LLMService{PG: pg}
+}
+
+// storedOrgLLMSettings is an org_llm_settings row with the key decrypted
+type storedOrgLLMSettings struct {
+	settings db.OrgLLMSettings
+	apiKey   string
+}
+
+func (s *LLMService) loadOrgSettings(orgID string) (storedOrgLLMSettings, error) {
+	stored := storedOrgLLMSettings{settings: db.OrgLLMSettings{OrganizationID: orgID, UsesDefault: true}}
+	var budget sql.NullInt64
+	var updatedAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT COALESCE(provider, ''), COALESCE(model, ''), COALESCE(api_key, ''), COALESCE(base_url, ''),
+		       COALESCE(api_version, ''), monthly_token_budget, COALESCE(updated_by::text, ''), updated_at
+		FROM org_llm_settings
+		WHERE organization_id = $1
+	`, orgID).Scan(&stored.settings.Provider, &stored.settings.Model, &stored.apiKey, &stored.settings.BaseURL,
+		&stored.settings.APIVersion, &budget, &stored.settings.UpdatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return stored, nil
+	}
+	if err != nil {
+		return stored, fmt.Errorf("failed to get LLM settings: %w", err)
+	}
+	decryptColumns(&stored.apiKey)
+	stored.settings.HasAPIKey = stored.apiKey != ""
+	stored.settings.UsesDefault = stored.settings.Provider == ""
+	if budget.Valid {
+		stored.settings.MonthlyTokenBudget = &budget.Int64
+	}
+	stored.settings.UpdatedAt = nullTimePtr(updatedAt)
+	return stored, nil
+}
+
+// GetOrgSettings returns an organization's LLM settings without its key
+func (s *LLMService) GetOrgSettings(orgID string) (db.OrgLLMSettings, error) {
+	stored, err := s.loadOrgSettings(orgID)
+	return stored.settings, err
+}
+
+// UpdateOrgSettings changes the settings that are set in req. A provider of
+// the organization's own is checked to have everything it requires.
+func (s *LLMService) UpdateOrgSettings(orgID, userID string, req db.UpdateOrgLLMSettingsRequest) (db.OrgLLMSettings, error) {
+	stored, err := s.loadOrgSettings(orgID)
+	if err != nil {
+		return stored.settings, err
+	}
+	settings := stored.settings
+	apiKey := stored.apiKey
+
+	if req.Provider != nil {
+		settings.Provider = strings.TrimSpace(*req.Provider)
+	}
+	if req.Model != nil {
+		settings.Model = strings.TrimSpace(*req.Model)
+	}
+	if req.APIKey != nil {
+		apiKey = strings.TrimSpace(*req.APIKey)
+	}
+	if req.BaseURL != nil {
+		settings.BaseURL = strings.TrimSpace(*req.BaseURL)
+	}
+	if req.APIVersion != nil {
+		settings.APIVersion = strings.TrimSpace(*req.APIVersion)
+	}
+	if req.MonthlyTokenBudget != nil {
+		if *req.MonthlyTokenBudget < 0 {
+			settings.MonthlyTokenBudget = nil
+		} else {
+			budget := *req.MonthlyTokenBudget
+			settings.MonthlyTokenBudget = &budget
+		}
+	}
+
+	if settings.Provider != "" {
+		provider, ok := llm.Lookup(settings.Provider)
+		if !ok {
+			return settings, fmt.Errorf("%w: %q", ErrLLMProviderNotFound, settings.Provider)
+		}
+		cfg := llm.Config{Provider: settings.Provider, Model: settings.Model, APIKey: apiKey,
+			BaseURL: settings.BaseURL, APIVersion: settings.APIVersion}
+		if err := llm.ValidateConfig(provider, cfg); err != nil {
+			return settings, fmt.Errorf("%w: %v", ErrInvalidLLMSettings, err)
+		}
+	}
+
+	sealedKey := ""
+	if apiKey != "" {
+		if sealedKey, err = encryptColumn(apiKey); err != nil {
+			return settings, err
+		}
+	}
+	var budget interface{}
+	if settings.MonthlyTokenBudget != nil {
+		budget = *settings.MonthlyTokenBudget
+	}
+	var updatedAt time.Time
+	err = s.PG.QueryRow(`
+		INSERT INTO org_llm_settings (organization_id, provider, model, api_key, base_url, api_version, monthly_token_budget, updated_by)
+		SELECT o.id, $2, $3, $4, $5, $6, $7::bigint, $8::uuid FROM organizations o WHERE o.id = $1
+		ON CONFLICT (organization_id) DO UPDATE
+		SET provider = EXCLUDED.provider, model = EXCLUDED.model, api_key = EXCLUDED.api_key,
+		    base_url = EXCLUDED.base_url, api_version = EXCLUDED.api_version,
+		    monthly_token_budget = EXCLUDED.monthly_token_budget, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at
+	`, orgID, nullIfEmpty(settings.Provider), nullIfEmpty(settings.Model), nullIfEmpty(sealedKey),
+		nullIfEmpty(settings.BaseURL), nullIfEmpty(settings.APIVersion), budget, nullIfEmpty(userID)).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, ErrOrgSettingsNotFound
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to update LLM settings: %w", err)
+	}
+
+	settings.HasAPIKey = apiKey != ""
+	settings.UsesDefault = settings.Provider == ""
+	settings.UpdatedBy = userID
+	settings.UpdatedAt = &updatedAt
+	return settings, nil
+}
+
+// resolveConfig returns the provider connection and monthly token budget that
+// apply to an organization: its own provider if it has one, otherwise the
+// instance default. An organization's budget applies either way.
+func (s *LLMService) resolveConfig(orgID string) (llm.Config, int64, error) {
+	stored, err := s.loadOrgSettings(orgID)
+	if err != nil {
+		return llm.Config{}, 0, err
+	}
+
+	budget := config.Current().LLM.MonthlyTokenBudget
+	if stored.settings.MonthlyTokenBudget != nil {
+		budget = *stored.settings.MonthlyTokenBudget
+	}
+
+	if !stored.settings.UsesDefault {
+		return llm.Config{
+			Provider:   stored.settings.Provider,
+			Model:      stored.settings.Model,
+			APIKey:     stored.apiKey,
+			BaseURL:    stored.settings.BaseURL,
+			APIVersion: stored.settings.APIVersion,
+		}, budget, nil
+	}
+
+	def := config.Current().LLM
+	if def.Provider == "" {
+		return llm.Config{}, budget, ErrLLMNotConfigured
+	}
+	return llm.Config{
+		Provider:   def.Provider,
+		Model:      def.Model,
+		APIKey:     def.APIKey,
+		BaseURL:    def.BaseURL,
+		APIVersion: def.APIVersion,
+	}, budget, nil
+}
+
+// usedThisMonth is the tokens an organization used this calendar month (UTC)
+func (s *LLMService) usedThisMonth(orgID string) (int64, error) {
+	var used int64
+	err := s.PG.QueryRow(`
+		SELECT COALESCE(SUM(input_tokens + output_tokens), 0)
+		FROM llm_usage
+		WHERE organization_id = $1 AND created_at >= date_trunc('month', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+	`, orgID).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+	return used, nil
+}
+
+// Complete runs a completion for an organization's AI feature. Calls are
+// refused once the organization's monthly budget is used up; a call already
+// under way may overshoot it. Failed calls are metered too, with no tokens.
+func (s *LLMService) Complete(ctx context.Context, orgID, userID, feature string, req llm.Request) (*llm.Response, error) {
+	cfg, budget, err := s.resolveConfig(orgID)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := llm.Lookup(cfg.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrLLMProviderNotFound, cfg.Provider)
+	}
+	if err := llm.ValidateConfig(provider, cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLLMNotConfigured, err)
+	}
+
+	if budget > 0 {
+		used, err := s.usedThisMonth(orgID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= budget {
+			return nil, ErrLLMBudgetExceeded
+		}
+	}
+
+	resp, callErr := provider.Complete(ctx, cfg, req)
+	usage, model := llm.Usage{}, cfg.Model
+	if callErr == nil {
+		usage, model = resp.Usage, resp.Model
+	}
+	if _, err := s.PG.Exec(`
+		INSERT INTO llm_usage (organization_id, provider, model, feature, input_tokens, output_tokens, succeeded, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, orgID, cfg.Provider, model, feature, usage.InputTokens, usage.OutputTokens, callErr == nil, nullIfEmpty(userID)); err != nil {
+		log.Printf("⚠️  Failed to meter LLM usage for org %s: %v", orgID, err)
+	}
+	if callErr != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrLLMRequestFailed, provider.Name(), callErr)
+	}
+	resp.Provider = cfg.Provider
+	return resp, nil
+}
+
+// GetUsage reports an organization's LLM usage between from and to, broken
+// down by provider, model and feature, and its standing against this month's
+// budget
+func (s *LLMService) GetUsage(orgID string, from, to time.Time) (*db.LLMUsageReport, error) {
+	report := &db.LLMUsageReport{OrganizationID: orgID, From: from, To: to, Breakdown: []db.LLMUsageBreakdown{}}
+
+	rows, err := s.PG.Query(`
+		SELECT provider, model, feature, COUNT(*), COUNT(*) FILTER (WHERE NOT succeeded),
+		       COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0)
+		FROM llm_usage
+		WHERE organization_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY provider, model, feature
+		ORDER BY SUM(input_tokens + output_tokens) DESC
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b db.LLMUsageBreakdown
+		if err := rows.Scan(&b.Provider, &b.Model, &b.Feature, &b.Requests, &b.Failed, &b.InputTokens, &b.OutputTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan LLM usage: %w", err)
+		}
+		report.Requests += b.Requests
+		report.InputTokens += b.InputTokens
+		report.OutputTokens += b.OutputTokens
+		report.Breakdown = append(report.Breakdown, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, budget, err := s.resolveConfig(orgID)
+	if err != nil && !errors.Is(err, ErrLLMNotConfigured) {
+		return nil, err
+	}
+	report.MonthlyTokenBudget = budget
+	if report.UsedThisMonth, err = s.usedThisMonth(orgID); err != nil {
+		return nil, err
+	}
+	if budget > 0 {
+		remaining := budget - report.UsedThisMonth
+		if remaining < 0 {
+			remaining = 0
+		}
+		report.RemainingThisMonth = &remaining
+	}
+	return report, nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/llm"
+)
+
+// Built-in LLM provider types
+const (
+	LLMProviderOpenAI      = "openai"
+	LLMProviderAzureOpenAI = "azure_openai"
+	LLMProviderAnthropic   = "anthropic"
+	LLMProviderOllama      = "ollama"
+)
+
+const (
+	defaultLLMMaxTokens    = 1024
+	anthropicAPIVersion    = "2023-06-01"
+	defaultOpenAIBaseURL   = "https://api.openai.com/v1"
+	defaultAnthropicURL    = "https://api.anthropic.com"
+	defaultOllamaBaseURL   = "http://localhost:11434"
+	llmProviderHTTPTimeout = 2 * time.Minute
+)
+
+func init() {
+	// Local models can take a while to answer on modest hardware
+	client := &http.Client{Timeout: llmProviderHTTPTimeout}
+	llm.Register(openAIProvider{client: client})
+	llm.Register(azureOpenAIProvider{client: client})
+	llm.Register(anthropicProvider{client: client})
+	llm.Register(ollamaProvider{client: client})
+}
+
+func llmMaxTokens(req llm.Request) int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return defaultLLMMaxTokens
+}
+
+// openAIMessages puts the system prompt first, as OpenAI-style APIs expect
+func openAIMessages(req llm.Request) []map[string]string {
+	messages := make([]map[string]string, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, map[string]string{"role": "system", "content": req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, map[string]string{"role": m.Role, "content": m.Content})
+	}
+	return messages
+}
+
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (r openAIChatResponse) toResponse(model string) (*llm.Response, error) {
+	if len(r.Choices) == 0 {
+		return nil, errors.New("response has no choices")
+	}
+	if r.Model != "" {
+		model = r.Model
+	}
+	return &llm.Response{
+		Text:  r.Choices[0].Message.Content,
+		Model: model,
+		Usage: llm.Usage{InputTokens: r.Usage.PromptTokens, OutputTokens: r.Usage.CompletionTokens},
+	}, nil
+}
+
+func openAIChatBody(model string, req llm.Request) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":      model,
+		"messages":   openAIMessages(req),
+		"max_tokens": llmMaxTokens(req),
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	return body
+}
+
+type openAIProvider struct{ client *http.Client }
+
+func (openAIProvider) Type() string { return LLMProviderOpenAI }
+func (openAIProvider) Name() string { return "OpenAI" }
+
+func (openAIProvider) Requirements() llm.Requirements {
+	return llm.Requirements{APIKey: true}
+}
+
+func (p openAIProvider) Complete(ctx context.Context, cfg llm.Config, req llm.Request) (*llm.Response, error) {
+	base := cfg.BaseURL
+	if base == "" {
+		base = defaultOpenAIBaseURL
+	}
+	var out openAIChatResponse
+	headers := map[string]string{"Authorization": "Bearer " + cfg.APIKey}
+	if err := postLLMJSON(ctx, p.client, strings.TrimRight(base, "/")+"/chat/completions", headers, openAIChatBody(cfg.Model, req), &out); err != nil {
+		return nil, err
+	}
+	return out.toResponse(cfg.Model)
+}
+
+// azureOpenAIProvider talks to an Azure OpenAI resource. Config.Model is the
+// deployment name and Config.BaseURL the resource endpoint.
+type azureOpenAIProvider struct{ client *http.Client }
+
+func (azureOpenAIProvider) Type() string { return LLMProviderAzureOpenAI }
+func (azureOpenAIProvider) Name() string { return "Azure OpenAI" }
+
+func (azureOpenAIProvider) Requirements() llm.Requirements {
+	return llm.Requirements{APIKey: true, BaseURL: true, APIVersion: true}
+}
+
+func (p azureOpenAIProvider) Complete(ctx context.Context, cfg llm.Config, req llm.Request) (*llm.Response, error) {
+	target := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		strings.TrimRight(cfg.BaseURL, "/"), url.PathEscape(cfg.Model), url.QueryEscape(cfg.APIVersion))
+	body := openAIChatBody(cfg.Model, req)
+	delete(body, "model") // the deployment picks the model
+
+	var out openAIChatResponse
+	if err := postLLMJSON(ctx, p.client, target, map[string]string{"api-key": cfg.APIKey}, body, &out); err != nil {
+		return nil, err
+	}
+	return out.toResponse(cfg.Model)
+}
+
+type anthropicProvider struct{ client *http.Client }
+
+func (anthropicProvider) Type() string { return LLMProviderAnthropic }
+func (anthropicProvider) Name() string { return "Anthropic" }
+
+func (anthropicProvider) Requirements() llm.Requirements {
+	return llm.Requirements{APIKey: true}
+}
+
+func (p anthropicProvider) Complete(ctx context.Context, cfg llm.Config, req llm.Request) (*llm.Response, error) {
+	base := cfg.BaseURL
+	if base == "" {
+		base = defaultAnthropicURL
+	}
+	body := map[string]interface{}{
+		"model":      cfg.Model,
+		"max_tokens": llmMaxTokens(req),
+		"messages":   req.Messages,
+	}
+	if req.System != "" {
+		body["system"] = req.System
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+
+	var out struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{"x-api-key": cfg.APIKey, "anthropic-version": anthropicAPIVersion}
+	if err := postLLMJSON(ctx, p.client, strings.TrimRight(base, "/")+"/v1/messages", headers, body, &out); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range out.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	model := cfg.Model
+	if out.Model != "" {
+		model = out.Model
+	}
+	return &llm.Response{
+		Text:  text.String(),
+		Model: model,
+		Usage: llm.Usage{InputTokens: out.Usage.InputTokens, OutputTokens: out.Usage.OutputTokens},
+	}, nil
+}
+
+// ollamaProvider runs completions on a local Ollama server. No key is needed.
+type ollamaProvider struct{ client *http.Client }
+
+func (ollamaProvider) Type() string { return LLMProviderOllama }
+func (ollamaProvider) Name() string { return "Ollama (local)" }
+
+func (ollamaProvider) Requirements() llm.Requirements {
+	return llm.Requirements{}
+}
+
+func (p ollamaProvider) Complete(ctx context.Context, cfg llm.Config, req llm.Request) (*llm.Response, error) {
+	base := cfg.BaseURL
+	if base == "" {
+		base = defaultOllamaBaseURL
+	}
+	options := map[string]interface{}{"num_predict": llmMaxTokens(req)}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	body := map[string]interface{}{
+		"model":    cfg.Model,
+		"messages": openAIMessages(req),
+		"stream":   false,
+		"options":  options,
+	}
+
+	var out struct {
+		Model   string `json:"model"`
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
+	}
+	var headers map[string]string
+	if cfg.APIKey != "" {
+		// For Ollama behind an authenticating proxy
+		headers = map[string]string{"Authorization": "Bearer " + cfg.APIKey}
+	}
+	if err := postLLMJSON(ctx, p.client, strings.TrimRight(base, "/")+"/api/chat", headers, body, &out); err != nil {
+		return nil, err
+	}
+	model := cfg.Model
+	if out.Model != "" {
+		model = out.Model
+	}
+	return &llm.Response{
+		Text:  out.Message.Content,
+		Model: model,
+		Usage: llm.Usage{InputTokens: out.PromptEvalCount, OutputTokens: out.EvalCount},
+	}, nil
+}
+
+// postLLMJSON posts payload and decodes a successful response into out.
+// Errors never include the request URL or headers, which may carry keys.
+func postLLMJSON(ctx context.Context, client *http.Client, target string, headers map[string]string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid request URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
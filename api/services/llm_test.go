@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/llm"
+)
+
+func TestLLMProvidersSpeakTheirAPIs(t *testing.T) {
+	cases := []struct {
+		provider string
+		path     string
+		header   string
+		response string
+		cfg      llm.Config
+	}{
+		{LLMProviderOpenAI, "/chat/completions", "Authorization",
+			`{"model":"gpt-4o-mini","choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`,
+			llm.Config{Model: "gpt-4o-mini", APIKey: "k"}},
+		{LLMProviderAzureOpenAI, "/openai/deployments/triage/chat/completions", "api-key",
+			`{"choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`,
+			llm.Config{Model: "triage", APIKey: "k", APIVersion: "2024-06-01"}},
+		{LLMProviderAnthropic, "/v1/messages", "x-api-key",
+			`{"model":"claude","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":10,"output_tokens":2}}`,
+			llm.Config{Model: "claude", APIKey: "k"}},
+		{LLMProviderOllama, "/api/chat", "",
+			`{"model":"llama3.1","message":{"content":"hi"},"prompt_eval_count":10,"eval_count":2}`,
+			llm.Config{Model: "llama3.1"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider, func(t *testing.T) {
+			var system string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tc.path {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if tc.header != "" && r.Header.Get(tc.header) == "" {
+					t.Errorf("missing %s header", tc.header)
+				}
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+				if s, ok := body["system"].(string); ok {
+					system = s
+				} else if msgs, ok := body["messages"].([]interface{}); ok && len(msgs) > 0 {
+					if first, _ := msgs[0].(map[string]interface{}); first["role"] == "system" {
+						system, _ = first["content"].(string)
+					}
+				}
+				w.Write([]byte(tc.response))
+			}))
+			defer srv.Close()
+
+			provider, ok := llm.Lookup(tc.provider)
+			if !ok {
+				t.Fatalf("provider %s not registered", tc.provider)
+			}
+			cfg := tc.cfg
+			cfg.BaseURL = srv.URL
+			resp, err := provider.Complete(context.Background(), cfg, llm.Request{
+				System:   "be brief",
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "hello"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text != "hi" || resp.Usage.Total() != 12 {
+				t.Errorf("unexpected response %+v", resp)
+			}
+			if system != "be brief" {
+				t.Errorf("system prompt not sent, got %q", system)
+			}
+		})
+	}
+}
+
+func TestLLMCompleteRefusesOverBudget(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	saved := config.Current().LLM
+	defer config.Update(func(c *config.Config) { c.LLM = saved })
+	config.Update(func(c *config.Config) {
+		c.LLM = config.LLMConfig{Provider: LLMProviderOllama, Model: "llama3.1", MonthlyTokenBudget: 1000}
+	})
+
+	mock.ExpectQuery(`FROM org_llm_settings`).WithArgs("org-1").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`FROM llm_usage`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"used"}).AddRow(int64(1000)))
+
+	_, err = NewLLMService(pg).Complete(context.Background(), "org-1", "alice", "summary", llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hello"}},
+	})
+	if !errors.Is(err, ErrLLMBudgetExceeded) {
+		t.Fatalf("expected ErrLLMBudgetExceeded, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLLMCompleteMetersUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"llama3.1","message":{"content":"hi"},"prompt_eval_count":7,"eval_count":3}`))
+	}))
+	defer srv.Close()
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	saved := config.Current().LLM
+	defer config.Update(func(c *config.Config) { c.LLM = saved })
+	config.Update(func(c *config.Config) { c.LLM = config.LLMConfig{} })
+
+	// The organization brings its own local model and has no budget
+	mock.ExpectQuery(`FROM org_llm_settings`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"provider", "model", "api_key", "base_url", "api_version", "budget", "updated_by", "updated_at"}).
+			AddRow(LLMProviderOllama, "llama3.1", "", srv.URL, "", nil, "", nil))
+	mock.ExpectExec(`INSERT INTO llm_usage`).
+		WithArgs("org-1", LLMProviderOllama, "llama3.1", "summary", 7, 3, true, "alice").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	resp, err := NewLLMService(pg).Complete(context.Background(), "org-1", "alice", "summary", llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hello"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "hi" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
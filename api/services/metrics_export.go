@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var ErrInvalidMetricsExportConfig = errors.New("invalid metrics export config")
+
+// metricsExportQueues are the pgmq queues whose depth is exported
+var metricsExportQueues = []string{
+	"incident_notifications", "incident_actions", "chat_notifications",
+	"whatsapp_notifications", "webpush_notifications", "voice_calls", "federation_events",
+}
+
+// datadogGauge is the Datadog v2 series type for gauges
+const datadogGauge = 3
+
+// MetricsExportService pushes SLAR's operational metrics (open incidents,
+// incident rate, MTTA/MTTR, queue depths) to Datadog or a Prometheus
+// remote-write endpoint
+type MetricsExportService struct {
+	PG     *sql.DB
+	cfg    config.MetricsExportConfig
+	client *http.Client
+}
+
+// NewMetricsExportService checks the exporter config; cfg.Provider must be set
+func NewMetricsExportService(pg *sql.DB, cfg config.MetricsExportConfig) (*MetricsExportService, error) {
+	switch cfg.Provider {
+	case db.MetricsExportProviderDatadog:
+		if cfg.DatadogAPIKey == "" {
+			return nil, fmt.Errorf("%w: datadog_api_key is required", ErrInvalidMetricsExportConfig)
+		}
+		if cfg.DatadogSite == "" {
+			cfg.DatadogSite = "datadoghq.com"
+		}
+	case db.MetricsExportProviderRemoteWrite:
+		u, err := url.Parse(cfg.RemoteWriteURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("%w: remote_write_url must be an http(s) URL", ErrInvalidMetricsExportConfig)
+		}
+	default:
+		return nil, fmt.Errorf("%w: provider must be %s or %s", ErrInvalidMetricsExportConfig,
+			db.MetricsExportProviderDatadog, db.MetricsExportProviderRemoteWrite)
+	}
+	return &MetricsExportService{
+		PG:     pg,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Export collects the current metrics and pushes them, returning how many
+// were sent
+func (s *MetricsExportService) Export() (int, error) {
+	now := time.Now()
+	metrics, err := s.CollectMetrics(now)
+	if err != nil {
+		return 0, err
+	}
+	if len(metrics) == 0 {
+		return 0, nil
+	}
+	return len(metrics), s.Push(metrics, now)
+}
+
+// CollectMetrics gathers per-organization incident metrics and queue depths.
+// Test and drill incidents are left out.
+func (s *MetricsExportService) CollectMetrics(now time.Time) ([]db.OperationalMetric, error) {
+	rateSince := now.Add(-db.MetricsExportRateWindowSeconds * time.Second)
+	mttSince := now.Add(-db.MetricsExportMTTWindowSeconds * time.Second)
+
+	rows, err := s.PG.Query(`
+		SELECT i.organization_id::text,
+		       COUNT(*) FILTER (WHERE i.status = 'triggered'),
+		       COUNT(*) FILTER (WHERE i.status = 'acknowledged'),
+		       COUNT(*) FILTER (WHERE i.created_at >= $1),
+		       AVG(EXTRACT(EPOCH FROM (i.acknowledged_at - i.created_at))) FILTER (WHERE i.acknowledged_at >= $2),
+		       AVG(EXTRACT(EPOCH FROM (i.resolved_at - i.created_at))) FILTER (WHERE i.resolved_at >= $2)
+		FROM incidents i
+		WHERE i.organization_id IS NOT NULL
+		  AND NOT COALESCE(i.is_test, false) AND i.drill_id IS NULL
+		  AND (i.status IN ('triggered', 'acknowledged') OR i.created_at >= $1
+		       OR i.acknowledged_at >= $2 OR i.resolved_at >= $2)
+		GROUP BY i.organization_id
+	`, rateSince, mttSince)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect incident metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var metrics []db.OperationalMetric
+	for rows.Next() {
+		var orgID string
+		var triggered, acknowledged, created int
+		var mtta, mttr sql.NullFloat64
+		if err := rows.Scan(&orgID, &triggered, &acknowledged, &created, &mtta, &mttr); err != nil {
+			return nil, fmt.Errorf("failed to scan incident metrics: %w", err)
+		}
+		org := map[string]string{"organization_id": orgID}
+		metrics = append(metrics,
+			db.OperationalMetric{Name: "incidents.open", Value: float64(triggered), Tags: withTag(org, "status", "triggered")},
+			db.OperationalMetric{Name: "incidents.open", Value: float64(acknowledged), Tags: withTag(org, "status", "acknowledged")},
+			db.OperationalMetric{Name: "incidents.created_last_hour", Value: float64(created), Tags: org},
+		)
+		if mtta.Valid {
+			metrics = append(metrics, db.OperationalMetric{Name: "incidents.mtta_seconds", Value: mtta.Float64, Tags: org})
+		}
+		if mttr.Valid {
+			metrics = append(metrics, db.OperationalMetric{Name: "incidents.mttr_seconds", Value: mttr.Float64, Tags: org})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, queue := range metricsExportQueues {
+		var length, oldestAge sql.NullFloat64
+		if err := s.PG.QueryRow(`SELECT queue_length, oldest_msg_age_sec FROM pgmq.metrics($1)`, queue).Scan(&length, &oldestAge); err != nil {
+			log.Printf("Metrics export: failed to get metrics for queue %s: %v", queue, err)
+			continue
+		}
+		tags := map[string]string{"queue": queue}
+		metrics = append(metrics, db.OperationalMetric{Name: "queue.depth", Value: length.Float64, Tags: tags})
+		metrics = append(metrics, db.OperationalMetric{Name: "queue.oldest_message_age_seconds", Value: oldestAge.Float64, Tags: tags})
+	}
+	return metrics, nil
+}
+
+func withTag(tags map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// Push sends metrics sampled at the given time to the configured provider
+func (s *MetricsExportService) Push(metrics []db.OperationalMetric, at time.Time) error {
+	var req *http.Request
+	var err error
+	switch s.cfg.Provider {
+	case db.MetricsExportProviderDatadog:
+		body, encErr := encodeDatadogSeries(metrics, s.cfg.Tags, at)
+		if encErr != nil {
+			return encErr
+		}
+		req, err = http.NewRequest(http.MethodPost, "https://api."+s.cfg.DatadogSite+"/api/v2/series", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("DD-API-KEY", s.cfg.DatadogAPIKey)
+	case db.MetricsExportProviderRemoteWrite:
+		body := snappyEncodeBlock(encodeRemoteWrite(metrics, s.cfg.Tags, at))
+		req, err = http.NewRequest(http.MethodPost, s.cfg.RemoteWriteURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		if s.cfg.RemoteWriteBearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+s.cfg.RemoteWriteBearerToken)
+		} else if s.cfg.RemoteWriteUsername != "" {
+			req.SetBasicAuth(s.cfg.RemoteWriteUsername, s.cfg.RemoteWritePassword)
+		}
+	default:
+		return ErrInvalidMetricsExportConfig
+	}
+	req.Header.Set("User-Agent", "SLAR-Metrics-Export/1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics to %s: %w", s.cfg.Provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s rejected metrics with status %d: %s", s.cfg.Provider, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// encodeDatadogSeries builds a Datadog v2 series payload, one gauge point per
+// metric named "slar.<name>"
+func encodeDatadogSeries(metrics []db.OperationalMetric, extraTags map[string]string, at time.Time) ([]byte, error) {
+	type point struct {
+		Timestamp int64   `json:"timestamp"`
+		Value     float64 `json:"value"`
+	}
+	type series struct {
+		Metric string   `json:"metric"`
+		Type   int      `json:"type"`
+		Points []point  `json:"points"`
+		Tags   []string `json:"tags,omitempty"`
+	}
+
+	payload := struct {
+		Series []series `json:"series"`
+	}{Series: make([]series, 0, len(metrics))}
+	for _, m := range metrics {
+		var tags []string
+		for _, name := range sortedTagNames(m.Tags, extraTags) {
+			tags = append(tags, name+":"+metricTag(m.Tags, extraTags, name))
+		}
+		payload.Series = append(payload.Series, series{
+			Metric: "slar." + m.Name,
+			Type:   datadogGauge,
+			Points: []point{{Timestamp: at.Unix(), Value: m.Value}},
+			Tags:   tags,
+		})
+	}
+	return json.Marshal(payload)
+}
+
+// encodeRemoteWrite builds a Prometheus remote-write WriteRequest protobuf,
+// one sample per metric named "slar_<name>" with dots turned to underscores:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; } // milliseconds
+func encodeRemoteWrite(metrics []db.OperationalMetric, extraTags map[string]string, at time.Time) []byte {
+	var req []byte
+	for _, m := range metrics {
+		// Labels must be sorted by name; "__name__" sorts ahead of lowercase names
+		var ts []byte
+		ts = appendProtoBytes(ts, 1, encodeRemoteWriteLabel("__name__", "slar_"+strings.ReplaceAll(m.Name, ".", "_")))
+		for _, name := range sortedTagNames(m.Tags, extraTags) {
+			ts = appendProtoBytes(ts, 1, encodeRemoteWriteLabel(name, metricTag(m.Tags, extraTags, name)))
+		}
+
+		var sample []byte
+		sample = binary.AppendUvarint(sample, 1<<3|1) // field 1, fixed64
+		sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(m.Value))
+		sample = binary.AppendUvarint(sample, 2<<3|0) // field 2, varint
+		sample = binary.AppendUvarint(sample, uint64(at.UnixMilli()))
+		ts = appendProtoBytes(ts, 2, sample)
+
+		req = appendProtoBytes(req, 1, ts)
+	}
+	return req
+}
+
+func encodeRemoteWriteLabel(name, value string) []byte {
+	var label []byte
+	label = appendProtoBytes(label, 1, []byte(name))
+	return appendProtoBytes(label, 2, []byte(value))
+}
+
+// appendProtoBytes appends a length-delimited protobuf field
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// snappyEncodeBlock wraps data in the snappy block format remote write
+// requires, as uncompressed literals. Payloads are a few KB, so skipping
+// compression costs little and avoids a dependency.
+func snappyEncodeBlock(data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		n := len(data)
+		if n > 1<<16 {
+			n = 1 << 16
+		}
+		if n <= 60 {
+			out = append(out, byte(n-1)<<2)
+		} else {
+			// Tag 61: literal length-1 follows in two little-endian bytes
+			out = append(out, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		out = append(out, data[:n]...)
+		data = data[n:]
+	}
+	return out
+}
+
+// sortedTagNames merges a metric's tags with the configured extra tags; the
+// metric's own tags win on conflicts
+func sortedTagNames(tags, extra map[string]string) []string {
+	names := make([]string, 0, len(tags)+len(extra))
+	for name := range extra {
+		if _, ok := tags[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func metricTag(tags, extra map[string]string, name string) string {
+	if v, ok := tags[name]; ok {
+		return v
+	}
+	return extra[name]
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// snappyDecodeLiterals reverses snappyEncodeBlock; it only understands
+// literal elements
+func snappyDecodeLiterals(t *testing.T, block []byte) []byte {
+	t.Helper()
+	size, n := binary.Uvarint(block)
+	block = block[n:]
+	var out []byte
+	for len(block) > 0 {
+		tag := block[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected non-literal element %#x", tag)
+		}
+		length := int(tag>>2) + 1
+		block = block[1:]
+		switch tag >> 2 {
+		case 60:
+			length, block = int(block[0])+1, block[1:]
+		case 61:
+			length, block = int(block[0])|int(block[1])<<8+1, block[2:]
+		}
+		out = append(out, block[:length]...)
+		block = block[length:]
+	}
+	if uint64(len(out)) != size {
+		t.Fatalf("decoded %d bytes, header says %d", len(out), size)
+	}
+	return out
+}
+
+func TestSnappyEncodeBlockRoundTrip(t *testing.T) {
+	for _, size := range []int{1, 60, 61, 1000, 1<<16 + 5} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		got := snappyDecodeLiterals(t, snappyEncodeBlock(data))
+		if string(got) != string(data) {
+			t.Errorf("size %d: round trip changed the data", size)
+		}
+	}
+}
+
+// protoFields splits a protobuf message into its fields
+func protoFields(t *testing.T, msg []byte) (fields []int, values [][]byte) {
+	t.Helper()
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		msg = msg[n:]
+		switch key & 7 {
+		case 0:
+			_, n = binary.Uvarint(msg)
+			values = append(values, msg[:n])
+		case 1:
+			n = 8
+			values = append(values, msg[:8])
+		case 2:
+			length, ln := binary.Uvarint(msg)
+			values = append(values, msg[ln:ln+int(length)])
+			n = ln + int(length)
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, int(key>>3))
+		msg = msg[n:]
+	}
+	return fields, values
+}
+
+func TestEncodeRemoteWrite(t *testing.T) {
+	at := time.Date(2026, 4, 5, 12, 0, 0, 0, time.UTC)
+	metrics := []db.OperationalMetric{{
+		Name:  "incidents.open",
+		Value: 4,
+		Tags:  map[string]string{"organization_id": "org-1", "status": "triggered"},
+	}}
+
+	fields, series := protoFields(t, encodeRemoteWrite(metrics, map[string]string{"env": "prod", "status": "ignored"}, at))
+	if len(fields) != 1 || fields[0] != 1 {
+		t.Fatalf("expected one timeseries, got fields %v", fields)
+	}
+
+	fields, values := protoFields(t, series[0])
+	var labels [][2]string
+	var sample []byte
+	for i, field := range fields {
+		switch field {
+		case 1:
+			_, kv := protoFields(t, values[i])
+			labels = append(labels, [2]string{string(kv[0]), string(kv[1])})
+		case 2:
+			sample = values[i]
+		}
+	}
+	want := [][2]string{{"__name__", "slar_incidents_open"}, {"env", "prod"}, {"organization_id", "org-1"}, {"status", "triggered"}}
+	if len(labels) != len(want) {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+	for i := range want {
+		if labels[i] != want[i] {
+			t.Errorf("label %d = %v, want %v", i, labels[i], want[i])
+		}
+	}
+
+	_, sv := protoFields(t, sample)
+	if v := math.Float64frombits(binary.LittleEndian.Uint64(sv[0])); v != 4 {
+		t.Errorf("sample value = %v, want 4", v)
+	}
+	if ms, _ := binary.Uvarint(sv[1]); int64(ms) != at.UnixMilli() {
+		t.Errorf("sample timestamp = %d, want %d", ms, at.UnixMilli())
+	}
+}
+
+func TestEncodeDatadogSeries(t *testing.T) {
+	at := time.Date(2026, 4, 5, 12, 0, 0, 0, time.UTC)
+	body, err := encodeDatadogSeries([]db.OperationalMetric{{
+		Name:  "queue.depth",
+		Value: 12,
+		Tags:  map[string]string{"queue": "incident_notifications"},
+	}}, map[string]string{"env": "prod"}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Series []struct {
+			Metric string
+			Type   int
+			Points []struct {
+				Timestamp int64
+				Value     float64
+			}
+			Tags []string
+		}
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	s := payload.Series[0]
+	if s.Metric != "slar.queue.depth" || s.Type != datadogGauge {
+		t.Errorf("unexpected series %+v", s)
+	}
+	if s.Points[0].Timestamp != at.Unix() || s.Points[0].Value != 12 {
+		t.Errorf("unexpected point %+v", s.Points[0])
+	}
+	if len(s.Tags) != 2 || s.Tags[0] != "env:prod" || s.Tags[1] != "queue:incident_notifications" {
+		t.Errorf("unexpected tags %v", s.Tags)
+	}
+}
+
+func TestNewMetricsExportServiceValidatesConfig(t *testing.T) {
+	cases := []config.MetricsExportConfig{
+		{Provider: "statsd"},
+		{Provider: db.MetricsExportProviderDatadog},
+		{Provider: db.MetricsExportProviderRemoteWrite, RemoteWriteURL: "not-a-url"},
+	}
+	for _, cfg := range cases {
+		if _, err := NewMetricsExportService(nil, cfg); !errors.Is(err, ErrInvalidMetricsExportConfig) {
+			t.Errorf("%+v: expected ErrInvalidMetricsExportConfig, got %v", cfg, err)
+		}
+	}
+}
+
+func TestPushRemoteWrite(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service, err := NewMetricsExportService(nil, config.MetricsExportConfig{
+		Provider:            db.MetricsExportProviderRemoteWrite,
+		RemoteWriteURL:      server.URL + "/api/prom/push",
+		RemoteWriteUsername: "123456",
+		RemoteWritePassword: "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := []db.OperationalMetric{{Name: "incidents.mtta_seconds", Value: 90, Tags: map[string]string{"organization_id": "org-1"}}}
+	if err := service.Push(metrics, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Header.Get("Content-Encoding") != "snappy" || got.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("missing remote write headers: %v", got.Header)
+	}
+	if user, pass, ok := got.BasicAuth(); !ok || user != "123456" || pass != "token" {
+		t.Errorf("expected basic auth, got %q %q %v", user, pass, ok)
+	}
+	if fields, _ := protoFields(t, snappyDecodeLiterals(t, body)); len(fields) != 1 {
+		t.Errorf("expected one timeseries in the body, got %d", len(fields))
+	}
+}
+
+func TestPushReportsRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	service, _ := NewMetricsExportService(nil, config.MetricsExportConfig{
+		Provider:       db.MetricsExportProviderRemoteWrite,
+		RemoteWriteURL: server.URL,
+	})
+	err := service.Push([]db.OperationalMetric{{Name: "queue.depth", Value: 1}}, time.Now())
+	if err == nil {
+		t.Fatal("expected an error for a rejected push")
+	}
+}
+
+func TestCollectMetrics(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Date(2026, 4, 5, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM incidents i`).
+		WithArgs(now.Add(-time.Hour), now.Add(-24*time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"org", "triggered", "acknowledged", "created", "mtta", "mttr"}).
+			AddRow("org-1", 2, 1, 5, 120.0, nil))
+	for i, queue := range metricsExportQueues {
+		q := mock.ExpectQuery(`pgmq.metrics`).WithArgs(queue)
+		if i == 0 {
+			q.WillReturnRows(sqlmock.NewRows([]string{"queue_length", "oldest_msg_age_sec"}).AddRow(7, 30))
+		} else {
+			q.WillReturnError(errors.New("queue does not exist"))
+		}
+	}
+
+	metrics, err := (&MetricsExportService{PG: pg}).CollectMetrics(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, m := range metrics {
+		values[m.Name+"/"+m.Tags["status"]+m.Tags["queue"]] = m.Value
+	}
+	want := map[string]float64{
+		"incidents.open/triggered":                                2,
+		"incidents.open/acknowledged":                             1,
+		"incidents.created_last_hour/":                            5,
+		"incidents.mtta_seconds/":                                 120,
+		"queue.depth/incident_notifications":                      7,
+		"queue.oldest_message_age_seconds/incident_notifications": 30,
+	}
+	if len(values) != len(want) {
+		t.Errorf("got metrics %v, want %v", values, want)
+	}
+	for key, v := range want {
+		if values[key] != v {
+			t.Errorf("%s = %v, want %v", key, values[key], v)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/notify"
+)
+
+var ErrUnknownNotificationChannel = errors.New("unknown notification channel")
+
+// apiStatusError is a non-2xx response from a delivery provider's API, kept
+// so callers can tell rejected credentials from other failures
+type apiStatusError struct {
+	StatusCode int
+	msg        string
+}
+
+func (e *apiStatusError) Error() string { return e.msg }
+
+const (
+	testNotificationHeadline = "SLAR test notification"
+	testNotificationBody     = "This is a test. If you can read it, SLAR can reach you here."
+)
+
+// NotificationCheckService sends a synthetic test notification to a user on
+// each channel and reports what happened, so setup problems (bad bot token,
+// expired device registration, missing Slack channel) show up before a page
+type NotificationCheckService struct {
+	PG       *sql.DB
+	Slack    *SlackService
+	Email    *EmailService
+	FCM      *FCMService
+	WebPush  *WebPushService
+	WhatsApp *WhatsAppService
+}
+
+// NewNotificationCheckService creates a new NotificationCheckService
+func NewNotificationCheckService(pg *sql.DB, slack *SlackService, email *EmailService, fcm *FCMService,
+	webPush *WebPushService, whatsApp *WhatsAppService) *NotificationCheckService {
+	return &NotificationCheckService{PG: pg, Slack: slack, Email: email, FCM: fcm, WebPush: webPush, WhatsApp: whatsApp}
+}
+
+// SendTestNotification tests the given channels (all when empty) for a user.
+// Channels are tried one after another; one failing doesn't stop the rest.
+func (s *NotificationCheckService) SendTestNotification(userID string, channels []string) (*db.TestNotificationResult, error) {
+	if len(channels) == 0 {
+		channels = db.NotificationChannels
+	}
+	for _, channel := range channels {
+		if !containsString(db.NotificationChannels, channel) {
+			return nil, fmt.Errorf("%w: %q (expected one of %s)", ErrUnknownNotificationChannel, channel, strings.Join(db.NotificationChannels, ", "))
+		}
+	}
+
+	result := &db.TestNotificationResult{Results: []db.NotificationChannelResult{}}
+	for _, channel := range uniqueStrings(channels) {
+		var r db.NotificationChannelResult
+		switch channel {
+		case db.NotificationChannelSlack:
+			r = s.testSlack(userID)
+		case db.NotificationChannelEmail:
+			r = s.testEmail(userID)
+		case db.NotificationChannelPush:
+			r = s.testPush(userID)
+		case db.NotificationChannelWebPush:
+			r = s.testWebPush(userID)
+		case db.NotificationChannelWhatsApp:
+			r = s.testWhatsApp(userID)
+		}
+		r.Channel = channel
+
+		switch r.Status {
+		case db.NotificationCheckSent:
+			result.Sent++
+		case db.NotificationCheckFailed:
+			result.Failed++
+		default:
+			result.Skipped++
+		}
+		result.Results = append(result.Results, r)
+	}
+	return result, nil
+}
+
+func skippedCheck(code, detail string) db.NotificationChannelResult {
+	return db.NotificationChannelResult{Status: db.NotificationCheckSkipped, Code: code, Detail: detail}
+}
+
+// failedCheck classifies a delivery error by the provider's HTTP status
+func failedCheck(err error, recipient string) db.NotificationChannelResult {
+	r := db.NotificationChannelResult{Status: db.NotificationCheckFailed, Code: db.NotificationCheckDeliveryFailed, Detail: err.Error(), Recipient: recipient}
+	var statusErr *apiStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			r.Code = db.NotificationCheckCredentialsInvalid
+		case http.StatusNotFound, http.StatusGone:
+			r.Code = db.NotificationCheckTokenInvalid
+		}
+	}
+	return r
+}
+
+// maskRecipient keeps only the end of an address or number
+func maskRecipient(recipient string) string {
+	if at := strings.LastIndex(recipient, "@"); at > 0 {
+		return recipient[:1] + "***" + recipient[at:]
+	}
+	if len(recipient) > 4 {
+		return "***" + recipient[len(recipient)-4:]
+	}
+	return recipient
+}
+
+// slackCheckCode maps Slack Web API error codes to diagnostic codes
+func slackCheckCode(slackError string) string {
+	switch slackError {
+	case "invalid_auth", "not_authed", "account_inactive", "token_revoked", "token_expired", "missing_scope":
+		return db.NotificationCheckCredentialsInvalid
+	case "channel_not_found", "not_in_channel", "is_archived", "user_not_found", "cannot_dm_bot":
+		return db.NotificationCheckChannelNotFound
+	}
+	return db.NotificationCheckDeliveryFailed
+}
+
+func (s *NotificationCheckService) testSlack(userID string) db.NotificationChannelResult {
+	if s.Slack == nil || s.Slack.botToken == "" {
+		return skippedCheck(db.NotificationCheckNotConfigured, "SLACK_BOT_TOKEN is not set")
+	}
+	config, err := s.Slack.getUserNotificationConfig(userID)
+	if err != nil {
+		return failedCheck(err, "")
+	}
+	if config.SlackUserID == "" && config.SlackChannelID == "" {
+		return skippedCheck(db.NotificationCheckNotSetUp, "No Slack user or channel in your notification settings")
+	}
+
+	channel := config.SlackUserID
+	if config.SlackChannelID != "" {
+		channel = config.SlackChannelID
+	}
+	resp, err := s.Slack.sendSlackMessage(channel, SlackMessage{Text: ":white_check_mark: *" + testNotificationHeadline + "*\n" + testNotificationBody})
+	if err != nil {
+		r := failedCheck(err, channel)
+		if resp != nil {
+			r.Code = slackCheckCode(resp.Error)
+		}
+		return r
+	}
+	r := db.NotificationChannelResult{Status: db.NotificationCheckSent, Recipient: channel}
+	if !config.SlackEnabled {
+		r.Detail = "Delivered, but Slack notifications are turned off in your settings"
+	}
+	return r
+}
+
+func (s *NotificationCheckService) testEmail(userID string) db.NotificationChannelResult {
+	if !s.Email.IsConfigured() {
+		return skippedCheck(db.NotificationCheckNotConfigured, "SMTP is not configured")
+	}
+	var email string
+	if err := s.PG.QueryRow(`SELECT COALESCE(email, '') FROM users WHERE id = $1`, userID).Scan(&email); err != nil {
+		return failedCheck(err, "")
+	}
+	if email == "" {
+		return skippedCheck(db.NotificationCheckNotSetUp, "Your account has no email address")
+	}
+	if err := s.Email.Send(email, testNotificationHeadline, testNotificationBody+"\n"); err != nil {
+		return failedCheck(err, maskRecipient(email))
+	}
+	return db.NotificationChannelResult{Status: db.NotificationCheckSent, Recipient: maskRecipient(email)}
+}
+
+func (s *NotificationCheckService) testPush(userID string) db.NotificationChannelResult {
+	if s.FCM == nil || !s.FCM.IsCloudRelayEnabled() {
+		return skippedCheck(db.NotificationCheckNotConfigured, "The mobile push cloud relay is not configured")
+	}
+	var token string
+	if err := s.PG.QueryRow(`SELECT COALESCE(fcm_token, '') FROM users WHERE id = $1`, userID).Scan(&token); err != nil {
+		return failedCheck(err, "")
+	}
+	if token == "" {
+		return skippedCheck(db.NotificationCheckNotSetUp, "No mobile device is registered for your account")
+	}
+	if err := s.FCM.SendNotificationToUserViaRelay(userID, testNotificationHeadline, testNotificationBody, map[string]string{"type": "test"}); err != nil {
+		return failedCheck(err, "")
+	}
+	return db.NotificationChannelResult{Status: db.NotificationCheckSent}
+}
+
+// testWebPush sends to every subscribed browser; the channel counts as sent
+// when at least one received it. Expired subscriptions are removed.
+func (s *NotificationCheckService) testWebPush(userID string) db.NotificationChannelResult {
+	if s.WebPush == nil || !s.WebPush.IsConfigured() {
+		return skippedCheck(db.NotificationCheckNotConfigured, "No VAPID key pair is configured")
+	}
+	subs, err := s.WebPush.ListSubscriptions(userID)
+	if err != nil {
+		return failedCheck(err, "")
+	}
+	if len(subs) == 0 {
+		return skippedCheck(db.NotificationCheckNotSetUp, "No browser is subscribed to notifications")
+	}
+
+	body, err := json.Marshal(webPushPayload{
+		Type:  "test",
+		Title: testNotificationHeadline,
+		Body:  testNotificationBody,
+		URL:   webBaseURL(),
+		Tag:   "slar-test",
+	})
+	if err != nil {
+		return failedCheck(err, "")
+	}
+
+	delivered := 0
+	var lastErr error
+	for _, sub := range subs {
+		err := s.WebPush.send(sub, body, false, "")
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, errWebPushGone):
+			s.PG.Exec(`DELETE FROM web_push_subscriptions WHERE id = $1`, sub.ID)
+			lastErr = &apiStatusError{StatusCode: http.StatusGone, msg: err.Error()}
+		default:
+			lastErr = err
+		}
+	}
+	if delivered == 0 {
+		return failedCheck(lastErr, "")
+	}
+	r := db.NotificationChannelResult{Status: db.NotificationCheckSent, Recipient: fmt.Sprintf("%d of %d browsers", delivered, len(subs))}
+	if lastErr != nil {
+		r.Detail = "Some browsers could not be reached: " + lastErr.Error()
+	}
+	return r
+}
+
+// testWhatsApp sends a free-form message inside the user's 24h session
+// window and the page template outside it, as incident notifications do
+func (s *NotificationCheckService) testWhatsApp(userID string) db.NotificationChannelResult {
+	if s.WhatsApp == nil || !s.WhatsApp.IsConfigured() {
+		return skippedCheck(db.NotificationCheckNotConfigured, "WhatsApp credentials are not configured")
+	}
+	consent, err := s.WhatsApp.GetConsent(userID)
+	if err != nil {
+		return failedCheck(err, "")
+	}
+	if consent == nil || consent.Status != db.WhatsAppOptedIn || consent.PhoneNumber == "" {
+		return skippedCheck(db.NotificationCheckNotSetUp, "You have not opted in to WhatsApp notifications")
+	}
+
+	to := strings.TrimPrefix(consent.PhoneNumber, "+")
+	msg := notify.Message{Event: "test", Headline: testNotificationHeadline, Title: testNotificationBody}
+	payload := whatsAppTemplatePayload(to, msg, webBaseURL())
+	if consent.LastInboundAt != nil && time.Since(*consent.LastInboundAt) <= db.WhatsAppSessionWindow {
+		payload = whatsAppTextPayload(to, msg)
+	}
+	if err := s.WhatsApp.post(payload); err != nil {
+		r := failedCheck(err, maskRecipient(to))
+		if strings.Contains(err.Error(), "131047") { // re-engagement message: session window closed
+			r.Code = db.NotificationCheckOutsideSession
+		}
+		return r
+	}
+	return db.NotificationChannelResult{Status: db.NotificationCheckSent, Recipient: maskRecipient(to)}
+}
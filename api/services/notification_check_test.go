@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestSendTestNotificationRejectsUnknownChannel(t *testing.T) {
+	s := NewNotificationCheckService(nil, nil, nil, nil, nil, nil)
+	if _, err := s.SendTestNotification("user-1", []string{"slack", "pager"}); !errors.Is(err, ErrUnknownNotificationChannel) {
+		t.Errorf("error = %v, want ErrUnknownNotificationChannel", err)
+	}
+}
+
+func TestSendTestNotificationSkipsUnconfiguredChannels(t *testing.T) {
+	oldWhatsApp, oldWebPush := config.Current().WhatsApp, config.Current().WebPush
+	defer config.Update(func(c *config.Config) { c.WhatsApp, c.WebPush = oldWhatsApp, oldWebPush })
+	config.Update(func(c *config.Config) { c.WhatsApp.AccessToken, c.WebPush.VAPIDPrivateKey = "", "" })
+
+	s := NewNotificationCheckService(nil, &SlackService{}, &EmailService{}, &FCMService{}, &WebPushService{}, &WhatsAppService{})
+	result, err := s.SendTestNotification("user-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Skipped != len(db.NotificationChannels) || result.Sent != 0 || result.Failed != 0 {
+		t.Errorf("result = %+v, want every channel skipped", result)
+	}
+	for _, r := range result.Results {
+		if r.Code != db.NotificationCheckNotConfigured {
+			t.Errorf("%s: code = %s, want %s", r.Channel, r.Code, db.NotificationCheckNotConfigured)
+		}
+	}
+}
+
+func TestTestWhatsAppReportsRejectedCredentials(t *testing.T) {
+	old := config.Current().WhatsApp
+	defer config.Update(func(c *config.Config) { c.WhatsApp = old })
+	config.Update(func(c *config.Config) {
+		c.WhatsApp.PhoneNumberID = "1055"
+		c.WhatsApp.AccessToken = "expired"
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"code":190,"message":"Invalid OAuth access token"}}`))
+	}))
+	defer server.Close()
+	oldBase := whatsAppAPIBaseURL
+	whatsAppAPIBaseURL = server.URL
+	defer func() { whatsAppAPIBaseURL = oldBase }()
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	recent := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(`FROM whatsapp_consents`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "phone_number", "status", "source", "opted_in_at", "opted_out_at", "last_inbound_at", "updated_at"}).
+			AddRow("user-1", "+14155550123", "opted_in", "web", recent, nil, recent, recent))
+
+	s := NewNotificationCheckService(pg, nil, nil, nil, nil, &WhatsAppService{PG: pg, client: server.Client()})
+	result, err := s.SendTestNotification("user-1", []string{db.NotificationChannelWhatsApp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := result.Results[0]
+	if r.Status != db.NotificationCheckFailed || r.Code != db.NotificationCheckCredentialsInvalid || r.Recipient != "***0123" {
+		t.Errorf("result = %+v, want failed with credentials_invalid to ***0123", r)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSlackCheckCode(t *testing.T) {
+	cases := map[string]string{
+		"channel_not_found": db.NotificationCheckChannelNotFound,
+		"not_in_channel":    db.NotificationCheckChannelNotFound,
+		"invalid_auth":      db.NotificationCheckCredentialsInvalid,
+		"ratelimited":       db.NotificationCheckDeliveryFailed,
+	}
+	for slackError, want := range cases {
+		if got := slackCheckCode(slackError); got != want {
+			t.Errorf("slackCheckCode(%s) = %s, want %s", slackError, got, want)
+		}
+	}
+}
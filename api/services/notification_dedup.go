@@ -0,0 +1,111 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// HeldSlackNotificationsQueue holds back the Slack DMs of pages to users whose
+// dedup policy is push_first, until their delay has passed
+const HeldSlackNotificationsQueue = "held_slack_notifications"
+
+// GetNotificationDedup returns the user's cross-channel dedup policy; users
+// who never set one get every channel at once
+func (s *SlackService) GetNotificationDedup(userID string) (*db.NotificationDedup, error) {
+	dedup := db.NotificationDedup{Policy: db.NotificationDedupAll, DelaySeconds: db.NotificationDedupDefaultDelaySeconds}
+	err := s.PG.QueryRow(`
+		SELECT dedup_policy, dedup_delay_seconds FROM user_notification_configs WHERE user_id = $1
+	`, userID).Scan(&dedup.Policy, &dedup.DelaySeconds)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get notification dedup policy: %w", err)
+	}
+	return &dedup, nil
+}
+
+// UpdateNotificationDedup sets the user's cross-channel dedup policy
+func (s *SlackService) UpdateNotificationDedup(userID string, req db.UpdateNotificationDedupRequest) (*db.NotificationDedup, error) {
+	if req.DelaySeconds == 0 {
+		req.DelaySeconds = db.NotificationDedupDefaultDelaySeconds
+	}
+	var dedup db.NotificationDedup
+	err := s.PG.QueryRow(`
+		INSERT INTO user_notification_configs (user_id, slack_enabled, email_enabled, push_enabled, dedup_policy, dedup_delay_seconds)
+		VALUES ($1, true, true, true, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET dedup_policy = EXCLUDED.dedup_policy, dedup_delay_seconds = EXCLUDED.dedup_delay_seconds, updated_at = NOW()
+		RETURNING dedup_policy, dedup_delay_seconds
+	`, userID, req.Policy, req.DelaySeconds).Scan(&dedup.Policy, &dedup.DelaySeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update notification dedup policy: %w", err)
+	}
+	return &dedup, nil
+}
+
+// SlackNotificationHoldDelay is how long the Slack DM of a notification should
+// be held back under the user's dedup policy. Only pages that also go out by
+// push are held, and only for users with a device or browser to push to; on
+// any error the DM goes out right away.
+func SlackNotificationHoldDelay(pg *sql.DB, userID, notificationType string, channels []string) time.Duration {
+	if !isPageNotificationType(notificationType) || userID == "" ||
+		!containsString(channels, db.NotificationChannelSlack) || !containsString(channels, db.NotificationChannelPush) {
+		return 0
+	}
+	var delaySeconds int
+	err := pg.QueryRow(`
+		SELECT c.dedup_delay_seconds
+		FROM user_notification_configs c
+		WHERE c.user_id::text = $1 AND c.dedup_policy = $2
+		AND (
+			EXISTS (SELECT 1 FROM user_devices d WHERE d.user_id = c.user_id)
+			OR EXISTS (SELECT 1 FROM web_push_subscriptions w WHERE w.user_id::text = $1)
+		)
+	`, userID, db.NotificationDedupPushFirst).Scan(&delaySeconds)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("⚠️  Failed to get notification dedup policy for user %s: %v", userID, err)
+		}
+		return 0
+	}
+	return time.Duration(delaySeconds) * time.Second
+}
+
+// HeldSlackNotificationRedundant reports whether a Slack DM held back by the
+// push_first policy is no longer needed: the user's latest page for the
+// incident was delivered by push or seen on any channel, or the incident is
+// no longer triggered
+func HeldSlackNotificationRedundant(pg *sql.DB, userID, incidentID string) (bool, error) {
+	var redundant bool
+	err := pg.QueryRow(`
+		SELECT NOT EXISTS (SELECT 1 FROM incidents WHERE id = $2 AND status = 'triggered')
+		OR COALESCE((
+			SELECT nr.delivered_channel IN ('push', 'web') OR nr.seen_at IS NOT NULL
+			FROM notification_receipts nr
+			WHERE nr.incident_id = $2 AND nr.user_id::text = $1
+			ORDER BY nr.sent_at DESC
+			LIMIT 1
+		), false)
+	`, userID, incidentID).Scan(&redundant)
+	if err != nil {
+		return false, fmt.Errorf("failed to check held Slack notification: %w", err)
+	}
+	return redundant, nil
+}
+
+// queueIncidentNotification sends a notification to the Slack worker's queue,
+// or to HeldSlackNotificationsQueue when the user's dedup policy holds it back
+func queueIncidentNotification(pg *sql.DB, userID, notificationType string, channels []string, payload []byte) error {
+	var err error
+	if delay := SlackNotificationHoldDelay(pg, userID, notificationType, channels); delay > 0 {
+		_, err = pg.Exec(`SELECT pgmq.send($1, $2, $3)`, HeldSlackNotificationsQueue, string(payload), int(delay.Seconds()))
+	} else {
+		_, err = pg.Exec(`SELECT pgmq.send($1, $2)`, "incident_notifications", string(payload))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send notification to queue: %w", err)
+	}
+	return nil
+}
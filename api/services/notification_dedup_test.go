@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestSlackNotificationHoldDelay(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	// Not a page, or not also pushed: sent right away without a lookup
+	if d := SlackNotificationHoldDelay(pg, "user-1", "resolved", []string{"slack"}); d != 0 {
+		t.Errorf("resolved delay = %v", d)
+	}
+	if d := SlackNotificationHoldDelay(pg, "user-1", "assigned", []string{"slack"}); d != 0 {
+		t.Errorf("Slack-only page delay = %v", d)
+	}
+
+	mock.ExpectQuery(`FROM user_notification_configs c`).WithArgs("user-1", db.NotificationDedupPushFirst).
+		WillReturnRows(sqlmock.NewRows([]string{"dedup_delay_seconds"}).AddRow(90))
+	if d := SlackNotificationHoldDelay(pg, "user-1", "escalated", []string{"slack", "push"}); d != 90*time.Second {
+		t.Errorf("push_first delay = %v, want 90s", d)
+	}
+
+	// Policy "all", or nowhere to push to
+	mock.ExpectQuery(`FROM user_notification_configs c`).WithArgs("user-2", db.NotificationDedupPushFirst).
+		WillReturnRows(sqlmock.NewRows([]string{"dedup_delay_seconds"}))
+	if d := SlackNotificationHoldDelay(pg, "user-2", "paged", []string{"slack", "push"}); d != 0 {
+		t.Errorf("default policy delay = %v", d)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHeldSlackNotificationRedundant(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	for _, want := range []bool{true, false} {
+		mock.ExpectQuery(`FROM notification_receipts nr`).WithArgs("user-1", testIncidentID).
+			WillReturnRows(sqlmock.NewRows([]string{"redundant"}).AddRow(want))
+		got, err := HeldSlackNotificationRedundant(pg, "user-1", testIncidentID)
+		if err != nil || got != want {
+			t.Errorf("redundant = %v, %v; want %v", got, err, want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateNotificationDedupDefaultsDelay(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`INSERT INTO user_notification_configs`).
+		WithArgs("user-1", db.NotificationDedupPushFirst, db.NotificationDedupDefaultDelaySeconds).
+		WillReturnRows(sqlmock.NewRows([]string{"dedup_policy", "dedup_delay_seconds"}).
+			AddRow(db.NotificationDedupPushFirst, db.NotificationDedupDefaultDelaySeconds))
+
+	s := &SlackService{PG: pg}
+	dedup, err := s.UpdateNotificationDedup("user-1", db.UpdateNotificationDedupRequest{Policy: db.NotificationDedupPushFirst})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dedup.Policy != db.NotificationDedupPushFirst || dedup.DelaySeconds != db.NotificationDedupDefaultDelaySeconds {
+		t.Errorf("dedup = %+v", dedup)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrNotificationReceiptNotFound = errors.New("no page has been sent to this user for this incident")
+
+// isPageNotificationType reports whether a notification type asks a responder to
+// act on an incident; only those get read receipts
+func isPageNotificationType(notificationType string) bool {
+	switch notificationType {
+	case "assigned", "escalated", "paged":
+		return true
+	}
+	return false
+}
+
+// RecordNotificationSent stores a pending receipt for a page that was just queued.
+// It is shared by the API's LightweightNotificationSender and the worker's
+// NotificationWorker so both paths feed the same "seen at" tracking.
+func RecordNotificationSent(pg *sql.DB, userID, incidentID, notificationType string) error {
+	if !isPageNotificationType(notificationType) || userID == "" || incidentID == "" {
+		return nil
+	}
+	_, err := pg.Exec(`
+		INSERT INTO notification_receipts (incident_id, user_id, notification_type)
+		VALUES ($1, $2, $3)
+	`, incidentID, userID, notificationType)
+	if err != nil {
+		return fmt.Errorf("failed to record notification receipt: %w", err)
+	}
+	return nil
+}
+
+const notificationReceiptColumns = `
+	nr.id, nr.incident_id, nr.user_id, COALESCE(u.name, u.email, ''), nr.notification_type,
+	nr.sent_at, nr.delivered_at, COALESCE(nr.delivered_channel, ''), nr.seen_at, COALESCE(nr.seen_channel, '')
+`
+
+func scanNotificationReceipt(row interface{ Scan(...interface{}) error }) (db.NotificationReceipt, error) {
+	var r db.NotificationReceipt
+	var deliveredAt, seenAt sql.NullTime
+	err := row.Scan(&r.ID, &r.IncidentID, &r.UserID, &r.UserName, &r.NotificationType,
+		&r.SentAt, &deliveredAt, &r.DeliveredChannel, &seenAt, &r.SeenChannel)
+	if deliveredAt.Valid {
+		r.DeliveredAt = &deliveredAt.Time
+	}
+	if seenAt.Valid {
+		r.SeenAt = &seenAt.Time
+	}
+	return r, err
+}
+
+// RecordNotificationReceipt marks the user's latest page for an incident as
+// delivered or seen. Only the first report of each kind is kept; a "seen" report
+// also counts as delivered.
+func (s *IncidentService) RecordNotificationReceipt(incidentID, userID string, req db.RecordNotificationReceiptRequest) (*db.NotificationReceipt, error) {
+	seen := req.Event == db.ReceiptEventSeen
+	receipt, err := scanNotificationReceipt(s.PG.QueryRow(`
+		WITH latest AS (
+			SELECT id FROM notification_receipts
+			WHERE incident_id = $1 AND user_id = $2
+			ORDER BY sent_at DESC
+			LIMIT 1
+		), updated AS (
+			UPDATE notification_receipts r SET
+				delivered_at      = COALESCE(r.delivered_at, NOW()),
+				delivered_channel = COALESCE(r.delivered_channel, $3),
+				seen_at           = CASE WHEN $4 THEN COALESCE(r.seen_at, NOW()) ELSE r.seen_at END,
+				seen_channel      = CASE WHEN $4 THEN COALESCE(r.seen_channel, $3) ELSE r.seen_channel END
+			FROM latest
+			WHERE r.id = latest.id
+			RETURNING r.*
+		)
+		SELECT `+notificationReceiptColumns+`
+		FROM updated nr
+		LEFT JOIN users u ON u.id = nr.user_id
+	`, incidentID, userID, req.Channel, seen))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotificationReceiptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record notification receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// ListNotificationReceipts returns the latest page receipt for each responder
+// paged on an incident, most recently paged first
+func (s *IncidentService) ListNotificationReceipts(incidentID string) ([]db.NotificationReceipt, error) {
+	rows, err := s.PG.Query(`
+		SELECT * FROM (
+			SELECT DISTINCT ON (nr.user_id) `+notificationReceiptColumns+`
+			FROM notification_receipts nr
+			LEFT JOIN users u ON u.id = nr.user_id
+			WHERE nr.incident_id = $1
+			ORDER BY nr.user_id, nr.sent_at DESC
+		) latest
+		ORDER BY sent_at DESC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification receipts: %w", err)
+	}
+	defer rows.Close()
+
+	receipts := []db.NotificationReceipt{}
+	for rows.Next() {
+		r, err := scanNotificationReceipt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification receipt: %w", err)
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, rows.Err()
+}
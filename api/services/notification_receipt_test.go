@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var receiptColumns = []string{"id", "incident_id", "user_id", "user_name", "notification_type",
+	"sent_at", "delivered_at", "delivered_channel", "seen_at", "seen_channel"}
+
+func TestRecordNotificationSentOnlyTracksPages(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectExec(`INSERT INTO notification_receipts`).WithArgs("inc-1", "user-1", "escalated").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := RecordNotificationSent(pg, "user-1", "inc-1", "escalated"); err != nil {
+		t.Fatal(err)
+	}
+	// Informational notifications don't need a receipt
+	if err := RecordNotificationSent(pg, "user-1", "inc-1", "resolved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordNotificationReceipt(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	svc := NewIncidentService(pg, nil)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE notification_receipts`).WithArgs("inc-1", "user-1", "push", true).
+		WillReturnRows(sqlmock.NewRows(receiptColumns).
+			AddRow("r-1", "inc-1", "user-1", "Dana", "paged", now.Add(-time.Minute), now, "push", now, "push"))
+
+	receipt, err := svc.RecordNotificationReceipt("inc-1", "user-1", db.RecordNotificationReceiptRequest{Channel: "push", Event: db.ReceiptEventSeen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.SeenAt == nil || receipt.SeenChannel != "push" || receipt.DeliveredAt == nil {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+
+	// User was never paged on this incident
+	mock.ExpectQuery(`UPDATE notification_receipts`).WithArgs("inc-1", "user-2", "slack", false).
+		WillReturnRows(sqlmock.NewRows(receiptColumns))
+	_, err = svc.RecordNotificationReceipt("inc-1", "user-2", db.RecordNotificationReceiptRequest{Channel: "slack", Event: db.ReceiptEventDelivered})
+	if !errors.Is(err, ErrNotificationReceiptNotFound) {
+		t.Errorf("err = %v, want ErrNotificationReceiptNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListNotificationReceipts(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT DISTINCT ON \(nr.user_id\)`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows(receiptColumns).
+			AddRow("r-2", "inc-1", "user-2", "Lee", "escalated", now, nil, "", nil, "").
+			AddRow("r-1", "inc-1", "user-1", "Dana", "assigned", now.Add(-10*time.Minute), now, "push", now, "push"))
+
+	receipts, err := NewIncidentService(pg, nil).ListNotificationReceipts("inc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 2 {
+		t.Fatalf("got %d receipts, want 2", len(receipts))
+	}
+	if receipts[0].SeenAt != nil || receipts[0].DeliveredAt != nil {
+		t.Errorf("unseen page has timestamps: %+v", receipts[0])
+	}
+	if receipts[1].SeenAt == nil {
+		t.Errorf("seen page missing seen_at: %+v", receipts[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -468,7 +468,9 @@ func (s *OnCallService) IsUserGroupLeader(groupID, userID string) (bool, error)
 	return count > 0, err
 }
 
-// SwapSchedules swaps two schedules - simplified for leaders (no approval needed)
+// SwapSchedules swaps two schedules. Managers of both shifts' schedulers swap
+// them right away; other members may only ask to swap one of their own
+// shifts, which creates a swap request for a manager to approve.
 func (s *OnCallService) SwapSchedules(req db.ShiftSwapRequest, requestorID string) (db.ShiftSwapResponse, error) {
 	var response db.ShiftSwapResponse
 
@@ -488,24 +490,29 @@ func (s *OnCallService) SwapSchedules(req db.ShiftSwapRequest, requestorID strin
 		return response, fmt.Errorf("cannot swap schedules from different groups")
 	}
 
-	// Check if requestor is a leader in the group
-	isLeader, err := s.IsUserGroupLeader(schedule1.GroupID, requestorID)
+	// Schedule managers swap instantly, without approval
+	isManager, err := canManageShifts(s.PG, requestorID, []string{schedule1.ID, schedule2.ID})
 	if err != nil {
-		return response, fmt.Errorf("failed to check leader status: %w", err)
+		return response, err
 	}
-
-	// If requestor is not a leader, they can only swap their own schedules
-	if !isLeader && schedule1.UserID != requestorID {
-		return response, fmt.Errorf("only group leaders can swap other people's schedules")
-	}
-
-	// For leaders, allow instant swap without approval
-	if isLeader || req.SwapType == "instant" {
+	if isManager {
 		return s.executeScheduleSwap(schedule1, schedule2, req.SwapMessage, requestorID)
 	}
 
-	// For non-leaders, create swap request (future enhancement)
-	return response, fmt.Errorf("swap requests are not implemented yet - only instant swaps are supported")
+	// Everyone else can only ask to swap their own schedules
+	if schedule1.UserID != requestorID {
+		return response, ErrSchedulerForbidden
+	}
+	swap, err := s.requestScheduleSwap(schedule1, schedule2, req.SwapMessage, requestorID)
+	if err != nil {
+		return response, err
+	}
+	response.Success = true
+	response.Message = "Swap requested; a schedule manager has to approve it"
+	response.CurrentSchedule = schedule1
+	response.TargetSchedule = schedule2
+	response.SwapRequest = swap
+	return response, nil
 }
 
 // executeScheduleSwap performs the actual schedule swap
@@ -296,6 +296,12 @@ func max(a, b int) int {
 
 // UpdateSchedulerWithShiftsOptimized updates a scheduler and replaces all its shifts with optimization
 func (s *OptimizedSchedulerService) UpdateSchedulerWithShiftsOptimized(schedulerID string, schedulerReq db.CreateSchedulerRequest, shifts []db.CreateShiftRequest, updatedBy string) (db.Scheduler, []db.Shift, error) {
+	if ok, err := canManageSchedulers(s.PG, updatedBy, []string{schedulerID}); err != nil {
+		return db.Scheduler{}, nil, err
+	} else if !ok {
+		return db.Scheduler{}, nil, ErrSchedulerForbidden
+	}
+
 	// Start transaction
 	tx, err := s.PG.Begin()
 	if err != nil {
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrOrgSettingsNotFound       = errors.New("organization not found")
+	ErrInvalidOrgDisplaySettings = errors.New("invalid display settings")
+)
+
+// OrgSettingsService stores organization-wide preferences kept in
+// organizations.settings
+type OrgSettingsService struct {
+	PG *sql.DB
+}
+
+// NewOrgSettingsService creates a new OrgSettingsService
+func NewOrgSettingsService(pg *sql.DB) *OrgSettingsService {
+	return &OrgSettingsService{PG: pg}
+}
+
+// GetDisplaySettings returns an organization's display settings, with
+// defaults for anything it hasn't chosen
+func (s *OrgSettingsService) GetDisplaySettings(orgID string) (db.OrgDisplaySettings, error) {
+	settings, err := loadOrgDisplaySettings(s.PG, orgID)
+	if err == sql.ErrNoRows {
+		return settings, ErrOrgSettingsNotFound
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to get display settings: %w", err)
+	}
+	return settings, nil
+}
+
+// UpdateDisplaySettings changes the display settings that are set in req
+func (s *OrgSettingsService) UpdateDisplaySettings(orgID string, req db.UpdateOrgDisplaySettingsRequest) (db.OrgDisplaySettings, error) {
+	settings, err := s.GetDisplaySettings(orgID)
+	if err != nil {
+		return settings, err
+	}
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.DateFormat != nil {
+		settings.DateFormat = *req.DateFormat
+	}
+	if req.TimeFormat != nil {
+		settings.TimeFormat = *req.TimeFormat
+	}
+	if req.WeekStart != nil {
+		settings.WeekStart = *req.WeekStart
+	}
+	if err := validateOrgDisplaySettings(settings); err != nil {
+		return settings, err
+	}
+
+	raw, _ := json.Marshal(settings)
+	_, err = s.PG.Exec(`
+		UPDATE organizations
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{display}', $2::jsonb), updated_at = NOW()
+		WHERE id = $1
+	`, orgID, string(raw))
+	if err != nil {
+		return settings, fmt.Errorf("failed to update display settings: %w", err)
+	}
+	return settings, nil
+}
+
+func validateOrgDisplaySettings(settings db.OrgDisplaySettings) error {
+	if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "" {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidOrgDisplaySettings, settings.Timezone)
+	}
+	if _, ok := db.OrgDateFormats[settings.DateFormat]; !ok {
+		return fmt.Errorf("%w: unsupported date_format %q", ErrInvalidOrgDisplaySettings, settings.DateFormat)
+	}
+	if settings.TimeFormat != db.TimeFormat24h && settings.TimeFormat != db.TimeFormat12h {
+		return fmt.Errorf("%w: time_format must be 24h or 12h", ErrInvalidOrgDisplaySettings)
+	}
+	if settings.WeekStart != db.WeekStartMonday && settings.WeekStart != db.WeekStartSunday {
+		return fmt.Errorf("%w: week_start must be monday or sunday", ErrInvalidOrgDisplaySettings)
+	}
+	return nil
+}
+
+// loadOrgDisplaySettings reads an organization's display settings. Values
+// that are missing or no longer valid fall back to the defaults one by one.
+func loadOrgDisplaySettings(q queryRower, orgID string) (db.OrgDisplaySettings, error) {
+	settings := db.DefaultOrgDisplaySettings()
+	var raw []byte
+	err := q.QueryRow(`
+		SELECT COALESCE(settings->'display', '{}'::jsonb) FROM organizations WHERE id = $1
+	`, orgID).Scan(&raw)
+	if err != nil {
+		return settings, err
+	}
+
+	var stored db.OrgDisplaySettings
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return settings, nil
+	}
+	if _, err := time.LoadLocation(stored.Timezone); err == nil && stored.Timezone != "" {
+		settings.Timezone = stored.Timezone
+	}
+	if _, ok := db.OrgDateFormats[stored.DateFormat]; ok {
+		settings.DateFormat = stored.DateFormat
+	}
+	if stored.TimeFormat == db.TimeFormat24h || stored.TimeFormat == db.TimeFormat12h {
+		settings.TimeFormat = stored.TimeFormat
+	}
+	if stored.WeekStart == db.WeekStartMonday || stored.WeekStart == db.WeekStartSunday {
+		settings.WeekStart = stored.WeekStart
+	}
+	return settings, nil
+}
+
+// orgDisplaySettingsOrDefault is loadOrgDisplaySettings for callers that
+// render or bucket something and must not fail because of it
+func orgDisplaySettingsOrDefault(q queryRower, orgID string) db.OrgDisplaySettings {
+	if orgID == "" {
+		return db.DefaultOrgDisplaySettings()
+	}
+	settings, err := loadOrgDisplaySettings(q, orgID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: failed to load display settings for org %s: %v", orgID, err)
+	}
+	return settings
+}
+
+// orgLocation is the organization's timezone
+func orgLocation(settings db.OrgDisplaySettings) *time.Location {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FormatOrgTime renders t in the organization's timezone, date and clock
+// format, e.g. "31/01/2026 2:05 PM CET"
+func FormatOrgTime(settings db.OrgDisplaySettings, t time.Time) string {
+	layout, ok := db.OrgDateFormats[settings.DateFormat]
+	if !ok {
+		layout = db.OrgDateFormats[db.DefaultOrgDisplaySettings().DateFormat]
+	}
+	if settings.TimeFormat == db.TimeFormat12h {
+		layout += " 3:04 PM MST"
+	} else {
+		layout += " 15:04 MST"
+	}
+	return t.In(orgLocation(settings)).Format(layout)
+}
+
+// orgStartOfWeek returns midnight on the first day of the week containing t,
+// in the organization's timezone
+func orgStartOfWeek(settings db.OrgDisplaySettings, t time.Time) time.Time {
+	local := t.In(orgLocation(settings))
+	first := time.Monday
+	if settings.WeekStart == db.WeekStartSunday {
+		first = time.Sunday
+	}
+	back := (int(local.Weekday()) - int(first) + 7) % 7
+	day := local.AddDate(0, 0, -back)
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, local.Location())
+}
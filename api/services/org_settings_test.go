@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestOrgDisplaySettingsFormattingAndWeeks(t *testing.T) {
+	settings := db.OrgDisplaySettings{Timezone: "America/New_York", DateFormat: "DD/MM/YYYY", TimeFormat: db.TimeFormat12h, WeekStart: db.WeekStartSunday}
+	at := time.Date(2026, 1, 31, 19, 5, 0, 0, time.UTC) // Saturday
+
+	if got := FormatOrgTime(settings, at); got != "31/01/2026 2:05 PM EST" {
+		t.Errorf("FormatOrgTime = %q", got)
+	}
+	if got := orgStartOfWeek(settings, at); got.Format(time.RFC3339) != "2026-01-25T00:00:00-05:00" {
+		t.Errorf("Sunday week start = %s", got.Format(time.RFC3339))
+	}
+	settings.WeekStart = db.WeekStartMonday
+	if got := orgStartOfWeek(settings, at); got.Format(time.RFC3339) != "2026-01-26T00:00:00-05:00" {
+		t.Errorf("Monday week start = %s", got.Format(time.RFC3339))
+	}
+
+	from, to := DefaultCalendarRange(settings, at)
+	if from.Weekday() != time.Monday || to.Weekday() != time.Monday || to.Sub(from) != 28*24*time.Hour {
+		t.Errorf("calendar range %s - %s should cover four whole weeks", from, to)
+	}
+}
+
+func TestLoadOrgDisplaySettingsFallsBackPerField(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM organizations`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"display"}).
+			AddRow([]byte(`{"timezone":"Asia/Tokyo","date_format":"bogus","week_start":"sunday"}`)))
+
+	settings, err := NewOrgSettingsService(pg).GetDisplaySettings("org-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := db.OrgDisplaySettings{Timezone: "Asia/Tokyo", DateFormat: "YYYY-MM-DD", TimeFormat: db.TimeFormat24h, WeekStart: db.WeekStartSunday}
+	if settings != want {
+		t.Errorf("got %+v, want %+v", settings, want)
+	}
+}
+
+func TestUpdateDisplaySettingsRejectsUnknownTimezone(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM organizations`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"display"}).AddRow([]byte(`{}`)))
+
+	tz := "Mars/Olympus"
+	_, err = NewOrgSettingsService(pg).UpdateDisplaySettings("org-1", db.UpdateOrgDisplaySettingsRequest{Timezone: &tz})
+	if !errors.Is(err, ErrInvalidOrgDisplaySettings) {
+		t.Fatalf("expected ErrInvalidOrgDisplaySettings, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
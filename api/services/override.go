@@ -2,6 +2,7 @@ package services
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/vanchonlee/slar/db"
 )
 
+var ErrOverrideNotFound = errors.New("override not found")
+
 type OverrideService struct {
 	PG *sql.DB
 }
@@ -17,8 +20,15 @@ func NewOverrideService(pg *sql.DB) *OverrideService {
 	return &OverrideService{PG: pg}
 }
 
-// CreateOverride creates a new schedule override
+// CreateOverride creates a new schedule override. Only managers of the
+// shift's scheduler may override it.
 func (s *OverrideService) CreateOverride(req db.CreateScheduleOverrideRequest, createdBy string) (db.ScheduleOverride, error) {
+	if ok, err := canManageShifts(s.PG, createdBy, []string{req.OriginalScheduleID}); err != nil {
+		return db.ScheduleOverride{}, err
+	} else if !ok {
+		return db.ScheduleOverride{}, ErrSchedulerForbidden
+	}
+
 	override := db.ScheduleOverride{
 		ID:                 uuid.New().String(),
 		OriginalScheduleID: req.OriginalScheduleID,
@@ -130,9 +140,26 @@ func (s *OverrideService) ListOverrides(groupID string) ([]db.ScheduleOverride,
 	return overrides, nil
 }
 
-// DeleteOverride deactivates an override (soft delete)
-func (s *OverrideService) DeleteOverride(overrideID string) error {
-	_, err := s.PG.Exec(`
+// DeleteOverride deactivates an override (soft delete). Only managers of the
+// overridden shift's scheduler may remove it.
+func (s *OverrideService) DeleteOverride(overrideID, deletedBy string) error {
+	var shiftID string
+	err := s.PG.QueryRow(`
+		SELECT original_schedule_id::text FROM schedule_overrides WHERE id::text = $1
+	`, overrideID).Scan(&shiftID)
+	if err == sql.ErrNoRows {
+		return ErrOverrideNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get override: %w", err)
+	}
+	if ok, err := canManageShifts(s.PG, deletedBy, []string{shiftID}); err != nil {
+		return err
+	} else if !ok {
+		return ErrSchedulerForbidden
+	}
+
+	_, err = s.PG.Exec(`
 		UPDATE schedule_overrides 
 		SET is_active = false, updated_at = $2
 		WHERE id = $1
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// pagingAuditSkew is how far a channel's delivery record may precede the
+// receipt of the page it belongs to; receipts are written once every channel
+// has been queued, so a fast delivery can be logged slightly earlier
+const pagingAuditSkew = 30 * time.Second
+
+// pagingAuditDelivery is a channel attempt for a responder, before it is
+// matched to the page it delivered
+type pagingAuditDelivery struct {
+	UserID string
+	db.PagingAuditDelivery
+}
+
+// GetPagingAudit breaks down an incident's pages by escalation level: who each
+// level paged, which channels carried the page and whether it was delivered,
+// seen and acknowledged. It is assembled from notification_receipts,
+// notification_logs and voice_calls so post-incident reviews can check paging worked.
+func (s *IncidentService) GetPagingAudit(incident *db.IncidentResponse) ([]db.PagingAuditLevel, error) {
+	var levels []db.EscalationLevel
+	if incident.EscalationPolicyID != "" {
+		var err error
+		if levels, err = s.getTimelineEscalationLevels(incident.EscalationPolicyID); err != nil {
+			return nil, err
+		}
+	}
+	history, err := s.getEscalationHistory(incident.ID)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := s.listPagingAuditReceipts(incident.ID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.listPagingAuditDeliveries(incident.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildPagingAudit(&incident.Incident, levels, history, receipts, deliveries), nil
+}
+
+// buildPagingAudit places each page in the level that was current when it was
+// sent, and each delivery under the responder's latest page before it
+func buildPagingAudit(incident *db.Incident, levels []db.EscalationLevel, history map[int]escalationRecord, receipts []db.NotificationReceipt, deliveries []pagingAuditDelivery) []db.PagingAuditLevel {
+	// Level 1 is paged when the incident is created, later levels when escalated
+	starts := map[int]time.Time{1: incident.CreatedAt}
+	for level, record := range history {
+		starts[level] = record.At
+	}
+	audit := make([]db.PagingAuditLevel, 0, len(starts))
+	for level, at := range starts {
+		audit = append(audit, db.PagingAuditLevel{Level: level, StartedAt: at, Responders: []db.PagingAuditResponder{}})
+	}
+	sort.Slice(audit, func(i, j int) bool {
+		if audit[i].StartedAt.Equal(audit[j].StartedAt) {
+			return audit[i].Level < audit[j].Level
+		}
+		return audit[i].StartedAt.Before(audit[j].StartedAt)
+	})
+
+	for i := range audit {
+		entry := &audit[i]
+		for _, level := range levels {
+			if level.LevelNumber == entry.Level {
+				entry.TargetType, entry.TargetID, entry.TargetName = level.TargetType, level.TargetID, level.TargetName
+			}
+		}
+		if incident.AcknowledgedAt != nil && !incident.AcknowledgedAt.Before(entry.StartedAt) &&
+			(i == len(audit)-1 || incident.AcknowledgedAt.Before(audit[i+1].StartedAt)) {
+			entry.Acknowledged = true
+		}
+	}
+
+	// Receipts are sorted by sent_at, so a responder's pages stay in order
+	type pageRef struct{ level, responder int }
+	pagesByUser := map[string][]pageRef{}
+	for _, receipt := range receipts {
+		idx := 0
+		for i := range audit {
+			if !audit[i].StartedAt.After(receipt.SentAt.Add(pagingAuditSkew)) {
+				idx = i
+			}
+		}
+		audit[idx].Responders = append(audit[idx].Responders, db.PagingAuditResponder{
+			UserID:           receipt.UserID,
+			UserName:         receipt.UserName,
+			NotificationType: receipt.NotificationType,
+			SentAt:           receipt.SentAt,
+			DeliveredAt:      receipt.DeliveredAt,
+			DeliveredChannel: receipt.DeliveredChannel,
+			SeenAt:           receipt.SeenAt,
+			SeenChannel:      receipt.SeenChannel,
+			Deliveries:       []db.PagingAuditDelivery{},
+		})
+		pagesByUser[receipt.UserID] = append(pagesByUser[receipt.UserID], pageRef{idx, len(audit[idx].Responders) - 1})
+	}
+
+	for _, delivery := range deliveries {
+		var match *db.PagingAuditResponder
+		for _, ref := range pagesByUser[delivery.UserID] {
+			responder := &audit[ref.level].Responders[ref.responder]
+			if !responder.SentAt.After(delivery.At.Add(pagingAuditSkew)) {
+				match = responder
+			}
+		}
+		if match != nil {
+			match.Deliveries = append(match.Deliveries, delivery.PagingAuditDelivery)
+		}
+	}
+
+	for i := range audit {
+		for j := range audit[i].Responders {
+			responder := &audit[i].Responders[j]
+			responder.Outcome = pagingOutcome(responder, audit[i].Acknowledged && responder.UserID == incident.AcknowledgedBy)
+		}
+	}
+	return audit
+}
+
+// pagingOutcome reports the furthest a page got with its responder
+func pagingOutcome(responder *db.PagingAuditResponder, acknowledged bool) string {
+	delivered, failed := responder.DeliveredAt != nil, 0
+	for _, d := range responder.Deliveries {
+		if d.Outcome == db.VoiceOutcomeAcknowledged {
+			acknowledged = true
+		}
+		if pagingDeliverySucceeded(d) {
+			delivered = true
+		} else if d.Status == "failed" || d.Outcome == db.VoiceOutcomeUnanswered || d.Outcome == db.VoiceOutcomeFailed {
+			failed++
+		}
+	}
+	switch {
+	case acknowledged:
+		return db.PagingOutcomeAcknowledged
+	case responder.SeenAt != nil:
+		return db.PagingOutcomeSeen
+	case delivered:
+		return db.PagingOutcomeDelivered
+	case failed > 0 && failed == len(responder.Deliveries):
+		return db.PagingOutcomeFailed
+	}
+	return db.PagingOutcomeQueued
+}
+
+// pagingDeliverySucceeded reports whether a channel got the page to the
+// responder; a phone call counts once it was answered
+func pagingDeliverySucceeded(d db.PagingAuditDelivery) bool {
+	if d.Channel == "voice" {
+		switch d.Outcome {
+		case db.VoiceOutcomeAcknowledged, db.VoiceOutcomeEscalated, db.VoiceOutcomeEscalationFailed, db.VoiceOutcomeNoInput:
+			return true
+		}
+		return false
+	}
+	return d.Status == "sent" || d.Status == "delivered"
+}
+
+// listPagingAuditReceipts returns every page sent for an incident, oldest first
+func (s *IncidentService) listPagingAuditReceipts(incidentID string) ([]db.NotificationReceipt, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+notificationReceiptColumns+`
+		FROM notification_receipts nr
+		LEFT JOIN users u ON u.id = nr.user_id
+		WHERE nr.incident_id = $1
+		ORDER BY nr.sent_at ASC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification receipts: %w", err)
+	}
+	defer rows.Close()
+
+	var receipts []db.NotificationReceipt
+	for rows.Next() {
+		r, err := scanNotificationReceipt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification receipt: %w", err)
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, rows.Err()
+}
+
+// listPagingAuditDeliveries returns the channel attempts for an incident's
+// pages, oldest first. Notification logs also cover resolved/acknowledged
+// notices, which are left out; their types are stored with and without an
+// "incident_" prefix depending on the sender.
+func (s *IncidentService) listPagingAuditDeliveries(incidentID string) ([]pagingAuditDelivery, error) {
+	rows, err := s.PG.Query(`
+		SELECT user_id, notification_type, channel, COALESCE(status, ''), COALESCE(error_message, ''), '' AS outcome,
+		       COALESCE(sent_at, created_at) AS at
+		FROM notification_logs
+		WHERE incident_id = $1
+		UNION ALL
+		SELECT user_id, notification_type, 'voice', status, '', COALESCE(outcome, ''), created_at
+		FROM voice_calls
+		WHERE incident_id = $1
+		ORDER BY at ASC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []pagingAuditDelivery
+	for rows.Next() {
+		var d pagingAuditDelivery
+		var notificationType string
+		if err := rows.Scan(&d.UserID, &notificationType, &d.Channel, &d.Status, &d.Error, &d.Outcome, &d.At); err != nil {
+			return nil, fmt.Errorf("failed to scan notification delivery: %w", err)
+		}
+		if !isPageNotificationType(strings.TrimPrefix(notificationType, "incident_")) {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestBuildPagingAudit(t *testing.T) {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	escalated := created.Add(5 * time.Minute)
+	acked := escalated.Add(2 * time.Minute)
+
+	incident := &db.Incident{ID: "inc-1", CreatedAt: created, AcknowledgedBy: "bob", AcknowledgedAt: &acked}
+	levels := []db.EscalationLevel{
+		{LevelNumber: 1, TargetType: "user", TargetID: "alice", TargetName: "Alice"},
+		{LevelNumber: 2, TargetType: "group", TargetID: "g-1", TargetName: "SRE"},
+	}
+	history := map[int]escalationRecord{2: {At: escalated, UserID: "bob"}}
+	receipts := []db.NotificationReceipt{
+		{UserID: "alice", UserName: "Alice", NotificationType: "assigned", SentAt: created.Add(time.Second)},
+		{UserID: "bob", UserName: "Bob", NotificationType: "escalated", SentAt: escalated.Add(time.Second)},
+	}
+	deliveries := []pagingAuditDelivery{
+		{"alice", db.PagingAuditDelivery{Channel: "slack", Status: "failed", Error: "user_not_found", At: created.Add(time.Second)}},
+		{"alice", db.PagingAuditDelivery{Channel: "voice", Status: "no-answer", Outcome: db.VoiceOutcomeUnanswered, At: created.Add(2 * time.Second)}},
+		// Logged a moment before the receipt is written
+		{"bob", db.PagingAuditDelivery{Channel: "web_push", Status: "sent", At: escalated}},
+	}
+
+	audit := buildPagingAudit(incident, levels, history, receipts, deliveries)
+	if len(audit) != 2 {
+		t.Fatalf("expected 2 levels, got %d", len(audit))
+	}
+
+	first, second := audit[0], audit[1]
+	if first.Level != 1 || first.TargetName != "Alice" || first.Acknowledged {
+		t.Errorf("unexpected level 1: %+v", first)
+	}
+	if len(first.Responders) != 1 || first.Responders[0].Outcome != db.PagingOutcomeFailed || len(first.Responders[0].Deliveries) != 2 {
+		t.Errorf("expected alice's page to have failed on both channels: %+v", first.Responders)
+	}
+
+	if second.Level != 2 || second.TargetName != "SRE" || !second.Acknowledged || !second.StartedAt.Equal(escalated) {
+		t.Errorf("unexpected level 2: %+v", second)
+	}
+	if len(second.Responders) != 1 || second.Responders[0].UserID != "bob" || second.Responders[0].Outcome != db.PagingOutcomeAcknowledged {
+		t.Errorf("expected bob to have acknowledged at level 2: %+v", second.Responders)
+	}
+	if got := second.Responders[0].Deliveries; len(got) != 1 || got[0].Channel != "web_push" {
+		t.Errorf("expected bob's web push delivery, got %+v", got)
+	}
+}
+
+func TestPagingOutcome(t *testing.T) {
+	seen := time.Now()
+	cases := []struct {
+		name      string
+		responder db.PagingAuditResponder
+		want      string
+	}{
+		{"no deliveries yet", db.PagingAuditResponder{}, db.PagingOutcomeQueued},
+		{"one channel pending", db.PagingAuditResponder{Deliveries: []db.PagingAuditDelivery{
+			{Channel: "slack", Status: "failed"}, {Channel: "voice", Status: "queued"},
+		}}, db.PagingOutcomeQueued},
+		{"answered call", db.PagingAuditResponder{Deliveries: []db.PagingAuditDelivery{
+			{Channel: "voice", Status: "completed", Outcome: db.VoiceOutcomeNoInput},
+		}}, db.PagingOutcomeDelivered},
+		{"acknowledged by phone", db.PagingAuditResponder{Deliveries: []db.PagingAuditDelivery{
+			{Channel: "voice", Status: "completed", Outcome: db.VoiceOutcomeAcknowledged},
+		}}, db.PagingOutcomeAcknowledged},
+		{"opened", db.PagingAuditResponder{SeenAt: &seen}, db.PagingOutcomeSeen},
+	}
+	for _, tc := range cases {
+		if got := pagingOutcome(&tc.responder, false); got != tc.want {
+			t.Errorf("%s: outcome = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
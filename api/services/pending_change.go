@@ -0,0 +1,403 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// defaultPendingChangeTTL applies when pending_change_ttl is unset
+const defaultPendingChangeTTL = 24 * time.Hour
+
+var (
+	ErrPendingChangeNotFound = errors.New("pending change not found")
+	// ErrPendingChangeClosed means the change was already decided or expired
+	ErrPendingChangeClosed = errors.New("pending change is no longer pending")
+	// ErrPendingChangeSelfApproval means the requester tried to approve their own change
+	ErrPendingChangeSelfApproval = errors.New("a change must be approved by a different admin")
+)
+
+// PendingChangeService holds destructive admin operations until a second
+// org admin approves them, and runs them once approved
+type PendingChangeService struct {
+	PG                 *sql.DB
+	GroupService       *GroupService
+	APIKeyService      *APIKeyService
+	IntegrationService *IntegrationService
+}
+
+func NewPendingChangeService(pg *sql.DB, groupService *GroupService, apiKeyService *APIKeyService, integrationService *IntegrationService) *PendingChangeService {
+	return &PendingChangeService{
+		PG:                 pg,
+		GroupService:       groupService,
+		APIKeyService:      apiKeyService,
+		IntegrationService: integrationService,
+	}
+}
+
+// pendingChangeTTL is how long a change waits for approval
+func pendingChangeTTL() time.Duration {
+	if ttl := config.Current().PendingChangeTTL; ttl > 0 {
+		return ttl
+	}
+	return defaultPendingChangeTTL
+}
+
+const pendingChangeSelect = `
+	SELECT pc.id, pc.organization_id, pc.action, pc.resource_id, pc.resource_name, pc.payload,
+	       pc.reason, pc.status, pc.requested_by, COALESCE(ru.name, ''),
+	       COALESCE(pc.decided_by::text, ''), COALESCE(du.name, ''), pc.decided_at,
+	       pc.error, pc.expires_at, pc.created_at
+	FROM pending_changes pc
+	LEFT JOIN users ru ON ru.id = pc.requested_by
+	LEFT JOIN users du ON du.id = pc.decided_by`
+
+func scanPendingChange(row interface{ Scan(...interface{}) error }) (*db.PendingChange, error) {
+	var c db.PendingChange
+	var payload []byte
+	var decidedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.OrganizationID, &c.Action, &c.ResourceID, &c.ResourceName, &payload,
+		&c.Reason, &c.Status, &c.RequestedBy, &c.RequestedByName,
+		&c.DecidedBy, &c.DecidedByName, &decidedAt,
+		&c.Error, &c.ExpiresAt, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > 0 && string(payload) != "{}" {
+		c.Payload = payload
+	}
+	if decidedAt.Valid {
+		c.DecidedAt = &decidedAt.Time
+	}
+	return &c, nil
+}
+
+// GroupDeleteChange returns the change to hold for approval when deleting the
+// group would remove a team that still has members, or nil when the group can
+// be deleted straight away. Groups outside an organization have no admins to
+// approve and are never held.
+func (s *PendingChangeService) GroupDeleteChange(groupID string) (*db.PendingChange, error) {
+	var orgID, name string
+	var hasMembers bool
+	err := s.PG.QueryRow(`
+		SELECT COALESCE(g.organization_id::text, ''), g.name,
+		       EXISTS (SELECT 1 FROM memberships m WHERE m.resource_type = 'group' AND m.resource_id = g.id)
+		FROM groups g
+		WHERE g.id = $1 AND g.is_active = true
+	`, groupID).Scan(&orgID, &name, &hasMembers)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if !hasMembers || orgID == "" {
+		return nil, nil
+	}
+	return &db.PendingChange{
+		OrganizationID: orgID,
+		Action:         db.PendingChangeGroupDelete,
+		ResourceID:     groupID,
+		ResourceName:   name,
+	}, nil
+}
+
+// IntegrationDisableChange returns the change to hold for approval when the
+// update would disable an integration that feeds alerts into active services
+// outside test mode, or nil when the update can be applied straight away. The
+// whole update is held and applied on approval.
+func (s *PendingChangeService) IntegrationDisableChange(integrationID string, req db.UpdateIntegrationRequest) (*db.PendingChange, error) {
+	if req.IsActive == nil || *req.IsActive {
+		return nil, nil
+	}
+
+	var orgID, name string
+	var live bool
+	err := s.PG.QueryRow(`
+		SELECT COALESCE(i.organization_id::text, ''), i.name,
+		       i.is_active AND NOT COALESCE(i.test_mode, false) AND EXISTS (
+		           SELECT 1 FROM service_integrations si
+		           JOIN services s ON s.id = si.service_id
+		           WHERE si.integration_id = i.id AND si.is_active = true AND s.is_active = true
+		       )
+		FROM integrations i
+		WHERE i.id = $1
+	`, integrationID).Scan(&orgID, &name, &live)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integration: %w", err)
+	}
+	if !live || orgID == "" {
+		return nil, nil
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal integration update: %w", err)
+	}
+	return &db.PendingChange{
+		OrganizationID: orgID,
+		Action:         db.PendingChangeIntegrationDisable,
+		ResourceID:     integrationID,
+		ResourceName:   name,
+		Payload:        payload,
+	}, nil
+}
+
+// APIKeysRotateChange is the change that rotates every API key of an
+// organization; it always needs approval
+func APIKeysRotateChange(orgID string) *db.PendingChange {
+	return &db.PendingChange{OrganizationID: orgID, Action: db.PendingChangeAPIKeysRotate}
+}
+
+// RequestChange records a change for another admin to approve. If the same
+// operation on the same resource is already waiting, that request is returned
+// instead.
+func (s *PendingChangeService) RequestChange(change *db.PendingChange, userID, reason string) (*db.PendingChange, error) {
+	// Free the slot held by a request that expired before the worker got to it
+	if _, err := s.PG.Exec(`
+		UPDATE pending_changes SET status = 'expired'
+		WHERE organization_id = $1 AND action = $2 AND resource_id = $3
+		AND status = 'pending' AND expires_at <= NOW()
+	`, change.OrganizationID, change.Action, change.ResourceID); err != nil {
+		return nil, fmt.Errorf("failed to expire pending changes: %w", err)
+	}
+
+	payload := []byte(change.Payload)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	var id string
+	err := s.PG.QueryRow(`
+		INSERT INTO pending_changes (organization_id, action, resource_id, resource_name, payload, reason, requested_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (organization_id, action, resource_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id
+	`, change.OrganizationID, change.Action, change.ResourceID, change.ResourceName, payload, reason,
+		userID, time.Now().Add(pendingChangeTTL())).Scan(&id)
+	if err == sql.ErrNoRows {
+		existing, err := scanPendingChange(s.PG.QueryRow(pendingChangeSelect+`
+			WHERE pc.organization_id = $1 AND pc.action = $2 AND pc.resource_id = $3 AND pc.status = 'pending'
+		`, change.OrganizationID, change.Action, change.ResourceID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending change: %w", err)
+		}
+		return existing, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pending change: %w", err)
+	}
+
+	created, err := s.GetPendingChange(change.OrganizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.auditChange(created, userID, "admin.change_requested", "pending", "", reason)
+	return created, nil
+}
+
+// ListPendingChanges returns the organization's changes, newest first,
+// optionally only those with the given status
+func (s *PendingChangeService) ListPendingChanges(orgID, status string) ([]db.PendingChange, error) {
+	rows, err := s.PG.Query(pendingChangeSelect+`
+		WHERE pc.organization_id = $1 AND ($2 = '' OR pc.status = $2)
+		ORDER BY pc.created_at DESC
+		LIMIT 200
+	`, orgID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []db.PendingChange{}
+	for rows.Next() {
+		c, err := scanPendingChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending change: %w", err)
+		}
+		changes = append(changes, *c)
+	}
+	return changes, rows.Err()
+}
+
+// GetPendingChange returns one of the organization's changes
+func (s *PendingChangeService) GetPendingChange(orgID, id string) (*db.PendingChange, error) {
+	c, err := scanPendingChange(s.PG.QueryRow(pendingChangeSelect+`
+		WHERE pc.id = $1 AND pc.organization_id = $2
+	`, id, orgID))
+	if err == sql.ErrNoRows {
+		return nil, ErrPendingChangeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending change: %w", err)
+	}
+	return c, nil
+}
+
+// openPendingChange loads a change that can still be decided
+func (s *PendingChangeService) openPendingChange(orgID, id string) (*db.PendingChange, error) {
+	c, err := s.GetPendingChange(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status == db.PendingChangeStatusPending && !c.ExpiresAt.After(time.Now()) {
+		s.expireChange(c)
+		return nil, ErrPendingChangeClosed
+	}
+	if c.Status != db.PendingChangeStatusPending {
+		return nil, ErrPendingChangeClosed
+	}
+	return c, nil
+}
+
+// decideChange moves a pending change to status if nobody decided it first
+func (s *PendingChangeService) decideChange(c *db.PendingChange, status, userID string) error {
+	result, err := s.PG.Exec(`
+		UPDATE pending_changes SET status = $3, decided_by = $4, decided_at = NOW()
+		WHERE id = $1 AND organization_id = $2 AND status = 'pending' AND expires_at > NOW()
+	`, c.ID, c.OrganizationID, status, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update pending change: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPendingChangeClosed
+	}
+	return nil
+}
+
+// ApproveChange approves a change requested by another admin and runs it.
+// The result is whatever the operation returns, such as the rotated keys.
+func (s *PendingChangeService) ApproveChange(orgID, id, userID string) (*db.PendingChange, interface{}, error) {
+	c, err := s.openPendingChange(orgID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.RequestedBy == userID {
+		return nil, nil, ErrPendingChangeSelfApproval
+	}
+	if err := s.decideChange(c, db.PendingChangeStatusApproved, userID); err != nil {
+		return nil, nil, err
+	}
+	s.auditChange(c, userID, "admin.change_approved", "success", "", "")
+
+	result, execErr := s.executeChange(c)
+	if execErr != nil {
+		log.Printf("PendingChange: failed to run %s %s: %v", c.Action, c.ID, execErr)
+		if _, err := s.PG.Exec(`UPDATE pending_changes SET status = 'failed', error = $2 WHERE id = $1`,
+			c.ID, execErr.Error()); err != nil {
+			log.Printf("PendingChange: failed to mark %s failed: %v", c.ID, err)
+		}
+		s.auditChange(c, userID, "admin.change_executed", "failure", execErr.Error(), "")
+	} else {
+		s.auditChange(c, userID, "admin.change_executed", "success", "", "")
+	}
+
+	updated, err := s.GetPendingChange(orgID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, result, nil
+}
+
+// RejectChange turns a change down. The requester may withdraw their own.
+func (s *PendingChangeService) RejectChange(orgID, id, userID, reason string) (*db.PendingChange, error) {
+	c, err := s.openPendingChange(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decideChange(c, db.PendingChangeStatusRejected, userID); err != nil {
+		return nil, err
+	}
+	s.auditChange(c, userID, "admin.change_rejected", "success", "", reason)
+	return s.GetPendingChange(orgID, id)
+}
+
+// executeChange runs an approved operation
+func (s *PendingChangeService) executeChange(c *db.PendingChange) (interface{}, error) {
+	switch c.Action {
+	case db.PendingChangeGroupDelete:
+		return nil, s.GroupService.DeleteGroup(c.ResourceID)
+	case db.PendingChangeAPIKeysRotate:
+		return s.APIKeyService.RotateOrgAPIKeys(c.OrganizationID)
+	case db.PendingChangeIntegrationDisable:
+		var req db.UpdateIntegrationRequest
+		if err := json.Unmarshal(c.Payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid integration update: %w", err)
+		}
+		return s.IntegrationService.UpdateIntegration(c.ResourceID, req)
+	}
+	return nil, fmt.Errorf("unknown pending change action %q", c.Action)
+}
+
+// expireChange marks a lapsed change expired and records it
+func (s *PendingChangeService) expireChange(c *db.PendingChange) {
+	result, err := s.PG.Exec(`UPDATE pending_changes SET status = 'expired' WHERE id = $1 AND status = 'pending'`, c.ID)
+	if err != nil {
+		log.Printf("PendingChange: failed to expire %s: %v", c.ID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.auditChange(c, c.RequestedBy, "admin.change_expired", "failure", "not approved in time", "")
+	}
+}
+
+// ExpirePendingChanges expires every change that waited past its deadline
+func (s *PendingChangeService) ExpirePendingChanges() (int, error) {
+	rows, err := s.PG.Query(pendingChangeSelect + `
+		WHERE pc.status = 'pending' AND pc.expires_at <= NOW()
+		LIMIT 500
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get lapsed pending changes: %w", err)
+	}
+	var lapsed []*db.PendingChange
+	for rows.Next() {
+		c, err := scanPendingChange(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pending change: %w", err)
+		}
+		lapsed = append(lapsed, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get lapsed pending changes: %w", err)
+	}
+
+	for _, c := range lapsed {
+		s.expireChange(c)
+	}
+	return len(lapsed), nil
+}
+
+// auditChange writes a step of the approval workflow to the audit log, which
+// is exported to SIEM with the other audit events. reason is the reason given
+// for this step, if any.
+func (s *PendingChangeService) auditChange(c *db.PendingChange, userID, eventType, status, errorMessage, reason string) {
+	params, _ := json.Marshal(map[string]interface{}{
+		"target_id":   c.ResourceID,
+		"target_name": c.ResourceName,
+		"reason":      reason,
+	})
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"requested_by": c.RequestedBy,
+		"expires_at":   c.ExpiresAt,
+	})
+	_, err := s.PG.Exec(`
+		INSERT INTO agent_audit_logs (event_id, event_type, event_category, user_id, org_id, action,
+			resource_type, resource_id, request_params, status, error_message, metadata)
+		VALUES ($1, $2, 'admin', $3, $4, $5, 'pending_change', $6, $7, $8, NULLIF($9, ''), $10)
+	`, uuid.New().String(), eventType, userID, c.OrganizationID, c.Action, c.ID, params, status, errorMessage, metadata)
+	if err != nil {
+		log.Printf("PendingChange: failed to write audit log for %s: %v", c.ID, err)
+	}
+}
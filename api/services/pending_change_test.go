@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var pendingChangeColumns = []string{"id", "organization_id", "action", "resource_id", "resource_name", "payload",
+	"reason", "status", "requested_by", "requested_by_name", "decided_by", "decided_by_name", "decided_at",
+	"error", "expires_at", "created_at"}
+
+func pendingGroupDeleteRow(status, decidedBy string, expiresAt time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows(pendingChangeColumns).AddRow("pc-1", "org-1", db.PendingChangeGroupDelete, "grp-1", "Payments",
+		[]byte("{}"), "team disbanded", status, "alice", "Alice", decidedBy, "", nil, "", expiresAt, time.Now())
+}
+
+func TestApproveChange(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := NewPendingChangeService(pg, NewGroupService(pg), nil, nil)
+	expiresAt := time.Now().Add(time.Hour)
+
+	// The requester cannot approve their own change
+	mock.ExpectQuery(`FROM pending_changes pc`).WithArgs("pc-1", "org-1").
+		WillReturnRows(pendingGroupDeleteRow(db.PendingChangeStatusPending, "", expiresAt))
+	if _, _, err := s.ApproveChange("org-1", "pc-1", "alice"); !errors.Is(err, ErrPendingChangeSelfApproval) {
+		t.Errorf("self approval err = %v", err)
+	}
+
+	// A second admin approves; the group is deleted and each step audited
+	mock.ExpectQuery(`FROM pending_changes pc`).WithArgs("pc-1", "org-1").
+		WillReturnRows(pendingGroupDeleteRow(db.PendingChangeStatusPending, "", expiresAt))
+	mock.ExpectExec(`UPDATE pending_changes SET status = \$3`).WithArgs("pc-1", "org-1", db.PendingChangeStatusApproved, "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO agent_audit_logs`).
+		WithArgs(sqlmock.AnyArg(), "admin.change_approved", "bob", "org-1", db.PendingChangeGroupDelete, "pc-1",
+			sqlmock.AnyArg(), "success", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE groups SET is_active = false`).WithArgs(sqlmock.AnyArg(), "grp-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO agent_audit_logs`).
+		WithArgs(sqlmock.AnyArg(), "admin.change_executed", "bob", "org-1", db.PendingChangeGroupDelete, "pc-1",
+			sqlmock.AnyArg(), "success", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM pending_changes pc`).WithArgs("pc-1", "org-1").
+		WillReturnRows(pendingGroupDeleteRow(db.PendingChangeStatusApproved, "bob", expiresAt))
+
+	change, _, err := s.ApproveChange("org-1", "pc-1", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Status != db.PendingChangeStatusApproved || change.DecidedBy != "bob" {
+		t.Errorf("change = %+v", change)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApproveExpiredChange(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := NewPendingChangeService(pg, nil, nil, nil)
+
+	mock.ExpectQuery(`FROM pending_changes pc`).WithArgs("pc-1", "org-1").
+		WillReturnRows(pendingGroupDeleteRow(db.PendingChangeStatusPending, "", time.Now().Add(-time.Minute)))
+	mock.ExpectExec(`UPDATE pending_changes SET status = 'expired'`).WithArgs("pc-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO agent_audit_logs`).
+		WithArgs(sqlmock.AnyArg(), "admin.change_expired", "alice", "org-1", db.PendingChangeGroupDelete, "pc-1",
+			sqlmock.AnyArg(), "failure", "not approved in time", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, _, err := s.ApproveChange("org-1", "pc-1", "bob"); !errors.Is(err, ErrPendingChangeClosed) {
+		t.Errorf("err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/llm"
+)
+
+var ErrPostmortemExists = errors.New("incident already has a postmortem written or edited by a person")
+
+const (
+	postmortemDraftFeature   = "postmortem_draft"
+	postmortemMaxEvents      = 300
+	postmortemMaxChat        = 200
+	postmortemMaxLineBytes   = 1000
+	postmortemDraftMaxTokens = 4096
+)
+
+const postmortemSystemPrompt = `You write blameless incident postmortem drafts for on-call teams.
+Use only the facts in the incident record you are given. Where it doesn't say, write "Unknown" instead of guessing.
+Answer with one JSON object and nothing else, with these keys:
+  "summary", "impact", "root_cause", "resolution": strings
+  "timeline": array of {"time": UTC timestamp from the record, "description": string}
+  "action_items", "lessons_learned": arrays of strings`
+
+// postmortemContext is the incident record a draft is written from
+type postmortemContext struct {
+	incident *db.IncidentResponse
+	events   []db.IncidentEvent
+	notes    []db.IncidentEvent
+	chat     []postmortemChatMessage
+}
+
+type postmortemChatMessage struct {
+	role      string
+	content   string
+	createdAt time.Time
+}
+
+// DraftPostmortem asks the organization's language model for a postmortem of
+// the incident, written from its timeline, alerts, notes and the AI chat
+// sessions that attached artifacts to it, and saves it as the incident's
+// postmortem for people to edit. The draft carries provenance saying it is
+// AI-generated. An existing postmortem is only replaced if it is an unedited
+// AI draft or req.Overwrite is set.
+func (s *IncidentService) DraftPostmortem(ctx context.Context, incidentID, userID string, req db.DraftPostmortemRequest) (*db.PostmortemDraft, error) {
+	if s.LLM == nil {
+		return nil, ErrLLMNotConfigured
+	}
+	incident, err := s.GetIncident(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	existing, _ := incident.CustomFields[db.PostmortemField].(string)
+	if existing != "" && !req.Overwrite && !isUneditedAIPostmortem(incident.CustomFields) {
+		return nil, ErrPostmortemExists
+	}
+
+	pc, err := s.loadPostmortemContext(incident)
+	if err != nil {
+		return nil, err
+	}
+	prompt := buildPostmortemPrompt(pc)
+	if instructions := strings.TrimSpace(req.Instructions); instructions != "" {
+		prompt += "\n# Instructions from the requester\n" + instructions + "\n"
+	}
+	// The record may hold credentials pasted into notes or chat, and the
+	// provider may be a third party
+	prompt, _ = redactArtifactContent(prompt)
+
+	temperature := 0.2
+	resp, err := s.LLM.Complete(ctx, incident.OrganizationID, userID, postmortemDraftFeature, llm.Request{
+		System:      postmortemSystemPrompt,
+		Messages:    []llm.Message{{Role: llm.RoleUser, Content: prompt}},
+		MaxTokens:   postmortemDraftMaxTokens,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	draft := parsePostmortemDraft(resp.Text)
+	draft.IncidentID = incidentID
+	draft.Provenance = db.PostmortemProvenance{
+		GeneratedBy:  db.PostmortemGeneratedByAI,
+		Provider:     resp.Provider,
+		Model:        resp.Model,
+		GeneratedAt:  time.Now().UTC(),
+		RequestedBy:  userID,
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+		Sources:      db.PostmortemSources{Events: len(pc.events), Notes: len(pc.notes), ChatMessages: len(pc.chat)},
+	}
+	draft.Markdown = renderPostmortemMarkdown(incident, draft)
+	draft.Provenance.ContentSHA256 = postmortemHash(draft.Markdown)
+
+	provenanceJSON, err := json.Marshal(draft.Provenance)
+	if err != nil {
+		return nil, err
+	}
+	// Only replace the postmortem the decision above was made on, so an edit
+	// saved while the model was writing isn't lost
+	result, err := s.PG.Exec(`
+		UPDATE incidents
+		SET custom_fields = COALESCE(custom_fields, '{}'::jsonb) || jsonb_build_object($2::text, $3::text, $4::text, $5::jsonb),
+		    updated_at = NOW()
+		WHERE id = $1 AND COALESCE(custom_fields->>$2, '') = $6
+	`, incidentID, db.PostmortemField, draft.Markdown, db.PostmortemProvenanceField, string(provenanceJSON), existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save postmortem draft: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrPostmortemExists
+	}
+
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventPostmortemDrafted, map[string]interface{}{
+		"generated_by": db.PostmortemGeneratedByAI,
+		"provider":     draft.Provenance.Provider,
+		"model":        draft.Provenance.Model,
+		"replaced":     existing != "",
+	}, userID); err != nil {
+		log.Printf("Failed to record postmortem draft event for incident %s: %v", incidentID, err)
+	}
+	return draft, nil
+}
+
+// isUneditedAIPostmortem reports whether the incident's postmortem is still
+// exactly what a model drafted
+func isUneditedAIPostmortem(customFields map[string]interface{}) bool {
+	text, _ := customFields[db.PostmortemField].(string)
+	provenance, _ := customFields[db.PostmortemProvenanceField].(map[string]interface{})
+	if text == "" || provenance == nil || provenance["generated_by"] != db.PostmortemGeneratedByAI {
+		return false
+	}
+	return provenance["content_sha256"] == postmortemHash(text)
+}
+
+func postmortemHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *IncidentService) loadPostmortemContext(incident *db.IncidentResponse) (*postmortemContext, error) {
+	pc := &postmortemContext{incident: incident}
+
+	events, err := s.GetIncidentEvents(incident.ID, postmortemMaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	// Oldest first; the most recent events are kept when there are too many
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	for _, event := range events {
+		if event.EventType == db.IncidentEventNoteAdded {
+			pc.notes = append(pc.notes, event)
+		} else {
+			pc.events = append(pc.events, event)
+		}
+	}
+
+	// Chat sessions are linked to the incident by the artifacts they attached
+	rows, err := s.PG.Query(`
+		SELECT role, content, created_at
+		FROM claude_messages
+		WHERE conversation_id IN (
+		        SELECT DISTINCT session_id FROM incident_artifacts
+		        WHERE incident_id = $1 AND session_id IS NOT NULL AND session_id <> ''
+		      )
+		  AND message_type = 'text' AND role IN ('user', 'assistant') AND COALESCE(content, '') <> ''
+		ORDER BY created_at
+		LIMIT $2
+	`, incident.ID, postmortemMaxChat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident chat history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m postmortemChatMessage
+		var createdAt sql.NullTime
+		if err := rows.Scan(&m.role, &m.content, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		if createdAt.Valid {
+			m.createdAt = createdAt.Time
+		}
+		pc.chat = append(pc.chat, m)
+	}
+	return pc, rows.Err()
+}
+
+// postmortemLine flattens and shortens text for the prompt
+func postmortemLine(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if short, truncated := truncateArtifactContent(text, postmortemMaxLineBytes); truncated {
+		return short + "…"
+	}
+	return text
+}
+
+func postmortemTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func buildPostmortemPrompt(pc *postmortemContext) string {
+	inc := pc.incident
+	var b strings.Builder
+
+	b.WriteString("# Incident\n")
+	fmt.Fprintf(&b, "Title: %s\n", postmortemLine(inc.Title))
+	if inc.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", postmortemLine(inc.Description))
+	}
+	fmt.Fprintf(&b, "Status: %s, urgency: %s", inc.Status, inc.Urgency)
+	if inc.Severity != "" {
+		fmt.Fprintf(&b, ", severity: %s", inc.Severity)
+	}
+	if inc.Priority != "" {
+		fmt.Fprintf(&b, ", priority: %s", inc.Priority)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Triggered: %s\n", postmortemTime(inc.CreatedAt))
+	if inc.AcknowledgedAt != nil {
+		fmt.Fprintf(&b, "Acknowledged: %s by %s\n", postmortemTime(*inc.AcknowledgedAt), inc.AcknowledgedByName)
+	}
+	if inc.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved: %s by %s\n", postmortemTime(*inc.ResolvedAt), inc.ResolvedByName)
+	}
+
+	b.WriteString("\n# Alerts\n")
+	fmt.Fprintf(&b, "Source: %s, alerts received: %d\n", inc.Source, inc.AlertCount)
+	if inc.IncidentKey != "" {
+		fmt.Fprintf(&b, "Alert key: %s\n", inc.IncidentKey)
+	}
+	if len(inc.Labels) > 0 {
+		keys := make([]string, 0, len(inc.Labels))
+		for k := range inc.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels := make([]string, 0, len(keys))
+		for _, k := range keys {
+			labels = append(labels, fmt.Sprintf("%s=%v", k, inc.Labels[k]))
+		}
+		fmt.Fprintf(&b, "Labels: %s\n", postmortemLine(strings.Join(labels, ", ")))
+	}
+
+	b.WriteString("\n# Timeline\n")
+	for _, event := range pc.events {
+		line := fmt.Sprintf("- %s %s", postmortemTime(event.CreatedAt), event.EventType)
+		if event.CreatedByName != "" {
+			line += " by " + event.CreatedByName
+		}
+		if len(event.EventData) > 0 {
+			data, _ := json.Marshal(event.EventData)
+			line += ": " + postmortemLine(string(data))
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if len(pc.notes) > 0 {
+		b.WriteString("\n# Responder notes\n")
+		for _, note := range pc.notes {
+			text, _ := note.EventData["note"].(string)
+			fmt.Fprintf(&b, "- %s %s: %s\n", postmortemTime(note.CreatedAt), note.CreatedByName, postmortemLine(text))
+		}
+	}
+
+	if len(pc.chat) > 0 {
+		b.WriteString("\n# AI assistant chat during the incident\n")
+		for _, m := range pc.chat {
+			fmt.Fprintf(&b, "- %s %s: %s\n", postmortemTime(m.createdAt), m.role, postmortemLine(m.content))
+		}
+	}
+	return b.String()
+}
+
+// parsePostmortemDraft reads the model's JSON answer. Models, local ones
+// especially, sometimes wrap it in a code fence or prose; anything that isn't
+// a JSON object is kept as the draft's text.
+func parsePostmortemDraft(text string) *db.PostmortemDraft {
+	draft := &db.PostmortemDraft{}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start >= 0 && end > start {
+		var out struct {
+			Summary        string                       `json:"summary"`
+			Impact         string                       `json:"impact"`
+			RootCause      string                       `json:"root_cause"`
+			Resolution     string                       `json:"resolution"`
+			Timeline       []db.PostmortemTimelineEntry `json:"timeline"`
+			ActionItems    []string                     `json:"action_items"`
+			LessonsLearned []string                     `json:"lessons_learned"`
+		}
+		if json.Unmarshal([]byte(text[start:end+1]), &out) == nil && out.Summary != "" {
+			draft.Summary, draft.Impact, draft.RootCause, draft.Resolution = out.Summary, out.Impact, out.RootCause, out.Resolution
+			draft.Timeline, draft.ActionItems, draft.LessonsLearned = out.Timeline, out.ActionItems, out.LessonsLearned
+			draft.Structured = true
+			return draft
+		}
+	}
+	draft.Markdown = strings.TrimSpace(text)
+	return draft
+}
+
+// renderPostmortemMarkdown writes the draft as the postmortem text people
+// edit, headed by a notice that it is AI-generated
+func renderPostmortemMarkdown(incident *db.IncidentResponse, draft *db.PostmortemDraft) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Postmortem: %s\n\n", incident.Title)
+	fmt.Fprintf(&b, "> **AI-generated draft** by %s (%s) on %s. Review and edit before sharing.\n\n",
+		draft.Provenance.Model, draft.Provenance.Provider, draft.Provenance.GeneratedAt.Format("2006-01-02 15:04 MST"))
+
+	if !draft.Structured {
+		b.WriteString(draft.Markdown + "\n")
+		return b.String()
+	}
+
+	section := func(title, body string) {
+		if body = strings.TrimSpace(body); body != "" {
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", title, body)
+		}
+	}
+	list := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "## %s\n\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		b.WriteString("\n")
+	}
+
+	section("Summary", draft.Summary)
+	section("Impact", draft.Impact)
+	if len(draft.Timeline) > 0 {
+		b.WriteString("## Timeline\n\n")
+		for _, entry := range draft.Timeline {
+			fmt.Fprintf(&b, "- **%s** %s\n", entry.Time, entry.Description)
+		}
+		b.WriteString("\n")
+	}
+	section("Root cause", draft.RootCause)
+	section("Resolution", draft.Resolution)
+	list("Action items", draft.ActionItems)
+	list("Lessons learned", draft.LessonsLearned)
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
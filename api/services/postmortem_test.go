@@ -0,0 +1,93 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParsePostmortemDraft(t *testing.T) {
+	fenced := "Here is the draft:\n```json\n" +
+		`{"summary":"Checkout was down","impact":"5xx for 12 minutes","root_cause":"Bad deploy",` +
+		`"resolution":"Rolled back","timeline":[{"time":"2026-04-01T10:00:00Z","description":"Alert fired"}],` +
+		`"action_items":["Add canary"],"lessons_learned":["Deploys need canaries"]}` + "\n```"
+	draft := parsePostmortemDraft(fenced)
+	if !draft.Structured || draft.Summary != "Checkout was down" || len(draft.Timeline) != 1 || draft.ActionItems[0] != "Add canary" {
+		t.Errorf("fenced JSON not parsed: %+v", draft)
+	}
+
+	draft = parsePostmortemDraft("The incident was caused by {something}.")
+	if draft.Structured || draft.Markdown != "The incident was caused by {something}." {
+		t.Errorf("prose should be kept as is: %+v", draft)
+	}
+}
+
+func TestRenderPostmortemMarkdownMarksAIGenerated(t *testing.T) {
+	incident := &db.IncidentResponse{Incident: db.Incident{Title: "Checkout down"}}
+	draft := &db.PostmortemDraft{
+		Summary:     "Checkout was down",
+		RootCause:   "Bad deploy",
+		ActionItems: []string{"Add canary"},
+		Structured:  true,
+		Provenance: db.PostmortemProvenance{
+			GeneratedBy: db.PostmortemGeneratedByAI, Provider: LLMProviderOllama, Model: "llama3.1",
+			GeneratedAt: time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	md := renderPostmortemMarkdown(incident, draft)
+	for _, want := range []string{"# Postmortem: Checkout down", "**AI-generated draft** by llama3.1 (ollama)",
+		"## Summary\n\nCheckout was down", "## Root cause", "- Add canary"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "## Impact") {
+		t.Error("empty sections should be left out")
+	}
+}
+
+func TestIsUneditedAIPostmortem(t *testing.T) {
+	text := "# Postmortem: Checkout down\n"
+	fields := map[string]interface{}{
+		db.PostmortemField: text,
+		db.PostmortemProvenanceField: map[string]interface{}{
+			"generated_by":   db.PostmortemGeneratedByAI,
+			"content_sha256": postmortemHash(text),
+		},
+	}
+	if !isUneditedAIPostmortem(fields) {
+		t.Error("untouched draft should be replaceable")
+	}
+
+	fields[db.PostmortemField] = text + "Edited by a responder\n"
+	if isUneditedAIPostmortem(fields) {
+		t.Error("edited draft must not be replaced")
+	}
+	if isUneditedAIPostmortem(map[string]interface{}{db.PostmortemField: "Written by hand"}) {
+		t.Error("hand-written postmortem must not be replaced")
+	}
+}
+
+func TestBuildPostmortemPrompt(t *testing.T) {
+	created := time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+	pc := &postmortemContext{
+		incident: &db.IncidentResponse{Incident: db.Incident{
+			Title: "Checkout down", Status: db.IncidentStatusResolved, Urgency: db.IncidentUrgencyHigh,
+			Source: "datadog", AlertCount: 3, CreatedAt: created,
+			Labels: map[string]interface{}{"service": "checkout"},
+		}},
+		events: []db.IncidentEvent{{EventType: db.IncidentEventTriggered, CreatedAt: created}},
+		notes: []db.IncidentEvent{{EventType: db.IncidentEventNoteAdded, CreatedAt: created.Add(time.Minute),
+			CreatedByName: "Alice", EventData: map[string]interface{}{"note": "rolling back"}}},
+		chat: []postmortemChatMessage{{role: "assistant", content: "Error rate spiked after deploy", createdAt: created}},
+	}
+	prompt := buildPostmortemPrompt(pc)
+	for _, want := range []string{"Title: Checkout down", "alerts received: 3", "Labels: service=checkout",
+		"2026-04-01T10:00:00Z triggered", "Alice: rolling back", "assistant: Error rate spiked after deploy"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+const (
+	readTokenPrefix      = "ro_"
+	defaultReadIncidents = 100
+	maxReadIncidents     = 500
+)
+
+var (
+	ErrReadTokenNotFound     = errors.New("read token not found")
+	ErrReadTokenUnauthorized = errors.New("invalid read token")
+	ErrInvalidReadToken      = errors.New("invalid read token request")
+)
+
+// groupAccessScopeSQL restricts groups (aliased g) to those the user ($1) can
+// see in the organization ($2), as ListGroups does
+const groupAccessScopeSQL = `
+			g.organization_id = $2
+			AND (
+				EXISTS (
+					SELECT 1 FROM memberships m
+					WHERE m.user_id = $1 AND m.resource_type = 'group' AND m.resource_id = g.id
+				)
+				OR (
+					g.visibility IN ('organization', 'public')
+					AND EXISTS (
+						SELECT 1 FROM memberships m
+						WHERE m.user_id = $1 AND m.resource_type = 'org' AND m.resource_id = $2
+					)
+				)
+			)
+`
+
+// ReadTokenService issues read-only tokens that users create for dashboards
+// and portals, and serves the few endpoints those tokens may call. Every read
+// is made as the token's owner, so a token never sees more than they do.
+type ReadTokenService struct {
+	PG *sql.DB
+}
+
+// NewReadTokenService creates a new ReadTokenService
+func NewReadTokenService(pg *sql.DB) *ReadTokenService {
+	return &ReadTokenService{PG: pg}
+}
+
+// normalizeReadScopes checks scopes against db.ValidReadScopes and drops duplicates
+func normalizeReadScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidReadToken)
+	}
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, scope := range scopes {
+		if !containsString(db.ValidReadScopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidReadToken, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// CreateToken issues a read token owned by userID. The caller must already
+// have checked that the user can access the organization and project.
+// The raw token is only returned once.
+func (s *ReadTokenService) CreateToken(userID string, req db.CreateReadTokenRequest) (*db.ReadToken, error) {
+	scopes, err := normalizeReadScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidReadToken)
+	}
+
+	raw, _, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	raw = readTokenPrefix + raw
+
+	t := db.ReadToken{UserID: userID, OrganizationID: req.OrganizationID, ProjectID: req.ProjectID, Name: req.Name,
+		Scopes: scopes, IsActive: true, ExpiresAt: req.ExpiresAt, Token: raw}
+	err = s.PG.QueryRow(`
+		INSERT INTO read_tokens (user_id, organization_id, project_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, userID, req.OrganizationID, nullIfEmpty(req.ProjectID), req.Name, hashInvitationToken(raw),
+		pq.Array(scopes), req.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create read token: %w", err)
+	}
+	return &t, nil
+}
+
+// ListTokens returns a user's read tokens (without secrets)
+func (s *ReadTokenService) ListTokens(userID string) ([]db.ReadToken, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, user_id, organization_id, COALESCE(project_id::text, ''), name, scopes, is_active,
+		       expires_at, last_used_at, created_at
+		FROM read_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []db.ReadToken{}
+	for rows.Next() {
+		var t db.ReadToken
+		var scopes pq.StringArray
+		var expiresAt, lastUsedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.OrganizationID, &t.ProjectID, &t.Name, &scopes, &t.IsActive,
+			&expiresAt, &lastUsedAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.Scopes = []string(scopes)
+		t.ExpiresAt = nullTimePtr(expiresAt)
+		t.LastUsedAt = nullTimePtr(lastUsedAt)
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken deactivates one of a user's read tokens
+func (s *ReadTokenService) RevokeToken(userID, tokenID string) error {
+	result, err := s.PG.Exec(`UPDATE read_tokens SET is_active = FALSE WHERE id = $1 AND user_id = $2`, tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrReadTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateToken resolves a raw read token. Tokens stop working once they
+// expire or their owner is deactivated or leaves the organization.
+func (s *ReadTokenService) AuthenticateToken(raw string) (*db.ReadToken, error) {
+	var t db.ReadToken
+	var scopes pq.StringArray
+	err := s.PG.QueryRow(`
+		SELECT t.id, t.user_id, t.organization_id, COALESCE(t.project_id::text, ''), t.name, t.scopes
+		FROM read_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1 AND t.is_active = TRUE
+		  AND (t.expires_at IS NULL OR t.expires_at > NOW())
+		  AND u.is_active = TRUE
+		  AND EXISTS (
+			SELECT 1 FROM memberships m
+			WHERE m.user_id = t.user_id AND m.resource_type = 'org' AND m.resource_id = t.organization_id
+		  )
+	`, hashInvitationToken(raw)).Scan(&t.ID, &t.UserID, &t.OrganizationID, &t.ProjectID, &t.Name, &scopes)
+	if err == sql.ErrNoRows {
+		return nil, ErrReadTokenUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Scopes = []string(scopes)
+	t.IsActive = true
+
+	go func() {
+		if _, err := s.PG.Exec(`UPDATE read_tokens SET last_used_at = NOW() WHERE id = $1`, t.ID); err != nil {
+			log.Printf("Warning: failed to update read token last_used_at: %v", err)
+		}
+	}()
+	return &t, nil
+}
+
+// ListIncidents returns the incidents the token's owner can see, newest
+// first. status is "open" (triggered or acknowledged, the default), "all" or
+// a single status.
+func (s *ReadTokenService) ListIncidents(token *db.ReadToken, status string, limit int) ([]db.WallboardIncident, error) {
+	if limit <= 0 {
+		limit = defaultReadIncidents
+	}
+	if limit > maxReadIncidents {
+		limit = maxReadIncidents
+	}
+
+	query := `
+		SELECT i.id, i.title, i.status, i.urgency, COALESCE(i.severity, ''),
+		       COALESCE(s.name, ''), COALESCE(g.name, ''), COALESCE(u.name, ''), i.created_at
+		FROM incidents i
+		LEFT JOIN services s ON i.service_id = s.id
+		LEFT JOIN groups g ON i.group_id = g.id
+		LEFT JOIN users u ON i.assigned_to = u.id
+		WHERE
+` + incidentAccessScopeSQL + `
+		  AND NOT i.is_test
+		  AND ($3 = '' OR i.project_id::text = $3)`
+	args := []interface{}{token.UserID, token.OrganizationID, token.ProjectID, limit}
+
+	switch status {
+	case "", "open":
+		query += " AND i.status IN ('triggered', 'acknowledged')"
+	case "all":
+	default:
+		query += " AND i.status = $5"
+		args = append(args, status)
+	}
+	query += " ORDER BY i.created_at DESC LIMIT $4"
+
+	return queryWallboardIncidents(s.PG, query, args...)
+}
+
+// GetOnCall returns who is on call now for every active group the token's
+// owner can see. Groups with nobody on call are included so gaps are visible.
+func (s *ReadTokenService) GetOnCall(token *db.ReadToken) ([]db.WallboardOnCall, error) {
+	query := `
+		SELECT g.id, g.name, COALESCE(u.name, ''), COALESCE(u.email, ''), cur.end_time, COALESCE(cur.is_override, FALSE)
+		FROM groups g
+		LEFT JOIN LATERAL (
+			SELECT sh.user_id, sh.end_time, sh.is_override
+			FROM shifts sh
+			WHERE sh.group_id = g.id
+			  AND sh.is_active = true
+			  AND NOW() BETWEEN sh.start_time AND sh.end_time
+			ORDER BY sh.is_override DESC, sh.start_time ASC
+			LIMIT 1
+		) cur ON TRUE
+		LEFT JOIN users u ON cur.user_id = u.id
+		WHERE
+` + groupAccessScopeSQL + `
+		  AND g.is_active = true
+		  AND ($3 = '' OR g.project_id::text = $3)
+		ORDER BY g.name ASC`
+	return queryWallboardOnCall(s.PG, query, token.UserID, token.OrganizationID, token.ProjectID)
+}
+
+// GetMonitorStatus returns the last check of every active uptime monitor
+// belonging to a group the token's owner can see
+func (s *ReadTokenService) GetMonitorStatus(token *db.ReadToken) (*db.WallboardMonitors, error) {
+	query := `
+		SELECT m.id, m.name, COALESCE(g.name, ''), m.is_up, m.last_latency, COALESCE(m.last_error, ''), m.last_check_at
+		FROM monitors m
+		JOIN monitor_deployments d ON m.deployment_id = d.id
+		JOIN groups g ON d.group_id = g.id
+		WHERE
+` + groupAccessScopeSQL + `
+		  AND m.is_active = true
+		  AND ($3 = '' OR g.project_id::text = $3)
+		ORDER BY m.is_up ASC NULLS FIRST, m.name ASC`
+	return queryWallboardMonitors(s.PG, query, token.UserID, token.OrganizationID, token.ProjectID)
+}
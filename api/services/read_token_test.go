@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestNormalizeReadScopes(t *testing.T) {
+	got, err := normalizeReadScopes([]string{db.ReadScopeIncidents, db.ReadScopeMonitors, db.ReadScopeIncidents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != db.ReadScopeIncidents || got[1] != db.ReadScopeMonitors {
+		t.Errorf("normalizeReadScopes = %v, want [incidents:read monitors:read]", got)
+	}
+
+	for _, scopes := range [][]string{nil, {"incidents:write"}, {db.ReadScopeOnCall, "manage_services"}} {
+		if _, err := normalizeReadScopes(scopes); !errors.Is(err, ErrInvalidReadToken) {
+			t.Errorf("normalizeReadScopes(%v) error = %v, want ErrInvalidReadToken", scopes, err)
+		}
+	}
+}
+
+func TestCreateReadTokenRejectsPastExpiry(t *testing.T) {
+	pg, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	past := time.Now().Add(-time.Hour)
+	_, err = NewReadTokenService(pg).CreateToken("user-1", db.CreateReadTokenRequest{
+		Name: "grafana", OrganizationID: "org-1", Scopes: []string{db.ReadScopeIncidents}, ExpiresAt: &past,
+	})
+	if !errors.Is(err, ErrInvalidReadToken) {
+		t.Errorf("CreateToken error = %v, want ErrInvalidReadToken", err)
+	}
+}
+
+func TestAuthenticateReadTokenUnknown(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM read_tokens t`).
+		WithArgs(hashInvitationToken("ro_nope")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "organization_id", "project_id", "name", "scopes"}))
+
+	if _, err := NewReadTokenService(pg).AuthenticateToken("ro_nope"); !errors.Is(err, ErrReadTokenUnauthorized) {
+		t.Errorf("AuthenticateToken error = %v, want ErrReadTokenUnauthorized", err)
+	}
+}
+
+func TestReadTokenListIncidentsScopesToOwner(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	token := &db.ReadToken{UserID: "user-1", OrganizationID: "org-1", ProjectID: "proj-1"}
+	columns := []string{"id", "title", "status", "urgency", "severity", "service", "group", "assignee", "created_at"}
+
+	mock.ExpectQuery(`m.user_id = \$1.*i.status IN \('triggered', 'acknowledged'\).*LIMIT \$4`).
+		WithArgs("user-1", "org-1", "proj-1", maxReadIncidents).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("inc-1", "DB down", "triggered", "high", "critical", "db", "dba", "", time.Now()))
+	mock.ExpectQuery(`i.status = \$5`).
+		WithArgs("user-1", "org-1", "proj-1", defaultReadIncidents, "resolved").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	s := NewReadTokenService(pg)
+	incidents, err := s.ListIncidents(token, "", 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || incidents[0].ID != "inc-1" {
+		t.Errorf("ListIncidents = %+v, want inc-1", incidents)
+	}
+	if _, err := s.ListIncidents(token, "resolved", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
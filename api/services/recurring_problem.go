@@ -0,0 +1,349 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+const (
+	recurringProblemReportMaxAge = 24 * time.Hour
+	recurringProblemSampleSize   = 5
+)
+
+// recurringIncident is a resolved incident as clustered by the report
+type recurringIncident struct {
+	id          string
+	title       string
+	fingerprint string
+	serviceID   string
+	serviceName string
+	createdAt   time.Time
+	resolvedAt  time.Time
+	unacked     bool
+}
+
+// recurringProblemsWindow is the period the next report covers
+func recurringProblemsWindow(now time.Time) (time.Time, time.Time) {
+	weeks := config.Current().RecurringProblemsWeeks
+	if weeks <= 0 {
+		weeks = 4
+	}
+	return now.AddDate(0, 0, -7*weeks), now
+}
+
+// BuildRecurringProblemsReport clusters an organization's resolved incidents
+// from the report window into recurring problems per service and stores the
+// result as its latest report. Test and drill incidents are left out.
+func (s *IncidentService) BuildRecurringProblemsReport(orgID string) (*db.RecurringProblemsReport, error) {
+	from, to := recurringProblemsWindow(time.Now().UTC())
+	rows, err := s.PG.Query(`
+		SELECT i.id, i.title, COALESCE(NULLIF(i.labels->>'fingerprint', ''), NULLIF(i.incident_key, ''), ''),
+		       COALESCE(i.service_id::text, ''), COALESCE(svc.name, ''), i.created_at, i.resolved_at,
+		       i.acknowledged_at IS NULL
+		FROM incidents i
+		LEFT JOIN services svc ON svc.id = i.service_id
+		WHERE i.organization_id = $1 AND i.status = 'resolved' AND i.resolved_at >= $2 AND i.resolved_at < $3
+		  AND NOT COALESCE(i.is_test, false) AND i.drill_id IS NULL
+		ORDER BY i.created_at
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resolved incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []recurringIncident
+	for rows.Next() {
+		var inc recurringIncident
+		if err := rows.Scan(&inc.id, &inc.title, &inc.fingerprint, &inc.serviceID, &inc.serviceName,
+			&inc.createdAt, &inc.resolvedAt, &inc.unacked); err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, inc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &db.RecurringProblemsReport{
+		OrganizationID:    orgID,
+		WindowStart:       from,
+		WindowEnd:         to,
+		IncidentsAnalyzed: len(incidents),
+		Services:          clusterRecurringProblems(incidents),
+	}
+	servicesJSON, err := json.Marshal(report.Services)
+	if err != nil {
+		return nil, err
+	}
+	err = s.PG.QueryRow(`
+		INSERT INTO recurring_problem_reports (organization_id, window_start, window_end, incidents_analyzed, services, generated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (organization_id) DO UPDATE
+		SET window_start = EXCLUDED.window_start, window_end = EXCLUDED.window_end,
+		    incidents_analyzed = EXCLUDED.incidents_analyzed, services = EXCLUDED.services, generated_at = NOW()
+		RETURNING generated_at
+	`, orgID, from, to, report.IncidentsAnalyzed, string(servicesJSON)).Scan(&report.GeneratedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recurring problems report: %w", err)
+	}
+	return report, nil
+}
+
+// GetRecurringProblemsReport returns an organization's latest recurring
+// problems report, building it if the worker hasn't yet. With serviceID set
+// only that service's problems are returned.
+func (s *IncidentService) GetRecurringProblemsReport(orgID, serviceID string) (*db.RecurringProblemsReport, error) {
+	report := &db.RecurringProblemsReport{OrganizationID: orgID}
+	var servicesJSON []byte
+	err := s.PG.QueryRow(`
+		SELECT window_start, window_end, incidents_analyzed, services, generated_at
+		FROM recurring_problem_reports
+		WHERE organization_id = $1
+	`, orgID).Scan(&report.WindowStart, &report.WindowEnd, &report.IncidentsAnalyzed, &servicesJSON, &report.GeneratedAt)
+	switch {
+	case err == sql.ErrNoRows:
+		if report, err = s.BuildRecurringProblemsReport(orgID); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to get recurring problems report: %w", err)
+	default:
+		if err := json.Unmarshal(servicesJSON, &report.Services); err != nil {
+			return nil, fmt.Errorf("failed to decode recurring problems report: %w", err)
+		}
+	}
+
+	if serviceID != "" {
+		filtered := []db.ServiceRecurringProblems{}
+		for _, svc := range report.Services {
+			if svc.ServiceID == serviceID {
+				filtered = append(filtered, svc)
+			}
+		}
+		report.Services = filtered
+	}
+	if report.Services == nil {
+		report.Services = []db.ServiceRecurringProblems{}
+	}
+	return report, nil
+}
+
+// RefreshRecurringProblemReports rebuilds the reports that are a day old, or
+// missing for organizations with resolved incidents in the window. It is run
+// by the incident worker.
+func (s *IncidentService) RefreshRecurringProblemReports() (int, error) {
+	from, to := recurringProblemsWindow(time.Now().UTC())
+	rows, err := s.PG.Query(`
+		SELECT DISTINCT i.organization_id::text
+		FROM incidents i
+		LEFT JOIN recurring_problem_reports r ON r.organization_id = i.organization_id
+		WHERE i.organization_id IS NOT NULL AND i.status = 'resolved' AND i.resolved_at >= $1 AND i.resolved_at < $2
+		  AND (r.generated_at IS NULL OR r.generated_at < NOW() - $3 * INTERVAL '1 second')
+	`, from, to, recurringProblemReportMaxAge.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to find organizations due a recurring problems report: %w", err)
+	}
+	var orgIDs []string
+	for rows.Next() {
+		var orgID string
+		if err := rows.Scan(&orgID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgIDs = append(orgIDs, orgID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	built := 0
+	for _, orgID := range orgIDs {
+		if _, err := s.BuildRecurringProblemsReport(orgID); err != nil {
+			return built, fmt.Errorf("org %s: %w", orgID, err)
+		}
+		built++
+	}
+	return built, nil
+}
+
+// recurringCluster is a problem being built up from incidents
+type recurringCluster struct {
+	problem      db.RecurringProblem
+	tokens       map[string]bool
+	titles       map[string]int
+	fingerprints map[string]int
+	ids          []string
+}
+
+func (c *recurringCluster) add(inc recurringIncident) {
+	c.problem.Incidents++
+	open := int64(inc.resolvedAt.Sub(inc.createdAt).Seconds())
+	if open < 0 {
+		open = 0
+	}
+	c.problem.TotalSecondsOpen += open
+	if c.problem.FirstSeen.IsZero() || inc.createdAt.Before(c.problem.FirstSeen) {
+		c.problem.FirstSeen = inc.createdAt
+	}
+	if inc.createdAt.After(c.problem.LastSeen) {
+		c.problem.LastSeen = inc.createdAt
+	}
+	if inc.unacked {
+		c.problem.ResolvedWithoutAck++
+	}
+	c.titles[inc.title]++
+	if inc.fingerprint != "" {
+		c.fingerprints[inc.fingerprint]++
+	}
+	c.ids = append(c.ids, inc.id)
+}
+
+// finish fills in what is derived from the whole cluster
+func (c *recurringCluster) finish() db.RecurringProblem {
+	p := c.problem
+	p.AvgSecondsOpen = p.TotalSecondsOpen / int64(p.Incidents)
+	p.DistinctTitles = len(c.titles)
+	best := 0
+	for title, n := range c.titles {
+		if n > best || (n == best && title < p.Title) {
+			p.Title, best = title, n
+		}
+	}
+	// Only a fingerprint every incident shares names the problem
+	if len(c.fingerprints) == 1 {
+		for fp, n := range c.fingerprints {
+			if n == p.Incidents {
+				p.Fingerprint = fp
+			}
+		}
+	}
+	// Most recent incidents first
+	for i := len(c.ids) - 1; i >= 0 && len(p.SampleIncidentIDs) < recurringProblemSampleSize; i-- {
+		p.SampleIncidentIDs = append(p.SampleIncidentIDs, c.ids[i])
+	}
+	return p
+}
+
+// clusterRecurringProblems groups each service's incidents, oldest first, into
+// problems: an incident joins the problem that already has its fingerprint,
+// otherwise the most similar problem by title, otherwise starts a new one.
+// Services are ordered by the time their recurring problems took, and
+// services without any are left out.
+func clusterRecurringProblems(incidents []recurringIncident) []db.ServiceRecurringProblems {
+	type serviceClusters struct {
+		svc           db.ServiceRecurringProblems
+		clusters      []*recurringCluster
+		byFingerprint map[string]*recurringCluster
+	}
+	byService := map[string]*serviceClusters{}
+	var order []string
+
+	for _, inc := range incidents {
+		sc := byService[inc.serviceID]
+		if sc == nil {
+			sc = &serviceClusters{
+				svc:           db.ServiceRecurringProblems{ServiceID: inc.serviceID, ServiceName: inc.serviceName},
+				byFingerprint: map[string]*recurringCluster{},
+			}
+			byService[inc.serviceID] = sc
+			order = append(order, inc.serviceID)
+		}
+		sc.svc.Incidents++
+
+		tokens := recurringTitleTokens(inc.title)
+		cluster := sc.byFingerprint[inc.fingerprint]
+		if inc.fingerprint == "" || cluster == nil {
+			best := db.RecurringProblemTitleSimilarity
+			for _, candidate := range sc.clusters {
+				if sim := jaccardSimilarity(tokens, candidate.tokens); sim >= best {
+					cluster, best = candidate, sim
+				}
+			}
+		}
+		if cluster == nil {
+			cluster = &recurringCluster{tokens: tokens, titles: map[string]int{}, fingerprints: map[string]int{}}
+			sc.clusters = append(sc.clusters, cluster)
+		}
+		if inc.fingerprint != "" && sc.byFingerprint[inc.fingerprint] == nil {
+			sc.byFingerprint[inc.fingerprint] = cluster
+		}
+		cluster.add(inc)
+	}
+
+	var result []db.ServiceRecurringProblems
+	totals := map[string]int64{}
+	for _, serviceID := range order {
+		sc := byService[serviceID]
+		for _, cluster := range sc.clusters {
+			if cluster.problem.Incidents >= db.RecurringProblemMinIncidents {
+				sc.svc.Problems = append(sc.svc.Problems, cluster.finish())
+			}
+		}
+		if len(sc.svc.Problems) == 0 {
+			continue
+		}
+		sort.SliceStable(sc.svc.Problems, func(i, j int) bool {
+			a, b := sc.svc.Problems[i], sc.svc.Problems[j]
+			if a.TotalSecondsOpen != b.TotalSecondsOpen {
+				return a.TotalSecondsOpen > b.TotalSecondsOpen
+			}
+			return a.Incidents > b.Incidents
+		})
+		if len(sc.svc.Problems) > db.RecurringProblemsPerService {
+			sc.svc.Problems = sc.svc.Problems[:db.RecurringProblemsPerService]
+		}
+		for _, p := range sc.svc.Problems {
+			totals[serviceID] += p.TotalSecondsOpen
+		}
+		result = append(result, sc.svc)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return totals[result[i].ServiceID] > totals[result[j].ServiceID] })
+	return result
+}
+
+var recurringTitleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "in": true, "is": true, "of": true,
+	"for": true, "to": true, "at": true, "and": true, "from": true, "with": true,
+}
+
+// recurringTitleTokens is the set of words that identify a problem in an
+// incident title. Words with digits (hosts, IDs, counts, values) vary between
+// occurrences of the same problem and are dropped.
+func recurringTitleTokens(title string) map[string]bool {
+	tokens := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if recurringTitleStopWords[word] || strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			continue
+		}
+		tokens[word] = true
+	}
+	if len(tokens) == 0 {
+		tokens[strings.ToLower(strings.TrimSpace(title))] = true
+	}
+	return tokens
+}
+
+func jaccardSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClusterRecurringProblems(t *testing.T) {
+	base := time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+	inc := func(id, title, fingerprint, serviceID string, day int, minutesOpen int) recurringIncident {
+		created := base.AddDate(0, 0, day)
+		return recurringIncident{id: id, title: title, fingerprint: fingerprint, serviceID: serviceID,
+			serviceName: "svc-" + serviceID, createdAt: created, resolvedAt: created.Add(time.Duration(minutesOpen) * time.Minute)}
+	}
+	incidents := []recurringIncident{
+		// Same problem, host and value differ
+		inc("1", "High CPU on web-01: 95%", "", "api", 0, 30),
+		inc("2", "High CPU on web-07: 91%", "", "api", 3, 60),
+		// Same fingerprint, titles unrelated
+		inc("3", "Disk almost full", "fp-disk", "api", 1, 10),
+		inc("4", "Volume /data at 90%", "fp-disk", "api", 5, 10),
+		// One-off
+		inc("5", "Certificate expired", "", "api", 2, 120),
+		// Another service with more time spent
+		inc("6", "Payment gateway timeouts", "", "payments", 1, 200),
+		inc("7", "Payment gateway timeouts", "", "payments", 4, 200),
+		// Same title on a different service is a different problem
+		inc("8", "High CPU on db-01: 99%", "", "db", 1, 5),
+	}
+
+	services := clusterRecurringProblems(incidents)
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services with recurring problems, got %+v", services)
+	}
+	if services[0].ServiceID != "payments" || services[1].ServiceID != "api" {
+		t.Errorf("services should be ordered by time spent, got %s, %s", services[0].ServiceID, services[1].ServiceID)
+	}
+
+	api := services[1]
+	if api.Incidents != 5 || len(api.Problems) != 2 {
+		t.Fatalf("unexpected api problems: %+v", api)
+	}
+	cpu, disk := api.Problems[0], api.Problems[1]
+	if cpu.Incidents != 2 || cpu.TotalSecondsOpen != 90*60 || cpu.AvgSecondsOpen != 45*60 || cpu.Fingerprint != "" {
+		t.Errorf("unexpected CPU problem: %+v", cpu)
+	}
+	if cpu.SampleIncidentIDs[0] != "2" || !cpu.LastSeen.Equal(base.AddDate(0, 0, 3)) {
+		t.Errorf("most recent incident should come first: %+v", cpu)
+	}
+	if disk.Fingerprint != "fp-disk" || disk.Incidents != 2 || disk.DistinctTitles != 2 {
+		t.Errorf("unexpected disk problem: %+v", disk)
+	}
+}
+
+func TestRecurringTitleTokens(t *testing.T) {
+	a := recurringTitleTokens("[FIRING:1] KubePodCrashLooping in namespace prod-3")
+	b := recurringTitleTokens("[FIRING:2] KubePodCrashLooping in namespace prod-7")
+	if jaccardSimilarity(a, b) != 1 {
+		t.Errorf("expected identical tokens, got %v and %v", a, b)
+	}
+	if tokens := recurringTitleTokens("500"); !tokens["500"] {
+		t.Errorf("all-digit titles should keep the title itself, got %v", tokens)
+	}
+}
+
+func TestGetRecurringProblemsReportFiltersService(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now().UTC()
+	mock.ExpectQuery(`FROM recurring_problem_reports`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"window_start", "window_end", "incidents_analyzed", "services", "generated_at"}).
+			AddRow(now.AddDate(0, 0, -28), now, 5,
+				[]byte(`[{"service_id":"api","incidents":3,"problems":[]},{"service_id":"db","incidents":2,"problems":[]}]`), now))
+
+	report, err := (&IncidentService{PG: pg}).GetRecurringProblemsReport("org-1", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Services) != 1 || report.Services[0].ServiceID != "db" || report.IncidentsAnalyzed != 5 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
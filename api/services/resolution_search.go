@@ -0,0 +1,123 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+const (
+	defaultResolutionSearchLimit = 10
+	maxResolutionSearchLimit     = 50
+)
+
+var ErrEmptySearchQuery = errors.New("search query is required")
+
+// SearchResolutions finds past incidents whose resolution notes, postmortem
+// or notes match q, best match first. A match on the incident's title or
+// description also counts, at a lower weight, so searching for a symptom
+// finds how it was fixed. Only incidents with something written about the
+// fix are returned.
+//
+// Filters: current_user_id and current_org_id (required), q (required),
+// project_id, group_id, service_id and limit.
+func (s *IncidentService) SearchResolutions(filters map[string]interface{}) ([]db.ResolutionSearchResult, error) {
+	results := []db.ResolutionSearchResult{}
+
+	q, _ := filters["q"].(string)
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	currentUserID, _ := filters["current_user_id"].(string)
+	currentOrgID, _ := filters["current_org_id"].(string)
+	if currentUserID == "" || currentOrgID == "" {
+		return results, nil
+	}
+
+	limit := defaultResolutionSearchLimit
+	if l, ok := filters["limit"].(int); ok && l > 0 {
+		limit = l
+	}
+	if limit > maxResolutionSearchLimit {
+		limit = maxResolutionSearchLimit
+	}
+
+	// $3 is the search query; the text excerpt prefers the resolution, then
+	// the postmortem, then notes
+	query := `
+		SELECT i.id, i.title, i.status, COALESCE(i.severity, ''),
+		       COALESCE(s.name, ''), COALESCE(g.name, ''),
+		       i.created_at, i.resolved_at, COALESCE(u_resolved.name, u_resolved.email, ''),
+		       r.resolution_text,
+		       ts_headline('english',
+		           COALESCE(NULLIF(r.resolution_text, ''), NULLIF(r.postmortem_text, ''), r.notes_text),
+		           websearch_to_tsquery('english', $3),
+		           'StartSel=**, StopSel=**, MaxWords=35, MinWords=10, MaxFragments=2'),
+		       (ts_rank(r.search_vector, websearch_to_tsquery('english', $3)) +
+		        0.5 * ts_rank(COALESCE(i.search_vector, ''::tsvector), websearch_to_tsquery('english', $3)))::float8 AS rank,
+		       COALESCE(i.custom_fields->>'postmortem_url', '')
+		FROM incident_resolution_index r
+		JOIN incidents i ON i.id = r.incident_id
+		LEFT JOIN services s ON i.service_id = s.id
+		LEFT JOIN groups g ON i.group_id = g.id
+		LEFT JOIN users u_resolved ON i.resolved_by = u_resolved.id
+		WHERE (r.resolution_text <> '' OR r.postmortem_text <> '' OR r.notes_text <> '')
+		AND (r.search_vector @@ websearch_to_tsquery('english', $3)
+		     OR i.search_vector @@ websearch_to_tsquery('english', $3))
+		AND
+` + incidentAccessScopeSQL
+
+	args := []interface{}{currentUserID, currentOrgID, q}
+	argIndex := 4
+
+	for _, column := range []struct{ filter, expr string }{
+		{"project_id", "i.project_id"},
+		{"service_id", "i.service_id"},
+	} {
+		if value, ok := filters[column.filter].(string); ok && value != "" {
+			query += fmt.Sprintf(" AND %s = $%d", column.expr, argIndex)
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	// Group filter includes the group's sub-teams
+	if groupID, ok := filters["group_id"].(string); ok && groupID != "" {
+		query += fmt.Sprintf(" AND i.group_id IN (SELECT group_id FROM group_subtree($%d))", argIndex)
+		args = append(args, groupID)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" ORDER BY rank DESC, i.resolved_at DESC NULLS LAST LIMIT $%d", argIndex)
+	args = append(args, limit)
+
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r db.ResolutionSearchResult
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(
+			&r.IncidentID, &r.Title, &r.Status, &r.Severity,
+			&r.ServiceName, &r.GroupName,
+			&r.CreatedAt, &resolvedAt, &r.ResolvedByName,
+			&r.Resolution, &r.Snippet, &r.Rank, &r.PostmortemURL,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan resolution search result: %w", err)
+		}
+		if resolvedAt.Valid {
+			r.ResolvedAt = &resolvedAt.Time
+		}
+		r.IncidentURL = webBaseURL() + "/incidents/" + r.IncidentID
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
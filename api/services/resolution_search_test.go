@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSearchResolutions(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	service := &IncidentService{PG: pg}
+	if _, err := service.SearchResolutions(map[string]interface{}{"q": "  "}); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Fatalf("blank query: err = %v, want ErrEmptySearchQuery", err)
+	}
+
+	resolvedAt := time.Now().Add(-48 * time.Hour)
+	mock.ExpectQuery(`FROM incident_resolution_index r`).
+		WithArgs("user-1", "org-1", "redis failover", "svc-1", 50).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "title", "status", "severity", "service_name", "group_name",
+			"created_at", "resolved_at", "resolved_by_name", "resolution_text", "snippet", "rank", "postmortem_url",
+		}).AddRow("inc-9", "Redis primary down", "resolved", "critical", "cache", "SRE",
+			resolvedAt.Add(-time.Hour), resolvedAt, "Dana", "Promoted replica and restarted sentinel",
+			"**Promoted** replica and restarted sentinel", 0.42, "https://wiki.example.com/pm/9"))
+
+	results, err := service.SearchResolutions(map[string]interface{}{
+		"current_user_id": "user-1",
+		"current_org_id":  "org-1",
+		"q":               "redis failover",
+		"service_id":      "svc-1",
+		"limit":           500, // capped
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	r := results[0]
+	if r.ResolvedAt == nil || r.PostmortemURL == "" || r.IncidentURL == "" || r.Rank != 0.42 {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
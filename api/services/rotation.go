@@ -242,8 +242,15 @@ func (s *RotationService) GetRotationPreview(rotationCycleID string, weeks int)
 	return previews, nil
 }
 
-// CreateScheduleOverride creates an override for an existing schedule
+// CreateScheduleOverride creates an override for an existing schedule. Only
+// managers of the schedule's scheduler may override it.
 func (s *RotationService) CreateScheduleOverride(req db.CreateScheduleOverrideRequest, createdBy string) (string, error) {
+	if ok, err := canManageShifts(s.PG, createdBy, []string{req.OriginalScheduleID}); err != nil {
+		return "", err
+	} else if !ok {
+		return "", ErrSchedulerForbidden
+	}
+
 	var overrideID string
 
 	// Use database function to create override
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrRoutingKeyNotFound        = errors.New("routing key not found")
+	ErrRoutingKeyTaken           = errors.New("routing key is already in use")
+	ErrRoutingKeyPrimary         = errors.New("the primary routing key cannot be revoked, rotate it instead")
+	ErrInvalidRoutingKey         = errors.New("invalid routing key")
+	ErrRoutingKeyServiceNotFound = errors.New("service not found")
+)
+
+const routingKeySelect = `
+	SELECT k.id, k.service_id, k.routing_key, k.routing_key = s.routing_key,
+	       k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW()),
+	       COALESCE(k.created_by, ''), k.created_at, k.expires_at, k.revoked_at, k.last_used_at, k.use_count
+	FROM service_routing_keys k
+	JOIN services s ON s.id = k.service_id
+`
+
+func scanRoutingKey(row interface{ Scan(...interface{}) error }) (db.ServiceRoutingKey, error) {
+	var key db.ServiceRoutingKey
+	var expiresAt, revokedAt, lastUsedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.ServiceID, &key.RoutingKey, &key.IsPrimary, &key.Active,
+		&key.CreatedBy, &key.CreatedAt, &expiresAt, &revokedAt, &lastUsedAt, &key.UseCount)
+	if err != nil {
+		return key, err
+	}
+	key.ExpiresAt = nullTimePtr(expiresAt)
+	key.RevokedAt = nullTimePtr(revokedAt)
+	key.LastUsedAt = nullTimePtr(lastUsedAt)
+	return key, nil
+}
+
+// generateRoutingKey returns a random 32-character hex routing key
+func generateRoutingKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate routing key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// newRoutingKey returns the requested key, or a generated one, once it is
+// known not to be used by any service (revoked keys are never reused)
+func newRoutingKey(q queryRower, requested string) (string, error) {
+	key := strings.TrimSpace(requested)
+	if key == "" {
+		var err error
+		if key, err = generateRoutingKey(); err != nil {
+			return "", err
+		}
+	}
+	if strings.ContainsAny(key, " /?#") {
+		return "", fmt.Errorf("%w: routing keys cannot contain spaces, '/', '?' or '#'", ErrInvalidRoutingKey)
+	}
+
+	var taken bool
+	err := q.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM services WHERE routing_key = $1)
+		    OR EXISTS(SELECT 1 FROM service_routing_keys WHERE routing_key = $1)
+	`, key).Scan(&taken)
+	if err != nil {
+		return "", fmt.Errorf("failed to check routing key: %w", err)
+	}
+	if taken {
+		return "", ErrRoutingKeyTaken
+	}
+	return key, nil
+}
+
+// registerPrimaryRoutingKey makes sure the service's primary key has a row;
+// services created before rotation existed, or by the seeder, may lack one
+func registerPrimaryRoutingKey(tx *sql.Tx, serviceID string) error {
+	_, err := tx.Exec(`
+		INSERT INTO service_routing_keys (service_id, routing_key, created_by, created_at)
+		SELECT id, routing_key, created_by, created_at FROM services WHERE id = $1
+		ON CONFLICT (routing_key) DO NOTHING
+	`, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to register primary routing key: %w", err)
+	}
+	return nil
+}
+
+// recordRoutingKeyUse counts an event received on a key. Best effort: a
+// failure here must not reject the event.
+func (s *ServiceService) recordRoutingKeyUse(serviceID, routingKey string) {
+	_, err := s.PG.Exec(`
+		INSERT INTO service_routing_keys (service_id, routing_key, last_used_at, use_count)
+		VALUES ($1, $2, NOW(), 1)
+		ON CONFLICT (routing_key) DO UPDATE
+		SET last_used_at = NOW(), use_count = service_routing_keys.use_count + 1
+		WHERE service_routing_keys.service_id = EXCLUDED.service_id
+	`, serviceID, routingKey)
+	if err != nil {
+		log.Printf("WARN: failed to record use of routing key for service %s: %v", serviceID, err)
+	}
+}
+
+// ListRoutingKeys returns every key of a service, primary first, including
+// expired and revoked ones
+func (s *ServiceService) ListRoutingKeys(serviceID string) ([]db.ServiceRoutingKey, error) {
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := registerPrimaryRoutingKey(tx, serviceID); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(routingKeySelect+`
+		WHERE k.service_id = $1
+		ORDER BY k.routing_key = s.routing_key DESC, k.created_at DESC
+	`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routing keys: %w", err)
+	}
+
+	keys := []db.ServiceRoutingKey{}
+	for rows.Next() {
+		key, err := scanRoutingKey(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan routing key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, tx.Commit()
+}
+
+// CreateRoutingKey adds an extra active key to a service, e.g. for a second
+// sender that should be revocable on its own
+func (s *ServiceService) CreateRoutingKey(serviceID string, req db.CreateServiceRoutingKeyRequest, createdBy string) (*db.ServiceRoutingKey, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidRoutingKey)
+	}
+	routingKey, err := newRoutingKey(s.PG, req.RoutingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyID string
+	err = s.PG.QueryRow(`
+		INSERT INTO service_routing_keys (service_id, routing_key, created_by, expires_at)
+		SELECT id, $2, $3, $4 FROM services WHERE id = $1 AND is_active = true
+		RETURNING id
+	`, serviceID, routingKey, nullIfEmpty(createdBy), req.ExpiresAt).Scan(&keyID)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoutingKeyServiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create routing key: %w", err)
+	}
+
+	key, err := scanRoutingKey(s.PG.QueryRow(routingKeySelect+` WHERE k.id = $1`, keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routing key: %w", err)
+	}
+	return &key, nil
+}
+
+// RotateRoutingKey makes a new key the service's primary. The previous key
+// keeps routing events for graceMinutes so senders can switch over; 0 revokes
+// it straight away.
+func (s *ServiceService) RotateRoutingKey(serviceID string, req db.RotateServiceRoutingKeyRequest, rotatedBy string) (*db.RoutingKeyRotation, error) {
+	graceMinutes := db.DefaultRoutingKeyGraceMinutes
+	if req.GraceMinutes != nil {
+		graceMinutes = *req.GraceMinutes
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousKey string
+	err = tx.QueryRow(`SELECT routing_key FROM services WHERE id = $1 AND is_active = true FOR UPDATE`, serviceID).Scan(&previousKey)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoutingKeyServiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	if err := registerPrimaryRoutingKey(tx, serviceID); err != nil {
+		return nil, err
+	}
+	routingKey, err := newRoutingKey(tx, req.RoutingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// An earlier expiry (a key already being phased out) is kept
+	if graceMinutes == 0 {
+		_, err = tx.Exec(`
+			UPDATE service_routing_keys SET revoked_at = NOW()
+			WHERE service_id = $1 AND routing_key = $2 AND revoked_at IS NULL
+		`, serviceID, previousKey)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE service_routing_keys
+			SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), NOW() + make_interval(mins => $3))
+			WHERE service_id = $1 AND routing_key = $2
+		`, serviceID, previousKey, graceMinutes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire previous routing key: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO service_routing_keys (service_id, routing_key, created_by) VALUES ($1, $2, $3)
+	`, serviceID, routingKey, nullIfEmpty(rotatedBy)); err != nil {
+		return nil, fmt.Errorf("failed to create routing key: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE services SET routing_key = $2, updated_at = NOW() WHERE id = $1`, serviceID, routingKey); err != nil {
+		return nil, fmt.Errorf("failed to update service routing key: %w", err)
+	}
+
+	rotation := &db.RoutingKeyRotation{}
+	if rotation.RoutingKey, err = scanRoutingKey(tx.QueryRow(routingKeySelect+` WHERE k.routing_key = $1`, routingKey)); err != nil {
+		return nil, fmt.Errorf("failed to load routing key: %w", err)
+	}
+	if rotation.Previous, err = scanRoutingKey(tx.QueryRow(routingKeySelect+` WHERE k.routing_key = $1`, previousKey)); err != nil {
+		return nil, fmt.Errorf("failed to load previous routing key: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit routing key rotation: %w", err)
+	}
+	return rotation, nil
+}
+
+// RevokeRoutingKey stops a non-primary key from routing events immediately
+func (s *ServiceService) RevokeRoutingKey(serviceID, keyID string) error {
+	var isPrimary bool
+	err := s.PG.QueryRow(`
+		SELECT k.routing_key = s.routing_key
+		FROM service_routing_keys k
+		JOIN services s ON s.id = k.service_id
+		WHERE k.id = $1 AND k.service_id = $2
+	`, keyID, serviceID).Scan(&isPrimary)
+	if err == sql.ErrNoRows {
+		return ErrRoutingKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get routing key: %w", err)
+	}
+	if isPrimary {
+		return ErrRoutingKeyPrimary
+	}
+
+	_, err = s.PG.Exec(`
+		UPDATE service_routing_keys SET revoked_at = NOW()
+		WHERE id = $1 AND service_id = $2 AND revoked_at IS NULL
+	`, keyID, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke routing key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var routingKeyColumns = []string{"id", "service_id", "routing_key", "is_primary", "active",
+	"created_by", "created_at", "expires_at", "revoked_at", "last_used_at", "use_count"}
+
+func TestRotateRoutingKeyKeepsPreviousDuringGrace(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	grace := 60
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT routing_key FROM services`).WithArgs("svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"routing_key"}).AddRow("old-key"))
+	mock.ExpectExec(`INSERT INTO service_routing_keys`).WithArgs("svc-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("new-key").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`SET expires_at = LEAST`).WithArgs("svc-1", "old-key", grace).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO service_routing_keys`).WithArgs("svc-1", "new-key", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE services SET routing_key`).WithArgs("svc-1", "new-key").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM service_routing_keys k`).WithArgs("new-key").
+		WillReturnRows(sqlmock.NewRows(routingKeyColumns).
+			AddRow("key-2", "svc-1", "new-key", true, true, "user-1", now, nil, nil, nil, 0))
+	expires := now.Add(time.Hour)
+	mock.ExpectQuery(`FROM service_routing_keys k`).WithArgs("old-key").
+		WillReturnRows(sqlmock.NewRows(routingKeyColumns).
+			AddRow("key-1", "svc-1", "old-key", false, true, "", now.Add(-24*time.Hour), expires, nil, now, 42))
+	mock.ExpectCommit()
+
+	rotation, err := NewServiceService(pg).RotateRoutingKey("svc-1",
+		db.RotateServiceRoutingKeyRequest{RoutingKey: "new-key", GraceMinutes: &grace}, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotation.RoutingKey.IsPrimary || rotation.RoutingKey.RoutingKey != "new-key" {
+		t.Errorf("unexpected new key %+v", rotation.RoutingKey)
+	}
+	if !rotation.Previous.Active || rotation.Previous.ExpiresAt == nil || rotation.Previous.UseCount != 42 {
+		t.Errorf("previous key should stay active until its grace period ends, got %+v", rotation.Previous)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRotateRoutingKeyRejectsKeyInUse(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT routing_key FROM services`).WithArgs("svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"routing_key"}).AddRow("old-key"))
+	mock.ExpectExec(`INSERT INTO service_routing_keys`).WithArgs("svc-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("other-services-key").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = NewServiceService(pg).RotateRoutingKey("svc-1", db.RotateServiceRoutingKeyRequest{RoutingKey: "other-services-key"}, "user-1")
+	if !errors.Is(err, ErrRoutingKeyTaken) {
+		t.Fatalf("expected ErrRoutingKeyTaken, got %v", err)
+	}
+}
+
+func TestRevokeRoutingKeyRefusesPrimary(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT k.routing_key = s.routing_key`).WithArgs("key-1", "svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"is_primary"}).AddRow(true))
+
+	if err := NewServiceService(pg).RevokeRoutingKey("svc-1", "key-1"); !errors.Is(err, ErrRoutingKeyPrimary) {
+		t.Fatalf("expected ErrRoutingKeyPrimary, got %v", err)
+	}
+}
@@ -0,0 +1,523 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+// PreviewScheduleImpact applies unsaved shift and override changes of a group
+// in memory and reports, over the next req.Hours, where the on-call person
+// changes and which open incidents would change hands: incidents whose
+// assignee goes off call, and coming escalation levels that would page
+// someone else. With req.Notify the users on both sides of an incident change
+// get a Slack DM. Invalid or unknown operations are rejected with a
+// *ShiftBatchError; nothing is saved either way.
+func (s *IncidentService) PreviewScheduleImpact(groupID string, req db.ScheduleImpactRequest) (*db.ScheduleImpact, error) {
+	hours := req.Hours
+	if hours <= 0 {
+		hours = db.ScheduleImpactDefaultHours
+	}
+	if hours > db.ScheduleImpactMaxHours {
+		hours = db.ScheduleImpactMaxHours
+	}
+	now := time.Now().UTC()
+	to := now.Add(time.Duration(hours) * time.Hour)
+
+	var errs []db.ShiftBatchItemError
+	if len(req.Shifts.Create)+len(req.Shifts.Update)+len(req.Shifts.Delete) > 0 {
+		errs = validateShiftBatch(req.Shifts)
+	}
+	for i, o := range req.Overrides.Create {
+		if !o.OverrideEndTime.After(o.OverrideStartTime) {
+			errs = append(errs, db.ShiftBatchItemError{Op: "override_create", Index: i, ShiftID: o.OriginalScheduleID,
+				Code: db.ShiftBatchErrorInvalid, Message: "override_end_time must be after override_start_time"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ShiftBatchError{Errors: errs}
+	}
+
+	schedulers, err := s.groupSchedulerNames(groupID)
+	if err != nil {
+		return nil, err
+	}
+	shifts, overrides, err := loadOnCallShifts(s.PG, groupID, now, to)
+	if err != nil {
+		return nil, err
+	}
+	// Shifts outside the window may be moved into it or overridden
+	var missing []string
+	for _, update := range req.Shifts.Update {
+		if !hasOnCallShift(shifts, update.ID) {
+			missing = append(missing, update.ID)
+		}
+	}
+	for _, o := range req.Overrides.Create {
+		if !hasOnCallShift(shifts, o.OriginalScheduleID) {
+			missing = append(missing, o.OriginalScheduleID)
+		}
+	}
+	if missing = uniqueStrings(missing); len(missing) > 0 {
+		moved, err := s.onCallShiftsByID(groupID, missing)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, moved...)
+	}
+
+	after, afterOverrides, errs := applyScheduleChange(shifts, overrides, req, schedulers)
+	if len(errs) > 0 {
+		return nil, &ShiftBatchError{Errors: errs}
+	}
+	beforeIntervals := resolveOnCallIntervals(shifts, overrides, now, to)
+	afterIntervals := resolveOnCallIntervals(after, afterOverrides, now, to)
+
+	incidents, err := s.scheduleImpactIncidents(groupID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &db.ScheduleImpact{
+		GroupID:   groupID,
+		From:      now,
+		To:        to,
+		Changes:   diffOnCallIntervals(beforeIntervals, afterIntervals, now, to),
+		Incidents: incidentScheduleImpacts(incidents, beforeIntervals, afterIntervals, groupID, schedulers, now, to),
+		Notified:  []string{},
+	}
+	if err := s.fillScheduleImpactNames(result); err != nil {
+		return nil, err
+	}
+
+	if req.Notify {
+		result.Notified = s.notifyScheduleImpact(result.Incidents)
+	}
+	return result, nil
+}
+
+// groupSchedulerNames returns the names of the group's active schedulers by ID
+func (s *IncidentService) groupSchedulerNames(groupID string) (map[string]string, error) {
+	rows, err := s.PG.Query(`
+		SELECT id::text, COALESCE(display_name, name, '')
+		FROM schedulers
+		WHERE group_id = $1 AND is_active = true
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedulers: %w", err)
+	}
+	defer rows.Close()
+	names := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduler: %w", err)
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// onCallShiftsByID returns the group's active shifts with the given IDs
+func (s *IncidentService) onCallShiftsByID(groupID string, ids []string) ([]onCallShift, error) {
+	rows, err := s.PG.Query(`
+		SELECT s.id, s.scheduler_id, COALESCE(sc.display_name, sc.name, ''), s.user_id, s.start_time, s.end_time
+		FROM shifts s
+		JOIN schedulers sc ON sc.id = s.scheduler_id
+		WHERE s.group_id = $1 AND s.is_active = true AND sc.is_active = true AND s.id::text = ANY($2)
+	`, groupID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shifts: %w", err)
+	}
+	defer rows.Close()
+	shifts := []onCallShift{}
+	for rows.Next() {
+		var sh onCallShift
+		if err := rows.Scan(&sh.ID, &sh.SchedulerID, &sh.SchedulerName, &sh.UserID, &sh.Start, &sh.End); err != nil {
+			return nil, fmt.Errorf("failed to scan shift: %w", err)
+		}
+		shifts = append(shifts, sh)
+	}
+	return shifts, rows.Err()
+}
+
+func hasOnCallShift(shifts []onCallShift, id string) bool {
+	for _, sh := range shifts {
+		if sh.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// applyScheduleChange returns the shifts and overrides as they would be after
+// the proposed change. Created shifts and overrides get placeholder IDs;
+// created overrides come last so they win over existing ones.
+func applyScheduleChange(shifts []onCallShift, overrides []onCallOverride, req db.ScheduleImpactRequest, schedulers map[string]string) ([]onCallShift, []onCallOverride, []db.ShiftBatchItemError) {
+	var errs []db.ShiftBatchItemError
+	notFound := func(op string, index int, id, message string) {
+		errs = append(errs, db.ShiftBatchItemError{Op: op, Index: index, ShiftID: id, Code: db.ShiftBatchErrorNotFound, Message: message})
+	}
+
+	byID := map[string]int{}
+	after := make([]onCallShift, len(shifts))
+	copy(after, shifts)
+	for i, sh := range after {
+		byID[sh.ID] = i
+	}
+
+	deleted := map[string]bool{}
+	for i, del := range req.Shifts.Delete {
+		if _, ok := byID[del.ID]; !ok {
+			notFound("delete", i, del.ID, "shift not found in this group")
+			continue
+		}
+		deleted[del.ID] = true
+	}
+	for i, update := range req.Shifts.Update {
+		idx, ok := byID[update.ID]
+		if !ok {
+			notFound("update", i, update.ID, "shift not found in this group")
+			continue
+		}
+		sh := &after[idx]
+		if update.UserID != nil {
+			sh.UserID = *update.UserID
+		}
+		if update.StartTime != nil {
+			sh.Start = *update.StartTime
+		}
+		if update.EndTime != nil {
+			sh.End = *update.EndTime
+		}
+		if !sh.End.After(sh.Start) {
+			errs = append(errs, db.ShiftBatchItemError{Op: "update", Index: i, ShiftID: update.ID,
+				Code: db.ShiftBatchErrorInvalid, Message: "end_time must be after start_time"})
+		}
+	}
+	for i, create := range req.Shifts.Create {
+		name, ok := schedulers[create.SchedulerID]
+		if !ok {
+			notFound("create", i, "", "scheduler not found in this group")
+			continue
+		}
+		after = append(after, onCallShift{
+			ID:            fmt.Sprintf("create[%d]", i),
+			SchedulerID:   create.SchedulerID,
+			SchedulerName: name,
+			UserID:        create.UserID,
+			Start:         create.StartTime,
+			End:           create.EndTime,
+		})
+	}
+
+	kept := after[:0]
+	for _, sh := range after {
+		if !deleted[sh.ID] {
+			kept = append(kept, sh)
+		}
+	}
+
+	removed := map[string]bool{}
+	for _, id := range req.Overrides.Delete {
+		removed[id] = true
+	}
+	afterOverrides := []onCallOverride{}
+	for _, o := range overrides {
+		if !removed[o.ID] {
+			afterOverrides = append(afterOverrides, o)
+		}
+	}
+	for i, create := range req.Overrides.Create {
+		if _, ok := byID[create.OriginalScheduleID]; !ok {
+			notFound("override_create", i, create.OriginalScheduleID, "shift not found in this group")
+			continue
+		}
+		afterOverrides = append(afterOverrides, onCallOverride{
+			ID:        fmt.Sprintf("override_create[%d]", i),
+			ShiftID:   create.OriginalScheduleID,
+			NewUserID: create.NewUserID,
+			Start:     create.OverrideStartTime,
+			End:       create.OverrideEndTime,
+		})
+	}
+	return kept, afterOverrides, errs
+}
+
+// onCallUserAt returns who is on call at t for a scheduler, or for the whole
+// group when schedulerID is empty. Like effective_shifts, the most recently
+// started shift wins where several cover t.
+func onCallUserAt(intervals []db.OnCallInterval, schedulerID string, t time.Time) string {
+	var userID string
+	var start time.Time
+	for _, iv := range intervals {
+		if schedulerID != "" && iv.SchedulerID != schedulerID {
+			continue
+		}
+		if iv.Start.After(t) || !iv.End.After(t) {
+			continue
+		}
+		if userID == "" || iv.Start.After(start) {
+			userID, start = iv.UserID, iv.Start
+		}
+	}
+	return userID
+}
+
+// diffOnCallIntervals lists, per scheduler, the stretches of [from, to) where
+// the on-call person differs between before and after
+func diffOnCallIntervals(before, after []db.OnCallInterval, from, to time.Time) []db.ScheduleImpactChange {
+	names := map[string]string{}
+	bounds := map[string][]time.Time{}
+	for _, iv := range append(append([]db.OnCallInterval{}, before...), after...) {
+		names[iv.SchedulerID] = iv.SchedulerName
+		bounds[iv.SchedulerID] = append(bounds[iv.SchedulerID], iv.Start, iv.End)
+	}
+	schedulerIDs := make([]string, 0, len(bounds))
+	for id := range bounds {
+		schedulerIDs = append(schedulerIDs, id)
+	}
+	sort.Strings(schedulerIDs)
+
+	changes := []db.ScheduleImpactChange{}
+	for _, schedulerID := range schedulerIDs {
+		points := append(bounds[schedulerID], from, to)
+		sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+		for i := 0; i+1 < len(points); i++ {
+			a, b := points[i], points[i+1]
+			if !b.After(a) || a.Before(from) || b.After(to) {
+				continue
+			}
+			fromUser, toUser := onCallUserAt(before, schedulerID, a), onCallUserAt(after, schedulerID, a)
+			if fromUser == toUser {
+				continue
+			}
+			if n := len(changes); n > 0 {
+				last := &changes[n-1]
+				if last.SchedulerID == schedulerID && last.End.Equal(a) &&
+					last.FromUserID == fromUser && last.ToUserID == toUser {
+					last.End = b
+					continue
+				}
+			}
+			changes = append(changes, db.ScheduleImpactChange{
+				SchedulerID:   schedulerID,
+				SchedulerName: names[schedulerID],
+				Start:         a,
+				End:           b,
+				FromUserID:    fromUser,
+				ToUserID:      toUser,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Start.Before(changes[j].Start) })
+	return changes
+}
+
+// impactIncident is an open incident with its escalation timeline
+type impactIncident struct {
+	Incident db.Incident
+	Timeline *db.EscalationTimeline
+}
+
+// scheduleImpactIncidents loads the triggered and acknowledged incidents whose
+// escalation policy pages the group or one of its schedulers
+func (s *IncidentService) scheduleImpactIncidents(groupID string, now time.Time) ([]impactIncident, error) {
+	rows, err := s.PG.Query(`
+		SELECT i.id, i.title, i.status, COALESCE(i.assigned_to::text, ''), COALESCE(i.group_id::text, ''),
+		       i.escalation_policy_id::text, COALESCE(i.current_escalation_level, 0), i.last_escalated_at,
+		       COALESCE(i.escalation_status, ''), i.created_at
+		FROM incidents i
+		WHERE i.status IN ('triggered', 'acknowledged') AND i.escalation_policy_id IS NOT NULL
+		AND EXISTS (
+			SELECT 1 FROM escalation_levels el
+			WHERE el.policy_id = i.escalation_policy_id
+			AND ((el.target_type = 'scheduler' AND el.target_id IN (SELECT id FROM schedulers WHERE group_id = $1))
+			  OR (el.target_type = 'group' AND el.target_id = $1)
+			  OR (el.target_type = 'current_schedule' AND i.group_id = $1))
+		)
+		ORDER BY i.created_at ASC
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open incidents: %w", err)
+	}
+	incidents := []db.Incident{}
+	for rows.Next() {
+		var inc db.Incident
+		if err := rows.Scan(&inc.ID, &inc.Title, &inc.Status, &inc.AssignedTo, &inc.GroupID,
+			&inc.EscalationPolicyID, &inc.CurrentEscalationLevel, &inc.LastEscalatedAt,
+			&inc.EscalationStatus, &inc.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, inc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get open incidents: %w", err)
+	}
+
+	levels := map[string][]db.EscalationLevel{}
+	result := make([]impactIncident, 0, len(incidents))
+	for _, inc := range incidents {
+		policyLevels, ok := levels[inc.EscalationPolicyID]
+		if !ok {
+			if policyLevels, err = s.getTimelineEscalationLevels(inc.EscalationPolicyID); err != nil {
+				return nil, err
+			}
+			levels[inc.EscalationPolicyID] = policyLevels
+		}
+		history, err := s.getEscalationHistory(inc.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, impactIncident{Incident: inc, Timeline: buildEscalationTimeline(&inc, policyLevels, history, now)})
+	}
+	return result, nil
+}
+
+// incidentScheduleImpacts compares who each open incident's active and coming
+// escalation levels resolve to before and after the change. Only levels that
+// page this group or its schedulers are affected; a group that has nobody on
+// call leaves the level to its sub-teams, reported as an empty user.
+func incidentScheduleImpacts(incidents []impactIncident, before, after []db.OnCallInterval, groupID string, schedulers map[string]string, now, to time.Time) []db.ScheduleImpactIncident {
+	impacts := []db.ScheduleImpactIncident{}
+	for _, item := range incidents {
+		inc := item.Incident
+		for _, step := range item.Timeline.Steps {
+			var schedulerID string
+			_, groupScheduler := schedulers[step.TargetID]
+			switch {
+			case step.TargetType == "scheduler" && groupScheduler:
+				schedulerID = step.TargetID
+			case step.TargetType == "group" && step.TargetID == groupID:
+			case step.TargetType == "current_schedule" && inc.GroupID == groupID:
+			default:
+				continue
+			}
+
+			impact := db.ScheduleImpactIncident{IncidentID: inc.ID, Title: inc.Title, Status: inc.Status, Level: step.Level}
+			switch {
+			case step.State == db.EscalationStepActive && inc.AssignedTo != "":
+				impact.Kind, impact.At = db.ScheduleImpactCurrentAssignee, now
+				impact.FromUserID = onCallUserAt(before, schedulerID, now)
+				impact.ToUserID = onCallUserAt(after, schedulerID, now)
+				if impact.FromUserID != inc.AssignedTo {
+					continue // assigned by hand or by another level
+				}
+			case step.State == db.EscalationStepScheduled && step.ETA != nil && step.ETA.Before(to):
+				impact.Kind, impact.At = db.ScheduleImpactEscalation, *step.ETA
+				at := laterTime(*step.ETA, now)
+				impact.FromUserID = onCallUserAt(before, schedulerID, at)
+				impact.ToUserID = onCallUserAt(after, schedulerID, at)
+			default:
+				continue
+			}
+			if impact.FromUserID != impact.ToUserID {
+				impacts = append(impacts, impact)
+			}
+		}
+	}
+	return impacts
+}
+
+// fillScheduleImpactNames adds user names to the preview
+func (s *IncidentService) fillScheduleImpactNames(result *db.ScheduleImpact) error {
+	userIDs := []string{}
+	for _, c := range result.Changes {
+		userIDs = append(userIDs, c.FromUserID, c.ToUserID)
+	}
+	for _, i := range result.Incidents {
+		userIDs = append(userIDs, i.FromUserID, i.ToUserID)
+	}
+	userIDs = uniqueStrings(userIDs)
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	rows, err := s.PG.Query(`SELECT id::text, COALESCE(name, '') FROM users WHERE id::text = ANY($1)`, pq.Array(userIDs))
+	if err != nil {
+		return fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+	names := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get users: %w", err)
+	}
+
+	for i := range result.Changes {
+		c := &result.Changes[i]
+		c.FromUserName, c.ToUserName = names[c.FromUserID], names[c.ToUserID]
+	}
+	for i := range result.Incidents {
+		c := &result.Incidents[i]
+		c.FromUserName, c.ToUserName = names[c.FromUserID], names[c.ToUserID]
+	}
+	return nil
+}
+
+// scheduleImpactMessage explains an incident impact to the people on both sides
+func scheduleImpactMessage(impact db.ScheduleImpactIncident) string {
+	from, to := impact.FromUserName, impact.ToUserName
+	if from == "" {
+		from = "nobody in the group"
+	}
+	if to == "" {
+		to = "nobody in the group"
+	}
+	if impact.Kind == db.ScheduleImpactCurrentAssignee {
+		return fmt.Sprintf("A proposed schedule change takes the assignee off call: %s is on call now, %s would be.", from, to)
+	}
+	return fmt.Sprintf("A proposed schedule change would move the level %d escalation at %s UTC from %s to %s.",
+		impact.Level, impact.At.UTC().Format("Jan 2 15:04"), from, to)
+}
+
+// notifyScheduleImpact DMs the users on both sides of each incident impact,
+// once per user and incident, and returns who was notified
+func (s *IncidentService) notifyScheduleImpact(impacts []db.ScheduleImpactIncident) []string {
+	notified := []string{}
+	sent := map[string]bool{}
+	for _, impact := range impacts {
+		for _, userID := range []string{impact.FromUserID, impact.ToUserID} {
+			if userID == "" || sent[userID+"/"+impact.IncidentID] {
+				continue
+			}
+			sent[userID+"/"+impact.IncidentID] = true
+
+			channels := []string{"slack"}
+			payload, err := json.Marshal(map[string]interface{}{
+				"type":        "schedule_impact",
+				"user_id":     userID,
+				"incident_id": impact.IncidentID,
+				"channels":    channels,
+				"priority":    "medium",
+				"data":        map[string]interface{}{"message": scheduleImpactMessage(impact)},
+				"created_at":  time.Now(),
+				"retry_count": 0,
+			})
+			if err != nil {
+				log.Printf("⚠️  failed to marshal schedule impact notification: %v", err)
+				continue
+			}
+			if err := queueIncidentNotification(s.PG, userID, "schedule_impact", channels, payload); err != nil {
+				log.Printf("⚠️  %v", err)
+				continue
+			}
+			notified = append(notified, userID)
+		}
+	}
+	return uniqueStrings(notified)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestScheduleImpactOfOverride(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	to := now.Add(24 * time.Hour)
+	shifts := []onCallShift{
+		{ID: "sh-1", SchedulerID: "sch-1", SchedulerName: "Primary", UserID: "alice", Start: now.Add(-4 * time.Hour), End: now.Add(8 * time.Hour)},
+		{ID: "sh-2", SchedulerID: "sch-1", SchedulerName: "Primary", UserID: "bob", Start: now.Add(8 * time.Hour), End: now.Add(20 * time.Hour)},
+	}
+	schedulers := map[string]string{"sch-1": "Primary", "sch-2": "Secondary"}
+
+	// Carol covers alice for the next two hours
+	after, afterOverrides, errs := applyScheduleChange(shifts, nil, db.ScheduleImpactRequest{
+		Overrides: db.ScheduleImpactOverrides{Create: []db.CreateScheduleOverrideRequest{
+			{OriginalScheduleID: "sh-1", NewUserID: "carol", OverrideStartTime: now, OverrideEndTime: now.Add(2 * time.Hour)},
+		}},
+	}, schedulers)
+	if len(errs) > 0 {
+		t.Fatalf("errs = %+v", errs)
+	}
+	before := resolveOnCallIntervals(shifts, nil, now, to)
+	afterIntervals := resolveOnCallIntervals(after, afterOverrides, now, to)
+
+	changes := diffOnCallIntervals(before, afterIntervals, now, to)
+	if len(changes) != 1 || changes[0].FromUserID != "alice" || changes[0].ToUserID != "carol" ||
+		!changes[0].Start.Equal(now) || !changes[0].End.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	eta := now.Add(time.Hour)
+	later := now.Add(3 * time.Hour)
+	incidents := []impactIncident{
+		{
+			Incident: db.Incident{ID: "inc-1", Status: db.IncidentStatusTriggered, AssignedTo: "alice", GroupID: "grp-1"},
+			Timeline: &db.EscalationTimeline{Steps: []db.EscalationTimelineStep{
+				{Level: 1, State: db.EscalationStepActive, TargetType: "scheduler", TargetID: "sch-1"},
+				{Level: 2, State: db.EscalationStepScheduled, TargetType: "current_schedule", ETA: &eta},
+				{Level: 3, State: db.EscalationStepScheduled, TargetType: "scheduler", TargetID: "sch-1", ETA: &later},
+				{Level: 4, State: db.EscalationStepScheduled, TargetType: "scheduler", TargetID: "other-group", ETA: &eta},
+			}},
+		},
+		// Assigned by hand, so the change does not take it away from anyone
+		{
+			Incident: db.Incident{ID: "inc-2", Status: db.IncidentStatusAcknowledged, AssignedTo: "dave", GroupID: "grp-1"},
+			Timeline: &db.EscalationTimeline{Steps: []db.EscalationTimelineStep{
+				{Level: 1, State: db.EscalationStepActive, TargetType: "group", TargetID: "grp-1"},
+			}},
+		},
+	}
+	impacts := incidentScheduleImpacts(incidents, before, afterIntervals, "grp-1", schedulers, now, to)
+	if len(impacts) != 2 {
+		t.Fatalf("impacts = %+v", impacts)
+	}
+	if impacts[0].IncidentID != "inc-1" || impacts[0].Kind != db.ScheduleImpactCurrentAssignee ||
+		impacts[0].FromUserID != "alice" || impacts[0].ToUserID != "carol" {
+		t.Errorf("impacts[0] = %+v", impacts[0])
+	}
+	if impacts[1].Kind != db.ScheduleImpactEscalation || impacts[1].Level != 2 || !impacts[1].At.Equal(eta) ||
+		impacts[1].ToUserID != "carol" {
+		t.Errorf("impacts[1] = %+v", impacts[1])
+	}
+}
+
+func TestApplyScheduleChange(t *testing.T) {
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	shifts := []onCallShift{
+		{ID: "sh-1", SchedulerID: "sch-1", UserID: "alice", Start: start, End: start.Add(8 * time.Hour)},
+		{ID: "sh-2", SchedulerID: "sch-1", UserID: "bob", Start: start.Add(8 * time.Hour), End: start.Add(16 * time.Hour)},
+	}
+	overrides := []onCallOverride{{ID: "ov-1", ShiftID: "sh-2", NewUserID: "carol", Start: start.Add(8 * time.Hour), End: start.Add(9 * time.Hour)}}
+	dave := "dave"
+
+	after, afterOverrides, errs := applyScheduleChange(shifts, overrides, db.ScheduleImpactRequest{
+		Shifts: db.ShiftBatchRequest{
+			Create: []db.ShiftBatchCreate{
+				{SchedulerID: "sch-1", UserID: "erin", StartTime: start.Add(16 * time.Hour), EndTime: start.Add(24 * time.Hour)},
+				{SchedulerID: "sch-9", UserID: "erin", StartTime: start, EndTime: start.Add(time.Hour)},
+			},
+			Update: []db.ShiftBatchUpdate{{ID: "sh-1", UserID: &dave}},
+			Delete: []db.ShiftBatchDelete{{ID: "sh-2"}, {ID: "sh-7"}},
+		},
+		Overrides: db.ScheduleImpactOverrides{Delete: []string{"ov-1"}},
+	}, map[string]string{"sch-1": "Primary"})
+
+	if len(errs) != 2 || errs[0].Op != "delete" || errs[0].ShiftID != "sh-7" || errs[1].Op != "create" || errs[1].Index != 1 {
+		t.Errorf("errs = %+v", errs)
+	}
+	if len(after) != 2 || after[0].UserID != "dave" || after[1].ID != "create[0]" || after[1].SchedulerName != "Primary" {
+		t.Errorf("after = %+v", after)
+	}
+	if len(afterOverrides) != 0 {
+		t.Errorf("overrides = %+v", afterOverrides)
+	}
+	if shifts[0].UserID != "alice" {
+		t.Error("original shifts were modified")
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// shiftSlot is the part of a shift schedule regeneration works with
+type shiftSlot struct {
+	ID        string
+	UserID    string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// RegenerateSchedulesForDeparture recomputes the future shifts of every
+// scheduler in the group that still has shifts for the departing user,
+// redistributing them round-robin across the scheduler's remaining rotation
+// members. All schedulers are updated in one transaction; with preview the
+// plan is returned and nothing is written.
+func (s *SchedulerService) RegenerateSchedulesForDeparture(groupID, departingUserID string, preview bool) (*db.ScheduleRegenerationResult, error) {
+	now := time.Now()
+	result := &db.ScheduleRegenerationResult{
+		GroupID:     groupID,
+		UserID:      departingUserID,
+		Preview:     preview,
+		Schedulers:  []db.SchedulerRegeneration{},
+		GeneratedAt: now,
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	members, err := groupMemberSet(tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	delete(members, departingUserID)
+
+	rows, err := tx.Query(`
+		SELECT sc.id, COALESCE(NULLIF(sc.display_name, ''), sc.name)
+		FROM schedulers sc
+		WHERE sc.group_id = $1 AND EXISTS (
+			SELECT 1 FROM shifts sh
+			WHERE sh.scheduler_id = sc.id AND sh.user_id = $2 AND sh.is_active = true AND sh.end_time > $3
+		)
+		ORDER BY sc.name ASC
+	`, groupID, departingUserID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find affected schedulers: %w", err)
+	}
+	for rows.Next() {
+		var regen db.SchedulerRegeneration
+		if err := rows.Scan(&regen.SchedulerID, &regen.SchedulerName); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan scheduler: %w", err)
+		}
+		result.Schedulers = append(result.Schedulers, regen)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range result.Schedulers {
+		regen := &result.Schedulers[i]
+		slots, err := lockSchedulerShifts(tx, regen.SchedulerID)
+		if err != nil {
+			return nil, err
+		}
+		regen.Members, regen.Reassignments = planShiftRedistribution(slots, departingUserID, members, now)
+		if len(regen.Members) == 0 {
+			regen.SkippedReason = "no remaining group members in this rotation"
+			regen.Reassignments = []db.ShiftReassignment{}
+			continue
+		}
+		result.ReassignedShifts += len(regen.Reassignments)
+
+		if preview {
+			continue
+		}
+		for _, r := range regen.Reassignments {
+			if _, err := tx.Exec(`UPDATE shifts SET user_id = $2, updated_at = NOW() WHERE id = $1`, r.ShiftID, r.ToUserID); err != nil {
+				return nil, fmt.Errorf("failed to reassign shift %s: %w", r.ShiftID, err)
+			}
+		}
+	}
+
+	if preview {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit schedule regeneration: %w", err)
+	}
+	return result, nil
+}
+
+// groupMemberSet returns the IDs of a group's current members
+func groupMemberSet(tx *sql.Tx, groupID string) (map[string]bool, error) {
+	rows, err := tx.Query(`
+		SELECT user_id FROM memberships WHERE resource_type = 'group' AND resource_id = $1
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := map[string]bool{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan group member: %w", err)
+		}
+		members[userID] = true
+	}
+	return members, rows.Err()
+}
+
+// lockSchedulerShifts returns a scheduler's active shifts in time order,
+// locked until the transaction ends
+func lockSchedulerShifts(tx *sql.Tx, schedulerID string) ([]shiftSlot, error) {
+	rows, err := tx.Query(`
+		SELECT id, user_id, start_time, end_time
+		FROM shifts
+		WHERE scheduler_id = $1 AND is_active = true
+		ORDER BY start_time ASC, id ASC
+		FOR UPDATE
+	`, schedulerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduler shifts: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []shiftSlot
+	for rows.Next() {
+		var slot shiftSlot
+		if err := rows.Scan(&slot.ID, &slot.UserID, &slot.StartTime, &slot.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan shift: %w", err)
+		}
+		slots = append(slots, slot)
+	}
+	return slots, rows.Err()
+}
+
+// planShiftRedistribution works out a scheduler's new future shifts once
+// departingUserID leaves. The rotation order is the order members first
+// appear in the scheduler's shifts, keeping only current group members.
+// Shifts that haven't started, plus the departing user's shift in progress,
+// are handed out round-robin in that order, continuing from whoever was last
+// on call. It returns the remaining rotation and the shifts that change hands.
+func planShiftRedistribution(slots []shiftSlot, departingUserID string, members map[string]bool, now time.Time) ([]string, []db.ShiftReassignment) {
+	rotation := []string{}
+	position := map[string]int{}
+	for _, slot := range slots {
+		if _, seen := position[slot.UserID]; seen || slot.UserID == departingUserID || !members[slot.UserID] {
+			continue
+		}
+		position[slot.UserID] = len(rotation)
+		rotation = append(rotation, slot.UserID)
+	}
+	reassignments := []db.ShiftReassignment{}
+	if len(rotation) == 0 {
+		return rotation, reassignments
+	}
+
+	next := 0
+	for _, slot := range slots {
+		if slot.StartTime.After(now) {
+			break
+		}
+		if pos, ok := position[slot.UserID]; ok {
+			next = pos + 1
+		}
+	}
+
+	for _, slot := range slots {
+		started := !slot.StartTime.After(now)
+		if started && (slot.UserID != departingUserID || !slot.EndTime.After(now)) {
+			continue
+		}
+		to := rotation[next%len(rotation)]
+		next++
+		if to == slot.UserID {
+			continue
+		}
+		reassignments = append(reassignments, db.ShiftReassignment{
+			ShiftID:    slot.ID,
+			StartTime:  slot.StartTime,
+			EndTime:    slot.EndTime,
+			FromUserID: slot.UserID,
+			ToUserID:   to,
+		})
+	}
+	return rotation, reassignments
+}
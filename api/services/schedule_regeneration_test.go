@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPlanShiftRedistribution(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return now.Add(time.Duration(d) * 24 * time.Hour).Truncate(24 * time.Hour) }
+	slots := []shiftSlot{
+		{ID: "s1", UserID: "alice", StartTime: day(-3), EndTime: day(-2)},
+		{ID: "s2", UserID: "bob", StartTime: day(-2), EndTime: day(-1)},
+		{ID: "s3", UserID: "carol", StartTime: day(-1), EndTime: day(0)},
+		{ID: "s4", UserID: "dave", StartTime: day(0), EndTime: day(1)}, // in progress
+		{ID: "s5", UserID: "alice", StartTime: day(1), EndTime: day(2)},
+		{ID: "s6", UserID: "bob", StartTime: day(2), EndTime: day(3)},
+		{ID: "s7", UserID: "carol", StartTime: day(3), EndTime: day(4)},
+		{ID: "s8", UserID: "dave", StartTime: day(4), EndTime: day(5)},
+	}
+	members := map[string]bool{"alice": true, "bob": true, "carol": true}
+
+	rotation, reassignments := planShiftRedistribution(slots, "dave", members, now)
+	if len(rotation) != 3 || rotation[0] != "alice" || rotation[2] != "carol" {
+		t.Fatalf("unexpected rotation %v", rotation)
+	}
+
+	// carol was last on call, so the rotation continues alice, bob, carol, alice, bob
+	want := map[string]string{"s4": "alice", "s5": "bob", "s6": "carol", "s7": "alice", "s8": "bob"}
+	if len(reassignments) != len(want) {
+		t.Fatalf("got %d reassignments, want %d: %+v", len(reassignments), len(want), reassignments)
+	}
+	for _, r := range reassignments {
+		if want[r.ShiftID] != r.ToUserID {
+			t.Errorf("shift %s: got %s, want %s", r.ShiftID, r.ToUserID, want[r.ShiftID])
+		}
+	}
+}
+
+func TestPlanShiftRedistributionWithoutRemainingMembers(t *testing.T) {
+	now := time.Now()
+	slots := []shiftSlot{{ID: "s1", UserID: "dave", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}}
+
+	rotation, reassignments := planShiftRedistribution(slots, "dave", map[string]bool{"alice": true}, now)
+	if len(rotation) != 0 || len(reassignments) != 0 {
+		t.Errorf("expected nothing to redistribute, got %v %v", rotation, reassignments)
+	}
+}
+
+func TestRegenerateSchedulesPreviewWritesNothing(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM memberships`).WithArgs("grp-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice").AddRow("bob").AddRow("dave"))
+	mock.ExpectQuery(`FROM schedulers sc`).WithArgs("grp-1", "dave", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("sch-1", "Primary"))
+	mock.ExpectQuery(`FROM shifts`).WithArgs("sch-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "start_time", "end_time"}).
+			AddRow("s1", "alice", now.Add(-48*time.Hour), now.Add(-24*time.Hour)).
+			AddRow("s2", "dave", now.Add(24*time.Hour), now.Add(48*time.Hour)).
+			AddRow("s3", "bob", now.Add(48*time.Hour), now.Add(72*time.Hour)))
+	mock.ExpectRollback()
+
+	result, err := NewSchedulerService(pg).RegenerateSchedulesForDeparture("grp-1", "dave", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Preview || result.ReassignedShifts != 2 || len(result.Schedulers) != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
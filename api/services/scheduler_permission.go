@@ -0,0 +1,233 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	// ErrSchedulerForbidden means the user doesn't manage the scheduler whose
+	// shifts or overrides they tried to change
+	ErrSchedulerForbidden = errors.New("only schedule managers can change this schedule")
+	// ErrSchedulerManagersForbidden means the user tried to choose a
+	// scheduler's managers without being an owner or admin of its group
+	ErrSchedulerManagersForbidden = errors.New("only group owners and admins can choose schedule managers")
+	ErrSchedulerNotInGroup        = errors.New("scheduler not found in this group")
+	ErrSchedulerManagerNotMember  = errors.New("schedule managers must be members of the group")
+	ErrSchedulerManagerNotFound   = errors.New("user is not a manager of this scheduler")
+)
+
+// scheduleManagerCondition is true when the user in $1 manages the schedule:
+// they own or administer its group, or are listed as a manager of the
+// scheduler. Shifts without a scheduler are managed by the group alone.
+func scheduleManagerCondition(groupCol, schedulerCol string) string {
+	return fmt.Sprintf(`(
+		EXISTS (
+			SELECT 1 FROM memberships m
+			WHERE m.resource_type = 'group' AND m.resource_id = %[1]s AND m.user_id = $1
+			  AND m.role IN ('owner', 'admin')
+		)
+		OR EXISTS (
+			SELECT 1 FROM scheduler_managers sm
+			WHERE sm.scheduler_id = %[2]s AND sm.user_id = $1
+		)
+	)`, groupCol, schedulerCol)
+}
+
+// canManageSchedulers reports whether the user manages every one of the
+// schedulers. Unknown IDs are ignored; callers report those themselves.
+func canManageSchedulers(pg *sql.DB, userID string, schedulerIDs []string) (bool, error) {
+	if len(schedulerIDs) == 0 {
+		return true, nil
+	}
+	var denied int
+	err := pg.QueryRow(`
+		SELECT COUNT(*) FROM schedulers sc
+		WHERE sc.id::text = ANY($2) AND NOT `+scheduleManagerCondition("sc.group_id", "sc.id"),
+		userID, pq.Array(schedulerIDs)).Scan(&denied)
+	if err != nil {
+		return false, fmt.Errorf("failed to check schedule permissions: %w", err)
+	}
+	return denied == 0, nil
+}
+
+// canManageShifts reports whether the user manages the schedulers of every
+// one of the shifts. Unknown IDs are ignored.
+func canManageShifts(pg *sql.DB, userID string, shiftIDs []string) (bool, error) {
+	if len(shiftIDs) == 0 {
+		return true, nil
+	}
+	var denied int
+	err := pg.QueryRow(`
+		SELECT COUNT(*) FROM shifts s
+		WHERE s.id::text = ANY($2) AND NOT `+scheduleManagerCondition("s.group_id", "s.scheduler_id"),
+		userID, pq.Array(shiftIDs)).Scan(&denied)
+	if err != nil {
+		return false, fmt.Errorf("failed to check schedule permissions: %w", err)
+	}
+	return denied == 0, nil
+}
+
+// isGroupScheduleAdmin reports whether the user owns or administers the
+// group, which makes them a manager of all its schedulers
+func isGroupScheduleAdmin(pg *sql.DB, groupID, userID string) (bool, error) {
+	var ok bool
+	err := pg.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM memberships
+			WHERE resource_type = 'group' AND resource_id::text = $1 AND user_id::text = $2
+			  AND role IN ('owner', 'admin')
+		)
+	`, groupID, userID).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("failed to check group role: %w", err)
+	}
+	return ok, nil
+}
+
+// CanManageScheduler reports whether the user may change the scheduler's
+// shifts and overrides
+func (s *SchedulerService) CanManageScheduler(schedulerID, userID string) (bool, error) {
+	return canManageSchedulers(s.PG, userID, []string{schedulerID})
+}
+
+// CanManageGroupSchedules reports whether the user manages every scheduler of
+// the group, as its owners and admins do
+func (s *SchedulerService) CanManageGroupSchedules(groupID, userID string) (bool, error) {
+	return isGroupScheduleAdmin(s.PG, groupID, userID)
+}
+
+// requireSchedulerManager returns ErrSchedulerForbidden unless the user
+// manages the scheduler
+func (s *SchedulerService) requireSchedulerManager(schedulerID, userID string) error {
+	ok, err := s.CanManageScheduler(schedulerID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSchedulerForbidden
+	}
+	return nil
+}
+
+// schedulerInGroup returns ErrSchedulerNotInGroup unless the scheduler is an
+// active scheduler of the group
+func (s *SchedulerService) schedulerInGroup(groupID, schedulerID string) error {
+	var exists bool
+	err := s.PG.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM schedulers
+			WHERE id::text = $1 AND group_id::text = $2 AND is_active = true
+		)
+	`, schedulerID, groupID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduler: %w", err)
+	}
+	if !exists {
+		return ErrSchedulerNotInGroup
+	}
+	return nil
+}
+
+// ListSchedulerManagers returns the users listed as managers of the
+// scheduler. The group's owners and admins manage it too without being
+// listed.
+func (s *SchedulerService) ListSchedulerManagers(groupID, schedulerID string) ([]db.SchedulerManager, error) {
+	if err := s.schedulerInGroup(groupID, schedulerID); err != nil {
+		return nil, err
+	}
+	rows, err := s.PG.Query(`
+		SELECT sm.scheduler_id, sm.user_id, COALESCE(u.name, ''), COALESCE(u.email, ''),
+		       COALESCE(sm.created_by::text, ''), sm.created_at
+		FROM scheduler_managers sm
+		JOIN users u ON u.id = sm.user_id
+		WHERE sm.scheduler_id::text = $1
+		ORDER BY sm.created_at ASC
+	`, schedulerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduler managers: %w", err)
+	}
+	defer rows.Close()
+
+	managers := []db.SchedulerManager{}
+	for rows.Next() {
+		var m db.SchedulerManager
+		if err := rows.Scan(&m.SchedulerID, &m.UserID, &m.UserName, &m.UserEmail, &m.CreatedBy, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduler manager: %w", err)
+		}
+		managers = append(managers, m)
+	}
+	return managers, rows.Err()
+}
+
+// AddSchedulerManager lets a member of the group manage the scheduler. Only
+// the group's owners and admins may choose managers.
+func (s *SchedulerService) AddSchedulerManager(groupID, schedulerID string, req db.AddSchedulerManagerRequest, addedBy string) (*db.SchedulerManager, error) {
+	if err := s.schedulerInGroup(groupID, schedulerID); err != nil {
+		return nil, err
+	}
+	if ok, err := isGroupScheduleAdmin(s.PG, groupID, addedBy); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrSchedulerManagersForbidden
+	}
+
+	var member bool
+	err := s.PG.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM memberships
+			WHERE resource_type = 'group' AND resource_id::text = $1 AND user_id::text = $2
+		)
+	`, groupID, req.UserID).Scan(&member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check group membership: %w", err)
+	}
+	if !member {
+		return nil, ErrSchedulerManagerNotMember
+	}
+
+	m := db.SchedulerManager{SchedulerID: schedulerID, UserID: req.UserID}
+	err = s.PG.QueryRow(`
+		WITH added AS (
+			INSERT INTO scheduler_managers (scheduler_id, user_id, created_by)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (scheduler_id, user_id) DO UPDATE SET scheduler_id = EXCLUDED.scheduler_id
+			RETURNING user_id, created_by, created_at
+		)
+		SELECT COALESCE(u.name, ''), COALESCE(u.email, ''), COALESCE(a.created_by::text, ''), a.created_at
+		FROM added a
+		JOIN users u ON u.id = a.user_id
+	`, schedulerID, req.UserID, addedBy).Scan(&m.UserName, &m.UserEmail, &m.CreatedBy, &m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add scheduler manager: %w", err)
+	}
+	return &m, nil
+}
+
+// RemoveSchedulerManager takes a user off the scheduler's managers. Only the
+// group's owners and admins may choose managers.
+func (s *SchedulerService) RemoveSchedulerManager(groupID, schedulerID, userID, removedBy string) error {
+	if err := s.schedulerInGroup(groupID, schedulerID); err != nil {
+		return err
+	}
+	if ok, err := isGroupScheduleAdmin(s.PG, groupID, removedBy); err != nil {
+		return err
+	} else if !ok {
+		return ErrSchedulerManagersForbidden
+	}
+
+	result, err := s.PG.Exec(`
+		DELETE FROM scheduler_managers WHERE scheduler_id::text = $1 AND user_id::text = $2
+	`, schedulerID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove scheduler manager: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSchedulerManagerNotFound
+	}
+	return nil
+}
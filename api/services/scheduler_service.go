@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/vanchonlee/slar/db"
 )
 
@@ -661,8 +662,14 @@ func (s *SchedulerService) GetOrCreateDefaultScheduler(groupID, createdBy string
 	return s.CreateScheduler(groupID, req, createdBy)
 }
 
-// DeleteScheduler soft deletes a scheduler and all its associated shifts
-func (s *SchedulerService) DeleteScheduler(schedulerID string) error {
+// DeleteScheduler soft deletes a scheduler and all its associated shifts and
+// moves it to the trash, from where it can be restored with those shifts.
+// Only its schedule managers may delete it.
+func (s *SchedulerService) DeleteScheduler(schedulerID, deletedBy string) error {
+	if err := s.requireSchedulerManager(schedulerID, deletedBy); err != nil {
+		return err
+	}
+
 	// Start a transaction to ensure atomicity
 	tx, err := s.PG.Begin()
 	if err != nil {
@@ -671,11 +678,16 @@ func (s *SchedulerService) DeleteScheduler(schedulerID string) error {
 	defer tx.Rollback()
 
 	// First, soft delete all shifts associated with this scheduler
-	_, err = tx.Exec(`
-		UPDATE shifts
-		SET is_active = false, updated_at = $1
-		WHERE scheduler_id = $2
-	`, time.Now(), schedulerID)
+	var snapshot schedulerTrashSnapshot
+	err = tx.QueryRow(`
+		WITH deactivated AS (
+			UPDATE shifts
+			SET is_active = false, updated_at = $1
+			WHERE scheduler_id = $2 AND is_active = true
+			RETURNING id
+		)
+		SELECT COALESCE(array_agg(id::text), '{}') FROM deactivated
+	`, time.Now(), schedulerID).Scan(pq.Array(&snapshot.ShiftIDs))
 
 	if err != nil {
 		return fmt.Errorf("failed to deactivate scheduler shifts: %w", err)
@@ -701,6 +713,10 @@ func (s *SchedulerService) DeleteScheduler(schedulerID string) error {
 		return fmt.Errorf("scheduler not found")
 	}
 
+	if err := moveToTrash(tx, db.TrashResourceScheduler, schedulerID, deletedBy, snapshot); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -948,8 +964,13 @@ func countOverriddenShifts(shifts []db.Shift) int {
 	return count
 }
 
-// UpdateSchedulerWithShifts updates a scheduler and replaces all its shifts in a single transaction
+// UpdateSchedulerWithShifts updates a scheduler and replaces all its shifts in a single transaction.
+// Only its schedule managers may update it.
 func (s *SchedulerService) UpdateSchedulerWithShifts(schedulerID string, schedulerReq db.CreateSchedulerRequest, shifts []db.CreateShiftRequest, updatedBy string) (db.Scheduler, []db.Shift, error) {
+	if err := s.requireSchedulerManager(schedulerID, updatedBy); err != nil {
+		return db.Scheduler{}, nil, err
+	}
+
 	// Start transaction
 	tx, err := s.PG.Begin()
 	if err != nil {
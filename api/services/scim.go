@@ -0,0 +1,1006 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanchonlee/slar/db"
+)
+
+// SCIM errors mapped to SCIM status codes in handlers
+var (
+	ErrSCIMNotFound      = errors.New("resource not found")
+	ErrSCIMConflict      = errors.New("resource already exists")
+	ErrSCIMInvalidFilter = errors.New("unsupported filter")
+	ErrSCIMInvalidValue  = errors.New("invalid value")
+	ErrSCIMUnauthorized  = errors.New("invalid SCIM token")
+)
+
+// SCIMService implements SCIM 2.0 user and group provisioning for one organization
+// at a time. Users are global SLAR users joined to the org via memberships;
+// groups are SLAR groups owned by the org. Group membership changes go through
+// GroupService so ReBAC rules stay consistent with the UI, and each request's
+// changes are applied in one transaction.
+type SCIMService struct {
+	PG           *sql.DB
+	GroupService *GroupService
+}
+
+// NewSCIMService creates a new SCIMService
+func NewSCIMService(pg *sql.DB, groupService *GroupService) *SCIMService {
+	return &SCIMService{PG: pg, GroupService: groupService}
+}
+
+// TOKENS
+
+// CreateToken issues a new SCIM bearer token for an org. The raw token is only returned once.
+func (s *SCIMService) CreateToken(orgID, name, createdBy string) (*db.SCIMToken, error) {
+	raw, _, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	raw = "scim_" + raw
+	hash := hashInvitationToken(raw)
+
+	t := db.SCIMToken{OrganizationID: orgID, Name: name, IsActive: true, CreatedBy: createdBy, Token: raw}
+	err = s.PG.QueryRow(`
+		INSERT INTO scim_tokens (organization_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, orgID, name, hash, nullIfEmpty(createdBy)).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SCIM token: %w", err)
+	}
+	return &t, nil
+}
+
+// ListTokens returns SCIM tokens for an org (without secrets)
+func (s *SCIMService) ListTokens(orgID string) ([]db.SCIMToken, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, organization_id, name, is_active, COALESCE(created_by::text, ''), last_used_at, created_at
+		FROM scim_tokens
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []db.SCIMToken{}
+	for rows.Next() {
+		var t db.SCIMToken
+		if err := rows.Scan(&t.ID, &t.OrganizationID, &t.Name, &t.IsActive, &t.CreatedBy, &t.LastUsedAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken deactivates a SCIM token
+func (s *SCIMService) RevokeToken(orgID, tokenID string) error {
+	result, err := s.PG.Exec(`UPDATE scim_tokens SET is_active = FALSE WHERE id = $1 AND organization_id = $2`, tokenID, orgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSCIMNotFound
+	}
+	return nil
+}
+
+// AuthenticateToken resolves a raw bearer token to its organization
+func (s *SCIMService) AuthenticateToken(raw string) (string, error) {
+	var orgID string
+	err := s.PG.QueryRow(`
+		UPDATE scim_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1 AND is_active = TRUE
+		RETURNING organization_id
+	`, hashInvitationToken(raw)).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return "", ErrSCIMUnauthorized
+	}
+	if err != nil {
+		return "", err
+	}
+	return orgID, nil
+}
+
+// USERS
+
+// scimOnlyOrg limits writes to a user's global columns (name, email, phone,
+// is_active) to users the provisioning org is the only organization of ($1 is
+// the user, $2 the org). Users shared with other organizations are only
+// joined to or removed from the org's memberships.
+const scimOnlyOrg = `NOT EXISTS (
+	SELECT 1 FROM memberships WHERE user_id = $1 AND resource_type = 'org' AND resource_id <> $2
+)`
+
+const scimUserSelect = `
+	SELECT u.id, u.name, u.email, COALESCE(u.phone, ''), COALESCE(u.team, ''), COALESCE(u.is_active, TRUE),
+		u.created_at, u.updated_at, COALESCE(x.external_id, '')
+	FROM users u
+	JOIN memberships m ON m.user_id = u.id AND m.resource_type = 'org' AND m.resource_id = $1
+	LEFT JOIN scim_external_ids x ON x.organization_id = $1 AND x.resource_type = 'user' AND x.resource_id = u.id
+`
+
+// ListUsers returns org users matching an optional SCIM filter, with 1-based pagination
+func (s *SCIMService) ListUsers(orgID, filter string, startIndex, count int) ([]db.SCIMUser, int, error) {
+	where, args, err := scimUserFilterSQL(filter, []interface{}{orgID})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*) FROM users u
+		JOIN memberships m ON m.user_id = u.id AND m.resource_type = 'org' AND m.resource_id = $1
+		LEFT JOIN scim_external_ids x ON x.organization_id = $1 AND x.resource_type = 'user' AND x.resource_id = u.id
+	` + where
+	if err := s.PG.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := scimUserSelect + where + fmt.Sprintf(" ORDER BY u.created_at ASC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, count, startIndex-1)
+
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []db.SCIMUser{}
+	for rows.Next() {
+		u, err := scanSCIMUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// GetUser returns a single org user as a SCIM resource, including group memberships in the org
+func (s *SCIMService) GetUser(orgID, userID string) (*db.SCIMUser, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrSCIMNotFound
+	}
+	row := s.PG.QueryRow(scimUserSelect+" WHERE u.id = $2", orgID, userID)
+	u, err := scanSCIMUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrSCIMNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.PG.Query(`
+		SELECT g.id, g.name FROM memberships m
+		JOIN groups g ON g.id = m.resource_id
+		WHERE m.resource_type = 'group' AND m.user_id = $1 AND g.organization_id = $2 AND g.is_active = TRUE
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var g db.SCIMMultiValued
+		if err := rows.Scan(&g.Value, &g.Display); err != nil {
+			return nil, err
+		}
+		u.Groups = append(u.Groups, g)
+	}
+	return &u, rows.Err()
+}
+
+// CreateUser provisions a user into the org. If a SLAR user with the same email
+// already exists, it is linked rather than duplicated, and only updated when
+// it belongs to no other organization.
+func (s *SCIMService) CreateUser(orgID string, in db.SCIMUser) (*db.SCIMUser, error) {
+	email := scimPrimaryEmail(in)
+	if email == "" {
+		return nil, fmt.Errorf("%w: userName or emails is required", ErrSCIMInvalidValue)
+	}
+	active := in.Active == nil || *in.Active
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRow(`SELECT id FROM users WHERE lower(email) = lower($1) LIMIT 1`, email).Scan(&userID)
+	switch {
+	case err == sql.ErrNoRows:
+		userID = uuid.New().String()
+		now := time.Now()
+		phone, encErr := encryptColumn(scimPrimaryPhone(in))
+		if encErr != nil {
+			return nil, encErr
+		}
+		_, err = tx.Exec(`
+			INSERT INTO users (id, provider, provider_id, name, email, phone, role, team, is_active, created_at, updated_at)
+			VALUES ($1, 'scim', $2, $3, $4, $5, 'engineer', 'Default Team', $6, $7, $7)
+		`, userID, scimProviderID(in, userID), scimDisplayName(in, email), email, phone, active, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		var inOrg int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM memberships WHERE user_id = $1 AND resource_type = 'org' AND resource_id = $2
+		`, userID, orgID).Scan(&inOrg); err != nil {
+			return nil, err
+		}
+		if inOrg > 0 {
+			var linked int
+			tx.QueryRow(`
+				SELECT COUNT(*) FROM scim_external_ids WHERE organization_id = $1 AND resource_type = 'user' AND resource_id = $2
+			`, orgID, userID).Scan(&linked)
+			if linked > 0 {
+				return nil, ErrSCIMConflict
+			}
+		}
+		_, err = tx.Exec(`UPDATE users SET name = $3, is_active = $4, updated_at = NOW() WHERE id = $1 AND `+scimOnlyOrg,
+			userID, orgID, scimDisplayName(in, email), active)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO memberships (user_id, resource_type, resource_id, role, created_at, updated_at)
+		VALUES ($1, 'org', $2, 'member', NOW(), NOW())
+		ON CONFLICT (user_id, resource_type, resource_id) DO NOTHING
+	`, userID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add user to organization: %w", err)
+	}
+
+	if err := setSCIMExternalID(tx, orgID, "user", userID, in.ExternalID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetUser(orgID, userID)
+}
+
+// ReplaceUser applies a full PUT of the user's SCIM attributes. Profile
+// attributes of users in other organizations too are left as they are.
+func (s *SCIMService) ReplaceUser(orgID, userID string, in db.SCIMUser) (*db.SCIMUser, error) {
+	current, err := s.GetUser(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := scimPrimaryEmail(in)
+	if email == "" {
+		email = current.UserName
+	}
+	active := in.Active == nil || *in.Active
+	phone, err := encryptColumn(scimPrimaryPhone(in))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET name = $3, email = $4, phone = $5, is_active = $6, updated_at = NOW()
+		WHERE id = $1 AND `+scimOnlyOrg, userID, orgID, scimDisplayName(in, email), email, phone, active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if err := setSCIMExternalID(tx, orgID, "user", userID, in.ExternalID); err != nil {
+		return nil, err
+	}
+	if !active {
+		if err := removeUserFromOrgGroups(tx, orgID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetUser(orgID, userID)
+}
+
+// PatchUser applies SCIM PatchOp operations. Supported: active, displayName,
+// name.formatted, userName, externalId, phoneNumbers (both path and value-map forms).
+// Profile attributes of users in other organizations too are left as they are.
+func (s *SCIMService) PatchUser(orgID, userID string, ops []db.SCIMPatchOperation) (*db.SCIMUser, error) {
+	current, err := s.GetUser(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	updates := map[string]interface{}{}
+	for _, op := range ops {
+		if !strings.EqualFold(op.Op, "replace") && !strings.EqualFold(op.Op, "add") {
+			continue
+		}
+		attrs := map[string]interface{}{}
+		if op.Path != "" {
+			attrs[op.Path] = op.Value
+		} else if m, ok := op.Value.(map[string]interface{}); ok {
+			attrs = m
+		}
+		for path, value := range attrs {
+			switch strings.ToLower(path) {
+			case "active":
+				b, ok := scimBool(value)
+				if !ok {
+					return nil, fmt.Errorf("%w: active must be a boolean", ErrSCIMInvalidValue)
+				}
+				updates["is_active"] = b
+			case "displayname", "name.formatted":
+				if str, ok := value.(string); ok && str != "" {
+					updates["name"] = str
+				}
+			case "username":
+				if str, ok := value.(string); ok && str != "" {
+					updates["email"] = str
+				}
+			case "externalid":
+				if str, ok := value.(string); ok {
+					if err := setSCIMExternalID(tx, orgID, "user", userID, str); err != nil {
+						return nil, err
+					}
+				}
+			case `phonenumbers[type eq "work"].value`, "phonenumbers":
+				if str, ok := value.(string); ok {
+					updates["phone"] = str
+				} else if list, ok := value.([]interface{}); ok && len(list) > 0 {
+					if m, ok := list[0].(map[string]interface{}); ok {
+						if str, ok := m["value"].(string); ok {
+							updates["phone"] = str
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if phone, ok := updates["phone"].(string); ok {
+		sealed, err := encryptColumn(phone)
+		if err != nil {
+			return nil, err
+		}
+		updates["phone"] = sealed
+	}
+
+	if len(updates) > 0 {
+		setClauses := []string{}
+		args := []interface{}{userID, orgID}
+		for col, val := range updates {
+			args = append(args, val)
+			setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col, len(args)))
+		}
+		query := "UPDATE users SET " + strings.Join(setClauses, ", ") + ", updated_at = NOW() WHERE id = $1 AND " + scimOnlyOrg
+		if _, err := tx.Exec(query, args...); err != nil {
+			return nil, fmt.Errorf("failed to patch user: %w", err)
+		}
+	}
+
+	if active, ok := updates["is_active"].(bool); ok && !active && current.Active != nil && *current.Active {
+		if err := removeUserFromOrgGroups(tx, orgID, userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetUser(orgID, userID)
+}
+
+// DeleteUser deprovisions a user from the org: removes org and group memberships.
+// The user row is deactivated only if they no longer belong to any organization.
+func (s *SCIMService) DeleteUser(orgID, userID string) error {
+	if _, err := s.GetUser(orgID, userID); err != nil {
+		return err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := removeUserFromOrgGroups(tx, orgID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM memberships WHERE user_id = $1 AND resource_type = 'org' AND resource_id = $2`, userID, orgID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM memberships WHERE user_id = $1 AND resource_type = 'project'
+		AND resource_id IN (SELECT id FROM projects WHERE organization_id = $2)
+	`, userID, orgID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM scim_external_ids WHERE organization_id = $1 AND resource_type = 'user' AND resource_id = $2`, orgID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE users SET is_active = FALSE, updated_at = NOW()
+		WHERE id = $1 AND NOT EXISTS (
+			SELECT 1 FROM memberships WHERE user_id = $1 AND resource_type = 'org'
+		)
+	`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func removeUserFromOrgGroups(ex scimExecer, orgID, userID string) error {
+	_, err := ex.Exec(`
+		DELETE FROM memberships
+		WHERE user_id = $1 AND resource_type = 'group'
+		AND resource_id IN (SELECT id FROM groups WHERE organization_id = $2)
+	`, userID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to remove user from groups: %w", err)
+	}
+	return nil
+}
+
+// GROUPS
+
+const scimGroupSelect = `
+	SELECT g.id, g.name, g.created_at, g.updated_at, COALESCE(x.external_id, '')
+	FROM groups g
+	LEFT JOIN scim_external_ids x ON x.organization_id = $1 AND x.resource_type = 'group' AND x.resource_id = g.id
+	WHERE g.organization_id = $1 AND g.is_active = TRUE
+`
+
+// ListGroups returns org groups matching an optional displayName/externalId filter
+func (s *SCIMService) ListGroups(orgID, filter string, startIndex, count int, includeMembers bool) ([]db.SCIMGroup, int, error) {
+	args := []interface{}{orgID}
+	where := ""
+	if filter != "" {
+		attr, value, err := parseSCIMFilter(filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch strings.ToLower(attr) {
+		case "displayname":
+			where = " AND lower(g.name) = lower($2)"
+		case "externalid":
+			where = " AND x.external_id = $2"
+		case "id":
+			if _, err := uuid.Parse(value); err != nil {
+				return []db.SCIMGroup{}, 0, nil
+			}
+			where = " AND g.id = $2"
+		default:
+			return nil, 0, ErrSCIMInvalidFilter
+		}
+		args = append(args, value)
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*) FROM groups g
+		LEFT JOIN scim_external_ids x ON x.organization_id = $1 AND x.resource_type = 'group' AND x.resource_id = g.id
+		WHERE g.organization_id = $1 AND g.is_active = TRUE
+	` + where
+	if err := s.PG.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := scimGroupSelect + where + fmt.Sprintf(" ORDER BY g.created_at ASC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, count, startIndex-1)
+	rows, err := s.PG.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	groups := []db.SCIMGroup{}
+	for rows.Next() {
+		g, err := scanSCIMGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if includeMembers {
+		for i := range groups {
+			members, err := groupMembers(s.PG, groups[i].ID)
+			if err != nil {
+				return nil, 0, err
+			}
+			groups[i].Members = members
+		}
+	}
+	return groups, total, nil
+}
+
+// GetGroup returns a single org group with members
+func (s *SCIMService) GetGroup(orgID, groupID string) (*db.SCIMGroup, error) {
+	if _, err := uuid.Parse(groupID); err != nil {
+		return nil, ErrSCIMNotFound
+	}
+	g, err := scanSCIMGroup(s.PG.QueryRow(scimGroupSelect+" AND g.id = $2", orgID, groupID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSCIMNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.Members, err = groupMembers(s.PG, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// CreateGroup provisions a new escalation group in the org with the given members
+func (s *SCIMService) CreateGroup(orgID string, in db.SCIMGroup) (*db.SCIMGroup, error) {
+	if strings.TrimSpace(in.DisplayName) == "" {
+		return nil, fmt.Errorf("%w: displayName is required", ErrSCIMInvalidValue)
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var existing int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM groups WHERE organization_id = $1 AND is_active = TRUE AND lower(name) = lower($2)
+	`, orgID, in.DisplayName).Scan(&existing); err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrSCIMConflict
+	}
+
+	groupID := uuid.New().String()
+	now := time.Now()
+	_, err = tx.Exec(`
+		INSERT INTO groups (id, name, description, type, visibility, is_active, created_at, updated_at, escalation_timeout, escalation_method, organization_id)
+		VALUES ($1, $2, 'Provisioned via SCIM', $3, $4, TRUE, $5, $5, 300, $6, $7)
+	`, groupID, in.DisplayName, db.GroupTypeEscalation, db.GroupVisibilityPrivate, now, db.EscalationMethodParallel, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+	if err := setSCIMExternalID(tx, orgID, "group", groupID, in.ExternalID); err != nil {
+		return nil, err
+	}
+
+	for _, m := range in.Members {
+		if err := s.addGroupMember(tx, orgID, groupID, m.Value); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(orgID, groupID)
+}
+
+// ReplaceGroup applies a full PUT: displayName and the complete member list
+func (s *SCIMService) ReplaceGroup(orgID, groupID string, in db.SCIMGroup) (*db.SCIMGroup, error) {
+	current, err := s.GetGroup(orgID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if in.DisplayName != "" && in.DisplayName != current.DisplayName {
+		if _, err := tx.Exec(`UPDATE groups SET name = $2, updated_at = NOW() WHERE id = $1`, groupID, in.DisplayName); err != nil {
+			return nil, err
+		}
+	}
+	if err := setSCIMExternalID(tx, orgID, "group", groupID, in.ExternalID); err != nil {
+		return nil, err
+	}
+	if err := s.replaceGroupMembers(tx, orgID, groupID, in.Members); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(orgID, groupID)
+}
+
+// replaceGroupMembers makes members the group's complete member list
+func (s *SCIMService) replaceGroupMembers(tx *sql.Tx, orgID, groupID string, members []db.SCIMMultiValued) error {
+	current, err := groupMembers(tx, groupID)
+	if err != nil {
+		return err
+	}
+
+	desired := map[string]bool{}
+	for _, m := range members {
+		desired[m.Value] = true
+	}
+	for _, m := range current {
+		if !desired[m.Value] {
+			if err := s.GroupService.RemoveGroupMemberTx(tx, groupID, m.Value); err != nil {
+				return err
+			}
+		}
+		delete(desired, m.Value)
+	}
+	for userID := range desired {
+		if err := s.addGroupMember(tx, orgID, groupID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var scimMemberPathFilter = regexp.MustCompile(`(?i)^members\[value eq "([^"]+)"\]$`)
+
+// PatchGroup applies SCIM PatchOp operations for displayName and members add/remove/replace
+func (s *SCIMService) PatchGroup(orgID, groupID string, ops []db.SCIMPatchOperation) (*db.SCIMGroup, error) {
+	if _, err := s.GetGroup(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, op := range ops {
+		opName := strings.ToLower(op.Op)
+		path := strings.ToLower(op.Path)
+
+		switch {
+		case path == "members" && opName == "add":
+			for _, id := range scimMemberValues(op.Value) {
+				if err := s.addGroupMember(tx, orgID, groupID, id); err != nil {
+					return nil, err
+				}
+			}
+		case path == "members" && opName == "remove":
+			ids := scimMemberValues(op.Value)
+			if op.Value == nil {
+				// Remove all members
+				members, err := groupMembers(tx, groupID)
+				if err != nil {
+					return nil, err
+				}
+				for _, m := range members {
+					ids = append(ids, m.Value)
+				}
+			}
+			for _, id := range ids {
+				if err := s.GroupService.RemoveGroupMemberTx(tx, groupID, id); err != nil {
+					return nil, err
+				}
+			}
+		case path == "members" && opName == "replace":
+			if err := s.replaceGroupMembers(tx, orgID, groupID, scimMembersFromIDs(scimMemberValues(op.Value))); err != nil {
+				return nil, err
+			}
+		case scimMemberPathFilter.MatchString(op.Path) && opName == "remove":
+			id := scimMemberPathFilter.FindStringSubmatch(op.Path)[1]
+			if err := s.GroupService.RemoveGroupMemberTx(tx, groupID, id); err != nil {
+				return nil, err
+			}
+		case opName == "replace" || opName == "add":
+			attrs := map[string]interface{}{}
+			if op.Path != "" {
+				attrs[op.Path] = op.Value
+			} else if m, ok := op.Value.(map[string]interface{}); ok {
+				attrs = m
+			}
+			for attr, value := range attrs {
+				switch strings.ToLower(attr) {
+				case "displayname":
+					if name, ok := value.(string); ok && name != "" {
+						if _, err := tx.Exec(`UPDATE groups SET name = $2, updated_at = NOW() WHERE id = $1`, groupID, name); err != nil {
+							return nil, err
+						}
+					}
+				case "externalid":
+					if ext, ok := value.(string); ok {
+						if err := setSCIMExternalID(tx, orgID, "group", groupID, ext); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(orgID, groupID)
+}
+
+// DeleteGroup soft-deletes the group and removes all of its memberships
+func (s *SCIMService) DeleteGroup(orgID, groupID string) error {
+	if _, err := s.GetGroup(orgID, groupID); err != nil {
+		return err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE groups SET is_active = false, updated_at = NOW() WHERE id = $1`, groupID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM memberships WHERE resource_type = 'group' AND resource_id = $1`, groupID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM scim_external_ids WHERE organization_id = $1 AND resource_type = 'group' AND resource_id = $2`, orgID, groupID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func groupMembers(q rowsQueryer, groupID string) ([]db.SCIMMultiValued, error) {
+	rows, err := q.Query(`
+		SELECT u.id, COALESCE(u.name, u.email) FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.resource_type = 'group' AND m.resource_id = $1
+		ORDER BY m.created_at ASC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []db.SCIMMultiValued{}
+	for rows.Next() {
+		var m db.SCIMMultiValued
+		if err := rows.Scan(&m.Value, &m.Display); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// addGroupMember adds an org user to the group; unknown users are rejected
+func (s *SCIMService) addGroupMember(tx *sql.Tx, orgID, groupID, userID string) error {
+	notProvisioned := fmt.Errorf("%w: member %s is not provisioned in this organization", ErrSCIMInvalidValue, userID)
+	if _, err := uuid.Parse(userID); err != nil {
+		return notProvisioned
+	}
+	var inOrg, inGroup bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM memberships WHERE user_id = $1 AND resource_type = 'org' AND resource_id = $2),
+		       EXISTS (SELECT 1 FROM memberships WHERE user_id = $1 AND resource_type = 'group' AND resource_id = $3)
+	`, userID, orgID, groupID).Scan(&inOrg, &inGroup); err != nil {
+		return err
+	}
+	if !inOrg {
+		return notProvisioned
+	}
+	if inGroup {
+		return nil
+	}
+	_, err := s.GroupService.AddGroupMemberTx(tx, groupID, db.AddGroupMemberRequest{UserID: userID}, "")
+	return err
+}
+
+// HELPERS
+
+type scimRowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSCIMUser(row scimRowScanner) (db.SCIMUser, error) {
+	var (
+		u                  db.SCIMUser
+		name, email, phone string
+		team               string
+		active             bool
+		created, updated   time.Time
+	)
+	if err := row.Scan(&u.ID, &name, &email, &phone, &team, &active, &created, &updated, &u.ExternalID); err != nil {
+		return u, err
+	}
+	decryptColumns(&phone)
+	u.Schemas = []string{db.SCIMSchemaUser}
+	u.UserName = email
+	u.DisplayName = name
+	u.Name = &db.SCIMName{Formatted: name}
+	u.Active = &active
+	u.Emails = []db.SCIMMultiValued{{Value: email, Type: "work", Primary: true}}
+	if phone != "" {
+		u.PhoneNumbers = []db.SCIMMultiValued{{Value: phone, Type: "work"}}
+	}
+	u.Meta = &db.SCIMMeta{ResourceType: "User", Created: &created, LastModified: &updated, Location: "/scim/v2/Users/" + u.ID}
+	return u, nil
+}
+
+func scanSCIMGroup(row scimRowScanner) (db.SCIMGroup, error) {
+	var (
+		g                db.SCIMGroup
+		created, updated time.Time
+	)
+	if err := row.Scan(&g.ID, &g.DisplayName, &created, &updated, &g.ExternalID); err != nil {
+		return g, err
+	}
+	g.Schemas = []string{db.SCIMSchemaGroup}
+	g.Meta = &db.SCIMMeta{ResourceType: "Group", Created: &created, LastModified: &updated, Location: "/scim/v2/Groups/" + g.ID}
+	return g, nil
+}
+
+// scimExecer is satisfied by both *sql.DB and *sql.Tx
+type scimExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func setSCIMExternalID(ex scimExecer, orgID, resourceType, resourceID, externalID string) error {
+	if externalID == "" {
+		return nil
+	}
+	_, err := ex.Exec(`
+		INSERT INTO scim_external_ids (organization_id, resource_type, resource_id, external_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, resource_type, resource_id) DO UPDATE SET external_id = EXCLUDED.external_id
+	`, orgID, resourceType, resourceID, externalID)
+	if err != nil {
+		return fmt.Errorf("failed to store externalId: %w", err)
+	}
+	return nil
+}
+
+// scimUserFilterSQL translates a supported SCIM filter into a WHERE clause
+func scimUserFilterSQL(filter string, args []interface{}) (string, []interface{}, error) {
+	if filter == "" {
+		return "", args, nil
+	}
+	attr, value, err := parseSCIMFilter(filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args = append(args, value)
+	placeholder := "$" + strconv.Itoa(len(args))
+	switch strings.ToLower(attr) {
+	case "username", "emails.value", `emails[type eq "work"].value`:
+		return " WHERE lower(u.email) = lower(" + placeholder + ")", args, nil
+	case "externalid":
+		return " WHERE x.external_id = " + placeholder, args, nil
+	case "id":
+		if _, err := uuid.Parse(value); err != nil {
+			return " WHERE FALSE AND " + placeholder + "::text IS NOT NULL", args, nil
+		}
+		return " WHERE u.id = " + placeholder + "::uuid", args, nil
+	}
+	return "", nil, ErrSCIMInvalidFilter
+}
+
+var scimEqFilter = regexp.MustCompile(`(?i)^\s*([a-z0-9_.]+(?:\[[^\]]+\])?(?:\.[a-z0-9_]+)?)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseSCIMFilter parses the `attr eq "value"` filters IdPs send during provisioning.
+// Other operators are rejected with ErrSCIMInvalidFilter.
+func parseSCIMFilter(filter string) (string, string, error) {
+	m := scimEqFilter.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", ErrSCIMInvalidFilter
+	}
+	value := strings.ReplaceAll(m[2], `\"`, `"`)
+	return strings.TrimSpace(m[1]), value, nil
+}
+
+func scimPrimaryEmail(u db.SCIMUser) string {
+	for _, e := range u.Emails {
+		if e.Primary && e.Value != "" {
+			return strings.TrimSpace(e.Value)
+		}
+	}
+	if strings.Contains(u.UserName, "@") {
+		return strings.TrimSpace(u.UserName)
+	}
+	if len(u.Emails) > 0 {
+		return strings.TrimSpace(u.Emails[0].Value)
+	}
+	return strings.TrimSpace(u.UserName)
+}
+
+func scimPrimaryPhone(u db.SCIMUser) string {
+	for _, p := range u.PhoneNumbers {
+		if p.Primary {
+			return p.Value
+		}
+	}
+	if len(u.PhoneNumbers) > 0 {
+		return u.PhoneNumbers[0].Value
+	}
+	return ""
+}
+
+func scimDisplayName(u db.SCIMUser, email string) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil {
+		if u.Name.Formatted != "" {
+			return u.Name.Formatted
+		}
+		if full := strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName); full != "" {
+			return full
+		}
+	}
+	return strings.Split(email, "@")[0]
+}
+
+func scimProviderID(u db.SCIMUser, fallback string) string {
+	if u.ExternalID != "" {
+		return u.ExternalID
+	}
+	return fallback
+}
+
+// scimBool accepts JSON booleans and the "True"/"False" strings Azure AD sends
+func scimBool(v interface{}) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		parsed, err := strconv.ParseBool(strings.ToLower(b))
+		return parsed, err == nil
+	}
+	return false, false
+}
+
+func scimMemberValues(v interface{}) []string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	ids := make([]string, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			if id, ok := m["value"].(string); ok && id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func scimMembersFromIDs(ids []string) []db.SCIMMultiValued {
+	members := make([]db.SCIMMultiValued, len(ids))
+	for i, id := range ids {
+		members[i] = db.SCIMMultiValued{Value: id}
+	}
+	return members
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    string
+		wantAttr  string
+		wantValue string
+		wantErr   bool
+	}{
+		{name: "okta userName", filter: `userName eq "alice@example.com"`, wantAttr: "userName", wantValue: "alice@example.com"},
+		{name: "case-insensitive operator", filter: `externalId EQ "00u1abc"`, wantAttr: "externalId", wantValue: "00u1abc"},
+		{name: "azure displayName with spaces", filter: `displayName eq "SRE On-Call"`, wantAttr: "displayName", wantValue: "SRE On-Call"},
+		{name: "escaped quote", filter: `displayName eq "Team \"A\""`, wantAttr: "displayName", wantValue: `Team "A"`},
+		{name: "unsupported operator", filter: `userName sw "alice"`, wantErr: true},
+		{name: "compound filter", filter: `userName eq "a" and active eq "true"`, wantErr: true},
+		{name: "empty", filter: ``, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr, value, err := parseSCIMFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSCIMFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if attr != tt.wantAttr || value != tt.wantValue {
+				t.Errorf("parseSCIMFilter() = (%q, %q), want (%q, %q)", attr, value, tt.wantAttr, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestSCIMBool(t *testing.T) {
+	tests := []struct {
+		in     interface{}
+		want   bool
+		wantOK bool
+	}{
+		{true, true, true},
+		{false, false, true},
+		{"False", false, true}, // Azure AD sends strings
+		{"True", true, true},
+		{"maybe", false, false},
+		{1, false, false},
+	}
+	for _, tt := range tests {
+		got, ok := scimBool(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("scimBool(%v) = (%v, %v), want (%v, %v)", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSCIMPrimaryEmailAndDisplayName(t *testing.T) {
+	u := db.SCIMUser{
+		UserName: "alice",
+		Emails: []db.SCIMMultiValued{
+			{Value: "alice.personal@example.com"},
+			{Value: "alice@example.com", Primary: true},
+		},
+		Name: &db.SCIMName{GivenName: "Alice", FamilyName: "Nguyen"},
+	}
+	if got := scimPrimaryEmail(u); got != "alice@example.com" {
+		t.Errorf("scimPrimaryEmail() = %q, want primary email", got)
+	}
+	if got := scimDisplayName(u, "alice@example.com"); got != "Alice Nguyen" {
+		t.Errorf("scimDisplayName() = %q, want %q", got, "Alice Nguyen")
+	}
+
+	bare := db.SCIMUser{UserName: "bob@example.com"}
+	if got := scimPrimaryEmail(bare); got != "bob@example.com" {
+		t.Errorf("scimPrimaryEmail() = %q, want userName fallback", got)
+	}
+	if got := scimDisplayName(bare, "bob@example.com"); got != "bob" {
+		t.Errorf("scimDisplayName() = %q, want local-part fallback", got)
+	}
+}
+
+func TestPatchUserOnlyTouchesProfilesOwnedByTheOrg(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	userID := "6f1c2a9e-3b7d-4c55-9a0e-1d2f3b4c5d6e"
+	userRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "email", "phone", "team", "is_active", "created_at", "updated_at", "external_id"}).
+			AddRow(userID, "Alice", "alice@example.com", "", "SRE", true, time.Now(), time.Now(), "")
+	}
+	mock.ExpectQuery(`FROM users u`).WithArgs("org-1", userID).WillReturnRows(userRow())
+	mock.ExpectQuery(`SELECT g.id, g.name FROM memberships`).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET name = \$3, updated_at = NOW\(\) WHERE id = \$1 AND NOT EXISTS \(\s+SELECT 1 FROM memberships WHERE user_id = \$1 AND resource_type = 'org' AND resource_id <> \$2`).
+		WithArgs(userID, "org-1", "Mallory").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM users u`).WithArgs("org-1", userID).WillReturnRows(userRow())
+	mock.ExpectQuery(`SELECT g.id, g.name FROM memberships`).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	s := NewSCIMService(pg, nil)
+	user, err := s.PatchUser("org-1", userID, []db.SCIMPatchOperation{{Op: "replace", Path: "displayName", Value: "Mallory"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.DisplayName != "Alice" {
+		t.Errorf("a user shared with another org kept %q, got %q", "Alice", user.DisplayName)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuthenticateTokenRecordsUseInline(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`UPDATE scim_tokens SET last_used_at = NOW\(\)`).WithArgs(hashInvitationToken("scim_good")).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}).AddRow("org-1"))
+	mock.ExpectQuery(`UPDATE scim_tokens SET last_used_at = NOW\(\)`).WithArgs(hashInvitationToken("scim_revoked")).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id"}))
+
+	s := NewSCIMService(pg, nil)
+	if orgID, err := s.AuthenticateToken("scim_good"); err != nil || orgID != "org-1" {
+		t.Errorf("AuthenticateToken() = %q, %v", orgID, err)
+	}
+	if _, err := s.AuthenticateToken("scim_revoked"); err != ErrSCIMUnauthorized {
+		t.Errorf("expected ErrSCIMUnauthorized, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -3,14 +3,18 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/vanchonlee/slar/db"
 	"github.com/vanchonlee/slar/internal/config"
 )
 
+var ErrServiceNotFound = errors.New("service not found")
+
 type ServiceService struct {
 	PG *sql.DB
 }
@@ -19,6 +23,20 @@ func NewServiceService(pg *sql.DB) *ServiceService {
 	return &ServiceService{PG: pg}
 }
 
+// GetServiceOrganizationID returns the organization a service belongs to,
+// or "" for a service created before organizations existed
+func (s *ServiceService) GetServiceOrganizationID(serviceID string) (string, error) {
+	var orgID sql.NullString
+	err := s.PG.QueryRow(`SELECT organization_id FROM services WHERE id = $1`, serviceID).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return "", ErrServiceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get service organization: %w", err)
+	}
+	return orgID.String, nil
+}
+
 // CreateService creates a new service within a group
 func (s *ServiceService) CreateService(groupID string, req db.CreateServiceRequest, createdBy string) (db.Service, error) {
 	service := db.Service{
@@ -33,6 +51,9 @@ func (s *ServiceService) CreateService(groupID string, req db.CreateServiceReque
 		CreatedBy:      createdBy,
 		OrganizationID: req.OrganizationID,
 		ProjectID:      req.ProjectID,
+
+		RevenuePerMinute:   positiveOrNil(req.RevenuePerMinute),
+		EngineerHourlyCost: positiveOrNil(req.EngineerHourlyCost),
 	}
 
 	// Set default integration and notification settings
@@ -56,26 +77,26 @@ func (s *ServiceService) CreateService(groupID string, req db.CreateServiceReque
 	integrationsJSON, _ := json.Marshal(service.Integrations)
 	notificationJSON, _ := json.Marshal(service.NotificationSettings)
 
-	// Insert service with organization_id and project_id
-	_, err := s.PG.Exec(`
+	// Insert service with organization_id and project_id. Without an explicit
+	// escalation policy the service inherits the group's default.
+	err := s.PG.QueryRow(`
 		INSERT INTO services (id, group_id, name, description, routing_key, escalation_policy_id,
 						  is_active, created_at, updated_at, created_by, integrations, notification_settings,
-						  organization_id, project_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+						  organization_id, project_id, revenue_per_minute, engineer_hourly_cost)
+		VALUES ($1, $2, $3, $4, $5,
+		        COALESCE($6::uuid, (SELECT default_escalation_policy_id FROM groups WHERE id = $2)),
+		        $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING COALESCE(escalation_policy_id::text, '')
 	`, service.ID, service.GroupID, service.Name, service.Description, service.RoutingKey,
 		req.EscalationPolicyID, service.IsActive, service.CreatedAt, service.UpdatedAt,
 		service.CreatedBy, integrationsJSON, notificationJSON,
-		nullIfEmptyStr(service.OrganizationID), nullIfEmptyStr(service.ProjectID))
+		nullIfEmptyStr(service.OrganizationID), nullIfEmptyStr(service.ProjectID),
+		service.RevenuePerMinute, service.EngineerHourlyCost).Scan(&service.EscalationPolicyID)
 
 	if err != nil {
 		return service, fmt.Errorf("failed to create service: %w", err)
 	}
 
-	// Set escalation policy ID if provided
-	if req.EscalationPolicyID != nil {
-		service.EscalationPolicyID = *req.EscalationPolicyID
-	}
-
 	// Populate computed webhook URLs
 	s.populateWebhookURLs(&service)
 
@@ -90,6 +111,14 @@ func nullIfEmptyStr(s string) interface{} {
 	return s
 }
 
+// positiveOrNil treats unset and zero cost parameters alike (stored as NULL)
+func positiveOrNil(v *float64) *float64 {
+	if v == nil || *v <= 0 {
+		return nil
+	}
+	return v
+}
+
 // GetService returns a specific service by ID
 func (s *ServiceService) GetService(serviceID string) (db.Service, error) {
 	var service db.Service
@@ -101,7 +130,7 @@ func (s *ServiceService) GetService(serviceID string) (db.Service, error) {
 		       s.is_active, s.created_at, s.updated_at, COALESCE(s.created_by, '') as created_by,
 		       COALESCE(s.integrations, '{}') as integrations,
 		       COALESCE(s.notification_settings, '{}') as notification_settings,
-		       g.name as group_name
+		       g.name as group_name, s.revenue_per_minute, s.engineer_hourly_cost
 		FROM services s
 		LEFT JOIN groups g ON s.group_id = g.id
 		WHERE s.id = $1
@@ -110,6 +139,7 @@ func (s *ServiceService) GetService(serviceID string) (db.Service, error) {
 		&service.RoutingKey, &escalationPolicyID, &service.IsActive,
 		&service.CreatedAt, &service.UpdatedAt, &service.CreatedBy,
 		&integrationsJSON, &notificationJSON, &service.GroupName,
+		&service.RevenuePerMinute, &service.EngineerHourlyCost,
 	)
 
 	if err != nil {
@@ -209,6 +239,7 @@ func (s *ServiceService) UpdateService(serviceID string, req db.UpdateServiceReq
 	if req.Description != nil {
 		service.Description = *req.Description
 	}
+	previousRoutingKey := service.RoutingKey
 	if req.RoutingKey != nil {
 		service.RoutingKey = *req.RoutingKey
 	}
@@ -224,6 +255,12 @@ func (s *ServiceService) UpdateService(serviceID string, req db.UpdateServiceReq
 	if req.NotificationSettings != nil {
 		service.NotificationSettings = req.NotificationSettings
 	}
+	if req.RevenuePerMinute != nil {
+		service.RevenuePerMinute = positiveOrNil(req.RevenuePerMinute)
+	}
+	if req.EngineerHourlyCost != nil {
+		service.EngineerHourlyCost = positiveOrNil(req.EngineerHourlyCost)
+	}
 
 	service.UpdatedAt = time.Now()
 
@@ -235,50 +272,83 @@ func (s *ServiceService) UpdateService(serviceID string, req db.UpdateServiceReq
 	_, err = s.PG.Exec(`
 		UPDATE services 
 		SET name = $2, description = $3, routing_key = $4, escalation_policy_id = $5,
-		    is_active = $6, updated_at = $7, integrations = $8, notification_settings = $9
+		    is_active = $6, updated_at = $7, integrations = $8, notification_settings = $9,
+		    revenue_per_minute = $10, engineer_hourly_cost = $11
 		WHERE id = $1
 	`, serviceID, service.Name, service.Description, service.RoutingKey,
 		service.EscalationPolicyID, service.IsActive, service.UpdatedAt,
-		integrationsJSON, notificationJSON)
+		integrationsJSON, notificationJSON, service.RevenuePerMinute, service.EngineerHourlyCost)
 
 	if err != nil {
 		return service, fmt.Errorf("failed to update service: %w", err)
 	}
 
+	// Editing the key directly is a hard cutover; RotateRoutingKey keeps the
+	// old key working for a grace period
+	if service.RoutingKey != previousRoutingKey {
+		if _, err := s.PG.Exec(`
+			UPDATE service_routing_keys SET revoked_at = NOW()
+			WHERE service_id = $1 AND routing_key = $2 AND revoked_at IS NULL
+		`, serviceID, previousRoutingKey); err != nil {
+			return service, fmt.Errorf("failed to revoke previous routing key: %w", err)
+		}
+	}
+
 	// Populate computed webhook URLs
 	s.populateWebhookURLs(&service)
 
 	return service, nil
 }
 
-// DeleteService soft deletes a service
-func (s *ServiceService) DeleteService(serviceID string) error {
-	// Soft delete service and its integrations
-	result, err := s.PG.Exec(`
-		WITH deleted_service AS (
-			UPDATE services SET is_active = false, updated_at = $1 WHERE id = $2 RETURNING id
-		)
-		UPDATE service_integrations SET is_active = false, updated_at = $1 
-		WHERE service_id = $2 AND is_active = true
-	`, time.Now(), serviceID)
-
+// DeleteService soft deletes a service and moves it to the trash, from
+// where it can be restored with its integration mappings
+func (s *ServiceService) DeleteService(serviceID, deletedBy string) error {
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var snapshot serviceTrashSnapshot
+	now := time.Now()
+	err = tx.QueryRow(`
+		UPDATE services s SET is_active = false, updated_at = $1
+		FROM (SELECT id, is_active FROM services WHERE id = $2 FOR UPDATE) old
+		WHERE s.id = old.id
+		RETURNING old.is_active
+	`, now, serviceID).Scan(&snapshot.WasActive)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("service not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete service: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// Soft delete its integrations, remembering which ones to bring back
+	err = tx.QueryRow(`
+		WITH deactivated AS (
+			UPDATE service_integrations SET is_active = false, updated_at = $1
+			WHERE service_id = $2 AND is_active = true
+			RETURNING id
+		)
+		SELECT COALESCE(array_agg(id::text), '{}') FROM deactivated
+	`, now, serviceID).Scan(pq.Array(&snapshot.ServiceIntegrationIDs))
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to delete service integrations: %w", err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("service not found")
+	if err := moveToTrash(tx, db.TrashResourceService, serviceID, deletedBy, snapshot); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
 	return nil
 }
 
-// GetServiceByRoutingKey returns a service by its routing key
+// GetServiceByRoutingKey returns the service an event's routing key routes
+// to: its primary key or any other key that is still active. The use is
+// counted against the key.
 func (s *ServiceService) GetServiceByRoutingKey(routingKey string) (db.Service, error) {
 	var service db.Service
 	var integrationsJSON, notificationJSON []byte
@@ -292,7 +362,14 @@ func (s *ServiceService) GetServiceByRoutingKey(routingKey string) (db.Service,
 		       g.name as group_name
 		FROM services s
 		LEFT JOIN groups g ON s.group_id = g.id
-		WHERE s.routing_key = $1 AND s.is_active = true
+		WHERE s.is_active = true AND (
+			s.routing_key = $1 OR s.id IN (
+				SELECT service_id FROM service_routing_keys
+				WHERE routing_key = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+			)
+		)
+		ORDER BY s.routing_key = $1 DESC
+		LIMIT 1
 	`, routingKey).Scan(
 		&service.ID, &service.GroupID, &service.Name, &service.Description,
 		&service.RoutingKey, &escalationPolicyID, &service.IsActive,
@@ -320,6 +397,8 @@ func (s *ServiceService) GetServiceByRoutingKey(routingKey string) (db.Service,
 		service.EscalationPolicyID = escalationPolicyID.String
 	}
 
+	s.recordRoutingKeyUse(service.ID, routingKey)
+
 	// Populate computed webhook URLs
 	s.populateWebhookURLs(&service)
 
@@ -407,8 +486,9 @@ func (s *ServiceService) ListServices(filters map[string]interface{}) ([]db.Serv
 		argIndex += 2
 	}
 
+	// Group filter includes the group's sub-teams
 	if groupID, ok := filters["group_id"].(string); ok && groupID != "" {
-		query += fmt.Sprintf(" AND s.group_id = $%d", argIndex)
+		query += fmt.Sprintf(" AND s.group_id IN (SELECT group_id FROM group_subtree($%d))", argIndex)
 		args = append(args, groupID)
 		argIndex++
 	}
@@ -552,10 +632,7 @@ func (s *ServiceService) ListAllServices(isActive *bool) ([]db.Service, error) {
 
 // populateWebhookURLs computes and sets the webhook URLs for a service
 func (s *ServiceService) populateWebhookURLs(service *db.Service) {
-	baseURL := config.App.WebhookAPIBaseURL
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
-	}
+	baseURL := config.WebhookBaseURL()
 
 	service.GenericWebhookURL = fmt.Sprintf("%s/webhook/generic/%s", baseURL, service.RoutingKey)
 	service.PrometheusWebhookURL = fmt.Sprintf("%s/webhook/prometheus/%s", baseURL, service.RoutingKey)
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrAlertMuteNotFound = errors.New("alert mute not found")
+	ErrInvalidAlertMute  = errors.New("invalid alert mute")
+)
+
+const alertMuteColumns = `
+	id, service_id, alert_names, match_labels, COALESCE(reason, ''), expires_at, muted_count, last_muted_at,
+	COALESCE(created_by::text, ''), created_at
+`
+
+func scanAlertMute(row interface{ Scan(...interface{}) error }) (db.ServiceAlertMute, error) {
+	var m db.ServiceAlertMute
+	var alertNames pq.StringArray
+	var matchLabels []byte
+	var lastMutedAt sql.NullTime
+	err := row.Scan(&m.ID, &m.ServiceID, &alertNames, &matchLabels, &m.Reason, &m.ExpiresAt, &m.MutedCount,
+		&lastMutedAt, &m.CreatedBy, &m.CreatedAt)
+	if err != nil {
+		return m, err
+	}
+	m.AlertNames = []string(alertNames)
+	if m.AlertNames == nil {
+		m.AlertNames = []string{}
+	}
+	m.MatchLabels = map[string]string{}
+	if len(matchLabels) > 0 {
+		json.Unmarshal(matchLabels, &m.MatchLabels)
+	}
+	if lastMutedAt.Valid {
+		m.LastMutedAt = &lastMutedAt.Time
+	}
+	return m, nil
+}
+
+// ListAlertMutes returns the mutes of one of the organization's services,
+// newest first. Expired mutes are left out unless includeExpired is set.
+func (s *ServiceService) ListAlertMutes(orgID, serviceID string, includeExpired bool) ([]db.ServiceAlertMute, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+alertMuteColumns+`
+		FROM service_alert_mutes
+		WHERE service_id = $1 AND ($2 OR expires_at > NOW())
+		AND service_id IN (SELECT id FROM services WHERE organization_id = $3)
+		ORDER BY created_at DESC
+	`, serviceID, includeExpired, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert mutes: %w", err)
+	}
+	defer rows.Close()
+
+	mutes := []db.ServiceAlertMute{}
+	for rows.Next() {
+		m, err := scanAlertMute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert mute: %w", err)
+		}
+		mutes = append(mutes, m)
+	}
+	return mutes, rows.Err()
+}
+
+// alertMuteExpiry resolves when a requested mute ends, as of now
+func alertMuteExpiry(req db.CreateServiceAlertMuteRequest, now time.Time) (time.Time, error) {
+	if (req.ExpiresAt == nil) == (req.DurationMinutes == 0) {
+		return time.Time{}, fmt.Errorf("%w: set either expires_at or duration_minutes", ErrInvalidAlertMute)
+	}
+	expiresAt := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) {
+		return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAlertMute)
+	}
+	if expiresAt.Sub(now) > db.AlertMuteMaxDuration {
+		return time.Time{}, fmt.Errorf("%w: mutes can last at most %d days", ErrInvalidAlertMute,
+			int(db.AlertMuteMaxDuration.Hours()/24))
+	}
+	return expiresAt, nil
+}
+
+// CreateAlertMute mutes the alerts matching the request on one of the
+// organization's services until the mute expires
+func (s *ServiceService) CreateAlertMute(orgID, serviceID string, req db.CreateServiceAlertMuteRequest, createdBy string) (*db.ServiceAlertMute, error) {
+	alertNames := []string{}
+	for _, name := range req.AlertNames {
+		if name = strings.TrimSpace(name); name != "" && !containsString(alertNames, name) {
+			alertNames = append(alertNames, name)
+		}
+	}
+	for key := range req.MatchLabels {
+		if strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%w: label names must not be empty", ErrInvalidAlertMute)
+		}
+	}
+	if len(alertNames) == 0 && len(req.MatchLabels) == 0 {
+		return nil, fmt.Errorf("%w: set alert_names or match_labels; use a maintenance window to silence the whole service", ErrInvalidAlertMute)
+	}
+	expiresAt, err := alertMuteExpiry(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := scanAlertMute(s.PG.QueryRow(`
+		INSERT INTO service_alert_mutes (service_id, alert_names, match_labels, reason, expires_at, created_by)
+		SELECT id, $2, $3::jsonb, $4, $5, $6 FROM services WHERE id = $1 AND organization_id = $7
+		RETURNING `+alertMuteColumns,
+		serviceID, pq.Array(alertNames), marshalMatchLabels(req.MatchLabels), nullIfEmpty(strings.TrimSpace(req.Reason)),
+		expiresAt, nullIfEmpty(createdBy), orgID))
+	if err == sql.ErrNoRows {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert mute: %w", err)
+	}
+	return &m, nil
+}
+
+// DeleteAlertMute lifts a mute of one of the organization's services before
+// it expires
+func (s *ServiceService) DeleteAlertMute(orgID, serviceID, muteID string) error {
+	result, err := s.PG.Exec(`
+		DELETE FROM service_alert_mutes
+		WHERE id = $1 AND service_id = $2
+		AND service_id IN (SELECT id FROM services WHERE organization_id = $3)
+	`, muteID, serviceID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert mute: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAlertMuteNotFound
+	}
+	return nil
+}
+
+// MatchAlertMute returns the service's active mute that silences an alert,
+// counting the alert against it, or nil when the alert should page
+func (s *ServiceService) MatchAlertMute(serviceID, alertName string, labels map[string]string) (*db.ServiceAlertMute, error) {
+	if serviceID == "" {
+		return nil, nil
+	}
+	m, err := scanAlertMute(s.PG.QueryRow(`
+		UPDATE service_alert_mutes
+		SET muted_count = muted_count + 1, last_muted_at = NOW()
+		WHERE id = (
+		        SELECT id FROM service_alert_mutes
+		        WHERE service_id = $1 AND expires_at > NOW()
+		          AND (cardinality(alert_names) = 0 OR $2 = ANY(alert_names))
+		          AND $3::jsonb @> match_labels
+		        ORDER BY created_at ASC
+		        LIMIT 1
+		      )
+		RETURNING `+alertMuteColumns,
+		serviceID, alertName, marshalMatchLabels(labels)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to match alert mutes: %w", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestCreateAlertMuteValidation(t *testing.T) {
+	s := &ServiceService{}
+	past := time.Now().Add(-time.Hour)
+	cases := []struct {
+		name string
+		req  db.CreateServiceAlertMuteRequest
+	}{
+		{"no matcher", db.CreateServiceAlertMuteRequest{AlertNames: []string{" "}, DurationMinutes: 60}},
+		{"no expiry", db.CreateServiceAlertMuteRequest{AlertNames: []string{"DiskFull"}}},
+		{"both expiries", db.CreateServiceAlertMuteRequest{AlertNames: []string{"DiskFull"}, DurationMinutes: 60, ExpiresAt: &past}},
+		{"expired", db.CreateServiceAlertMuteRequest{AlertNames: []string{"DiskFull"}, ExpiresAt: &past}},
+		{"too long", db.CreateServiceAlertMuteRequest{AlertNames: []string{"DiskFull"}, DurationMinutes: 31 * 24 * 60}},
+		{"empty label", db.CreateServiceAlertMuteRequest{MatchLabels: map[string]string{"": "x"}, DurationMinutes: 60}},
+	}
+	for _, tc := range cases {
+		if _, err := s.CreateAlertMute("org-1", "svc-1", tc.req, "alice"); !errors.Is(err, ErrInvalidAlertMute) {
+			t.Errorf("%s: expected ErrInvalidAlertMute, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestMatchAlertMute(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	columns := []string{"id", "service_id", "alert_names", "match_labels", "reason", "expires_at", "muted_count",
+		"last_muted_at", "created_by", "created_at"}
+	expiresAt := time.Now().Add(time.Hour)
+	mock.ExpectQuery(`UPDATE service_alert_mutes`).
+		WithArgs("svc-1", "DiskFull", `{"instance":"db-1"}`).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("mute-1", "svc-1", "{DiskFull}", []byte(`{"instance":"db-1"}`), "known noisy", expiresAt, 3,
+				time.Now(), "alice", time.Now()))
+	mock.ExpectQuery(`UPDATE service_alert_mutes`).
+		WithArgs("svc-1", "HighLatency", `{}`).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	s := NewServiceService(pg)
+	mute, err := s.MatchAlertMute("svc-1", "DiskFull", map[string]string{"instance": "db-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mute == nil || mute.ID != "mute-1" || len(mute.AlertNames) != 1 || mute.MatchLabels["instance"] != "db-1" || mute.MutedCount != 3 {
+		t.Errorf("unexpected mute %+v", mute)
+	}
+
+	if mute, err := s.MatchAlertMute("svc-1", "HighLatency", nil); err != nil || mute != nil {
+		t.Errorf("expected no mute, got %+v, %v", mute, err)
+	}
+	if mute, err := s.MatchAlertMute("", "DiskFull", nil); err != nil || mute != nil {
+		t.Errorf("alerts without a service are never muted, got %+v, %v", mute, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateAlertMuteOtherOrgService(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`INSERT INTO service_alert_mutes`).
+		WithArgs("svc-1", sqlmock.AnyArg(), `{}`, nil, sqlmock.AnyArg(), "alice", "org-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := NewServiceService(pg)
+	req := db.CreateServiceAlertMuteRequest{AlertNames: []string{"DiskFull"}, DurationMinutes: 60}
+	if _, err := s.CreateAlertMute("org-2", "svc-1", req, "alice"); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("expected ErrServiceNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
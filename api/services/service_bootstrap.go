@@ -0,0 +1,336 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var (
+	ErrBootstrapRoutingKeyTaken        = errors.New("routing key is already used by another service")
+	ErrBootstrapMemberNotInGroup       = errors.New("rotation members must belong to the group")
+	ErrBootstrapIntegrationNotFound    = errors.New("integration not found")
+	ErrBootstrapInvalidIntegrationType = errors.New("unsupported integration type for alert routing")
+)
+
+// bootstrapIntegrationTypes are the alert-producing integration types a bootstrap can create
+var bootstrapIntegrationTypes = map[string]bool{
+	"prometheus": true,
+	"datadog":    true,
+	"grafana":    true,
+	"webhook":    true,
+	"aws":        true,
+	"custom":     true,
+}
+
+// BootstrapService creates a service together with its on-call scheduler (weekly
+// rotation), escalation policy and integration in a single transaction. The new
+// policy becomes the group's default when the group does not have one yet.
+func (s *ServiceService) BootstrapService(groupID string, req db.BootstrapServiceRequest, createdBy string) (db.BootstrapServiceResult, error) {
+	var result db.BootstrapServiceResult
+
+	integrationType := req.IntegrationType
+	if integrationType == "" {
+		integrationType = db.BootstrapDefaultIntegrationType
+	}
+	if req.IntegrationID == "" && !bootstrapIntegrationTypes[integrationType] {
+		return result, fmt.Errorf("%w: %s", ErrBootstrapInvalidIntegrationType, integrationType)
+	}
+
+	weeks := req.RotationWeeks
+	if weeks <= 0 {
+		weeks = db.BootstrapDefaultRotationWeeks
+	}
+	if weeks > db.BootstrapMaxRotationWeeks {
+		weeks = db.BootstrapMaxRotationWeeks
+	}
+	start := time.Now().UTC().Truncate(time.Hour).Add(time.Hour)
+	if req.RotationStart != nil {
+		start = req.RotationStart.UTC()
+	}
+	timeout := req.AckTimeoutMinutes
+	if timeout <= 0 {
+		timeout = db.BootstrapDefaultTimeoutMinutes
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var routingKeyTaken bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM services WHERE routing_key = $1)`, req.RoutingKey).Scan(&routingKeyTaken)
+	if err != nil {
+		return result, fmt.Errorf("failed to check routing key: %w", err)
+	}
+	if routingKeyTaken {
+		return result, ErrBootstrapRoutingKeyTaken
+	}
+
+	if err := checkRotationMembers(tx, groupID, req.Members); err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+
+	// 1. Scheduler with a weekly rotation across the members
+	schedulers := &OptimizedSchedulerService{PG: s.PG}
+	schedulerName, err := schedulers.generateUniqueNameOptimized(tx, groupID, bootstrapSlug(req.Name)+"-oncall")
+	if err != nil {
+		return result, fmt.Errorf("failed to generate scheduler name: %w", err)
+	}
+	result.Scheduler = db.Scheduler{
+		Name:           schedulerName,
+		DisplayName:    req.Name + " On-Call",
+		GroupID:        groupID,
+		Description:    "Weekly rotation created by service bootstrap",
+		IsActive:       true,
+		RotationType:   db.ScheduleTypeWeekly,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		CreatedBy:      createdBy,
+		OrganizationID: req.OrganizationID,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO schedulers (name, display_name, group_id, description, is_active, rotation_type, created_at, updated_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, result.Scheduler.Name, result.Scheduler.DisplayName, groupID, result.Scheduler.Description,
+		result.Scheduler.IsActive, result.Scheduler.RotationType, now, now, createdBy).Scan(&result.Scheduler.ID)
+	if err != nil {
+		return result, fmt.Errorf("failed to create scheduler: %w", err)
+	}
+	shifts, err := schedulers.batchInsertShifts(tx, result.Scheduler.ID, groupID, weeklyRotationShifts(req.Members, start, weeks), createdBy)
+	if err != nil {
+		return result, fmt.Errorf("failed to create shifts: %w", err)
+	}
+	result.Scheduler.Shifts = shifts
+
+	// 2. Escalation policy: page the on-call user, then the whole group
+	result.EscalationPolicy = db.EscalationPolicy{
+		ID:                   uuid.New().String(),
+		Name:                 req.Name + " Default",
+		Description:          "Pages " + result.Scheduler.DisplayName + ", then the whole group",
+		IsActive:             true,
+		RepeatMaxTimes:       1,
+		EscalateAfterMinutes: timeout,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		GroupID:              groupID,
+		CreatedBy:            createdBy,
+	}
+	levels := []db.EscalationLevel{
+		{LevelNumber: 1, TargetType: "scheduler", TargetID: result.Scheduler.ID, TimeoutMinutes: timeout},
+		{LevelNumber: 2, TargetType: "group", TargetID: groupID, TimeoutMinutes: timeout},
+	}
+	if err := insertEscalationPolicy(tx, &result.EscalationPolicy, levels); err != nil {
+		return result, err
+	}
+
+	// 3. Service
+	result.Service = db.Service{
+		ID:                 uuid.New().String(),
+		GroupID:            groupID,
+		Name:               req.Name,
+		Description:        req.Description,
+		RoutingKey:         req.RoutingKey,
+		EscalationPolicyID: result.EscalationPolicy.ID,
+		IsActive:           true,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		CreatedBy:          createdBy,
+		OrganizationID:     req.OrganizationID,
+		ProjectID:          req.ProjectID,
+		Integrations:       map[string]interface{}{},
+		NotificationSettings: map[string]interface{}{
+			"email": true,
+			"fcm":   true,
+			"sms":   false,
+		},
+	}
+	integrationsJSON, _ := json.Marshal(result.Service.Integrations)
+	notificationJSON, _ := json.Marshal(result.Service.NotificationSettings)
+	_, err = tx.Exec(`
+		INSERT INTO services (id, group_id, name, description, routing_key, escalation_policy_id,
+						  is_active, created_at, updated_at, created_by, integrations, notification_settings,
+						  organization_id, project_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, result.Service.ID, groupID, result.Service.Name, result.Service.Description, result.Service.RoutingKey,
+		result.Service.EscalationPolicyID, true, now, now, createdBy, integrationsJSON, notificationJSON,
+		nullIfEmptyStr(req.OrganizationID), nullIfEmptyStr(req.ProjectID))
+	if err != nil {
+		return result, fmt.Errorf("failed to create service: %w", err)
+	}
+	s.populateWebhookURLs(&result.Service)
+
+	// 4. Integration, linked to the service
+	if req.IntegrationID != "" {
+		result.Integration, err = lookupBootstrapIntegration(tx, req.IntegrationID)
+	} else {
+		result.Integration, err = insertBootstrapIntegration(tx, req, integrationType, createdBy, now)
+	}
+	if err != nil {
+		return result, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO service_integrations (id, service_id, integration_id, routing_conditions,
+		                                 priority, is_active, created_at, updated_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, uuid.New().String(), result.Service.ID, result.Integration.ID, []byte("{}"), 100, true, now, now, createdBy)
+	if err != nil {
+		return result, fmt.Errorf("failed to link integration: %w", err)
+	}
+	result.WebhookURL = result.Integration.WebhookURL
+
+	// 5. Group default policy, only if none is set
+	res, err := tx.Exec(`
+		UPDATE groups SET default_escalation_policy_id = $1, updated_at = NOW()
+		WHERE id = $2 AND default_escalation_policy_id IS NULL
+	`, result.EscalationPolicy.ID, groupID)
+	if err != nil {
+		return result, fmt.Errorf("failed to set group default escalation policy: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		result.GroupDefaultPolicySet = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Bootstrapped service %s (%s): scheduler=%s shifts=%d policy=%s integration=%s",
+		result.Service.Name, result.Service.ID, result.Scheduler.ID, len(shifts), result.EscalationPolicy.ID, result.Integration.ID)
+	return result, nil
+}
+
+// checkRotationMembers requires every rotation member to be a member of the group
+func checkRotationMembers(tx *sql.Tx, groupID string, members []string) error {
+	rows, err := tx.Query(`
+		SELECT user_id FROM memberships
+		WHERE resource_type = 'group' AND resource_id = $1 AND user_id = ANY($2)
+	`, groupID, pq.Array(members))
+	if err != nil {
+		return fmt.Errorf("failed to check group members: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return fmt.Errorf("failed to scan group member: %w", err)
+		}
+		found[userID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check group members: %w", err)
+	}
+
+	var missing []string
+	for _, userID := range members {
+		if !found[userID] {
+			missing = append(missing, userID)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrBootstrapMemberNotInGroup, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// weeklyRotationShifts hands on-call to the next member every 7 days from start
+func weeklyRotationShifts(members []string, start time.Time, weeks int) []db.CreateShiftRequest {
+	shifts := make([]db.CreateShiftRequest, 0, weeks)
+	for week := 0; week < weeks; week++ {
+		shiftStart := start.AddDate(0, 0, 7*week)
+		shifts = append(shifts, db.CreateShiftRequest{
+			UserID:       members[week%len(members)],
+			ShiftType:    db.ScheduleTypeWeekly,
+			StartTime:    shiftStart,
+			EndTime:      shiftStart.AddDate(0, 0, 7),
+			RotationDays: 7,
+		})
+	}
+	return shifts
+}
+
+func lookupBootstrapIntegration(tx *sql.Tx, integrationID string) (db.Integration, error) {
+	var integration db.Integration
+	var webhookURL sql.NullString
+	err := tx.QueryRow(`
+		SELECT id, name, type, is_active, webhook_url FROM integrations WHERE id = $1
+	`, integrationID).Scan(&integration.ID, &integration.Name, &integration.Type, &integration.IsActive, &webhookURL)
+	if err == sql.ErrNoRows {
+		return integration, ErrBootstrapIntegrationNotFound
+	}
+	if err != nil {
+		return integration, fmt.Errorf("failed to get integration: %w", err)
+	}
+	integration.WebhookURL = webhookURL.String
+	return integration, nil
+}
+
+func insertBootstrapIntegration(tx *sql.Tx, req db.BootstrapServiceRequest, integrationType, createdBy string, now time.Time) (db.Integration, error) {
+	integration := db.Integration{
+		ID:                uuid.New().String(),
+		Name:              req.Name + " " + integrationType,
+		Type:              integrationType,
+		Description:       "Created by service bootstrap",
+		Config:            map[string]interface{}{},
+		IsActive:          true,
+		HeartbeatInterval: 300,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		CreatedBy:         createdBy,
+		OrganizationID:    req.OrganizationID,
+		ProjectID:         req.ProjectID,
+	}
+
+	baseURL := config.WebhookBaseURL()
+	integration.WebhookURL = fmt.Sprintf("%s/webhook/%s/%s", baseURL, integration.Type, integration.ID)
+
+	_, err := tx.Exec(`
+		INSERT INTO integrations (id, name, type, description, config, webhook_secret, webhook_url,
+		                         is_active, heartbeat_interval, created_at, updated_at, created_by,
+		                         organization_id, project_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, integration.ID, integration.Name, integration.Type, integration.Description,
+		[]byte("{}"), "", integration.WebhookURL, integration.IsActive,
+		integration.HeartbeatInterval, now, now, createdBy,
+		integration.OrganizationID, integration.ProjectID)
+	if err != nil {
+		return integration, fmt.Errorf("failed to create integration: %w", err)
+	}
+	return integration, nil
+}
+
+// bootstrapSlug turns a service name into a scheduler name ("Payments API" -> "payments-api")
+func bootstrapSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "service"
+	}
+	return slug
+}
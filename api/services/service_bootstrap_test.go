@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestWeeklyRotationShifts(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	shifts := weeklyRotationShifts([]string{"alice", "bob"}, start, 3)
+
+	if len(shifts) != 3 {
+		t.Fatalf("got %d shifts, want 3", len(shifts))
+	}
+	wantUsers := []string{"alice", "bob", "alice"}
+	for i, shift := range shifts {
+		if shift.UserID != wantUsers[i] {
+			t.Errorf("shift %d user = %s, want %s", i, shift.UserID, wantUsers[i])
+		}
+		if !shift.StartTime.Equal(start.AddDate(0, 0, 7*i)) || !shift.EndTime.Equal(shift.StartTime.AddDate(0, 0, 7)) {
+			t.Errorf("shift %d = %v..%v, want week %d", i, shift.StartTime, shift.EndTime, i)
+		}
+	}
+}
+
+func TestBootstrapSlug(t *testing.T) {
+	tests := map[string]string{
+		"Payments API":     "payments-api",
+		"  Edge / CDN  ":   "edge-cdn",
+		"checkout-v2":      "checkout-v2",
+		"!!!":              "service",
+		"Auth (internal)!": "auth-internal",
+	}
+	for name, want := range tests {
+		if got := bootstrapSlug(name); got != want {
+			t.Errorf("bootstrapSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBootstrapServiceRejectsNonMembers(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM services WHERE routing_key`).WithArgs("payments").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT user_id FROM memberships`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+	mock.ExpectRollback()
+
+	req := db.BootstrapServiceRequest{Name: "Payments", RoutingKey: "payments", Members: []string{"alice", "mallory"}}
+	_, err = NewServiceService(pg).BootstrapService("group-1", req, "alice")
+	if !errors.Is(err, ErrBootstrapMemberNotInGroup) {
+		t.Fatalf("err = %v, want ErrBootstrapMemberNotInGroup", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBootstrapServiceRejectsUnknownIntegrationType(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	req := db.BootstrapServiceRequest{Name: "Payments", RoutingKey: "payments", Members: []string{"alice"}, IntegrationType: "github"}
+	_, err = NewServiceService(pg).BootstrapService("group-1", req, "alice")
+	if !errors.Is(err, ErrBootstrapInvalidIntegrationType) {
+		t.Fatalf("err = %v, want ErrBootstrapInvalidIntegrationType", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
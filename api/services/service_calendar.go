@@ -0,0 +1,216 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+
+const maintenanceWindowColumns = `
+	id, service_id, title, description, start_time, end_time, COALESCE(created_by, ''), created_at
+`
+
+func scanMaintenanceWindow(row interface{ Scan(...interface{}) error }) (db.MaintenanceWindow, error) {
+	var mw db.MaintenanceWindow
+	err := row.Scan(&mw.ID, &mw.ServiceID, &mw.Title, &mw.Description, &mw.StartTime, &mw.EndTime, &mw.CreatedBy, &mw.CreatedAt)
+	return mw, err
+}
+
+// ListMaintenanceWindows returns a service's maintenance windows overlapping [from, to]
+func (s *ServiceService) ListMaintenanceWindows(serviceID string, from, to time.Time) ([]db.MaintenanceWindow, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+maintenanceWindowColumns+`
+		FROM service_maintenance_windows
+		WHERE service_id = $1 AND end_time >= $2 AND start_time <= $3
+		ORDER BY start_time ASC
+	`, serviceID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []db.MaintenanceWindow{}
+	for rows.Next() {
+		mw, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance window: %w", err)
+		}
+		windows = append(windows, mw)
+	}
+	return windows, rows.Err()
+}
+
+// CreateMaintenanceWindow schedules maintenance on one of the organization's
+// services
+func (s *ServiceService) CreateMaintenanceWindow(orgID, serviceID string, req db.CreateMaintenanceWindowRequest, createdBy string) (*db.MaintenanceWindow, error) {
+	mw, err := scanMaintenanceWindow(s.PG.QueryRow(`
+		INSERT INTO service_maintenance_windows (service_id, title, description, start_time, end_time, created_by)
+		SELECT id, $2, $3, $4, $5, $6 FROM services WHERE id = $1 AND organization_id = $7
+		RETURNING `+maintenanceWindowColumns,
+		serviceID, req.Title, req.Description, req.StartTime, req.EndTime, nullIfEmpty(createdBy), orgID))
+	if err == sql.ErrNoRows {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+	return &mw, nil
+}
+
+// DeleteMaintenanceWindow removes a maintenance window from one of the
+// organization's services
+func (s *ServiceService) DeleteMaintenanceWindow(orgID, serviceID, windowID string) error {
+	result, err := s.PG.Exec(`
+		DELETE FROM service_maintenance_windows
+		WHERE id = $1 AND service_id = $2
+		AND service_id IN (SELECT id FROM services WHERE organization_id = $3)
+	`, windowID, serviceID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance window: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	return nil
+}
+
+// ServiceDisplaySettings returns the display settings of the service's
+// organization, or the defaults
+func (s *ServiceService) ServiceDisplaySettings(serviceID string) db.OrgDisplaySettings {
+	var orgID string
+	if err := s.PG.QueryRow(`
+		SELECT COALESCE(organization_id::text, '') FROM services WHERE id = $1
+	`, serviceID).Scan(&orgID); err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: failed to get organization of service %s: %v", serviceID, err)
+	}
+	return orgDisplaySettingsOrDefault(s.PG, orgID)
+}
+
+// DefaultCalendarRange covers whole weeks in the organization's timezone,
+// from the week CalendarDefaultPastDays ago through the week
+// CalendarDefaultFutureDays ahead
+func DefaultCalendarRange(display db.OrgDisplaySettings, now time.Time) (time.Time, time.Time) {
+	from := orgStartOfWeek(display, now.AddDate(0, 0, -db.CalendarDefaultPastDays))
+	to := orgStartOfWeek(display, now.AddDate(0, 0, db.CalendarDefaultFutureDays)).AddDate(0, 0, 7)
+	return from, to
+}
+
+// GetServiceCalendar combines a service's maintenance windows, the on-call
+// shifts that would be paged for it and its incidents over [from, to] into
+// one timeline ordered by start time
+func (s *ServiceService) GetServiceCalendar(serviceID string, from, to time.Time) (*db.ServiceCalendar, error) {
+	calendar := &db.ServiceCalendar{ServiceID: serviceID, From: from, To: to, Events: []db.ServiceCalendarEvent{}}
+
+	windows, err := s.ListMaintenanceWindows(serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, mw := range windows {
+		end := mw.EndTime
+		calendar.Events = append(calendar.Events, db.ServiceCalendarEvent{
+			Type: db.CalendarEventMaintenance, ID: mw.ID, Title: mw.Title, StartTime: mw.StartTime, EndTime: &end,
+		})
+	}
+
+	shifts, err := s.calendarShifts(serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	calendar.Events = append(calendar.Events, shifts...)
+
+	incidents, err := s.calendarIncidents(serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	calendar.Events = append(calendar.Events, incidents...)
+
+	sort.SliceStable(calendar.Events, func(i, j int) bool {
+		return calendar.Events[i].StartTime.Before(calendar.Events[j].StartTime)
+	})
+	return calendar, nil
+}
+
+// calendarShifts returns the service's own shifts plus the shifts of schedulers
+// its escalation policy pages, with any override covering the shift applied
+func (s *ServiceService) calendarShifts(serviceID string, from, to time.Time) ([]db.ServiceCalendarEvent, error) {
+	rows, err := s.PG.Query(`
+		SELECT sh.id, sh.start_time, sh.end_time,
+		       COALESCE(so.new_user_id, sh.user_id)::text, COALESCE(u.name, ''),
+		       COALESCE(sc.display_name, sc.name, ''), so.id IS NOT NULL
+		FROM shifts sh
+		JOIN schedulers sc ON sc.id = sh.scheduler_id
+		LEFT JOIN LATERAL (
+			SELECT o.id, o.new_user_id FROM schedule_overrides o
+			WHERE o.original_schedule_id = sh.id AND o.is_active = true
+			AND o.override_start_time <= sh.start_time AND o.override_end_time >= sh.end_time
+			ORDER BY o.created_at DESC
+			LIMIT 1
+		) so ON true
+		LEFT JOIN users u ON u.id = COALESCE(so.new_user_id, sh.user_id)
+		WHERE sh.is_active = true AND sc.is_active = true
+		AND sh.end_time >= $2 AND sh.start_time <= $3
+		AND (
+			sh.service_id = $1
+			OR sh.scheduler_id IN (
+				SELECT el.target_id FROM escalation_levels el
+				JOIN services sv ON sv.escalation_policy_id = el.policy_id
+				WHERE sv.id = $1 AND el.target_type = 'scheduler'
+			)
+		)
+		ORDER BY sh.start_time ASC
+	`, serviceID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar shifts: %w", err)
+	}
+	defer rows.Close()
+
+	var events []db.ServiceCalendarEvent
+	for rows.Next() {
+		var ev db.ServiceCalendarEvent
+		var end time.Time
+		if err := rows.Scan(&ev.ID, &ev.StartTime, &end, &ev.UserID, &ev.UserName, &ev.SchedulerName, &ev.IsOverride); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar shift: %w", err)
+		}
+		ev.Type = db.CalendarEventOnCallShift
+		ev.EndTime = &end
+		ev.Title = ev.UserName + " on call"
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// calendarIncidents returns the service's incidents open at any point in [from, to]
+func (s *ServiceService) calendarIncidents(serviceID string, from, to time.Time) ([]db.ServiceCalendarEvent, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, title, created_at, resolved_at, status, COALESCE(severity, ''), COALESCE(urgency, '')
+		FROM incidents
+		WHERE service_id = $1 AND created_at <= $3
+		AND (resolved_at IS NULL OR resolved_at >= $2)
+		ORDER BY created_at ASC
+	`, serviceID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []db.ServiceCalendarEvent
+	for rows.Next() {
+		ev := db.ServiceCalendarEvent{Type: db.CalendarEventIncident}
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&ev.ID, &ev.Title, &ev.StartTime, &resolvedAt, &ev.Status, &ev.Severity, &ev.Urgency); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar incident: %w", err)
+		}
+		if resolvedAt.Valid {
+			ev.EndTime = &resolvedAt.Time
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
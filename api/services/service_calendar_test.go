@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestGetServiceCalendarMergesSourcesByStartTime(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	at := func(h int) time.Time { return from.Add(time.Duration(h) * time.Hour) }
+
+	mock.ExpectQuery(`FROM service_maintenance_windows`).
+		WithArgs("svc-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_id", "title", "description", "start_time", "end_time", "created_by", "created_at"}).
+			AddRow("mw-1", "svc-1", "DB upgrade", "", at(30), at(32), "u-1", at(0)))
+	mock.ExpectQuery(`FROM shifts sh`).
+		WithArgs("svc-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start_time", "end_time", "user_id", "user_name", "scheduler_name", "is_override"}).
+			AddRow("sh-1", at(0), at(24), "u-2", "Alice", "Primary", false).
+			AddRow("sh-2", at(24), at(48), "u-3", "Bob", "Primary", true))
+	mock.ExpectQuery(`FROM incidents`).
+		WithArgs("svc-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "created_at", "resolved_at", "status", "severity", "urgency"}).
+			AddRow("inc-1", "High latency", at(10), at(11), "resolved", "SEV2", "high").
+			AddRow("inc-2", "Errors", at(40), nil, "triggered", "", "high"))
+
+	service := &ServiceService{PG: pg}
+	calendar, err := service.GetServiceCalendar("svc-1", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantOrder := []string{"sh-1", "inc-1", "sh-2", "mw-1", "inc-2"}
+	if len(calendar.Events) != len(wantOrder) {
+		t.Fatalf("got %d events, want %d", len(calendar.Events), len(wantOrder))
+	}
+	for i, id := range wantOrder {
+		if calendar.Events[i].ID != id {
+			t.Errorf("event %d = %s, want %s", i, calendar.Events[i].ID, id)
+		}
+	}
+
+	if ev := calendar.Events[2]; ev.Type != db.CalendarEventOnCallShift || !ev.IsOverride || ev.UserName != "Bob" {
+		t.Errorf("unexpected override shift event: %+v", ev)
+	}
+	if ev := calendar.Events[3]; ev.Type != db.CalendarEventMaintenance || ev.EndTime == nil || !ev.EndTime.Equal(at(32)) {
+		t.Errorf("unexpected maintenance event: %+v", ev)
+	}
+	if ev := calendar.Events[4]; ev.Type != db.CalendarEventIncident || ev.EndTime != nil {
+		t.Errorf("open incident should have no end time: %+v", ev)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteMaintenanceWindowNotFound(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectExec(`DELETE FROM service_maintenance_windows`).
+		WithArgs("mw-1", "svc-1", "org-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	service := &ServiceService{PG: pg}
+	if err := service.DeleteMaintenanceWindow("org-1", "svc-1", "mw-1"); err != ErrMaintenanceWindowNotFound {
+		t.Errorf("err = %v, want ErrMaintenanceWindowNotFound", err)
+	}
+}
+
+func TestCreateMaintenanceWindowOtherOrgService(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	start := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	req := db.CreateMaintenanceWindowRequest{Title: "DB upgrade", StartTime: start, EndTime: start.Add(time.Hour)}
+	mock.ExpectQuery(`INSERT INTO service_maintenance_windows`).
+		WithArgs("svc-1", "DB upgrade", "", start, start.Add(time.Hour), "alice", "org-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	service := &ServiceService{PG: pg}
+	if _, err := service.CreateMaintenanceWindow("org-2", "svc-1", req, "alice"); err != ErrServiceNotFound {
+		t.Errorf("err = %v, want ErrServiceNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
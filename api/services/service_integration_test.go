@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestServiceIntegrationEffectiveEscalationPolicy(t *testing.T) {
+	tests := []struct {
+		binding db.ServiceIntegration
+		want    string
+	}{
+		{db.ServiceIntegration{}, "svc-policy"},
+		{db.ServiceIntegration{Environment: "prod"}, "svc-policy"},
+		{db.ServiceIntegration{Environment: "staging"}, ""},
+		{db.ServiceIntegration{Environment: "staging", EscalationPolicyID: "staging-policy"}, "staging-policy"},
+		{db.ServiceIntegration{Environment: "production", EscalationPolicyID: "night-policy"}, "night-policy"},
+	}
+	for _, tt := range tests {
+		if got := tt.binding.EffectiveEscalationPolicy("svc-policy"); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.binding, got, tt.want)
+		}
+	}
+}
+
+func TestValidateServiceIntegrationEnvironment(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := NewIntegrationService(pg)
+
+	si := db.ServiceIntegration{ServiceID: "svc-1", Environment: " Staging "}
+	if err := s.validateServiceIntegrationEnvironment(&si); err != nil || si.Environment != "staging" {
+		t.Fatalf("got %q, %v", si.Environment, err)
+	}
+
+	si.Environment = "staging env"
+	if err := s.validateServiceIntegrationEnvironment(&si); !errors.Is(err, ErrInvalidServiceIntegration) {
+		t.Errorf("expected ErrInvalidServiceIntegration for a bad environment, got %v", err)
+	}
+
+	// The policy must belong to the service's group
+	si.Environment, si.EscalationPolicyID = "staging", "pol-other"
+	mock.ExpectQuery(`FROM escalation_policies ep`).WithArgs("pol-other", "svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	if err := s.validateServiceIntegrationEnvironment(&si); !errors.Is(err, ErrInvalidServiceIntegration) {
+		t.Errorf("expected ErrInvalidServiceIntegration for another group's policy, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidSeverityMapping = errors.New("invalid severity mapping")
+
+// SEVERITY MAPPINGS
+
+// ListSeverityMappings returns a service's severity mappings
+func (s *ServiceService) ListSeverityMappings(serviceID string) ([]db.ServiceSeverityMapping, error) {
+	rows, err := s.PG.Query(`
+		SELECT service_id, severity, COALESCE(priority, ''), COALESCE(urgency, ''), created_at
+		FROM service_severity_mappings
+		WHERE service_id = $1
+		ORDER BY severity ASC
+	`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list severity mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []db.ServiceSeverityMapping{}
+	for rows.Next() {
+		var m db.ServiceSeverityMapping
+		if err := rows.Scan(&m.ServiceID, &m.Severity, &m.Priority, &m.Urgency, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan severity mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+// SetSeverityMappings replaces a service's severity mappings
+func (s *ServiceService) SetSeverityMappings(serviceID string, req db.SetSeverityMappingsRequest) ([]db.ServiceSeverityMapping, error) {
+	seen := map[string]bool{}
+	for i, m := range req.Mappings {
+		severity := strings.ToLower(strings.TrimSpace(m.Severity))
+		if severity == "" {
+			return nil, fmt.Errorf("%w: mapping %d needs a severity", ErrInvalidSeverityMapping, i+1)
+		}
+		if m.Priority == "" && m.Urgency == "" {
+			return nil, fmt.Errorf("%w: mapping %d must set priority or urgency", ErrInvalidSeverityMapping, i+1)
+		}
+		if seen[severity] {
+			return nil, fmt.Errorf("%w: severity %q is mapped more than once", ErrInvalidSeverityMapping, severity)
+		}
+		seen[severity] = true
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM service_severity_mappings WHERE service_id = $1`, serviceID); err != nil {
+		return nil, fmt.Errorf("failed to replace severity mappings: %w", err)
+	}
+	for _, m := range req.Mappings {
+		_, err := tx.Exec(`
+			INSERT INTO service_severity_mappings (service_id, severity, priority, urgency)
+			VALUES ($1, $2, $3, $4)
+		`, serviceID, strings.ToLower(strings.TrimSpace(m.Severity)), nullIfEmpty(m.Priority), nullIfEmpty(m.Urgency))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save severity mapping: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.ListSeverityMappings(serviceID)
+}
+
+// severityMappingFor returns the service's mapping for a severity, or nil
+// when the service hasn't mapped it
+func severityMappingFor(q queryRower, serviceID, severity string) (*db.ServiceSeverityMapping, error) {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if serviceID == "" || severity == "" {
+		return nil, nil
+	}
+	var m db.ServiceSeverityMapping
+	err := q.QueryRow(`
+		SELECT service_id, severity, COALESCE(priority, ''), COALESCE(urgency, ''), created_at
+		FROM service_severity_mappings
+		WHERE service_id = $1 AND severity = $2
+	`, serviceID, severity).Scan(&m.ServiceID, &m.Severity, &m.Priority, &m.Urgency, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get severity mapping: %w", err)
+	}
+	return &m, nil
+}
+
+// ApplySeverityMapping sets a new incident's priority and urgency from its
+// service's mapping for its severity. With keepExplicit, fields the caller
+// already set are left alone; otherwise the mapping overrides the global
+// severity defaults. Lookup failures keep the incident as it is.
+func (s *IncidentService) ApplySeverityMapping(incident *db.Incident, keepExplicit bool) {
+	mapping, err := severityMappingFor(s.PG, incident.ServiceID, incident.Severity)
+	if err != nil {
+		log.Printf("⚠️  Failed to map severity for service %s: %v", incident.ServiceID, err)
+		return
+	}
+	if mapping == nil {
+		return
+	}
+	if mapping.Priority != "" && (!keepExplicit || incident.Priority == "") {
+		incident.Priority = mapping.Priority
+	}
+	if mapping.Urgency != "" && (!keepExplicit || incident.Urgency == "") {
+		incident.Urgency = mapping.Urgency
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var severityMappingColumns = []string{"service_id", "severity", "priority", "urgency", "created_at"}
+
+func TestApplySeverityMapping(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	svc := NewIncidentService(pg, nil)
+
+	for _, keepExplicit := range []bool{false, true} {
+		mock.ExpectQuery(`FROM service_severity_mappings`).WithArgs("svc-dev", "critical").
+			WillReturnRows(sqlmock.NewRows(severityMappingColumns).AddRow("svc-dev", "critical", "P4", "low", time.Now()))
+
+		incident := &db.Incident{ServiceID: "svc-dev", Severity: "Critical", Priority: "P1", Urgency: ""}
+		svc.ApplySeverityMapping(incident, keepExplicit)
+
+		wantPriority := "P4"
+		if keepExplicit {
+			wantPriority = "P1"
+		}
+		if incident.Priority != wantPriority || incident.Urgency != "low" {
+			t.Errorf("keepExplicit=%v: got priority %q urgency %q", keepExplicit, incident.Priority, incident.Urgency)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetSeverityMappingsRejectsDuplicates(t *testing.T) {
+	_, err := NewServiceService(nil).SetSeverityMappings("svc-1", db.SetSeverityMappingsRequest{
+		Mappings: []db.SeverityMappingInput{
+			{Severity: "critical", Priority: "P1"},
+			{Severity: " CRITICAL", Urgency: "low"},
+		},
+	})
+	if !errors.Is(err, ErrInvalidSeverityMapping) {
+		t.Fatalf("expected ErrInvalidSeverityMapping, got %v", err)
+	}
+}
+
+func TestRecordIncidentAlertAppliesSeverityMapping(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE incidents i`).WithArgs("inc-1", "critical").
+		WillReturnRows(sqlmock.NewRows([]string{"alert_count", "prev_severity", "severity", "urgency", "priority", "service_id", "assigned_to", "is_test"}).
+			AddRow(2, "warning", "critical", "low", "P5", "svc-dev", "", false))
+	mock.ExpectQuery(`FROM service_urgency_rules`).WithArgs("svc-dev").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_id", "name", "position", "min_alert_count", "severities", "set_urgency", "set_priority", "repage", "created_at"}))
+	mock.ExpectQuery(`FROM service_severity_mappings`).WithArgs("svc-dev", "critical").
+		WillReturnRows(sqlmock.NewRows(severityMappingColumns).AddRow("svc-dev", "critical", "P4", "low", time.Now()))
+	mock.ExpectExec(`UPDATE incidents SET urgency`).WithArgs("inc-1", "low", "P4").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventUrgencyChanged, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	change, err := NewIncidentService(pg, nil).RecordIncidentAlert("inc-1", "critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.ToUrgency != "low" || change.ToPriority != "P4" {
+		t.Errorf("a mapped critical on a dev service should stay low urgency, got %+v", change)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
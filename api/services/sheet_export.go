@@ -0,0 +1,551 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Sentinel errors for group sheet exports (mapped to HTTP status in handlers)
+var (
+	ErrSheetExportNotFound = errors.New("sheet export not found")
+	ErrInvalidSheetExport  = errors.New("invalid sheet export")
+)
+
+const (
+	sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+	// sheetExportLag keeps the export this far behind the newest resolutions,
+	// so incidents resolved in transactions committed out of order aren't skipped
+	sheetExportLag = 5 * time.Second
+
+	sheetTimeFormat = "2006-01-02 15:04:05"
+)
+
+var (
+	sheetSpreadsheetIDPattern  = regexp.MustCompile(`^[\w-]{20,100}$`)
+	sheetSpreadsheetURLPattern = regexp.MustCompile(`/spreadsheets/d/([\w-]+)`)
+
+	// sheetsAPIBaseURL is overridden in tests
+	sheetsAPIBaseURL = "https://sheets.googleapis.com"
+)
+
+// SheetExportService manages per-group sheet exports and appends the groups'
+// resolved incidents to them
+type SheetExportService struct {
+	PG     *sql.DB
+	client *http.Client
+}
+
+func NewSheetExportService(pg *sql.DB) *SheetExportService {
+	return &SheetExportService{
+		PG:     pg,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// EXPORTS
+
+const sheetExportColumns = `
+	id, group_id, sink_type, name, COALESCE(spreadsheet_id, ''), COALESCE(sheet_name, ''),
+	COALESCE(credentials, ''), COALESCE(service_account_email, ''), COALESCE(webhook_url, ''), is_active,
+	exported_count, last_exported_at, COALESCE(last_error, ''), last_error_at, COALESCE(created_by, ''),
+	created_at, updated_at`
+
+type sheetExportRowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSheetExport scans a row of sheetExportColumns, decrypting its secrets
+func scanSheetExport(row sheetExportRowScanner) (*db.GroupSheetExport, error) {
+	var e db.GroupSheetExport
+	var lastExportedAt, lastErrorAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.GroupID, &e.SinkType, &e.Name, &e.SpreadsheetID, &e.SheetName,
+		&e.Credentials, &e.ServiceAccountEmail, &e.WebhookURL, &e.IsActive,
+		&e.ExportedCount, &lastExportedAt, &e.LastError, &lastErrorAt, &e.CreatedBy,
+		&e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if lastExportedAt.Valid {
+		e.LastExportedAt = &lastExportedAt.Time
+	}
+	if lastErrorAt.Valid {
+		e.LastErrorAt = &lastErrorAt.Time
+	}
+	decryptColumns(&e.Credentials, &e.WebhookURL)
+	return &e, nil
+}
+
+// ListExports returns a group's sheet exports (without their secrets)
+func (s *SheetExportService) ListExports(groupID string) ([]db.GroupSheetExport, error) {
+	rows, err := s.PG.Query(`
+		SELECT `+sheetExportColumns+`
+		FROM group_sheet_exports
+		WHERE group_id = $1
+		ORDER BY created_at ASC
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sheet exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []db.GroupSheetExport{}
+	for rows.Next() {
+		e, err := scanSheetExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sheet export: %w", err)
+		}
+		exports = append(exports, *e)
+	}
+	return exports, rows.Err()
+}
+
+// CreateExport adds a sheet export to a group. It picks up incidents
+// resolved from now on; earlier ones are not backfilled.
+func (s *SheetExportService) CreateExport(groupID string, req db.CreateGroupSheetExportRequest, createdBy string) (*db.GroupSheetExport, error) {
+	e := db.GroupSheetExport{
+		GroupID:       groupID,
+		SinkType:      strings.TrimSpace(req.SinkType),
+		Name:          strings.TrimSpace(req.Name),
+		SpreadsheetID: strings.TrimSpace(req.SpreadsheetID),
+		SheetName:     strings.TrimSpace(req.SheetName),
+		Credentials:   strings.TrimSpace(req.Credentials),
+		WebhookURL:    strings.TrimSpace(req.WebhookURL),
+		IsActive:      true,
+		CreatedBy:     createdBy,
+	}
+	if err := validateSheetExport(&e); err != nil {
+		return nil, err
+	}
+
+	credentials, webhookURL, err := encryptSheetExportSecrets(e)
+	if err != nil {
+		return nil, err
+	}
+	err = s.PG.QueryRow(`
+		INSERT INTO group_sheet_exports (group_id, sink_type, name, spreadsheet_id, sheet_name, credentials,
+		                                 service_account_email, webhook_url, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`, groupID, e.SinkType, e.Name, nullIfEmpty(e.SpreadsheetID), nullIfEmpty(e.SheetName), credentials,
+		nullIfEmpty(e.ServiceAccountEmail), webhookURL, nullIfEmpty(createdBy)).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sheet export: %w", err)
+	}
+	return &e, nil
+}
+
+// UpdateExport changes a group's sheet export. Secrets left empty are kept.
+func (s *SheetExportService) UpdateExport(groupID, exportID string, req db.UpdateGroupSheetExportRequest) (*db.GroupSheetExport, error) {
+	e, err := s.getExport(groupID, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		e.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.SpreadsheetID != nil {
+		e.SpreadsheetID = strings.TrimSpace(*req.SpreadsheetID)
+	}
+	if req.SheetName != nil {
+		e.SheetName = strings.TrimSpace(*req.SheetName)
+	}
+	if req.Credentials != nil && strings.TrimSpace(*req.Credentials) != "" {
+		e.Credentials = strings.TrimSpace(*req.Credentials)
+	}
+	if req.WebhookURL != nil && strings.TrimSpace(*req.WebhookURL) != "" {
+		e.WebhookURL = strings.TrimSpace(*req.WebhookURL)
+	}
+	if req.IsActive != nil {
+		e.IsActive = *req.IsActive
+	}
+	if err := validateSheetExport(e); err != nil {
+		return nil, err
+	}
+
+	credentials, webhookURL, err := encryptSheetExportSecrets(*e)
+	if err != nil {
+		return nil, err
+	}
+	err = s.PG.QueryRow(`
+		UPDATE group_sheet_exports
+		SET name = $3, spreadsheet_id = $4, sheet_name = $5, credentials = $6, service_account_email = $7,
+		    webhook_url = $8, is_active = $9, updated_at = NOW()
+		WHERE id = $1 AND group_id = $2
+		RETURNING updated_at
+	`, exportID, groupID, e.Name, nullIfEmpty(e.SpreadsheetID), nullIfEmpty(e.SheetName), credentials,
+		nullIfEmpty(e.ServiceAccountEmail), webhookURL, e.IsActive).Scan(&e.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update sheet export: %w", err)
+	}
+	return e, nil
+}
+
+// DeleteExport removes a sheet export from a group
+func (s *SheetExportService) DeleteExport(groupID, exportID string) error {
+	result, err := s.PG.Exec(`DELETE FROM group_sheet_exports WHERE id = $1 AND group_id = $2`, exportID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sheet export: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSheetExportNotFound
+	}
+	return nil
+}
+
+// getExport loads a group's sheet export with its secrets decrypted
+func (s *SheetExportService) getExport(groupID, exportID string) (*db.GroupSheetExport, error) {
+	e, err := scanSheetExport(s.PG.QueryRow(`
+		SELECT `+sheetExportColumns+`
+		FROM group_sheet_exports
+		WHERE id = $1 AND group_id = $2
+	`, exportID, groupID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSheetExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sheet export: %w", err)
+	}
+	return e, nil
+}
+
+// validateSheetExport checks an export's settings for its sink and clears
+// the settings the sink doesn't use. Spreadsheet URLs are accepted in place
+// of the spreadsheet ID.
+func validateSheetExport(e *db.GroupSheetExport) error {
+	if e.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSheetExport)
+	}
+	switch e.SinkType {
+	case db.SheetSinkGoogleSheets:
+		if m := sheetSpreadsheetURLPattern.FindStringSubmatch(e.SpreadsheetID); m != nil {
+			e.SpreadsheetID = m[1]
+		}
+		if !sheetSpreadsheetIDPattern.MatchString(e.SpreadsheetID) {
+			return fmt.Errorf("%w: spreadsheet_id must be a Google Sheets spreadsheet ID or URL", ErrInvalidSheetExport)
+		}
+		if e.SheetName == "" {
+			e.SheetName = db.SheetExportDefaultSheetName
+		}
+		if len([]rune(e.SheetName)) > 100 {
+			return fmt.Errorf("%w: sheet_name must be at most 100 characters", ErrInvalidSheetExport)
+		}
+		if e.Credentials == "" {
+			return fmt.Errorf("%w: credentials (a service account key) are required for Google Sheets", ErrInvalidSheetExport)
+		}
+		conf, err := google.JWTConfigFromJSON([]byte(e.Credentials), sheetsScope)
+		if err != nil || conf.Email == "" {
+			return fmt.Errorf("%w: credentials must be a Google service account key in JSON", ErrInvalidSheetExport)
+		}
+		e.ServiceAccountEmail = conf.Email
+		e.WebhookURL = ""
+	case db.SheetSinkCSVWebhook:
+		u, err := url.Parse(e.WebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%w: webhook_url must be an http(s) URL", ErrInvalidSheetExport)
+		}
+		e.SpreadsheetID, e.SheetName, e.Credentials, e.ServiceAccountEmail = "", "", "", ""
+	default:
+		return fmt.Errorf("%w: sink_type must be %s or %s", ErrInvalidSheetExport, db.SheetSinkGoogleSheets, db.SheetSinkCSVWebhook)
+	}
+	return nil
+}
+
+// encryptSheetExportSecrets seals the service account key and webhook URL for storage
+func encryptSheetExportSecrets(e db.GroupSheetExport) (interface{}, interface{}, error) {
+	credentials, err := encryptColumn(e.Credentials)
+	if err != nil {
+		return nil, nil, err
+	}
+	webhookURL, err := encryptColumn(e.WebhookURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nullIfEmpty(credentials), nullIfEmpty(webhookURL), nil
+}
+
+// EXPORT
+
+// sheetExportTarget is an active sheet export and its export position
+type sheetExportTarget struct {
+	export     db.GroupSheetExport
+	resolvedAt time.Time
+	incidentID string
+}
+
+// sheetIncident is a resolved incident as exported
+type sheetIncident struct {
+	ID             string
+	ShortID        string
+	Title          string
+	Severity       string
+	Urgency        string
+	ServiceName    string
+	AssignedTo     string
+	ResolvedBy     string
+	CreatedAt      time.Time
+	AcknowledgedAt *time.Time
+	ResolvedAt     time.Time
+}
+
+// Export runs one pass over every active sheet export, returning how many
+// incidents were exported. One failing export doesn't hold up the others.
+func (s *SheetExportService) Export() (int, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, group_id, sink_type, name, COALESCE(spreadsheet_id, ''), COALESCE(sheet_name, ''),
+		       COALESCE(credentials, ''), COALESCE(webhook_url, ''), exported_count,
+		       resolved_at_cursor, incident_id_cursor::text
+		FROM group_sheet_exports
+		WHERE is_active = true
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sheet exports: %w", err)
+	}
+	var targets []sheetExportTarget
+	for rows.Next() {
+		var t sheetExportTarget
+		e := &t.export
+		if err := rows.Scan(&e.ID, &e.GroupID, &e.SinkType, &e.Name, &e.SpreadsheetID, &e.SheetName,
+			&e.Credentials, &e.WebhookURL, &e.ExportedCount, &t.resolvedAt, &t.incidentID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan sheet export: %w", err)
+		}
+		decryptColumns(&e.Credentials, &e.WebhookURL)
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list sheet exports: %w", err)
+	}
+
+	exported := 0
+	var errs []error
+	for _, t := range targets {
+		n, err := s.exportTarget(t)
+		exported += n
+		if err != nil {
+			log.Printf("⚠️  Sheet export %q of group %s failed: %v", t.export.Name, t.export.GroupID, err)
+			if _, dbErr := s.PG.Exec(`
+				UPDATE group_sheet_exports SET last_error = $2, last_error_at = NOW(), updated_at = NOW()
+				WHERE id = $1
+			`, t.export.ID, truncateRunes(err.Error(), 1000)); dbErr != nil {
+				log.Printf("⚠️  Failed to record sheet export error: %v", dbErr)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return exported, errors.Join(errs...)
+}
+
+// exportTarget appends the incidents resolved after the export's cursor and
+// moves the cursor past them. On a send error the cursor stays put so the
+// same incidents are retried next pass.
+func (s *SheetExportService) exportTarget(t sheetExportTarget) (int, error) {
+	incidents, err := s.resolvedIncidentsAfter(t.export.GroupID, t.resolvedAt, t.incidentID)
+	if err != nil || len(incidents) == 0 {
+		return 0, err
+	}
+
+	rows := make([][]string, 0, len(incidents))
+	for _, inc := range incidents {
+		rows = append(rows, sheetExportRow(inc))
+	}
+	if err := s.send(t.export, rows); err != nil {
+		return 0, err
+	}
+
+	last := incidents[len(incidents)-1]
+	if _, err := s.PG.Exec(`
+		UPDATE group_sheet_exports
+		SET resolved_at_cursor = $2, incident_id_cursor = $3, exported_count = exported_count + $4,
+		    last_exported_at = NOW(), last_error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, t.export.ID, last.ResolvedAt, last.ID, len(incidents)); err != nil {
+		return len(incidents), fmt.Errorf("failed to move sheet export cursor: %w", err)
+	}
+	return len(incidents), nil
+}
+
+// resolvedIncidentsAfter reads the group's incidents resolved after a
+// (resolved_at, id) position, oldest first. Test incidents are left out.
+func (s *SheetExportService) resolvedIncidentsAfter(groupID string, at time.Time, id string) ([]sheetIncident, error) {
+	rows, err := s.PG.Query(`
+		SELECT i.id, COALESCE(i.short_id, ''), i.title, COALESCE(i.severity, ''), i.urgency, COALESCE(sv.name, ''),
+		       COALESCE(ua.name, ua.email, ''), COALESCE(ur.name, ur.email, ''),
+		       i.created_at, i.acknowledged_at, i.resolved_at
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		LEFT JOIN users ua ON ua.id = i.assigned_to
+		LEFT JOIN users ur ON ur.id = i.resolved_by
+		WHERE i.group_id::text = $1 AND i.status = 'resolved' AND NOT COALESCE(i.is_test, false)
+		  AND (i.resolved_at, i.id) > ($2, $3::uuid)
+		  AND i.resolved_at < LOCALTIMESTAMP - $4 * INTERVAL '1 second'
+		ORDER BY i.resolved_at, i.id
+		LIMIT $5
+	`, groupID, at, id, int(sheetExportLag.Seconds()), db.SheetExportBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resolved incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []sheetIncident{}
+	for rows.Next() {
+		var inc sheetIncident
+		var acknowledgedAt sql.NullTime
+		if err := rows.Scan(&inc.ID, &inc.ShortID, &inc.Title, &inc.Severity, &inc.Urgency, &inc.ServiceName,
+			&inc.AssignedTo, &inc.ResolvedBy, &inc.CreatedAt, &acknowledgedAt, &inc.ResolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan resolved incident: %w", err)
+		}
+		if acknowledgedAt.Valid {
+			inc.AcknowledgedAt = &acknowledgedAt.Time
+		}
+		incidents = append(incidents, inc)
+	}
+	return incidents, rows.Err()
+}
+
+// sheetExportRow renders an incident as a row of db.SheetExportColumns
+func sheetExportRow(inc sheetIncident) []string {
+	acknowledgedAt, timeToAck := "", ""
+	if inc.AcknowledgedAt != nil {
+		acknowledgedAt = inc.AcknowledgedAt.Format(sheetTimeFormat)
+		timeToAck = sheetMinutes(inc.AcknowledgedAt.Sub(inc.CreatedAt))
+	}
+	shortID := inc.ShortID
+	if shortID == "" {
+		shortID = inc.ID
+	}
+	return []string{
+		shortID,
+		sheetText(inc.Title),
+		sheetText(inc.Severity),
+		sheetText(inc.Urgency),
+		sheetText(inc.ServiceName),
+		sheetText(inc.AssignedTo),
+		sheetText(inc.ResolvedBy),
+		inc.CreatedAt.Format(sheetTimeFormat),
+		acknowledgedAt,
+		inc.ResolvedAt.Format(sheetTimeFormat),
+		timeToAck,
+		sheetMinutes(inc.ResolvedAt.Sub(inc.CreatedAt)),
+		incidentLinkURL(inc.ID, inc.ShortID),
+	}
+}
+
+// sheetMinutes renders a duration in minutes with one decimal
+func sheetMinutes(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.FormatFloat(d.Minutes(), 'f', 1, 64)
+}
+
+// sheetText keeps free text from being read as a formula by Sheets or Excel
+// by quoting values that start with a formula character
+func sheetText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// send appends rows to the export's sink
+func (s *SheetExportService) send(e db.GroupSheetExport, rows [][]string) error {
+	switch e.SinkType {
+	case db.SheetSinkGoogleSheets:
+		return s.appendToGoogleSheet(e, rows)
+	case db.SheetSinkCSVWebhook:
+		return s.postCSV(e, rows)
+	}
+	return fmt.Errorf("%w: unsupported sink type %q", ErrInvalidSheetExport, e.SinkType)
+}
+
+// appendToGoogleSheet appends rows after the last row of the export's tab,
+// headed by the column names on the export's first append. The append isn't
+// idempotent: rows are appended again if moving the cursor fails afterwards.
+func (s *SheetExportService) appendToGoogleSheet(e db.GroupSheetExport, rows [][]string) error {
+	conf, err := google.JWTConfigFromJSON([]byte(e.Credentials), sheetsScope)
+	if err != nil {
+		return fmt.Errorf("invalid service account key: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), oauth2.HTTPClient, s.client), 30*time.Second)
+	defer cancel()
+
+	values := make([][]string, 0, len(rows)+1)
+	if e.ExportedCount == 0 {
+		values = append(values, db.SheetExportColumns)
+	}
+	body, err := json.Marshal(map[string]interface{}{"majorDimension": "ROWS", "values": append(values, rows...)})
+	if err != nil {
+		return err
+	}
+
+	sheetRange := "'" + strings.ReplaceAll(e.SheetName, "'", "''") + "'!A1"
+	endpoint := sheetsAPIBaseURL + "/v4/spreadsheets/" + url.PathEscape(e.SpreadsheetID) + "/values/" +
+		url.PathEscape(sheetRange) + ":append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := conf.Client(ctx).Do(req)
+	if err != nil {
+		return fmt.Errorf("Google Sheets unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("Google Sheets returned %d: %s", resp.StatusCode, truncateRunes(strings.TrimSpace(string(respBody)), 512))
+	}
+	return nil
+}
+
+// postCSV posts rows as a CSV document with a header row. Transport errors
+// are unwrapped so the webhook URL, which may carry a token, stays out of logs.
+func (s *SheetExportService) postCSV(e db.GroupSheetExport, rows [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(db.SheetExportColumns)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.WebhookURL, &buf)
+	if err != nil {
+		return errors.New("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	req.Header.Set("User-Agent", "SLAR-Sheet-Export/1.0")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("CSV webhook unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("CSV webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+// ShiftBatchError rejects a whole shift batch, listing every operation that
+// could not be applied
+type ShiftBatchError struct {
+	Errors []db.ShiftBatchItemError
+}
+
+func (e *ShiftBatchError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, item := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s[%d]: %s", item.Op, item.Index, item.Message))
+	}
+	return "shift batch rejected: " + strings.Join(messages, "; ")
+}
+
+// HasCode reports whether any operation failed with code
+func (e *ShiftBatchError) HasCode(code string) bool {
+	for _, item := range e.Errors {
+		if item.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftBatchColumns are the columns returned for created and updated shifts
+const shiftBatchColumns = `id, COALESCE(scheduler_id::text, ''), group_id, user_id, shift_type, start_time, end_time,
+	is_active, is_recurring, rotation_days, service_id, schedule_scope, created_at, updated_at, COALESCE(created_by, '')`
+
+func scanBatchShift(row interface{ Scan(...interface{}) error }) (db.Shift, error) {
+	var shift db.Shift
+	err := row.Scan(&shift.ID, &shift.SchedulerID, &shift.GroupID, &shift.UserID, &shift.ShiftType,
+		&shift.StartTime, &shift.EndTime, &shift.IsActive, &shift.IsRecurring, &shift.RotationDays,
+		&shift.ServiceID, &shift.ScheduleScope, &shift.CreatedAt, &shift.UpdatedAt, &shift.CreatedBy)
+	shift.EffectiveUserID = shift.UserID
+	return shift, err
+}
+
+// sameShiftVersion compares a shift's updated_at with the one a client sent
+// back. Browsers keep milliseconds, Postgres microseconds.
+func sameShiftVersion(current, seen time.Time) bool {
+	return current.Truncate(time.Millisecond).Equal(seen.Truncate(time.Millisecond))
+}
+
+// validateShiftBatch checks what can be checked without the database
+func validateShiftBatch(req db.ShiftBatchRequest) []db.ShiftBatchItemError {
+	var errs []db.ShiftBatchItemError
+	invalid := func(op string, index int, id, message string) {
+		errs = append(errs, db.ShiftBatchItemError{Op: op, Index: index, ShiftID: id, Code: db.ShiftBatchErrorInvalid, Message: message})
+	}
+
+	total := len(req.Create) + len(req.Update) + len(req.Delete)
+	if total == 0 {
+		invalid("batch", 0, "", "batch has no operations")
+		return errs
+	}
+	if total > db.ShiftBatchMaxOperations {
+		invalid("batch", 0, "", fmt.Sprintf("batch has %d operations, the maximum is %d", total, db.ShiftBatchMaxOperations))
+		return errs
+	}
+
+	for i, create := range req.Create {
+		switch {
+		case !create.EndTime.After(create.StartTime):
+			invalid("create", i, "", "end_time must be after start_time")
+		case create.ScheduleScope == "service" && create.ServiceID == nil:
+			invalid("create", i, "", "service_id is required when schedule_scope is 'service'")
+		case (create.ScheduleScope == "" || create.ScheduleScope == "group") && create.ServiceID != nil:
+			invalid("create", i, "", "service_id must be empty when schedule_scope is 'group'")
+		case create.ScheduleScope != "" && create.ScheduleScope != "group" && create.ScheduleScope != "service":
+			invalid("create", i, "", "schedule_scope must be 'group' or 'service'")
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, update := range req.Update {
+		if seen[update.ID] {
+			invalid("update", i, update.ID, "shift appears more than once in the batch")
+		}
+		seen[update.ID] = true
+		if update.StartTime != nil && update.EndTime != nil && !update.EndTime.After(*update.StartTime) {
+			invalid("update", i, update.ID, "end_time must be after start_time")
+		}
+	}
+	for i, del := range req.Delete {
+		if seen[del.ID] {
+			invalid("delete", i, del.ID, "shift appears more than once in the batch")
+		}
+		seen[del.ID] = true
+	}
+	return errs
+}
+
+// shiftBatchOrigin is the operation that created or updated a shift
+type shiftBatchOrigin struct {
+	op    string
+	index int
+}
+
+// overlapError reports shift a overlapping shift b. Shifts created by the
+// batch are named by their operation, since their IDs are rolled back.
+func overlapError(op string, index int, a, b string, touched map[string]shiftBatchOrigin) db.ShiftBatchItemError {
+	other := "shift " + b
+	if from, ok := touched[b]; ok && from.op == "create" {
+		other = fmt.Sprintf("create[%d]", from.index)
+	}
+	item := db.ShiftBatchItemError{Op: op, Index: index, Code: db.ShiftBatchErrorOverlap,
+		Message: "overlaps " + other + " of the same user"}
+	if op != "create" {
+		item.ShiftID = a
+	}
+	return item
+}
+
+// BatchEditShifts applies the creates, updates and deletes of a shift batch
+// to a group in one transaction. Updates and deletes must carry the shift's
+// current updated_at. After applying them, no created or updated shift may
+// overlap another active shift of the same user in the group. Any failure
+// rejects the whole batch with a *ShiftBatchError; a user who doesn't manage
+// every scheduler touched gets ErrSchedulerForbidden.
+func (s *SchedulerService) BatchEditShifts(groupID string, req db.ShiftBatchRequest, userID string) (db.ShiftBatchResult, error) {
+	result := db.ShiftBatchResult{Created: []db.Shift{}, Updated: []db.Shift{}, Deleted: []string{}}
+	if errs := validateShiftBatch(req); len(errs) > 0 {
+		return result, &ShiftBatchError{Errors: errs}
+	}
+
+	// The user must manage every scheduler the batch touches
+	var schedulerIDs, shiftIDs []string
+	for _, create := range req.Create {
+		schedulerIDs = append(schedulerIDs, create.SchedulerID)
+	}
+	for _, update := range req.Update {
+		shiftIDs = append(shiftIDs, update.ID)
+	}
+	for _, del := range req.Delete {
+		shiftIDs = append(shiftIDs, del.ID)
+	}
+	if ok, err := canManageSchedulers(s.PG, userID, schedulerIDs); err != nil {
+		return result, err
+	} else if !ok {
+		return result, ErrSchedulerForbidden
+	}
+	if ok, err := canManageShifts(s.PG, userID, shiftIDs); err != nil {
+		return result, err
+	} else if !ok {
+		return result, ErrSchedulerForbidden
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var errs []db.ShiftBatchItemError
+
+	// Creates may only target the group's active schedulers
+	if len(req.Create) > 0 {
+		schedulerIDs := make([]string, 0, len(req.Create))
+		for _, create := range req.Create {
+			schedulerIDs = append(schedulerIDs, create.SchedulerID)
+		}
+		rows, err := tx.Query(`
+			SELECT id::text FROM schedulers
+			WHERE group_id = $1 AND is_active = true AND id::text = ANY($2)
+		`, groupID, pq.Array(schedulerIDs))
+		if err != nil {
+			return result, fmt.Errorf("failed to check schedulers: %w", err)
+		}
+		schedulers := map[string]bool{}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return result, fmt.Errorf("failed to scan scheduler: %w", err)
+			}
+			schedulers[id] = true
+		}
+		rows.Close()
+		for i, create := range req.Create {
+			if !schedulers[create.SchedulerID] {
+				errs = append(errs, db.ShiftBatchItemError{Op: "create", Index: i, Code: db.ShiftBatchErrorNotFound,
+					Message: "scheduler not found in this group"})
+			}
+		}
+	}
+
+	// Lock the shifts being changed and check nobody changed them first
+	type currentShift struct {
+		updatedAt  time.Time
+		start, end time.Time
+	}
+	current := map[string]currentShift{}
+	var lockIDs []string
+	for _, update := range req.Update {
+		lockIDs = append(lockIDs, update.ID)
+	}
+	for _, del := range req.Delete {
+		lockIDs = append(lockIDs, del.ID)
+	}
+	if len(lockIDs) > 0 {
+		rows, err := tx.Query(`
+			SELECT id::text, updated_at, start_time, end_time FROM shifts
+			WHERE group_id = $1 AND is_active = true AND id::text = ANY($2)
+			FOR UPDATE
+		`, groupID, pq.Array(lockIDs))
+		if err != nil {
+			return result, fmt.Errorf("failed to lock shifts: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			var shift currentShift
+			if err := rows.Scan(&id, &shift.updatedAt, &shift.start, &shift.end); err != nil {
+				rows.Close()
+				return result, fmt.Errorf("failed to scan shift: %w", err)
+			}
+			current[id] = shift
+		}
+		rows.Close()
+	}
+	check := func(op string, index int, id string, seen time.Time) bool {
+		shift, ok := current[id]
+		switch {
+		case !ok:
+			errs = append(errs, db.ShiftBatchItemError{Op: op, Index: index, ShiftID: id, Code: db.ShiftBatchErrorNotFound,
+				Message: "shift not found in this group"})
+		case !sameShiftVersion(shift.updatedAt, seen):
+			errs = append(errs, db.ShiftBatchItemError{Op: op, Index: index, ShiftID: id, Code: db.ShiftBatchErrorConflict,
+				Message: "shift was changed by someone else since " + seen.Format(time.RFC3339Nano)})
+		default:
+			return true
+		}
+		return false
+	}
+	for i, update := range req.Update {
+		if !check("update", i, update.ID, update.UpdatedAt) {
+			continue
+		}
+		start, end := current[update.ID].start, current[update.ID].end
+		if update.StartTime != nil {
+			start = *update.StartTime
+		}
+		if update.EndTime != nil {
+			end = *update.EndTime
+		}
+		if !end.After(start) {
+			errs = append(errs, db.ShiftBatchItemError{Op: "update", Index: i, ShiftID: update.ID, Code: db.ShiftBatchErrorInvalid,
+				Message: "end_time must be after start_time"})
+		}
+	}
+	for i, del := range req.Delete {
+		check("delete", i, del.ID, del.UpdatedAt)
+	}
+	if len(errs) > 0 {
+		return result, &ShiftBatchError{Errors: errs}
+	}
+
+	// Apply: deletes first so their time slots are free for the rest
+	now := time.Now()
+	for _, del := range req.Delete {
+		if _, err := tx.Exec(`UPDATE shifts SET is_active = false, updated_at = $1 WHERE id = $2`, now, del.ID); err != nil {
+			return result, fmt.Errorf("failed to delete shift %s: %w", del.ID, err)
+		}
+		result.Deleted = append(result.Deleted, del.ID)
+	}
+
+	touched := map[string]shiftBatchOrigin{}
+	for i, update := range req.Update {
+		shift, err := scanBatchShift(tx.QueryRow(`
+			UPDATE shifts SET
+				user_id = COALESCE($2, user_id),
+				start_time = COALESCE($3, start_time),
+				end_time = COALESCE($4, end_time),
+				updated_at = $5
+			WHERE id = $1
+			RETURNING `+shiftBatchColumns,
+			update.ID, update.UserID, update.StartTime, update.EndTime, now))
+		if err != nil {
+			return result, fmt.Errorf("failed to update shift %s: %w", update.ID, err)
+		}
+		touched[shift.ID] = shiftBatchOrigin{"update", i}
+		result.Updated = append(result.Updated, shift)
+	}
+	for i, create := range req.Create {
+		scope := create.ScheduleScope
+		if scope == "" {
+			scope = "group"
+		}
+		shift, err := scanBatchShift(tx.QueryRow(`
+			INSERT INTO shifts (scheduler_id, group_id, user_id, shift_type, start_time, end_time,
+			                    is_active, is_recurring, rotation_days, service_id, schedule_scope,
+			                    created_at, updated_at, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, true, false, 0, $7, $8, $9, $9, $10)
+			RETURNING `+shiftBatchColumns,
+			create.SchedulerID, groupID, create.UserID, db.ScheduleTypeCustom, create.StartTime, create.EndTime,
+			create.ServiceID, scope, now, userID))
+		if err != nil {
+			return result, fmt.Errorf("failed to create shift: %w", err)
+		}
+		touched[shift.ID] = shiftBatchOrigin{"create", i}
+		result.Created = append(result.Created, shift)
+	}
+
+	// The final schedule must not put anyone on two shifts at once
+	if len(touched) > 0 {
+		touchedIDs := make([]string, 0, len(touched))
+		for id := range touched {
+			touchedIDs = append(touchedIDs, id)
+		}
+		rows, err := tx.Query(`
+			SELECT a.id::text, b.id::text
+			FROM shifts a
+			JOIN shifts b ON b.user_id = a.user_id AND b.id <> a.id
+			WHERE a.id::text = ANY($1) AND b.group_id = $2
+			  AND a.is_active = true AND b.is_active = true
+			  AND a.start_time < b.end_time AND b.start_time < a.end_time
+			ORDER BY a.id, b.id
+		`, pq.Array(touchedIDs), groupID)
+		if err != nil {
+			return result, fmt.Errorf("failed to check overlapping shifts: %w", err)
+		}
+		reported := map[[2]string]bool{}
+		for rows.Next() {
+			var a, b string
+			if err := rows.Scan(&a, &b); err != nil {
+				rows.Close()
+				return result, fmt.Errorf("failed to scan overlap: %w", err)
+			}
+			// Two touched shifts overlapping each other are reported once
+			if reported[[2]string{b, a}] {
+				continue
+			}
+			reported[[2]string{a, b}] = true
+			errs = append(errs, overlapError(touched[a].op, touched[a].index, a, b, touched))
+		}
+		rows.Close()
+		if len(errs) > 0 {
+			return result, &ShiftBatchError{Errors: errs}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestValidateShiftBatch(t *testing.T) {
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	service := "svc-1"
+	errs := validateShiftBatch(db.ShiftBatchRequest{
+		Create: []db.ShiftBatchCreate{
+			{SchedulerID: "sch-1", UserID: "u-1", StartTime: start, EndTime: start.Add(8 * time.Hour)},
+			{SchedulerID: "sch-1", UserID: "u-1", StartTime: start, EndTime: start},
+			{SchedulerID: "sch-1", UserID: "u-1", StartTime: start, EndTime: start.Add(time.Hour), ServiceID: &service},
+		},
+		Update: []db.ShiftBatchUpdate{{ID: "sh-1", UpdatedAt: start}},
+		Delete: []db.ShiftBatchDelete{{ID: "sh-1", UpdatedAt: start}},
+	})
+	want := []struct {
+		op    string
+		index int
+	}{{"create", 1}, {"create", 2}, {"delete", 0}}
+	if len(errs) != len(want) {
+		t.Fatalf("errs = %+v", errs)
+	}
+	for i, w := range want {
+		if errs[i].Op != w.op || errs[i].Index != w.index || errs[i].Code != db.ShiftBatchErrorInvalid {
+			t.Errorf("errs[%d] = %+v, want %s[%d]", i, errs[i], w.op, w.index)
+		}
+	}
+
+	if errs := validateShiftBatch(db.ShiftBatchRequest{}); len(errs) != 1 {
+		t.Errorf("empty batch errs = %+v", errs)
+	}
+}
+
+func TestBatchEditShiftsRejectsStaleShift(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	seen := time.Date(2026, 6, 1, 9, 0, 0, 123000000, time.UTC)
+	changed := seen.Add(time.Minute)
+	start := seen.Add(time.Hour)
+
+	mock.ExpectQuery(`FROM shifts s`).WithArgs("user-1", `{"sh-1","sh-2","sh-3"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM shifts`).WithArgs("grp-1", `{"sh-1","sh-2","sh-3"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at", "start_time", "end_time"}).
+			AddRow("sh-1", seen.Add(456*time.Microsecond), start, start.Add(time.Hour)).
+			AddRow("sh-2", changed, start, start.Add(time.Hour)))
+	mock.ExpectRollback()
+
+	_, err = (&SchedulerService{PG: pg}).BatchEditShifts("grp-1", db.ShiftBatchRequest{
+		Update: []db.ShiftBatchUpdate{
+			// Same version at millisecond precision, as a browser sends it back
+			{ID: "sh-1", UpdatedAt: seen},
+			{ID: "sh-2", UpdatedAt: seen},
+		},
+		Delete: []db.ShiftBatchDelete{{ID: "sh-3", UpdatedAt: seen}},
+	}, "user-1")
+
+	var batchErr *ShiftBatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(batchErr.Errors) != 2 ||
+		batchErr.Errors[0].ShiftID != "sh-2" || batchErr.Errors[0].Code != db.ShiftBatchErrorConflict ||
+		batchErr.Errors[1].ShiftID != "sh-3" || batchErr.Errors[1].Code != db.ShiftBatchErrorNotFound {
+		t.Errorf("errors = %+v", batchErr.Errors)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBatchEditShiftsRequiresScheduleManager(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM schedulers sc`).WithArgs("user-1", `{"sch-1"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`FROM shifts s`).WithArgs("user-1", `{"sh-1"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	_, err = (&SchedulerService{PG: pg}).BatchEditShifts("grp-1", db.ShiftBatchRequest{
+		Create: []db.ShiftBatchCreate{{SchedulerID: "sch-1", UserID: "u-1", StartTime: start, EndTime: start.Add(time.Hour)}},
+		Delete: []db.ShiftBatchDelete{{ID: "sh-1", UpdatedAt: start}},
+	}, "user-1")
+	if !errors.Is(err, ErrSchedulerForbidden) {
+		t.Errorf("err = %v, want ErrSchedulerForbidden", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOverlapErrorNamesCreatedShifts(t *testing.T) {
+	touched := map[string]shiftBatchOrigin{"new-1": {"create", 0}, "sh-1": {"update", 2}}
+	item := overlapError("update", 2, "sh-1", "new-1", touched)
+	if item.ShiftID != "sh-1" || item.Message != "overlaps create[0] of the same user" {
+		t.Errorf("item = %+v", item)
+	}
+	item = overlapError("create", 0, "new-1", "sh-9", touched)
+	if item.ShiftID != "" || item.Message != "overlaps shift sh-9 of the same user" {
+		t.Errorf("item = %+v", item)
+	}
+}
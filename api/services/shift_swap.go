@@ -0,0 +1,197 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrSwapRequestNotFound = errors.New("swap request not found")
+	// ErrSwapRequestClosed means the request was already decided or cancelled
+	ErrSwapRequestClosed = errors.New("swap request is no longer pending")
+	// ErrSwapRequestStale means either shift was reassigned or removed after
+	// the swap was requested
+	ErrSwapRequestStale = errors.New("the shifts changed since the swap was requested")
+)
+
+const swapRequestSelect = `
+	SELECT id, group_id, current_shift_id, target_shift_id, current_user_id, target_user_id,
+	       message, status, requested_by, COALESCE(decided_by::text, ''), decided_at, created_at
+	FROM shift_swap_requests
+`
+
+func scanSwapRequest(row interface{ Scan(...interface{}) error }) (*db.SwapRequest, error) {
+	var r db.SwapRequest
+	var decidedAt sql.NullTime
+	if err := row.Scan(&r.ID, &r.GroupID, &r.CurrentShiftID, &r.TargetShiftID, &r.CurrentUserID, &r.TargetUserID,
+		&r.Message, &r.Status, &r.RequestedBy, &r.DecidedBy, &decidedAt, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	if decidedAt.Valid {
+		r.DecidedAt = &decidedAt.Time
+	}
+	return &r, nil
+}
+
+// requestScheduleSwap records a member's request to swap their shift with
+// another, or returns the one already open for the same shifts
+func (s *OnCallService) requestScheduleSwap(current, target db.Shift, message, requestorID string) (*db.SwapRequest, error) {
+	swap, err := scanSwapRequest(s.PG.QueryRow(`
+		INSERT INTO shift_swap_requests (group_id, current_shift_id, target_shift_id, current_user_id, target_user_id, message, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (current_shift_id, target_shift_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id, group_id, current_shift_id, target_shift_id, current_user_id, target_user_id,
+		          message, status, requested_by, COALESCE(decided_by::text, ''), decided_at, created_at
+	`, current.GroupID, current.ID, target.ID, current.UserID, target.UserID, message, requestorID))
+	if err == sql.ErrNoRows {
+		swap, err = scanSwapRequest(s.PG.QueryRow(swapRequestSelect+`
+			WHERE current_shift_id = $1 AND target_shift_id = $2 AND status = 'pending'
+		`, current.ID, target.ID))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create swap request: %w", err)
+	}
+	return swap, nil
+}
+
+// ListSwapRequests returns the group's swap requests, newest first,
+// optionally only those with the given status
+func (s *OnCallService) ListSwapRequests(groupID, status string) ([]db.SwapRequest, error) {
+	rows, err := s.PG.Query(swapRequestSelect+`
+		WHERE group_id::text = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT 200
+	`, groupID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list swap requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []db.SwapRequest{}
+	for rows.Next() {
+		r, err := scanSwapRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan swap request: %w", err)
+		}
+		requests = append(requests, *r)
+	}
+	return requests, rows.Err()
+}
+
+// openSwapRequest loads a pending swap request of the group that the user
+// manages both shifts of
+func (s *OnCallService) openSwapRequest(groupID, id, userID string) (*db.SwapRequest, error) {
+	swap, err := scanSwapRequest(s.PG.QueryRow(swapRequestSelect+`
+		WHERE id::text = $1 AND group_id::text = $2
+	`, id, groupID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSwapRequestNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap request: %w", err)
+	}
+	if swap.Status != db.SwapRequestPending {
+		return nil, ErrSwapRequestClosed
+	}
+	ok, err := canManageShifts(s.PG, userID, []string{swap.CurrentShiftID, swap.TargetShiftID})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSchedulerForbidden
+	}
+	return swap, nil
+}
+
+// decideSwapRequest moves a pending swap request to status, failing if
+// someone else decided it first
+func (s *OnCallService) decideSwapRequest(id, status, fromStatus, userID string) error {
+	result, err := s.PG.Exec(`
+		UPDATE shift_swap_requests
+		SET status = $2, decided_by = NULLIF($3, '')::uuid, decided_at = CASE WHEN $3 = '' THEN NULL ELSE NOW() END
+		WHERE id::text = $1 AND status = $4
+	`, id, status, userID, fromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update swap request: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSwapRequestClosed
+	}
+	return nil
+}
+
+// ApproveSwapRequest swaps the shifts of a pending request. The approver must
+// manage both shifts, and neither may have been reassigned since the request.
+func (s *OnCallService) ApproveSwapRequest(groupID, id, userID string) (db.ShiftSwapResponse, error) {
+	var response db.ShiftSwapResponse
+	swap, err := s.openSwapRequest(groupID, id, userID)
+	if err != nil {
+		return response, err
+	}
+
+	current, err := s.getScheduleByID(swap.CurrentShiftID)
+	if err != nil {
+		return response, fmt.Errorf("failed to get current schedule: %w", err)
+	}
+	target, err := s.getScheduleByID(swap.TargetShiftID)
+	if err != nil {
+		return response, fmt.Errorf("failed to get target schedule: %w", err)
+	}
+	if !current.IsActive || !target.IsActive || current.UserID != swap.CurrentUserID || target.UserID != swap.TargetUserID {
+		return response, ErrSwapRequestStale
+	}
+
+	// Claim the request first so two approvals can't swap the shifts twice
+	if err := s.decideSwapRequest(swap.ID, db.SwapRequestApproved, db.SwapRequestPending, userID); err != nil {
+		return response, err
+	}
+	response, err = s.executeScheduleSwap(current, target, swap.Message, userID)
+	if err != nil {
+		if reopenErr := s.decideSwapRequest(swap.ID, db.SwapRequestPending, db.SwapRequestApproved, ""); reopenErr != nil {
+			return response, fmt.Errorf("%w (and failed to reopen the request: %v)", err, reopenErr)
+		}
+		return response, err
+	}
+	swap.Status = db.SwapRequestApproved
+	swap.DecidedBy = userID
+	swap.DecidedAt = &response.SwappedAt
+	response.SwapRequest = swap
+	return response, nil
+}
+
+// RejectSwapRequest turns down a pending request; the rejecter must manage
+// both shifts
+func (s *OnCallService) RejectSwapRequest(groupID, id, userID string) (*db.SwapRequest, error) {
+	swap, err := s.openSwapRequest(groupID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decideSwapRequest(swap.ID, db.SwapRequestRejected, db.SwapRequestPending, userID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	swap.Status = db.SwapRequestRejected
+	swap.DecidedBy = userID
+	swap.DecidedAt = &now
+	return swap, nil
+}
+
+// CancelSwapRequest withdraws a pending request the user made
+func (s *OnCallService) CancelSwapRequest(groupID, id, userID string) error {
+	result, err := s.PG.Exec(`
+		UPDATE shift_swap_requests
+		SET status = 'cancelled'
+		WHERE id::text = $1 AND group_id::text = $2 AND requested_by::text = $3 AND status = 'pending'
+	`, id, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel swap request: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSwapRequestNotFound
+	}
+	return nil
+}
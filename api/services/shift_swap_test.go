@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+var swapShiftColumns = []string{"id", "group_id", "user_id", "shift_type", "start_time", "end_time",
+	"is_active", "is_recurring", "rotation_days", "created_at", "updated_at", "created_by",
+	"user_name", "user_email", "user_team"}
+
+var swapRequestColumns = []string{"id", "group_id", "current_shift_id", "target_shift_id", "current_user_id", "target_user_id",
+	"message", "status", "requested_by", "decided_by", "decided_at", "created_at"}
+
+func expectSwapShift(mock sqlmock.Sqlmock, id, userID string, active bool) {
+	now := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM shifts os`).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(swapShiftColumns).
+			AddRow(id, "grp-1", userID, "custom", now, now.Add(8*time.Hour), active, false, 0, now, now, "", "", "", ""))
+}
+
+func TestSwapSchedulesRequestsSwapForMembers(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	expectSwapShift(mock, "sh-1", "user-1", true)
+	expectSwapShift(mock, "sh-2", "user-2", true)
+	mock.ExpectQuery(`FROM shifts s`).WithArgs("user-1", `{"sh-1","sh-2"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO shift_swap_requests`).
+		WithArgs("grp-1", "sh-1", "sh-2", "user-1", "user-2", "vacation", "user-1").
+		WillReturnRows(sqlmock.NewRows(swapRequestColumns).
+			AddRow("swap-1", "grp-1", "sh-1", "sh-2", "user-1", "user-2", "vacation", db.SwapRequestPending, "user-1", "", nil, time.Now()))
+
+	service := &OnCallService{PG: pg}
+	response, err := service.SwapSchedules(db.ShiftSwapRequest{
+		CurrentScheduleID: "sh-1", TargetScheduleID: "sh-2", SwapMessage: "vacation", SwapType: db.SwapTypeInstant,
+	}, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.SwapRequest == nil || response.SwapRequest.ID != "swap-1" || response.SwapRequest.Status != db.SwapRequestPending {
+		t.Errorf("swap request = %+v", response.SwapRequest)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// Members can't touch other people's shifts at all
+	expectSwapShift(mock, "sh-2", "user-2", true)
+	expectSwapShift(mock, "sh-1", "user-1", true)
+	mock.ExpectQuery(`FROM shifts s`).WithArgs("user-1", `{"sh-2","sh-1"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	_, err = service.SwapSchedules(db.ShiftSwapRequest{CurrentScheduleID: "sh-2", TargetScheduleID: "sh-1"}, "user-1")
+	if !errors.Is(err, ErrSchedulerForbidden) {
+		t.Errorf("err = %v, want ErrSchedulerForbidden", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApproveSwapRequestRejectsStaleShifts(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM shift_swap_requests`).WithArgs("swap-1", "grp-1").
+		WillReturnRows(sqlmock.NewRows(swapRequestColumns).
+			AddRow("swap-1", "grp-1", "sh-1", "sh-2", "user-1", "user-2", "", db.SwapRequestPending, "user-1", "", nil, time.Now()))
+	mock.ExpectQuery(`FROM shifts s`).WithArgs("manager-1", `{"sh-1","sh-2"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectSwapShift(mock, "sh-1", "user-1", true)
+	// Reassigned to someone else after the request
+	expectSwapShift(mock, "sh-2", "user-3", true)
+
+	_, err = (&OnCallService{PG: pg}).ApproveSwapRequest("grp-1", "swap-1", "manager-1")
+	if !errors.Is(err, ErrSwapRequestStale) {
+		t.Errorf("err = %v, want ErrSwapRequestStale", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,635 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/encryption"
+)
+
+var ErrInvalidSIEMConfig = errors.New("invalid SIEM config")
+
+// siemExportLag keeps the export this far behind the newest events, so rows
+// committed a little out of order aren't skipped by the cursor
+const siemExportLag = 5 * time.Second
+
+// SIEMExportService streams incident lifecycle and audit events to the SIEMs
+// configured through "siem" integrations, each integration picking up where
+// its cursor in siem_export_cursors left off
+type SIEMExportService struct {
+	PG     *sql.DB
+	client *http.Client
+}
+
+func NewSIEMExportService(pg *sql.DB) *SIEMExportService {
+	return &SIEMExportService{
+		PG:     pg,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// siemConfig is the parsed config of a SIEM integration
+type siemConfig struct {
+	Provider   string
+	URL        string
+	Token      string
+	Index      string
+	Format     string
+	EventTypes []string
+}
+
+// parseSIEMConfig reads and checks a SIEM integration's config
+func parseSIEMConfig(config map[string]interface{}) (siemConfig, error) {
+	cfg := siemConfig{
+		Provider:   strings.TrimSpace(configString(config, "provider")),
+		URL:        strings.TrimRight(strings.TrimSpace(configString(config, "url")), "/"),
+		Token:      configString(config, "token"),
+		Index:      strings.TrimSpace(configString(config, "index")),
+		Format:     strings.ToLower(strings.TrimSpace(configString(config, "format"))),
+		EventTypes: configStrings(config, "event_types"),
+	}
+	if cfg.Format == "" {
+		cfg.Format = db.SIEMFormatJSON
+	}
+	if cfg.Format != db.SIEMFormatJSON && cfg.Format != db.SIEMFormatCEF {
+		return cfg, fmt.Errorf("%w: format must be %s or %s", ErrInvalidSIEMConfig, db.SIEMFormatJSON, db.SIEMFormatCEF)
+	}
+
+	u, err := url.Parse(cfg.URL)
+	switch cfg.Provider {
+	case db.SIEMProviderSplunkHEC, db.SIEMProviderElastic:
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return cfg, fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidSIEMConfig)
+		}
+		if cfg.Provider == db.SIEMProviderSplunkHEC && cfg.Token == "" {
+			return cfg, fmt.Errorf("%w: token is required for Splunk HEC", ErrInvalidSIEMConfig)
+		}
+		if cfg.Provider == db.SIEMProviderElastic && cfg.Index == "" {
+			cfg.Index = db.SIEMDefaultElasticIndex
+		}
+	case db.SIEMProviderSyslog:
+		if err != nil || (u.Scheme != "tcp" && u.Scheme != "udp") || u.Port() == "" {
+			return cfg, fmt.Errorf("%w: url must be tcp://host:port or udp://host:port", ErrInvalidSIEMConfig)
+		}
+	default:
+		return cfg, fmt.Errorf("%w: provider must be %s, %s or %s", ErrInvalidSIEMConfig,
+			db.SIEMProviderSplunkHEC, db.SIEMProviderElastic, db.SIEMProviderSyslog)
+	}
+	return cfg, nil
+}
+
+// wants reports whether an event type passes the event type filter
+func (c siemConfig) wants(eventType string) bool {
+	return len(c.EventTypes) == 0 || containsString(c.EventTypes, strings.ToLower(eventType))
+}
+
+// prepareSIEMConfig validates a SIEM integration's config and seals the token
+// before it is stored
+func prepareSIEMConfig(config map[string]interface{}) error {
+	if _, err := parseSIEMConfig(config); err != nil {
+		return err
+	}
+	token := configString(config, "token")
+	if token == "" || encryption.IsEncrypted(token) {
+		return nil
+	}
+	sealed, err := encryptColumn(token)
+	if err != nil {
+		return err
+	}
+	config["token"] = sealed
+	return nil
+}
+
+// siemTarget is an active SIEM integration and its export position
+type siemTarget struct {
+	integrationID   string
+	orgID           string
+	cfg             siemConfig
+	incidentEventAt time.Time
+	incidentEventID string
+	auditEventAt    time.Time
+	auditEventID    string
+}
+
+// Export runs one export pass over every active SIEM integration, returning
+// how many events were sent. Integrations seen for the first time start from
+// now; one failing integration doesn't hold up the others.
+func (s *SIEMExportService) Export() (int, error) {
+	rows, err := s.PG.Query(`
+		SELECT g.id, g.organization_id::text, g.config,
+		       c.incident_event_at, c.incident_event_id::text, c.audit_event_at, c.audit_event_id::text
+		FROM integrations g
+		LEFT JOIN siem_export_cursors c ON c.integration_id = g.id
+		WHERE g.type = $1 AND g.is_active = true AND g.organization_id IS NOT NULL
+	`, db.SIEMIntegrationType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list SIEM integrations: %w", err)
+	}
+	var targets []siemTarget
+	var newIntegrations []string
+	for rows.Next() {
+		var t siemTarget
+		var configJSON []byte
+		var incidentAt, auditAt sql.NullTime
+		var incidentID, auditID sql.NullString
+		if err := rows.Scan(&t.integrationID, &t.orgID, &configJSON, &incidentAt, &incidentID, &auditAt, &auditID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan SIEM integration: %w", err)
+		}
+		if !incidentAt.Valid {
+			newIntegrations = append(newIntegrations, t.integrationID)
+			continue
+		}
+		var config map[string]interface{}
+		if err := json.Unmarshal(configJSON, &config); err != nil {
+			log.Printf("⚠️  Skipping SIEM integration %s: invalid config: %v", t.integrationID, err)
+			continue
+		}
+		cfg, err := parseSIEMConfig(config)
+		if err != nil {
+			log.Printf("⚠️  Skipping SIEM integration %s: %v", t.integrationID, err)
+			continue
+		}
+		decryptColumns(&cfg.Token)
+		t.cfg = cfg
+		t.incidentEventAt, t.incidentEventID = incidentAt.Time, incidentID.String
+		t.auditEventAt, t.auditEventID = auditAt.Time, auditID.String
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list SIEM integrations: %w", err)
+	}
+
+	for _, id := range newIntegrations {
+		if _, err := s.PG.Exec(`
+			INSERT INTO siem_export_cursors (integration_id, incident_event_at, audit_event_at)
+			VALUES ($1, LOCALTIMESTAMP, NOW())
+			ON CONFLICT (integration_id) DO NOTHING
+		`, id); err != nil {
+			return 0, fmt.Errorf("failed to start SIEM export cursor: %w", err)
+		}
+	}
+
+	sent := 0
+	var errs []error
+	for _, t := range targets {
+		n, err := s.exportIntegration(t)
+		sent += n
+		if err != nil {
+			log.Printf("⚠️  SIEM export for integration %s failed: %v", t.integrationID, err)
+			if _, dbErr := s.PG.Exec(`
+				UPDATE siem_export_cursors SET last_error = $2, last_error_at = NOW(), updated_at = NOW()
+				WHERE integration_id = $1
+			`, t.integrationID, truncateRunes(err.Error(), 1000)); dbErr != nil {
+				log.Printf("⚠️  Failed to record SIEM export error: %v", dbErr)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// exportIntegration sends the events after the integration's cursor and
+// moves the cursor past them. Events filtered out still move it; on a send
+// error it stays put so the same events are retried next pass.
+func (s *SIEMExportService) exportIntegration(t siemTarget) (int, error) {
+	incidentEvents, err := s.incidentEventsAfter(t.orgID, t.incidentEventAt, t.incidentEventID)
+	if err != nil {
+		return 0, err
+	}
+	auditEvents, err := s.auditEventsAfter(t.orgID, t.auditEventAt, t.auditEventID)
+	if err != nil {
+		return 0, err
+	}
+	if len(incidentEvents) == 0 && len(auditEvents) == 0 {
+		return 0, nil
+	}
+
+	var events []db.SIEMEvent
+	for _, e := range append(append([]db.SIEMEvent{}, incidentEvents...), auditEvents...) {
+		if t.cfg.wants(e.Type) {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	if len(events) > 0 {
+		if err := s.send(t.cfg, events); err != nil {
+			return 0, err
+		}
+	}
+
+	if n := len(incidentEvents); n > 0 {
+		t.incidentEventAt, t.incidentEventID = incidentEvents[n-1].Time, incidentEvents[n-1].ID
+	}
+	if n := len(auditEvents); n > 0 {
+		t.auditEventAt, t.auditEventID = auditEvents[n-1].Time, auditEvents[n-1].ID
+	}
+	if _, err := s.PG.Exec(`
+		UPDATE siem_export_cursors
+		SET incident_event_at = $2, incident_event_id = $3, audit_event_at = $4, audit_event_id = $5,
+		    exported_count = exported_count + $6,
+		    last_exported_at = CASE WHEN $6 > 0 THEN NOW() ELSE last_exported_at END,
+		    last_error = NULL, updated_at = NOW()
+		WHERE integration_id = $1
+	`, t.integrationID, t.incidentEventAt, t.incidentEventID, t.auditEventAt, t.auditEventID, len(events)); err != nil {
+		return len(events), fmt.Errorf("failed to move SIEM export cursor: %w", err)
+	}
+	return len(events), nil
+}
+
+// incidentEventsAfter reads the organization's incident events after a
+// (created_at, id) position, oldest first. Test incidents are left out.
+func (s *SIEMExportService) incidentEventsAfter(orgID string, at time.Time, id string) ([]db.SIEMEvent, error) {
+	rows, err := s.PG.Query(`
+		SELECT ie.id, ie.event_type, ie.event_data, ie.created_at,
+		       COALESCE(ie.created_by::text, ''), COALESCE(u.name, u.email, ''),
+		       i.id, COALESCE(i.number, 0), i.title, i.status, COALESCE(i.severity, '')
+		FROM incident_events ie
+		JOIN incidents i ON i.id = ie.incident_id
+		LEFT JOIN users u ON u.id = ie.created_by
+		WHERE i.organization_id::text = $1 AND NOT COALESCE(i.is_test, false)
+		  AND (ie.created_at, ie.id) > ($2, $3::uuid)
+		  AND ie.created_at < LOCALTIMESTAMP - $4 * INTERVAL '1 second'
+		ORDER BY ie.created_at, ie.id
+		LIMIT $5
+	`, orgID, at, id, int(siemExportLag.Seconds()), db.SIEMExportBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read incident events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []db.SIEMEvent{}
+	for rows.Next() {
+		e := db.SIEMEvent{Category: db.SIEMCategoryIncident, OrganizationID: orgID}
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &data, &e.Time, &e.ActorID, &e.ActorName,
+			&e.IncidentID, &e.IncidentNumber, &e.IncidentTitle, &e.IncidentStatus, &e.Severity); err != nil {
+			return nil, fmt.Errorf("failed to scan incident event: %w", err)
+		}
+		if len(data) > 0 {
+			_ = json.Unmarshal(data, &e.Data)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// auditEventsAfter reads the organization's audit log after an
+// (event_time, id) position, oldest first
+func (s *SIEMExportService) auditEventsAfter(orgID string, at time.Time, id string) ([]db.SIEMEvent, error) {
+	rows, err := s.PG.Query(`
+		SELECT a.id, a.event_type, a.event_time, a.user_id::text, COALESCE(a.user_email, ''),
+		       COALESCE(host(a.source_ip), ''), a.status, a.event_category, a.action,
+		       COALESCE(a.resource_type, ''), COALESCE(a.resource_id, ''),
+		       COALESCE(a.error_code, ''), COALESCE(a.error_message, '')
+		FROM agent_audit_logs a
+		WHERE a.org_id::text = $1
+		  AND (a.event_time, a.id) > ($2, $3::uuid)
+		  AND a.event_time < NOW() - $4 * INTERVAL '1 second'
+		ORDER BY a.event_time, a.id
+		LIMIT $5
+	`, orgID, at, id, int(siemExportLag.Seconds()), db.SIEMExportBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []db.SIEMEvent{}
+	for rows.Next() {
+		e := db.SIEMEvent{Category: db.SIEMCategoryAudit, OrganizationID: orgID}
+		var category, action, resourceType, resourceID, errorCode, errorMessage string
+		if err := rows.Scan(&e.ID, &e.Type, &e.Time, &e.ActorID, &e.ActorName, &e.SourceIP, &e.Outcome,
+			&category, &action, &resourceType, &resourceID, &errorCode, &errorMessage); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		e.Data = map[string]interface{}{"category": category, "action": action}
+		for key, value := range map[string]string{
+			"resource_type": resourceType, "resource_id": resourceID,
+			"error_code": errorCode, "error_message": errorMessage,
+		} {
+			if value != "" {
+				e.Data[key] = value
+			}
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// send delivers a batch of events to the integration's SIEM
+func (s *SIEMExportService) send(cfg siemConfig, events []db.SIEMEvent) error {
+	switch cfg.Provider {
+	case db.SIEMProviderSplunkHEC:
+		body, err := encodeSplunkHEC(cfg, events)
+		if err != nil {
+			return err
+		}
+		endpoint := cfg.URL
+		if !strings.Contains(endpoint, "/services/collector") {
+			endpoint += "/services/collector/event"
+		}
+		_, err = s.post(endpoint, "application/json", "Splunk "+cfg.Token, body)
+		return err
+	case db.SIEMProviderElastic:
+		body, err := encodeElasticBulk(cfg, events)
+		if err != nil {
+			return err
+		}
+		auth := ""
+		if cfg.Token != "" {
+			auth = "ApiKey " + cfg.Token
+		}
+		respBody, err := s.post(cfg.URL+"/_bulk", "application/x-ndjson", auth, body)
+		if err != nil {
+			return err
+		}
+		return elasticBulkError(respBody)
+	case db.SIEMProviderSyslog:
+		return sendSyslog(cfg, events)
+	}
+	return ErrInvalidSIEMConfig
+}
+
+func (s *SIEMExportService) post(endpoint, contentType, authorization string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "SLAR-SIEM-Export/1.0")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SIEM unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("SIEM returned %d: %s", resp.StatusCode, truncateRunes(strings.TrimSpace(string(respBody)), 512))
+	}
+	return respBody, nil
+}
+
+// encodeSplunkHEC builds a HEC batch: one event object per event, back to back
+func encodeSplunkHEC(cfg siemConfig, events []db.SIEMEvent) ([]byte, error) {
+	type hecEvent struct {
+		Time       float64     `json:"time"`
+		Source     string      `json:"source"`
+		Sourcetype string      `json:"sourcetype"`
+		Index      string      `json:"index,omitempty"`
+		Event      interface{} `json:"event"`
+	}
+	var buf bytes.Buffer
+	for _, e := range events {
+		he := hecEvent{
+			Time:       float64(e.Time.UnixMilli()) / 1000,
+			Source:     "slar",
+			Sourcetype: "slar:" + cfg.Format,
+			Index:      cfg.Index,
+			Event:      e,
+		}
+		if cfg.Format == db.SIEMFormatCEF {
+			he.Event = formatCEF(e)
+		}
+		b, err := json.Marshal(he)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeElasticBulk builds a _bulk request creating one document per event.
+// Documents are keyed by event ID, so a batch that is retried after a partial
+// failure doesn't index events twice.
+func encodeElasticBulk(cfg siemConfig, events []db.SIEMEvent) ([]byte, error) {
+	type action struct {
+		Create struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		} `json:"create"`
+	}
+	type jsonDoc struct {
+		Timestamp time.Time `json:"@timestamp"`
+		db.SIEMEvent
+	}
+	type cefDoc struct {
+		Timestamp time.Time `json:"@timestamp"`
+		Message   string    `json:"message"`
+	}
+
+	var buf bytes.Buffer
+	for _, e := range events {
+		var a action
+		a.Create.Index, a.Create.ID = cfg.Index, e.ID
+		var doc interface{} = jsonDoc{Timestamp: e.Time, SIEMEvent: e}
+		if cfg.Format == db.SIEMFormatCEF {
+			doc = cefDoc{Timestamp: e.Time, Message: formatCEF(e)}
+		}
+		for _, v := range []interface{}{a, doc} {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// elasticBulkError turns the item failures of a _bulk response into an error.
+// Conflicts mean the document was already indexed by an earlier attempt.
+func elasticBulkError(respBody []byte) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("invalid response from Elasticsearch: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	failed := 0
+	var first string
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Status >= 300 && result.Status != http.StatusConflict {
+				if failed == 0 {
+					first = result.Error.Type + ": " + result.Error.Reason
+				}
+				failed++
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("Elasticsearch rejected %d events (%s)", failed, first)
+}
+
+// sendSyslog sends one RFC 5424 message per event; over TCP messages are
+// framed by octet counting (RFC 6587)
+func sendSyslog(cfg siemConfig, events []db.SIEMEvent) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout(u.Scheme, u.Host, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("syslog collector unreachable: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	hostname, _ := os.Hostname()
+	for _, e := range events {
+		msg := formatSyslog(cfg, e, hostname)
+		if u.Scheme == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return fmt.Errorf("failed to write to syslog collector: %w", err)
+		}
+	}
+	return nil
+}
+
+// syslogFacility is log audit (13)
+const syslogFacility = 13
+
+// formatSyslog renders an event as an RFC 5424 message whose body is the
+// event in the configured format
+func formatSyslog(cfg siemConfig, e db.SIEMEvent, hostname string) string {
+	if hostname == "" {
+		hostname = "-"
+	}
+	body := formatCEF(e)
+	if cfg.Format == db.SIEMFormatJSON {
+		b, _ := json.Marshal(e)
+		body = string(b)
+	}
+	msgID := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, truncateRunes(e.Type, 32))
+	return fmt.Sprintf("<%d>1 %s %s slar - %s - %s",
+		syslogFacility*8+syslogSeverity(cefSeverity(e)), e.Time.UTC().Format(time.RFC3339Nano), hostname, msgID, body)
+}
+
+// syslogSeverity maps a CEF severity onto syslog's
+func syslogSeverity(cef int) int {
+	switch {
+	case cef >= 9:
+		return 2 // critical
+	case cef >= 7:
+		return 3 // error
+	case cef >= 5:
+		return 4 // warning
+	default:
+		return 6 // informational
+	}
+}
+
+// cefSeverity rates an event 0-10: incident events by the incident's
+// severity, audit events by their outcome
+func cefSeverity(e db.SIEMEvent) int {
+	if e.Category == db.SIEMCategoryAudit {
+		if e.Outcome == "failure" {
+			return 7
+		}
+		return 3
+	}
+	switch strings.ToLower(e.Severity) {
+	case "critical":
+		return 10
+	case "high", "error":
+		return 8
+	case "low", "info":
+		return 3
+	default:
+		return 5
+	}
+}
+
+// formatCEF renders an event in ArcSight Common Event Format
+func formatCEF(e db.SIEMEvent) string {
+	ext := []string{
+		"rt=" + strconv.FormatInt(e.Time.UnixMilli(), 10),
+		"cat=" + e.Category,
+		"externalId=" + cefEscapeExtension(e.ID),
+	}
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+cefEscapeExtension(value))
+		}
+	}
+	add("suid", e.ActorID)
+	add("suser", e.ActorName)
+	add("src", e.SourceIP)
+	add("outcome", e.Outcome)
+	add("cs1Label", "organizationId")
+	add("cs1", e.OrganizationID)
+	if e.IncidentID != "" {
+		add("cs2Label", "incidentId")
+		add("cs2", e.IncidentID)
+		add("cs3Label", "incidentTitle")
+		add("cs3", e.IncidentTitle)
+		add("cs4Label", "incidentStatus")
+		add("cs4", e.IncidentStatus)
+		if e.IncidentNumber > 0 {
+			add("cn1Label", "incidentNumber")
+			add("cn1", strconv.FormatInt(e.IncidentNumber, 10))
+		}
+	}
+	if len(e.Data) > 0 {
+		if b, err := json.Marshal(e.Data); err == nil {
+			add("msg", string(b))
+		}
+	}
+
+	name := "Incident " + strings.ReplaceAll(e.Type, "_", " ")
+	if e.Category == db.SIEMCategoryAudit {
+		name = "Audit " + e.Type
+	}
+	return fmt.Sprintf("CEF:0|SLAR|SLAR|1.0|%s|%s|%d|%s",
+		cefEscapeHeader(e.Type), cefEscapeHeader(name), cefSeverity(e), strings.Join(ext, " "))
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\r", `\r`, "\n", `\n`)
+)
+
+func cefEscapeHeader(s string) string    { return cefHeaderEscaper.Replace(s) }
+func cefEscapeExtension(s string) string { return cefExtensionEscaper.Replace(s) }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseSIEMConfig(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"provider": "arcsight", "url": "https://siem.example.com"},
+		{"provider": db.SIEMProviderSplunkHEC, "url": "https://splunk.example.com:8088"},
+		{"provider": db.SIEMProviderElastic, "url": "ftp://es"},
+		{"provider": db.SIEMProviderSyslog, "url": "https://syslog.example.com"},
+		{"provider": db.SIEMProviderSyslog, "url": "udp://syslog.example.com:514", "format": "leef"},
+	} {
+		if _, err := parseSIEMConfig(config); !errors.Is(err, ErrInvalidSIEMConfig) {
+			t.Errorf("%v: expected ErrInvalidSIEMConfig, got %v", config, err)
+		}
+	}
+
+	cfg, err := parseSIEMConfig(map[string]interface{}{
+		"provider":    db.SIEMProviderElastic,
+		"url":         "https://es.example.com/",
+		"event_types": []interface{}{"Acknowledged", "tool.executed"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.URL != "https://es.example.com" || cfg.Index != db.SIEMDefaultElasticIndex || cfg.Format != db.SIEMFormatJSON {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if !cfg.wants("acknowledged") || !cfg.wants("tool.executed") || cfg.wants("resolved") {
+		t.Error("event type filter not applied")
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	e := db.SIEMEvent{
+		ID:             "ev-1",
+		Category:       db.SIEMCategoryIncident,
+		Type:           "acknowledged",
+		Time:           time.UnixMilli(1767225600000),
+		OrganizationID: "org-1",
+		IncidentID:     "inc-1",
+		IncidentNumber: 42,
+		IncidentTitle:  "DB a=b | down\nagain",
+		Severity:       "critical",
+		ActorName:      `Dana\Ops`,
+	}
+	got := formatCEF(e)
+	if !strings.HasPrefix(got, "CEF:0|SLAR|SLAR|1.0|acknowledged|Incident acknowledged|10|rt=1767225600000 cat=incident externalId=ev-1 ") {
+		t.Errorf("header = %q", got)
+	}
+	for _, want := range []string{`suser=Dana\\Ops`, `cs3=DB a\=b | down\nagain`, "cn1=42"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q missing %q", got, want)
+		}
+	}
+
+	audit := formatCEF(db.SIEMEvent{ID: "a-1", Category: db.SIEMCategoryAudit, Type: "auth|failed", Outcome: "failure", Time: e.Time})
+	if !strings.HasPrefix(audit, `CEF:0|SLAR|SLAR|1.0|auth\|failed|Audit auth\|failed|7|`) {
+		t.Errorf("audit = %q", audit)
+	}
+}
+
+func TestSIEMSendSplunkHEC(t *testing.T) {
+	var lines []string
+	splunk := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk hec-token" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var body strings.Builder
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Body.Read(buf)
+			body.Write(buf[:n])
+			if err != nil {
+				break
+			}
+		}
+		lines = strings.Split(strings.TrimSpace(body.String()), "\n")
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer splunk.Close()
+
+	s := &SIEMExportService{client: &http.Client{Timeout: 5 * time.Second}}
+	cfg := siemConfig{Provider: db.SIEMProviderSplunkHEC, URL: splunk.URL, Token: "hec-token", Index: "security", Format: db.SIEMFormatJSON}
+	events := []db.SIEMEvent{
+		{ID: "ev-1", Category: db.SIEMCategoryIncident, Type: "triggered", Time: time.Now()},
+		{ID: "ev-2", Category: db.SIEMCategoryAudit, Type: "tool.executed", Time: time.Now()},
+	}
+	if err := s.send(cfg, events); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d HEC events, want 2", len(lines))
+	}
+	var first struct {
+		Index      string       `json:"index"`
+		Sourcetype string       `json:"sourcetype"`
+		Event      db.SIEMEvent `json:"event"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Index != "security" || first.Sourcetype != "slar:json" || first.Event.ID != "ev-1" {
+		t.Errorf("first HEC event = %+v", first)
+	}
+}
+
+func TestElasticBulkError(t *testing.T) {
+	if err := elasticBulkError([]byte(`{"errors":false,"items":[]}`)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	// Already indexed by an earlier attempt
+	if err := elasticBulkError([]byte(`{"errors":true,"items":[{"create":{"status":409}}]}`)); err != nil {
+		t.Errorf("conflict treated as failure: %v", err)
+	}
+	err := elasticBulkError([]byte(`{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}]}`))
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestSIEMExportStartsNewIntegrationsFromNow(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM integrations g`).WithArgs(db.SIEMIntegrationType).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "config",
+			"incident_event_at", "incident_event_id", "audit_event_at", "audit_event_id"}).
+			AddRow("integ-1", "org-1", []byte(`{"provider":"syslog","url":"udp://127.0.0.1:514"}`), nil, nil, nil, nil))
+	mock.ExpectExec(`INSERT INTO siem_export_cursors`).WithArgs("integ-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sent, err := NewSIEMExportService(pg).Export()
+	if err != nil || sent != 0 {
+		t.Errorf("sent = %d, err = %v", sent, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/faults"
 )
 
 type SlackService struct {
@@ -35,6 +36,7 @@ type SlackAttachment struct {
 	Title     string       `json:"title,omitempty"`
 	Text      string       `json:"text,omitempty"`
 	Fields    []SlackField `json:"fields,omitempty"`
+	ImageURL  string       `json:"image_url,omitempty"`
 	Footer    string       `json:"footer,omitempty"`
 	Timestamp int64        `json:"ts,omitempty"`
 }
@@ -200,10 +202,11 @@ func (s *SlackService) createIncidentSlackMessage(incident *db.Incident, user *d
 			},
 			{
 				Title: "Created At",
-				Value: incident.CreatedAt.Format("2006-01-02 15:04:05 UTC"),
+				Value: FormatOrgTime(orgDisplaySettingsOrDefault(s.PG, incident.OrganizationID), incident.CreatedAt),
 				Short: true,
 			},
 		},
+		ImageURL:  latestIncidentSnapshotURL(s.PG, incident.ID), // chart an integration attached, if any
 		Footer:    "SLAR Incident Management",
 		Timestamp: incident.CreatedAt.Unix(),
 	}
@@ -220,6 +223,9 @@ func (s *SlackService) createIncidentSlackMessage(incident *db.Incident, user *d
 // sendSlackMessage sends message to Slack using chat.postMessage API
 func (s *SlackService) sendSlackMessage(channel string, message SlackMessage) (*SlackResponse, error) {
 	message.Channel = channel
+	if err := faults.ProviderOutage("slack"); err != nil {
+		return nil, err
+	}
 
 	jsonData, err := json.Marshal(message)
 	if err != nil {
@@ -325,7 +331,8 @@ func (s *SlackService) getIncidentDetails(incidentID string) (*db.Incident, erro
 	var incident db.Incident
 
 	query := `
-		SELECT id, title, description, status, urgency, severity, source, created_at, updated_at
+		SELECT id, title, description, status, urgency, severity, source, created_at, updated_at,
+		       COALESCE(organization_id::text, '')
 		FROM incidents
 		WHERE id = $1
 	`
@@ -340,6 +347,7 @@ func (s *SlackService) getIncidentDetails(incidentID string) (*db.Incident, erro
 		&incident.Source,
 		&incident.CreatedAt,
 		&incident.UpdatedAt,
+		&incident.OrganizationID,
 	)
 
 	if err != nil {
@@ -376,6 +384,7 @@ func (s *SlackService) getUserDetails(userID string) (*db.User, error) {
 	}
 
 	user.Phone = phone.String
+	decryptColumns(&user.Phone)
 
 	return &user, nil
 }
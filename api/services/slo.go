@@ -0,0 +1,427 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrSLONotFound        = errors.New("slo not found")
+	ErrSLOMonitorRequired = errors.New("monitor_id is required for monitor-backed SLOs")
+	ErrSLOMonitorNotFound = errors.New("monitor not found in the service's organization")
+)
+
+// sloMonitorInServiceOrg holds when the monitor ($1) belongs to a group in the
+// same organization as the service ($2); monitors are scoped through their
+// deployment's group
+const sloMonitorInServiceOrg = `EXISTS (
+	SELECT 1 FROM monitors m
+	JOIN monitor_deployments d ON m.deployment_id = d.id
+	JOIN groups g ON d.group_id = g.id
+	JOIN services sv ON sv.id = $2
+	WHERE m.id = $1 AND g.organization_id = sv.organization_id
+)`
+
+const sloColumns = `
+	slo.id, slo.service_id, slo.name, slo.target, slo.window_days, slo.source,
+	COALESCE(slo.monitor_id::text, ''), slo.burn_rate_threshold, slo.is_active,
+	COALESCE(slo.created_by, ''), slo.created_at, slo.updated_at
+`
+
+func scanSLO(row interface{ Scan(...interface{}) error }, extra ...interface{}) (db.ServiceSLO, error) {
+	var slo db.ServiceSLO
+	var burnRateThreshold sql.NullFloat64
+	dest := append([]interface{}{&slo.ID, &slo.ServiceID, &slo.Name, &slo.Target, &slo.WindowDays, &slo.Source,
+		&slo.MonitorID, &burnRateThreshold, &slo.IsActive, &slo.CreatedBy, &slo.CreatedAt, &slo.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return slo, err
+	}
+	if burnRateThreshold.Valid {
+		slo.BurnRateThreshold = &burnRateThreshold.Float64
+	}
+	return slo, nil
+}
+
+// ListSLOs returns a service's SLOs with their current error budget status
+func (s *ServiceService) ListSLOs(serviceID string) ([]db.ServiceSLO, error) {
+	rows, err := s.PG.Query(`SELECT `+sloColumns+` FROM service_slos slo WHERE slo.service_id = $1 ORDER BY slo.created_at ASC`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SLOs: %w", err)
+	}
+	slos := []db.ServiceSLO{}
+	for rows.Next() {
+		slo, err := scanSLO(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan SLO: %w", err)
+		}
+		slos = append(slos, slo)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range slos {
+		status, err := computeSLOStatus(s.PG, slos[i], now)
+		if err != nil {
+			log.Printf("Failed to compute status for SLO %s: %v", slos[i].ID, err)
+			continue
+		}
+		slos[i].Status = &status
+	}
+	return slos, nil
+}
+
+// GetSLO returns one SLO with its current error budget status
+func (s *ServiceService) GetSLO(serviceID, sloID string) (*db.ServiceSLO, error) {
+	slo, err := scanSLO(s.PG.QueryRow(`SELECT `+sloColumns+` FROM service_slos slo WHERE slo.id = $1 AND slo.service_id = $2`, sloID, serviceID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSLONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SLO: %w", err)
+	}
+
+	status, err := computeSLOStatus(s.PG, slo, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	slo.Status = &status
+	return &slo, nil
+}
+
+// CreateSLO adds an SLO to a service
+func (s *ServiceService) CreateSLO(serviceID string, req db.CreateServiceSLORequest, createdBy string) (*db.ServiceSLO, error) {
+	if req.WindowDays == 0 {
+		req.WindowDays = 30
+	}
+	if req.Source == "" {
+		req.Source = db.SLOSourceIncidents
+	}
+	if req.Source == db.SLOSourceMonitor && req.MonitorID == "" {
+		return nil, ErrSLOMonitorRequired
+	}
+	if req.Source != db.SLOSourceMonitor {
+		req.MonitorID = ""
+	} else {
+		var inOrg bool
+		if err := s.PG.QueryRow(`SELECT `+sloMonitorInServiceOrg, req.MonitorID, serviceID).Scan(&inOrg); err != nil {
+			return nil, fmt.Errorf("failed to check SLO monitor: %w", err)
+		}
+		if !inOrg {
+			return nil, ErrSLOMonitorNotFound
+		}
+	}
+
+	slo, err := scanSLO(s.PG.QueryRow(`
+		INSERT INTO service_slos AS slo (service_id, name, target, window_days, source, monitor_id,
+		                                 burn_rate_threshold, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+sloColumns,
+		serviceID, req.Name, req.Target, req.WindowDays, req.Source, nullIfEmpty(req.MonitorID),
+		positiveOrNil(req.BurnRateThreshold), nullIfEmpty(createdBy)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SLO: %w", err)
+	}
+	return &slo, nil
+}
+
+// UpdateSLO changes an SLO's target, window, burn-rate threshold or active flag
+func (s *ServiceService) UpdateSLO(serviceID, sloID string, req db.UpdateServiceSLORequest) (*db.ServiceSLO, error) {
+	query := "UPDATE service_slos slo SET updated_at = NOW()"
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		query += fmt.Sprintf(", name = $%d", argIndex)
+		args = append(args, *req.Name)
+		argIndex++
+	}
+	if req.Target != nil {
+		query += fmt.Sprintf(", target = $%d", argIndex)
+		args = append(args, *req.Target)
+		argIndex++
+	}
+	if req.WindowDays != nil {
+		query += fmt.Sprintf(", window_days = $%d", argIndex)
+		args = append(args, *req.WindowDays)
+		argIndex++
+	}
+	if req.BurnRateThreshold != nil {
+		query += fmt.Sprintf(", burn_rate_threshold = $%d", argIndex)
+		args = append(args, positiveOrNil(req.BurnRateThreshold))
+		argIndex++
+	}
+	if req.IsActive != nil {
+		query += fmt.Sprintf(", is_active = $%d", argIndex)
+		args = append(args, *req.IsActive)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" WHERE slo.id = $%d AND slo.service_id = $%d RETURNING ", argIndex, argIndex+1) + sloColumns
+	args = append(args, sloID, serviceID)
+
+	slo, err := scanSLO(s.PG.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrSLONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update SLO: %w", err)
+	}
+	return &slo, nil
+}
+
+// DeleteSLO removes an SLO and its monitor check history
+func (s *ServiceService) DeleteSLO(serviceID, sloID string) error {
+	result, err := s.PG.Exec(`DELETE FROM service_slos WHERE id = $1 AND service_id = $2`, sloID, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete SLO: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSLONotFound
+	}
+	return nil
+}
+
+// RecordSLOMonitorCheck counts one monitor result towards every active SLO
+// backed by that monitor
+func RecordSLOMonitorCheck(pg *sql.DB, monitorID string, isUp bool) error {
+	good := 0
+	if isUp {
+		good = 1
+	}
+	_, err := pg.Exec(`
+		INSERT INTO slo_check_buckets (slo_id, bucket_start, total_checks, good_checks)
+		SELECT id, date_trunc('hour', NOW()), 1, $2
+		FROM service_slos
+		WHERE monitor_id = $1 AND source = 'monitor' AND is_active = TRUE
+		ON CONFLICT (slo_id, bucket_start) DO UPDATE
+		SET total_checks = slo_check_buckets.total_checks + 1,
+		    good_checks = slo_check_buckets.good_checks + EXCLUDED.good_checks
+	`, monitorID, good)
+	if err != nil {
+		return fmt.Errorf("failed to record SLO monitor check: %w", err)
+	}
+	return nil
+}
+
+// computeSLOStatus measures an SLO over its window ending at now
+func computeSLOStatus(pg *sql.DB, slo db.ServiceSLO, now time.Time) (db.SLOStatus, error) {
+	windowStart := now.AddDate(0, 0, -slo.WindowDays)
+	burnStart := now.Add(-db.SLOBurnRateWindow)
+
+	if slo.Source == db.SLOSourceMonitor {
+		// Buckets are hourly, so the burn window covers the current and previous hour
+		var total, good, burnTotal, burnGood int
+		err := pg.QueryRow(`
+			SELECT COALESCE(SUM(total_checks), 0), COALESCE(SUM(good_checks), 0),
+			       COALESCE(SUM(total_checks) FILTER (WHERE bucket_start >= date_trunc('hour', $3::timestamptz)), 0),
+			       COALESCE(SUM(good_checks) FILTER (WHERE bucket_start >= date_trunc('hour', $3::timestamptz)), 0)
+			FROM slo_check_buckets
+			WHERE slo_id = $1 AND bucket_start >= date_trunc('hour', $2::timestamptz)
+		`, slo.ID, windowStart, burnStart).Scan(&total, &good, &burnTotal, &burnGood)
+		if err != nil {
+			return db.SLOStatus{}, fmt.Errorf("failed to get SLO check counts: %w", err)
+		}
+
+		status := db.NewSLOStatus(slo.Target, windowStart, now,
+			failureRatio(total-good, total), failureRatio(burnTotal-burnGood, burnTotal))
+		status.TotalChecks = total
+		status.GoodChecks = good
+		return status, nil
+	}
+
+	// Burn-rate incidents report on the SLO; counting them as downtime would
+	// keep the budget burning after the outage that caused them is over
+	rows, err := pg.Query(`
+		SELECT created_at, resolved_at
+		FROM incidents
+		WHERE service_id = $1 AND urgency = 'high' AND NOT is_test AND drill_id IS NULL
+		  AND source IS DISTINCT FROM $4
+		  AND created_at < $3 AND (resolved_at IS NULL OR resolved_at > $2)
+	`, slo.ServiceID, windowStart, now, db.IncidentSourceSLO)
+	if err != nil {
+		return db.SLOStatus{}, fmt.Errorf("failed to get SLO incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var downtime []timeRange
+	for rows.Next() {
+		var r timeRange
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&r.start, &resolvedAt); err != nil {
+			return db.SLOStatus{}, fmt.Errorf("failed to scan SLO incident: %w", err)
+		}
+		r.end = now
+		if resolvedAt.Valid {
+			r.end = resolvedAt.Time
+		}
+		downtime = append(downtime, r)
+	}
+	if err := rows.Err(); err != nil {
+		return db.SLOStatus{}, err
+	}
+
+	windowMinutes := now.Sub(windowStart).Minutes()
+	badMinutes := coveredMinutes(downtime, windowStart, now)
+	burnBadMinutes := coveredMinutes(downtime, burnStart, now)
+
+	status := db.NewSLOStatus(slo.Target, windowStart, now,
+		badMinutes/windowMinutes, burnBadMinutes/db.SLOBurnRateWindow.Minutes())
+	status.BudgetMinutes = windowMinutes * (1 - slo.Target/100)
+	status.BadMinutes = badMinutes
+	return status, nil
+}
+
+func failureRatio(bad, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(bad) / float64(total)
+}
+
+type timeRange struct {
+	start, end time.Time
+}
+
+// coveredMinutes returns how many minutes of [from, to) are covered by at least
+// one range, so overlapping incidents are not counted twice
+func coveredMinutes(ranges []timeRange, from, to time.Time) float64 {
+	clipped := make([]timeRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.start.Before(from) {
+			r.start = from
+		}
+		if r.end.After(to) {
+			r.end = to
+		}
+		if r.end.After(r.start) {
+			clipped = append(clipped, r)
+		}
+	}
+	sort.Slice(clipped, func(i, j int) bool { return clipped[i].start.Before(clipped[j].start) })
+
+	var covered time.Duration
+	var current *timeRange
+	for i := range clipped {
+		r := clipped[i]
+		if current != nil && !r.start.After(current.end) {
+			if r.end.After(current.end) {
+				current.end = r.end
+			}
+			continue
+		}
+		if current != nil {
+			covered += current.end.Sub(current.start)
+		}
+		current = &r
+	}
+	if current != nil {
+		covered += current.end.Sub(current.start)
+	}
+	return covered.Minutes()
+}
+
+// EvaluateSLOBurnRates opens an incident on the service for every SLO whose burn
+// rate is above its threshold, unless one is already open. It returns the
+// number of incidents opened.
+func (s *IncidentService) EvaluateSLOBurnRates() (int, error) {
+	rows, err := s.PG.Query(`
+		SELECT ` + sloColumns + `, sv.name, COALESCE(sv.organization_id::text, ''), COALESCE(sv.project_id::text, ''),
+		       COALESCE(sv.group_id::text, ''), COALESCE(sv.escalation_policy_id::text, '')
+		FROM service_slos slo
+		JOIN services sv ON sv.id = slo.service_id
+		WHERE slo.is_active = TRUE AND slo.burn_rate_threshold IS NOT NULL AND sv.is_active = TRUE
+		  AND (slo.source <> 'monitor' OR EXISTS (
+		      SELECT 1 FROM monitors m
+		      JOIN monitor_deployments d ON m.deployment_id = d.id
+		      JOIN groups g ON d.group_id = g.id
+		      WHERE m.id = slo.monitor_id AND g.organization_id = sv.organization_id))
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list burn-rate SLOs: %w", err)
+	}
+
+	type burnTarget struct {
+		slo     db.ServiceSLO
+		service db.Service
+	}
+	var targets []burnTarget
+	for rows.Next() {
+		var t burnTarget
+		slo, err := scanSLO(rows, &t.service.Name, &t.service.OrganizationID, &t.service.ProjectID,
+			&t.service.GroupID, &t.service.EscalationPolicyID)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan burn-rate SLO: %w", err)
+		}
+		t.slo = slo
+		t.service.ID = slo.ServiceID
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	opened := 0
+	now := time.Now()
+	for _, t := range targets {
+		status, err := computeSLOStatus(s.PG, t.slo, now)
+		if err != nil {
+			log.Printf("Failed to compute status for SLO %s: %v", t.slo.ID, err)
+			continue
+		}
+		if status.BurnRate <= *t.slo.BurnRateThreshold {
+			continue
+		}
+
+		incidentKey := "slo-burn:" + t.slo.ID
+		var open bool
+		err = s.PG.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM incidents WHERE incident_key = $1 AND status IN ('triggered', 'acknowledged'))
+		`, incidentKey).Scan(&open)
+		if err != nil {
+			log.Printf("Failed to check open burn-rate incident for SLO %s: %v", t.slo.ID, err)
+			continue
+		}
+		if open {
+			continue
+		}
+
+		incident := &db.Incident{
+			Title: fmt.Sprintf("SLO %q burning error budget at %.1fx", t.slo.Name, status.BurnRate),
+			Description: fmt.Sprintf("Service %s: SLO %q (%g%% over %d days) burned its error budget at %.1fx over the last hour (threshold %.1fx). SLI %.3f%%, %.1f%% of the budget remaining.",
+				t.service.Name, t.slo.Name, t.slo.Target, t.slo.WindowDays, status.BurnRate, *t.slo.BurnRateThreshold,
+				status.SLI, status.BudgetRemaining*100),
+			Severity:           "critical",
+			Urgency:            db.IncidentUrgencyHigh,
+			Source:             db.IncidentSourceSLO,
+			IncidentKey:        incidentKey,
+			ServiceID:          t.service.ID,
+			OrganizationID:     t.service.OrganizationID,
+			ProjectID:          t.service.ProjectID,
+			GroupID:            t.service.GroupID,
+			EscalationPolicyID: t.service.EscalationPolicyID,
+			Labels: map[string]interface{}{
+				"slo_id":    t.slo.ID,
+				"slo_name":  t.slo.Name,
+				"burn_rate": status.BurnRate,
+			},
+		}
+		if _, err := s.CreateIncident(incident); err != nil {
+			log.Printf("Failed to open burn-rate incident for SLO %s: %v", t.slo.ID, err)
+			continue
+		}
+		opened++
+	}
+	return opened, nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestCoveredMinutesMergesOverlaps(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+
+	ranges := []timeRange{
+		{at(10), at(40)},
+		{at(30), at(50)},   // overlaps the first: 10-50
+		{at(100), at(110)}, // separate: 10 minutes
+		{at(-30), at(5)},   // clipped to 0-5
+		{at(200), at(300)}, // clipped to 200-240
+	}
+	if got := coveredMinutes(ranges, at(0), at(240)); got != 40+10+5+40 {
+		t.Errorf("coveredMinutes = %v, want 95", got)
+	}
+	if got := coveredMinutes(nil, at(0), at(240)); got != 0 {
+		t.Errorf("coveredMinutes(nil) = %v, want 0", got)
+	}
+}
+
+func TestComputeSLOStatusFromIncidents(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	slo := db.ServiceSLO{ID: "slo-1", ServiceID: "svc-1", Target: 99.9, WindowDays: 30, Source: db.SLOSourceIncidents}
+
+	// 21.6 minutes of downtime in a 30-day window is half of a 99.9% budget (43.2 minutes);
+	// the ongoing incident started 15 minutes ago and also drives the 1h burn rate
+	mock.ExpectQuery(`SELECT created_at, resolved_at\s+FROM incidents`).
+		WithArgs("svc-1", now.AddDate(0, 0, -30), now, db.IncidentSourceSLO).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "resolved_at"}).
+			AddRow(now.Add(-48*time.Hour), now.Add(-48*time.Hour+396*time.Second)).
+			AddRow(now.Add(-15*time.Minute), nil))
+
+	status, err := computeSLOStatus(pg, slo, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.BadMinutes != 21.6 {
+		t.Errorf("BadMinutes = %v, want 21.6", status.BadMinutes)
+	}
+	if status.BudgetConsumed != 0.5 || status.BudgetRemaining != 0.5 {
+		t.Errorf("budget consumed/remaining = %v/%v, want 0.5/0.5", status.BudgetConsumed, status.BudgetRemaining)
+	}
+	if status.SLI != 99.95 {
+		t.Errorf("SLI = %v, want 99.95", status.SLI)
+	}
+	// 15 bad minutes out of 60 against a 0.1% budget
+	if status.BurnRate != 250 {
+		t.Errorf("BurnRate = %v, want 250", status.BurnRate)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestComputeSLOStatusFromMonitor(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	slo := db.ServiceSLO{ID: "slo-1", ServiceID: "svc-1", Target: 99, WindowDays: 7, Source: db.SLOSourceMonitor, MonitorID: "mon-1"}
+
+	mock.ExpectQuery(`FROM slo_check_buckets`).WithArgs("slo-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "good", "burn_total", "burn_good"}).AddRow(10000, 9950, 120, 114))
+
+	status, err := computeSLOStatus(pg, slo, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SLI != 99.5 || status.BudgetConsumed != 0.5 || status.BurnRate != 5 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.TotalChecks != 10000 || status.GoodChecks != 9950 {
+		t.Errorf("check counts = %d/%d", status.GoodChecks, status.TotalChecks)
+	}
+}
+
+func TestCreateSLORejectsMonitorFromAnotherOrg(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`FROM monitors m`).WithArgs("mon-other", "svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	s := NewServiceService(pg)
+	_, err = s.CreateSLO("svc-1", db.CreateServiceSLORequest{
+		Name: "uptime", Target: 99.9, Source: db.SLOSourceMonitor, MonitorID: "mon-other",
+	}, "user-1")
+	if !errors.Is(err, ErrSLOMonitorNotFound) {
+		t.Errorf("err = %v, want ErrSLOMonitorNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var ErrInvalidTwilioSignature = errors.New("invalid Twilio webhook signature")
+
+var smsIncidentRefPattern = regexp.MustCompile(`^[0-9a-f-]+$`)
+
+const smsHelpReply = "SLAR commands: ACK <incident id> to acknowledge, RES <incident id> to resolve. " +
+	"The ID can be its first 8 characters; omit it when only one open incident is assigned to you."
+
+// SMSService links users' phone numbers and runs the incident commands they
+// text to the Twilio number (ACK/RES), for engineers without data connectivity
+type SMSService struct {
+	PG              *sql.DB
+	IncidentService *IncidentService
+}
+
+func NewSMSService(pg *sql.DB, incidentService *IncidentService) *SMSService {
+	return &SMSService{PG: pg, IncidentService: incidentService}
+}
+
+// IsConfigured reports whether a Twilio number is set up to receive commands
+func (s *SMSService) IsConfigured() bool {
+	return config.Current().Twilio.AuthToken != "" && config.Current().Twilio.PhoneNumber != ""
+}
+
+// WebhookURL is the URL Twilio posts inbound messages to, and signs
+func (s *SMSService) WebhookURL() string {
+	if config.Current().Twilio.WebhookURL != "" {
+		return config.Current().Twilio.WebhookURL
+	}
+	return config.WebhookBaseURL() + "/sms/twilio/webhook"
+}
+
+// PHONE LINKING
+
+// GetPhone returns the user's linked number, or nil if they have none
+func (s *SMSService) GetPhone(userID string) (*db.SMSPhone, error) {
+	var p db.SMSPhone
+	var verifiedAt, codeExpiresAt, lastInboundAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT user_id, phone_number, voice_enabled, verified_at, code_expires_at, last_inbound_at, updated_at
+		FROM sms_phones
+		WHERE user_id = $1
+	`, userID).Scan(&p.UserID, &p.PhoneNumber, &p.VoiceEnabled, &verifiedAt, &codeExpiresAt, &lastInboundAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SMS phone: %w", err)
+	}
+	decryptColumns(&p.PhoneNumber)
+	p.Verified = verifiedAt.Valid
+	p.VerifiedAt, p.LastInboundAt = nullTimePtr(verifiedAt), nullTimePtr(lastInboundAt)
+	if !p.Verified {
+		p.CodeExpiresAt = nullTimePtr(codeExpiresAt)
+	}
+	return &p, nil
+}
+
+// StartVerification links a number to the user, unverified until they text
+// the returned code from it. Linking a new number replaces the old one.
+func (s *SMSService) StartVerification(userID string, req db.SetSMSPhoneRequest) (*db.SMSPhoneVerification, error) {
+	phone, err := normalizeWhatsAppNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	encPhone, err := encryptColumn(phone)
+	if err != nil {
+		return nil, err
+	}
+	code, err := newSMSVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(db.SMSVerificationCodeTTL)
+	_, err = s.PG.Exec(`
+		INSERT INTO sms_phones (user_id, phone_number, phone_lookup, verification_code_hash, code_expires_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET phone_number = EXCLUDED.phone_number, phone_lookup = EXCLUDED.phone_lookup,
+		    verification_code_hash = EXCLUDED.verification_code_hash, code_expires_at = EXCLUDED.code_expires_at,
+		    verified_at = NULL, updated_at = NOW()
+	`, userID, encPhone, whatsAppPhoneLookup(phone), smsCodeHash(code), expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link SMS phone: %w", err)
+	}
+
+	p, err := s.GetPhone(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &db.SMSPhoneVerification{
+		Phone:     p,
+		Code:      code,
+		SendTo:    config.Current().Twilio.PhoneNumber,
+		Message:   "VERIFY " + code,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// RemovePhone unlinks the user's number
+func (s *SMSService) RemovePhone(userID string) error {
+	if _, err := s.PG.Exec(`DELETE FROM sms_phones WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to remove SMS phone: %w", err)
+	}
+	return nil
+}
+
+func newSMSVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func smsCodeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// INBOUND WEBHOOK
+
+// VerifyTwilioSignature checks the X-Twilio-Signature of an inbound message
+func (s *SMSService) VerifyTwilioSignature(webhookURL string, params url.Values, signature string) error {
+	return verifyTwilioSignature(webhookURL, params, signature)
+}
+
+// verifyTwilioSignature checks X-Twilio-Signature: the base64 HMAC-SHA1, keyed
+// with the auth token, of the webhook URL followed by each POST parameter's
+// name and value in name order
+func verifyTwilioSignature(webhookURL string, params url.Values, signature string) error {
+	token := config.Current().Twilio.AuthToken
+	if token == "" || signature == "" {
+		return ErrInvalidTwilioSignature
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := hmac.New(sha1.New, []byte(token))
+	mac.Write([]byte(webhookURL))
+	for _, name := range names {
+		for _, value := range params[name] {
+			mac.Write([]byte(name + value))
+		}
+	}
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidTwilioSignature
+	}
+	return nil
+}
+
+// HandleInboundMessage runs the command in a text from a phone number and
+// returns the reply to send back
+func (s *SMSService) HandleInboundMessage(from, body string) (string, error) {
+	fields := strings.Fields(strings.ToUpper(body))
+	if len(fields) == 0 {
+		return smsHelpReply, nil
+	}
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+
+	switch fields[0] {
+	case "VERIFY", "LINK":
+		return s.verifyPhone(from, arg)
+	case "ACK", "ACKNOWLEDGE":
+		return s.runIncidentCommand(from, db.IncidentStatusAcknowledged, strings.ToLower(arg))
+	case "RES", "RESOLVE":
+		return s.runIncidentCommand(from, db.IncidentStatusResolved, strings.ToLower(arg))
+	}
+	return smsHelpReply, nil
+}
+
+// verifyPhone completes linking when the code arrives from the number it was issued for
+func (s *SMSService) verifyPhone(from, code string) (string, error) {
+	lookup := whatsAppPhoneLookup(from)
+	var taken bool
+	if err := s.PG.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM sms_phones WHERE phone_lookup = $1 AND verified_at IS NOT NULL)
+	`, lookup).Scan(&taken); err != nil {
+		return "", fmt.Errorf("failed to check SMS phone: %w", err)
+	}
+	if taken {
+		return "This number is already linked to a SLAR account.", nil
+	}
+
+	var userID string
+	err := s.PG.QueryRow(`
+		UPDATE sms_phones
+		SET verified_at = NOW(), verification_code_hash = NULL, code_expires_at = NULL,
+		    last_inbound_at = NOW(), updated_at = NOW()
+		WHERE phone_lookup = $1 AND verification_code_hash = $2 AND code_expires_at > NOW()
+		  AND verified_at IS NULL
+		RETURNING user_id
+	`, lookup, smsCodeHash(code)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "That code is wrong or has expired. Request a new one in SLAR and text VERIFY <code> from this phone.", nil
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return "This number is already linked to a SLAR account.", nil
+		}
+		return "", fmt.Errorf("failed to verify SMS phone: %w", err)
+	}
+	log.Printf("SMS phone verified for user %s", userID)
+	return "Your number is linked to SLAR. Reply ACK or RES with an incident ID to act on it.", nil
+}
+
+// smsIncident is an open incident an SMS command may act on
+type smsIncident struct {
+	ID     string
+	Title  string
+	Status string
+}
+
+// runIncidentCommand acknowledges or resolves an incident as the user whose
+// verified number sent the command
+func (s *SMSService) runIncidentCommand(from, action, ref string) (string, error) {
+	var userID string
+	err := s.PG.QueryRow(`
+		UPDATE sms_phones SET last_inbound_at = NOW()
+		WHERE phone_lookup = $1 AND verified_at IS NOT NULL
+		RETURNING user_id
+	`, whatsAppPhoneLookup(from)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "This number isn't linked to a SLAR account. Link it in SLAR, then text VERIFY <code>.", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up SMS sender: %w", err)
+	}
+
+	if ref != "" && (len(ref) < db.SMSIncidentRefMinLength || !smsIncidentRefPattern.MatchString(ref)) {
+		return fmt.Sprintf("Send at least the first %d characters of the incident ID, e.g. ACK 3f2a9c1b.", db.SMSIncidentRefMinLength), nil
+	}
+	matches, err := s.findSMSIncidents(userID, ref)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(matches) == 0 && ref == "":
+		return "You have no open incidents assigned. Reply with the incident ID, e.g. ACK 3f2a9c1b.", nil
+	case len(matches) == 0:
+		return fmt.Sprintf("No open incident matches %s.", ref), nil
+	case len(matches) > 1 && ref == "":
+		return "You have more than one open incident assigned. Reply with the incident ID, e.g. ACK 3f2a9c1b.", nil
+	case len(matches) > 1:
+		return fmt.Sprintf("%s matches more than one incident. Send more characters of the ID.", ref), nil
+	}
+
+	incident := matches[0]
+	shortID := smsIncidentRef(incident.ID)
+	if action == db.IncidentStatusAcknowledged {
+		if incident.Status != db.IncidentStatusTriggered {
+			return fmt.Sprintf("%s is already acknowledged: %s", shortID, incident.Title), nil
+		}
+		if err := s.IncidentService.AcknowledgeIncident(incident.ID, userID, "Acknowledged by SMS"); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Acknowledged %s: %s", shortID, incident.Title), nil
+	}
+	if err := s.IncidentService.ResolveIncident(incident.ID, userID, "Resolved by SMS", ""); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Resolved %s: %s", shortID, incident.Title), nil
+}
+
+// findSMSIncidents returns up to two open incidents in the user's
+// organizations whose ID starts with ref, or assigned to the user when ref is
+// empty
+func (s *SMSService) findSMSIncidents(userID, ref string) ([]smsIncident, error) {
+	rows, err := s.PG.Query(`
+		SELECT i.id, i.title, i.status
+		FROM incidents i
+		WHERE i.status IN ('triggered', 'acknowledged')
+		  AND EXISTS (
+			SELECT 1 FROM memberships m
+			WHERE m.resource_type = 'org' AND m.resource_id = i.organization_id AND m.user_id = $1
+		  )
+		  AND (($2 = '' AND i.assigned_to = $1) OR ($2 <> '' AND i.id::text LIKE $2 || '%'))
+		ORDER BY i.created_at DESC
+		LIMIT 2
+	`, userID, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find incident for SMS command: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []smsIncident
+	for rows.Next() {
+		var i smsIncident
+		if err := rows.Scan(&i.ID, &i.Title, &i.Status); err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, rows.Err()
+}
+
+// smsIncidentRef is the short form of an incident ID used in SMS commands
+func smsIncidentRef(id string) string {
+	if len(id) > db.SMSIncidentRefLength {
+		return id[:db.SMSIncidentRefLength]
+	}
+	return id
+}
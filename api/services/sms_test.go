@@ -0,0 +1,128 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestVerifyTwilioSignature(t *testing.T) {
+	old := config.Current().Twilio
+	defer config.Update(func(c *config.Config) { c.Twilio = old })
+	config.Update(func(c *config.Config) { c.Twilio.AuthToken = "12345" })
+
+	webhookURL := "https://slar.example.com/sms/twilio/webhook"
+	params := url.Values{"From": {"+14155550123"}, "Body": {"ACK 3f2a"}, "AccountSid": {"AC1"}}
+	mac := hmac.New(sha1.New, []byte("12345"))
+	mac.Write([]byte(webhookURL + "AccountSidAC1" + "BodyACK 3f2a" + "From+14155550123"))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	service := NewSMSService(nil, nil)
+	if err := service.VerifyTwilioSignature(webhookURL, params, signature); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	params.Set("Body", "RES 3f2a")
+	if err := service.VerifyTwilioSignature(webhookURL, params, signature); err != ErrInvalidTwilioSignature {
+		t.Errorf("tampered body accepted: %v", err)
+	}
+}
+
+func TestSMSAcknowledgeByIncidentRef(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`UPDATE sms_phones SET last_inbound_at`).
+		WithArgs(whatsAppPhoneLookup("+14155550123")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+	mock.ExpectQuery(`FROM incidents i`).
+		WithArgs("user-1", "3f2a9c").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status"}).
+			AddRow("3f2a9c1b-0000-4000-8000-000000000001", "Disk full on db-1", "triggered"))
+	mock.ExpectExec(`UPDATE incidents`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	service := NewSMSService(pg, &IncidentService{PG: pg})
+	reply, err := service.HandleInboundMessage("+14155550123", "ack 3F2A9C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Acknowledged 3f2a9c1b: Disk full on db-1" {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSMSCommandReplies(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	service := NewSMSService(pg, &IncidentService{PG: pg})
+
+	// Unknown number
+	mock.ExpectQuery(`UPDATE sms_phones SET last_inbound_at`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	if reply, _ := service.HandleInboundMessage("+14155550199", "RES 1234"); !strings.Contains(reply, "isn't linked") {
+		t.Errorf("unknown number got %q", reply)
+	}
+
+	// Ambiguous bare ACK
+	mock.ExpectQuery(`UPDATE sms_phones SET last_inbound_at`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("user-1", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status"}).
+			AddRow("inc-1", "A", "triggered").AddRow("inc-2", "B", "triggered"))
+	if reply, _ := service.HandleInboundMessage("+14155550123", "ACK"); !strings.Contains(reply, "more than one") {
+		t.Errorf("ambiguous ACK got %q", reply)
+	}
+
+	// Already acknowledged
+	mock.ExpectQuery(`UPDATE sms_phones SET last_inbound_at`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("user-1", "1234").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status"}).AddRow("12345678-aaaa", "A", "acknowledged"))
+	if reply, _ := service.HandleInboundMessage("+14155550123", "ACK 1234"); reply != "12345678 is already acknowledged: A" {
+		t.Errorf("acknowledged incident got %q", reply)
+	}
+
+	if reply, _ := service.HandleInboundMessage("+14155550123", "hello"); reply != smsHelpReply {
+		t.Errorf("unknown command got %q", reply)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSMSVerifyPhone(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	lookup := whatsAppPhoneLookup("+14155550123")
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs(lookup).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`UPDATE sms_phones`).WithArgs(lookup, smsCodeHash("482913")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+
+	reply, err := NewSMSService(pg, nil).HandleInboundMessage("+14155550123", "VERIFY 482913")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reply, "Your number is linked") {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
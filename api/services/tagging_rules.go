@@ -0,0 +1,323 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidTaggingRule = errors.New("invalid tagging rule")
+
+// TAGGING RULES
+
+// ListTaggingRules returns an organization's tagging rules in evaluation order
+func (s *IncidentService) ListTaggingRules(orgID string) ([]db.IncidentTaggingRule, error) {
+	return listTaggingRules(s.PG, orgID)
+}
+
+func listTaggingRules(pg *sql.DB, orgID string) ([]db.IncidentTaggingRule, error) {
+	rows, err := pg.Query(`
+		SELECT id, organization_id, name, position, conditions, add_tags,
+		       COALESCE(set_team, ''), COALESCE(set_component, ''), stop, created_at
+		FROM incident_tagging_rules
+		WHERE organization_id = $1
+		ORDER BY position ASC, created_at ASC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tagging rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []db.IncidentTaggingRule{}
+	for rows.Next() {
+		var r db.IncidentTaggingRule
+		var conditions []byte
+		if err := rows.Scan(&r.ID, &r.OrganizationID, &r.Name, &r.Position, &conditions, pq.Array(&r.AddTags),
+			&r.SetTeam, &r.SetComponent, &r.Stop, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tagging rule: %w", err)
+		}
+		if err := json.Unmarshal(conditions, &r.Conditions); err != nil {
+			return nil, fmt.Errorf("failed to decode tagging rule %s: %w", r.ID, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// normalizeTag lowercases a tag and turns runs of spaces into dashes, so
+// "Database Outage" and "database-outage" are the same tag
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+}
+
+// validateTaggingRules normalizes the rules' tags and checks every rule has
+// an action and every pattern compiles
+func validateTaggingRules(inputs []db.TaggingRuleInput) ([]db.TaggingRuleInput, error) {
+	if len(inputs) > db.TaggingMaxRules {
+		return nil, fmt.Errorf("%w: at most %d rules", ErrInvalidTaggingRule, db.TaggingMaxRules)
+	}
+	rules := make([]db.TaggingRuleInput, len(inputs))
+	for i, r := range inputs {
+		r.Name = strings.TrimSpace(r.Name)
+		r.SetTeam = strings.TrimSpace(r.SetTeam)
+		r.SetComponent = strings.TrimSpace(r.SetComponent)
+		tags := make([]string, 0, len(r.AddTags))
+		for _, tag := range r.AddTags {
+			tag = normalizeTag(tag)
+			if tag == "" {
+				continue
+			}
+			if len(tag) > db.TaggingMaxTagLength {
+				return nil, fmt.Errorf("%w: rule %d has a tag longer than %d characters", ErrInvalidTaggingRule, i+1, db.TaggingMaxTagLength)
+			}
+			if !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		r.AddTags = tags
+		if len(r.AddTags) == 0 && r.SetTeam == "" && r.SetComponent == "" {
+			return nil, fmt.Errorf("%w: rule %d must add tags or set a team or component", ErrInvalidTaggingRule, i+1)
+		}
+		if len(r.Conditions) == 0 || len(r.Conditions) > db.TaggingMaxConditions {
+			return nil, fmt.Errorf("%w: rule %d needs 1 to %d conditions", ErrInvalidTaggingRule, i+1, db.TaggingMaxConditions)
+		}
+		for j, cond := range r.Conditions {
+			if len(cond.Pattern) > db.TaggingMaxPatternLength {
+				return nil, fmt.Errorf("%w: rule %d condition %d: pattern is longer than %d characters",
+					ErrInvalidTaggingRule, i+1, j+1, db.TaggingMaxPatternLength)
+			}
+			if cond.Field != db.TaggingFieldLabel && cond.Label != "" {
+				return nil, fmt.Errorf("%w: rule %d condition %d: label only applies to the label field", ErrInvalidTaggingRule, i+1, j+1)
+			}
+			if _, err := compileTaggingCondition(cond); err != nil {
+				return nil, fmt.Errorf("%w: rule %d condition %d: %v", ErrInvalidTaggingRule, i+1, j+1, err)
+			}
+		}
+		rules[i] = r
+	}
+	return rules, nil
+}
+
+// SetTaggingRules replaces an organization's tagging rules; their order is the
+// evaluation order
+func (s *IncidentService) SetTaggingRules(orgID string, req db.SetTaggingRulesRequest) ([]db.IncidentTaggingRule, error) {
+	rules, err := validateTaggingRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM incident_tagging_rules WHERE organization_id = $1`, orgID); err != nil {
+		return nil, fmt.Errorf("failed to replace tagging rules: %w", err)
+	}
+	for i, r := range rules {
+		conditions, err := json.Marshal(r.Conditions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tagging rule: %w", err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO incident_tagging_rules (organization_id, name, position, conditions, add_tags, set_team, set_component, stop)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, orgID, r.Name, i, conditions, pq.Array(r.AddTags), nullIfEmpty(r.SetTeam), nullIfEmpty(r.SetComponent), r.Stop)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save tagging rule: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.ListTaggingRules(orgID)
+}
+
+// TestTaggingRules runs the organization's tagging rules, or the proposed ones
+// in the request, against a sample incident
+func (s *IncidentService) TestTaggingRules(orgID string, req db.TestTaggingRulesRequest) (*db.TaggingResult, error) {
+	var rules []db.IncidentTaggingRule
+	if req.Rules != nil {
+		inputs, err := validateTaggingRules(req.Rules)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range inputs {
+			rules = append(rules, db.IncidentTaggingRule{Name: r.Name, Position: i, Conditions: r.Conditions,
+				AddTags: r.AddTags, SetTeam: r.SetTeam, SetComponent: r.SetComponent, Stop: r.Stop})
+		}
+	} else {
+		var err error
+		if rules, err = listTaggingRules(s.PG, orgID); err != nil {
+			return nil, err
+		}
+	}
+	result := evaluateTaggingRules(compileTaggingRules(rules), &db.Incident{
+		Title: req.Title, Description: req.Description, Labels: req.Labels,
+	})
+	return &result, nil
+}
+
+// MATCHING
+
+type compiledTaggingCondition struct {
+	field, label string
+	keyword      string // lowercased, for keyword conditions
+	re           *regexp.Regexp
+}
+
+type compiledTaggingRule struct {
+	rule       db.IncidentTaggingRule
+	conditions []compiledTaggingCondition
+}
+
+func compileTaggingCondition(cond db.TaggingCondition) (compiledTaggingCondition, error) {
+	c := compiledTaggingCondition{field: cond.Field, label: cond.Label}
+	switch cond.Match {
+	case db.TaggingMatchKeyword:
+		c.keyword = strings.ToLower(cond.Pattern)
+		if strings.TrimSpace(c.keyword) == "" {
+			return c, errors.New("keyword is empty")
+		}
+	case db.TaggingMatchRegex:
+		re, err := regexp.Compile(cond.Pattern)
+		if err != nil {
+			return c, fmt.Errorf("invalid regex: %v", err)
+		}
+		c.re = re
+	default:
+		return c, fmt.Errorf("unknown match %q", cond.Match)
+	}
+	switch cond.Field {
+	case db.TaggingFieldTitle, db.TaggingFieldDescription, db.TaggingFieldLabel:
+	default:
+		return c, fmt.Errorf("unknown field %q", cond.Field)
+	}
+	return c, nil
+}
+
+// compileTaggingRules compiles stored rules, skipping any that no longer
+// compile rather than failing the incident
+func compileTaggingRules(rules []db.IncidentTaggingRule) []compiledTaggingRule {
+	compiled := make([]compiledTaggingRule, 0, len(rules))
+rules:
+	for _, r := range rules {
+		cr := compiledTaggingRule{rule: r}
+		for _, cond := range r.Conditions {
+			c, err := compileTaggingCondition(cond)
+			if err != nil {
+				log.Printf("⚠️  Skipping tagging rule %q: %v", r.Name, err)
+				continue rules
+			}
+			cr.conditions = append(cr.conditions, c)
+		}
+		if len(cr.conditions) > 0 {
+			compiled = append(compiled, cr)
+		}
+	}
+	return compiled
+}
+
+func (c compiledTaggingCondition) matchValue(value string) bool {
+	if c.re != nil {
+		return c.re.MatchString(value)
+	}
+	return strings.Contains(strings.ToLower(value), c.keyword)
+}
+
+func (c compiledTaggingCondition) matches(incident *db.Incident) bool {
+	switch c.field {
+	case db.TaggingFieldTitle:
+		return c.matchValue(incident.Title)
+	case db.TaggingFieldDescription:
+		return c.matchValue(incident.Description)
+	case db.TaggingFieldLabel:
+		if c.label != "" {
+			value, ok := incident.Labels[c.label]
+			return ok && value != nil && c.matchValue(fmt.Sprint(value))
+		}
+		// Any label; sorted so the outcome doesn't depend on map order
+		keys := make([]string, 0, len(incident.Labels))
+		for key := range incident.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if value := incident.Labels[key]; value != nil && c.matchValue(fmt.Sprint(value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evaluateTaggingRules runs rules in order against an incident. Every
+// matching rule adds its tags; the first to set a team or component wins,
+// unless the incident already has one. A matching rule with Stop ends the
+// evaluation.
+func evaluateTaggingRules(rules []compiledTaggingRule, incident *db.Incident) db.TaggingResult {
+	result := db.TaggingResult{Tags: []string{}, Team: incident.Team, Component: incident.Component, Rules: []string{}}
+	for _, tag := range incident.Tags {
+		if tag = normalizeTag(tag); tag != "" && !containsString(result.Tags, tag) {
+			result.Tags = append(result.Tags, tag)
+		}
+	}
+
+	for _, r := range rules {
+		matched := true
+		for _, c := range r.conditions {
+			if !c.matches(incident) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		result.Rules = append(result.Rules, r.rule.Name)
+		for _, tag := range r.rule.AddTags {
+			if !containsString(result.Tags, tag) {
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+		if result.Team == "" {
+			result.Team = r.rule.SetTeam
+		}
+		if result.Component == "" {
+			result.Component = r.rule.SetComponent
+		}
+		if r.rule.Stop {
+			break
+		}
+	}
+	return result
+}
+
+// ApplyTaggingRules tags a new incident with its organization's tagging
+// rules and returns the names of the rules that matched. Lookup failures
+// leave the incident as it is.
+func (s *IncidentService) ApplyTaggingRules(incident *db.Incident) []string {
+	if incident.OrganizationID == "" {
+		return nil
+	}
+	rules, err := listTaggingRules(s.PG, incident.OrganizationID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load tagging rules for org %s: %v", incident.OrganizationID, err)
+		return nil
+	}
+	if len(rules) == 0 && len(incident.Tags) == 0 {
+		return nil
+	}
+	result := evaluateTaggingRules(compileTaggingRules(rules), incident)
+	incident.Tags, incident.Team, incident.Component = result.Tags, result.Team, result.Component
+	return result.Rules
+}
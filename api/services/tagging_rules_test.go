@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestEvaluateTaggingRules(t *testing.T) {
+	rules := compileTaggingRules([]db.IncidentTaggingRule{
+		{Name: "db", Conditions: []db.TaggingCondition{
+			{Field: db.TaggingFieldTitle, Match: db.TaggingMatchRegex, Pattern: `(?i)\b(postgres|mysql)\b`},
+		}, AddTags: []string{"database"}, SetTeam: "data", SetComponent: "postgres"},
+		{Name: "prod", Conditions: []db.TaggingCondition{
+			{Field: db.TaggingFieldLabel, Label: "env", Match: db.TaggingMatchKeyword, Pattern: "prod"},
+		}, AddTags: []string{"production", "database"}, SetTeam: "sre"},
+		{Name: "disk", Conditions: []db.TaggingCondition{
+			{Field: db.TaggingFieldDescription, Match: db.TaggingMatchKeyword, Pattern: "DISK FULL"},
+		}, AddTags: []string{"disk"}, Stop: true},
+		{Name: "any label", Conditions: []db.TaggingCondition{
+			{Field: db.TaggingFieldLabel, Match: db.TaggingMatchKeyword, Pattern: "eu-west"},
+		}, AddTags: []string{"eu"}},
+	})
+
+	tests := []struct {
+		name     string
+		incident db.Incident
+		want     db.TaggingResult
+	}{
+		{
+			name:     "no match",
+			incident: db.Incident{Title: "CPU high"},
+			want:     db.TaggingResult{Tags: []string{}, Rules: []string{}},
+		},
+		{
+			name: "first team wins and tags are merged",
+			incident: db.Incident{Title: "Postgres replica lag",
+				Labels: map[string]interface{}{"env": "Production", "region": "eu-west-1"}},
+			want: db.TaggingResult{Tags: []string{"database", "production", "eu"}, Team: "data", Component: "postgres",
+				Rules: []string{"db", "prod", "any label"}},
+		},
+		{
+			name:     "stop ends evaluation",
+			incident: db.Incident{Description: "disk full on /var", Labels: map[string]interface{}{"region": "eu-west-1"}},
+			want:     db.TaggingResult{Tags: []string{"disk"}, Rules: []string{"disk"}},
+		},
+		{
+			name:     "existing values are kept",
+			incident: db.Incident{Title: "mysql down", Team: "payments", Tags: []string{"Customer Facing"}},
+			want: db.TaggingResult{Tags: []string{"customer-facing", "database"}, Team: "payments", Component: "postgres",
+				Rules: []string{"db"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluateTaggingRules(rules, &tt.incident)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTaggingRules(t *testing.T) {
+	title := db.TaggingCondition{Field: db.TaggingFieldTitle, Match: db.TaggingMatchKeyword, Pattern: "db"}
+	rules, err := validateTaggingRules([]db.TaggingRuleInput{
+		{Name: " db ", Conditions: []db.TaggingCondition{title}, AddTags: []string{"Database Outage", "database-outage", " "}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules[0].Name != "db" || !reflect.DeepEqual(rules[0].AddTags, []string{"database-outage"}) {
+		t.Errorf("rule not normalized: %+v", rules[0])
+	}
+
+	invalid := map[string]db.TaggingRuleInput{
+		"no action":     {Name: "x", Conditions: []db.TaggingCondition{title}, AddTags: []string{" "}},
+		"bad regex":     {Name: "x", Conditions: []db.TaggingCondition{{Field: db.TaggingFieldTitle, Match: db.TaggingMatchRegex, Pattern: "("}}, SetTeam: "t"},
+		"empty keyword": {Name: "x", Conditions: []db.TaggingCondition{{Field: db.TaggingFieldTitle, Match: db.TaggingMatchKeyword, Pattern: "  "}}, SetTeam: "t"},
+		"stray label":   {Name: "x", Conditions: []db.TaggingCondition{{Field: db.TaggingFieldTitle, Label: "env", Match: db.TaggingMatchKeyword, Pattern: "a"}}, SetTeam: "t"},
+	}
+	for name, rule := range invalid {
+		if _, err := validateTaggingRules([]db.TaggingRuleInput{rule}); !errors.Is(err, ErrInvalidTaggingRule) {
+			t.Errorf("%s: got %v, want ErrInvalidTaggingRule", name, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// IsTestIncident reports whether an incident was created through a test-mode
+// integration or API key. Unknown incidents are treated as real.
+func IsTestIncident(pg *sql.DB, incidentID string) (bool, error) {
+	var isTest bool
+	err := pg.QueryRow(`SELECT is_test FROM incidents WHERE id = $1`, incidentID).Scan(&isTest)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check test incident: %w", err)
+	}
+	return isTest, nil
+}
+
+// TestModeChannels returns the notification channels still used for a test
+// incident: nobody is paged for real, so push, email and SMS are dropped, and
+// Slack is kept only when a test channel is configured.
+func TestModeChannels(channels []string, slackTestChannel string) []string {
+	var kept []string
+	for _, channel := range channels {
+		if channel == "slack" && slackTestChannel != "" {
+			kept = append(kept, channel)
+		}
+	}
+	return kept
+}
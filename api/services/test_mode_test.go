@@ -0,0 +1,35 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTestModeChannels(t *testing.T) {
+	channels := []string{"push", "slack", "email", "sms"}
+
+	if got := TestModeChannels(channels, "#alerts-test"); !reflect.DeepEqual(got, []string{"slack"}) {
+		t.Errorf("with test channel = %v, want [slack]", got)
+	}
+	if got := TestModeChannels(channels, ""); len(got) != 0 {
+		t.Errorf("without test channel = %v, want none", got)
+	}
+}
+
+func TestIsTestIncidentUnknownIncident(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT is_test FROM incidents`).WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"is_test"}))
+
+	isTest, err := IsTestIncident(pg, "missing")
+	if err != nil || isTest {
+		t.Fatalf("IsTestIncident = %v, %v; want false, nil", isTest, err)
+	}
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// defaultTrashRetention applies when trash_retention is unset
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+var (
+	ErrTrashItemNotFound    = errors.New("trash item not found")
+	ErrInvalidTrashResource = errors.New("unknown trash resource type")
+	// ErrTrashRestoreConflict means a resource with the same ID exists again
+	ErrTrashRestoreConflict = errors.New("resource already exists")
+)
+
+// trashTables maps trash resource types to the table holding the resource
+var trashTables = map[string]string{
+	db.TrashResourceService:          "services",
+	db.TrashResourceScheduler:        "schedulers",
+	db.TrashResourceEscalationPolicy: "escalation_policies",
+	db.TrashResourceIntegration:      "integrations",
+}
+
+// TrashService lists and restores deleted services, schedulers, escalation
+// policies and integrations
+type TrashService struct {
+	PG *sql.DB
+}
+
+func NewTrashService(pg *sql.DB) *TrashService {
+	return &TrashService{PG: pg}
+}
+
+// trashRetention is how long deleted resources stay restorable
+func trashRetention() time.Duration {
+	if retention := config.Current().TrashRetention; retention > 0 {
+		return retention
+	}
+	return defaultTrashRetention
+}
+
+// serviceTrashSnapshot is what restoring a soft-deleted service needs
+type serviceTrashSnapshot struct {
+	WasActive             bool     `json:"was_active"`
+	ServiceIntegrationIDs []string `json:"service_integration_ids"`
+}
+
+// schedulerTrashSnapshot lists the shifts deactivated with a scheduler
+type schedulerTrashSnapshot struct {
+	ShiftIDs []string `json:"shift_ids"`
+}
+
+// escalationPolicyTrashSnapshot holds the rows of a deleted escalation policy
+type escalationPolicyTrashSnapshot struct {
+	Policy          json.RawMessage `json:"policy"`
+	Levels          json.RawMessage `json:"levels"`
+	DefaultForGroup []string        `json:"default_for_groups"`
+}
+
+// integrationTrashSnapshot holds the rows of a deleted integration
+type integrationTrashSnapshot struct {
+	Integration         json.RawMessage `json:"integration"`
+	ServiceIntegrations json.RawMessage `json:"service_integrations"`
+	MonitorDeployments  []string        `json:"monitor_deployment_ids"`
+}
+
+// moveToTrash records a resource being deleted in tx. For resources that are
+// deleted outright it must run before the DELETE, since it reads the name
+// and organization from the resource's row.
+func moveToTrash(tx *sql.Tx, resourceType, resourceID, deletedBy string, snapshot interface{}) error {
+	table, ok := trashTables[resourceType]
+	if !ok {
+		return ErrInvalidTrashResource
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO trash (resource_type, resource_id, organization_id, name, snapshot, deleted_by)
+		SELECT $1, id, organization_id, COALESCE(name, ''), $3, NULLIF($4, '')::uuid
+		FROM `+table+` WHERE id = $2
+		ON CONFLICT (resource_type, resource_id) DO UPDATE SET
+			organization_id = EXCLUDED.organization_id,
+			name = EXCLUDED.name,
+			snapshot = EXCLUDED.snapshot,
+			deleted_by = EXCLUDED.deleted_by,
+			deleted_at = NOW()
+	`, resourceType, resourceID, data, deletedBy)
+	if err != nil {
+		return fmt.Errorf("failed to move %s to trash: %w", resourceType, err)
+	}
+	return nil
+}
+
+// ListTrash returns an organization's deleted resources, newest first,
+// optionally of one resource type
+func (s *TrashService) ListTrash(orgID, resourceType string) ([]db.TrashItem, error) {
+	if _, ok := trashTables[resourceType]; resourceType != "" && !ok {
+		return nil, ErrInvalidTrashResource
+	}
+	rows, err := s.PG.Query(`
+		SELECT t.id, t.resource_type, t.resource_id, COALESCE(t.organization_id::text, ''), t.name,
+		       COALESCE(t.deleted_by::text, ''), COALESCE(u.name, ''), t.deleted_at
+		FROM trash t
+		LEFT JOIN users u ON u.id = t.deleted_by
+		WHERE t.organization_id = $1 AND ($2 = '' OR t.resource_type = $2)
+		ORDER BY t.deleted_at DESC
+	`, orgID, resourceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	defer rows.Close()
+
+	retention := trashRetention()
+	items := []db.TrashItem{}
+	for rows.Next() {
+		var item db.TrashItem
+		if err := rows.Scan(&item.ID, &item.ResourceType, &item.ResourceID, &item.OrganizationID, &item.Name,
+			&item.DeletedBy, &item.DeletedByName, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		item.PurgeAt = item.DeletedAt.Add(retention)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RestoreTrashItem brings a deleted resource of the organization back and
+// removes it from the trash
+func (s *TrashService) RestoreTrashItem(orgID, resourceType, resourceID string) (db.TrashItem, error) {
+	var item db.TrashItem
+	if _, ok := trashTables[resourceType]; !ok {
+		return item, ErrInvalidTrashResource
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return item, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var snapshot []byte
+	err = tx.QueryRow(`
+		SELECT id, resource_type, resource_id, COALESCE(organization_id::text, ''), name,
+		       COALESCE(deleted_by::text, ''), deleted_at, snapshot
+		FROM trash
+		WHERE resource_type = $1 AND resource_id::text = $2 AND organization_id = $3
+		FOR UPDATE
+	`, resourceType, resourceID, orgID).Scan(&item.ID, &item.ResourceType, &item.ResourceID,
+		&item.OrganizationID, &item.Name, &item.DeletedBy, &item.DeletedAt, &snapshot)
+	if err == sql.ErrNoRows {
+		return item, ErrTrashItemNotFound
+	}
+	if err != nil {
+		return item, fmt.Errorf("failed to get trash item: %w", err)
+	}
+
+	switch resourceType {
+	case db.TrashResourceService:
+		err = restoreService(tx, item.ResourceID, snapshot)
+	case db.TrashResourceScheduler:
+		err = restoreScheduler(tx, item.ResourceID, snapshot)
+	case db.TrashResourceEscalationPolicy:
+		err = restoreEscalationPolicy(tx, item.ResourceID, snapshot)
+	case db.TrashResourceIntegration:
+		err = restoreIntegration(tx, item.ResourceID, snapshot)
+	}
+	if err != nil {
+		return item, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM trash WHERE id = $1`, item.ID); err != nil {
+		return item, fmt.Errorf("failed to remove trash item: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return item, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return item, nil
+}
+
+func restoreService(tx *sql.Tx, serviceID string, data []byte) error {
+	var snapshot serviceTrashSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid service snapshot: %w", err)
+	}
+	result, err := tx.Exec(`UPDATE services SET is_active = $2, updated_at = NOW() WHERE id = $1`,
+		serviceID, snapshot.WasActive)
+	if err != nil {
+		return fmt.Errorf("failed to restore service: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTrashItemNotFound
+	}
+	if _, err := tx.Exec(`
+		UPDATE service_integrations SET is_active = true, updated_at = NOW()
+		WHERE service_id = $1 AND id = ANY($2)
+	`, serviceID, pq.Array(snapshot.ServiceIntegrationIDs)); err != nil {
+		return fmt.Errorf("failed to restore service integrations: %w", err)
+	}
+	return nil
+}
+
+func restoreScheduler(tx *sql.Tx, schedulerID string, data []byte) error {
+	var snapshot schedulerTrashSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid scheduler snapshot: %w", err)
+	}
+	result, err := tx.Exec(`UPDATE schedulers SET is_active = true, updated_at = NOW() WHERE id = $1`, schedulerID)
+	if err != nil {
+		return fmt.Errorf("failed to restore scheduler: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTrashItemNotFound
+	}
+	if _, err := tx.Exec(`
+		UPDATE shifts SET is_active = true, updated_at = NOW()
+		WHERE scheduler_id = $1 AND id = ANY($2)
+	`, schedulerID, pq.Array(snapshot.ShiftIDs)); err != nil {
+		return fmt.Errorf("failed to restore shifts: %w", err)
+	}
+	return nil
+}
+
+func restoreEscalationPolicy(tx *sql.Tx, policyID string, data []byte) error {
+	var snapshot escalationPolicyTrashSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid escalation policy snapshot: %w", err)
+	}
+	result, err := tx.Exec(`
+		INSERT INTO escalation_policies
+		SELECT * FROM jsonb_populate_record(NULL::escalation_policies, $1)
+		ON CONFLICT (id) DO NOTHING
+	`, []byte(snapshot.Policy))
+	if err != nil {
+		return fmt.Errorf("failed to restore escalation policy: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTrashRestoreConflict
+	}
+	if len(snapshot.Levels) > 0 {
+		if _, err := tx.Exec(`
+			INSERT INTO escalation_levels
+			SELECT * FROM jsonb_populate_recordset(NULL::escalation_levels, $1)
+			ON CONFLICT (id) DO NOTHING
+		`, []byte(snapshot.Levels)); err != nil {
+			return fmt.Errorf("failed to restore escalation levels: %w", err)
+		}
+	}
+	// Deleting the policy cleared it as these groups' default
+	if _, err := tx.Exec(`
+		UPDATE groups SET default_escalation_policy_id = $1
+		WHERE id = ANY($2) AND default_escalation_policy_id IS NULL
+	`, policyID, pq.Array(snapshot.DefaultForGroup)); err != nil {
+		return fmt.Errorf("failed to restore group defaults: %w", err)
+	}
+	return nil
+}
+
+func restoreIntegration(tx *sql.Tx, integrationID string, data []byte) error {
+	var snapshot integrationTrashSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid integration snapshot: %w", err)
+	}
+	result, err := tx.Exec(`
+		INSERT INTO integrations
+		SELECT * FROM jsonb_populate_record(NULL::integrations, $1)
+		ON CONFLICT (id) DO NOTHING
+	`, []byte(snapshot.Integration))
+	if err != nil {
+		return fmt.Errorf("failed to restore integration: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTrashRestoreConflict
+	}
+	if len(snapshot.ServiceIntegrations) > 0 {
+		// Mappings to services that have since been removed stay gone
+		if _, err := tx.Exec(`
+			INSERT INTO service_integrations
+			SELECT r.* FROM jsonb_populate_recordset(NULL::service_integrations, $1) r
+			WHERE EXISTS (SELECT 1 FROM services s WHERE s.id = r.service_id)
+			ON CONFLICT DO NOTHING
+		`, []byte(snapshot.ServiceIntegrations)); err != nil {
+			return fmt.Errorf("failed to restore service integrations: %w", err)
+		}
+	}
+	// Deleting the integration unlinked these monitor deployments
+	if _, err := tx.Exec(`
+		UPDATE monitor_deployments SET integration_id = $1
+		WHERE id = ANY($2) AND integration_id IS NULL
+	`, integrationID, pq.Array(snapshot.MonitorDeployments)); err != nil {
+		return fmt.Errorf("failed to restore monitor deployments: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredTrash forgets deleted resources past trash_retention; they can
+// no longer be restored
+func (s *TrashService) PurgeExpiredTrash() (int64, error) {
+	result, err := s.PG.Exec(`DELETE FROM trash WHERE deleted_at < $1`, time.Now().Add(-trashRetention()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestDeleteServiceMovesToTrash(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE services s SET is_active = false`).WithArgs(sqlmock.AnyArg(), "svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(true))
+	mock.ExpectQuery(`UPDATE service_integrations SET is_active = false`).WithArgs(sqlmock.AnyArg(), "svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"ids"}).AddRow("{si-1,si-2}"))
+	mock.ExpectExec(`INSERT INTO trash`).
+		WithArgs(db.TrashResourceService, "svc-1", []byte(`{"was_active":true,"service_integration_ids":["si-1","si-2"]}`), "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := (&ServiceService{PG: pg}).DeleteService("svc-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRestoreTrashItem(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+	s := NewTrashService(pg)
+
+	if _, err := s.RestoreTrashItem("org-1", "incident", "inc-1"); !errors.Is(err, ErrInvalidTrashResource) {
+		t.Errorf("unknown type err = %v", err)
+	}
+
+	columns := []string{"id", "resource_type", "resource_id", "organization_id", "name", "deleted_by", "deleted_at", "snapshot"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM trash`).WithArgs(db.TrashResourceScheduler, "sch-1", "org-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("t-1", db.TrashResourceScheduler, "sch-1", "org-1",
+			"Primary", "user-1", time.Now(), []byte(`{"shift_ids":["sh-1"]}`)))
+	mock.ExpectExec(`UPDATE schedulers SET is_active = true`).WithArgs("sch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE shifts SET is_active = true`).WithArgs("sch-1", "{\"sh-1\"}").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM trash`).WithArgs("t-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	item, err := s.RestoreTrashItem("org-1", db.TrashResourceScheduler, "sch-1")
+	if err != nil || item.Name != "Primary" {
+		t.Fatalf("item = %+v, err = %v", item, err)
+	}
+
+	// Another organization's trash is not visible
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM trash`).WithArgs(db.TrashResourceScheduler, "sch-1", "org-2").
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectRollback()
+	if _, err := s.RestoreTrashItem("org-2", db.TrashResourceScheduler, "sch-1"); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Errorf("other org err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
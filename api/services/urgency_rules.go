@@ -0,0 +1,263 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrInvalidUrgencyRule = errors.New("invalid urgency rule")
+
+// URGENCY RULES
+
+// ListUrgencyRules returns a service's urgency rules in evaluation order
+func (s *ServiceService) ListUrgencyRules(serviceID string) ([]db.ServiceUrgencyRule, error) {
+	return listUrgencyRules(s.PG, serviceID)
+}
+
+// rowsQueryer is a *sql.DB or *sql.Tx
+type rowsQueryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func listUrgencyRules(q rowsQueryer, serviceID string) ([]db.ServiceUrgencyRule, error) {
+	rows, err := q.Query(`
+		SELECT id, service_id, name, position, min_alert_count, severities,
+		       COALESCE(set_urgency, ''), COALESCE(set_priority, ''), repage, created_at
+		FROM service_urgency_rules
+		WHERE service_id = $1
+		ORDER BY position ASC, created_at ASC
+	`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list urgency rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []db.ServiceUrgencyRule{}
+	for rows.Next() {
+		var r db.ServiceUrgencyRule
+		if err := rows.Scan(&r.ID, &r.ServiceID, &r.Name, &r.Position, &r.MinAlertCount, pq.Array(&r.Severities),
+			&r.SetUrgency, &r.SetPriority, &r.Repage, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan urgency rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// SetUrgencyRules replaces a service's urgency rules; their order is the evaluation order
+func (s *ServiceService) SetUrgencyRules(serviceID string, req db.SetUrgencyRulesRequest) ([]db.ServiceUrgencyRule, error) {
+	for i, r := range req.Rules {
+		if r.SetUrgency == "" && r.SetPriority == "" {
+			return nil, fmt.Errorf("%w: rule %d must set urgency or priority", ErrInvalidUrgencyRule, i+1)
+		}
+		if r.MinAlertCount == 0 && len(r.Severities) == 0 {
+			return nil, fmt.Errorf("%w: rule %d needs min_alert_count or severities", ErrInvalidUrgencyRule, i+1)
+		}
+		if r.Repage && r.SetUrgency != db.IncidentUrgencyHigh {
+			return nil, fmt.Errorf("%w: rule %d can only re-page when it raises urgency to high", ErrInvalidUrgencyRule, i+1)
+		}
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM service_urgency_rules WHERE service_id = $1`, serviceID); err != nil {
+		return nil, fmt.Errorf("failed to replace urgency rules: %w", err)
+	}
+	for i, r := range req.Rules {
+		severities := make([]string, 0, len(r.Severities))
+		for _, sev := range r.Severities {
+			if sev = strings.ToLower(strings.TrimSpace(sev)); sev != "" {
+				severities = append(severities, sev)
+			}
+		}
+		_, err := tx.Exec(`
+			INSERT INTO service_urgency_rules (service_id, name, position, min_alert_count, severities, set_urgency, set_priority, repage)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, serviceID, strings.TrimSpace(r.Name), i, r.MinAlertCount, pq.Array(severities),
+			nullIfEmpty(r.SetUrgency), nullIfEmpty(r.SetPriority), r.Repage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save urgency rule: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.ListUrgencyRules(serviceID)
+}
+
+// matchUrgencyRule returns the first rule matching an incident's alert count and severity
+func matchUrgencyRule(rules []db.ServiceUrgencyRule, alertCount int, severity string) *db.ServiceUrgencyRule {
+	severity = strings.ToLower(severity)
+	for i, r := range rules {
+		if alertCount < r.MinAlertCount {
+			continue
+		}
+		if len(r.Severities) > 0 && !containsString(r.Severities, severity) {
+			continue
+		}
+		return &rules[i]
+	}
+	return nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// urgencyForSeverity is the urgency a new webhook incident gets for a severity
+func urgencyForSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "info", "warning":
+		return db.IncidentUrgencyLow
+	}
+	return db.IncidentUrgencyHigh
+}
+
+// RECALCULATION
+
+// RecordIncidentAlert adds another alert to an open incident and recomputes
+// its urgency and priority. The service's first matching urgency rule wins;
+// without one, a severity change applies the service's severity mapping or
+// can still raise urgency the way it would for a new incident. Changes are
+// recorded as incident events, and rules with repage notify the assignee
+// again when urgency is raised.
+// It returns nil when the incident is no longer open.
+func (s *IncidentService) RecordIncidentAlert(incidentID, severity string) (*db.UrgencyChange, error) {
+	change := db.UrgencyChange{IncidentID: incidentID}
+	var serviceID, assignedTo string
+	var isTest bool
+
+	// The alert count and the urgency it leads to are written together
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE incidents i
+		SET alert_count = i.alert_count + 1, severity = COALESCE(NULLIF($2, ''), i.severity), updated_at = NOW()
+		FROM (SELECT id, severity FROM incidents WHERE id = $1 FOR UPDATE) prev
+		WHERE i.id = prev.id AND i.status IN ('triggered', 'acknowledged')
+		RETURNING i.alert_count, COALESCE(prev.severity, ''), COALESCE(i.severity, ''), i.urgency, COALESCE(i.priority, ''),
+		          COALESCE(i.service_id::text, ''), COALESCE(i.assigned_to::text, ''), COALESCE(i.is_test, false)
+	`, incidentID, strings.TrimSpace(severity)).Scan(&change.AlertCount, &change.FromSeverity, &change.ToSeverity,
+		&change.FromUrgency, &change.FromPriority, &serviceID, &assignedTo, &isTest)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record incident alert: %w", err)
+	}
+	change.ToUrgency, change.ToPriority = change.FromUrgency, change.FromPriority
+	severityChanged := change.ToSeverity != change.FromSeverity
+
+	var rule *db.ServiceUrgencyRule
+	if serviceID != "" {
+		rules, err := listUrgencyRules(tx, serviceID)
+		if err != nil {
+			return nil, err
+		}
+		rule = matchUrgencyRule(rules, change.AlertCount, change.ToSeverity)
+	}
+	var mapping *db.ServiceSeverityMapping
+	if rule == nil && severityChanged {
+		if mapping, err = severityMappingFor(tx, serviceID, change.ToSeverity); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case rule != nil:
+		change.Rule = rule.Name
+		if rule.SetUrgency != "" {
+			change.ToUrgency = rule.SetUrgency
+		}
+		if rule.SetPriority != "" {
+			change.ToPriority = rule.SetPriority
+		}
+	case mapping != nil:
+		if mapping.Priority != "" {
+			change.ToPriority = mapping.Priority
+		}
+		if mapping.Urgency == db.IncidentUrgencyHigh {
+			change.ToUrgency = db.IncidentUrgencyHigh
+		}
+	case severityChanged && urgencyForSeverity(change.ToSeverity) == db.IncidentUrgencyHigh:
+		change.ToUrgency = db.IncidentUrgencyHigh
+	}
+
+	if change.ToUrgency == change.FromUrgency && change.ToPriority == change.FromPriority {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to record incident alert: %w", err)
+		}
+		if severityChanged {
+			s.createIncidentEvent(incidentID, db.IncidentEventUpdated, map[string]interface{}{
+				"updated_fields": map[string]interface{}{"severity": change.ToSeverity},
+				"from_severity":  change.FromSeverity,
+				"alert_count":    change.AlertCount,
+				"reason":         "alert severity changed",
+			}, "")
+		}
+		return &change, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE incidents SET urgency = $2, priority = NULLIF($3, ''), updated_at = NOW() WHERE id = $1
+	`, incidentID, change.ToUrgency, change.ToPriority); err != nil {
+		return nil, fmt.Errorf("failed to update incident urgency: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update incident urgency: %w", err)
+	}
+
+	raised := change.FromUrgency != db.IncidentUrgencyHigh && change.ToUrgency == db.IncidentUrgencyHigh
+	change.Repaged = rule != nil && rule.Repage && raised && assignedTo != "" && !isTest
+
+	eventData := map[string]interface{}{
+		"from_urgency":  change.FromUrgency,
+		"to_urgency":    change.ToUrgency,
+		"from_priority": change.FromPriority,
+		"to_priority":   change.ToPriority,
+		"alert_count":   change.AlertCount,
+		"repaged":       change.Repaged,
+	}
+	if severityChanged {
+		eventData["from_severity"], eventData["to_severity"] = change.FromSeverity, change.ToSeverity
+	}
+	if rule != nil {
+		eventData["rule"] = rule.Name
+		eventData["reason"] = fmt.Sprintf("urgency rule %q matched", rule.Name)
+	} else if mapping != nil {
+		eventData["reason"] = "service severity mapping for " + change.ToSeverity
+	} else {
+		eventData["reason"] = "alert severity changed to " + change.ToSeverity
+	}
+	if err := s.createIncidentEvent(incidentID, db.IncidentEventUrgencyChanged, eventData, ""); err != nil {
+		log.Printf("⚠️  Failed to record urgency change for incident %s: %v", incidentID, err)
+	}
+
+	// Queued before returning so the re-page isn't lost if the caller exits
+	if change.Repaged && s.NotificationWorker != nil {
+		message := fmt.Sprintf("Urgency raised to high (%s, %d alerts)", change.Rule, change.AlertCount)
+		if err := s.NotificationWorker.SendIncidentPagedNotification(assignedTo, incidentID, message); err != nil {
+			log.Printf("⚠️  Failed to re-page incident %s: %v", incidentID, err)
+		}
+	}
+
+	return &change, nil
+}
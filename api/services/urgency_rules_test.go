@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestMatchUrgencyRule(t *testing.T) {
+	rules := []db.ServiceUrgencyRule{
+		{Name: "critical", Severities: []string{"critical"}, SetPriority: "P1"},
+		{Name: "storm", MinAlertCount: 5, SetUrgency: "high"},
+	}
+	tests := []struct {
+		alerts   int
+		severity string
+		want     string
+	}{
+		{1, "Critical", "critical"},
+		{4, "warning", ""},
+		{5, "warning", "storm"},
+		{9, "critical", "critical"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if rule := matchUrgencyRule(rules, tt.alerts, tt.severity); rule != nil {
+			got = rule.Name
+		}
+		if got != tt.want {
+			t.Errorf("matchUrgencyRule(%d, %q) = %q, want %q", tt.alerts, tt.severity, got, tt.want)
+		}
+	}
+}
+
+type recordingSender struct {
+	LightweightNotificationSender
+	paged chan string
+}
+
+func (r *recordingSender) SendIncidentPagedNotification(userID, incidentID, message string) error {
+	r.paged <- userID
+	return nil
+}
+
+func TestRecordIncidentAlertRaisesUrgencyAndRepages(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE incidents i\s+SET alert_count = i.alert_count \+ 1`).WithArgs("inc-1", "warning").
+		WillReturnRows(sqlmock.NewRows([]string{"alert_count", "prev_severity", "severity", "urgency", "priority", "service_id", "assigned_to", "is_test"}).
+			AddRow(5, "warning", "warning", "low", "P3", "svc-1", "user-1", false))
+	mock.ExpectQuery(`FROM service_urgency_rules`).WithArgs("svc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_id", "name", "position", "min_alert_count", "severities", "set_urgency", "set_priority", "repage", "created_at"}).
+			AddRow("r-1", "svc-1", "storm", 0, 5, pq.StringArray{}, "high", "P2", true, time.Now()))
+	mock.ExpectExec(`UPDATE incidents SET urgency`).WithArgs("inc-1", "high", "P2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventUrgencyChanged, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	sender := &recordingSender{paged: make(chan string, 1)}
+	svc := NewIncidentService(pg, nil)
+	svc.NotificationWorker = sender
+
+	change, err := svc.RecordIncidentAlert("inc-1", "warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.ToUrgency != "high" || change.ToPriority != "P2" || change.Rule != "storm" || !change.Repaged {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if got := <-sender.paged; got != "user-1" {
+		t.Errorf("re-paged %q, want user-1", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordIncidentAlertSeverityUpgradeWithoutRules(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE incidents i`).WithArgs("inc-1", "critical").
+		WillReturnRows(sqlmock.NewRows([]string{"alert_count", "prev_severity", "severity", "urgency", "priority", "service_id", "assigned_to", "is_test"}).
+			AddRow(2, "warning", "critical", "low", "", "", "", false))
+	mock.ExpectExec(`UPDATE incidents SET urgency`).WithArgs("inc-1", "high", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventUrgencyChanged, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	change, err := NewIncidentService(pg, nil).RecordIncidentAlert("inc-1", "critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.ToUrgency != "high" || change.Repaged {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// UsageService meters what each organization uses per month, for billing and
+// capacity planning
+type UsageService struct {
+	PG *sql.DB
+}
+
+func NewUsageService(pg *sql.DB) *UsageService {
+	return &UsageService{PG: pg}
+}
+
+// usageMonth returns the first instant of t's month in UTC
+func usageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MeterOrgUsage counts an organization's usage for the month containing at
+// and stores it. Members and services can only be counted as they are now,
+// so they are raised to the current count while the month is still running
+// and left alone once it has ended.
+func (s *UsageService) MeterOrgUsage(orgID string, at time.Time) (*db.OrgUsageMonth, error) {
+	now := time.Now()
+	from := usageMonth(at)
+	to := from.AddDate(0, 1, 0)
+
+	usage := db.OrgUsageMonth{Month: from, Notifications: map[string]int{}}
+	if now.Before(to) {
+		err := s.PG.QueryRow(`
+			SELECT
+				(SELECT COUNT(DISTINCT user_id) FROM memberships WHERE resource_type = 'org' AND resource_id = $1),
+				(SELECT COUNT(*) FROM services WHERE organization_id = $1 AND is_active = true)
+		`, orgID).Scan(&usage.PeakUsers, &usage.PeakServices)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count members and services: %w", err)
+		}
+	}
+
+	err := s.PG.QueryRow(`
+		SELECT COUNT(*) FROM incidents
+		WHERE organization_id = $1 AND created_at >= $2 AND created_at < $3 AND NOT COALESCE(is_test, false)
+	`, orgID, from, to).Scan(&usage.Incidents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	// Voice calls are logged on their own rather than in notification_logs
+	rows, err := s.PG.Query(`
+		SELECT channel, COUNT(*) FROM (
+			SELECT nl.channel
+			FROM notification_logs nl
+			JOIN incidents i ON i.id = nl.incident_id
+			WHERE i.organization_id = $1 AND nl.created_at >= $2 AND nl.created_at < $3
+			UNION ALL
+			SELECT 'voice'
+			FROM voice_calls vc
+			JOIN incidents i ON i.id = vc.incident_id
+			WHERE i.organization_id = $1 AND vc.created_at >= $2 AND vc.created_at < $3
+		) sent
+		GROUP BY channel
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channel string
+		var count int
+		if err := rows.Scan(&channel, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan notification count: %w", err)
+		}
+		usage.Notifications[channel] = count
+		usage.NotificationsTotal += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	notifications, err := json.Marshal(usage.Notifications)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification counts: %w", err)
+	}
+	var updatedAt time.Time
+	err = s.PG.QueryRow(`
+		INSERT INTO org_usage_monthly (organization_id, month, peak_users, peak_services, incidents, notifications, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (organization_id, month) DO UPDATE SET
+			peak_users = GREATEST(org_usage_monthly.peak_users, EXCLUDED.peak_users),
+			peak_services = GREATEST(org_usage_monthly.peak_services, EXCLUDED.peak_services),
+			incidents = EXCLUDED.incidents,
+			notifications = EXCLUDED.notifications,
+			updated_at = NOW()
+		RETURNING peak_users, peak_services, updated_at
+	`, orgID, from, usage.PeakUsers, usage.PeakServices, usage.Incidents, notifications).
+		Scan(&usage.PeakUsers, &usage.PeakServices, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store usage: %w", err)
+	}
+	usage.UpdatedAt = &updatedAt
+	return &usage, nil
+}
+
+// GetOrgUsage returns the organization's usage for the last months calendar
+// months, newest first, metering the current month first so it is up to
+// date. Months with nothing metered are returned as zeros.
+func (s *UsageService) GetOrgUsage(orgID string, months int) (*db.OrgUsage, error) {
+	if months <= 0 {
+		months = db.OrgUsageDefaultMonths
+	}
+	if months > db.OrgUsageMaxMonths {
+		months = db.OrgUsageMaxMonths
+	}
+
+	now := time.Now()
+	if _, err := s.MeterOrgUsage(orgID, now); err != nil {
+		return nil, err
+	}
+
+	current := usageMonth(now)
+	rows, err := s.PG.Query(`
+		SELECT month, peak_users, peak_services, incidents, notifications, updated_at
+		FROM org_usage_monthly
+		WHERE organization_id = $1 AND month >= $2
+		ORDER BY month DESC
+	`, orgID, current.AddDate(0, 1-months, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	defer rows.Close()
+
+	var metered []db.OrgUsageMonth
+	for rows.Next() {
+		var m db.OrgUsageMonth
+		var notifications []byte
+		var updatedAt time.Time
+		if err := rows.Scan(&m.Month, &m.PeakUsers, &m.PeakServices, &m.Incidents, &notifications, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		if err := json.Unmarshal(notifications, &m.Notifications); err != nil {
+			return nil, fmt.Errorf("failed to decode notification counts: %w", err)
+		}
+		for _, count := range m.Notifications {
+			m.NotificationsTotal += count
+		}
+		m.UpdatedAt = &updatedAt
+		metered = append(metered, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return &db.OrgUsage{OrganizationID: orgID, Months: fillUsageMonths(metered, current, months)}, nil
+}
+
+// fillUsageMonths lays metered months out newest first from current back
+// months months, with zeros for the gaps
+func fillUsageMonths(metered []db.OrgUsageMonth, current time.Time, months int) []db.OrgUsageMonth {
+	byMonth := map[string]db.OrgUsageMonth{}
+	for _, m := range metered {
+		byMonth[usageMonth(m.Month).Format("2006-01")] = m
+	}
+
+	filled := make([]db.OrgUsageMonth, 0, months)
+	for i := 0; i < months; i++ {
+		month := current.AddDate(0, -i, 0)
+		m, ok := byMonth[month.Format("2006-01")]
+		if !ok {
+			m = db.OrgUsageMonth{}
+		}
+		m.Month = month
+		if m.Notifications == nil {
+			m.Notifications = map[string]int{}
+		}
+		filled = append(filled, m)
+	}
+	return filled
+}
+
+// RefreshOrgUsage meters the current month of every organization, and
+// finalizes last month for those not metered since it ended. It is run by
+// the incident worker.
+func (s *UsageService) RefreshOrgUsage() (int, error) {
+	current := usageMonth(time.Now())
+	previous := current.AddDate(0, -1, 0)
+
+	rows, err := s.PG.Query(`
+		SELECT o.id::text,
+		       NOT EXISTS (
+		           SELECT 1 FROM org_usage_monthly u
+		           WHERE u.organization_id = o.id AND u.month = $1 AND u.updated_at >= $2
+		       ) AND COALESCE(o.created_at < $2, true)
+		FROM organizations o
+		WHERE COALESCE(o.is_active, true)
+	`, previous, current)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	type orgToMeter struct {
+		id       string
+		finalize bool
+	}
+	var orgs []orgToMeter
+	for rows.Next() {
+		var o orgToMeter
+		if err := rows.Scan(&o.id, &o.finalize); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	metered := 0
+	for _, o := range orgs {
+		if o.finalize {
+			if _, err := s.MeterOrgUsage(o.id, previous); err != nil {
+				return metered, fmt.Errorf("org %s: %w", o.id, err)
+			}
+		}
+		if _, err := s.MeterOrgUsage(o.id, current); err != nil {
+			return metered, fmt.Errorf("org %s: %w", o.id, err)
+		}
+		metered++
+	}
+	return metered, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestFillUsageMonths(t *testing.T) {
+	current := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	metered := []db.OrgUsageMonth{
+		{Month: current, PeakUsers: 4, Incidents: 7, Notifications: map[string]int{"email": 3}, NotificationsTotal: 3},
+		{Month: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), PeakUsers: 2},
+	}
+
+	months := fillUsageMonths(metered, current, 4)
+	if len(months) != 4 {
+		t.Fatalf("months = %+v", months)
+	}
+	want := []string{"2026-03", "2026-02", "2026-01", "2025-12"}
+	for i, m := range months {
+		if m.Month.Format("2006-01") != want[i] {
+			t.Errorf("months[%d] = %s, want %s", i, m.Month.Format("2006-01"), want[i])
+		}
+		if m.Notifications == nil {
+			t.Errorf("months[%d] has nil notifications", i)
+		}
+	}
+	if months[0].Incidents != 7 || months[0].NotificationsTotal != 3 || months[2].PeakUsers != 2 {
+		t.Errorf("months = %+v", months)
+	}
+	if months[1].PeakUsers != 0 || months[1].UpdatedAt != nil {
+		t.Errorf("gap month = %+v", months[1])
+	}
+}
+
+func TestMeterOrgUsageFinalizesPastMonth(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	// A month that has ended keeps its member and service peaks
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM incidents`).WithArgs("org-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(`FROM notification_logs nl`).WithArgs("org-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "count"}).AddRow("slack", 9).AddRow("voice", 2))
+	mock.ExpectQuery(`INSERT INTO org_usage_monthly`).
+		WithArgs("org-1", from, 0, 0, 5, []byte(`{"slack":9,"voice":2}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"peak_users", "peak_services", "updated_at"}).AddRow(12, 3, time.Now()))
+
+	usage, err := NewUsageService(pg).MeterOrgUsage("org-1", from.Add(10*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.PeakUsers != 12 || usage.PeakServices != 3 || usage.Incidents != 5 || usage.NotificationsTotal != 11 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -34,6 +34,7 @@ func (s *UserService) ListUsers() ([]db.User, error) {
 		if err != nil {
 			continue
 		}
+		decryptColumns(&u.Phone, &u.FCMToken)
 		users = append(users, u)
 	}
 	return users, nil
@@ -44,6 +45,7 @@ func (s *UserService) GetUser(id string) (db.User, error) {
 	// Query by id (UUID) - this is called by ensureUserExists with the converted UUID
 	err := s.PG.QueryRow(`SELECT id, provider, provider_id, name, email, COALESCE(phone, '') as phone, role, team, COALESCE(fcm_token, '') as fcm_token, is_active, created_at, updated_at FROM users WHERE id = $1`, id).
 		Scan(&u.ID, &u.Provider, &u.ProviderID, &u.Name, &u.Email, &u.Phone, &u.Role, &u.Team, &u.FCMToken, &u.IsActive, &u.CreatedAt, &u.UpdatedAt)
+	decryptColumns(&u.Phone, &u.FCMToken)
 	return u, err
 }
 
@@ -69,6 +71,7 @@ func (s *UserService) GetUserByEmail(email string) (*db.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	decryptColumns(&u.Phone, &u.FCMToken)
 	return &u, nil
 }
 
@@ -126,8 +129,13 @@ func (s *UserService) CreateUser(c *gin.Context) (db.User, error) {
 		user.FCMToken = ""
 	}
 
-	_, err := s.PG.Exec(`INSERT INTO users (id, name, email, phone, role, team, fcm_token, is_active, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
-		user.ID, user.Name, user.Email, user.Phone, user.Role, user.Team, user.FCMToken, user.IsActive, user.CreatedAt, user.UpdatedAt)
+	phone, fcmToken, err := encryptUserColumns(user.Phone, user.FCMToken)
+	if err != nil {
+		return user, err
+	}
+
+	_, err = s.PG.Exec(`INSERT INTO users (id, name, email, phone, role, team, fcm_token, is_active, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+		user.ID, user.Name, user.Email, phone, user.Role, user.Team, fcmToken, user.IsActive, user.CreatedAt, user.UpdatedAt)
 
 	return user, err
 }
@@ -141,10 +149,13 @@ func (s *UserService) UpdateUser(id string, c *gin.Context) (db.User, error) {
 	user.ID = id
 	user.UpdatedAt = time.Now()
 
-	var err error
+	phone, fcmToken, err := encryptUserColumns(user.Phone, user.FCMToken)
+	if err != nil {
+		return user, err
+	}
 
 	_, err = s.PG.Exec(`UPDATE users SET name=$2, email=$3, phone=$4, role=$5, team=$6, fcm_token=$7, updated_at=$8 WHERE id=$1`,
-		user.ID, user.Name, user.Email, user.Phone, user.Role, user.Team, user.FCMToken, user.UpdatedAt)
+		user.ID, user.Name, user.Email, phone, user.Role, user.Team, fcmToken, user.UpdatedAt)
 
 	return user, err
 }
@@ -171,6 +182,7 @@ func (s *UserService) GetCurrentOnCallUser() (db.User, error) {
 		ORDER BY es.start_time DESC 
 		LIMIT 1`, now).
 		Scan(&u.ID, &u.Name, &u.Email, &u.Phone, &u.Role, &u.Team, &u.FCMToken, &u.IsActive, &u.CreatedAt, &u.UpdatedAt)
+	decryptColumns(&u.Phone, &u.FCMToken)
 
 	return u, err
 }
@@ -267,12 +279,9 @@ func (s *UserService) GetOnCallSchedule(id string) (db.Shift, error) {
 	return schedule, err
 }
 
-// UpdateFCMToken updates user's FCM token
+// UpdateFCMToken registers an FCM token as one of the user's devices
 func (s *UserService) UpdateFCMToken(userID, fcmToken string) error {
-	_, err := s.PG.Exec(
-		"UPDATE users SET fcm_token = $1, updated_at = NOW() WHERE id = $2",
-		fcmToken, userID,
-	)
+	_, err := s.RegisterDevice(userID, db.RegisterDeviceRequest{FCMToken: fcmToken})
 	return err
 }
 
@@ -286,7 +295,12 @@ func (s *UserService) CreateUserRecord(user db.User) error {
 		user.FCMToken = ""
 	}
 
-	_, err := s.PG.Exec(`
+	phone, fcmToken, err := encryptUserColumns(user.Phone, user.FCMToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.PG.Exec(`
 		INSERT INTO users (id, provider, provider_id, name, email, phone, role, team, fcm_token, is_active, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		ON CONFLICT (id) DO UPDATE SET
@@ -294,8 +308,8 @@ func (s *UserService) CreateUserRecord(user db.User) error {
 			email = EXCLUDED.email,
 			provider_id = EXCLUDED.provider_id,
 			updated_at = EXCLUDED.updated_at`,
-		user.ID, user.Provider, user.ProviderID, user.Name, user.Email, user.Phone, user.Role, user.Team,
-		user.FCMToken, user.IsActive, user.CreatedAt, user.UpdatedAt)
+		user.ID, user.Provider, user.ProviderID, user.Name, user.Email, phone, user.Role, user.Team,
+		fcmToken, user.IsActive, user.CreatedAt, user.UpdatedAt)
 
 	return err
 }
@@ -361,6 +375,7 @@ func (s *UserService) SearchUsers(query string, excludeIDs []string, limit int)
 		if err != nil {
 			return nil, err
 		}
+		decryptColumns(&user.Phone, &user.FCMToken)
 		users = append(users, user)
 	}
 
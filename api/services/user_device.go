@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/vanchonlee/slar/db"
+)
+
+var ErrUserDeviceNotFound = errors.New("device not found")
+
+// userDeviceValidationBatch caps the dry-run sends of one validation pass
+const userDeviceValidationBatch = 500
+
+func fcmTokenLookup(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DEVICES
+
+// RegisterDevice registers an app installation for a user. Registering the
+// same token again refreshes its metadata, clears its failures and moves it
+// to the current user.
+func (s *UserService) RegisterDevice(userID string, req db.RegisterDeviceRequest) (*db.UserDevice, error) {
+	token := strings.TrimSpace(req.FCMToken)
+	if token == "" {
+		return nil, fmt.Errorf("fcm_token is required")
+	}
+	encToken, err := encryptColumn(token)
+	if err != nil {
+		return nil, err
+	}
+
+	device := db.UserDevice{
+		UserID:     userID,
+		Platform:   truncateRunes(strings.TrimSpace(req.Platform), 32),
+		AppVersion: truncateRunes(strings.TrimSpace(req.AppVersion), 64),
+		DeviceName: truncateRunes(strings.TrimSpace(req.DeviceName), 255),
+	}
+	err = s.PG.QueryRow(`
+		INSERT INTO user_devices (user_id, fcm_token, token_lookup, platform, app_version, device_name)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (token_lookup) DO UPDATE
+		SET user_id = EXCLUDED.user_id, fcm_token = EXCLUDED.fcm_token,
+		    platform = COALESCE(EXCLUDED.platform, user_devices.platform),
+		    app_version = COALESCE(EXCLUDED.app_version, user_devices.app_version),
+		    device_name = COALESCE(EXCLUDED.device_name, user_devices.device_name),
+		    last_seen_at = NOW(), failure_count = 0, last_error = NULL
+		RETURNING id, created_at, last_seen_at
+	`, userID, encToken, fcmTokenLookup(token), nullIfEmpty(device.Platform), nullIfEmpty(device.AppVersion), nullIfEmpty(device.DeviceName)).
+		Scan(&device.ID, &device.CreatedAt, &device.LastSeenAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save device: %w", err)
+	}
+
+	// The token carried over from users.fcm_token has no lookup; drop it once
+	// the app registers it properly
+	var legacyID, legacyToken string
+	err = s.PG.QueryRow(`SELECT id, fcm_token FROM user_devices WHERE token_lookup = 'legacy:' || $1`, userID).
+		Scan(&legacyID, &legacyToken)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check legacy device: %w", err)
+	}
+	if decryptColumns(&legacyToken); legacyToken == token {
+		if _, err := s.PG.Exec(`DELETE FROM user_devices WHERE id = $1`, legacyID); err != nil {
+			return nil, fmt.Errorf("failed to remove legacy device: %w", err)
+		}
+	}
+
+	// users.fcm_token keeps the most recently registered device for callers
+	// that only know about one
+	if _, err := s.PG.Exec(`UPDATE users SET fcm_token = $1, updated_at = NOW() WHERE id = $2`, encToken, userID); err != nil {
+		return nil, fmt.Errorf("failed to update FCM token: %w", err)
+	}
+	return &device, nil
+}
+
+// ListDevices returns a user's registered app installations, most recently
+// seen first
+func (s *UserService) ListDevices(userID string) ([]db.UserDevice, error) {
+	return listUserDevices(s.PG, `WHERE user_id = $1`, userID)
+}
+
+// DeleteDevice removes one of a user's devices by ID
+func (s *UserService) DeleteDevice(userID, deviceID string) error {
+	result, err := s.PG.Exec(`DELETE FROM user_devices WHERE id = $1 AND user_id = $2`, deviceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove device: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserDeviceNotFound
+	}
+	return syncLegacyFCMToken(s.PG, userID)
+}
+
+func listUserDevices(pg *sql.DB, where string, args ...interface{}) ([]db.UserDevice, error) {
+	rows, err := pg.Query(`
+		SELECT id, user_id, fcm_token, COALESCE(platform, ''), COALESCE(app_version, ''), COALESCE(device_name, ''),
+		       created_at, last_seen_at, last_success_at, last_validated_at, failure_count, COALESCE(last_error, '')
+		FROM user_devices
+		`+where+`
+		ORDER BY last_seen_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []db.UserDevice{}
+	for rows.Next() {
+		var d db.UserDevice
+		var lastSuccessAt, lastValidatedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.UserID, &d.FCMToken, &d.Platform, &d.AppVersion, &d.DeviceName,
+			&d.CreatedAt, &d.LastSeenAt, &lastSuccessAt, &lastValidatedAt, &d.FailureCount, &d.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		decryptColumns(&d.FCMToken)
+		if lastSuccessAt.Valid {
+			d.LastSuccessAt = &lastSuccessAt.Time
+		}
+		if lastValidatedAt.Valid {
+			d.LastValidatedAt = &lastValidatedAt.Time
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// syncLegacyFCMToken points users.fcm_token at the user's most recently seen
+// remaining device, or clears it
+func syncLegacyFCMToken(pg *sql.DB, userID string) error {
+	_, err := pg.Exec(`
+		UPDATE users SET fcm_token = (
+			SELECT fcm_token FROM user_devices WHERE user_id = $1 ORDER BY last_seen_at DESC LIMIT 1
+		), updated_at = NOW()
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update FCM token: %w", err)
+	}
+	return nil
+}
+
+// TOKEN LIFECYCLE
+
+// isUnregisteredFCMToken reports send errors meaning the token will never
+// work again: the app was uninstalled or reinstalled, or the token belongs to
+// another Firebase project
+func isUnregisteredFCMToken(err error) bool {
+	return messaging.IsUnregistered(err) || messaging.IsSenderIDMismatch(err)
+}
+
+// recordDeviceSend updates a device after a send: success clears its
+// failures, an unregistered token removes it, and other errors count towards
+// UserDeviceMaxFailures
+func (s *FCMService) recordDeviceSend(device db.UserDevice, sendErr error) {
+	if sendErr == nil {
+		if _, err := s.PG.Exec(`UPDATE user_devices SET last_success_at = NOW(), failure_count = 0, last_error = NULL WHERE id = $1`, device.ID); err != nil {
+			log.Printf("Failed to record FCM send for device %s: %v", device.ID, err)
+		}
+		return
+	}
+	if isUnregisteredFCMToken(sendErr) {
+		s.pruneDevice(device, sendErr.Error())
+		return
+	}
+
+	var failures int
+	err := s.PG.QueryRow(`
+		UPDATE user_devices SET failure_count = failure_count + 1, last_error = $2
+		WHERE id = $1
+		RETURNING failure_count
+	`, device.ID, truncateRunes(sendErr.Error(), 500)).Scan(&failures)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to record FCM error for device %s: %v", device.ID, err)
+		}
+		return
+	}
+	if failures >= db.UserDeviceMaxFailures {
+		s.pruneDevice(device, fmt.Sprintf("%d sends in a row failed, last: %v", failures, sendErr))
+	}
+}
+
+func (s *FCMService) pruneDevice(device db.UserDevice, reason string) {
+	if _, err := s.PG.Exec(`DELETE FROM user_devices WHERE id = $1`, device.ID); err != nil {
+		log.Printf("Failed to prune device %s: %v", device.ID, err)
+		return
+	}
+	if err := syncLegacyFCMToken(s.PG, device.UserID); err != nil {
+		log.Printf("Failed to prune device %s: %v", device.ID, err)
+	}
+	log.Printf("Pruned FCM device %s of user %s: %s", device.ID, device.UserID, reason)
+}
+
+// ValidateDeviceTokens prunes devices that have not registered for
+// UserDeviceStaleAfter, then checks the tokens not validated in the last day
+// with an FCM dry-run send and prunes those FCM rejects. Dry runs need direct
+// FCM; tokens held by the cloud relay are its to validate.
+func (s *FCMService) ValidateDeviceTokens() (validated, pruned int, err error) {
+	cutoff := time.Now().Add(-db.UserDeviceStaleAfter)
+	rows, err := s.PG.Query(`
+		DELETE FROM user_devices
+		WHERE last_seen_at < $1 AND (last_success_at IS NULL OR last_success_at < $1)
+		RETURNING user_id
+	`, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prune stale devices: %w", err)
+	}
+	users := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to prune stale devices: %w", err)
+		}
+		pruned++
+		users = append(users, userID)
+	}
+	rows.Close()
+	for _, userID := range uniqueStrings(users) {
+		if err := syncLegacyFCMToken(s.PG, userID); err != nil {
+			return 0, pruned, err
+		}
+	}
+
+	if s.client == nil {
+		return 0, pruned, nil
+	}
+	devices, err := listUserDevices(s.PG, fmt.Sprintf(`
+		WHERE id IN (
+			SELECT id FROM user_devices
+			WHERE last_validated_at IS NULL OR last_validated_at < NOW() - INTERVAL '1 day'
+			ORDER BY last_validated_at NULLS FIRST
+			LIMIT %d
+		)`, userDeviceValidationBatch))
+	if err != nil {
+		return 0, pruned, err
+	}
+
+	for _, device := range devices {
+		if device.FCMToken == "" {
+			continue
+		}
+		_, sendErr := s.client.SendDryRun(context.Background(), &messaging.Message{Token: device.FCMToken})
+		switch {
+		case sendErr == nil:
+			validated++
+			if _, err := s.PG.Exec(`UPDATE user_devices SET last_validated_at = NOW() WHERE id = $1`, device.ID); err != nil {
+				return validated, pruned, fmt.Errorf("failed to record device validation: %w", err)
+			}
+		// The dry-run message is known to be valid, so an invalid argument
+		// here is the token
+		case isUnregisteredFCMToken(sendErr) || messaging.IsInvalidArgument(sendErr):
+			s.pruneDevice(device, sendErr.Error())
+			pruned++
+		default:
+			// Transient (quota, outage): try again on the next pass
+			log.Printf("Failed to validate FCM device %s: %v", device.ID, sendErr)
+		}
+	}
+	return validated, pruned, nil
+}
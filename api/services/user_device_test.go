@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestRegisterDeviceReplacesLegacyToken(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`INSERT INTO user_devices`).
+		WithArgs("user-1", sqlmock.AnyArg(), fcmTokenLookup("token-abc"), "android", nil, "Pixel 8").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "last_seen_at"}).AddRow("dev-2", time.Now(), time.Now()))
+	mock.ExpectQuery(`FROM user_devices WHERE token_lookup = 'legacy:' \|\| \$1`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fcm_token"}).AddRow("dev-1", "token-abc"))
+	mock.ExpectExec(`DELETE FROM user_devices WHERE id = \$1`).WithArgs("dev-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET fcm_token`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	device, err := NewUserService(pg).RegisterDevice("user-1", db.RegisterDeviceRequest{
+		FCMToken: " token-abc ", Platform: "android", DeviceName: "Pixel 8",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.ID != "dev-2" || device.Platform != "android" {
+		t.Errorf("device = %+v, want dev-2 on android", device)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordDeviceSendPrunesAfterRepeatedFailures(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	device := db.UserDevice{ID: "dev-1", UserID: "user-1"}
+	mock.ExpectQuery(`UPDATE user_devices SET failure_count = failure_count \+ 1`).WithArgs("dev-1", "quota exceeded").
+		WillReturnRows(sqlmock.NewRows([]string{"failure_count"}).AddRow(3))
+	mock.ExpectQuery(`UPDATE user_devices SET failure_count = failure_count \+ 1`).WithArgs("dev-1", "quota exceeded").
+		WillReturnRows(sqlmock.NewRows([]string{"failure_count"}).AddRow(db.UserDeviceMaxFailures))
+	mock.ExpectExec(`DELETE FROM user_devices WHERE id = \$1`).WithArgs("dev-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET fcm_token = \(`).WithArgs("user-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user_devices SET last_success_at = NOW\(\), failure_count = 0`).WithArgs("dev-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := &FCMService{PG: pg}
+	s.recordDeviceSend(device, errors.New("quota exceeded"))
+	s.recordDeviceSend(device, errors.New("quota exceeded"))
+	s.recordDeviceSend(db.UserDevice{ID: "dev-2", UserID: "user-1"}, nil)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
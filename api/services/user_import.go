@@ -0,0 +1,288 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanchonlee/slar/db"
+)
+
+var (
+	ErrUserImportMissingColumns = errors.New("CSV header must include name and email columns")
+	ErrUserImportEmpty          = errors.New("CSV has no user rows")
+	ErrUserImportTooManyRows    = fmt.Errorf("CSV has more than %d user rows", db.UserImportMaxRows)
+	ErrUserImportOrgRequired    = errors.New("users are imported into an organization")
+)
+
+var (
+	validUserRoles   = map[string]bool{"admin": true, "engineer": true, "manager": true}
+	userPhonePattern = regexp.MustCompile(`^\+?[0-9 ()\-.]{7,20}$`)
+)
+
+// UserImportService creates and updates users in bulk from a CSV export
+type UserImportService struct {
+	PG           *sql.DB
+	EmailService *EmailService
+}
+
+func NewUserImportService(pg *sql.DB, emailService *EmailService) *UserImportService {
+	return &UserImportService{PG: pg, EmailService: emailService}
+}
+
+// ParseUserImportCSV reads a CSV with a header row naming its columns (name,
+// email, team, role, phone, in any order; team, role and phone are optional).
+// Row-level problems are left to validation so they are reported per row.
+func ParseUserImportCSV(r io.Reader) ([]db.UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrUserImportEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, ErrUserImportMissingColumns
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, ErrUserImportMissingColumns
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []db.UserImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := db.UserImportRow{
+			Line:  line,
+			Name:  field(record, "name"),
+			Email: strings.ToLower(field(record, "email")),
+			Team:  field(record, "team"),
+			Role:  strings.ToLower(field(record, "role")),
+			Phone: field(record, "phone"),
+		}
+		if row == (db.UserImportRow{Line: line}) {
+			continue // blank line
+		}
+		rows = append(rows, row)
+		if len(rows) > db.UserImportMaxRows {
+			return nil, ErrUserImportTooManyRows
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrUserImportEmpty
+	}
+	return rows, nil
+}
+
+// validateUserImportRow applies defaults and returns the row's validation errors
+func validateUserImportRow(row *db.UserImportRow) []string {
+	var errs []string
+	if row.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if row.Email == "" {
+		errs = append(errs, "email is required")
+	} else if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+		errs = append(errs, "email is not a valid address")
+	}
+	if row.Role == "" {
+		row.Role = db.UserImportDefaultRole
+	} else if !validUserRoles[row.Role] {
+		errs = append(errs, "role must be one of admin, engineer, manager")
+	}
+	if row.Team == "" {
+		row.Team = db.UserImportDefaultTeam
+	}
+	if row.Phone != "" && !userPhonePattern.MatchString(row.Phone) {
+		errs = append(errs, "phone is not a valid phone number")
+	}
+	return errs
+}
+
+// ImportUsers creates users that don't exist yet and updates the ones that do,
+// matching on email, so re-importing the same file is a no-op. Every imported
+// user is added to orgID. Accounts that exist but aren't members of orgID yet
+// are only added to it: their profile belongs to the organizations they're
+// already in. New users get an invitation email. With dryRun nothing is
+// written and no email is sent.
+func (s *UserImportService) ImportUsers(rows []db.UserImportRow, orgID string, dryRun bool) (*db.UserImportResult, error) {
+	if orgID == "" {
+		return nil, ErrUserImportOrgRequired
+	}
+	result := &db.UserImportResult{DryRun: dryRun, Total: len(rows)}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	seen := make(map[string]int)
+	var invite []int
+	for i := range rows {
+		row := rows[i]
+		res := db.UserImportRowResult{Line: row.Line, Email: row.Email}
+
+		res.Errors = validateUserImportRow(&row)
+		if first, ok := seen[row.Email]; ok && row.Email != "" {
+			res.Errors = append(res.Errors, fmt.Sprintf("duplicate of line %d", first))
+		}
+		if len(res.Errors) > 0 {
+			res.Status = db.UserImportStatusInvalid
+			result.Invalid++
+			result.Rows = append(result.Rows, res)
+			continue
+		}
+		seen[row.Email] = row.Line
+
+		res.UserID, res.Status, err = upsertImportedUser(tx, row, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import line %d: %w", row.Line, err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO memberships (user_id, resource_type, resource_id, role, created_at, updated_at)
+			VALUES ($1, 'org', $2, 'member', NOW(), NOW())
+			ON CONFLICT (user_id, resource_type, resource_id) DO NOTHING
+		`, res.UserID, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add line %d to organization: %w", row.Line, err)
+		}
+
+		switch res.Status {
+		case db.UserImportStatusCreated:
+			result.Created++
+			invite = append(invite, len(result.Rows))
+		case db.UserImportStatusUpdated:
+			result.Updated++
+		case db.UserImportStatusAdded:
+			result.Added++
+		default:
+			result.Unchanged++
+		}
+		result.Rows = append(result.Rows, res)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if s.EmailService != nil && s.EmailService.IsConfigured() {
+		for _, i := range invite {
+			if err := s.sendImportInvitation(result.Rows[i].Email); err != nil {
+				log.Printf("Warning: failed to send import invitation email to %s: %v", result.Rows[i].Email, err)
+				continue
+			}
+			result.Rows[i].InvitationSent = true
+		}
+	}
+
+	return result, nil
+}
+
+// upsertImportedUser creates the row's user or brings an existing member of
+// orgID up to date, returning its ID and whether it was created, updated,
+// unchanged or only added to the organization
+func upsertImportedUser(tx *sql.Tx, row db.UserImportRow, orgID string) (string, string, error) {
+	var userID, name, team, role, phone string
+	var active, member bool
+	err := tx.QueryRow(`
+		SELECT u.id, u.name, u.team, u.role, COALESCE(u.phone, ''), COALESCE(u.is_active, true),
+		       EXISTS (SELECT 1 FROM memberships m
+		               WHERE m.user_id = u.id AND m.resource_type = 'org' AND m.resource_id = $2)
+		FROM users u WHERE lower(u.email) = $1 LIMIT 1
+	`, row.Email, orgID).Scan(&userID, &name, &team, &role, &phone, &active, &member)
+
+	if err == sql.ErrNoRows {
+		encPhone, err := encryptColumn(row.Phone)
+		if err != nil {
+			return "", "", err
+		}
+		userID = uuid.New().String()
+		now := time.Now()
+		_, err = tx.Exec(`
+			INSERT INTO users (id, provider, provider_id, name, email, phone, role, team, is_active, created_at, updated_at)
+			VALUES ($1, 'import', $1, $2, $3, $4, $5, $6, true, $7, $7)
+		`, userID, row.Name, row.Email, encPhone, row.Role, row.Team, now)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create user: %w", err)
+		}
+		return userID, db.UserImportStatusCreated, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if !member {
+		return userID, db.UserImportStatusAdded, nil
+	}
+
+	decryptColumns(&phone)
+	if active && name == row.Name && team == row.Team && role == row.Role && (row.Phone == "" || phone == row.Phone) {
+		return userID, db.UserImportStatusUnchanged, nil
+	}
+
+	// An empty phone cell keeps the user's current number
+	newPhone := row.Phone
+	if newPhone == "" {
+		newPhone = phone
+	}
+	encPhone, err := encryptColumn(newPhone)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = tx.Exec(`
+		UPDATE users SET name = $2, team = $3, role = $4, phone = $5, is_active = true, updated_at = NOW()
+		WHERE id = $1
+	`, userID, row.Name, row.Team, row.Role, encPhone)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to update user: %w", err)
+	}
+	return userID, db.UserImportStatusUpdated, nil
+}
+
+func (s *UserImportService) sendImportInvitation(email string) error {
+	subject := "You have been added to SLAR"
+	body := fmt.Sprintf(
+		"An account has been created for you on SLAR.\n\nSign in with your company account to get started:\n%s\n",
+		webBaseURL(),
+	)
+	return s.EmailService.Send(email, subject, body)
+}
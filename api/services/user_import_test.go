@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestParseUserImportCSV(t *testing.T) {
+	csvData := "\ufeffEmail,Name,Role\n" +
+		"Alice@Example.com, Alice ,Admin\n" +
+		"\n" +
+		"bob@example.com,Bob\n"
+
+	rows, err := ParseUserImportCSV(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	want := db.UserImportRow{Line: 2, Name: "Alice", Email: "alice@example.com", Role: "admin"}
+	if rows[0] != want {
+		t.Errorf("row 0 = %+v, want %+v", rows[0], want)
+	}
+	if rows[1].Line != 4 || rows[1].Email != "bob@example.com" {
+		t.Errorf("row 1 = %+v", rows[1])
+	}
+}
+
+func TestParseUserImportCSVRequiresNameAndEmail(t *testing.T) {
+	_, err := ParseUserImportCSV(strings.NewReader("name,team\nAlice,SRE\n"))
+	if !errors.Is(err, ErrUserImportMissingColumns) {
+		t.Fatalf("err = %v, want ErrUserImportMissingColumns", err)
+	}
+}
+
+func TestValidateUserImportRow(t *testing.T) {
+	row := db.UserImportRow{Name: "Alice", Email: "alice@example.com"}
+	if errs := validateUserImportRow(&row); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if row.Role != db.UserImportDefaultRole || row.Team != db.UserImportDefaultTeam {
+		t.Errorf("defaults not applied: %+v", row)
+	}
+
+	bad := db.UserImportRow{Email: "not-an-email", Role: "owner", Phone: "call me"}
+	if errs := validateUserImportRow(&bad); len(errs) != 4 {
+		t.Errorf("got %d errors (%v), want 4", len(errs), errs)
+	}
+}
+
+var importUserColumns = []string{"id", "name", "team", "role", "phone", "is_active", "member"}
+
+func TestImportUsersDryRunReportsRowsWithoutWriting(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT u.id, u.name, u.team, u.role`).WithArgs("alice@example.com", "org-1").
+		WillReturnRows(sqlmock.NewRows(importUserColumns).AddRow("user-1", "Alice", "SRE", "engineer", "", true, true))
+	mock.ExpectExec(`INSERT INTO memberships`).WithArgs("user-1", "org-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT u.id, u.name, u.team, u.role`).WithArgs("bob@example.com", "org-1").
+		WillReturnRows(sqlmock.NewRows(importUserColumns))
+	mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO memberships`).WithArgs(sqlmock.AnyArg(), "org-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+
+	rows := []db.UserImportRow{
+		{Line: 2, Name: "Alice", Email: "alice@example.com", Team: "SRE"},
+		{Line: 3, Name: "Bob", Email: "bob@example.com"},
+		{Line: 4, Name: "Alice again", Email: "alice@example.com"},
+	}
+	result, err := NewUserImportService(pg, nil).ImportUsers(rows, "org-1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unchanged != 1 || result.Created != 1 || result.Invalid != 1 {
+		t.Errorf("result = %+v, want 1 unchanged, 1 created, 1 invalid", result)
+	}
+	if got := result.Rows[2].Errors; len(got) != 1 || got[0] != "duplicate of line 2" {
+		t.Errorf("duplicate row errors = %v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportUsersLeavesOtherOrgsUsersAlone(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	// carol has an account through another organization: she joins org-1 but
+	// her name, team, role and phone are not overwritten
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT u.id, u.name, u.team, u.role`).WithArgs("carol@example.com", "org-1").
+		WillReturnRows(sqlmock.NewRows(importUserColumns).AddRow("user-3", "Carol", "Payments", "admin", "", true, false))
+	mock.ExpectExec(`INSERT INTO memberships`).WithArgs("user-3", "org-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	rows := []db.UserImportRow{{Line: 2, Name: "Mallory", Email: "carol@example.com", Role: "engineer", Phone: "+15550100"}}
+	result, err := NewUserImportService(pg, nil).ImportUsers(rows, "org-1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Rows[0].Status != db.UserImportStatusAdded {
+		t.Errorf("result = %+v, want carol added without changes", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewUserImportService(pg, nil).ImportUsers(rows, "", false); !errors.Is(err, ErrUserImportOrgRequired) {
+		t.Errorf("expected ErrUserImportOrgRequired without an organization, got %v", err)
+	}
+}
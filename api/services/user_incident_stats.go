@@ -0,0 +1,71 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// GetIncidentStats returns a user's acknowledgements, resolutions, response
+// time and pages for incidents in an organization between from and to
+func (s *UserService) GetIncidentStats(userID, orgID string, from, to time.Time) (*db.UserIncidentStats, error) {
+	stats := &db.UserIncidentStats{
+		UserID:         userID,
+		OrganizationID: orgID,
+		From:           from,
+		To:             to,
+		Timezone:       s.notificationTimezone(userID, orgID),
+	}
+
+	var avgResponse sql.NullFloat64
+	err := s.PG.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE i.acknowledged_by = $1 AND i.acknowledged_at >= $3 AND i.acknowledged_at < $4),
+			COUNT(*) FILTER (WHERE i.resolved_by = $1 AND i.resolved_at >= $3 AND i.resolved_at < $4),
+			AVG(EXTRACT(EPOCH FROM (i.acknowledged_at - i.created_at)))
+				FILTER (WHERE i.acknowledged_by = $1 AND i.acknowledged_at >= $3 AND i.acknowledged_at < $4)
+		FROM incidents i
+		WHERE i.organization_id = $2 AND i.is_test = false AND i.drill_id IS NULL
+		  AND (i.acknowledged_by = $1 OR i.resolved_by = $1)
+	`, userID, orgID, from, to).Scan(&stats.Acknowledged, &stats.Resolved, &avgResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident participation: %w", err)
+	}
+	if avgResponse.Valid {
+		stats.AvgResponseSeconds = &avgResponse.Float64
+	}
+
+	err = s.PG.QueryRow(`
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE EXTRACT(ISODOW FROM nr.sent_at AT TIME ZONE $5) IN (6, 7)
+				OR EXTRACT(HOUR FROM nr.sent_at AT TIME ZONE $5) NOT BETWEEN $6 AND $7),
+			COUNT(*) FILTER (WHERE nr.notification_type = 'escalated')
+		FROM notification_receipts nr
+		JOIN incidents i ON i.id = nr.incident_id
+		WHERE nr.user_id = $1 AND i.organization_id = $2 AND i.is_test = false AND i.drill_id IS NULL
+		  AND nr.sent_at >= $3 AND nr.sent_at < $4
+	`, userID, orgID, from, to, stats.Timezone, db.WorkdayStartHour, db.WorkdayEndHour-1).
+		Scan(&stats.Pages, &stats.AfterHoursPages, &stats.EscalationsReceived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pages received: %w", err)
+	}
+
+	return stats, nil
+}
+
+// notificationTimezone returns the user's notification timezone, or the
+// organization's default timezone when it is unset or not a valid IANA name
+func (s *UserService) notificationTimezone(userID, orgID string) string {
+	var tz sql.NullString
+	if err := s.PG.QueryRow(`
+		SELECT notification_timezone FROM user_notification_configs WHERE user_id = $1
+	`, userID).Scan(&tz); err == nil && tz.String != "" {
+		if _, err := time.LoadLocation(tz.String); err == nil {
+			return tz.String
+		}
+	}
+	return orgDisplaySettingsOrDefault(s.PG, orgID).Timezone
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestGetIncidentStats(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -30)
+
+	mock.ExpectQuery(`SELECT notification_timezone FROM user_notification_configs`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"notification_timezone"}).AddRow("Not/AZone"))
+	mock.ExpectQuery(`FROM organizations`).WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"display"}).AddRow([]byte(`{"timezone":"Europe/Berlin"}`)))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("user-1", "org-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"acked", "resolved", "avg"}).AddRow(4, 3, 150.5))
+	mock.ExpectQuery(`FROM notification_receipts nr`).
+		WithArgs("user-1", "org-1", from, to, "Europe/Berlin", db.WorkdayStartHour, db.WorkdayEndHour-1).
+		WillReturnRows(sqlmock.NewRows([]string{"pages", "after_hours", "escalated"}).AddRow(9, 2, 1))
+
+	stats, err := (&UserService{PG: pg}).GetIncidentStats("user-1", "org-1", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Timezone != "Europe/Berlin" {
+		t.Errorf("invalid timezone should fall back to the org timezone, got %q", stats.Timezone)
+	}
+	if stats.Acknowledged != 4 || stats.Resolved != 3 || stats.AvgResponseSeconds == nil || *stats.AvgResponseSeconds != 150.5 {
+		t.Errorf("unexpected participation: %+v", stats)
+	}
+	if stats.Pages != 9 || stats.AfterHoursPages != 2 || stats.EscalationsReceived != 1 {
+		t.Errorf("unexpected pages: %+v", stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
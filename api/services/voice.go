@@ -0,0 +1,419 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/internal/faults"
+)
+
+var (
+	ErrVoicePhoneNotVerified = errors.New("link and verify a phone number before enabling phone calls")
+	ErrVoiceCallNotFound     = errors.New("voice call not found")
+)
+
+const voiceCallQueue = "voice_calls"
+
+// twilioAPIBaseURL is overridden in tests
+var twilioAPIBaseURL = "https://api.twilio.com/2010-04-01"
+
+// VoiceService pages users by phone call through Twilio. The call reads a
+// short summary of the incident and an IVR menu: 1 acknowledges, 2 escalates
+// to the next level. Every call's outcome is recorded as an incident event.
+type VoiceService struct {
+	PG *sql.DB
+	// IncidentService acts on keypresses; the notification worker, which
+	// only places calls, leaves it nil
+	IncidentService *IncidentService
+	client          *http.Client
+}
+
+func NewVoiceService(pg *sql.DB, incidentService *IncidentService) *VoiceService {
+	return &VoiceService{
+		PG:              pg,
+		IncidentService: incidentService,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsConfigured reports whether Twilio credentials and a caller number are set
+func (s *VoiceService) IsConfigured() bool {
+	t := config.Current().Twilio
+	return t.AccountSID != "" && t.AuthToken != "" && t.PhoneNumber != ""
+}
+
+// CallbackURL is the URL Twilio requests, and signs, for a call: its TwiML
+// (action ""), the menu choice ("gather") or status updates ("status")
+func (s *VoiceService) CallbackURL(callID, action string) string {
+	u := config.WebhookBaseURL() + "/voice/twilio/calls/" + callID
+	if action != "" {
+		u += "/" + action
+	}
+	return u
+}
+
+// VerifyTwilioSignature checks the X-Twilio-Signature of a call callback
+func (s *VoiceService) VerifyTwilioSignature(callbackURL string, params url.Values, signature string) error {
+	return verifyTwilioSignature(callbackURL, params, signature)
+}
+
+// SetVoiceCalls turns phone-call pages on or off for a user's verified number
+func (s *VoiceService) SetVoiceCalls(userID string, enabled bool) error {
+	result, err := s.PG.Exec(`
+		UPDATE sms_phones SET voice_enabled = $2, updated_at = NOW()
+		WHERE user_id = $1 AND verified_at IS NOT NULL
+	`, userID, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to update phone call setting: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrVoicePhoneNotVerified
+	}
+	return nil
+}
+
+// EnqueueVoiceCall queues a phone-call page. Nothing is queued for other
+// notifications, unless the user enabled calls on a verified number, or for
+// incidents that are test incidents or no longer triggered.
+func EnqueueVoiceCall(pg *sql.DB, userID, incidentID, notificationType string) error {
+	if !isPageNotification(notificationType) {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_id":     userID,
+		"incident_id": incidentID,
+		"type":        notificationType,
+		"created_at":  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal voice call: %w", err)
+	}
+
+	_, err = pg.Exec(`
+		SELECT pgmq.send($1, $2)
+		WHERE EXISTS (
+			SELECT 1 FROM sms_phones p, incidents i
+			WHERE p.user_id::text = $3 AND p.verified_at IS NOT NULL AND p.voice_enabled
+			AND i.id = $4 AND i.status = 'triggered' AND NOT COALESCE(i.is_test, false)
+		)
+	`, voiceCallQueue, string(payload), userID, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to queue voice call: %w", err)
+	}
+	return nil
+}
+
+// voiceIncident is what a call says about an incident
+type voiceIncident struct {
+	Title       string
+	Status      string
+	Severity    string
+	ServiceName string
+}
+
+func (s *VoiceService) getVoiceIncident(incidentID string) (*voiceIncident, error) {
+	var inc voiceIncident
+	err := s.PG.QueryRow(`
+		SELECT i.title, i.status, COALESCE(i.severity, ''), COALESCE(sv.name, '')
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		WHERE i.id = $1
+	`, incidentID).Scan(&inc.Title, &inc.Status, &inc.Severity, &inc.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// DeliverIncidentCall calls a user about an incident that is still triggered
+func (s *VoiceService) DeliverIncidentCall(userID, incidentID, notificationType string) error {
+	if !s.IsConfigured() {
+		return nil
+	}
+
+	var phone string
+	err := s.PG.QueryRow(`
+		SELECT phone_number FROM sms_phones
+		WHERE user_id = $1 AND verified_at IS NOT NULL AND voice_enabled
+	`, userID).Scan(&phone)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get phone for voice call: %w", err)
+	}
+	decryptColumns(&phone)
+
+	inc, err := s.getVoiceIncident(incidentID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident for voice call: %w", err)
+	}
+	if inc.Status != db.IncidentStatusTriggered {
+		return nil // acknowledged while queued
+	}
+
+	call := db.VoiceCall{IncidentID: incidentID, UserID: userID, NotificationType: notificationType}
+	if err := s.PG.QueryRow(`
+		INSERT INTO voice_calls (incident_id, user_id, notification_type)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, incidentID, userID, notificationType).Scan(&call.ID); err != nil {
+		return fmt.Errorf("failed to record voice call: %w", err)
+	}
+
+	sid, err := s.placeCall(phone, call.ID)
+	if err != nil {
+		call.Status, call.Outcome = "failed", db.VoiceOutcomeFailed
+		if _, dbErr := s.PG.Exec(`
+			UPDATE voice_calls SET status = $2, outcome = $3, updated_at = NOW() WHERE id = $1
+		`, call.ID, call.Status, call.Outcome); dbErr != nil {
+			log.Printf("⚠️  Failed to update voice call %s: %v", call.ID, dbErr)
+		}
+		s.recordVoiceCallEvent(&call)
+		return err
+	}
+
+	if _, err := s.PG.Exec(`UPDATE voice_calls SET call_sid = $2, updated_at = NOW() WHERE id = $1`, call.ID, sid); err != nil {
+		log.Printf("⚠️  Failed to store call SID for voice call %s: %v", call.ID, err)
+	}
+	log.Printf("📞 Calling user %s about incident %s (call %s)", userID, incidentID, call.ID)
+	return nil
+}
+
+// placeCall starts a Twilio call whose TwiML and status updates come back to SLAR
+func (s *VoiceService) placeCall(to, callID string) (string, error) {
+	if err := faults.ProviderOutage("voice"); err != nil {
+		return "", err
+	}
+	t := config.Current().Twilio
+	form := url.Values{
+		"To":                   {to},
+		"From":                 {t.PhoneNumber},
+		"Url":                  {s.CallbackURL(callID, "")},
+		"Method":               {http.MethodPost},
+		"StatusCallback":       {s.CallbackURL(callID, "status")},
+		"StatusCallbackMethod": {http.MethodPost},
+		"Timeout":              {"30"},
+	}
+	req, err := http.NewRequest(http.MethodPost, twilioAPIBaseURL+"/Accounts/"+t.AccountSID+"/Calls.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to place voice call: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("twilio rejected voice call with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var created struct {
+		SID string `json:"sid"`
+	}
+	json.Unmarshal(body, &created)
+	return created.SID, nil
+}
+
+// getVoiceCall loads a call by ID
+func (s *VoiceService) getVoiceCall(callID string) (*db.VoiceCall, error) {
+	var c db.VoiceCall
+	var sid, outcome, digits sql.NullString
+	var duration sql.NullInt64
+	err := s.PG.QueryRow(`
+		SELECT id, incident_id, user_id, notification_type, call_sid, status, outcome, digits,
+		       duration_seconds, created_at, updated_at
+		FROM voice_calls
+		WHERE id::text = $1
+	`, callID).Scan(&c.ID, &c.IncidentID, &c.UserID, &c.NotificationType, &sid, &c.Status, &outcome, &digits,
+		&duration, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrVoiceCallNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voice call: %w", err)
+	}
+	c.CallSID, c.Outcome, c.Digits = sid.String, outcome.String, digits.String
+	c.DurationSeconds = int(duration.Int64)
+	return &c, nil
+}
+
+// CallTwiML is what a call says when answered: the incident summary and menu
+func (s *VoiceService) CallTwiML(callID string) (string, error) {
+	call, err := s.getVoiceCall(callID)
+	if err != nil {
+		return "", err
+	}
+	inc, err := s.getVoiceIncident(call.IncidentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get incident for voice call: %w", err)
+	}
+	if inc.Status != db.IncidentStatusTriggered {
+		return twimlDocument(twimlSay(fmt.Sprintf("The SLAR incident %s is already %s. Goodbye.", inc.Title, inc.Status)), "<Hangup/>"), nil
+	}
+	return s.menuTwiML(callID, spokenIncidentSummary(inc)), nil
+}
+
+// menuTwiML reads intro, then waits for one key
+func (s *VoiceService) menuTwiML(callID, intro string) string {
+	gather := fmt.Sprintf(`<Gather numDigits="1" timeout="10" method="POST" action="%s">`, xmlText(s.CallbackURL(callID, "gather"))) +
+		twimlSay(intro) +
+		twimlSay("Press "+db.VoiceDigitAcknowledge+" to acknowledge. Press "+db.VoiceDigitEscalate+" to escalate.") +
+		"</Gather>"
+	return twimlDocument(gather, twimlSay("No option was chosen. Goodbye."), "<Hangup/>")
+}
+
+// spokenIncidentSummary introduces the incident: severity, service and title
+func spokenIncidentSummary(inc *voiceIncident) string {
+	var b strings.Builder
+	b.WriteString("This is SLAR. ")
+	if inc.Severity != "" {
+		b.WriteString("A " + inc.Severity + " severity incident")
+	} else {
+		b.WriteString("An incident")
+	}
+	if inc.ServiceName != "" {
+		b.WriteString(" on " + inc.ServiceName)
+	}
+	b.WriteString(" needs your attention: " + strings.TrimRight(inc.Title, ". ") + ".")
+	return b.String()
+}
+
+// HandleGather acts on the key pressed during a call and returns what to say
+func (s *VoiceService) HandleGather(callID, digits string) (string, error) {
+	call, err := s.getVoiceCall(callID)
+	if err != nil {
+		return "", err
+	}
+	if call.Outcome != "" {
+		return twimlDocument(twimlSay("This call was already handled. Goodbye."), "<Hangup/>"), nil
+	}
+	inc, err := s.getVoiceIncident(call.IncidentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get incident for voice call: %w", err)
+	}
+	if inc.Status != db.IncidentStatusTriggered && (digits == db.VoiceDigitAcknowledge || digits == db.VoiceDigitEscalate) {
+		return twimlDocument(twimlSay(fmt.Sprintf("The incident is already %s. Goodbye.", inc.Status)), "<Hangup/>"), nil
+	}
+
+	var reply string
+	switch digits {
+	case db.VoiceDigitAcknowledge:
+		if err := s.IncidentService.AcknowledgeIncident(call.IncidentID, call.UserID, "Acknowledged by phone"); err != nil {
+			return "", err
+		}
+		call.Outcome, reply = db.VoiceOutcomeAcknowledged, "The incident is acknowledged. Goodbye."
+	case db.VoiceDigitEscalate:
+		if _, err := s.IncidentService.ManualEscalateIncident(call.IncidentID, call.UserID); err != nil {
+			log.Printf("⚠️  Voice call %s could not escalate incident %s: %v", call.ID, call.IncidentID, err)
+			call.Outcome, reply = db.VoiceOutcomeEscalationFailed, "The incident could not be escalated: "+err.Error()+". It is still triggered. Goodbye."
+		} else {
+			call.Outcome, reply = db.VoiceOutcomeEscalated, "The incident is escalated to the next level. Goodbye."
+		}
+	default:
+		return s.menuTwiML(callID, "That is not an option."), nil
+	}
+
+	call.Digits = digits
+	if s.completeVoiceCall(call) {
+		s.recordVoiceCallEvent(call)
+	}
+	return twimlDocument(twimlSay(reply), "<Hangup/>"), nil
+}
+
+// HandleStatus records Twilio's status updates. A call that ends without a
+// choice is recorded as no input or unanswered.
+func (s *VoiceService) HandleStatus(callID, callStatus string, durationSeconds int) error {
+	call, err := s.getVoiceCall(callID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.PG.Exec(`
+		UPDATE voice_calls SET status = $2, duration_seconds = NULLIF($3, 0), updated_at = NOW() WHERE id = $1
+	`, call.ID, callStatus, durationSeconds); err != nil {
+		return fmt.Errorf("failed to update voice call: %w", err)
+	}
+	call.Status, call.DurationSeconds = callStatus, durationSeconds
+
+	switch callStatus {
+	case "completed":
+		call.Outcome = db.VoiceOutcomeNoInput
+	case "busy", "no-answer", "canceled":
+		call.Outcome = db.VoiceOutcomeUnanswered
+	case "failed":
+		call.Outcome = db.VoiceOutcomeFailed
+	default:
+		return nil // still ringing or in progress
+	}
+	if s.completeVoiceCall(call) {
+		s.recordVoiceCallEvent(call)
+	}
+	return nil
+}
+
+// completeVoiceCall stores a call's outcome unless it already has one, and
+// reports whether it did
+func (s *VoiceService) completeVoiceCall(call *db.VoiceCall) bool {
+	result, err := s.PG.Exec(`
+		UPDATE voice_calls SET outcome = $2, digits = NULLIF($3, ''), updated_at = NOW()
+		WHERE id = $1 AND outcome IS NULL
+	`, call.ID, call.Outcome, call.Digits)
+	if err != nil {
+		log.Printf("⚠️  Failed to record outcome of voice call %s: %v", call.ID, err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// recordVoiceCallEvent adds the call's outcome to the incident timeline
+func (s *VoiceService) recordVoiceCallEvent(call *db.VoiceCall) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"call_id":           call.ID,
+		"user_id":           call.UserID,
+		"notification_type": call.NotificationType,
+		"outcome":           call.Outcome,
+		"call_status":       call.Status,
+		"duration_seconds":  call.DurationSeconds,
+	})
+	if _, err := s.PG.Exec(`
+		INSERT INTO incident_events (incident_id, event_type, event_data, created_by)
+		VALUES ($1, $2, $3, $4)
+	`, call.IncidentID, db.IncidentEventVoiceCall, string(data), call.UserID); err != nil {
+		log.Printf("⚠️  Failed to record voice call event for incident %s: %v", call.IncidentID, err)
+	}
+}
+
+// TWIML
+
+func twimlDocument(verbs ...string) string {
+	return xml.Header + "<Response>" + strings.Join(verbs, "") + "</Response>"
+}
+
+func twimlSay(text string) string {
+	return "<Say>" + xmlText(text) + "</Say>"
+}
+
+// xmlText escapes s for element text and double-quoted attributes
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+var voiceCallColumns = []string{"id", "incident_id", "user_id", "notification_type", "call_sid", "status",
+	"outcome", "digits", "duration_seconds", "created_at", "updated_at"}
+
+func TestSpokenIncidentSummary(t *testing.T) {
+	got := spokenIncidentSummary(&voiceIncident{Title: "Disk full on db-1.", Severity: "critical", ServiceName: "Payments"})
+	want := "This is SLAR. A critical severity incident on Payments needs your attention: Disk full on db-1."
+	if got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if got := spokenIncidentSummary(&voiceIncident{Title: "Latency"}); got != "This is SLAR. An incident needs your attention: Latency." {
+		t.Errorf("summary without severity or service = %q", got)
+	}
+}
+
+func TestCallTwiMLOffersMenu(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM voice_calls`).WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows(voiceCallColumns).
+			AddRow("call-1", "inc-1", "user-1", "assigned", "CA1", "in-progress", nil, nil, nil, now, now))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "status", "severity", "service"}).
+			AddRow("Disk <full>", "triggered", "high", "Payments"))
+
+	twiml, err := NewVoiceService(pg, nil).CallTwiML("call-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<Gather numDigits="1"`, "/voice/twilio/calls/call-1/gather", "Disk &lt;full&gt;", "Press 1 to acknowledge. Press 2 to escalate."} {
+		if !strings.Contains(twiml, want) {
+			t.Errorf("TwiML missing %q:\n%s", want, twiml)
+		}
+	}
+}
+
+func TestHandleGatherAcknowledges(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM voice_calls`).WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows(voiceCallColumns).
+			AddRow("call-1", "inc-1", "user-1", "assigned", "CA1", "in-progress", nil, nil, nil, now, now))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "status", "severity", "service"}).
+			AddRow("Disk full", "triggered", "", ""))
+	mock.ExpectExec(`UPDATE incidents`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE voice_calls SET outcome`).
+		WithArgs("call-1", db.VoiceOutcomeAcknowledged, "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventVoiceCall, sqlmock.AnyArg(), "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	twiml, err := NewVoiceService(pg, &IncidentService{PG: pg}).HandleGather("call-1", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(twiml, "The incident is acknowledged.") {
+		t.Errorf("unexpected TwiML %s", twiml)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandleStatusRecordsUnansweredCall(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM voice_calls`).WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows(voiceCallColumns).
+			AddRow("call-1", "inc-1", "user-1", "escalated", "CA1", "ringing", nil, nil, nil, now, now))
+	mock.ExpectExec(`UPDATE voice_calls SET status`).WithArgs("call-1", "no-answer", 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE voice_calls SET outcome`).
+		WithArgs("call-1", db.VoiceOutcomeUnanswered, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO incident_events`).
+		WithArgs("inc-1", db.IncidentEventVoiceCall, sqlmock.AnyArg(), "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := NewVoiceService(pg, nil).HandleStatus("call-1", "no-answer", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeliverIncidentCall(t *testing.T) {
+	old := config.Current().Twilio
+	oldBase := twilioAPIBaseURL
+	defer func() {
+		config.Update(func(c *config.Config) { c.Twilio = old })
+		twilioAPIBaseURL = oldBase
+	}()
+	config.Update(func(c *config.Config) {
+		c.Twilio = config.TwilioConfig{AccountSID: "AC1", AuthToken: "token", PhoneNumber: "+14155550100"}
+	})
+
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Path != "/Accounts/AC1/Calls.json" || user != "AC1" || pass != "token" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, user)
+		}
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"CA123"}`))
+	}))
+	defer server.Close()
+	twilioAPIBaseURL = server.URL
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	mock.ExpectQuery(`SELECT phone_number FROM sms_phones`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"phone_number"}).AddRow("+14155550123"))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "status", "severity", "service"}).
+			AddRow("Disk full", "triggered", "", ""))
+	mock.ExpectQuery(`INSERT INTO voice_calls`).WithArgs("inc-1", "user-1", "assigned").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("call-1"))
+	mock.ExpectExec(`UPDATE voice_calls SET call_sid`).WithArgs("call-1", "CA123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := NewVoiceService(pg, nil).DeliverIncidentCall("user-1", "inc-1", "assigned"); err != nil {
+		t.Fatal(err)
+	}
+	if form["To"][0] != "+14155550123" || form["From"][0] != "+14155550100" {
+		t.Errorf("unexpected call numbers %v", form)
+	}
+	if !strings.HasSuffix(form["Url"][0], "/voice/twilio/calls/call-1") || !strings.HasSuffix(form["StatusCallback"][0], "/voice/twilio/calls/call-1/status") {
+		t.Errorf("unexpected callbacks %v", form)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
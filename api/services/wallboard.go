@@ -0,0 +1,350 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+const (
+	// WallboardRefreshInterval is the polling interval suggested to displays
+	WallboardRefreshInterval = 30 * time.Second
+
+	maxWallboardIncidents = 200
+)
+
+var (
+	ErrWallboardTokenNotFound = errors.New("wallboard token not found")
+	ErrWallboardUnauthorized  = errors.New("invalid wallboard token")
+)
+
+// wallboardSeverityRank orders severity buckets on the display, most severe first.
+// Incidents without a severity fall back to their urgency.
+var wallboardSeverityRank = map[string]int{
+	"critical": 0,
+	"high":     1,
+	"error":    2,
+	"warning":  3,
+	"medium":   4,
+	"low":      5,
+	"info":     6,
+}
+
+// WallboardService serves read-only NOC/TV dashboards authenticated by a
+// wallboard token instead of a user session
+type WallboardService struct {
+	PG *sql.DB
+}
+
+// NewWallboardService creates a new WallboardService
+func NewWallboardService(pg *sql.DB) *WallboardService {
+	return &WallboardService{PG: pg}
+}
+
+// TOKENS
+
+// CreateToken issues a new wallboard token for an org. The raw token is only returned once.
+func (s *WallboardService) CreateToken(orgID, projectID, name, createdBy string) (*db.WallboardToken, error) {
+	raw, _, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	raw = "wb_" + raw
+
+	t := db.WallboardToken{OrganizationID: orgID, ProjectID: projectID, Name: name, IsActive: true, CreatedBy: createdBy, Token: raw}
+	err = s.PG.QueryRow(`
+		INSERT INTO wallboard_tokens (organization_id, project_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, orgID, nullIfEmpty(projectID), name, hashInvitationToken(raw), nullIfEmpty(createdBy)).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallboard token: %w", err)
+	}
+	return &t, nil
+}
+
+// ListTokens returns wallboard tokens for an org (without secrets)
+func (s *WallboardService) ListTokens(orgID string) ([]db.WallboardToken, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, organization_id, COALESCE(project_id::text, ''), name, is_active,
+		       COALESCE(created_by::text, ''), last_used_at, created_at
+		FROM wallboard_tokens
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []db.WallboardToken{}
+	for rows.Next() {
+		var t db.WallboardToken
+		if err := rows.Scan(&t.ID, &t.OrganizationID, &t.ProjectID, &t.Name, &t.IsActive, &t.CreatedBy, &t.LastUsedAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken deactivates a wallboard token
+func (s *WallboardService) RevokeToken(orgID, tokenID string) error {
+	result, err := s.PG.Exec(`UPDATE wallboard_tokens SET is_active = FALSE WHERE id = $1 AND organization_id = $2`, tokenID, orgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWallboardTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateToken resolves a raw wallboard token to its org (and optional project) scope
+func (s *WallboardService) AuthenticateToken(raw string) (*db.WallboardToken, error) {
+	var t db.WallboardToken
+	err := s.PG.QueryRow(`
+		SELECT id, organization_id, COALESCE(project_id::text, ''), name
+		FROM wallboard_tokens
+		WHERE token_hash = $1 AND is_active = TRUE
+	`, hashInvitationToken(raw)).Scan(&t.ID, &t.OrganizationID, &t.ProjectID, &t.Name)
+	if err == sql.ErrNoRows {
+		return nil, ErrWallboardUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.IsActive = true
+
+	go func() {
+		if _, err := s.PG.Exec(`UPDATE wallboard_tokens SET last_used_at = NOW() WHERE id = $1`, t.ID); err != nil {
+			log.Printf("Warning: failed to update wallboard token last_used_at: %v", err)
+		}
+	}()
+	return &t, nil
+}
+
+// DISPLAY DATA
+
+// GetWallboard returns incidents, on-call roster and monitor status in one bundle
+func (s *WallboardService) GetWallboard(orgID, projectID string) (*db.Wallboard, error) {
+	incidents, err := s.GetOpenIncidents(orgID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	oncall, err := s.GetOnCallRoster(orgID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	monitors, err := s.GetMonitorStatus(orgID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &db.Wallboard{
+		GeneratedAt:            time.Now().UTC(),
+		RefreshIntervalSeconds: int(WallboardRefreshInterval.Seconds()),
+		Incidents:              *incidents,
+		OnCall:                 oncall,
+		Monitors:               *monitors,
+	}, nil
+}
+
+// GetOpenIncidents returns triggered and acknowledged incidents grouped by severity
+func (s *WallboardService) GetOpenIncidents(orgID, projectID string) (*db.WallboardIncidents, error) {
+	query := `
+		SELECT i.id, i.title, i.status, i.urgency, COALESCE(i.severity, ''),
+		       COALESCE(s.name, ''), COALESCE(g.name, ''), COALESCE(u.name, ''), i.created_at
+		FROM incidents i
+		LEFT JOIN services s ON i.service_id = s.id
+		LEFT JOIN groups g ON i.group_id = g.id
+		LEFT JOIN users u ON i.assigned_to = u.id
+		WHERE i.organization_id = $1
+		  AND i.status IN ('triggered', 'acknowledged') AND NOT i.is_test
+		  AND ($2 = '' OR i.project_id::text = $2)
+		ORDER BY i.created_at DESC
+		LIMIT $3
+	`
+	incidents, err := queryWallboardIncidents(s.PG, query, orgID, projectID, maxWallboardIncidents)
+	if err != nil {
+		return nil, err
+	}
+	return groupWallboardIncidents(incidents), nil
+}
+
+// queryWallboardIncidents runs a query selecting the WallboardIncident columns
+func queryWallboardIncidents(pg *sql.DB, query string, args ...interface{}) ([]db.WallboardIncident, error) {
+	rows, err := pg.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallboard incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []db.WallboardIncident{}
+	for rows.Next() {
+		var inc db.WallboardIncident
+		if err := rows.Scan(&inc.ID, &inc.Title, &inc.Status, &inc.Urgency, &inc.Severity,
+			&inc.ServiceName, &inc.GroupName, &inc.AssignedToName, &inc.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wallboard incident: %w", err)
+		}
+		incidents = append(incidents, inc)
+	}
+	return incidents, rows.Err()
+}
+
+// groupWallboardIncidents buckets incidents by severity, most severe first.
+// Input order (newest first) is kept within a bucket.
+func groupWallboardIncidents(incidents []db.WallboardIncident) *db.WallboardIncidents {
+	result := &db.WallboardIncidents{BySeverity: []db.WallboardSeverityGroup{}}
+	index := map[string]int{}
+	for _, inc := range incidents {
+		inc.Severity = wallboardSeverity(inc.Severity, inc.Urgency)
+
+		result.Total++
+		switch inc.Status {
+		case "triggered":
+			result.Triggered++
+		case "acknowledged":
+			result.Acknowledged++
+		}
+
+		i, ok := index[inc.Severity]
+		if !ok {
+			i = len(result.BySeverity)
+			index[inc.Severity] = i
+			result.BySeverity = append(result.BySeverity, db.WallboardSeverityGroup{Severity: inc.Severity})
+		}
+		result.BySeverity[i].Count++
+		result.BySeverity[i].Incidents = append(result.BySeverity[i].Incidents, inc)
+	}
+
+	sort.SliceStable(result.BySeverity, func(a, b int) bool {
+		return wallboardSeverityOrder(result.BySeverity[a].Severity) < wallboardSeverityOrder(result.BySeverity[b].Severity)
+	})
+	return result
+}
+
+func wallboardSeverity(severity, urgency string) string {
+	if severity = strings.ToLower(strings.TrimSpace(severity)); severity != "" {
+		return severity
+	}
+	if urgency = strings.ToLower(strings.TrimSpace(urgency)); urgency != "" {
+		return urgency
+	}
+	return "unknown"
+}
+
+func wallboardSeverityOrder(severity string) int {
+	if rank, ok := wallboardSeverityRank[severity]; ok {
+		return rank
+	}
+	return len(wallboardSeverityRank)
+}
+
+// GetOnCallRoster returns who is currently on call for every group in scope.
+// Groups with nobody on call are included with an empty user so gaps are visible.
+func (s *WallboardService) GetOnCallRoster(orgID, projectID string) ([]db.WallboardOnCall, error) {
+	query := `
+		SELECT g.id, g.name, COALESCE(u.name, ''), COALESCE(u.email, ''), cur.end_time, COALESCE(cur.is_override, FALSE)
+		FROM groups g
+		LEFT JOIN LATERAL (
+			SELECT sh.user_id, sh.end_time, sh.is_override
+			FROM shifts sh
+			WHERE sh.group_id = g.id
+			  AND sh.is_active = true
+			  AND NOW() BETWEEN sh.start_time AND sh.end_time
+			ORDER BY sh.is_override DESC, sh.start_time ASC
+			LIMIT 1
+		) cur ON TRUE
+		LEFT JOIN users u ON cur.user_id = u.id
+		WHERE g.organization_id = $1
+		  AND g.is_active = true
+		  AND ($2 = '' OR g.project_id::text = $2)
+		ORDER BY g.name ASC
+	`
+	return queryWallboardOnCall(s.PG, query, orgID, projectID)
+}
+
+// queryWallboardOnCall runs a query selecting the WallboardOnCall columns
+func queryWallboardOnCall(pg *sql.DB, query string, args ...interface{}) ([]db.WallboardOnCall, error) {
+	rows, err := pg.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallboard on-call roster: %w", err)
+	}
+	defer rows.Close()
+
+	roster := []db.WallboardOnCall{}
+	for rows.Next() {
+		var entry db.WallboardOnCall
+		var shiftEnd sql.NullTime
+		if err := rows.Scan(&entry.GroupID, &entry.GroupName, &entry.UserName, &entry.UserEmail, &shiftEnd, &entry.IsOverride); err != nil {
+			return nil, fmt.Errorf("failed to scan wallboard on-call entry: %w", err)
+		}
+		if shiftEnd.Valid {
+			entry.ShiftEnd = &shiftEnd.Time
+		}
+		roster = append(roster, entry)
+	}
+	return roster, rows.Err()
+}
+
+// GetMonitorStatus returns the last check result of every active uptime monitor in scope
+func (s *WallboardService) GetMonitorStatus(orgID, projectID string) (*db.WallboardMonitors, error) {
+	query := `
+		SELECT m.id, m.name, COALESCE(g.name, ''), m.is_up, m.last_latency, COALESCE(m.last_error, ''), m.last_check_at
+		FROM monitors m
+		JOIN monitor_deployments d ON m.deployment_id = d.id
+		JOIN groups g ON d.group_id = g.id
+		WHERE g.organization_id = $1
+		  AND m.is_active = true
+		  AND ($2 = '' OR g.project_id::text = $2)
+		ORDER BY m.is_up ASC NULLS FIRST, m.name ASC
+	`
+	return queryWallboardMonitors(s.PG, query, orgID, projectID)
+}
+
+// queryWallboardMonitors runs a query selecting the WallboardMonitor columns
+// and tallies the results
+func queryWallboardMonitors(pg *sql.DB, query string, args ...interface{}) (*db.WallboardMonitors, error) {
+	rows, err := pg.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallboard monitors: %w", err)
+	}
+	defer rows.Close()
+
+	result := &db.WallboardMonitors{Monitors: []db.WallboardMonitor{}}
+	for rows.Next() {
+		var m db.WallboardMonitor
+		var isUp sql.NullBool
+		var latency sql.NullInt64
+		var lastCheck sql.NullTime
+		if err := rows.Scan(&m.ID, &m.Name, &m.GroupName, &isUp, &latency, &m.LastError, &lastCheck); err != nil {
+			return nil, fmt.Errorf("failed to scan wallboard monitor: %w", err)
+		}
+		result.Total++
+		switch {
+		case !isUp.Valid:
+			result.Unknown++
+		case isUp.Bool:
+			m.IsUp = &isUp.Bool
+			result.Up++
+		default:
+			m.IsUp = &isUp.Bool
+			result.Down++
+		}
+		if latency.Valid {
+			l := int(latency.Int64)
+			m.LastLatency = &l
+		}
+		if lastCheck.Valid {
+			m.LastCheckAt = &lastCheck.Time
+		}
+		result.Monitors = append(result.Monitors, m)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+func TestGroupWallboardIncidents(t *testing.T) {
+	incidents := []db.WallboardIncident{
+		{ID: "1", Status: "triggered", Urgency: "low", Severity: "warning"},
+		{ID: "2", Status: "acknowledged", Urgency: "high", Severity: "Critical"},
+		{ID: "3", Status: "triggered", Urgency: "high"},
+		{ID: "4", Status: "triggered", Urgency: "low", Severity: "critical"},
+		{ID: "5", Status: "triggered", Severity: "sev-custom"},
+	}
+
+	got := groupWallboardIncidents(incidents)
+
+	if got.Total != 5 || got.Triggered != 4 || got.Acknowledged != 1 {
+		t.Fatalf("counts = (%d, %d, %d), want (5, 4, 1)", got.Total, got.Triggered, got.Acknowledged)
+	}
+
+	wantOrder := []struct {
+		severity string
+		ids      []string
+	}{
+		{"critical", []string{"2", "4"}},
+		{"high", []string{"3"}},
+		{"warning", []string{"1"}},
+		{"sev-custom", []string{"5"}},
+	}
+	if len(got.BySeverity) != len(wantOrder) {
+		t.Fatalf("got %d severity groups, want %d", len(got.BySeverity), len(wantOrder))
+	}
+	for i, want := range wantOrder {
+		group := got.BySeverity[i]
+		if group.Severity != want.severity {
+			t.Errorf("group %d severity = %q, want %q", i, group.Severity, want.severity)
+		}
+		if group.Count != len(want.ids) {
+			t.Errorf("group %q count = %d, want %d", group.Severity, group.Count, len(want.ids))
+		}
+		for j, id := range want.ids {
+			if j < len(group.Incidents) && group.Incidents[j].ID != id {
+				t.Errorf("group %q incident %d = %s, want %s", group.Severity, j, group.Incidents[j].ID, id)
+			}
+		}
+	}
+}
+
+func TestGroupWallboardIncidentsEmpty(t *testing.T) {
+	got := groupWallboardIncidents(nil)
+	if got.Total != 0 || got.BySeverity == nil || len(got.BySeverity) != 0 {
+		t.Errorf("groupWallboardIncidents(nil) = %+v, want empty non-nil groups", got)
+	}
+}
@@ -0,0 +1,533 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/internal/faults"
+)
+
+var (
+	ErrInvalidWebPushSubscription  = errors.New("invalid push subscription")
+	ErrWebPushSubscriptionNotFound = errors.New("push subscription not found")
+	ErrWebPushSubscriptionTaken    = errors.New("this browser is subscribed for another user")
+)
+
+const webPushNotificationQueue = "webpush_notifications"
+
+// errWebPushGone means the push service dropped the subscription (the user
+// revoked permission or the browser unsubscribed)
+var errWebPushGone = errors.New("push subscription expired")
+
+// WebPushService delivers incident notifications to browsers through the
+// Push API, signed with the server's VAPID key (RFC 8292) and encrypted for
+// each subscription (RFC 8291)
+type WebPushService struct {
+	PG     *sql.DB
+	client *http.Client
+}
+
+func NewWebPushService(pg *sql.DB) *WebPushService {
+	return &WebPushService{
+		PG:     pg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsConfigured reports whether a VAPID key pair is set
+func (s *WebPushService) IsConfigured() bool {
+	return config.Current().WebPush.VAPIDPublicKey != "" && config.Current().WebPush.VAPIDPrivateKey != ""
+}
+
+// PublicKey is the VAPID application server key browsers subscribe with
+func (s *WebPushService) PublicKey() string {
+	return config.Current().WebPush.VAPIDPublicKey
+}
+
+// SUBSCRIPTIONS
+
+// decodeWebPushKey decodes a base64url value as sent by browsers, with or without padding
+func decodeWebPushKey(v string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(v), "="))
+}
+
+// webPushServiceHosts are the browsers' push services subscriptions may point
+// at; a leading dot matches any subdomain
+var webPushServiceHosts = []string{
+	"fcm.googleapis.com",                // Chrome, Edge on Android, Opera
+	"updates.push.services.mozilla.com", // Firefox
+	".notify.windows.com",               // Edge on Windows
+	".push.apple.com",                   // Safari
+}
+
+// isWebPushServiceEndpoint reports whether endpoint is an https URL on one
+// of the known push services, so deliveries never reach arbitrary hosts
+func isWebPushServiceEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil || (u.Port() != "" && u.Port() != "443") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range webPushServiceHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func webPushEndpointLookup(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateWebPushSubscription checks a subscription is usable before it is stored
+func validateWebPushSubscription(req db.WebPushSubscribeRequest) error {
+	u, err := url.Parse(req.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: endpoint must be an https URL", ErrInvalidWebPushSubscription)
+	}
+	if !isWebPushServiceEndpoint(req.Endpoint) {
+		return fmt.Errorf("%w: endpoint is not a known browser push service", ErrInvalidWebPushSubscription)
+	}
+	p256dh, err := decodeWebPushKey(req.Keys.P256dh)
+	if err != nil || len(p256dh) != 65 || p256dh[0] != 0x04 {
+		return fmt.Errorf("%w: keys.p256dh must be an uncompressed P-256 public key", ErrInvalidWebPushSubscription)
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return fmt.Errorf("%w: keys.p256dh is not a valid P-256 public key", ErrInvalidWebPushSubscription)
+	}
+	auth, err := decodeWebPushKey(req.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return fmt.Errorf("%w: keys.auth must be a 16-byte secret", ErrInvalidWebPushSubscription)
+	}
+	return nil
+}
+
+// Subscribe registers a browser for a user. Subscribing the same browser
+// again replaces its keys; a browser subscribed for another user is refused
+// until that user unsubscribes it.
+func (s *WebPushService) Subscribe(userID string, req db.WebPushSubscribeRequest) (*db.WebPushSubscription, error) {
+	req.Endpoint = strings.TrimSpace(req.Endpoint)
+	if err := validateWebPushSubscription(req); err != nil {
+		return nil, err
+	}
+
+	encEndpoint, err := encryptColumn(req.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	encP256dh, err := encryptColumn(strings.TrimSpace(req.Keys.P256dh))
+	if err != nil {
+		return nil, err
+	}
+	encAuth, err := encryptColumn(strings.TrimSpace(req.Keys.Auth))
+	if err != nil {
+		return nil, err
+	}
+
+	sub := db.WebPushSubscription{UserID: userID, UserAgent: truncateRunes(strings.TrimSpace(req.UserAgent), 255)}
+	err = s.PG.QueryRow(`
+		INSERT INTO web_push_subscriptions (user_id, endpoint, endpoint_lookup, p256dh, auth, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (endpoint_lookup) DO UPDATE
+		SET endpoint = EXCLUDED.endpoint, p256dh = EXCLUDED.p256dh,
+		    auth = EXCLUDED.auth, user_agent = EXCLUDED.user_agent
+		WHERE web_push_subscriptions.user_id = EXCLUDED.user_id
+		RETURNING id, created_at
+	`, userID, encEndpoint, webPushEndpointLookup(req.Endpoint), encP256dh, encAuth, nullIfEmpty(sub.UserAgent)).
+		Scan(&sub.ID, &sub.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebPushSubscriptionTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save push subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions returns a user's subscribed browsers, including their keys
+func (s *WebPushService) ListSubscriptions(userID string) ([]db.WebPushSubscription, error) {
+	rows, err := s.PG.Query(`
+		SELECT id, user_id, endpoint, p256dh, auth, COALESCE(user_agent, ''), created_at, last_used_at
+		FROM web_push_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []db.WebPushSubscription{}
+	for rows.Next() {
+		var sub db.WebPushSubscription
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Endpoint, &sub.P256dh, &sub.Auth, &sub.UserAgent,
+			&sub.CreatedAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		decryptColumns(&sub.Endpoint, &sub.P256dh, &sub.Auth)
+		if lastUsedAt.Valid {
+			sub.LastUsedAt = &lastUsedAt.Time
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Unsubscribe removes the subscription of the browser with this endpoint
+func (s *WebPushService) Unsubscribe(userID, endpoint string) error {
+	_, err := s.PG.Exec(`DELETE FROM web_push_subscriptions WHERE user_id = $1 AND endpoint_lookup = $2`,
+		userID, webPushEndpointLookup(strings.TrimSpace(endpoint)))
+	if err != nil {
+		return fmt.Errorf("failed to remove push subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription removes one of a user's subscriptions by ID
+func (s *WebPushService) DeleteSubscription(userID, subscriptionID string) error {
+	result, err := s.PG.Exec(`DELETE FROM web_push_subscriptions WHERE id = $1 AND user_id = $2`, subscriptionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove push subscription: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWebPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// NOTIFICATIONS
+
+// EnqueueWebPushNotification queues an incident notification for a user's
+// browsers. Nothing is queued unless the user has a subscription, never for
+// test incidents, and not for updates the incident's commander muted the user
+// from.
+func EnqueueWebPushNotification(pg *sql.DB, userID, incidentID, notificationType string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_id":     userID,
+		"incident_id": incidentID,
+		"type":        notificationType,
+		"created_at":  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal web push notification: %w", err)
+	}
+
+	_, err = pg.Exec(`
+		SELECT pgmq.send($1, $2)
+		WHERE EXISTS (
+			SELECT 1 FROM web_push_subscriptions w, incidents i
+			WHERE w.user_id::text = $3
+			AND i.id = $4 AND NOT COALESCE(i.is_test, false)
+			AND NOT (NOT $5 AND EXISTS (
+				SELECT 1 FROM incident_notification_settings ns
+				WHERE ns.incident_id = i.id AND $3 = ANY(ns.muted_user_ids)
+			))
+		)
+	`, webPushNotificationQueue, string(payload), userID, incidentID, isPageNotification(notificationType))
+	if err != nil {
+		return fmt.Errorf("failed to queue web push notification: %w", err)
+	}
+	return nil
+}
+
+// webPushPayload is what the service worker receives and shows
+type webPushPayload struct {
+	Type               string `json:"type"`
+	IncidentID         string `json:"incident_id"`
+	Title              string `json:"title"`
+	Body               string `json:"body"`
+	URL                string `json:"url"`
+	AckURL             string `json:"ack_url,omitempty"`
+	Tag                string `json:"tag"`
+	RequireInteraction bool   `json:"require_interaction"`
+}
+
+// DeliverIncidentNotification sends an incident notification to every browser
+// a user subscribed. Browsers the push service no longer knows are removed.
+// It fails only when no browser could be reached, so the notification is retried.
+func (s *WebPushService) DeliverIncidentNotification(userID, incidentID, notificationType string) error {
+	if !s.IsConfigured() {
+		return nil
+	}
+	subs, err := s.ListSubscriptions(userID)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+
+	inc := chatIncident{ID: incidentID}
+	err = s.PG.QueryRow(`
+		SELECT i.title, i.status, COALESCE(i.severity, ''), i.urgency, COALESCE(sv.name, ''), COALESCE(u.name, ''), i.short_id
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		LEFT JOIN users u ON u.id::text = $2
+		WHERE i.id = $1
+	`, incidentID, userID).Scan(&inc.Title, &inc.Status, &inc.Severity, &inc.Urgency, &inc.ServiceName, &inc.UserName, &inc.ShortID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident for web push notification: %w", err)
+	}
+
+	page := isPageNotification(notificationType)
+	ackURL := ""
+	if page && inc.Status == db.IncidentStatusTriggered {
+		if token, err := createIncidentAckLink(s.PG, incidentID, userID); err != nil {
+			log.Printf("⚠️  Failed to create acknowledge link for incident %s: %v", incidentID, err)
+		} else {
+			ackURL = ackLinkURL(token)
+		}
+	}
+	msg := buildChatMessage(inc, notificationType, ackURL)
+	body, err := json.Marshal(webPushPayload{
+		Type:               notificationType,
+		IncidentID:         incidentID,
+		Title:              msg.Headline,
+		Body:               msg.Title,
+		URL:                msg.IncidentURL,
+		AckURL:             ackURL,
+		Tag:                "incident-" + incidentID,
+		RequireInteraction: page,
+	})
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	delivered := false
+	for _, sub := range subs {
+		sendErr := s.send(sub, body, page, incidentID)
+		switch {
+		case sendErr == nil:
+			delivered = true
+			if _, err := s.PG.Exec(`UPDATE web_push_subscriptions SET last_used_at = NOW() WHERE id = $1`, sub.ID); err != nil {
+				log.Printf("⚠️  Failed to update push subscription %s: %v", sub.ID, err)
+			}
+		case errors.Is(sendErr, errWebPushGone):
+			if _, err := s.PG.Exec(`DELETE FROM web_push_subscriptions WHERE id = $1`, sub.ID); err != nil {
+				log.Printf("⚠️  Failed to remove expired push subscription %s: %v", sub.ID, err)
+			}
+		default:
+			lastErr = sendErr
+		}
+		s.logNotification(userID, incidentID, notificationType, sub, msg.Headline+"\n"+msg.Title, sendErr)
+	}
+	if delivered {
+		return nil
+	}
+	return lastErr
+}
+
+// send encrypts a payload for one subscription and posts it to its push service
+func (s *WebPushService) send(sub db.WebPushSubscription, payload []byte, page bool, incidentID string) error {
+	if err := faults.ProviderOutage("web_push"); err != nil {
+		return err
+	}
+	if !isWebPushServiceEndpoint(sub.Endpoint) {
+		return errors.New("subscription endpoint is not a known push service")
+	}
+	body, err := encryptWebPushPayload(sub.P256dh, sub.Auth, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt push message: %w", err)
+	}
+	authorization, err := vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Authorization", authorization)
+	// A page nobody saw within the hour is stale; the incident has escalated by then
+	req.Header.Set("TTL", "3600")
+	// Later notifications about the same incident replace undelivered ones
+	req.Header.Set("Topic", strings.ReplaceAll(incidentID, "-", ""))
+	if page {
+		req.Header.Set("Urgency", "high")
+	} else {
+		req.Header.Set("Urgency", "normal")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The endpoint is a capability URL; keep it out of errors and logs
+		return errors.New("push service request failed")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errWebPushGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &apiStatusError{StatusCode: resp.StatusCode, msg: fmt.Sprintf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))}
+	}
+	return nil
+}
+
+// webPushRecordSize is the aes128gcm record size; payloads are sent as a single record
+const webPushRecordSize = 4096
+
+// encryptWebPushPayload encrypts a message for a subscription with the
+// aes128gcm content coding as used by Web Push (RFC 8291, RFC 8188)
+func encryptWebPushPayload(p256dh, auth string, plaintext []byte) ([]byte, error) {
+	uaPublicBytes, err := decodeWebPushKey(p256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodeWebPushKey(auth)
+	if err != nil {
+		return nil, err
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record
+	record := append(append([]byte{}, plaintext...), 0x02)
+	if len(record)+gcm.Overhead() > webPushRecordSize {
+		return nil, errors.New("push message too large")
+	}
+
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// vapidAuthorization returns the VAPID Authorization header for a push
+// service endpoint (RFC 8292)
+func vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", ErrInvalidWebPushSubscription
+	}
+	key, err := vapidSigningKey(config.Current().WebPush.VAPIDPrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	subject := config.Current().WebPush.Subject
+	if subject == "" {
+		subject = webBaseURL()
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	}).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+	return "vapid t=" + token + ", k=" + strings.TrimRight(config.Current().WebPush.VAPIDPublicKey, "="), nil
+}
+
+// vapidSigningKey turns the base64url VAPID private key into an ECDSA key
+func vapidSigningKey(privateKey string) (*ecdsa.PrivateKey, error) {
+	d, err := decodeWebPushKey(privateKey)
+	if err != nil {
+		return nil, errors.New("invalid VAPID private key")
+	}
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, errors.New("invalid VAPID private key")
+	}
+	point := priv.PublicKey().Bytes() // 0x04 || X || Y
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, nil
+}
+
+func (s *WebPushService) logNotification(userID, incidentID, notificationType string, sub db.WebPushSubscription, message string, sendErr error) {
+	status, errorMsg := "sent", ""
+	var sentAt interface{} = time.Now()
+	if sendErr != nil {
+		status, errorMsg, sentAt = "failed", sendErr.Error(), nil
+	}
+	recipient := sub.UserAgent
+	if recipient == "" {
+		recipient = "browser " + sub.ID
+	}
+	_, err := s.PG.Exec(`
+		INSERT INTO notification_logs (user_id, incident_id, notification_type, channel, recipient, message, status, error_message, sent_at)
+		VALUES ($1, $2, $3, 'web_push', $4, $5, $6, $7, $8)
+	`, userID, incidentID, "incident_"+notificationType, recipient, message, status, errorMsg, sentAt)
+	if err != nil {
+		log.Printf("Failed to log notification: %v", err)
+	}
+}
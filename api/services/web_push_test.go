@@ -0,0 +1,171 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func newTestSubscriptionKeys(t *testing.T) (*ecdh.PrivateKey, []byte) {
+	t.Helper()
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return priv, auth
+}
+
+func TestEncryptWebPushPayload(t *testing.T) {
+	uaPrivate, auth := newTestSubscriptionKeys(t)
+	uaPublic := uaPrivate.PublicKey().Bytes()
+
+	body, err := encryptWebPushPayload(base64.RawURLEncoding.EncodeToString(uaPublic),
+		base64.URLEncoding.EncodeToString(auth), []byte(`{"title":"page"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decrypt the way a browser does (RFC 8291 section 3.4)
+	salt, rs, idLen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	if rs != webPushRecordSize || idLen != 65 {
+		t.Fatalf("header rs=%d idlen=%d", rs, idLen)
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, _ := uaPrivate.ECDH(asPublic)
+	ikm, _ := hkdf.Key(sha256.New, shared, auth, "WebPush: info\x00"+string(uaPublic)+string(asPublic.Bytes()), 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plain, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if string(plain) != "{\"title\":\"page\"}\x02" {
+		t.Errorf("plaintext = %q", plain)
+	}
+}
+
+func TestVAPIDAuthorization(t *testing.T) {
+	old := config.Current().WebPush
+	defer config.Update(func(c *config.Config) { c.WebPush = old })
+
+	key, _ := ecdh.P256().GenerateKey(rand.Reader)
+	config.Update(func(c *config.Config) {
+		c.WebPush.VAPIDPrivateKey = base64.RawURLEncoding.EncodeToString(key.Bytes())
+		c.WebPush.VAPIDPublicKey = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+		c.WebPush.Subject = "mailto:oncall@example.com"
+	})
+
+	header, err := vapidAuthorization("https://fcm.googleapis.com/fcm/send/abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(header, "vapid t="), ", k=", 2)
+	if len(parts) != 2 || parts[1] != config.Current().WebPush.VAPIDPublicKey {
+		t.Fatalf("header = %q", header)
+	}
+
+	signingKey, _ := vapidSigningKey(config.Current().WebPush.VAPIDPrivateKey)
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(parts[0], claims, func(*jwt.Token) (interface{}, error) {
+		return &signingKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"})); err != nil {
+		t.Fatalf("token does not verify: %v", err)
+	}
+	if claims["aud"] != "https://fcm.googleapis.com" || claims["sub"] != "mailto:oncall@example.com" {
+		t.Errorf("claims = %v", claims)
+	}
+}
+
+func TestValidateWebPushSubscription(t *testing.T) {
+	uaPrivate, auth := newTestSubscriptionKeys(t)
+
+	var req db.WebPushSubscribeRequest
+	req.Endpoint = "https://updates.push.services.mozilla.com/wpush/v2/abc"
+	req.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes())
+	req.Keys.Auth = base64.RawURLEncoding.EncodeToString(auth)
+	if err := validateWebPushSubscription(req); err != nil {
+		t.Errorf("valid subscription rejected: %v", err)
+	}
+
+	bad := req
+	bad.Endpoint = "http://push.example.com/abc"
+	if err := validateWebPushSubscription(bad); !errors.Is(err, ErrInvalidWebPushSubscription) {
+		t.Errorf("plain http endpoint accepted: %v", err)
+	}
+	bad = req
+	bad.Endpoint = "https://169.254.169.254/latest/meta-data"
+	if err := validateWebPushSubscription(bad); !errors.Is(err, ErrInvalidWebPushSubscription) {
+		t.Errorf("endpoint outside the push services accepted: %v", err)
+	}
+	bad = req
+	bad.Keys.Auth = base64.RawURLEncoding.EncodeToString([]byte("short"))
+	if err := validateWebPushSubscription(bad); !errors.Is(err, ErrInvalidWebPushSubscription) {
+		t.Errorf("short auth secret accepted: %v", err)
+	}
+}
+
+func TestIsWebPushServiceEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     bool
+	}{
+		{"https://fcm.googleapis.com/fcm/send/abc", true},
+		{"https://updates.push.services.mozilla.com/wpush/v2/abc", true},
+		{"https://wns2-par02p.notify.windows.com/w/?token=abc", true},
+		{"https://web.push.apple.com/QGuQyavXutnMH", true},
+		{"http://fcm.googleapis.com/fcm/send/abc", false},
+		{"https://fcm.googleapis.com:8443/fcm/send/abc", false},
+		{"https://fcm.googleapis.com.evil.example/abc", false},
+		{"https://notify.windows.com.evil.example/abc", false},
+		{"https://internal.example.com/hook", false},
+	}
+	for _, tt := range tests {
+		if got := isWebPushServiceEndpoint(tt.endpoint); got != tt.want {
+			t.Errorf("isWebPushServiceEndpoint(%q) = %v, want %v", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeRefusesAnotherUsersBrowser(t *testing.T) {
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	uaPrivate, auth := newTestSubscriptionKeys(t)
+	var req db.WebPushSubscribeRequest
+	req.Endpoint = "https://fcm.googleapis.com/fcm/send/abc"
+	req.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes())
+	req.Keys.Auth = base64.RawURLEncoding.EncodeToString(auth)
+
+	mock.ExpectQuery(`WHERE web_push_subscriptions.user_id = EXCLUDED.user_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	if _, err := (&WebPushService{PG: pg}).Subscribe("user-2", req); !errors.Is(err, ErrWebPushSubscriptionTaken) {
+		t.Errorf("err = %v, want ErrWebPushSubscriptionTaken", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidSourceCIDR = errors.New("invalid source CIDR")
+
+// Webhook rejection reasons, also used as the counter column suffix
+const (
+	WebhookRejectSourceIP  = "ip"
+	WebhookRejectUserAgent = "user_agent"
+)
+
+// WebhookAccessPolicy is the subset of an integration needed to gate inbound webhooks
+type WebhookAccessPolicy struct {
+	IntegrationID      string
+	AllowedSourceCIDRs []string
+	AllowedUserAgents  []string
+}
+
+// NormalizeSourceCIDRs validates an allowlist, converting bare IPs to
+// single-host CIDRs (/32 or /128) and dropping blanks and duplicates
+func NormalizeSourceCIDRs(entries []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidSourceCIDR, entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSourceCIDR, entry)
+		}
+		if cidr := network.String(); !seen[cidr] {
+			seen[cidr] = true
+			out = append(out, cidr)
+		}
+	}
+	return out, nil
+}
+
+func normalizeUserAgents(entries []string) []string {
+	out := []string{}
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// Check returns the rejection reason for a request, or "" if it is allowed.
+// Empty lists allow everything so existing integrations are unaffected.
+func (p WebhookAccessPolicy) Check(clientIP, userAgent string) string {
+	if len(p.AllowedSourceCIDRs) > 0 {
+		ip := net.ParseIP(clientIP)
+		allowed := false
+		for _, cidr := range p.AllowedSourceCIDRs {
+			if _, network, err := net.ParseCIDR(cidr); err == nil && ip != nil && network.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return WebhookRejectSourceIP
+		}
+	}
+
+	if len(p.AllowedUserAgents) > 0 {
+		ua := strings.ToLower(userAgent)
+		allowed := false
+		for _, prefix := range p.AllowedUserAgents {
+			if strings.HasPrefix(ua, strings.ToLower(prefix)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return WebhookRejectUserAgent
+		}
+	}
+	return ""
+}
+
+// GetWebhookAccessPolicy loads the allowlists for an integration
+func (s *IntegrationService) GetWebhookAccessPolicy(integrationID string) (WebhookAccessPolicy, error) {
+	policy := WebhookAccessPolicy{IntegrationID: integrationID}
+	err := s.PG.QueryRow(`
+		SELECT allowed_source_cidrs, allowed_user_agents
+		FROM integrations
+		WHERE id = $1
+	`, integrationID).Scan(pq.Array(&policy.AllowedSourceCIDRs), pq.Array(&policy.AllowedUserAgents))
+	if err == sql.ErrNoRows {
+		return policy, fmt.Errorf("integration not found")
+	}
+	if err != nil {
+		return policy, fmt.Errorf("failed to get webhook access policy: %w", err)
+	}
+	return policy, nil
+}
+
+// RecordWebhookRejection increments the rejected-request counter for an integration
+func (s *IntegrationService) RecordWebhookRejection(integrationID, reason, source string) error {
+	column := "rejected_ip_count"
+	if reason == WebhookRejectUserAgent {
+		column = "rejected_user_agent_count"
+	}
+	_, err := s.PG.Exec(fmt.Sprintf(`
+		UPDATE integrations
+		SET %s = %s + 1, last_rejected_at = NOW(), last_rejected_source = $2
+		WHERE id = $1
+	`, column, column), integrationID, source)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook rejection: %w", err)
+	}
+	return nil
+}
+
+// RecordWebhookPayloadError counts a rejected webhook payload and keeps the
+// latest error message so senders can see why their requests fail
+func (s *IntegrationService) RecordWebhookPayloadError(integrationID, message string) error {
+	_, err := s.PG.Exec(`
+		UPDATE integrations
+		SET payload_error_count = payload_error_count + 1, last_payload_error = $2, last_payload_error_at = NOW()
+		WHERE id = $1
+	`, integrationID, message)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook payload error: %w", err)
+	}
+	return nil
+}
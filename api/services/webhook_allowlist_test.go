@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSourceCIDRs(t *testing.T) {
+	got, err := NormalizeSourceCIDRs([]string{" 10.0.0.0/8 ", "192.168.1.7", "", "2001:db8::1", "10.1.2.3/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "2001:db8::1/128"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeSourceCIDRs() = %v, want %v", got, want)
+	}
+
+	if _, err := NormalizeSourceCIDRs([]string{"not-an-ip"}); !errors.Is(err, ErrInvalidSourceCIDR) {
+		t.Errorf("expected ErrInvalidSourceCIDR, got %v", err)
+	}
+}
+
+func TestWebhookAccessPolicyCheck(t *testing.T) {
+	policy := WebhookAccessPolicy{
+		AllowedSourceCIDRs: []string{"10.0.0.0/8", "203.0.113.5/32"},
+		AllowedUserAgents:  []string{"Grafana/", "Alertmanager"},
+	}
+
+	tests := []struct {
+		name   string
+		ip, ua string
+		want   string
+	}{
+		{"allowed ip and ua", "10.4.5.6", "Grafana/10.2.0", ""},
+		{"ua prefix is case-insensitive", "203.0.113.5", "alertmanager/0.27", ""},
+		{"ip outside ranges", "198.51.100.1", "Grafana/10.2.0", WebhookRejectSourceIP},
+		{"unparseable ip", "", "Grafana/10.2.0", WebhookRejectSourceIP},
+		{"ua not allowed", "10.4.5.6", "curl/8.0", WebhookRejectUserAgent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Check(tt.ip, tt.ua); got != tt.want {
+				t.Errorf("Check(%q, %q) = %q, want %q", tt.ip, tt.ua, got, tt.want)
+			}
+		})
+	}
+
+	if got := (WebhookAccessPolicy{}).Check("198.51.100.1", "curl/8.0"); got != "" {
+		t.Errorf("empty policy should allow everything, got %q", got)
+	}
+}
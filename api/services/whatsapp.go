@@ -0,0 +1,476 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+	"github.com/vanchonlee/slar/internal/faults"
+	"github.com/vanchonlee/slar/notify"
+)
+
+var (
+	ErrInvalidWhatsAppNumber    = errors.New("phone_number must be an international number, e.g. +14155550123")
+	ErrWhatsAppConsentRequired  = errors.New("consent must be given to receive WhatsApp notifications")
+	ErrInvalidWhatsAppSignature = errors.New("invalid WhatsApp webhook signature")
+)
+
+const whatsAppNotificationQueue = "whatsapp_notifications"
+
+var (
+	whatsAppNumberPattern  = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
+	whatsAppNumberStripper = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+	// whatsAppAPIBaseURL is overridden in tests
+	whatsAppAPIBaseURL = "https://graph.facebook.com/v21.0"
+)
+
+// WhatsAppService delivers incident notifications through the WhatsApp
+// Business Cloud API to users who opted in, and tracks their consent
+type WhatsAppService struct {
+	PG     *sql.DB
+	client *http.Client
+}
+
+func NewWhatsAppService(pg *sql.DB) *WhatsAppService {
+	return &WhatsAppService{
+		PG:     pg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsConfigured reports whether WhatsApp credentials are set
+func (s *WhatsAppService) IsConfigured() bool {
+	return config.Current().WhatsApp.PhoneNumberID != "" && config.Current().WhatsApp.AccessToken != ""
+}
+
+// CONSENT
+
+// normalizeWhatsAppNumber strips formatting from a phone number and checks it is in E.164 form
+func normalizeWhatsAppNumber(phone string) (string, error) {
+	phone = whatsAppNumberStripper.Replace(strings.TrimSpace(phone))
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + strings.TrimPrefix(phone, "00")
+	}
+	if !whatsAppNumberPattern.MatchString(phone) {
+		return "", ErrInvalidWhatsAppNumber
+	}
+	return phone, nil
+}
+
+// whatsAppPhoneLookup hashes a number's digits so inbound messages (which carry
+// the sender without "+") can be matched against encrypted numbers
+func whatsAppPhoneLookup(phone string) string {
+	sum := sha256.Sum256([]byte(strings.TrimPrefix(phone, "+")))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetConsent returns a user's WhatsApp consent, or nil if they never opted in
+func (s *WhatsAppService) GetConsent(userID string) (*db.WhatsAppConsent, error) {
+	var c db.WhatsAppConsent
+	var optedInAt, optedOutAt, lastInboundAt sql.NullTime
+	err := s.PG.QueryRow(`
+		SELECT user_id, phone_number, status, source, opted_in_at, opted_out_at, last_inbound_at, updated_at
+		FROM whatsapp_consents
+		WHERE user_id = $1
+	`, userID).Scan(&c.UserID, &c.PhoneNumber, &c.Status, &c.Source, &optedInAt, &optedOutAt, &lastInboundAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WhatsApp consent: %w", err)
+	}
+	decryptColumns(&c.PhoneNumber)
+	if optedInAt.Valid {
+		c.OptedInAt = &optedInAt.Time
+	}
+	if optedOutAt.Valid {
+		c.OptedOutAt = &optedOutAt.Time
+	}
+	if lastInboundAt.Valid {
+		c.LastInboundAt = &lastInboundAt.Time
+	}
+	return &c, nil
+}
+
+// OptIn records a user's consent to WhatsApp notifications on a number
+func (s *WhatsAppService) OptIn(userID string, req db.WhatsAppOptInRequest) (*db.WhatsAppConsent, error) {
+	if !req.Consent {
+		return nil, ErrWhatsAppConsentRequired
+	}
+	phone, err := normalizeWhatsAppNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	encPhone, err := encryptColumn(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO whatsapp_consents (user_id, phone_number, phone_lookup, status, source, opted_in_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET phone_number = EXCLUDED.phone_number, phone_lookup = EXCLUDED.phone_lookup,
+		    status = EXCLUDED.status, source = EXCLUDED.source, opted_in_at = NOW(), updated_at = NOW()
+	`, userID, encPhone, whatsAppPhoneLookup(phone), db.WhatsAppOptedIn, db.WhatsAppSourceWeb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record WhatsApp consent: %w", err)
+	}
+	if err := recordWhatsAppConsentEvent(tx, userID, db.WhatsAppOptedIn, db.WhatsAppSourceWeb); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetConsent(userID)
+}
+
+// OptOut withdraws a user's consent; nothing more is sent until they opt in again
+func (s *WhatsAppService) OptOut(userID, source string) error {
+	tx, err := s.PG.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE whatsapp_consents
+		SET status = $2, source = $3, opted_out_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1 AND status = $4
+	`, userID, db.WhatsAppOptedOut, source, db.WhatsAppOptedIn)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw WhatsApp consent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil // not opted in
+	}
+	if err := recordWhatsAppConsentEvent(tx, userID, db.WhatsAppOptedOut, source); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func recordWhatsAppConsentEvent(tx *sql.Tx, userID, action, source string) error {
+	_, err := tx.Exec(`
+		INSERT INTO whatsapp_consent_events (user_id, action, source) VALUES ($1, $2, $3)
+	`, userID, action, source)
+	if err != nil {
+		return fmt.Errorf("failed to record WhatsApp consent event: %w", err)
+	}
+	return nil
+}
+
+// INBOUND WEBHOOK
+
+// VerifyWebhookSubscription answers Meta's webhook subscription handshake
+func (s *WhatsAppService) VerifyWebhookSubscription(mode, token string) bool {
+	verifyToken := config.Current().WhatsApp.VerifyToken
+	return mode == "subscribe" && verifyToken != "" && hmac.Equal([]byte(token), []byte(verifyToken))
+}
+
+// VerifyWebhookSignature checks the X-Hub-Signature-256 header against the app secret
+func (s *WhatsAppService) VerifyWebhookSignature(body []byte, signature string) error {
+	secret := config.Current().WhatsApp.AppSecret
+	if secret == "" {
+		return ErrInvalidWhatsAppSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidWhatsAppSignature
+	}
+	return nil
+}
+
+// whatsAppWebhook is the subset of the Cloud API webhook payload SLAR reads
+type whatsAppWebhook struct {
+	Entry []struct {
+		Changes []struct {
+			Field string `json:"field"`
+			Value struct {
+				Messages []struct {
+					From string `json:"from"`
+					Type string `json:"type"`
+					Text struct {
+						Body string `json:"body"`
+					} `json:"text"`
+					Button struct {
+						Text string `json:"text"`
+					} `json:"button"`
+				} `json:"messages"`
+			} `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+// HandleWebhook processes inbound messages: any message opens the 24h session
+// window, and STOP/START replies withdraw or restore consent
+func (s *WhatsAppService) HandleWebhook(body []byte) error {
+	var payload whatsAppWebhook
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return fmt.Errorf("invalid WhatsApp webhook payload: %w", err)
+	}
+
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+			for _, m := range change.Value.Messages {
+				text := m.Text.Body
+				if m.Type == "button" {
+					text = m.Button.Text
+				}
+				if err := s.handleInboundMessage(m.From, text); err != nil {
+					log.Printf("⚠️  Failed to handle WhatsApp message: %v", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *WhatsAppService) handleInboundMessage(from, text string) error {
+	var userID, status string
+	err := s.PG.QueryRow(`
+		UPDATE whatsapp_consents SET last_inbound_at = NOW()
+		WHERE phone_lookup = $1
+		RETURNING user_id, status
+	`, whatsAppPhoneLookup(from)).Scan(&userID, &status)
+	if err == sql.ErrNoRows {
+		return nil // not a SLAR user
+	}
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(text)) {
+	case "STOP", "UNSUBSCRIBE":
+		return s.OptOut(userID, db.WhatsAppSourceWhatsApp)
+	case "START", "SUBSCRIBE":
+		if status == db.WhatsAppOptedIn {
+			return nil
+		}
+		tx, err := s.PG.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(`
+			UPDATE whatsapp_consents SET status = $2, source = $3, opted_in_at = NOW(), updated_at = NOW()
+			WHERE user_id = $1
+		`, userID, db.WhatsAppOptedIn, db.WhatsAppSourceWhatsApp); err != nil {
+			return err
+		}
+		if err := recordWhatsAppConsentEvent(tx, userID, db.WhatsAppOptedIn, db.WhatsAppSourceWhatsApp); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	return nil
+}
+
+// NOTIFICATIONS
+
+// EnqueueWhatsAppNotification queues an incident notification for a user's
+// WhatsApp. Nothing is queued unless the user is opted in, never for test
+// incidents, and not for updates the incident's commander muted the user from.
+func EnqueueWhatsAppNotification(pg *sql.DB, userID, incidentID, notificationType string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_id":     userID,
+		"incident_id": incidentID,
+		"type":        notificationType,
+		"created_at":  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal WhatsApp notification: %w", err)
+	}
+
+	_, err = pg.Exec(`
+		SELECT pgmq.send($1, $2)
+		WHERE EXISTS (
+			SELECT 1 FROM whatsapp_consents c, incidents i
+			WHERE c.user_id::text = $3 AND c.status = 'opted_in'
+			AND i.id = $4 AND NOT COALESCE(i.is_test, false)
+			AND NOT (NOT $5 AND EXISTS (
+				SELECT 1 FROM incident_notification_settings ns
+				WHERE ns.incident_id = i.id AND $3 = ANY(ns.muted_user_ids)
+			))
+		)
+	`, whatsAppNotificationQueue, string(payload), userID, incidentID, isPageNotification(notificationType))
+	if err != nil {
+		return fmt.Errorf("failed to queue WhatsApp notification: %w", err)
+	}
+	return nil
+}
+
+// DeliverIncidentNotification sends an incident notification to an opted-in
+// user. Pages use the approved page template with an acknowledge link, since
+// the business starts the conversation; updates are free-form session messages
+// and are skipped outside the user's 24h session window.
+func (s *WhatsAppService) DeliverIncidentNotification(userID, incidentID, notificationType string) error {
+	if !s.IsConfigured() {
+		return nil
+	}
+	consent, err := s.GetConsent(userID)
+	if err != nil || consent == nil || consent.Status != db.WhatsAppOptedIn || consent.PhoneNumber == "" {
+		return err
+	}
+
+	inc := chatIncident{ID: incidentID}
+	err = s.PG.QueryRow(`
+		SELECT i.title, i.status, COALESCE(i.severity, ''), i.urgency, COALESCE(sv.name, ''), COALESCE(u.name, ''), i.short_id
+		FROM incidents i
+		LEFT JOIN services sv ON sv.id = i.service_id
+		LEFT JOIN users u ON u.id::text = $2
+		WHERE i.id = $1
+	`, incidentID, userID).Scan(&inc.Title, &inc.Status, &inc.Severity, &inc.Urgency, &inc.ServiceName, &inc.UserName, &inc.ShortID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get incident for WhatsApp notification: %w", err)
+	}
+
+	to := strings.TrimPrefix(consent.PhoneNumber, "+")
+	var payload map[string]interface{}
+	if isPageNotification(notificationType) {
+		link := incidentLinkURL(incidentID, inc.ShortID)
+		if inc.Status == db.IncidentStatusTriggered {
+			if token, err := createIncidentAckLink(s.PG, incidentID, userID); err != nil {
+				log.Printf("⚠️  Failed to create acknowledge link for incident %s: %v", incidentID, err)
+			} else {
+				link = ackLinkURL(token)
+			}
+		}
+		payload = whatsAppTemplatePayload(to, buildChatMessage(inc, notificationType, ""), link)
+	} else {
+		if consent.LastInboundAt == nil || time.Since(*consent.LastInboundAt) > db.WhatsAppSessionWindow {
+			log.Printf("💬 Skipping WhatsApp %s update for user %s: outside the 24h session window", notificationType, userID)
+			return nil
+		}
+		payload = whatsAppTextPayload(to, buildChatMessage(inc, notificationType, ""))
+	}
+
+	sendErr := s.post(payload)
+	s.logNotification(userID, incidentID, notificationType, to, payload, sendErr)
+	return sendErr
+}
+
+// whatsAppTemplatePayload fills the page template's body parameters:
+// {{1}} headline, {{2}} incident title, {{3}} link
+func whatsAppTemplatePayload(to string, msg notify.Message, link string) map[string]interface{} {
+	params := []map[string]string{}
+	for _, text := range []string{msg.Headline, msg.Title, link} {
+		params = append(params, map[string]string{"type": "text", "text": whatsAppTemplateText(text)})
+	}
+	return map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "template",
+		"template": map[string]interface{}{
+			"name":     config.Current().WhatsApp.PageTemplate,
+			"language": map[string]string{"code": config.Current().WhatsApp.TemplateLanguage},
+			"components": []map[string]interface{}{
+				{"type": "body", "parameters": params},
+			},
+		},
+	}
+}
+
+// whatsAppTemplateText makes a value valid as a template parameter, which
+// can't contain newlines, tabs or runs of spaces
+func whatsAppTemplateText(s string) string {
+	return truncateRunes(strings.Join(strings.Fields(s), " "), 1024)
+}
+
+func whatsAppTextPayload(to string, msg notify.Message) map[string]interface{} {
+	lines := []string{"*" + msg.Headline + "*", msg.Title}
+	for _, f := range msg.Fields {
+		lines = append(lines, f.Name+": "+f.Value)
+	}
+	if msg.IncidentURL != "" {
+		lines = append(lines, msg.IncidentURL)
+	}
+	return map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "text",
+		"text":              map[string]interface{}{"body": strings.Join(lines, "\n"), "preview_url": false},
+	}
+}
+
+func (s *WhatsAppService) post(payload map[string]interface{}) error {
+	if err := faults.ProviderOutage("whatsapp"); err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost,
+		whatsAppAPIBaseURL+"/"+url.PathEscape(config.Current().WhatsApp.PhoneNumberID)+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+config.Current().WhatsApp.AccessToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("WhatsApp request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &apiStatusError{StatusCode: resp.StatusCode, msg: fmt.Sprintf("WhatsApp API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))}
+	}
+	return nil
+}
+
+func (s *WhatsAppService) logNotification(userID, incidentID, notificationType, to string, payload map[string]interface{}, sendErr error) {
+	status, errorMsg := "sent", ""
+	var sentAt interface{} = time.Now()
+	if sendErr != nil {
+		status, errorMsg, sentAt = "failed", sendErr.Error(), nil
+	}
+	message, _ := json.Marshal(payload["template"])
+	if payload["type"] == "text" {
+		message, _ = json.Marshal(payload["text"])
+	}
+	// Only the last digits of the number are logged
+	recipient := to
+	if len(recipient) > 4 {
+		recipient = "***" + recipient[len(recipient)-4:]
+	}
+	_, err := s.PG.Exec(`
+		INSERT INTO notification_logs (user_id, incident_id, notification_type, channel, recipient, message, status, error_message, sent_at)
+		VALUES ($1, $2, $3, 'whatsapp', $4, $5, $6, $7, $8)
+	`, userID, incidentID, "incident_"+notificationType, recipient, string(message), status, errorMsg, sentAt)
+	if err != nil {
+		log.Printf("Failed to log notification: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+func TestNormalizeWhatsAppNumber(t *testing.T) {
+	tests := []struct {
+		in, want string
+		valid    bool
+	}{
+		{"+1 (415) 555-0123", "+14155550123", true},
+		{"0084 912.345.678", "+84912345678", true},
+		{"4155550123", "", false},
+		{"+0123456789", "", false},
+		{"+12", "", false},
+	}
+	for _, tt := range tests {
+		got, err := normalizeWhatsAppNumber(tt.in)
+		if tt.valid && (err != nil || got != tt.want) {
+			t.Errorf("normalizeWhatsAppNumber(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if !tt.valid && err != ErrInvalidWhatsAppNumber {
+			t.Errorf("normalizeWhatsAppNumber(%q) err = %v, want ErrInvalidWhatsAppNumber", tt.in, err)
+		}
+	}
+	if whatsAppPhoneLookup("+14155550123") != whatsAppPhoneLookup("14155550123") {
+		t.Error("lookup must match inbound senders, which have no leading +")
+	}
+}
+
+func TestVerifyWhatsAppWebhookSignature(t *testing.T) {
+	old := config.Current().WhatsApp
+	defer config.Update(func(c *config.Config) { c.WhatsApp = old })
+	config.Update(func(c *config.Config) { c.WhatsApp.AppSecret = "s3cret" })
+
+	body := []byte(`{"entry":[]}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	service := NewWhatsAppService(nil)
+	if err := service.VerifyWebhookSignature(body, signature); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	if err := service.VerifyWebhookSignature([]byte(`{"entry":[{}]}`), signature); err != ErrInvalidWhatsAppSignature {
+		t.Errorf("tampered body accepted: %v", err)
+	}
+}
+
+func TestDeliverWhatsAppNotification(t *testing.T) {
+	old := config.Current().WhatsApp
+	defer config.Update(func(c *config.Config) { c.WhatsApp = old })
+	config.Update(func(c *config.Config) {
+		c.WhatsApp.PhoneNumberID = "1055"
+		c.WhatsApp.AccessToken = "token"
+		c.WhatsApp.PageTemplate = "incident_page"
+		c.WhatsApp.TemplateLanguage = "en"
+	})
+
+	var posted []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1055/messages" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		posted = append(posted, body)
+		w.Write([]byte(`{"messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	oldBase := whatsAppAPIBaseURL
+	whatsAppAPIBaseURL = server.URL
+	defer func() { whatsAppAPIBaseURL = oldBase }()
+
+	pg, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pg.Close()
+
+	consentColumns := []string{"user_id", "phone_number", "status", "source", "opted_in_at", "opted_out_at", "last_inbound_at", "updated_at"}
+	incidentColumns := []string{"title", "status", "severity", "urgency", "service", "user", "short_id"}
+	stale := time.Now().Add(-48 * time.Hour)
+
+	// A page outside the session window is sent as a template with an acknowledge link
+	mock.ExpectQuery(`FROM whatsapp_consents`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows(consentColumns).AddRow("user-1", "+14155550123", "opted_in", "web", stale, nil, stale, stale))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1", "user-1").
+		WillReturnRows(sqlmock.NewRows(incidentColumns).AddRow("Checkout errors", "triggered", "SEV1", "high", "checkout", "Alice", "k3m9x2ab7q"))
+	mock.ExpectExec(`INSERT INTO incident_ack_links`).
+		WithArgs(sqlmock.AnyArg(), "inc-1", "user-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO notification_logs`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// An update outside the session window is skipped
+	mock.ExpectQuery(`FROM whatsapp_consents`).WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows(consentColumns).AddRow("user-1", "+14155550123", "opted_in", "web", stale, nil, stale, stale))
+	mock.ExpectQuery(`FROM incidents i`).WithArgs("inc-1", "user-1").
+		WillReturnRows(sqlmock.NewRows(incidentColumns).AddRow("Checkout errors", "acknowledged", "SEV1", "high", "checkout", "Alice", "k3m9x2ab7q"))
+
+	service := &WhatsAppService{PG: pg, client: server.Client()}
+	if err := service.DeliverIncidentNotification("user-1", "inc-1", "escalated"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeliverIncidentNotification("user-1", "inc-1", "acknowledged"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(posted) != 1 {
+		t.Fatalf("expected one message, got %d", len(posted))
+	}
+	if posted[0]["type"] != "template" || posted[0]["to"] != "14155550123" {
+		t.Errorf("expected template to 14155550123, got %v", posted[0])
+	}
+	template := posted[0]["template"].(map[string]interface{})
+	params := template["components"].([]interface{})[0].(map[string]interface{})["parameters"].([]interface{})
+	if len(params) != 3 || params[1].(map[string]interface{})["text"] != "Checkout errors" {
+		t.Errorf("unexpected template parameters: %v", params)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
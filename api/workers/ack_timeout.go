@@ -0,0 +1,113 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// ackTimeoutCandidate is an acknowledged incident that has gone unresolved
+// for longer than its policy's ack_timeout_minutes
+type ackTimeoutCandidate struct {
+	IncidentID     string
+	AcknowledgedBy string
+	AcknowledgedAt time.Time
+	AfterMinutes   int
+}
+
+// processAckTimeouts puts incidents back into escalation when they are still
+// unresolved ack_timeout_minutes after being acknowledged, so a responder who
+// acknowledges and then disappears doesn't silence the incident for good.
+// Policies without ack_timeout_minutes keep acknowledgment final.
+func (w *IncidentWorker) processAckTimeouts() {
+	candidates, err := w.getAckTimedOutIncidents()
+	if err != nil {
+		log.Printf("Worker: failed to get incidents past their acknowledgment timeout: %v", err)
+		return
+	}
+
+	for _, c := range candidates {
+		w.reescalateAckTimedOutIncident(c)
+	}
+}
+
+// getAckTimedOutIncidents finds acknowledged incidents whose policy re-escalates
+// after an acknowledgment timeout that has passed
+func (w *IncidentWorker) getAckTimedOutIncidents() ([]ackTimeoutCandidate, error) {
+	rows, err := w.PG.Query(`
+		SELECT i.id, COALESCE(i.acknowledged_by::text, ''), i.acknowledged_at, ep.ack_timeout_minutes
+		FROM incidents i
+		JOIN escalation_policies ep ON ep.id = i.escalation_policy_id
+		WHERE i.status = 'acknowledged'
+		AND i.acknowledged_at IS NOT NULL
+		AND NOT COALESCE(i.is_test, false)
+		AND ep.ack_timeout_minutes > 0
+		AND i.acknowledged_at < NOW() - INTERVAL '1 minute' * ep.ack_timeout_minutes
+		ORDER BY i.acknowledged_at ASC
+		LIMIT 50
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []ackTimeoutCandidate
+	for rows.Next() {
+		var c ackTimeoutCandidate
+		if err := rows.Scan(&c.IncidentID, &c.AcknowledgedBy, &c.AcknowledgedAt, &c.AfterMinutes); err != nil {
+			log.Printf("Worker: error scanning acknowledgment timeout candidate: %v", err)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// reescalateAckTimedOutIncident returns one incident to triggered, records it
+// in the timeline and escalates it right away: to the next level, or to the
+// last level again when escalation had already run its course
+func (w *IncidentWorker) reescalateAckTimedOutIncident(c ackTimeoutCandidate) {
+	// Only if nobody resolved or re-acknowledged it in the meantime
+	rows, err := w.PG.Query(`
+		UPDATE incidents i
+		SET status = 'triggered', acknowledged_by = NULL, acknowledged_at = NULL, updated_at = NOW(),
+		    escalation_status = 'pending',
+		    current_escalation_level = LEAST(i.current_escalation_level, GREATEST(COALESCE(
+		        (SELECT MAX(el.level_number) FROM escalation_levels el WHERE el.policy_id = i.escalation_policy_id), 1) - 1, 0))
+		WHERE i.id = $1 AND i.status = 'acknowledged' AND i.acknowledged_at = $2
+		RETURNING i.id, i.title, i.description, i.status, i.urgency, i.priority,
+		          i.created_at, i.updated_at, i.assigned_to, i.assigned_at,
+		          i.source, i.service_id, i.escalation_policy_id, i.group_id,
+		          i.current_escalation_level, i.last_escalated_at, i.escalation_status,
+		          i.severity, i.incident_key, i.alert_count
+	`, c.IncidentID, c.AcknowledgedAt)
+	if err != nil {
+		log.Printf("Worker: failed to re-trigger incident %s after acknowledgment timeout: %v", c.IncidentID, err)
+		return
+	}
+	incidents := scanEscalationIncidents(rows)
+	rows.Close()
+	if len(incidents) == 0 {
+		return
+	}
+
+	eventData := map[string]interface{}{
+		"acknowledged_by": c.AcknowledgedBy,
+		"acknowledged_at": c.AcknowledgedAt,
+		"after_minutes":   c.AfterMinutes,
+		"reason":          "ack_timeout",
+	}
+	if c.AcknowledgedBy != "" {
+		if name, err := w.getUserName(c.AcknowledgedBy); err == nil {
+			eventData["acknowledged_by_name"] = name
+		}
+	}
+	if err := w.createIncidentEvent(c.IncidentID, db.IncidentEventAckTimedOut, eventData, "system"); err != nil {
+		log.Printf("Worker: failed to log acknowledgment timeout event: %v", err)
+	}
+
+	log.Printf("Worker: incident %s still unresolved %d minutes after acknowledgment, re-escalating",
+		c.IncidentID, c.AfterMinutes)
+	w.processIncidentEscalation(incidents[0])
+}
@@ -0,0 +1,163 @@
+package workers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// reassignCandidate is a triggered incident whose assignee ran out of time to acknowledge
+type reassignCandidate struct {
+	IncidentID   string
+	GroupID      string
+	AssignedTo   string
+	AfterMinutes int
+}
+
+// processAutoReassignments hands incidents to the next schedule member when
+// the assignee hasn't acknowledged within the policy's reassign_after_minutes.
+// Escalation levels are left alone: the incident keeps escalating on its own
+// schedule, it just stops waiting on someone who isn't responding.
+func (w *IncidentWorker) processAutoReassignments() {
+	candidates, err := w.getIncidentsNeedingReassignment()
+	if err != nil {
+		log.Printf("Worker: failed to get incidents needing reassignment: %v", err)
+		return
+	}
+
+	for _, c := range candidates {
+		w.reassignUnacknowledgedIncident(c)
+	}
+}
+
+// getIncidentsNeedingReassignment finds triggered incidents whose current
+// assignee has held them longer than the policy allows without acknowledging
+func (w *IncidentWorker) getIncidentsNeedingReassignment() ([]reassignCandidate, error) {
+	rows, err := w.PG.Query(`
+		SELECT i.id, COALESCE(i.group_id::text, ''), i.assigned_to, ep.reassign_after_minutes
+		FROM incidents i
+		JOIN escalation_policies ep ON ep.id = i.escalation_policy_id
+		WHERE i.status = 'triggered'
+		AND i.assigned_to IS NOT NULL
+		AND i.group_id IS NOT NULL
+		AND NOT COALESCE(i.is_test, false)
+		AND ep.reassign_after_minutes > 0
+		AND COALESCE(i.assigned_at, i.created_at) < NOW() - INTERVAL '1 minute' * ep.reassign_after_minutes
+		ORDER BY i.created_at ASC
+		LIMIT 50
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []reassignCandidate
+	for rows.Next() {
+		var c reassignCandidate
+		if err := rows.Scan(&c.IncidentID, &c.GroupID, &c.AssignedTo, &c.AfterMinutes); err != nil {
+			log.Printf("Worker: error scanning reassignment candidate: %v", err)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// nextScheduleMember returns who should take over from userID: someone else
+// on call now in userID's current schedule, then anyone else on call now in
+// the group; people whose shift hasn't started yet are never picked. People
+// the incident was already reassigned away from, or who are on vacation or
+// DND, are skipped so it never bounces back to someone who didn't respond.
+func (w *IncidentWorker) nextScheduleMember(incidentID, groupID, userID string) (string, error) {
+	var nextUserID string
+	err := w.PG.QueryRow(`
+		SELECT es.effective_user_id
+		FROM effective_shifts es
+		WHERE es.group_id = $1
+		AND es.effective_user_id <> $2
+		AND es.start_time <= NOW()
+		AND es.end_time > NOW()
+		AND user_available(es.effective_user_id, NOW())
+		AND NOT EXISTS (
+			SELECT 1 FROM incident_events ie
+			WHERE ie.incident_id = $3
+			AND ie.event_type = 'reassigned'
+			AND ie.event_data->>'from_user_id' = es.effective_user_id::text
+		)
+		ORDER BY es.scheduler_id IN (
+				SELECT cur.scheduler_id FROM effective_shifts cur
+				WHERE cur.group_id = $1
+				AND cur.effective_user_id = $2
+				AND cur.start_time <= NOW()
+				AND cur.end_time >= NOW()
+			) DESC,
+			es.start_time ASC
+		LIMIT 1
+	`, groupID, userID, incidentID).Scan(&nextUserID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return nextUserID, err
+}
+
+// reassignUnacknowledgedIncident moves one incident to the next schedule
+// member, records it in the timeline and notifies both people
+func (w *IncidentWorker) reassignUnacknowledgedIncident(c reassignCandidate) {
+	nextUserID, err := w.nextScheduleMember(c.IncidentID, c.GroupID, c.AssignedTo)
+	if err != nil {
+		log.Printf("Worker: failed to find next schedule member for incident %s: %v", c.IncidentID, err)
+		return
+	}
+	if nextUserID == "" {
+		// Nobody to hand over to; regular escalation keeps going
+		return
+	}
+
+	// Only move the incident if it is still triggered and still theirs
+	result, err := w.PG.Exec(`
+		UPDATE incidents
+		SET assigned_to = $3, assigned_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND assigned_to = $2 AND status = 'triggered'
+	`, c.IncidentID, c.AssignedTo, nextUserID)
+	if err != nil {
+		log.Printf("Worker: failed to reassign incident %s: %v", c.IncidentID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
+
+	eventData := map[string]interface{}{
+		"from_user_id":  c.AssignedTo,
+		"to_user_id":    nextUserID,
+		"after_minutes": c.AfterMinutes,
+		"reason":        "not_acknowledged",
+	}
+	if name, err := w.getUserName(c.AssignedTo); err == nil {
+		eventData["from_user"] = name
+	}
+	toName := "the next on-call"
+	if name, err := w.getUserName(nextUserID); err == nil {
+		eventData["to_user"] = name
+		toName = name
+	}
+	if err := w.createIncidentEvent(c.IncidentID, db.IncidentEventReassigned, eventData, "system"); err != nil {
+		log.Printf("Worker: failed to log reassignment event: %v", err)
+	}
+
+	log.Printf("Worker: reassigned incident %s from %s to %s (not acknowledged within %d minutes)",
+		c.IncidentID, c.AssignedTo, nextUserID, c.AfterMinutes)
+
+	if w.NotificationWorker == nil {
+		return
+	}
+	if err := w.NotificationWorker.SendIncidentAssignedNotification(nextUserID, c.IncidentID); err != nil {
+		log.Printf("⚠️  Failed to send incident assignment notification: %v", err)
+	}
+	message := fmt.Sprintf("Not acknowledged within %d minutes, reassigned to %s", c.AfterMinutes, toName)
+	if err := w.NotificationWorker.SendIncidentReassignedNotification(c.AssignedTo, c.IncidentID, message); err != nil {
+		log.Printf("⚠️  Failed to send incident reassignment notification: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package workers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// deliveryMaxAttempts bounds redelivery of a chat, WhatsApp, web push or
+// voice notification that could not be delivered
+const deliveryMaxAttempts = 3
+
+// deliveryMessage is queued by services.EnqueueChatNotification,
+// services.EnqueueWhatsAppNotification, services.EnqueueWebPushNotification,
+// services.EnqueueVoiceCall and services.EnqueueFederationEvent
+type deliveryMessage struct {
+	UserID     string `json:"user_id"`
+	IncidentID string `json:"incident_id"`
+	Type       string `json:"type"`
+}
+
+// processChatNotificationsQueue posts queued incident notifications to group
+// chat channels
+func (w *NotificationWorker) processChatNotificationsQueue(queueName string) {
+	w.processDeliveryQueue(queueName, "chat", w.ChatChannels.DeliverIncidentNotification)
+}
+
+// processWhatsAppNotificationsQueue sends queued incident notifications to
+// opted-in users' WhatsApp
+func (w *NotificationWorker) processWhatsAppNotificationsQueue(queueName string) {
+	w.processDeliveryQueue(queueName, "WhatsApp", w.WhatsApp.DeliverIncidentNotification)
+}
+
+// processWebPushNotificationsQueue sends queued incident notifications to
+// users' subscribed browsers
+func (w *NotificationWorker) processWebPushNotificationsQueue(queueName string) {
+	w.processDeliveryQueue(queueName, "web push", w.WebPush.DeliverIncidentNotification)
+}
+
+// processVoiceCallsQueue calls users about incidents they are paged for
+func (w *NotificationWorker) processVoiceCallsQueue(queueName string) {
+	w.processDeliveryQueue(queueName, "voice", w.Voice.DeliverIncidentCall)
+}
+
+// processFederationEventsQueue forwards new incidents to remote SLAR
+// instances and reports status changes of federated incidents to their origin
+func (w *NotificationWorker) processFederationEventsQueue(queueName string) {
+	w.processDeliveryQueue(queueName, "federation", w.Federation.DeliverIncidentEvent)
+}
+
+// processDeliveryQueue hands queued incident notifications to deliver. Failed
+// messages are left on the queue and retried after the visibility timeout.
+func (w *NotificationWorker) processDeliveryQueue(queueName, label string, deliver func(userID, incidentID, notificationType string) error) {
+	rows, err := w.PG.Query(`SELECT msg_id, read_ct, enqueued_at, vt, message FROM pgmq.read($1, 30, $2)`, queueName, 10)
+	if err != nil {
+		log.Printf("❌ Failed to read from queue %s: %v", queueName, err)
+		return
+	}
+
+	var messages []PGMQMessage
+	for rows.Next() {
+		var m PGMQMessage
+		var vt time.Time
+		var messageRaw []byte
+		if err := rows.Scan(&m.MsgID, &m.ReadCT, &m.EnqueuedAt, &vt, &messageRaw); err != nil {
+			log.Printf("❌ Failed to scan message from queue %s: %v", queueName, err)
+			continue
+		}
+		m.Message = json.RawMessage(messageRaw)
+		messages = append(messages, m)
+	}
+	rows.Close()
+
+	for _, m := range messages {
+		var msg deliveryMessage
+		if err := json.Unmarshal(m.Message, &msg); err != nil {
+			log.Printf("❌ Failed to unmarshal %s notification: %v", label, err)
+			w.deleteMessage(queueName, m.MsgID)
+			continue
+		}
+
+		if err := deliver(msg.UserID, msg.IncidentID, msg.Type); err != nil {
+			if m.ReadCT < deliveryMaxAttempts {
+				log.Printf("⚠️  %s notification for incident %s failed (attempt %d), will retry: %v", label, msg.IncidentID, m.ReadCT, err)
+				continue
+			}
+			log.Printf("❌ Giving up on %s notification for incident %s after %d attempts: %v", label, msg.IncidentID, m.ReadCT, err)
+		}
+		w.deleteMessage(queueName, m.MsgID)
+	}
+}
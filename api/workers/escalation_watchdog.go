@@ -0,0 +1,118 @@
+package workers
+
+import (
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/db"
+	"github.com/vanchonlee/slar/internal/config"
+)
+
+// Watchdog metrics, published on /internal/metrics
+var (
+	escalationStallsDetected = expvar.NewInt("escalation_watchdog_stalls_detected")
+	escalationRedrives       = expvar.NewInt("escalation_watchdog_redrives")
+)
+
+const defaultEscalationWatchdogGrace = 2 * time.Minute
+
+// runEscalationWatchdog re-drives incidents whose escalation is overdue by more
+// than the configured grace period. These are incidents the regular escalation
+// loop missed, e.g. because a worker crashed mid-escalation or the policy's
+// levels were removed, and would otherwise stall without anyone being paged.
+func (w *IncidentWorker) runEscalationWatchdog() {
+	grace := config.Current().EscalationWatchdogGrace
+	if grace <= 0 {
+		grace = defaultEscalationWatchdogGrace
+	}
+
+	incidents, err := w.getStalledEscalations(grace)
+	if err != nil {
+		log.Printf("Worker: escalation watchdog failed to get stalled incidents: %v", err)
+		return
+	}
+	if len(incidents) == 0 {
+		return
+	}
+
+	log.Printf("Worker: escalation watchdog found %d stalled incidents", len(incidents))
+
+	for _, incident := range incidents {
+		w.reportStalledEscalation(incident, grace)
+		w.processIncidentEscalation(incident)
+		escalationRedrives.Add(1)
+	}
+}
+
+// getStalledEscalations finds triggered incidents whose current escalation step
+// timed out more than grace ago
+func (w *IncidentWorker) getStalledEscalations(grace time.Duration) ([]db.Incident, error) {
+	query := `
+		SELECT i.id, i.title, i.description, i.status, i.urgency, i.priority,
+		       i.created_at, i.updated_at, i.assigned_to, i.assigned_at,
+		       i.source, i.service_id, i.escalation_policy_id, i.group_id,
+		       i.current_escalation_level, i.last_escalated_at, i.escalation_status,
+		       i.severity, i.incident_key, i.alert_count
+		FROM incidents i
+		WHERE i.status = 'triggered'
+		AND i.escalation_policy_id IS NOT NULL
+		AND i.escalation_status IN ('none', 'pending', 'escalating')
+		AND COALESCE(i.last_escalated_at, i.created_at) < NOW()
+			- INTERVAL '1 minute' * COALESCE(
+				(SELECT el.timeout_minutes FROM escalation_levels el
+				 WHERE el.policy_id = i.escalation_policy_id
+				 AND el.level_number = GREATEST(i.current_escalation_level, 1)
+				 LIMIT 1),
+				0)
+			- INTERVAL '1 second' * $1
+		ORDER BY i.created_at ASC
+		LIMIT 50
+	`
+
+	rows, err := w.PG.Query(query, int(grace.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEscalationIncidents(rows), nil
+}
+
+// reportStalledEscalation records an escalation_stalled system event and bumps
+// the stall metric, once per stalled escalation step
+func (w *IncidentWorker) reportStalledEscalation(incident db.Incident, grace time.Duration) {
+	since := incident.CreatedAt
+	if incident.LastEscalatedAt != nil {
+		since = *incident.LastEscalatedAt
+	}
+
+	var reported bool
+	err := w.PG.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM incident_events
+			WHERE incident_id = $1 AND event_type = 'escalation_stalled' AND created_at >= $2
+		)
+	`, incident.ID, since).Scan(&reported)
+	if err != nil {
+		log.Printf("Worker: failed to check stall events for incident %s: %v", incident.ID, err)
+	}
+	if reported {
+		return
+	}
+
+	escalationStallsDetected.Add(1)
+	log.Printf("Worker: escalation watchdog re-driving incident %s (level %d, status %s, stalled since %v)",
+		incident.ID, incident.CurrentEscalationLevel, incident.EscalationStatus, since)
+
+	eventData := map[string]interface{}{
+		"escalation_level":  incident.CurrentEscalationLevel,
+		"escalation_status": incident.EscalationStatus,
+		"stalled_since":     since,
+		"grace_seconds":     int(grace.Seconds()),
+		"reason":            "escalation_watchdog",
+	}
+	if err := w.createIncidentEvent(incident.ID, "escalation_stalled", eventData, "system"); err != nil {
+		log.Printf("Worker: failed to log escalation stall event: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/services"
+)
+
+const fcmTokenValidationInterval = time.Hour
+
+// FCMTokenWorker periodically prunes stale devices and validates FCM tokens,
+// so pages aren't silently lost to tokens of reinstalled or removed apps
+type FCMTokenWorker struct {
+	FCMService *services.FCMService
+	Interval   time.Duration
+}
+
+func NewFCMTokenWorker(fcmService *services.FCMService) *FCMTokenWorker {
+	return &FCMTokenWorker{FCMService: fcmService, Interval: fcmTokenValidationInterval}
+}
+
+// StartFCMTokenWorker validates tokens every Interval
+func (w *FCMTokenWorker) StartFCMTokenWorker() {
+	log.Printf("FCM token worker started, validating every %s...", w.Interval)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.validateTokens()
+	}
+}
+
+func (w *FCMTokenWorker) validateTokens() {
+	validated, pruned, err := w.FCMService.ValidateDeviceTokens()
+	if err != nil {
+		log.Printf("Worker: failed to validate FCM tokens: %v", err)
+		return
+	}
+	if validated > 0 || pruned > 0 {
+		log.Printf("Worker: validated %d FCM tokens, pruned %d devices", validated, pruned)
+	}
+}
@@ -0,0 +1,58 @@
+package workers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/services"
+)
+
+// processHeldSlackNotificationsQueue releases Slack DMs held back by the
+// push_first dedup policy once their delay is over: they go on to the Slack
+// worker unless the push made them redundant, in which case they're dropped.
+// Messages only become visible on this queue when their delay has passed.
+func (w *NotificationWorker) processHeldSlackNotificationsQueue(queueName string) {
+	rows, err := w.PG.Query(`SELECT msg_id, read_ct, enqueued_at, vt, message FROM pgmq.read($1, 30, $2)`, queueName, 10)
+	if err != nil {
+		log.Printf("❌ Failed to read from queue %s: %v", queueName, err)
+		return
+	}
+
+	var messages []PGMQMessage
+	for rows.Next() {
+		var m PGMQMessage
+		var vt time.Time
+		var messageRaw []byte
+		if err := rows.Scan(&m.MsgID, &m.ReadCT, &m.EnqueuedAt, &vt, &messageRaw); err != nil {
+			log.Printf("❌ Failed to scan message from queue %s: %v", queueName, err)
+			continue
+		}
+		m.Message = json.RawMessage(messageRaw)
+		messages = append(messages, m)
+	}
+	rows.Close()
+
+	for _, m := range messages {
+		var msg deliveryMessage
+		if err := json.Unmarshal(m.Message, &msg); err != nil {
+			log.Printf("❌ Failed to unmarshal held Slack notification: %v", err)
+			w.deleteMessage(queueName, m.MsgID)
+			continue
+		}
+
+		redundant, err := services.HeldSlackNotificationRedundant(w.PG, msg.UserID, msg.IncidentID)
+		if err != nil && m.ReadCT < deliveryMaxAttempts {
+			log.Printf("⚠️  %v (attempt %d), will retry", err, m.ReadCT)
+			continue
+		}
+		if redundant {
+			log.Printf("🔕 Dropping held Slack %s notification for incident %s: user %s already got the push",
+				msg.Type, msg.IncidentID, msg.UserID)
+		} else if _, err := w.PG.Exec(`SELECT pgmq.send($1, $2)`, "incident_notifications", string(m.Message)); err != nil {
+			log.Printf("⚠️  Failed to release held Slack notification for incident %s, will retry: %v", msg.IncidentID, err)
+			continue
+		}
+		w.deleteMessage(queueName, m.MsgID)
+	}
+}
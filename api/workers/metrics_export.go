@@ -0,0 +1,56 @@
+package workers
+
+import (
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/services"
+)
+
+// Metrics export counters, published on /internal/metrics
+var (
+	metricsExportPushes   = expvar.NewInt("metrics_export_pushes")
+	metricsExportFailures = expvar.NewInt("metrics_export_failures")
+)
+
+const defaultMetricsExportInterval = time.Minute
+
+// MetricsExportWorker periodically pushes SLAR's operational metrics to the
+// configured Datadog or Prometheus remote-write endpoint
+type MetricsExportWorker struct {
+	Exporter *services.MetricsExportService
+	Interval time.Duration
+}
+
+func NewMetricsExportWorker(exporter *services.MetricsExportService, interval time.Duration) *MetricsExportWorker {
+	if interval <= 0 {
+		interval = defaultMetricsExportInterval
+	}
+	return &MetricsExportWorker{Exporter: exporter, Interval: interval}
+}
+
+// StartMetricsExportWorker pushes metrics every Interval
+func (w *MetricsExportWorker) StartMetricsExportWorker() {
+	log.Printf("Metrics export worker started, pushing every %s...", w.Interval)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.pushMetrics()
+	}
+}
+
+func (w *MetricsExportWorker) pushMetrics() {
+	sent, err := w.Exporter.Export()
+	if err != nil {
+		metricsExportFailures.Add(1)
+		log.Printf("Worker: failed to export metrics: %v", err)
+		return
+	}
+	metricsExportPushes.Add(1)
+	if sent > 0 {
+		log.Printf("Worker: exported %d metrics", sent)
+	}
+}
@@ -7,21 +7,27 @@ import (
 	"log"
 	"time"
 
+	"github.com/vanchonlee/slar/internal/config"
 	"github.com/vanchonlee/slar/services"
 )
 
 // NotificationWorker handles processing notification messages from PGMQ
 // Note: Slack notifications are handled by the Python SlackWorker for rich formatting
 type NotificationWorker struct {
-	PG         *sql.DB
-	FCMService *services.FCMService
+	PG           *sql.DB
+	FCMService   *services.FCMService
+	ChatChannels *services.ChatChannelService // Discord, Telegram and Google Chat group channels
+	WhatsApp     *services.WhatsAppService
+	WebPush      *services.WebPushService
+	Voice        *services.VoiceService      // Phone-call pages; keypresses are handled by the API
+	Federation   *services.FederationService // Forwarding to remote SLAR instances
 }
 
 // NotificationMessage represents a message in the notification queue
 type NotificationMessage struct {
 	UserID      string                 `json:"user_id"`
 	IncidentID  string                 `json:"incident_id"`
-	Type        string                 `json:"type"`           // "assigned", "escalated", "resolved", "acknowledged"
+	Type        string                 `json:"type"`           // "assigned", "escalated", "paged", "reassigned", "handoff", "claimed", "resolved", "acknowledged"
 	Priority    string                 `json:"priority"`       // "high", "medium", "low"
 	Channels    []string               `json:"channels"`       // ["slack", "email", "push"]
 	Data        map[string]interface{} `json:"data,omitempty"` // Additional context data
@@ -40,8 +46,13 @@ type PGMQMessage struct {
 
 func NewNotificationWorker(pg *sql.DB, fcmService *services.FCMService) *NotificationWorker {
 	return &NotificationWorker{
-		PG:         pg,
-		FCMService: fcmService,
+		PG:           pg,
+		FCMService:   fcmService,
+		ChatChannels: services.NewChatChannelService(pg),
+		WhatsApp:     services.NewWhatsAppService(pg),
+		WebPush:      services.NewWebPushService(pg),
+		Voice:        services.NewVoiceService(pg, nil),
+		Federation:   services.NewFederationService(pg),
 	}
 }
 
@@ -65,6 +76,24 @@ func (w *NotificationWorker) processNotificationMessages() {
 	// Process incident actions (acknowledge, resolve, etc.)
 	w.processIncidentActionsQueue("incident_actions")
 
+	// Post incident notifications to group chat channels (Discord, Telegram, Google Chat)
+	w.processChatNotificationsQueue("chat_notifications")
+
+	// Send incident notifications to opted-in users over WhatsApp
+	w.processWhatsAppNotificationsQueue("whatsapp_notifications")
+
+	// Send incident notifications to users' subscribed browsers (Web Push)
+	w.processWebPushNotificationsQueue("webpush_notifications")
+
+	// Call users who enabled phone-call pages
+	w.processVoiceCallsQueue("voice_calls")
+
+	// Release Slack DMs held back for users who put push first
+	w.processHeldSlackNotificationsQueue(services.HeldSlackNotificationsQueue)
+
+	// Forward incidents to remote SLAR instances and mirror status back
+	w.processFederationEventsQueue("federation_events")
+
 	// Process general notifications (for future use)
 	// w.processQueueMessages("general_notifications")
 }
@@ -80,6 +109,30 @@ func (w *NotificationWorker) deleteMessage(queueName string, msgID int64) {
 
 // sendNotificationMessage sends a notification message to PGMQ queue
 func (w *NotificationWorker) sendNotificationMessage(queueName string, msg *NotificationMessage) error {
+	isTest, err := services.IsTestIncident(w.PG, msg.IncidentID)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if isTest {
+		// Test incidents never page anyone: Slack goes to the test channel only
+		msg.Channels = services.TestModeChannels(msg.Channels, config.Current().SlackTestChannel)
+		if len(msg.Channels) == 0 {
+			log.Printf("🧪 Skipping %s notification for test incident %s (no test channel configured)", msg.Type, msg.IncidentID)
+			return nil
+		}
+		if msg.Data == nil {
+			msg.Data = map[string]interface{}{}
+		}
+		msg.Data["test_mode"] = true
+		msg.Data["slack_channel"] = config.Current().SlackTestChannel
+	} else if msg.ScheduledAt == nil && queueName == "incident_notifications" {
+		// Users who put push first get the Slack DM only if the push goes unnoticed
+		if delay := services.SlackNotificationHoldDelay(w.PG, msg.UserID, msg.Type, msg.Channels); delay > 0 {
+			releaseAt := time.Now().Add(delay)
+			queueName, msg.ScheduledAt = services.HeldSlackNotificationsQueue, &releaseAt
+		}
+	}
+
 	msgJSON, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal notification message: %v", err)
@@ -104,6 +157,24 @@ func (w *NotificationWorker) sendNotificationMessage(queueName string, msg *Noti
 		return fmt.Errorf("failed to send message to queue %s: %v", queueName, err)
 	}
 
+	if err := services.EnqueueChatNotification(w.PG, msg.UserID, msg.IncidentID, msg.Type); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := services.EnqueueWhatsAppNotification(w.PG, msg.UserID, msg.IncidentID, msg.Type); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := services.EnqueueWebPushNotification(w.PG, msg.UserID, msg.IncidentID, msg.Type); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := services.EnqueueVoiceCall(w.PG, msg.UserID, msg.IncidentID, msg.Type); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	// Track read receipts for pages (no-op for informational notifications)
+	if err := services.RecordNotificationSent(w.PG, msg.UserID, msg.IncidentID, msg.Type); err != nil {
+		log.Printf("⚠️  Failed to track page receipt: %v", err)
+	}
+
 	return nil
 }
 
@@ -223,11 +294,75 @@ func (w *NotificationWorker) SendIncidentAcknowledgedNotification(userID, incide
 	return w.sendNotificationMessage("incident_notifications", message)
 }
 
+// SendIncidentPagedNotification is a helper to send ad-hoc page notifications
+func (w *NotificationWorker) SendIncidentPagedNotification(userID, incidentID, pageMessage string) error {
+	message := &NotificationMessage{
+		UserID:     userID,
+		IncidentID: incidentID,
+		Type:       "paged",
+		Priority:   "high",
+		Channels:   []string{"slack", "push"},
+		Data:       map[string]interface{}{"message": pageMessage},
+		RetryCount: 0,
+		CreatedAt:  time.Now(),
+	}
+
+	return w.sendNotificationMessage("incident_notifications", message)
+}
+
+// SendIncidentReassignedNotification tells a former assignee the incident was handed to someone else
+func (w *NotificationWorker) SendIncidentReassignedNotification(userID, incidentID, message string) error {
+	msg := &NotificationMessage{
+		UserID:     userID,
+		IncidentID: incidentID,
+		Type:       "reassigned",
+		Priority:   "medium",
+		Channels:   []string{"slack"},
+		Data:       map[string]interface{}{"message": message},
+		RetryCount: 0,
+		CreatedAt:  time.Now(),
+	}
+
+	return w.sendNotificationMessage("incident_notifications", msg)
+}
+
+// SendIncidentHandoffNotification tells a former assignee their incident went to the incoming on-call
+func (w *NotificationWorker) SendIncidentHandoffNotification(userID, incidentID, message string) error {
+	msg := &NotificationMessage{
+		UserID:     userID,
+		IncidentID: incidentID,
+		Type:       "handoff",
+		Priority:   "medium",
+		Channels:   []string{"slack"},
+		Data:       map[string]interface{}{"message": message},
+		RetryCount: 0,
+		CreatedAt:  time.Now(),
+	}
+
+	return w.sendNotificationMessage("incident_notifications", msg)
+}
+
+// SendIncidentClaimedNotification tells a responder paged in parallel that someone else acknowledged first
+func (w *NotificationWorker) SendIncidentClaimedNotification(userID, incidentID, message string) error {
+	msg := &NotificationMessage{
+		UserID:     userID,
+		IncidentID: incidentID,
+		Type:       "claimed",
+		Priority:   "medium",
+		Channels:   []string{"slack"},
+		Data:       map[string]interface{}{"message": message},
+		RetryCount: 0,
+		CreatedAt:  time.Now(),
+	}
+
+	return w.sendNotificationMessage("incident_notifications", msg)
+}
+
 // GetQueueStats returns statistics about notification queues
 func (w *NotificationWorker) GetQueueStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
-	queues := []string{"incident_notifications", "general_notifications"}
+	queues := []string{"incident_notifications", "general_notifications", "chat_notifications", "whatsapp_notifications", "webpush_notifications"}
 
 	for _, queue := range queues {
 		query := `SELECT pgmq.metrics($1)`
@@ -0,0 +1,55 @@
+package workers
+
+import (
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/services"
+)
+
+// Sheet export counters, published on /internal/metrics
+var (
+	sheetExportedIncidents = expvar.NewInt("sheet_exported_incidents")
+	sheetExportFailures    = expvar.NewInt("sheet_export_failures")
+)
+
+const defaultSheetExportInterval = time.Minute
+
+// SheetExportWorker periodically appends newly resolved incidents to the
+// groups' sheet exports
+type SheetExportWorker struct {
+	Exporter *services.SheetExportService
+	Interval time.Duration
+}
+
+func NewSheetExportWorker(exporter *services.SheetExportService, interval time.Duration) *SheetExportWorker {
+	if interval <= 0 {
+		interval = defaultSheetExportInterval
+	}
+	return &SheetExportWorker{Exporter: exporter, Interval: interval}
+}
+
+// StartSheetExportWorker exports resolved incidents every Interval
+func (w *SheetExportWorker) StartSheetExportWorker() {
+	log.Printf("Sheet export worker started, exporting every %s...", w.Interval)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.exportIncidents()
+	}
+}
+
+func (w *SheetExportWorker) exportIncidents() {
+	exported, err := w.Exporter.Export()
+	sheetExportedIncidents.Add(int64(exported))
+	if err != nil {
+		sheetExportFailures.Add(1)
+		log.Printf("Worker: sheet export failed: %v", err)
+	}
+	if exported > 0 {
+		log.Printf("Worker: exported %d resolved incidents to sheets", exported)
+	}
+}
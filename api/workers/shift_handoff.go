@@ -0,0 +1,161 @@
+package workers
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/vanchonlee/slar/db"
+)
+
+// handoffCandidate is an open incident whose assignee's shift has ended
+type handoffCandidate struct {
+	IncidentID  string
+	GroupID     string
+	AssignedTo  string
+	SchedulerID string
+}
+
+// processShiftHandoffs hands open incidents of groups with reassign_on_handoff
+// to the incoming on-call once the assignee's shift in the group has ended.
+// Only shifts that ended after the incident was assigned count, so incidents
+// given by hand to someone off call stay where they are.
+func (w *IncidentWorker) processShiftHandoffs() {
+	candidates, err := w.getIncidentsNeedingHandoff()
+	if err != nil {
+		log.Printf("Worker: failed to get incidents needing handoff: %v", err)
+		return
+	}
+
+	for _, c := range candidates {
+		w.handOffIncident(c)
+	}
+}
+
+// getIncidentsNeedingHandoff finds triggered and acknowledged incidents whose
+// assignee had a shift in the incident's group end since they were assigned
+// and is not on call there now
+func (w *IncidentWorker) getIncidentsNeedingHandoff() ([]handoffCandidate, error) {
+	rows, err := w.PG.Query(`
+		SELECT i.id, i.group_id::text, i.assigned_to::text, COALESCE(ended.scheduler_id::text, '')
+		FROM incidents i
+		JOIN groups g ON g.id = i.group_id AND g.reassign_on_handoff = true
+		CROSS JOIN LATERAL (
+			SELECT es.scheduler_id
+			FROM effective_shifts es
+			WHERE es.group_id = i.group_id
+			AND es.effective_user_id = i.assigned_to
+			AND es.is_active = true
+			AND es.end_time <= NOW()
+			AND es.end_time > COALESCE(i.assigned_at, i.created_at)
+			ORDER BY es.end_time DESC
+			LIMIT 1
+		) ended
+		WHERE i.status IN ('triggered', 'acknowledged')
+		AND i.assigned_to IS NOT NULL
+		AND NOT COALESCE(i.is_test, false)
+		AND NOT EXISTS (
+			SELECT 1 FROM effective_shifts cur
+			WHERE cur.group_id = i.group_id
+			AND cur.effective_user_id = i.assigned_to
+			AND cur.is_active = true
+			AND cur.start_time <= NOW()
+			AND cur.end_time > NOW()
+		)
+		ORDER BY i.created_at ASC
+		LIMIT 50
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []handoffCandidate
+	for rows.Next() {
+		var c handoffCandidate
+		if err := rows.Scan(&c.IncidentID, &c.GroupID, &c.AssignedTo, &c.SchedulerID); err != nil {
+			log.Printf("Worker: error scanning handoff candidate: %v", err)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// incomingOnCall returns who is on call and available in the group now other
+// than userID, preferring the scheduler whose shift just ended
+func (w *IncidentWorker) incomingOnCall(groupID, userID, schedulerID string) (string, error) {
+	var nextUserID string
+	err := w.PG.QueryRow(`
+		SELECT es.effective_user_id
+		FROM effective_shifts es
+		WHERE es.group_id = $1
+		AND es.effective_user_id <> $2
+		AND es.is_active = true
+		AND es.start_time <= NOW()
+		AND es.end_time > NOW()
+		AND user_available(es.effective_user_id, NOW())
+		ORDER BY es.scheduler_id::text = $3 DESC, es.start_time DESC
+		LIMIT 1
+	`, groupID, userID, schedulerID).Scan(&nextUserID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return nextUserID, err
+}
+
+// handOffIncident moves one incident to the incoming on-call, records the
+// handoff in the timeline and notifies both people
+func (w *IncidentWorker) handOffIncident(c handoffCandidate) {
+	nextUserID, err := w.incomingOnCall(c.GroupID, c.AssignedTo, c.SchedulerID)
+	if err != nil {
+		log.Printf("Worker: failed to find incoming on-call for incident %s: %v", c.IncidentID, err)
+		return
+	}
+	if nextUserID == "" {
+		// Nobody on call to take it; leave it with the last person
+		return
+	}
+
+	// Only move the incident if it is still open and still theirs
+	result, err := w.PG.Exec(`
+		UPDATE incidents
+		SET assigned_to = $3, assigned_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND assigned_to = $2 AND status IN ('triggered', 'acknowledged')
+	`, c.IncidentID, c.AssignedTo, nextUserID)
+	if err != nil {
+		log.Printf("Worker: failed to hand off incident %s: %v", c.IncidentID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
+
+	eventData := map[string]interface{}{
+		"from_user_id": c.AssignedTo,
+		"to_user_id":   nextUserID,
+		"reason":       "shift_handoff",
+	}
+	if name, err := w.getUserName(c.AssignedTo); err == nil {
+		eventData["from_user"] = name
+	}
+	toName := "the incoming on-call"
+	if name, err := w.getUserName(nextUserID); err == nil {
+		eventData["to_user"] = name
+		toName = name
+	}
+	if err := w.createIncidentEvent(c.IncidentID, db.IncidentEventReassigned, eventData, "system"); err != nil {
+		log.Printf("Worker: failed to log handoff event: %v", err)
+	}
+
+	log.Printf("Worker: handed off incident %s from %s to %s (shift ended)", c.IncidentID, c.AssignedTo, nextUserID)
+
+	if w.NotificationWorker == nil {
+		return
+	}
+	if err := w.NotificationWorker.SendIncidentAssignedNotification(nextUserID, c.IncidentID); err != nil {
+		log.Printf("⚠️  Failed to send incident assignment notification: %v", err)
+	}
+	if err := w.NotificationWorker.SendIncidentHandoffNotification(c.AssignedTo, c.IncidentID, "Your shift ended, handed off to "+toName); err != nil {
+		log.Printf("⚠️  Failed to send incident handoff notification: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package workers
+
+import (
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/vanchonlee/slar/services"
+)
+
+// SIEM export counters, published on /internal/metrics
+var (
+	siemExportedEvents = expvar.NewInt("siem_exported_events")
+	siemExportFailures = expvar.NewInt("siem_export_failures")
+)
+
+const defaultSIEMExportInterval = 15 * time.Second
+
+// SIEMExportWorker periodically streams new incident and audit events to the
+// SIEMs configured through "siem" integrations
+type SIEMExportWorker struct {
+	Exporter *services.SIEMExportService
+	Interval time.Duration
+}
+
+func NewSIEMExportWorker(exporter *services.SIEMExportService, interval time.Duration) *SIEMExportWorker {
+	if interval <= 0 {
+		interval = defaultSIEMExportInterval
+	}
+	return &SIEMExportWorker{Exporter: exporter, Interval: interval}
+}
+
+// StartSIEMExportWorker exports events every Interval
+func (w *SIEMExportWorker) StartSIEMExportWorker() {
+	log.Printf("SIEM export worker started, exporting every %s...", w.Interval)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.exportEvents()
+	}
+}
+
+func (w *SIEMExportWorker) exportEvents() {
+	sent, err := w.Exporter.Export()
+	siemExportedEvents.Add(int64(sent))
+	if err != nil {
+		siemExportFailures.Add(1)
+		log.Printf("Worker: SIEM export failed: %v", err)
+	}
+	if sent > 0 {
+		log.Printf("Worker: exported %d events to SIEM", sent)
+	}
+}
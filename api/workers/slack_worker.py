@@ -341,8 +341,10 @@ class SlackWorker:
 
             if notification_type == 'assigned':
                 return self.send_incident_assigned_notification(user_data, incident_data, notification_msg)
-            elif notification_type == 'escalated':
+            elif notification_type in ('escalated', 'paged'):
                 return self.send_incident_escalated_notification(user_data, incident_data, notification_msg)
+            elif notification_type in ('reassigned', 'handoff', 'claimed', 'schedule_impact'):
+                return self.send_incident_reassigned_notification(user_data, incident_data, notification_msg)
             elif notification_type == 'acknowledged':
                 return self.send_incident_x_notification(user_data, incident_data, notification_msg, 'acknowledged')
             elif notification_type == 'resolved':
@@ -442,7 +444,7 @@ class SlackWorker:
             # Send message using Slack Client
             notification_text = f"[Assigned] {incident_message.get_title()}"
             response = self.slack_client.chat_postMessage(
-                channel=f"@{slack_user_id}",
+                channel=self._recipient(notification_msg, slack_user_id),
                 text=notification_text,
                 blocks=blocks
             )
@@ -450,7 +452,7 @@ class SlackWorker:
             logger.info(f"📨 Slack response: {response}")
 
             notification_msg_with_recipient = notification_msg.copy()
-            notification_msg_with_recipient['recipient'] = f"@{slack_user_id}"
+            notification_msg_with_recipient['recipient'] = self._recipient(notification_msg, slack_user_id)
 
             message_ts = response.get('ts') if response else None
             channel_id = response.get('channel') if response else None
@@ -466,7 +468,7 @@ class SlackWorker:
         except Exception as e:
             logger.error(f"❌ Failed to send Slack notification: {e}")
             notification_msg_with_recipient = notification_msg.copy()
-            notification_msg_with_recipient['recipient'] = f"@{slack_user_id}"
+            notification_msg_with_recipient['recipient'] = self._recipient(notification_msg, slack_user_id)
             self.repo.log_notification(notification_msg_with_recipient, 'slack', False, str(e))
             return False
 
@@ -566,13 +568,13 @@ class SlackWorker:
             incident_short_id = f"#{incident_data.get('id', '')[-8:]}"
             incident_title = incident_data.get('title', 'Unknown Incident')
             self.slack_client.chat_postMessage(
-                channel=f"@{slack_user_id}",
+                channel=self._recipient(notification_msg, slack_user_id),
                 text=f"Incident {incident_short_id} \"{incident_title}\" acknowledged",
                 blocks=blocks
             )
 
             notification_msg_with_recipient = notification_msg.copy()
-            notification_msg_with_recipient['recipient'] = f"@{slack_user_id}"
+            notification_msg_with_recipient['recipient'] = self._recipient(notification_msg, slack_user_id)
             self.repo.log_notification(notification_msg_with_recipient, 'slack', True, None)
             return True
 
@@ -625,13 +627,13 @@ class SlackWorker:
                 })
 
             response = self.slack_client.chat_postMessage(
-                channel=f"@{slack_user_id}",
+                channel=self._recipient(notification_msg, slack_user_id),
                 text=f"Incident Resolved",
                 blocks=blocks
             )
 
             notification_msg_with_recipient = notification_msg.copy()
-            notification_msg_with_recipient['recipient'] = f"@{slack_user_id}"
+            notification_msg_with_recipient['recipient'] = self._recipient(notification_msg, slack_user_id)
             self.repo.log_notification(notification_msg_with_recipient, 'slack', True, None)
             return True
 
@@ -639,6 +641,53 @@ class SlackWorker:
             logger.error(f"❌ Failed to send new Slack resolved notification: {e}")
             return False
             
+    def send_incident_reassigned_notification(self, user_data: Dict, incident_data: Dict, notification_msg: Dict) -> bool:
+        """Tell a former assignee the incident was handed to the next schedule member
+        or to the incoming on-call at the end of their shift, a responder paged in
+        parallel that someone else claimed it, or both sides of an incident that a
+        proposed schedule change would move"""
+        try:
+            slack_user_id = user_data['slack_user_id'].lstrip('@')
+            reason = (notification_msg.get('data') or {}).get('message', 'Reassigned to the next on-call')
+            header = "↪️ Incident Reassigned"
+            if notification_msg.get('type') == 'claimed':
+                header = "🙋 Incident Claimed"
+            elif notification_msg.get('type') == 'handoff':
+                header = "🔁 Incident Handed Off"
+            elif notification_msg.get('type') == 'schedule_impact':
+                header = "📅 Proposed Schedule Change"
+
+            blocks = [
+                {"type": "header", "text": {"type": "plain_text", "text": header}},
+                {"type": "section", "text": {"type": "mrkdwn", "text": f"*{incident_data.get('title', 'Unknown Incident')}*\n{reason}"}},
+            ]
+            if incident_data.get('id'):
+                blocks.append({
+                    "type": "actions",
+                    "elements": [
+                        {
+                            "type": "button",
+                            "text": {"type": "plain_text", "text": "View Incident"},
+                            "url": self.builder.get_incident_url(incident_data['id'])
+                        }
+                    ]
+                })
+
+            self.slack_client.chat_postMessage(
+                channel=self._recipient(notification_msg, slack_user_id),
+                text=f"Incident reassigned: {incident_data.get('title', 'Unknown Incident')}",
+                blocks=blocks
+            )
+
+            notification_msg_with_recipient = notification_msg.copy()
+            notification_msg_with_recipient['recipient'] = self._recipient(notification_msg, slack_user_id)
+            self.repo.log_notification(notification_msg_with_recipient, 'slack', True, None)
+            return True
+
+        except Exception as e:
+            logger.error(f"❌ Failed to send Slack reassigned notification: {e}")
+            return False
+
     def send_incident_escalated_notification(self, user_data: Dict, incident_data: Dict, notification_msg: Dict) -> bool:
         """Send Slack notification for incident escalation"""
         try:
@@ -668,13 +717,13 @@ class SlackWorker:
                 })
             
             response = self.slack_client.chat_postMessage(
-                channel=f"@{slack_user_id}",
+                channel=self._recipient(notification_msg, slack_user_id),
                 text=f"🔄 [Escalated] {incident_message.get_title()}",
                 blocks=blocks
             )
 
             notification_msg_with_recipient = notification_msg.copy()
-            notification_msg_with_recipient['recipient'] = f"@{slack_user_id}"
+            notification_msg_with_recipient['recipient'] = self._recipient(notification_msg, slack_user_id)
 
             message_ts = response.get('ts') if response else None
             channel_id = response.get('channel') if response else None
@@ -688,6 +737,11 @@ class SlackWorker:
             logger.error(f"❌ Failed to send Slack escalation notification: {e}")
             return False
 
+    def _recipient(self, notification_msg: Dict, slack_user_id: str) -> str:
+        """Test-mode incidents are posted to the configured test channel instead of the user's DM"""
+        test_channel = (notification_msg.get('data') or {}).get('slack_channel')
+        return test_channel or f"@{slack_user_id}"
+
     def handle_failed_message(self, queue_name: str, msg_id: int, notification_msg: Dict, read_ct: int = 0):
         """Handle failed message processing with retry logic"""
         try:
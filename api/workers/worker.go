@@ -35,8 +35,230 @@ func (w *IncidentWorker) StartIncidentWorker() {
 	ticker := time.NewTicker(5 * time.Second) // Check every 30 seconds
 	defer ticker.Stop()
 
-	for range ticker.C {
-		w.processEscalations()
+	purgeTicker := time.NewTicker(time.Hour)
+	defer purgeTicker.Stop()
+
+	sloTicker := time.NewTicker(time.Minute)
+	defer sloTicker.Stop()
+
+	watchdogTicker := time.NewTicker(time.Minute)
+	defer watchdogTicker.Stop()
+
+	reassignTicker := time.NewTicker(30 * time.Second)
+	defer reassignTicker.Stop()
+
+	drillTicker := time.NewTicker(30 * time.Second)
+	defer drillTicker.Stop()
+
+	silenceTicker := time.NewTicker(time.Minute)
+	defer silenceTicker.Stop()
+
+	stormTicker := time.NewTicker(time.Minute)
+	defer stormTicker.Stop()
+
+	statusUpdateTicker := time.NewTicker(time.Minute)
+	defer statusUpdateTicker.Stop()
+
+	// Reports are rebuilt once a day; checking hourly survives restarts
+	reportTicker := time.NewTicker(time.Hour)
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.processEscalations()
+		case <-purgeTicker.C:
+			w.purgeIdempotencyKeys()
+			w.purgeStaleIncidentViewers()
+			w.purgeExpiredIncidentArtifacts()
+			w.maintainIncidentPartitions()
+			w.purgeExpiredTrash()
+			w.expirePendingChanges()
+		case <-sloTicker.C:
+			w.evaluateSLOBurnRates()
+		case <-watchdogTicker.C:
+			w.runEscalationWatchdog()
+		case <-reassignTicker.C:
+			w.processAutoReassignments()
+			w.processShiftHandoffs()
+			w.processAckTimeouts()
+		case <-drillTicker.C:
+			w.runDrills()
+		case <-silenceTicker.C:
+			w.evaluateIntegrationSilence()
+		case <-stormTicker.C:
+			w.evaluateAlertStorms()
+		case <-statusUpdateTicker.C:
+			w.sendIncidentStatusUpdates()
+		case <-reportTicker.C:
+			w.refreshRecurringProblemReports()
+			w.meterOrgUsage()
+			w.sendActivityDigests()
+		}
+	}
+}
+
+// evaluateSLOBurnRates opens incidents for SLOs burning their error budget too fast
+func (w *IncidentWorker) evaluateSLOBurnRates() {
+	opened, err := w.IncidentService.EvaluateSLOBurnRates()
+	if err != nil {
+		log.Printf("Worker: failed to evaluate SLO burn rates: %v", err)
+		return
+	}
+	if opened > 0 {
+		log.Printf("Worker: opened %d SLO burn-rate incidents", opened)
+	}
+}
+
+// runDrills starts scheduled drills that are due and ends finished ones
+func (w *IncidentWorker) runDrills() {
+	started, ended, err := w.IncidentService.RunDrills()
+	if err != nil {
+		log.Printf("Worker: failed to run drills: %v", err)
+	}
+	if started > 0 || ended > 0 {
+		log.Printf("Worker: started %d drills, ended %d drills", started, ended)
+	}
+}
+
+// evaluateIntegrationSilence pages on integrations that stopped sending webhooks
+// and resolves those incidents once they recover
+func (w *IncidentWorker) evaluateIntegrationSilence() {
+	opened, resolved, err := w.IncidentService.EvaluateIntegrationSilence()
+	if err != nil {
+		log.Printf("Worker: failed to evaluate integration silence: %v", err)
+		return
+	}
+	if opened > 0 || resolved > 0 {
+		log.Printf("Worker: opened %d integration silence incidents, resolved %d", opened, resolved)
+	}
+}
+
+// evaluateAlertStorms opens incidents for services whose incident rate spikes
+// past their baseline and resolves them once it settles
+func (w *IncidentWorker) evaluateAlertStorms() {
+	opened, resolved, err := w.IncidentService.EvaluateAlertStorms()
+	if err != nil {
+		log.Printf("Worker: failed to evaluate alert storms: %v", err)
+		return
+	}
+	if opened > 0 || resolved > 0 {
+		log.Printf("Worker: opened %d alert storm incidents, resolved %d", opened, resolved)
+	}
+}
+
+// sendIncidentStatusUpdates posts the periodic status updates incident
+// commanders asked for
+func (w *IncidentWorker) sendIncidentStatusUpdates() {
+	sent, err := w.IncidentService.SendIncidentStatusUpdates()
+	if err != nil {
+		log.Printf("Worker: failed to send incident status updates: %v", err)
+		return
+	}
+	if sent > 0 {
+		log.Printf("Worker: queued %d incident status updates", sent)
+	}
+}
+
+// refreshRecurringProblemReports rebuilds each organization's daily report
+// of recurring problems
+func (w *IncidentWorker) refreshRecurringProblemReports() {
+	built, err := w.IncidentService.RefreshRecurringProblemReports()
+	if err != nil {
+		log.Printf("Worker: failed to refresh recurring problem reports: %v", err)
+	}
+	if built > 0 {
+		log.Printf("Worker: rebuilt %d recurring problem reports", built)
+	}
+}
+
+// purgeIdempotencyKeys removes incident idempotency keys past their TTL
+func (w *IncidentWorker) purgeIdempotencyKeys() {
+	purged, err := w.IncidentService.PurgeExpiredIdempotencyKeys()
+	if err != nil {
+		log.Printf("Worker: failed to purge idempotency keys: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Worker: purged %d expired idempotency keys", purged)
+	}
+}
+
+// purgeExpiredIncidentArtifacts removes diagnostic artifacts past their retention
+func (w *IncidentWorker) purgeExpiredIncidentArtifacts() {
+	purged, err := w.IncidentService.PurgeExpiredIncidentArtifacts()
+	if err != nil {
+		log.Printf("Worker: failed to purge incident artifacts: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Worker: purged %d expired incident artifacts", purged)
+	}
+}
+
+// maintainIncidentPartitions keeps upcoming monthly incidents and
+// incident_events partitions ready and drops event months past their retention
+func (w *IncidentWorker) maintainIncidentPartitions() {
+	created, dropped, err := w.IncidentService.MaintainIncidentPartitions()
+	if err != nil {
+		log.Printf("Worker: failed to maintain incident partitions: %v", err)
+		return
+	}
+	if created > 0 || dropped > 0 {
+		log.Printf("Worker: created %d incident partitions, dropped %d incident_events partitions", created, dropped)
+	}
+}
+
+// purgeExpiredTrash forgets deleted resources past their trash retention
+func (w *IncidentWorker) purgeExpiredTrash() {
+	purged, err := services.NewTrashService(w.PG).PurgeExpiredTrash()
+	if err != nil {
+		log.Printf("Worker: failed to purge trash: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Worker: purged %d expired trash items", purged)
+	}
+}
+
+// expirePendingChanges closes destructive admin changes nobody approved in time
+func (w *IncidentWorker) expirePendingChanges() {
+	expired, err := services.NewPendingChangeService(w.PG, nil, nil, nil).ExpirePendingChanges()
+	if err != nil {
+		log.Printf("Worker: failed to expire pending changes: %v", err)
+		return
+	}
+	if expired > 0 {
+		log.Printf("Worker: expired %d pending admin changes", expired)
+	}
+}
+
+// meterOrgUsage updates each organization's monthly usage rollup
+func (w *IncidentWorker) meterOrgUsage() {
+	metered, err := services.NewUsageService(w.PG).RefreshOrgUsage()
+	if err != nil {
+		log.Printf("Worker: failed to meter organization usage: %v", err)
+	}
+	if metered > 0 {
+		log.Printf("Worker: metered usage for %d organizations", metered)
+	}
+}
+
+// sendActivityDigests emails managers the digests whose period has ended
+func (w *IncidentWorker) sendActivityDigests() {
+	sent, err := services.NewActivityDigestService(w.PG, services.NewEmailService()).SendDueDigests()
+	if err != nil {
+		log.Printf("Worker: failed to send activity digests: %v", err)
+	}
+	if sent > 0 {
+		log.Printf("Worker: sent %d activity digests", sent)
+	}
+}
+
+// purgeStaleIncidentViewers removes presence rows left by closed incident pages
+func (w *IncidentWorker) purgeStaleIncidentViewers() {
+	if _, err := w.IncidentService.PurgeStaleIncidentViewers(); err != nil {
+		log.Printf("Worker: failed to purge incident viewers: %v", err)
 	}
 }
 
@@ -65,6 +287,10 @@ func (w *IncidentWorker) processEscalations() {
 	}
 }
 
+// freezeTimeoutFactor shortens escalation step timeouts while a freeze
+// period covers the incident
+const freezeTimeoutFactor = `(` + services.FreezeTimeoutPercentSQL + ` / 100.0)::float8`
+
 // getIncidentsNeedingEscalation finds incidents that need to be escalated
 func (w *IncidentWorker) getIncidentsNeedingEscalation() ([]db.Incident, error) {
 	// First, let's debug what incidents exist and check timezone issues
@@ -147,7 +373,7 @@ func (w *IncidentWorker) getIncidentsNeedingEscalation() ([]db.Incident, error)
 				SELECT 1 FROM escalation_levels el1
 				WHERE el1.policy_id = i.escalation_policy_id
 				AND el1.level_number = 1
-				AND i.created_at < NOW() - INTERVAL '1 minute' * el1.timeout_minutes
+				AND i.created_at < NOW() - INTERVAL '1 minute' * el1.timeout_minutes * `+freezeTimeoutFactor+`
 			 ))
 			OR
 			-- Already escalated: check if current level has timed out and next level exists
@@ -157,7 +383,25 @@ func (w *IncidentWorker) getIncidentsNeedingEscalation() ([]db.Incident, error)
 				SELECT 1 FROM escalation_levels el_current
 				WHERE el_current.policy_id = i.escalation_policy_id
 				AND el_current.level_number = i.current_escalation_level
-				AND i.last_escalated_at < NOW() - INTERVAL '1 minute' * el_current.timeout_minutes
+				AND i.last_escalated_at < NOW() - INTERVAL '1 minute' * el_current.timeout_minutes * `+freezeTimeoutFactor+`
+			 )
+			 AND EXISTS (
+				SELECT 1 FROM escalation_levels el_next
+				WHERE el_next.policy_id = i.escalation_policy_id
+				AND el_next.level_number = i.current_escalation_level + 1
+			 ))
+			OR
+			-- Page unseen: policy escalates early when nobody has opened the latest page
+			(EXISTS (
+				SELECT 1 FROM escalation_policies ep
+				WHERE ep.id = i.escalation_policy_id
+				AND ep.unseen_escalate_after_minutes > 0
+				AND COALESCE(i.last_escalated_at, i.created_at) < NOW() - INTERVAL '1 minute' * ep.unseen_escalate_after_minutes
+			 )
+			 AND NOT EXISTS (
+				SELECT 1 FROM notification_receipts nr
+				WHERE nr.incident_id = i.id
+				AND nr.seen_at >= COALESCE(i.last_escalated_at, i.created_at)
 			 )
 			 AND EXISTS (
 				SELECT 1 FROM escalation_levels el_next
@@ -176,6 +420,11 @@ func (w *IncidentWorker) getIncidentsNeedingEscalation() ([]db.Incident, error)
 	}
 	defer rows.Close()
 
+	return scanEscalationIncidents(rows), nil
+}
+
+// scanEscalationIncidents scans the incident columns shared by the escalation and watchdog queries
+func scanEscalationIncidents(rows *sql.Rows) []db.Incident {
 	var incidents []db.Incident
 	for rows.Next() {
 		var incident db.Incident
@@ -218,7 +467,7 @@ func (w *IncidentWorker) getIncidentsNeedingEscalation() ([]db.Incident, error)
 		incidents = append(incidents, incident)
 	}
 
-	return incidents, nil
+	return incidents
 }
 
 // processIncidentEscalation handles escalation for a single incident
@@ -257,23 +506,29 @@ func (w *IncidentWorker) processIncidentEscalation(incident db.Incident) {
 	nextLevel := incident.CurrentEscalationLevel + 1
 	logger.Debug("Next escalation level should be %d (current: %d)", nextLevel, incident.CurrentEscalationLevel)
 
-	if nextLevel > len(escalationLevels) {
+	// A level can have several targets (one row each), all paged in parallel
+	maxLevel := 0
+	for _, level := range escalationLevels {
+		if level.LevelNumber > maxLevel {
+			maxLevel = level.LevelNumber
+		}
+	}
+	if nextLevel > maxLevel {
 		log.Printf("Worker: incident %s has reached maximum escalation level (next: %d, max: %d)",
-			incident.ID, nextLevel, len(escalationLevels))
+			incident.ID, nextLevel, maxLevel)
 		w.updateIncidentEscalation(incident.ID, incident.CurrentEscalationLevel, "completed")
 		return
 	}
 
 	// Get the escalation level to process
-	var targetLevel db.EscalationLevel
+	var targets []db.EscalationLevel
 	for _, level := range escalationLevels {
 		if level.LevelNumber == nextLevel {
-			targetLevel = level
-			break
+			targets = append(targets, level)
 		}
 	}
 
-	if targetLevel.ID == "" {
+	if len(targets) == 0 {
 		log.Printf("Worker: escalation level %d not found for incident %s (available levels: %v)",
 			nextLevel, incident.ID, func() []int {
 				var levels []int
@@ -286,11 +541,47 @@ func (w *IncidentWorker) processIncidentEscalation(incident db.Incident) {
 		return
 	}
 
-	logger.Debug("Found target level %d - Type: %s, Target: %s",
-		targetLevel.LevelNumber, targetLevel.TargetType, targetLevel.TargetID)
+	targetLevel := targets[0]
+	logger.Debug("Found target level %d - %d target(s), first: %s %s",
+		targetLevel.LevelNumber, len(targets), targetLevel.TargetType, targetLevel.TargetID)
+
+	// Process escalation based on target type. Several targets, or a group
+	// escalating in parallel, page everyone they resolve to at once. Targets
+	// resolved through a schedule pass over anyone on vacation or DND.
+	var pagedUsers []string
+	var skipped []db.SkippedResponder
+	success := false
+	scheduleTarget := targetLevel.TargetType == "scheduler" || targetLevel.TargetType == "group" || targetLevel.TargetType == "current_schedule" ||
+		targetLevel.TargetType == db.EscalationTargetExternalSchedule
+	if len(targets) == 1 && !scheduleTarget {
+		success = w.processEscalationTarget(incident, targetLevel)
+	} else if users, passed, err := services.ResolveEscalationLevelUsers(w.PG, incident.GroupID, targets); err != nil {
+		log.Printf("Worker: failed to resolve escalation level %d for incident %s: %v", nextLevel, incident.ID, err)
+	} else {
+		skipped = passed
+		w.logSkippedResponders(incident.ID, nextLevel, skipped)
+		success = w.escalateToLevelUsers(incident, targets, users)
+		pagedUsers = users
+	}
 
-	// Process escalation based on target type
-	success := w.processEscalationTarget(incident, targetLevel)
+	// Everyone the level would have paged is away: fall through to the next
+	// level now rather than retrying this one
+	if !success && len(pagedUsers) == 0 && len(skipped) > 0 {
+		for _, level := range escalationLevels {
+			if level.LevelNumber == nextLevel+1 {
+				log.Printf("Worker: everyone at level %d of incident %s is unavailable, moving on to level %d",
+					nextLevel, incident.ID, nextLevel+1)
+				w.updateIncidentEscalation(incident.ID, nextLevel, "pending")
+				incident.CurrentEscalationLevel = nextLevel
+				w.processIncidentEscalation(incident)
+				return
+			}
+		}
+		log.Printf("Worker: everyone at final level %d of incident %s is unavailable", nextLevel, incident.ID)
+		w.updateIncidentEscalation(incident.ID, nextLevel, "completed")
+		w.createEscalationCompletionEvent(incident.ID, nextLevel)
+		return
+	}
 
 	// Update incident escalation status
 	if success {
@@ -301,6 +592,16 @@ func (w *IncidentWorker) processIncidentEscalation(incident db.Incident) {
 			"target_id":        targetLevel.TargetID,
 			"reason":           "escalation_policy",
 		}
+		if len(targets) > 1 {
+			levelTargets := make([]map[string]interface{}, 0, len(targets))
+			for _, t := range targets {
+				levelTargets = append(levelTargets, map[string]interface{}{"target_type": t.TargetType, "target_id": t.TargetID})
+			}
+			eventData["targets"] = levelTargets
+		}
+		if len(pagedUsers) > 1 {
+			eventData[services.ParallelPagedUsersKey] = pagedUsers
+		}
 
 		// Get assignee info for the event
 		if assigneeID, err := w.getIncidentAssignee(incident.ID); err == nil && assigneeID != "" {
@@ -350,7 +651,7 @@ func (w *IncidentWorker) getEscalationLevels(policyID string) ([]db.EscalationLe
 		SELECT id, policy_id, level_number, target_type, target_id, timeout_minutes
 		FROM escalation_levels
 		WHERE policy_id = $1
-		ORDER BY level_number ASC
+		ORDER BY level_number ASC, created_at ASC
 	`
 
 	rows, err := w.PG.Query(query, policyID)
@@ -396,6 +697,55 @@ func (w *IncidentWorker) processEscalationTarget(incident db.Incident, level db.
 	}
 }
 
+// escalateToLevelUsers pages everyone a level resolved to at once. The first
+// of them is assigned until one of the others acknowledges first and claims it.
+func (w *IncidentWorker) escalateToLevelUsers(incident db.Incident, targets []db.EscalationLevel, userIDs []string) bool {
+	external := false
+	for _, target := range targets {
+		if target.TargetType == "external" {
+			external = w.escalateToExternal(incident, target.TargetID) || external
+		}
+	}
+	if len(userIDs) == 0 {
+		log.Printf("Worker: no one to page at this level for incident %s", incident.ID)
+		return external
+	}
+	if !w.escalateToUserWithNotification(incident, userIDs[0], false) {
+		return false
+	}
+	if w.NotificationWorker == nil {
+		return true
+	}
+	for _, userID := range userIDs {
+		if err := w.NotificationWorker.SendIncidentEscalatedNotification(userID, incident.ID); err != nil {
+			log.Printf("⚠️  Failed to send incident escalation notification to %s: %v", userID, err)
+		}
+	}
+	log.Printf("✅ Sent incident escalation notification to %d responder(s) for incident %s", len(userIDs), incident.ID)
+	return true
+}
+
+// logSkippedResponders records in the timeline who an escalation level
+// passed over because they were on vacation or DND
+func (w *IncidentWorker) logSkippedResponders(incidentID string, level int, skipped []db.SkippedResponder) {
+	if len(skipped) == 0 {
+		return
+	}
+	names := make([]string, 0, len(skipped))
+	for _, r := range skipped {
+		names = append(names, r.UserName)
+	}
+	eventData := map[string]interface{}{
+		"escalation_level": level,
+		"skipped":          skipped,
+		"skipped_users":    names,
+		"reason":           "unavailable",
+	}
+	if err := w.createIncidentEvent(incidentID, db.IncidentEventResponderSkipped, eventData, "system"); err != nil {
+		log.Printf("Worker: failed to log skipped responders: %v", err)
+	}
+}
+
 // escalateToUser assigns incident to a specific user
 func (w *IncidentWorker) escalateToUser(incident db.Incident, userID string) bool {
 	// Assign without sending assignment notification (we'll send escalation notification instead)
@@ -418,7 +768,7 @@ func (w *IncidentWorker) escalateToUserWithNotification(incident db.Incident, us
 
 	query := `
 		UPDATE incidents
-		SET assigned_to = $1
+		SET assigned_to = $1, assigned_at = NOW()
 		WHERE id = $2
 	`
 
@@ -454,7 +804,7 @@ func (w *IncidentWorker) escalateToUserWithNotification(incident db.Incident, us
 	return true
 }
 
-// escalateToScheduler finds current on-call user in scheduler and assigns
+// escalateToScheduler finds current available on-call user in scheduler and assigns
 // This uses the effective_shifts view which automatically handles schedule overrides
 func (w *IncidentWorker) escalateToScheduler(incident db.Incident, schedulerID string) bool {
 	logger.Debug("Escalating to scheduler %s for incident %s (policy: %s, group: %s)",
@@ -468,6 +818,7 @@ func (w *IncidentWorker) escalateToScheduler(incident db.Incident, schedulerID s
 		AND group_id = $2
 		AND start_time <= NOW()
 		AND end_time >= NOW()
+		AND user_available(effective_user_id, NOW())
 		ORDER BY start_time ASC
 		LIMIT 1
 	`
@@ -499,17 +850,19 @@ func (w *IncidentWorker) escalateToScheduler(incident db.Incident, schedulerID s
 	return success
 }
 
-// escalateToGroup assigns to current on-call user in group
+// escalateToGroup assigns to current on-call user in group, falling back to its
+// sub-teams (nearest first) when nobody in the group itself is on call
 // This uses the effective_shifts view which automatically handles schedule overrides
 func (w *IncidentWorker) escalateToGroup(incident db.Incident, groupID string) bool {
 	// Find current on-call user using effective_shifts view
 	query := `
-		SELECT effective_user_id
-		FROM effective_shifts
-		WHERE group_id = $1
-		AND start_time <= NOW()
-		AND end_time >= NOW()
-		ORDER BY start_time ASC
+		SELECT es.effective_user_id
+		FROM effective_shifts es
+		JOIN group_subtree($1) gs ON gs.group_id = es.group_id
+		WHERE es.start_time <= NOW()
+		AND es.end_time >= NOW()
+		AND user_available(es.effective_user_id, NOW())
+		ORDER BY gs.depth ASC, es.start_time ASC
 		LIMIT 1
 	`
 
@@ -113,3 +113,51 @@ self.addEventListener('message', (event) => {
     self.skipWaiting();
   }
 });
+
+// Push event - incident pages sent by the API through Web Push
+self.addEventListener('push', (event) => {
+  if (!event.data) {
+    return;
+  }
+
+  let payload;
+  try {
+    payload = event.data.json();
+  } catch (e) {
+    payload = { title: 'SLAR', body: event.data.text() };
+  }
+
+  const options = {
+    body: payload.body,
+    icon: '/icon.png',
+    badge: '/icon.png',
+    tag: payload.tag,
+    renotify: !!payload.tag,
+    requireInteraction: !!payload.require_interaction,
+    data: { url: payload.url || '/incidents', ackUrl: payload.ack_url },
+  };
+  if (payload.ack_url) {
+    options.actions = [{ action: 'acknowledge', title: 'Acknowledge' }];
+  }
+
+  event.waitUntil(self.registration.showNotification(payload.title || 'SLAR', options));
+});
+
+// Notification click - open the incident (or its acknowledge link), reusing an open tab
+self.addEventListener('notificationclick', (event) => {
+  event.notification.close();
+
+  const { url, ackUrl } = event.notification.data || {};
+  const target = event.action === 'acknowledge' && ackUrl ? ackUrl : url;
+
+  event.waitUntil(
+    self.clients.matchAll({ type: 'window', includeUncontrolled: true }).then((clientList) => {
+      for (const client of clientList) {
+        if (client.url === target && 'focus' in client) {
+          return client.focus();
+        }
+      }
+      return self.clients.openWindow(target);
+    })
+  );
+});
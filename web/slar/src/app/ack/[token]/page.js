@@ -0,0 +1,142 @@
+"use client";
+
+import { useState, useEffect } from "react";
+import { useParams } from 'next/navigation';
+import Link from 'next/link';
+import { getConfigSync } from '../../../lib/config';
+
+// Public acknowledge page for links posted to group chat channels - no auth required.
+// Acknowledging needs an explicit click so chat link previews can't do it.
+export default function AcknowledgeIncidentPage() {
+  const params = useParams();
+  const token = params.token;
+
+  const [link, setLink] = useState(null);
+  const [loading, setLoading] = useState(true);
+  const [submitting, setSubmitting] = useState(false);
+  const [error, setError] = useState(null);
+  const [message, setMessage] = useState(null);
+
+  useEffect(() => {
+    async function fetchAckLink() {
+      if (!token) return;
+
+      setLoading(true);
+      setError(null);
+
+      try {
+        const apiUrl = getConfigSync().apiUrl;
+        const response = await fetch(`${apiUrl}/ack/${token}`);
+        const data = await response.json().catch(() => ({}));
+
+        if (!response.ok) {
+          throw new Error(data.error || 'Failed to load acknowledge link');
+        }
+        setLink(data.ack_link);
+      } catch (err) {
+        setError(err.message);
+      } finally {
+        setLoading(false);
+      }
+    }
+
+    fetchAckLink();
+  }, [token]);
+
+  const handleAcknowledge = async () => {
+    setSubmitting(true);
+    setError(null);
+
+    try {
+      const apiUrl = getConfigSync().apiUrl;
+      const response = await fetch(`${apiUrl}/ack/${token}`, { method: 'POST' });
+      const data = await response.json().catch(() => ({}));
+
+      if (data.ack_link) {
+        setLink(data.ack_link);
+      }
+      if (!response.ok) {
+        throw new Error(data.error || 'Failed to acknowledge incident');
+      }
+      setMessage(data.message || 'Incident acknowledged');
+    } catch (err) {
+      setError(err.message);
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  if (loading) {
+    return (
+      <div className="min-h-screen bg-gray-50 dark:bg-gray-950 flex items-center justify-center">
+        <div className="text-center space-y-4">
+          <div className="animate-spin rounded-full h-12 w-12 border-b-2 border-emerald-600 mx-auto" />
+          <p className="text-gray-600 dark:text-gray-400">Loading incident...</p>
+        </div>
+      </div>
+    );
+  }
+
+  const canAcknowledge = link && link.status === 'triggered' && !message;
+
+  return (
+    <div className="min-h-screen bg-gray-50 dark:bg-gray-950 flex items-center justify-center">
+      <div className="w-full max-w-md px-4">
+        <div className="bg-white dark:bg-gray-900 border border-gray-200 dark:border-gray-800 rounded-xl p-6 space-y-4">
+          {link && (
+            <div className="space-y-2">
+              <p className="text-xs font-medium uppercase tracking-wide text-gray-500 dark:text-gray-400">
+                {link.service_name || 'Incident'}
+              </p>
+              <h1 className="text-lg font-semibold text-gray-900 dark:text-gray-100">{link.title}</h1>
+              <div className="flex flex-wrap gap-2 text-xs">
+                <span className="px-2 py-0.5 rounded-full bg-gray-100 dark:bg-gray-800 text-gray-700 dark:text-gray-300">
+                  {link.status}
+                </span>
+                <span className="px-2 py-0.5 rounded-full bg-gray-100 dark:bg-gray-800 text-gray-700 dark:text-gray-300">
+                  {link.urgency} urgency
+                </span>
+                {link.severity && (
+                  <span className="px-2 py-0.5 rounded-full bg-gray-100 dark:bg-gray-800 text-gray-700 dark:text-gray-300">
+                    {link.severity}
+                  </span>
+                )}
+              </div>
+              {link.user_name && (
+                <p className="text-sm text-gray-600 dark:text-gray-400">
+                  Acknowledging as <span className="font-medium">{link.user_name}</span>
+                </p>
+              )}
+            </div>
+          )}
+
+          {error && (
+            <p className="text-sm text-red-600 dark:text-red-400">{error}</p>
+          )}
+          {message && (
+            <p className="text-sm text-emerald-600 dark:text-emerald-400">{message}</p>
+          )}
+
+          {canAcknowledge && (
+            <button
+              onClick={handleAcknowledge}
+              disabled={submitting}
+              className="w-full px-4 py-2 bg-emerald-600 text-white rounded-lg hover:bg-emerald-700 disabled:opacity-50 transition-colors"
+            >
+              {submitting ? 'Acknowledging...' : 'Acknowledge incident'}
+            </button>
+          )}
+
+          {link && (
+            <Link
+              href={`/incidents/${link.incident_id}`}
+              className="block text-center text-sm text-emerald-600 hover:text-emerald-700 dark:text-emerald-400 dark:hover:text-emerald-300"
+            >
+              Open in SLAR
+            </Link>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
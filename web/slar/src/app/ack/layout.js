@@ -0,0 +1,9 @@
+// Public layout for chat acknowledge links - no auth required
+// Uses fixed positioning to cover the main app layout (sidebar, nav)
+export default function AckLayout({ children }) {
+  return (
+    <div className="fixed inset-0 z-50 bg-gray-50 dark:bg-gray-950 overflow-auto">
+      {children}
+    </div>
+  );
+}
@@ -5,11 +5,14 @@ import { useParams, useRouter } from 'next/navigation';
 import { useAuth } from '../../../contexts/AuthContext';
 import { apiClient } from '../../../lib/api';
 import { MarkdownRenderer } from '../../../components/ui';
+import IncidentPresence from '../../../components/incidents/IncidentPresence';
+import IncidentTasks from '../../../components/incidents/IncidentTasks';
+import PastResolutions from '../../../components/incidents/PastResolutions';
 
 export default function IncidentDetailPage() {
   const params = useParams();
   const router = useRouter();
-  const { session } = useAuth();
+  const { user, session } = useAuth();
   const [incident, setIncident] = useState(null);
   const [events, setEvents] = useState([]);
   const [loading, setLoading] = useState(true);
@@ -48,6 +51,15 @@ export default function IncidentDetailPage() {
     fetchIncident();
   }, [session, params.id]);
 
+  const refreshEvents = async () => {
+    try {
+      const data = await apiClient.getIncidentEvents(params.id);
+      setEvents(data.events || []);
+    } catch (err) {
+      console.error('Error refreshing incident events:', err);
+    }
+  };
+
   const handleAction = async (action) => {
     if (!incident) return;
 
@@ -157,6 +169,10 @@ export default function IncidentDetailPage() {
             )}
           </div>
 
+          <div className="mb-4">
+            <IncidentPresence incidentId={incident.id} session={session} currentUserId={user?.id} />
+          </div>
+
 
         </div>
 
@@ -249,6 +265,14 @@ export default function IncidentDetailPage() {
             </div>
           </div>
 
+          {/* Tasks */}
+          <IncidentTasks
+            incidentId={incident.id}
+            initialTasks={incident.tasks || []}
+            currentUserId={user?.id}
+            onChange={refreshEvents}
+          />
+
           {/* Timeline */}
           <div className="bg-white dark:bg-gray-800 rounded-lg border border-gray-200 dark:border-gray-700 p-6">
             <h3 className="text-lg font-semibold text-gray-900 dark:text-white mb-4">Timeline</h3>
@@ -415,6 +439,9 @@ export default function IncidentDetailPage() {
               )}
             </div>
           </div>
+
+          {/* Past fixes for similar incidents */}
+          <PastResolutions incident={incident} />
         </div>
       </div>
     </div>
@@ -5,7 +5,7 @@ import { useRouter, usePathname } from 'next/navigation';
 import { useEffect, useState, useRef, useCallback } from 'react';
 import { apiClient } from '../../lib/api';
 
-const PUBLIC_ROUTES = ['/login', '/signup', '/auth/callback', '/', '/onboarding', '/shared'];
+const PUBLIC_ROUTES = ['/login', '/signup', '/auth/callback', '/', '/onboarding', '/shared', '/ack'];
 
 export default function AuthWrapper({ children }) {
   const { user, session, loading, signOut } = useAuth();
@@ -18,7 +18,7 @@ export default function AuthWrapper({ children }) {
   const lastCheckedUserIdRef = useRef(null);
   const isCheckingRef = useRef(false);
 
-  const isPublicRoute = PUBLIC_ROUTES.includes(pathname) || pathname.startsWith('/shared/');
+  const isPublicRoute = PUBLIC_ROUTES.includes(pathname) || pathname.startsWith('/shared/') || pathname.startsWith('/ack/');
   const isOnboardingPage = pathname === '/onboarding';
 
   // Handle session invalidation (401 from API)
@@ -32,6 +32,7 @@ export default function EscalationPolicyModal({
     name: '',
     escalate_after_minutes: 5,
     repeat_max_times: 1,
+    reassign_after_minutes: 0,
     is_active: true
   });
 
@@ -121,6 +122,7 @@ export default function EscalationPolicyModal({
               name: policyDetail.name || '',
               escalate_after_minutes: policyDetail.escalate_after_minutes || 5,
               repeat_max_times: policyDetail.repeat_max_times || 1,
+              reassign_after_minutes: policyDetail.reassign_after_minutes || 0,
               is_active: policyDetail.is_active !== undefined ? policyDetail.is_active : true
             });
             
@@ -177,6 +179,7 @@ export default function EscalationPolicyModal({
           name: editPolicy.name || '',
           escalate_after_minutes: editPolicy.escalate_after_minutes || 5,
           repeat_max_times: editPolicy.repeat_max_times || 1,
+          reassign_after_minutes: editPolicy.reassign_after_minutes || 0,
           is_active: editPolicy.is_active !== undefined ? editPolicy.is_active : true
         });
         
@@ -203,6 +206,7 @@ export default function EscalationPolicyModal({
           name: '',
           escalate_after_minutes: 5,
           repeat_max_times: 1,
+          reassign_after_minutes: 0,
           is_active: true
         });
         setEscalationSteps([
@@ -354,6 +358,7 @@ export default function EscalationPolicyModal({
         name: policyData.name,
         repeat_max_times: policyData.repeat_max_times,
         escalate_after_minutes: policyData.escalate_after_minutes,
+        reassign_after_minutes: policyData.reassign_after_minutes,
         levels: levels
       };
 
@@ -721,6 +726,44 @@ export default function EscalationPolicyModal({
               How many times to repeat the entire escalation chain
             </p>
           </Field>
+
+          {/* Reassign If Not Acknowledged */}
+          <Field>
+            <Label className="text-xs sm:text-sm font-medium text-gray-900 dark:text-white">
+              Reassign If Not Acknowledged After
+            </Label>
+            <Menu>
+              <MenuButton className="mt-2 inline-flex w-full justify-between items-center rounded-lg bg-gray-50/80 dark:bg-gray-700/80 backdrop-blur-sm px-3 sm:px-4 py-2 sm:py-3 text-xs sm:text-sm text-gray-900 dark:text-white data-hover:bg-gray-100 dark:data-hover:bg-gray-600 data-focus:outline-2 data-focus:-outline-offset-2 data-focus:outline-blue-500 data-focus:bg-white dark:data-focus:bg-gray-600">
+                {policyData.reassign_after_minutes > 0 ? `${policyData.reassign_after_minutes} minutes` : "Never"}
+                <ChevronDownIcon className="h-4 w-4 sm:h-5 sm:w-5 text-gray-400" />
+              </MenuButton>
+              <MenuItems
+                transition
+                anchor="bottom start"
+                className="w-48 origin-top-left rounded-lg bg-white/90 dark:bg-gray-700/90 backdrop-blur-sm shadow-lg p-1 transition duration-100 ease-out data-closed:scale-95 data-closed:opacity-0"
+              >
+                {[
+                  { value: 0, label: "Never" },
+                  { value: 5, label: "5 minutes" },
+                  { value: 10, label: "10 minutes" },
+                  { value: 15, label: "15 minutes" },
+                  { value: 30, label: "30 minutes" }
+                ].map((option) => (
+                  <MenuItem key={option.value}>
+                    <button
+                      onClick={() => setPolicyData(prev => ({ ...prev, reassign_after_minutes: option.value }))}
+                      className="group flex w-full items-center rounded-lg px-3 py-2 text-xs sm:text-sm text-gray-700 dark:text-gray-200 data-focus:bg-blue-100 dark:data-focus:bg-blue-900"
+                    >
+                      {option.label}
+                    </button>
+                  </MenuItem>
+                ))}
+              </MenuItems>
+            </Menu>
+            <p className="mt-1 text-xs text-gray-500 dark:text-gray-400">
+              Hand the incident to the next schedule member when the assignee hasn&apos;t acknowledged in time
+            </p>
+          </Field>
           </div>
           )}
         </div>
@@ -171,6 +171,15 @@ export default function IncidentCard({ incident, onAction, showActions = true })
               <span>{incident.alert_count} alerts</span>
             </span>
           )}
+
+          {incident.open_task_count > 0 && (
+            <span className="flex items-center space-x-1">
+              <svg className="w-3 h-3" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M9 5H7a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V7a2 2 0 00-2-2h-2M9 5a2 2 0 002 2h2a2 2 0 002-2M9 5a2 2 0 012-2h2a2 2 0 012 2m-6 9l2 2 4-4" />
+              </svg>
+              <span>{incident.open_task_count}/{incident.task_count} tasks open</span>
+            </span>
+          )}
         </div>
         
         <div className="flex items-center space-x-2">
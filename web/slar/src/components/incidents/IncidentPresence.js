@@ -0,0 +1,102 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import { apiClient } from '../../lib/api';
+
+// Heartbeat well inside the server's 30s presence TTL
+const HEARTBEAT_INTERVAL_MS = 10000;
+const STREAM_RETRY_MS = 5000;
+
+const initials = (name = '') =>
+    name
+        .split(/[\s@.]+/)
+        .filter(Boolean)
+        .slice(0, 2)
+        .map((part) => part[0].toUpperCase())
+        .join('') || '?';
+
+/**
+ * Shows teammates who currently have this incident open, and registers the
+ * current user as viewing (or editing, while `editing` is true).
+ */
+export default function IncidentPresence({ incidentId, session, currentUserId, editing = false }) {
+    const [viewers, setViewers] = useState([]);
+
+    // Heartbeat while the page is open; leave when it closes
+    useEffect(() => {
+        if (!session?.access_token || !incidentId) return;
+        apiClient.setToken(session.access_token);
+
+        const mode = editing ? 'editing' : 'viewing';
+        const beat = () => apiClient.updateIncidentPresence(incidentId, mode).catch(() => {});
+        beat();
+        const timer = setInterval(beat, HEARTBEAT_INTERVAL_MS);
+        return () => clearInterval(timer);
+    }, [session, incidentId, editing]);
+
+    useEffect(() => {
+        if (!session?.access_token || !incidentId) return;
+        return () => {
+            apiClient.leaveIncidentPresence(incidentId).catch(() => {});
+        };
+    }, [session, incidentId]);
+
+    // Live updates, reconnecting if the stream drops
+    useEffect(() => {
+        if (!session?.access_token || !incidentId) return;
+        const controller = new AbortController();
+        let retry;
+
+        const connect = async () => {
+            try {
+                await apiClient.streamIncidentPresence(incidentId, setViewers, controller.signal);
+            } catch (err) {
+                console.error('Presence stream error:', err);
+            }
+            if (!controller.signal.aborted) {
+                retry = setTimeout(connect, STREAM_RETRY_MS);
+            }
+        };
+        connect();
+
+        return () => {
+            controller.abort();
+            clearTimeout(retry);
+        };
+    }, [session, incidentId]);
+
+    const others = viewers.filter((v) => v.user_id !== currentUserId);
+    if (others.length === 0) return null;
+
+    const editors = others.filter((v) => v.mode === 'editing');
+
+    return (
+        <div className="flex items-center space-x-2 text-sm text-gray-600 dark:text-gray-400">
+            <div className="flex -space-x-2">
+                {others.slice(0, 5).map((v) => (
+                    <span
+                        key={v.user_id}
+                        title={`${v.name || v.email} (${v.mode})`}
+                        className={`inline-flex items-center justify-center w-7 h-7 rounded-full text-xs font-medium ring-2 ring-white dark:ring-gray-900 ${
+                            v.mode === 'editing'
+                                ? 'bg-orange-100 text-orange-800 dark:bg-orange-900/30 dark:text-orange-300'
+                                : 'bg-blue-100 text-blue-800 dark:bg-blue-900/30 dark:text-blue-300'
+                        }`}
+                    >
+                        {initials(v.name || v.email)}
+                    </span>
+                ))}
+                {others.length > 5 && (
+                    <span className="inline-flex items-center justify-center w-7 h-7 rounded-full text-xs font-medium bg-gray-100 text-gray-700 ring-2 ring-white dark:bg-gray-800 dark:text-gray-300 dark:ring-gray-900">
+                        +{others.length - 5}
+                    </span>
+                )}
+            </div>
+            <span>
+                {editors.length > 0
+                    ? `${editors[0].name || editors[0].email}${editors.length > 1 ? ` and ${editors.length - 1} more` : ''} editing`
+                    : `${others.length} ${others.length === 1 ? 'teammate' : 'teammates'} also viewing`}
+            </span>
+        </div>
+    );
+}
@@ -0,0 +1,173 @@
+'use client';
+
+import { useState } from 'react';
+import { apiClient } from '../../lib/api';
+
+/**
+ * Response checklist for an incident: add, assign, complete and reorder tasks.
+ * onChange is called after a task is completed or reopened so the timeline can
+ * refresh.
+ */
+export default function IncidentTasks({ incidentId, initialTasks = [], currentUserId, onChange }) {
+    const [tasks, setTasks] = useState(initialTasks);
+    const [newTitle, setNewTitle] = useState('');
+    const [saving, setSaving] = useState(false);
+    const [error, setError] = useState(null);
+
+    const run = async (action) => {
+        try {
+            setSaving(true);
+            setError(null);
+            await action();
+        } catch (err) {
+            console.error('Task update failed:', err);
+            setError(err.message || 'Failed to update tasks');
+        } finally {
+            setSaving(false);
+        }
+    };
+
+    const replaceTask = (task) => setTasks((prev) => prev.map((t) => (t.id === task.id ? task : t)));
+
+    const handleAdd = (e) => {
+        e.preventDefault();
+        const title = newTitle.trim();
+        if (!title) return;
+        run(async () => {
+            const data = await apiClient.createIncidentTask(incidentId, { title });
+            setTasks((prev) => [...prev, data.task]);
+            setNewTitle('');
+        });
+    };
+
+    const handleToggle = (task) =>
+        run(async () => {
+            const data = await apiClient.updateIncidentTask(incidentId, task.id, { completed: !task.completed });
+            replaceTask(data.task);
+            onChange?.();
+        });
+
+    const handleAssignToggle = (task) =>
+        run(async () => {
+            const assignedTo = task.assigned_to === currentUserId ? '' : currentUserId;
+            const data = await apiClient.updateIncidentTask(incidentId, task.id, { assigned_to: assignedTo });
+            replaceTask(data.task);
+        });
+
+    const handleDelete = (task) =>
+        run(async () => {
+            await apiClient.deleteIncidentTask(incidentId, task.id);
+            setTasks((prev) => prev.filter((t) => t.id !== task.id));
+        });
+
+    const handleMove = (index, delta) =>
+        run(async () => {
+            const order = tasks.map((t) => t.id);
+            const [moved] = order.splice(index, 1);
+            order.splice(index + delta, 0, moved);
+            const data = await apiClient.reorderIncidentTasks(incidentId, order);
+            setTasks(data.tasks || []);
+        });
+
+    const openCount = tasks.filter((t) => !t.completed).length;
+
+    return (
+        <div className="bg-white dark:bg-gray-800 rounded-lg border border-gray-200 dark:border-gray-700 p-6">
+            <div className="flex items-center justify-between mb-4">
+                <h3 className="text-lg font-semibold text-gray-900 dark:text-white">Tasks</h3>
+                {tasks.length > 0 && (
+                    <span className="text-sm text-gray-500 dark:text-gray-400">
+                        {tasks.length - openCount} of {tasks.length} done
+                    </span>
+                )}
+            </div>
+
+            {error && (
+                <p className="mb-3 text-sm text-red-600 dark:text-red-400">{error}</p>
+            )}
+
+            {tasks.length === 0 ? (
+                <p className="text-sm text-gray-500 dark:text-gray-400 mb-4">No tasks yet. Add the steps responders should work through.</p>
+            ) : (
+                <ul className="divide-y divide-gray-200 dark:divide-gray-700 mb-4">
+                    {tasks.map((task, index) => (
+                        <li key={task.id} className="flex items-center gap-3 py-2">
+                            <input
+                                type="checkbox"
+                                checked={task.completed}
+                                disabled={saving}
+                                onChange={() => handleToggle(task)}
+                                className="h-4 w-4 rounded border-gray-300 text-blue-600 focus:ring-blue-500"
+                            />
+                            <div className="flex-1 min-w-0">
+                                <p className={`text-sm ${task.completed ? 'line-through text-gray-400 dark:text-gray-500' : 'text-gray-900 dark:text-white'}`}>
+                                    {task.title}
+                                </p>
+                                <p className="text-xs text-gray-500 dark:text-gray-400">
+                                    {task.completed
+                                        ? `Done${task.completed_by_name ? ` by ${task.completed_by_name}` : ''}`
+                                        : task.assigned_to_name
+                                            ? `Assigned to ${task.assigned_to_name}`
+                                            : 'Unassigned'}
+                                </p>
+                            </div>
+                            <div className="flex items-center gap-1 text-xs">
+                                {!task.completed && currentUserId && (
+                                    <button
+                                        onClick={() => handleAssignToggle(task)}
+                                        disabled={saving}
+                                        className="px-2 py-1 text-blue-600 dark:text-blue-400 hover:underline disabled:opacity-50"
+                                    >
+                                        {task.assigned_to === currentUserId ? 'Unassign' : 'Take'}
+                                    </button>
+                                )}
+                                <button
+                                    onClick={() => handleMove(index, -1)}
+                                    disabled={saving || index === 0}
+                                    title="Move up"
+                                    className="px-1 text-gray-500 hover:text-gray-700 dark:hover:text-gray-200 disabled:opacity-30"
+                                >
+                                    ↑
+                                </button>
+                                <button
+                                    onClick={() => handleMove(index, 1)}
+                                    disabled={saving || index === tasks.length - 1}
+                                    title="Move down"
+                                    className="px-1 text-gray-500 hover:text-gray-700 dark:hover:text-gray-200 disabled:opacity-30"
+                                >
+                                    ↓
+                                </button>
+                                <button
+                                    onClick={() => handleDelete(task)}
+                                    disabled={saving}
+                                    title="Delete task"
+                                    className="px-1 text-gray-400 hover:text-red-600 disabled:opacity-30"
+                                >
+                                    ×
+                                </button>
+                            </div>
+                        </li>
+                    ))}
+                </ul>
+            )}
+
+            <form onSubmit={handleAdd} className="flex gap-2">
+                <input
+                    type="text"
+                    value={newTitle}
+                    onChange={(e) => setNewTitle(e.target.value)}
+                    placeholder="Add a task, e.g. Fail over to the standby database"
+                    maxLength={500}
+                    className="flex-1 px-3 py-2 text-sm border border-gray-300 dark:border-gray-600 rounded-md bg-white dark:bg-gray-700 text-gray-900 dark:text-white focus:outline-none focus:ring-2 focus:ring-blue-500"
+                />
+                <button
+                    type="submit"
+                    disabled={saving || !newTitle.trim()}
+                    className="px-4 py-2 text-sm font-medium text-white bg-blue-600 rounded-md hover:bg-blue-700 disabled:opacity-50"
+                >
+                    Add
+                </button>
+            </form>
+        </div>
+    );
+}
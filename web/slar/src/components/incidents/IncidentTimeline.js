@@ -20,6 +20,7 @@ export default function IncidentTimeline({ events }) {
                     </div>
                 );
             case 'resolved':
+            case 'task_completed':
                 return (
                     <div className="w-8 h-8 bg-green-100 dark:bg-green-900/20 rounded-full flex items-center justify-center">
                         <svg className="w-4 h-4 text-green-600 dark:text-green-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
@@ -28,6 +29,8 @@ export default function IncidentTimeline({ events }) {
                     </div>
                 );
             case 'assigned':
+            case 'reassigned':
+            case 'responder_skipped':
             case 'escalated':
                 return (
                     <div className="w-8 h-8 bg-blue-100 dark:bg-blue-900/20 rounded-full flex items-center justify-center">
@@ -114,6 +117,34 @@ export default function IncidentTimeline({ events }) {
                 }
 
                 return completedDescription;
+            case 'urgency_changed': {
+                const parts = [];
+                if (eventData.from_urgency !== eventData.to_urgency) {
+                    parts.push(`urgency ${eventData.from_urgency} → ${eventData.to_urgency}`);
+                }
+                if (eventData.from_priority !== eventData.to_priority) {
+                    parts.push(`priority ${eventData.from_priority || 'none'} → ${eventData.to_priority || 'none'}`);
+                }
+                let urgencyDescription = `Recalculated after ${eventData.alert_count} alerts: ${parts.join(', ')}`;
+                if (eventData.repaged) {
+                    urgencyDescription += ' (re-paged)';
+                }
+                return urgencyDescription;
+            }
+            case 'task_completed':
+                return `Task completed: ${eventData.task_title}` + (eventData.user_name ? ` by ${eventData.user_name}` : '');
+            case 'task_reopened':
+                return `Task reopened: ${eventData.task_title}` + (eventData.user_name ? ` by ${eventData.user_name}` : '');
+            case 'responder_skipped': {
+                const skipped = (eventData.skipped || []).map(r => `${r.user_name || 'on-call user'} (${r.kind === 'dnd' ? 'DND' : 'vacation'})`);
+                return `Level ${eventData.escalation_level} skipped ${skipped.join(', ') || 'unavailable responders'}`;
+            }
+            case 'reassigned':
+                if (eventData.reason === 'shift_handoff') {
+                    return `Handed off from ${eventData.from_user || 'previous assignee'} to ${eventData.to_user || 'incoming on-call'} (shift ended)`;
+                }
+                return `Reassigned from ${eventData.from_user || 'previous assignee'} to ${eventData.to_user || 'next on-call'}` +
+                    ` (not acknowledged within ${eventData.after_minutes} minutes)`;
             default:
                 return eventType.replace('_', ' ').toLowerCase();
         }
@@ -430,6 +430,11 @@ export default function IncidentsTable({
                           #{incident.incident_number}
                         </div>
                       )}
+                      {incident.open_task_count > 0 && (
+                        <div className="text-xs text-gray-500 dark:text-gray-400 mt-1">
+                          {incident.open_task_count} of {incident.task_count} tasks open
+                        </div>
+                      )}
                     </div>
                   </td>
 
@@ -0,0 +1,77 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import Link from 'next/link';
+import { apiClient } from '../../lib/api';
+import { MarkdownRenderer } from '../ui';
+
+/**
+ * "How did we fix this last time": past incidents whose resolution notes or
+ * postmortems match this incident's title.
+ */
+export default function PastResolutions({ incident }) {
+    const [results, setResults] = useState([]);
+    const [loading, setLoading] = useState(true);
+
+    useEffect(() => {
+        if (!incident?.title || !incident?.organization_id) {
+            setLoading(false);
+            return;
+        }
+        let cancelled = false;
+        apiClient
+            .searchResolutions(incident.title, { org_id: incident.organization_id, limit: 5 })
+            .then((data) => {
+                if (!cancelled) {
+                    setResults((data.results || []).filter((r) => r.incident_id !== incident.id));
+                }
+            })
+            .catch((err) => console.error('Error searching past resolutions:', err))
+            .finally(() => !cancelled && setLoading(false));
+        return () => {
+            cancelled = true;
+        };
+    }, [incident?.id, incident?.title, incident?.organization_id]);
+
+    if (loading || results.length === 0) return null;
+
+    return (
+        <div className="bg-white dark:bg-gray-800 rounded-lg border border-gray-200 dark:border-gray-700 p-6">
+            <h3 className="text-lg font-semibold text-gray-900 dark:text-white mb-4">Similar past fixes</h3>
+            <ul className="space-y-4">
+                {results.map((r) => (
+                    <li key={r.incident_id}>
+                        <Link
+                            href={`/incidents/${r.incident_id}`}
+                            className="text-sm font-medium text-blue-600 dark:text-blue-400 hover:underline"
+                        >
+                            {r.title}
+                        </Link>
+                        <p className="text-xs text-gray-500 dark:text-gray-400">
+                            {r.resolved_at ? `Resolved ${new Date(r.resolved_at).toLocaleDateString()}` : r.status}
+                            {r.resolved_by_name && ` by ${r.resolved_by_name}`}
+                            {r.service_name && ` · ${r.service_name}`}
+                        </p>
+                        {r.snippet && (
+                            <MarkdownRenderer
+                                content={r.snippet}
+                                size="sm"
+                                className="mt-1 text-sm text-gray-700 dark:text-gray-300"
+                            />
+                        )}
+                        {r.postmortem_url && (
+                            <a
+                                href={r.postmortem_url}
+                                target="_blank"
+                                rel="noopener noreferrer"
+                                className="text-xs text-blue-600 dark:text-blue-400 hover:underline"
+                            >
+                                Postmortem →
+                            </a>
+                        )}
+                    </li>
+                ))}
+            </ul>
+        </div>
+    );
+}
@@ -11,7 +11,8 @@ import {
   LinkIcon,
   CloudIcon,
   BoltIcon,
-  CubeIcon
+  CubeIcon,
+  GlobeAltIcon
 } from '@heroicons/react/24/outline';
 
 const INTEGRATION_TYPES = [
@@ -55,6 +56,14 @@ const INTEGRATION_TYPES = [
     bgColor: 'bg-amber-50 dark:bg-amber-900/20',
     borderColor: 'border-amber-200 dark:border-amber-800'
   },
+  {
+    value: 'federation',
+    label: 'SLAR Federation',
+    icon: GlobeAltIcon,
+    color: 'text-emerald-600 dark:text-emerald-400',
+    bgColor: 'bg-emerald-50 dark:bg-emerald-900/20',
+    borderColor: 'border-emerald-200 dark:border-emerald-800'
+  },
   { 
     value: 'custom', 
     label: 'Custom', 
@@ -81,6 +90,13 @@ export default function IntegrationModal({
     type: 'prometheus',
     description: ''
   });
+  // Federation: where to forward incidents and which ones
+  const [federation, setFederation] = useState({
+    remote_url: '',
+    remote_api_key: '',
+    routing_key: '',
+    severities: ''
+  });
 
   const isEditMode = mode === 'edit';
   const modalTitle = isEditMode ? 'Edit Integration' : 'Create New Integration';
@@ -95,6 +111,13 @@ export default function IntegrationModal({
           type: integration.type || 'prometheus',
           description: integration.description || ''
         });
+        const config = integration.config || {};
+        setFederation({
+          remote_url: config.remote_url || '',
+          remote_api_key: '',
+          routing_key: config.routing_key || '',
+          severities: (config.severities || []).join(', ')
+        });
       } else {
         // Reset for create mode
         setFormData({
@@ -102,10 +125,26 @@ export default function IntegrationModal({
           type: 'prometheus',
           description: ''
         });
+        setFederation({ remote_url: '', remote_api_key: '', routing_key: '', severities: '' });
       }
     }
   }, [isOpen, isEditMode, integration]);
 
+  const buildPayload = () => {
+    if (formData.type !== 'federation') return formData;
+    const config = {
+      ...(isEditMode ? integration?.config : {}),
+      remote_url: federation.remote_url.trim(),
+      routing_key: federation.routing_key.trim(),
+      severities: federation.severities.split(',').map(s => s.trim().toLowerCase()).filter(Boolean)
+    };
+    // Left blank when editing: keep the stored (encrypted) key
+    if (federation.remote_api_key.trim()) {
+      config.remote_api_key = federation.remote_api_key.trim();
+    }
+    return { ...formData, config };
+  };
+
   const handleSubmit = async (e) => {
     e.preventDefault();
     
@@ -138,7 +177,7 @@ export default function IntegrationModal({
       let response;
       if (isEditMode) {
         // Update existing integration
-        response = await apiClient.updateIntegration(integration.id, formData, rebacFilters);
+        response = await apiClient.updateIntegration(integration.id, buildPayload(), rebacFilters);
         if (response.integration) {
           onIntegrationUpdated && onIntegrationUpdated(response.integration);
           toast.success('Integration updated successfully!');
@@ -147,7 +186,7 @@ export default function IntegrationModal({
         // Create new integration with ReBAC context
         // organization_id is MANDATORY, project_id is OPTIONAL
         const createData = {
-          ...formData,
+          ...buildPayload(),
           organization_id: currentOrg.id,
           ...(currentProject?.id && { project_id: currentProject.id })
         };
@@ -196,6 +235,11 @@ export default function IntegrationModal({
           placeholder: 'e.g., AWS CloudWatch',
           description: 'AWS CloudWatch integration for receiving alerts'
         };
+      case 'federation':
+        return {
+          placeholder: 'e.g., Central NOC',
+          description: 'Forward selected incidents to another SLAR instance and mirror status changes back'
+        };
       case 'custom':
         return {
           placeholder: 'e.g., My Custom Integration',
@@ -286,6 +330,39 @@ export default function IntegrationModal({
           rows={3}
         />
 
+        {formData.type === 'federation' && (
+          <div className="space-y-4">
+            <Input
+              label="Remote SLAR API URL"
+              value={federation.remote_url}
+              onChange={(e) => setFederation(prev => ({ ...prev, remote_url: e.target.value }))}
+              placeholder="https://noc.example.com"
+              required
+            />
+            <Input
+              label="Remote API Key"
+              type="password"
+              value={federation.remote_api_key}
+              onChange={(e) => setFederation(prev => ({ ...prev, remote_api_key: e.target.value }))}
+              placeholder={isEditMode ? 'Leave blank to keep the current key' : 'API key created on the remote instance'}
+              required={!isEditMode}
+            />
+            <Input
+              label="Remote Routing Key"
+              value={federation.routing_key}
+              onChange={(e) => setFederation(prev => ({ ...prev, routing_key: e.target.value }))}
+              placeholder="Routing key of the remote service incidents land on"
+              required
+            />
+            <Input
+              label="Severities to forward"
+              value={federation.severities}
+              onChange={(e) => setFederation(prev => ({ ...prev, severities: e.target.value }))}
+              placeholder="e.g., critical, high (blank = all)"
+            />
+          </div>
+        )}
+
         {/* Info Message */}
         <div className="p-4 bg-blue-50/50 dark:bg-blue-900/20 rounded-lg">
           <div className="flex items-start gap-3">
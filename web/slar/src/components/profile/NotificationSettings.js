@@ -17,6 +17,7 @@ import {
   CheckCircleIcon 
 } from '../ui/Icons';
 import { Input, Select } from '../ui';
+import { isWebPushSupported, getBrowserSubscription, subscribeBrowser, unsubscribeBrowser } from '../../lib/webPush';
 
 const timezoneOptions = [
   { value: 'UTC', label: 'UTC (Coordinated Universal Time)' },
@@ -48,12 +49,21 @@ export default function NotificationSettings() {
   const [loading, setLoading] = useState(true);
   const [saving, setSaving] = useState(false);
   const [testing, setTesting] = useState(false);
+  const [whatsApp, setWhatsApp] = useState({ available: false, consent: null });
+  const [whatsAppPhone, setWhatsAppPhone] = useState('');
+  const [whatsAppAgreed, setWhatsAppAgreed] = useState(false);
+  const [whatsAppSaving, setWhatsAppSaving] = useState(false);
+  const [webPush, setWebPush] = useState({ available: false, publicKey: '', subscriptions: [] });
+  const [browserEndpoint, setBrowserEndpoint] = useState(null);
+  const [webPushSaving, setWebPushSaving] = useState(false);
 
   useEffect(() => {
     if (session?.access_token) {
       apiClient.setToken(session.access_token);
       loadNotificationConfig();
       loadNotificationStats();
+      loadWhatsAppConsent();
+      loadWebPush();
     }
   }, [session]);
 
@@ -85,6 +95,105 @@ export default function NotificationSettings() {
     }
   };
 
+  const loadWhatsAppConsent = async () => {
+    try {
+      const response = await apiClient.getWhatsAppConsent();
+      setWhatsApp({ available: response.available, consent: response.consent });
+      setWhatsAppPhone(response.consent?.phone_number || '');
+    } catch (error) {
+      console.error('Failed to load WhatsApp consent:', error);
+    }
+  };
+
+  const handleWhatsAppOptIn = async () => {
+    setWhatsAppSaving(true);
+    try {
+      const response = await apiClient.optInWhatsApp({ phone_number: whatsAppPhone, consent: whatsAppAgreed });
+      setWhatsApp(prev => ({ ...prev, consent: response.consent }));
+      setWhatsAppAgreed(false);
+      toast.success('WhatsApp notifications enabled');
+    } catch (error) {
+      console.error('Failed to opt in to WhatsApp:', error);
+      toast.error(error.message || 'Failed to enable WhatsApp notifications');
+    } finally {
+      setWhatsAppSaving(false);
+    }
+  };
+
+  const handleWhatsAppOptOut = async () => {
+    setWhatsAppSaving(true);
+    try {
+      await apiClient.optOutWhatsApp();
+      await loadWhatsAppConsent();
+      toast.success('WhatsApp notifications disabled');
+    } catch (error) {
+      console.error('Failed to opt out of WhatsApp:', error);
+      toast.error('Failed to disable WhatsApp notifications');
+    } finally {
+      setWhatsAppSaving(false);
+    }
+  };
+
+  const loadWebPush = async () => {
+    if (!isWebPushSupported()) {
+      return;
+    }
+    try {
+      const response = await apiClient.getWebPush();
+      setWebPush({
+        available: response.available,
+        publicKey: response.public_key,
+        subscriptions: response.subscriptions || []
+      });
+      const subscription = await getBrowserSubscription();
+      setBrowserEndpoint(subscription?.endpoint || null);
+    } catch (error) {
+      console.error('Failed to load browser notifications:', error);
+    }
+  };
+
+  const handleWebPushEnable = async () => {
+    setWebPushSaving(true);
+    try {
+      const subscription = await subscribeBrowser(webPush.publicKey);
+      await apiClient.subscribeWebPush({ ...subscription, user_agent: navigator.userAgent });
+      await loadWebPush();
+      toast.success('Browser notifications enabled');
+    } catch (error) {
+      console.error('Failed to enable browser notifications:', error);
+      toast.error(error.message || 'Failed to enable browser notifications');
+    } finally {
+      setWebPushSaving(false);
+    }
+  };
+
+  const handleWebPushDisable = async () => {
+    setWebPushSaving(true);
+    try {
+      const endpoint = await unsubscribeBrowser();
+      if (endpoint) {
+        await apiClient.unsubscribeWebPush(endpoint);
+      }
+      await loadWebPush();
+      toast.success('Browser notifications disabled');
+    } catch (error) {
+      console.error('Failed to disable browser notifications:', error);
+      toast.error('Failed to disable browser notifications');
+    } finally {
+      setWebPushSaving(false);
+    }
+  };
+
+  const handleWebPushRemove = async (subscriptionId) => {
+    try {
+      await apiClient.deleteWebPushSubscription(subscriptionId);
+      await loadWebPush();
+    } catch (error) {
+      console.error('Failed to remove browser:', error);
+      toast.error('Failed to remove browser');
+    }
+  };
+
   const handleSave = async () => {
     setSaving(true);
     try {
@@ -350,6 +459,126 @@ export default function NotificationSettings() {
         </div>
       </div>
 
+      {/* WhatsApp Notifications */}
+      {whatsApp.available && (
+        <div className="border border-gray-200 rounded-lg p-6">
+          <div className="flex items-center space-x-3 mb-4">
+            <div className="w-8 h-8 bg-emerald-600 rounded-lg flex items-center justify-center">
+              <SmartphoneIcon className="w-5 h-5 text-white" />
+            </div>
+            <div>
+              <h4 className="text-lg font-medium text-gray-900">WhatsApp Notifications</h4>
+              <p className="text-sm text-gray-600">Get pages on WhatsApp where SMS is unreliable</p>
+            </div>
+          </div>
+
+          {whatsApp.consent?.status === 'opted_in' ? (
+            <div className="space-y-3">
+              <div className="flex items-start space-x-2">
+                <CheckCircleIcon className="w-5 h-5 text-emerald-600 mt-0.5 flex-shrink-0" />
+                <p className="text-sm text-gray-700">
+                  Sending to <span className="font-medium">{whatsApp.consent.phone_number}</span>.
+                  Reply STOP on WhatsApp at any time to opt out.
+                </p>
+              </div>
+              <button
+                onClick={handleWhatsAppOptOut}
+                disabled={whatsAppSaving}
+                className="px-3 py-2 text-sm border border-gray-300 text-gray-700 rounded-md hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                Opt out
+              </button>
+            </div>
+          ) : (
+            <div className="space-y-4">
+              <Input
+                label="WhatsApp Number"
+                type="tel"
+                value={whatsAppPhone}
+                onChange={(e) => setWhatsAppPhone(e.target.value)}
+                placeholder="+84 912 345 678"
+                helperText="International format, including the country code."
+                leftIcon={<SmartphoneIcon className="w-4 h-4" />}
+              />
+              <label className="flex items-start space-x-2 text-sm text-gray-700">
+                <input
+                  type="checkbox"
+                  checked={whatsAppAgreed}
+                  onChange={(e) => setWhatsAppAgreed(e.target.checked)}
+                  className="mt-0.5"
+                />
+                <span>I agree to receive incident notifications from SLAR on WhatsApp at this number.</span>
+              </label>
+              <button
+                onClick={handleWhatsAppOptIn}
+                disabled={whatsAppSaving || !whatsAppAgreed || !whatsAppPhone}
+                className="px-3 py-2 text-sm bg-emerald-600 text-white rounded-md hover:bg-emerald-700 disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                {whatsAppSaving ? 'Saving...' : 'Enable WhatsApp'}
+              </button>
+            </div>
+          )}
+        </div>
+      )}
+
+      {/* Browser Notifications */}
+      {webPush.available && (
+        <div className="border border-gray-200 rounded-lg p-6">
+          <div className="flex items-center space-x-3 mb-4">
+            <div className="w-8 h-8 bg-indigo-600 rounded-lg flex items-center justify-center">
+              <AlertCircleIcon className="w-5 h-5 text-white" />
+            </div>
+            <div>
+              <h4 className="text-lg font-medium text-gray-900">Browser Notifications</h4>
+              <p className="text-sm text-gray-600">Get pages on this computer even when SLAR isn&apos;t open</p>
+            </div>
+          </div>
+
+          <div className="space-y-4">
+            {browserEndpoint ? (
+              <div className="flex items-start space-x-2">
+                <CheckCircleIcon className="w-5 h-5 text-indigo-600 mt-0.5 flex-shrink-0" />
+                <p className="text-sm text-gray-700">This browser receives incident notifications.</p>
+              </div>
+            ) : (
+              <p className="text-sm text-gray-700">
+                Your browser will ask for permission to show notifications.
+              </p>
+            )}
+            <button
+              onClick={browserEndpoint ? handleWebPushDisable : handleWebPushEnable}
+              disabled={webPushSaving}
+              className={browserEndpoint
+                ? 'px-3 py-2 text-sm border border-gray-300 text-gray-700 rounded-md hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed'
+                : 'px-3 py-2 text-sm bg-indigo-600 text-white rounded-md hover:bg-indigo-700 disabled:opacity-50 disabled:cursor-not-allowed'}
+            >
+              {webPushSaving ? 'Saving...' : browserEndpoint ? 'Disable on this browser' : 'Enable on this browser'}
+            </button>
+
+            {webPush.subscriptions.length > 0 && (
+              <div>
+                <p className="text-sm font-medium text-gray-900 mb-2">Subscribed browsers</p>
+                <ul className="divide-y divide-gray-100 border border-gray-100 rounded-md">
+                  {webPush.subscriptions.map((sub) => (
+                    <li key={sub.id} className="flex items-center justify-between px-3 py-2 text-sm">
+                      <span className="text-gray-700 truncate mr-3" title={sub.user_agent}>
+                        {sub.user_agent || 'Unknown browser'}
+                      </span>
+                      <button
+                        onClick={() => handleWebPushRemove(sub.id)}
+                        className="text-red-600 hover:text-red-700 flex-shrink-0"
+                      >
+                        Remove
+                      </button>
+                    </li>
+                  ))}
+                </ul>
+              </div>
+            )}
+          </div>
+        </div>
+      )}
+
       {/* General Settings */}
       <div className="border border-gray-200 rounded-lg p-6">
         <h4 className="text-lg font-medium text-gray-900 mb-4">General Settings</h4>
@@ -60,16 +60,20 @@ class APIClient {
       if (!response.ok) {
         // Try to parse error response body for detailed error message
         let errorMessage = `HTTP error! status: ${response.status}`;
+        let errorData = null;
         try {
-          const errorData = await response.json();
-          // API returns error in 'error' or 'message' field
-          errorMessage = errorData.error || errorData.message || errorMessage;
+          // Shared error schema: { code, message, field_errors, request_id }
+          errorData = await response.json();
+          errorMessage = errorData.message || errorData.error || errorMessage;
         } catch {
           // If response body is not JSON, use status text
           errorMessage = response.statusText || errorMessage;
         }
         const error = new Error(errorMessage);
         error.status = response.status;
+        error.code = errorData?.code;
+        error.fieldErrors = errorData?.field_errors || [];
+        error.requestId = errorData?.request_id || response.headers.get('X-Request-ID');
         throw error;
       }
       return await response.json();
@@ -87,6 +91,51 @@ class APIClient {
     return this.request(`/dashboard${queryString ? `?${queryString}` : ''}`);
   }
 
+  // Saved analytics dashboards (private, or shared with a group via group_id)
+  async getAnalyticsMetrics() {
+    return this.request('/analytics/metrics');
+  }
+
+  async getAnalyticsDashboards() {
+    return this.request('/analytics/dashboards');
+  }
+
+  async getAnalyticsDashboard(dashboardId) {
+    return this.request(`/analytics/dashboards/${dashboardId}`);
+  }
+
+  // dashboard: { name, description, group_id, widgets: [{ metric, visualization, x, y, w, h, filters }], filters }
+  async createAnalyticsDashboard(dashboard) {
+    return this.request('/analytics/dashboards', {
+      method: 'POST',
+      body: JSON.stringify(dashboard)
+    });
+  }
+
+  async updateAnalyticsDashboard(dashboardId, updates) {
+    return this.request(`/analytics/dashboards/${dashboardId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(updates)
+    });
+  }
+
+  async deleteAnalyticsDashboard(dashboardId) {
+    return this.request(`/analytics/dashboards/${dashboardId}`, {
+      method: 'DELETE'
+    });
+  }
+
+  // Noisy alerts: alerts ranked by how often their incidents were resolved
+  // without acknowledgement or auto-resolved (group_by: alertname | fingerprint)
+  async getNoisyAlerts(filters = {}) {
+    const params = this._buildReBACParams(filters);
+    ['from', 'to', 'group_by', 'service_id', 'min_incidents', 'limit'].forEach((key) => {
+      if (filters[key]) params.append(key, filters[key]);
+    });
+    const queryString = params.toString();
+    return this.request(`/analytics/noisy-alerts${queryString ? `?${queryString}` : ''}`);
+  }
+
   // Incident endpoints (PagerDuty-style)
   // ReBAC: org_id is required for tenant isolation, project_id is optional
   async getIncidents(queryString = '', filters = {}) {
@@ -199,6 +248,115 @@ class APIClient {
     return this.request(`/incidents/${incidentId}/events${queryString ? `?${queryString}` : ''}`);
   }
 
+  // Past incidents whose resolution notes/postmortems match q ("how did we fix this last time")
+  // ReBAC: org_id is required for tenant isolation
+  async searchResolutions(q, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    params.append('q', q);
+    if (filters.service_id) params.append('service_id', filters.service_id);
+    if (filters.group_id) params.append('group_id', filters.group_id);
+    if (filters.limit) params.append('limit', filters.limit.toString());
+    return this.request(`/search/resolutions?${params.toString()}`);
+  }
+
+  // Incident tasks (response checklist)
+  async getIncidentTasks(incidentId) {
+    return this.request(`/incidents/${incidentId}/tasks`);
+  }
+
+  async createIncidentTask(incidentId, task) {
+    return this.request(`/incidents/${incidentId}/tasks`, {
+      method: 'POST',
+      body: JSON.stringify(task)
+    });
+  }
+
+  // updates: { title?, description?, assigned_to?, completed? }
+  async updateIncidentTask(incidentId, taskId, updates) {
+    return this.request(`/incidents/${incidentId}/tasks/${taskId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(updates)
+    });
+  }
+
+  async deleteIncidentTask(incidentId, taskId) {
+    return this.request(`/incidents/${incidentId}/tasks/${taskId}`, {
+      method: 'DELETE'
+    });
+  }
+
+  // taskIds must list every task of the incident in the new order
+  async reorderIncidentTasks(incidentId, taskIds) {
+    return this.request(`/incidents/${incidentId}/tasks/order`, {
+      method: 'PUT',
+      body: JSON.stringify({ task_ids: taskIds })
+    });
+  }
+
+  // Presence: heartbeat while the incident is open (mode: 'viewing' | 'editing')
+  async updateIncidentPresence(incidentId, mode = 'viewing') {
+    return this.request(`/incidents/${incidentId}/presence`, {
+      method: 'POST',
+      body: JSON.stringify({ mode })
+    });
+  }
+
+  async leaveIncidentPresence(incidentId) {
+    return this.request(`/incidents/${incidentId}/presence`, {
+      method: 'DELETE'
+    });
+  }
+
+  async getIncidentPresence(incidentId) {
+    return this.request(`/incidents/${incidentId}/presence`);
+  }
+
+  // Server-sent presence updates. EventSource can't send the Authorization
+  // header, so read the stream with fetch. Resolves when the stream ends or
+  // signal is aborted.
+  async streamIncidentPresence(incidentId, onViewers, signal) {
+    const response = await fetch(`${this.baseURL}/incidents/${incidentId}/presence/stream`, {
+      headers: {
+        Accept: 'text/event-stream',
+        ...(this.token && { Authorization: `Bearer ${this.token}` }),
+      },
+      signal,
+    });
+    if (!response.ok || !response.body) {
+      throw new Error(`Presence stream failed: ${response.status}`);
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    try {
+      while (true) {
+        const { value, done } = await reader.read();
+        if (done) break;
+        buffer += decoder.decode(value, { stream: true });
+
+        let boundary;
+        while ((boundary = buffer.indexOf('\n\n')) !== -1) {
+          const chunk = buffer.slice(0, boundary);
+          buffer = buffer.slice(boundary + 2);
+          const data = chunk
+            .split('\n')
+            .filter((line) => line.startsWith('data:'))
+            .map((line) => line.slice(5).trimStart())
+            .join('\n');
+          if (!data) continue; // keep-alive comment
+          try {
+            onViewers(JSON.parse(data).viewers || []);
+          } catch (err) {
+            console.error('Invalid presence event:', err);
+          }
+        }
+      }
+    } catch (err) {
+      if (err.name !== 'AbortError') throw err;
+    }
+  }
+
   // ReBAC: org_id is required for tenant isolation, project_id is optional
   async getIncidentStats(filters = {}) {
     const params = this._buildReBACParams(filters);
@@ -423,6 +581,16 @@ class APIClient {
     });
   }
 
+  // Emergency broadcast: pages every active group member at once (group leaders and org admins only)
+  async broadcastToGroup(groupId, broadcastData, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/groups/${groupId}/broadcast${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST',
+      body: JSON.stringify(broadcastData)
+    });
+  }
+
   // Simple GitHub-style user search
   // ReBAC: org_id is required for tenant isolation
   async searchUsers(filters = {}) {
@@ -830,6 +998,48 @@ class APIClient {
     return this.request(`/alerts/by-labels${queryString ? `?${queryString}` : ''}`);
   }
 
+  // Dedup review queue: alerts that only matched an open incident by title
+  async getDedupReviews(filters = {}) {
+    const params = this._buildReBACParams(filters);
+    if (filters.status) params.append('status', filters.status);
+    if (filters.limit) params.append('limit', filters.limit);
+    const queryString = params.toString();
+    return this.request(`/dedup-reviews${queryString ? `?${queryString}` : ''}`);
+  }
+
+  async attachDedupReview(reviewId, incidentId = null, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/dedup-reviews/${reviewId}/attach${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST',
+      body: JSON.stringify(incidentId ? { incident_id: incidentId } : {})
+    });
+  }
+
+  async promoteDedupReview(reviewId, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/dedup-reviews/${reviewId}/promote${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST'
+    });
+  }
+
+  async dismissDedupReview(reviewId, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/dedup-reviews/${reviewId}/dismiss${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST'
+    });
+  }
+
+  async getDedupStats(filters = {}) {
+    const params = this._buildReBACParams(filters);
+    if (filters.from) params.append('from', filters.from);
+    if (filters.to) params.append('to', filters.to);
+    const queryString = params.toString();
+    return this.request(`/dedup-reviews/stats${queryString ? `?${queryString}` : ''}`);
+  }
+
   async getUserPreferences() {
     return this.request('/user/preferences');
   }
@@ -987,6 +1197,36 @@ class APIClient {
     });
   }
 
+  // Drills: practice incidents that page the service's real escalation path
+  async getServiceDrills(serviceId, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/services/${serviceId}/drills${queryString ? `?${queryString}` : ''}`);
+  }
+
+  async createServiceDrill(serviceId, drillData = {}, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/services/${serviceId}/drills${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST',
+      body: JSON.stringify(drillData)
+    });
+  }
+
+  async cancelServiceDrill(serviceId, drillId, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/services/${serviceId}/drills/${drillId}/cancel${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST'
+    });
+  }
+
+  async getServiceDrillReport(serviceId, drillId, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    return this.request(`/services/${serviceId}/drills/${drillId}/report${queryString ? `?${queryString}` : ''}`);
+  }
+
   // ReBAC: org_id is required for tenant isolation
   async getIntegrationServices(integrationId, filters = {}) {
     const params = this._buildReBACParams(filters);
@@ -1079,6 +1319,38 @@ class APIClient {
     return this.request(`/groups/${groupId}/escalation-policies/${policyId}/detail${queryString ? `?${queryString}` : ''}`);
   }
 
+  // Returns the policy as YAML text, with targets referenced by name
+  async exportEscalationPolicyYAML(groupId, policyId, filters = {}) {
+    const params = this._buildReBACParams(filters);
+    const queryString = params.toString();
+    const url = `${this.baseURL}/groups/${groupId}/escalation-policies/${policyId}/export${queryString ? `?${queryString}` : ''}`;
+
+    const response = await fetch(url, {
+      headers: {
+        ...(this.token && { Authorization: `Bearer ${this.token}` }),
+      },
+    });
+
+    if (!response.ok) {
+      throw new Error(`Export failed: ${response.status}`);
+    }
+
+    return response.text();
+  }
+
+  // Creates or updates (by name) a policy in the group from YAML text.
+  // With dryRun the document is only validated.
+  async importEscalationPolicyYAML(groupId, yamlText, { dryRun = false, ...filters } = {}) {
+    const params = this._buildReBACParams(filters);
+    if (dryRun) params.append('dry_run', 'true');
+    const queryString = params.toString();
+    return this.request(`/groups/${groupId}/escalation-policies/import${queryString ? `?${queryString}` : ''}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/yaml' },
+      body: yamlText,
+    });
+  }
+
   // ReBAC: levelData should include organization_id (required) and project_id (optional)
   async createEscalationLevel(levelData, filters = {}) {
     const params = this._buildReBACParams(filters);
@@ -1183,6 +1455,92 @@ class APIClient {
     return this.request('/users/me/notifications/stats');
   }
 
+  // Get incident participation metrics for a user ('me' for the current user)
+  // filters: { org_id, from, to } (RFC3339, defaults to the last 30 days)
+  async getUserIncidentStats(userId = 'me', filters = {}) {
+    const params = this._buildReBACParams(filters);
+    if (filters.from) params.append('from', filters.from);
+    if (filters.to) params.append('to', filters.to);
+    const queryString = params.toString();
+    return this.request(`/users/${userId}/incident-stats${queryString ? `?${queryString}` : ''}`);
+  }
+
+  // Get current user's WhatsApp consent
+  async getWhatsAppConsent() {
+    return this.request('/users/me/whatsapp');
+  }
+
+  // Opt in to WhatsApp notifications ({ phone_number, consent: true })
+  async optInWhatsApp(data) {
+    return this.request('/users/me/whatsapp/opt-in', {
+      method: 'POST',
+      body: JSON.stringify(data)
+    });
+  }
+
+  // Opt out of WhatsApp notifications
+  async optOutWhatsApp() {
+    return this.request('/users/me/whatsapp/opt-out', {
+      method: 'POST'
+    });
+  }
+
+  // Get the current user's phone for SMS commands (ACK/RES by text)
+  async getSMSPhone() {
+    return this.request('/users/me/sms');
+  }
+
+  // Link a phone for SMS commands ({ phone_number }); returns the VERIFY code to text from it
+  async setSMSPhone(data) {
+    return this.request('/users/me/sms', {
+      method: 'POST',
+      body: JSON.stringify(data)
+    });
+  }
+
+  // Unlink the current user's SMS phone
+  async removeSMSPhone() {
+    return this.request('/users/me/sms', {
+      method: 'DELETE'
+    });
+  }
+
+  // Turn phone-call pages to the verified SMS phone on or off ({ enabled })
+  async setVoiceCalls(data) {
+    return this.request('/users/me/voice', {
+      method: 'PUT',
+      body: JSON.stringify(data)
+    });
+  }
+
+  // Get the Web Push public key and the current user's subscribed browsers
+  async getWebPush() {
+    return this.request('/users/me/web-push');
+  }
+
+  // Register this browser's push subscription ({ endpoint, keys, user_agent })
+  async subscribeWebPush(subscription) {
+    return this.request('/users/me/web-push/subscriptions', {
+      method: 'POST',
+      body: JSON.stringify(subscription)
+    });
+  }
+
+  // Remove this browser's push subscription
+  async unsubscribeWebPush(endpoint) {
+    return this.request('/users/me/web-push/unsubscribe', {
+      method: 'POST',
+      body: JSON.stringify({ endpoint })
+    });
+  }
+
+  // Remove one of the current user's subscribed browsers
+  async deleteWebPushSubscription(subscriptionId) {
+    return this.request(`/users/me/web-push/subscriptions/${subscriptionId}`, {
+      method: 'DELETE'
+    });
+  }
+
   // Get current user info
   async getCurrentUser() {
     return this.request('/user/me');
@@ -1899,6 +2257,31 @@ class APIClient {
     });
   }
 
+  /**
+   * Get effective feature flags for the current organization
+   * @param {object} filters - { org_id }
+   * @returns {Promise<object>} { features: { key: enabled }, flags: [...] }
+   */
+  async getFeatures(filters = {}) {
+    const params = this._buildReBACParams({ org_id: filters.org_id });
+    const query = params.toString();
+    return this.request(`/features${query ? `?${query}` : ''}`);
+  }
+
+  /**
+   * Enable or disable a feature flag for an organization (org admins)
+   * @param {string} orgId - Organization ID
+   * @param {string} key - Feature flag key
+   * @param {boolean|null} enabled - null restores the default
+   * @returns {Promise<object>} Updated flag
+   */
+  async setOrgFeatureFlag(orgId, key, enabled) {
+    return this.request(`/orgs/${orgId}/features/${key}`, {
+      method: 'PUT',
+      body: JSON.stringify({ enabled })
+    });
+  }
+
   /**
    * Get projects within an organization
    * @param {string} orgId - Organization ID
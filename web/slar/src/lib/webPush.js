@@ -0,0 +1,74 @@
+/**
+ * Browser (Web Push) subscription helpers.
+ *
+ * The service worker in public/sw.js shows the notifications; these helpers
+ * ask for permission and manage this browser's PushSubscription.
+ */
+
+export const isWebPushSupported = () =>
+  typeof window !== 'undefined' &&
+  'serviceWorker' in navigator &&
+  'PushManager' in window &&
+  'Notification' in window;
+
+// VAPID keys are base64url; PushManager wants the raw bytes
+const urlBase64ToUint8Array = (base64String) => {
+  const padding = '='.repeat((4 - (base64String.length % 4)) % 4);
+  const base64 = (base64String + padding).replace(/-/g, '+').replace(/_/g, '/');
+  const raw = window.atob(base64);
+  return Uint8Array.from(raw, (c) => c.charCodeAt(0));
+};
+
+const getRegistration = async () => {
+  const existing = await navigator.serviceWorker.getRegistration('/');
+  if (existing) {
+    return existing;
+  }
+  await navigator.serviceWorker.register('/sw.js', { scope: '/' });
+  return navigator.serviceWorker.ready;
+};
+
+/**
+ * Returns this browser's current push subscription, or null
+ */
+export const getBrowserSubscription = async () => {
+  if (!isWebPushSupported()) {
+    return null;
+  }
+  const registration = await navigator.serviceWorker.getRegistration('/');
+  return registration ? registration.pushManager.getSubscription() : null;
+};
+
+/**
+ * Asks for notification permission and subscribes this browser
+ * @param {string} publicKey - VAPID public key from GET /users/me/web-push
+ * @returns {Promise<object>} PushSubscription JSON to send to the API
+ */
+export const subscribeBrowser = async (publicKey) => {
+  const permission = await Notification.requestPermission();
+  if (permission !== 'granted') {
+    throw new Error('Notification permission was not granted');
+  }
+  const registration = await getRegistration();
+  const subscription =
+    (await registration.pushManager.getSubscription()) ||
+    (await registration.pushManager.subscribe({
+      userVisibleOnly: true,
+      applicationServerKey: urlBase64ToUint8Array(publicKey),
+    }));
+  return subscription.toJSON();
+};
+
+/**
+ * Unsubscribes this browser
+ * @returns {Promise<string|null>} the endpoint that was removed
+ */
+export const unsubscribeBrowser = async () => {
+  const subscription = await getBrowserSubscription();
+  if (!subscription) {
+    return null;
+  }
+  const { endpoint } = subscription;
+  await subscription.unsubscribe();
+  return endpoint;
+};